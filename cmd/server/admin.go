@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/twitchtv/twirp"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const adminRequestTimeout = 10 * time.Second
+
+// flags shared by subcommands that talk to a running server
+var adminFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "url",
+		Usage:   "URL of the server, defaults to the configured port on localhost",
+		EnvVars: []string{"LIVEKIT_URL"},
+	},
+	&cli.StringFlag{
+		Name:  "api-key",
+		Usage: "API key to sign requests with, defaults to the first configured key",
+	},
+}
+
+var roomFlag = &cli.StringFlag{
+	Name:     "room",
+	Usage:    "name of the room",
+	Required: true,
+}
+
+var identityFlag = &cli.StringFlag{
+	Name:     "identity",
+	Usage:    "identity of the participant",
+	Required: true,
+}
+
+func adminCommands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:  "rooms",
+			Usage: "manage rooms of a running cluster",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list active rooms",
+					Flags:  adminFlags,
+					Action: listRooms,
+				},
+				{
+					Name:   "delete",
+					Usage:  "close a room, disconnecting all of its participants",
+					Flags:  append([]cli.Flag{roomFlag}, adminFlags...),
+					Action: deleteRoom,
+				},
+			},
+		},
+		{
+			Name:  "participants",
+			Usage: "manage participants of a running cluster",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list participants of a room",
+					Flags:  append([]cli.Flag{roomFlag}, adminFlags...),
+					Action: listParticipants,
+				},
+				{
+					Name:   "remove",
+					Usage:  "remove a participant from a room",
+					Flags:  append([]cli.Flag{roomFlag, identityFlag}, adminFlags...),
+					Action: removeParticipant,
+				},
+				{
+					Name:  "mute",
+					Usage: "mute a track published by a participant",
+					Flags: append([]cli.Flag{
+						roomFlag,
+						identityFlag,
+						&cli.StringFlag{
+							Name:     "track",
+							Usage:    "sid of the track",
+							Required: true,
+						},
+						&cli.BoolFlag{
+							Name:  "unmute",
+							Usage: "unmute the track instead",
+						},
+					}, adminFlags...),
+					Action: muteTrack,
+				},
+			},
+		},
+		{
+			Name:  "token",
+			Usage: "create access tokens",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "create",
+					Usage: "create an access token signed with a configured key",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "room",
+							Usage: "name of the room the token is valid for",
+						},
+						&cli.StringFlag{
+							Name:  "identity",
+							Usage: "identity of the participant, required to join",
+						},
+						&cli.BoolFlag{
+							Name:  "join",
+							Usage: "allow joining the room",
+						},
+						&cli.BoolFlag{
+							Name:  "admin",
+							Usage: "allow managing the room",
+						},
+						&cli.BoolFlag{
+							Name:  "list",
+							Usage: "allow listing rooms",
+						},
+						&cli.BoolFlag{
+							Name:  "create",
+							Usage: "allow creating and deleting rooms",
+						},
+						&cli.DurationFlag{
+							Name:  "valid-for",
+							Usage: "how long the token is valid for",
+							Value: 6 * time.Hour,
+						},
+						&cli.StringFlag{
+							Name:  "api-key",
+							Usage: "API key to sign the token with, defaults to the first configured key",
+						},
+					},
+					Action: createAccessToken,
+				},
+			},
+		},
+	}
+}
+
+func listRooms(c *cli.Context) error {
+	client, ctx, cancel, err := newRoomServiceClient(c, &auth.VideoGrant{RoomList: true})
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	res, err := client.ListRooms(ctx, &livekit.ListRoomsRequest{})
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SID", "Name", "Participants", "Publishers", "Created At"})
+	for _, rm := range res.Rooms {
+		table.Append([]string{
+			rm.Sid, rm.Name,
+			strconv.Itoa(int(rm.NumParticipants)), strconv.Itoa(int(rm.NumPublishers)),
+			time.Unix(rm.CreationTime, 0).UTC().Format("2006-01-02 15:04:05"),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func deleteRoom(c *cli.Context) error {
+	client, ctx, cancel, err := newRoomServiceClient(c, &auth.VideoGrant{RoomCreate: true})
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if _, err = client.DeleteRoom(ctx, &livekit.DeleteRoomRequest{Room: c.String("room")}); err != nil {
+		return err
+	}
+	fmt.Println("deleted room", c.String("room"))
+	return nil
+}
+
+func listParticipants(c *cli.Context) error {
+	room := c.String("room")
+	client, ctx, cancel, err := newRoomServiceClient(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	res, err := client.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room})
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetRowLine(true)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"SID", "Identity", "State", "Tracks", "Joined At"})
+	for _, p := range res.Participants {
+		tracks := make([]string, 0, len(p.Tracks))
+		for _, t := range p.Tracks {
+			track := fmt.Sprintf("%s %s %s", t.Sid, t.Type, t.Source)
+			if t.Muted {
+				track += " (muted)"
+			}
+			tracks = append(tracks, track)
+		}
+		table.Append([]string{
+			p.Sid, p.Identity, p.State.String(), strings.Join(tracks, "\n"),
+			time.Unix(p.JoinedAt, 0).UTC().Format("2006-01-02 15:04:05"),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func removeParticipant(c *cli.Context) error {
+	room := c.String("room")
+	client, ctx, cancel, err := newRoomServiceClient(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	if _, err = client.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{
+		Room:     room,
+		Identity: c.String("identity"),
+	}); err != nil {
+		return err
+	}
+	fmt.Println("removed", c.String("identity"), "from", room)
+	return nil
+}
+
+func muteTrack(c *cli.Context) error {
+	room := c.String("room")
+	client, ctx, cancel, err := newRoomServiceClient(c, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	res, err := client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+		Room:     room,
+		Identity: c.String("identity"),
+		TrackSid: c.String("track"),
+		Muted:    !c.Bool("unmute"),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("track", res.Track.Sid, "muted:", res.Track.Muted)
+	return nil
+}
+
+func createAccessToken(c *cli.Context) error {
+	conf, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+	apiKey, apiSecret, err := getAPIKeyPair(conf, c.String("api-key"))
+	if err != nil {
+		return err
+	}
+
+	grant := &auth.VideoGrant{
+		Room:       c.String("room"),
+		RoomJoin:   c.Bool("join"),
+		RoomAdmin:  c.Bool("admin"),
+		RoomList:   c.Bool("list"),
+		RoomCreate: c.Bool("create"),
+	}
+	if grant.RoomJoin && c.String("identity") == "" {
+		return fmt.Errorf("identity is required to join")
+	}
+	if (grant.RoomJoin || grant.RoomAdmin) && grant.Room == "" {
+		return fmt.Errorf("room is required to join or manage a room")
+	}
+	if !grant.RoomJoin && !grant.RoomAdmin && !grant.RoomList && !grant.RoomCreate {
+		return fmt.Errorf("at least one of --join, --admin, --list or --create is required")
+	}
+
+	token, err := auth.NewAccessToken(apiKey, apiSecret).
+		AddGrant(grant).
+		SetIdentity(c.String("identity")).
+		SetValidFor(c.Duration("valid-for")).
+		ToJWT()
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// newRoomServiceClient creates a client authenticated with a short-lived token carrying the given grant
+func newRoomServiceClient(c *cli.Context, grant *auth.VideoGrant) (livekit.RoomService, context.Context, context.CancelFunc, error) {
+	conf, err := getConfig(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	apiKey, apiSecret, err := getAPIKeyPair(conf, c.String("api-key"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	token, err := auth.NewAccessToken(apiKey, apiSecret).
+		AddGrant(grant).
+		SetValidFor(time.Minute).
+		ToJWT()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminRequestTimeout)
+	ctx, err = twirp.WithHTTPRequestHeaders(ctx, http.Header{"Authorization": []string{"Bearer " + token}})
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	client := livekit.NewRoomServiceProtobufClient(getServerURL(conf, c.String("url")), &http.Client{})
+	return client, ctx, cancel, nil
+}
+
+// getServerURL returns the URL of the local server when not explicitly set
+func getServerURL(conf *config.Config, url string) string {
+	if url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	if len(conf.Listeners) == 0 {
+		return fmt.Sprintf("http://localhost:%d", conf.Port)
+	}
+
+	l := conf.Listeners[0]
+	scheme := "http"
+	if l.TLS.CertFile != "" {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(l.Address)
+	if err != nil {
+		return scheme + "://" + l.Address
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// getAPIKeyPair looks up a configured key, or the first one when apiKey is empty
+func getAPIKeyPair(conf *config.Config, apiKey string) (string, string, error) {
+	keys := conf.Keys
+	if len(keys) == 0 && conf.KeyFile != "" {
+		f, err := os.Open(conf.KeyFile)
+		if err != nil {
+			return "", "", err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		if err = yaml.NewDecoder(f).Decode(&keys); err != nil {
+			return "", "", err
+		}
+	}
+	if len(keys) == 0 {
+		return "", "", fmt.Errorf("keys are not configured")
+	}
+
+	if apiKey != "" {
+		secret, ok := keys[apiKey]
+		if !ok {
+			return "", "", fmt.Errorf("API key %s is not configured", apiKey)
+		}
+		return apiKey, secret, nil
+	}
+	// map order is random, pick keys in a stable order
+	apiKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		apiKeys = append(apiKeys, k)
+	}
+	sort.Strings(apiKeys)
+	return apiKeys[0], keys[apiKeys[0]], nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestGetServerURL(t *testing.T) {
+	conf := &config.Config{Port: 7880}
+	require.Equal(t, "http://localhost:7880", getServerURL(conf, ""))
+	require.Equal(t, "https://livekit.example.com", getServerURL(conf, "https://livekit.example.com/"))
+
+	conf.Listeners = []config.ListenerConfig{
+		{Address: "0.0.0.0:443", TLS: config.ListenerTLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}},
+	}
+	require.Equal(t, "https://localhost:443", getServerURL(conf, ""))
+
+	conf.Listeners = []config.ListenerConfig{{Address: "10.0.0.1:7880"}}
+	require.Equal(t, "http://10.0.0.1:7880", getServerURL(conf, ""))
+}
+
+func TestGetAPIKeyPair(t *testing.T) {
+	conf := &config.Config{Keys: map[string]string{"key2": "secret2", "key1": "secret1"}}
+
+	key, secret, err := getAPIKeyPair(conf, "")
+	require.NoError(t, err)
+	require.Equal(t, "key1", key)
+	require.Equal(t, "secret1", secret)
+
+	key, secret, err = getAPIKeyPair(conf, "key2")
+	require.NoError(t, err)
+	require.Equal(t, "key2", key)
+	require.Equal(t, "secret2", secret)
+
+	_, _, err = getAPIKeyPair(conf, "key3")
+	require.Error(t, err)
+
+	_, _, err = getAPIKeyPair(&config.Config{}, "")
+	require.Error(t, err)
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
-	"gopkg.in/yaml.v3"
 
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/utils"
@@ -88,34 +87,9 @@ func createToken(c *cli.Context) error {
 	}
 
 	// use the first API key from config
-	if len(conf.Keys) == 0 {
-		// try to load from file
-		if _, err := os.Stat(conf.KeyFile); err != nil {
-			return err
-		}
-		f, err := os.Open(conf.KeyFile)
-		if err != nil {
-			return err
-		}
-		defer func() {
-			_ = f.Close()
-		}()
-		decoder := yaml.NewDecoder(f)
-		if err = decoder.Decode(conf.Keys); err != nil {
-			return err
-		}
-
-		if len(conf.Keys) == 0 {
-			return fmt.Errorf("keys are not configured")
-		}
-	}
-
-	var apiKey string
-	var apiSecret string
-	for k, v := range conf.Keys {
-		apiKey = k
-		apiSecret = v
-		break
+	apiKey, apiSecret, err := getAPIKeyPair(conf, "")
+	if err != nil {
+		return err
 	}
 
 	grant := &auth.VideoGrant{
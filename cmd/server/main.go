@@ -7,13 +7,18 @@ import (
 	"os/signal"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/telemetry/errorreporting"
+	"github.com/livekit/livekit-server/pkg/telemetry/profiler"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/telemetry/tracing"
 	"github.com/livekit/protocol/logger"
 
 	"github.com/livekit/livekit-server/pkg/config"
@@ -36,6 +41,11 @@ var baseFlags = []cli.Flag{
 		Usage:   "LiveKit config in YAML, typically passed in as an environment var in a container",
 		EnvVars: []string{"LIVEKIT_CONFIG"},
 	},
+	&cli.StringFlag{
+		Name:    "profile",
+		Usage:   "built-in profile to start from: " + strings.Join(config.ProfileNames(), ", ") + ". config and flags override it",
+		EnvVars: []string{"LIVEKIT_PROFILE"},
+	},
 	&cli.StringFlag{
 		Name:  "key-file",
 		Usage: "path to file that contains API keys/secrets",
@@ -118,7 +128,7 @@ func main() {
 		Description: "run without subcommands to start the server",
 		Flags:       append(baseFlags, generatedFlags...),
 		Action:      startServer,
-		Commands: []*cli.Command{
+		Commands: append([]*cli.Command{
 			{
 				Name:   "generate-keys",
 				Usage:  "generates an API key and secret pair",
@@ -163,7 +173,7 @@ func main() {
 				Usage:  "prints app help, including all generated configuration flags",
 				Action: helpVerbose,
 			},
-		},
+		}, adminCommands()...),
 		Version: version.Version,
 	}
 
@@ -173,34 +183,49 @@ func main() {
 }
 
 func getConfig(c *cli.Context) (*config.Config, error) {
-	confString, err := getConfigString(c.String("config"), c.String("config-body"))
+	conf, placeholderKeys, err := loadConfig(c)
 	if err != nil {
 		return nil, err
 	}
+	config.InitLoggerFromConfig(conf.Logging)
+
+	if isDevModeWithoutConfig(c, conf) {
+		logger.Infow("starting in development mode")
+	}
+	if placeholderKeys {
+		logger.Infow("no keys provided, using placeholder keys",
+			"API Key", "devkey",
+			"API Secret", "secret",
+		)
+	}
+	return conf, nil
+}
+
+// loadConfig reads the configuration, it is also used to reload it
+func loadConfig(c *cli.Context) (conf *config.Config, placeholderKeys bool, err error) {
+	confString, err := getConfigString(c.String("config"), c.String("config-body"))
+	if err != nil {
+		return nil, false, err
+	}
 
 	strictMode := true
 	if c.Bool("disable-strict-config") {
 		strictMode = false
 	}
 
-	conf, err := config.NewConfig(confString, strictMode, c, baseFlags)
+	conf, err = config.NewConfig(confString, strictMode, c, baseFlags)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	config.InitLoggerFromConfig(conf.Logging)
 
-	if c.String("config") == "" && c.String("config-body") == "" && conf.Development {
+	if isDevModeWithoutConfig(c, conf) {
 		// use single port UDP when no config is provided
 		conf.RTC.UDPPort = 7882
 		conf.RTC.ICEPortRangeStart = 0
 		conf.RTC.ICEPortRangeEnd = 0
-		logger.Infow("starting in development mode")
 
 		if len(conf.Keys) == 0 {
-			logger.Infow("no keys provided, using placeholder keys",
-				"API Key", "devkey",
-				"API Secret", "secret",
-			)
+			placeholderKeys = true
 			conf.Keys = map[string]string{
 				"devkey": "secret",
 			}
@@ -213,7 +238,11 @@ func getConfig(c *cli.Context) (*config.Config, error) {
 			}
 		}
 	}
-	return conf, nil
+	return conf, placeholderKeys, nil
+}
+
+func isDevModeWithoutConfig(c *cli.Context, conf *config.Config) bool {
+	return c.String("config") == "" && c.String("config-body") == "" && conf.Development
 }
 
 func startServer(c *cli.Context) error {
@@ -232,6 +261,11 @@ func startServer(c *cli.Context) error {
 		return err
 	}
 
+	// mixing audio needs an opus codec, linked in builds with the opus tag
+	if len(conf.Room.MixedAudioTrack.Rooms) != 0 && audio.GetOpusCodec() == nil {
+		return fmt.Errorf("room.mixed_audio_track requires a server built with libopus, using -tags opus")
+	}
+
 	if memProfile != "" {
 		if f, err := os.Create(memProfile); err != nil {
 			return err
@@ -251,8 +285,28 @@ func startServer(c *cli.Context) error {
 	}
 
 	prometheus.Init(currentNode.Id, currentNode.Type, conf.Environment)
+	if err = errorreporting.Init(conf.ErrorReporting, currentNode.Id, conf.Environment); err != nil {
+		return err
+	}
+	defer errorreporting.Close()
+	if err = profiler.Start(conf.ContinuousProfiling, currentNode.Id, conf.Environment); err != nil {
+		return err
+	}
+	defer profiler.Stop()
+	if err = tracing.Init(conf.Tracing, currentNode.Id, conf.Environment); err != nil {
+		return err
+	}
+	defer tracing.Close()
 
-	server, err := service.InitializeServer(conf, currentNode)
+	current := config.NewCurrent(conf)
+	current.SetLoader(func() (*config.Config, error) {
+		conf, _, err := loadConfig(c)
+		return conf, err
+	})
+	current.OnReload(func(conf *config.Config) {
+		config.SetLogLevel(conf.Logging)
+	})
+	server, err := service.InitializeServer(current, currentNode)
 	if err != nil {
 		return err
 	}
@@ -266,6 +320,20 @@ func startServer(c *cli.Context) error {
 		server.Stop(false)
 	}()
 
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	go func() {
+		for range reloadChan {
+			res, err := current.Reload()
+			if err != nil {
+				logger.Warnw("could not reload configuration", err)
+				continue
+			}
+			logger.Infow("configuration reloaded", "changed", res.Changed, "restartRequired", res.RestartRequired)
+		}
+	}()
+
 	return server.Start()
 }
 
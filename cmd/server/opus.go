@@ -0,0 +1,11 @@
+//go:build opus && cgo
+
+package main
+
+import (
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+)
+
+func init() {
+	audio.RegisterOpusCodec(audio.LibOpus{})
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/mitchellh/go-homedir"
@@ -16,6 +17,8 @@ import (
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/logger/pionlogger"
 	redisLiveKit "github.com/livekit/protocol/redis"
+
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 var DefaultStunServers = []string{
@@ -25,16 +28,32 @@ var DefaultStunServers = []string{
 
 type CongestionControlProbeMode string
 type StreamTrackerType string
+type CodecFallbackPolicy string
+type PanicPolicy string
 
 const (
 	generatedCLIFlagUsage = "generated"
 
 	CongestionControlProbeModePadding CongestionControlProbeMode = "padding"
 	CongestionControlProbeModeMedia   CongestionControlProbeMode = "media"
+	// padding probes at a gain over the channel capacity every cycle, also when no track is deficient
+	CongestionControlProbeModeBBR CongestionControlProbeMode = "bbr"
 
 	StreamTrackerTypePacket StreamTrackerType = "packet"
 	StreamTrackerTypeFrame  StreamTrackerType = "frame"
 
+	// subscriber is not given the track
+	CodecFallbackPolicyExclude CodecFallbackPolicy = "exclude"
+	// publisher is asked to publish a codec the subscriber can decode
+	CodecFallbackPolicyRequestBackup CodecFallbackPolicy = "request_backup"
+	// track is routed through a transcoder when one is available
+	CodecFallbackPolicyTranscode CodecFallbackPolicy = "transcode"
+
+	// the goroutine of the track that panicked is stopped and the track closed
+	PanicPolicyRecover PanicPolicy = "recover"
+	// the panic crashes the server
+	PanicPolicyExit PanicPolicy = "exit"
+
 	StatsUpdateInterval          = time.Second * 10
 	TelemetryStatsUpdateInterval = time.Second * 30
 )
@@ -45,8 +64,11 @@ var (
 )
 
 type Config struct {
+	// built-in profile the config is applied on top of, see ProfileNames
+	Profile        string                   `yaml:"profile,omitempty"`
 	Port           uint32                   `yaml:"port"`
 	BindAddresses  []string                 `yaml:"bind_addresses,omitempty"`
+	Listeners      []ListenerConfig         `yaml:"listeners,omitempty"`
 	PrometheusPort uint32                   `yaml:"prometheus_port,omitempty"`
 	Environment    string                   `yaml:"environment,omitempty"`
 	RTC            RTCConfig                `yaml:"rtc,omitempty"`
@@ -57,12 +79,35 @@ type Config struct {
 	TURN           TURNConfig               `yaml:"turn,omitempty"`
 	Egress         EgressConfig             `yaml:"egress,omitempty"`
 	Ingress        IngressConfig            `yaml:"ingress,omitempty"`
+	Transcoder     TranscoderConfig         `yaml:"transcoder,omitempty"`
+	RTMP           RTMPConfig               `yaml:"rtmp,omitempty"`
+	Playback       PlaybackConfig           `yaml:"playback,omitempty"`
+	Timeline       TimelineConfig           `yaml:"timeline,omitempty"`
+	Presence       PresenceConfig           `yaml:"presence,omitempty"`
+	RoomManifest   RoomManifestConfig       `yaml:"room_manifest,omitempty"`
+	Retention      RetentionConfig          `yaml:"retention,omitempty"`
+	Autoscaling    AutoscalingConfig        `yaml:"autoscaling,omitempty"`
+	Dashboard      DashboardConfig          `yaml:"dashboard,omitempty"`
 	WebHook        WebHookConfig            `yaml:"webhook,omitempty"`
 	NodeSelector   NodeSelectorConfig       `yaml:"node_selector,omitempty"`
 	KeyFile        string                   `yaml:"key_file,omitempty"`
 	Keys           map[string]string        `yaml:"keys,omitempty"`
+	CORS           CORSConfig               `yaml:"cors,omitempty"`
 	Region         string                   `yaml:"region,omitempty"`
 	SignalRelay    SignalRelayConfig        `yaml:"signal_relay,omitempty"`
+	WebTransport   WebTransportConfig       `yaml:"webtransport,omitempty"`
+	ErrorReporting ErrorReportingConfig     `yaml:"error_reporting,omitempty"`
+	Profiling      ProfilingConfig          `yaml:"profiling,omitempty"`
+	// API keys whose tokens may administer nodes, e.g. drain, profiling and config reload. refused when empty
+	AdminAPIKeys []string `yaml:"admin_api_keys,omitempty"`
+	// pushes profiles to a continuous profiler, with media goroutines labelled by room
+	ContinuousProfiling ContinuousProfilingConfig `yaml:"continuous_profiling,omitempty"`
+	// exports spans of joins, negotiations, publications and subscriptions over OTLP
+	Tracing TracingConfig `yaml:"tracing,omitempty"`
+	// authentication and encryption of media relayed between nodes
+	MediaRelay MediaRelayConfig `yaml:"media_relay,omitempty"`
+	// locates clients by address for placing rooms, choosing TURN servers and enriching telemetry
+	GeoIP GeoIPConfig `yaml:"geoip,omitempty"`
 	// LogLevel is deprecated
 	LogLevel string        `yaml:"log_level,omitempty"`
 	Logging  LoggingConfig `yaml:"logging,omitempty"`
@@ -88,8 +133,44 @@ type RTCConfig struct {
 	UseMDNS                 bool             `yaml:"use_mdns,omitempty"`
 	StrictACKs              bool             `yaml:"strict_acks,omitempty"`
 
-	// Number of packets to buffer for NACK
-	PacketBufferSize int `yaml:"packet_buffer_size,omitempty"`
+	// host candidate advertisement per network interface, interfaces not listed follow use_external_ip/node_ip
+	InterfaceNAT []InterfaceNATConfig `yaml:"interface_nat,omitempty"`
+	// CIDRs of internal networks, clients connecting from them are given local addresses instead of the NAT mapping
+	InternalNetworks []string `yaml:"internal_networks,omitempty"`
+	// when use_external_ip is set, the external IPs are resolved again at this interval, participants are asked to
+	// reconnect when they changed. 0 disables it
+	ExternalIPCheckInterval time.Duration `yaml:"external_ip_check_interval,omitempty"`
+	// IPv6 only deployment, only IPv6 candidates are gathered and advertised and external IPs are resolved over IPv6
+	IPv6Only bool `yaml:"ipv6_only,omitempty"`
+	// prefix of the NAT64 gateway of an IPv6 only network, e.g. 64:ff9b::/96. IPv6 candidates are synthesized in it
+	// for the IPv4 candidates of clients, the node reaches IPv4 only clients through the gateway
+	NAT64Prefix string `yaml:"nat64_prefix,omitempty"`
+	// kernel receive timestamps of media packets (SO_TIMESTAMPING, Linux only) are used for jitter and bandwidth
+	// estimation in place of the time packets are read: software or hardware, empty disables it
+	PacketTimestamping string `yaml:"packet_timestamping,omitempty"`
+
+	// Number of packets to buffer for NACK, for video, audio and screen share tracks
+	PacketBufferSize            int `yaml:"packet_buffer_size,omitempty"`
+	PacketBufferSizeAudio       int `yaml:"packet_buffer_size_audio,omitempty"`
+	PacketBufferSizeScreenShare int `yaml:"packet_buffer_size_screenshare,omitempty"`
+
+	// when set, packet buffers are sized to hold this much media at the observed packet rate
+	PacketBufferDuration PacketBufferDurationConfig `yaml:"packet_buffer_duration,omitempty"`
+
+	// NACK based retransmission of audio to subscribers
+	AudioNACK AudioNACKConfig `yaml:"audio_nack,omitempty"`
+
+	// FlexFEC protection of video sent to subscribers on lossy links
+	FlexFEC FlexFECConfig `yaml:"flexfec,omitempty"`
+
+	// retransmit video to subscribers on a separate RTX stream (RFC 4588) when they accept it
+	SubscriberRTX bool `yaml:"subscriber_rtx,omitempty"`
+
+	// pacing of packets sent to subscribers to their estimated channel capacity
+	Pacer PacerConfig `yaml:"pacer,omitempty"`
+
+	// congestion control feedback sent to publishers
+	TWCC TWCCConfig `yaml:"twcc,omitempty"`
 
 	// Throttle periods for pli/fir rtcp packets
 	PLIThrottle PLIThrottleConfig `yaml:"pli_throttle,omitempty"`
@@ -107,6 +188,49 @@ type RTCConfig struct {
 
 	// force a reconnect on a subscription error
 	ReconnectOnSubscriptionError *bool `yaml:"reconnect_on_subscription_error,omitempty"`
+
+	// reconnect backoff sent to clients in the join response and leave requests
+	ReconnectPolicy ReconnectPolicyConfig `yaml:"reconnect_policy,omitempty"`
+
+	// binds participant identities to the DTLS certificate fingerprint declared by their clients
+	IdentityBinding IdentityBindingConfig `yaml:"identity_binding,omitempty"`
+
+	// pace video subscriptions of a room after many participants (re)connect at once
+	SlowStart SlowStartConfig `yaml:"slow_start,omitempty"`
+
+	// ordering and nomination of ICE candidate pairs
+	ICEPrioritization ICEPrioritizationConfig `yaml:"ice_prioritization,omitempty"`
+
+	// the node connects out to passive TCP candidates of clients, e.g. other servers of a cascade
+	TCPActive TCPActiveConfig `yaml:"tcp_active,omitempty"`
+
+	// per track jitter and forwarding delay histograms sent to the telemetry sink, for research use
+	DetailedStats DetailedStatsConfig `yaml:"detailed_stats,omitempty"`
+}
+
+type TCPActiveConfig struct {
+	// requires tcp_port
+	Enabled bool `yaml:"enabled,omitempty"`
+	// CIDRs the node may connect to, public addresses only when empty
+	Networks    []string      `yaml:"networks,omitempty"`
+	DialTimeout time.Duration `yaml:"dial_timeout,omitempty"`
+	// connections opened per second at most, across clients
+	DialRate float32 `yaml:"dial_rate,omitempty"`
+}
+
+type ICEPrioritizationConfig struct {
+	// candidates of this address family are preferred by clients, ipv4 or ipv6
+	PreferFamily string `yaml:"prefer_family,omitempty"`
+	// local preference taken from TCP candidates, so that clients prefer UDP by at least this margin
+	TCPMargin uint16 `yaml:"tcp_margin,omitempty"`
+	// when the server nominates, time a succeeded pair of each candidate type waits for a better pair.
+	// Raising relay_wait penalizes relayed pairs
+	HostWait  time.Duration `yaml:"host_wait,omitempty"`
+	SrflxWait time.Duration `yaml:"srflx_wait,omitempty"`
+	PrflxWait time.Duration `yaml:"prflx_wait,omitempty"`
+	RelayWait time.Duration `yaml:"relay_wait,omitempty"`
+	// nominate the first pair that succeeds, regardless of the waits
+	AggressiveNomination bool `yaml:"aggressive_nomination,omitempty"`
 }
 
 type TURNServer struct {
@@ -115,6 +239,29 @@ type TURNServer struct {
 	Protocol   string `yaml:"protocol"`
 	Username   string `yaml:"username,omitempty"`
 	Credential string `yaml:"credential,omitempty"`
+	// shared secret of a server validating TURN REST API credentials, participants are then issued credentials of
+	// their own instead of username and credential
+	Secret string `yaml:"secret,omitempty"`
+	// node selector region the server is in. When a client is located, servers of other regions than the one
+	// nearest to it are left out
+	Region string `yaml:"region,omitempty"`
+}
+
+type GeoIPConfig struct {
+	// maxmind, ip-api or the name of a provider registered with geoip.RegisterProvider, disabled when empty
+	Provider string `yaml:"provider,omitempty"`
+	// path of a MaxMind city database, reloaded when the file changes
+	DBPath string `yaml:"db_path,omitempty"`
+	// how often the database file is checked for changes
+	ReloadInterval time.Duration `yaml:"reload_interval,omitempty"`
+	// base URL of the ip-api service, https only
+	URL string `yaml:"url,omitempty"`
+	// key of the ip-api service
+	APIKey string `yaml:"api_key,omitempty"`
+	// lookups are cached for this long, 0 to disable caching
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+	// number of addresses cached
+	CacheSize int `yaml:"cache_size,omitempty"`
 }
 
 type PLIThrottleConfig struct {
@@ -129,6 +276,87 @@ type CongestionControlConfig struct {
 	UseSendSideBWE     bool                       `yaml:"send_side_bandwidth_estimation,omitempty"`
 	ProbeMode          CongestionControlProbeMode `yaml:"padding_mode,omitempty"`
 	MinChannelCapacity int64                      `yaml:"min_channel_capacity,omitempty"`
+	BandwidthEstimator string                     `yaml:"bandwidth_estimator,omitempty"`
+	// when set, the inputs of each subscriber's stream allocator are recorded to this directory, to be replayed
+	// with the stream allocator simulator
+	TraceDir string `yaml:"trace_dir,omitempty"`
+}
+
+type PacketBufferDurationConfig struct {
+	Audio       time.Duration `yaml:"audio,omitempty"`
+	Video       time.Duration `yaml:"video,omitempty"`
+	ScreenShare time.Duration `yaml:"screenshare,omitempty"`
+}
+
+type AudioNACKConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// number of packets kept per subscribed audio track for retransmission
+	HistorySize int `yaml:"history_size,omitempty"`
+	// packets older than this are not retransmitted as they would arrive too late to be played out
+	MaxLatency time.Duration `yaml:"max_latency,omitempty"`
+}
+
+type FlexFECConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// downlink loss below this fraction is left to NACK, no repair packets are sent
+	MinLoss float32 `yaml:"min_loss,omitempty"`
+	// repair packets sent relative to media packets at most, i.e. 0.3 adds up to 30% to the bitrate
+	MaxOverhead float32 `yaml:"max_overhead,omitempty"`
+}
+
+type PacerConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// packets are sent at this multiple of the estimated channel capacity
+	Multiplier float32 `yaml:"multiplier,omitempty"`
+	// burst tolerance, packets worth this long at the pacing rate are sent back to back
+	MaxBurst time.Duration `yaml:"max_burst,omitempty"`
+	// media queued longer than this is sent regardless of the rate
+	MaxQueueDelay time.Duration `yaml:"max_queue_delay,omitempty"`
+}
+
+type TWCCConfig struct {
+	// feedback is sent this often, shorter intervals help publishers on high RTT links converge faster
+	FeedbackInterval time.Duration `yaml:"feedback_interval,omitempty"`
+	// transport-cc, or ccfb to offer RFC 8888 feedback to publishers supporting it
+	FeedbackFormat string `yaml:"feedback_format,omitempty"`
+}
+
+type DetailedStatsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// period covered by each exported histogram
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+type ReconnectPolicyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// delay before the first reconnect attempt, doubled on every retry
+	InitialDelay time.Duration `yaml:"initial_delay,omitempty"`
+	MaxDelay     time.Duration `yaml:"max_delay,omitempty"`
+	// number of attempts before clients give up, 0 leaves it to the client
+	MaxRetries uint32 `yaml:"max_retries,omitempty"`
+	// fraction of each delay that clients randomize, between 0 and 1
+	Jitter float32 `yaml:"jitter,omitempty"`
+}
+
+// IdentityBindingConfig guards against a token being replayed by a second device. Clients declaring the fingerprint
+// of their DTLS certificate at join, along with a signature of a nonce from /rtc/nonce made with its key, bind their
+// identity to it. Later joins and resumes of the identity have to declare the same fingerprint, prove it again and
+// complete DTLS with that certificate. Bindings are kept in the room store, shared by all nodes
+type IdentityBindingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// how long a binding outlives the participant that created it, defaults to 10m
+	Window time.Duration `yaml:"window,omitempty"`
+}
+
+type SlowStartConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// slow-start begins when this many participants join or resume within JoinWindow
+	JoinThreshold int           `yaml:"join_threshold,omitempty"`
+	JoinWindow    time.Duration `yaml:"join_window,omitempty"`
+	// slow-start ends this long after the last burst of joins
+	Duration time.Duration `yaml:"duration,omitempty"`
+	// video subscriptions admitted per second in the room during slow-start
+	SubscriptionsPerSecond int `yaml:"subscriptions_per_second,omitempty"`
 }
 
 type InterfacesConfig struct {
@@ -136,6 +364,24 @@ type InterfacesConfig struct {
 	Excludes []string `yaml:"excludes,omitempty"`
 }
 
+const (
+	// advertise the external IP of the node, resolved with STUN when use_external_ip is set
+	InterfaceAdvertiseExternal = "external"
+	// advertise the addresses of the interface
+	InterfaceAdvertiseAsIs = "as_is"
+)
+
+const (
+	ICEFamilyIPv4 = "ipv4"
+	ICEFamilyIPv6 = "ipv6"
+)
+
+type InterfaceNATConfig struct {
+	Interface string `yaml:"interface"`
+	// external, as_is, or an IP address to advertise instead of the interface addresses
+	Advertise string `yaml:"advertise"`
+}
+
 type IPsConfig struct {
 	Includes []string `yaml:"includes,omitempty"`
 	Excludes []string `yaml:"excludes,omitempty"`
@@ -181,6 +427,16 @@ type StreamTrackersConfig struct {
 type VideoConfig struct {
 	DynacastPauseDelay time.Duration        `yaml:"dynacast_pause_delay,omitempty"`
 	StreamTracker      StreamTrackersConfig `yaml:"stream_tracker,omitempty"`
+	// what to do when a subscriber cannot decode any of the codecs published for a track
+	CodecFallback CodecFallbackPolicy `yaml:"codec_fallback,omitempty"`
+	// lower layers of video published as a single layer, generated by transcoder workers
+	SimulcastGeneration SimulcastGenerationConfig `yaml:"simulcast_generation,omitempty"`
+}
+
+type SimulcastGenerationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// tracks narrower than this are forwarded as published only
+	MinWidth uint32 `yaml:"min_width,omitempty"`
 }
 
 type RoomConfig struct {
@@ -191,16 +447,134 @@ type RoomConfig struct {
 	EmptyTimeout       uint32      `yaml:"empty_timeout,omitempty"`
 	EnableRemoteUnmute bool        `yaml:"enable_remote_unmute,omitempty"`
 	MaxMetadataSize    uint32      `yaml:"max_metadata_size,omitempty"`
+	// room name patterns of voice rooms, video is neither negotiated nor forwarded in them
+	AudioOnlyRooms []string `yaml:"audio_only_rooms,omitempty"`
+	// room name patterns of rooms whose clients encrypt frames with insertable streams, payloads of their tracks are
+	// not inspected
+	OpaquePayloadRooms []string `yaml:"opaque_payload_rooms,omitempty"`
+	// rooms mirrored to a standby node which takes them over should the node hosting them fail
+	HighAvailability HighAvailabilityConfig `yaml:"high_availability,omitempty"`
+	// caps the bitrate forwarded to the subscribers of a room together
+	BitrateBudget BitrateBudgetConfig `yaml:"bitrate_budget,omitempty"`
+	// a video track following the loudest speaker, published by the server
+	ActiveSpeakerTrack ActiveSpeakerTrackConfig `yaml:"active_speaker_track,omitempty"`
+	// an audio track mixing the audio of all participants, published by the server
+	MixedAudioTrack MixedAudioTrackConfig `yaml:"mixed_audio_track,omitempty"`
+	// rooms whose media has to be end-to-end encrypted
+	E2EE E2EEConfig `yaml:"e2ee,omitempty"`
+	// constraints on the names and sources of the tracks a participant publishes
+	TrackPolicy TrackPolicyConfig `yaml:"track_policy,omitempty"`
+	// constraints on the ICE candidates of clients, the first policy matching a room applies
+	CandidatePolicies []CandidatePolicyConfig `yaml:"candidate_policies,omitempty"`
+}
+
+type HighAvailabilityConfig struct {
+	// room name patterns of high availability rooms
+	Rooms []string `yaml:"rooms,omitempty"`
+	// how often participants and their tracks and subscriptions are mirrored for the standby node
+	MirrorInterval time.Duration `yaml:"mirror_interval,omitempty"`
+}
+
+type BitrateBudgetConfig struct {
+	// room name patterns the budget applies to, all rooms when empty
+	Rooms []string `yaml:"rooms,omitempty"`
+	// bits per second the subscribers of a room share, 0 to disable
+	MaxBitrate int64 `yaml:"max_bitrate,omitempty"`
+	// how often the budget is divided among subscribers
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// shares of the budget of participants relative to each other, participants not matching any have priority 1
+	Priorities []ParticipantPriorityConfig `yaml:"priorities,omitempty"`
+}
+
+type ActiveSpeakerTrackConfig struct {
+	// room name patterns of rooms the track is published in
+	Rooms []string `yaml:"rooms,omitempty"`
+	// identity of the participant the track is published by
+	Identity string `yaml:"identity,omitempty"`
+	// how long another participant has to be the loudest speaker before the track switches to them
+	SwitchDelay time.Duration `yaml:"switch_delay,omitempty"`
+}
+
+type MixedAudioTrackConfig struct {
+	// room name patterns of rooms the track is published in
+	Rooms []string `yaml:"rooms,omitempty"`
+	// identity of the participant the track is published by
+	Identity string `yaml:"identity,omitempty"`
+	// participants subscribed to the track are forwarded a mix without their own audio
+	ExcludeOwnAudio bool `yaml:"exclude_own_audio,omitempty"`
+	// bitrate of the mix, in bps
+	Bitrate int `yaml:"bitrate,omitempty"`
+	// audio of each participant buffered to absorb jitter, rounded to 20 ms frames
+	JitterBuffer time.Duration `yaml:"jitter_buffer,omitempty"`
+}
+
+type E2EEConfig struct {
+	// room name patterns of rooms requiring end-to-end encryption
+	Rooms []string `yaml:"rooms,omitempty"`
+	// consecutive frames found not to be encrypted before a track is unpublished
+	MaxPlaintextFrames int `yaml:"max_plaintext_frames,omitempty"`
+}
+
+type TrackPolicyConfig struct {
+	// room name patterns the policy applies to, all rooms when empty
+	Rooms []string `yaml:"rooms,omitempty"`
+	// tracks published by a participant must have unique names
+	UniqueNames bool `yaml:"unique_names,omitempty"`
+	// a participant may publish one track per source, any number of tracks of unknown source
+	UniqueSources bool `yaml:"unique_sources,omitempty"`
+	// sources tracks may be published from, any when empty
+	AllowedSources []string `yaml:"allowed_sources,omitempty"`
+	// regular expression track names must match
+	NamePattern string `yaml:"name_pattern,omitempty"`
+}
+
+type CandidatePolicyConfig struct {
+	// room name patterns the policy applies to, all rooms when empty
+	Rooms []string `yaml:"rooms,omitempty"`
+	// media has to go through TURN, only relay candidates of clients are accepted
+	RelayOnly bool `yaml:"relay_only,omitempty"`
+	// host candidates of clients are not accepted, their local addresses are not connected to
+	NoHost bool `yaml:"no_host,omitempty"`
+	// peer reflexive candidates, discovered from connectivity checks rather than signalled, are not accepted
+	NoPrflx bool `yaml:"no_prflx,omitempty"`
+}
+
+type ParticipantPriorityConfig struct {
+	// participant identity patterns
+	Identities []string `yaml:"identities"`
+	Priority   uint32   `yaml:"priority"`
 }
 
 type CodecSpec struct {
 	Mime     string `yaml:"mime"`
 	FmtpLine string `yaml:"fmtp_line"`
+	// room name patterns the codec is enabled in, all rooms when empty. For codecs not every subscriber can decode
+	Rooms []string `yaml:"rooms,omitempty"`
 }
 
 type LoggingConfig struct {
 	logger.Config `yaml:",inline"`
 	PionLevel     string `yaml:"pion_level,omitempty"`
+	// repeated identical warnings within the interval are logged once with a count, 0 to disable
+	WarningThrottleInterval time.Duration `yaml:"warning_throttle_interval,omitempty"`
+	// replaces participant identities, names and metadata in logs and error reports
+	PIIRedaction PIIRedactionConfig `yaml:"pii_redaction,omitempty"`
+}
+
+type PIIRedactionConfig struct {
+	// hash replaces identities, names and metadata with pseudonyms, redact replaces identities with pseudonyms
+	// and removes names and metadata. values are left as they are when empty
+	Mode string `yaml:"mode,omitempty"`
+	// key pseudonyms are derived with, required when a mode is set. the pseudonym of an identity stays the same
+	// as long as the key does
+	HashKey string `yaml:"hash_key,omitempty"`
+	// also redact participants in webhook payloads
+	Webhooks bool `yaml:"webhooks,omitempty"`
+}
+
+// NewRedactor returns nil when redaction is disabled
+func (c PIIRedactionConfig) NewRedactor() (*utils.PIIRedactor, error) {
+	return utils.NewPIIRedactor(utils.PIIRedactionMode(c.Mode), c.HashKey)
 }
 
 type TURNConfig struct {
@@ -213,12 +587,50 @@ type TURNConfig struct {
 	RelayPortRangeStart uint16 `yaml:"relay_range_start,omitempty"`
 	RelayPortRangeEnd   uint16 `yaml:"relay_range_end,omitempty"`
 	ExternalTLS         bool   `yaml:"external_tls,omitempty"`
+	// obtains and renews the certificate of the domain from an ACME CA, instead of cert_file and key_file
+	TLSAuto TURNAutoTLSConfig `yaml:"tls_auto,omitempty"`
+	// how long TURN credentials issued to a participant are valid for. Credentials of the embedded server are
+	// revoked when the participant disconnects, those of servers with a secret are valid till they expire
+	CredentialTTL time.Duration `yaml:"credential_ttl,omitempty"`
+	// limits on the relay allocations and bandwidth of the embedded server
+	Quota TURNQuotaConfig `yaml:"quota,omitempty"`
+}
+
+// TURNQuotaConfig keeps a single client from exhausting the relay capacity of the embedded TURN server, limits are
+// off when 0
+type TURNQuotaConfig struct {
+	MaxAllocationsPerParticipant int `yaml:"max_allocations_per_participant,omitempty"`
+	MaxAllocationsPerRoom        int `yaml:"max_allocations_per_room,omitempty"`
+	// relayed to and from the clients, in bps
+	MaxBitratePerParticipant int64 `yaml:"max_bitrate_per_participant,omitempty"`
+	MaxBitratePerRoom        int64 `yaml:"max_bitrate_per_room,omitempty"`
+}
+
+type TURNAutoTLSConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// contact for the CA about problems with the certificate
+	Email string `yaml:"email,omitempty"`
+	// certificates and the account key are kept here across restarts, to stay within CA rate limits
+	CacheDir string `yaml:"cache_dir,omitempty"`
+	// ACME directory of the CA, defaults to Let's Encrypt
+	DirectoryURL string `yaml:"directory_url,omitempty"`
+	// answers HTTP-01 challenges on this port. when 0, the domain is validated with TLS-ALPN-01 on tls_port,
+	// which the CA only uses on port 443
+	HTTPPort int `yaml:"http_port,omitempty"`
+	// certificates are renewed this long before they expire, defaults to 30 days
+	RenewBefore time.Duration `yaml:"renew_before,omitempty"`
 }
 
 type WebHookConfig struct {
 	URLs []string `yaml:"urls"`
 	// key to use for webhook
 	APIKey string `yaml:"api_key"`
+	// events are held this long to be delivered in order per room, 0 to send right away
+	ReorderWindow time.Duration `yaml:"reorder_window,omitempty"`
+	// failed deliveries are retried for this long, later events are sent meanwhile
+	MaxRetryDuration time.Duration `yaml:"max_retry_duration,omitempty"`
+	// sends segment_started, segment_uploaded and segment_failed for the segments of segmented egress outputs
+	SegmentEvents bool `yaml:"segment_events,omitempty"`
 }
 
 type NodeSelectorConfig struct {
@@ -227,6 +639,23 @@ type NodeSelectorConfig struct {
 	CPULoadLimit float32        `yaml:"cpu_load_limit,omitempty"`
 	SysloadLimit float32        `yaml:"sysload_limit,omitempty"`
 	Regions      []RegionConfig `yaml:"regions,omitempty"`
+	// nodes whose ICE connections recently failed at a higher rate are avoided for new rooms, 0 to disable
+	MaxICEFailureRate float32 `yaml:"max_ice_failure_rate,omitempty"`
+	// ICE connection attempts a node needs to have seen before its failure rate is considered
+	MinICEAttempts int `yaml:"min_ice_attempts,omitempty"`
+}
+
+type MediaRelayConfig struct {
+	// secret shared by the nodes of the cluster, nodes relaying media authenticate each other with it and derive
+	// the keys of their links from it
+	PreSharedKey string `yaml:"pre_shared_key,omitempty"`
+	// how often the keys of links are rotated
+	KeyRotationInterval time.Duration `yaml:"key_rotation_interval,omitempty"`
+	// UDP port media of rooms spanning several nodes is relayed on, rooms are hosted by a single node when 0
+	Port uint32 `yaml:"port,omitempty"`
+	// clients a node takes before participants joining the rooms it hosts are placed on other nodes, only the
+	// limits of the node are checked when 0
+	MaxNodeClients uint32 `yaml:"max_node_clients,omitempty"`
 }
 
 type SignalRelayConfig struct {
@@ -251,6 +680,26 @@ type LimitConfig struct {
 	BytesPerSec            float32 `yaml:"bytes_per_sec,omitempty"`
 	SubscriptionLimitVideo int32   `yaml:"subscription_limit_video,omitempty"`
 	SubscriptionLimitAudio int32   `yaml:"subscription_limit_audio,omitempty"`
+	// sheds video progressively when the CPU of the node is overloaded, so that audio keeps flowing
+	CPUProtection CPUProtectionConfig `yaml:"cpu_protection,omitempty"`
+}
+
+// CPUProtectionConfig sets the CPU loads, between 0 and 1, from which each step of shedding is taken. A step whose
+// load is 0 is not taken, protection is disabled when all are 0
+type CPUProtectionConfig struct {
+	// subscribers are forwarded video at medium quality at most
+	ReduceLayersLoad float32 `yaml:"reduce_layers_load,omitempty"`
+	// the video of participants who have not spoken recently is paused, lowest priority first, one more participant
+	// per interval while the load stays at or above it
+	PauseVideoLoad float32 `yaml:"pause_video_load,omitempty"`
+	// new video tracks are refused
+	RefuseVideoLoad float32 `yaml:"refuse_video_load,omitempty"`
+	// how far below its load the CPU load has to fall for a step to be undone
+	Hysteresis float32 `yaml:"hysteresis,omitempty"`
+	// how often the CPU load is sampled
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// participants not matching any have priority 1
+	Priorities []ParticipantPriorityConfig `yaml:"priorities,omitempty"`
 }
 
 type EgressConfig struct {
@@ -262,6 +711,184 @@ type IngressConfig struct {
 	WHIPBaseURL string `yaml:"whip_base_url"`
 }
 
+type TranscoderConfig struct {
+	// port transcoder workers connect to over gRPC, workers are not accepted when 0
+	Port uint32 `yaml:"port,omitempty"`
+}
+
+type RTMPConfig struct {
+	// TCP port of the RTMP ingest listener, disabled when 0
+	Port uint32 `yaml:"port,omitempty"`
+	// send ingest_health_changed webhooks when the warnings of a stream change
+	HealthWebhooks bool `yaml:"health_webhooks,omitempty"`
+}
+
+type WebTransportConfig struct {
+	// UDP port of the QUIC listener for WebTransport signaling, disabled when 0
+	Port uint32 `yaml:"port,omitempty"`
+	// certificate served on the QUIC listener, required with port
+	TLS ListenerTLSConfig `yaml:"tls,omitempty"`
+}
+
+type PlaybackConfig struct {
+	// largest media file that can be played into a room, in bytes. defaults to 256MB
+	MaxFileSize int64 `yaml:"max_file_size,omitempty"`
+	// allow fetching media from loopback, private and link-local addresses, e.g. object storage inside the network.
+	// refused by default as URLs are given by API callers
+	AllowPrivateURLs bool `yaml:"allow_private_urls,omitempty"`
+}
+
+type DashboardConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// API keys that may log into the dashboard, all keys may when empty
+	APIKeys []string `yaml:"api_keys,omitempty"`
+	// how long a dashboard login lasts, defaults to 8h
+	SessionTTL time.Duration `yaml:"session_ttl,omitempty"`
+}
+
+type ErrorReportingConfig struct {
+	// Sentry compatible DSN, e.g. https://<key>@sentry.example.com/<project>. reporting is disabled when empty
+	DSN string `yaml:"dsn,omitempty"`
+	// handling of panics in media goroutines, defaults to recover
+	PanicPolicy PanicPolicy `yaml:"panic_policy,omitempty"`
+}
+
+type ProfilingConfig struct {
+	// serves /debug/pprof and /debug/runtime on the API port, requests need a token signed with one of admin_api_keys
+	Enabled bool `yaml:"enabled,omitempty"`
+	// profiles captured through the profiling API are uploaded here, they are returned in the response otherwise
+	Storage ObjectStorageConfig `yaml:"storage,omitempty"`
+}
+
+type ContinuousProfilingConfig struct {
+	// Pyroscope compatible server, e.g. http://pyroscope:4040. disabled when empty
+	ServerAddress string `yaml:"server_address,omitempty"`
+	// defaults to livekit-server
+	ApplicationName string `yaml:"application_name,omitempty"`
+	// sent as a bearer token
+	AuthToken string `yaml:"auth_token,omitempty"`
+	// length of each profile, defaults to 10s
+	UploadInterval time.Duration `yaml:"upload_interval,omitempty"`
+	// added to the labels of every profile, node_id and env are always set
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+type TracingConfig struct {
+	// OTLP gRPC collector, e.g. otel-collector:4317. disabled when empty
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// connects without TLS
+	Insecure bool `yaml:"insecure,omitempty"`
+	// sent with every export, e.g. for authentication
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// fraction of joins traced, defaults to 1. joins with a sampled traceparent header are always traced
+	SampleRatio float32 `yaml:"sample_ratio,omitempty"`
+	// defaults to livekit-server
+	ServiceName string `yaml:"service_name,omitempty"`
+}
+
+// ObjectStorageConfig is an S3 compatible bucket
+type ObjectStorageConfig struct {
+	// defaults to https://s3.<region>.amazonaws.com
+	Endpoint  string `yaml:"endpoint,omitempty"`
+	Region    string `yaml:"region,omitempty"`
+	Bucket    string `yaml:"bucket,omitempty"`
+	AccessKey string `yaml:"access_key,omitempty"`
+	Secret    string `yaml:"secret,omitempty"`
+	// prepended to object keys
+	Prefix string `yaml:"prefix,omitempty"`
+	// bucket in the path instead of the host name, for MinIO and similar
+	ForcePathStyle bool `yaml:"force_path_style,omitempty"`
+}
+
+type CORSConfig struct {
+	// origins browsers may connect from, e.g. https://app.example.com or https://*.example.com.
+	// any origin is allowed when empty
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	// further restricts the origins tokens issued with an API key may be used from
+	APIKeyOrigins map[string][]string `yaml:"api_key_origins,omitempty"`
+}
+
+// ListenerConfig serves the API on an additional address, replacing port and bind_addresses when set
+type ListenerConfig struct {
+	// host:port to listen on, e.g. 10.0.0.1:7880 or [::]:443
+	Address string            `yaml:"address"`
+	TLS     ListenerTLSConfig `yaml:"tls,omitempty"`
+	// API keys accepted on this listener, all keys are accepted when empty
+	APIKeys []string `yaml:"api_keys,omitempty"`
+}
+
+type ListenerTLSConfig struct {
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// when set, clients must present a certificate signed by one of these CAs
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+}
+
+type AutoscalingConfig struct {
+	// fraction of node capacity autoscalers should aim for, between 0 and 1. defaults to 0.7
+	TargetUtilization float32 `yaml:"target_utilization,omitempty"`
+	// participants a node is sized for, used to estimate participant headroom. 0 derives it from the current load
+	MaxParticipants int32 `yaml:"max_participants,omitempty"`
+}
+
+type TimelineConfig struct {
+	// persist room events so that they can be queried with GetRoomTimeline
+	Enabled bool `yaml:"enabled,omitempty"`
+	// events of a room are kept for this long after the last one was recorded. defaults to 7 days
+	Retention time.Duration `yaml:"retention,omitempty"`
+	// most recent events kept for each room. defaults to 10000
+	MaxEvents int `yaml:"max_events,omitempty"`
+}
+
+type PresenceConfig struct {
+	// serve participant, publisher and watch counts and room counters at /presence/ without joining rooms
+	Enabled bool `yaml:"enabled,omitempty"`
+	// watch counts reported by external services expire when not reported again within this. defaults to 30s
+	WatcherTTL time.Duration `yaml:"watcher_ttl,omitempty"`
+	// counters of a room are kept for this long after their last change. defaults to 24h
+	CounterRetention time.Duration `yaml:"counter_retention,omitempty"`
+	// the node hosting a room checks its presence for changes at this interval, room_presence_changed webhooks are
+	// sent at most this often. defaults to 2s
+	CheckInterval time.Duration `yaml:"check_interval,omitempty"`
+	// send room_presence_changed webhooks
+	Webhooks bool `yaml:"webhooks,omitempty"`
+}
+
+type RoomManifestConfig struct {
+	// when a room closes, list its recordings, timeline and usage in a room_manifest webhook and GetRoomManifest
+	Enabled bool `yaml:"enabled,omitempty"`
+	// egress of the room still running when it closes is waited for this long. defaults to 2 minutes
+	EgressWait time.Duration `yaml:"egress_wait,omitempty"`
+	// manifests are kept for this long. defaults to 7 days
+	Retention time.Duration `yaml:"retention,omitempty"`
+}
+
+// RetentionConfig deletes data of closed rooms once it is older than their policy allows. rooms are found through
+// their manifests, so room_manifest must be enabled with a retention longer than any policy
+type RetentionConfig struct {
+	// the first policy matching a room applies, rooms matching no policy are left alone
+	Policies []RetentionPolicy `yaml:"policies,omitempty"`
+	// how often expired rooms are looked for. defaults to 1h
+	CheckInterval time.Duration `yaml:"check_interval,omitempty"`
+	// log and report what would be deleted without deleting anything
+	DryRun bool `yaml:"dry_run,omitempty"`
+	// bucket recordings are stored in, also used to delete recordings of erased participants. artifacts stored
+	// elsewhere are not deleted
+	Storage ObjectStorageConfig `yaml:"storage,omitempty"`
+}
+
+type RetentionPolicy struct {
+	Name string `yaml:"name"`
+	// rooms created with this API key, any key when empty
+	APIKey string `yaml:"api_key,omitempty"`
+	// rooms whose name starts with this prefix, any room when empty
+	RoomPrefix string `yaml:"room_prefix,omitempty"`
+	// days after the room closed that its data is deleted
+	Days int `yaml:"days"`
+	// also delete recordings and segments in the storage bucket
+	DeleteArtifacts bool `yaml:"delete_artifacts,omitempty"`
+}
+
 // not exposed to YAML
 type APIConfig struct {
 	// amount of time to wait for API to execute, default 2s
@@ -283,14 +910,16 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 	conf := &Config{
 		Port: 7880,
 		RTC: RTCConfig{
-			UseExternalIP:     false,
-			TCPPort:           7881,
-			UDPPort:           0,
-			ICEPortRangeStart: 0,
-			ICEPortRangeEnd:   0,
-			STUNServers:       []string{},
-			PacketBufferSize:  500,
-			StrictACKs:        true,
+			UseExternalIP:               false,
+			TCPPort:                     7881,
+			UDPPort:                     0,
+			ICEPortRangeStart:           0,
+			ICEPortRangeEnd:             0,
+			STUNServers:                 []string{},
+			PacketBufferSize:            500,
+			PacketBufferSizeAudio:       200,
+			PacketBufferSizeScreenShare: 500,
+			StrictACKs:                  true,
 			PLIThrottle: PLIThrottleConfig{
 				LowQuality:  500 * time.Millisecond,
 				MidQuality:  time.Second,
@@ -301,6 +930,34 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 				AllowPause: false,
 				ProbeMode:  CongestionControlProbeModePadding,
 			},
+			AudioNACK: AudioNACKConfig{
+				Enabled:     false,
+				HistorySize: 100,
+				MaxLatency:  300 * time.Millisecond,
+			},
+			FlexFEC: FlexFECConfig{
+				Enabled:     false,
+				MinLoss:     0.02,
+				MaxOverhead: 0.3,
+			},
+			DetailedStats: DetailedStatsConfig{
+				Enabled:  false,
+				Interval: 30 * time.Second,
+			},
+			Pacer: PacerConfig{
+				Enabled:       false,
+				Multiplier:    1.5,
+				MaxBurst:      40 * time.Millisecond,
+				MaxQueueDelay: 250 * time.Millisecond,
+			},
+			TWCC: TWCCConfig{
+				FeedbackInterval: 100 * time.Millisecond,
+				FeedbackFormat:   "transport-cc",
+			},
+			TCPActive: TCPActiveConfig{
+				DialTimeout: 5 * time.Second,
+				DialRate:    20,
+			},
 		},
 		Audio: AudioConfig{
 			ActiveLevel:     35, // -35dBov
@@ -384,6 +1041,11 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 					},
 				},
 			},
+			CodecFallback: CodecFallbackPolicyRequestBackup,
+			SimulcastGeneration: SimulcastGenerationConfig{
+				Enabled:  false,
+				MinWidth: 640,
+			},
 		},
 		Redis: redisLiveKit.RedisConfig{},
 		Room: RoomConfig{
@@ -395,20 +1057,57 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 				{Mime: webrtc.MimeTypeH264},
 				// {Mime: webrtc.MimeTypeAV1},
 				// {Mime: webrtc.MimeTypeVP9},
+				// {Mime: webrtc.MimeTypeH265},
 			},
 			EmptyTimeout: 5 * 60,
+			HighAvailability: HighAvailabilityConfig{
+				MirrorInterval: 2 * time.Second,
+			},
+			BitrateBudget: BitrateBudgetConfig{
+				Interval: 2 * time.Second,
+			},
+			ActiveSpeakerTrack: ActiveSpeakerTrackConfig{
+				Identity:    "active-speaker",
+				SwitchDelay: time.Second,
+			},
+			MixedAudioTrack: MixedAudioTrackConfig{
+				Identity:        "audio-mix",
+				ExcludeOwnAudio: true,
+				Bitrate:         32000,
+				JitterBuffer:    60 * time.Millisecond,
+			},
+			E2EE: E2EEConfig{
+				MaxPlaintextFrames: 5,
+			},
 		},
 		Logging: LoggingConfig{
-			PionLevel: "error",
+			PionLevel:               "error",
+			WarningThrottleInterval: 10 * time.Second,
 		},
 		TURN: TURNConfig{
-			Enabled: false,
+			Enabled:       false,
+			CredentialTTL: 24 * time.Hour,
+		},
+		WebHook: WebHookConfig{
+			ReorderWindow:    500 * time.Millisecond,
+			MaxRetryDuration: 5 * time.Minute,
 		},
 		NodeSelector: NodeSelectorConfig{
-			Kind:         "any",
-			SortBy:       "random",
-			SysloadLimit: 0.9,
-			CPULoadLimit: 0.9,
+			Kind:              "any",
+			SortBy:            "random",
+			SysloadLimit:      0.9,
+			CPULoadLimit:      0.9,
+			MaxICEFailureRate: 0.5,
+			MinICEAttempts:    20,
+		},
+		MediaRelay: MediaRelayConfig{
+			KeyRotationInterval: 10 * time.Minute,
+		},
+		GeoIP: GeoIPConfig{
+			ReloadInterval: time.Minute,
+			URL:            "https://pro.ip-api.com",
+			CacheTTL:       time.Hour,
+			CacheSize:      10000,
 		},
 		SignalRelay: SignalRelayConfig{
 			Enabled:          false,
@@ -420,6 +1119,20 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 		Keys: map[string]string{},
 	}
 
+	var profileFlag string
+	if c != nil {
+		profileFlag = c.String("profile")
+	}
+	profile, err := profileName(confString, profileFlag)
+	if err != nil {
+		return nil, err
+	}
+	if profile != "" {
+		if err = conf.applyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
 	if confString != "" {
 		decoder := yaml.NewDecoder(strings.NewReader(confString))
 		decoder.KnownFields(strictMode)
@@ -433,6 +1146,7 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 			return nil, err
 		}
 	}
+	conf.Profile = profile
 
 	// expand env vars in filenames
 	file, err := homedir.Expand(os.ExpandEnv(conf.KeyFile))
@@ -483,6 +1197,10 @@ func NewConfig(confString string, strictMode bool, c *cli.Context, baseFlags []c
 		conf.Environment = "dev"
 	}
 
+	if _, err = conf.Logging.PIIRedaction.NewRedactor(); err != nil {
+		return nil, err
+	}
+
 	return conf, nil
 }
 
@@ -747,6 +1465,17 @@ func (conf *Config) unmarshalKeys(keys string) error {
 	return nil
 }
 
+// the logger set up by InitLoggerFromConfig
+var leveledLogger atomic.Value // *utils.LeveledLogger
+
+// SetLogLevel changes the levels of the loggers set up by InitLoggerFromConfig
+func SetLogLevel(config LoggingConfig) {
+	pionlogger.SetLogLevel(config.PionLevel)
+	if l, ok := leveledLogger.Load().(*utils.LeveledLogger); ok {
+		l.SetLevel(config.Level)
+	}
+}
+
 // Note: only pass in logr.Logger with default depth
 func SetLogger(l logger.Logger) {
 	logger.SetLogger(l, "livekit")
@@ -754,5 +1483,24 @@ func SetLogger(l logger.Logger) {
 
 func InitLoggerFromConfig(config LoggingConfig) {
 	pionlogger.SetLogLevel(config.PionLevel)
-	logger.InitFromConfig(config.Config, "livekit")
+	// filtered by the leveled logger, so that the level can be reloaded
+	zapConfig := config.Config
+	zapConfig.Level = "debug"
+	zl, err := logger.NewZapLogger(&zapConfig)
+	if err != nil {
+		return
+	}
+	leveled := utils.NewLeveledLogger(zl, config.Level)
+	leveledLogger.Store(leveled)
+	var l logger.Logger = leveled
+	if config.WarningThrottleInterval > 0 {
+		l = utils.NewThrottledLogger(l, config.WarningThrottleInterval)
+	}
+	// the mode is validated with the config
+	redactor, _ := config.PIIRedaction.NewRedactor()
+	utils.SetPIIRedactor(redactor)
+	if redactor != nil {
+		l = utils.NewRedactingLogger(l, redactor)
+	}
+	SetLogger(l)
 }
@@ -66,3 +66,53 @@ func TestGeneratedFlags(t *testing.T) {
 	require.NotNil(t, conf.RTC.ReconnectOnSubscriptionError)
 	require.False(t, *conf.RTC.ReconnectOnSubscriptionError)
 }
+
+func TestConfig_Profile(t *testing.T) {
+	t.Run("applies defaults", func(t *testing.T) {
+		// node_ip is set to skip resolving the external IP
+		const content = `profile: turn-only-edge
+rtc:
+  node_ip: 10.0.0.1`
+		conf, err := NewConfig(content, true, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, ProfileTURNOnlyEdge, conf.Profile)
+		require.True(t, conf.TURN.Enabled)
+		require.True(t, conf.RTC.UseExternalIP)
+		require.Equal(t, 300, conf.RTC.PacketBufferSize)
+		// untouched defaults are kept
+		require.True(t, conf.Room.AutoCreate)
+	})
+
+	t.Run("config overrides profile", func(t *testing.T) {
+		const content = `profile: kubernetes-cluster
+rtc:
+  port_range_start: 40000
+  use_external_ip: false
+  node_ip: 10.0.0.1`
+		conf, err := NewConfig(content, true, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint32(40000), conf.RTC.ICEPortRangeStart)
+		require.Equal(t, uint32(60000), conf.RTC.ICEPortRangeEnd)
+		require.False(t, conf.RTC.UseExternalIP)
+		require.Equal(t, "sysload", conf.NodeSelector.Kind)
+	})
+
+	t.Run("flag selects profile", func(t *testing.T) {
+		app := cli.NewApp()
+		app.Flags = []cli.Flag{&cli.StringFlag{Name: "profile"}}
+		set := flag.NewFlagSet("test", 0)
+		set.String("profile", ProfileDev, "")
+		c := cli.NewContext(app, set, nil)
+
+		conf, err := NewConfig("profile: kubernetes-cluster", true, c, app.Flags)
+		require.NoError(t, err)
+		require.Equal(t, ProfileDev, conf.Profile)
+		require.True(t, conf.Development)
+		require.Equal(t, uint32(7882), conf.RTC.UDPPort)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, err := NewConfig("profile: unknown", true, nil, nil)
+		require.Error(t, err)
+	})
+}
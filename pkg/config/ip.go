@@ -13,6 +13,11 @@ import (
 )
 
 func (conf *Config) determineIP() (string, error) {
+	getExternalIP, getLocalIPAddresses := GetExternalIP, GetLocalIPAddresses
+	if conf.RTC.IPv6Only {
+		getExternalIP, getLocalIPAddresses = GetExternalIPv6, GetLocalIPv6Addresses
+	}
+
 	if conf.RTC.UseExternalIP {
 		stunServers := conf.RTC.STUNServers
 		if len(stunServers) == 0 {
@@ -21,7 +26,7 @@ func (conf *Config) determineIP() (string, error) {
 		var err error
 		for i := 0; i < 3; i++ {
 			var ip string
-			ip, err = GetExternalIP(context.Background(), stunServers, nil)
+			ip, err = getExternalIP(context.Background(), stunServers, nil)
 			if err == nil {
 				return ip, nil
 			} else {
@@ -32,7 +37,7 @@ func (conf *Config) determineIP() (string, error) {
 	}
 
 	// use local ip instead
-	addresses, err := GetLocalIPAddresses(false)
+	addresses, err := getLocalIPAddresses(false)
 	if len(addresses) > 0 {
 		return addresses[0], err
 	}
@@ -40,6 +45,15 @@ func (conf *Config) determineIP() (string, error) {
 }
 
 func GetLocalIPAddresses(includeLoopback bool) ([]string, error) {
+	return getLocalIPAddresses(includeLoopback, false)
+}
+
+// GetLocalIPv6Addresses returns the global IPv6 addresses of the node, link local ones cannot be reached by clients
+func GetLocalIPv6Addresses(includeLoopback bool) ([]string, error) {
+	return getLocalIPAddresses(includeLoopback, true)
+}
+
+func getLocalIPAddresses(includeLoopback bool, ipv6 bool) ([]string, error) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return nil, err
@@ -55,12 +69,19 @@ func GetLocalIPAddresses(includeLoopback bool) ([]string, error) {
 			var ip net.IP
 			switch typedAddr := addr.(type) {
 			case *net.IPNet:
-				ip = typedAddr.IP.To4()
+				ip = typedAddr.IP
 			case *net.IPAddr:
-				ip = typedAddr.IP.To4()
+				ip = typedAddr.IP
 			default:
 				continue
 			}
+			if ipv6 {
+				if ip.To4() != nil || ip.IsLinkLocalUnicast() {
+					continue
+				}
+			} else {
+				ip = ip.To4()
+			}
 			if ip == nil {
 				continue
 			}
@@ -88,13 +109,22 @@ func GetLocalIPAddresses(includeLoopback bool) ([]string, error) {
 // GetExternalIP return external IP for localAddr from stun server. If localAddr is nil, a local address is chosen automatically,
 // else the address will be used to validate the external IP is accessible from the outside.
 func GetExternalIP(ctx context.Context, stunServers []string, localAddr net.Addr) (string, error) {
+	return getExternalIP(ctx, "udp4", stunServers, localAddr)
+}
+
+// GetExternalIPv6 is GetExternalIP for the IPv6 address of the node, asking STUN servers over IPv6
+func GetExternalIPv6(ctx context.Context, stunServers []string, localAddr net.Addr) (string, error) {
+	return getExternalIP(ctx, "udp6", stunServers, localAddr)
+}
+
+func getExternalIP(ctx context.Context, network string, stunServers []string, localAddr net.Addr) (string, error) {
 	if len(stunServers) == 0 {
 		return "", errors.New("STUN servers are required but not defined")
 	}
 	dialer := &net.Dialer{
 		LocalAddr: localAddr,
 	}
-	conn, err := dialer.Dial("udp4", stunServers[0])
+	conn, err := dialer.Dial(network, stunServers[0])
 	if err != nil {
 		return "", err
 	}
@@ -123,8 +153,11 @@ func GetExternalIP(ctx context.Context, stunServers []string, localAddr net.Addr
 			stunErr = err
 			return
 		}
-		ip := xorAddr.IP.To4()
-		if ip != nil {
+		if network == "udp6" {
+			if xorAddr.IP.To4() == nil {
+				ipChan <- xorAddr.IP.String()
+			}
+		} else if ip := xorAddr.IP.To4(); ip != nil {
 			ipChan <- ip.String()
 		}
 	})
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ProfileDev               = "dev"
+	ProfileSingleNodePublic  = "single-node-public"
+	ProfileKubernetesCluster = "kubernetes-cluster"
+	ProfileTURNOnlyEdge      = "turn-only-edge"
+)
+
+// profiles are applied on top of the defaults, config files and flags override them
+var profiles = map[string]string{
+	// local development, single UDP port and a logger that is easy to read
+	ProfileDev: `
+development: true
+rtc:
+  tcp_port: 7881
+  udp_port: 7882
+  use_external_ip: false
+  packet_buffer_size: 200
+  packet_buffer_size_screenshare: 500
+logging:
+  level: debug
+`,
+	// a single server with a public IP, ICE over a port range with TURN/UDP as fallback for restrictive networks
+	ProfileSingleNodePublic: `
+rtc:
+  tcp_port: 7881
+  port_range_start: 50000
+  port_range_end: 60000
+  use_external_ip: true
+turn:
+  enabled: true
+  udp_port: 3478
+  relay_range_start: 30000
+  relay_range_end: 40000
+`,
+	// nodes running with host networking behind a load balancer, rooms spread by system load
+	ProfileKubernetesCluster: `
+rtc:
+  tcp_port: 7881
+  port_range_start: 50000
+  port_range_end: 60000
+  use_external_ip: true
+  packet_buffer_size: 500
+  packet_buffer_size_screenshare: 500
+node_selector:
+  kind: sysload
+  sort_by: sysload
+`,
+	// edge nodes relaying media over TURN, with TLS terminated by a load balancer and small buffers
+	ProfileTURNOnlyEdge: `
+rtc:
+  tcp_port: 7881
+  udp_port: 7882
+  use_external_ip: true
+  packet_buffer_size: 300
+  packet_buffer_size_audio: 100
+  packet_buffer_size_screenshare: 500
+turn:
+  enabled: true
+  tls_port: 5349
+  udp_port: 3478
+  external_tls: true
+  relay_range_start: 30000
+  relay_range_end: 40000
+`,
+}
+
+// ProfileNames lists the built-in profiles
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (conf *Config) applyProfile(name string) error {
+	profile, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %s, available profiles: %s", name, strings.Join(ProfileNames(), ", "))
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(profile))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(conf); err != nil {
+		return fmt.Errorf("could not apply profile %s: %v", name, err)
+	}
+	conf.Profile = name
+	return nil
+}
+
+// profileName returns the profile selected in the config, flags take precedence
+func profileName(confString string, flagValue string) (string, error) {
+	if flagValue != "" || confString == "" {
+		return flagValue, nil
+	}
+
+	selected := struct {
+		Profile string `yaml:"profile"`
+	}{}
+	if err := yaml.Unmarshal([]byte(confString), &selected); err != nil {
+		return "", fmt.Errorf("could not parse config: %v", err)
+	}
+	return selected.Profile, nil
+}
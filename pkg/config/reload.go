@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"go.uber.org/atomic"
+)
+
+var ErrConfigReloadUnavailable = errors.New("configuration is not loaded from a source it can be reloaded from")
+
+// the settings that are applied without restarting, other changes are ignored till the next restart
+var reloadableSettings = []struct {
+	name  string
+	field func(conf *Config) interface{}
+}{
+	{"logging.level", func(conf *Config) interface{} { return &conf.Logging.Level }},
+	{"logging.pion_level", func(conf *Config) interface{} { return &conf.Logging.PionLevel }},
+	{"room.empty_timeout", func(conf *Config) interface{} { return &conf.Room.EmptyTimeout }},
+	{"room.max_participants", func(conf *Config) interface{} { return &conf.Room.MaxParticipants }},
+	{"room.enabled_codecs", func(conf *Config) interface{} { return &conf.Room.EnabledCodecs }},
+	{"limit", func(conf *Config) interface{} { return &conf.Limit }},
+	{"webhook.urls", func(conf *Config) interface{} { return &conf.WebHook.URLs }},
+	{"rtc.turn_servers", func(conf *Config) interface{} { return &conf.RTC.TURNServers }},
+	{"turn.credential_ttl", func(conf *Config) interface{} { return &conf.TURN.CredentialTTL }},
+}
+
+// ReloadResult lists what a reload changed
+type ReloadResult struct {
+	Changed []string `json:"changed"`
+	// settings other than the reloadable ones changed, they apply after a restart
+	RestartRequired bool `json:"restart_required"`
+}
+
+// Current is the configuration in effect. Components read the settings that can be reloaded through it on use,
+// rather than keeping the values they were started with. A reload swaps in a new snapshot, snapshots are never
+// modified
+type Current struct {
+	conf atomic.Value // *Config
+
+	lock     sync.Mutex
+	load     func() (*Config, error)
+	onReload []func(conf *Config)
+}
+
+func NewCurrent(conf *Config) *Current {
+	c := &Current{}
+	c.conf.Store(conf)
+	return c
+}
+
+func (c *Current) Get() *Config {
+	return c.conf.Load().(*Config)
+}
+
+// SetLoader sets where the configuration is reloaded from, e.g. the config file
+func (c *Current) SetLoader(load func() (*Config, error)) {
+	c.lock.Lock()
+	c.load = load
+	c.lock.Unlock()
+}
+
+// OnReload is called with the new snapshot after a reload changed any setting, for components that have to apply
+// settings rather than read them
+func (c *Current) OnReload(f func(conf *Config)) {
+	c.lock.Lock()
+	c.onReload = append(c.onReload, f)
+	c.lock.Unlock()
+}
+
+// Reload loads the configuration again and applies it
+func (c *Current) Reload() (*ReloadResult, error) {
+	c.lock.Lock()
+	load := c.load
+	c.lock.Unlock()
+	if load == nil {
+		return nil, ErrConfigReloadUnavailable
+	}
+
+	conf, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return c.Apply(conf), nil
+}
+
+// Apply swaps in a snapshot with the reloadable settings of conf
+func (c *Current) Apply(conf *Config) *ReloadResult {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	prev := c.Get()
+	next := *prev
+	res := &ReloadResult{}
+	for _, s := range reloadableSettings {
+		dst := reflect.ValueOf(s.field(&next)).Elem()
+		src := reflect.ValueOf(s.field(conf)).Elem()
+		if !reflect.DeepEqual(dst.Interface(), src.Interface()) {
+			dst.Set(src)
+			res.Changed = append(res.Changed, s.name)
+		}
+	}
+
+	// what remains differing needs a restart
+	fixed := *conf
+	for _, s := range reloadableSettings {
+		reflect.ValueOf(s.field(&fixed)).Elem().Set(reflect.ValueOf(s.field(&next)).Elem())
+	}
+	res.RestartRequired = !reflect.DeepEqual(&fixed, &next)
+
+	if len(res.Changed) == 0 {
+		return res
+	}
+	c.conf.Store(&next)
+	for _, f := range c.onReload {
+		f(&next)
+	}
+	return res
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrentReload(t *testing.T) {
+	load := func(content string) *Config {
+		conf, err := NewConfig(content, true, nil, nil)
+		require.NoError(t, err)
+		return conf
+	}
+	initial := load(`room:
+  empty_timeout: 10
+webhook:
+  api_key: key
+  urls: ["https://a"]`)
+	c := NewCurrent(initial)
+
+	_, err := c.Reload()
+	require.ErrorIs(t, err, ErrConfigReloadUnavailable)
+
+	var reloaded *Config
+	c.OnReload(func(conf *Config) {
+		reloaded = conf
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		res := c.Apply(load(`room:
+  empty_timeout: 10
+webhook:
+  api_key: key
+  urls: ["https://a"]`))
+		require.Empty(t, res.Changed)
+		require.False(t, res.RestartRequired)
+		require.Nil(t, reloaded)
+		require.Same(t, initial, c.Get())
+	})
+
+	t.Run("reloadable settings", func(t *testing.T) {
+		c.SetLoader(func() (*Config, error) {
+			return load(`room:
+  empty_timeout: 20
+limit:
+  num_tracks: 100
+webhook:
+  api_key: key
+  urls: ["https://a", "https://b"]`), nil
+		})
+		res, err := c.Reload()
+		require.NoError(t, err)
+		require.Equal(t, []string{"room.empty_timeout", "limit", "webhook.urls"}, res.Changed)
+		require.False(t, res.RestartRequired)
+
+		require.Same(t, reloaded, c.Get())
+		require.EqualValues(t, 20, c.Get().Room.EmptyTimeout)
+		require.EqualValues(t, 100, c.Get().Limit.NumTracks)
+		require.Equal(t, []string{"https://a", "https://b"}, c.Get().WebHook.URLs)
+		// snapshots are not modified
+		require.EqualValues(t, 10, initial.Room.EmptyTimeout)
+	})
+
+	t.Run("other settings need a restart", func(t *testing.T) {
+		res := c.Apply(load(`port: 7990
+room:
+  empty_timeout: 30
+limit:
+  num_tracks: 100
+webhook:
+  api_key: key
+  urls: ["https://a", "https://b"]`))
+		require.Equal(t, []string{"room.empty_timeout"}, res.Changed)
+		require.True(t, res.RestartRequired)
+		require.EqualValues(t, 30, c.Get().Room.EmptyTimeout)
+		require.EqualValues(t, 7880, c.Get().Port)
+	})
+}
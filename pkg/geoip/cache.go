@@ -0,0 +1,48 @@
+package geoip
+
+import (
+	"context"
+	"net"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+type cachedLocation struct {
+	location  *Location
+	expiresAt time.Time
+}
+
+// CachingProvider caches the lookups of a provider, addresses not located included so that they are not looked up
+// again on every connection. Failed lookups are not cached
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+	cache    *lru.Cache[string, cachedLocation]
+}
+
+func NewCachingProvider(provider Provider, size int, ttl time.Duration) (*CachingProvider, error) {
+	cache, err := lru.New[string, cachedLocation](size)
+	if err != nil {
+		return nil, err
+	}
+	return &CachingProvider{
+		provider: provider,
+		ttl:      ttl,
+		cache:    cache,
+	}, nil
+}
+
+func (c *CachingProvider) Lookup(ctx context.Context, ip net.IP) (*Location, error) {
+	key := ip.String()
+	if cached, ok := c.cache.Get(key); ok && time.Now().Before(cached.expiresAt) {
+		return cached.location, nil
+	}
+
+	loc, err := c.provider.Lookup(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Add(key, cachedLocation{location: loc, expiresAt: time.Now().Add(c.ttl)})
+	return loc, nil
+}
@@ -0,0 +1,117 @@
+package geoip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	ProviderMaxMind = "maxmind"
+	ProviderIPAPI   = "ip-api"
+)
+
+var (
+	ErrUnknownProvider = errors.New("geoip: unknown provider")
+	ErrInvalidAddress  = errors.New("geoip: invalid address")
+	ErrInvalidDatabase = errors.New("geoip: invalid database")
+)
+
+// Location is where an address is, zero values are unknown
+type Location struct {
+	// ISO 3166-1 country code
+	CountryCode string
+	// ISO 3166-2 subdivision code, without the country code, or the name of the subdivision
+	Region    string
+	City      string
+	Latitude  float64
+	Longitude float64
+	// latitude and longitude are known
+	HasCoordinates bool
+}
+
+// Provider locates addresses. A nil location without an error means the address is not known to the provider,
+// e. g. a private address
+type Provider interface {
+	Lookup(ctx context.Context, ip net.IP) (*Location, error)
+}
+
+type ProviderFactory func(conf config.GeoIPConfig) (Provider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider makes a provider, e. g. one querying an in-house service, available to the geoip config by name
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	providers[name] = factory
+}
+
+// NewProvider creates the configured provider with its lookups cached, nil when none is configured
+func NewProvider(conf config.GeoIPConfig) (Provider, error) {
+	var p Provider
+	var err error
+	switch conf.Provider {
+	case "":
+		return nil, nil
+	case ProviderMaxMind:
+		p, err = NewMaxMindProvider(conf.DBPath, conf.ReloadInterval)
+	case ProviderIPAPI:
+		p, err = NewIPAPIProvider(conf.URL, conf.APIKey)
+	default:
+		providersMu.RLock()
+		factory := providers[conf.Provider]
+		providersMu.RUnlock()
+		if factory == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, conf.Provider)
+		}
+		p, err = factory(conf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.CacheTTL > 0 && conf.CacheSize > 0 {
+		return NewCachingProvider(p, conf.CacheSize, conf.CacheTTL)
+	}
+	return p, nil
+}
+
+// LookupAddress locates an address given as a string, nil when the provider is nil or the address cannot be located
+func LookupAddress(ctx context.Context, p Provider, address string) (*Location, error) {
+	if p == nil || address == "" {
+		return nil, nil
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return nil, nil
+	}
+	return p.Lookup(ctx, ip)
+}
+
+type locationKey struct{}
+
+// NewContext returns a context carrying the location of the client a request is made for
+func NewContext(ctx context.Context, loc *Location) context.Context {
+	if loc == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// FromContext returns the location of the client a request is made for, nil when not known
+func FromContext(ctx context.Context) *Location {
+	loc, _ := ctx.Value(locationKey{}).(*Location)
+	return loc
+}
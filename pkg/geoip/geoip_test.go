@@ -0,0 +1,338 @@
+package geoip
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// ---------------------------------------------------------------------------
+// a minimal MaxMind DB writer, enough for the provider to be tested against
+
+var mmdbMetadataStart = []byte("\xAB\xCD\xEFMaxMind.com")
+
+const (
+	mmdbDataSectionSeparator = 16
+
+	mmdbTypeString = 2
+	mmdbTypeDouble = 3
+	mmdbTypeUint16 = 5
+	mmdbTypeUint32 = 6
+	mmdbTypeMap    = 7
+	mmdbTypeArray  = 11
+)
+
+type testNetwork struct {
+	cidr   string
+	record map[string]interface{}
+}
+
+func encodeControl(typ byte, size int) []byte {
+	var b []byte
+	var ctrl byte
+	switch {
+	case size < 29:
+		ctrl = byte(size)
+	case size < 285:
+		ctrl, b = 29, []byte{byte(size - 29)}
+	default:
+		s := size - 285
+		ctrl, b = 30, []byte{byte(s >> 8), byte(s)}
+	}
+	if typ > 7 {
+		return append([]byte{ctrl, typ - 7}, b...)
+	}
+	return append([]byte{typ<<5 | ctrl}, b...)
+}
+
+func encodeValue(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(encodeControl(mmdbTypeString, len(v)), v...)
+	case float64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(v))
+		return append(encodeControl(mmdbTypeDouble, 8), b...)
+	case uint32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, v)
+		return append(encodeControl(mmdbTypeUint32, 4), b...)
+	case uint16:
+		return append(encodeControl(mmdbTypeUint16, 2), byte(v>>8), byte(v))
+	case []interface{}:
+		b := encodeControl(mmdbTypeArray, len(v))
+		for _, e := range v {
+			b = append(b, encodeValue(e)...)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b := encodeControl(mmdbTypeMap, len(v))
+		for _, k := range keys {
+			b = append(b, encodeValue(k)...)
+			b = append(b, encodeValue(v[k])...)
+		}
+		return b
+	}
+	panic("unsupported value")
+}
+
+func writeTestDB(t *testing.T, path string, recordSize int, networks []testNetwork) {
+	const empty = -1
+	type node struct {
+		children [2]int
+		// data offset of a child, -1 when the child is a node or empty
+		data [2]int
+	}
+	nodes := []*node{{children: [2]int{empty, empty}, data: [2]int{-1, -1}}}
+
+	var data []byte
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		require.NoError(t, err)
+		ones, bits := ipNet.Mask.Size()
+		ip := ipNet.IP.To16()
+		if bits == 32 {
+			// IPv4 networks are in the ::/96 subtree
+			ip = append(make(net.IP, 12), ipNet.IP.To4()...)
+			ones += 96
+		}
+
+		offset := len(data)
+		data = append(data, encodeValue(n.record)...)
+
+		current := 0
+		for i := 0; i < ones; i++ {
+			bit := ip[i/8] >> (7 - uint(i%8)) & 1
+			if i == ones-1 {
+				nodes[current].data[bit] = offset
+				break
+			}
+			if nodes[current].children[bit] == empty {
+				nodes = append(nodes, &node{children: [2]int{empty, empty}, data: [2]int{-1, -1}})
+				nodes[current].children[bit] = len(nodes) - 1
+			}
+			current = nodes[current].children[bit]
+		}
+	}
+
+	nodeCount := len(nodes)
+	record := func(n *node, bit int) uint32 {
+		switch {
+		case n.data[bit] >= 0:
+			return uint32(nodeCount + mmdbDataSectionSeparator + n.data[bit])
+		case n.children[bit] == empty:
+			return uint32(nodeCount)
+		default:
+			return uint32(n.children[bit])
+		}
+	}
+	var file []byte
+	for _, n := range nodes {
+		left, right := record(n, 0), record(n, 1)
+		switch recordSize {
+		case 24:
+			file = append(file, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			file = append(file, byte(left>>16), byte(left>>8), byte(left), byte(left>>24)<<4|byte(right>>24), byte(right>>16), byte(right>>8), byte(right))
+		default:
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint32(b, left)
+			binary.BigEndian.PutUint32(b[4:], right)
+			file = append(file, b...)
+		}
+	}
+	file = append(file, make([]byte, mmdbDataSectionSeparator)...)
+	file = append(file, data...)
+	file = append(file, mmdbMetadataStart...)
+	file = append(file, encodeValue(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+		"ip_version":                  uint16(6),
+		"database_type":               "Test-City",
+	})...)
+
+	require.NoError(t, os.WriteFile(path, file, 0o644))
+}
+
+func cityRecord(country string, subdivision string, city string, lat float64, lon float64) map[string]interface{} {
+	return map[string]interface{}{
+		"country":      map[string]interface{}{"iso_code": country},
+		"subdivisions": []interface{}{map[string]interface{}{"iso_code": subdivision}},
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": city, "de": city + "-de"}},
+		"location":     map[string]interface{}{"latitude": lat, "longitude": lon},
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+func TestMaxMindProvider(t *testing.T) {
+	networks := []testNetwork{
+		{cidr: "81.2.69.0/24", record: cityRecord("GB", "ENG", "London", 51.5142, -0.0931)},
+		{cidr: "2001:480::/32", record: cityRecord("US", "CA", "San Diego", 32.7203, -117.1552)},
+	}
+
+	for _, recordSize := range []int{24, 28, 32} {
+		path := filepath.Join(t.TempDir(), "city.mmdb")
+		writeTestDB(t, path, recordSize, networks)
+
+		p, err := NewMaxMindProvider(path, time.Minute)
+		require.NoError(t, err)
+
+		loc, err := p.Lookup(context.Background(), net.ParseIP("81.2.69.160"))
+		require.NoError(t, err)
+		require.Equal(t, &Location{
+			CountryCode:    "GB",
+			Region:         "ENG",
+			City:           "London",
+			Latitude:       51.5142,
+			Longitude:      -0.0931,
+			HasCoordinates: true,
+		}, loc, "record size %d", recordSize)
+
+		loc, err = p.Lookup(context.Background(), net.ParseIP("2001:480::1"))
+		require.NoError(t, err)
+		require.Equal(t, "San Diego", loc.City)
+
+		loc, err = p.Lookup(context.Background(), net.ParseIP("81.2.70.1"))
+		require.NoError(t, err)
+		require.Nil(t, loc)
+	}
+
+	t.Run("reloaded when replaced", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "city.mmdb")
+		writeTestDB(t, path, 24, networks[:1])
+		p, err := NewMaxMindProvider(path, time.Millisecond)
+		require.NoError(t, err)
+
+		loc, err := p.Lookup(context.Background(), net.ParseIP("2001:480::1"))
+		require.NoError(t, err)
+		require.Nil(t, loc)
+
+		writeTestDB(t, path, 24, networks)
+		require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+		time.Sleep(5 * time.Millisecond)
+		loc, err = p.Lookup(context.Background(), net.ParseIP("2001:480::1"))
+		require.NoError(t, err)
+		require.Equal(t, "US", loc.CountryCode)
+
+		// a broken file does not replace the database loaded
+		require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o644))
+		time.Sleep(5 * time.Millisecond)
+		loc, err = p.Lookup(context.Background(), net.ParseIP("2001:480::1"))
+		require.NoError(t, err)
+		require.Equal(t, "US", loc.CountryCode)
+	})
+
+	t.Run("invalid database", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "city.mmdb")
+		require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o644))
+		_, err := NewMaxMindProvider(path, time.Minute)
+		require.ErrorIs(t, err, ErrInvalidDatabase)
+	})
+}
+
+func TestIPAPIProvider(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "key", r.URL.Query().Get("key"))
+		switch r.URL.Path {
+		case "/json/81.2.69.160":
+			_, _ = w.Write([]byte(`{"status":"success","countryCode":"GB","region":"ENG","city":"London","lat":51.5142,"lon":-0.0931}`))
+		case "/json/10.0.0.1":
+			_, _ = w.Write([]byte(`{"status":"fail","message":"private range"}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":"fail","message":"invalid query"}`))
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewIPAPIProvider("http://ip-api.com", "")
+	require.ErrorIs(t, err, ErrInsecureURL)
+
+	p, err := NewIPAPIProvider(server.URL+"/", "key")
+	require.NoError(t, err)
+	p.client = server.Client()
+	loc, err := p.Lookup(context.Background(), net.ParseIP("81.2.69.160"))
+	require.NoError(t, err)
+	require.Equal(t, "London", loc.City)
+	require.True(t, loc.HasCoordinates)
+
+	loc, err = p.Lookup(context.Background(), net.ParseIP("10.0.0.1"))
+	require.NoError(t, err)
+	require.Nil(t, loc)
+
+	_, err = p.Lookup(context.Background(), net.ParseIP("1.1.1.1"))
+	require.ErrorIs(t, err, ErrLookupFailed)
+}
+
+type countingProvider struct {
+	lookups atomic.Int32
+}
+
+func (p *countingProvider) Lookup(_ context.Context, ip net.IP) (*Location, error) {
+	p.lookups.Add(1)
+	return &Location{City: ip.String()}, nil
+}
+
+func TestCachingProvider(t *testing.T) {
+	counting := &countingProvider{}
+	p, err := NewCachingProvider(counting, 10, 20*time.Millisecond)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		loc, err := p.Lookup(context.Background(), net.ParseIP("81.2.69.160"))
+		require.NoError(t, err)
+		require.Equal(t, "81.2.69.160", loc.City)
+	}
+	require.Equal(t, int32(1), counting.lookups.Load())
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = p.Lookup(context.Background(), net.ParseIP("81.2.69.160"))
+	require.NoError(t, err)
+	require.Equal(t, int32(2), counting.lookups.Load())
+}
+
+func TestNewProvider(t *testing.T) {
+	p, err := NewProvider(config.GeoIPConfig{})
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	_, err = NewProvider(config.GeoIPConfig{Provider: "unknown"})
+	require.ErrorIs(t, err, ErrUnknownProvider)
+
+	custom := &countingProvider{}
+	RegisterProvider("custom", func(conf config.GeoIPConfig) (Provider, error) {
+		return custom, nil
+	})
+	p, err = NewProvider(config.GeoIPConfig{Provider: "custom"})
+	require.NoError(t, err)
+	require.Equal(t, custom, p)
+
+	// private addresses are not looked up
+	loc, err := LookupAddress(context.Background(), p, "192.168.1.1")
+	require.NoError(t, err)
+	require.Nil(t, loc)
+	_, err = LookupAddress(context.Background(), p, "not an address")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+	loc, err = LookupAddress(context.Background(), p, "81.2.69.160")
+	require.NoError(t, err)
+	require.Equal(t, "81.2.69.160", loc.City)
+}
@@ -0,0 +1,98 @@
+package geoip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// the HTTPS endpoint of ip-api needs an API key, the free endpoint is plain HTTP only
+	DefaultIPAPIURL = "https://pro.ip-api.com"
+
+	ipAPITimeout = 2 * time.Second
+)
+
+var (
+	ErrLookupFailed = errors.New("geoip: lookup failed")
+	ErrInsecureURL  = errors.New("geoip: lookup URL must use https")
+)
+
+type ipAPIResponse struct {
+	Status      string  `json:"status"`
+	Message     string  `json:"message"`
+	CountryCode string  `json:"countryCode"`
+	Region      string  `json:"region"`
+	City        string  `json:"city"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+}
+
+// IPAPIProvider locates addresses with the ip-api service, or a service answering the same way. Client addresses
+// are sent over HTTPS only
+type IPAPIProvider struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewIPAPIProvider(serviceURL string, apiKey string) (*IPAPIProvider, error) {
+	if serviceURL == "" {
+		serviceURL = DefaultIPAPIURL
+	}
+	if u, err := url.Parse(serviceURL); err != nil || u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrInsecureURL, serviceURL)
+	}
+	return &IPAPIProvider{
+		url:    strings.TrimSuffix(serviceURL, "/"),
+		apiKey: apiKey,
+		client: &http.Client{Timeout: ipAPITimeout},
+	}, nil
+}
+
+func (p *IPAPIProvider) Lookup(ctx context.Context, ip net.IP) (*Location, error) {
+	query := url.Values{"fields": {"status,message,countryCode,region,city,lat,lon"}}
+	if p.apiKey != "" {
+		query.Set("key", p.apiKey)
+	}
+	lookupURL := fmt.Sprintf("%s/json/%s?%s", p.url, ip.String(), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lookupURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrLookupFailed, res.Status)
+	}
+	body := &ipAPIResponse{}
+	if err = json.NewDecoder(res.Body).Decode(body); err != nil {
+		return nil, err
+	}
+	if body.Status != "success" {
+		// private and reserved ranges are not located
+		if body.Message == "private range" || body.Message == "reserved range" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrLookupFailed, body.Message)
+	}
+
+	return &Location{
+		CountryCode:    body.CountryCode,
+		Region:         body.Region,
+		City:           body.City,
+		Latitude:       body.Lat,
+		Longitude:      body.Lon,
+		HasCoordinates: true,
+	}, nil
+}
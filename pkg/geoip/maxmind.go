@@ -0,0 +1,138 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const DefaultReloadInterval = time.Minute
+
+// MaxMindProvider locates addresses with a MaxMind city database, e. g. GeoLite2-City. The database file is checked
+// for changes every reload interval, a replaced file is loaded without a restart
+type MaxMindProvider struct {
+	path           string
+	reloadInterval time.Duration
+
+	lock      sync.RWMutex
+	db        *maxminddb.Reader
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+func NewMaxMindProvider(path string, reloadInterval time.Duration) (*MaxMindProvider, error) {
+	if reloadInterval <= 0 {
+		reloadInterval = DefaultReloadInterval
+	}
+	p := &MaxMindProvider{
+		path:           path,
+		reloadInterval: reloadInterval,
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *MaxMindProvider) Lookup(_ context.Context, ip net.IP) (*Location, error) {
+	p.maybeReload()
+
+	p.lock.RLock()
+	db := p.db
+	p.lock.RUnlock()
+
+	record := &maxMindCityRecord{}
+	if _, found, err := db.LookupNetwork(ip, record); err != nil || !found {
+		return nil, err
+	}
+	return record.location(), nil
+}
+
+func (p *MaxMindProvider) maybeReload() {
+	p.lock.Lock()
+	if time.Since(p.checkedAt) < p.reloadInterval {
+		p.lock.Unlock()
+		return
+	}
+	p.checkedAt = time.Now()
+	modTime, size := p.modTime, p.size
+	p.lock.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		logger.Warnw("could not check geoip database", err, "path", p.path)
+		return
+	}
+	if info.ModTime().Equal(modTime) && info.Size() == size {
+		return
+	}
+	if err = p.load(); err != nil {
+		// keeps using the database loaded before, the file may be in the middle of being replaced
+		logger.Warnw("could not reload geoip database", err, "path", p.path)
+		return
+	}
+	logger.Infow("reloaded geoip database", "path", p.path)
+}
+
+func (p *MaxMindProvider) load() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	// read into memory rather than mapped, lookups may still use the database being replaced
+	db, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+
+	p.lock.Lock()
+	p.db = db
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.checkedAt = time.Now()
+	p.lock.Unlock()
+	return nil
+}
+
+// maxMindCityRecord is the part of a GeoIP2 or GeoLite2 city record locations are made of
+type maxMindCityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+func (r *maxMindCityRecord) location() *Location {
+	loc := &Location{
+		CountryCode: r.Country.ISOCode,
+		City:        r.City.Names["en"],
+	}
+	if len(r.Subdivisions) != 0 {
+		loc.Region = r.Subdivisions[0].ISOCode
+	}
+	if r.Location.Latitude != nil && r.Location.Longitude != nil {
+		loc.Latitude, loc.Longitude, loc.HasCoordinates = *r.Location.Latitude, *r.Location.Longitude, true
+	}
+	return loc
+}
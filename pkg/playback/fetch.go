@@ -0,0 +1,111 @@
+package playback
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	fetchDialTimeout = 10 * time.Second
+)
+
+var (
+	ErrForbiddenAddress = errors.New("media URL resolves to a private address")
+
+	// shared address space of carrier-grade NAT, not covered by net.IP.IsPrivate
+	sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+)
+
+// newFetchClient returns a client for media URLs given by API callers. Unless allowPrivate, it refuses to connect
+// to loopback, private, link-local and other non public addresses, checked on the resolved address of every
+// connection so redirects and DNS names pointing inside the network are refused too
+func newFetchClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: fetchDialTimeout,
+	}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			// a proxy would be dialed instead of the media host
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+		},
+	}
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip[0] == 0 || sharedAddressSpace.Contains(ip) {
+			return false
+		}
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+// fetch streams the media at mediaURL into memory, stopping as soon as it is larger than maxSize
+func fetch(ctx context.Context, client *http.Client, mediaURL string, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch media, status: %d", res.StatusCode)
+	}
+	if res.ContentLength > maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	buf := &bytes.Buffer{}
+	if res.ContentLength > 0 {
+		buf.Grow(int(res.ContentLength))
+	}
+	if _, err = io.Copy(&cappedWriter{w: buf, remaining: maxSize}, res.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cappedWriter fails writes going past remaining bytes
+type cappedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > c.remaining {
+		return 0, ErrFileTooLarge
+	}
+	n, err := c.w.Write(p)
+	c.remaining -= int64(n)
+	return n, err
+}
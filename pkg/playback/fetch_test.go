@@ -0,0 +1,56 @@
+package playback
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// chunked, without a content length
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(make([]byte, 1000))
+	}))
+	defer server.Close()
+
+	t.Run("private addresses are refused", func(t *testing.T) {
+		_, err := fetch(context.Background(), newFetchClient(false), server.URL, 2000)
+		require.ErrorIs(t, err, ErrForbiddenAddress)
+	})
+
+	t.Run("private addresses can be allowed", func(t *testing.T) {
+		data, err := fetch(context.Background(), newFetchClient(true), server.URL, 2000)
+		require.NoError(t, err)
+		require.Len(t, data, 1000)
+	})
+
+	t.Run("size is capped while streaming", func(t *testing.T) {
+		_, err := fetch(context.Background(), newFetchClient(true), server.URL, 999)
+		require.ErrorIs(t, err, ErrFileTooLarge)
+	})
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for ip, public := range map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		require.Equal(t, public, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
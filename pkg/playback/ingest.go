@@ -0,0 +1,200 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+)
+
+const (
+	ingestTrackPrefix = "IGT_"
+
+	// used when the source repeats or rewinds timestamps
+	defaultIngestFrameDuration = 33 * time.Millisecond
+)
+
+var ErrMissingAVCConfig = errors.New("frame received before the AVC configuration")
+
+type IngestParams struct {
+	RoomName  livekit.RoomName
+	Identity  livekit.ParticipantIdentity
+	Name      livekit.ParticipantName
+	Metadata  string
+	TrackName string
+	Connect   SignalConnector
+	Logger    logger.Logger
+}
+
+// Ingest publishes a live H.264 stream pushed to the server, e.g. over RTMP, as the camera track of a participant.
+// Frames are forwarded as they arrive, RTP timestamps follow the timestamps of the source. Like a client with
+// dynacast, the track is paused while no subscriber needs it
+type Ingest struct {
+	params      IngestParams
+	participant *Participant
+	track       *webrtc.TrackLocalStaticSample
+	trackInfo   *livekit.TrackInfo
+
+	lock            sync.Mutex
+	avc             *avcConfig
+	pending         *ingestFrame
+	waitingKeyframe bool
+	paused          bool
+}
+
+type ingestFrame struct {
+	timestamp time.Duration
+	data      []byte
+}
+
+// StartIngest joins the room and publishes the video track, ctx bounds the join and publication
+func StartIngest(ctx context.Context, params IngestParams) (*Ingest, error) {
+	participant, err := NewParticipant(ParticipantParams{
+		RoomName: params.RoomName,
+		Identity: params.Identity,
+		Name:     params.Name,
+		Metadata: params.Metadata,
+		Connect:  params.Connect,
+		Logger:   params.Logger,
+	}, []webrtc.RTPCodecCapability{h264Codec})
+	if err != nil {
+		return nil, err
+	}
+	if err = participant.Join(ctx); err != nil {
+		return nil, err
+	}
+
+	track, err := webrtc.NewTrackLocalStaticSample(h264Codec, utils.NewGuid(ingestTrackPrefix), string(params.Identity))
+	if err != nil {
+		participant.Close()
+		return nil, err
+	}
+	ti, err := participant.PublishTrack(track, &livekit.AddTrackRequest{
+		Name:   params.TrackName,
+		Type:   livekit.TrackType_VIDEO,
+		Source: livekit.TrackSource_CAMERA,
+	})
+	if err != nil {
+		participant.Close()
+		return nil, err
+	}
+	i := &Ingest{
+		params:          params,
+		participant:     participant,
+		track:           track,
+		trackInfo:       ti,
+		waitingKeyframe: true,
+	}
+	participant.OnSubscribedQualityUpdate(i.onSubscribedQualityUpdate)
+	participant.Negotiate()
+	return i, nil
+}
+
+func (i *Ingest) TrackInfo() *livekit.TrackInfo {
+	return i.trackInfo
+}
+
+// OnClosed is called once the participant has left the room
+func (i *Ingest) OnClosed(f func()) {
+	i.participant.OnDisconnected(f)
+}
+
+func (i *Ingest) Close() {
+	i.participant.Close()
+}
+
+// SetAVCConfig takes the AVCDecoderConfigurationRecord sent ahead of the frames, parameter sets are inserted
+// before each IDR frame that does not carry them
+func (i *Ingest) SetAVCConfig(record []byte) error {
+	avc, err := parseAVCConfig(record)
+	if err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	i.avc = avc
+	i.lock.Unlock()
+	return nil
+}
+
+// onSubscribedQualityUpdate pauses forwarding while no quality of the track is needed, frames are dropped before
+// they are packetized. Forwarding resumes at the next keyframe of the source
+func (i *Ingest) onSubscribedQualityUpdate(update *livekit.SubscribedQualityUpdate) {
+	if update.TrackSid != i.trackInfo.Sid {
+		return
+	}
+
+	needed := false
+	for _, codec := range update.SubscribedCodecs {
+		for _, q := range codec.Qualities {
+			if q.Enabled {
+				needed = true
+			}
+		}
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.paused != needed {
+		return
+	}
+	i.paused = !needed
+	if i.paused {
+		i.pending = nil
+	} else {
+		i.waitingKeyframe = true
+	}
+	i.params.Logger.Infow("ingest dynacast", "trackID", i.trackInfo.Sid, "paused", i.paused)
+}
+
+// WriteAVCFrame takes a frame of length prefixed NAL units. A frame is sent when the next one arrives, so that its
+// duration is known. Frames are dropped until the peer connection is up and a keyframe is received, and while
+// the track is paused
+func (i *Ingest) WriteAVCFrame(timestamp time.Duration, keyframe bool, data []byte) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.avc == nil {
+		return ErrMissingAVCConfig
+	}
+	if !i.participant.IsConnected() {
+		i.pending = nil
+		i.waitingKeyframe = true
+		return nil
+	}
+	if i.paused {
+		return nil
+	}
+	if i.waitingKeyframe {
+		if !keyframe {
+			return nil
+		}
+		i.waitingKeyframe = false
+	}
+
+	pending := i.pending
+	i.pending = &ingestFrame{
+		timestamp: timestamp,
+		data:      i.avc.toAnnexB(data),
+	}
+	if pending == nil {
+		return nil
+	}
+
+	duration := timestamp - pending.timestamp
+	if duration <= 0 {
+		duration = defaultIngestFrameDuration
+	}
+	return i.track.WriteSample(media.Sample{
+		Data:     pending.data,
+		Duration: duration,
+	})
+}
@@ -0,0 +1,58 @@
+package playback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+func TestIngestDynacast(t *testing.T) {
+	track, err := webrtc.NewTrackLocalStaticSample(h264Codec, "video", "ingest")
+	require.NoError(t, err)
+	participant := &Participant{}
+	participant.connected.Store(true)
+	i := &Ingest{
+		params:          IngestParams{Logger: logger.GetLogger()},
+		participant:     participant,
+		track:           track,
+		trackInfo:       &livekit.TrackInfo{Sid: "TR_ingest"},
+		avc:             &avcConfig{lengthSize: 4},
+		waitingKeyframe: true,
+	}
+	frame := []byte{0, 0, 0, 1, 0x65}
+	qualityUpdate := func(trackID string, enabled bool) *livekit.SubscribedQualityUpdate {
+		return &livekit.SubscribedQualityUpdate{
+			TrackSid: trackID,
+			SubscribedCodecs: []*livekit.SubscribedCodec{{
+				Codec:     "h264",
+				Qualities: []*livekit.SubscribedQuality{{Quality: livekit.VideoQuality_LOW, Enabled: enabled}},
+			}},
+		}
+	}
+
+	require.NoError(t, i.WriteAVCFrame(0, true, frame))
+	require.NotNil(t, i.pending)
+
+	// other tracks are not affected
+	i.onSubscribedQualityUpdate(qualityUpdate("TR_other", false))
+	require.False(t, i.paused)
+
+	i.onSubscribedQualityUpdate(qualityUpdate("TR_ingest", false))
+	require.True(t, i.paused)
+	require.Nil(t, i.pending)
+	require.NoError(t, i.WriteAVCFrame(33*time.Millisecond, true, frame))
+	require.Nil(t, i.pending)
+
+	// resumes at the next keyframe
+	i.onSubscribedQualityUpdate(qualityUpdate("TR_ingest", true))
+	require.False(t, i.paused)
+	require.NoError(t, i.WriteAVCFrame(66*time.Millisecond, false, frame))
+	require.Nil(t, i.pending)
+	require.NoError(t, i.WriteAVCFrame(100*time.Millisecond, true, frame))
+	require.NotNil(t, i.pending)
+}
@@ -0,0 +1,64 @@
+package playback
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	ivfFileHeaderLen  = 32
+	ivfFrameHeaderLen = 12
+)
+
+func parseIVF(data []byte) (*Media, error) {
+	if len(data) < ivfFileHeaderLen {
+		return nil, ErrTruncated
+	}
+
+	var mime string
+	switch string(data[8:12]) {
+	case "VP80":
+		mime = webrtc.MimeTypeVP8
+	case "VP90":
+		mime = webrtc.MimeTypeVP9
+	case "AV01":
+		mime = webrtc.MimeTypeAV1
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+
+	headerLen := int(binary.LittleEndian.Uint16(data[6:8]))
+	if headerLen < ivfFileHeaderLen || headerLen > len(data) {
+		headerLen = ivfFileHeaderLen
+	}
+	// timebase is numerator / denominator seconds
+	denominator := binary.LittleEndian.Uint32(data[16:20])
+	numerator := binary.LittleEndian.Uint32(data[20:24])
+	if denominator == 0 || numerator == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+
+	track := &MediaTrack{
+		Codec: webrtc.RTPCodecCapability{MimeType: mime, ClockRate: 90000},
+	}
+	data = data[headerLen:]
+	for len(data) >= ivfFrameHeaderLen {
+		size := int(binary.LittleEndian.Uint32(data[0:4]))
+		ts := binary.LittleEndian.Uint64(data[4:12])
+		if len(data) < ivfFrameHeaderLen+size {
+			// keep the frames read so far
+			break
+		}
+		frame := data[ivfFrameHeaderLen : ivfFrameHeaderLen+size]
+		data = data[ivfFrameHeaderLen+size:]
+
+		track.Samples = append(track.Samples, Sample{
+			Data:     frame,
+			PTS:      time.Duration(ts*uint64(numerator)) * time.Second / time.Duration(denominator),
+			Keyframe: isKeyframe(mime, frame),
+		})
+	}
+	return &Media{Tracks: []*MediaTrack{track}}, nil
+}
@@ -0,0 +1,372 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+)
+
+const (
+	playbackPrefix      = "PB_"
+	playbackTrackPrefix = "PBT_"
+
+	DefaultMaxFileSize = 256 << 20
+
+	DefaultTestPatternDuration = time.Minute
+	MaxTestPatternDuration     = 10 * time.Minute
+	testPatternTrackName       = "test pattern"
+)
+
+var (
+	ErrPlaybackNotFound = errors.New("playback not found")
+	ErrInvalidURL       = errors.New("media URL must be http or https")
+	ErrFileTooLarge     = errors.New("media file is too large")
+	ErrInvalidDuration  = errors.New("test pattern duration is out of range")
+)
+
+type StartRequest struct {
+	RoomName livekit.RoomName
+	Identity livekit.ParticipantIdentity
+	Name     livekit.ParticipantName
+	Metadata string
+	// http(s) location of the media, object storage is accessed through (pre-signed) URLs
+	URL  string
+	Loop bool
+}
+
+type TestPatternRequest struct {
+	RoomName livekit.RoomName
+	Identity livekit.ParticipantIdentity
+	Name     livekit.ParticipantName
+	Metadata string
+	// test pattern is unpublished after this, DefaultTestPatternDuration when zero
+	Duration time.Duration
+}
+
+type Info struct {
+	PlaybackID string            `json:"playback_id"`
+	RoomName   string            `json:"room"`
+	Identity   string            `json:"identity"`
+	URL        string            `json:"url"`
+	State      PlayerState       `json:"state"`
+	Loop       bool              `json:"loop"`
+	PositionMs int64             `json:"position_ms"`
+	DurationMs int64             `json:"duration_ms"`
+	TrackIDs   []livekit.TrackID `json:"track_ids"`
+	// set for test patterns, unix milliseconds
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+type ManagerParams struct {
+	Connect SignalConnector
+	// client fetching media, defaults to one refusing private addresses unless AllowPrivateURLs
+	HTTPClient       *http.Client
+	AllowPrivateURLs bool
+	MaxFileSize      int64
+	Logger           logger.Logger
+}
+
+// Manager runs file playbacks, each published into a room by its own participant. Playbacks are node-local, they
+// are held by the node that started them and can only be listed and controlled there
+type Manager struct {
+	params ManagerParams
+
+	lock      sync.Mutex
+	playbacks map[string]*playback
+}
+
+type playback struct {
+	id          string
+	req         StartRequest
+	participant *Participant
+	player      *Player
+	trackIDs    []livekit.TrackID
+	expiresAt   time.Time
+}
+
+func NewManager(params ManagerParams) *Manager {
+	if params.HTTPClient == nil {
+		params.HTTPClient = newFetchClient(params.AllowPrivateURLs)
+	}
+	if params.MaxFileSize <= 0 {
+		params.MaxFileSize = DefaultMaxFileSize
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+	return &Manager{
+		params:    params,
+		playbacks: make(map[string]*playback),
+	}
+}
+
+func (m *Manager) Start(ctx context.Context, req StartRequest) (*Info, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, ErrInvalidURL
+	}
+
+	data, err := fetch(ctx, m.params.HTTPClient, req.URL, m.params.MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	media, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	id := utils.NewGuid(playbackPrefix)
+	if req.Identity == "" {
+		req.Identity = livekit.ParticipantIdentity(id)
+	}
+	pbLogger := m.params.Logger.WithValues("playbackID", id, "room", req.RoomName, "participant", req.Identity)
+	pb := &playback{
+		id:  id,
+		req: req,
+	}
+	err = m.startPlayback(ctx, pb, path.Base(req.URL), media, pbLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	pbLogger.Infow("playback started", "url", req.URL, "duration", media.Duration(), "tracks", len(media.Tracks))
+	return pb.info(), nil
+}
+
+// StartTestPattern publishes a color bar video with a tone for a limited duration, to check downstream paths during
+// diagnostics. It is managed like a looping playback and can be stopped early with Stop.
+func (m *Manager) StartTestPattern(ctx context.Context, req TestPatternRequest) (*Info, error) {
+	if req.Duration == 0 {
+		req.Duration = DefaultTestPatternDuration
+	}
+	if req.Duration < 0 || req.Duration > MaxTestPatternDuration {
+		return nil, ErrInvalidDuration
+	}
+
+	id := utils.NewGuid(playbackPrefix)
+	if req.Identity == "" {
+		req.Identity = livekit.ParticipantIdentity(id)
+	}
+	pbLogger := m.params.Logger.WithValues("playbackID", id, "room", req.RoomName, "participant", req.Identity)
+	pb := &playback{
+		id: id,
+		req: StartRequest{
+			RoomName: req.RoomName,
+			Identity: req.Identity,
+			Name:     req.Name,
+			Metadata: req.Metadata,
+			Loop:     true,
+		},
+		expiresAt: time.Now().Add(req.Duration),
+	}
+	if err := m.startPlayback(ctx, pb, testPatternTrackName, NewTestPattern(), pbLogger); err != nil {
+		return nil, err
+	}
+
+	expiry := time.AfterFunc(req.Duration, func() {
+		pbLogger.Infow("test pattern expired")
+		pb.participant.Close()
+	})
+	pb.participant.OnDisconnected(func() {
+		expiry.Stop()
+		m.onPlaybackDisconnected(pb)
+	})
+
+	pbLogger.Infow("test pattern started", "duration", req.Duration)
+	return pb.info(), nil
+}
+
+func (m *Manager) startPlayback(ctx context.Context, pb *playback, trackName string, media *Media, pbLogger logger.Logger) error {
+	req := pb.req
+	var codecs []webrtc.RTPCodecCapability
+	for _, t := range media.Tracks {
+		codecs = append(codecs, t.Codec)
+	}
+	participant, err := NewParticipant(ParticipantParams{
+		RoomName: req.RoomName,
+		Identity: req.Identity,
+		Name:     req.Name,
+		Metadata: req.Metadata,
+		Connect:  m.params.Connect,
+		Logger:   pbLogger,
+	}, codecs)
+	if err != nil {
+		return err
+	}
+	if err = participant.Join(ctx); err != nil {
+		return err
+	}
+
+	pb.participant = participant
+	writers := make([]SampleWriter, 0, len(media.Tracks))
+	for _, t := range media.Tracks {
+		track, err := webrtc.NewTrackLocalStaticSample(t.Codec, utils.NewGuid(playbackTrackPrefix), string(req.Identity))
+		if err != nil {
+			participant.Close()
+			return err
+		}
+
+		addReq := &livekit.AddTrackRequest{
+			Name:   trackName,
+			Type:   livekit.TrackType_VIDEO,
+			Source: livekit.TrackSource_CAMERA,
+		}
+		if t.Kind() == webrtc.RTPCodecTypeAudio {
+			addReq.Type = livekit.TrackType_AUDIO
+			addReq.Source = livekit.TrackSource_MICROPHONE
+		}
+		ti, err := participant.PublishTrack(track, addReq)
+		if err != nil {
+			participant.Close()
+			return err
+		}
+		pb.trackIDs = append(pb.trackIDs, livekit.TrackID(ti.Sid))
+		writers = append(writers, track)
+	}
+
+	pb.player = NewPlayer(PlayerParams{
+		Media:   media,
+		Writers: writers,
+		Loop:    req.Loop,
+		Logger:  pbLogger,
+	})
+	pb.player.OnEnded(func() {
+		pbLogger.Infow("playback ended")
+		participant.Close()
+	})
+	participant.OnConnected(pb.player.Start)
+	participant.OnDisconnected(func() {
+		m.onPlaybackDisconnected(pb)
+	})
+
+	m.lock.Lock()
+	m.playbacks[pb.id] = pb
+	m.lock.Unlock()
+
+	participant.Negotiate()
+	return nil
+}
+
+func (m *Manager) onPlaybackDisconnected(pb *playback) {
+	pb.player.Stop()
+	m.lock.Lock()
+	delete(m.playbacks, pb.id)
+	m.lock.Unlock()
+}
+
+func (m *Manager) get(roomName livekit.RoomName, playbackID string) (*playback, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pb := m.playbacks[playbackID]
+	if pb == nil || pb.req.RoomName != roomName {
+		return nil, ErrPlaybackNotFound
+	}
+	return pb, nil
+}
+
+func (m *Manager) Pause(roomName livekit.RoomName, playbackID string) (*Info, error) {
+	pb, err := m.get(roomName, playbackID)
+	if err != nil {
+		return nil, err
+	}
+	pb.player.Pause()
+	return pb.info(), nil
+}
+
+func (m *Manager) Resume(roomName livekit.RoomName, playbackID string) (*Info, error) {
+	pb, err := m.get(roomName, playbackID)
+	if err != nil {
+		return nil, err
+	}
+	pb.player.Resume()
+	return pb.info(), nil
+}
+
+func (m *Manager) Seek(roomName livekit.RoomName, playbackID string, pos time.Duration) (*Info, error) {
+	pb, err := m.get(roomName, playbackID)
+	if err != nil {
+		return nil, err
+	}
+	if err = pb.player.Seek(pos); err != nil {
+		return nil, err
+	}
+	return pb.info(), nil
+}
+
+func (m *Manager) SetLoop(roomName livekit.RoomName, playbackID string, loop bool) (*Info, error) {
+	pb, err := m.get(roomName, playbackID)
+	if err != nil {
+		return nil, err
+	}
+	pb.player.SetLoop(loop)
+	return pb.info(), nil
+}
+
+func (m *Manager) Stop(roomName livekit.RoomName, playbackID string) error {
+	pb, err := m.get(roomName, playbackID)
+	if err != nil {
+		return err
+	}
+	pb.participant.Close()
+	return nil
+}
+
+// Close stops all playbacks
+func (m *Manager) Close() {
+	m.lock.Lock()
+	var participants []*Participant
+	for _, pb := range m.playbacks {
+		participants = append(participants, pb.participant)
+	}
+	m.lock.Unlock()
+
+	for _, p := range participants {
+		p.Close()
+	}
+}
+
+func (m *Manager) List(roomName livekit.RoomName) []*Info {
+	m.lock.Lock()
+	var playbacks []*playback
+	for _, pb := range m.playbacks {
+		if roomName == "" || pb.req.RoomName == roomName {
+			playbacks = append(playbacks, pb)
+		}
+	}
+	m.lock.Unlock()
+
+	infos := make([]*Info, 0, len(playbacks))
+	for _, pb := range playbacks {
+		infos = append(infos, pb.info())
+	}
+	return infos
+}
+
+func (pb *playback) info() *Info {
+	info := &Info{
+		PlaybackID: pb.id,
+		RoomName:   string(pb.req.RoomName),
+		Identity:   string(pb.req.Identity),
+		URL:        pb.req.URL,
+		State:      pb.player.State(),
+		Loop:       pb.player.Loop(),
+		PositionMs: pb.player.Position().Milliseconds(),
+		DurationMs: pb.player.Duration().Milliseconds(),
+		TrackIDs:   pb.trackIDs,
+	}
+	if !pb.expiresAt.IsZero() {
+		info.ExpiresAt = pb.expiresAt.UnixMilli()
+	}
+	return info
+}
@@ -0,0 +1,210 @@
+package playback
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported media format")
+	ErrUnsupportedCodec  = errors.New("unsupported codec")
+	ErrNoPlayableTracks  = errors.New("media has no playable tracks")
+	ErrTruncated         = errors.New("media is truncated")
+)
+
+// Sample is a single encoded frame of a track
+type Sample struct {
+	Data     []byte
+	PTS      time.Duration
+	Keyframe bool
+}
+
+// MediaTrack holds all the samples of a track in decode order
+type MediaTrack struct {
+	Codec   webrtc.RTPCodecCapability
+	Samples []Sample
+}
+
+func (t *MediaTrack) Kind() webrtc.RTPCodecType {
+	if t.Codec.MimeType == webrtc.MimeTypeOpus {
+		return webrtc.RTPCodecTypeAudio
+	}
+	return webrtc.RTPCodecTypeVideo
+}
+
+func (t *MediaTrack) Duration() time.Duration {
+	if len(t.Samples) == 0 {
+		return 0
+	}
+	last := t.Samples[len(t.Samples)-1].PTS
+	return last + t.frameDuration(len(t.Samples)-1)
+}
+
+// frameDuration returns how long sample i is presented for
+func (t *MediaTrack) frameDuration(i int) time.Duration {
+	if i+1 < len(t.Samples) {
+		return t.Samples[i+1].PTS - t.Samples[i].PTS
+	}
+	if i > 0 {
+		return t.Samples[i].PTS - t.Samples[i-1].PTS
+	}
+	if t.Kind() == webrtc.RTPCodecTypeAudio {
+		return 20 * time.Millisecond
+	}
+	return 33 * time.Millisecond
+}
+
+// seekIndex returns the index of the first sample at or after pos
+func (t *MediaTrack) seekIndex(pos time.Duration) int {
+	return sort.Search(len(t.Samples), func(i int) bool {
+		return t.Samples[i].PTS >= pos
+	})
+}
+
+// keyframeBefore returns the PTS of the closest keyframe at or before pos
+func (t *MediaTrack) keyframeBefore(pos time.Duration) time.Duration {
+	idx := t.seekIndex(pos)
+	if idx < len(t.Samples) && t.Samples[idx].PTS == pos && t.Samples[idx].Keyframe {
+		return pos
+	}
+	for i := idx - 1; i >= 0; i-- {
+		if t.Samples[i].Keyframe {
+			return t.Samples[i].PTS
+		}
+	}
+	return 0
+}
+
+// Media is a demuxed media file, kept in memory for seeking and looping
+type Media struct {
+	Tracks []*MediaTrack
+}
+
+func (m *Media) Duration() time.Duration {
+	var d time.Duration
+	for _, t := range m.Tracks {
+		if td := t.Duration(); td > d {
+			d = td
+		}
+	}
+	return d
+}
+
+// Parse demuxes an MP4, WebM, Ogg (Opus) or IVF file. Tracks with codecs that cannot be published are dropped
+func Parse(data []byte) (*Media, error) {
+	var (
+		m   *Media
+		err error
+	)
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+		m, err = parseOgg(data)
+	case bytes.HasPrefix(data, []byte("DKIF")):
+		m, err = parseIVF(data)
+	case bytes.HasPrefix(data, []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		m, err = parseWebM(data)
+	case len(data) >= 8 && bytes.Equal(data[4:8], []byte("ftyp")):
+		m, err = parseMP4(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := m.Tracks[:0]
+	for _, t := range m.Tracks {
+		if len(t.Samples) == 0 {
+			continue
+		}
+		// playback can only start on a keyframe
+		t.Samples[0].Keyframe = true
+		tracks = append(tracks, t)
+	}
+	if len(tracks) == 0 {
+		return nil, ErrNoPlayableTracks
+	}
+	m.Tracks = tracks
+	return m, nil
+}
+
+// ------------------------------------------------
+
+func isVP8Keyframe(frame []byte) bool {
+	return len(frame) > 0 && frame[0]&0x01 == 0
+}
+
+func isVP9Keyframe(frame []byte) bool {
+	if len(frame) == 0 {
+		return false
+	}
+	// frame_marker(2), profile_low_bit(1), profile_high_bit(1)
+	b := frame[0]
+	if b>>6 != 0x2 {
+		return false
+	}
+	profile := (b>>5)&0x1 | ((b>>4)&0x1)<<1
+	shift := uint(3)
+	if profile == 3 {
+		// reserved_zero
+		shift--
+	}
+	if (b>>shift)&0x1 == 1 {
+		// show_existing_frame
+		return false
+	}
+	return (b>>(shift-1))&0x1 == 0
+}
+
+func isH264Keyframe(annexB []byte) bool {
+	for _, nalu := range splitAnnexB(annexB) {
+		if len(nalu) > 0 && nalu[0]&0x1f == 5 {
+			return true
+		}
+	}
+	return false
+}
+
+func splitAnnexB(data []byte) [][]byte {
+	var (
+		nalus [][]byte
+		start = -1
+	)
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			end := i
+			if end > start && data[end-1] == 0 {
+				end--
+			}
+			nalus = append(nalus, data[start:end])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+	return nalus
+}
+
+func isKeyframe(mime string, frame []byte) bool {
+	switch mime {
+	case webrtc.MimeTypeOpus:
+		return true
+	case webrtc.MimeTypeVP8:
+		return isVP8Keyframe(frame)
+	case webrtc.MimeTypeVP9:
+		return isVP9Keyframe(frame)
+	case webrtc.MimeTypeH264:
+		return isH264Keyframe(frame)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,265 @@
+package playback
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// 20ms CELT frame
+var opusFrame = []byte{0xfc, 0x01, 0x02}
+
+func TestParseOgg(t *testing.T) {
+	big := append([]byte{0xfc}, bytes.Repeat([]byte{0xaa}, 300)...)
+	data := oggPage(1, []byte("OpusHead\x01\x02"))
+	data = append(data, oggPage(1, []byte("OpusTags"))...)
+	// another logical stream is ignored
+	data = append(data, oggPage(2, []byte("garbage"))...)
+	data = append(data, oggPage(1, opusFrame, big, opusFrame)...)
+
+	m, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, m.Tracks, 1)
+
+	track := m.Tracks[0]
+	require.Equal(t, webrtc.MimeTypeOpus, track.Codec.MimeType)
+	require.Len(t, track.Samples, 3)
+	require.Equal(t, big, track.Samples[1].Data)
+	for i, s := range track.Samples {
+		require.Equal(t, time.Duration(i)*20*time.Millisecond, s.PTS)
+	}
+	require.Equal(t, 60*time.Millisecond, m.Duration())
+}
+
+func TestOpusPacketDuration(t *testing.T) {
+	require.Equal(t, 20*time.Millisecond, opusPacketDuration([]byte{0xfc}))
+	// SILK 60ms
+	require.Equal(t, 60*time.Millisecond, opusPacketDuration([]byte{0x18}))
+	// two 10ms frames
+	require.Equal(t, 20*time.Millisecond, opusPacketDuration([]byte{0x01}))
+	// code 3 with 3 CELT 2.5ms frames
+	require.Equal(t, 7500*time.Microsecond, opusPacketDuration([]byte{0x83, 0x03}))
+	require.Equal(t, time.Duration(0), opusPacketDuration(nil))
+}
+
+func TestParseIVF(t *testing.T) {
+	data := make([]byte, ivfFileHeaderLen)
+	copy(data, "DKIF")
+	binary.LittleEndian.PutUint16(data[6:], ivfFileHeaderLen)
+	copy(data[8:], "VP80")
+	binary.LittleEndian.PutUint32(data[16:], 30)
+	binary.LittleEndian.PutUint32(data[20:], 1)
+	for i, frame := range [][]byte{{0x10, 0x02}, {0x11, 0x02}, {0x11, 0x03}} {
+		header := make([]byte, ivfFrameHeaderLen)
+		binary.LittleEndian.PutUint32(header[0:], uint32(len(frame)))
+		binary.LittleEndian.PutUint64(header[4:], uint64(i))
+		data = append(append(data, header...), frame...)
+	}
+	// truncated frame is dropped
+	data = append(data, 0xff, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 1)
+
+	m, err := Parse(data)
+	require.NoError(t, err)
+	track := m.Tracks[0]
+	require.Equal(t, webrtc.MimeTypeVP8, track.Codec.MimeType)
+	require.Len(t, track.Samples, 3)
+	require.True(t, track.Samples[0].Keyframe)
+	require.False(t, track.Samples[1].Keyframe)
+	require.Equal(t, time.Second/15, track.Samples[2].PTS)
+}
+
+func TestParseWebM(t *testing.T) {
+	tracks := ebml(ebmlIDTracks,
+		ebml(ebmlIDTrackEntry, ebml(ebmlIDTrackNumber, []byte{1}), ebml(ebmlIDCodecID, []byte("V_VP8"))),
+		ebml(ebmlIDTrackEntry, ebml(ebmlIDTrackNumber, []byte{2}), ebml(ebmlIDCodecID, []byte("A_OPUS"))),
+		ebml(ebmlIDTrackEntry, ebml(ebmlIDTrackNumber, []byte{3}), ebml(ebmlIDCodecID, []byte("A_AAC"))),
+	)
+	cluster := func(timecode byte, blocks ...[]byte) []byte {
+		children := [][]byte{ebml(ebmlIDClusterTimecode, []byte{timecode})}
+		for _, b := range blocks {
+			children = append(children, ebml(ebmlIDSimpleBlock, b))
+		}
+		// live muxers write clusters with unknown size
+		return unknownSize(ebml(ebmlIDCluster, children...))
+	}
+	block := func(track byte, relative int16, flags byte, frame ...byte) []byte {
+		b := []byte{0x80 | track, byte(relative >> 8), byte(relative), flags}
+		return append(b, frame...)
+	}
+
+	segment := unknownSize(ebml(ebmlIDSegment,
+		ebml(ebmlIDInfo, ebml(ebmlIDTimecodeScale, []byte{0x0f, 0x42, 0x40})),
+		tracks,
+		cluster(0,
+			block(1, 0, 0x80, 0x10),
+			block(2, 0, 0x80, opusFrame...),
+			block(3, 0, 0x80, 0x00),
+			block(2, 20, 0x80, opusFrame...),
+			block(1, 33, 0x00, 0x11),
+		),
+		cluster(66, block(1, 0, 0x00, 0x11)),
+	))
+	data := append(ebml(ebmlIDHeader, ebml(0x4282, []byte("webm"))), segment...)
+
+	m, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, m.Tracks, 2)
+
+	video, audio := m.Tracks[0], m.Tracks[1]
+	require.Equal(t, webrtc.MimeTypeVP8, video.Codec.MimeType)
+	require.Len(t, video.Samples, 3)
+	require.True(t, video.Samples[0].Keyframe)
+	require.False(t, video.Samples[1].Keyframe)
+	require.Equal(t, []time.Duration{0, 33 * time.Millisecond, 66 * time.Millisecond},
+		[]time.Duration{video.Samples[0].PTS, video.Samples[1].PTS, video.Samples[2].PTS})
+
+	require.Equal(t, webrtc.MimeTypeOpus, audio.Codec.MimeType)
+	require.Len(t, audio.Samples, 2)
+	require.Equal(t, opusFrame, audio.Samples[1].Data)
+	require.Equal(t, 20*time.Millisecond, audio.Samples[1].PTS)
+}
+
+func TestParseMP4(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0xe0, 0x1f}
+	pps := []byte{0x68, 0xce}
+	avcC := []byte{1, 0x42, 0xe0, 0x1f, 0xff, 0xe1}
+	avcC = append(avcC, 0, byte(len(sps)))
+	avcC = append(avcC, sps...)
+	avcC = append(avcC, 1, 0, byte(len(pps)))
+	avcC = append(avcC, pps...)
+
+	idr := lengthPrefixed([]byte{0x65, 0x88}, []byte{0x06, 0x01})
+	nonIDR := lengthPrefixed([]byte{0x41, 0x9a})
+	samples := [][]byte{idr, nonIDR, nonIDR}
+
+	ftyp := mp4Box4("ftyp", []byte("isom\x00\x00\x02\x00"))
+	// mdat payload starts after ftyp and the mdat header
+	mdatOffset := uint32(len(ftyp) + 8)
+	var mdat []byte
+	sizes := fullBox(0, u32(0), u32(uint32(len(samples))))
+	for _, s := range samples {
+		mdat = append(mdat, s...)
+		sizes = append(sizes, u32(uint32(len(s)))...)
+	}
+
+	mdhd := fullBox(0, u32(0), u32(0), u32(90000), u32(0), u32(0))
+	avc1 := mp4Box4("avc1", append(make([]byte, 78), mp4Box4("avcC", avcC)...))
+	stbl := mp4Box4("stbl",
+		mp4Box4("stsd", append(fullBox(0, u32(1)), avc1...)),
+		mp4Box4("stts", fullBox(0, u32(1), u32(3), u32(3000))),
+		// two samples in the first chunk, one in the second
+		mp4Box4("stsc", fullBox(0, u32(2), u32(1), u32(2), u32(1), u32(2), u32(1), u32(1))),
+		mp4Box4("stsz", sizes),
+		mp4Box4("stco", fullBox(0, u32(2), u32(mdatOffset), u32(mdatOffset+uint32(len(idr)+len(nonIDR))))),
+		mp4Box4("stss", fullBox(0, u32(1), u32(1))),
+	)
+	aac := mp4Box4("trak", mp4Box4("mdia",
+		mp4Box4("mdhd", mdhd),
+		mp4Box4("minf", mp4Box4("stbl", mp4Box4("stsd", append(fullBox(0, u32(1)), mp4Box4("mp4a", make([]byte, 28))...)))),
+	))
+	moov := mp4Box4("moov",
+		mp4Box4("trak", mp4Box4("mdia", mp4Box4("mdhd", mdhd), mp4Box4("minf", stbl))),
+		aac,
+	)
+	data := append(ftyp, mp4Box4("mdat", mdat)...)
+	data = append(data, moov...)
+
+	m, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, m.Tracks, 1)
+
+	track := m.Tracks[0]
+	require.Equal(t, webrtc.MimeTypeH264, track.Codec.MimeType)
+	require.Len(t, track.Samples, 3)
+	require.True(t, track.Samples[0].Keyframe)
+	require.False(t, track.Samples[1].Keyframe)
+	require.Equal(t, 2*time.Second/30, track.Samples[2].PTS)
+
+	// parameter sets are inserted before the IDR
+	require.Equal(t, [][]byte{sps, pps, {0x65, 0x88}, {0x06, 0x01}}, splitAnnexB(track.Samples[0].Data))
+	require.Equal(t, [][]byte{{0x41, 0x9a}}, splitAnnexB(track.Samples[2].Data))
+}
+
+func TestParseUnsupported(t *testing.T) {
+	_, err := Parse([]byte("RIFF....WAVE"))
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestVP9Keyframe(t *testing.T) {
+	// profile 0 key frame, show_existing_frame = 0, frame_type = 0
+	require.True(t, isVP9Keyframe([]byte{0x80}))
+	// inter frame
+	require.False(t, isVP9Keyframe([]byte{0x84}))
+	// profile 3 key frame
+	require.True(t, isVP9Keyframe([]byte{0xb0}))
+}
+
+// ------------------------------------------------
+
+func oggPage(serial uint32, packets ...[]byte) []byte {
+	var (
+		lacing []byte
+		body   []byte
+	)
+	for _, p := range packets {
+		l := len(p)
+		for ; l >= 255; l -= 255 {
+			lacing = append(lacing, 255)
+		}
+		lacing = append(lacing, byte(l))
+		body = append(body, p...)
+	}
+	header := make([]byte, oggPageHeaderLen)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint32(header[14:], serial)
+	header[26] = byte(len(lacing))
+	return append(append(header, lacing...), body...)
+}
+
+func ebml(id uint32, children ...[]byte) []byte {
+	var out []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> uint(shift)); b != 0 || len(out) > 0 {
+			out = append(out, b)
+		}
+	}
+	payload := bytes.Join(children, nil)
+	// 8 byte size
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(payload)))
+	size[0] = 0x01
+	return append(append(out, size...), payload...)
+}
+
+func unknownSize(element []byte) []byte {
+	idLen := ebmlVarintLen(element[0])
+	copy(element[idLen:], []byte{0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	return element
+}
+
+func mp4Box4(boxType string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	return append(append(u32(uint32(8+len(payload))), boxType...), payload...)
+}
+
+func fullBox(version byte, fields ...[]byte) []byte {
+	return append([]byte{version, 0, 0, 0}, bytes.Join(fields, nil)...)
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func lengthPrefixed(nalus ...[]byte) []byte {
+	var out []byte
+	for _, n := range nalus {
+		out = append(append(out, u32(uint32(len(n)))...), n...)
+	}
+	return out
+}
@@ -0,0 +1,400 @@
+package playback
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var errInvalidAVCConfig = errors.New("invalid avcC configuration")
+
+var h264Codec = webrtc.RTPCodecCapability{
+	MimeType:    webrtc.MimeTypeH264,
+	ClockRate:   90000,
+	SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+}
+
+var annexBStartCode = []byte{0, 0, 0, 1}
+
+type mp4Box struct {
+	boxType string
+	payload []byte
+}
+
+// readMP4Boxes splits data into ISO BMFF boxes
+func readMP4Boxes(data []byte) []mp4Box {
+	var boxes []mp4Box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		boxType := string(data[4:8])
+		headerLen := uint64(8)
+		switch size {
+		case 0:
+			// extends to end of file
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerLen = 16
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			return boxes
+		}
+		boxes = append(boxes, mp4Box{boxType: boxType, payload: data[headerLen:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+func findMP4Box(boxes []mp4Box, path ...string) *mp4Box {
+	for i := range boxes {
+		if boxes[i].boxType != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return &boxes[i]
+		}
+		if b := findMP4Box(readMP4Boxes(boxes[i].payload), path[1:]...); b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// parseMP4 reads progressive (non-fragmented) MP4 files using the sample tables of each track.
+// Samples are kept in decode order and timed by their decode timestamps
+func parseMP4(data []byte) (*Media, error) {
+	top := readMP4Boxes(data)
+	moov := findMP4Box(top, "moov")
+	if moov == nil {
+		return nil, ErrTruncated
+	}
+
+	m := &Media{}
+	for _, trak := range readMP4Boxes(moov.payload) {
+		if trak.boxType != "trak" {
+			continue
+		}
+		t, err := parseMP4Track(data, readMP4Boxes(trak.payload))
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			m.Tracks = append(m.Tracks, t)
+		}
+	}
+	if len(m.Tracks) == 0 {
+		if findMP4Box(top, "moof") != nil {
+			// fragmented files carry samples in movie fragments
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, ErrNoPlayableTracks
+	}
+	return m, nil
+}
+
+func parseMP4Track(data []byte, trak []mp4Box) (*MediaTrack, error) {
+	mdhd := findMP4Box(trak, "mdia", "mdhd")
+	stbl := findMP4Box(trak, "mdia", "minf", "stbl")
+	if mdhd == nil || stbl == nil || len(mdhd.payload) < 4 {
+		return nil, ErrTruncated
+	}
+
+	var timescale uint32
+	if mdhd.payload[0] == 1 {
+		if len(mdhd.payload) < 24 {
+			return nil, ErrTruncated
+		}
+		timescale = binary.BigEndian.Uint32(mdhd.payload[20:24])
+	} else {
+		if len(mdhd.payload) < 16 {
+			return nil, ErrTruncated
+		}
+		timescale = binary.BigEndian.Uint32(mdhd.payload[12:16])
+	}
+	if timescale == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+
+	tables := readMP4Boxes(stbl.payload)
+	stsd := findMP4Box(tables, "stsd")
+	if stsd == nil || len(stsd.payload) < 8 {
+		return nil, ErrTruncated
+	}
+	entries := readMP4Boxes(stsd.payload[8:])
+	if len(entries) == 0 {
+		return nil, ErrTruncated
+	}
+
+	// sample entry header lengths, ISO/IEC 14496-12, 12.1.3 and 12.2.3
+	const (
+		visualSampleEntryLen = 78
+		audioSampleEntryLen  = 28
+	)
+	var (
+		codec webrtc.RTPCodecCapability
+		avc   *avcConfig
+	)
+	entry := entries[0]
+	switch entry.boxType {
+	case "avc1", "avc3":
+		if len(entry.payload) < visualSampleEntryLen {
+			return nil, ErrTruncated
+		}
+		avcC := findMP4Box(readMP4Boxes(entry.payload[visualSampleEntryLen:]), "avcC")
+		if avcC == nil {
+			return nil, errInvalidAVCConfig
+		}
+		var err error
+		if avc, err = parseAVCConfig(avcC.payload); err != nil {
+			return nil, err
+		}
+		codec = h264Codec
+	case "vp08":
+		codec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}
+	case "vp09":
+		codec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000}
+	case "av01":
+		codec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeAV1, ClockRate: 90000}
+	case "Opus":
+		if len(entry.payload) < audioSampleEntryLen {
+			return nil, ErrTruncated
+		}
+		codec = opusCodec
+	default:
+		// e.g. AAC audio, which cannot be published
+		return nil, nil
+	}
+
+	sizes, err := readMP4SampleSizes(findMP4Box(tables, "stsz"))
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := readMP4SampleOffsets(tables, sizes)
+	if err != nil {
+		return nil, err
+	}
+	durations := readMP4Table(findMP4Box(tables, "stts"), 8)
+	var keyframes map[uint32]bool
+	if stss := findMP4Box(tables, "stss"); stss != nil {
+		keyframes = make(map[uint32]bool)
+		for _, e := range readMP4Table(stss, 4) {
+			keyframes[e[0]] = true
+		}
+	}
+
+	track := &MediaTrack{Codec: codec}
+	var (
+		dts      uint64
+		sttsIdx  int
+		sttsLeft uint32
+	)
+	if len(durations) > 0 {
+		sttsLeft = durations[0][0]
+	}
+	for i, size := range sizes {
+		offset := offsets[i]
+		if offset+uint64(size) > uint64(len(data)) {
+			// keep what is available of partially downloaded files
+			break
+		}
+		frame := data[offset : offset+uint64(size)]
+		if avc != nil {
+			frame = avc.toAnnexB(frame)
+		}
+
+		keyframe := keyframes == nil || keyframes[uint32(i+1)]
+		if codec.MimeType == webrtc.MimeTypeVP8 || codec.MimeType == webrtc.MimeTypeVP9 {
+			keyframe = isKeyframe(codec.MimeType, frame)
+		}
+		track.Samples = append(track.Samples, Sample{
+			Data:     frame,
+			PTS:      time.Duration(dts) * time.Second / time.Duration(timescale),
+			Keyframe: keyframe,
+		})
+
+		for sttsLeft == 0 && sttsIdx+1 < len(durations) {
+			sttsIdx++
+			sttsLeft = durations[sttsIdx][0]
+		}
+		if sttsIdx < len(durations) {
+			dts += uint64(durations[sttsIdx][1])
+			if sttsLeft > 0 {
+				sttsLeft--
+			}
+		}
+	}
+	return track, nil
+}
+
+// readMP4Table reads the uint32 columns of a full box table with an entry count
+func readMP4Table(box *mp4Box, entryLen int) [][]uint32 {
+	if box == nil || len(box.payload) < 8 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(box.payload[4:8]))
+	body := box.payload[8:]
+	if count > len(body)/entryLen {
+		count = len(body) / entryLen
+	}
+	table := make([][]uint32, 0, count)
+	for i := 0; i < count; i++ {
+		row := make([]uint32, entryLen/4)
+		for j := range row {
+			row[j] = binary.BigEndian.Uint32(body[i*entryLen+j*4:])
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+func readMP4SampleSizes(stsz *mp4Box) ([]uint32, error) {
+	if stsz == nil || len(stsz.payload) < 12 {
+		return nil, ErrTruncated
+	}
+	sampleSize := binary.BigEndian.Uint32(stsz.payload[4:8])
+	count := int(binary.BigEndian.Uint32(stsz.payload[8:12]))
+	body := stsz.payload[12:]
+	if sampleSize == 0 && count > len(body)/4 {
+		return nil, ErrTruncated
+	}
+
+	sizes := make([]uint32, count)
+	for i := range sizes {
+		if sampleSize != 0 {
+			sizes[i] = sampleSize
+		} else {
+			sizes[i] = binary.BigEndian.Uint32(body[i*4:])
+		}
+	}
+	return sizes, nil
+}
+
+func readMP4SampleOffsets(tables []mp4Box, sizes []uint32) ([]uint64, error) {
+	var chunks []uint64
+	if stco := findMP4Box(tables, "stco"); stco != nil {
+		for _, e := range readMP4Table(stco, 4) {
+			chunks = append(chunks, uint64(e[0]))
+		}
+	} else if co64 := findMP4Box(tables, "co64"); co64 != nil {
+		for _, e := range readMP4Table(co64, 8) {
+			chunks = append(chunks, uint64(e[0])<<32|uint64(e[1]))
+		}
+	} else {
+		return nil, ErrTruncated
+	}
+
+	// stsc: first_chunk, samples_per_chunk, sample_description_index
+	stsc := readMP4Table(findMP4Box(tables, "stsc"), 12)
+	numSamples := len(sizes)
+	offsets := make([]uint64, 0, numSamples)
+	sample := 0
+	for c := range chunks {
+		samplesPerChunk := uint32(0)
+		for _, e := range stsc {
+			if e[0] > uint32(c+1) {
+				break
+			}
+			samplesPerChunk = e[1]
+		}
+
+		offset := chunks[c]
+		for s := uint32(0); s < samplesPerChunk && sample < numSamples; s++ {
+			offsets = append(offsets, offset)
+			offset += uint64(sizes[sample])
+			sample++
+		}
+	}
+	if len(offsets) < numSamples {
+		return nil, ErrTruncated
+	}
+	return offsets, nil
+}
+
+// ------------------------------------------------
+
+// avcConfig is the AVCDecoderConfigurationRecord, ISO/IEC 14496-15, 5.3.3.1
+type avcConfig struct {
+	lengthSize int
+	sps        [][]byte
+	pps        [][]byte
+}
+
+func parseAVCConfig(data []byte) (*avcConfig, error) {
+	if len(data) < 6 {
+		return nil, errInvalidAVCConfig
+	}
+	c := &avcConfig{lengthSize: int(data[4]&0x3) + 1}
+
+	readSets := func(data []byte, count int) ([][]byte, []byte, error) {
+		var sets [][]byte
+		for i := 0; i < count; i++ {
+			if len(data) < 2 {
+				return nil, nil, errInvalidAVCConfig
+			}
+			l := int(binary.BigEndian.Uint16(data[0:2]))
+			if len(data) < 2+l {
+				return nil, nil, errInvalidAVCConfig
+			}
+			sets = append(sets, data[2:2+l])
+			data = data[2+l:]
+		}
+		return sets, data, nil
+	}
+
+	var err error
+	rest := data[6:]
+	if c.sps, rest, err = readSets(rest, int(data[5]&0x1f)); err != nil {
+		return nil, err
+	}
+	if len(rest) < 1 {
+		return nil, errInvalidAVCConfig
+	}
+	if c.pps, _, err = readSets(rest[1:], int(rest[0])); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// toAnnexB converts a length prefixed sample to start code delimited NAL units, inserting parameter sets before IDR frames
+func (c *avcConfig) toAnnexB(sample []byte) []byte {
+	var (
+		out       []byte
+		hasParams bool
+	)
+	for len(sample) >= c.lengthSize {
+		l := 0
+		for _, b := range sample[:c.lengthSize] {
+			l = l<<8 | int(b)
+		}
+		sample = sample[c.lengthSize:]
+		if l > len(sample) {
+			break
+		}
+		nalu := sample[:l]
+		sample = sample[l:]
+		if len(nalu) == 0 {
+			continue
+		}
+
+		switch nalu[0] & 0x1f {
+		case 7, 8:
+			hasParams = true
+		case 5:
+			if !hasParams {
+				for _, ps := range append(append([][]byte{}, c.sps...), c.pps...) {
+					out = append(out, annexBStartCode...)
+					out = append(out, ps...)
+				}
+				hasParams = true
+			}
+		}
+		out = append(out, annexBStartCode...)
+		out = append(out, nalu...)
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package playback
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const oggPageHeaderLen = 27
+
+var opusCodec = webrtc.RTPCodecCapability{
+	MimeType:    webrtc.MimeTypeOpus,
+	ClockRate:   48000,
+	Channels:    2,
+	SDPFmtpLine: "minptime=10;useinbandfec=1",
+}
+
+// parseOgg reads the first Opus logical stream of an Ogg file
+func parseOgg(data []byte) (*Media, error) {
+	track := &MediaTrack{Codec: opusCodec}
+
+	var (
+		serial     uint32
+		hasStream  bool
+		numPackets int
+		pending    []byte
+		pts        time.Duration
+	)
+	for len(data) > 0 {
+		if len(data) < oggPageHeaderLen || !bytes.HasPrefix(data, []byte("OggS")) {
+			return nil, ErrTruncated
+		}
+		pageSerial := binary.LittleEndian.Uint32(data[14:18])
+		numSegments := int(data[26])
+		if len(data) < oggPageHeaderLen+numSegments {
+			return nil, ErrTruncated
+		}
+		lacing := data[oggPageHeaderLen : oggPageHeaderLen+numSegments]
+		body := data[oggPageHeaderLen+numSegments:]
+
+		bodyLen := 0
+		for _, l := range lacing {
+			bodyLen += int(l)
+		}
+		if len(body) < bodyLen {
+			return nil, ErrTruncated
+		}
+		data = body[bodyLen:]
+
+		if !hasStream {
+			if !bytes.HasPrefix(body, []byte("OpusHead")) {
+				// not an opus stream, skip
+				continue
+			}
+			serial = pageSerial
+			hasStream = true
+		} else if pageSerial != serial {
+			continue
+		}
+
+		offset := 0
+		for _, l := range lacing {
+			pending = append(pending, body[offset:offset+int(l)]...)
+			offset += int(l)
+			if l == 255 {
+				// packet continues in next segment
+				continue
+			}
+
+			// first two packets are the OpusHead and OpusTags headers
+			if numPackets >= 2 && len(pending) > 0 {
+				track.Samples = append(track.Samples, Sample{
+					Data:     pending,
+					PTS:      pts,
+					Keyframe: true,
+				})
+				pts += opusPacketDuration(pending)
+			}
+			numPackets++
+			pending = nil
+		}
+	}
+
+	if !hasStream {
+		return nil, ErrUnsupportedCodec
+	}
+	return &Media{Tracks: []*MediaTrack{track}}, nil
+}
+
+// opusPacketDuration decodes the duration of an Opus packet from its TOC byte as defined in RFC 6716, section 3.1
+func opusPacketDuration(packet []byte) time.Duration {
+	if len(packet) == 0 {
+		return 0
+	}
+
+	toc := packet[0]
+	config := toc >> 3
+	var frameDuration time.Duration
+	switch {
+	case config < 12:
+		// SILK
+		frameDuration = []time.Duration{10, 20, 40, 60}[config%4] * time.Millisecond
+	case config < 16:
+		// hybrid
+		frameDuration = []time.Duration{10, 20}[config%2] * time.Millisecond
+	default:
+		// CELT
+		frameDuration = []time.Duration{2500, 5000, 10000, 20000}[config%4] * time.Microsecond
+	}
+
+	var numFrames int
+	switch toc & 0x3 {
+	case 0:
+		numFrames = 1
+	case 1, 2:
+		numFrames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0
+		}
+		numFrames = int(packet[1] & 0x3f)
+	}
+	return frameDuration * time.Duration(numFrames)
+}
@@ -0,0 +1,336 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	joinTimeout         = 10 * time.Second
+	trackPublishTimeout = 5 * time.Second
+)
+
+var (
+	ErrJoinTimeout        = errors.New("timed out joining room")
+	ErrPublishTimeout     = errors.New("timed out publishing track")
+	ErrConnectionClosed   = errors.New("signal connection closed")
+	ErrUnexpectedResponse = errors.New("unexpected signal response")
+)
+
+// SignalConnector starts the signal connection of a participant in a room, possibly on another node
+type SignalConnector func(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit) (routing.MessageSink, routing.MessageSource, error)
+
+type ParticipantParams struct {
+	RoomName livekit.RoomName
+	Identity livekit.ParticipantIdentity
+	Name     livekit.ParticipantName
+	Metadata string
+	Connect  SignalConnector
+	Logger   logger.Logger
+}
+
+// Participant is a publish only participant that runs inside the server, speaking the signal protocol
+// over the router and sending media over a regular peer connection
+type Participant struct {
+	params    ParticipantParams
+	ctx       context.Context
+	cancel    context.CancelFunc
+	publisher *rtc.PCTransport
+	sink      routing.MessageSink
+	source    routing.MessageSource
+
+	lock           sync.Mutex
+	info           *livekit.ParticipantInfo
+	pendingTracks  map[string]chan *livekit.TrackInfo
+	onConnected    func()
+	onDisconnected func()
+	onQualities    func(update *livekit.SubscribedQualityUpdate)
+	connected      atomic.Bool
+	closed         atomic.Bool
+}
+
+func NewParticipant(params ParticipantParams, codecs []webrtc.RTPCodecCapability) (*Participant, error) {
+	p := &Participant{
+		params:        params,
+		pendingTracks: make(map[string]chan *livekit.TrackInfo),
+	}
+	// the signal connection outlives the request that started the participant
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	var enabledCodecs []*livekit.Codec
+	for _, c := range codecs {
+		enabledCodecs = append(enabledCodecs, &livekit.Codec{Mime: c.MimeType, FmtpLine: c.SDPFmtpLine})
+	}
+
+	conf := rtc.WebRTCConfig{}
+	conf.SettingEngine.SetLite(false)
+	var err error
+	p.publisher, err = rtc.NewPCTransport(rtc.TransportParams{
+		ParticipantIdentity: params.Identity,
+		ProtocolVersion:     types.CurrentProtocol,
+		Config:              &conf,
+		EnabledCodecs:       enabledCodecs,
+		Logger:              params.Logger,
+		IsOfferer:           true,
+		IsSendSide:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// signal targets are named from the server's point of view
+	p.publisher.OnICECandidate(func(c *webrtc.ICECandidate) error {
+		if c == nil {
+			return nil
+		}
+		trickle := rtc.ToProtoTrickle(c.ToJSON())
+		trickle.Target = livekit.SignalTarget_PUBLISHER
+		return p.sendRequest(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Trickle{Trickle: trickle},
+		})
+	})
+	p.publisher.OnOffer(func(offer webrtc.SessionDescription) error {
+		return p.sendRequest(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Offer{Offer: rtc.ToProtoSessionDescription(offer)},
+		})
+	})
+	p.publisher.OnInitialConnected(func() {
+		p.connected.Store(true)
+		p.lock.Lock()
+		onConnected := p.onConnected
+		p.lock.Unlock()
+		if onConnected != nil {
+			onConnected()
+		}
+	})
+	p.publisher.OnFailed(func(_ bool) {
+		p.params.Logger.Warnw("playback participant connection failed", nil)
+		p.Close()
+	})
+	return p, nil
+}
+
+// OnConnected is called once media can flow
+func (p *Participant) OnConnected(f func()) {
+	p.lock.Lock()
+	p.onConnected = f
+	p.lock.Unlock()
+}
+
+// OnDisconnected is called once the participant has left the room
+func (p *Participant) OnDisconnected(f func()) {
+	p.lock.Lock()
+	p.onDisconnected = f
+	p.lock.Unlock()
+}
+
+// OnSubscribedQualityUpdate is called with the qualities of a published track subscribers need, as dynacast
+// signals them to clients. A source can stop producing layers not needed, or the track when none is
+func (p *Participant) OnSubscribedQualityUpdate(f func(update *livekit.SubscribedQualityUpdate)) {
+	p.lock.Lock()
+	p.onQualities = f
+	p.lock.Unlock()
+}
+
+func (p *Participant) Info() *livekit.ParticipantInfo {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.info
+}
+
+// Join joins the room and waits for the join response, ctx bounds the wait only
+func (p *Participant) Join(ctx context.Context) error {
+	grants := &auth.ClaimGrants{
+		Identity: string(p.params.Identity),
+		Name:     string(p.params.Name),
+		Metadata: p.params.Metadata,
+		Video: &auth.VideoGrant{
+			RoomJoin: true,
+			Room:     string(p.params.RoomName),
+		},
+	}
+	// publish only, keeps the publisher transport primary
+	grants.Video.SetCanSubscribe(false)
+	sink, source, err := p.params.Connect(p.ctx, p.params.RoomName, routing.ParticipantInit{
+		Identity: p.params.Identity,
+		Name:     p.params.Name,
+		Client: &livekit.ClientInfo{
+			Sdk:      livekit.ClientInfo_GO,
+			Protocol: int32(types.CurrentProtocol),
+		},
+		Grants: grants,
+	})
+	if err != nil {
+		p.Close()
+		return err
+	}
+	p.sink = sink
+	p.source = source
+
+	timer := time.NewTimer(joinTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		p.Close()
+		return ctx.Err()
+	case <-timer.C:
+		p.Close()
+		return ErrJoinTimeout
+	case msg := <-source.ReadChan():
+		res, ok := msg.(*livekit.SignalResponse)
+		if !ok || res.GetJoin() == nil {
+			p.Close()
+			if msg == nil {
+				return ErrConnectionClosed
+			}
+			return ErrUnexpectedResponse
+		}
+		p.lock.Lock()
+		p.info = res.GetJoin().Participant
+		p.lock.Unlock()
+	}
+
+	go p.readResponses()
+	return nil
+}
+
+// PublishTrack announces a track and adds it to the peer connection once the server has accepted it
+func (p *Participant) PublishTrack(track *webrtc.TrackLocalStaticSample, req *livekit.AddTrackRequest) (*livekit.TrackInfo, error) {
+	published := make(chan *livekit.TrackInfo, 1)
+	req.Cid = track.ID()
+	p.lock.Lock()
+	p.pendingTracks[req.Cid] = published
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.pendingTracks, req.Cid)
+		p.lock.Unlock()
+	}()
+
+	if err := p.sendRequest(&livekit.SignalRequest{
+		Message: &livekit.SignalRequest_AddTrack{AddTrack: req},
+	}); err != nil {
+		return nil, err
+	}
+
+	var ti *livekit.TrackInfo
+	select {
+	case ti = <-published:
+	case <-time.After(trackPublishTimeout):
+		return nil, ErrPublishTimeout
+	}
+
+	if _, _, err := p.publisher.AddTrack(track, types.AddTrackParams{}); err != nil {
+		return nil, err
+	}
+	return ti, nil
+}
+
+// Negotiate starts negotiation after tracks are published
+func (p *Participant) Negotiate() {
+	p.publisher.Negotiate(false)
+}
+
+func (p *Participant) IsConnected() bool {
+	return p.connected.Load()
+}
+
+func (p *Participant) Close() {
+	if p.closed.Swap(true) {
+		return
+	}
+
+	if p.sink != nil {
+		_ = p.sendRequest(&livekit.SignalRequest{
+			Message: &livekit.SignalRequest_Leave{Leave: &livekit.LeaveRequest{}},
+		})
+		p.sink.Close()
+	}
+	if p.source != nil {
+		p.source.Close()
+	}
+	p.publisher.Close()
+	p.cancel()
+
+	p.lock.Lock()
+	onDisconnected := p.onDisconnected
+	p.lock.Unlock()
+	if onDisconnected != nil {
+		onDisconnected()
+	}
+}
+
+func (p *Participant) sendRequest(req *livekit.SignalRequest) error {
+	if p.sink == nil {
+		return ErrConnectionClosed
+	}
+	return p.sink.WriteMessage(req)
+}
+
+func (p *Participant) readResponses() {
+	defer p.Close()
+
+	for msg := range p.source.ReadChan() {
+		res, ok := msg.(*livekit.SignalResponse)
+		if !ok {
+			continue
+		}
+
+		switch m := res.Message.(type) {
+		case *livekit.SignalResponse_Answer:
+			p.publisher.HandleRemoteDescription(rtc.FromProtoSessionDescription(m.Answer))
+
+		case *livekit.SignalResponse_Trickle:
+			if m.Trickle.Target != livekit.SignalTarget_PUBLISHER {
+				continue
+			}
+			candidate, err := rtc.FromProtoTrickle(m.Trickle)
+			if err != nil {
+				p.params.Logger.Warnw("could not decode ICE candidate", err)
+				continue
+			}
+			p.publisher.AddICECandidate(candidate)
+
+		case *livekit.SignalResponse_TrackPublished:
+			p.lock.Lock()
+			if published := p.pendingTracks[m.TrackPublished.Cid]; published != nil {
+				select {
+				case published <- m.TrackPublished.Track:
+				default:
+				}
+			}
+			p.lock.Unlock()
+
+		case *livekit.SignalResponse_SubscribedQualityUpdate:
+			p.lock.Lock()
+			onQualities := p.onQualities
+			p.lock.Unlock()
+			if onQualities != nil {
+				onQualities(m.SubscribedQualityUpdate)
+			}
+
+		case *livekit.SignalResponse_Leave:
+			p.params.Logger.Infow("playback participant asked to leave", "reason", m.Leave.Reason)
+			return
+
+		case *livekit.SignalResponse_Offer:
+			// cannot subscribe, the subscriber peer connection is never used
+			p.params.Logger.Debugw("ignoring subscriber offer")
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package playback
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/frostbyte73/core"
+	"github.com/pion/webrtc/v3/pkg/media"
+
+	"github.com/livekit/protocol/logger"
+)
+
+var ErrInvalidPosition = errors.New("position is beyond the end of media")
+
+type PlayerState string
+
+const (
+	PlayerStatePending PlayerState = "pending"
+	PlayerStatePlaying PlayerState = "playing"
+	PlayerStatePaused  PlayerState = "paused"
+	PlayerStateEnded   PlayerState = "ended"
+)
+
+// SampleWriter receives the samples of a track, implemented by *webrtc.TrackLocalStaticSample
+type SampleWriter interface {
+	WriteSample(s media.Sample) error
+}
+
+type PlayerParams struct {
+	Media *Media
+	// one writer per media track
+	Writers []SampleWriter
+	Loop    bool
+	Logger  logger.Logger
+}
+
+// Player paces the samples of all tracks of a media against a shared clock
+type Player struct {
+	params   PlayerParams
+	duration time.Duration
+
+	lock      sync.Mutex
+	state     PlayerState
+	loop      bool
+	base      time.Duration
+	startedAt time.Time
+	seekTo    *time.Duration
+	onEnded   func()
+
+	wake chan struct{}
+	done core.Fuse
+}
+
+func NewPlayer(params PlayerParams) *Player {
+	return &Player{
+		params:   params,
+		duration: params.Media.Duration(),
+		state:    PlayerStatePending,
+		loop:     params.Loop,
+		wake:     make(chan struct{}, 1),
+		done:     core.NewFuse(),
+	}
+}
+
+// OnEnded is called once a started player reaches the end of media without looping, or is stopped
+func (p *Player) OnEnded(f func()) {
+	p.lock.Lock()
+	p.onEnded = f
+	p.lock.Unlock()
+}
+
+func (p *Player) Start() {
+	p.lock.Lock()
+	if p.state != PlayerStatePending {
+		p.lock.Unlock()
+		return
+	}
+	p.state = PlayerStatePlaying
+	p.startedAt = time.Now()
+	p.lock.Unlock()
+
+	go p.run()
+}
+
+func (p *Player) Pause() {
+	p.lock.Lock()
+	if p.state == PlayerStatePlaying {
+		p.base = p.positionLocked()
+		p.state = PlayerStatePaused
+	}
+	p.lock.Unlock()
+	p.notify()
+}
+
+func (p *Player) Resume() {
+	p.lock.Lock()
+	if p.state == PlayerStatePaused {
+		p.state = PlayerStatePlaying
+		p.startedAt = time.Now()
+	}
+	p.lock.Unlock()
+	p.notify()
+}
+
+func (p *Player) Seek(pos time.Duration) error {
+	if pos < 0 || pos >= p.duration {
+		return ErrInvalidPosition
+	}
+
+	p.lock.Lock()
+	p.seekTo = &pos
+	p.lock.Unlock()
+	p.notify()
+	return nil
+}
+
+func (p *Player) SetLoop(loop bool) {
+	p.lock.Lock()
+	p.loop = loop
+	p.lock.Unlock()
+}
+
+func (p *Player) Loop() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.loop
+}
+
+func (p *Player) Stop() {
+	p.done.Break()
+}
+
+func (p *Player) State() PlayerState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.state
+}
+
+func (p *Player) Duration() time.Duration {
+	return p.duration
+}
+
+// Position returns the current media position
+func (p *Player) Position() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.positionLocked()
+}
+
+func (p *Player) positionLocked() time.Duration {
+	if p.state != PlayerStatePlaying {
+		return p.base
+	}
+	pos := p.base + time.Since(p.startedAt)
+	if pos > p.duration {
+		return p.duration
+	}
+	return pos
+}
+
+func (p *Player) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Player) run() {
+	tracks := p.params.Media.Tracks
+	idx := make([]int, len(tracks))
+	defer p.end()
+
+	for {
+		p.lock.Lock()
+		if p.seekTo != nil {
+			p.seekLocked(idx, *p.seekTo)
+			p.seekTo = nil
+		}
+		state, base, startedAt := p.state, p.base, p.startedAt
+		p.lock.Unlock()
+
+		if state == PlayerStatePaused {
+			select {
+			case <-p.done.Watch():
+				return
+			case <-p.wake:
+				continue
+			}
+		}
+
+		// next sample in presentation order across tracks
+		next := -1
+		for i, t := range tracks {
+			if idx[i] >= len(t.Samples) {
+				continue
+			}
+			if next < 0 || t.Samples[idx[i]].PTS < tracks[next].Samples[idx[next]].PTS {
+				next = i
+			}
+		}
+		if next < 0 {
+			if !p.restart(idx) {
+				return
+			}
+			continue
+		}
+
+		t := tracks[next]
+		sample := t.Samples[idx[next]]
+		if wait := time.Until(startedAt.Add(sample.PTS - base)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-p.done.Watch():
+				timer.Stop()
+				return
+			case <-p.wake:
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+		}
+
+		// write under the lock so that no sample goes out once Pause or Seek return
+		p.lock.Lock()
+		if p.state != state || p.seekTo != nil {
+			p.lock.Unlock()
+			continue
+		}
+		err := p.params.Writers[next].WriteSample(media.Sample{
+			Data:     sample.Data,
+			Duration: t.frameDuration(idx[next]),
+		})
+		p.lock.Unlock()
+		if err != nil {
+			p.params.Logger.Debugw("could not write sample", "error", err, "mime", t.Codec.MimeType)
+		}
+		idx[next]++
+	}
+}
+
+// seekLocked moves all tracks to the keyframe before pos so that video can be decoded from there
+func (p *Player) seekLocked(idx []int, pos time.Duration) {
+	target := pos
+	for _, t := range p.params.Media.Tracks {
+		if kf := t.keyframeBefore(pos); kf < target {
+			target = kf
+		}
+	}
+	for i, t := range p.params.Media.Tracks {
+		idx[i] = t.seekIndex(target)
+	}
+	p.base = target
+	p.startedAt = time.Now()
+}
+
+// restart rewinds to the beginning when looping, returns false when playback has ended
+func (p *Player) restart(idx []int) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.loop {
+		return false
+	}
+	for i := range idx {
+		idx[i] = 0
+	}
+	// media time 0 continues where this pass ended
+	p.startedAt = p.startedAt.Add(p.duration - p.base)
+	p.base = 0
+	return true
+}
+
+func (p *Player) end() {
+	p.lock.Lock()
+	p.base = p.positionLocked()
+	p.state = PlayerStateEnded
+	onEnded := p.onEnded
+	p.lock.Unlock()
+
+	p.done.Break()
+	if onEnded != nil {
+		onEnded()
+	}
+}
@@ -0,0 +1,141 @@
+package playback
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+type sampleRecorder struct {
+	lock    sync.Mutex
+	samples []media.Sample
+}
+
+func (r *sampleRecorder) WriteSample(s media.Sample) error {
+	r.lock.Lock()
+	r.samples = append(r.samples, s)
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *sampleRecorder) data() []byte {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var data []byte
+	for _, s := range r.samples {
+		data = append(data, s.Data...)
+	}
+	return data
+}
+
+// testMedia has a video track with a keyframe every 5 samples and an audio track, 10ms per sample
+func testMedia(numSamples int) *Media {
+	video := &MediaTrack{Codec: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}}
+	audio := &MediaTrack{Codec: opusCodec}
+	for i := 0; i < numSamples; i++ {
+		pts := time.Duration(i) * 10 * time.Millisecond
+		video.Samples = append(video.Samples, Sample{Data: []byte{byte(i)}, PTS: pts, Keyframe: i%5 == 0})
+		audio.Samples = append(audio.Samples, Sample{Data: []byte{byte(i)}, PTS: pts, Keyframe: true})
+	}
+	return &Media{Tracks: []*MediaTrack{video, audio}}
+}
+
+func newTestPlayer(m *Media, loop bool) (*Player, *sampleRecorder, *sampleRecorder) {
+	video, audio := &sampleRecorder{}, &sampleRecorder{}
+	return NewPlayer(PlayerParams{
+		Media:   m,
+		Writers: []SampleWriter{video, audio},
+		Loop:    loop,
+		Logger:  logger.GetLogger(),
+	}), video, audio
+}
+
+func TestPlayer(t *testing.T) {
+	t.Run("plays to the end", func(t *testing.T) {
+		p, video, audio := newTestPlayer(testMedia(10), false)
+		ended := make(chan struct{})
+		p.OnEnded(func() { close(ended) })
+		require.Equal(t, 100*time.Millisecond, p.Duration())
+
+		start := time.Now()
+		p.Start()
+		select {
+		case <-ended:
+		case <-time.After(time.Second):
+			t.Fatal("player did not end")
+		}
+		// samples are paced in real time
+		require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+		require.Equal(t, PlayerStateEnded, p.State())
+		require.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, video.data())
+		require.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, audio.data())
+		require.Equal(t, 10*time.Millisecond, video.samples[9].Duration)
+	})
+
+	t.Run("pause and resume", func(t *testing.T) {
+		p, video, _ := newTestPlayer(testMedia(100), false)
+		p.Pause()
+		require.Equal(t, PlayerStatePending, p.State())
+
+		p.Start()
+		time.Sleep(50 * time.Millisecond)
+		p.Pause()
+		require.Equal(t, PlayerStatePaused, p.State())
+		pos := p.Position()
+		written := len(video.data())
+
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, pos, p.Position())
+		require.Equal(t, written, len(video.data()))
+
+		p.Resume()
+		require.Eventually(t, func() bool { return len(video.data()) > written }, time.Second, 5*time.Millisecond)
+		p.Stop()
+	})
+
+	t.Run("seek starts from the previous keyframe", func(t *testing.T) {
+		p, video, audio := newTestPlayer(testMedia(100), false)
+		p.Start()
+		time.Sleep(20 * time.Millisecond)
+		p.Pause()
+
+		require.ErrorIs(t, p.Seek(2*time.Second), ErrInvalidPosition)
+		require.NoError(t, p.Seek(730*time.Millisecond))
+		require.Eventually(t, func() bool { return p.Position() == 700*time.Millisecond }, time.Second, 5*time.Millisecond)
+
+		before := len(video.data())
+		p.Resume()
+		require.Eventually(t, func() bool { return len(video.data()) > before+1 }, time.Second, 5*time.Millisecond)
+		p.Stop()
+
+		require.Equal(t, byte(70), video.data()[before])
+		require.Contains(t, audio.data(), byte(70))
+	})
+
+	t.Run("loops", func(t *testing.T) {
+		p, video, _ := newTestPlayer(testMedia(5), true)
+		ended := make(chan struct{})
+		p.OnEnded(func() { close(ended) })
+		p.Start()
+
+		require.Eventually(t, func() bool { return len(video.data()) >= 12 }, time.Second, 5*time.Millisecond)
+		require.Equal(t, []byte{0, 1, 2, 3, 4, 0, 1}, video.data()[:7])
+
+		// stops at the end of the current pass once looping is disabled
+		p.SetLoop(false)
+		select {
+		case <-ended:
+		case <-time.After(time.Second):
+			t.Fatal("player did not end")
+		}
+		data := video.data()
+		require.Equal(t, byte(4), data[len(data)-1])
+	})
+}
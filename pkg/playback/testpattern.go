@@ -0,0 +1,295 @@
+package playback
+
+import (
+	_ "embed"
+	"time"
+)
+
+// Test pattern is H.264 constrained baseline made of I_PCM macroblocks, so that it can be produced without an encoder.
+// Every second has an IDR frame with the marker moved, the frames in between skip all macroblocks.
+const (
+	testPatternWidthMbs  = 20
+	testPatternHeightMbs = 15
+	testPatternFPS       = 15
+	testPatternSeconds   = 8
+
+	testPatternWidth  = testPatternWidthMbs * 16
+	testPatternHeight = testPatternHeightMbs * 16
+	// bars take the top two thirds, the marker strip is in the last macroblock row
+	testPatternBarsHeight = testPatternHeight * 2 / 3
+	testPatternMarkerTop  = testPatternHeight - 16
+
+	h264NALTypeSlice = 1
+	h264NALTypeIDR   = 5
+	h264NALTypeSPS   = 7
+	h264NALTypePPS   = 8
+
+	h264MbTypeIPCM = 25
+)
+
+// testTone is one second of a 1 kHz sine at -18 dBFS, mono Opus in 20 ms CELT frames at 32 kbps CBR. There is no
+// Opus encoder in the server, it was encoded offline from the second second of a continuous tone, so that the
+// encoder has settled and the tone loops without a click
+//
+//go:embed testtone.opus
+var testTone []byte
+
+type yuv struct {
+	y, cb, cr byte
+}
+
+// 75% color bars, BT.601 video range
+var testPatternBars = []yuv{
+	{180, 128, 128}, // gray
+	{162, 44, 142},  // yellow
+	{131, 156, 44},  // cyan
+	{112, 72, 58},   // green
+	{84, 184, 198},  // magenta
+	{65, 100, 212},  // red
+	{35, 212, 114},  // blue
+}
+
+var (
+	testPatternBlack = yuv{16, 128, 128}
+	testPatternWhite = yuv{235, 128, 128}
+	// castellations below the bars
+	testPatternCastellations = []yuv{
+		{35, 212, 114},
+		{16, 128, 128},
+		{84, 184, 198},
+		{16, 128, 128},
+		{131, 156, 44},
+		{16, 128, 128},
+		{180, 128, 128},
+	}
+)
+
+// NewTestPattern returns a looping color bar video with a marker stepping along the bottom every second, and a tone
+func NewTestPattern() *Media {
+	track := &MediaTrack{Codec: h264Codec}
+	sps, pps := testPatternParameterSets()
+
+	frameDuration := time.Second / testPatternFPS
+	frameNum := 0
+	for s := 0; s < testPatternSeconds; s++ {
+		for f := 0; f < testPatternFPS; f++ {
+			pts := time.Duration(s)*time.Second + time.Duration(f)*frameDuration
+			if f == 0 {
+				frameNum = 0
+				data := append([]byte{}, sps...)
+				data = append(data, pps...)
+				data = append(data, testPatternIDR(s)...)
+				track.Samples = append(track.Samples, Sample{Data: data, PTS: pts, Keyframe: true})
+				continue
+			}
+
+			frameNum++
+			track.Samples = append(track.Samples, Sample{Data: testPatternSkipFrame(frameNum), PTS: pts})
+		}
+	}
+	return &Media{Tracks: []*MediaTrack{track, testPatternTone()}}
+}
+
+// testPatternTone repeats the tone for as long as the video lasts
+func testPatternTone() *MediaTrack {
+	tone, err := parseOgg(testTone)
+	if err != nil {
+		// the embedded tone is checked by tests
+		panic(err)
+	}
+
+	samples := tone.Tracks[0].Samples
+	track := &MediaTrack{Codec: opusCodec}
+	for s := 0; s < testPatternSeconds; s++ {
+		for _, sample := range samples {
+			sample.PTS += time.Duration(s) * time.Second
+			track.Samples = append(track.Samples, sample)
+		}
+	}
+	return track
+}
+
+func testPatternPixel(x, y, second int) yuv {
+	switch {
+	case y < testPatternBarsHeight:
+		return testPatternBars[x*len(testPatternBars)/testPatternWidth]
+	case y < testPatternMarkerTop:
+		return testPatternCastellations[x*len(testPatternCastellations)/testPatternWidth]
+	default:
+		segment := testPatternWidth / testPatternSeconds
+		if x/segment == second && x%segment >= 4 && x%segment < segment-4 && y >= testPatternMarkerTop+4 && y < testPatternHeight-4 {
+			return testPatternWhite
+		}
+		return testPatternBlack
+	}
+}
+
+func testPatternParameterSets() ([]byte, []byte) {
+	sps := &h264BitWriter{}
+	sps.writeBits(66, 8)   // profile_idc, baseline
+	sps.writeBits(0xe0, 8) // constraint_set0/1/2
+	sps.writeBits(31, 8)   // level_idc
+	sps.writeUE(0)         // seq_parameter_set_id
+	sps.writeUE(0)         // log2_max_frame_num_minus4
+	sps.writeUE(2)         // pic_order_cnt_type
+	sps.writeUE(1)         // max_num_ref_frames
+	sps.writeBits(0, 1)    // gaps_in_frame_num_value_allowed_flag
+	sps.writeUE(testPatternWidthMbs - 1)
+	sps.writeUE(testPatternHeightMbs - 1)
+	sps.writeBits(1, 1) // frame_mbs_only_flag
+	sps.writeBits(1, 1) // direct_8x8_inference_flag
+	sps.writeBits(0, 1) // frame_cropping_flag
+	sps.writeBits(0, 1) // vui_parameters_present_flag
+	sps.writeTrailingBits()
+
+	pps := &h264BitWriter{}
+	pps.writeUE(0)      // pic_parameter_set_id
+	pps.writeUE(0)      // seq_parameter_set_id
+	pps.writeBits(0, 1) // entropy_coding_mode_flag, CAVLC
+	pps.writeBits(0, 1) // bottom_field_pic_order_in_frame_present_flag
+	pps.writeUE(0)      // num_slice_groups_minus1
+	pps.writeUE(0)      // num_ref_idx_l0_default_active_minus1
+	pps.writeUE(0)      // num_ref_idx_l1_default_active_minus1
+	pps.writeBits(0, 1) // weighted_pred_flag
+	pps.writeBits(0, 2) // weighted_bipred_idc
+	pps.writeSE(0)      // pic_init_qp_minus26
+	pps.writeSE(0)      // pic_init_qs_minus26
+	pps.writeSE(0)      // chroma_qp_index_offset
+	pps.writeBits(1, 1) // deblocking_filter_control_present_flag
+	pps.writeBits(0, 1) // constrained_intra_pred_flag
+	pps.writeBits(0, 1) // redundant_pic_cnt_present_flag
+	pps.writeTrailingBits()
+
+	return h264NALU(3, h264NALTypeSPS, sps.bytes()), h264NALU(3, h264NALTypePPS, pps.bytes())
+}
+
+func testPatternIDR(second int) []byte {
+	w := &h264BitWriter{}
+	w.writeUE(0)                  // first_mb_in_slice
+	w.writeUE(7)                  // slice_type, I
+	w.writeUE(0)                  // pic_parameter_set_id
+	w.writeBits(0, 4)             // frame_num
+	w.writeUE(uint32(second % 2)) // idr_pic_id, differs between consecutive IDRs
+	w.writeBits(0, 1)             // no_output_of_prior_pics_flag
+	w.writeBits(0, 1)             // long_term_reference_flag
+	w.writeSE(0)                  // slice_qp_delta
+	w.writeUE(1)                  // disable_deblocking_filter_idc
+
+	for mbY := 0; mbY < testPatternHeightMbs; mbY++ {
+		for mbX := 0; mbX < testPatternWidthMbs; mbX++ {
+			w.writeUE(h264MbTypeIPCM)
+			w.align()
+			for y := 0; y < 16; y++ {
+				for x := 0; x < 16; x++ {
+					w.writeByte(testPatternPixel(mbX*16+x, mbY*16+y, second).y)
+				}
+			}
+			for _, cr := range []bool{false, true} {
+				for y := 0; y < 8; y++ {
+					for x := 0; x < 8; x++ {
+						p := testPatternPixel(mbX*16+x*2, mbY*16+y*2, second)
+						if cr {
+							w.writeByte(p.cr)
+						} else {
+							w.writeByte(p.cb)
+						}
+					}
+				}
+			}
+		}
+	}
+	w.writeTrailingBits()
+	return h264NALU(3, h264NALTypeIDR, w.bytes())
+}
+
+func testPatternSkipFrame(frameNum int) []byte {
+	w := &h264BitWriter{}
+	w.writeUE(0)                                          // first_mb_in_slice
+	w.writeUE(5)                                          // slice_type, P
+	w.writeUE(0)                                          // pic_parameter_set_id
+	w.writeBits(uint32(frameNum%16), 4)                   // frame_num
+	w.writeBits(0, 1)                                     // num_ref_idx_active_override_flag
+	w.writeBits(0, 1)                                     // ref_pic_list_modification_flag_l0
+	w.writeBits(0, 1)                                     // adaptive_ref_pic_marking_mode_flag
+	w.writeSE(0)                                          // slice_qp_delta
+	w.writeUE(1)                                          // disable_deblocking_filter_idc
+	w.writeUE(testPatternWidthMbs * testPatternHeightMbs) // mb_skip_run
+	w.writeTrailingBits()
+	return h264NALU(2, h264NALTypeSlice, w.bytes())
+}
+
+// h264NALU returns a start code prefixed NAL unit with emulation prevention applied to the RBSP
+func h264NALU(refIdc byte, nalType byte, rbsp []byte) []byte {
+	out := append([]byte{}, annexBStartCode...)
+	out = append(out, refIdc<<5|nalType)
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros == 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+type h264BitWriter struct {
+	buf   []byte
+	cur   byte
+	nbits uint
+}
+
+func (w *h264BitWriter) writeBits(v uint32, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i)&1)
+		w.nbits++
+		if w.nbits == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.nbits = 0, 0
+		}
+	}
+}
+
+func (w *h264BitWriter) writeByte(b byte) {
+	w.writeBits(uint32(b), 8)
+}
+
+// writeUE writes an unsigned Exp-Golomb code
+func (w *h264BitWriter) writeUE(v uint32) {
+	v++
+	n := uint(0)
+	for t := v; t > 1; t >>= 1 {
+		n++
+	}
+	w.writeBits(0, n)
+	w.writeBits(v, n+1)
+}
+
+// writeSE writes a signed Exp-Golomb code
+func (w *h264BitWriter) writeSE(v int32) {
+	if v > 0 {
+		w.writeUE(uint32(2*v - 1))
+	} else {
+		w.writeUE(uint32(-2 * v))
+	}
+}
+
+func (w *h264BitWriter) align() {
+	if w.nbits != 0 {
+		w.writeBits(0, 8-w.nbits)
+	}
+}
+
+func (w *h264BitWriter) writeTrailingBits() {
+	w.writeBits(1, 1)
+	w.align()
+}
+
+func (w *h264BitWriter) bytes() []byte {
+	return w.buf
+}
@@ -0,0 +1,70 @@
+package playback
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestH264BitWriter(t *testing.T) {
+	w := &h264BitWriter{}
+	// 1, 010, 011, 00100
+	for _, v := range []uint32{0, 1, 2, 3} {
+		w.writeUE(v)
+	}
+	w.writeTrailingBits()
+	require.Equal(t, []byte{0xa6, 0x48}, w.bytes())
+
+	w = &h264BitWriter{}
+	// 1, 010, 011
+	for _, v := range []int32{0, 1, -1} {
+		w.writeSE(v)
+	}
+	w.writeTrailingBits()
+	require.Equal(t, []byte{0xa7}, w.bytes())
+}
+
+func TestH264NALU(t *testing.T) {
+	nalu := h264NALU(3, h264NALTypeIDR, []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x04})
+	require.Equal(t, [][]byte{{0x65, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x00, 0x04}}, splitAnnexB(nalu))
+}
+
+func TestTestPattern(t *testing.T) {
+	m := NewTestPattern()
+	require.Len(t, m.Tracks, 2)
+
+	track := m.Tracks[0]
+	require.Equal(t, webrtc.MimeTypeH264, track.Codec.MimeType)
+	require.Len(t, track.Samples, testPatternSeconds*testPatternFPS)
+	require.InDelta(t, testPatternSeconds*time.Second, m.Duration(), float64(time.Millisecond))
+
+	for i, s := range track.Samples {
+		require.Equal(t, i%testPatternFPS == 0, s.Keyframe)
+		require.Equal(t, s.Keyframe, isH264Keyframe(s.Data))
+	}
+
+	nalus := splitAnnexB(track.Samples[0].Data)
+	require.Len(t, nalus, 3)
+	require.Equal(t, []byte{h264NALTypeSPS, h264NALTypePPS, h264NALTypeIDR},
+		[]byte{nalus[0][0] & 0x1f, nalus[1][0] & 0x1f, nalus[2][0] & 0x1f})
+	// profile-level-id matches the negotiated codec
+	require.Equal(t, []byte{0x42, 0xe0, 0x1f}, nalus[0][1:4])
+	// every macroblock carries 384 PCM samples
+	require.Greater(t, len(nalus[2]), testPatternWidthMbs*testPatternHeightMbs*384)
+
+	// marker moves every second
+	require.NotEqual(t, track.Samples[0].Data, track.Samples[testPatternFPS].Data)
+	require.Equal(t, testPatternWhite, testPatternPixel(testPatternWidth/testPatternSeconds+20, testPatternHeight-8, 1))
+	require.Equal(t, testPatternBlack, testPatternPixel(20, testPatternHeight-8, 1))
+
+	// the tone lasts as long as the video
+	tone := m.Tracks[1]
+	require.Equal(t, webrtc.MimeTypeOpus, tone.Codec.MimeType)
+	require.Len(t, tone.Samples, testPatternSeconds*50)
+	for i, s := range tone.Samples {
+		require.Equal(t, time.Duration(i)*20*time.Millisecond, s.PTS)
+		require.Equal(t, 20*time.Millisecond, opusPacketDuration(s.Data))
+	}
+}
@@ -0,0 +1,247 @@
+package playback
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Matroska element IDs, https://www.matroska.org/technical/elements.html
+const (
+	ebmlIDHeader          = 0x1a45dfa3
+	ebmlIDSegment         = 0x18538067
+	ebmlIDInfo            = 0x1549a966
+	ebmlIDTimecodeScale   = 0x2ad7b1
+	ebmlIDTracks          = 0x1654ae6b
+	ebmlIDTrackEntry      = 0xae
+	ebmlIDTrackNumber     = 0xd7
+	ebmlIDCodecID         = 0x86
+	ebmlIDCodecPrivate    = 0x63a2
+	ebmlIDCluster         = 0x1f43b675
+	ebmlIDClusterTimecode = 0xe7
+	ebmlIDSimpleBlock     = 0xa3
+	ebmlIDBlockGroup      = 0xa0
+	ebmlIDBlock           = 0xa1
+
+	defaultTimecodeScale = 1000000
+)
+
+type webmTrack struct {
+	number  uint64
+	codecID string
+	private []byte
+	track   *MediaTrack
+	avc     *avcConfig
+}
+
+// parseWebM walks the element tree flat, descending into the masters it needs. This also copes with
+// unknown-sized segments and clusters written by live muxers such as browsers
+func parseWebM(data []byte) (*Media, error) {
+	var (
+		tracks          []*webmTrack
+		timecodeScale   = uint64(defaultTimecodeScale)
+		clusterTimecode uint64
+	)
+	byNumber := func(n uint64) *webmTrack {
+		for _, t := range tracks {
+			if t.number == n {
+				return t
+			}
+		}
+		return nil
+	}
+
+	for len(data) > 0 {
+		id, size, headerLen, ok := readEBMLElement(data)
+		if !ok {
+			break
+		}
+		if size < 0 || headerLen+size > len(data) {
+			// unknown or truncated size, only valid for masters
+			switch id {
+			case ebmlIDSegment, ebmlIDCluster:
+				data = data[headerLen:]
+				continue
+			default:
+				return nil, ErrTruncated
+			}
+		}
+		payload := data[headerLen : headerLen+size]
+
+		switch id {
+		case ebmlIDSegment, ebmlIDInfo, ebmlIDTracks, ebmlIDCluster, ebmlIDBlockGroup:
+			// descend
+			data = data[headerLen:]
+			continue
+
+		case ebmlIDTrackEntry:
+			tracks = append(tracks, &webmTrack{})
+			data = data[headerLen:]
+			continue
+
+		case ebmlIDTimecodeScale:
+			timecodeScale = readEBMLUint(payload)
+
+		case ebmlIDTrackNumber:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].number = readEBMLUint(payload)
+			}
+
+		case ebmlIDCodecID:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].codecID = string(payload)
+			}
+
+		case ebmlIDCodecPrivate:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].private = payload
+			}
+
+		case ebmlIDClusterTimecode:
+			clusterTimecode = readEBMLUint(payload)
+
+		case ebmlIDSimpleBlock, ebmlIDBlock:
+			trackNumber, n := readEBMLVarint(payload)
+			if n <= 0 || len(payload) < n+3 {
+				break
+			}
+			t := byNumber(trackNumber)
+			if t == nil {
+				break
+			}
+			if t.track == nil && !t.setup() {
+				// unsupported codec, ignore its blocks
+				t.number = 0
+				break
+			}
+
+			relative := int64(int16(binary.BigEndian.Uint16(payload[n : n+2])))
+			flags := payload[n+2]
+			if flags&0x06 != 0 {
+				// laced blocks are not used for the codecs supported here
+				break
+			}
+			frame := payload[n+3:]
+			if t.avc != nil {
+				frame = t.avc.toAnnexB(frame)
+			}
+
+			ts := int64(clusterTimecode) + relative
+			if ts < 0 {
+				ts = 0
+			}
+			keyframe := isKeyframe(t.track.Codec.MimeType, frame)
+			if id == ebmlIDSimpleBlock && flags&0x80 != 0 {
+				keyframe = true
+			}
+			t.track.Samples = append(t.track.Samples, Sample{
+				Data:     frame,
+				PTS:      time.Duration(uint64(ts) * timecodeScale),
+				Keyframe: keyframe,
+			})
+		}
+		data = data[headerLen+size:]
+	}
+
+	m := &Media{}
+	for _, t := range tracks {
+		if t.track != nil {
+			m.Tracks = append(m.Tracks, t.track)
+		}
+	}
+	if len(m.Tracks) == 0 {
+		return nil, ErrNoPlayableTracks
+	}
+	return m, nil
+}
+
+func (t *webmTrack) setup() bool {
+	var codec webrtc.RTPCodecCapability
+	switch t.codecID {
+	case "A_OPUS":
+		codec = opusCodec
+	case "V_VP8":
+		codec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}
+	case "V_VP9":
+		codec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000}
+	case "V_AV1":
+		codec = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeAV1, ClockRate: 90000}
+	case "V_MPEG4/ISO/AVC":
+		avc, err := parseAVCConfig(t.private)
+		if err != nil {
+			return false
+		}
+		t.avc = avc
+		codec = h264Codec
+	default:
+		return false
+	}
+	t.track = &MediaTrack{Codec: codec}
+	return true
+}
+
+// readEBMLElement returns the element ID, the payload size (-1 when unknown) and the header length
+func readEBMLElement(data []byte) (id uint32, size int, headerLen int, ok bool) {
+	if len(data) == 0 {
+		return
+	}
+	idLen := ebmlVarintLen(data[0])
+	if idLen == 0 || idLen > 4 || len(data) < idLen {
+		return
+	}
+	for _, b := range data[:idLen] {
+		id = id<<8 | uint32(b)
+	}
+
+	if len(data) <= idLen {
+		return
+	}
+	sizeLen := ebmlVarintLen(data[idLen])
+	if sizeLen == 0 || len(data) < idLen+sizeLen {
+		return
+	}
+	v, _ := readEBMLVarint(data[idLen:])
+	if v == 1<<(7*uint(sizeLen))-1 {
+		size = -1
+	} else if v > uint64(len(data)) {
+		// larger than the file, treat as truncated
+		size = len(data)
+	} else {
+		size = int(v)
+	}
+	return id, size, idLen + sizeLen, true
+}
+
+func ebmlVarintLen(b byte) int {
+	for i := 0; i < 8; i++ {
+		if b&(0x80>>uint(i)) != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// readEBMLVarint decodes a variable length integer with its length marker removed
+func readEBMLVarint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	n := ebmlVarintLen(data[0])
+	if n == 0 || len(data) < n {
+		return 0, 0
+	}
+	v := uint64(data[0] & (0xff >> uint(n)))
+	for _, b := range data[1:n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, n
+}
+
+func readEBMLUint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
@@ -0,0 +1,382 @@
+package relay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	DefaultKeyRotationInterval = 10 * time.Minute
+
+	handshakeVersion = 1
+	nonceSize        = 32
+	proofSize        = sha256.Size
+	// epoch of the key and sequence number of the packet, also the nonce of the packet
+	headerSize = 12
+	// sequence numbers of packets received out of order within the window are accepted once
+	replayWindow = 64
+
+	roleInitiator = "initiator"
+	roleResponder = "responder"
+)
+
+var (
+	ErrMissingPreSharedKey  = errors.New("relay: pre-shared key is not set")
+	ErrUnexpectedNode       = errors.New("relay: unexpected remote node")
+	ErrAuthenticationFailed = errors.New("relay: authentication failed")
+	ErrNotEstablished       = errors.New("relay: link is not established")
+	ErrPacketTooShort       = errors.New("relay: packet too short")
+	ErrUnknownKey           = errors.New("relay: packet sealed with an unknown key")
+	ErrDecryptionFailed     = errors.New("relay: could not decrypt packet")
+	ErrReplayed             = errors.New("relay: replayed packet")
+)
+
+type LinkParams struct {
+	LocalNodeID  livekit.NodeID
+	RemoteNodeID livekit.NodeID
+	// secret shared by the nodes of the cluster, nodes prove they know it and link keys are derived from it
+	PreSharedKey string
+	// the key packets are sealed with is replaced this often
+	KeyRotationInterval time.Duration
+	Logger              logger.Logger
+}
+
+type LinkStats struct {
+	Established        bool
+	Epoch              uint32
+	PacketsSealed      uint64
+	PacketsOpened      uint64
+	DecryptionFailures uint64
+	ReplayedPackets    uint64
+	KeyRotations       uint64
+	LastOpenedAt       time.Time
+}
+
+// Link authenticates the node at the other end of an inter-node media link and encrypts the packets relayed over it,
+// so that relays can traverse untrusted networks between regions. Both nodes prove knowledge of the pre-shared key
+// of the cluster in a handshake, which yields a session key unique to the link. Packets are sealed with AES-GCM
+// under keys derived from the session key per direction and epoch; the sender moves to the next epoch every key
+// rotation interval and the receiver follows, accepting packets of the previous epoch still in flight. Packets are
+// checked against replays.
+type Link struct {
+	params  LinkParams
+	metrics *prometheus.RelayLinkMetrics
+
+	lock       sync.Mutex
+	sessionKey []byte
+	localRole  string
+	remoteRole string
+
+	sendEpoch    uint32
+	sendEpochAt  time.Time
+	sendAEAD     cipher.AEAD
+	sendSequence uint64
+
+	recvEpoch   uint32
+	recvAEADs   map[uint32]cipher.AEAD
+	recvHighest uint64
+	recvWindow  uint64
+
+	stats LinkStats
+}
+
+func NewLink(params LinkParams) (*Link, error) {
+	if params.PreSharedKey == "" {
+		return nil, ErrMissingPreSharedKey
+	}
+	if params.KeyRotationInterval <= 0 {
+		params.KeyRotationInterval = DefaultKeyRotationInterval
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+	params.Logger = params.Logger.WithValues("remoteNodeID", params.RemoteNodeID)
+
+	return &Link{
+		params:  params,
+		metrics: prometheus.NewRelayLinkMetrics(params.RemoteNodeID),
+	}, nil
+}
+
+// Handshake mutually authenticates the nodes over conn and establishes the keys of the link. The node opening the
+// link is the initiator. Deadlines are up to the caller
+func (l *Link) Handshake(conn io.ReadWriter, initiator bool) error {
+	err := l.handshake(conn, initiator)
+	if err != nil {
+		l.params.Logger.Warnw("relay link handshake failed", err)
+		l.metrics.Error(err)
+		return err
+	}
+
+	l.params.Logger.Infow("relay link established")
+	l.metrics.SetUp(true)
+	return nil
+}
+
+func (l *Link) handshake(conn io.ReadWriter, initiator bool) error {
+	localNonce := make([]byte, nonceSize)
+	if _, err := rand.Read(localNonce); err != nil {
+		return err
+	}
+
+	var transcript []byte
+	if initiator {
+		if _, err := conn.Write(marshalHello(l.params.LocalNodeID, localNonce)); err != nil {
+			return err
+		}
+		remoteNonce, err := l.readHello(conn)
+		if err != nil {
+			return err
+		}
+		transcript = makeTranscript(l.params.LocalNodeID, l.params.RemoteNodeID, localNonce, remoteNonce)
+		if err = l.readProof(conn, transcript, roleResponder); err != nil {
+			return err
+		}
+		if _, err = conn.Write(l.proof(transcript, roleInitiator)); err != nil {
+			return err
+		}
+	} else {
+		remoteNonce, err := l.readHello(conn)
+		if err != nil {
+			return err
+		}
+		transcript = makeTranscript(l.params.RemoteNodeID, l.params.LocalNodeID, remoteNonce, localNonce)
+		if _, err = conn.Write(append(marshalHello(l.params.LocalNodeID, localNonce), l.proof(transcript, roleResponder)...)); err != nil {
+			return err
+		}
+		if err = l.readProof(conn, transcript, roleInitiator); err != nil {
+			return err
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.sessionKey = l.mac([]byte("session"), transcript)
+	if initiator {
+		l.localRole, l.remoteRole = roleInitiator, roleResponder
+	} else {
+		l.localRole, l.remoteRole = roleResponder, roleInitiator
+	}
+	l.sendEpoch = 0
+	l.sendEpochAt = time.Now()
+	l.sendAEAD = l.deriveAEAD(l.localRole, 0)
+	l.sendSequence = 0
+	l.recvEpoch = 0
+	l.recvAEADs = map[uint32]cipher.AEAD{0: l.deriveAEAD(l.remoteRole, 0)}
+	l.recvHighest = 0
+	l.recvWindow = 0
+	l.stats.Established = true
+	return nil
+}
+
+func (l *Link) readHello(conn io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if header[0] != handshakeVersion {
+		return nil, ErrAuthenticationFailed
+	}
+	body := make([]byte, int(header[1])+nonceSize)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return nil, err
+	}
+	if nodeID := livekit.NodeID(body[:header[1]]); nodeID != l.params.RemoteNodeID {
+		l.params.Logger.Warnw("unexpected node on relay link", nil, "nodeID", nodeID)
+		return nil, ErrUnexpectedNode
+	}
+	return body[header[1]:], nil
+}
+
+func (l *Link) readProof(conn io.Reader, transcript []byte, role string) error {
+	proof := make([]byte, proofSize)
+	if _, err := io.ReadFull(conn, proof); err != nil {
+		return err
+	}
+	if !hmac.Equal(proof, l.proof(transcript, role)) {
+		return ErrAuthenticationFailed
+	}
+	return nil
+}
+
+func (l *Link) proof(transcript []byte, role string) []byte {
+	return l.mac([]byte("auth "+role), transcript)
+}
+
+func (l *Link) mac(label []byte, data []byte) []byte {
+	h := hmac.New(sha256.New, []byte(l.params.PreSharedKey))
+	h.Write(label)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func (l *Link) deriveAEAD(role string, epoch uint32) cipher.AEAD {
+	h := hmac.New(sha256.New, l.sessionKey)
+	h.Write([]byte("key " + role))
+	_ = binary.Write(h, binary.BigEndian, epoch)
+	// a 32 byte key and the standard nonce size never fail
+	block, _ := aes.NewCipher(h.Sum(nil))
+	aead, _ := cipher.NewGCM(block)
+	return aead
+}
+
+// Seal encrypts and authenticates a packet to send over the link
+func (l *Link) Seal(payload []byte) ([]byte, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.sessionKey == nil {
+		return nil, ErrNotEstablished
+	}
+	if time.Since(l.sendEpochAt) >= l.params.KeyRotationInterval {
+		l.sendEpoch++
+		l.sendEpochAt = time.Now()
+		l.sendAEAD = l.deriveAEAD(l.localRole, l.sendEpoch)
+		l.stats.KeyRotations++
+		l.metrics.KeyRotated()
+		l.params.Logger.Debugw("rotated relay link key", "epoch", l.sendEpoch)
+	}
+
+	l.sendSequence++
+	packet := make([]byte, headerSize, headerSize+len(payload)+l.sendAEAD.Overhead())
+	binary.BigEndian.PutUint32(packet, l.sendEpoch)
+	binary.BigEndian.PutUint64(packet[4:], l.sendSequence)
+	packet = l.sendAEAD.Seal(packet, packet[:headerSize], payload, packet[:headerSize])
+
+	l.stats.PacketsSealed++
+	l.metrics.PacketSent()
+	return packet, nil
+}
+
+// Open authenticates and decrypts a packet received over the link
+func (l *Link) Open(packet []byte) ([]byte, error) {
+	payload, err := l.open(packet)
+	if err != nil {
+		l.metrics.Error(err)
+		return nil, err
+	}
+	l.metrics.PacketReceived()
+	return payload, nil
+}
+
+func (l *Link) open(packet []byte) ([]byte, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.sessionKey == nil {
+		return nil, ErrNotEstablished
+	}
+	if len(packet) < headerSize {
+		return nil, ErrPacketTooShort
+	}
+
+	header := packet[:headerSize]
+	epoch := binary.BigEndian.Uint32(header)
+	sequence := binary.BigEndian.Uint64(header[4:])
+
+	aead := l.recvAEADs[epoch]
+	if aead == nil {
+		if epoch != l.recvEpoch+1 {
+			return nil, ErrUnknownKey
+		}
+		// the sender rotated its key
+		aead = l.deriveAEAD(l.remoteRole, epoch)
+	}
+	if l.isReplayedLocked(sequence) {
+		l.stats.ReplayedPackets++
+		return nil, ErrReplayed
+	}
+	payload, err := aead.Open(nil, header, packet[headerSize:], header)
+	if err != nil {
+		l.stats.DecryptionFailures++
+		return nil, ErrDecryptionFailed
+	}
+
+	if epoch == l.recvEpoch+1 {
+		// keep the key of the previous epoch for packets still in flight
+		delete(l.recvAEADs, l.recvEpoch-1)
+		l.recvAEADs[epoch] = aead
+		l.recvEpoch = epoch
+	}
+	l.markReceivedLocked(sequence)
+	l.stats.PacketsOpened++
+	l.stats.LastOpenedAt = time.Now()
+	return payload, nil
+}
+
+func (l *Link) isReplayedLocked(sequence uint64) bool {
+	if sequence > l.recvHighest {
+		return false
+	}
+	diff := l.recvHighest - sequence
+	if diff >= replayWindow {
+		return true
+	}
+	return l.recvWindow&(1<<diff) != 0
+}
+
+func (l *Link) markReceivedLocked(sequence uint64) {
+	if sequence <= l.recvHighest {
+		l.recvWindow |= 1 << (l.recvHighest - sequence)
+		return
+	}
+
+	if shift := sequence - l.recvHighest; shift >= replayWindow {
+		l.recvWindow = 0
+	} else {
+		l.recvWindow <<= shift
+	}
+	l.recvWindow |= 1
+	l.recvHighest = sequence
+}
+
+func (l *Link) Stats() LinkStats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	stats := l.stats
+	stats.Epoch = l.sendEpoch
+	return stats
+}
+
+// Close forgets the keys of the link and removes its metrics, the link is not to be used after
+func (l *Link) Close() {
+	l.lock.Lock()
+	l.sessionKey = nil
+	l.sendAEAD = nil
+	l.recvAEADs = nil
+	l.stats.Established = false
+	l.lock.Unlock()
+
+	l.metrics.Delete()
+}
+
+func marshalHello(nodeID livekit.NodeID, nonce []byte) []byte {
+	hello := []byte{handshakeVersion, byte(len(nodeID))}
+	hello = append(hello, nodeID...)
+	return append(hello, nonce...)
+}
+
+func makeTranscript(initiatorID, responderID livekit.NodeID, initiatorNonce, responderNonce []byte) []byte {
+	var transcript []byte
+	for _, nodeID := range []livekit.NodeID{initiatorID, responderID} {
+		transcript = append(transcript, byte(len(nodeID)))
+		transcript = append(transcript, nodeID...)
+	}
+	transcript = append(transcript, initiatorNonce...)
+	return append(transcript, responderNonce...)
+}
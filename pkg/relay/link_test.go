@@ -0,0 +1,166 @@
+package relay
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+func init() {
+	prometheus.Init("test", livekit.NodeType_SERVER, "test")
+}
+
+func newTestLinks(t *testing.T, initiatorKey, responderKey string, rotation time.Duration) (*Link, *Link, error, error) {
+	initiator, err := NewLink(LinkParams{LocalNodeID: "a", RemoteNodeID: "b", PreSharedKey: initiatorKey, KeyRotationInterval: rotation})
+	require.NoError(t, err)
+	responder, err := NewLink(LinkParams{LocalNodeID: "b", RemoteNodeID: "a", PreSharedKey: responderKey, KeyRotationInterval: rotation})
+	require.NoError(t, err)
+	t.Cleanup(initiator.Close)
+	t.Cleanup(responder.Close)
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	_ = c1.SetDeadline(time.Now().Add(time.Second))
+	_ = c2.SetDeadline(time.Now().Add(time.Second))
+
+	done := make(chan error, 1)
+	go func() {
+		err := responder.Handshake(c2, false)
+		if err != nil {
+			_ = c2.Close()
+		}
+		done <- err
+	}()
+	initiatorErr := initiator.Handshake(c1, true)
+	if initiatorErr != nil {
+		_ = c1.Close()
+	}
+	return initiator, responder, initiatorErr, <-done
+}
+
+func TestLink(t *testing.T) {
+	t.Run("packets are sealed and opened in both directions", func(t *testing.T) {
+		a, b, errA, errB := newTestLinks(t, "secret", "secret", 0)
+		require.NoError(t, errA)
+		require.NoError(t, errB)
+
+		packet, err := a.Seal([]byte("media"))
+		require.NoError(t, err)
+		require.NotContains(t, string(packet), "media")
+		payload, err := b.Open(packet)
+		require.NoError(t, err)
+		require.Equal(t, []byte("media"), payload)
+
+		packet, err = b.Seal([]byte("feedback"))
+		require.NoError(t, err)
+		payload, err = a.Open(packet)
+		require.NoError(t, err)
+		require.Equal(t, []byte("feedback"), payload)
+
+		// tampered
+		packet, err = a.Seal([]byte("media"))
+		require.NoError(t, err)
+		packet[len(packet)-1] ^= 1
+		_, err = b.Open(packet)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+		// sealed by the receiver itself
+		packet, err = b.Seal([]byte("media"))
+		require.NoError(t, err)
+		_, err = b.Open(packet)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+
+		require.Equal(t, uint64(1), a.Stats().PacketsOpened)
+		require.Equal(t, uint64(2), b.Stats().DecryptionFailures)
+	})
+
+	t.Run("nodes with another key are rejected", func(t *testing.T) {
+		a, b, errA, errB := newTestLinks(t, "secret", "other", 0)
+		require.ErrorIs(t, errA, ErrAuthenticationFailed)
+		require.Error(t, errB)
+
+		_, err := a.Seal([]byte("media"))
+		require.ErrorIs(t, err, ErrNotEstablished)
+		_, err = b.Seal([]byte("media"))
+		require.ErrorIs(t, err, ErrNotEstablished)
+	})
+
+	t.Run("unexpected nodes are rejected", func(t *testing.T) {
+		a, err := NewLink(LinkParams{LocalNodeID: "a", RemoteNodeID: "b", PreSharedKey: "secret"})
+		require.NoError(t, err)
+		defer a.Close()
+		c, err := NewLink(LinkParams{LocalNodeID: "c", RemoteNodeID: "a", PreSharedKey: "secret"})
+		require.NoError(t, err)
+		defer c.Close()
+
+		c1, c2 := net.Pipe()
+		go func() {
+			_ = c.Handshake(c2, true)
+			_ = c2.Close()
+		}()
+		require.ErrorIs(t, a.Handshake(c1, false), ErrUnexpectedNode)
+		_ = c1.Close()
+	})
+
+	t.Run("replayed packets are dropped", func(t *testing.T) {
+		a, b, errA, errB := newTestLinks(t, "secret", "secret", 0)
+		require.NoError(t, errA)
+		require.NoError(t, errB)
+
+		var packets [][]byte
+		for i := 0; i < replayWindow+2; i++ {
+			packet, err := a.Seal([]byte{byte(i)})
+			require.NoError(t, err)
+			packets = append(packets, packet)
+		}
+
+		// out of order within the window is fine, once
+		_, err := b.Open(packets[2])
+		require.NoError(t, err)
+		_, err = b.Open(packets[1])
+		require.NoError(t, err)
+		_, err = b.Open(packets[1])
+		require.ErrorIs(t, err, ErrReplayed)
+
+		_, err = b.Open(packets[len(packets)-1])
+		require.NoError(t, err)
+		// fell out of the window
+		_, err = b.Open(packets[0])
+		require.ErrorIs(t, err, ErrReplayed)
+		require.Equal(t, uint64(2), b.Stats().ReplayedPackets)
+	})
+
+	t.Run("keys are rotated", func(t *testing.T) {
+		a, b, errA, errB := newTestLinks(t, "secret", "secret", 20*time.Millisecond)
+		require.NoError(t, errA)
+		require.NoError(t, errB)
+
+		first, err := a.Seal([]byte{0})
+		require.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+		second, err := a.Seal([]byte{1})
+		require.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+		third, err := a.Seal([]byte{2})
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), a.Stats().Epoch)
+		require.Equal(t, uint64(2), a.Stats().KeyRotations)
+
+		// the receiver follows one epoch at a time
+		_, err = b.Open(third)
+		require.ErrorIs(t, err, ErrUnknownKey)
+		_, err = b.Open(second)
+		require.NoError(t, err)
+		_, err = b.Open(third)
+		require.NoError(t, err)
+		// two epochs back
+		_, err = b.Open(first)
+		require.ErrorIs(t, err, ErrUnknownKey)
+	})
+}
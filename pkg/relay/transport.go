@@ -0,0 +1,611 @@
+package relay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/quic-go/quic-go"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	transportALPN    = "livekit-relay"
+	handshakeTimeout = 10 * time.Second
+	keepAlivePeriod  = 5 * time.Second
+
+	frameControl byte = 1
+	frameMedia   byte = 2
+
+	controlQueueSize = 256
+	mediaQueueSize   = 1024
+	maxFrameSize     = 0xffff
+
+	mediaFlagKeyFrame byte = 1
+)
+
+var (
+	ErrTransportClosed = errors.New("relay: transport closed")
+	ErrConnClosed      = errors.New("relay: connection closed")
+	ErrFrameTooLarge   = errors.New("relay: frame too large")
+	ErrInvalidFrame    = errors.New("relay: invalid frame")
+)
+
+type MessageType string
+
+const (
+	// an edge node takes part in a room hosted by the remote node
+	MessageJoinRoom  MessageType = "join_room"
+	MessageLeaveRoom MessageType = "leave_room"
+	// a participant connected to the sending node joined or changed, its tracks can be subscribed to
+	MessageParticipantUpdate MessageType = "participant_update"
+	// media of a track published to the receiving node is to be relayed, or no longer
+	MessageSubscribe   MessageType = "subscribe"
+	MessageUnsubscribe MessageType = "unsubscribe"
+	// a key frame of a layer of a relayed track is needed
+	MessagePLI MessageType = "pli"
+	// RTCP sender report of a layer of a relayed track, for aligning its layers
+	MessageSenderReport MessageType = "sender_report"
+	// a data packet sent to the room on the sending node, by one of its participants or by the server
+	MessageData MessageType = "data"
+	// active speakers connected to the sending node changed
+	MessageSpeakers MessageType = "speakers"
+	// the room changed on the origin, or closed there
+	MessageRoomUpdate MessageType = "room_update"
+	MessageCloseRoom  MessageType = "close_room"
+)
+
+// Message is a control message exchanged by nodes relaying the media of a room
+type Message struct {
+	Type MessageType      `json:"type"`
+	Room livekit.RoomName `json:"room,omitempty"`
+	// livekit.ParticipantInfo, protobuf encoded so that fields a node does not know of are kept
+	Participant []byte `json:"participant,omitempty"`
+	// codecs the tracks of the participant are relayed with
+	Codecs map[livekit.TrackID]webrtc.RTPCodecParameters `json:"codecs,omitempty"`
+	// header extensions of the relayed packets the receiving node parses, the dependency descriptor of scalable codecs
+	HeaderExtensions map[livekit.TrackID][]webrtc.RTPHeaderExtensionParameter `json:"header_extensions,omitempty"`
+	TrackID          livekit.TrackID                                          `json:"track_id,omitempty"`
+	Layer            int32                                                    `json:"layer,omitempty"`
+	SenderReport     *SenderReport                                            `json:"sender_report,omitempty"`
+	// livekit.DataPacket, protobuf encoded
+	Data []byte `json:"data,omitempty"`
+	// livekit.ActiveSpeakerUpdate with the speakers that changed, protobuf encoded
+	Speakers []byte `json:"speakers,omitempty"`
+	// livekit.Room, protobuf encoded
+	RoomInfo []byte `json:"room_info,omitempty"`
+}
+
+type SenderReport struct {
+	RTPTimestamp uint32 `json:"rtp_timestamp"`
+	NTPTimestamp uint64 `json:"ntp_timestamp"`
+}
+
+func (m *Message) SetParticipant(pi *livekit.ParticipantInfo) error {
+	data, err := proto.Marshal(pi)
+	if err != nil {
+		return err
+	}
+	m.Participant = data
+	return nil
+}
+
+func (m *Message) ParticipantInfo() (*livekit.ParticipantInfo, error) {
+	pi := &livekit.ParticipantInfo{}
+	if err := proto.Unmarshal(m.Participant, pi); err != nil {
+		return nil, err
+	}
+	return pi, nil
+}
+
+func (m *Message) SetDataPacket(dp *livekit.DataPacket) error {
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		return err
+	}
+	m.Data = data
+	return nil
+}
+
+func (m *Message) DataPacket() (*livekit.DataPacket, error) {
+	dp := &livekit.DataPacket{}
+	if err := proto.Unmarshal(m.Data, dp); err != nil {
+		return nil, err
+	}
+	return dp, nil
+}
+
+func (m *Message) SetSpeakers(speakers []*livekit.SpeakerInfo) error {
+	data, err := proto.Marshal(&livekit.ActiveSpeakerUpdate{Speakers: speakers})
+	if err != nil {
+		return err
+	}
+	m.Speakers = data
+	return nil
+}
+
+func (m *Message) SpeakerInfos() ([]*livekit.SpeakerInfo, error) {
+	update := &livekit.ActiveSpeakerUpdate{}
+	if err := proto.Unmarshal(m.Speakers, update); err != nil {
+		return nil, err
+	}
+	return update.Speakers, nil
+}
+
+func (m *Message) SetRoomInfo(room *livekit.Room) error {
+	data, err := proto.Marshal(room)
+	if err != nil {
+		return err
+	}
+	m.RoomInfo = data
+	return nil
+}
+
+func (m *Message) RoomProto() (*livekit.Room, error) {
+	room := &livekit.Room{}
+	if err := proto.Unmarshal(m.RoomInfo, room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// MediaPacket is an RTP packet of a relayed track, along with what the sending node learnt parsing it
+type MediaPacket struct {
+	TrackID  livekit.TrackID
+	Layer    int32
+	Temporal int32
+	KeyFrame bool
+	Packet   []byte
+}
+
+func (p *MediaPacket) marshal() ([]byte, error) {
+	if len(p.TrackID) > 0xff {
+		return nil, ErrInvalidFrame
+	}
+	data := make([]byte, 0, 5+len(p.TrackID)+len(p.Packet))
+	data = append(data, frameMedia, byte(len(p.TrackID)))
+	data = append(data, p.TrackID...)
+	var flags byte
+	if p.KeyFrame {
+		flags |= mediaFlagKeyFrame
+	}
+	data = append(data, byte(int8(p.Layer)), byte(int8(p.Temporal)), flags)
+	return append(data, p.Packet...), nil
+}
+
+func unmarshalMediaPacket(data []byte) (*MediaPacket, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0])+3 {
+		return nil, ErrInvalidFrame
+	}
+	n := int(data[0])
+	header := data[1+n:]
+	return &MediaPacket{
+		TrackID:  livekit.TrackID(data[1 : 1+n]),
+		Layer:    int32(int8(header[0])),
+		Temporal: int32(int8(header[1])),
+		KeyFrame: header[2]&mediaFlagKeyFrame != 0,
+		Packet:   header[3:],
+	}, nil
+}
+
+// ConnHandler handles what the remote nodes of the connections of a transport send
+type ConnHandler interface {
+	HandleMessage(c *Conn, msg *Message)
+	HandleMedia(c *Conn, pkt *MediaPacket)
+	HandleClose(c *Conn)
+}
+
+type TransportParams struct {
+	NodeID livekit.NodeID
+	// UDP port the QUIC listener is bound to
+	Port                uint32
+	PreSharedKey        string
+	KeyRotationInterval time.Duration
+	Handler             ConnHandler
+	Logger              logger.Logger
+}
+
+// Transport carries the media and control messages of rooms spanning nodes over QUIC connections between the nodes.
+// QUIC only provides congestion control and multiplexing here: the certificates are made up on start and not
+// checked, each connection is authenticated by the handshake of a Link and everything sent over it is sealed by the
+// link. Control messages and media share a single stream so that the link sees its packets in order; media is
+// dropped rather than queued when a connection cannot keep up
+type Transport struct {
+	params    TransportParams
+	tlsConfig *tls.Config
+	listener  *quic.Listener
+
+	lock   sync.Mutex
+	conns  map[*Conn]struct{}
+	closed bool
+}
+
+func NewTransport(params TransportParams) (*Transport, error) {
+	if params.PreSharedKey == "" {
+		return nil, ErrMissingPreSharedKey
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+	cert, err := newSelfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transport{
+		params: params,
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{transportALPN},
+			// links authenticate nodes
+			InsecureSkipVerify: true,
+		},
+		conns: make(map[*Conn]struct{}),
+	}, nil
+}
+
+func newSelfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: transportALPN},
+		DNSNames:     []string{transportALPN},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func (t *Transport) quicConfig() *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout: handshakeTimeout,
+		KeepAlivePeriod:      keepAlivePeriod,
+	}
+}
+
+// Start listens for connections of other nodes
+func (t *Transport) Start() error {
+	listener, err := quic.ListenAddr(fmt.Sprintf(":%d", t.params.Port), t.tlsConfig, t.quicConfig())
+	if err != nil {
+		return err
+	}
+	t.listener = listener
+	go t.acceptWorker()
+	return nil
+}
+
+// Addr is the address of the listener, once started
+func (t *Transport) Addr() net.Addr {
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr()
+}
+
+func (t *Transport) acceptWorker() {
+	for {
+		qconn, err := t.listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			if _, err := t.accept(qconn); err != nil {
+				t.params.Logger.Infow("could not accept relay connection", "error", err, "remote", qconn.RemoteAddr())
+				_ = qconn.CloseWithError(0, "handshake failed")
+			}
+		}()
+	}
+}
+
+func (t *Transport) accept(qconn quic.Connection) (*Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	stream, err := qconn.AcceptStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = stream.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	// the initiator names itself, the link checks it knows the key
+	header := make([]byte, 1)
+	if _, err = io.ReadFull(stream, header); err != nil {
+		return nil, err
+	}
+	remoteNodeID := make([]byte, header[0])
+	if _, err = io.ReadFull(stream, remoteNodeID); err != nil {
+		return nil, err
+	}
+	return t.establish(qconn, stream, livekit.NodeID(remoteNodeID), false)
+}
+
+// Connect opens a connection to another node, addr being the host and port of its listener
+func (t *Transport) Connect(ctx context.Context, remoteNodeID livekit.NodeID, addr string) (*Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	qconn, err := quic.DialAddr(ctx, addr, t.tlsConfig, t.quicConfig())
+	if err != nil {
+		return nil, err
+	}
+	c, err := t.connect(ctx, qconn, remoteNodeID)
+	if err != nil {
+		_ = qconn.CloseWithError(0, "handshake failed")
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *Transport) connect(ctx context.Context, qconn quic.Connection, remoteNodeID livekit.NodeID) (*Conn, error) {
+	stream, err := qconn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_ = stream.SetDeadline(time.Now().Add(handshakeTimeout))
+	if _, err = stream.Write(append([]byte{byte(len(t.params.NodeID))}, t.params.NodeID...)); err != nil {
+		return nil, err
+	}
+	return t.establish(qconn, stream, remoteNodeID, true)
+}
+
+func (t *Transport) establish(qconn quic.Connection, stream quic.Stream, remoteNodeID livekit.NodeID, initiator bool) (*Conn, error) {
+	link, err := NewLink(LinkParams{
+		LocalNodeID:         t.params.NodeID,
+		RemoteNodeID:        remoteNodeID,
+		PreSharedKey:        t.params.PreSharedKey,
+		KeyRotationInterval: t.params.KeyRotationInterval,
+		Logger:              t.params.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = link.Handshake(stream, initiator); err != nil {
+		link.Close()
+		return nil, err
+	}
+	_ = stream.SetDeadline(time.Time{})
+
+	c := &Conn{
+		transport:    t,
+		remoteNodeID: remoteNodeID,
+		link:         link,
+		qconn:        qconn,
+		stream:       stream,
+		controlQueue: make(chan []byte, controlQueueSize),
+		mediaQueue:   make(chan []byte, mediaQueueSize),
+		closed:       make(chan struct{}),
+		logger:       t.params.Logger.WithValues("remoteNodeID", remoteNodeID),
+	}
+
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		c.Close()
+		return nil, ErrTransportClosed
+	}
+	t.conns[c] = struct{}{}
+	t.lock.Unlock()
+
+	go c.writeWorker()
+	go c.readWorker()
+	return c, nil
+}
+
+func (t *Transport) removeConn(c *Conn) {
+	t.lock.Lock()
+	delete(t.conns, c)
+	t.lock.Unlock()
+}
+
+// Close closes the listener and the connections
+func (t *Transport) Close() {
+	t.lock.Lock()
+	t.closed = true
+	conns := make([]*Conn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.lock.Unlock()
+
+	if t.listener != nil {
+		_ = t.listener.Close()
+	}
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// ---------------------------------------------
+
+// Conn is an authenticated connection to another node
+type Conn struct {
+	transport    *Transport
+	remoteNodeID livekit.NodeID
+	link         *Link
+	qconn        quic.Connection
+	stream       quic.Stream
+	logger       logger.Logger
+
+	controlQueue chan []byte
+	mediaQueue   chan []byte
+	droppedMedia atomic.Uint64
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *Conn) RemoteNodeID() livekit.NodeID {
+	return c.remoteNodeID
+}
+
+// SendMessage queues a control message, waiting for room in the queue
+func (c *Conn) SendMessage(msg *Message) error {
+	if c.IsClosed() {
+		return ErrConnClosed
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case c.controlQueue <- append([]byte{frameControl}, data...):
+		return nil
+	case <-c.closed:
+		return ErrConnClosed
+	}
+}
+
+// SendMedia queues a media packet, it is dropped when the queue is full
+func (c *Conn) SendMedia(pkt *MediaPacket) error {
+	if c.IsClosed() {
+		return ErrConnClosed
+	}
+	data, err := pkt.marshal()
+	if err != nil {
+		return err
+	}
+	select {
+	case c.mediaQueue <- data:
+		return nil
+	case <-c.closed:
+		return ErrConnClosed
+	default:
+		if c.droppedMedia.Inc()%100 == 1 {
+			c.logger.Debugw("relay connection congested, dropping media", "dropped", c.droppedMedia.Load())
+		}
+		return nil
+	}
+}
+
+// DroppedMedia is the number of media packets dropped for the connection not keeping up
+func (c *Conn) DroppedMedia() uint64 {
+	return c.droppedMedia.Load()
+}
+
+func (c *Conn) IsClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		_ = c.qconn.CloseWithError(0, "")
+		c.link.Close()
+		c.transport.removeConn(c)
+		if h := c.transport.params.Handler; h != nil {
+			h.HandleClose(c)
+		}
+	})
+}
+
+func (c *Conn) writeWorker() {
+	defer c.Close()
+
+	header := make([]byte, 2)
+	for {
+		var data []byte
+		// control messages go first
+		select {
+		case data = <-c.controlQueue:
+		default:
+			select {
+			case data = <-c.controlQueue:
+			case data = <-c.mediaQueue:
+			case <-c.closed:
+				return
+			}
+		}
+
+		frame, err := c.link.Seal(data)
+		if err != nil {
+			return
+		}
+		if len(frame) > maxFrameSize {
+			c.logger.Warnw("dropping relay frame", ErrFrameTooLarge, "size", len(frame))
+			continue
+		}
+		binary.BigEndian.PutUint16(header, uint16(len(frame)))
+		if _, err = c.stream.Write(header); err != nil {
+			return
+		}
+		if _, err = c.stream.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Conn) readWorker() {
+	defer c.Close()
+
+	handler := c.transport.params.Handler
+	header := make([]byte, 2)
+	buf := make([]byte, maxFrameSize)
+	for {
+		if _, err := io.ReadFull(c.stream, header); err != nil {
+			return
+		}
+		frame := buf[:binary.BigEndian.Uint16(header)]
+		if _, err := io.ReadFull(c.stream, frame); err != nil {
+			return
+		}
+		data, err := c.link.Open(frame)
+		if err != nil || len(data) == 0 {
+			// frames are neither lost nor reordered on the stream, failing to open one is tampering
+			c.logger.Warnw("could not open relay frame", err)
+			return
+		}
+		if handler == nil {
+			continue
+		}
+
+		switch data[0] {
+		case frameControl:
+			msg := &Message{}
+			if err = json.Unmarshal(data[1:], msg); err != nil {
+				c.logger.Warnw("could not decode relay message", err)
+				continue
+			}
+			handler.HandleMessage(c, msg)
+		case frameMedia:
+			pkt, err := unmarshalMediaPacket(data[1:])
+			if err != nil {
+				continue
+			}
+			handler.HandleMedia(c, pkt)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+type testHandler struct {
+	lock     sync.Mutex
+	messages []*Message
+	media    []*MediaPacket
+	closed   int
+}
+
+func (h *testHandler) HandleMessage(_ *Conn, msg *Message) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.messages = append(h.messages, msg)
+}
+
+func (h *testHandler) HandleMedia(_ *Conn, pkt *MediaPacket) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.media = append(h.media, pkt)
+}
+
+func (h *testHandler) HandleClose(_ *Conn) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.closed++
+}
+
+func (h *testHandler) counts() (int, int, int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.messages), len(h.media), h.closed
+}
+
+func newTestTransport(t *testing.T, nodeID livekit.NodeID, key string, handler ConnHandler) *Transport {
+	transport, err := NewTransport(TransportParams{
+		NodeID:       nodeID,
+		PreSharedKey: key,
+		Handler:      handler,
+	})
+	require.NoError(t, err)
+	require.NoError(t, transport.Start())
+	t.Cleanup(transport.Close)
+	return transport
+}
+
+func listenerAddr(transport *Transport) string {
+	return fmt.Sprintf("127.0.0.1:%d", transport.Addr().(*net.UDPAddr).Port)
+}
+
+func TestTransport(t *testing.T) {
+	t.Run("messages and media reach the remote node", func(t *testing.T) {
+		originHandler, edgeHandler := &testHandler{}, &testHandler{}
+		origin := newTestTransport(t, "origin", "secret", originHandler)
+		edge := newTestTransport(t, "edge", "secret", edgeHandler)
+
+		conn, err := edge.Connect(context.Background(), "origin", listenerAddr(origin))
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("origin"), conn.RemoteNodeID())
+
+		msg := &Message{Type: MessageParticipantUpdate, Room: "room"}
+		require.NoError(t, msg.SetParticipant(&livekit.ParticipantInfo{Identity: "alice"}))
+		require.NoError(t, conn.SendMessage(msg))
+		require.NoError(t, conn.SendMedia(&MediaPacket{TrackID: "TR_1", Layer: 2, Temporal: -1, KeyFrame: true, Packet: []byte("rtp")}))
+
+		require.Eventually(t, func() bool {
+			messages, media, _ := originHandler.counts()
+			return messages == 1 && media == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		pi, err := originHandler.messages[0].ParticipantInfo()
+		require.NoError(t, err)
+		require.Equal(t, "alice", pi.Identity)
+		require.Equal(t, livekit.RoomName("room"), originHandler.messages[0].Room)
+		require.Equal(t, &MediaPacket{TrackID: "TR_1", Layer: 2, Temporal: -1, KeyFrame: true, Packet: []byte("rtp")}, originHandler.media[0])
+
+		conn.Close()
+		require.Eventually(t, func() bool {
+			_, _, closed := originHandler.counts()
+			return closed == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		_, _, closed := edgeHandler.counts()
+		require.Equal(t, 1, closed)
+		require.ErrorIs(t, conn.SendMessage(msg), ErrConnClosed)
+	})
+
+	t.Run("nodes without the key are not connected", func(t *testing.T) {
+		originHandler := &testHandler{}
+		origin := newTestTransport(t, "origin", "secret", originHandler)
+		edge := newTestTransport(t, "edge", "guess", &testHandler{})
+
+		_, err := edge.Connect(context.Background(), "origin", listenerAddr(origin))
+		require.Error(t, err)
+		messages, _, closed := originHandler.counts()
+		require.Zero(t, messages)
+		require.Zero(t, closed)
+	})
+
+	t.Run("unexpected remote node", func(t *testing.T) {
+		origin := newTestTransport(t, "origin", "secret", &testHandler{})
+		edge := newTestTransport(t, "edge", "secret", &testHandler{})
+
+		_, err := edge.Connect(context.Background(), "other", listenerAddr(origin))
+		require.Error(t, err)
+	})
+
+	_, err := NewTransport(TransportParams{NodeID: "node"})
+	require.ErrorIs(t, err, ErrMissingPreSharedKey)
+}
@@ -0,0 +1,62 @@
+package routing
+
+import (
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+// isNodeFull tells whether participants joining the rooms hosted by a node should be placed on other nodes
+func isNodeFull(conf *config.Config, node *livekit.Node) bool {
+	if selector.LimitsReached(conf.Limit, node.Stats) {
+		return true
+	}
+	maxClients := conf.MediaRelay.MaxNodeClients
+	return maxClients > 0 && node.Stats != nil && uint32(node.Stats.NumClients) >= maxClients
+}
+
+// selectEdgeNode picks the node a participant joining a room whose node is full is placed on. Nodes in the region
+// the participant asked for are preferred, then nodes already hosting the room, the available node with the fewest
+// clients is picked among them. Returns nil when all nodes are full
+func selectEdgeNode(
+	conf *config.Config,
+	origin *livekit.Node,
+	edgeNodeIDs []string,
+	nodes []*livekit.Node,
+	regionHint string,
+) *livekit.Node {
+	isEdge := make(map[string]bool, len(edgeNodeIDs))
+	for _, nodeID := range edgeNodeIDs {
+		isEdge[nodeID] = true
+	}
+	rank := func(node *livekit.Node) int {
+		r := 0
+		if regionHint == "" || node.Region != regionHint {
+			r += 2
+		}
+		if !isEdge[node.Id] {
+			r++
+		}
+		return r
+	}
+
+	var selected *livekit.Node
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		if node.Id == origin.Id || isNodeFull(conf, node) {
+			continue
+		}
+		if selected == nil || rank(node) < rank(selected) ||
+			(rank(node) == rank(selected) && numClients(node) < numClients(selected)) {
+			selected = node
+		}
+	}
+	return selected
+}
+
+func numClients(node *livekit.Node) int32 {
+	if node.Stats == nil {
+		return 0
+	}
+	return node.Stats.NumClients
+}
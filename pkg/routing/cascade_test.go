@@ -0,0 +1,57 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestSelectEdgeNode(t *testing.T) {
+	conf := &config.Config{}
+	conf.MediaRelay.MaxNodeClients = 100
+	conf.Limit.NumTracks = 1000
+
+	newNode := func(id string, clients int32, tracks int32) *livekit.Node {
+		return &livekit.Node{
+			Id:    id,
+			State: livekit.NodeState_SERVING,
+			Stats: &livekit.NodeStats{UpdatedAt: time.Now().Unix(), NumClients: clients, NumTracksIn: tracks},
+		}
+	}
+	origin := newNode("origin", 100, 0)
+	require.True(t, isNodeFull(conf, origin))
+	require.True(t, isNodeFull(conf, newNode("tracks", 10, 1000)))
+	require.False(t, isNodeFull(conf, newNode("free", 99, 999)))
+
+	t.Run("nodes already hosting the room are preferred", func(t *testing.T) {
+		nodes := []*livekit.Node{origin, newNode("edge", 80, 0), newNode("idle", 0, 0)}
+		require.Equal(t, "edge", selectEdgeNode(conf, origin, []string{"edge"}, nodes, "").Id)
+	})
+
+	t.Run("least loaded node once edges are full", func(t *testing.T) {
+		nodes := []*livekit.Node{origin, newNode("edge", 100, 0), newNode("busy", 50, 0), newNode("idle", 10, 0)}
+		require.Equal(t, "idle", selectEdgeNode(conf, origin, []string{"edge"}, nodes, "").Id)
+	})
+
+	t.Run("nodes in the hinted region are preferred", func(t *testing.T) {
+		far := newNode("far", 0, 0)
+		near := newNode("near", 50, 0)
+		near.Region = "eu"
+		nodes := []*livekit.Node{origin, newNode("edge", 10, 0), far, near}
+		require.Equal(t, "near", selectEdgeNode(conf, origin, []string{"edge"}, nodes, "eu").Id)
+		require.Equal(t, "edge", selectEdgeNode(conf, origin, []string{"edge"}, nodes, "us").Id)
+	})
+
+	t.Run("unavailable nodes are skipped", func(t *testing.T) {
+		stale := newNode("stale", 0, 0)
+		stale.Stats.UpdatedAt = time.Now().Add(-time.Minute).Unix()
+		draining := newNode("draining", 0, 0)
+		draining.State = livekit.NodeState_SHUTTING_DOWN
+		require.Nil(t, selectEdgeNode(conf, origin, nil, []*livekit.Node{origin, stale, draining}, ""))
+	})
+}
@@ -3,11 +3,14 @@ package routing
 import (
 	"context"
 	"encoding/json"
+	"math"
 
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -45,6 +48,35 @@ type ParticipantInit struct {
 	AdaptiveStream       bool
 	ID                   livekit.ParticipantID
 	SubscriberAllowPause *bool
+	// capabilities declared by the client, kept by name so that nodes knowing more of them can act on those
+	Capabilities []string
+	// DTLS certificate fingerprint declared by the client
+	Fingerprint string
+	// hints about the device of the client, for choosing what to send before bandwidth is estimated
+	DeviceHints DeviceHints
+	// video is not sent to the client till it asks for it again, e. g. a listen-only mobile client
+	AudioOnlyDownlink bool
+	// W3C traceparent of the join on the signal node, the session is traced as part of it
+	TraceParent string
+	// located by the signal node, so that the RTC node does not look the address up again
+	ClientLocation *geoip.Location
+}
+
+const (
+	DeviceClassWatch   = "watch"
+	DeviceClassMobile  = "mobile"
+	DeviceClassTablet  = "tablet"
+	DeviceClassDesktop = "desktop"
+	DeviceClassTV      = "tv"
+)
+
+// DeviceHints are declared by clients when joining, zero values are unknown
+type DeviceHints struct {
+	// screen size in physical pixels
+	ScreenWidth  uint32
+	ScreenHeight uint32
+	DeviceClass  string
+	BatterySaver bool
 }
 
 type NewParticipantCallback func(
@@ -77,6 +109,11 @@ type Router interface {
 	GetNodeForRoom(ctx context.Context, roomName livekit.RoomName) (*livekit.Node, error)
 	SetNodeForRoom(ctx context.Context, roomName livekit.RoomName, nodeId livekit.NodeID) error
 	ClearRoomState(ctx context.Context, roomName livekit.RoomName) error
+	// HostsRoom tells whether a node hosts the room, either as the node the room was placed on or as a node media
+	// of the room is relayed to
+	HostsRoom(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) (bool, error)
+	// RemoveEdgeNodeForRoom is called by a node media of the room is relayed to once it no longer hosts the room
+	RemoveEdgeNodeForRoom(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error
 
 	GetRegion() string
 
@@ -98,13 +135,15 @@ type MessageRouter interface {
 	// Write a message to a participant or room
 	WriteParticipantRTC(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) error
 	WriteRoomRTC(ctx context.Context, roomName livekit.RoomName, msg *livekit.RTCNodeMessage) error
+	// Write a message to a node, the participant keys of the message are left as they are
+	WriteNodeRTC(ctx context.Context, rtcNodeID string, msg *livekit.RTCNodeMessage) error
 }
 
-func CreateRouter(config *config.Config, rc redis.UniversalClient, node LocalNode, signalClient SignalClient) Router {
+func CreateRouter(current *config.Current, rc redis.UniversalClient, node LocalNode, signalClient SignalClient) Router {
 	lr := NewLocalRouter(node, signalClient)
 
 	if rc != nil {
-		return NewRedisRouter(config, lr, rc)
+		return NewRedisRouter(current, lr, rc)
 	}
 
 	// local routing and store
@@ -112,6 +151,37 @@ func CreateRouter(config *config.Config, rc redis.UniversalClient, node LocalNod
 	return lr
 }
 
+// Client capabilities, the certificate fingerprint, device hints, the audio only downlink setting, the trace
+// context and the client location are not part of StartSession yet, they are appended as extra fields that nodes
+// unaware of them skip:
+//
+//	StartSession.client_capabilities = 100; (repeated string)
+//	StartSession.fingerprint = 101;
+//	StartSession.screen_width = 102;
+//	StartSession.screen_height = 103;
+//	StartSession.device_class = 104;
+//	StartSession.battery_saver = 105;
+//	StartSession.audio_only_downlink = 106;
+//	StartSession.trace_parent = 107;
+//	StartSession.client_location = 108; (message: country_code = 1, region = 2, city = 3, latitude = 4, longitude = 5)
+const (
+	startSessionCapabilitiesField protowire.Number = 100
+	startSessionFingerprintField  protowire.Number = 101
+	startSessionScreenWidthField  protowire.Number = 102
+	startSessionScreenHeightField protowire.Number = 103
+	startSessionDeviceClassField  protowire.Number = 104
+	startSessionBatterySaverField protowire.Number = 105
+	startSessionAudioOnlyField    protowire.Number = 106
+	startSessionTraceParentField  protowire.Number = 107
+	startSessionLocationField     protowire.Number = 108
+
+	locationCountryCodeField protowire.Number = 1
+	locationRegionField      protowire.Number = 2
+	locationCityField        protowire.Number = 3
+	locationLatitudeField    protowire.Number = 4
+	locationLongitudeField   protowire.Number = 5
+)
+
 func (pi *ParticipantInit) ToStartSession(roomName livekit.RoomName, connectionID livekit.ConnectionID) (*livekit.StartSession, error) {
 	claims, err := json.Marshal(pi.Grants)
 	if err != nil {
@@ -136,6 +206,47 @@ func (pi *ParticipantInit) ToStartSession(roomName livekit.RoomName, connectionI
 		subscriberAllowPause := *pi.SubscriberAllowPause
 		ss.SubscriberAllowPause = &subscriberAllowPause
 	}
+	if len(pi.Capabilities) != 0 || pi.Fingerprint != "" || pi.DeviceHints != (DeviceHints{}) || pi.AudioOnlyDownlink || pi.TraceParent != "" || pi.ClientLocation != nil {
+		m := ss.ProtoReflect()
+		unknown := m.GetUnknown()
+		for _, capability := range pi.Capabilities {
+			unknown = protowire.AppendTag(unknown, startSessionCapabilitiesField, protowire.BytesType)
+			unknown = protowire.AppendString(unknown, capability)
+		}
+		if pi.Fingerprint != "" {
+			unknown = protowire.AppendTag(unknown, startSessionFingerprintField, protowire.BytesType)
+			unknown = protowire.AppendString(unknown, pi.Fingerprint)
+		}
+		if pi.DeviceHints.ScreenWidth != 0 {
+			unknown = protowire.AppendTag(unknown, startSessionScreenWidthField, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, uint64(pi.DeviceHints.ScreenWidth))
+		}
+		if pi.DeviceHints.ScreenHeight != 0 {
+			unknown = protowire.AppendTag(unknown, startSessionScreenHeightField, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, uint64(pi.DeviceHints.ScreenHeight))
+		}
+		if pi.DeviceHints.DeviceClass != "" {
+			unknown = protowire.AppendTag(unknown, startSessionDeviceClassField, protowire.BytesType)
+			unknown = protowire.AppendString(unknown, pi.DeviceHints.DeviceClass)
+		}
+		if pi.DeviceHints.BatterySaver {
+			unknown = protowire.AppendTag(unknown, startSessionBatterySaverField, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, protowire.EncodeBool(true))
+		}
+		if pi.AudioOnlyDownlink {
+			unknown = protowire.AppendTag(unknown, startSessionAudioOnlyField, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, protowire.EncodeBool(true))
+		}
+		if pi.TraceParent != "" {
+			unknown = protowire.AppendTag(unknown, startSessionTraceParentField, protowire.BytesType)
+			unknown = protowire.AppendString(unknown, pi.TraceParent)
+		}
+		if pi.ClientLocation != nil {
+			unknown = protowire.AppendTag(unknown, startSessionLocationField, protowire.BytesType)
+			unknown = protowire.AppendBytes(unknown, appendLocation(nil, pi.ClientLocation))
+		}
+		m.SetUnknown(unknown)
+	}
 
 	return ss, nil
 }
@@ -162,6 +273,143 @@ func ParticipantInitFromStartSession(ss *livekit.StartSession, region string) (*
 		subscriberAllowPause := *ss.SubscriberAllowPause
 		pi.SubscriberAllowPause = &subscriberAllowPause
 	}
+	readStartSessionExtensions(ss, pi)
 
 	return pi, nil
 }
+
+// readStartSessionExtensions reads the extra fields of a StartSession into the ParticipantInit
+func readStartSessionExtensions(ss *livekit.StartSession, pi *ParticipantInit) {
+	unknown := ss.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return
+		}
+		unknown = unknown[n:]
+
+		if typ == protowire.BytesType && (num == startSessionCapabilitiesField || num == startSessionFingerprintField || num == startSessionDeviceClassField || num == startSessionTraceParentField) {
+			value, m := protowire.ConsumeString(unknown)
+			if m < 0 {
+				return
+			}
+			switch num {
+			case startSessionCapabilitiesField:
+				pi.Capabilities = append(pi.Capabilities, value)
+			case startSessionFingerprintField:
+				pi.Fingerprint = value
+			case startSessionTraceParentField:
+				pi.TraceParent = value
+			default:
+				pi.DeviceHints.DeviceClass = value
+			}
+			unknown = unknown[m:]
+			continue
+		}
+		if typ == protowire.BytesType && num == startSessionLocationField {
+			value, m := protowire.ConsumeBytes(unknown)
+			if m < 0 {
+				return
+			}
+			pi.ClientLocation = consumeLocation(value)
+			unknown = unknown[m:]
+			continue
+		}
+		if typ == protowire.VarintType && (num == startSessionScreenWidthField || num == startSessionScreenHeightField || num == startSessionBatterySaverField || num == startSessionAudioOnlyField) {
+			value, m := protowire.ConsumeVarint(unknown)
+			if m < 0 {
+				return
+			}
+			switch num {
+			case startSessionScreenWidthField:
+				pi.DeviceHints.ScreenWidth = uint32(value)
+			case startSessionScreenHeightField:
+				pi.DeviceHints.ScreenHeight = uint32(value)
+			case startSessionBatterySaverField:
+				pi.DeviceHints.BatterySaver = protowire.DecodeBool(value)
+			default:
+				pi.AudioOnlyDownlink = protowire.DecodeBool(value)
+			}
+			unknown = unknown[m:]
+			continue
+		}
+
+		m := protowire.ConsumeFieldValue(num, typ, unknown)
+		if m < 0 {
+			return
+		}
+		unknown = unknown[m:]
+	}
+}
+
+func appendLocation(b []byte, loc *geoip.Location) []byte {
+	for _, field := range []struct {
+		num   protowire.Number
+		value string
+	}{
+		{locationCountryCodeField, loc.CountryCode},
+		{locationRegionField, loc.Region},
+		{locationCityField, loc.City},
+	} {
+		if field.value != "" {
+			b = protowire.AppendTag(b, field.num, protowire.BytesType)
+			b = protowire.AppendString(b, field.value)
+		}
+	}
+	if loc.HasCoordinates {
+		b = protowire.AppendTag(b, locationLatitudeField, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(loc.Latitude))
+		b = protowire.AppendTag(b, locationLongitudeField, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(loc.Longitude))
+	}
+	return b
+}
+
+// consumeLocation reads what could be read of a location, coordinates are only set when both are present
+func consumeLocation(b []byte) *geoip.Location {
+	loc := &geoip.Location{}
+	var hasLatitude, hasLongitude bool
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+
+		switch {
+		case typ == protowire.BytesType && (num == locationCountryCodeField || num == locationRegionField || num == locationCityField):
+			value, m := protowire.ConsumeString(b)
+			if m < 0 {
+				return loc
+			}
+			switch num {
+			case locationCountryCodeField:
+				loc.CountryCode = value
+			case locationRegionField:
+				loc.Region = value
+			default:
+				loc.City = value
+			}
+			b = b[m:]
+		case typ == protowire.Fixed64Type && (num == locationLatitudeField || num == locationLongitudeField):
+			value, m := protowire.ConsumeFixed64(b)
+			if m < 0 {
+				return loc
+			}
+			if num == locationLatitudeField {
+				loc.Latitude, hasLatitude = math.Float64frombits(value), true
+			} else {
+				loc.Longitude, hasLongitude = math.Float64frombits(value), true
+			}
+			b = b[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, b)
+			if m < 0 {
+				return loc
+			}
+			b = b[m:]
+		}
+	}
+	loc.HasCoordinates = hasLatitude && hasLongitude
+	return loc
+}
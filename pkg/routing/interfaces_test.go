@@ -0,0 +1,74 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/geoip"
+)
+
+func TestStartSessionExtensions(t *testing.T) {
+	pi := &ParticipantInit{
+		Identity:     "identity",
+		Client:       &livekit.ClientInfo{Protocol: 9},
+		Grants:       &auth.ClaimGrants{},
+		Capabilities: []string{"delta_roster", "not_known_yet"},
+		Fingerprint:  "sha-256 AB:CD",
+		DeviceHints: DeviceHints{
+			ScreenWidth:  1170,
+			ScreenHeight: 2532,
+			DeviceClass:  DeviceClassMobile,
+			BatterySaver: true,
+		},
+		AudioOnlyDownlink: true,
+		TraceParent:       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		ClientLocation: &geoip.Location{
+			CountryCode:    "GB",
+			Region:         "ENG",
+			City:           "London",
+			Latitude:       51.5142,
+			Longitude:      -0.0931,
+			HasCoordinates: true,
+		},
+	}
+	ss, err := pi.ToStartSession("room", "connection")
+	require.NoError(t, err)
+
+	// carried between nodes
+	data, err := proto.Marshal(ss)
+	require.NoError(t, err)
+	decoded := &livekit.StartSession{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+
+	decodedPI, err := ParticipantInitFromStartSession(decoded, "region")
+	require.NoError(t, err)
+	require.Equal(t, livekit.ParticipantIdentity("identity"), decodedPI.Identity)
+	require.Equal(t, []string{"delta_roster", "not_known_yet"}, decodedPI.Capabilities)
+	require.Equal(t, "sha-256 AB:CD", decodedPI.Fingerprint)
+	require.Equal(t, pi.DeviceHints, decodedPI.DeviceHints)
+	require.True(t, decodedPI.AudioOnlyDownlink)
+	require.Equal(t, pi.TraceParent, decodedPI.TraceParent)
+	require.Equal(t, pi.ClientLocation, decodedPI.ClientLocation)
+
+	pi.Capabilities = nil
+	pi.Fingerprint = ""
+	pi.DeviceHints = DeviceHints{}
+	pi.AudioOnlyDownlink = false
+	pi.TraceParent = ""
+	pi.ClientLocation = nil
+	ss, err = pi.ToStartSession("room", "connection")
+	require.NoError(t, err)
+	decodedPI, err = ParticipantInitFromStartSession(ss, "region")
+	require.NoError(t, err)
+	require.Empty(t, decodedPI.Capabilities)
+	require.Empty(t, decodedPI.Fingerprint)
+	require.Zero(t, decodedPI.DeviceHints)
+	require.False(t, decodedPI.AudioOnlyDownlink)
+	require.Empty(t, decodedPI.TraceParent)
+	require.Nil(t, decodedPI.ClientLocation)
+}
@@ -58,6 +58,14 @@ func (r *LocalRouter) ClearRoomState(_ context.Context, _ livekit.RoomName) erro
 	return nil
 }
 
+func (r *LocalRouter) HostsRoom(_ context.Context, _ livekit.RoomName, nodeID livekit.NodeID) (bool, error) {
+	return nodeID == livekit.NodeID(r.currentNode.Id), nil
+}
+
+func (r *LocalRouter) RemoveEdgeNodeForRoom(_ context.Context, _ livekit.RoomName, _ livekit.NodeID) error {
+	return nil
+}
+
 func (r *LocalRouter) RegisterNode() error {
 	return nil
 }
@@ -20,6 +20,11 @@ const (
 
 var redisCtx = context.Background()
 
+// nodes hosting a room in addition to the one it was placed on, set
+func roomEdgeNodesKey(roomName livekit.RoomName) string {
+	return "room_edge_nodes:" + string(roomName)
+}
+
 // location of the participant's RTC connection, hash
 func participantRTCKey(participantKey livekit.ParticipantKey) string {
 	return "participant_rtc:" + string(participantKey)
@@ -34,6 +34,7 @@ const (
 type RedisRouter struct {
 	*LocalRouter
 
+	current        *config.Current
 	rc             redis.UniversalClient
 	usePSRPCSignal bool
 	ctx            context.Context
@@ -46,16 +47,22 @@ type RedisRouter struct {
 	cancel func()
 }
 
-func NewRedisRouter(config *config.Config, lr *LocalRouter, rc redis.UniversalClient) *RedisRouter {
+func NewRedisRouter(current *config.Current, lr *LocalRouter, rc redis.UniversalClient) *RedisRouter {
 	rr := &RedisRouter{
 		LocalRouter:    lr,
+		current:        current,
 		rc:             rc,
-		usePSRPCSignal: config.SignalRelay.Enabled,
+		usePSRPCSignal: current.Get().SignalRelay.Enabled,
 	}
 	rr.ctx, rr.cancel = context.WithCancel(context.Background())
 	return rr
 }
 
+// config returns the configuration as last reloaded, e.g. for the limits nodes are full at
+func (r *RedisRouter) config() *config.Config {
+	return r.current.Get()
+}
+
 func (r *RedisRouter) RegisterNode() error {
 	r.nodeMu.RLock()
 	data, err := proto.Marshal((*livekit.Node)(r.currentNode))
@@ -108,6 +115,34 @@ func (r *RedisRouter) ClearRoomState(_ context.Context, roomName livekit.RoomNam
 	if err := r.rc.HDel(context.Background(), NodeRoomKey, string(roomName)).Err(); err != nil {
 		return errors.Wrap(err, "could not clear room state")
 	}
+	if err := r.rc.Del(context.Background(), roomEdgeNodesKey(roomName)).Err(); err != nil {
+		return errors.Wrap(err, "could not clear room state")
+	}
+	return nil
+}
+
+func (r *RedisRouter) HostsRoom(_ context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) (bool, error) {
+	originID, err := r.rc.HGet(r.ctx, NodeRoomKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "could not get node for room")
+	}
+	if originID == string(nodeID) {
+		return true, nil
+	}
+
+	isEdge, err := r.rc.SIsMember(r.ctx, roomEdgeNodesKey(roomName), string(nodeID)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get edge nodes for room")
+	}
+	return isEdge, nil
+}
+
+func (r *RedisRouter) RemoveEdgeNodeForRoom(_ context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	if err := r.rc.SRem(context.Background(), roomEdgeNodesKey(roomName), string(nodeID)).Err(); err != nil {
+		return errors.Wrap(err, "could not remove edge node for room")
+	}
 	return nil
 }
 
@@ -148,6 +183,9 @@ func (r *RedisRouter) StartParticipantSignal(ctx context.Context, roomName livek
 	if err != nil {
 		return
 	}
+	if r.config().MediaRelay.Port != 0 {
+		rtcNode = r.placeParticipant(roomName, pi, rtcNode, RegionHintFromContext(ctx))
+	}
 
 	if r.usePSRPCSignal {
 		connectionID, reqSink, resSource, err = r.StartParticipantSignalWithNodeID(ctx, roomName, pi, livekit.NodeID(rtcNode.Id))
@@ -191,6 +229,52 @@ func (r *RedisRouter) StartParticipantSignal(ctx context.Context, roomName livek
 	return connectionID, sink, resChan, nil
 }
 
+// placeParticipant picks the node hosting the room a participant is connected to. When the node the room was placed
+// on is full, the participant is placed on another node the media of the room is relayed to, preferably in the
+// hinted region. The hint is not considered otherwise, participants stay on the room's node while it has capacity
+func (r *RedisRouter) placeParticipant(roomName livekit.RoomName, pi ParticipantInit, origin *livekit.Node, regionHint string) *livekit.Node {
+	if pi.Reconnect {
+		// resuming sessions stay on their node
+		nodeID, err := r.getParticipantRTCNode(ParticipantKeyLegacy(roomName, pi.Identity), ParticipantKey(roomName, pi.Identity))
+		if err != nil || nodeID == origin.Id {
+			return origin
+		}
+		if hosts, err := r.HostsRoom(r.ctx, roomName, livekit.NodeID(nodeID)); err != nil || !hosts {
+			return origin
+		}
+		if node, err := r.GetNode(livekit.NodeID(nodeID)); err == nil && selector.IsAvailable(node) {
+			return node
+		}
+		return origin
+	}
+
+	if !isNodeFull(r.config(), origin) {
+		return origin
+	}
+
+	edgeNodeIDs, err := r.rc.SMembers(r.ctx, roomEdgeNodesKey(roomName)).Result()
+	if err != nil {
+		logger.Warnw("could not get edge nodes for room", err, "room", roomName)
+		return origin
+	}
+	nodes, err := r.ListNodes()
+	if err != nil {
+		logger.Warnw("could not list nodes", err)
+		return origin
+	}
+	edge := selectEdgeNode(r.config(), origin, edgeNodeIDs, nodes, regionHint)
+	if edge == nil {
+		return origin
+	}
+	if err := r.rc.SAdd(r.ctx, roomEdgeNodesKey(roomName), edge.Id).Err(); err != nil {
+		logger.Warnw("could not add edge node for room", err, "room", roomName)
+		return origin
+	}
+
+	logger.Infow("placing participant on edge node", "room", roomName, "participant", pi.Identity, "node", edge.Id, "originNode", origin.Id)
+	return edge
+}
+
 func (r *RedisRouter) WriteParticipantRTC(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) error {
 	pkey := ParticipantKeyLegacy(roomName, identity)
 	pkeyB62 := ParticipantKey(roomName, identity)
@@ -229,14 +313,21 @@ func (r *RedisRouter) startParticipantRTC(ss *livekit.StartSession, participantK
 	}
 
 	if rtcNode.Id != r.currentNode.Id {
-		err = ErrIncorrectRTCNode
-		logger.Errorw("called participant on incorrect node", err,
-			"rtcNode", rtcNode,
-		)
-		return err
+		// the room may be relayed to this node
+		hosts, err := r.HostsRoom(r.ctx, livekit.RoomName(ss.RoomName), livekit.NodeID(r.currentNode.Id))
+		if err != nil {
+			return err
+		}
+		if !hosts {
+			err = ErrIncorrectRTCNode
+			logger.Errorw("called participant on incorrect node", err,
+				"rtcNode", rtcNode,
+			)
+			return err
+		}
 	}
 
-	if err := r.SetParticipantRTCNode(participantKey, participantKeyB62, rtcNode.Id); err != nil {
+	if err := r.SetParticipantRTCNode(participantKey, participantKeyB62, r.currentNode.Id); err != nil {
 		return err
 	}
 
@@ -0,0 +1,21 @@
+package routing
+
+import (
+	"context"
+)
+
+type regionHintKey struct{}
+
+// WithRegionHint returns a context carrying the region a client asked rooms and participants to be placed in
+func WithRegionHint(ctx context.Context, region string) context.Context {
+	if region == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, regionHintKey{}, region)
+}
+
+// RegionHintFromContext returns the region hint of a context, empty when there is none
+func RegionHintFromContext(ctx context.Context) string {
+	region, _ := ctx.Value(regionHintKey{}).(string)
+	return region
+}
@@ -50,6 +50,21 @@ type FakeRouter struct {
 	getRegionReturnsOnCall map[int]struct {
 		result1 string
 	}
+	HostsRoomStub        func(context.Context, livekit.RoomName, livekit.NodeID) (bool, error)
+	hostsRoomMutex       sync.RWMutex
+	hostsRoomArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.NodeID
+	}
+	hostsRoomReturns struct {
+		result1 bool
+		result2 error
+	}
+	hostsRoomReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	ListNodesStub        func() ([]*livekit.Node, error)
 	listNodesMutex       sync.RWMutex
 	listNodesArgsForCall []struct {
@@ -92,6 +107,19 @@ type FakeRouter struct {
 	removeDeadNodesReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RemoveEdgeNodeForRoomStub        func(context.Context, livekit.RoomName, livekit.NodeID) error
+	removeEdgeNodeForRoomMutex       sync.RWMutex
+	removeEdgeNodeForRoomArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.NodeID
+	}
+	removeEdgeNodeForRoomReturns struct {
+		result1 error
+	}
+	removeEdgeNodeForRoomReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SetNodeForRoomStub        func(context.Context, livekit.RoomName, livekit.NodeID) error
 	setNodeForRoomMutex       sync.RWMutex
 	setNodeForRoomArgsForCall []struct {
@@ -148,6 +176,19 @@ type FakeRouter struct {
 	unregisterNodeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	WriteNodeRTCStub        func(context.Context, string, *livekit.RTCNodeMessage) error
+	writeNodeRTCMutex       sync.RWMutex
+	writeNodeRTCArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *livekit.RTCNodeMessage
+	}
+	writeNodeRTCReturns struct {
+		result1 error
+	}
+	writeNodeRTCReturnsOnCall map[int]struct {
+		result1 error
+	}
 	WriteParticipantRTCStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, *livekit.RTCNodeMessage) error
 	writeParticipantRTCMutex       sync.RWMutex
 	writeParticipantRTCArgsForCall []struct {
@@ -383,6 +424,72 @@ func (fake *FakeRouter) GetRegionReturnsOnCall(i int, result1 string) {
 	}{result1}
 }
 
+func (fake *FakeRouter) HostsRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.NodeID) (bool, error) {
+	fake.hostsRoomMutex.Lock()
+	ret, specificReturn := fake.hostsRoomReturnsOnCall[len(fake.hostsRoomArgsForCall)]
+	fake.hostsRoomArgsForCall = append(fake.hostsRoomArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.NodeID
+	}{arg1, arg2, arg3})
+	stub := fake.HostsRoomStub
+	fakeReturns := fake.hostsRoomReturns
+	fake.recordInvocation("HostsRoom", []interface{}{arg1, arg2, arg3})
+	fake.hostsRoomMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRouter) HostsRoomCallCount() int {
+	fake.hostsRoomMutex.RLock()
+	defer fake.hostsRoomMutex.RUnlock()
+	return len(fake.hostsRoomArgsForCall)
+}
+
+func (fake *FakeRouter) HostsRoomCalls(stub func(context.Context, livekit.RoomName, livekit.NodeID) (bool, error)) {
+	fake.hostsRoomMutex.Lock()
+	defer fake.hostsRoomMutex.Unlock()
+	fake.HostsRoomStub = stub
+}
+
+func (fake *FakeRouter) HostsRoomArgsForCall(i int) (context.Context, livekit.RoomName, livekit.NodeID) {
+	fake.hostsRoomMutex.RLock()
+	defer fake.hostsRoomMutex.RUnlock()
+	argsForCall := fake.hostsRoomArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRouter) HostsRoomReturns(result1 bool, result2 error) {
+	fake.hostsRoomMutex.Lock()
+	defer fake.hostsRoomMutex.Unlock()
+	fake.HostsRoomStub = nil
+	fake.hostsRoomReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRouter) HostsRoomReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.hostsRoomMutex.Lock()
+	defer fake.hostsRoomMutex.Unlock()
+	fake.HostsRoomStub = nil
+	if fake.hostsRoomReturnsOnCall == nil {
+		fake.hostsRoomReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.hostsRoomReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeRouter) ListNodes() ([]*livekit.Node, error) {
 	fake.listNodesMutex.Lock()
 	ret, specificReturn := fake.listNodesReturnsOnCall[len(fake.listNodesArgsForCall)]
@@ -609,6 +716,69 @@ func (fake *FakeRouter) RemoveDeadNodesReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRouter) RemoveEdgeNodeForRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.NodeID) error {
+	fake.removeEdgeNodeForRoomMutex.Lock()
+	ret, specificReturn := fake.removeEdgeNodeForRoomReturnsOnCall[len(fake.removeEdgeNodeForRoomArgsForCall)]
+	fake.removeEdgeNodeForRoomArgsForCall = append(fake.removeEdgeNodeForRoomArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.NodeID
+	}{arg1, arg2, arg3})
+	stub := fake.RemoveEdgeNodeForRoomStub
+	fakeReturns := fake.removeEdgeNodeForRoomReturns
+	fake.recordInvocation("RemoveEdgeNodeForRoom", []interface{}{arg1, arg2, arg3})
+	fake.removeEdgeNodeForRoomMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) RemoveEdgeNodeForRoomCallCount() int {
+	fake.removeEdgeNodeForRoomMutex.RLock()
+	defer fake.removeEdgeNodeForRoomMutex.RUnlock()
+	return len(fake.removeEdgeNodeForRoomArgsForCall)
+}
+
+func (fake *FakeRouter) RemoveEdgeNodeForRoomCalls(stub func(context.Context, livekit.RoomName, livekit.NodeID) error) {
+	fake.removeEdgeNodeForRoomMutex.Lock()
+	defer fake.removeEdgeNodeForRoomMutex.Unlock()
+	fake.RemoveEdgeNodeForRoomStub = stub
+}
+
+func (fake *FakeRouter) RemoveEdgeNodeForRoomArgsForCall(i int) (context.Context, livekit.RoomName, livekit.NodeID) {
+	fake.removeEdgeNodeForRoomMutex.RLock()
+	defer fake.removeEdgeNodeForRoomMutex.RUnlock()
+	argsForCall := fake.removeEdgeNodeForRoomArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRouter) RemoveEdgeNodeForRoomReturns(result1 error) {
+	fake.removeEdgeNodeForRoomMutex.Lock()
+	defer fake.removeEdgeNodeForRoomMutex.Unlock()
+	fake.RemoveEdgeNodeForRoomStub = nil
+	fake.removeEdgeNodeForRoomReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) RemoveEdgeNodeForRoomReturnsOnCall(i int, result1 error) {
+	fake.removeEdgeNodeForRoomMutex.Lock()
+	defer fake.removeEdgeNodeForRoomMutex.Unlock()
+	fake.RemoveEdgeNodeForRoomStub = nil
+	if fake.removeEdgeNodeForRoomReturnsOnCall == nil {
+		fake.removeEdgeNodeForRoomReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.removeEdgeNodeForRoomReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRouter) SetNodeForRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.NodeID) error {
 	fake.setNodeForRoomMutex.Lock()
 	ret, specificReturn := fake.setNodeForRoomReturnsOnCall[len(fake.setNodeForRoomArgsForCall)]
@@ -874,6 +1044,69 @@ func (fake *FakeRouter) UnregisterNodeReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeRouter) WriteNodeRTC(arg1 context.Context, arg2 string, arg3 *livekit.RTCNodeMessage) error {
+	fake.writeNodeRTCMutex.Lock()
+	ret, specificReturn := fake.writeNodeRTCReturnsOnCall[len(fake.writeNodeRTCArgsForCall)]
+	fake.writeNodeRTCArgsForCall = append(fake.writeNodeRTCArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 *livekit.RTCNodeMessage
+	}{arg1, arg2, arg3})
+	stub := fake.WriteNodeRTCStub
+	fakeReturns := fake.writeNodeRTCReturns
+	fake.recordInvocation("WriteNodeRTC", []interface{}{arg1, arg2, arg3})
+	fake.writeNodeRTCMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRouter) WriteNodeRTCCallCount() int {
+	fake.writeNodeRTCMutex.RLock()
+	defer fake.writeNodeRTCMutex.RUnlock()
+	return len(fake.writeNodeRTCArgsForCall)
+}
+
+func (fake *FakeRouter) WriteNodeRTCCalls(stub func(context.Context, string, *livekit.RTCNodeMessage) error) {
+	fake.writeNodeRTCMutex.Lock()
+	defer fake.writeNodeRTCMutex.Unlock()
+	fake.WriteNodeRTCStub = stub
+}
+
+func (fake *FakeRouter) WriteNodeRTCArgsForCall(i int) (context.Context, string, *livekit.RTCNodeMessage) {
+	fake.writeNodeRTCMutex.RLock()
+	defer fake.writeNodeRTCMutex.RUnlock()
+	argsForCall := fake.writeNodeRTCArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRouter) WriteNodeRTCReturns(result1 error) {
+	fake.writeNodeRTCMutex.Lock()
+	defer fake.writeNodeRTCMutex.Unlock()
+	fake.WriteNodeRTCStub = nil
+	fake.writeNodeRTCReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRouter) WriteNodeRTCReturnsOnCall(i int, result1 error) {
+	fake.writeNodeRTCMutex.Lock()
+	defer fake.writeNodeRTCMutex.Unlock()
+	fake.WriteNodeRTCStub = nil
+	if fake.writeNodeRTCReturnsOnCall == nil {
+		fake.writeNodeRTCReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.writeNodeRTCReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeRouter) WriteParticipantRTC(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity, arg4 *livekit.RTCNodeMessage) error {
 	fake.writeParticipantRTCMutex.Lock()
 	ret, specificReturn := fake.writeParticipantRTCReturnsOnCall[len(fake.writeParticipantRTCArgsForCall)]
@@ -1012,6 +1245,8 @@ func (fake *FakeRouter) Invocations() map[string][][]interface{} {
 	defer fake.getNodeForRoomMutex.RUnlock()
 	fake.getRegionMutex.RLock()
 	defer fake.getRegionMutex.RUnlock()
+	fake.hostsRoomMutex.RLock()
+	defer fake.hostsRoomMutex.RUnlock()
 	fake.listNodesMutex.RLock()
 	defer fake.listNodesMutex.RUnlock()
 	fake.onNewParticipantRTCMutex.RLock()
@@ -1022,6 +1257,8 @@ func (fake *FakeRouter) Invocations() map[string][][]interface{} {
 	defer fake.registerNodeMutex.RUnlock()
 	fake.removeDeadNodesMutex.RLock()
 	defer fake.removeDeadNodesMutex.RUnlock()
+	fake.removeEdgeNodeForRoomMutex.RLock()
+	defer fake.removeEdgeNodeForRoomMutex.RUnlock()
 	fake.setNodeForRoomMutex.RLock()
 	defer fake.setNodeForRoomMutex.RUnlock()
 	fake.startMutex.RLock()
@@ -1032,6 +1269,8 @@ func (fake *FakeRouter) Invocations() map[string][][]interface{} {
 	defer fake.stopMutex.RUnlock()
 	fake.unregisterNodeMutex.RLock()
 	defer fake.unregisterNodeMutex.RUnlock()
+	fake.writeNodeRTCMutex.RLock()
+	defer fake.writeNodeRTCMutex.RUnlock()
 	fake.writeParticipantRTCMutex.RLock()
 	defer fake.writeParticipantRTCMutex.RUnlock()
 	fake.writeRoomRTCMutex.RLock()
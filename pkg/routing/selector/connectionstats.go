@@ -0,0 +1,208 @@
+package selector
+
+import (
+	"github.com/thoas/go-funk"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// ICE connection outcomes are not part of NodeStats yet, they are appended as extra fields that nodes unaware of
+// them skip:
+//
+//	message ICEConnectionStats {
+//	  string network_type = 1;
+//	  string client_region = 2;
+//	  uint32 attempts = 3;
+//	  uint32 successes = 4;
+//	}
+//	NodeStats.ice_connection_stats = 100; // repeated ICEConnectionStats
+const (
+	nodeStatsICEConnectionStatsField protowire.Number = 100
+
+	iceConnectionStatsNetworkTypeField  protowire.Number = 1
+	iceConnectionStatsClientRegionField protowire.Number = 2
+	iceConnectionStatsAttemptsField     protowire.Number = 3
+	iceConnectionStatsSuccessesField    protowire.Number = 4
+)
+
+// ICEConnectionStats counts the ICE connections clients of a network type and region attempted to a node recently,
+// and how many of them succeeded
+type ICEConnectionStats struct {
+	NetworkType  string
+	ClientRegion string
+	Attempts     uint32
+	Successes    uint32
+}
+
+// SetICEConnectionStats attaches ICE connection outcomes to node stats, replacing those attached before
+func SetICEConnectionStats(stats *livekit.NodeStats, connectionStats []*ICEConnectionStats) {
+	m := stats.ProtoReflect()
+	unknown := removeField(m.GetUnknown(), nodeStatsICEConnectionStatsField)
+	for _, cs := range connectionStats {
+		var b []byte
+		if cs.NetworkType != "" {
+			b = protowire.AppendTag(b, iceConnectionStatsNetworkTypeField, protowire.BytesType)
+			b = protowire.AppendString(b, cs.NetworkType)
+		}
+		if cs.ClientRegion != "" {
+			b = protowire.AppendTag(b, iceConnectionStatsClientRegionField, protowire.BytesType)
+			b = protowire.AppendString(b, cs.ClientRegion)
+		}
+		b = protowire.AppendTag(b, iceConnectionStatsAttemptsField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(cs.Attempts))
+		b = protowire.AppendTag(b, iceConnectionStatsSuccessesField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(cs.Successes))
+
+		unknown = protowire.AppendTag(unknown, nodeStatsICEConnectionStatsField, protowire.BytesType)
+		unknown = protowire.AppendBytes(unknown, b)
+	}
+	m.SetUnknown(unknown)
+}
+
+// GetICEConnectionStats returns the ICE connection outcomes attached to node stats
+func GetICEConnectionStats(stats *livekit.NodeStats) []*ICEConnectionStats {
+	if stats == nil {
+		return nil
+	}
+
+	var connectionStats []*ICEConnectionStats
+	b := stats.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return connectionStats
+		}
+		b = b[n:]
+		if num == nodeStatsICEConnectionStatsField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return connectionStats
+			}
+			b = b[n:]
+			if cs := parseICEConnectionStats(v); cs != nil {
+				connectionStats = append(connectionStats, cs)
+			}
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return connectionStats
+		}
+		b = b[n:]
+	}
+	return connectionStats
+}
+
+func parseICEConnectionStats(b []byte) *ICEConnectionStats {
+	cs := &ICEConnectionStats{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil
+		}
+		b = b[n:]
+		switch {
+		case typ == protowire.BytesType && (num == iceConnectionStatsNetworkTypeField || num == iceConnectionStatsClientRegionField):
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return nil
+			}
+			if num == iceConnectionStatsNetworkTypeField {
+				cs.NetworkType = v
+			} else {
+				cs.ClientRegion = v
+			}
+			b = b[n:]
+		case typ == protowire.VarintType && (num == iceConnectionStatsAttemptsField || num == iceConnectionStatsSuccessesField):
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil
+			}
+			if num == iceConnectionStatsAttemptsField {
+				cs.Attempts = uint32(v)
+			} else {
+				cs.Successes = uint32(v)
+			}
+			b = b[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil
+			}
+			b = b[n:]
+		}
+	}
+	return cs
+}
+
+func removeField(b []byte, field protowire.Number) []byte {
+	var kept []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return kept
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return kept
+		}
+		if num != field {
+			kept = append(kept, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	return kept
+}
+
+// ICEFailureRate returns the rate of failed ICE connections to a node, of clients from a region when the node has
+// seen enough of them, of all clients otherwise. ok is false when there are not enough attempts to tell
+func ICEFailureRate(stats *livekit.NodeStats, clientRegion string, minAttempts int) (rate float32, ok bool) {
+	var attempts, successes, regionAttempts, regionSuccesses uint32
+	for _, cs := range GetICEConnectionStats(stats) {
+		attempts += cs.Attempts
+		successes += cs.Successes
+		if clientRegion != "" && cs.ClientRegion == clientRegion {
+			regionAttempts += cs.Attempts
+			regionSuccesses += cs.Successes
+		}
+	}
+
+	if minAttempts < 1 {
+		minAttempts = 1
+	}
+	switch {
+	case int(regionAttempts) >= minAttempts:
+		attempts, successes = regionAttempts, regionSuccesses
+	case int(attempts) < minAttempts:
+		return 0, false
+	}
+	if successes > attempts {
+		successes = attempts
+	}
+	return float32(attempts-successes) / float32(attempts), true
+}
+
+// HasElevatedICEFailureRate checks if ICE connections to a node fail more often than configured, e. g. because of
+// a broken load balancer in front of it
+func HasElevatedICEFailureRate(conf config.NodeSelectorConfig, node *livekit.Node, clientRegion string) bool {
+	if conf.MaxICEFailureRate <= 0 {
+		return false
+	}
+	rate, ok := ICEFailureRate(node.Stats, clientRegion, conf.MinICEAttempts)
+	return ok && rate > conf.MaxICEFailureRate
+}
+
+// ExcludeICEFailingNodes leaves out the nodes with an elevated ICE failure rate. All nodes are returned when every
+// available one has an elevated rate, as avoiding them all would leave nowhere to place a room
+func ExcludeICEFailingNodes(conf config.NodeSelectorConfig, nodes []*livekit.Node, clientRegion string) []*livekit.Node {
+	healthy := funk.Filter(nodes, func(node *livekit.Node) bool {
+		return !HasElevatedICEFailureRate(conf, node, clientRegion)
+	}).([]*livekit.Node)
+	if len(GetAvailableNodes(healthy)) == 0 {
+		return nodes
+	}
+	return healthy
+}
@@ -0,0 +1,95 @@
+package selector_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func nodeWithICEStats(id string, connectionStats ...*selector.ICEConnectionStats) *livekit.Node {
+	stats := &livekit.NodeStats{UpdatedAt: time.Now().Unix()}
+	selector.SetICEConnectionStats(stats, connectionStats)
+	return &livekit.Node{Id: id, State: livekit.NodeState_SERVING, Stats: stats}
+}
+
+func TestICEConnectionStats(t *testing.T) {
+	stats := &livekit.NodeStats{NumRooms: 3}
+	connectionStats := []*selector.ICEConnectionStats{
+		{NetworkType: "wifi", ClientRegion: "GB", Attempts: 10, Successes: 9},
+		{NetworkType: "cellular", Attempts: 4},
+	}
+	selector.SetICEConnectionStats(stats, connectionStats)
+	// setting again replaces
+	selector.SetICEConnectionStats(stats, connectionStats)
+
+	data, err := proto.Marshal(stats)
+	require.NoError(t, err)
+	decoded := &livekit.NodeStats{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+	require.Equal(t, int32(3), decoded.NumRooms)
+	require.Equal(t, connectionStats, selector.GetICEConnectionStats(decoded))
+
+	require.Empty(t, selector.GetICEConnectionStats(&livekit.NodeStats{}))
+	require.Empty(t, selector.GetICEConnectionStats(nil))
+}
+
+func TestICEFailureRate(t *testing.T) {
+	stats := nodeWithICEStats("node",
+		&selector.ICEConnectionStats{NetworkType: "wifi", ClientRegion: "GB", Attempts: 10, Successes: 2},
+		&selector.ICEConnectionStats{NetworkType: "wifi", ClientRegion: "US", Attempts: 30, Successes: 30},
+	).Stats
+
+	// the region of the client when it has enough attempts
+	rate, ok := selector.ICEFailureRate(stats, "GB", 10)
+	require.True(t, ok)
+	require.InDelta(t, 0.8, rate, 0.001)
+
+	// all clients otherwise
+	rate, ok = selector.ICEFailureRate(stats, "GB", 20)
+	require.True(t, ok)
+	require.InDelta(t, 0.2, rate, 0.001)
+	rate, ok = selector.ICEFailureRate(stats, "", 20)
+	require.True(t, ok)
+	require.InDelta(t, 0.2, rate, 0.001)
+
+	_, ok = selector.ICEFailureRate(stats, "", 50)
+	require.False(t, ok)
+	_, ok = selector.ICEFailureRate(&livekit.NodeStats{}, "", 0)
+	require.False(t, ok)
+}
+
+func TestExcludeICEFailingNodes(t *testing.T) {
+	conf := config.NodeSelectorConfig{MaxICEFailureRate: 0.5, MinICEAttempts: 10}
+	failing := nodeWithICEStats("failing", &selector.ICEConnectionStats{NetworkType: "wifi", ClientRegion: "GB", Attempts: 20, Successes: 2})
+	failingForGB := nodeWithICEStats("failing-gb",
+		&selector.ICEConnectionStats{NetworkType: "wifi", ClientRegion: "GB", Attempts: 10},
+		&selector.ICEConnectionStats{NetworkType: "wifi", ClientRegion: "US", Attempts: 100, Successes: 100},
+	)
+	healthy := nodeWithICEStats("healthy", &selector.ICEConnectionStats{NetworkType: "wifi", Attempts: 20, Successes: 19})
+	unknown := nodeWithICEStats("unknown", &selector.ICEConnectionStats{NetworkType: "wifi", Attempts: 5})
+	nodes := []*livekit.Node{failing, failingForGB, healthy, unknown}
+
+	require.Equal(t, []*livekit.Node{failingForGB, healthy, unknown}, selector.ExcludeICEFailingNodes(conf, nodes, "US"))
+	require.Equal(t, []*livekit.Node{healthy, unknown}, selector.ExcludeICEFailingNodes(conf, nodes, "GB"))
+
+	t.Run("recovers once rates normalize", func(t *testing.T) {
+		selector.SetICEConnectionStats(failing.Stats, []*selector.ICEConnectionStats{{NetworkType: "wifi", Attempts: 20, Successes: 20}})
+		require.Equal(t, []*livekit.Node{failing, healthy}, selector.ExcludeICEFailingNodes(conf, []*livekit.Node{failing, healthy}, ""))
+		selector.SetICEConnectionStats(failing.Stats, []*selector.ICEConnectionStats{{NetworkType: "wifi", Attempts: 20, Successes: 2}})
+	})
+
+	t.Run("all nodes when none are healthy", func(t *testing.T) {
+		require.Equal(t, []*livekit.Node{failing}, selector.ExcludeICEFailingNodes(conf, []*livekit.Node{failing}, ""))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Equal(t, nodes, selector.ExcludeICEFailingNodes(config.NodeSelectorConfig{}, nodes, "GB"))
+	})
+}
@@ -16,6 +16,11 @@ type NodeSelector interface {
 	SelectNode(nodes []*livekit.Node) (*livekit.Node, error)
 }
 
+// LocationSelector selects the node nearest to a location, e.g. that of the client creating a room
+type LocationSelector interface {
+	SelectNodeNear(nodes []*livekit.Node, lat float64, lon float64) (*livekit.Node, error)
+}
+
 func CreateNodeSelector(conf *config.Config) (NodeSelector, error) {
 	kind := conf.NodeSelector.Kind
 	if kind == "" {
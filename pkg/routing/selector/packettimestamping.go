@@ -0,0 +1,131 @@
+package selector
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// Packet timestamping is not part of NodeStats yet, it is appended as an extra field that nodes unaware of it skip:
+//
+//	message PacketTimestampingStats {
+//	  string mode = 1;
+//	  bool available = 2;
+//	  uint64 hardware_packets = 3;
+//	  uint64 software_packets = 4;
+//	}
+//	NodeStats.packet_timestamping = 101;
+const (
+	nodeStatsPacketTimestampingField protowire.Number = 101
+
+	packetTimestampingModeField            protowire.Number = 1
+	packetTimestampingAvailableField       protowire.Number = 2
+	packetTimestampingHardwarePacketsField protowire.Number = 3
+	packetTimestampingSoftwarePacketsField protowire.Number = 4
+)
+
+// PacketTimestampingStats tells whether a node takes the receive times of media packets from the kernel
+type PacketTimestampingStats struct {
+	// as configured, software or hardware
+	Mode string
+	// the sockets media is received on were set up for it
+	Available bool
+	// packets timestamped by the NIC and by the kernel since the node started
+	HardwarePackets uint64
+	SoftwarePackets uint64
+}
+
+// SetPacketTimestampingStats attaches packet timestamping to node stats, replacing what was attached before
+func SetPacketTimestampingStats(stats *livekit.NodeStats, pts *PacketTimestampingStats) {
+	m := stats.ProtoReflect()
+	unknown := removeField(m.GetUnknown(), nodeStatsPacketTimestampingField)
+	if pts != nil {
+		var b []byte
+		b = protowire.AppendTag(b, packetTimestampingModeField, protowire.BytesType)
+		b = protowire.AppendString(b, pts.Mode)
+		b = protowire.AppendTag(b, packetTimestampingAvailableField, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(pts.Available))
+		b = protowire.AppendTag(b, packetTimestampingHardwarePacketsField, protowire.VarintType)
+		b = protowire.AppendVarint(b, pts.HardwarePackets)
+		b = protowire.AppendTag(b, packetTimestampingSoftwarePacketsField, protowire.VarintType)
+		b = protowire.AppendVarint(b, pts.SoftwarePackets)
+
+		unknown = protowire.AppendTag(unknown, nodeStatsPacketTimestampingField, protowire.BytesType)
+		unknown = protowire.AppendBytes(unknown, b)
+	}
+	m.SetUnknown(unknown)
+}
+
+// GetPacketTimestampingStats returns the packet timestamping attached to node stats, nil when the node does not
+// timestamp packets
+func GetPacketTimestampingStats(stats *livekit.NodeStats) *PacketTimestampingStats {
+	if stats == nil {
+		return nil
+	}
+
+	var pts *PacketTimestampingStats
+	b := stats.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return pts
+		}
+		b = b[n:]
+		if num == nodeStatsPacketTimestampingField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return pts
+			}
+			b = b[n:]
+			pts = parsePacketTimestampingStats(v)
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return pts
+		}
+		b = b[n:]
+	}
+	return pts
+}
+
+func parsePacketTimestampingStats(b []byte) *PacketTimestampingStats {
+	pts := &PacketTimestampingStats{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil
+		}
+		b = b[n:]
+		switch {
+		case typ == protowire.BytesType && num == packetTimestampingModeField:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return nil
+			}
+			pts.Mode = v
+			b = b[n:]
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil
+			}
+			switch num {
+			case packetTimestampingAvailableField:
+				pts.Available = protowire.DecodeBool(v)
+			case packetTimestampingHardwarePacketsField:
+				pts.HardwarePackets = v
+			case packetTimestampingSoftwarePacketsField:
+				pts.SoftwarePackets = v
+			}
+			b = b[n:]
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil
+			}
+			b = b[n:]
+		}
+	}
+	return pts
+}
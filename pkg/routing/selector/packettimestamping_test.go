@@ -0,0 +1,41 @@
+package selector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func TestPacketTimestampingStats(t *testing.T) {
+	stats := &livekit.NodeStats{NumRooms: 3}
+	selector.SetICEConnectionStats(stats, []*selector.ICEConnectionStats{{NetworkType: "wifi", Attempts: 1}})
+	pts := &selector.PacketTimestampingStats{
+		Mode:            "hardware",
+		Available:       true,
+		HardwarePackets: 1000,
+		SoftwarePackets: 10,
+	}
+	selector.SetPacketTimestampingStats(stats, pts)
+	// setting again replaces
+	selector.SetPacketTimestampingStats(stats, pts)
+
+	data, err := proto.Marshal(stats)
+	require.NoError(t, err)
+	decoded := &livekit.NodeStats{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+	require.Equal(t, int32(3), decoded.NumRooms)
+	require.Equal(t, pts, selector.GetPacketTimestampingStats(decoded))
+	require.Len(t, selector.GetICEConnectionStats(decoded), 1)
+
+	selector.SetPacketTimestampingStats(decoded, nil)
+	require.Nil(t, selector.GetPacketTimestampingStats(decoded))
+	require.Len(t, selector.GetICEConnectionStats(decoded), 1)
+
+	require.Nil(t, selector.GetPacketTimestampingStats(&livekit.NodeStats{}))
+	require.Nil(t, selector.GetPacketTimestampingStats(nil))
+}
@@ -52,6 +52,19 @@ func NewRegionAwareSelector(currentRegion string, regions []config.RegionConfig,
 }
 
 func (s *RegionAwareSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node, error) {
+	return s.selectNearest(nodes, s.regionDistances)
+}
+
+// SelectNodeNear prefers available nodes that are closest to a location rather than to the current region
+func (s *RegionAwareSelector) SelectNodeNear(nodes []*livekit.Node, lat float64, lon float64) (*livekit.Node, error) {
+	regionDistances := make(map[string]float64, len(s.regions))
+	for _, region := range s.regions {
+		regionDistances[region.Name] = distanceBetween(lat, lon, region.Lat, region.Lon)
+	}
+	return s.selectNearest(nodes, regionDistances)
+}
+
+func (s *RegionAwareSelector) selectNearest(nodes []*livekit.Node, regionDistances map[string]float64) (*livekit.Node, error) {
 	nodes, err := s.SystemLoadSelector.filterNodes(nodes)
 	if err != nil {
 		return nil, err
@@ -66,7 +79,7 @@ func (s *RegionAwareSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node,
 			nearestNodes = append(nearestNodes, node)
 			continue
 		}
-		if dist, ok := s.regionDistances[node.Region]; ok {
+		if dist, ok := regionDistances[node.Region]; ok {
 			if dist < minDist {
 				minDist = dist
 				nearestRegion = node.Region
@@ -83,6 +96,19 @@ func (s *RegionAwareSelector) SelectNode(nodes []*livekit.Node) (*livekit.Node,
 	return SelectSortedNode(nodes, s.SortBy)
 }
 
+// NearestRegion returns the name of the region closest to a location, empty when there are no regions
+func NearestRegion(regions []config.RegionConfig, lat float64, lon float64) string {
+	nearest := ""
+	minDist := math.MaxFloat64
+	for _, region := range regions {
+		if dist := distanceBetween(lat, lon, region.Lat, region.Lon); dist < minDist {
+			minDist = dist
+			nearest = region.Name
+		}
+	}
+	return nearest
+}
+
 // haversine(θ) function
 func hsin(theta float64) float64 {
 	return math.Pow(math.Sin(theta/2), 2)
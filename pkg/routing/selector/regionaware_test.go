@@ -130,6 +130,25 @@ func TestRegionAwareRouting(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, node)
 	})
+
+	t.Run("picks node nearest to a location", func(t *testing.T) {
+		expectedNode := newTestNodeInRegion(regionSeattle, true)
+		nodes := []*livekit.Node{
+			newTestNodeInRegion(regionEast, true),
+			newTestNodeInRegion(regionWest, true),
+			expectedNode,
+		}
+		s, err := selector.NewRegionAwareSelector(regionEast, rc, sortBy)
+		require.NoError(t, err)
+		s.SysloadLimit = loadLimit
+
+		// Vancouver
+		node, err := s.SelectNodeNear(nodes, 49.2827, -123.1207)
+		require.NoError(t, err)
+		require.Equal(t, expectedNode, node)
+		require.Equal(t, regionSeattle, selector.NearestRegion(rc, 49.2827, -123.1207))
+		require.Empty(t, selector.NearestRegion(nil, 49.2827, -123.1207))
+	})
 }
 
 func newTestNodeInRegion(region string, available bool) *livekit.Node {
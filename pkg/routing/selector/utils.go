@@ -30,6 +30,13 @@ func GetAvailableNodes(nodes []*livekit.Node) []*livekit.Node {
 	}).([]*livekit.Node)
 }
 
+// GetNodesInRegion returns the nodes tagged with a region
+func GetNodesInRegion(nodes []*livekit.Node, region string) []*livekit.Node {
+	return funk.Filter(nodes, func(node *livekit.Node) bool {
+		return node.Region == region
+	}).([]*livekit.Node)
+}
+
 func GetNodeSysload(node *livekit.Node) float32 {
 	stats := node.Stats
 	numCpus := stats.NumCpus
@@ -0,0 +1,317 @@
+package rtc
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const activeSpeakerTrackName = "active speaker"
+
+type ActiveSpeakerTrackParams struct {
+	Config           config.ActiveSpeakerTrackConfig
+	ReceiverConfig   ReceiverConfig
+	SubscriberConfig DirectionConfig
+	AudioConfig      config.AudioConfig
+	Telemetry        telemetry.TelemetryService
+	// returns the camera of a participant, nil when the participant does not publish one
+	GetCamera func(participantID livekit.ParticipantID) types.MediaTrack
+	Logger    logger.Logger
+}
+
+// ActiveSpeakerTrack is a video track published by the server in the name of a participant of its own, forwarding
+// the camera of whichever participant is the loudest speaker. It has the codec of the camera it is created from,
+// cameras not published in that codec, not even as a simulcast codec, are not forwarded
+type ActiveSpeakerTrack struct {
+	*MediaTrackReceiver
+
+	params        ActiveSpeakerTrackParams
+	participantID livekit.ParticipantID
+	joinedAt      int64
+	receiver      *sfu.SwitchingReceiver
+
+	lock     sync.Mutex
+	selector *activeSpeakerSelector
+	// camera forwarded, it is told the highest quality subscribers of the track want
+	camera types.MediaTrack
+	// highest quality each subscriber of the track wants
+	subscriberQualities map[livekit.ParticipantID]livekit.VideoQuality
+}
+
+// NewActiveSpeakerTrack creates the track from the camera of a participant, nil when the camera has no receiver yet
+func NewActiveSpeakerTrack(params ActiveSpeakerTrackParams, camera types.MediaTrack) *ActiveSpeakerTrack {
+	receivers := camera.Receivers()
+	if len(receivers) == 0 {
+		return nil
+	}
+	codec := receivers[0].Codec()
+
+	cameraInfo := camera.ToProto()
+	trackInfo := &livekit.TrackInfo{
+		Sid:       utils.NewGuid(utils.TrackPrefix),
+		Type:      livekit.TrackType_VIDEO,
+		Name:      activeSpeakerTrackName,
+		Source:    livekit.TrackSource_CAMERA,
+		MimeType:  codec.MimeType,
+		Width:     cameraInfo.Width,
+		Height:    cameraInfo.Height,
+		Simulcast: cameraInfo.Simulcast,
+		// layers of the first camera stand in for those of the others, layers are selected from what is received
+		Layers: cameraInfo.Layers,
+		Codecs: []*livekit.SimulcastCodecInfo{{MimeType: codec.MimeType, Layers: cameraInfo.Layers}},
+	}
+
+	participantID := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
+	t := &ActiveSpeakerTrack{
+		params:        params,
+		participantID: participantID,
+		joinedAt:      time.Now().Unix(),
+		selector:      newActiveSpeakerSelector(params.Config.SwitchDelay),
+
+		subscriberQualities: make(map[livekit.ParticipantID]livekit.VideoQuality),
+	}
+	t.params.Logger = LoggerWithTrack(
+		LoggerWithParticipant(params.Logger, livekit.ParticipantIdentity(params.Config.Identity), participantID, false),
+		livekit.TrackID(trackInfo.Sid),
+		false,
+	)
+
+	t.receiver = sfu.NewSwitchingReceiver(sfu.SwitchingReceiverParams{
+		TrackID:  livekit.TrackID(trackInfo.Sid),
+		StreamID: string(participantID),
+		Codec:    codec,
+		Logger:   t.params.Logger,
+	})
+	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
+		TrackInfo:           trackInfo,
+		MediaTrack:          t,
+		ParticipantID:       participantID,
+		ParticipantIdentity: livekit.ParticipantIdentity(params.Config.Identity),
+		ParticipantVersion:  1,
+		ReceiverConfig:      params.ReceiverConfig,
+		SubscriberConfig:    params.SubscriberConfig,
+		AudioConfig:         params.AudioConfig,
+		Telemetry:           params.Telemetry,
+		Logger:              t.params.Logger,
+	})
+	t.MediaTrackReceiver.SetSimulcast(cameraInfo.Simulcast)
+	t.MediaTrackReceiver.SetupReceiver(t.receiver, 0, "")
+	t.MediaTrackReceiver.OnSubscriberMaxQualityChange(
+		func(subscriberID livekit.ParticipantID, _ webrtc.RTPCodecCapability, layer int32) {
+			t.setSubscriberMaxQuality(subscriberID, buffer.SpatialLayerToVideoQuality(layer, trackInfo))
+		},
+	)
+	return t
+}
+
+func (t *ActiveSpeakerTrack) ToProto() *livekit.TrackInfo {
+	info := t.MediaTrackReceiver.TrackInfo(true)
+	info.Muted = t.IsMuted()
+	info.Simulcast = t.IsSimulcast()
+	return info
+}
+
+// ParticipantInfo describes the participant the track is published by
+func (t *ActiveSpeakerTrack) ParticipantInfo() *livekit.ParticipantInfo {
+	return serverParticipantInfo(t.participantID, t.params.Config.Identity, activeSpeakerTrackName, t.joinedAt, t.ToProto())
+}
+
+// UpdateSpeakers follows the loudest of the active speakers, sorted loudest first, who publishes a camera
+func (t *ActiveSpeakerTrack) UpdateSpeakers(speakers []*livekit.SpeakerInfo) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	speakerIDs := make([]livekit.ParticipantID, 0, len(speakers))
+	for _, speaker := range speakers {
+		speakerIDs = append(speakerIDs, livekit.ParticipantID(speaker.Sid))
+	}
+	hasCamera := func(participantID livekit.ParticipantID) bool {
+		_, receiver := t.cameraReceiver(participantID)
+		return receiver != nil
+	}
+
+	speakerID, changed := t.selector.update(speakerIDs, hasCamera, time.Now())
+	// switching to a speaker is retried while their camera has no open receiver, e. g. during a reconnect
+	if !changed && (speakerID == "" || t.receiver.Source() != nil) {
+		return
+	}
+
+	var camera types.MediaTrack
+	var source sfu.TrackReceiver
+	if speakerID != "" {
+		camera, source = t.cameraReceiver(speakerID)
+	}
+	if err := t.receiver.SetSource(source); err != nil {
+		if changed {
+			t.params.Logger.Warnw("could not switch active speaker", err, "speakerID", speakerID)
+		}
+		return
+	}
+	t.setCameraLocked(camera)
+	t.params.Logger.Debugw("active speaker switched", "speakerID", speakerID)
+}
+
+// returns the camera of a participant and its receiver in the codec of the track, nil when there is none
+func (t *ActiveSpeakerTrack) cameraReceiver(participantID livekit.ParticipantID) (types.MediaTrack, sfu.TrackReceiver) {
+	camera := t.params.GetCamera(participantID)
+	if camera == nil || camera.IsMuted() {
+		return nil, nil
+	}
+
+	mime := t.receiver.Codec().MimeType
+	if mt, ok := camera.(interface {
+		Receiver(mime string) sfu.TrackReceiver
+	}); ok {
+		if receiver := mt.Receiver(mime); receiver != nil {
+			return camera, receiver
+		}
+		return nil, nil
+	}
+	for _, r := range camera.Receivers() {
+		if strings.EqualFold(r.Codec().MimeType, mime) {
+			return camera, r
+		}
+	}
+	return nil, nil
+}
+
+// setCameraLocked moves the quality subscribers of the track want from the camera forwarded so far to the new one,
+// so that dynacast of the forwarded camera keeps the layers the track needs published
+func (t *ActiveSpeakerTrack) setCameraLocked(camera types.MediaTrack) {
+	if t.camera == camera {
+		return
+	}
+	if t.camera != nil {
+		t.notifyCameraLocked(livekit.VideoQuality_OFF)
+	}
+	t.camera = camera
+	t.notifyCameraLocked(t.maxSubscriberQualityLocked())
+}
+
+func (t *ActiveSpeakerTrack) setSubscriberMaxQuality(subscriberID livekit.ParticipantID, quality livekit.VideoQuality) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if quality == livekit.VideoQuality_OFF {
+		delete(t.subscriberQualities, subscriberID)
+	} else {
+		t.subscriberQualities[subscriberID] = quality
+	}
+	t.notifyCameraLocked(t.maxSubscriberQualityLocked())
+}
+
+func (t *ActiveSpeakerTrack) maxSubscriberQualityLocked() livekit.VideoQuality {
+	maxQuality := livekit.VideoQuality_OFF
+	for _, quality := range t.subscriberQualities {
+		if maxQuality == livekit.VideoQuality_OFF || quality > maxQuality {
+			maxQuality = quality
+		}
+	}
+	return maxQuality
+}
+
+// notifyCameraLocked reports the subscribers of the track to the camera forwarded as a subscriber node of its own,
+// keyed by the track ID, the way subscribers on other nodes are reported
+func (t *ActiveSpeakerTrack) notifyCameraLocked(quality livekit.VideoQuality) {
+	camera, ok := t.camera.(types.LocalMediaTrack)
+	if !ok {
+		return
+	}
+	camera.NotifySubscriberNodeMaxQuality(livekit.NodeID(t.receiver.TrackID()), []types.SubscribedCodecQuality{
+		{CodecMime: t.receiver.Codec().MimeType, Quality: quality},
+	})
+}
+
+// Speaker returns the participant whose camera is forwarded, empty when there is none
+func (t *ActiveSpeakerTrack) Speaker() livekit.ParticipantID {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.receiver.Source() == nil {
+		return ""
+	}
+	return t.selector.current
+}
+
+func (t *ActiveSpeakerTrack) Close(willBeResumed bool) {
+	t.lock.Lock()
+	t.setCameraLocked(nil)
+	t.lock.Unlock()
+
+	t.MediaTrackReceiver.SetClosing()
+	t.MediaTrackReceiver.ClearAllReceivers(willBeResumed)
+	t.receiver.Close()
+	t.MediaTrackReceiver.Close()
+}
+
+// ---------------------------------------------------------------------
+
+// activeSpeakerSelector picks the participant an active speaker track follows. Another participant is followed once
+// they have been the loudest speaker for the switch delay, so that brief interjections do not switch the track.
+// The current speaker is kept while nobody speaks
+type activeSpeakerSelector struct {
+	switchDelay time.Duration
+
+	current        livekit.ParticipantID
+	candidate      livekit.ParticipantID
+	candidateSince time.Time
+}
+
+func newActiveSpeakerSelector(switchDelay time.Duration) *activeSpeakerSelector {
+	return &activeSpeakerSelector{
+		switchDelay: switchDelay,
+	}
+}
+
+// update takes the speakers sorted loudest first, returning the participant to follow and whether it changed
+func (s *activeSpeakerSelector) update(
+	speakers []livekit.ParticipantID,
+	hasCamera func(participantID livekit.ParticipantID) bool,
+	now time.Time,
+) (livekit.ParticipantID, bool) {
+	var loudest livekit.ParticipantID
+	for _, speaker := range speakers {
+		if hasCamera(speaker) {
+			loudest = speaker
+			break
+		}
+	}
+
+	if s.current != "" && !hasCamera(s.current) {
+		// camera of the current speaker is gone, switch without waiting
+		s.current, s.candidate = loudest, ""
+		return s.current, true
+	}
+
+	if loudest == "" || loudest == s.current {
+		s.candidate = ""
+		return s.current, false
+	}
+
+	if s.current == "" {
+		s.current, s.candidate = loudest, ""
+		return s.current, true
+	}
+
+	if s.candidate != loudest {
+		s.candidate, s.candidateSince = loudest, now
+	}
+	if now.Sub(s.candidateSince) < s.switchDelay {
+		return s.current, false
+	}
+
+	s.current, s.candidate = loudest, ""
+	return s.current, true
+}
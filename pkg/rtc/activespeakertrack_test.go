@@ -0,0 +1,109 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+func TestActiveSpeakerSelector(t *testing.T) {
+	cameras := map[livekit.ParticipantID]bool{"PA_a": true, "PA_b": true}
+	hasCamera := func(participantID livekit.ParticipantID) bool {
+		return cameras[participantID]
+	}
+	now := time.Now()
+
+	s := newActiveSpeakerSelector(time.Second)
+
+	// nobody speaking yet
+	speaker, changed := s.update(nil, hasCamera, now)
+	require.Equal(t, livekit.ParticipantID(""), speaker)
+	require.False(t, changed)
+
+	// first speaker is followed right away, speakers without a camera are skipped
+	speaker, changed = s.update([]livekit.ParticipantID{"PA_c", "PA_a"}, hasCamera, now)
+	require.Equal(t, livekit.ParticipantID("PA_a"), speaker)
+	require.True(t, changed)
+
+	// another speaker has to be the loudest for the switch delay
+	speaker, changed = s.update([]livekit.ParticipantID{"PA_b", "PA_a"}, hasCamera, now.Add(100*time.Millisecond))
+	require.Equal(t, livekit.ParticipantID("PA_a"), speaker)
+	require.False(t, changed)
+
+	// an interjection resets the delay
+	_, changed = s.update([]livekit.ParticipantID{"PA_a", "PA_b"}, hasCamera, now.Add(500*time.Millisecond))
+	require.False(t, changed)
+	_, changed = s.update([]livekit.ParticipantID{"PA_b"}, hasCamera, now.Add(600*time.Millisecond))
+	require.False(t, changed)
+	_, changed = s.update([]livekit.ParticipantID{"PA_b"}, hasCamera, now.Add(1500*time.Millisecond))
+	require.False(t, changed)
+
+	speaker, changed = s.update([]livekit.ParticipantID{"PA_b"}, hasCamera, now.Add(1600*time.Millisecond))
+	require.Equal(t, livekit.ParticipantID("PA_b"), speaker)
+	require.True(t, changed)
+
+	// current speaker is kept while nobody speaks
+	speaker, changed = s.update(nil, hasCamera, now.Add(5*time.Second))
+	require.Equal(t, livekit.ParticipantID("PA_b"), speaker)
+	require.False(t, changed)
+
+	// losing the camera switches without waiting
+	cameras["PA_b"] = false
+	speaker, changed = s.update([]livekit.ParticipantID{"PA_b", "PA_a"}, hasCamera, now.Add(5*time.Second))
+	require.Equal(t, livekit.ParticipantID("PA_a"), speaker)
+	require.True(t, changed)
+
+	cameras["PA_a"] = false
+	speaker, changed = s.update(nil, hasCamera, now.Add(6*time.Second))
+	require.Equal(t, livekit.ParticipantID(""), speaker)
+	require.True(t, changed)
+}
+
+func TestActiveSpeakerTrackSubscriberQualities(t *testing.T) {
+	track := &ActiveSpeakerTrack{
+		receiver: sfu.NewSwitchingReceiver(sfu.SwitchingReceiverParams{
+			TrackID: "TR_speaker",
+			Codec:   webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}},
+			Logger:  logger.GetLogger(),
+		}),
+		subscriberQualities: make(map[livekit.ParticipantID]livekit.VideoQuality),
+	}
+	lastQuality := func(camera *typesfakes.FakeLocalMediaTrack) livekit.VideoQuality {
+		n := camera.NotifySubscriberNodeMaxQualityCallCount()
+		require.NotZero(t, n)
+		nodeID, qualities := camera.NotifySubscriberNodeMaxQualityArgsForCall(n - 1)
+		require.Equal(t, livekit.NodeID("TR_speaker"), nodeID)
+		require.Equal(t, []types.SubscribedCodecQuality{{CodecMime: webrtc.MimeTypeVP8, Quality: qualities[0].Quality}}, qualities)
+		return qualities[0].Quality
+	}
+
+	first := &typesfakes.FakeLocalMediaTrack{}
+	track.lock.Lock()
+	track.setCameraLocked(first)
+	track.lock.Unlock()
+	require.Equal(t, livekit.VideoQuality_OFF, lastQuality(first))
+
+	// the forwarded camera is asked for the highest quality of the subscribers
+	track.setSubscriberMaxQuality("PA_1", livekit.VideoQuality_LOW)
+	track.setSubscriberMaxQuality("PA_2", livekit.VideoQuality_HIGH)
+	require.Equal(t, livekit.VideoQuality_HIGH, lastQuality(first))
+	track.setSubscriberMaxQuality("PA_2", livekit.VideoQuality_OFF)
+	require.Equal(t, livekit.VideoQuality_LOW, lastQuality(first))
+
+	// and moves to the next camera on a switch
+	second := &typesfakes.FakeLocalMediaTrack{}
+	track.lock.Lock()
+	track.setCameraLocked(second)
+	track.lock.Unlock()
+	require.Equal(t, livekit.VideoQuality_OFF, lastQuality(first))
+	require.Equal(t, livekit.VideoQuality_LOW, lastQuality(second))
+}
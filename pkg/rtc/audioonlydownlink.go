@@ -0,0 +1,72 @@
+package rtc
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// The audio only downlink setting is not part of ParticipantPermission yet, it is appended as an extra field that
+// nodes unaware of it skip. Being an unknown field, it is only carried by the binary protocols, an UpdateParticipant
+// posted as JSON leaves the setting as it is:
+//
+//	ParticipantPermission.audio_only_downlink = 100;
+const participantPermissionAudioOnlyDownlinkField protowire.Number = 100
+
+// SetAudioOnlyDownlinkPermission sets or clears the audio only downlink of a participant along with a permission
+// update, replacing any value the permission carries already
+func SetAudioOnlyDownlinkPermission(permission *livekit.ParticipantPermission, audioOnly bool) {
+	m := permission.ProtoReflect()
+	unknown := removeAudioOnlyDownlinkField(m.GetUnknown())
+	unknown = protowire.AppendTag(unknown, participantPermissionAudioOnlyDownlinkField, protowire.VarintType)
+	m.SetUnknown(protowire.AppendVarint(unknown, protowire.EncodeBool(audioOnly)))
+}
+
+// AudioOnlyDownlinkFromPermission returns the audio only downlink setting of a permission update, ok is false when
+// the update does not carry one
+func AudioOnlyDownlinkFromPermission(permission *livekit.ParticipantPermission) (audioOnly bool, ok bool) {
+	unknown := permission.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return
+		}
+		unknown = unknown[n:]
+
+		if num == participantPermissionAudioOnlyDownlinkField && typ == protowire.VarintType {
+			value, m := protowire.ConsumeVarint(unknown)
+			if m < 0 {
+				return
+			}
+			audioOnly, ok = protowire.DecodeBool(value), true
+			unknown = unknown[m:]
+			continue
+		}
+
+		m := protowire.ConsumeFieldValue(num, typ, unknown)
+		if m < 0 {
+			return
+		}
+		unknown = unknown[m:]
+	}
+	return
+}
+
+func removeAudioOnlyDownlinkField(unknown []byte) []byte {
+	var kept []byte
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return kept
+		}
+		m := protowire.ConsumeFieldValue(num, typ, unknown[n:])
+		if m < 0 {
+			return kept
+		}
+		if num != participantPermissionAudioOnlyDownlinkField {
+			kept = append(kept, unknown[:n+m]...)
+		}
+		unknown = unknown[n+m:]
+	}
+	return kept
+}
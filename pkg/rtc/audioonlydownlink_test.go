@@ -0,0 +1,61 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestAudioOnlyDownlinkPermission(t *testing.T) {
+	t.Run("not carried", func(t *testing.T) {
+		_, ok := AudioOnlyDownlinkFromPermission(&livekit.ParticipantPermission{CanSubscribe: true})
+		require.False(t, ok)
+	})
+
+	t.Run("survives a round trip", func(t *testing.T) {
+		permission := &livekit.ParticipantPermission{CanSubscribe: true}
+		SetAudioOnlyDownlinkPermission(permission, true)
+
+		data, err := proto.Marshal(&livekit.UpdateParticipantRequest{Room: "room", Permission: permission})
+		require.NoError(t, err)
+		decoded := &livekit.UpdateParticipantRequest{}
+		require.NoError(t, proto.Unmarshal(data, decoded))
+
+		require.True(t, decoded.Permission.CanSubscribe)
+		audioOnly, ok := AudioOnlyDownlinkFromPermission(decoded.Permission)
+		require.True(t, ok)
+		require.True(t, audioOnly)
+	})
+
+	t.Run("cleared", func(t *testing.T) {
+		permission := &livekit.ParticipantPermission{}
+		SetAudioOnlyDownlinkPermission(permission, true)
+		SetAudioOnlyDownlinkPermission(permission, false)
+
+		audioOnly, ok := AudioOnlyDownlinkFromPermission(permission)
+		require.True(t, ok)
+		require.False(t, audioOnly)
+		// replaced rather than repeated
+		require.Len(t, permission.ProtoReflect().GetUnknown(), 3)
+	})
+}
+
+func TestSetAudioOnlyDownlink(t *testing.T) {
+	p := newParticipantForTest("subscriber")
+	require.False(t, p.IsAudioOnlyDownlink())
+
+	permission := &livekit.ParticipantPermission{CanSubscribe: true, CanPublish: true, CanPublishData: true}
+	SetAudioOnlyDownlinkPermission(permission, true)
+	p.SetPermission(permission)
+	require.True(t, p.IsAudioOnlyDownlink())
+
+	// a permission update not carrying the setting leaves it as it is
+	p.SetPermission(&livekit.ParticipantPermission{CanSubscribe: true})
+	require.True(t, p.IsAudioOnlyDownlink())
+
+	p.SetAudioOnlyDownlink(false)
+	require.False(t, p.IsAudioOnlyDownlink())
+}
@@ -0,0 +1,75 @@
+package rtc
+
+import (
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"go.uber.org/atomic"
+)
+
+// byteCounter counts the RTP and RTCP bytes a peer connection sends and receives, the bulk of what goes over its
+// ICE transport, without collecting the stats of the whole peer connection. Registered first, it sees packets as
+// they are handed to the transport, after repair packets are added and pacing. Data channels are not counted
+type byteCounter struct {
+	interceptor.NoOp
+
+	sent     atomic.Uint64
+	received atomic.Uint64
+}
+
+func newByteCounter() *byteCounter {
+	return &byteCounter{}
+}
+
+// NewInterceptor returns the counter itself, it is created for one peer connection
+func (c *byteCounter) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return c, nil
+}
+
+func (c *byteCounter) BytesSent() uint64 {
+	return c.sent.Load()
+}
+
+func (c *byteCounter) BytesReceived() uint64 {
+	return c.received.Load()
+}
+
+func (c *byteCounter) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, a, err := reader.Read(b, a)
+		if err == nil {
+			c.received.Add(uint64(n))
+		}
+		return n, a, err
+	})
+}
+
+func (c *byteCounter) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, a interceptor.Attributes) (int, error) {
+		n, err := writer.Write(pkts, a)
+		if err == nil {
+			c.sent.Add(uint64(n))
+		}
+		return n, err
+	})
+}
+
+func (c *byteCounter) BindLocalStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, a interceptor.Attributes) (int, error) {
+		n, err := writer.Write(header, payload, a)
+		if err == nil {
+			c.sent.Add(uint64(n))
+		}
+		return n, err
+	})
+}
+
+func (c *byteCounter) BindRemoteStream(_ *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, a, err := reader.Read(b, a)
+		if err == nil {
+			c.received.Add(uint64(n))
+		}
+		return n, a, err
+	})
+}
@@ -0,0 +1,39 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteCounter(t *testing.T) {
+	c := newByteCounter()
+
+	rtpWriter := c.BindLocalStream(&interceptor.StreamInfo{}, interceptor.RTPWriterFunc(
+		func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+			return header.MarshalSize() + len(payload), nil
+		},
+	))
+	_, err := rtpWriter.Write(&rtp.Header{Version: 2}, make([]byte, 100), nil)
+	require.NoError(t, err)
+
+	rtcpWriter := c.BindRTCPWriter(interceptor.RTCPWriterFunc(func(_ []rtcp.Packet, _ interceptor.Attributes) (int, error) {
+		return 20, nil
+	}))
+	_, err = rtcpWriter.Write([]rtcp.Packet{&rtcp.PictureLossIndication{}}, nil)
+	require.NoError(t, err)
+
+	rtpReader := c.BindRemoteStream(&interceptor.StreamInfo{}, interceptor.RTPReaderFunc(
+		func(_ []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+			return 1200, a, nil
+		},
+	))
+	_, _, err = rtpReader.Read(make([]byte, 1500), nil)
+	require.NoError(t, err)
+
+	require.EqualValues(t, 12+100+20, c.BytesSent())
+	require.EqualValues(t, 1200, c.BytesReceived())
+}
@@ -0,0 +1,74 @@
+package rtc
+
+import (
+	"strings"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// CandidatePolicy constrains the ICE candidates of a client, so that compliance-sensitive rooms can force media
+// through TURN whatever the client is configured with. Candidates the policy does not allow are dropped before the ICE
+// agent sees them. Peer reflexive candidates are not signalled but learnt from connectivity checks, they are enforced
+// on the selected candidate pair instead. Candidates of the server are its own addresses and are not constrained
+type CandidatePolicy struct {
+	relayOnly bool
+	noHost    bool
+	noPrflx   bool
+}
+
+// NewCandidatePolicy creates the policy of the configuration, nil when it has no constraints
+func NewCandidatePolicy(conf config.CandidatePolicyConfig) *CandidatePolicy {
+	if !conf.RelayOnly && !conf.NoHost && !conf.NoPrflx {
+		return nil
+	}
+
+	return &CandidatePolicy{
+		relayOnly: conf.RelayOnly,
+		noHost:    conf.NoHost,
+		noPrflx:   conf.NoPrflx,
+	}
+}
+
+// RelayOnly tells whether media has to go through TURN
+func (c *CandidatePolicy) RelayOnly() bool {
+	return c != nil && c.relayOnly
+}
+
+// Allows tells whether a remote candidate of the given type may be connected to
+func (c *CandidatePolicy) Allows(typ webrtc.ICECandidateType) bool {
+	if c == nil {
+		return true
+	}
+
+	switch typ {
+	case webrtc.ICECandidateTypeRelay:
+		return true
+	case webrtc.ICECandidateTypeHost:
+		return !c.relayOnly && !c.noHost
+	case webrtc.ICECandidateTypePrflx:
+		return !c.relayOnly && !c.noPrflx
+	default:
+		return !c.relayOnly
+	}
+}
+
+// AllowsCandidate tells whether a signalled remote candidate, in its SDP attribute form, may be connected to.
+// Candidates that cannot be parsed are not allowed
+func (c *CandidatePolicy) AllowsCandidate(candidate string) bool {
+	if c == nil {
+		return true
+	}
+
+	parsed, err := ice.UnmarshalCandidate(strings.TrimPrefix(candidate, "candidate:"))
+	if err != nil {
+		return false
+	}
+	typ, err := webrtc.NewICECandidateType(parsed.Type().String())
+	if err != nil {
+		return false
+	}
+	return c.Allows(typ)
+}
@@ -0,0 +1,78 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestCandidatePolicy(t *testing.T) {
+	const (
+		host  = "candidate:1 1 udp 2130706431 192.168.1.10 50000 typ host"
+		srflx = "candidate:2 1 udp 1694498815 203.0.113.10 50000 typ srflx raddr 192.168.1.10 rport 50000"
+		relay = "candidate:3 1 udp 16777215 198.51.100.10 3478 typ relay raddr 203.0.113.10 rport 50000"
+	)
+
+	var policy *CandidatePolicy
+	require.Nil(t, NewCandidatePolicy(config.CandidatePolicyConfig{}))
+	require.False(t, policy.RelayOnly())
+	require.True(t, policy.AllowsCandidate(host))
+	require.True(t, policy.Allows(webrtc.ICECandidateTypePrflx))
+
+	t.Run("relay only", func(t *testing.T) {
+		policy := NewCandidatePolicy(config.CandidatePolicyConfig{RelayOnly: true})
+		require.True(t, policy.RelayOnly())
+		require.False(t, policy.AllowsCandidate(host))
+		require.False(t, policy.AllowsCandidate(srflx))
+		require.True(t, policy.AllowsCandidate(relay))
+		require.False(t, policy.Allows(webrtc.ICECandidateTypePrflx))
+		require.False(t, policy.AllowsCandidate("not a candidate"))
+	})
+
+	t.Run("no host", func(t *testing.T) {
+		policy := NewCandidatePolicy(config.CandidatePolicyConfig{NoHost: true})
+		require.False(t, policy.RelayOnly())
+		require.False(t, policy.AllowsCandidate(host))
+		require.True(t, policy.AllowsCandidate(srflx))
+		require.True(t, policy.AllowsCandidate(relay))
+		require.True(t, policy.Allows(webrtc.ICECandidateTypePrflx))
+	})
+
+	t.Run("no prflx", func(t *testing.T) {
+		policy := NewCandidatePolicy(config.CandidatePolicyConfig{NoPrflx: true})
+		require.True(t, policy.AllowsCandidate(host))
+		require.True(t, policy.AllowsCandidate(srflx))
+		require.False(t, policy.Allows(webrtc.ICECandidateTypePrflx))
+	})
+}
+
+func TestCandidatePolicySelectedPair(t *testing.T) {
+	const (
+		host  = "candidate:1 1 udp 2130706431 192.168.1.10 50000 typ host"
+		srflx = "candidate:2 1 udp 1694498815 203.0.113.10 50000 typ srflx raddr 192.168.1.10 rport 50000"
+	)
+	prflxPair := func(address string, port uint16) *webrtc.ICECandidatePair {
+		return &webrtc.ICECandidatePair{Remote: &webrtc.ICECandidate{
+			Typ:      webrtc.ICECandidateTypePrflx,
+			Protocol: webrtc.ICEProtocolUDP,
+			Address:  address,
+			Port:     port,
+		}}
+	}
+
+	transport := &PCTransport{params: TransportParams{
+		CandidatePolicy: NewCandidatePolicy(config.CandidatePolicyConfig{NoHost: true}),
+	}}
+	transport.allowedRemoteCandidates = []string{srflx}
+	transport.filteredRemoteCandidates = []string{host}
+
+	// checks from a signalled candidate are taken for it
+	require.True(t, transport.allowsSelectedPair(prflxPair("203.0.113.10", 50000)))
+	// checks from a candidate filtered out by the policy do not get through as peer reflexive
+	require.False(t, transport.allowsSelectedPair(prflxPair("192.168.1.10", 50000)))
+	// unknown addresses are peer reflexive
+	require.True(t, transport.allowsSelectedPair(prflxPair("203.0.113.20", 50000)))
+}
@@ -0,0 +1,94 @@
+package rtc
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// local preference taken from candidates of the address family that is not preferred
+const familyPreferenceMargin = 1 << 14
+
+// CandidatePrioritizer adjusts the priority of the local candidates advertised to clients. Clients order
+// candidate pairs with it when they are the controlling agent.
+// A candidate priority is (2^24)*type preference + (2^8)*local preference + (256 - component), only the
+// local preference is lowered so that candidate types keep their order.
+type CandidatePrioritizer struct {
+	preferFamily string
+	tcpMargin    uint16
+}
+
+func NewCandidatePrioritizer(conf config.ICEPrioritizationConfig) (*CandidatePrioritizer, error) {
+	switch conf.PreferFamily {
+	case "", config.ICEFamilyIPv4, config.ICEFamilyIPv6:
+	default:
+		return nil, fmt.Errorf("invalid ice prefer_family %s", conf.PreferFamily)
+	}
+	if conf.PreferFamily == "" && conf.TCPMargin == 0 {
+		return nil, nil
+	}
+	return &CandidatePrioritizer{
+		preferFamily: conf.PreferFamily,
+		tcpMargin:    conf.TCPMargin,
+	}, nil
+}
+
+func (p *CandidatePrioritizer) Priority(priority uint32, address string, tcp bool) uint32 {
+	if p == nil {
+		return priority
+	}
+
+	var margin uint32
+	if ip := net.ParseIP(address); ip != nil && p.preferFamily != "" {
+		isIPv4 := ip.To4() != nil
+		if isIPv4 != (p.preferFamily == config.ICEFamilyIPv4) {
+			margin += familyPreferenceMargin
+		}
+	}
+	if tcp {
+		margin += uint32(p.tcpMargin)
+	}
+	if margin == 0 {
+		return priority
+	}
+
+	localPreference := (priority >> 8) & 0xffff
+	if margin > localPreference {
+		localPreference = 0
+	} else {
+		localPreference -= margin
+	}
+	return priority&0xff000000 | localPreference<<8 | priority&0xff
+}
+
+func (p *CandidatePrioritizer) Candidate(c *webrtc.ICECandidate) *webrtc.ICECandidate {
+	if p == nil || c == nil {
+		return c
+	}
+	adjusted := *c
+	adjusted.Priority = p.Priority(c.Priority, c.Address, c.Protocol == webrtc.ICEProtocolTCP)
+	return &adjusted
+}
+
+// CandidateAttribute adjusts the priority in the value of an SDP candidate attribute:
+// foundation component transport priority address port typ type ...
+func (p *CandidatePrioritizer) CandidateAttribute(value string) string {
+	if p == nil {
+		return value
+	}
+	fields := strings.Fields(value)
+	if len(fields) < 6 {
+		return value
+	}
+	priority, err := strconv.ParseUint(fields[3], 10, 32)
+	if err != nil {
+		return value
+	}
+	fields[3] = strconv.FormatUint(uint64(p.Priority(uint32(priority), fields[4], strings.EqualFold(fields[2], "tcp"))), 10)
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,63 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestCandidatePrioritizer(t *testing.T) {
+	// host, local preference 65535, component 1
+	const hostUDP = uint32(126<<24 | 65535<<8 | 255)
+
+	t.Run("disabled", func(t *testing.T) {
+		p, err := NewCandidatePrioritizer(config.ICEPrioritizationConfig{})
+		require.NoError(t, err)
+		require.Nil(t, p)
+		require.Equal(t, hostUDP, p.Priority(hostUDP, "10.0.0.1", true))
+
+		_, err = NewCandidatePrioritizer(config.ICEPrioritizationConfig{PreferFamily: "ipx"})
+		require.Error(t, err)
+	})
+
+	t.Run("prefer family", func(t *testing.T) {
+		p, err := NewCandidatePrioritizer(config.ICEPrioritizationConfig{PreferFamily: config.ICEFamilyIPv6})
+		require.NoError(t, err)
+		require.Equal(t, hostUDP, p.Priority(hostUDP, "2001:db8::1", false))
+		ipv4 := p.Priority(hostUDP, "10.0.0.1", false)
+		require.Less(t, ipv4, hostUDP)
+		// type preference and component are kept
+		require.Equal(t, hostUDP>>24, ipv4>>24)
+		require.Equal(t, hostUDP&0xff, ipv4&0xff)
+		// hostnames are left as they are
+		require.Equal(t, hostUDP, p.Priority(hostUDP, "abc.local", false))
+	})
+
+	t.Run("tcp margin", func(t *testing.T) {
+		p, err := NewCandidatePrioritizer(config.ICEPrioritizationConfig{TCPMargin: 1000})
+		require.NoError(t, err)
+		require.Equal(t, hostUDP-1000<<8, p.Priority(hostUDP, "10.0.0.1", true))
+		// local preference does not go below 0
+		p.tcpMargin = 65535
+		low := uint32(126<<24 | 10<<8 | 255)
+		require.Equal(t, uint32(126<<24|255), p.Priority(low, "10.0.0.1", true))
+		p.tcpMargin = 1000
+
+		c := &webrtc.ICECandidate{Priority: hostUDP, Address: "10.0.0.1", Protocol: webrtc.ICEProtocolTCP, Typ: webrtc.ICECandidateTypeHost}
+		adjusted := p.Candidate(c)
+		require.Equal(t, hostUDP, c.Priority)
+		require.Less(t, adjusted.Priority, hostUDP)
+
+		require.Equal(t,
+			"1 1 tcp 2130450431 10.0.0.1 7881 typ host tcptype passive",
+			p.CandidateAttribute("1 1 tcp 2130706431 10.0.0.1 7881 typ host tcptype passive"),
+		)
+		require.Equal(t,
+			"1 1 udp 2130706431 10.0.0.1 7882 typ host",
+			p.CandidateAttribute("1 1 udp 2130706431 10.0.0.1 7882 typ host"),
+		)
+	})
+}
@@ -0,0 +1,31 @@
+package rtc
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// The negotiated client capabilities are not part of JoinResponse yet, they are appended as an extra field that
+// clients unaware of it skip. Clients declaring capabilities get the ones the server acknowledges by name:
+//
+//	JoinResponse.client_capabilities = 101; (repeated string)
+const joinResponseCapabilitiesField protowire.Number = 101
+
+// SetClientCapabilities attaches the negotiated capabilities to a JoinResponse
+func SetClientCapabilities(joinResponse *livekit.JoinResponse, capabilities types.ClientCapabilities) {
+	names := capabilities.Names()
+	if len(names) == 0 {
+		return
+	}
+
+	m := joinResponse.ProtoReflect()
+	unknown := m.GetUnknown()
+	for _, name := range names {
+		unknown = protowire.AppendTag(unknown, joinResponseCapabilitiesField, protowire.BytesType)
+		unknown = protowire.AppendString(unknown, name)
+	}
+	m.SetUnknown(unknown)
+}
@@ -0,0 +1,54 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func TestNegotiateClientCapabilities(t *testing.T) {
+	capabilities := types.NegotiateClientCapabilities([]string{"Delta_Roster", " single_pc", "av1_svc", "unknown"})
+	require.True(t, capabilities.Has(types.ClientCapabilityDeltaRoster))
+	// not implemented by the server
+	require.False(t, capabilities.Has(types.ClientCapabilitySinglePeerConnection))
+	require.Equal(t, "delta_roster", capabilities.String())
+
+	require.Empty(t, types.NegotiateClientCapabilities(nil).Names())
+}
+
+func TestSetClientCapabilities(t *testing.T) {
+	t.Run("none negotiated", func(t *testing.T) {
+		join := &livekit.JoinResponse{}
+		SetClientCapabilities(join, 0)
+		require.Empty(t, join.ProtoReflect().GetUnknown())
+	})
+
+	t.Run("survives a round trip", func(t *testing.T) {
+		join := &livekit.JoinResponse{ServerVersion: "1.0"}
+		SetClientCapabilities(join, types.ClientCapabilityDeltaRoster|types.ClientCapabilityResumeV2)
+
+		data, err := proto.Marshal(join)
+		require.NoError(t, err)
+		decoded := &livekit.JoinResponse{}
+		require.NoError(t, proto.Unmarshal(data, decoded))
+		require.Equal(t, "1.0", decoded.ServerVersion)
+
+		var names []string
+		unknown := decoded.ProtoReflect().GetUnknown()
+		for len(unknown) > 0 {
+			num, typ, n := protowire.ConsumeTag(unknown)
+			require.Equal(t, joinResponseCapabilitiesField, num)
+			require.Equal(t, protowire.BytesType, typ)
+			name, m := protowire.ConsumeString(unknown[n:])
+			names = append(names, name)
+			unknown = unknown[n+m:]
+		}
+		require.Equal(t, []string{"delta_roster", "resume_v2"}, names)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,11 +18,17 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	dd "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
+	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+	"github.com/livekit/livekit-server/pkg/sfu/twcc"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/logger/pionlogger"
 )
 
 const (
+	// consecutive checks external IPs have to be resolved to the same new mapping before it is applied, a single
+	// STUN server answering differently does not restart all connections
+	externalIPChangeConfirmations = 2
+
 	minUDPBufferSize     = 5_000_000
 	defaultUDPBufferSize = 16_777_216
 	frameMarking         = "urn:ietf:params:rtp-hdrext:framemarking"
@@ -37,11 +44,50 @@ type WebRTCConfig struct {
 	Publisher      DirectionConfig
 	Subscriber     DirectionConfig
 	NAT1To1IPs     []string
-	UseMDNS        bool
+	// clients on these networks are given local addresses, not the NAT mapping
+	InternalNetworks []*net.IPNet
+	// IPv4 candidates of clients are synthesized in this NAT64 prefix, nil when not configured
+	NAT64Prefix *net.IPNet
+	// adjusts the priority of candidates advertised to clients, nil when not configured
+	CandidatePrioritizer *CandidatePrioritizer
+	UseMDNS              bool
+	SlowStart            config.SlowStartConfig
+	FlexFEC              config.FlexFECConfig
+	SubscriberRTX        bool
+	Pacer                config.PacerConfig
+	TWCC                 config.TWCCConfig
+	// kernel receive times of media packets, nil when packet timestamping is not enabled
+	ArrivalTimes *buffer.ArrivalTimes
+	// connects out to passive TCP candidates of clients, nil when ICE-TCP active mode is not enabled
+	ActiveTCPMux *ActiveTCPMux
+
+	// external IPs resolved again while running, shared by copies of the config
+	externalIPs *externalIPs
+}
+
+type externalIPs struct {
+	lock       sync.RWMutex
+	nat1to1IPs []string
+	// nil until the external IPs changed, the setting engine holds the mapping resolved at start
+	hostMapping []string
 }
 
 type ReceiverConfig struct {
-	PacketBufferSize int
+	PacketBufferSize            int
+	PacketBufferSizeAudio       int
+	PacketBufferSizeScreenShare int
+
+	// when non-zero, packet buffers are sized from observed packet rate to hold this duration
+	PacketBufferDurationVideo       time.Duration
+	PacketBufferDurationAudio       time.Duration
+	PacketBufferDurationScreenShare time.Duration
+
+	// audio retransmission to subscribers, history size of 0 disables it
+	AudioNACKHistorySize int
+	AudioNACKMaxLatency  time.Duration
+
+	// histograms of published tracks are exported at this interval, 0 disables them
+	DetailedStatsInterval time.Duration
 }
 
 type RTPHeaderExtensionConfig struct {
@@ -82,14 +128,19 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 		s.SetInterfaceFilter(ifFilter)
 	}
 
-	var ipFilter func(net.IP) bool
-	if len(rtcConf.IPs.Includes) != 0 || len(rtcConf.IPs.Excludes) != 0 {
-		filter, err := IPFilterFromConf(rtcConf.IPs)
-		if err != nil {
+	ipFilter, err := ipFilterForConf(&rtcConf)
+	if err != nil {
+		return nil, err
+	}
+	if ipFilter != nil {
+		s.SetIPFilter(ipFilter)
+	}
+
+	var nat64Prefix *net.IPNet
+	if rtcConf.NAT64Prefix != "" {
+		if nat64Prefix, err = ParseNAT64Prefix(rtcConf.NAT64Prefix); err != nil {
 			return nil, err
 		}
-		ipFilter = filter
-		s.SetIPFilter(filter)
 	}
 
 	if !rtcConf.UseMDNS {
@@ -97,6 +148,7 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 	}
 
 	var nat1to1IPs []string
+	var hostMapping []string
 	// force it to the node IPs that the user has set
 	if externalIP != "" && (conf.RTC.UseExternalIP || (conf.RTC.NodeIP != "" && !conf.RTC.NodeIPAutoGenerated)) {
 		if conf.RTC.UseExternalIP {
@@ -106,29 +158,78 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 			}
 			if len(ips) == 0 {
 				logger.Infow("no external IPs found, using node IP for NAT1To1Ips", "ip", externalIP)
-				s.SetNAT1To1IPs([]string{externalIP}, webrtc.ICECandidateTypeHost)
+				hostMapping = []string{externalIP}
 			} else {
 				logger.Infow("using external IPs", "ips", ips)
-				s.SetNAT1To1IPs(ips, webrtc.ICECandidateTypeHost)
+				hostMapping = ips
 			}
 			nat1to1IPs = ips
 		} else {
-			s.SetNAT1To1IPs([]string{externalIP}, webrtc.ICECandidateTypeHost)
+			hostMapping = []string{externalIP}
+		}
+	}
+
+	if len(rtcConf.InterfaceNAT) != 0 {
+		ifaceIPs, err := getInterfaceIPs(ifFilter, ipFilter)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := getInterfaceNAT1to1IPs(rtcConf.InterfaceNAT, ifaceIPs, hostMapping, externalIP)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infow("using interface NAT mappings", "ips", ips)
+		hostMapping = ips
+		nat1to1IPs = ips
+	}
+	if len(hostMapping) != 0 {
+		s.SetNAT1To1IPs(hostMapping, webrtc.ICECandidateTypeHost)
+	}
+
+	var internalNetworks []*net.IPNet
+	for _, cidr := range rtcConf.InternalNetworks {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
 		}
+		internalNetworks = append(internalNetworks, ipnet)
 	}
 
 	if rtcConf.PacketBufferSize == 0 {
-		rtcConf.PacketBufferSize = 500
+		rtcConf.PacketBufferSize = buffer.DefaultVideoBufferPackets
+	}
+	if rtcConf.PacketBufferSizeAudio == 0 {
+		rtcConf.PacketBufferSizeAudio = buffer.DefaultAudioBufferPackets
+	}
+	if rtcConf.PacketBufferSizeScreenShare == 0 {
+		rtcConf.PacketBufferSizeScreenShare = buffer.DefaultScreenShareBufferPackets
 	}
 
 	var udpMux ice.UDPMux
-	var err error
+	var arrivalTimes *buffer.ArrivalTimes
 	networkTypes := make([]webrtc.NetworkType, 0, 4)
 
 	if !rtcConf.ForceTCP {
-		networkTypes = append(networkTypes,
-			webrtc.NetworkTypeUDP4, webrtc.NetworkTypeUDP6,
-		)
+		var tsNet *timestampingNet
+		if rtcConf.PacketTimestamping != "" {
+			tsNet, err = newTimestampingNet(rtcConf.PacketTimestamping)
+			switch {
+			case errors.Is(err, errPacketTimestampingUnsupported):
+				logger.Warnw("packet timestamping disabled", err)
+			case err != nil:
+				return nil, err
+			default:
+				s.SetNet(tsNet)
+				arrivalTimes = tsNet.arrivalTimes
+			}
+		}
+		if rtcConf.IPv6Only {
+			networkTypes = append(networkTypes, webrtc.NetworkTypeUDP6)
+		} else {
+			networkTypes = append(networkTypes,
+				webrtc.NetworkTypeUDP4, webrtc.NetworkTypeUDP6,
+			)
+		}
 		if rtcConf.ICEPortRangeStart != 0 && rtcConf.ICEPortRangeEnd != 0 {
 			if err := s.SetEphemeralUDPPortRange(uint16(rtcConf.ICEPortRangeStart), uint16(rtcConf.ICEPortRangeEnd)); err != nil {
 				return nil, err
@@ -148,6 +249,9 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 			if ifFilter != nil {
 				opts = append(opts, ice.UDPMuxFromPortWithInterfaceFilter(ifFilter))
 			}
+			if tsNet != nil {
+				opts = append(opts, ice.UDPMuxFromPortWithNet(tsNet))
+			}
 			udpMux, err := ice.NewMultiUDPMuxFromPort(int(rtcConf.UDPPort), opts...)
 			if err != nil {
 				return nil, err
@@ -162,23 +266,36 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 
 	// use TCP mux when it's set
 	var tcpListener *net.TCPListener
+	var activeTCPMux *ActiveTCPMux
 	if rtcConf.TCPPort != 0 {
-		networkTypes = append(networkTypes,
-			webrtc.NetworkTypeTCP4, webrtc.NetworkTypeTCP6,
-		)
-		tcpListener, err = net.ListenTCP("tcp", &net.TCPAddr{
+		tcpNetwork := "tcp"
+		if rtcConf.IPv6Only {
+			tcpNetwork = "tcp6"
+			networkTypes = append(networkTypes, webrtc.NetworkTypeTCP6)
+		} else {
+			networkTypes = append(networkTypes,
+				webrtc.NetworkTypeTCP4, webrtc.NetworkTypeTCP6,
+			)
+		}
+		tcpListener, err = net.ListenTCP(tcpNetwork, &net.TCPAddr{
 			Port: int(rtcConf.TCPPort),
 		})
 		if err != nil {
 			return nil, err
 		}
 
-		tcpMux := ice.NewTCPMuxDefault(ice.TCPMuxParams{
+		var tcpMux ice.TCPMux = ice.NewTCPMuxDefault(ice.TCPMuxParams{
 			Logger:          s.LoggerFactory.NewLogger("tcp_mux"),
 			Listener:        tcpListener,
 			ReadBufferSize:  readBufferSize,
 			WriteBufferSize: writeBufferSizeInBytes,
 		})
+		if rtcConf.TCPActive.Enabled {
+			if activeTCPMux, err = NewActiveTCPMux(tcpMux, rtcConf.TCPActive); err != nil {
+				return nil, err
+			}
+			tcpMux = activeTCPMux
+		}
 
 		s.SetICETCPMux(tcpMux)
 	}
@@ -222,6 +339,17 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 		},
 	}
 
+	switch twcc.Format(rtcConf.TWCC.FeedbackFormat) {
+	case "", twcc.FormatTransportCC:
+	case twcc.FormatCCFB:
+		// publishers not supporting it keep getting transport-cc feedback
+		ccfb := webrtc.RTCPFeedback{Type: "ack", Parameter: string(twcc.FormatCCFB)}
+		publisherConfig.RTCPFeedback.Audio = append(publisherConfig.RTCPFeedback.Audio, ccfb)
+		publisherConfig.RTCPFeedback.Video = append(publisherConfig.RTCPFeedback.Video, ccfb)
+	default:
+		return nil, fmt.Errorf("%w: %s", twcc.ErrUnknownFeedbackFormat, rtcConf.TWCC.FeedbackFormat)
+	}
+
 	// subscriber configuration
 	subscriberConfig := DirectionConfig{
 		StrictACKs: conf.RTC.StrictACKs,
@@ -236,7 +364,13 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 			},
 		},
 	}
+	if rtcConf.AudioNACK.Enabled {
+		subscriberConfig.RTCPFeedback.Audio = append(subscriberConfig.RTCPFeedback.Audio, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBNACK})
+	}
 	if rtcConf.CongestionControl.UseSendSideBWE {
+		if !streamallocator.HasBandwidthEstimator(rtcConf.CongestionControl.BandwidthEstimator) {
+			return nil, fmt.Errorf("%w: %s", streamallocator.ErrUnknownBandwidthEstimator, rtcConf.CongestionControl.BandwidthEstimator)
+		}
 		subscriberConfig.RTPHeaderExtension.Video = append(subscriberConfig.RTPHeaderExtension.Video, sdp.TransportCCURI)
 		subscriberConfig.RTCPFeedback.Video = append(subscriberConfig.RTCPFeedback.Video, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBTransportCC})
 	} else {
@@ -244,6 +378,33 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 		subscriberConfig.RTCPFeedback.Video = append(subscriberConfig.RTCPFeedback.Video, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBGoogREMB})
 	}
 
+	receiverConfig := ReceiverConfig{
+		PacketBufferSize:            rtcConf.PacketBufferSize,
+		PacketBufferSizeAudio:       rtcConf.PacketBufferSizeAudio,
+		PacketBufferSizeScreenShare: rtcConf.PacketBufferSizeScreenShare,
+
+		PacketBufferDurationVideo:       rtcConf.PacketBufferDuration.Video,
+		PacketBufferDurationAudio:       rtcConf.PacketBufferDuration.Audio,
+		PacketBufferDurationScreenShare: rtcConf.PacketBufferDuration.ScreenShare,
+	}
+	if rtcConf.AudioNACK.Enabled {
+		receiverConfig.AudioNACKHistorySize = rtcConf.AudioNACK.HistorySize
+		if receiverConfig.AudioNACKHistorySize <= 0 || receiverConfig.AudioNACKHistorySize > rtcConf.PacketBufferSizeAudio {
+			// cannot retransmit more than what the publisher side buffer holds
+			receiverConfig.AudioNACKHistorySize = rtcConf.PacketBufferSizeAudio
+		}
+		receiverConfig.AudioNACKMaxLatency = rtcConf.AudioNACK.MaxLatency
+	}
+	if rtcConf.DetailedStats.Enabled {
+		receiverConfig.DetailedStatsInterval = rtcConf.DetailedStats.Interval
+	}
+
+	prioritizer, err := NewCandidatePrioritizer(rtcConf.ICEPrioritization)
+	if err != nil {
+		return nil, err
+	}
+	setICEAcceptanceWaits(&s, rtcConf.ICEPrioritization)
+
 	if rtcConf.UseICELite {
 		s.SetLite(true)
 	} else if rtcConf.NodeIP == "" && !rtcConf.UseExternalIP {
@@ -259,20 +420,99 @@ func NewWebRTCConfig(conf *config.Config, externalIP string) (*WebRTCConfig, err
 	}
 
 	return &WebRTCConfig{
-		Configuration: c,
-		SettingEngine: s,
-		Receiver: ReceiverConfig{
-			PacketBufferSize: rtcConf.PacketBufferSize,
-		},
-		UDPMux:         udpMux,
-		TCPMuxListener: tcpListener,
-		Publisher:      publisherConfig,
-		Subscriber:     subscriberConfig,
-		NAT1To1IPs:     nat1to1IPs,
-		UseMDNS:        rtcConf.UseMDNS,
+		Configuration:        c,
+		SettingEngine:        s,
+		Receiver:             receiverConfig,
+		UDPMux:               udpMux,
+		TCPMuxListener:       tcpListener,
+		Publisher:            publisherConfig,
+		Subscriber:           subscriberConfig,
+		NAT1To1IPs:           nat1to1IPs,
+		InternalNetworks:     internalNetworks,
+		NAT64Prefix:          nat64Prefix,
+		CandidatePrioritizer: prioritizer,
+		ActiveTCPMux:         activeTCPMux,
+		UseMDNS:              rtcConf.UseMDNS,
+		SlowStart:            rtcConf.SlowStart,
+		FlexFEC:              rtcConf.FlexFEC,
+		SubscriberRTX:        rtcConf.SubscriberRTX,
+		Pacer:                rtcConf.Pacer,
+		TWCC:                 rtcConf.TWCC,
+		ArrivalTimes:         arrivalTimes,
+		externalIPs:          &externalIPs{nat1to1IPs: nat1to1IPs},
 	}, nil
 }
 
+// setICEAcceptanceWaits configures how long pion, when controlling, waits for a better pair before nominating one
+func setICEAcceptanceWaits(s *webrtc.SettingEngine, conf config.ICEPrioritizationConfig) {
+	if conf.AggressiveNomination {
+		s.SetHostAcceptanceMinWait(0)
+		s.SetSrflxAcceptanceMinWait(0)
+		s.SetPrflxAcceptanceMinWait(0)
+		s.SetRelayAcceptanceMinWait(0)
+		return
+	}
+	if conf.HostWait != 0 {
+		s.SetHostAcceptanceMinWait(conf.HostWait)
+	}
+	if conf.SrflxWait != 0 {
+		s.SetSrflxAcceptanceMinWait(conf.SrflxWait)
+	}
+	if conf.PrflxWait != 0 {
+		s.SetPrflxAcceptanceMinWait(conf.PrflxWait)
+	}
+	if conf.RelayWait != 0 {
+		s.SetRelayAcceptanceMinWait(conf.RelayWait)
+	}
+}
+
+// IsInternalClient returns true when the client address is on one of the internal networks
+func (c *WebRTCConfig) IsInternalClient(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range c.InternalNetworks {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNAT1To1IPs returns the external/local IP mappings of the node, as last resolved
+func (c *WebRTCConfig) GetNAT1To1IPs() []string {
+	if c.externalIPs == nil {
+		return c.NAT1To1IPs
+	}
+
+	c.externalIPs.lock.RLock()
+	defer c.externalIPs.lock.RUnlock()
+
+	return c.externalIPs.nat1to1IPs
+}
+
+// updatedHostMapping returns the host candidate mapping when the external IPs changed after the setting engine was
+// configured, nil otherwise
+func (c *WebRTCConfig) updatedHostMapping() []string {
+	if c.externalIPs == nil {
+		return nil
+	}
+
+	c.externalIPs.lock.RLock()
+	defer c.externalIPs.lock.RUnlock()
+
+	return c.externalIPs.hostMapping
+}
+
+func (c *WebRTCConfig) setNAT1To1IPs(ips []string) {
+	c.externalIPs.lock.Lock()
+	defer c.externalIPs.lock.Unlock()
+
+	c.externalIPs.nat1to1IPs = ips
+	c.externalIPs.hostMapping = ips
+}
+
 func (c *WebRTCConfig) SetBufferFactory(factory *buffer.Factory) {
 	c.BufferFactory = factory
 	c.SettingEngine.BufferFactory = factory.GetOrNew
@@ -291,7 +531,11 @@ func getNAT1to1IPsForConf(conf *config.Config, ipFilter func(net.IP) bool) ([]st
 	if len(stunServers) == 0 {
 		stunServers = config.DefaultStunServers
 	}
-	localIPs, err := config.GetLocalIPAddresses(conf.RTC.EnableLoopbackCandidate)
+	getExternalIP, getLocalIPAddresses := config.GetExternalIP, config.GetLocalIPAddresses
+	if conf.RTC.IPv6Only {
+		getExternalIP, getLocalIPAddresses = config.GetExternalIPv6, config.GetLocalIPv6Addresses
+	}
+	localIPs, err := getLocalIPAddresses(conf.RTC.EnableLoopbackCandidate)
 	if err != nil {
 		return nil, err
 	}
@@ -324,7 +568,7 @@ func getNAT1to1IPsForConf(conf *config.Config, ipFilter func(net.IP) bool) ([]st
 		go func(localIP string) {
 			defer wg.Done()
 			for _, port := range udpPorts {
-				addr, err := config.GetExternalIP(ctx, stunServers, &net.UDPAddr{IP: net.ParseIP(localIP), Port: port})
+				addr, err := getExternalIP(ctx, stunServers, &net.UDPAddr{IP: net.ParseIP(localIP), Port: port})
 				if err != nil {
 					if strings.Contains(err.Error(), "address already in use") {
 						logger.Debugw("failed to get external ip, address already in use", "local", localIP, "port", port)
@@ -395,6 +639,134 @@ done:
 	return nat1to1IPs, nil
 }
 
+type interfaceIPs struct {
+	name string
+	ips  []net.IP
+}
+
+func getInterfaceIPs(ifFilter func(string) bool, ipFilter func(net.IP) bool) ([]interfaceIPs, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []interfaceIPs
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || (ifFilter != nil && !ifFilter(iface.Name)) {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		entry := interfaceIPs{name: iface.Name}
+		for _, addr := range addrs {
+			var ip net.IP
+			switch typedAddr := addr.(type) {
+			case *net.IPNet:
+				ip = typedAddr.IP
+			case *net.IPAddr:
+				ip = typedAddr.IP
+			default:
+				continue
+			}
+			if ipFilter != nil && !ipFilter(ip) {
+				continue
+			}
+			entry.ips = append(entry.ips, ip)
+		}
+		if len(entry.ips) != 0 {
+			res = append(res, entry)
+		}
+	}
+	return res, nil
+}
+
+// getInterfaceNAT1to1IPs maps every local IP to the address advertised for its interface. Once there is a
+// mapping, pion drops host candidates of unmapped IPs, so interfaces without a strategy are mapped as
+// hostMapping would map them, or to themselves.
+func getInterfaceNAT1to1IPs(strategies []config.InterfaceNATConfig, ifaces []interfaceIPs, hostMapping []string, externalIP string) ([]string, error) {
+	advertise := make(map[string]string, len(strategies))
+	for _, strategy := range strategies {
+		switch strategy.Advertise {
+		case config.InterfaceAdvertiseExternal, config.InterfaceAdvertiseAsIs:
+		default:
+			if net.ParseIP(strategy.Advertise) == nil {
+				return nil, fmt.Errorf("invalid advertise %q for interface %s", strategy.Advertise, strategy.Interface)
+			}
+		}
+		advertise[strategy.Interface] = strategy.Advertise
+	}
+
+	// how the node would advertise a local IP without a strategy
+	defaultIP := func(local net.IP) net.IP {
+		for _, mapping := range hostMapping {
+			ips := strings.Split(mapping, "/")
+			ext := net.ParseIP(ips[0])
+			if ext == nil || isIPv4(ext) != isIPv4(local) {
+				continue
+			}
+			if len(ips) == 1 {
+				return ext
+			}
+			if ips[1] == local.String() {
+				return ext
+			}
+		}
+		return local
+	}
+
+	var nat1to1IPs []string
+	found := make(map[string]bool, len(advertise))
+	seen := make(map[string]bool)
+	for _, iface := range ifaces {
+		strategy, ok := advertise[iface.name]
+		found[iface.name] = ok
+		for _, local := range iface.ips {
+			if seen[local.String()] {
+				continue
+			}
+			seen[local.String()] = true
+
+			ext := defaultIP(local)
+			switch strategy {
+			case "":
+			case config.InterfaceAdvertiseAsIs:
+				ext = local
+			case config.InterfaceAdvertiseExternal:
+				if ext.Equal(local) {
+					ext = net.ParseIP(externalIP)
+				}
+				if ext == nil || isIPv4(ext) != isIPv4(local) {
+					if isIPv4(local) {
+						return nil, fmt.Errorf("no external IP to advertise for interface %s", iface.name)
+					}
+					// external IP is IPv4 only
+					ext = local
+				}
+			default:
+				if ip := net.ParseIP(strategy); isIPv4(ip) == isIPv4(local) {
+					ext = ip
+				} else {
+					ext = local
+				}
+			}
+			nat1to1IPs = append(nat1to1IPs, fmt.Sprintf("%s/%s", ext, local))
+		}
+	}
+
+	for _, strategy := range strategies {
+		if !found[strategy.Interface] {
+			logger.Warnw("no addresses found for interface with NAT strategy", nil, "interface", strategy.Interface)
+		}
+	}
+	return nat1to1IPs, nil
+}
+
+func isIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
+
 func InterfaceFilterFromConf(ifs config.InterfacesConfig) func(string) bool {
 	includes := ifs.Includes
 	excludes := ifs.Excludes
@@ -464,3 +836,163 @@ func IPFilterFromConf(ips config.IPsConfig) (func(ip net.IP) bool, error) {
 		return true
 	}, nil
 }
+
+// ipFilterForConf combines the IP filter of the configuration with the IPv6 only mode, nil when there is nothing to
+// filter
+func ipFilterForConf(rtcConf *config.RTCConfig) (func(net.IP) bool, error) {
+	var filter func(net.IP) bool
+	if len(rtcConf.IPs.Includes) != 0 || len(rtcConf.IPs.Excludes) != 0 {
+		var err error
+		if filter, err = IPFilterFromConf(rtcConf.IPs); err != nil {
+			return nil, err
+		}
+	}
+	if !rtcConf.IPv6Only {
+		return filter, nil
+	}
+
+	return func(ip net.IP) bool {
+		if ip.To4() != nil {
+			return false
+		}
+		return filter == nil || filter(ip)
+	}, nil
+}
+
+// ExternalIPWatcher resolves the external IPs of the node with STUN again at an interval, clouds may reassign them
+// while the node runs. Connections created afterwards advertise the new IPs, existing ones keep the candidates they
+// gathered and have to be restarted by the OnChanged callback
+type ExternalIPWatcher struct {
+	conf       *config.Config
+	rtcConf    *WebRTCConfig
+	externalIP string
+	ifFilter   func(string) bool
+	ipFilter   func(net.IP) bool
+	resolver   func() ([]string, error)
+	onChanged  func(previous, current []string)
+
+	// a changed mapping waiting to be confirmed by the next checks
+	pending      []string
+	pendingCount int
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func NewExternalIPWatcher(conf *config.Config, rtcConf *WebRTCConfig, externalIP string) (*ExternalIPWatcher, error) {
+	// the ports of the node are bound by now, the STUN servers see the same external IP from any port
+	resolveConf := *conf
+	resolveConf.RTC.UDPPort = 0
+	resolveConf.RTC.ICEPortRangeStart = 0
+	resolveConf.RTC.ICEPortRangeEnd = 0
+
+	w := &ExternalIPWatcher{
+		conf:       &resolveConf,
+		rtcConf:    rtcConf,
+		externalIP: externalIP,
+		stop:       make(chan struct{}),
+	}
+	if len(conf.RTC.Interfaces.Includes) != 0 || len(conf.RTC.Interfaces.Excludes) != 0 {
+		w.ifFilter = InterfaceFilterFromConf(conf.RTC.Interfaces)
+	}
+	ipFilter, err := ipFilterForConf(&conf.RTC)
+	if err != nil {
+		return nil, err
+	}
+	w.ipFilter = ipFilter
+	w.resolver = w.resolve
+	return w, nil
+}
+
+func (w *ExternalIPWatcher) OnChanged(f func(previous, current []string)) {
+	w.onChanged = f
+}
+
+func (w *ExternalIPWatcher) Start() {
+	go w.worker()
+}
+
+func (w *ExternalIPWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+func (w *ExternalIPWatcher) worker() {
+	ticker := time.NewTicker(w.conf.RTC.ExternalIPCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *ExternalIPWatcher) check() {
+	ips, err := w.resolver()
+	if err != nil || len(ips) == 0 {
+		// STUN servers being unreachable does not mean the IPs changed
+		logger.Debugw("could not resolve external IPs", "error", err)
+		return
+	}
+
+	previous := w.rtcConf.GetNAT1To1IPs()
+	if equalIPMappings(previous, ips) {
+		w.pending = nil
+		w.pendingCount = 0
+		return
+	}
+
+	if !equalIPMappings(w.pending, ips) {
+		w.pending = ips
+		w.pendingCount = 0
+	}
+	w.pendingCount++
+	if w.pendingCount < externalIPChangeConfirmations {
+		logger.Debugw("external IPs changed, waiting for confirmation", "previous", previous, "current", ips)
+		return
+	}
+	w.pending = nil
+	w.pendingCount = 0
+
+	logger.Infow("external IPs changed", "previous", previous, "current", ips)
+	w.rtcConf.setNAT1To1IPs(ips)
+	if w.onChanged != nil {
+		w.onChanged(previous, ips)
+	}
+}
+
+// resolve maps the local IPs of the node the way it was configured at start, with the external IPs STUN servers
+// see now
+func (w *ExternalIPWatcher) resolve() ([]string, error) {
+	ips, err := getNAT1to1IPsForConf(w.conf, w.ipFilter)
+	if err != nil || len(ips) == 0 || len(w.conf.RTC.InterfaceNAT) == 0 {
+		return ips, err
+	}
+
+	ifaceIPs, err := getInterfaceIPs(w.ifFilter, w.ipFilter)
+	if err != nil {
+		return nil, err
+	}
+	return getInterfaceNAT1to1IPs(w.conf.RTC.InterfaceNAT, ifaceIPs, ips, w.externalIP)
+}
+
+func equalIPMappings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package rtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu/twcc"
+)
+
+func TestInterfaceNAT1to1IPs(t *testing.T) {
+	ifaces := []interfaceIPs{
+		{name: "eth0", ips: []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("2001:db8::2")}},
+		{name: "wg0", ips: []net.IP{net.ParseIP("10.8.0.1")}},
+		{name: "eth1", ips: []net.IP{net.ParseIP("192.168.1.2")}},
+	}
+
+	t.Run("strategies", func(t *testing.T) {
+		ips, err := getInterfaceNAT1to1IPs([]config.InterfaceNATConfig{
+			{Interface: "eth0", Advertise: config.InterfaceAdvertiseExternal},
+			{Interface: "wg0", Advertise: config.InterfaceAdvertiseAsIs},
+			{Interface: "eth1", Advertise: "203.0.113.9"},
+		}, ifaces, nil, "203.0.113.1")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"203.0.113.1/10.0.0.2",
+			"2001:db8::2/2001:db8::2",
+			"10.8.0.1/10.8.0.1",
+			"203.0.113.9/192.168.1.2",
+		}, ips)
+	})
+
+	t.Run("unlisted interfaces follow the node mapping", func(t *testing.T) {
+		hostMapping := []string{"203.0.113.5/10.0.0.2", "10.8.0.1/10.8.0.1", "192.168.1.2/192.168.1.2"}
+		ips, err := getInterfaceNAT1to1IPs([]config.InterfaceNATConfig{
+			{Interface: "wg0", Advertise: config.InterfaceAdvertiseAsIs},
+			{Interface: "eth1", Advertise: config.InterfaceAdvertiseExternal},
+		}, ifaces, hostMapping, "203.0.113.1")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"203.0.113.5/10.0.0.2",
+			"2001:db8::2/2001:db8::2",
+			"10.8.0.1/10.8.0.1",
+			// not resolved with STUN, node IP is used
+			"203.0.113.1/192.168.1.2",
+		}, ips)
+
+		ips, err = getInterfaceNAT1to1IPs([]config.InterfaceNATConfig{
+			{Interface: "wg0", Advertise: config.InterfaceAdvertiseAsIs},
+		}, ifaces, []string{"203.0.113.1"}, "203.0.113.1")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"203.0.113.1/10.0.0.2",
+			"2001:db8::2/2001:db8::2",
+			"10.8.0.1/10.8.0.1",
+			"203.0.113.1/192.168.1.2",
+		}, ips)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := getInterfaceNAT1to1IPs([]config.InterfaceNATConfig{
+			{Interface: "eth0", Advertise: "public"},
+		}, ifaces, nil, "203.0.113.1")
+		require.Error(t, err)
+
+		_, err = getInterfaceNAT1to1IPs([]config.InterfaceNATConfig{
+			{Interface: "eth0", Advertise: config.InterfaceAdvertiseExternal},
+		}, ifaces, nil, "")
+		require.Error(t, err)
+	})
+}
+
+func TestIsInternalClient(t *testing.T) {
+	conf, err := NewWebRTCConfig(&config.Config{
+		RTC: config.RTCConfig{
+			InternalNetworks: []string{"10.0.0.0/8", "fd00::/8"},
+		},
+	}, "")
+	require.NoError(t, err)
+
+	require.True(t, conf.IsInternalClient("10.1.2.3"))
+	require.True(t, conf.IsInternalClient("fd00::1"))
+	require.False(t, conf.IsInternalClient("203.0.113.1"))
+	require.False(t, conf.IsInternalClient(""))
+
+	_, err = NewWebRTCConfig(&config.Config{
+		RTC: config.RTCConfig{
+			InternalNetworks: []string{"10.0.0.0"},
+		},
+	}, "")
+	require.Error(t, err)
+}
+
+func TestFeedbackFormat(t *testing.T) {
+	conf, err := NewWebRTCConfig(&config.Config{
+		RTC: config.RTCConfig{
+			TWCC: config.TWCCConfig{FeedbackFormat: string(twcc.FormatCCFB)},
+		},
+	}, "")
+	require.NoError(t, err)
+	ccfb := webrtc.RTCPFeedback{Type: "ack", Parameter: "ccfb"}
+	require.Contains(t, conf.Publisher.RTCPFeedback.Audio, ccfb)
+	require.Contains(t, conf.Publisher.RTCPFeedback.Video, ccfb)
+	// for publishers not supporting it
+	require.Contains(t, conf.Publisher.RTCPFeedback.Video, webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBTransportCC})
+
+	_, err = NewWebRTCConfig(&config.Config{
+		RTC: config.RTCConfig{
+			TWCC: config.TWCCConfig{FeedbackFormat: "remb"},
+		},
+	}, "")
+	require.ErrorIs(t, err, twcc.ErrUnknownFeedbackFormat)
+}
+
+func TestExternalIPsChanged(t *testing.T) {
+	conf, err := NewWebRTCConfig(&config.Config{}, "")
+	require.NoError(t, err)
+	require.Empty(t, conf.GetNAT1To1IPs())
+	require.Nil(t, conf.updatedHostMapping())
+
+	// copies held by rooms see the change
+	copied := *conf
+	conf.setNAT1To1IPs([]string{"203.0.113.2/10.0.0.1"})
+	require.Equal(t, []string{"203.0.113.2/10.0.0.1"}, copied.GetNAT1To1IPs())
+	require.Equal(t, []string{"203.0.113.2/10.0.0.1"}, copied.updatedHostMapping())
+
+	require.True(t, equalIPMappings([]string{"a/1", "b/2"}, []string{"b/2", "a/1"}))
+	require.False(t, equalIPMappings([]string{"a/1", "b/2"}, []string{"c/2", "a/1"}))
+	require.False(t, equalIPMappings([]string{"a/1"}, nil))
+}
+
+func TestExternalIPWatcher(t *testing.T) {
+	conf, err := NewWebRTCConfig(&config.Config{}, "")
+	require.NoError(t, err)
+	conf.setNAT1To1IPs([]string{"203.0.113.1/10.0.0.1"})
+
+	w, err := NewExternalIPWatcher(&config.Config{}, conf, "")
+	require.NoError(t, err)
+	var resolved []string
+	w.resolver = func() ([]string, error) {
+		return resolved, nil
+	}
+	var changes [][]string
+	w.OnChanged(func(previous, current []string) {
+		changes = append(changes, current)
+	})
+
+	// a single answer differing is not applied
+	resolved = []string{"203.0.113.2/10.0.0.1"}
+	w.check()
+	resolved = []string{"203.0.113.1/10.0.0.1"}
+	w.check()
+	resolved = nil
+	w.check()
+	require.Empty(t, changes)
+
+	resolved = []string{"203.0.113.2/10.0.0.1"}
+	w.check()
+	w.check()
+	require.Equal(t, [][]string{{"203.0.113.2/10.0.0.1"}}, changes)
+	require.Equal(t, []string{"203.0.113.2/10.0.0.1"}, conf.GetNAT1To1IPs())
+
+	w.check()
+	require.Len(t, changes, 1)
+}
+
+func TestIPFilterForConf(t *testing.T) {
+	filter, err := ipFilterForConf(&config.RTCConfig{})
+	require.NoError(t, err)
+	require.Nil(t, filter)
+
+	filter, err = ipFilterForConf(&config.RTCConfig{IPv6Only: true})
+	require.NoError(t, err)
+	require.False(t, filter(net.ParseIP("10.0.0.2")))
+	require.True(t, filter(net.ParseIP("2001:db8::2")))
+
+	filter, err = ipFilterForConf(&config.RTCConfig{
+		IPv6Only: true,
+		IPs:      config.IPsConfig{Excludes: []string{"2001:db8::/64"}},
+	})
+	require.NoError(t, err)
+	require.False(t, filter(net.ParseIP("10.0.0.2")))
+	require.False(t, filter(net.ParseIP("2001:db8::2")))
+	require.True(t, filter(net.ParseIP("2001:db8:1::2")))
+}
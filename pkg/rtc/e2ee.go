@@ -0,0 +1,217 @@
+package rtc
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// End-to-end encryption signaling is not part of the protocol messages yet, it is appended as extra fields that
+// clients unaware of it skip. Being unknown fields, they are only carried by the binary signal protocol and data
+// channels:
+//
+//	message E2EEKeyEvent {
+//	  string participant_sid = 1;
+//	  string participant_identity = 2;
+//	  string track_sid = 3;
+//	  uint32 key_index = 4;
+//	}
+//
+//	message E2EETrackRejected {
+//	  string cid = 1;
+//	  string track_sid = 2;
+//	  string reason = 3;
+//	}
+//
+//	JoinResponse.e2ee_required = 102;
+//	DataPacket.e2ee_key_event = 100;
+//	SignalResponse.e2ee_track_rejected = 100;
+//
+// Key events are relayed to the other participants of a room like user packets. The server sends one when it sees
+// a publisher encrypt with another key, publishers may send them ahead of rotating keys.
+const (
+	joinResponseE2EERequiredField        protowire.Number = 102
+	dataPacketE2EEKeyEventField          protowire.Number = 100
+	signalResponseE2EETrackRejectedField protowire.Number = 100
+
+	e2eeKeyEventParticipantSidField      protowire.Number = 1
+	e2eeKeyEventParticipantIdentityField protowire.Number = 2
+	e2eeKeyEventTrackSidField            protowire.Number = 3
+	e2eeKeyEventKeyIndexField            protowire.Number = 4
+
+	e2eeTrackRejectedCidField      protowire.Number = 1
+	e2eeTrackRejectedTrackSidField protowire.Number = 2
+	e2eeTrackRejectedReasonField   protowire.Number = 3
+)
+
+const (
+	E2EETrackRejectedNotEncrypted = "track is not encrypted"
+	E2EETrackRejectedPlaintext    = "frames are not encrypted"
+)
+
+type E2EEKeyEvent struct {
+	ParticipantID       livekit.ParticipantID
+	ParticipantIdentity livekit.ParticipantIdentity
+	TrackID             livekit.TrackID
+	KeyIndex            uint32
+}
+
+// SetE2EERequired tells a joining participant that the room requires end-to-end encryption
+func SetE2EERequired(joinResponse *livekit.JoinResponse) {
+	m := joinResponse.ProtoReflect()
+	unknown := protowire.AppendTag(m.GetUnknown(), joinResponseE2EERequiredField, protowire.VarintType)
+	m.SetUnknown(protowire.AppendVarint(unknown, protowire.EncodeBool(true)))
+}
+
+// NewE2EEKeyEventPacket creates a data packet carrying a key event
+func NewE2EEKeyEventPacket(event *E2EEKeyEvent) *livekit.DataPacket {
+	dp := &livekit.DataPacket{Kind: livekit.DataPacket_RELIABLE}
+	setE2EEKeyEvent(dp, event)
+	return dp
+}
+
+func setE2EEKeyEvent(dp *livekit.DataPacket, event *E2EEKeyEvent) {
+	var value []byte
+	appendString := func(field protowire.Number, v string) {
+		if v == "" {
+			return
+		}
+		value = protowire.AppendTag(value, field, protowire.BytesType)
+		value = protowire.AppendString(value, v)
+	}
+	appendString(e2eeKeyEventParticipantSidField, string(event.ParticipantID))
+	appendString(e2eeKeyEventParticipantIdentityField, string(event.ParticipantIdentity))
+	appendString(e2eeKeyEventTrackSidField, string(event.TrackID))
+	value = protowire.AppendTag(value, e2eeKeyEventKeyIndexField, protowire.VarintType)
+	value = protowire.AppendVarint(value, uint64(event.KeyIndex))
+
+	m := dp.ProtoReflect()
+	unknown := removeUnknownField(m.GetUnknown(), dataPacketE2EEKeyEventField)
+	unknown = protowire.AppendTag(unknown, dataPacketE2EEKeyEventField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, value))
+}
+
+// GetE2EEKeyEvent reads the key event a data packet carries, nil when it carries none
+func GetE2EEKeyEvent(dp *livekit.DataPacket) *E2EEKeyEvent {
+	value, ok := findUnknownBytesField(dp.ProtoReflect().GetUnknown(), dataPacketE2EEKeyEventField)
+	if !ok {
+		return nil
+	}
+
+	event := &E2EEKeyEvent{}
+	for len(value) > 0 {
+		num, typ, n := protowire.ConsumeTag(value)
+		if n < 0 {
+			return nil
+		}
+		value = value[n:]
+
+		switch {
+		case typ == protowire.BytesType && num != e2eeKeyEventKeyIndexField:
+			v, m := protowire.ConsumeString(value)
+			if m < 0 {
+				return nil
+			}
+			switch num {
+			case e2eeKeyEventParticipantSidField:
+				event.ParticipantID = livekit.ParticipantID(v)
+			case e2eeKeyEventParticipantIdentityField:
+				event.ParticipantIdentity = livekit.ParticipantIdentity(v)
+			case e2eeKeyEventTrackSidField:
+				event.TrackID = livekit.TrackID(v)
+			}
+			value = value[m:]
+		case typ == protowire.VarintType && num == e2eeKeyEventKeyIndexField:
+			v, m := protowire.ConsumeVarint(value)
+			if m < 0 {
+				return nil
+			}
+			event.KeyIndex = uint32(v)
+			value = value[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, value)
+			if m < 0 {
+				return nil
+			}
+			value = value[m:]
+		}
+	}
+	return event
+}
+
+// newE2EETrackRejectedResponse tells a publisher that a track was rejected for not being encrypted, by client track
+// ID when it was rejected before being published
+func newE2EETrackRejectedResponse(cid string, trackID livekit.TrackID, reason string) *livekit.SignalResponse {
+	var value []byte
+	for _, f := range []struct {
+		num protowire.Number
+		v   string
+	}{
+		{e2eeTrackRejectedCidField, cid},
+		{e2eeTrackRejectedTrackSidField, string(trackID)},
+		{e2eeTrackRejectedReasonField, reason},
+	} {
+		if f.v == "" {
+			continue
+		}
+		value = protowire.AppendTag(value, f.num, protowire.BytesType)
+		value = protowire.AppendString(value, f.v)
+	}
+
+	res := &livekit.SignalResponse{}
+	m := res.ProtoReflect()
+	unknown := protowire.AppendTag(nil, signalResponseE2EETrackRejectedField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, value))
+	return res
+}
+
+func findUnknownBytesField(unknown []byte, field protowire.Number) ([]byte, bool) {
+	var (
+		value []byte
+		found bool
+	)
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return nil, false
+		}
+		unknown = unknown[n:]
+
+		if num == field && typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(unknown)
+			if m < 0 {
+				return nil, false
+			}
+			// the last occurrence wins
+			value, found = v, true
+			unknown = unknown[m:]
+			continue
+		}
+
+		m := protowire.ConsumeFieldValue(num, typ, unknown)
+		if m < 0 {
+			return nil, false
+		}
+		unknown = unknown[m:]
+	}
+	return value, found
+}
+
+func removeUnknownField(unknown []byte, field protowire.Number) []byte {
+	var kept []byte
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return kept
+		}
+		m := protowire.ConsumeFieldValue(num, typ, unknown[n:])
+		if m < 0 {
+			return kept
+		}
+		if num != field {
+			kept = append(kept, unknown[:n+m]...)
+		}
+		unknown = unknown[n+m:]
+	}
+	return kept
+}
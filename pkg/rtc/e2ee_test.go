@@ -0,0 +1,48 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestE2EEKeyEvent(t *testing.T) {
+	event := &E2EEKeyEvent{
+		ParticipantID:       "PA_a",
+		ParticipantIdentity: "alice",
+		TrackID:             "TR_a",
+		KeyIndex:            3,
+	}
+	data, err := proto.Marshal(NewE2EEKeyEventPacket(event))
+	require.NoError(t, err)
+
+	dp := &livekit.DataPacket{}
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.Nil(t, dp.Value)
+	require.Equal(t, event, GetE2EEKeyEvent(dp))
+
+	require.Nil(t, GetE2EEKeyEvent(&livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{}}}))
+}
+
+func TestE2EESignaling(t *testing.T) {
+	joinResponse := &livekit.JoinResponse{}
+	SetE2EERequired(joinResponse)
+	num, typ, n := protowire.ConsumeTag(joinResponse.ProtoReflect().GetUnknown())
+	require.Positive(t, n)
+	require.Equal(t, joinResponseE2EERequiredField, num)
+	require.Equal(t, protowire.VarintType, typ)
+
+	res := newE2EETrackRejectedResponse("cid", "", E2EETrackRejectedNotEncrypted)
+	require.Nil(t, res.Message)
+	value, ok := findUnknownBytesField(res.ProtoReflect().GetUnknown(), signalResponseE2EETrackRejectedField)
+	require.True(t, ok)
+	cid, ok := findUnknownBytesField(value, e2eeTrackRejectedCidField)
+	require.True(t, ok)
+	require.Equal(t, "cid", string(cid))
+	_, ok = findUnknownBytesField(value, e2eeTrackRejectedTrackSidField)
+	require.False(t, ok)
+}
@@ -12,6 +12,7 @@ var (
 	ErrEmptyIdentity           = errors.New("participant identity cannot be empty")
 	ErrEmptyParticipantID      = errors.New("participant ID cannot be empty")
 	ErrMissingGrants           = errors.New("VideoGrant is missing")
+	ErrParticipantNotFound     = errors.New("participant is not in the room")
 
 	// Track subscription related
 	ErrNoTrackPermission         = errors.New("participant is not allowed to subscribe to this track")
@@ -20,4 +21,10 @@ var (
 	ErrTrackNotAttached          = errors.New("track is not yet attached")
 	ErrTrackNotBound             = errors.New("track not bound")
 	ErrSubscriptionLimitExceeded = errors.New("participant has exceeded its subscription limit")
+	ErrSubscriptionThrottled     = errors.New("subscription is delayed by room slow-start")
+	ErrNoCompatibleCodec         = errors.New("subscriber cannot decode any codec published for this track")
+
+	// Track publication related
+	ErrInvalidTrackSyncGroup  = errors.New("a track sync group needs at least two distinct tracks")
+	ErrTrackSyncGroupConflict = errors.New("track is already published or in another track sync group")
 )
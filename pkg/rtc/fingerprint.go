@@ -0,0 +1,109 @@
+package rtc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	ErrFingerprintCertificateMismatch = errors.New("certificate does not have the declared fingerprint")
+	ErrFingerprintSignatureInvalid    = errors.New("nonce signature does not verify with the certificate key")
+)
+
+// NormalizeFingerprint formats a certificate fingerprint as "<hash function> <hex bytes>", i.e. "sha-256 AB:CD:...",
+// an empty string is returned for anything that is not a fingerprint
+func NormalizeFingerprint(fingerprint string) string {
+	fields := strings.Fields(fingerprint)
+	if len(fields) != 2 {
+		return ""
+	}
+	return strings.ToLower(fields[0]) + " " + strings.ToUpper(fields[1])
+}
+
+// VerifyFingerprintProof checks that a client holds the private key of the certificate it declared the fingerprint
+// of: certificate, DER encoded, has to have the normalized fingerprint and signature has to be a signature of nonce
+// with its key, ECDSA and RSA PKCS #1 v1.5 over the SHA-256 of the nonce or Ed25519 over the nonce itself
+func VerifyFingerprintProof(fingerprint string, certificate []byte, nonce []byte, signature []byte) error {
+	cert, err := x509.ParseCertificate(certificate)
+	if err != nil {
+		return err
+	}
+	hashFunction, _, _ := strings.Cut(fingerprint, " ")
+	certFingerprint, err := certificateFingerprint(hashFunction, cert.Raw)
+	if err != nil {
+		return err
+	}
+	if certFingerprint != fingerprint {
+		return ErrFingerprintCertificateMismatch
+	}
+
+	digest := sha256.Sum256(nonce)
+	valid := false
+	switch key := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, nonce, signature)
+	default:
+		return fmt.Errorf("unsupported certificate key type %T", cert.PublicKey)
+	}
+	if !valid {
+		return ErrFingerprintSignatureInvalid
+	}
+	return nil
+}
+
+// certificateFingerprint returns the normalized fingerprint of a DER encoded certificate
+func certificateFingerprint(hashFunction string, der []byte) (string, error) {
+	var sum []byte
+	switch hashFunction {
+	case "sha-256":
+		s := sha256.Sum256(der)
+		sum = s[:]
+	case "sha-384":
+		s := sha512.Sum384(der)
+		sum = s[:]
+	case "sha-512":
+		s := sha512.Sum512(der)
+		sum = s[:]
+	default:
+		return "", fmt.Errorf("unsupported fingerprint hash function %q", hashFunction)
+	}
+
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return hashFunction + " " + strings.Join(hex, ":"), nil
+}
+
+// fingerprintFromSDP returns the DTLS certificate fingerprint of a session description, the session level one or
+// else the one of the first media section having it
+func fingerprintFromSDP(sd webrtc.SessionDescription) string {
+	parsed, err := sd.Unmarshal()
+	if err != nil {
+		return ""
+	}
+
+	if fingerprint, ok := parsed.Attribute("fingerprint"); ok {
+		return NormalizeFingerprint(fingerprint)
+	}
+	for _, m := range parsed.MediaDescriptions {
+		if fingerprint, ok := m.Attribute("fingerprint"); ok {
+			return NormalizeFingerprint(fingerprint)
+		}
+	}
+	return ""
+}
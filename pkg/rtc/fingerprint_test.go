@@ -0,0 +1,93 @@
+package rtc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/testutils"
+)
+
+func TestNormalizeFingerprint(t *testing.T) {
+	require.Equal(t, "sha-256 AB:CD:EF", NormalizeFingerprint(" SHA-256  ab:cd:EF "))
+	require.Empty(t, NormalizeFingerprint("ab:cd:ef"))
+	require.Empty(t, NormalizeFingerprint(""))
+}
+
+func TestFingerprintFromSDP(t *testing.T) {
+	session := "v=0\r\no=- 0 0 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\n"
+	media := "m=audio 9 UDP/TLS/RTP/SAVPF 111\r\nc=IN IP4 0.0.0.0\r\n"
+
+	sd := webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: session + "a=fingerprint:sha-256 ab:cd\r\n" + media}
+	require.Equal(t, "sha-256 AB:CD", fingerprintFromSDP(sd))
+
+	sd.SDP = session + media + "a=fingerprint:sha-256 ef:01\r\n"
+	require.Equal(t, "sha-256 EF:01", fingerprintFromSDP(sd))
+
+	sd.SDP = session + media
+	require.Empty(t, fingerprintFromSDP(sd))
+}
+
+func TestVerifyFingerprint(t *testing.T) {
+	p := newParticipantForTest("test")
+	p.params.Fingerprint = "sha-256 AB:CD"
+
+	sd := webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  "v=0\r\no=- 0 0 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\na=fingerprint:sha-256 ab:cd\r\n",
+	}
+	require.True(t, p.verifyFingerprint(sd))
+	require.False(t, p.IsClosed())
+
+	// another certificate than the one declared at join
+	sd.SDP = "v=0\r\no=- 0 0 IN IP4 0.0.0.0\r\ns=-\r\nt=0 0\r\na=fingerprint:sha-256 ef:01\r\n"
+	require.False(t, p.verifyFingerprint(sd))
+	testutils.WithTimeout(t, func() string {
+		if !p.IsClosed() {
+			return "participant was not closed"
+		}
+		return ""
+	})
+	require.Equal(t, types.ParticipantCloseReasonVerifyFailed, p.CloseReason())
+}
+
+func TestVerifyFingerprintProof(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	fingerprint, err := certificateFingerprint("sha-256", cert)
+	require.NoError(t, err)
+
+	nonce := []byte("nonce")
+	digest := sha256.Sum256(nonce)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	require.NoError(t, VerifyFingerprintProof(fingerprint, cert, nonce, signature))
+	require.ErrorIs(t, VerifyFingerprintProof(fingerprint, cert, []byte("other nonce"), signature), ErrFingerprintSignatureInvalid)
+	require.ErrorIs(t, VerifyFingerprintProof("sha-256 AA:BB", cert, nonce, signature), ErrFingerprintCertificateMismatch)
+
+	// a certificate of another key does not prove holding this one
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherCert, err := x509.CreateCertificate(rand.Reader, template, template, &otherKey.PublicKey, otherKey)
+	require.NoError(t, err)
+	otherFingerprint, err := certificateFingerprint("sha-256", otherCert)
+	require.NoError(t, err)
+	require.ErrorIs(t, VerifyFingerprintProof(otherFingerprint, otherCert, nonce, signature), ErrFingerprintSignatureInvalid)
+}
@@ -0,0 +1,262 @@
+package rtc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	defaultTCPActiveDialTimeout = 5 * time.Second
+	defaultTCPActiveDialRate    = 20
+
+	// port of active TCP candidates, which do not listen (RFC 6544)
+	tcpActiveCandidatePort = 9
+	// direction preferences of host candidates (RFC 6544)
+	tcpActiveDirectionPreference  = 6
+	tcpPassiveDirectionPreference = 4
+)
+
+// ActiveTCPMux adds ICE-TCP active mode to a TCP mux: the node connects out to the passive TCP candidates of clients.
+//
+// The ICE agent pairs its passive TCP candidates with those of clients as well, but it only sends over connections
+// clients opened. A connectivity check of such a pair failing for the lack of a connection starts a connection to the
+// client in the background, which is then added to the mux as if the client had opened it, and the checks that follow
+// go through. The node only connects to passive TCP candidates clients signalled, in networks it is allowed to and at
+// a limited rate. Clients are told about active mode by a TCP candidate of type active for each passive one (RFC 6544)
+type ActiveTCPMux struct {
+	ice.TCPMux
+
+	networks    []*net.IPNet
+	dialTimeout time.Duration
+	dialRate    float64
+	dial        func(local *net.TCPAddr, remote *net.TCPAddr, timeout time.Duration) (net.Conn, error)
+
+	lock sync.Mutex
+	// passive TCP candidates signalled by clients, by address, with the number of transports they were signalled to
+	signalled map[string]int
+	// connections being opened or that failed recently, by local and remote address, until they may be retried
+	dialing map[string]time.Time
+	pruneAt time.Time
+	// connections that may be opened right away, refilled at dialRate
+	dialTokens   float64
+	dialTokensAt time.Time
+}
+
+// NewActiveTCPMux wraps a TCP mux to connect out as configured
+func NewActiveTCPMux(mux ice.TCPMux, conf config.TCPActiveConfig) (*ActiveTCPMux, error) {
+	m := &ActiveTCPMux{
+		TCPMux:      mux,
+		dialTimeout: conf.DialTimeout,
+		dialRate:    float64(conf.DialRate),
+		dial:        dialTCP,
+		signalled:   make(map[string]int),
+		dialing:     make(map[string]time.Time),
+	}
+	if m.dialTimeout <= 0 {
+		m.dialTimeout = defaultTCPActiveDialTimeout
+	}
+	if m.dialRate <= 0 {
+		m.dialRate = defaultTCPActiveDialRate
+	}
+	m.dialTokens = m.dialRate
+	for _, cidr := range conf.Networks {
+		_, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		m.networks = append(m.networks, ipnet)
+	}
+	return m, nil
+}
+
+func (m *ActiveTCPMux) GetConnByUfrag(ufrag string, isIPv6 bool, local net.IP) (net.PacketConn, error) {
+	conn, err := m.TCPMux.GetConnByUfrag(ufrag, isIPv6, local)
+	if err != nil {
+		return nil, err
+	}
+	return &activeTCPPacketConn{PacketConn: conn, mux: m}, nil
+}
+
+// AddRemoteCandidate records a TCP candidate signalled by a client, only passive candidates are connected to
+func (m *ActiveTCPMux) AddRemoteCandidate(candidate string) string {
+	if m == nil {
+		return ""
+	}
+	address, ok := passiveTCPCandidateAddress(candidate)
+	if !ok {
+		return ""
+	}
+	m.lock.Lock()
+	m.signalled[address]++
+	m.lock.Unlock()
+	return address
+}
+
+// RemoveRemoteCandidate forgets an address returned by AddRemoteCandidate once the transport it was signalled to closed
+func (m *ActiveTCPMux) RemoveRemoteCandidate(address string) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.signalled[address] <= 1 {
+		delete(m.signalled, address)
+	} else {
+		m.signalled[address]--
+	}
+}
+
+// ActiveCandidate returns the active TCP candidate advertised along a passive host TCP candidate of the node, nil for
+// other candidates
+func (m *ActiveTCPMux) ActiveCandidate(c *webrtc.ICECandidate) *webrtc.ICECandidate {
+	if m == nil || c == nil || c.Protocol != webrtc.ICEProtocolTCP || c.Typ != webrtc.ICECandidateTypeHost ||
+		c.TCPType != ice.TCPTypePassive.String() {
+		return nil
+	}
+	active := *c
+	active.Port = tcpActiveCandidatePort
+	active.TCPType = ice.TCPTypeActive.String()
+	// local preference is direction preference << 13 | other preference
+	active.Priority = c.Priority + (tcpActiveDirectionPreference-tcpPassiveDirectionPreference)<<13<<8
+	return &active
+}
+
+// allows tells whether the node may connect to an address signalled by a client
+func (m *ActiveTCPMux) allows(ip net.IP) bool {
+	if len(m.networks) == 0 {
+		return ip.IsGlobalUnicast() && !ip.IsPrivate()
+	}
+	for _, ipnet := range m.networks {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ActiveTCPMux) connect(conn *activeTCPPacketConn, remote *net.TCPAddr) {
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok || !m.allows(remote.IP) {
+		return
+	}
+	adder, ok := conn.PacketConn.(interface {
+		AddConn(conn net.Conn, firstPacketData []byte) error
+	})
+	if !ok {
+		return
+	}
+
+	key := local.IP.String() + "-" + remote.String()
+	now := time.Now()
+	m.lock.Lock()
+	if m.signalled[remote.String()] == 0 {
+		m.lock.Unlock()
+		return
+	}
+	m.pruneLocked(now)
+	if retryAt, ok := m.dialing[key]; ok && now.Before(retryAt) {
+		m.lock.Unlock()
+		return
+	}
+	if !m.takeDialTokenLocked(now) {
+		m.lock.Unlock()
+		return
+	}
+	// failed attempts are not retried before the timeout elapsed again
+	m.dialing[key] = now.Add(2 * m.dialTimeout)
+	m.lock.Unlock()
+
+	go func() {
+		tcpConn, err := m.dial(&net.TCPAddr{IP: local.IP}, remote, m.dialTimeout)
+		if err == nil {
+			if err = adder.AddConn(tcpConn, nil); err != nil {
+				_ = tcpConn.Close()
+			}
+		}
+		if err != nil {
+			logger.Debugw("could not connect to passive TCP candidate", err, "local", local.IP, "remote", remote)
+			return
+		}
+
+		m.lock.Lock()
+		delete(m.dialing, key)
+		m.lock.Unlock()
+	}()
+}
+
+// pruneLocked drops connections that may be retried, so that addresses failing to connect are not kept
+func (m *ActiveTCPMux) pruneLocked(now time.Time) {
+	if now.Before(m.pruneAt) {
+		return
+	}
+	for key, retryAt := range m.dialing {
+		if !now.Before(retryAt) {
+			delete(m.dialing, key)
+		}
+	}
+	m.pruneAt = now.Add(2 * m.dialTimeout)
+}
+
+func (m *ActiveTCPMux) takeDialTokenLocked(now time.Time) bool {
+	if !m.dialTokensAt.IsZero() {
+		m.dialTokens += now.Sub(m.dialTokensAt).Seconds() * m.dialRate
+		if m.dialTokens > m.dialRate {
+			m.dialTokens = m.dialRate
+		}
+	}
+	m.dialTokensAt = now
+	if m.dialTokens < 1 {
+		return false
+	}
+	m.dialTokens--
+	return true
+}
+
+func passiveTCPCandidateAddress(candidate string) (string, bool) {
+	c, err := ice.UnmarshalCandidate(strings.TrimPrefix(candidate, "candidate:"))
+	if err != nil || c.NetworkType().IsUDP() || c.TCPType() != ice.TCPTypePassive {
+		return "", false
+	}
+	ip := net.ParseIP(c.Address())
+	if ip == nil {
+		return "", false
+	}
+	return (&net.TCPAddr{IP: ip, Port: c.Port()}).String(), true
+}
+
+func dialTCP(local *net.TCPAddr, remote *net.TCPAddr, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{
+		LocalAddr: local,
+		Timeout:   timeout,
+	}
+	return dialer.Dial("tcp", remote.String())
+}
+
+// ---------------------------------------------
+
+type activeTCPPacketConn struct {
+	net.PacketConn
+	mux *ActiveTCPMux
+}
+
+func (c *activeTCPPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	// the mux has no connection with the address
+	if errors.Is(err, io.ErrClosedPipe) {
+		if remote, ok := addr.(*net.TCPAddr); ok {
+			c.mux.connect(c, remote)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,218 @@
+package rtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestActiveTCPMux(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	mux, err := NewActiveTCPMux(ice.NewTCPMuxDefault(ice.TCPMuxParams{
+		Listener:       listener,
+		ReadBufferSize: 8,
+	}), config.TCPActiveConfig{Enabled: true, Networks: []string{"127.0.0.0/8"}})
+	require.NoError(t, err)
+	defer mux.Close()
+
+	// passive candidate of a client
+	client, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer client.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := client.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	conn, err := mux.GetConnByUfrag("ufrag", false, net.IPv4(127, 0, 0, 1))
+	require.NoError(t, err)
+	clientAddr := client.Addr().(*net.TCPAddr)
+	require.Equal(t, clientAddr.String(), mux.AddRemoteCandidate(passiveTCPCandidate(clientAddr)))
+
+	// the first check has no connection to go through and starts one
+	_, err = conn.WriteTo([]byte("check"), clientAddr)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	var clientConn net.Conn
+	select {
+	case clientConn = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("passive candidate not connected to")
+	}
+	defer clientConn.Close()
+
+	require.Eventually(t, func() bool {
+		_, err := conn.WriteTo([]byte("check"), clientAddr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	header := make([]byte, 2)
+	_, err = io.ReadFull(clientConn, header)
+	require.NoError(t, err)
+	payload := make([]byte, binary.BigEndian.Uint16(header))
+	_, err = io.ReadFull(clientConn, payload)
+	require.NoError(t, err)
+	require.Equal(t, "check", string(payload))
+
+	// responses of the client are read from the mux
+	_, err = clientConn.Write(append([]byte{0, 8}, "response"...))
+	require.NoError(t, err)
+	buf := make([]byte, 1500)
+	n, addr, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, "response", string(buf[:n]))
+	require.Equal(t, clientAddr.String(), addr.String())
+}
+
+func TestActiveTCPMuxNetworks(t *testing.T) {
+	mux, err := NewActiveTCPMux(nil, config.TCPActiveConfig{})
+	require.NoError(t, err)
+	require.Equal(t, defaultTCPActiveDialTimeout, mux.dialTimeout)
+	require.True(t, mux.allows(net.ParseIP("203.0.113.10")))
+	require.True(t, mux.allows(net.ParseIP("2001:db8::1")))
+	require.False(t, mux.allows(net.ParseIP("10.0.0.2")))
+	require.False(t, mux.allows(net.ParseIP("127.0.0.1")))
+	require.False(t, mux.allows(net.ParseIP("169.254.169.254")))
+
+	mux, err = NewActiveTCPMux(nil, config.TCPActiveConfig{Networks: []string{"10.0.0.0/8"}})
+	require.NoError(t, err)
+	require.True(t, mux.allows(net.ParseIP("10.0.0.2")))
+	require.False(t, mux.allows(net.ParseIP("203.0.113.10")))
+
+	_, err = NewActiveTCPMux(nil, config.TCPActiveConfig{Networks: []string{"10.0.0.0"}})
+	require.Error(t, err)
+
+	// addresses outside the networks or not signalled are not connected to, failed connections are not retried right
+	// away
+	var dials atomic.Int32
+	mux.dial = func(_, _ *net.TCPAddr, _ time.Duration) (net.Conn, error) {
+		dials.Inc()
+		return nil, io.EOF
+	}
+	public := &net.TCPAddr{IP: net.ParseIP("203.0.113.10"), Port: 9000}
+	private := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 9000}
+	unsignalled := &net.TCPAddr{IP: net.ParseIP("10.0.0.3"), Port: 9000}
+	mux.AddRemoteCandidate(passiveTCPCandidate(public))
+	mux.AddRemoteCandidate(passiveTCPCandidate(private))
+	conn := &activeTCPPacketConn{PacketConn: &closedPacketConn{}, mux: mux}
+	_, _ = conn.WriteTo(nil, public)
+	_, _ = conn.WriteTo(nil, unsignalled)
+	_, _ = conn.WriteTo(nil, private)
+	_, _ = conn.WriteTo(nil, private)
+	require.Eventually(t, func() bool {
+		return dials.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.EqualValues(t, 1, dials.Load())
+
+	// failed connections are forgotten once they may be retried
+	mux.lock.Lock()
+	for key := range mux.dialing {
+		mux.dialing[key] = time.Now()
+	}
+	mux.pruneAt = time.Time{}
+	mux.pruneLocked(time.Now())
+	require.Empty(t, mux.dialing)
+	mux.lock.Unlock()
+
+	// addresses are signalled until every transport they were signalled to closed
+	mux.AddRemoteCandidate(passiveTCPCandidate(private))
+	mux.RemoveRemoteCandidate(private.String())
+	_, _ = conn.WriteTo(nil, private)
+	require.Eventually(t, func() bool {
+		return dials.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+	mux.RemoveRemoteCandidate(private.String())
+	mux.lock.Lock()
+	require.NotContains(t, mux.signalled, private.String())
+	mux.lock.Unlock()
+
+	// active and UDP candidates are not connected to
+	require.Empty(t, mux.AddRemoteCandidate("candidate:1 1 tcp 1518280447 10.0.0.4 9 typ host tcptype active"))
+	require.Empty(t, mux.AddRemoteCandidate("candidate:1 1 udp 2130706431 10.0.0.4 9000 typ host"))
+}
+
+func TestActiveTCPMuxDialRate(t *testing.T) {
+	mux, err := NewActiveTCPMux(nil, config.TCPActiveConfig{Networks: []string{"10.0.0.0/8"}, DialRate: 2})
+	require.NoError(t, err)
+	var dials atomic.Int32
+	mux.dial = func(_, _ *net.TCPAddr, _ time.Duration) (net.Conn, error) {
+		dials.Inc()
+		return nil, io.EOF
+	}
+
+	conn := &activeTCPPacketConn{PacketConn: &closedPacketConn{}, mux: mux}
+	for i := 0; i < 5; i++ {
+		remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, byte(10+i)), Port: 9000}
+		mux.AddRemoteCandidate(passiveTCPCandidate(remote))
+		_, _ = conn.WriteTo(nil, remote)
+	}
+	require.Eventually(t, func() bool {
+		return dials.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.EqualValues(t, 2, dials.Load())
+}
+
+func TestActiveTCPCandidate(t *testing.T) {
+	mux, err := NewActiveTCPMux(nil, config.TCPActiveConfig{})
+	require.NoError(t, err)
+
+	passive := &webrtc.ICECandidate{
+		Foundation: "1",
+		Priority:   1518280447,
+		Address:    "203.0.113.1",
+		Protocol:   webrtc.ICEProtocolTCP,
+		Port:       7881,
+		Typ:        webrtc.ICECandidateTypeHost,
+		Component:  1,
+		TCPType:    "passive",
+	}
+	active := mux.ActiveCandidate(passive)
+	require.NotNil(t, active)
+	require.Equal(t, "active", active.TCPType)
+	require.EqualValues(t, 9, active.Port)
+	require.Equal(t, "203.0.113.1", active.Address)
+	// direction preference 6 instead of 4
+	require.Equal(t, passive.Priority+(6-4)<<13<<8, active.Priority)
+
+	udp := *passive
+	udp.Protocol = webrtc.ICEProtocolUDP
+	udp.TCPType = ""
+	require.Nil(t, mux.ActiveCandidate(&udp))
+	require.Nil(t, mux.ActiveCandidate(nil))
+	require.Nil(t, (*ActiveTCPMux)(nil).ActiveCandidate(passive))
+}
+
+func passiveTCPCandidate(addr *net.TCPAddr) string {
+	return fmt.Sprintf("candidate:1 1 tcp 1518280447 %s %d typ host tcptype passive", addr.IP, addr.Port)
+}
+
+type closedPacketConn struct {
+	net.PacketConn
+}
+
+func (c *closedPacketConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 7881}
+}
+
+func (c *closedPacketConn) WriteTo(_ []byte, _ net.Addr) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func (c *closedPacketConn) AddConn(_ net.Conn, _ []byte) error {
+	return nil
+}
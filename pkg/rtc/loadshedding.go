@@ -0,0 +1,55 @@
+package rtc
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// Load shedding is not part of the protocol messages yet, it is appended as an extra field that clients unaware of
+// it skip. Being an unknown field, it is only carried by the binary signal protocol:
+//
+//	message LoadShedding {
+//	  LoadSheddingLevel level = 1;
+//	  // video tracks of the room the server paused
+//	  repeated string paused_track_sids = 2;
+//	}
+//
+//	enum LoadSheddingLevel {
+//	  NONE = 0;
+//	  REDUCE_LAYERS = 1;
+//	  PAUSE_VIDEO = 2;
+//	  REFUSE_VIDEO = 3;
+//	}
+//
+//	SignalResponse.load_shedding = 102;
+const (
+	signalResponseLoadSheddingField protowire.Number = 102
+
+	loadSheddingLevelField           protowire.Number = 1
+	loadSheddingPausedTrackSidsField protowire.Number = 2
+)
+
+var ErrVideoRefusedOverloaded = errors.New("the server is overloaded, video tracks are refused")
+
+// newLoadSheddingResponse tells a participant how far the node goes in shedding video, and which video tracks of the
+// room it paused
+func newLoadSheddingResponse(level types.LoadSheddingLevel, pausedTrackIDs []livekit.TrackID) *livekit.SignalResponse {
+	var value []byte
+	value = protowire.AppendTag(value, loadSheddingLevelField, protowire.VarintType)
+	value = protowire.AppendVarint(value, uint64(level))
+	for _, trackID := range pausedTrackIDs {
+		value = protowire.AppendTag(value, loadSheddingPausedTrackSidsField, protowire.BytesType)
+		value = protowire.AppendString(value, string(trackID))
+	}
+
+	res := &livekit.SignalResponse{}
+	m := res.ProtoReflect()
+	unknown := protowire.AppendTag(nil, signalResponseLoadSheddingField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, value))
+	return res
+}
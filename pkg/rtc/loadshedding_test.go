@@ -0,0 +1,72 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+func TestLoadSheddingResponse(t *testing.T) {
+	data, err := proto.Marshal(newLoadSheddingResponse(types.LoadSheddingPauseVideo, []livekit.TrackID{"TR_1", "TR_2"}))
+	require.NoError(t, err)
+
+	res := &livekit.SignalResponse{}
+	require.NoError(t, proto.Unmarshal(data, res))
+	require.Nil(t, res.Message)
+	value, ok := findUnknownBytesField(res.ProtoReflect().GetUnknown(), signalResponseLoadSheddingField)
+	require.True(t, ok)
+
+	num, typ, n := protowire.ConsumeTag(value)
+	require.Positive(t, n)
+	require.Equal(t, loadSheddingLevelField, num)
+	require.Equal(t, protowire.VarintType, typ)
+	level, m := protowire.ConsumeVarint(value[n:])
+	require.Equal(t, uint64(types.LoadSheddingPauseVideo), level)
+
+	var trackIDs []string
+	for rest := value[n+m:]; len(rest) > 0; {
+		num, typ, n := protowire.ConsumeTag(rest)
+		require.Equal(t, loadSheddingPausedTrackSidsField, num)
+		require.Equal(t, protowire.BytesType, typ)
+		trackID, m := protowire.ConsumeString(rest[n:])
+		require.Positive(t, m)
+		trackIDs = append(trackIDs, trackID)
+		rest = rest[n+m:]
+	}
+	require.Equal(t, []string{"TR_1", "TR_2"}, trackIDs)
+}
+
+func TestPublicationRefusedOverloaded(t *testing.T) {
+	p := newParticipantForTest("test")
+	p.params.AdmitPublication = func(kind livekit.TrackType) error {
+		if kind == livekit.TrackType_VIDEO {
+			return ErrVideoRefusedOverloaded
+		}
+		return nil
+	}
+	sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+
+	p.AddTrack(&livekit.AddTrackRequest{Cid: "cid1", Name: "mic", Type: livekit.TrackType_AUDIO})
+	require.Equal(t, 1, sink.WriteMessageCallCount())
+	require.IsType(t, &livekit.SignalResponse_TrackPublished{}, sink.WriteMessageArgsForCall(0).(*livekit.SignalResponse).Message)
+
+	p.AddTrack(&livekit.AddTrackRequest{Cid: "cid2", Name: "webcam", Type: livekit.TrackType_VIDEO})
+	require.Equal(t, 2, sink.WriteMessageCallCount())
+	res := sink.WriteMessageArgsForCall(1).(*livekit.SignalResponse)
+	value, ok := findUnknownBytesField(res.ProtoReflect().GetUnknown(), signalResponseTrackRejectedField)
+	require.True(t, ok)
+	cid, ok := findUnknownBytesField(value, trackRejectedCidField)
+	require.True(t, ok)
+	require.Equal(t, "cid2", string(cid))
+
+	_, _, n := protowire.ConsumeTag(value[len(cid)+2:])
+	code, _ := protowire.ConsumeVarint(value[len(cid)+2+n:])
+	require.Equal(t, uint64(TrackRejectedServerOverloaded), code)
+}
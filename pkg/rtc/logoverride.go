@@ -0,0 +1,349 @@
+package rtc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+	"go.uber.org/atomic"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/logger/pionlogger"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+// LogOverride raises the log level of a room, or of a participant identity, until it expires
+type LogOverride struct {
+	Room      livekit.RoomName            `json:"room,omitempty"`
+	Identity  livekit.ParticipantIdentity `json:"identity,omitempty"`
+	Level     string                      `json:"level"`
+	ExpiresAt time.Time                   `json:"expires_at"`
+}
+
+func (o *LogOverride) matches(room livekit.RoomName, identity livekit.ParticipantIdentity) bool {
+	if o.Room == "" && o.Identity == "" {
+		return false
+	}
+	return (o.Room == "" || o.Room == room) && (o.Identity == "" || o.Identity == identity)
+}
+
+type logOverrideRegistry struct {
+	// fast path for the common case of no overrides
+	count atomic.Int32
+
+	lock      sync.RWMutex
+	overrides []*LogOverride
+	verbose   logger.Logger
+}
+
+var logOverrides = &logOverrideRegistry{}
+
+// InitLogOverrides sets up the logger used while an override is active, it logs at debug level with the
+// encoding of the node logger
+func InitLogOverrides(conf logger.Config) error {
+	conf.Level = "debug"
+	conf.Sample = false
+	l, err := logger.NewZapLogger(&conf)
+	if err != nil {
+		return err
+	}
+
+	logOverrides.lock.Lock()
+	// one level for the logger wrapper, as with logger.GetLogger. identities, names and metadata are redacted as
+	// they are by the node logger, including the values of overridden loggers
+	logOverrides.verbose = utils.NewRedactingLogger(l.WithCallDepth(2).WithName("livekit"), utils.GetPIIRedactor())
+	logOverrides.lock.Unlock()
+	return nil
+}
+
+// SetLogOverrides replaces the active overrides
+func SetLogOverrides(overrides []*LogOverride) {
+	logOverrides.lock.Lock()
+	defer logOverrides.lock.Unlock()
+
+	logOverrides.overrides = logOverrides.overrides[:0]
+	now := time.Now()
+	for _, o := range overrides {
+		if o.ExpiresAt.After(now) {
+			logOverrides.overrides = append(logOverrides.overrides, o)
+		}
+	}
+	logOverrides.count.Store(int32(len(logOverrides.overrides)))
+}
+
+// AddLogOverride adds or replaces the override of a room or identity
+func AddLogOverride(override *LogOverride) {
+	logOverrides.lock.Lock()
+	defer logOverrides.lock.Unlock()
+
+	overrides := make([]*LogOverride, 0, len(logOverrides.overrides)+1)
+	now := time.Now()
+	for _, o := range logOverrides.overrides {
+		if o.ExpiresAt.After(now) && (o.Room != override.Room || o.Identity != override.Identity) {
+			overrides = append(overrides, o)
+		}
+	}
+	if override.ExpiresAt.After(now) {
+		overrides = append(overrides, override)
+	}
+	logOverrides.overrides = overrides
+	logOverrides.count.Store(int32(len(overrides)))
+}
+
+func GetLogOverrides() []*LogOverride {
+	logOverrides.lock.RLock()
+	defer logOverrides.lock.RUnlock()
+
+	now := time.Now()
+	overrides := make([]*LogOverride, 0, len(logOverrides.overrides))
+	for _, o := range logOverrides.overrides {
+		if o.ExpiresAt.After(now) {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides
+}
+
+// level returns the most verbose level overriding a room or participant
+func (r *logOverrideRegistry) level(room livekit.RoomName, identity livekit.ParticipantIdentity) (zapcore.Level, logger.Logger, bool) {
+	if r.count.Load() == 0 {
+		return 0, nil, false
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.verbose == nil {
+		return 0, nil, false
+	}
+	found := false
+	level := zapcore.FatalLevel
+	now := time.Now()
+	for _, o := range r.overrides {
+		if !o.matches(room, identity) || !o.ExpiresAt.After(now) {
+			continue
+		}
+		if l := logger.ParseZapLevel(o.Level); l < level {
+			level = l
+			found = true
+		}
+	}
+	return level, r.verbose, found
+}
+
+// ------------------------------------------------
+
+// overrideLogger logs through a debug level logger while an override matches its room or participant
+type overrideLogger struct {
+	base     logger.Logger
+	room     livekit.RoomName
+	identity livekit.ParticipantIdentity
+
+	// applied to the verbose logger when an override is active
+	values    []interface{}
+	names     []string
+	callDepth int
+}
+
+func newOverrideLogger(l logger.Logger) *overrideLogger {
+	if ol, ok := l.(*overrideLogger); ok {
+		dup := *ol
+		return &dup
+	}
+	return &overrideLogger{
+		// account for the wrapper
+		base: l.WithCallDepth(1),
+	}
+}
+
+func (l *overrideLogger) verbose(level zapcore.Level) (logger.Logger, bool) {
+	overrideLevel, verbose, ok := logOverrides.level(l.room, l.identity)
+	if !ok || level < overrideLevel {
+		return nil, false
+	}
+	for _, name := range l.names {
+		verbose = verbose.WithName(name)
+	}
+	if l.callDepth != 0 {
+		verbose = verbose.WithCallDepth(l.callDepth)
+	}
+	return verbose.WithValues(l.values...), true
+}
+
+func (l *overrideLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	if v, ok := l.verbose(zapcore.DebugLevel); ok {
+		v.Debugw(msg, keysAndValues...)
+		return
+	}
+	l.base.Debugw(msg, keysAndValues...)
+}
+
+func (l *overrideLogger) Infow(msg string, keysAndValues ...interface{}) {
+	if v, ok := l.verbose(zapcore.InfoLevel); ok {
+		v.Infow(msg, keysAndValues...)
+		return
+	}
+	l.base.Infow(msg, keysAndValues...)
+}
+
+func (l *overrideLogger) Warnw(msg string, err error, keysAndValues ...interface{}) {
+	if v, ok := l.verbose(zapcore.WarnLevel); ok {
+		v.Warnw(msg, err, keysAndValues...)
+		return
+	}
+	l.base.Warnw(msg, err, keysAndValues...)
+}
+
+func (l *overrideLogger) Errorw(msg string, err error, keysAndValues ...interface{}) {
+	if v, ok := l.verbose(zapcore.ErrorLevel); ok {
+		v.Errorw(msg, err, keysAndValues...)
+		return
+	}
+	l.base.Errorw(msg, err, keysAndValues...)
+}
+
+func (l *overrideLogger) WithValues(keysAndValues ...interface{}) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithValues(keysAndValues...)
+	dup.values = append(append([]interface{}{}, l.values...), keysAndValues...)
+	return &dup
+}
+
+func (l *overrideLogger) WithName(name string) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithName(name)
+	dup.names = append(append([]string{}, l.names...), name)
+	return &dup
+}
+
+func (l *overrideLogger) WithCallDepth(depth int) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithCallDepth(depth)
+	dup.callDepth += depth
+	return &dup
+}
+
+func (l *overrideLogger) WithItemSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithItemSampler()
+	return &dup
+}
+
+func (l *overrideLogger) WithoutSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithoutSampler()
+	return &dup
+}
+
+// errorReportingContext returns the room and participant of a logger created with LoggerWithRoom/LoggerWithParticipant
+func errorReportingContext(l logger.Logger) []interface{} {
+	ol, ok := l.(*overrideLogger)
+	if !ok {
+		return nil
+	}
+	var keysAndValues []interface{}
+	if ol.room != "" {
+		keysAndValues = append(keysAndValues, "room", ol.room)
+	}
+	if ol.identity != "" {
+		keysAndValues = append(keysAndValues, "participant", utils.GetPIIRedactor().Identity(string(ol.identity)))
+	}
+	return keysAndValues
+}
+
+// profilingLabels labels goroutines with the room of a logger created with LoggerWithRoom
+func profilingLabels(l logger.Logger) []string {
+	if ol, ok := l.(*overrideLogger); ok && ol.room != "" {
+		return []string{"room", string(ol.room)}
+	}
+	return nil
+}
+
+func (l *overrideLogger) isOverridden() bool {
+	_, _, ok := logOverrides.level(l.room, l.identity)
+	return ok
+}
+
+// ------------------------------------------------
+
+// newPionLoggerFactory lets pion logs of an overridden participant through regardless of the pion log level
+func newPionLoggerFactory(l logger.Logger) logging.LoggerFactory {
+	lf := pionlogger.NewLoggerFactory(l)
+	ol, ok := l.(*overrideLogger)
+	if !ok {
+		return lf
+	}
+	return &pionOverrideLoggerFactory{
+		LoggerFactory: lf,
+		logger:        ol,
+	}
+}
+
+type pionOverrideLoggerFactory struct {
+	*pionlogger.LoggerFactory
+	logger *overrideLogger
+}
+
+func (f *pionOverrideLoggerFactory) NewLogger(scope string) logging.LeveledLogger {
+	return &pionOverrideLogger{
+		LeveledLogger: f.LoggerFactory.NewLogger(scope),
+		logger:        f.logger.WithName(scope).(*overrideLogger),
+	}
+}
+
+type pionOverrideLogger struct {
+	logging.LeveledLogger
+	logger *overrideLogger
+}
+
+func (l *pionOverrideLogger) Debug(msg string) {
+	if l.logger.isOverridden() {
+		l.logger.Debugw(msg)
+		return
+	}
+	l.LeveledLogger.Debug(msg)
+}
+
+func (l *pionOverrideLogger) Debugf(format string, args ...interface{}) {
+	if l.logger.isOverridden() {
+		l.logger.Debugw(fmt.Sprintf(format, args...))
+		return
+	}
+	l.LeveledLogger.Debugf(format, args...)
+}
+
+func (l *pionOverrideLogger) Info(msg string) {
+	if l.logger.isOverridden() {
+		l.logger.Infow(msg)
+		return
+	}
+	l.LeveledLogger.Info(msg)
+}
+
+func (l *pionOverrideLogger) Infof(format string, args ...interface{}) {
+	if l.logger.isOverridden() {
+		l.logger.Infow(fmt.Sprintf(format, args...))
+		return
+	}
+	l.LeveledLogger.Infof(format, args...)
+}
+
+func (l *pionOverrideLogger) Warn(msg string) {
+	if l.logger.isOverridden() {
+		l.logger.Warnw(msg, nil)
+		return
+	}
+	l.LeveledLogger.Warn(msg)
+}
+
+func (l *pionOverrideLogger) Warnf(format string, args ...interface{}) {
+	if l.logger.isOverridden() {
+		l.logger.Warnw(fmt.Sprintf(format, args...), nil)
+		return
+	}
+	l.LeveledLogger.Warnf(format, args...)
+}
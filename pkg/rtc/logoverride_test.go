@@ -0,0 +1,157 @@
+package rtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+func TestLogOverrideRedaction(t *testing.T) {
+	redactor, err := utils.NewPIIRedactor(utils.PIIRedactionHash, "key")
+	require.NoError(t, err)
+	prevRedactor := utils.GetPIIRedactor()
+	utils.SetPIIRedactor(redactor)
+	logOverrides.lock.Lock()
+	prevVerbose := logOverrides.verbose
+	logOverrides.lock.Unlock()
+	t.Cleanup(func() {
+		utils.SetPIIRedactor(prevRedactor)
+		logOverrides.lock.Lock()
+		logOverrides.verbose = prevVerbose
+		logOverrides.lock.Unlock()
+	})
+
+	require.NoError(t, InitLogOverrides(logger.Config{}))
+	logOverrides.lock.RLock()
+	defer logOverrides.lock.RUnlock()
+	require.IsType(t, &utils.RedactingLogger{}, logOverrides.verbose)
+}
+
+func TestLogOverride(t *testing.T) {
+	verbose := &recordingLogger{}
+	logOverrides.lock.Lock()
+	prevVerbose := logOverrides.verbose
+	logOverrides.verbose = verbose
+	logOverrides.lock.Unlock()
+	t.Cleanup(func() {
+		SetLogOverrides(nil)
+		logOverrides.lock.Lock()
+		logOverrides.verbose = prevVerbose
+		logOverrides.lock.Unlock()
+	})
+
+	roomLogger := LoggerWithRoom(logger.GetLogger(), "room", "RM_1")
+	alice := LoggerWithParticipant(roomLogger, "alice", "PA_1", false)
+	bob := LoggerWithParticipant(roomLogger, "bob", "PA_2", false)
+	other := LoggerWithParticipant(LoggerWithRoom(logger.GetLogger(), "other", "RM_2"), "alice", "PA_3", false)
+
+	alice.Debugw("before override")
+	require.Empty(t, verbose.messages())
+
+	t.Run("participant", func(t *testing.T) {
+		AddLogOverride(&LogOverride{Room: "room", Identity: "alice", Level: "debug", ExpiresAt: time.Now().Add(time.Minute)})
+		alice.WithValues("trackID", "TR_1").Debugw("track debug")
+		bob.Debugw("not overridden")
+		other.Debugw("other room")
+		require.Equal(t, []string{"track debug"}, verbose.messages())
+		require.Contains(t, verbose.lastValues(), livekit.ParticipantIdentity("alice"))
+		require.Contains(t, verbose.lastValues(), "TR_1")
+	})
+
+	t.Run("room", func(t *testing.T) {
+		verbose.reset()
+		SetLogOverrides([]*LogOverride{{Room: "room", Level: "info", ExpiresAt: time.Now().Add(time.Minute)}})
+		bob.Debugw("below override level")
+		bob.Infow("room info")
+		roomLogger.Infow("room level")
+		require.Equal(t, []string{"room info", "room level"}, verbose.messages())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		verbose.reset()
+		AddLogOverride(&LogOverride{Room: "room", Level: "info", ExpiresAt: time.Now().Add(-time.Second)})
+		bob.Infow("expired")
+		require.Empty(t, verbose.messages())
+		require.Empty(t, GetLogOverrides())
+	})
+}
+
+type recordingLogger struct {
+	lock    sync.Mutex
+	entries []recordedEntry
+	values  []interface{}
+	parent  *recordingLogger
+}
+
+type recordedEntry struct {
+	msg    string
+	values []interface{}
+}
+
+func (l *recordingLogger) root() *recordingLogger {
+	if l.parent != nil {
+		return l.parent.root()
+	}
+	return l
+}
+
+func (l *recordingLogger) record(msg string, keysAndValues ...interface{}) {
+	r := l.root()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries = append(r.entries, recordedEntry{msg: msg, values: append(append([]interface{}{}, l.values...), keysAndValues...)})
+}
+
+func (l *recordingLogger) messages() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var msgs []string
+	for _, e := range l.entries {
+		msgs = append(msgs, e.msg)
+	}
+	return msgs
+}
+
+func (l *recordingLogger) lastValues() []interface{} {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.entries[len(l.entries)-1].values
+}
+
+func (l *recordingLogger) reset() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = nil
+}
+
+func (l *recordingLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues...)
+}
+
+func (l *recordingLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues...)
+}
+
+func (l *recordingLogger) Warnw(msg string, _ error, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues...)
+}
+
+func (l *recordingLogger) Errorw(msg string, _ error, keysAndValues ...interface{}) {
+	l.record(msg, keysAndValues...)
+}
+
+func (l *recordingLogger) WithValues(keysAndValues ...interface{}) logger.Logger {
+	return &recordingLogger{parent: l, values: append(append([]interface{}{}, l.values...), keysAndValues...)}
+}
+
+func (l *recordingLogger) WithName(_ string) logger.Logger   { return l }
+func (l *recordingLogger) WithCallDepth(_ int) logger.Logger { return l }
+func (l *recordingLogger) WithItemSampler() logger.Logger    { return l }
+func (l *recordingLogger) WithoutSampler() logger.Logger     { return l }
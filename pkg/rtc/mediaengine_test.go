@@ -24,3 +24,74 @@ func TestIsCodecEnabled(t *testing.T) {
 		require.False(t, IsCodecEnabled(enabledCodecs, webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}))
 	})
 }
+
+func TestH265Negotiation(t *testing.T) {
+	// offer of a Safari publisher preferring H.265
+	offerer := webrtc.MediaEngine{}
+	require.NoError(t, offerer.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH265, ClockRate: 90000, SDPFmtpLine: "profile-id=1;tier-flag=0;level-id=93;tx-mode=SRST"},
+		PayloadType:        98,
+	}, webrtc.RTPCodecTypeVideo))
+	require.NoError(t, offerer.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f"},
+		PayloadType:        102,
+	}, webrtc.RTPCodecTypeVideo))
+	publisher, err := webrtc.NewAPI(webrtc.WithMediaEngine(&offerer)).NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer publisher.Close()
+	_, err = publisher.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly})
+	require.NoError(t, err)
+	offer, err := publisher.CreateOffer(nil)
+	require.NoError(t, err)
+
+	negotiate := func(enabledCodecs []*livekit.Codec) []webrtc.RTPCodecParameters {
+		me, err := createMediaEngine(enabledCodecs, DirectionConfig{})
+		require.NoError(t, err)
+		pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(me)).NewPeerConnection(webrtc.Configuration{})
+		require.NoError(t, err)
+		defer pc.Close()
+
+		require.NoError(t, pc.SetRemoteDescription(offer))
+		_, err = pc.CreateAnswer(nil)
+		require.NoError(t, err)
+		return pc.GetTransceivers()[0].Receiver().GetParameters().Codecs
+	}
+
+	codecs := negotiate([]*livekit.Codec{{Mime: "video/h264"}, {Mime: "video/h265"}})
+	require.NotEmpty(t, codecs)
+	require.Equal(t, webrtc.MimeTypeH265, codecs[0].MimeType)
+
+	// not enabled in the room
+	codecs = negotiate([]*livekit.Codec{{Mime: "video/h264"}})
+	for _, c := range codecs {
+		require.NotEqual(t, webrtc.MimeTypeH265, c.MimeType)
+	}
+}
+
+func TestREDFeedback(t *testing.T) {
+	offer := func(conf DirectionConfig) string {
+		me, err := createMediaEngine([]*livekit.Codec{{Mime: "audio/opus"}, {Mime: "audio/red"}}, conf)
+		require.NoError(t, err)
+		pc, err := webrtc.NewAPI(webrtc.WithMediaEngine(me)).NewPeerConnection(webrtc.Configuration{})
+		require.NoError(t, err)
+		defer pc.Close()
+
+		_, err = pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly})
+		require.NoError(t, err)
+		offer, err := pc.CreateOffer(nil)
+		require.NoError(t, err)
+		return offer.SDP
+	}
+
+	// RED is retransmitted like the opus it carries
+	sdp := offer(DirectionConfig{
+		RTCPFeedback: RTCPFeedbackConfig{Audio: []webrtc.RTCPFeedback{{Type: webrtc.TypeRTCPFBNACK}}},
+	})
+	require.Contains(t, sdp, "a=rtpmap:63 red/48000/2")
+	require.Contains(t, sdp, "a=rtcp-fb:111 nack")
+	require.Contains(t, sdp, "a=rtcp-fb:63 nack")
+
+	sdp = offer(DirectionConfig{})
+	require.Contains(t, sdp, "a=rtpmap:63 red/48000/2")
+	require.NotContains(t, sdp, "nack")
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
 
-	"github.com/livekit/mediatransportutil/pkg/twcc"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
@@ -19,6 +19,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
+	"github.com/livekit/livekit-server/pkg/sfu/twcc"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 )
 
@@ -34,7 +35,17 @@ type MediaTrack struct {
 
 	dynacastManager *DynacastManager
 
-	lock sync.RWMutex
+	// lower simulcast layers are generated from the single published layer
+	generatesLayers atomic.Bool
+
+	lock              sync.RWMutex
+	onCodecSwitched   func(fromMime string, toMime string)
+	onKeyEpochChanged func(keyEpoch uint8)
+	onPlaintext       func()
+
+	// client track ID of a media source that replaces the current one, and of the last replacement
+	pendingReplacementCid string
+	replacementCid        string
 }
 
 type MediaTrackParams struct {
@@ -52,9 +63,23 @@ type MediaTrackParams struct {
 	PLIThrottleConfig config.PLIThrottleConfig
 	AudioConfig       config.AudioConfig
 	VideoConfig       config.VideoConfig
+	CodecTranscoder   CodecTranscoder
 	Telemetry         telemetry.TelemetryService
 	Logger            logger.Logger
 	SimTracks         map[uint32]SimulcastTrackInfo
+
+	// set when lower layers of single layer video may be generated
+	SimulcastGenerator SimulcastGenerator
+
+	// frames have to be end-to-end encrypted as declared in the track info
+	E2EERequired       bool
+	MaxPlaintextFrames int
+	// payloads are not inspected, e. g. frames encrypted by clients with insertable streams
+	OpaquePayload bool
+
+	// forwarding is held until the other tracks declared to start together are ready when set
+	StartBarrier       *sfu.StartBarrier
+	StartBarrierMember string
 }
 
 func NewMediaTrack(params MediaTrackParams) *MediaTrack {
@@ -72,8 +97,11 @@ func NewMediaTrack(params MediaTrackParams) *MediaTrack {
 		ReceiverConfig:      params.ReceiverConfig,
 		SubscriberConfig:    params.SubscriberConfig,
 		AudioConfig:         params.AudioConfig,
+		CodecFallback:       params.VideoConfig.CodecFallback,
+		CodecTranscoder:     params.CodecTranscoder,
 		Telemetry:           params.Telemetry,
 		Logger:              params.Logger,
+		OpaquePayload:       params.OpaquePayload,
 	})
 	t.MediaTrackReceiver.OnVideoLayerUpdate(func(layers []*livekit.VideoLayer) {
 		t.params.Telemetry.TrackPublishedUpdate(context.Background(), t.PublisherID(),
@@ -133,6 +161,9 @@ func (t *MediaTrack) OnSubscribedMaxQualityChange(
 	}
 
 	handler := func(subscribedQualities []*livekit.SubscribedCodec, maxSubscribedQualities []types.SubscribedCodecQuality) {
+		if t.generatesLayers.Load() {
+			subscribedQualities, maxSubscribedQualities = publishedLayerQualities(subscribedQualities, maxSubscribedQualities)
+		}
 		if f != nil && !t.IsMuted() {
 			_ = f(t.ID(), subscribedQualities, maxSubscribedQualities)
 		}
@@ -148,12 +179,86 @@ func (t *MediaTrack) OnSubscribedMaxQualityChange(
 	t.dynacastManager.OnSubscribedMaxQualityChange(handler)
 }
 
+// publishedLayerQualities maps subscribed qualities of a track with generated layers to the single layer the
+// publisher sends, it is needed as long as any layer is
+func publishedLayerQualities(
+	subscribedQualities []*livekit.SubscribedCodec,
+	maxSubscribedQualities []types.SubscribedCodecQuality,
+) ([]*livekit.SubscribedCodec, []types.SubscribedCodecQuality) {
+	codecs := make([]*livekit.SubscribedCodec, 0, len(subscribedQualities))
+	for _, sc := range subscribedQualities {
+		enabled := false
+		for _, sq := range sc.Qualities {
+			enabled = enabled || sq.Enabled
+		}
+		qualities := make([]*livekit.SubscribedQuality, 0, len(sc.Qualities))
+		for _, sq := range sc.Qualities {
+			qualities = append(qualities, &livekit.SubscribedQuality{Quality: sq.Quality, Enabled: enabled})
+		}
+		codecs = append(codecs, &livekit.SubscribedCodec{Codec: sc.Codec, Qualities: qualities})
+	}
+
+	maxQualities := make([]types.SubscribedCodecQuality, 0, len(maxSubscribedQualities))
+	for _, q := range maxSubscribedQualities {
+		if q.Quality != livekit.VideoQuality_OFF {
+			q.Quality = livekit.VideoQuality_HIGH
+		}
+		maxQualities = append(maxQualities, q)
+	}
+	return codecs, maxQualities
+}
+
 func (t *MediaTrack) NotifySubscriberNodeMaxQuality(nodeID livekit.NodeID, qualities []types.SubscribedCodecQuality) {
 	if t.dynacastManager != nil {
 		t.dynacastManager.NotifySubscriberNodeMaxQuality(nodeID, qualities)
 	}
 }
 
+// OnCodecSwitched is called when the publisher switches codec of the primary receiver without re-publishing
+func (t *MediaTrack) OnCodecSwitched(f func(fromMime string, toMime string)) {
+	t.lock.Lock()
+	t.onCodecSwitched = f
+	t.lock.Unlock()
+}
+
+// OnKeyEpochChanged is called when the publisher of an end-to-end encrypted track starts using another key
+func (t *MediaTrack) OnKeyEpochChanged(f func(keyEpoch uint8)) {
+	t.lock.Lock()
+	t.onKeyEpochChanged = f
+	t.lock.Unlock()
+}
+
+// OnPlaintextDetected is called when the frames of a track required to be end-to-end encrypted turn out not to be,
+// the track is no longer forwarded then
+func (t *MediaTrack) OnPlaintextDetected(f func()) {
+	t.lock.Lock()
+	t.onPlaintext = f
+	t.lock.Unlock()
+}
+
+func (t *MediaTrack) handlePlaintext() {
+	t.lock.RLock()
+	onPlaintext := t.onPlaintext
+	t.lock.RUnlock()
+	if onPlaintext != nil {
+		onPlaintext()
+	}
+}
+
+func (t *MediaTrack) handleKeyIndexChange(keyIndex uint8) {
+	// simulcast layers report the same key
+	if !t.MediaTrackReceiver.SetKeyEpoch(keyIndex) {
+		return
+	}
+
+	t.lock.RLock()
+	onKeyEpochChanged := t.onKeyEpochChanged
+	t.lock.RUnlock()
+	if onKeyEpochChanged != nil {
+		onKeyEpochChanged(keyIndex)
+	}
+}
+
 func (t *MediaTrack) SignalCid() string {
 	return t.params.SignalCid
 }
@@ -163,6 +268,13 @@ func (t *MediaTrack) HasSdpCid(cid string) bool {
 		return true
 	}
 
+	t.lock.RLock()
+	isReplacement := t.pendingReplacementCid == cid || t.replacementCid == cid
+	t.lock.RUnlock()
+	if cid != "" && isReplacement {
+		return true
+	}
+
 	info := t.params.TrackInfo
 	for _, c := range info.Codecs {
 		if c.Cid == cid {
@@ -193,9 +305,18 @@ func (t *MediaTrack) SetPendingCodecSid(codecs []*livekit.SimulcastCodec) {
 	t.MediaTrackReceiver.UpdateTrackInfo(ti)
 }
 
+// SetPendingReplacement marks media of the given client track ID as the new source of this track,
+// when it is received, subscribers are moved to it keeping track SID and subscriptions
+func (t *MediaTrack) SetPendingReplacement(cid string) {
+	t.lock.Lock()
+	t.pendingReplacementCid = cid
+	t.lock.Unlock()
+}
+
 // AddReceiver adds a new RTP receiver to the track, returns true when receiver represents a new codec
 func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.TrackRemote, twcc *twcc.Responder, mid string) bool {
 	var newCodec bool
+	var switchedFromMime string
 	buff, rtcpReader := t.params.BufferFactory.GetBufferPair(uint32(track.SSRC()))
 	if buff == nil || rtcpReader == nil {
 		t.params.Logger.Errorw("could not retrieve buffer pair", nil)
@@ -203,7 +324,7 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 	}
 
 	rtcpReader.OnPacket(func(bytes []byte) {
-		pkts, err := rtcp.Unmarshal(bytes)
+		pkts, err := buffer.UnmarshalRTCP(bytes)
 		if err != nil {
 			t.params.Logger.Errorw("could not unmarshal RTCP", err)
 			return
@@ -224,7 +345,8 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 	layer := buffer.RidToSpatialLayer(track.RID(), t.trackInfo)
 	t.params.Logger.Debugw("AddReceiver", "mime", track.Codec().MimeType)
 	wr := t.MediaTrackReceiver.Receiver(mime)
-	if wr == nil {
+	isReplacement := t.pendingReplacementCid != "" && t.pendingReplacementCid == track.ID()
+	if wr == nil || isReplacement {
 		var priority int
 		for idx, c := range t.params.TrackInfo.Codecs {
 			if strings.HasSuffix(mime, c.MimeType) {
@@ -232,6 +354,39 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 				break
 			}
 		}
+		receiverOpts := []sfu.ReceiverOpts{
+			sfu.WithPliThrottleConfig(t.params.PLIThrottleConfig),
+			sfu.WithAudioConfig(t.params.AudioConfig),
+			sfu.WithLoadBalanceThreshold(20),
+			sfu.WithStreamTrackers(),
+			sfu.WithErrorContext(errorReportingContext(t.params.Logger)...),
+			sfu.WithProfilingLabels(profilingLabels(t.params.Logger)...),
+		}
+		if t.params.TrackInfo.Encryption == livekit.Encryption_GCM {
+			receiverOpts = append(receiverOpts, sfu.WithKeyIndexObserver(t.handleKeyIndexChange))
+		}
+		if t.params.E2EERequired {
+			receiverOpts = append(receiverOpts, sfu.WithFrameEncryptionCheck(
+				t.params.TrackInfo.Encryption,
+				t.params.MaxPlaintextFrames,
+				t.handlePlaintext,
+			))
+		}
+		if t.params.StartBarrier != nil {
+			receiverOpts = append(receiverOpts, sfu.WithStartBarrier(t.params.StartBarrier, t.params.StartBarrierMember))
+		}
+		if t.PrimaryReceiver() == nil && !isReplacement && t.canGenerateLayers(track, mid) {
+			if t.requestGeneratedLayers(track) {
+				receiverOpts = append(receiverOpts, sfu.WithGeneratedLayers())
+				layer = buffer.VideoQualityToSpatialLayer(livekit.VideoQuality_HIGH, t.params.TrackInfo)
+			}
+		}
+		stopHistograms := func() {}
+		if t.params.ReceiverConfig.DetailedStatsInterval > 0 {
+			histograms := sfu.NewTrackHistograms(track.Codec().ClockRate)
+			receiverOpts = append(receiverOpts, sfu.WithTrackHistograms(histograms))
+			stopHistograms = t.exportHistograms(mime, histograms)
+		}
 		newWR := sfu.NewWebRTCReceiver(
 			receiver,
 			track,
@@ -239,13 +394,16 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 			LoggerWithCodecMime(t.params.Logger, mime),
 			twcc,
 			t.params.VideoConfig.StreamTracker,
-			sfu.WithPliThrottleConfig(t.params.PLIThrottleConfig),
-			sfu.WithAudioConfig(t.params.AudioConfig),
-			sfu.WithLoadBalanceThreshold(20),
-			sfu.WithStreamTrackers(),
+			receiverOpts...,
 		)
 		newWR.SetRTCPCh(t.params.RTCPChan)
 		newWR.OnCloseHandler(func() {
+			stopHistograms()
+			if t.MediaTrackReceiver.Receiver(mime) != newWR && t.MediaTrackReceiver.PrimaryReceiver() != nil {
+				// replaced by a codec switch or a new source, track continues with the new receiver
+				return
+			}
+
 			t.MediaTrackReceiver.SetClosing()
 			t.MediaTrackReceiver.ClearReceiver(mime, false)
 			if t.MediaTrackReceiver.TryClose() {
@@ -282,7 +440,20 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 
 		t.buffer = buff
 
-		t.MediaTrackReceiver.SetupReceiver(newWR, priority, mid)
+		replaced := false
+		if isReplacement {
+			t.pendingReplacementCid = ""
+			t.replacementCid = track.ID()
+			replaced = t.MediaTrackReceiver.ReplaceReceiver(newWR, mid)
+		}
+		if !replaced && t.MediaTrackReceiver.PrimaryReceiver() != nil && (isReplacement || t.isPrimaryMid(mid)) {
+			// publisher renegotiated the primary transceiver with a different codec,
+			// or replaced the source with one using a different codec
+			switchedFromMime, _ = t.MediaTrackReceiver.SwitchPrimaryCodec(newWR, mid)
+		}
+		if switchedFromMime == "" && !replaced {
+			t.MediaTrackReceiver.SetupReceiver(newWR, priority, mid)
+		}
 
 		for ssrc, info := range t.params.SimTracks {
 			if info.Mid == mid {
@@ -292,20 +463,31 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 		wr = newWR
 		newCodec = true
 	}
+	onCodecSwitched := t.onCodecSwitched
 	t.lock.Unlock()
 
+	if switchedFromMime != "" && onCodecSwitched != nil {
+		onCodecSwitched(switchedFromMime, mime)
+	}
+
 	wr.(*sfu.WebRTCReceiver).AddUpTrack(track, buff)
 
 	// LK-TODO: can remove this completely when VideoLayers protocol becomes the default as it has info from client or if we decide to use TrackInfo.Simulcast
-	if t.numUpTracks.Inc() > 1 || track.RID() != "" {
+	if t.numUpTracks.Inc() > 1 || track.RID() != "" || t.generatesLayers.Load() {
 		// cannot only rely on numUpTracks since we fire metadata events immediately after the first layer
 		t.SetSimulcast(true)
 	}
 
 	if t.IsSimulcast() {
-		t.MediaTrackReceiver.SetLayerSsrc(mime, track.RID(), uint32(track.SSRC()))
+		rid := track.RID()
+		if rid == "" && t.generatesLayers.Load() {
+			rid = buffer.FullResolution
+		}
+		t.MediaTrackReceiver.SetLayerSsrc(mime, rid, uint32(track.SSRC()))
 	}
 
+	buff.SetTrackSource(t.params.TrackInfo.Source)
+	buff.SetOpaquePayload(t.IsOpaquePayload())
 	buff.Bind(receiver.GetParameters(), track.Codec().RTPCodecCapability)
 
 	// if subscriber request fps before fps calculated, update them after fps updated.
@@ -326,6 +508,134 @@ func (t *MediaTrack) AddReceiver(receiver *webrtc.RTPReceiver, track *webrtc.Tra
 	return newCodec
 }
 
+// canGenerateLayers tells whether lower simulcast layers can be generated from a video track published as a single
+// layer, large enough to be worth scaling down
+func (t *MediaTrack) canGenerateLayers(track *webrtc.TrackRemote, mid string) bool {
+	conf := t.params.VideoConfig.SimulcastGeneration
+	ti := t.params.TrackInfo
+	if !conf.Enabled || t.params.SimulcastGenerator == nil || ti.Type != livekit.TrackType_VIDEO || ti.Simulcast {
+		return false
+	}
+	if track.RID() != "" || len(ti.Layers) > 1 || ti.Width < conf.MinWidth || sfu.IsSvcCodec(track.Codec().MimeType) {
+		return false
+	}
+	for _, info := range t.params.SimTracks {
+		if info.Mid == mid {
+			return false
+		}
+	}
+	return true
+}
+
+// requestGeneratedLayers asks for the lower layers and announces them in the track info, the published layer
+// becomes the top one. Layers are not announced when they cannot be generated.
+func (t *MediaTrack) requestGeneratedLayers(track *webrtc.TrackRemote) bool {
+	layers := generatedLayers(t.params.TrackInfo)
+	if err := t.params.SimulcastGenerator.RequestLayers(t, track.Codec(), layers[:len(layers)-1]); err != nil {
+		t.params.Logger.Infow("cannot generate simulcast layers", "error", err)
+		return false
+	}
+
+	ti := proto.Clone(t.params.TrackInfo).(*livekit.TrackInfo)
+	ti.Layers = layers
+	t.params.TrackInfo = ti
+	t.MediaTrackReceiver.UpdateTrackInfo(ti)
+	t.generatesLayers.Store(true)
+	t.params.Logger.Infow("generating simulcast layers", "layers", layers)
+	return true
+}
+
+// generatedLayers returns the layers of a single layer track once lower ones are generated, lowest first, the
+// published layer being the last
+func generatedLayers(ti *livekit.TrackInfo) []*livekit.VideoLayer {
+	top := &livekit.VideoLayer{
+		Quality: livekit.VideoQuality_HIGH,
+		Width:   ti.Width,
+		Height:  ti.Height,
+	}
+	if len(ti.Layers) != 0 {
+		top.Bitrate = ti.Layers[0].Bitrate
+		top.Ssrc = ti.Layers[0].Ssrc
+	}
+
+	scaled := func(quality livekit.VideoQuality, divisor uint32, bitrateDivisor uint32) *livekit.VideoLayer {
+		return &livekit.VideoLayer{
+			Quality: quality,
+			// encoders want even dimensions
+			Width:   (top.Width / divisor) &^ 1,
+			Height:  (top.Height / divisor) &^ 1,
+			Bitrate: top.Bitrate / bitrateDivisor,
+		}
+	}
+	return []*livekit.VideoLayer{
+		scaled(livekit.VideoQuality_LOW, 4, 10),
+		scaled(livekit.VideoQuality_MEDIUM, 2, 3),
+		top,
+	}
+}
+
+// AddGeneratedLayer attaches a track published by a transcoder worker as a generated lower layer of this track
+func (t *MediaTrack) AddGeneratedLayer(quality livekit.VideoQuality, rendition *MediaTrack) error {
+	wr, ok := t.PrimaryReceiver().(*sfu.WebRTCReceiver)
+	receiver := rendition.PrimaryReceiver()
+	if !ok || receiver == nil {
+		return ErrNoReceiver
+	}
+
+	layer := buffer.VideoQualityToSpatialLayer(quality, t.params.TrackInfo)
+	if err := wr.AddGeneratedLayer(layer, receiver); err != nil {
+		return err
+	}
+	rendition.AddOnClose(func() {
+		wr.RemoveGeneratedLayer(layer)
+	})
+	return nil
+}
+
+// exportHistograms sends the histograms of a receiver to telemetry every interval until stopped, the partial
+// interval is sent on stop
+func (t *MediaTrack) exportHistograms(mime string, histograms *sfu.TrackHistograms) func() {
+	done := make(chan struct{})
+	var once sync.Once
+	send := func() {
+		jitter, delay := histograms.Rotate()
+		t.params.Telemetry.TrackHistograms(context.Background(), t.params.ParticipantID, t.params.ParticipantIdentity, t.ToProto(), mime, jitter, delay)
+	}
+
+	go func() {
+		ticker := time.NewTicker(t.params.ReceiverConfig.DetailedStatsInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				send()
+				return
+			case <-ticker.C:
+				send()
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+func (t *MediaTrack) isPrimaryMid(mid string) bool {
+	if mid == "" {
+		return false
+	}
+
+	ti := t.MediaTrackReceiver.TrackInfo(false)
+	if ti.Mid != "" {
+		return ti.Mid == mid
+	}
+	return len(ti.Codecs) != 0 && ti.Codecs[0].Mid == mid
+}
+
 func (t *MediaTrack) GetConnectionScoreAndQuality() (float32, livekit.ConnectionQuality) {
 	receiver := t.PrimaryReceiver()
 	if rtcReceiver, ok := receiver.(*sfu.WebRTCReceiver); ok {
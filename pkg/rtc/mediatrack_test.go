@@ -3,9 +3,18 @@ package rtc
 import (
 	"testing"
 
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 )
 
 func TestTrackInfo(t *testing.T) {
@@ -41,6 +50,59 @@ func TestTrackInfo(t *testing.T) {
 	require.True(t, mt.ToProto().Simulcast)
 }
 
+func TestTrackEncryptionStatus(t *testing.T) {
+	t.Run("not encrypted", func(t *testing.T) {
+		mt := NewMediaTrack(MediaTrackParams{
+			TrackInfo: &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_AUDIO},
+		})
+		require.Equal(t, &types.TrackEncryptionStatus{KeyEpoch: -1, ServerReadable: true}, types.GetTrackEncryptionStatus(mt.ToProto()))
+	})
+
+	t.Run("key epoch follows the publisher", func(t *testing.T) {
+		mt := NewMediaTrack(MediaTrackParams{
+			TrackInfo: &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_AUDIO, Encryption: livekit.Encryption_GCM},
+		})
+		var changes []uint8
+		mt.OnKeyEpochChanged(func(keyEpoch uint8) {
+			changes = append(changes, keyEpoch)
+		})
+
+		expected := &types.TrackEncryptionStatus{Encrypted: true, Cipher: types.CipherAESGCM, KeyEpoch: -1}
+		require.Equal(t, expected, types.GetTrackEncryptionStatus(mt.ToProto()))
+
+		mt.handleKeyIndexChange(0)
+		mt.handleKeyIndexChange(0)
+		mt.handleKeyIndexChange(3)
+		require.Equal(t, []uint8{0, 3}, changes)
+
+		expected.KeyEpoch = 3
+		ti := mt.ToProto()
+		require.Equal(t, expected, types.GetTrackEncryptionStatus(ti))
+		require.Equal(t, expected, mt.EncryptionStatus())
+
+		// replaced, not repeated, when set again
+		types.SetTrackEncryptionStatus(ti, types.NewTrackEncryptionStatus(livekit.Encryption_CUSTOM))
+		require.Equal(t, &types.TrackEncryptionStatus{Encrypted: true, Cipher: types.CipherCustom, KeyEpoch: -1}, types.GetTrackEncryptionStatus(ti))
+		data, err := proto.Marshal(ti)
+		require.NoError(t, err)
+		decoded := &livekit.TrackInfo{}
+		require.NoError(t, proto.Unmarshal(data, decoded))
+		require.Equal(t, types.GetTrackEncryptionStatus(ti), types.GetTrackEncryptionStatus(decoded))
+	})
+
+	t.Run("key index from frame trailer", func(t *testing.T) {
+		// IV length and key index trail the IV
+		keyIndex, ok := sfu.E2EEKeyIndex(append(make([]byte, 40), 12, 5))
+		require.True(t, ok)
+		require.Equal(t, uint8(5), keyIndex)
+
+		_, ok = sfu.E2EEKeyIndex(append(make([]byte, 40), 8, 5))
+		require.False(t, ok)
+		_, ok = sfu.E2EEKeyIndex([]byte{12, 5})
+		require.False(t, ok)
+	})
+}
+
 func TestGetQualityForDimension(t *testing.T) {
 	t.Run("landscape source", func(t *testing.T) {
 		mt := NewMediaTrack(MediaTrackParams{TrackInfo: &livekit.TrackInfo{
@@ -99,3 +161,238 @@ func TestGetQualityForDimension(t *testing.T) {
 		require.Equal(t, livekit.VideoQuality_HIGH, mt.GetQualityForDimension(1000, 700))
 	})
 }
+
+type codecTrackReceiver struct {
+	sfu.TrackReceiver
+	codec  webrtc.RTPCodecParameters
+	closed bool
+}
+
+func (c *codecTrackReceiver) Codec() webrtc.RTPCodecParameters {
+	return c.codec
+}
+
+func (c *codecTrackReceiver) Close() {
+	c.closed = true
+}
+
+func TestSwitchPrimaryCodec(t *testing.T) {
+	mt := NewMediaTrack(MediaTrackParams{
+		TrackInfo: &livekit.TrackInfo{
+			Sid:  "testsid",
+			Type: livekit.TrackType_VIDEO,
+		},
+		Logger: logger.GetLogger(),
+	})
+
+	vp8 := &codecTrackReceiver{codec: webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}}}
+	av1 := &codecTrackReceiver{codec: webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: "video/AV1"}}}
+
+	// nothing to switch from
+	_, switched := mt.SwitchPrimaryCodec(av1, "0")
+	require.False(t, switched)
+
+	mt.SetupReceiver(vp8, 0, "0")
+	require.Equal(t, webrtc.MimeTypeVP8, mt.ToProto().MimeType)
+
+	// same codec is not a switch
+	_, switched = mt.SwitchPrimaryCodec(vp8, "0")
+	require.False(t, switched)
+	require.False(t, vp8.closed)
+
+	fromMime, switched := mt.SwitchPrimaryCodec(av1, "0")
+	require.True(t, switched)
+	require.Equal(t, webrtc.MimeTypeVP8, fromMime)
+	// the receiver of the previous codec is closed
+	require.True(t, vp8.closed)
+	require.False(t, av1.closed)
+
+	ti := mt.ToProto()
+	require.Equal(t, "testsid", ti.Sid)
+	require.Equal(t, "video/AV1", ti.MimeType)
+	require.Equal(t, "video/AV1", ti.Codecs[0].MimeType)
+	require.Equal(t, sfu.TrackReceiver(av1), mt.PrimaryReceiver())
+	require.Nil(t, mt.Receiver(webrtc.MimeTypeVP8))
+	require.Len(t, mt.Receivers(), 1)
+}
+
+func TestReplaceReceiver(t *testing.T) {
+	mt := NewMediaTrack(MediaTrackParams{
+		TrackInfo: &livekit.TrackInfo{
+			Sid:  "testsid",
+			Type: livekit.TrackType_VIDEO,
+		},
+		SdpCid: "cid",
+		Logger: logger.GetLogger(),
+	})
+
+	vp8 := &codecTrackReceiver{codec: webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}}}
+	vp8New := &codecTrackReceiver{codec: webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}}}
+	av1 := &codecTrackReceiver{codec: webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: "video/AV1"}}}
+
+	// nothing to replace
+	require.False(t, mt.ReplaceReceiver(vp8New, "1"))
+
+	mt.SetupReceiver(vp8, 0, "0")
+
+	require.False(t, mt.HasSdpCid("newcid"))
+	mt.SetPendingReplacement("newcid")
+	require.True(t, mt.HasSdpCid("newcid"))
+	require.True(t, mt.HasSdpCid("cid"))
+
+	// codec not published
+	require.False(t, mt.ReplaceReceiver(av1, "1"))
+
+	require.True(t, mt.ReplaceReceiver(vp8New, "1"))
+	ti := mt.ToProto()
+	require.Equal(t, "testsid", ti.Sid)
+	require.Equal(t, "1", ti.Mid)
+	require.Equal(t, webrtc.MimeTypeVP8, ti.MimeType)
+	require.Equal(t, sfu.TrackReceiver(vp8New), mt.PrimaryReceiver())
+	require.Len(t, mt.Receivers(), 1)
+}
+
+func TestCodecFallback(t *testing.T) {
+	vp8 := webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}}
+	av1 := webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: "video/AV1"}}
+
+	newSubscriber := func(codecs ...string) *typesfakes.FakeLocalParticipant {
+		sub := &typesfakes.FakeLocalParticipant{}
+		sub.IDReturns("sub")
+		var subscriberCodecs []*livekit.Codec
+		for _, c := range codecs {
+			subscriberCodecs = append(subscriberCodecs, &livekit.Codec{Mime: c})
+		}
+		sub.GetSubscriberCodecsReturns(subscriberCodecs)
+		return sub
+	}
+
+	type request struct {
+		mime  string
+		layer int32
+	}
+	newTrack := func(policy config.CodecFallbackPolicy, transcoder CodecTranscoder) (*MediaTrack, *[]request) {
+		mt := NewMediaTrack(MediaTrackParams{
+			TrackInfo: &livekit.TrackInfo{
+				Sid:  "testsid",
+				Type: livekit.TrackType_VIDEO,
+			},
+			VideoConfig:     config.VideoConfig{CodecFallback: policy},
+			CodecTranscoder: transcoder,
+			Logger:          logger.GetLogger(),
+		})
+		var requests []request
+		mt.MediaTrackReceiver.OnSubscriberMaxQualityChange(func(_ livekit.ParticipantID, codec webrtc.RTPCodecCapability, layer int32) {
+			requests = append(requests, request{mime: codec.MimeType, layer: layer})
+		})
+		return mt, &requests
+	}
+
+	t.Run("compatible", func(t *testing.T) {
+		mt, requests := newTrack(config.CodecFallbackPolicyRequestBackup, nil)
+		require.NoError(t, mt.checkSubscriberCodecs(newSubscriber(webrtc.MimeTypeVP8), []webrtc.RTPCodecParameters{av1, vp8}))
+		require.NoError(t, mt.checkSubscriberCodecs(newSubscriber(), []webrtc.RTPCodecParameters{av1}))
+		require.Empty(t, *requests)
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		mt, requests := newTrack(config.CodecFallbackPolicyExclude, nil)
+		err := mt.checkSubscriberCodecs(newSubscriber(webrtc.MimeTypeVP8), []webrtc.RTPCodecParameters{av1})
+		require.ErrorIs(t, err, ErrNoCompatibleCodec)
+		require.Empty(t, *requests)
+	})
+
+	t.Run("request backup", func(t *testing.T) {
+		mt, requests := newTrack(config.CodecFallbackPolicyRequestBackup, nil)
+		sub := newSubscriber(webrtc.MimeTypeOpus, webrtc.MimeTypeVP8)
+
+		// requested once across retries
+		for i := 0; i < 2; i++ {
+			err := mt.checkSubscriberCodecs(sub, []webrtc.RTPCodecParameters{av1})
+			require.ErrorIs(t, err, ErrNoCompatibleCodec)
+		}
+		require.Len(t, *requests, 1)
+		require.Equal(t, webrtc.MimeTypeVP8, (*requests)[0].mime)
+		require.NotEqual(t, buffer.InvalidLayerSpatial, (*requests)[0].layer)
+
+		// request is withdrawn once backup codec is available
+		require.NoError(t, mt.checkSubscriberCodecs(sub, []webrtc.RTPCodecParameters{av1, vp8}))
+		require.Len(t, *requests, 2)
+		require.Equal(t, request{mime: webrtc.MimeTypeVP8, layer: buffer.InvalidLayerSpatial}, (*requests)[1])
+	})
+
+	t.Run("transcode", func(t *testing.T) {
+		transcoder := &testCodecTranscoder{}
+		mt, requests := newTrack(config.CodecFallbackPolicyTranscode, transcoder)
+		err := mt.checkSubscriberCodecs(newSubscriber(webrtc.MimeTypeVP8), []webrtc.RTPCodecParameters{av1})
+		require.ErrorIs(t, err, ErrNoCompatibleCodec)
+		require.Equal(t, []string{webrtc.MimeTypeVP8}, transcoder.requested)
+		require.Empty(t, *requests)
+
+		// falls back to requesting backup codec without a transcoder
+		mt, requests = newTrack(config.CodecFallbackPolicyTranscode, nil)
+		err = mt.checkSubscriberCodecs(newSubscriber(webrtc.MimeTypeVP8), []webrtc.RTPCodecParameters{av1})
+		require.ErrorIs(t, err, ErrNoCompatibleCodec)
+		require.Len(t, *requests, 1)
+	})
+}
+
+type testCodecTranscoder struct {
+	requested []string
+}
+
+func (c *testCodecTranscoder) RequestTranscode(_ types.MediaTrack, _ webrtc.RTPCodecParameters, to webrtc.RTPCodecCapability) error {
+	c.requested = append(c.requested, to.MimeType)
+	return nil
+}
+
+func TestGeneratedLayers(t *testing.T) {
+	layers := generatedLayers(&livekit.TrackInfo{
+		Width:  1278,
+		Height: 718,
+		Layers: []*livekit.VideoLayer{
+			{Quality: livekit.VideoQuality_HIGH, Width: 1278, Height: 718, Bitrate: 3_000_000, Ssrc: 1234},
+		},
+	})
+	require.Equal(t, []*livekit.VideoLayer{
+		{Quality: livekit.VideoQuality_LOW, Width: 318, Height: 178, Bitrate: 300_000},
+		{Quality: livekit.VideoQuality_MEDIUM, Width: 638, Height: 358, Bitrate: 1_000_000},
+		{Quality: livekit.VideoQuality_HIGH, Width: 1278, Height: 718, Bitrate: 3_000_000, Ssrc: 1234},
+	}, layers)
+
+	t.Run("publisher sends the top layer while any layer is subscribed", func(t *testing.T) {
+		subscribed, maxSubscribed := publishedLayerQualities(
+			[]*livekit.SubscribedCodec{
+				{
+					Codec: webrtc.MimeTypeVP8,
+					Qualities: []*livekit.SubscribedQuality{
+						{Quality: livekit.VideoQuality_LOW, Enabled: true},
+						{Quality: livekit.VideoQuality_MEDIUM},
+						{Quality: livekit.VideoQuality_HIGH},
+					},
+				},
+				{
+					Codec: webrtc.MimeTypeH264,
+					Qualities: []*livekit.SubscribedQuality{
+						{Quality: livekit.VideoQuality_LOW},
+						{Quality: livekit.VideoQuality_HIGH},
+					},
+				},
+			},
+			[]types.SubscribedCodecQuality{
+				{CodecMime: webrtc.MimeTypeVP8, Quality: livekit.VideoQuality_LOW},
+				{CodecMime: webrtc.MimeTypeH264, Quality: livekit.VideoQuality_OFF},
+			},
+		)
+		for _, sq := range subscribed[0].Qualities {
+			require.True(t, sq.Enabled)
+		}
+		for _, sq := range subscribed[1].Qualities {
+			require.False(t, sq.Enabled)
+		}
+		require.Equal(t, []types.SubscribedCodecQuality{
+			{CodecMime: webrtc.MimeTypeVP8, Quality: livekit.VideoQuality_HIGH},
+			{CodecMime: webrtc.MimeTypeH264, Quality: livekit.VideoQuality_OFF},
+		}, maxSubscribed)
+	})
+}
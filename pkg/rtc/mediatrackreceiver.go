@@ -66,6 +66,16 @@ func (r *simulcastReceiver) Priority() int {
 	return r.priority
 }
 
+// CodecTranscoder converts a published track into a codec that a subscriber can decode
+type CodecTranscoder interface {
+	RequestTranscode(track types.MediaTrack, from webrtc.RTPCodecParameters, to webrtc.RTPCodecCapability) error
+}
+
+// SimulcastGenerator generates lower simulcast layers of a track published as a single layer
+type SimulcastGenerator interface {
+	RequestLayers(track types.MediaTrack, codec webrtc.RTPCodecParameters, layers []*livekit.VideoLayer) error
+}
+
 type MediaTrackReceiverParams struct {
 	TrackInfo           *livekit.TrackInfo
 	MediaTrack          types.MediaTrack
@@ -76,14 +86,22 @@ type MediaTrackReceiverParams struct {
 	ReceiverConfig      ReceiverConfig
 	SubscriberConfig    DirectionConfig
 	AudioConfig         config.AudioConfig
+	CodecFallback       config.CodecFallbackPolicy
+	CodecTranscoder     CodecTranscoder
 	Telemetry           telemetry.TelemetryService
 	Logger              logger.Logger
+	// payloads are not inspected, tracks declaring custom encryption always have opaque payloads
+	OpaquePayload bool
 }
 
 type MediaTrackReceiver struct {
 	params      MediaTrackReceiverParams
 	muted       atomic.Bool
 	simulcasted atomic.Bool
+	// index of the key an end-to-end encrypted track is encrypted with, -1 till seen
+	keyEpoch atomic.Int32
+	// highest quality forwarded while the node sheds load, livekit.VideoQuality
+	loadSheddingQuality atomic.Int32
 
 	lock            sync.RWMutex
 	receivers       []*simulcastReceiver
@@ -93,6 +111,9 @@ type MediaTrackReceiver struct {
 	potentialCodecs []webrtc.RTPCodecParameters
 	state           mediaTrackReceiverState
 
+	// codecs requested from publisher on behalf of subscribers which cannot decode published codecs
+	fallbackRequests map[livekit.ParticipantID]webrtc.RTPCodecCapability
+
 	onSetupReceiver     func(mime string)
 	onMediaLossFeedback func(dt *sfu.DownTrack, report *rtcp.ReceiverReport)
 	onVideoLayerUpdate  func(layers []*livekit.VideoLayer)
@@ -102,11 +123,15 @@ type MediaTrackReceiver struct {
 }
 
 func NewMediaTrackReceiver(params MediaTrackReceiverParams) *MediaTrackReceiver {
+	if params.TrackInfo.GetEncryption() == livekit.Encryption_CUSTOM {
+		params.OpaquePayload = true
+	}
 	t := &MediaTrackReceiver{
-		params:          params,
-		trackInfo:       proto.Clone(params.TrackInfo).(*livekit.TrackInfo),
-		layerDimensions: make(map[livekit.VideoQuality]*livekit.VideoLayer),
-		state:           mediaTrackReceiverStateOpen,
+		params:           params,
+		trackInfo:        proto.Clone(params.TrackInfo).(*livekit.TrackInfo),
+		layerDimensions:  make(map[livekit.VideoQuality]*livekit.VideoLayer),
+		state:            mediaTrackReceiverStateOpen,
+		fallbackRequests: make(map[livekit.ParticipantID]webrtc.RTPCodecCapability),
 	}
 
 	t.MediaTrackSubscriptions = NewMediaTrackSubscriptions(MediaTrackSubscriptionsParams{
@@ -114,10 +139,13 @@ func NewMediaTrackReceiver(params MediaTrackReceiverParams) *MediaTrackReceiver
 		IsRelayed:        params.IsRelayed,
 		ReceiverConfig:   params.ReceiverConfig,
 		SubscriberConfig: params.SubscriberConfig,
+		OpaquePayload:    params.OpaquePayload,
 		Telemetry:        params.Telemetry,
 		Logger:           params.Logger,
 	})
 	t.MediaTrackSubscriptions.OnDownTrackCreated(t.onDownTrackCreated)
+	t.keyEpoch.Store(-1)
+	t.loadSheddingQuality.Store(int32(livekit.VideoQuality_HIGH))
 
 	if t.trackInfo.Muted {
 		t.SetMuted(true)
@@ -205,6 +233,132 @@ func (t *MediaTrackReceiver) SetupReceiver(receiver sfu.TrackReceiver, priority
 	}
 }
 
+// SwitchPrimaryCodec replaces the primary receiver with a receiver of a different codec published on the same mid,
+// subscribers of the previous codec are moved to the new one keeping track SID unchanged. The previous receiver is
+// closed once its subscribers moved
+func (t *MediaTrackReceiver) SwitchPrimaryCodec(receiver sfu.TrackReceiver, mid string) (string, bool) {
+	t.lock.Lock()
+	if t.state != mediaTrackReceiverStateOpen {
+		t.params.Logger.Warnw("cannot switch codec on a track not open", nil)
+		t.lock.Unlock()
+		return "", false
+	}
+
+	newCodec := receiver.Codec()
+	var oldMime string
+	var oldReceiver sfu.TrackReceiver
+	for idx, r := range t.receivers {
+		if r.Priority() != 0 {
+			continue
+		}
+		if _, ok := r.TrackReceiver.(*DummyReceiver); ok {
+			break
+		}
+
+		oldMime = r.Codec().MimeType
+		oldReceiver = r.TrackReceiver
+		t.receivers[idx] = &simulcastReceiver{TrackReceiver: receiver, priority: 0}
+		break
+	}
+	if oldMime == "" || strings.EqualFold(oldMime, newCodec.MimeType) {
+		t.lock.Unlock()
+		return "", false
+	}
+
+	t.trackInfo.MimeType = newCodec.MimeType
+	t.trackInfo.Mid = mid
+	if len(t.trackInfo.Codecs) != 0 {
+		t.trackInfo.Codecs[0].MimeType = newCodec.MimeType
+		t.trackInfo.Codecs[0].Mid = mid
+	}
+
+	potentialCodecs := make([]webrtc.RTPCodecParameters, 0, len(t.potentialCodecs))
+	for _, pc := range t.potentialCodecs {
+		if !strings.EqualFold(pc.MimeType, oldMime) {
+			potentialCodecs = append(potentialCodecs, pc)
+		}
+	}
+	t.potentialCodecs = append([]webrtc.RTPCodecParameters{newCodec}, potentialCodecs...)
+
+	t.shadowReceiversLocked()
+
+	onSetupReceiver := t.onSetupReceiver
+	t.params.Logger.Infow("switched primary codec", "from", oldMime, "to", newCodec.MimeType, "receivers", t.receiversShadow)
+	t.lock.Unlock()
+
+	if onSetupReceiver != nil {
+		onSetupReceiver(newCodec.MimeType)
+	}
+
+	// subscribers re-subscribe and negotiate the new codec
+	t.removeAllSubscribersForMime(oldMime, true)
+	if closer, ok := oldReceiver.(interface{ Close() }); ok {
+		closer.Close()
+	}
+	return oldMime, true
+}
+
+// ReplaceReceiver replaces the receiver of a codec with a receiver of a new media source, subscribers are moved
+// to the new receiver in place, keeping track SID and subscriptions without re-negotiation
+func (t *MediaTrackReceiver) ReplaceReceiver(receiver sfu.TrackReceiver, mid string) bool {
+	t.lock.Lock()
+	if t.state != mediaTrackReceiverStateOpen {
+		t.params.Logger.Warnw("cannot replace receiver on a track not open", nil)
+		t.lock.Unlock()
+		return false
+	}
+
+	mime := receiver.Codec().MimeType
+	priority := -1
+	for idx, r := range t.receivers {
+		if !strings.EqualFold(r.Codec().MimeType, mime) {
+			continue
+		}
+		if _, ok := r.TrackReceiver.(*DummyReceiver); ok {
+			break
+		}
+
+		priority = r.Priority()
+		t.receivers[idx] = &simulcastReceiver{TrackReceiver: receiver, priority: priority}
+		break
+	}
+	if priority < 0 {
+		t.lock.Unlock()
+		return false
+	}
+
+	if mid != "" {
+		if priority == 0 {
+			t.trackInfo.Mid = mid
+		}
+		if priority < len(t.trackInfo.Codecs) {
+			t.trackInfo.Codecs[priority].Mid = mid
+		}
+	}
+
+	t.shadowReceiversLocked()
+	receivers := t.receiversShadow
+	potentialCodecs := t.getPotentialCodecsLocked()
+	t.params.Logger.Infow("replaced receiver", "mime", mime, "mid", mid, "receivers", t.receiversShadow)
+	t.lock.Unlock()
+
+	for _, subTrack := range t.MediaTrackSubscriptions.getAllSubscribedTracks() {
+		dt := subTrack.DownTrack()
+		if dt == nil {
+			continue
+		}
+		if current := dt.Receiver(); current != nil && !strings.EqualFold(current.Codec().MimeType, mime) {
+			// forwarding a different codec
+			continue
+		}
+
+		wr := t.newWrappedReceiver(subTrack.Subscriber(), receivers, potentialCodecs)
+		wr.DetermineReceiver(dt.Codec())
+		dt.SetReceiver(wr)
+	}
+	return true
+}
+
 func (t *MediaTrackReceiver) SetPotentialCodecs(codecs []webrtc.RTPCodecParameters, headers []webrtc.RTPHeaderExtensionParameter) {
 	t.lock.Lock()
 	t.potentialCodecs = codecs
@@ -417,6 +571,51 @@ func (t *MediaTrackReceiver) SetMuted(muted bool) {
 	t.MediaTrackSubscriptions.SetMuted(muted)
 }
 
+// SetLoadSheddingQuality limits the quality of video forwarded to subscribers while the node sheds load, HIGH when
+// it does not and OFF to pause the track. Subscriptions keep their settings for when the limit is lifted
+func (t *MediaTrackReceiver) SetLoadSheddingQuality(quality livekit.VideoQuality) {
+	if t.Kind() != livekit.TrackType_VIDEO || livekit.VideoQuality(t.loadSheddingQuality.Swap(int32(quality))) == quality {
+		return
+	}
+
+	t.params.Logger.Infow("updated load shedding quality", "quality", quality)
+	for _, st := range t.getAllSubscribedTracks() {
+		st.UpdateVideoLayer()
+	}
+}
+
+func (t *MediaTrackReceiver) LoadSheddingQuality() livekit.VideoQuality {
+	return livekit.VideoQuality(t.loadSheddingQuality.Load())
+}
+
+// IsOpaquePayload tells whether payloads of the track are forwarded without being inspected, e. g. frames encrypted
+// by clients with insertable streams
+func (t *MediaTrackReceiver) IsOpaquePayload() bool {
+	return t.params.OpaquePayload
+}
+
+// SetKeyEpoch records the index of the key the publisher encrypts the track with, returns whether it changed
+func (t *MediaTrackReceiver) SetKeyEpoch(keyEpoch uint8) bool {
+	return t.keyEpoch.Swap(int32(keyEpoch)) != int32(keyEpoch)
+}
+
+// EncryptionStatus tells whether the media of the track is end-to-end encrypted and readable by the server
+func (t *MediaTrackReceiver) EncryptionStatus() *types.TrackEncryptionStatus {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.encryptionStatusLocked()
+}
+
+func (t *MediaTrackReceiver) encryptionStatusLocked() *types.TrackEncryptionStatus {
+	status := types.NewTrackEncryptionStatus(t.trackInfo.Encryption)
+	// only the trailer of frames encrypted with AES-GCM by clients is known
+	if status.Cipher == types.CipherAESGCM {
+		status.KeyEpoch = t.keyEpoch.Load()
+	}
+	return status
+}
+
 func (t *MediaTrackReceiver) AddOnClose(f func()) {
 	if f == nil {
 		return
@@ -436,8 +635,7 @@ func (t *MediaTrackReceiver) AddSubscriber(sub types.LocalParticipant) (types.Su
 	}
 
 	receivers := t.receiversShadow
-	potentialCodecs := make([]webrtc.RTPCodecParameters, len(t.potentialCodecs))
-	copy(potentialCodecs, t.potentialCodecs)
+	potentialCodecs := t.getPotentialCodecsLocked()
 	t.lock.RUnlock()
 
 	if len(receivers) == 0 {
@@ -445,7 +643,21 @@ func (t *MediaTrackReceiver) AddSubscriber(sub types.LocalParticipant) (types.Su
 		return nil, ErrNoReceiver
 	}
 
-	for _, receiver := range receivers {
+	if t.Kind() == livekit.TrackType_VIDEO {
+		if err := t.checkSubscriberCodecs(sub, potentialCodecs); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.MediaTrackSubscriptions.AddSubscriber(sub, t.newWrappedReceiver(sub, receivers, potentialCodecs))
+}
+
+// returns potential codecs including codecs of all receivers, should be called with lock held
+func (t *MediaTrackReceiver) getPotentialCodecsLocked() []webrtc.RTPCodecParameters {
+	potentialCodecs := make([]webrtc.RTPCodecParameters, len(t.potentialCodecs))
+	copy(potentialCodecs, t.potentialCodecs)
+
+	for _, receiver := range t.receiversShadow {
 		codec := receiver.Codec()
 		var found bool
 		for _, pc := range potentialCodecs {
@@ -458,7 +670,14 @@ func (t *MediaTrackReceiver) AddSubscriber(sub types.LocalParticipant) (types.Su
 			potentialCodecs = append(potentialCodecs, codec)
 		}
 	}
+	return potentialCodecs
+}
 
+func (t *MediaTrackReceiver) newWrappedReceiver(
+	sub types.LocalParticipant,
+	receivers []*simulcastReceiver,
+	potentialCodecs []webrtc.RTPCodecParameters,
+) *WrappedReceiver {
 	streamId := string(t.PublisherID())
 	if sub.ProtocolVersion().SupportsPackedStreamId() {
 		// when possible, pack both IDs in streamID to allow new streams to be generated
@@ -466,24 +685,92 @@ func (t *MediaTrackReceiver) AddSubscriber(sub types.LocalParticipant) (types.Su
 		streamId = PackStreamID(t.PublisherID(), t.ID())
 	}
 
-	tLogger := LoggerWithTrack(sub.GetLogger(), t.ID(), t.params.IsRelayed)
-	wr := NewWrappedReceiver(WrappedReceiverParams{
+	return NewWrappedReceiver(WrappedReceiverParams{
 		Receivers:      receivers,
 		TrackID:        t.ID(),
 		StreamId:       streamId,
 		UpstreamCodecs: potentialCodecs,
-		Logger:         tLogger,
+		Logger:         LoggerWithTrack(sub.GetLogger(), t.ID(), t.params.IsRelayed),
 		DisableRed:     t.trackInfo.GetDisableRed() || !t.params.AudioConfig.ActiveREDEncoding,
 	})
-	return t.MediaTrackSubscriptions.AddSubscriber(sub, wr)
 }
 
 // RemoveSubscriber removes participant from subscription
 // stop all forwarders to the client
 func (t *MediaTrackReceiver) RemoveSubscriber(subscriberID livekit.ParticipantID, willBeResumed bool) {
+	t.clearFallbackRequest(subscriberID)
 	_ = t.MediaTrackSubscriptions.RemoveSubscriber(subscriberID, willBeResumed)
 }
 
+// checkSubscriberCodecs applies codec fallback policy when subscriber cannot decode any of the published codecs
+func (t *MediaTrackReceiver) checkSubscriberCodecs(sub types.LocalParticipant, potentialCodecs []webrtc.RTPCodecParameters) error {
+	subscriberCodecs := sub.GetSubscriberCodecs()
+	if len(subscriberCodecs) == 0 {
+		// nothing known about subscriber, let negotiation decide
+		return nil
+	}
+
+	for _, pc := range potentialCodecs {
+		if IsCodecEnabled(subscriberCodecs, pc.RTPCodecCapability) {
+			t.clearFallbackRequest(sub.ID())
+			return nil
+		}
+	}
+
+	// pick the first video codec subscriber can decode as the fallback
+	var fallback webrtc.RTPCodecCapability
+	for _, c := range subscriberCodecs {
+		if strings.HasPrefix(strings.ToLower(c.Mime), "video/") {
+			fallback = webrtc.RTPCodecCapability{MimeType: c.Mime, SDPFmtpLine: c.FmtpLine}
+			break
+		}
+	}
+
+	policy := t.params.CodecFallback
+	if fallback.MimeType == "" {
+		policy = config.CodecFallbackPolicyExclude
+	}
+	if policy == config.CodecFallbackPolicyTranscode {
+		if t.params.CodecTranscoder == nil || len(potentialCodecs) == 0 {
+			policy = config.CodecFallbackPolicyRequestBackup
+		} else if err := t.params.CodecTranscoder.RequestTranscode(t.params.MediaTrack, potentialCodecs[0], fallback); err != nil {
+			t.params.Logger.Warnw("could not request transcode, requesting backup codec", err, "subscriberID", sub.ID(), "codec", fallback.MimeType)
+			policy = config.CodecFallbackPolicyRequestBackup
+		}
+	}
+
+	switch policy {
+	case config.CodecFallbackPolicyRequestBackup:
+		t.lock.Lock()
+		_, requested := t.fallbackRequests[sub.ID()]
+		t.fallbackRequests[sub.ID()] = fallback
+		t.lock.Unlock()
+
+		if !requested {
+			t.params.Logger.Infow("subscriber cannot decode published codecs, requesting backup codec", "subscriberID", sub.ID(), "codec", fallback.MimeType)
+			t.MediaTrackSubscriptions.notifySubscriberMaxQuality(sub.ID(), fallback, buffer.VideoQualityToSpatialLayer(livekit.VideoQuality_HIGH, t.TrackInfo(false)))
+		}
+
+	case config.CodecFallbackPolicyTranscode:
+		t.params.Logger.Infow("subscriber cannot decode published codecs, transcode requested", "subscriberID", sub.ID(), "codec", fallback.MimeType)
+
+	default:
+		t.params.Logger.Infow("subscriber cannot decode published codecs, excluding track", "subscriberID", sub.ID())
+	}
+	return ErrNoCompatibleCodec
+}
+
+func (t *MediaTrackReceiver) clearFallbackRequest(subscriberID livekit.ParticipantID) {
+	t.lock.Lock()
+	fallback, ok := t.fallbackRequests[subscriberID]
+	delete(t.fallbackRequests, subscriberID)
+	t.lock.Unlock()
+
+	if ok {
+		t.MediaTrackSubscriptions.notifySubscriberMaxQuality(subscriberID, fallback, buffer.InvalidLayerSpatial)
+	}
+}
+
 func (t *MediaTrackReceiver) removeAllSubscribersForMime(mime string, willBeResumed bool) {
 	t.params.Logger.Infow("removing all subscribers for mime", "mime", mime)
 	for _, subscriberID := range t.MediaTrackSubscriptions.GetAllSubscribersForMime(mime) {
@@ -532,6 +819,10 @@ func (t *MediaTrackReceiver) TrackInfo(generateLayer bool) *livekit.TrackInfo {
 	defer t.lock.RUnlock()
 
 	ti := proto.Clone(t.trackInfo).(*livekit.TrackInfo)
+	if !t.params.IsRelayed {
+		// relayed tracks carry the status set by the node the publisher is connected to
+		types.SetTrackEncryptionStatus(ti, t.encryptionStatusLocked())
+	}
 	if !generateLayer {
 		return ti
 	}
@@ -38,6 +38,7 @@ type MediaTrackSubscriptionsParams struct {
 
 	ReceiverConfig   ReceiverConfig
 	SubscriberConfig DirectionConfig
+	OpaquePayload    bool
 
 	Telemetry telemetry.TelemetryService
 
@@ -59,6 +60,12 @@ func (t *MediaTrackSubscriptions) OnSubscriberMaxQualityChange(f func(subscriber
 	t.onSubscriberMaxQualityChange = f
 }
 
+func (t *MediaTrackSubscriptions) notifySubscriberMaxQuality(subscriberID livekit.ParticipantID, codec webrtc.RTPCodecCapability, layer int32) {
+	if t.onSubscriberMaxQualityChange != nil {
+		t.onSubscriberMaxQualityChange(subscriberID, codec, layer)
+	}
+}
+
 func (t *MediaTrackSubscriptions) SetMuted(muted bool) {
 	// update mute of all subscribed tracks
 	for _, st := range t.getAllSubscribedTracks() {
@@ -98,14 +105,17 @@ func (t *MediaTrackSubscriptions) AddSubscriber(sub types.LocalParticipant, wr *
 	for _, c := range codecs {
 		c.RTCPFeedback = rtcpFeedback
 	}
-	downTrack, err := sfu.NewDownTrack(
-		codecs,
-		wr,
-		sub.GetBufferFactory(),
-		subscriberID,
-		t.params.ReceiverConfig.PacketBufferSize,
-		LoggerWithTrack(sub.GetLogger(), trackID, t.params.IsRelayed),
-	)
+	downTrack, err := sfu.NewDownTrack(sfu.DownTrackParams{
+		Codecs:               codecs,
+		Receiver:             wr,
+		BufferFactory:        sub.GetBufferFactory(),
+		SubID:                subscriberID,
+		MaxTrack:             t.params.ReceiverConfig.PacketBufferSize,
+		Logger:               LoggerWithTrack(sub.GetLogger(), trackID, t.params.IsRelayed),
+		AudioNACKHistorySize: t.params.ReceiverConfig.AudioNACKHistorySize,
+		AudioNACKMaxLatency:  t.params.ReceiverConfig.AudioNACKMaxLatency,
+		OpaquePayload:        t.params.OpaquePayload,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -122,12 +132,15 @@ func (t *MediaTrackSubscriptions) AddSubscriber(sub types.LocalParticipant, wr *
 		MediaTrack:        t.params.MediaTrack,
 		DownTrack:         downTrack,
 		AdaptiveStream:    sub.GetAdaptiveStream(),
+		DeviceHints:       sub.GetDeviceHints(),
 	})
 
 	// Bind callback can happen from replaceTrack, so set it up early
 	var reusingTransceiver atomic.Bool
 	var dtState sfu.DownTrackState
 	downTrack.OnBinding(func() {
+		// receiver could have been replaced before binding
+		wr := downTrack.Receiver().(*WrappedReceiver)
 		wr.DetermineReceiver(downTrack.Codec())
 		if reusingTransceiver.Load() {
 			downTrack.SeedState(dtState)
@@ -152,9 +165,7 @@ func (t *MediaTrackSubscriptions) AddSubscriber(sub types.LocalParticipant, wr *
 	})
 
 	downTrack.OnMaxLayerChanged(func(dt *sfu.DownTrack, layer int32) {
-		if t.onSubscriberMaxQualityChange != nil {
-			t.onSubscriberMaxQualityChange(subscriberID, dt.Codec(), layer)
-		}
+		t.notifySubscriberMaxQuality(subscriberID, dt.Codec(), layer)
 	})
 
 	downTrack.OnRttUpdate(func(_ *sfu.DownTrack, rtt uint32) {
@@ -0,0 +1,127 @@
+package rtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const mixedAudioTrackName = "audio mix"
+
+type MixedAudioTrackParams struct {
+	Config           config.MixedAudioTrackConfig
+	ReceiverConfig   ReceiverConfig
+	SubscriberConfig DirectionConfig
+	AudioConfig      config.AudioConfig
+	Telemetry        telemetry.TelemetryService
+	Logger           logger.Logger
+}
+
+// MixedAudioTrack is an audio track published by the server in the name of a participant of its own, mixing the
+// audio of the participants of a room
+type MixedAudioTrack struct {
+	*MediaTrackReceiver
+
+	params        MixedAudioTrackParams
+	participantID livekit.ParticipantID
+	joinedAt      int64
+	receiver      *sfu.MixedAudioReceiver
+}
+
+// NewMixedAudioTrack creates the track, failing when there is no opus codec to mix with
+func NewMixedAudioTrack(params MixedAudioTrackParams) (*MixedAudioTrack, error) {
+	trackInfo := &livekit.TrackInfo{
+		Sid:        utils.NewGuid(utils.TrackPrefix),
+		Type:       livekit.TrackType_AUDIO,
+		Name:       mixedAudioTrackName,
+		Source:     livekit.TrackSource_MICROPHONE,
+		MimeType:   webrtc.MimeTypeOpus,
+		DisableRed: true,
+		Codecs:     []*livekit.SimulcastCodecInfo{{MimeType: webrtc.MimeTypeOpus}},
+	}
+
+	participantID := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
+	t := &MixedAudioTrack{
+		params:        params,
+		participantID: participantID,
+		joinedAt:      time.Now().Unix(),
+	}
+	t.params.Logger = LoggerWithTrack(
+		LoggerWithParticipant(params.Logger, livekit.ParticipantIdentity(params.Config.Identity), participantID, false),
+		livekit.TrackID(trackInfo.Sid),
+		false,
+	)
+
+	receiver, err := sfu.NewMixedAudioReceiver(sfu.MixedAudioReceiverParams{
+		TrackID:  livekit.TrackID(trackInfo.Sid),
+		StreamID: string(participantID),
+		Codec:    webrtc.RTPCodecParameters{RTPCodecCapability: opusCodecCapability, PayloadType: 111},
+		Mixer: audio.MixerParams{
+			Bitrate:      params.Config.Bitrate,
+			JitterFrames: int(params.Config.JitterBuffer / audio.MixerFrameDuration),
+		},
+		ExcludeOwnAudio: params.Config.ExcludeOwnAudio,
+		Logger:          t.params.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.receiver = receiver
+
+	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
+		TrackInfo:           trackInfo,
+		MediaTrack:          t,
+		ParticipantID:       participantID,
+		ParticipantIdentity: livekit.ParticipantIdentity(params.Config.Identity),
+		ParticipantVersion:  1,
+		ReceiverConfig:      params.ReceiverConfig,
+		SubscriberConfig:    params.SubscriberConfig,
+		AudioConfig:         params.AudioConfig,
+		Telemetry:           params.Telemetry,
+		Logger:              t.params.Logger,
+	})
+	t.MediaTrackReceiver.SetupReceiver(t.receiver, 0, "")
+	return t, nil
+}
+
+func (t *MixedAudioTrack) ToProto() *livekit.TrackInfo {
+	info := t.MediaTrackReceiver.TrackInfo(false)
+	info.Muted = t.IsMuted()
+	return info
+}
+
+// ParticipantInfo describes the participant the track is published by
+func (t *MixedAudioTrack) ParticipantInfo() *livekit.ParticipantInfo {
+	return serverParticipantInfo(t.participantID, t.params.Config.Identity, mixedAudioTrackName, t.joinedAt, t.ToProto())
+}
+
+// SetInputs mixes the audio received by the receivers, keyed by the participant whose audio they receive. Audio of
+// participants left out is no longer mixed in
+func (t *MixedAudioTrack) SetInputs(inputs map[livekit.ParticipantID]sfu.TrackReceiver) {
+	for _, participantID := range t.receiver.Inputs() {
+		if inputs[participantID] == nil {
+			t.receiver.RemoveInput(participantID)
+		}
+	}
+	for participantID, receiver := range inputs {
+		if err := t.receiver.SetInput(participantID, receiver); err != nil {
+			t.params.Logger.Debugw("could not mix in audio", "error", err, "participantID", participantID)
+		}
+	}
+}
+
+func (t *MixedAudioTrack) Close(willBeResumed bool) {
+	t.MediaTrackReceiver.SetClosing()
+	t.MediaTrackReceiver.ClearAllReceivers(willBeResumed)
+	t.receiver.Close()
+	t.MediaTrackReceiver.Close()
+}
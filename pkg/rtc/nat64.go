@@ -0,0 +1,83 @@
+package rtc
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseNAT64Prefix parses the IPv6 prefix of a NAT64 gateway, its length has to be one RFC 6052 defines
+func ParseNAT64Prefix(prefix string) (*net.IPNet, error) {
+	ip, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("NAT64 prefix %s is not an IPv6 prefix", prefix)
+	}
+	switch ones, _ := ipnet.Mask.Size(); ones {
+	case 32, 40, 48, 56, 64, 96:
+	default:
+		return nil, fmt.Errorf("invalid NAT64 prefix length /%d, one of /32, /40, /48, /56, /64 or /96 is required", ones)
+	}
+	return ipnet, nil
+}
+
+// synthesizeNAT64 embeds an IPv4 address in a NAT64 prefix, as laid out in RFC 6052 section 2.2. Bits 64 to 71 are
+// left zero
+func synthesizeNAT64(prefix *net.IPNet, ip net.IP) net.IP {
+	v4 := ip.To4()
+	if v4 == nil {
+		return nil
+	}
+
+	synthesized := make(net.IP, net.IPv6len)
+	copy(synthesized, prefix.IP.To16())
+	ones, _ := prefix.Mask.Size()
+	switch ones {
+	case 32:
+		copy(synthesized[4:8], v4)
+	case 40:
+		copy(synthesized[5:8], v4[:3])
+		synthesized[9] = v4[3]
+	case 48:
+		copy(synthesized[6:8], v4[:2])
+		copy(synthesized[9:11], v4[2:])
+	case 56:
+		synthesized[7] = v4[0]
+		copy(synthesized[9:12], v4[1:])
+	case 64:
+		copy(synthesized[9:13], v4)
+	case 96:
+		copy(synthesized[12:16], v4)
+	default:
+		return nil
+	}
+	return synthesized
+}
+
+// synthesizeNAT64Candidate returns the candidate attribute of an IPv4 candidate with its address synthesized in a
+// NAT64 prefix, false when there is no prefix or the candidate is not IPv4
+func synthesizeNAT64Candidate(prefix *net.IPNet, candidate string) (string, bool) {
+	if prefix == nil {
+		return "", false
+	}
+
+	value := strings.TrimPrefix(candidate, "candidate:")
+	// foundation component transport priority address port typ type ...
+	fields := strings.Fields(value)
+	if len(fields) < 8 {
+		return "", false
+	}
+	ip := net.ParseIP(fields[4])
+	if ip == nil {
+		// mDNS host names are not resolved
+		return "", false
+	}
+	synthesized := synthesizeNAT64(prefix, ip)
+	if synthesized == nil {
+		return "", false
+	}
+	fields[4] = synthesized.String()
+	return candidate[:len(candidate)-len(value)] + strings.Join(fields, " "), true
+}
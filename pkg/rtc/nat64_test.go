@@ -0,0 +1,66 @@
+package rtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNAT64Prefix(t *testing.T) {
+	prefix, err := ParseNAT64Prefix("64:ff9b::/96")
+	require.NoError(t, err)
+	require.Equal(t, "64:ff9b::/96", prefix.String())
+
+	for _, invalid := range []string{"", "64:ff9b::", "10.0.0.0/8", "64:ff9b::/80", "64:ff9b::/128"} {
+		_, err := ParseNAT64Prefix(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestSynthesizeNAT64(t *testing.T) {
+	// RFC 6052 section 2.4
+	for prefix, expected := range map[string]string{
+		"64:ff9b::/96":          "64:ff9b::c000:221",
+		"2001:db8::/32":         "2001:db8:c000:221::",
+		"2001:db8:100::/40":     "2001:db8:1c0:2:21::",
+		"2001:db8:122::/48":     "2001:db8:122:c000:2:2100::",
+		"2001:db8:122:300::/56": "2001:db8:122:3c0:0:221::",
+		"2001:db8:122:344::/64": "2001:db8:122:344:c0:2:2100:0",
+		"2001:db8:122:344::/96": "2001:db8:122:344::c000:221",
+	} {
+		ipnet, err := ParseNAT64Prefix(prefix)
+		require.NoError(t, err)
+		require.Equal(t, net.ParseIP(expected), synthesizeNAT64(ipnet, net.ParseIP("192.0.2.33")), prefix)
+	}
+
+	ipnet, err := ParseNAT64Prefix("64:ff9b::/96")
+	require.NoError(t, err)
+	require.Nil(t, synthesizeNAT64(ipnet, net.ParseIP("2001:db8::1")))
+}
+
+func TestSynthesizeNAT64Candidate(t *testing.T) {
+	prefix, err := ParseNAT64Prefix("64:ff9b::/96")
+	require.NoError(t, err)
+
+	synthesized, ok := synthesizeNAT64Candidate(prefix, "candidate:1 1 udp 1694498815 192.0.2.33 50000 typ srflx raddr 10.0.0.2 rport 50000")
+	require.True(t, ok)
+	require.Equal(t, "candidate:1 1 udp 1694498815 64:ff9b::c000:221 50000 typ srflx raddr 10.0.0.2 rport 50000", synthesized)
+
+	// SDP attribute form
+	synthesized, ok = synthesizeNAT64Candidate(prefix, "1 1 tcp 2124414975 192.0.2.33 9 typ host tcptype active")
+	require.True(t, ok)
+	require.Equal(t, "1 1 tcp 2124414975 64:ff9b::c000:221 9 typ host tcptype active", synthesized)
+
+	for _, candidate := range []string{
+		"candidate:1 1 udp 2130706431 2001:db8::2 50000 typ host",
+		"candidate:1 1 udp 2130706431 3c5f9d2e-4a1b-4c8e-9b0f-1d2e3f4a5b6c.local 50000 typ host",
+		"candidate:1 1 udp",
+	} {
+		_, ok := synthesizeNAT64Candidate(prefix, candidate)
+		require.False(t, ok, candidate)
+	}
+
+	_, ok = synthesizeNAT64Candidate(nil, "candidate:1 1 udp 1694498815 192.0.2.33 50000 typ srflx")
+	require.False(t, ok)
+}
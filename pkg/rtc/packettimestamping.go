@@ -0,0 +1,97 @@
+package rtc
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/pion/transport/v2"
+	"github.com/pion/transport/v2/stdnet"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	PacketTimestampingSoftware = "software"
+	PacketTimestampingHardware = "hardware"
+
+	// room for the timestamping control message
+	packetTimestampingOOBSize = 128
+)
+
+var (
+	ErrInvalidPacketTimestamping     = errors.New("packet_timestamping must be software or hardware")
+	errPacketTimestampingUnsupported = errors.New("packet timestamping is only supported on Linux")
+)
+
+// timestampingNet sets up the UDP sockets ICE gathers candidates on and the UDP mux listens on to receive kernel
+// timestamps, and records them for the buffers of the packets. Other sockets are left as they are
+type timestampingNet struct {
+	transport.Net
+	mode         string
+	arrivalTimes *buffer.ArrivalTimes
+}
+
+func newTimestampingNet(mode string) (*timestampingNet, error) {
+	if mode != PacketTimestampingSoftware && mode != PacketTimestampingHardware {
+		return nil, ErrInvalidPacketTimestamping
+	}
+	prometheus.SetPacketTimestamping(mode, false)
+	if runtime.GOOS != "linux" {
+		return nil, errPacketTimestampingUnsupported
+	}
+	n, err := stdnet.NewNet()
+	if err != nil {
+		return nil, err
+	}
+	return &timestampingNet{
+		Net:          n,
+		mode:         mode,
+		arrivalTimes: buffer.NewArrivalTimes(),
+	}, nil
+}
+
+func (n *timestampingNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok {
+		return conn, nil
+	}
+	if err = enablePacketTimestamping(udpConn, n.mode == PacketTimestampingHardware); err != nil {
+		logger.Warnw("could not enable packet timestamping", err, "address", udpConn.LocalAddr())
+		return conn, nil
+	}
+	prometheus.SetPacketTimestamping(n.mode, true)
+	return &timestampingUDPConn{UDPConn: udpConn, net: n, oob: make([]byte, packetTimestampingOOBSize)}, nil
+}
+
+// timestampingUDPConn records the kernel timestamps of the packets read from it
+type timestampingUDPConn struct {
+	*net.UDPConn
+	net *timestampingNet
+
+	// buffer for control messages, packets are read by a single goroutine so the lock is not contended
+	lock sync.Mutex
+	oob  []byte
+}
+
+func (c *timestampingUDPConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	c.lock.Lock()
+	n, addr, at, hardware, err := readTimestamped(c.UDPConn, p, c.oob, c.net.mode == PacketTimestampingHardware)
+	c.lock.Unlock()
+	if err != nil {
+		return n, nil, err
+	}
+	if !at.IsZero() {
+		c.net.arrivalTimes.Record(p[:n], at)
+		prometheus.RecordPacketTimestamp(hardware)
+	}
+	return n, addr, nil
+}
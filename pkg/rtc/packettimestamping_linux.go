@@ -0,0 +1,59 @@
+//go:build linux
+
+package rtc
+
+import (
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func enablePacketTimestamping(conn *net.UDPConn, hardware bool) error {
+	flags := unix.SOF_TIMESTAMPING_RX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE
+	if hardware {
+		flags |= unix.SOF_TIMESTAMPING_RX_HARDWARE | unix.SOF_TIMESTAMPING_RAW_HARDWARE
+	}
+
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	if err = rc.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// readTimestamped reads a packet along with the time the kernel received it, zero when it carries no timestamp.
+// fromNIC tells whether the NIC took the timestamp, which is preferred when hardware timestamps are used
+func readTimestamped(conn *net.UDPConn, p []byte, oob []byte, hardware bool) (n int, addr net.Addr, at time.Time, fromNIC bool, err error) {
+	n, oobn, _, udpAddr, err := conn.ReadMsgUDP(p, oob)
+	if err != nil {
+		return n, nil, time.Time{}, false, err
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return n, udpAddr, time.Time{}, false, nil
+	}
+	for _, msg := range msgs {
+		// struct scm_timestamping: software, deprecated and raw hardware timestamps
+		if msg.Header.Level != unix.SOL_SOCKET || msg.Header.Type != unix.SO_TIMESTAMPING ||
+			len(msg.Data) < 3*int(unsafe.Sizeof(unix.Timespec{})) {
+			continue
+		}
+		ts := (*[3]unix.Timespec)(unsafe.Pointer(&msg.Data[0]))
+		if hardware && (ts[2].Sec != 0 || ts[2].Nsec != 0) {
+			return n, udpAddr, time.Unix(ts[2].Unix()), true, nil
+		}
+		if ts[0].Sec != 0 || ts[0].Nsec != 0 {
+			return n, udpAddr, time.Unix(ts[0].Unix()), false, nil
+		}
+	}
+	return n, udpAddr, time.Time{}, false, nil
+}
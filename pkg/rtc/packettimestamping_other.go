@@ -0,0 +1,17 @@
+//go:build !linux
+
+package rtc
+
+import (
+	"net"
+	"time"
+)
+
+func enablePacketTimestamping(_ *net.UDPConn, _ bool) error {
+	return errPacketTimestampingUnsupported
+}
+
+func readTimestamped(conn *net.UDPConn, p []byte, _ []byte, _ bool) (n int, addr net.Addr, at time.Time, fromNIC bool, err error) {
+	n, addr, err = conn.ReadFrom(p)
+	return
+}
@@ -0,0 +1,53 @@
+package rtc
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestampingNet(t *testing.T) {
+	_, err := newTimestampingNet("ptp")
+	require.ErrorIs(t, err, ErrInvalidPacketTimestamping)
+
+	if runtime.GOOS != "linux" {
+		t.Skip("packet timestamping is only supported on Linux")
+	}
+
+	tsNet, err := newTimestampingNet(PacketTimestampingSoftware)
+	require.NoError(t, err)
+	conn, err := tsNet.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	if _, ok := conn.(*timestampingUDPConn); !ok {
+		t.Skip("socket could not be set up for packet timestamping")
+	}
+
+	sender, err := net.DialUDP("udp4", nil, conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer sender.Close()
+	pkt, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 100, SSRC: 1234},
+		Payload: []byte{1, 2, 3},
+	}).Marshal()
+	require.NoError(t, err)
+	_, err = sender.Write(pkt)
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	buf := make([]byte, 1500)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Equal(t, pkt, buf[:n])
+
+	// received by the kernel before it was read
+	now := time.Now()
+	at := tsNet.arrivalTimes.Get(buf[:n], now)
+	require.True(t, at.Before(now))
+	require.Less(t, now.Sub(at), time.Second)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
 
@@ -25,8 +26,9 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
 	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+	"github.com/livekit/livekit-server/pkg/sfu/twcc"
 	"github.com/livekit/livekit-server/pkg/telemetry"
-	"github.com/livekit/mediatransportutil/pkg/twcc"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -39,6 +41,10 @@ const (
 
 	disconnectCleanupDuration = 15 * time.Second
 	migrationWaitDuration     = 3 * time.Second
+
+	// clients ping every 10s, missing a ping is a sign the signal connection is degrading
+	unstableSignalTimeout  = 15 * time.Second
+	stabilityCheckInterval = time.Second
 )
 
 type pendingTrackInfo struct {
@@ -66,10 +72,18 @@ type ParticipantParams struct {
 	AudioConfig                  config.AudioConfig
 	VideoConfig                  config.VideoConfig
 	ProtocolVersion              types.ProtocolVersion
+	Capabilities                 types.ClientCapabilities
+	Fingerprint                  string
 	Telemetry                    telemetry.TelemetryService
 	PLIThrottleConfig            config.PLIThrottleConfig
 	CongestionControlConfig      config.CongestionControlConfig
 	EnabledCodecs                []*livekit.Codec
+	AudioOnly                    bool
+	E2EERequired                 bool
+	MaxPlaintextFrames           int
+	OpaquePayload                bool
+	TrackPolicy                  *TrackPolicy
+	CandidatePolicy              *CandidatePolicy
 	Logger                       logger.Logger
 	SimTracks                    map[uint32]SimulcastTrackInfo
 	Grants                       *auth.ClaimGrants
@@ -79,6 +93,8 @@ type ParticipantParams struct {
 	Region                       string
 	Migration                    bool
 	AdaptiveStream               bool
+	DeviceHints                  routing.DeviceHints
+	AudioOnlyDownlink            bool
 	AllowTCPFallback             bool
 	TCPFallbackRTTThreshold      int
 	AllowUDPUnstableFallback     bool
@@ -92,23 +108,40 @@ type ParticipantParams struct {
 	SubscriberAllowPause         bool
 	SubscriptionLimitAudio       int32
 	SubscriptionLimitVideo       int32
+	CodecTranscoder              CodecTranscoder
+	SimulcastGenerator           SimulcastGenerator
+	ReconnectPolicy              config.ReconnectPolicyConfig
+	AdmitSubscription            func(kind livekit.TrackType) time.Duration
+	// returns why a new track of the given kind cannot be published, e.g. because the node is overloaded
+	AdmitPublication func(kind livekit.TrackType) error
+	// goroutines, sockets and timers of the participant are accounted to it, nil to not account
+	Resources *sutils.ResourceOwner
+	// tracks published by a previous session of the participant, e.g. on the node the room failed over from.
+	// Their IDs are reused when the participant publishes matching tracks again
+	PreviousTracks []*livekit.TrackInfo
+	// span of the session of the participant, connection, negotiations, publications and subscriptions are traced
+	// as its children
+	TraceContext trace.SpanContext
 }
 
 type ParticipantImpl struct {
 	params ParticipantParams
 
 	isClosed    atomic.Bool
+	closeReason atomic.Int32 // types.ParticipantCloseReason
 	state       atomic.Value // livekit.ParticipantInfo_State
 	resSinkMu   sync.Mutex
 	resSink     routing.MessageSink
 	grants      *auth.ClaimGrants
 	isPublisher atomic.Bool
+	// video subscriptions are kept muted while set
+	audioOnlyDownlink atomic.Bool
 
 	// when first connected
 	connectedAt time.Time
 	// timer that's set when disconnect is detected on primary PC
-	disconnectTimer *time.Timer
-	migrationTimer  *time.Timer
+	disconnectTimer *sutils.ResourceTimer
+	migrationTimer  *sutils.ResourceTimer
 
 	rtcpCh chan []rtcp.Packet
 
@@ -129,6 +162,9 @@ type ParticipantImpl struct {
 	// keeps track of unpublished tracks in order to reuse trackID
 	unpublishedTracks []*livekit.TrackInfo
 
+	// tracks declared to start together, guarded by lock
+	startBarriers []*sfu.StartBarrier
+
 	requireBroadcast bool
 	// queued participant updates before join response is sent
 	// guarded by updateLock
@@ -163,11 +199,15 @@ type ParticipantImpl struct {
 
 	onClose            func(types.LocalParticipant)
 	onClaimsChanged    func(participant types.LocalParticipant)
+	onUnstable         func(participant types.LocalParticipant)
+	onNetworkChanged   func(participant types.LocalParticipant, target livekit.SignalTarget, previous, current *webrtc.ICECandidatePair)
 	onICEConfigChanged func(participant types.LocalParticipant, iceConfig *livekit.ICEConfig)
 
 	cachedDownTracks map[livekit.TrackID]*downTrackState
 
 	supervisor *supervisor.ParticipantSupervisor
+
+	tracing *participantTracing
 }
 
 func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
@@ -192,14 +232,26 @@ func NewParticipant(params ParticipantParams) (*ParticipantImpl, error) {
 			telemetry.BytesTrackIDForParticipantID(telemetry.BytesTrackTypeData, params.SID),
 			params.SID,
 			params.Telemetry),
-		supervisor: supervisor.NewParticipantSupervisor(supervisor.ParticipantSupervisorParams{Logger: params.Logger}),
+		supervisor:        supervisor.NewParticipantSupervisor(supervisor.ParticipantSupervisorParams{Logger: params.Logger}),
+		unpublishedTracks: append([]*livekit.TrackInfo(nil), params.PreviousTracks...),
+		tracing:           newParticipantTracing(params.TraceContext, params.Identity, params.SID),
 	}
 	p.version.Store(params.InitialVersion)
 	p.timedVersion.Update(params.VersionGenerator.New())
 	p.migrateState.Store(types.MigrateStateInit)
 	p.state.Store(livekit.ParticipantInfo_JOINING)
 	p.grants = params.Grants
+	p.audioOnlyDownlink.Store(params.AudioOnlyDownlink)
 	p.SetResponseSink(params.Sink)
+	if params.CandidatePolicy.RelayOnly() {
+		// the configuration can be shared with other participants
+		clientConf := &livekit.ClientConfiguration{}
+		if params.ClientConf != nil {
+			clientConf = proto.Clone(params.ClientConf).(*livekit.ClientConfiguration)
+		}
+		clientConf.ForceRelay = livekit.ClientConfigSetting_ENABLED
+		p.params.ClientConf = clientConf
+	}
 
 	p.supervisor.OnPublicationError(p.onPublicationError)
 
@@ -228,6 +280,94 @@ func (p *ParticipantImpl) GetAdaptiveStream() bool {
 	return p.params.AdaptiveStream
 }
 
+func (p *ParticipantImpl) GetDeviceHints() routing.DeviceHints {
+	return p.params.DeviceHints
+}
+
+func (p *ParticipantImpl) IsAudioOnlyDownlink() bool {
+	return p.audioOnlyDownlink.Load()
+}
+
+// SetAudioOnlyDownlink keeps the video subscriptions of the participant muted while set. Video tracks are still
+// subscribed to and negotiated so that clearing it resumes them without renegotiation
+func (p *ParticipantImpl) SetAudioOnlyDownlink(audioOnly bool) {
+	if p.audioOnlyDownlink.Swap(audioOnly) == audioOnly {
+		return
+	}
+
+	p.params.Logger.Infow("updated audio only downlink", "audioOnly", audioOnly)
+	for _, st := range p.GetSubscribedTracks() {
+		if st.MediaTrack().Kind() == livekit.TrackType_VIDEO {
+			st.UpdateVideoLayer()
+		}
+	}
+}
+
+// DeclareTrackSyncGroup holds forwarding of the named tracks until all of them are ready, so that subscribers get
+// them starting together. Tracks are matched by name or track ID when published and must not have been published
+// yet. Forwarding starts regardless when the timeout, counted from the first packet of any of the tracks, expires
+func (p *ParticipantImpl) DeclareTrackSyncGroup(tracks []string, timeout time.Duration) error {
+	members := make(map[string]bool, len(tracks))
+	for _, track := range tracks {
+		if track != "" {
+			members[track] = true
+		}
+	}
+	if len(members) < 2 {
+		return ErrInvalidTrackSyncGroup
+	}
+
+	for _, t := range p.GetPublishedTracks() {
+		if members[t.Name()] || members[string(t.ID())] {
+			return ErrTrackSyncGroupConflict
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	barriers := p.startBarriers[:0]
+	for _, b := range p.startBarriers {
+		if !b.IsReleased() {
+			barriers = append(barriers, b)
+		}
+	}
+	p.startBarriers = barriers
+	for _, b := range p.startBarriers {
+		for _, member := range b.Members() {
+			if members[member] {
+				return ErrTrackSyncGroupConflict
+			}
+		}
+	}
+
+	names := make([]string, 0, len(members))
+	for member := range members {
+		names = append(names, member)
+	}
+	p.startBarriers = append(p.startBarriers, sfu.NewStartBarrier(names, timeout, p.params.Logger.WithValues("syncGroup", names)))
+	p.params.Logger.Infow("declared track sync group", "tracks", names, "timeout", timeout)
+	return nil
+}
+
+// startBarrierForTrack returns the pending barrier a track is declared in and the name it was declared with
+func (p *ParticipantImpl) startBarrierForTrack(ti *livekit.TrackInfo) (*sfu.StartBarrier, string) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, b := range p.startBarriers {
+		if b.IsReleased() {
+			continue
+		}
+		for _, member := range b.Members() {
+			if member == ti.Name || member == ti.Sid {
+				return b, member
+			}
+		}
+	}
+	return nil, ""
+}
+
 func (p *ParticipantImpl) ID() livekit.ParticipantID {
 	return p.params.SID
 }
@@ -244,6 +384,11 @@ func (p *ParticipantImpl) ProtocolVersion() types.ProtocolVersion {
 	return p.params.ProtocolVersion
 }
 
+// Capabilities returns the capabilities negotiated with the client at join
+func (p *ParticipantImpl) Capabilities() types.ClientCapabilities {
+	return p.params.Capabilities
+}
+
 func (p *ParticipantImpl) IsReady() bool {
 	state := p.State()
 
@@ -286,6 +431,18 @@ func (p *ParticipantImpl) GetICEConnectionType() types.ICEConnectionType {
 	return p.TransportManager.GetICEConnectionType()
 }
 
+func (p *ParticipantImpl) GetResourceStats() sutils.ResourceStats {
+	return p.params.Resources.Stats()
+}
+
+func (p *ParticipantImpl) GetSelectedICECandidatePairs() map[livekit.SignalTarget]*webrtc.ICECandidatePair {
+	return p.TransportManager.GetSelectedICECandidatePairs()
+}
+
+func (p *ParticipantImpl) CloseReason() types.ParticipantCloseReason {
+	return types.ParticipantCloseReason(p.closeReason.Load())
+}
+
 func (p *ParticipantImpl) GetBufferFactory() *buffer.Factory {
 	return p.params.Config.BufferFactory
 }
@@ -348,6 +505,10 @@ func (p *ParticipantImpl) SetPermission(permission *livekit.ParticipantPermissio
 	if permission == nil {
 		return false
 	}
+	if audioOnly, ok := AudioOnlyDownlinkFromPermission(permission); ok {
+		p.SetAudioOnlyDownlink(audioOnly)
+	}
+
 	p.lock.Lock()
 	video := p.grants.Video
 
@@ -505,6 +666,45 @@ func (p *ParticipantImpl) OnClaimsChanged(callback func(types.LocalParticipant))
 	p.lock.Unlock()
 }
 
+func (p *ParticipantImpl) OnUnstable(callback func(types.LocalParticipant)) {
+	p.lock.Lock()
+	p.onUnstable = callback
+	p.lock.Unlock()
+}
+
+func (p *ParticipantImpl) getOnUnstable() func(types.LocalParticipant) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.onUnstable
+}
+
+func (p *ParticipantImpl) OnNetworkChanged(callback func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair)) {
+	p.lock.Lock()
+	p.onNetworkChanged = callback
+	p.lock.Unlock()
+}
+
+func (p *ParticipantImpl) getOnNetworkChanged() func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.onNetworkChanged
+}
+
+// Checkpoint returns the current subscriptions and subscription permission of the participant
+func (p *ParticipantImpl) Checkpoint() *types.ParticipantCheckpoint {
+	permission, permissionVersion := p.UpTrackManager.SubscriptionPermission()
+	changedAt := p.SubscriptionManager.DesiredSubscriptionsChangedAt()
+	if !permissionVersion.IsZero() && permissionVersion.Time().After(changedAt) {
+		changedAt = permissionVersion.Time()
+	}
+	return &types.ParticipantCheckpoint{
+		SubscribedTracks:       p.SubscriptionManager.GetDesiredSubscriptions(),
+		SubscriptionPermission: permission,
+		CreatedAt:              time.Now(),
+		ChangedAt:              changedAt,
+	}
+}
+
 // HandleOffer an offer from remote participant, used when clients make the initial connection
 func (p *ParticipantImpl) HandleOffer(offer webrtc.SessionDescription) {
 	p.params.Logger.Debugw("received offer", "transport", livekit.SignalTarget_PUBLISHER)
@@ -513,8 +713,13 @@ func (p *ParticipantImpl) HandleOffer(offer webrtc.SessionDescription) {
 		shouldPend = true
 	}
 
+	if !p.verifyFingerprint(offer) {
+		return
+	}
+
 	offer = p.setCodecPreferencesForPublisher(offer)
 
+	p.tracing.negotiationStarted(livekit.SignalTarget_PUBLISHER)
 	p.TransportManager.HandleOffer(offer, shouldPend)
 }
 
@@ -522,6 +727,9 @@ func (p *ParticipantImpl) HandleOffer(offer webrtc.SessionDescription) {
 // offer and client answers
 func (p *ParticipantImpl) HandleAnswer(answer webrtc.SessionDescription) {
 	p.params.Logger.Debugw("received answer", "transport", livekit.SignalTarget_SUBSCRIBER)
+	if !p.verifyFingerprint(answer) {
+		return
+	}
 
 	/* from server received join request to client answer
 	 * 1. server send join response & offer
@@ -531,9 +739,28 @@ func (p *ParticipantImpl) HandleAnswer(answer webrtc.SessionDescription) {
 	signalConnCost := time.Since(p.ConnectedAt()).Milliseconds()
 	p.TransportManager.UpdateSignalingRTT(uint32(signalConnCost))
 
+	p.tracing.negotiationCompleted(livekit.SignalTarget_SUBSCRIBER)
 	p.TransportManager.HandleAnswer(answer)
 }
 
+// verifyFingerprint checks that a client which declared a certificate fingerprint at join uses that certificate,
+// the participant is closed when it does not
+func (p *ParticipantImpl) verifyFingerprint(sd webrtc.SessionDescription) bool {
+	if p.params.Fingerprint == "" {
+		return true
+	}
+
+	if fingerprint := fingerprintFromSDP(sd); fingerprint != p.params.Fingerprint {
+		p.params.Logger.Warnw("certificate fingerprint does not match the declared one", nil,
+			"fingerprint", fingerprint, "declared", p.params.Fingerprint)
+		go func() {
+			_ = p.Close(true, types.ParticipantCloseReasonVerifyFailed)
+		}()
+		return false
+	}
+	return true
+}
+
 func (p *ParticipantImpl) onPublisherAnswer(answer webrtc.SessionDescription) error {
 	p.params.Logger.Debugw("sending answer", "transport", livekit.SignalTarget_PUBLISHER)
 	answer = p.configurePublisherAnswer(answer)
@@ -544,9 +771,10 @@ func (p *ParticipantImpl) onPublisherAnswer(answer webrtc.SessionDescription) er
 	}); err != nil {
 		return err
 	}
+	p.tracing.negotiationCompleted(livekit.SignalTarget_PUBLISHER)
 
 	if p.MigrateState() == types.MigrateStateSync {
-		go p.handleMigrateMutedTrack()
+		p.params.Resources.Go(p.handleMigrateMutedTrack)
 	}
 	return nil
 }
@@ -583,11 +811,11 @@ func (p *ParticipantImpl) handleMigrateMutedTrack() {
 
 	// launch callbacks in goroutine since they could block.
 	// callbacks handle webhooks as well as db persistence
-	go func() {
+	p.params.Resources.Go(func() {
 		for _, t := range addedTracks {
 			p.handleTrackPublished(t)
 		}
-	}()
+	})
 }
 
 func (p *ParticipantImpl) removeMutedTrackNotFired(mt *MediaTrack) {
@@ -612,12 +840,38 @@ func (p *ParticipantImpl) AddTrack(req *livekit.AddTrackRequest) {
 		p.params.Logger.Warnw("no permission to publish track", nil)
 		return
 	}
+	if p.params.AudioOnly && req.Type != livekit.TrackType_AUDIO {
+		p.params.Logger.Warnw("cannot publish video in audio only room", nil, "source", req.Source)
+		return
+	}
+	if p.params.E2EERequired && req.Encryption == livekit.Encryption_NONE {
+		p.params.Logger.Warnw("cannot publish unencrypted track in end-to-end encrypted room", nil, "source", req.Source)
+		_ = p.writeMessage(newE2EETrackRejectedResponse(req.Cid, "", E2EETrackRejectedNotEncrypted))
+		return
+	}
+	if p.params.TrackPolicy != nil && req.Sid == "" {
+		if err := p.params.TrackPolicy.Check(req, p.otherPublicationsLocked(req.Cid)); err != nil {
+			p.params.Logger.Infow("track rejected by track policy", "error", err, "name", req.Name, "source", req.Source)
+			p.tracing.publicationRejected(req, err)
+			_ = p.writeMessage(newTrackRejectedResponse(req.Cid, err))
+			return
+		}
+	}
+	if p.params.AdmitPublication != nil && req.Sid == "" {
+		if err := p.params.AdmitPublication(req.Type); err != nil {
+			p.params.Logger.Infow("track refused", "error", err, "name", req.Name, "source", req.Source)
+			p.tracing.publicationRejected(req, err)
+			_ = p.writeMessage(newTrackRejectedResponse(req.Cid, err))
+			return
+		}
+	}
 
 	ti := p.addPendingTrackLocked(req)
 	if ti == nil {
 		return
 	}
 
+	p.tracing.publicationStarted(ti)
 	p.sendTrackPublished(req.Cid, ti)
 }
 
@@ -643,6 +897,7 @@ func (p *ParticipantImpl) SetMigrateInfo(
 func (p *ParticipantImpl) Start() {
 	p.once.Do(func() {
 		p.UpTrackManager.Start()
+		p.params.Resources.Go(p.stabilityWorker)
 	})
 }
 
@@ -651,6 +906,7 @@ func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseRea
 		// already closed
 		return nil
 	}
+	p.closeReason.Store(int32(reason))
 
 	p.params.Logger.Infow("participant closing", "sendLeave", sendLeave, "reason", reason.String())
 	p.clearDisconnectTimer()
@@ -658,16 +914,19 @@ func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseRea
 
 	// send leave message
 	if sendLeave {
+		leave := &livekit.LeaveRequest{
+			Reason: reason.ToDisconnectReason(),
+		}
+		SetReconnectPolicy(leave, p.params.ReconnectPolicy)
 		_ = p.writeMessage(&livekit.SignalResponse{
 			Message: &livekit.SignalResponse_Leave{
-				Leave: &livekit.LeaveRequest{
-					Reason: reason.ToDisconnectReason(),
-				},
+				Leave: leave,
 			},
 		})
 	}
 
 	p.supervisor.Stop()
+	p.tracing.close(reason)
 
 	p.pendingTracksLock.Lock()
 	p.pendingTracks = make(map[string]*pendingTrackInfo)
@@ -694,10 +953,10 @@ func (p *ParticipantImpl) Close(sendLeave bool, reason types.ParticipantCloseRea
 
 	// Close peer connections without blocking participant Close. If peer connections are gathering candidates
 	// Close will block.
-	go func() {
+	p.params.Resources.Go(func() {
 		p.SubscriptionManager.Close(!sendLeave)
 		p.TransportManager.Close()
-	}()
+	})
 
 	p.dataChannelStats.Report()
 	return nil
@@ -753,7 +1012,7 @@ func (p *ParticipantImpl) MaybeStartMigration(force bool, onStart func()) bool {
 	p.clearMigrationTimer()
 
 	p.lock.Lock()
-	p.migrationTimer = time.AfterFunc(migrationWaitDuration, func() {
+	p.migrationTimer = p.params.Resources.AfterFunc(migrationWaitDuration, func() {
 		p.clearMigrationTimer()
 
 		if p.isClosed.Load() || p.IsDisconnected() {
@@ -821,6 +1080,19 @@ func (p *ParticipantImpl) ICERestart(iceConfig *livekit.ICEConfig) {
 	p.TransportManager.ICERestart(iceConfig)
 }
 
+// HandleNodeIPChanged issues a full reconnect after the external IPs of the node changed. Candidates of the peer
+// connections keep the mapping they were gathered with, restarting ICE would advertise the previous IPs again, new
+// peer connections are set up with the new mapping
+func (p *ParticipantImpl) HandleNodeIPChanged() bool {
+	if p.State() != livekit.ParticipantInfo_ACTIVE {
+		return false
+	}
+
+	p.params.Logger.Infow("issuing full reconnect on node IP change")
+	p.IssueFullReconnect(types.ParticipantCloseReasonNodeIPChanged)
+	return true
+}
+
 func (p *ParticipantImpl) OnICEConfigChanged(f func(participant types.LocalParticipant, iceConfig *livekit.ICEConfig)) {
 	p.lock.Lock()
 	p.onICEConfigChanged = f
@@ -1018,7 +1290,10 @@ func (p *ParticipantImpl) setupTransportManager() error {
 		TCPFallbackRTTThreshold:  p.params.TCPFallbackRTTThreshold,
 		AllowUDPUnstableFallback: p.params.AllowUDPUnstableFallback,
 		TURNSEnabled:             p.params.TURNSEnabled,
+		AudioOnly:                p.params.AudioOnly,
+		CandidatePolicy:          p.params.CandidatePolicy,
 		Logger:                   p.params.Logger,
+		Resources:                p.params.Resources,
 	})
 	if err != nil {
 		return err
@@ -1031,7 +1306,7 @@ func (p *ParticipantImpl) setupTransportManager() error {
 		if p.params.ClientConf == nil {
 			p.params.ClientConf = &livekit.ClientConfiguration{}
 		}
-		if iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS {
+		if iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS || p.params.CandidatePolicy.RelayOnly() {
 			p.params.ClientConf.ForceRelay = livekit.ClientConfigSetting_ENABLED
 		} else {
 			// UNSET indicates that clients could override RTCConfiguration to forceRelay
@@ -1061,6 +1336,7 @@ func (p *ParticipantImpl) setupTransportManager() error {
 	tm.OnPrimaryTransportInitialConnected(p.onPrimaryTransportInitialConnected)
 	tm.OnPrimaryTransportFullyEstablished(p.onPrimaryTransportFullyEstablished)
 	tm.OnAnyTransportFailed(p.onAnyTransportFailed)
+	tm.OnAnyTransportNetworkChanged(p.onAnyTransportNetworkChanged)
 	tm.OnAnyTransportNegotiationFailed(p.onAnyTransportNegotiationFailed)
 
 	tm.OnDataMessage(p.onDataMessage)
@@ -1101,7 +1377,10 @@ func (p *ParticipantImpl) setupSubscriptionManager() {
 		OnTrackUnsubscribed:    p.onTrackUnsubscribed,
 		OnSubscriptionError:    p.onSubscriptionError,
 		SubscriptionLimitVideo: p.params.SubscriptionLimitVideo,
+		AdmitSubscription:      p.params.AdmitSubscription,
 		SubscriptionLimitAudio: p.params.SubscriptionLimitAudio,
+		Resources:              p.params.Resources,
+		TraceContext:           p.params.TraceContext,
 	})
 }
 
@@ -1154,6 +1433,7 @@ func (p *ParticipantImpl) setIsPublisher(isPublisher bool) {
 // when the server has an offer for participant
 func (p *ParticipantImpl) onSubscriberOffer(offer webrtc.SessionDescription) error {
 	p.params.Logger.Debugw("sending offer", "transport", livekit.SignalTarget_SUBSCRIBER)
+	p.tracing.negotiationStarted(livekit.SignalTarget_SUBSCRIBER)
 	return p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_Offer{
 			Offer: ToProtoSessionDescription(offer),
@@ -1212,21 +1492,46 @@ func (p *ParticipantImpl) onMediaTrack(track *webrtc.TrackRemote, rtpReceiver *w
 }
 
 func (p *ParticipantImpl) onDataMessage(kind livekit.DataPacket_Kind, data []byte) {
-	if p.IsDisconnected() || !p.CanPublishData() {
+	receivedAt := time.Now()
+	if p.IsDisconnected() {
 		return
 	}
 
-	p.dataChannelStats.AddBytes(uint64(len(data)), false)
-
 	dp := livekit.DataPacket{}
 	if err := proto.Unmarshal(data, &dp); err != nil {
 		p.params.Logger.Warnw("could not parse data packet", err)
 		return
 	}
 
+	// answered regardless of data permissions, the response only goes back to the participant
+	if req := GetTimeSync(&dp); req != nil && dp.Value == nil {
+		p.dataChannelStats.AddBytes(uint64(len(data)), false)
+		p.sendTimeSyncResponse(kind, req, receivedAt)
+		return
+	}
+
+	if !p.CanPublishData() {
+		return
+	}
+
+	p.dataChannelStats.AddBytes(uint64(len(data)), false)
+
 	// trust the channel that it came in as the source of truth
 	dp.Kind = kind
 
+	if event := GetE2EEKeyEvent(&dp); event != nil && dp.Value == nil {
+		p.lock.RLock()
+		onDataPacket := p.onDataPacket
+		p.lock.RUnlock()
+		if onDataPacket != nil {
+			// trust the channel that it came in as the source of the key event
+			event.ParticipantID = p.params.SID
+			event.ParticipantIdentity = p.params.Identity
+			onDataPacket(p, NewE2EEKeyEventPacket(event))
+		}
+		return
+	}
+
 	// only forward on user payloads
 	switch payload := dp.Value.(type) {
 	case *livekit.DataPacket_User:
@@ -1246,6 +1551,24 @@ func (p *ParticipantImpl) onDataMessage(kind livekit.DataPacket_Kind, data []byt
 	}
 }
 
+// sendTimeSyncResponse answers a time sync request on the channel it came in, stamped as late as possible
+func (p *ParticipantImpl) sendTimeSyncResponse(kind livekit.DataPacket_Kind, req *TimeSync, receivedAt time.Time) {
+	dp := NewTimeSyncPacket(kind, &TimeSync{
+		ID:                 req.ID,
+		OriginateTimestamp: req.OriginateTimestamp,
+		Received:           receivedAt,
+		Transmitted:        time.Now(),
+	})
+	data, err := proto.Marshal(dp)
+	if err != nil {
+		p.params.Logger.Warnw("could not marshal time sync response", err)
+		return
+	}
+	if err = p.SendDataPacket(dp, data); err != nil {
+		p.params.Logger.Debugw("could not send time sync response", "error", err)
+	}
+}
+
 func (p *ParticipantImpl) onICECandidate(c *webrtc.ICECandidate, target livekit.SignalTarget) error {
 	if c == nil || p.IsDisconnected() {
 		return nil
@@ -1260,16 +1583,17 @@ func (p *ParticipantImpl) onICECandidate(c *webrtc.ICECandidate, target livekit.
 
 func (p *ParticipantImpl) onPublisherInitialConnected() {
 	p.supervisor.SetPublisherPeerConnectionConnected(true)
-	go p.publisherRTCPWorker()
+	p.params.Resources.Go(p.publisherRTCPWorker)
 }
 
 func (p *ParticipantImpl) onSubscriberInitialConnected() {
-	go p.subscriberRTCPWorker()
+	p.params.Resources.Go(p.subscriberRTCPWorker)
 
 	p.setDowntracksConnected()
 }
 
 func (p *ParticipantImpl) onPrimaryTransportInitialConnected() {
+	p.tracing.connected(p.GetICEConnectionType())
 	if !p.hasPendingMigratedTrack() && p.MigrateState() == types.MigrateStateSync {
 		p.SetMigrateState(types.MigrateStateComplete)
 	}
@@ -1292,7 +1616,7 @@ func (p *ParticipantImpl) setupDisconnectTimer() {
 	p.clearDisconnectTimer()
 
 	p.lock.Lock()
-	p.disconnectTimer = time.AfterFunc(disconnectCleanupDuration, func() {
+	p.disconnectTimer = p.params.Resources.AfterFunc(disconnectCleanupDuration, func() {
 		p.clearDisconnectTimer()
 
 		if p.isClosed.Load() || p.IsDisconnected() {
@@ -1312,6 +1636,55 @@ func (p *ParticipantImpl) onAnyTransportFailed() {
 	p.setupDisconnectTimer()
 }
 
+func (p *ParticipantImpl) onAnyTransportNetworkChanged(target livekit.SignalTarget, previous, current *webrtc.ICECandidatePair) {
+	if onNetworkChanged := p.getOnNetworkChanged(); onNetworkChanged != nil {
+		onNetworkChanged(p, target, previous, current)
+	}
+}
+
+func (p *ParticipantImpl) instabilityReason() string {
+	switch {
+	case p.TransportManager.SinceLastSignal() > unstableSignalTimeout:
+		return "signal timeout"
+	case p.TransportManager.HasICEDisconnected():
+		return "ICE disconnected"
+	default:
+		return ""
+	}
+}
+
+// stabilityWorker watches signal heartbeats and ICE connectivity, notifying once when the
+// participant looks like it is about to disconnect and again only after it has recovered
+func (p *ParticipantImpl) stabilityWorker() {
+	ticker := time.NewTicker(stabilityCheckInterval)
+	defer ticker.Stop()
+
+	unstable := false
+	for range ticker.C {
+		if p.IsClosed() || p.IsDisconnected() {
+			return
+		}
+
+		reason := p.instabilityReason()
+		if reason == "" {
+			if unstable {
+				p.params.Logger.Infow("participant connection recovered")
+				unstable = false
+			}
+			continue
+		}
+		if unstable {
+			continue
+		}
+
+		unstable = true
+		p.params.Logger.Infow("participant connection unstable", "reason", reason)
+		if onUnstable := p.getOnUnstable(); onUnstable != nil {
+			onUnstable(p)
+		}
+	}
+}
+
 // subscriberRTCPWorker sends SenderReports periodically when the participant is subscribed to
 // other publishedTracks in the room.
 func (p *ParticipantImpl) subscriberRTCPWorker() {
@@ -1478,7 +1851,13 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 			return nil
 		}
 
-		track.(*MediaTrack).SetPendingCodecSid(req.SimulcastCodecs)
+		if len(req.SimulcastCodecs) == 0 {
+			// no new codecs, media published with this cid replaces the source of the existing track
+			p.params.Logger.Infow("replacing track source", "trackID", req.Sid, "cid", req.Cid)
+			track.(*MediaTrack).SetPendingReplacement(req.Cid)
+		} else {
+			track.(*MediaTrack).SetPendingCodecSid(req.SimulcastCodecs)
+		}
 		ti := track.ToProto()
 		return ti
 	}
@@ -1530,6 +1909,31 @@ func (p *ParticipantImpl) addPendingTrackLocked(req *livekit.AddTrackRequest) *l
 	return ti
 }
 
+// otherPublicationsLocked returns the tracks published or pending publication, other than those of the client track ID
+// which are being republished
+func (p *ParticipantImpl) otherPublicationsLocked(cid string) []*livekit.TrackInfo {
+	var infos []*livekit.TrackInfo
+	for _, track := range p.GetPublishedTracks() {
+		if track.(types.LocalMediaTrack).SignalCid() == cid {
+			continue
+		}
+		infos = append(infos, &livekit.TrackInfo{
+			Sid:    string(track.ID()),
+			Name:   track.Name(),
+			Source: track.Source(),
+		})
+	}
+
+	p.pendingTracksLock.RLock()
+	for pendingCid, pti := range p.pendingTracks {
+		if pendingCid != cid {
+			infos = append(infos, pti.trackInfos...)
+		}
+	}
+	p.pendingTracksLock.RUnlock()
+	return infos
+}
+
 func (p *ParticipantImpl) sendTrackPublished(cid string, ti *livekit.TrackInfo) {
 	p.params.Logger.Debugw("sending track published", "cid", cid, "trackInfo", ti.String())
 	_ = p.writeMessage(&livekit.SignalResponse{
@@ -1622,7 +2026,10 @@ func (p *ParticipantImpl) mediaTrackReceived(track *webrtc.TrackRemote, rtpRecei
 
 	ssrc := uint32(track.SSRC())
 	if p.twcc == nil {
-		p.twcc = twcc.NewTransportWideCCResponder(ssrc)
+		p.twcc = twcc.NewResponder(twcc.ResponderParams{
+			SSRC:             ssrc,
+			FeedbackInterval: p.params.Config.TWCC.FeedbackInterval,
+		})
 		p.twcc.OnFeedback(func(pkt rtcp.RawPacket) {
 			p.postRtcp([]rtcp.Packet{&pkt})
 		})
@@ -1675,6 +2082,7 @@ func (p *ParticipantImpl) addMigrateMutedTrack(cid string, ti *livekit.TrackInfo
 }
 
 func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *livekit.TrackInfo) *MediaTrack {
+	startBarrier, startBarrierMember := p.startBarrierForTrack(ti)
 	mt := NewMediaTrack(MediaTrackParams{
 		TrackInfo:           proto.Clone(ti).(*livekit.TrackInfo),
 		SignalCid:           signalCid,
@@ -1692,9 +2100,60 @@ func (p *ParticipantImpl) addMediaTrack(signalCid string, sdpCid string, ti *liv
 		SubscriberConfig:    p.params.Config.Subscriber,
 		PLIThrottleConfig:   p.params.PLIThrottleConfig,
 		SimTracks:           p.params.SimTracks,
+		CodecTranscoder:     p.params.CodecTranscoder,
+		SimulcastGenerator:  p.params.SimulcastGenerator,
+		E2EERequired:        p.params.E2EERequired,
+		MaxPlaintextFrames:  p.params.MaxPlaintextFrames,
+		OpaquePayload:       p.params.OpaquePayload,
+		StartBarrier:        startBarrier,
+		StartBarrierMember:  startBarrierMember,
 	})
 
 	mt.OnSubscribedMaxQualityChange(p.onSubscribedMaxQualityChange)
+	mt.OnCodecSwitched(func(fromMime string, toMime string) {
+		p.params.Logger.Infow("published track switched codec", "trackID", mt.ID(), "from", fromMime, "to", toMime)
+
+		p.lock.RLock()
+		onTrackUpdated := p.onTrackUpdated
+		p.lock.RUnlock()
+
+		p.dirty.Store(true)
+		if onTrackUpdated != nil {
+			onTrackUpdated(p, mt)
+		}
+	})
+	mt.OnKeyEpochChanged(func(keyEpoch uint8) {
+		p.params.Logger.Debugw("published track key epoch changed", "trackID", mt.ID(), "keyEpoch", keyEpoch)
+
+		p.lock.RLock()
+		onTrackUpdated := p.onTrackUpdated
+		onDataPacket := p.onDataPacket
+		p.lock.RUnlock()
+
+		p.dirty.Store(true)
+		if onTrackUpdated != nil {
+			onTrackUpdated(p, mt)
+		}
+		if p.params.E2EERequired && onDataPacket != nil {
+			onDataPacket(p, NewE2EEKeyEventPacket(&E2EEKeyEvent{
+				ParticipantID:       p.params.SID,
+				ParticipantIdentity: p.params.Identity,
+				TrackID:             mt.ID(),
+				KeyIndex:            uint32(keyEpoch),
+			}))
+		}
+	})
+	mt.OnPlaintextDetected(func() {
+		p.params.Logger.Warnw("unpublishing track which is not end-to-end encrypted", nil, "trackID", mt.ID())
+
+		p.RemovePublishedTrack(mt, false, false)
+		if p.ProtocolVersion().SupportsUnpublish() {
+			p.sendTrackUnpublished(mt.ID())
+		} else {
+			p.sendTrackMuted(mt.ID(), true)
+		}
+		_ = p.writeMessage(newE2EETrackRejectedResponse("", mt.ID(), E2EETrackRejectedPlaintext))
+	})
 
 	// add to published and clean up pending
 	p.supervisor.SetPublishedTrack(livekit.TrackID(ti.Sid), mt)
@@ -1775,6 +2234,7 @@ func (p *ParticipantImpl) handleTrackPublished(track types.MediaTrack) {
 	p.pendingTracksLock.Lock()
 	delete(p.pendingPublishingTracks, track.ID())
 	p.pendingTracksLock.Unlock()
+	p.tracing.published(track.ID())
 
 	if !p.hasPendingMigratedTrack() {
 		p.SetMigrateState(types.MigrateStateComplete)
@@ -2022,12 +2482,14 @@ func (p *ParticipantImpl) GetCachedDownTrack(trackID livekit.TrackID) (*webrtc.R
 }
 
 func (p *ParticipantImpl) IssueFullReconnect(reason types.ParticipantCloseReason) {
+	leave := &livekit.LeaveRequest{
+		CanReconnect: true,
+		Reason:       reason.ToDisconnectReason(),
+	}
+	SetReconnectPolicy(leave, p.params.ReconnectPolicy)
 	_ = p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_Leave{
-			Leave: &livekit.LeaveRequest{
-				CanReconnect: true,
-				Reason:       reason.ToDisconnectReason(),
-			},
+			Leave: leave,
 		},
 	})
 	p.CloseSignalConnection()
@@ -225,6 +225,33 @@ func TestOutOfOrderUpdates(t *testing.T) {
 	require.Equal(t, "second update", sent.GetUpdate().Participants[0].Metadata)
 }
 
+func TestResumeDeltaRoster(t *testing.T) {
+	others := []*livekit.ParticipantInfo{
+		{Sid: "PA_before", Identity: "before", Version: 1},
+		{Sid: "PA_after", Identity: "after", Version: 1},
+	}
+	resume := func(capabilities types.ClientCapabilities) []*livekit.ParticipantInfo {
+		p := newParticipantForTest("test")
+		p.params.Capabilities = capabilities
+		p.updateState(livekit.ParticipantInfo_JOINED)
+		sink := p.getResponseSink().(*routingfakes.FakeMessageSink)
+
+		require.NoError(t, p.SendParticipantUpdate(others[:1]))
+		time.Sleep(time.Millisecond)
+		p.UpdateLastSeenSignal()
+		time.Sleep(time.Millisecond)
+		// sent after the signal connection was last seen working, may not have been received
+		require.NoError(t, p.SendParticipantUpdate(others[1:]))
+
+		require.NoError(t, p.SendResumeParticipantUpdate(others))
+		require.Equal(t, 3, sink.WriteMessageCallCount())
+		return sink.WriteMessageArgsForCall(2).(*livekit.SignalResponse).GetUpdate().Participants
+	}
+
+	require.Len(t, resume(0), 2)
+	require.Equal(t, others[1:], resume(types.ClientCapabilityDeltaRoster))
+}
+
 // after disconnection, things should continue to function and not panic
 func TestDisconnectTiming(t *testing.T) {
 	t.Run("Negotiate doesn't panic after channel closed", func(t *testing.T) {
@@ -245,6 +272,71 @@ func TestDisconnectTiming(t *testing.T) {
 	})
 }
 
+func TestInstability(t *testing.T) {
+	p := newParticipantForTest("test")
+	p.UpdateLastSeenSignal()
+	require.Empty(t, p.instabilityReason())
+
+	p.TransportManager.lock.Lock()
+	p.TransportManager.lastSignalAt = time.Now().Add(-unstableSignalTimeout - time.Second)
+	p.TransportManager.lock.Unlock()
+	require.Equal(t, "signal timeout", p.instabilityReason())
+
+	p.UpdateLastSeenSignal()
+	require.Empty(t, p.instabilityReason())
+}
+
+func TestDeclareTrackSyncGroup(t *testing.T) {
+	p := newParticipantForTest("test")
+	track := &typesfakes.FakeMediaTrack{}
+	track.IDReturns("TR_published")
+	track.NameReturns("screen")
+	p.UpTrackManager.AddPublishedTrack(track)
+
+	require.ErrorIs(t, p.DeclareTrackSyncGroup([]string{"mic"}, time.Minute), ErrInvalidTrackSyncGroup)
+	require.ErrorIs(t, p.DeclareTrackSyncGroup([]string{"mic", "mic", ""}, time.Minute), ErrInvalidTrackSyncGroup)
+	require.ErrorIs(t, p.DeclareTrackSyncGroup([]string{"mic", "screen"}, time.Minute), ErrTrackSyncGroupConflict)
+	require.ErrorIs(t, p.DeclareTrackSyncGroup([]string{"mic", "TR_published"}, time.Minute), ErrTrackSyncGroupConflict)
+
+	require.NoError(t, p.DeclareTrackSyncGroup([]string{"mic", "camera"}, time.Minute))
+	require.ErrorIs(t, p.DeclareTrackSyncGroup([]string{"camera", "clip"}, time.Minute), ErrTrackSyncGroupConflict)
+
+	barrier, member := p.startBarrierForTrack(&livekit.TrackInfo{Sid: "TR_camera", Name: "camera"})
+	require.NotNil(t, barrier)
+	require.Equal(t, "camera", member)
+	barrier, _ = p.startBarrierForTrack(&livekit.TrackInfo{Sid: "TR_clip", Name: "clip"})
+	require.Nil(t, barrier)
+
+	// released groups no longer hold their tracks, the timeout starts with the first packet
+	require.NoError(t, p.DeclareTrackSyncGroup([]string{"clip-audio", "clip-video"}, time.Millisecond))
+	barrier, member = p.startBarrierForTrack(&livekit.TrackInfo{Name: "clip-audio"})
+	require.False(t, barrier.Admit(member, false, false))
+	require.Eventually(t, func() bool {
+		barrier, _ := p.startBarrierForTrack(&livekit.TrackInfo{Name: "clip-audio"})
+		return barrier == nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, p.DeclareTrackSyncGroup([]string{"clip-audio", "clip-video"}, time.Minute))
+}
+
+func TestCheckpoint(t *testing.T) {
+	p := newParticipantForTest("test")
+	require.Nil(t, p.Checkpoint().SubscriptionPermission)
+	require.True(t, p.Checkpoint().ChangedAt.IsZero())
+
+	permission := &livekit.SubscriptionPermission{
+		TrackPermissions: []*livekit.TrackPermission{
+			{ParticipantIdentity: "p1", AllTracks: true},
+		},
+	}
+	require.NoError(t, p.UpdateSubscriptionPermission(permission, utils.TimedVersion{}, nil, nil))
+
+	checkpoint := p.Checkpoint()
+	require.True(t, proto.Equal(permission, checkpoint.SubscriptionPermission))
+	require.Empty(t, checkpoint.SubscribedTracks)
+	require.WithinDuration(t, time.Now(), checkpoint.CreatedAt, time.Second)
+	require.WithinDuration(t, time.Now(), checkpoint.ChangedAt, time.Second)
+}
+
 func TestCorrectJoinedAt(t *testing.T) {
 	p := newParticipantForTest("test")
 	info := p.ToProto()
@@ -694,3 +786,22 @@ func newParticipantForTestWithOpts(identity livekit.ParticipantIdentity, opts *p
 func newParticipantForTest(identity livekit.ParticipantIdentity) *ParticipantImpl {
 	return newParticipantForTestWithOpts(identity, nil)
 }
+
+func TestHandleNodeIPChanged(t *testing.T) {
+	p := newParticipantForTest("test")
+	sink := p.getResponseSink().(*routingfakes.FakeMessageSink)
+
+	// not connected yet
+	p.updateState(livekit.ParticipantInfo_JOINED)
+	require.False(t, p.HandleNodeIPChanged())
+	require.Equal(t, 0, sink.WriteMessageCallCount())
+
+	p.updateState(livekit.ParticipantInfo_ACTIVE)
+	writes := sink.WriteMessageCallCount()
+	require.True(t, p.HandleNodeIPChanged())
+	require.Equal(t, writes+1, sink.WriteMessageCallCount())
+	leave := sink.WriteMessageArgsForCall(writes).(*livekit.SignalResponse).GetLeave()
+	require.NotNil(t, leave)
+	require.True(t, leave.CanReconnect)
+	require.Equal(t, livekit.DisconnectReason_STATE_MISMATCH, leave.Reason)
+}
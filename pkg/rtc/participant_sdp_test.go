@@ -0,0 +1,73 @@
+package rtc
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// FuzzPublisherSDP runs publisher offers through the codec preferences applied before they are handed to pion,
+// and through the configuration of the answer to them
+func FuzzPublisherSDP(f *testing.F) {
+	chromeOffer, err := os.ReadFile("testdata/chrome_publisher_offer.sdp")
+	require.NoError(f, err)
+	f.Add(string(chromeOffer))
+
+	// offer of a go client
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(f, err)
+	defer pc.Close()
+	for _, capability := range []webrtc.RTPCodecCapability{
+		{MimeType: webrtc.MimeTypeOpus},
+		{MimeType: webrtc.MimeTypeVP8},
+	} {
+		track, err := webrtc.NewTrackLocalStaticRTP(capability, "TR_"+capability.MimeType, "stream")
+		require.NoError(f, err)
+		_, err = pc.AddTransceiverFromTrack(track, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly})
+		require.NoError(f, err)
+	}
+	goOffer, err := pc.CreateOffer(nil)
+	require.NoError(f, err)
+	f.Add(goOffer.SDP)
+
+	answerer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(f, err)
+	defer answerer.Close()
+	require.NoError(f, answerer.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: string(chromeOffer)}))
+	answer, err := answerer.CreateAnswer(nil)
+	require.NoError(f, err)
+
+	// tracks pending publication match the media sections of the seeds
+	participant := newParticipantForTestWithOpts("publisher", &participantOpts{
+		publisher: true,
+	})
+	participant.AddTrack(&livekit.AddTrackRequest{
+		Type:   livekit.TrackType_AUDIO,
+		Cid:    "TR_audio_cid",
+		Stereo: true,
+	})
+	participant.AddTrack(&livekit.AddTrackRequest{
+		Type:   livekit.TrackType_VIDEO,
+		Source: livekit.TrackSource_CAMERA,
+		SimulcastCodecs: []*livekit.SimulcastCodec{
+			{Codec: "h264", Cid: "TR_video_cid"},
+			{Codec: "vp8"},
+		},
+	})
+
+	f.Fuzz(func(t *testing.T, sdp string) {
+		// pion does not always parse what it marshals, a mangled offer fails negotiation of the client sending it,
+		// only the answer built by the server is required to stay valid
+		offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp}
+		participant.setCodecPreferencesForPublisher(offer)
+
+		participant.TransportManager.lastPublisherOffer.Store(offer)
+		configured := participant.configurePublisherAnswer(answer)
+		_, err := configured.Unmarshal()
+		require.NoError(t, err, "configured answer cannot be parsed")
+	})
+}
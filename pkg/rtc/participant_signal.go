@@ -11,6 +11,7 @@ import (
 	"github.com/livekit/psrpc"
 
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 )
 
 func (p *ParticipantImpl) getResponseSink() routing.MessageSink {
@@ -33,6 +34,12 @@ func (p *ParticipantImpl) SendJoinResponse(joinResponse *livekit.JoinResponse) e
 	}
 	p.updateLock.Unlock()
 
+	SetReconnectPolicy(joinResponse, p.params.ReconnectPolicy)
+	SetClientCapabilities(joinResponse, p.params.Capabilities)
+	if p.params.E2EERequired {
+		SetE2EERequired(joinResponse)
+	}
+
 	// send Join response
 	err := p.writeMessage(&livekit.SignalResponse{
 		Message: &livekit.SignalResponse_Join{
@@ -105,6 +112,31 @@ func (p *ParticipantImpl) SendParticipantUpdate(participantsToUpdate []*livekit.
 	})
 }
 
+// SendResumeParticipantUpdate sends the roster to a resuming participant. Clients with a delta roster keep theirs
+// across the resume, they only get participants that were updated after the signal connection was last seen working
+func (p *ParticipantImpl) SendResumeParticipantUpdate(participantsToUpdate []*livekit.ParticipantInfo) error {
+	if !p.params.Capabilities.Has(types.ClientCapabilityDeltaRoster) {
+		return p.SendParticipantUpdate(participantsToUpdate)
+	}
+
+	lastSignalAt := p.TransportManager.LastSeenSignalAt()
+	changed := make([]*livekit.ParticipantInfo, 0, len(participantsToUpdate))
+	p.updateLock.Lock()
+	for _, pi := range participantsToUpdate {
+		if info, ok := p.updateCache.Get(livekit.ParticipantID(pi.Sid)); ok &&
+			info.version >= pi.Version && info.updatedAt.Before(lastSignalAt) {
+			continue
+		}
+		changed = append(changed, pi)
+	}
+	p.updateLock.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+	return p.SendParticipantUpdate(changed)
+}
+
 // SendSpeakerUpdate notifies participant changes to speakers. only send members that have changed since last update
 func (p *ParticipantImpl) SendSpeakerUpdate(speakers []*livekit.SpeakerInfo, force bool) error {
 	if !p.IsReady() {
@@ -224,6 +256,10 @@ func (p *ParticipantImpl) sendDisconnectUpdatesForReconnect() error {
 	})
 }
 
+func (p *ParticipantImpl) SendLoadShedding(level types.LoadSheddingLevel, pausedTrackIDs []livekit.TrackID) error {
+	return p.writeMessage(newLoadSheddingResponse(level, pausedTrackIDs))
+}
+
 func (p *ParticipantImpl) sendICECandidate(c *webrtc.ICECandidate, target livekit.SignalTarget) error {
 	trickle := ToProtoTrickle(c.ToJSON())
 	trickle.Target = target
@@ -0,0 +1,165 @@
+package rtc
+
+import (
+	"errors"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/tracing"
+)
+
+var errParticipantClosed = errors.New("participant closed")
+
+// participantTracing traces how long the connection of a participant, its negotiations and the publication of its
+// tracks take. Spans are children of the session of the participant, so they are part of the trace of its join
+type participantTracing struct {
+	parent trace.SpanContext
+
+	lock         sync.Mutex
+	connect      trace.Span
+	negotiations map[livekit.SignalTarget]trace.Span
+	publications map[livekit.TrackID]trace.Span
+	closed       bool
+}
+
+func newParticipantTracing(parent trace.SpanContext, identity livekit.ParticipantIdentity, sid livekit.ParticipantID) *participantTracing {
+	t := &participantTracing{
+		parent:       parent,
+		negotiations: make(map[livekit.SignalTarget]trace.Span),
+		publications: make(map[livekit.TrackID]trace.Span),
+	}
+	t.connect = t.start("rtc.Connect",
+		tracing.Participant(identity),
+		attribute.String("participant_sid", string(sid)),
+	)
+	return t
+}
+
+// start returns nil when the session is not traced
+func (t *participantTracing) start(name string, attrs ...attribute.KeyValue) trace.Span {
+	if !t.parent.IsSampled() {
+		return nil
+	}
+	return tracing.StartFrom(t.parent, name, attrs...)
+}
+
+// connected ends the connection span once the primary transport is connected
+func (t *participantTracing) connected(iceConnectionType types.ICEConnectionType) {
+	t.lock.Lock()
+	span := t.connect
+	t.connect = nil
+	t.lock.Unlock()
+
+	if span != nil {
+		span.SetAttributes(attribute.String("ice_connection_type", string(iceConnectionType)))
+		span.End()
+	}
+}
+
+// negotiationStarted is called on an offer, from the client for the publisher transport, to the client for the
+// subscriber transport. A negotiation still going on is superseded
+func (t *participantTracing) negotiationStarted(target livekit.SignalTarget) {
+	span := t.start("rtc.Negotiate", attribute.String("transport", target.String()))
+	if span == nil {
+		return
+	}
+
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		span.End()
+		return
+	}
+	prev := t.negotiations[target]
+	t.negotiations[target] = span
+	t.lock.Unlock()
+
+	if prev != nil {
+		prev.SetAttributes(attribute.Bool("superseded", true))
+		prev.End()
+	}
+}
+
+// negotiationCompleted is called on the answer
+func (t *participantTracing) negotiationCompleted(target livekit.SignalTarget) {
+	t.lock.Lock()
+	span := t.negotiations[target]
+	delete(t.negotiations, target)
+	t.lock.Unlock()
+
+	if span != nil {
+		span.End()
+	}
+}
+
+// publicationStarted is called when a track is accepted, the span ends when its media is received
+func (t *participantTracing) publicationStarted(ti *livekit.TrackInfo) {
+	span := t.start("rtc.PublishTrack",
+		attribute.String("track_sid", ti.Sid),
+		attribute.String("kind", ti.Type.String()),
+		attribute.String("source", ti.Source.String()),
+	)
+	if span == nil {
+		return
+	}
+
+	t.lock.Lock()
+	if t.closed || t.publications[livekit.TrackID(ti.Sid)] != nil {
+		t.lock.Unlock()
+		span.End()
+		return
+	}
+	t.publications[livekit.TrackID(ti.Sid)] = span
+	t.lock.Unlock()
+}
+
+func (t *participantTracing) publicationRejected(req *livekit.AddTrackRequest, err error) {
+	span := t.start("rtc.PublishTrack",
+		attribute.String("kind", req.Type.String()),
+		attribute.String("source", req.Source.String()),
+	)
+	if span != nil {
+		tracing.End(span, err)
+	}
+}
+
+func (t *participantTracing) published(trackID livekit.TrackID) {
+	t.lock.Lock()
+	span := t.publications[trackID]
+	delete(t.publications, trackID)
+	t.lock.Unlock()
+
+	if span != nil {
+		span.End()
+	}
+}
+
+// close ends the spans still open as failed
+func (t *participantTracing) close(reason types.ParticipantCloseReason) {
+	t.lock.Lock()
+	t.closed = true
+	spans := make([]trace.Span, 0, len(t.negotiations)+len(t.publications)+1)
+	if t.connect != nil {
+		spans = append(spans, t.connect)
+		t.connect = nil
+	}
+	for _, span := range t.negotiations {
+		spans = append(spans, span)
+	}
+	for _, span := range t.publications {
+		spans = append(spans, span)
+	}
+	t.negotiations = make(map[livekit.SignalTarget]trace.Span)
+	t.publications = make(map[livekit.TrackID]trace.Span)
+	t.lock.Unlock()
+
+	for _, span := range spans {
+		span.SetAttributes(attribute.String("close_reason", reason.String()))
+		tracing.End(span, errParticipantClosed)
+	}
+}
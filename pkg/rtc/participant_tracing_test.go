@@ -0,0 +1,80 @@
+package rtc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/tracing"
+)
+
+func TestParticipantTracing(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	newRecorder := func() *tracetest.SpanRecorder {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		return recorder
+	}
+
+	spanNames := func(spans []sdktrace.ReadOnlySpan) []string {
+		var names []string
+		for _, span := range spans {
+			names = append(names, span.Name())
+		}
+		return names
+	}
+
+	t.Run("session not traced", func(t *testing.T) {
+		recorder := newRecorder()
+		pt := newParticipantTracing(trace.SpanContext{}, "p", "PA_p")
+		pt.negotiationStarted(livekit.SignalTarget_PUBLISHER)
+		pt.negotiationCompleted(livekit.SignalTarget_PUBLISHER)
+		pt.close(types.ParticipantCloseReasonClientRequestLeave)
+		require.Empty(t, recorder.Started())
+	})
+
+	t.Run("spans are children of the session", func(t *testing.T) {
+		recorder := newRecorder()
+		_, session := tracing.Start(context.Background(), "rtc.StartSession")
+		session.End()
+
+		pt := newParticipantTracing(session.SpanContext(), "p", "PA_p")
+		pt.negotiationStarted(livekit.SignalTarget_PUBLISHER)
+		pt.negotiationCompleted(livekit.SignalTarget_PUBLISHER)
+		pt.connected(types.ICEConnectionTypeUDP)
+		pt.publicationStarted(&livekit.TrackInfo{Sid: "TR_video", Type: livekit.TrackType_VIDEO})
+		pt.publicationStarted(&livekit.TrackInfo{Sid: "TR_audio", Type: livekit.TrackType_AUDIO})
+		pt.published("TR_video")
+		pt.negotiationStarted(livekit.SignalTarget_SUBSCRIBER)
+		require.Equal(t, []string{"rtc.StartSession", "rtc.Negotiate", "rtc.Connect", "rtc.PublishTrack"}, spanNames(recorder.Ended()))
+
+		// what did not complete fails with the participant
+		pt.close(types.ParticipantCloseReasonClientRequestLeave)
+		ended := recorder.Ended()
+		require.Len(t, ended, 6)
+		for _, span := range ended[1:] {
+			require.Equal(t, session.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+		for _, span := range ended[:4] {
+			require.Equal(t, codes.Unset, span.Status().Code)
+		}
+		for _, span := range ended[4:] {
+			require.Equal(t, codes.Error, span.Status().Code)
+		}
+
+		// spans are not left open once closed
+		pt.negotiationStarted(livekit.SignalTarget_SUBSCRIBER)
+		pt.close(types.ParticipantCloseReasonClientRequestLeave)
+		require.Len(t, recorder.Ended(), 7)
+	})
+}
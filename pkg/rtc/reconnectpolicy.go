@@ -0,0 +1,58 @@
+package rtc
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// The reconnect policy is not part of the protocol messages yet, it is appended as an extra field that
+// clients unaware of it skip. Being an unknown field, it is only carried by the binary signal protocol:
+//
+//	message ReconnectPolicy {
+//	  uint32 initial_delay_ms = 1;
+//	  uint32 max_delay_ms = 2;
+//	  uint32 max_retries = 3;
+//	  float jitter = 4;
+//	}
+//
+//	JoinResponse.reconnect_policy = 100;
+//	LeaveRequest.reconnect_policy = 100;
+const (
+	reconnectPolicyField protowire.Number = 100
+
+	reconnectPolicyInitialDelayField protowire.Number = 1
+	reconnectPolicyMaxDelayField     protowire.Number = 2
+	reconnectPolicyMaxRetriesField   protowire.Number = 3
+	reconnectPolicyJitterField       protowire.Number = 4
+)
+
+// SetReconnectPolicy attaches the reconnect policy to a JoinResponse or LeaveRequest
+func SetReconnectPolicy(msg proto.Message, conf config.ReconnectPolicyConfig) {
+	if !conf.Enabled {
+		return
+	}
+
+	var policy []byte
+	appendVarint := func(field protowire.Number, v uint64) {
+		if v == 0 {
+			return
+		}
+		policy = protowire.AppendTag(policy, field, protowire.VarintType)
+		policy = protowire.AppendVarint(policy, v)
+	}
+	appendVarint(reconnectPolicyInitialDelayField, uint64(conf.InitialDelay.Milliseconds()))
+	appendVarint(reconnectPolicyMaxDelayField, uint64(conf.MaxDelay.Milliseconds()))
+	appendVarint(reconnectPolicyMaxRetriesField, uint64(conf.MaxRetries))
+	if jitter := math.Min(math.Max(float64(conf.Jitter), 0), 1); jitter > 0 {
+		policy = protowire.AppendTag(policy, reconnectPolicyJitterField, protowire.Fixed32Type)
+		policy = protowire.AppendFixed32(policy, math.Float32bits(float32(jitter)))
+	}
+
+	m := msg.ProtoReflect()
+	unknown := protowire.AppendTag(m.GetUnknown(), reconnectPolicyField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, policy))
+}
@@ -0,0 +1,67 @@
+package rtc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestSetReconnectPolicy(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		leave := &livekit.LeaveRequest{CanReconnect: true}
+		SetReconnectPolicy(leave, config.ReconnectPolicyConfig{InitialDelay: time.Second})
+		require.Empty(t, leave.ProtoReflect().GetUnknown())
+	})
+
+	t.Run("survives a round trip", func(t *testing.T) {
+		join := &livekit.JoinResponse{ServerVersion: "1.0"}
+		SetReconnectPolicy(join, config.ReconnectPolicyConfig{
+			Enabled:      true,
+			InitialDelay: 500 * time.Millisecond,
+			MaxDelay:     10 * time.Second,
+			MaxRetries:   5,
+			Jitter:       2,
+		})
+
+		data, err := proto.Marshal(join)
+		require.NoError(t, err)
+		decoded := &livekit.JoinResponse{}
+		require.NoError(t, proto.Unmarshal(data, decoded))
+		require.Equal(t, "1.0", decoded.ServerVersion)
+
+		num, typ, n := protowire.ConsumeTag(decoded.ProtoReflect().GetUnknown())
+		require.Equal(t, reconnectPolicyField, num)
+		require.Equal(t, protowire.BytesType, typ)
+		policy, _ := protowire.ConsumeBytes(decoded.ProtoReflect().GetUnknown()[n:])
+
+		fields := map[protowire.Number]uint64{}
+		for len(policy) > 0 {
+			num, typ, n := protowire.ConsumeTag(policy)
+			policy = policy[n:]
+			switch typ {
+			case protowire.VarintType:
+				fields[num], n = protowire.ConsumeVarint(policy)
+			case protowire.Fixed32Type:
+				var v uint32
+				v, n = protowire.ConsumeFixed32(policy)
+				fields[num] = uint64(v)
+			}
+			policy = policy[n:]
+		}
+		require.Equal(t, map[protowire.Number]uint64{
+			reconnectPolicyInitialDelayField: 500,
+			reconnectPolicyMaxDelayField:     10000,
+			reconnectPolicyMaxRetriesField:   5,
+			// clamped
+			reconnectPolicyJitterField: uint64(math.Float32bits(1)),
+		}, fields)
+	})
+}
@@ -0,0 +1,134 @@
+package rtc
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	dd "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+type RelayedTrackParams struct {
+	TrackInfo           *livekit.TrackInfo
+	ParticipantID       livekit.ParticipantID
+	ParticipantIdentity livekit.ParticipantIdentity
+	ParticipantVersion  uint32
+	Codec               webrtc.RTPCodecParameters
+	HeaderExtensions    []webrtc.RTPHeaderExtensionParameter
+	StreamTrackers      config.StreamTrackersConfig
+	// called when the first subscriber on this node comes and when the last one goes
+	OnSubscribedChanged func(subscribed bool)
+	// called when subscribers need a key frame of a layer
+	OnPLI func(layer int32)
+
+	ReceiverConfig   ReceiverConfig
+	SubscriberConfig DirectionConfig
+	AudioConfig      config.AudioConfig
+	Telemetry        telemetry.TelemetryService
+	Logger           logger.Logger
+}
+
+// RelayedTrack is a track published by a participant connected to another node hosting the room, whose media is
+// relayed to this node
+type RelayedTrack struct {
+	*MediaTrackReceiver
+
+	params   RelayedTrackParams
+	receiver *sfu.RelayReceiver
+}
+
+func NewRelayedTrack(params RelayedTrackParams) *RelayedTrack {
+	t := &RelayedTrack{
+		params: params,
+	}
+	t.params.Logger = LoggerWithTrack(
+		LoggerWithParticipant(params.Logger, params.ParticipantIdentity, params.ParticipantID, false),
+		livekit.TrackID(params.TrackInfo.Sid),
+		true,
+	)
+
+	t.receiver = sfu.NewRelayReceiver(sfu.RelayReceiverParams{
+		TrackID:             livekit.TrackID(params.TrackInfo.Sid),
+		StreamID:            string(params.ParticipantID),
+		Codec:               params.Codec,
+		HeaderExtensions:    params.HeaderExtensions,
+		TrackInfo:           params.TrackInfo,
+		StreamTrackers:      params.StreamTrackers,
+		OnSubscribedChanged: params.OnSubscribedChanged,
+		OnPLI:               params.OnPLI,
+		Logger:              t.params.Logger,
+	})
+
+	t.MediaTrackReceiver = NewMediaTrackReceiver(MediaTrackReceiverParams{
+		TrackInfo:           params.TrackInfo,
+		MediaTrack:          t,
+		IsRelayed:           true,
+		ParticipantID:       params.ParticipantID,
+		ParticipantIdentity: params.ParticipantIdentity,
+		ParticipantVersion:  params.ParticipantVersion,
+		ReceiverConfig:      params.ReceiverConfig,
+		SubscriberConfig:    params.SubscriberConfig,
+		AudioConfig:         params.AudioConfig,
+		Telemetry:           params.Telemetry,
+		Logger:              t.params.Logger,
+	})
+	t.MediaTrackReceiver.SetupReceiver(t.receiver, 0, "")
+	return t
+}
+
+func (t *RelayedTrack) ToProto() *livekit.TrackInfo {
+	info := t.MediaTrackReceiver.TrackInfo(false)
+	info.Muted = t.IsMuted()
+	return info
+}
+
+// RelayReceiver receives the packets relayed to this node
+func (t *RelayedTrack) RelayReceiver() *sfu.RelayReceiver {
+	return t.receiver
+}
+
+func (t *RelayedTrack) Close(willBeResumed bool) {
+	t.MediaTrackReceiver.SetClosing()
+	t.MediaTrackReceiver.ClearAllReceivers(willBeResumed)
+	t.receiver.Close()
+	t.MediaTrackReceiver.Close()
+}
+
+// RelaySource returns the receiver the media of a track is relayed to other nodes from and the codec it is relayed
+// with. Audio sent with redundancy is relayed as its primary encoding. Layers of scalable codecs are relayed in a
+// single stream, the receiving node takes them from the dependency descriptor relayed in the packets, see
+// RelayHeaderExtensions
+func RelaySource(track types.MediaTrack) (sfu.TrackReceiver, webrtc.RTPCodecParameters, bool) {
+	receivers := track.Receivers()
+	if len(receivers) == 0 {
+		return nil, webrtc.RTPCodecParameters{}, false
+	}
+
+	receiver := receivers[0]
+	codec := receiver.Codec()
+	if strings.EqualFold(codec.MimeType, sfu.MimeTypeAudioRed) {
+		return receiver.GetPrimaryReceiverForRed(), webrtc.RTPCodecParameters{RTPCodecCapability: opusCodecCapability, PayloadType: 111}, true
+	}
+	return receiver, codec, true
+}
+
+// RelayHeaderExtensions returns the header extensions of relayed packets the receiving node parses, the dependency
+// descriptor of scalable codecs
+func RelayHeaderExtensions(receiver sfu.TrackReceiver) []webrtc.RTPHeaderExtensionParameter {
+	if !sfu.IsSvcCodec(receiver.Codec().MimeType) {
+		return nil
+	}
+	for _, ext := range receiver.HeaderExtensions() {
+		if ext.URI == dd.ExtensionUrl {
+			return []webrtc.RTPHeaderExtensionParameter{ext}
+		}
+	}
+	return nil
+}
@@ -20,9 +20,12 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/profiler"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
 )
 
 const (
@@ -32,6 +35,9 @@ const (
 	subscriberUpdateInterval  = 3 * time.Second
 
 	dataForwardLoadBalanceThreshold = 20
+
+	// transcoded renditions are picked only when none of the published codecs can be used
+	renditionPriority = 100
 )
 
 var (
@@ -59,6 +65,10 @@ type Room struct {
 	telemetry      telemetry.TelemetryService
 	egressLauncher EgressLauncher
 	trackManager   *RoomTrackManager
+	slowStart      *SlowStart
+
+	// goroutines, sockets and timers of the room and its participants
+	resources *sutils.ResourceOwner
 
 	// map of identity -> Participant
 	participants              map[livekit.ParticipantIdentity]types.LocalParticipant
@@ -70,6 +80,17 @@ type Room struct {
 	batchedUpdates   map[livekit.ParticipantIdentity]*livekit.ParticipantInfo
 	batchedUpdatesMu sync.Mutex
 
+	// published once the first camera is, when enabled
+	activeSpeakerTrackParams *ActiveSpeakerTrackParams
+	activeSpeakerTrack       *ActiveSpeakerTrack
+	mixedAudioTrack          *MixedAudioTrack
+
+	// participants connected to other nodes hosting the room, by identity
+	relayedParticipants map[livekit.ParticipantIdentity]*relayedParticipant
+	isRelayEdge         atomic.Bool
+	onRelayData         func(dp *livekit.DataPacket)
+	onRelaySpeakers     func(speakers []*livekit.SpeakerInfo)
+
 	// time the first participant joined the room
 	joinedAt atomic.Int64
 	holds    atomic.Int32
@@ -104,14 +125,33 @@ func NewRoom(
 		telemetry:                 telemetry,
 		egressLauncher:            egressLauncher,
 		trackManager:              NewRoomTrackManager(),
+		resources:                 sutils.NewResourceOwner(room.Name, nil),
 		serverInfo:                serverInfo,
 		participants:              make(map[livekit.ParticipantIdentity]types.LocalParticipant),
 		participantOpts:           make(map[livekit.ParticipantIdentity]*ParticipantOptions),
 		participantRequestSources: make(map[livekit.ParticipantIdentity]routing.MessageSource),
-		bufferFactory:             buffer.NewFactoryOfBufferFactory(config.Receiver.PacketBufferSize),
 		batchedUpdates:            make(map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
+		relayedParticipants:       make(map[livekit.ParticipantIdentity]*relayedParticipant),
 		closed:                    make(chan struct{}),
 	}
+	r.slowStart = NewSlowStart(config.SlowStart, r.Logger)
+	r.bufferFactory = buffer.NewFactoryOfBufferFactory(
+		buffer.PoolConfig{
+			NumPackets: config.Receiver.PacketBufferSizeAudio,
+			Duration:   config.Receiver.PacketBufferDurationAudio,
+			// retransmissions of audio to subscribers are served from the buffer
+			MinPackets: config.Receiver.AudioNACKHistorySize,
+		},
+		buffer.PoolConfig{
+			NumPackets: config.Receiver.PacketBufferSize,
+			Duration:   config.Receiver.PacketBufferDurationVideo,
+		},
+		buffer.PoolConfig{
+			NumPackets: config.Receiver.PacketBufferSizeScreenShare,
+			Duration:   config.Receiver.PacketBufferDurationScreenShare,
+		},
+	)
+	r.bufferFactory.SetArrivalTimes(config.ArrivalTimes)
 	r.protoProxy = utils.NewProtoProxy[*livekit.Room](roomUpdateInterval, r.updateProto)
 	if r.protoRoom.EmptyTimeout == 0 {
 		r.protoRoom.EmptyTimeout = DefaultEmptyTimeout
@@ -120,9 +160,9 @@ func NewRoom(
 		r.protoRoom.CreationTime = time.Now().Unix()
 	}
 
-	go r.audioUpdateWorker()
-	go r.connectionQualityWorker()
-	go r.changeUpdateWorker()
+	r.resources.Go(r.withProfilingLabels(r.audioUpdateWorker))
+	r.resources.Go(r.withProfilingLabels(r.connectionQualityWorker))
+	r.resources.Go(r.withProfilingLabels(r.changeUpdateWorker))
 
 	return r
 }
@@ -187,6 +227,14 @@ func (r *Room) GetActiveSpeakers() []*livekit.SpeakerInfo {
 		})
 	}
 
+	r.lock.RLock()
+	for _, rp := range r.relayedParticipants {
+		if rp.speaker != nil {
+			speakers = append(speakers, proto.Clone(rp.speaker).(*livekit.SpeakerInfo))
+		}
+	}
+	r.lock.RUnlock()
+
 	sort.Slice(speakers, func(i, j int) bool {
 		return speakers[i].Level > speakers[j].Level
 	})
@@ -203,6 +251,12 @@ func (r *Room) GetBufferFactory() *buffer.Factory {
 	return r.bufferFactory.CreateBufferFactory()
 }
 
+// Resources is what the goroutines, sockets and timers of the room are accounted to, participants account theirs
+// to a child of it
+func (r *Room) Resources() *sutils.ResourceOwner {
+	return r.resources
+}
+
 func (r *Room) FirstJoinedAt() int64 {
 	return r.joinedAt.Load()
 }
@@ -242,6 +296,7 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 	if r.participants[participant.Identity()] != nil {
 		return ErrAlreadyJoined
 	}
+	r.slowStart.ParticipantJoined()
 
 	if r.protoRoom.MaxParticipants > 0 && !participant.IsRecorder() {
 		numParticipants := uint32(0)
@@ -286,7 +341,9 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 			})
 		} else if state == livekit.ParticipantInfo_DISCONNECTED {
 			// remove participant from room
-			go r.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonStateDisconnected)
+			r.resources.Go(func() {
+				r.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonStateDisconnected)
+			})
 		}
 	})
 	participant.OnTrackUpdated(r.onTrackUpdated)
@@ -349,7 +406,7 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 		r.onParticipantChanged(participant)
 	}
 
-	time.AfterFunc(time.Minute, func() {
+	r.resources.AfterFunc(time.Minute, func() {
 		state := participant.State()
 		if state == livekit.ParticipantInfo_JOINING || state == livekit.ParticipantInfo_JOINED {
 			r.RemoveParticipant(participant.Identity(), participant.ID(), types.ParticipantCloseReasonJoinTimeout)
@@ -367,10 +424,10 @@ func (r *Room) Join(participant types.LocalParticipant, requestSource routing.Me
 	if participant.SubscriberAsPrimary() {
 		// initiates sub connection as primary
 		if participant.ProtocolVersion().SupportFastStart() {
-			go func() {
+			r.resources.Go(func() {
 				r.subscribeToExistingTracks(participant)
 				participant.Negotiate(true)
-			}()
+			})
 		} else {
 			participant.Negotiate(true)
 		}
@@ -399,6 +456,7 @@ func (r *Room) GetParticipantRequestSource(identity livekit.ParticipantIdentity)
 
 func (r *Room) ResumeParticipant(p types.LocalParticipant, requestSource routing.MessageSource, responseSink routing.MessageSink, iceServers []*livekit.ICEServer, reason livekit.ReconnectReason) error {
 	r.ReplaceParticipantRequestSource(p.Identity(), requestSource)
+	r.slowStart.ParticipantJoined()
 	// close previous sink, and link to new one
 	p.CloseSignalConnection()
 	p.SetResponseSink(responseSink)
@@ -413,7 +471,13 @@ func (r *Room) ResumeParticipant(p types.LocalParticipant, requestSource routing
 	}
 
 	updates := ToProtoParticipants(r.GetParticipants())
-	if err := p.SendParticipantUpdate(updates); err != nil {
+	for _, st := range r.getServerTracks() {
+		updates = append(updates, st.ParticipantInfo())
+	}
+	r.lock.RLock()
+	updates = append(updates, r.getRelayedParticipantsLocked(true)...)
+	r.lock.RUnlock()
+	if err := p.SendResumeParticipantUpdate(updates); err != nil {
 		return err
 	}
 
@@ -537,6 +601,11 @@ func (r *Room) UpdateVideoLayers(participant types.Participant, updateVideoLayer
 	return participant.UpdateVideoLayers(updateVideoLayers)
 }
 
+// AdmitSubscription returns how long a new subscription of the given kind should be delayed during slow-start
+func (r *Room) AdmitSubscription(kind livekit.TrackType) time.Duration {
+	return r.slowStart.Admit(kind)
+}
+
 func (r *Room) ResolveMediaTrackForSubscriber(subIdentity livekit.ParticipantIdentity, trackID livekit.TrackID) types.MediaResolverResult {
 	res := types.MediaResolverResult{}
 
@@ -556,6 +625,18 @@ func (r *Room) ResolveMediaTrackForSubscriber(subIdentity livekit.ParticipantIde
 	// when publisher is not found, we will assume it doesn't have permission to access
 	if pub != nil {
 		res.HasPermission = pub.HasPermission(trackID, subIdentity)
+	} else {
+		for _, st := range r.getServerTracks() {
+			if st.ID() == trackID {
+				res.HasPermission = true
+			}
+		}
+		r.lock.RLock()
+		if r.isRelayedTrackLocked(trackID) {
+			// permissions of relayed tracks are enforced by the node the publisher is connected to
+			res.HasPermission = true
+		}
+		r.lock.RUnlock()
 	}
 
 	return res
@@ -585,6 +666,12 @@ func (r *Room) CloseIfEmpty() {
 			return
 		}
 	}
+	for _, rp := range r.relayedParticipants {
+		if !r.isRelayEdge.Load() && !rp.info.Permission.GetRecorder() {
+			r.lock.Unlock()
+			return
+		}
+	}
 
 	var timeout uint32
 	var elapsed int64
@@ -613,11 +700,29 @@ func (r *Room) Close() {
 		// fall through
 	}
 	close(r.closed)
+	serverTracks := r.getServerTracksLocked()
+	relayedTracks := r.getRelayedTracksLocked()
+	r.relayedParticipants = make(map[livekit.ParticipantIdentity]*relayedParticipant)
 	r.lock.Unlock()
 	r.Logger.Infow("closing room")
 	for _, p := range r.GetParticipants() {
 		_ = p.Close(true, types.ParticipantCloseReasonRoomClose)
 	}
+	for _, st := range serverTracks {
+		r.trackManager.RemoveTrack(st)
+		st.Close(false)
+	}
+	for _, rt := range relayedTracks {
+		r.trackManager.RemoveTrack(rt)
+		rt.Close(false)
+	}
+	audioPoolStats, videoPoolStats, screenSharePoolStats := r.bufferFactory.PoolStats()
+	r.Logger.Infow("buffer pool stats",
+		"audio", audioPoolStats.String(),
+		"video", videoPoolStats.String(),
+		"screenShare", screenSharePoolStats.String(),
+	)
+	r.Logger.Infow("room resources at close", "resources", r.resources.Stats())
 	r.protoProxy.Stop()
 	if r.onClose != nil {
 		r.onClose()
@@ -748,6 +853,10 @@ func (r *Room) createJoinResponseLocked(participant types.LocalParticipant, iceS
 			otherParticipants = append(otherParticipants, p.ToProto())
 		}
 	}
+	for _, st := range r.getServerTracksLocked() {
+		otherParticipants = append(otherParticipants, st.ParticipantInfo())
+	}
+	otherParticipants = append(otherParticipants, r.getRelayedParticipantsLocked(true)...)
 
 	return &livekit.JoinResponse{
 		Room:              r.ToProto(),
@@ -837,6 +946,210 @@ func (r *Room) onTrackUnpublished(p types.LocalParticipant, track types.MediaTra
 	}
 }
 
+// EnableActiveSpeakerTrack publishes a video track following the loudest speaker once a camera is published
+func (r *Room) EnableActiveSpeakerTrack(conf config.ActiveSpeakerTrackConfig) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.activeSpeakerTrackParams = &ActiveSpeakerTrackParams{
+		Config:           conf,
+		ReceiverConfig:   r.config.Receiver,
+		SubscriberConfig: r.config.Subscriber,
+		AudioConfig:      *r.audioConfig,
+		Telemetry:        r.telemetry,
+		GetCamera:        r.getCamera,
+		Logger:           r.Logger,
+	}
+}
+
+// EnableMixedAudioTrack publishes an audio track mixing the audio of the participants
+func (r *Room) EnableMixedAudioTrack(conf config.MixedAudioTrackConfig) error {
+	track, err := NewMixedAudioTrack(MixedAudioTrackParams{
+		Config:           conf,
+		ReceiverConfig:   r.config.Receiver,
+		SubscriberConfig: r.config.Subscriber,
+		AudioConfig:      *r.audioConfig,
+		Telemetry:        r.telemetry,
+		Logger:           r.Logger,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	if r.mixedAudioTrack != nil || r.IsClosed() {
+		r.lock.Unlock()
+		track.Close(false)
+		return nil
+	}
+	r.mixedAudioTrack = track
+	r.lock.Unlock()
+
+	r.Logger.Infow("publishing mixed audio track", "trackID", track.ID())
+	r.trackManager.AddTrack(track, track.PublisherIdentity(), track.PublisherID())
+	r.sendParticipantUpdates([]*livekit.ParticipantInfo{track.ParticipantInfo()})
+	return nil
+}
+
+// serverTrack is a track published by the server in the name of a participant of its own
+type serverTrack interface {
+	types.MediaTrack
+	ParticipantInfo() *livekit.ParticipantInfo
+}
+
+func (r *Room) getServerTracks() []serverTrack {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.getServerTracksLocked()
+}
+
+func (r *Room) getServerTracksLocked() []serverTrack {
+	var tracks []serverTrack
+	if r.activeSpeakerTrack != nil {
+		tracks = append(tracks, r.activeSpeakerTrack)
+	}
+	if r.mixedAudioTrack != nil {
+		tracks = append(tracks, r.mixedAudioTrack)
+	}
+	return tracks
+}
+
+func (r *Room) getCamera(participantID livekit.ParticipantID) types.MediaTrack {
+	p := r.GetParticipantByID(participantID)
+	if p == nil || p.Hidden() {
+		return nil
+	}
+	for _, track := range p.GetPublishedTracks() {
+		if track.Kind() == livekit.TrackType_VIDEO && track.Source() == livekit.TrackSource_CAMERA {
+			return track
+		}
+	}
+	return nil
+}
+
+func (r *Room) updateActiveSpeakerTrack(speakers []*livekit.SpeakerInfo) {
+	r.lock.RLock()
+	params, track := r.activeSpeakerTrackParams, r.activeSpeakerTrack
+	r.lock.RUnlock()
+	if params == nil {
+		return
+	}
+
+	if track == nil {
+		for _, p := range r.GetParticipants() {
+			if camera := r.getCamera(p.ID()); camera != nil {
+				track = NewActiveSpeakerTrack(*params, camera)
+			}
+			if track != nil {
+				break
+			}
+		}
+		if track == nil {
+			return
+		}
+
+		r.lock.Lock()
+		if r.IsClosed() {
+			r.lock.Unlock()
+			track.Close(false)
+			return
+		}
+		r.activeSpeakerTrack = track
+		r.lock.Unlock()
+
+		r.Logger.Infow("publishing active speaker track", "trackID", track.ID(), "mime", track.ToProto().MimeType)
+		r.trackManager.AddTrack(track, track.PublisherIdentity(), track.PublisherID())
+		r.sendParticipantUpdates([]*livekit.ParticipantInfo{track.ParticipantInfo()})
+	}
+
+	track.UpdateSpeakers(speakers)
+}
+
+// updateMixedAudioTrack mixes the audio of the participants publishing it, a participant's first audio track other
+// than screen share audio
+func (r *Room) updateMixedAudioTrack() {
+	r.lock.RLock()
+	track := r.mixedAudioTrack
+	r.lock.RUnlock()
+	if track == nil {
+		return
+	}
+
+	inputs := make(map[livekit.ParticipantID]sfu.TrackReceiver)
+	for _, p := range r.GetParticipants() {
+		if p.Hidden() {
+			continue
+		}
+		for _, pt := range p.GetPublishedTracks() {
+			if pt.Kind() != livekit.TrackType_AUDIO || pt.Source() == livekit.TrackSource_SCREEN_SHARE_AUDIO {
+				continue
+			}
+			if mt, ok := pt.(*MediaTrack); ok {
+				if receiver := mt.PrimaryReceiver(); receiver != nil {
+					inputs[p.ID()] = receiver
+					break
+				}
+			}
+		}
+	}
+	track.SetInputs(inputs)
+}
+
+// LinkRendition attaches a transcoded rendition of a track as an additional receiver, so that subscribers
+// which cannot decode the source codec are forwarded the rendition
+func (r *Room) LinkRendition(trackID livekit.TrackID, renditionTrackID livekit.TrackID) error {
+	var source, rendition *MediaTrack
+	if info := r.trackManager.GetTrackInfo(trackID); info != nil {
+		source, _ = info.Track.(*MediaTrack)
+	}
+	if info := r.trackManager.GetTrackInfo(renditionTrackID); info != nil {
+		rendition, _ = info.Track.(*MediaTrack)
+	}
+	if source == nil || rendition == nil {
+		return ErrTrackNotFound
+	}
+
+	receiver := rendition.PrimaryReceiver()
+	if receiver == nil {
+		return ErrNoReceiver
+	}
+
+	mime := receiver.Codec().MimeType
+	r.Logger.Infow("linking transcoded rendition",
+		"trackID", trackID,
+		"renditionTrackID", renditionTrackID,
+		"mime", mime,
+	)
+	source.SetupReceiver(receiver, renditionPriority, "")
+	rendition.AddOnClose(func() {
+		source.ClearReceiver(mime, false)
+	})
+	return nil
+}
+
+// LinkGeneratedLayer attaches a track published by a transcoder worker as a lower simulcast layer of a single layer
+// track
+func (r *Room) LinkGeneratedLayer(trackID livekit.TrackID, renditionTrackID livekit.TrackID, quality livekit.VideoQuality) error {
+	var source, rendition *MediaTrack
+	if info := r.trackManager.GetTrackInfo(trackID); info != nil {
+		source, _ = info.Track.(*MediaTrack)
+	}
+	if info := r.trackManager.GetTrackInfo(renditionTrackID); info != nil {
+		rendition, _ = info.Track.(*MediaTrack)
+	}
+	if source == nil || rendition == nil {
+		return ErrTrackNotFound
+	}
+
+	r.Logger.Infow("linking generated layer",
+		"trackID", trackID,
+		"renditionTrackID", renditionTrackID,
+		"quality", quality,
+	)
+	return source.AddGeneratedLayer(quality, rendition)
+}
+
 func (r *Room) onParticipantUpdate(p types.LocalParticipant) {
 	// immediately notify when permissions or metadata changed
 	r.broadcastParticipantState(p, broadcastOptions{immediate: true})
@@ -847,6 +1160,13 @@ func (r *Room) onParticipantUpdate(p types.LocalParticipant) {
 
 func (r *Room) onDataPacket(source types.LocalParticipant, dp *livekit.DataPacket) {
 	BroadcastDataPacketForRoom(r, source, dp, r.Logger)
+
+	r.lock.RLock()
+	onRelayData := r.onRelayData
+	r.lock.RUnlock()
+	if onRelayData != nil {
+		onRelayData(dp)
+	}
 }
 
 func (r *Room) subscribeToExistingTracks(p types.LocalParticipant) {
@@ -870,6 +1190,13 @@ func (r *Room) subscribeToExistingTracks(p types.LocalParticipant) {
 			p.SubscribeToTrack(track.ID())
 		}
 	}
+	r.lock.RLock()
+	relayedTracks := r.getRelayedTracksLocked()
+	r.lock.RUnlock()
+	for _, track := range relayedTracks {
+		trackIDs = append(trackIDs, track.ID())
+		p.SubscribeToTrack(track.ID())
+	}
 	if len(trackIDs) > 0 {
 		r.Logger.Debugw("subscribed participant to existing tracks", "trackID", trackIDs)
 	}
@@ -1012,9 +1339,18 @@ func (r *Room) updateProto() *livekit.Room {
 	return room
 }
 
+// withProfilingLabels wraps a room worker to run with its goroutine labelled by room, for continuous profiling
+func (r *Room) withProfilingLabels(worker func()) func() {
+	return func() {
+		profiler.SetGoroutineLabels("room", string(r.Name()))
+		worker()
+	}
+}
+
 func (r *Room) changeUpdateWorker() {
 	subTicker := time.NewTicker(subscriberUpdateInterval)
 	defer subTicker.Stop()
+	defer prometheus.DeleteRoomResources(r.Name())
 
 	for !r.IsClosed() {
 		select {
@@ -1026,6 +1362,8 @@ func (r *Room) changeUpdateWorker() {
 			}
 			r.sendRoomUpdate()
 		case <-subTicker.C:
+			prometheus.SetRoomResources(r.Name(), r.resources.Stats())
+
 			r.batchedUpdatesMu.Lock()
 			updatesMap := r.batchedUpdates
 			r.batchedUpdates = make(map[livekit.ParticipantIdentity]*livekit.ParticipantInfo)
@@ -1076,7 +1414,10 @@ func (r *Room) audioUpdateWorker() {
 		if len(changedSpeakers) > 0 {
 			r.sendActiveSpeakers(activeSpeakers)
 			r.sendSpeakerChanges(changedSpeakers)
+			r.relaySpeakerChanges(changedSpeakers)
 		}
+		r.updateActiveSpeakerTrack(activeSpeakers)
+		r.updateMixedAudioTrack()
 
 		lastActiveMap = nextActiveMap
 
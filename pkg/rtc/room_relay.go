@@ -0,0 +1,243 @@
+package rtc
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+// relayedParticipant is a participant of the room connected to another node hosting it
+type relayedParticipant struct {
+	info   *livekit.ParticipantInfo
+	tracks map[livekit.TrackID]*RelayedTrack
+	// nil while not speaking
+	speaker *livekit.SpeakerInfo
+}
+
+// SetRelayEdge marks the room as placed on another node, which relays the participants of the room to this node.
+// Relayed participants then do not keep the room open
+func (r *Room) SetRelayEdge() {
+	r.isRelayEdge.Store(true)
+}
+
+// OnRelayData sets the callback data packets sent to the room on this node are passed to, by its participants or by
+// the server, for relaying them to the other nodes hosting the room
+func (r *Room) OnRelayData(f func(dp *livekit.DataPacket)) {
+	r.lock.Lock()
+	r.onRelayData = f
+	r.lock.Unlock()
+}
+
+// OnRelaySpeakers sets the callback changes of active speakers connected to this node are passed to
+func (r *Room) OnRelaySpeakers(f func(speakers []*livekit.SpeakerInfo)) {
+	r.lock.Lock()
+	r.onRelaySpeakers = f
+	r.lock.Unlock()
+}
+
+// SendRelayedDataPacket sends a data packet relayed from another node hosting the room to the participants of this
+// node
+func (r *Room) SendRelayedDataPacket(dp *livekit.DataPacket) {
+	BroadcastDataPacketForRoom(r, nil, dp, r.Logger)
+}
+
+// UpdateRelayedSpeakers applies changes of active speakers connected to other nodes hosting the room, they are sent
+// to the participants of this node along with the speakers of this node
+func (r *Room) UpdateRelayedSpeakers(speakers []*livekit.SpeakerInfo) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, speaker := range speakers {
+		for _, rp := range r.relayedParticipants {
+			if rp.info.Sid != speaker.Sid {
+				continue
+			}
+			if speaker.Active {
+				rp.speaker = speaker
+			} else {
+				rp.speaker = nil
+			}
+			break
+		}
+	}
+}
+
+// relaySpeakerChanges passes on the changes of active speakers connected to this node
+func (r *Room) relaySpeakerChanges(changedSpeakers []*livekit.SpeakerInfo) {
+	r.lock.RLock()
+	onRelaySpeakers := r.onRelaySpeakers
+	r.lock.RUnlock()
+	if onRelaySpeakers == nil {
+		return
+	}
+
+	var speakers []*livekit.SpeakerInfo
+	for _, speaker := range changedSpeakers {
+		if r.GetParticipantByID(livekit.ParticipantID(speaker.Sid)) != nil {
+			speakers = append(speakers, speaker)
+		}
+	}
+	if len(speakers) != 0 {
+		onRelaySpeakers(speakers)
+	}
+}
+
+// UpdateRelayedParticipant updates a participant connected to another node hosting the room, sending the update to
+// the participants of this node
+func (r *Room) UpdateRelayedParticipant(info *livekit.ParticipantInfo) {
+	identity := livekit.ParticipantIdentity(info.Identity)
+	if info.State == livekit.ParticipantInfo_DISCONNECTED {
+		r.RemoveRelayedParticipant(identity)
+		return
+	}
+
+	r.lock.Lock()
+	if r.IsClosed() || r.participants[identity] != nil {
+		r.lock.Unlock()
+		return
+	}
+	rp := r.relayedParticipants[identity]
+	if rp == nil {
+		rp = &relayedParticipant{tracks: make(map[livekit.TrackID]*RelayedTrack)}
+		r.relayedParticipants[identity] = rp
+	} else if rp.info.Sid == info.Sid && rp.info.Version > info.Version {
+		r.lock.Unlock()
+		return
+	}
+	rp.info = info
+	r.lock.Unlock()
+
+	if !info.Permission.GetHidden() {
+		r.sendParticipantUpdates([]*livekit.ParticipantInfo{info})
+	}
+}
+
+// AddRelayedTrack adds a track published by a relayed participant, subscribing the participants of this node to it
+func (r *Room) AddRelayedTrack(params RelayedTrackParams) (*RelayedTrack, error) {
+	params.ReceiverConfig = r.config.Receiver
+	params.SubscriberConfig = r.config.Subscriber
+	params.AudioConfig = *r.audioConfig
+	params.Telemetry = r.telemetry
+	params.Logger = r.Logger
+
+	r.lock.Lock()
+	rp := r.relayedParticipants[params.ParticipantIdentity]
+	if r.IsClosed() || rp == nil {
+		r.lock.Unlock()
+		return nil, ErrParticipantNotFound
+	}
+	trackID := livekit.TrackID(params.TrackInfo.Sid)
+	if track := rp.tracks[trackID]; track != nil {
+		r.lock.Unlock()
+		return track, nil
+	}
+	track := NewRelayedTrack(params)
+	rp.tracks[trackID] = track
+
+	var subscribers []types.LocalParticipant
+	for _, p := range r.participants {
+		if p.State() == livekit.ParticipantInfo_ACTIVE && r.autoSubscribe(p) {
+			subscribers = append(subscribers, p)
+		}
+	}
+	r.lock.Unlock()
+
+	r.Logger.Debugw("adding relayed track", "trackID", trackID, "participant", params.ParticipantIdentity)
+	r.trackManager.AddTrack(track, params.ParticipantIdentity, params.ParticipantID)
+	for _, p := range subscribers {
+		p.SubscribeToTrack(trackID)
+	}
+	return track, nil
+}
+
+// RemoveRelayedTrack removes a track no longer published by a relayed participant
+func (r *Room) RemoveRelayedTrack(identity livekit.ParticipantIdentity, trackID livekit.TrackID) {
+	r.lock.Lock()
+	var track *RelayedTrack
+	if rp := r.relayedParticipants[identity]; rp != nil {
+		track = rp.tracks[trackID]
+		delete(rp.tracks, trackID)
+	}
+	r.lock.Unlock()
+
+	if track != nil {
+		r.trackManager.RemoveTrack(track)
+		track.Close(false)
+	}
+}
+
+// RemoveRelayedParticipant removes a participant that left another node hosting the room
+func (r *Room) RemoveRelayedParticipant(identity livekit.ParticipantIdentity) {
+	r.lock.Lock()
+	rp := r.relayedParticipants[identity]
+	delete(r.relayedParticipants, identity)
+	r.lock.Unlock()
+	if rp == nil {
+		return
+	}
+
+	for _, track := range rp.tracks {
+		r.trackManager.RemoveTrack(track)
+		track.Close(false)
+	}
+
+	if !rp.info.Permission.GetHidden() {
+		info := proto.Clone(rp.info).(*livekit.ParticipantInfo)
+		info.State = livekit.ParticipantInfo_DISCONNECTED
+		r.sendParticipantUpdates([]*livekit.ParticipantInfo{info})
+	}
+	r.leftAt.Store(time.Now().Unix())
+}
+
+// GetRelayedParticipants returns the participants connected to other nodes hosting the room
+func (r *Room) GetRelayedParticipants() []*livekit.ParticipantInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.getRelayedParticipantsLocked(false)
+}
+
+// GetRelayedTrack returns a track of a relayed participant, nil when not relayed
+func (r *Room) GetRelayedTrack(identity livekit.ParticipantIdentity, trackID livekit.TrackID) *RelayedTrack {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if rp := r.relayedParticipants[identity]; rp != nil {
+		return rp.tracks[trackID]
+	}
+	return nil
+}
+
+func (r *Room) getRelayedParticipantsLocked(skipHidden bool) []*livekit.ParticipantInfo {
+	infos := make([]*livekit.ParticipantInfo, 0, len(r.relayedParticipants))
+	for _, rp := range r.relayedParticipants {
+		if skipHidden && rp.info.Permission.GetHidden() {
+			continue
+		}
+		infos = append(infos, rp.info)
+	}
+	return infos
+}
+
+func (r *Room) isRelayedTrackLocked(trackID livekit.TrackID) bool {
+	for _, rp := range r.relayedParticipants {
+		if rp.tracks[trackID] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Room) getRelayedTracksLocked() []*RelayedTrack {
+	var tracks []*RelayedTrack
+	for _, rp := range r.relayedParticipants {
+		for _, track := range rp.tracks {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 
@@ -407,6 +408,60 @@ func TestNewTrack(t *testing.T) {
 	})
 }
 
+func TestRelayedParticipants(t *testing.T) {
+	rm := newRoomWithParticipants(t, testRoomOpts{num: 2})
+	p0 := rm.GetParticipants()[0].(*typesfakes.FakeLocalParticipant)
+	updates := p0.SendParticipantUpdateCallCount()
+
+	info := &livekit.ParticipantInfo{
+		Sid:      "PA_relayed",
+		Identity: "relayed",
+		State:    livekit.ParticipantInfo_ACTIVE,
+		Version:  1,
+		Tracks:   []*livekit.TrackInfo{{Sid: "TR_relayed", Type: livekit.TrackType_AUDIO}},
+	}
+	rm.UpdateRelayedParticipant(info)
+	require.Equal(t, updates+1, p0.SendParticipantUpdateCallCount())
+	require.Equal(t, []*livekit.ParticipantInfo{info}, p0.SendParticipantUpdateArgsForCall(updates))
+
+	// tracks are only added for participants known to be relayed
+	_, err := rm.AddRelayedTrack(RelayedTrackParams{TrackInfo: &livekit.TrackInfo{Sid: "TR_other"}, ParticipantIdentity: "other"})
+	require.ErrorIs(t, err, ErrParticipantNotFound)
+
+	subscribed := p0.SubscribeToTrackCallCount()
+	track, err := rm.AddRelayedTrack(RelayedTrackParams{
+		TrackInfo:           info.Tracks[0],
+		ParticipantID:       "PA_relayed",
+		ParticipantIdentity: "relayed",
+		Codec:               webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000}, PayloadType: 111},
+	})
+	require.NoError(t, err)
+	require.Equal(t, subscribed+1, p0.SubscribeToTrackCallCount())
+	require.Equal(t, livekit.TrackID("TR_relayed"), p0.SubscribeToTrackArgsForCall(subscribed))
+	require.Equal(t, track, rm.GetRelayedTrack("relayed", "TR_relayed"))
+
+	res := rm.ResolveMediaTrackForSubscriber(p0.Identity(), "TR_relayed")
+	require.Equal(t, track, res.Track)
+	require.True(t, res.HasPermission)
+
+	joinResponse := rm.createJoinResponseLocked(p0, nil)
+	require.Contains(t, joinResponse.OtherParticipants, info)
+
+	// relayed participants keep the room open
+	for _, p := range rm.GetParticipants() {
+		rm.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonClientRequestLeave)
+	}
+	rm.protoRoom.EmptyTimeout = 0
+	rm.leftAt.Store(time.Now().Unix() - int64(RoomDepartureGrace))
+	rm.CloseIfEmpty()
+	require.False(t, rm.IsClosed())
+
+	rm.UpdateRelayedParticipant(&livekit.ParticipantInfo{Sid: "PA_relayed", Identity: "relayed", State: livekit.ParticipantInfo_DISCONNECTED})
+	require.Empty(t, rm.GetRelayedParticipants())
+	require.Nil(t, rm.GetRelayedTrack("relayed", "TR_relayed"))
+	require.True(t, track.RelayReceiver().IsClosed())
+}
+
 func TestActiveSpeakers(t *testing.T) {
 	t.Parallel()
 	getActiveSpeakerUpdates := func(p *typesfakes.FakeLocalParticipant) [][]*livekit.SpeakerInfo {
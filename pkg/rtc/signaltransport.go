@@ -0,0 +1,9 @@
+package rtc
+
+// SignalTransport is the kind of connection carrying the signal messages of a participant session
+type SignalTransport string
+
+const (
+	SignalTransportWebSocket    SignalTransport = "websocket"
+	SignalTransportWebTransport SignalTransport = "webtransport"
+)
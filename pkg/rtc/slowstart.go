@@ -0,0 +1,104 @@
+package rtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	defaultSlowStartJoinThreshold          = 10
+	defaultSlowStartJoinWindow             = 5 * time.Second
+	defaultSlowStartDuration               = 30 * time.Second
+	defaultSlowStartSubscriptionsPerSecond = 20
+)
+
+// SlowStart is a room-wide admission scheduler. When many participants (re)connect at once, every new video
+// subscription makes the publisher send a keyframe and ramps up its bitrate, spacing subscriptions out spreads
+// those keyframe requests over time instead of hitting publishers all at once.
+type SlowStart struct {
+	conf   config.SlowStartConfig
+	logger logger.Logger
+
+	lock     sync.Mutex
+	joins    []time.Time
+	until    time.Time
+	nextSlot time.Time
+}
+
+func NewSlowStart(conf config.SlowStartConfig, logger logger.Logger) *SlowStart {
+	if conf.JoinThreshold <= 0 {
+		conf.JoinThreshold = defaultSlowStartJoinThreshold
+	}
+	if conf.JoinWindow <= 0 {
+		conf.JoinWindow = defaultSlowStartJoinWindow
+	}
+	if conf.Duration <= 0 {
+		conf.Duration = defaultSlowStartDuration
+	}
+	if conf.SubscriptionsPerSecond <= 0 {
+		conf.SubscriptionsPerSecond = defaultSlowStartSubscriptionsPerSecond
+	}
+	return &SlowStart{
+		conf:   conf,
+		logger: logger,
+	}
+}
+
+// ParticipantJoined records a participant joining or resuming, starting slow-start on a burst
+func (s *SlowStart) ParticipantJoined() {
+	if !s.conf.Enabled {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	expired := 0
+	for expired < len(s.joins) && now.Sub(s.joins[expired]) > s.conf.JoinWindow {
+		expired++
+	}
+	s.joins = append(s.joins[expired:], now)
+
+	if len(s.joins) >= s.conf.JoinThreshold {
+		if !now.Before(s.until) {
+			s.logger.Infow("starting subscription slow-start", "joins", len(s.joins), "window", s.conf.JoinWindow)
+		}
+		s.until = now.Add(s.conf.Duration)
+	}
+}
+
+func (s *SlowStart) IsActive() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return time.Now().Before(s.until)
+}
+
+// Admit reserves a slot for a subscription, returning how long the subscriber has to wait before subscribing.
+// Only video is paced.
+func (s *SlowStart) Admit(kind livekit.TrackType) time.Duration {
+	if kind != livekit.TrackType_VIDEO {
+		return 0
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if !now.Before(s.until) {
+		return 0
+	}
+
+	slot := s.nextSlot
+	if slot.Before(now) {
+		slot = now
+	}
+	s.nextSlot = slot.Add(time.Second / time.Duration(s.conf.SubscriptionsPerSecond))
+	return slot.Sub(now)
+}
@@ -0,0 +1,61 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestSlowStart(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		s := NewSlowStart(config.SlowStartConfig{JoinThreshold: 1}, logger.GetLogger())
+		s.ParticipantJoined()
+		require.False(t, s.IsActive())
+		require.Zero(t, s.Admit(livekit.TrackType_VIDEO))
+	})
+
+	t.Run("paces video after a burst of joins", func(t *testing.T) {
+		s := NewSlowStart(config.SlowStartConfig{
+			Enabled:                true,
+			JoinThreshold:          3,
+			SubscriptionsPerSecond: 10,
+		}, logger.GetLogger())
+
+		s.ParticipantJoined()
+		s.ParticipantJoined()
+		require.False(t, s.IsActive())
+		require.Zero(t, s.Admit(livekit.TrackType_VIDEO))
+
+		s.ParticipantJoined()
+		require.True(t, s.IsActive())
+		require.Zero(t, s.Admit(livekit.TrackType_AUDIO))
+
+		require.Zero(t, s.Admit(livekit.TrackType_VIDEO))
+		require.InDelta(t, 100*time.Millisecond, s.Admit(livekit.TrackType_VIDEO), float64(5*time.Millisecond))
+		require.InDelta(t, 200*time.Millisecond, s.Admit(livekit.TrackType_VIDEO), float64(5*time.Millisecond))
+	})
+
+	t.Run("joins outside of the window do not count", func(t *testing.T) {
+		s := NewSlowStart(config.SlowStartConfig{
+			Enabled:       true,
+			JoinThreshold: 2,
+			JoinWindow:    20 * time.Millisecond,
+			Duration:      50 * time.Millisecond,
+		}, logger.GetLogger())
+
+		s.ParticipantJoined()
+		time.Sleep(30 * time.Millisecond)
+		s.ParticipantJoined()
+		require.False(t, s.IsActive())
+
+		s.ParticipantJoined()
+		require.True(t, s.IsActive())
+		require.Eventually(t, func() bool { return !s.IsActive() }, time.Second, 10*time.Millisecond)
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
+	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
@@ -28,6 +29,7 @@ type SubscribedTrackParams struct {
 	MediaTrack        types.MediaTrack
 	DownTrack         *sfu.DownTrack
 	AdaptiveStream    bool
+	DeviceHints       routing.DeviceHints
 }
 
 type SubscribedTrack struct {
@@ -93,17 +95,16 @@ func (t *SubscribedTrack) Bound() {
 		//    time of subscription, we might not be able to trigger adaptive stream updates on the client side
 		//    (since there isn't any video frames coming through). this will leave the stream "stuck" on off, without
 		//    a trigger to re-enable it
-		var desiredLayer int32
-		if t.params.AdaptiveStream {
-			desiredLayer = buffer.VideoQualityToSpatialLayer(livekit.VideoQuality_LOW, t.params.MediaTrack.ToProto())
-		} else {
-			desiredLayer = buffer.VideoQualityToSpatialLayer(livekit.VideoQuality_HIGH, t.params.MediaTrack.ToProto())
-		}
+		// Without adaptive stream, the device of the subscriber bounds the quality until it sends its settings
+		desiredLayer := t.initialSpatialLayer()
 		settings := t.settings.Load()
 		if settings != nil {
 			desiredLayer = t.spatialLayerFromSettings(settings)
 		}
-		t.DownTrack().SetMaxSpatialLayer(desiredLayer)
+		t.DownTrack().SetMaxSpatialLayer(t.limitSpatialLayerForLoad(desiredLayer))
+		if t.params.Subscriber.IsAudioOnlyDownlink() || t.params.MediaTrack.LoadSheddingQuality() == livekit.VideoQuality_OFF {
+			t.updateDownTrackMute()
+		}
 	}
 
 	for _, cb := range callbacks {
@@ -196,6 +197,10 @@ func (t *SubscribedTrack) UpdateVideoLayer() {
 
 	settings := t.settings.Load()
 	if settings == nil {
+		// the layer set when bound, limited by load shedding
+		if t.IsBound() {
+			t.DownTrack().SetMaxSpatialLayer(t.limitSpatialLayerForLoad(t.initialSpatialLayer()))
+		}
 		return
 	}
 
@@ -203,7 +208,7 @@ func (t *SubscribedTrack) UpdateVideoLayer() {
 		"settings", settings,
 	)
 
-	spatial := t.spatialLayerFromSettings(settings)
+	spatial := t.limitSpatialLayerForLoad(t.spatialLayerFromSettings(settings))
 	t.DownTrack().SetMaxSpatialLayer(spatial)
 	if settings.Fps > 0 {
 		t.DownTrack().SetMaxTemporalLayer(t.MediaTrack().GetTemporalLayerForSpatialFps(spatial, settings.Fps, t.DownTrack().Codec().MimeType))
@@ -227,10 +232,58 @@ func (t *SubscribedTrack) SetRTPSender(sender *webrtc.RTPSender) {
 }
 
 func (t *SubscribedTrack) updateDownTrackMute() {
-	t.DownTrack().Mute(t.subMuted.Load())
+	muted := t.subMuted.Load()
+	if t.DownTrack().Kind() == webrtc.RTPCodecTypeVideo &&
+		(t.params.Subscriber.IsAudioOnlyDownlink() || t.params.MediaTrack.LoadSheddingQuality() == livekit.VideoQuality_OFF) {
+		// the subscriber settings are kept for when the audio only downlink is cleared or the track is resumed
+		muted = true
+	}
+	t.DownTrack().Mute(muted)
 	t.DownTrack().PubMute(t.pubMuted.Load())
 }
 
+// initialQualityForDevice is the highest quality worth sending to a device before any bandwidth is estimated:
+// low on battery saver, no more than what fits its screen, no more than medium on small devices
+func initialQualityForDevice(hints routing.DeviceHints, track types.MediaTrack) livekit.VideoQuality {
+	if hints.BatterySaver || hints.DeviceClass == routing.DeviceClassWatch {
+		return livekit.VideoQuality_LOW
+	}
+
+	quality := livekit.VideoQuality_HIGH
+	if hints.ScreenWidth > 0 && hints.ScreenHeight > 0 {
+		// video is mostly landscape, fitting it to the screen in any orientation
+		width, height := hints.ScreenWidth, hints.ScreenHeight
+		if width < height {
+			width, height = height, width
+		}
+		quality = track.GetQualityForDimension(width, height)
+	}
+	if hints.DeviceClass == routing.DeviceClassMobile && quality > livekit.VideoQuality_MEDIUM {
+		quality = livekit.VideoQuality_MEDIUM
+	}
+	return quality
+}
+
+func (t *SubscribedTrack) initialSpatialLayer() int32 {
+	quality := livekit.VideoQuality_LOW
+	if !t.params.AdaptiveStream {
+		quality = initialQualityForDevice(t.params.DeviceHints, t.params.MediaTrack)
+	}
+	return buffer.VideoQualityToSpatialLayer(quality, t.params.MediaTrack.ToProto())
+}
+
+// limitSpatialLayerForLoad lowers a layer to the quality forwarded while the node sheds load
+func (t *SubscribedTrack) limitSpatialLayerForLoad(layer int32) int32 {
+	quality := t.params.MediaTrack.LoadSheddingQuality()
+	if quality == livekit.VideoQuality_HIGH || quality == livekit.VideoQuality_OFF {
+		return layer
+	}
+	if limit := buffer.VideoQualityToSpatialLayer(quality, t.params.MediaTrack.ToProto()); limit < layer {
+		return limit
+	}
+	return layer
+}
+
 func (t *SubscribedTrack) spatialLayerFromSettings(settings *livekit.UpdateTrackSettings) int32 {
 	quality := settings.Quality
 	if settings.Width > 0 {
@@ -0,0 +1,40 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+)
+
+func TestInitialQualityForDevice(t *testing.T) {
+	track := &typesfakes.FakeMediaTrack{}
+	track.GetQualityForDimensionStub = func(width, height uint32) livekit.VideoQuality {
+		switch {
+		case height <= 360:
+			return livekit.VideoQuality_LOW
+		case height <= 720:
+			return livekit.VideoQuality_MEDIUM
+		default:
+			return livekit.VideoQuality_HIGH
+		}
+	}
+
+	require.Equal(t, livekit.VideoQuality_HIGH, initialQualityForDevice(routing.DeviceHints{}, track))
+	require.Equal(t, livekit.VideoQuality_LOW, initialQualityForDevice(routing.DeviceHints{BatterySaver: true}, track))
+	require.Equal(t, livekit.VideoQuality_LOW, initialQualityForDevice(routing.DeviceHints{DeviceClass: routing.DeviceClassWatch}, track))
+	require.Equal(t, livekit.VideoQuality_MEDIUM, initialQualityForDevice(routing.DeviceHints{DeviceClass: routing.DeviceClassMobile}, track))
+	require.Equal(t, livekit.VideoQuality_HIGH, initialQualityForDevice(routing.DeviceHints{DeviceClass: routing.DeviceClassTV}, track))
+
+	// screens in portrait fit landscape video by their width
+	require.Equal(t, livekit.VideoQuality_MEDIUM, initialQualityForDevice(routing.DeviceHints{ScreenWidth: 720, ScreenHeight: 1280}, track))
+	width, height := track.GetQualityForDimensionArgsForCall(0)
+	require.Equal(t, uint32(1280), width)
+	require.Equal(t, uint32(720), height)
+	require.Equal(t, livekit.VideoQuality_HIGH, initialQualityForDevice(routing.DeviceHints{ScreenWidth: 2560, ScreenHeight: 1440}, track))
+	require.Equal(t, livekit.VideoQuality_MEDIUM, initialQualityForDevice(routing.DeviceHints{ScreenWidth: 1170, ScreenHeight: 2532, DeviceClass: routing.DeviceClassMobile}, track))
+}
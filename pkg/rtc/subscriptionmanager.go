@@ -22,11 +22,15 @@ import (
 	"time"
 
 	"github.com/pion/webrtc/v3/pkg/rtcerr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/tracing"
+	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 )
@@ -56,6 +60,13 @@ type SubscriptionManagerParams struct {
 	Telemetry           telemetry.TelemetryService
 
 	SubscriptionLimitVideo, SubscriptionLimitAudio int32
+
+	// optional, returns how long a new subscription has to wait before being set up
+	AdmitSubscription func(kind livekit.TrackType) time.Duration
+	// optional, the reconcile worker and timers are accounted to it
+	Resources *utils.ResourceOwner
+	// subscriptions are traced till bound as children of it, when the session of the participant is traced
+	TraceContext trace.SpanContext
 }
 
 // SubscriptionManager manages a participant's subscriptions
@@ -71,6 +82,9 @@ type SubscriptionManager struct {
 	closeCh      chan struct{}
 	doneCh       chan struct{}
 
+	// last time tracks were subscribed to or unsubscribed from, or their settings changed
+	desiredChangedAt time.Time
+
 	onSubscribeStatusChanged func(publisherID livekit.ParticipantID, subscribed bool)
 }
 
@@ -84,7 +98,7 @@ func NewSubscriptionManager(params SubscriptionManagerParams) *SubscriptionManag
 		doneCh:        make(chan struct{}),
 	}
 
-	go m.reconcileWorker()
+	m.params.Resources.Go(m.reconcileWorker)
 	return m
 }
 
@@ -99,6 +113,12 @@ func (m *SubscriptionManager) Close(willBeResumed bool) {
 
 	<-m.doneCh
 
+	m.lock.RLock()
+	for _, s := range m.subscriptions {
+		s.endSpan(errParticipantClosed)
+	}
+	m.lock.RUnlock()
+
 	subTracks := m.GetSubscribedTracks()
 	downTracksToClose := make([]*sfu.DownTrack, 0, len(subTracks))
 	for _, st := range subTracks {
@@ -134,6 +154,9 @@ func (m *SubscriptionManager) SubscribeToTrack(trackID livekit.TrackID) {
 		m.subscriptions[trackID] = sub
 	}
 	desireChanged := sub.setDesired(true)
+	if desireChanged {
+		m.desiredChangedAt = time.Now()
+	}
 	m.lock.Unlock()
 	if desireChanged {
 		sub.logger.Infow("subscribing to track")
@@ -152,6 +175,10 @@ func (m *SubscriptionManager) UnsubscribeFromTrack(trackID livekit.TrackID) {
 	}
 
 	if sub.setDesired(false) {
+		m.lock.Lock()
+		m.desiredChangedAt = time.Now()
+		m.lock.Unlock()
+
 		sub.logger.Infow("unsubscribing from track")
 		m.queueReconcile(trackID)
 	}
@@ -182,6 +209,27 @@ func (m *SubscriptionManager) HasSubscriptions() bool {
 	return false
 }
 
+// GetDesiredSubscriptions returns the tracks the participant wants to be subscribed to, with their settings
+func (m *SubscriptionManager) GetDesiredSubscriptions() map[livekit.TrackID]*livekit.UpdateTrackSettings {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	desired := make(map[livekit.TrackID]*livekit.UpdateTrackSettings)
+	for trackID, s := range m.subscriptions {
+		if s.isDesired() {
+			desired[trackID] = s.getSettings()
+		}
+	}
+	return desired
+}
+
+// DesiredSubscriptionsChangedAt returns when the desired subscriptions, or their settings, last changed
+func (m *SubscriptionManager) DesiredSubscriptionsChangedAt() time.Time {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.desiredChangedAt
+}
+
 func (m *SubscriptionManager) GetSubscribedParticipants() []livekit.ParticipantID {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -211,6 +259,7 @@ func (m *SubscriptionManager) UpdateSubscribedTrackSettings(trackID livekit.Trac
 		sub = newTrackSubscription(m.params.Participant.ID(), trackID, sLogger)
 		m.subscriptions[trackID] = sub
 	}
+	m.desiredChangedAt = time.Now()
 	m.lock.Unlock()
 
 	sub.setSettings(settings)
@@ -276,6 +325,7 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 	if s.needsSubscribe() {
 		numAttempts := s.getNumAttempts()
 		if numAttempts == 0 {
+			s.startSpan(m.params.TraceContext)
 			m.params.Telemetry.TrackSubscribeRequested(
 				context.Background(),
 				m.params.Participant.ID(),
@@ -288,7 +338,7 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 			s.recordAttempt(false)
 
 			switch err {
-			case ErrNoTrackPermission, ErrNoSubscribePermission, ErrNoReceiver, ErrNotOpen, ErrTrackNotAttached, ErrSubscriptionLimitExceeded:
+			case ErrNoTrackPermission, ErrNoSubscribePermission, ErrNoReceiver, ErrNotOpen, ErrTrackNotAttached, ErrSubscriptionLimitExceeded, ErrSubscriptionThrottled, ErrNoCompatibleCodec:
 				// these are errors that are outside of our control, so we'll keep trying
 				// - ErrNoTrackPermission: publisher did not grant subscriber permission, may change any moment
 				// - ErrNoSubscribePermission: participant was not granted canSubscribe, may change any moment
@@ -296,6 +346,8 @@ func (m *SubscriptionManager) reconcileSubscription(s *trackSubscription) {
 				// - ErrTrackNotAttached: Remote Track that is not attached, but may be attached later
 				// - ErrNotOpen: Track is closing or already closed
 				// - ErrSubscriptionLimitExceeded: the participant have reached the limit of subscriptions, wait for the other subscription to be unsubscribed
+				// - ErrSubscriptionThrottled: room is in slow-start, the subscription is retried once admitted
+				// - ErrNoCompatibleCodec: subscriber cannot decode published codecs, publisher may add a compatible codec
 				// We'll still log an event to reflect this in telemetry since it's been too long
 				if s.durationSinceStart() > subscriptionTimeout {
 					s.maybeRecordError(m.params.Telemetry, m.params.Participant.ID(), err, true)
@@ -406,6 +458,28 @@ func (m *SubscriptionManager) hasCapacityForSubscription(kind livekit.TrackType)
 	return true
 }
 
+// isAdmitted asks for an admission slot on the first attempt, subsequent attempts wait for that slot
+func (m *SubscriptionManager) isAdmitted(s *trackSubscription, kind livekit.TrackType) bool {
+	if m.params.AdmitSubscription == nil {
+		return true
+	}
+	if admitAt := s.getAdmitAt(); admitAt != nil {
+		return !time.Now().Before(*admitAt)
+	}
+
+	delay := m.params.AdmitSubscription(kind)
+	if delay <= 0 {
+		return true
+	}
+	admitAt := time.Now().Add(delay)
+	s.setAdmitAt(&admitAt)
+	s.logger.Debugw("delaying subscription", "delay", delay)
+	m.params.Resources.AfterFunc(delay, func() {
+		m.queueReconcile(s.trackID)
+	})
+	return false
+}
+
 func (m *SubscriptionManager) subscribe(s *trackSubscription) error {
 	s.logger.Debugw("executing subscribe")
 
@@ -461,11 +535,16 @@ func (m *SubscriptionManager) subscribe(s *trackSubscription) error {
 		return ErrNoTrackPermission
 	}
 
+	if !m.isAdmitted(s, track.Kind()) {
+		return ErrSubscriptionThrottled
+	}
+
 	subTrack, err := track.AddSubscriber(m.params.Participant)
 	if err != nil && err != errAlreadySubscribed {
 		// ignore already subscribed error
 		return err
 	}
+	s.clearAdmitAt()
 	if err == nil && subTrack != nil { // subTrack could be nil if already subscribed
 		subTrack.OnClose(func(willBeResumed bool) {
 			m.handleSubscribedTrackClose(s, willBeResumed)
@@ -473,6 +552,7 @@ func (m *SubscriptionManager) subscribe(s *trackSubscription) error {
 		subTrack.AddOnBind(func() {
 			s.setBound()
 			s.maybeRecordSuccess(m.params.Telemetry, m.params.Participant.ID())
+			s.endSpan(nil)
 		})
 		s.setSubscribedTrack(subTrack)
 
@@ -649,6 +729,12 @@ type trackSubscription struct {
 	bound             bool
 	kind              atomic.Pointer[livekit.TrackType]
 
+	// when a subscription delayed by slow-start may go ahead
+	admitAt atomic.Pointer[time.Time]
+
+	// traces the subscription till bound, nil when not traced
+	span trace.Span
+
 	// the later of when subscription was requested OR when the first failure was encountered OR when permission is granted
 	// this timestamp determines when failures are reported
 	subStartedAt atomic.Pointer[time.Time]
@@ -701,10 +787,48 @@ func (s *trackSubscription) setDesired(desired bool) bool {
 	} else {
 		s.setChangedNotifierLocked(nil)
 		s.setRemovedNotifierLocked(nil)
+		if s.span != nil {
+			s.span.SetAttributes(attribute.Bool("cancelled", true))
+			s.span.End()
+			s.span = nil
+		}
 	}
 	return true
 }
 
+// startSpan starts tracing the setup of the subscription, unless it is already traced
+func (s *trackSubscription) startSpan(parent trace.SpanContext) {
+	if !parent.IsSampled() {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.span != nil {
+		return
+	}
+	s.span = tracing.StartFrom(parent, "rtc.SubscribeTrack", attribute.String("track_sid", string(s.trackID)))
+}
+
+// endSpan ends tracing the setup of the subscription, with its outcome
+func (s *trackSubscription) endSpan(err error) {
+	s.lock.Lock()
+	span := s.span
+	s.span = nil
+	publisherIdentity := s.publisherIdentity
+	s.lock.Unlock()
+	if span == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("publisher", string(publisherIdentity))}
+	if kind, ok := s.getKind(); ok {
+		attrs = append(attrs, attribute.String("kind", kind.String()))
+	}
+	span.SetAttributes(attrs...)
+	tracing.End(span, err)
+}
+
 // set permission and return true if it has changed
 func (s *trackSubscription) setHasPermission(perm bool) bool {
 	s.lock.Lock()
@@ -819,6 +943,24 @@ func (s *trackSubscription) setSettings(settings *livekit.UpdateTrackSettings) {
 	}
 }
 
+func (s *trackSubscription) getAdmitAt() *time.Time {
+	return s.admitAt.Load()
+}
+
+func (s *trackSubscription) setAdmitAt(admitAt *time.Time) {
+	s.admitAt.Store(admitAt)
+}
+
+func (s *trackSubscription) clearAdmitAt() {
+	s.admitAt.Store(nil)
+}
+
+func (s *trackSubscription) getSettings() *livekit.UpdateTrackSettings {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.settings
+}
+
 // mark the subscription as bound - when we've received the client's answer
 func (s *trackSubscription) setBound() {
 	s.lock.Lock()
@@ -871,6 +1013,7 @@ func (s *trackSubscription) handleSourceTrackRemoved() {
 }
 
 func (s *trackSubscription) maybeRecordError(ts telemetry.TelemetryService, pID livekit.ParticipantID, err error, isUserError bool) {
+	s.endSpan(err)
 	if s.eventSent.Swap(true) {
 		return
 	}
@@ -347,6 +347,68 @@ func TestUpdateSettingsBeforeSubscription(t *testing.T) {
 	require.Equal(t, settings.Height, applied.Height)
 }
 
+func TestGetDesiredSubscriptions(t *testing.T) {
+	sm := newTestSubscriptionManager(t)
+	defer sm.Close(false)
+	resolver := newTestResolver(true, true, "pub", "pubID")
+	sm.params.TrackResolver = resolver.Resolve
+
+	settings := &livekit.UpdateTrackSettings{Width: 100, Height: 100}
+	sm.UpdateSubscribedTrackSettings("track1", settings)
+	sm.SubscribeToTrack("track1")
+	sm.SubscribeToTrack("track2")
+	// settings alone do not make a subscription desired
+	sm.UpdateSubscribedTrackSettings("track3", settings)
+
+	desired := sm.GetDesiredSubscriptions()
+	require.Len(t, desired, 2)
+	require.Equal(t, settings, desired["track1"])
+	require.Contains(t, desired, livekit.TrackID("track2"))
+	require.Nil(t, desired["track2"])
+
+	changedAt := sm.DesiredSubscriptionsChangedAt()
+	require.WithinDuration(t, time.Now(), changedAt, time.Second)
+
+	sm.UnsubscribeFromTrack("track1")
+	desired = sm.GetDesiredSubscriptions()
+	require.Len(t, desired, 1)
+	require.Contains(t, desired, livekit.TrackID("track2"))
+	require.False(t, sm.DesiredSubscriptionsChangedAt().Before(changedAt))
+
+	// unsubscribing again is not a change
+	changedAt = sm.DesiredSubscriptionsChangedAt()
+	sm.UnsubscribeFromTrack("track1")
+	require.Equal(t, changedAt, sm.DesiredSubscriptionsChangedAt())
+}
+
+func TestSubscriptionAdmission(t *testing.T) {
+	sm := newTestSubscriptionManager(t)
+	defer sm.Close(false)
+	resolver := newTestResolver(true, true, "pub", "pubID")
+	sm.params.TrackResolver = resolver.Resolve
+	admitted := atomic.Int32{}
+	sm.params.AdmitSubscription = func(kind livekit.TrackType) time.Duration {
+		admitted.Inc()
+		return 100 * time.Millisecond
+	}
+	subCount := atomic.Int32{}
+	sm.params.OnTrackSubscribed = func(subTrack types.SubscribedTrack) {
+		subCount.Inc()
+	}
+
+	sm.SubscribeToTrack("track")
+	s := sm.subscriptions["track"]
+	time.Sleep(50 * time.Millisecond)
+	require.True(t, s.needsSubscribe())
+	require.Zero(t, subCount.Load())
+
+	require.Eventually(t, func() bool {
+		return subCount.Load() == 1
+	}, subSettleTimeout, subCheckInterval, "track was not subscribed after admission delay")
+	// a slot is reserved only once per subscription
+	require.Equal(t, int32(1), admitted.Load())
+}
+
 func TestSubscriptionLimits(t *testing.T) {
 	sm := newTestSubscriptionManagerWithParams(t, testSubscriptionParams{
 		SubscriptionLimitAudio: 1,
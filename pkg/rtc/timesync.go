@@ -0,0 +1,123 @@
+package rtc
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/mediatransportutil"
+	"github.com/livekit/protocol/livekit"
+)
+
+// Time synchronization is not part of the protocol messages yet, it is appended as an extra field that clients
+// unaware of it skip. Being an unknown field, it is only carried by data channels:
+//
+//	message TimeSync {
+//	  uint64 id = 1;
+//	  fixed64 originate_timestamp = 2;
+//	  fixed64 receive_timestamp = 3;
+//	  fixed64 transmit_timestamp = 4;
+//	}
+//
+//	DataPacket.time_sync = 102;
+//
+// The exchange follows NTP. A participant sends a request carrying an ID and its own send time, which the server
+// echoes back along with the NTP timestamps of when it received the request and sent the response, on the data
+// channel the request came in. With the time the response arrived, the participant computes the round trip as
+// (arrival - originate) - (transmit - receive) and the offset of its clock as
+// ((receive - originate) + (transmit - arrival)) / 2. Taking the sample with the shortest round trip out of a few
+// aligns participants of a room to the server clock within a few milliseconds; requests are best sent on the lossy
+// channel, which does not delay them with retransmissions. Participants of a room relayed between nodes are
+// answered by the node they are connected to, whose clock is expected to be synchronized with NTP.
+const (
+	dataPacketTimeSyncField protowire.Number = 102
+
+	timeSyncIDField                 protowire.Number = 1
+	timeSyncOriginateTimestampField protowire.Number = 2
+	timeSyncReceiveTimestampField   protowire.Number = 3
+	timeSyncTransmitTimestampField  protowire.Number = 4
+)
+
+type TimeSync struct {
+	ID uint64
+	// on the clock of the participant, echoed as is
+	OriginateTimestamp uint64
+	// on the server clock, zero in requests
+	Received    time.Time
+	Transmitted time.Time
+}
+
+// NewTimeSyncPacket creates a data packet carrying a time sync request or response
+func NewTimeSyncPacket(kind livekit.DataPacket_Kind, ts *TimeSync) *livekit.DataPacket {
+	var value []byte
+	value = protowire.AppendTag(value, timeSyncIDField, protowire.VarintType)
+	value = protowire.AppendVarint(value, ts.ID)
+	appendTimestamp := func(field protowire.Number, v uint64) {
+		if v == 0 {
+			return
+		}
+		value = protowire.AppendTag(value, field, protowire.Fixed64Type)
+		value = protowire.AppendFixed64(value, v)
+	}
+	appendTimestamp(timeSyncOriginateTimestampField, ts.OriginateTimestamp)
+	if !ts.Received.IsZero() {
+		appendTimestamp(timeSyncReceiveTimestampField, uint64(mediatransportutil.ToNtpTime(ts.Received)))
+	}
+	if !ts.Transmitted.IsZero() {
+		appendTimestamp(timeSyncTransmitTimestampField, uint64(mediatransportutil.ToNtpTime(ts.Transmitted)))
+	}
+
+	dp := &livekit.DataPacket{Kind: kind}
+	m := dp.ProtoReflect()
+	unknown := protowire.AppendTag(m.GetUnknown(), dataPacketTimeSyncField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, value))
+	return dp
+}
+
+// GetTimeSync reads the time sync request or response a data packet carries, nil when it carries none
+func GetTimeSync(dp *livekit.DataPacket) *TimeSync {
+	value, ok := findUnknownBytesField(dp.ProtoReflect().GetUnknown(), dataPacketTimeSyncField)
+	if !ok {
+		return nil
+	}
+
+	ts := &TimeSync{}
+	for len(value) > 0 {
+		num, typ, n := protowire.ConsumeTag(value)
+		if n < 0 {
+			return nil
+		}
+		value = value[n:]
+
+		switch {
+		case typ == protowire.VarintType && num == timeSyncIDField:
+			v, m := protowire.ConsumeVarint(value)
+			if m < 0 {
+				return nil
+			}
+			ts.ID = v
+			value = value[m:]
+		case typ == protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(value)
+			if m < 0 {
+				return nil
+			}
+			switch num {
+			case timeSyncOriginateTimestampField:
+				ts.OriginateTimestamp = v
+			case timeSyncReceiveTimestampField:
+				ts.Received = mediatransportutil.NtpTime(v).Time()
+			case timeSyncTransmitTimestampField:
+				ts.Transmitted = mediatransportutil.NtpTime(v).Time()
+			}
+			value = value[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, value)
+			if m < 0 {
+				return nil
+			}
+			value = value[m:]
+		}
+	}
+	return ts
+}
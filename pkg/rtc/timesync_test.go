@@ -0,0 +1,39 @@
+package rtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestTimeSync(t *testing.T) {
+	req := &TimeSync{ID: 7, OriginateTimestamp: 123456789}
+	data, err := proto.Marshal(NewTimeSyncPacket(livekit.DataPacket_LOSSY, req))
+	require.NoError(t, err)
+
+	dp := &livekit.DataPacket{}
+	require.NoError(t, proto.Unmarshal(data, dp))
+	require.Nil(t, dp.Value)
+	require.Equal(t, req, GetTimeSync(dp))
+
+	now := time.Now()
+	res := &TimeSync{
+		ID:                 req.ID,
+		OriginateTimestamp: req.OriginateTimestamp,
+		Received:           now,
+		Transmitted:        now.Add(time.Millisecond),
+	}
+	received := GetTimeSync(NewTimeSyncPacket(livekit.DataPacket_LOSSY, res))
+	require.NotNil(t, received)
+	require.Equal(t, res.ID, received.ID)
+	require.Equal(t, res.OriginateTimestamp, received.OriginateTimestamp)
+	// NTP fractions are finer than a microsecond
+	require.WithinDuration(t, res.Received, received.Received, time.Microsecond)
+	require.WithinDuration(t, res.Transmitted, received.Transmitted, time.Microsecond)
+
+	require.Nil(t, GetTimeSync(&livekit.DataPacket{Value: &livekit.DataPacket_User{User: &livekit.UserPacket{}}}))
+}
@@ -0,0 +1,154 @@
+package rtc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// Rejections by the track policy are not part of the protocol messages yet, they are appended as an extra field that
+// clients unaware of it skip. Being an unknown field, it is only carried by the binary signal protocol:
+//
+//	message TrackRejected {
+//	  string cid = 1;
+//	  TrackRejectedCode code = 2;
+//	  string reason = 3;
+//	}
+//
+//	enum TrackRejectedCode {
+//	  UNKNOWN = 0;
+//	  DUPLICATE_NAME = 1;
+//	  DUPLICATE_SOURCE = 2;
+//	  SOURCE_NOT_ALLOWED = 3;
+//	  INVALID_NAME = 4;
+//	  SERVER_OVERLOADED = 5;
+//	}
+//
+//	SignalResponse.track_rejected = 101;
+const (
+	signalResponseTrackRejectedField protowire.Number = 101
+
+	trackRejectedCidField    protowire.Number = 1
+	trackRejectedCodeField   protowire.Number = 2
+	trackRejectedReasonField protowire.Number = 3
+)
+
+type TrackRejectedCode uint32
+
+const (
+	TrackRejectedUnknown TrackRejectedCode = iota
+	TrackRejectedDuplicateName
+	TrackRejectedDuplicateSource
+	TrackRejectedSourceNotAllowed
+	TrackRejectedInvalidName
+	TrackRejectedServerOverloaded
+)
+
+var (
+	ErrTrackNameNotUnique    = errors.New("a track with this name is already published")
+	ErrTrackSourceNotUnique  = errors.New("a track of this source is already published")
+	ErrTrackSourceNotAllowed = errors.New("tracks of this source are not allowed")
+	ErrTrackNameInvalid      = errors.New("track name does not match the naming policy")
+)
+
+// TrackPolicy constrains the names and sources of the tracks a participant publishes, so that clients republishing a
+// track without unpublishing it first cannot leave duplicate tracks in the room
+type TrackPolicy struct {
+	uniqueNames    bool
+	uniqueSources  bool
+	allowedSources map[livekit.TrackSource]bool
+	namePattern    *regexp.Regexp
+}
+
+// NewTrackPolicy creates the policy of the configuration, nil when it has no constraints
+func NewTrackPolicy(conf config.TrackPolicyConfig) (*TrackPolicy, error) {
+	if !conf.UniqueNames && !conf.UniqueSources && len(conf.AllowedSources) == 0 && conf.NamePattern == "" {
+		return nil, nil
+	}
+
+	policy := &TrackPolicy{
+		uniqueNames:   conf.UniqueNames,
+		uniqueSources: conf.UniqueSources,
+	}
+	if len(conf.AllowedSources) != 0 {
+		policy.allowedSources = make(map[livekit.TrackSource]bool, len(conf.AllowedSources))
+		for _, name := range conf.AllowedSources {
+			source, ok := livekit.TrackSource_value[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown track source %s", name)
+			}
+			policy.allowedSources[livekit.TrackSource(source)] = true
+		}
+	}
+	if conf.NamePattern != "" {
+		pattern, err := regexp.Compile(conf.NamePattern)
+		if err != nil {
+			return nil, err
+		}
+		policy.namePattern = pattern
+	}
+	return policy, nil
+}
+
+// Check returns why a track cannot be published alongside the tracks the participant already publishes
+func (t *TrackPolicy) Check(req *livekit.AddTrackRequest, published []*livekit.TrackInfo) error {
+	if t.allowedSources != nil && !t.allowedSources[req.Source] {
+		return ErrTrackSourceNotAllowed
+	}
+	if t.namePattern != nil && !t.namePattern.MatchString(req.Name) {
+		return ErrTrackNameInvalid
+	}
+	for _, ti := range published {
+		if t.uniqueNames && ti.Name == req.Name {
+			return ErrTrackNameNotUnique
+		}
+		// any number of tracks may not tell their source
+		if t.uniqueSources && req.Source != livekit.TrackSource_UNKNOWN && ti.Source == req.Source {
+			return ErrTrackSourceNotUnique
+		}
+	}
+	return nil
+}
+
+func trackRejectedCode(err error) TrackRejectedCode {
+	switch {
+	case errors.Is(err, ErrTrackNameNotUnique):
+		return TrackRejectedDuplicateName
+	case errors.Is(err, ErrTrackSourceNotUnique):
+		return TrackRejectedDuplicateSource
+	case errors.Is(err, ErrTrackSourceNotAllowed):
+		return TrackRejectedSourceNotAllowed
+	case errors.Is(err, ErrTrackNameInvalid):
+		return TrackRejectedInvalidName
+	case errors.Is(err, ErrVideoRefusedOverloaded):
+		return TrackRejectedServerOverloaded
+	default:
+		return TrackRejectedUnknown
+	}
+}
+
+// newTrackRejectedResponse tells a publisher that a track was rejected by the track policy or refused by the server
+func newTrackRejectedResponse(cid string, err error) *livekit.SignalResponse {
+	var value []byte
+	if cid != "" {
+		value = protowire.AppendTag(value, trackRejectedCidField, protowire.BytesType)
+		value = protowire.AppendString(value, cid)
+	}
+	value = protowire.AppendTag(value, trackRejectedCodeField, protowire.VarintType)
+	value = protowire.AppendVarint(value, uint64(trackRejectedCode(err)))
+	value = protowire.AppendTag(value, trackRejectedReasonField, protowire.BytesType)
+	value = protowire.AppendString(value, err.Error())
+
+	res := &livekit.SignalResponse{}
+	m := res.ProtoReflect()
+	unknown := protowire.AppendTag(nil, signalResponseTrackRejectedField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, value))
+	return res
+}
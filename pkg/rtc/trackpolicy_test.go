@@ -0,0 +1,89 @@
+package rtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+)
+
+func TestTrackPolicy(t *testing.T) {
+	policy, err := NewTrackPolicy(config.TrackPolicyConfig{})
+	require.NoError(t, err)
+	require.Nil(t, policy)
+
+	_, err = NewTrackPolicy(config.TrackPolicyConfig{AllowedSources: []string{"webcam"}})
+	require.Error(t, err)
+	_, err = NewTrackPolicy(config.TrackPolicyConfig{NamePattern: "("})
+	require.Error(t, err)
+
+	policy, err = NewTrackPolicy(config.TrackPolicyConfig{
+		UniqueNames:    true,
+		UniqueSources:  true,
+		AllowedSources: []string{"camera", "microphone", "unknown"},
+		NamePattern:    "^[a-z]+$",
+	})
+	require.NoError(t, err)
+
+	published := []*livekit.TrackInfo{
+		{Name: "cam", Source: livekit.TrackSource_CAMERA},
+		{Name: "extra", Source: livekit.TrackSource_UNKNOWN},
+	}
+	for _, tc := range []struct {
+		name string
+		req  *livekit.AddTrackRequest
+		err  error
+	}{
+		{"allowed", &livekit.AddTrackRequest{Name: "mic", Source: livekit.TrackSource_MICROPHONE}, nil},
+		{"duplicate name", &livekit.AddTrackRequest{Name: "cam", Source: livekit.TrackSource_MICROPHONE}, ErrTrackNameNotUnique},
+		{"invalid name", &livekit.AddTrackRequest{Name: "cam2", Source: livekit.TrackSource_CAMERA}, ErrTrackNameInvalid},
+		{"duplicate source", &livekit.AddTrackRequest{Name: "other", Source: livekit.TrackSource_CAMERA}, ErrTrackSourceNotUnique},
+		{"unknown sources are not unique", &livekit.AddTrackRequest{Name: "more", Source: livekit.TrackSource_UNKNOWN}, nil},
+		{"source not allowed", &livekit.AddTrackRequest{Name: "screen", Source: livekit.TrackSource_SCREEN_SHARE}, ErrTrackSourceNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.req, published)
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestTrackPolicyEnforced(t *testing.T) {
+	p := newParticipantForTest("test")
+	p.params.TrackPolicy, _ = NewTrackPolicy(config.TrackPolicyConfig{UniqueNames: true})
+	sink := p.params.Sink.(*routingfakes.FakeMessageSink)
+
+	p.AddTrack(&livekit.AddTrackRequest{Cid: "cid1", Name: "webcam", Type: livekit.TrackType_VIDEO})
+	require.Equal(t, 1, sink.WriteMessageCallCount())
+	require.IsType(t, &livekit.SignalResponse_TrackPublished{}, sink.WriteMessageArgsForCall(0).(*livekit.SignalResponse).Message)
+
+	// republishing the same client track is not a duplicate
+	p.AddTrack(&livekit.AddTrackRequest{Cid: "cid1", Name: "webcam", Type: livekit.TrackType_VIDEO})
+	require.Equal(t, 1, sink.WriteMessageCallCount())
+
+	p.AddTrack(&livekit.AddTrackRequest{Cid: "cid2", Name: "webcam", Type: livekit.TrackType_VIDEO})
+	require.Equal(t, 2, sink.WriteMessageCallCount())
+	res := sink.WriteMessageArgsForCall(1).(*livekit.SignalResponse)
+	require.Nil(t, res.Message)
+	value, ok := findUnknownBytesField(res.ProtoReflect().GetUnknown(), signalResponseTrackRejectedField)
+	require.True(t, ok)
+	cid, ok := findUnknownBytesField(value, trackRejectedCidField)
+	require.True(t, ok)
+	require.Equal(t, "cid2", string(cid))
+
+	num, typ, n := protowire.ConsumeTag(value[len(cid)+2:])
+	require.Positive(t, n)
+	require.Equal(t, trackRejectedCodeField, num)
+	require.Equal(t, protowire.VarintType, typ)
+	code, _ := protowire.ConsumeVarint(value[len(cid)+2+n:])
+	require.Equal(t, uint64(TrackRejectedDuplicateName), code)
+}
@@ -3,6 +3,9 @@ package rtc
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -12,7 +15,6 @@ import (
 	"github.com/pion/ice/v2"
 	"github.com/pion/interceptor"
 	"github.com/pion/interceptor/pkg/cc"
-	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/interceptor/pkg/twcc"
 	"github.com/pion/rtcp"
 	"github.com/pion/sdp/v3"
@@ -22,14 +24,17 @@ import (
 
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
-	"github.com/livekit/protocol/logger/pionlogger"
 	lksdp "github.com/livekit/protocol/sdp"
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/fec"
+	"github.com/livekit/livekit-server/pkg/sfu/pacer"
 	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 const (
@@ -51,6 +56,15 @@ const (
 	maxConnectTimeoutAfterICE = 20 * time.Second // max duration for waiting pc to connect after ICE is connected
 
 	shortConnectionThreshold = 90 * time.Second
+
+	// how long a peer reflexive candidate the candidate policy does not allow may take to be signalled as an allowed one
+	candidatePolicyGracePeriod = 2 * time.Second
+
+	// how often the bytes sent and received over the selected candidate pair are recorded in metrics
+	iceStatsInterval = 10 * time.Second
+
+	// data channels of voice rooms carry chat and state, not bulk transfers, pion's default is 1 MB
+	audioOnlySCTPMaxReceiveBufferSize = 256 * 1024
 )
 
 var (
@@ -164,8 +178,8 @@ type PCTransport struct {
 	iceConnectedAt             time.Time
 	firstConnectedAt           time.Time
 	connectedAt                time.Time
-	tcpICETimer                *time.Timer
-	connectAfterICETimer       *time.Timer // timer to wait for pc to connect after ice connected
+	tcpICETimer                *utils.ResourceTimer
+	connectAfterICETimer       *utils.ResourceTimer // timer to wait for pc to connect after ice connected
 	resetShortConnOnICERestart atomic.Bool
 	signalingRTT               atomic.Uint32 // milliseconds
 
@@ -179,11 +193,19 @@ type PCTransport struct {
 	onAnswer                  func(answer webrtc.SessionDescription) error
 	onInitialConnected        func()
 	onFailed                  func(isShortLived bool)
+	onNetworkChanged          func(previous, current *webrtc.ICECandidatePair)
 	onNegotiationStateChanged func(state NegotiationState)
 	onNegotiationFailed       func()
 
 	// stream allocator for subscriber PC
 	streamAllocator *streamallocator.StreamAllocator
+	allocatorTrace  *os.File
+
+	// FlexFEC repair streams for subscriber PC, nil when not enabled
+	fecProtector *fec.Protector
+
+	// paces packets sent on subscriber PC, nil when not enabled
+	pacer *pacer.Pacer
 
 	previousAnswer *webrtc.SessionDescription
 	// track id -> description map in previous offer sdp
@@ -193,6 +215,14 @@ type PCTransport struct {
 	preferTCP atomic.Bool
 	isClosed  atomic.Bool
 
+	selectedPair *webrtc.ICECandidatePair
+
+	iceMetrics    *prometheus.ICETransportMetrics
+	byteCounter   *byteCounter
+	iceStatsTimer *utils.ResourceTimer
+	// passive TCP candidates signalled by the client, released from the active TCP mux on close
+	activeTCPRemotes []string
+
 	eventChMu sync.RWMutex
 	eventCh   chan event
 
@@ -204,7 +234,7 @@ type PCTransport struct {
 	restartAtNextOffer        bool
 	negotiationState          NegotiationState
 	negotiateCounter          atomic.Int32
-	signalStateCheckTimer     *time.Timer
+	signalStateCheckTimer     *utils.ResourceTimer
 	currentOfferIceCredential string // ice user:pwd, for publish side ice restart checking
 	pendingRestartIceOffer    *webrtc.SessionDescription
 
@@ -229,18 +259,50 @@ type TransportParams struct {
 	ClientInfo              ClientInfo
 	IsOfferer               bool
 	IsSendSide              bool
-}
-
-func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimator cc.BandwidthEstimator)) (*webrtc.PeerConnection, *webrtc.MediaEngine, error) {
+	// no video is sent or received, bandwidth estimation and video repair are left out
+	AudioOnly bool
+	// the peer connection is accounted as a socket from creation till close, with its goroutines and timers
+	Resources *utils.ResourceOwner
+	// constraints on the candidates of the client, nil when there are none
+	CandidatePolicy *CandidatePolicy
+}
+
+func newPeerConnection(
+	params TransportParams,
+	fecProtector *fec.Protector,
+	pacer *pacer.Pacer,
+	byteCounter *byteCounter,
+	onBandwidthEstimator func(estimator cc.BandwidthEstimator),
+) (*webrtc.PeerConnection, *webrtc.MediaEngine, error) {
 	directionConfig := params.DirectionConfig
+	if params.AudioOnly {
+		directionConfig = audioOnlyDirectionConfig(directionConfig)
+	}
 
 	me, err := createMediaEngine(params.EnabledCodecs, directionConfig)
 	if err != nil {
 		return nil, nil, err
 	}
+	if fecProtector != nil {
+		if err := registerFlexFEC(me); err != nil {
+			return nil, nil, err
+		}
+	}
+	if params.IsSendSide && params.Config.SubscriberRTX && !params.AudioOnly {
+		if err := registerRTX(me, params.EnabledCodecs); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	se := params.Config.SettingEngine
 	se.DisableMediaEngineCopy(true)
+	// every peer connection has ICE credentials of its own, generated by its ICE agent from crypto/rand and replaced
+	// on ICE restarts, so that they are scoped to the participant session and of no use once it is over. Credentials
+	// set on the shared setting engine would be used by all participants and kept across restarts
+	se.SetICECredentials("", "")
+	if hostMapping := params.Config.updatedHostMapping(); hostMapping != nil {
+		se.SetNAT1To1IPs(hostMapping, webrtc.ICECandidateTypeHost)
+	}
 
 	// Change elliptic curve to improve connectivity
 	// https://github.com/pion/dtls/pull/474
@@ -270,12 +332,19 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 	}
 	se.SetDTLSRetransmissionInterval(dtlsRetransmissionInterval)
 	se.SetICETimeouts(iceDisconnectedTimeout, iceFailedTimeout, iceKeepaliveInterval)
+	if params.AudioOnly {
+		se.SetSCTPMaxReceiveBufferSize(audioOnlySCTPMaxReceiveBufferSize)
+	}
 
-	// if client don't support prflx over relay, we should not expose private address to it, use single external ip as host candidate
-	if !params.ClientInfo.SupportPrflxOverRelay() && len(params.Config.NAT1To1IPs) > 0 {
+	// clients on an internal network reach the node on its local addresses, they may not be able to hairpin through the NAT
+	if params.ClientInfo.ClientInfo != nil && params.Config.IsInternalClient(params.ClientInfo.Address) {
+		params.Logger.Debugw("client on internal network, using local addresses as host candidates")
+		se.SetNAT1To1IPs(nil, webrtc.ICECandidateTypeHost)
+	} else if nat1to1IPs := params.Config.GetNAT1To1IPs(); !params.ClientInfo.SupportPrflxOverRelay() && len(nat1to1IPs) > 0 {
+		// if client don't support prflx over relay, we should not expose private address to it, use single external ip as host candidate
 		var nat1to1Ips []string
 		var includeIps []string
-		for _, mapping := range params.Config.NAT1To1IPs {
+		for _, mapping := range nat1to1IPs {
 			if ips := strings.Split(mapping, "/"); len(ips) == 2 {
 				if ips[0] != ips[1] {
 					nat1to1Ips = append(nat1to1Ips, mapping)
@@ -298,12 +367,14 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 		}
 	}
 
-	lf := pionlogger.NewLoggerFactory(params.Logger)
+	lf := newPionLoggerFactory(params.Logger)
 	if lf != nil {
 		se.LoggerFactory = lf
 	}
 
 	ir := &interceptor.Registry{}
+	// counts packets as written to and read from the transport
+	ir.Add(byteCounter)
 	if params.IsSendSide {
 		isSendSideBWE := false
 		for _, ext := range directionConfig.RTPHeaderExtension.Video {
@@ -319,11 +390,12 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 			}
 		}
 
+		var tf interceptor.Factory
 		if isSendSideBWE {
 			gf, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
-				return gcc.NewSendSideBWE(
-					gcc.SendSideBWEInitialBitrate(1*1000*1000),
-					gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
+				return streamallocator.NewBandwidthEstimator(
+					params.CongestionControlConfig.BandwidthEstimator,
+					streamallocator.BandwidthEstimatorParams{Logger: params.Logger},
 				)
 			})
 			if err == nil {
@@ -334,12 +406,23 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 				})
 				ir.Add(gf)
 
-				tf, err := twcc.NewHeaderExtensionInterceptor()
-				if err == nil {
-					ir.Add(tf)
+				if hf, err := twcc.NewHeaderExtensionInterceptor(); err == nil {
+					tf = hf
 				}
 			}
 		}
+
+		// repair packets protect media packets as sent, i.e. after the transport-wide sequence number is added
+		if fecProtector != nil {
+			ir.Add(fecProtector)
+		}
+		if tf != nil {
+			ir.Add(tf)
+		}
+		// packets are held back before they are given a transport-wide sequence number
+		if pacer != nil {
+			ir.Add(pacer)
+		}
 	}
 	if len(params.SimTracks) > 0 {
 		f, err := NewUnhandleSimulcastInterceptorFactory(UnhandleSimulcastTracks(params.SimTracks))
@@ -358,6 +441,29 @@ func newPeerConnection(params TransportParams, onBandwidthEstimator func(estimat
 	return pc, me, err
 }
 
+// audioOnlyDirectionConfig leaves out video and transport-wide congestion control, it drives bandwidth estimation
+// which is of no use without video to allocate
+func audioOnlyDirectionConfig(dc DirectionConfig) DirectionConfig {
+	var audioExtensions []string
+	for _, ext := range dc.RTPHeaderExtension.Audio {
+		if ext != sdp.TransportCCURI {
+			audioExtensions = append(audioExtensions, ext)
+		}
+	}
+	var audioFeedback []webrtc.RTCPFeedback
+	for _, fb := range dc.RTCPFeedback.Audio {
+		if fb.Type != webrtc.TypeRTCPFBTransportCC {
+			audioFeedback = append(audioFeedback, fb)
+		}
+	}
+
+	return DirectionConfig{
+		RTPHeaderExtension: RTPHeaderExtensionConfig{Audio: audioExtensions},
+		RTCPFeedback:       RTCPFeedbackConfig{Audio: audioFeedback},
+		StrictACKs:         dc.StrictACKs,
+	}
+}
+
 func NewPCTransport(params TransportParams) (*PCTransport, error) {
 	if params.Logger == nil {
 		params.Logger = logger.GetLogger()
@@ -369,27 +475,55 @@ func NewPCTransport(params TransportParams) (*PCTransport, error) {
 		eventCh:                  make(chan event, 50),
 		previousTrackDescription: make(map[string]*trackDescription),
 		canReuseTransceiver:      true,
+		byteCounter:              newByteCounter(),
 	}
 	if params.IsSendSide {
+		t.iceMetrics = prometheus.NewICETransportMetrics("subscriber")
+	} else {
+		t.iceMetrics = prometheus.NewICETransportMetrics("publisher")
+	}
+	// audio is not allocated, nothing to estimate or probe for without video
+	if params.IsSendSide && !params.AudioOnly {
 		t.streamAllocator = streamallocator.NewStreamAllocator(streamallocator.StreamAllocatorParams{
-			Config: params.CongestionControlConfig,
-			Logger: params.Logger,
+			Config:        params.CongestionControlConfig,
+			Logger:        params.Logger,
+			TraceRecorder: t.createAllocatorTrace(),
 		})
+		if params.Config.FlexFEC.Enabled {
+			t.fecProtector = fec.NewProtector(fec.ProtectorParams{
+				PayloadType: flexFECPayloadType,
+				MinLoss:     float64(params.Config.FlexFEC.MinLoss),
+				MaxOverhead: float64(params.Config.FlexFEC.MaxOverhead),
+				Logger:      params.Logger,
+			})
+			t.streamAllocator.OnLossEstimate(t.fecProtector.SetLoss)
+		}
+		if params.Config.Pacer.Enabled {
+			t.pacer = pacer.NewPacer(pacer.PacerParams{
+				Multiplier:    float64(params.Config.Pacer.Multiplier),
+				MaxBurst:      params.Config.Pacer.MaxBurst,
+				MaxQueueDelay: params.Config.Pacer.MaxQueueDelay,
+				Logger:        params.Logger,
+			})
+			t.streamAllocator.OnEstimate(t.pacer.SetBitrate)
+			params.Resources.Go(t.pacer.Run)
+		}
 		t.streamAllocator.Start()
 	}
 
 	if err := t.createPeerConnection(); err != nil {
 		return nil, err
 	}
+	params.Resources.AddSockets(1)
 
-	go t.processEvents()
+	params.Resources.Go(t.processEvents)
 
 	return t, nil
 }
 
 func (t *PCTransport) createPeerConnection() error {
 	var bwe cc.BandwidthEstimator
-	pc, me, err := newPeerConnection(t.params, func(estimator cc.BandwidthEstimator) {
+	pc, me, err := newPeerConnection(t.params, t.fecProtector, t.pacer, t.byteCounter, func(estimator cc.BandwidthEstimator) {
 		bwe = estimator
 	})
 	if err != nil {
@@ -400,6 +534,9 @@ func (t *PCTransport) createPeerConnection() error {
 	t.pc.OnICEGatheringStateChange(t.onICEGatheringStateChange)
 	t.pc.OnICEConnectionStateChange(t.onICEConnectionStateChange)
 	t.pc.OnICECandidate(t.onICECandidateTrickle)
+	if iceTransport, err := t.getICETransport(); err == nil {
+		iceTransport.OnSelectedCandidatePairChange(t.onSelectedCandidatePairChange)
+	}
 
 	t.pc.OnConnectionStateChange(t.onPeerConnectionStateChange)
 
@@ -434,7 +571,7 @@ func (t *PCTransport) setICEStartedAt(at time.Time) {
 					tcpICETimeout = maxTcpICEConnectTimeout
 				}
 				t.params.Logger.Debugw("set tcp ice connect timer", "timeout", tcpICETimeout, "signalRTT", signalingRTT)
-				t.tcpICETimer = time.AfterFunc(tcpICETimeout, func() {
+				t.tcpICETimer = t.params.Resources.AfterFunc(tcpICETimeout, func() {
 					if t.pc.ICEConnectionState() == webrtc.ICEConnectionStateChecking {
 						t.params.Logger.Infow("tcp ice connect timeout", "timeout", tcpICETimeout, "signalRTT", signalingRTT)
 						t.handleConnectionFailed(true)
@@ -465,7 +602,7 @@ func (t *PCTransport) setICEConnectedAt(at time.Time) {
 			connTimeoutAfterICE = maxConnectTimeoutAfterICE
 		}
 		t.params.Logger.Debugw("setting connection timer after ice connected", "timeout", connTimeoutAfterICE, "iceDuration", iceDuration)
-		t.connectAfterICETimer = time.AfterFunc(connTimeoutAfterICE, func() {
+		t.connectAfterICETimer = t.params.Resources.AfterFunc(connTimeoutAfterICE, func() {
 			state := t.pc.ConnectionState()
 			// if pc is still checking or connected but not fully established after timeout, then fire connection fail
 			if state != webrtc.PeerConnectionStateClosed && state != webrtc.PeerConnectionStateFailed && !t.isFullyEstablished() {
@@ -512,7 +649,7 @@ func (t *PCTransport) IsShortConnection(at time.Time) (bool, time.Duration) {
 	return duration < shortConnectionThreshold, duration
 }
 
-func (t *PCTransport) getSelectedPair() (*webrtc.ICECandidatePair, error) {
+func (t *PCTransport) getICETransport() (*webrtc.ICETransport, error) {
 	sctp := t.pc.SCTP()
 	if sctp == nil {
 		return nil, errors.New("no SCTP")
@@ -528,9 +665,156 @@ func (t *PCTransport) getSelectedPair() (*webrtc.ICECandidatePair, error) {
 		return nil, errors.New("no ICE transport")
 	}
 
+	return iceTransport, nil
+}
+
+func (t *PCTransport) getSelectedPair() (*webrtc.ICECandidatePair, error) {
+	iceTransport, err := t.getICETransport()
+	if err != nil {
+		return nil, err
+	}
+
 	return iceTransport.GetSelectedCandidatePair()
 }
 
+// onSelectedCandidatePairChange follows the client across network changes. Remote candidates trickled in
+// mid-session are paired and checked by the ICE agent, once a pair on the new network is selected media moves
+// to it with the existing DTLS/SRTP context, no renegotiation or ICE restart is needed.
+func (t *PCTransport) onSelectedCandidatePairChange(pair *webrtc.ICECandidatePair) {
+	if pair == nil || pair.Remote == nil {
+		return
+	}
+	if t.params.CandidatePolicy != nil && !t.allowsSelectedPair(pair) {
+		t.enforceCandidatePolicy(pair)
+	}
+
+	t.lock.Lock()
+	previous := t.selectedPair
+	t.selectedPair = pair
+	t.lock.Unlock()
+
+	t.iceMetrics.SetSelectedPair(iceCandidatePairForMetrics(pair))
+	if previous == nil {
+		t.scheduleICEStats()
+	}
+	if previous == nil || previous.Remote == nil {
+		return
+	}
+	if previous.Remote.Address == pair.Remote.Address && previous.Remote.Port == pair.Remote.Port {
+		return
+	}
+
+	t.params.Logger.Infow(
+		"client network changed",
+		"previousAddr", fmt.Sprintf("%s:%d", previous.Remote.Address, previous.Remote.Port),
+		"remoteAddr", fmt.Sprintf("%s:%d", pair.Remote.Address, pair.Remote.Port),
+		"pair", pair,
+	)
+	if onNetworkChanged := t.getOnNetworkChanged(); onNetworkChanged != nil {
+		onNetworkChanged(previous, pair)
+	}
+}
+
+// allowsSelectedPair tells whether the candidate policy allows the remote candidate of a pair. Connectivity checks
+// can arrive before the candidate they are from is signalled, a peer reflexive candidate with the address of a
+// signalled one is taken for that one, including candidates dropped by the policy so that checks from their
+// address do not get them through as peer reflexive
+func (t *PCTransport) allowsSelectedPair(pair *webrtc.ICECandidatePair) bool {
+	typ := pair.Remote.Typ
+	if typ == webrtc.ICECandidateTypePrflx {
+		if candidate := t.findSignalledRemoteCandidate(pair.Remote); candidate != nil {
+			if signalledType, err := webrtc.NewICECandidateType(candidate.Type().String()); err == nil {
+				typ = signalledType
+			}
+		}
+	}
+	return t.params.CandidatePolicy.Allows(typ)
+}
+
+// enforceCandidatePolicy fails the connection over a pair the candidate policy does not allow. A peer reflexive
+// candidate may still turn out to be signalled, it is given a grace period to be
+func (t *PCTransport) enforceCandidatePolicy(pair *webrtc.ICECandidatePair) {
+	fail := func() {
+		t.params.Logger.Warnw("selected ICE candidate pair not allowed by candidate policy", nil, "pair", pair)
+		prometheus.ServiceOperationCounter.WithLabelValues("peer_connection", "error", "candidate_policy").Add(1)
+		t.handleConnectionFailed(false)
+	}
+
+	if pair.Remote.Typ != webrtc.ICECandidateTypePrflx || t.findSignalledRemoteCandidate(pair.Remote) != nil {
+		fail()
+		return
+	}
+
+	t.params.Resources.AfterFunc(candidatePolicyGracePeriod, func() {
+		if t.isClosed.Load() {
+			return
+		}
+		t.lock.RLock()
+		selected := t.selectedPair
+		t.lock.RUnlock()
+		if selected == pair && !t.allowsSelectedPair(pair) {
+			fail()
+		}
+	})
+}
+
+// findSignalledRemoteCandidate returns the signalled remote candidate with the address of a candidate, allowed or
+// filtered out, nil when none
+func (t *PCTransport) findSignalledRemoteCandidate(remote *webrtc.ICECandidate) ice.Candidate {
+	t.lock.RLock()
+	signalledRemoteCandidates := make([]string, 0, len(t.allowedRemoteCandidates)+len(t.filteredRemoteCandidates))
+	signalledRemoteCandidates = append(signalledRemoteCandidates, t.allowedRemoteCandidates...)
+	signalledRemoteCandidates = append(signalledRemoteCandidates, t.filteredRemoteCandidates...)
+	t.lock.RUnlock()
+
+	for _, ci := range signalledRemoteCandidates {
+		candidateValue := strings.TrimPrefix(ci, "candidate:")
+		candidate, err := ice.UnmarshalCandidate(candidateValue)
+		if err == nil &&
+			remote.Address == candidate.Address() &&
+			remote.Port == uint16(candidate.Port()) &&
+			remote.Protocol.String() == candidate.NetworkType().NetworkShort() {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// scheduleICEStats samples the RTP and RTCP bytes sent and received periodically, for them to be accounted to the
+// selected candidate pair
+func (t *PCTransport) scheduleICEStats() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.isClosed.Load() {
+		return
+	}
+
+	t.iceStatsTimer = t.params.Resources.AfterFunc(iceStatsInterval, func() {
+		t.recordICEStats()
+		t.scheduleICEStats()
+	})
+}
+
+func (t *PCTransport) recordICEStats() {
+	t.iceMetrics.RecordBytes(t.byteCounter.BytesSent(), t.byteCounter.BytesReceived())
+}
+
+// UpdateMediaRTT records a round trip time measured with RTCP for the selected candidate pair
+func (t *PCTransport) UpdateMediaRTT(rtt uint32) {
+	t.iceMetrics.RecordRTT(rtt)
+}
+
+func iceCandidatePairForMetrics(pair *webrtc.ICECandidatePair) prometheus.ICECandidatePair {
+	p := prometheus.ICECandidatePair{RemoteType: pair.Remote.Typ.String()}
+	if pair.Local != nil {
+		p.LocalType = pair.Local.Typ.String()
+		p.Protocol = pair.Local.Protocol.String()
+	} else {
+		p.Protocol = pair.Remote.Protocol.String()
+	}
+	return p
+}
+
 func (t *PCTransport) logICECandidates() {
 	t.postEvent(event{
 		signal: signalLogICECandidates,
@@ -857,6 +1141,11 @@ func (t *PCTransport) IsEstablished() bool {
 	return t.pc.ConnectionState() != webrtc.PeerConnectionStateNew
 }
 
+// IsICEDisconnected returns true when connectivity checks are failing, ICE will fail unless it recovers
+func (t *PCTransport) IsICEDisconnected() bool {
+	return t.pc.ICEConnectionState() == webrtc.ICEConnectionStateDisconnected
+}
+
 func (t *PCTransport) HasEverConnected() bool {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
@@ -885,6 +1174,24 @@ func (t *PCTransport) SendDataPacket(dp *livekit.DataPacket, data []byte) error
 	return dc.Send(data)
 }
 
+// createAllocatorTrace records the inputs of the stream allocator when a trace directory is configured, traces can
+// be replayed with the stream allocator simulator
+func (t *PCTransport) createAllocatorTrace() *streamallocator.TraceRecorder {
+	dir := t.params.CongestionControlConfig.TraceDir
+	if dir == "" {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s_%d.jsonl", t.params.ParticipantID, time.Now().UnixMilli())
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.params.Logger.Warnw("could not create stream allocator trace", err)
+		return nil
+	}
+	t.allocatorTrace = f
+	return streamallocator.NewTraceRecorder(f, nil, t.params.Logger)
+}
+
 func (t *PCTransport) Close() {
 	t.eventChMu.Lock()
 	if t.isClosed.Swap(true) {
@@ -898,8 +1205,30 @@ func (t *PCTransport) Close() {
 	if t.streamAllocator != nil {
 		t.streamAllocator.Stop()
 	}
+	if t.allocatorTrace != nil {
+		_ = t.allocatorTrace.Close()
+	}
+	if t.pacer != nil {
+		t.pacer.Stop()
+	}
+
+	t.lock.Lock()
+	iceStatsTimer := t.iceStatsTimer
+	t.iceStatsTimer = nil
+	activeTCPRemotes := t.activeTCPRemotes
+	t.activeTCPRemotes = nil
+	t.lock.Unlock()
+	for _, address := range activeTCPRemotes {
+		t.params.Config.ActiveTCPMux.RemoveRemoteCandidate(address)
+	}
+	if iceStatsTimer != nil {
+		iceStatsTimer.Stop()
+		t.recordICEStats()
+	}
+	t.iceMetrics.Close()
 
 	_ = t.pc.Close()
+	t.params.Resources.AddSockets(-1)
 
 	t.clearConnTimer()
 }
@@ -976,6 +1305,19 @@ func (t *PCTransport) getOnFailed() func(isShortLived bool) {
 	return t.onFailed
 }
 
+func (t *PCTransport) OnNetworkChanged(f func(previous, current *webrtc.ICECandidatePair)) {
+	t.lock.Lock()
+	t.onNetworkChanged = f
+	t.lock.Unlock()
+}
+
+func (t *PCTransport) getOnNetworkChanged() func(previous, current *webrtc.ICECandidatePair) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.onNetworkChanged
+}
+
 func (t *PCTransport) OnTrack(f func(track *webrtc.TrackRemote, rtpReceiver *webrtc.RTPReceiver)) {
 	t.pc.OnTrack(f)
 }
@@ -1126,6 +1468,30 @@ func (t *PCTransport) SetAllowPauseOfStreamAllocator(allowPause bool) {
 	t.streamAllocator.SetAllowPause(allowPause)
 }
 
+func (t *PCTransport) SeedChannelCapacityOfStreamAllocator(channelCapacity int64) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.SeedChannelCapacity(channelCapacity)
+}
+
+func (t *PCTransport) GetChannelCapacityOfStreamAllocator() int64 {
+	if t.streamAllocator == nil {
+		return 0
+	}
+
+	return t.streamAllocator.GetChannelCapacity()
+}
+
+func (t *PCTransport) SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity int64) {
+	if t.streamAllocator == nil {
+		return
+	}
+
+	t.streamAllocator.SetMaxChannelCapacity(maxChannelCapacity)
+}
+
 func (t *PCTransport) SetChannelCapacityOfStreamAllocator(channelCapacity int64) {
 	if t.streamAllocator == nil {
 		return
@@ -1134,6 +1500,14 @@ func (t *PCTransport) SetChannelCapacityOfStreamAllocator(channelCapacity int64)
 	t.streamAllocator.SetChannelCapacity(channelCapacity)
 }
 
+func (t *PCTransport) GetStreamAllocatorDiagnostics(trackID livekit.TrackID) (*streamallocator.Diagnostics, error) {
+	if t.streamAllocator == nil {
+		return nil, streamallocator.ErrDiagnosticsTrackNotFound
+	}
+
+	return t.streamAllocator.GetDiagnostics(trackID)
+}
+
 func (t *PCTransport) GetICEConnectionType() types.ICEConnectionType {
 	unknown := types.ICEConnectionTypeUnknown
 	if t.pc == nil {
@@ -1150,20 +1524,8 @@ func (t *PCTransport) GetICEConnectionType() types.ICEConnectionType {
 		// if the remote relay candidate pings us *before* we get a relay candidate,
 		// Pion would have created a prflx candidate with the same address as the relay candidate.
 		// to report an accurate connection type, we'll compare to see if existing relay candidates match
-		t.lock.RLock()
-		allowedRemoteCandidates := t.allowedRemoteCandidates
-		t.lock.RUnlock()
-
-		for _, ci := range allowedRemoteCandidates {
-			candidateValue := strings.TrimPrefix(ci, "candidate:")
-			candidate, err := ice.UnmarshalCandidate(candidateValue)
-			if err == nil && candidate.Type() == ice.CandidateTypeRelay {
-				if p.Remote.Address == candidate.Address() &&
-					p.Remote.Port == uint16(candidate.Port()) &&
-					p.Remote.Protocol.String() == candidate.NetworkType().NetworkShort() {
-					return types.ICEConnectionTypeTURN
-				}
-			}
+		if candidate := t.findSignalledRemoteCandidate(p.Remote); candidate != nil && candidate.Type() == ice.CandidateTypeRelay {
+			return types.ICEConnectionTypeTURN
 		}
 	}
 	if p.Remote.Protocol == webrtc.ICEProtocolTCP {
@@ -1469,9 +1831,9 @@ func (t *PCTransport) clearLocalDescriptionSent() {
 	t.allowedLocalCandidates = nil
 	t.lock.Lock()
 	t.allowedRemoteCandidates = nil
+	t.filteredRemoteCandidates = nil
 	t.lock.Unlock()
 	t.filteredLocalCandidates = nil
-	t.filteredRemoteCandidates = nil
 }
 
 func (t *PCTransport) handleLocalICECandidate(e *event) error {
@@ -1489,19 +1851,32 @@ func (t *PCTransport) handleLocalICECandidate(e *event) error {
 		return nil
 	}
 
-	if c != nil {
-		t.allowedLocalCandidates = append(t.allowedLocalCandidates, c.String())
-	}
-	if t.cacheLocalCandidates {
-		t.cachedLocalCandidates = append(t.cachedLocalCandidates, c)
-		return nil
+	candidates := []*webrtc.ICECandidate{c}
+	if active := t.params.Config.ActiveTCPMux.ActiveCandidate(c); active != nil {
+		// clients learn that the node connects out to their passive TCP candidates
+		candidates = append(candidates, active)
 	}
 
-	if onICECandidate := t.getOnICECandidate(); onICECandidate != nil {
-		return onICECandidate(c)
+	for _, candidate := range candidates {
+		if candidate != nil {
+			candidate = t.params.Config.CandidatePrioritizer.Candidate(candidate)
+			t.allowedLocalCandidates = append(t.allowedLocalCandidates, candidate.String())
+		}
+		if t.cacheLocalCandidates {
+			t.cachedLocalCandidates = append(t.cachedLocalCandidates, candidate)
+			continue
+		}
+
+		onICECandidate := t.getOnICECandidate()
+		if onICECandidate == nil {
+			return ErrNoICECandidateHandler
+		}
+		if err := onICECandidate(candidate); err != nil {
+			return err
+		}
 	}
 
-	return ErrNoICECandidateHandler
+	return nil
 }
 
 func (t *PCTransport) handleRemoteICECandidate(e *event) error {
@@ -1510,25 +1885,43 @@ func (t *PCTransport) handleRemoteICECandidate(e *event) error {
 	filtered := false
 	if t.preferTCP.Load() && !strings.Contains(c.Candidate, "tcp") {
 		t.params.Logger.Debugw("filtering out remote candidate", "candidate", c.Candidate)
-		t.filteredRemoteCandidates = append(t.filteredRemoteCandidates, c.Candidate)
+		filtered = true
+	} else if !t.params.CandidatePolicy.AllowsCandidate(c.Candidate) {
+		t.params.Logger.Debugw("filtering out remote candidate not allowed by candidate policy", "candidate", c.Candidate)
 		filtered = true
 	}
 
 	if filtered {
+		t.lock.Lock()
+		t.filteredRemoteCandidates = append(t.filteredRemoteCandidates, c.Candidate)
+		t.lock.Unlock()
 		return nil
 	}
 
-	t.lock.Lock()
-	t.allowedRemoteCandidates = append(t.allowedRemoteCandidates, c.Candidate)
-	t.lock.Unlock()
-
-	if t.pc.RemoteDescription() == nil {
-		t.pendingRemoteCandidates = append(t.pendingRemoteCandidates, c)
-		return nil
+	candidates := []*webrtc.ICECandidateInit{c}
+	if synthesized, ok := synthesizeNAT64Candidate(t.params.Config.NAT64Prefix, c.Candidate); ok {
+		// IPv4 clients of an IPv6 only deployment are reached through the NAT64 gateway
+		sc := *c
+		sc.Candidate = synthesized
+		candidates = append(candidates, &sc)
 	}
 
-	if err := t.pc.AddICECandidate(*c); err != nil {
-		return errors.Wrap(err, "add ice candidate failed")
+	for _, candidate := range candidates {
+		t.lock.Lock()
+		t.allowedRemoteCandidates = append(t.allowedRemoteCandidates, candidate.Candidate)
+		if address := t.params.Config.ActiveTCPMux.AddRemoteCandidate(candidate.Candidate); address != "" {
+			t.activeTCPRemotes = append(t.activeTCPRemotes, address)
+		}
+		t.lock.Unlock()
+
+		if t.pc.RemoteDescription() == nil {
+			t.pendingRemoteCandidates = append(t.pendingRemoteCandidates, candidate)
+			continue
+		}
+
+		if err := t.pc.AddICECandidate(*candidate); err != nil {
+			return errors.Wrap(err, "add ice candidate failed")
+		}
 	}
 
 	return nil
@@ -1553,7 +1946,7 @@ func (t *PCTransport) setNegotiationState(state NegotiationState) {
 	}
 }
 
-func (t *PCTransport) filterCandidates(sd webrtc.SessionDescription, preferTCP bool) webrtc.SessionDescription {
+func (t *PCTransport) filterCandidates(sd webrtc.SessionDescription, preferTCP bool, remote bool) webrtc.SessionDescription {
 	parsed, err := sd.Unmarshal()
 	if err != nil {
 		t.params.Logger.Errorw("could not unmarshal SDP to filter candidates", err)
@@ -1564,12 +1957,22 @@ func (t *PCTransport) filterCandidates(sd webrtc.SessionDescription, preferTCP b
 		filteredAttrs := make([]sdp.Attribute, 0, len(attrs))
 		for _, a := range attrs {
 			if a.Key == sdp.AttrKeyCandidate {
-				if preferTCP {
-					if strings.Contains(a.Value, "tcp") {
-						filteredAttrs = append(filteredAttrs, a)
+				if remote && !t.params.CandidatePolicy.AllowsCandidate(a.Value) {
+					t.lock.Lock()
+					t.filteredRemoteCandidates = append(t.filteredRemoteCandidates, a.Value)
+					t.lock.Unlock()
+					continue
+				}
+				a.Value = t.params.Config.CandidatePrioritizer.CandidateAttribute(a.Value)
+				if preferTCP && !strings.Contains(a.Value, "tcp") {
+					continue
+				}
+				filteredAttrs = append(filteredAttrs, a)
+				if remote {
+					// IPv4 clients of an IPv6 only deployment are reached through the NAT64 gateway
+					if synthesized, ok := synthesizeNAT64Candidate(t.params.Config.NAT64Prefix, a.Value); ok {
+						filteredAttrs = append(filteredAttrs, sdp.Attribute{Key: sdp.AttrKeyCandidate, Value: synthesized})
 					}
-				} else {
-					filteredAttrs = append(filteredAttrs, a)
 				}
 			} else {
 				filteredAttrs = append(filteredAttrs, a)
@@ -1593,6 +1996,154 @@ func (t *PCTransport) filterCandidates(sd webrtc.SessionDescription, preferTCP b
 	return sd
 }
 
+// addFlexFECStreams declares the repair stream of each video sender, RFC 8627 style, to the remote side
+func (t *PCTransport) addFlexFECStreams(sd webrtc.SessionDescription) webrtc.SessionDescription {
+	return t.addRepairStreams(sd, "FEC-FR", []string{strconv.Itoa(flexFECPayloadType)}, t.fecProtector.FECSSRC)
+}
+
+// addRTXStreams declares the retransmission stream of each video sender, RFC 4588 style, to the remote side
+func (t *PCTransport) addRTXStreams(sd webrtc.SessionDescription) webrtc.SessionDescription {
+	formats := make([]string, 0, len(rtxPayloadTypes))
+	for _, pt := range rtxPayloadTypes {
+		formats = append(formats, strconv.Itoa(int(pt)))
+	}
+	return t.addRepairStreams(sd, "FID", formats, sfu.RTXSSRC)
+}
+
+// addRepairStreams adds an SSRC group of the given semantics pairing each video sender with its repair stream to media
+// sections offering one of the repair formats
+func (t *PCTransport) addRepairStreams(
+	sd webrtc.SessionDescription,
+	semantics string,
+	repairFormats []string,
+	repairSSRC func(mediaSSRC uint32) uint32,
+) webrtc.SessionDescription {
+	parsed, err := sd.Unmarshal()
+	if err != nil {
+		t.params.Logger.Errorw("could not unmarshal SDP to add repair streams", err, "semantics", semantics)
+		return sd
+	}
+
+	mediaSSRCs := make(map[string]uint32)
+	for _, tr := range t.pc.GetTransceivers() {
+		sender := tr.Sender()
+		if tr.Kind() != webrtc.RTPCodecTypeVideo || sender == nil || sender.Track() == nil || tr.Mid() == "" {
+			continue
+		}
+		if encodings := sender.GetParameters().Encodings; len(encodings) != 0 {
+			mediaSSRCs[tr.Mid()] = uint32(encodings[0].SSRC)
+		}
+	}
+
+	for _, m := range parsed.MediaDescriptions {
+		mid, _ := m.Attribute(sdp.AttrKeyMID)
+		mediaSSRC, ok := mediaSSRCs[mid]
+		if !ok {
+			continue
+		}
+		hasRepairFormat := false
+		for _, format := range m.MediaName.Formats {
+			for _, repairFormat := range repairFormats {
+				if format == repairFormat {
+					hasRepairFormat = true
+					break
+				}
+			}
+		}
+		if !hasRepairFormat {
+			continue
+		}
+
+		ssrc := repairSSRC(mediaSSRC)
+		attrs := []sdp.Attribute{
+			sdp.NewAttribute(sdp.AttrKeySSRCGroup, fmt.Sprintf("%s %d %d", semantics, mediaSSRC, ssrc)),
+		}
+		prefix := fmt.Sprintf("%d ", mediaSSRC)
+		for _, a := range m.Attributes {
+			if a.Key == sdp.AttrKeySSRC && strings.HasPrefix(a.Value, prefix) {
+				attrs = append(attrs, sdp.NewAttribute(sdp.AttrKeySSRC, fmt.Sprintf("%d %s", ssrc, strings.TrimPrefix(a.Value, prefix))))
+			}
+		}
+		m.Attributes = append(m.Attributes, attrs...)
+	}
+
+	bytes, err := parsed.Marshal()
+	if err != nil {
+		t.params.Logger.Errorw("could not marshal SDP to add repair streams", err, "semantics", semantics)
+		return sd
+	}
+	sd.SDP = string(bytes)
+	return sd
+}
+
+// NegotiatedCodecs returns the media codecs the remote description accepted, nil until it is set
+func (t *PCTransport) NegotiatedCodecs() []*livekit.Codec {
+	return negotiatedCodecs(t.pc.RemoteDescription())
+}
+
+// negotiatedCodecs returns the media codecs of the media sections of a session description which are not rejected,
+// retransmission and redundancy codecs are left out
+func negotiatedCodecs(sd *webrtc.SessionDescription) []*livekit.Codec {
+	if sd == nil {
+		return nil
+	}
+	parsed, err := sd.Unmarshal()
+	if err != nil {
+		return nil
+	}
+
+	var codecs []*livekit.Codec
+	for _, m := range parsed.MediaDescriptions {
+		kind := m.MediaName.Media
+		if (kind != "audio" && kind != "video") || m.MediaName.Port.Value == 0 {
+			continue
+		}
+		for _, a := range m.Attributes {
+			if a.Key != "rtpmap" {
+				continue
+			}
+			// <payload type> <encoding name>/<clock rate>[/<channels>]
+			_, encoding, ok := strings.Cut(a.Value, " ")
+			if !ok {
+				continue
+			}
+			name, _, _ := strings.Cut(encoding, "/")
+			switch strings.ToLower(name) {
+			case "rtx", "red", "ulpfec", "flexfec-03":
+				continue
+			}
+			mime := kind + "/" + name
+			found := false
+			for _, c := range codecs {
+				if strings.EqualFold(c.Mime, mime) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				codecs = append(codecs, &livekit.Codec{Mime: mime})
+			}
+		}
+	}
+	return codecs
+}
+
+func isFlexFECNegotiated(sd webrtc.SessionDescription) bool {
+	parsed, err := sd.Unmarshal()
+	if err != nil {
+		return false
+	}
+
+	for _, m := range parsed.MediaDescriptions {
+		for _, a := range m.Attributes {
+			if a.Key == "rtpmap" && strings.Contains(strings.ToLower(a.Value), "flexfec-03/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (t *PCTransport) clearSignalStateCheckTimer() {
 	if t.signalStateCheckTimer != nil {
 		t.signalStateCheckTimer.Stop()
@@ -1604,7 +2155,7 @@ func (t *PCTransport) setupSignalStateCheckTimer() {
 	t.clearSignalStateCheckTimer()
 
 	negotiateVersion := t.negotiateCounter.Inc()
-	t.signalStateCheckTimer = time.AfterFunc(negotiationFailedTimeout, func() {
+	t.signalStateCheckTimer = t.params.Resources.AfterFunc(negotiationFailedTimeout, func() {
 		t.clearSignalStateCheckTimer()
 
 		failed := t.negotiationState != NegotiationStateNone
@@ -1657,6 +2208,7 @@ func (t *PCTransport) createAndSendOffer(options *webrtc.OfferOptions) error {
 
 	if options != nil && options.ICERestart {
 		t.clearLocalDescriptionSent()
+		t.iceMetrics.ICERestart(prometheus.ICERestartInitiatorServer)
 	}
 
 	offer, err := t.pc.CreateOffer(options)
@@ -1692,10 +2244,16 @@ func (t *PCTransport) createAndSendOffer(options *webrtc.OfferOptions) error {
 	// Filtered offer is sent to remote so that remote does not
 	// see filtered candidates.
 	//
-	offer = t.filterCandidates(offer, preferTCP)
+	offer = t.filterCandidates(offer, preferTCP, false)
 	if preferTCP {
 		t.params.Logger.Debugw("local offer (filtered)", "sdp", offer.SDP)
 	}
+	if t.fecProtector != nil {
+		offer = t.addFlexFECStreams(offer)
+	}
+	if t.params.IsSendSide && t.params.Config.SubscriberRTX && !t.params.AudioOnly {
+		offer = t.addRTXStreams(offer)
+	}
 
 	// indicate waiting for remote
 	t.setNegotiationState(NegotiationStateRemote)
@@ -1750,7 +2308,7 @@ func (t *PCTransport) setRemoteDescription(sd webrtc.SessionDescription) error {
 	if preferTCP {
 		t.params.Logger.Debugw("remote description (unfiltered)", "type", sd.Type, "sdp", sd.SDP)
 	}
-	sd = t.filterCandidates(sd, preferTCP)
+	sd = t.filterCandidates(sd, preferTCP, true)
 	if preferTCP {
 		t.params.Logger.Debugw("remote description (filtered)", "type", sd.Type, "sdp", sd.SDP)
 	}
@@ -1814,7 +2372,7 @@ func (t *PCTransport) createAndSendAnswer() error {
 	// Filtered answer is sent to remote so that remote does not
 	// see filtered candidates.
 	//
-	answer = t.filterCandidates(answer, preferTCP)
+	answer = t.filterCandidates(answer, preferTCP, false)
 	if preferTCP {
 		t.params.Logger.Debugw("local answer (filtered)", "sdp", answer.SDP)
 	}
@@ -1848,8 +2406,11 @@ func (t *PCTransport) handleRemoteOfferReceived(sd *webrtc.SessionDescription) e
 		return nil
 	}
 
-	if offerRestartICE && t.resetShortConnOnICERestart.CompareAndSwap(true, false) {
-		t.resetShortConn()
+	if offerRestartICE {
+		t.iceMetrics.ICERestart(prometheus.ICERestartInitiatorClient)
+		if t.resetShortConnOnICERestart.CompareAndSwap(true, false) {
+			t.resetShortConn()
+		}
 	}
 
 	if err := t.setRemoteDescription(*sd); err != nil {
@@ -1867,6 +2428,9 @@ func (t *PCTransport) handleRemoteAnswerReceived(sd *webrtc.SessionDescription)
 	if err := t.setRemoteDescription(*sd); err != nil {
 		return err
 	}
+	if t.fecProtector != nil {
+		t.fecProtector.SetNegotiated(isFlexFECNegotiated(*sd))
+	}
 
 	t.clearSignalStateCheckTimer()
 
@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/testutils"
 	"github.com/livekit/protocol/livekit"
 )
@@ -362,7 +364,7 @@ func TestFilteringCandidates(t *testing.T) {
 
 	// should not filter out UDP candidates if TCP is not preferred
 	offer = *transport.pc.LocalDescription()
-	filteredOffer := transport.filterCandidates(offer, false)
+	filteredOffer := transport.filterCandidates(offer, false, false)
 	require.EqualValues(t, offer.SDP, filteredOffer.SDP)
 
 	parsed, err := offer.Unmarshal()
@@ -440,13 +442,30 @@ func TestFilteringCandidates(t *testing.T) {
 	require.Equal(t, 2, tcp)
 
 	transport.SetPreferTCP(true)
-	filteredOffer = transport.filterCandidates(offer, true)
+	filteredOffer = transport.filterCandidates(offer, true, false)
 	parsed, err = filteredOffer.Unmarshal()
 	require.NoError(t, err)
 	udp, tcp = getNumTransportTypeCandidates(parsed)
 	require.Zero(t, udp)
 	require.Equal(t, 2, tcp)
 
+	// remote candidates are filtered by the candidate policy, host ones are not relayed
+	transport.params.CandidatePolicy = NewCandidatePolicy(config.CandidatePolicyConfig{RelayOnly: true})
+	filteredOffer = transport.filterCandidates(offer, false, true)
+	parsed, err = filteredOffer.Unmarshal()
+	require.NoError(t, err)
+	udp, tcp = getNumTransportTypeCandidates(parsed)
+	require.Zero(t, udp)
+	require.Zero(t, tcp)
+
+	// local candidates are not
+	filteredOffer = transport.filterCandidates(offer, false, false)
+	parsed, err = filteredOffer.Unmarshal()
+	require.NoError(t, err)
+	udp, tcp = getNumTransportTypeCandidates(parsed)
+	require.NotZero(t, udp)
+	require.Equal(t, 2, tcp)
+
 	transport.Close()
 }
 
@@ -543,3 +562,258 @@ func TestConfigureAudioTransceiver(t *testing.T) {
 		})
 	}
 }
+
+func TestFlexFECStreams(t *testing.T) {
+	params := TransportParams{
+		ParticipantID:       "id",
+		ParticipantIdentity: "identity",
+		Config: &WebRTCConfig{
+			FlexFEC: config.FlexFECConfig{
+				Enabled:     true,
+				MinLoss:     0.02,
+				MaxOverhead: 0.3,
+			},
+		},
+		EnabledCodecs: []*livekit.Codec{
+			{Mime: webrtc.MimeTypeOpus},
+			{Mime: webrtc.MimeTypeVP8},
+		},
+		IsSendSide: true,
+	}
+	transport, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	audioTrack, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "stream")
+	require.NoError(t, err)
+	_, err = transport.pc.AddTrack(audioTrack)
+	require.NoError(t, err)
+
+	videoTrack, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "stream")
+	require.NoError(t, err)
+	sender, err := transport.pc.AddTrack(videoTrack)
+	require.NoError(t, err)
+	mediaSSRC := uint32(sender.GetParameters().Encodings[0].SSRC)
+
+	offer, err := transport.pc.CreateOffer(nil)
+	require.NoError(t, err)
+	require.NoError(t, transport.pc.SetLocalDescription(offer))
+	require.True(t, isFlexFECNegotiated(offer))
+
+	munged := transport.addFlexFECStreams(offer)
+	parsed, err := munged.Unmarshal()
+	require.NoError(t, err)
+
+	fecSSRC := transport.fecProtector.FECSSRC(mediaSSRC)
+	for _, m := range parsed.MediaDescriptions {
+		group, hasGroup := m.Attribute(sdp.AttrKeySSRCGroup)
+		if m.MediaName.Media == "audio" {
+			require.False(t, hasGroup)
+			continue
+		}
+
+		require.Equal(t, fmt.Sprintf("FEC-FR %d %d", mediaSSRC, fecSSRC), group)
+		var fecCNAME, mediaCNAME string
+		for _, a := range m.Attributes {
+			if a.Key != sdp.AttrKeySSRC || !strings.Contains(a.Value, "cname:") {
+				continue
+			}
+			if strings.HasPrefix(a.Value, fmt.Sprintf("%d ", fecSSRC)) {
+				fecCNAME = a.Value[strings.Index(a.Value, " "):]
+			}
+			if strings.HasPrefix(a.Value, fmt.Sprintf("%d ", mediaSSRC)) {
+				mediaCNAME = a.Value[strings.Index(a.Value, " "):]
+			}
+		}
+		require.NotEmpty(t, fecCNAME)
+		require.Equal(t, mediaCNAME, fecCNAME)
+	}
+
+	// not negotiated without FlexFEC in the answer
+	params.Config = &WebRTCConfig{}
+	params.IsSendSide = false
+	answerer, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer answerer.Close()
+
+	require.NoError(t, answerer.pc.SetRemoteDescription(offer))
+	answer, err := answerer.pc.CreateAnswer(nil)
+	require.NoError(t, err)
+	require.False(t, isFlexFECNegotiated(answer))
+}
+
+func TestRTXStreams(t *testing.T) {
+	params := TransportParams{
+		ParticipantID:       "id",
+		ParticipantIdentity: "identity",
+		Config: &WebRTCConfig{
+			SubscriberRTX: true,
+		},
+		EnabledCodecs: []*livekit.Codec{
+			{Mime: webrtc.MimeTypeOpus},
+			{Mime: webrtc.MimeTypeVP8},
+		},
+		IsSendSide: true,
+	}
+	transport, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	videoTrack, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "stream")
+	require.NoError(t, err)
+	sender, err := transport.pc.AddTrack(videoTrack)
+	require.NoError(t, err)
+	mediaSSRC := uint32(sender.GetParameters().Encodings[0].SSRC)
+
+	offer, err := transport.pc.CreateOffer(nil)
+	require.NoError(t, err)
+	require.NoError(t, transport.pc.SetLocalDescription(offer))
+	require.Contains(t, offer.SDP, "a=fmtp:97 apt=96")
+
+	munged := transport.addRTXStreams(offer)
+	parsed, err := munged.Unmarshal()
+	require.NoError(t, err)
+	require.Len(t, parsed.MediaDescriptions, 1)
+	group, ok := parsed.MediaDescriptions[0].Attribute(sdp.AttrKeySSRCGroup)
+	require.True(t, ok)
+	require.Equal(t, fmt.Sprintf("FID %d %d", mediaSSRC, sfu.RTXSSRC(mediaSSRC)), group)
+	require.Contains(t, munged.SDP, fmt.Sprintf("a=ssrc:%d cname:", sfu.RTXSSRC(mediaSSRC)))
+
+	// a subscriber supporting RTX accepts it, retransmissions are in-band for others
+	me := &webrtc.MediaEngine{}
+	require.NoError(t, me.RegisterDefaultCodecs())
+	answerer, err := webrtc.NewAPI(webrtc.WithMediaEngine(me)).NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	defer answerer.Close()
+	require.NoError(t, answerer.SetRemoteDescription(munged))
+	answer, err := answerer.CreateAnswer(nil)
+	require.NoError(t, err)
+	require.Contains(t, answer.SDP, "a=fmtp:97 apt=96")
+
+	params.Config = &WebRTCConfig{}
+	withoutRTX, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer withoutRTX.Close()
+	_, err = withoutRTX.pc.AddTrack(videoTrack)
+	require.NoError(t, err)
+	offer, err = withoutRTX.pc.CreateOffer(nil)
+	require.NoError(t, err)
+	require.NotContains(t, offer.SDP, "apt=")
+}
+
+func TestAudioOnlyTransport(t *testing.T) {
+	params := TransportParams{
+		ParticipantID:       "id",
+		ParticipantIdentity: "identity",
+		Config: &WebRTCConfig{
+			FlexFEC:       config.FlexFECConfig{Enabled: true},
+			SubscriberRTX: true,
+			Pacer:         config.PacerConfig{Enabled: true},
+		},
+		DirectionConfig: DirectionConfig{
+			RTPHeaderExtension: RTPHeaderExtensionConfig{
+				Audio: []string{sdp.AudioLevelURI, sdp.TransportCCURI},
+				Video: []string{sdp.TransportCCURI},
+			},
+		},
+		EnabledCodecs: []*livekit.Codec{
+			{Mime: webrtc.MimeTypeOpus},
+			{Mime: webrtc.MimeTypeVP8},
+		},
+		IsSendSide: true,
+		AudioOnly:  true,
+	}
+	transport, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer transport.Close()
+
+	require.Nil(t, transport.streamAllocator)
+	require.Nil(t, transport.fecProtector)
+	// nothing estimates the channel to pace to
+	require.Nil(t, transport.pacer)
+
+	audioTrack, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "stream")
+	require.NoError(t, err)
+	_, err = transport.pc.AddTrack(audioTrack)
+	require.NoError(t, err)
+	offer, err := transport.pc.CreateOffer(nil)
+	require.NoError(t, err)
+	require.Contains(t, offer.SDP, sdp.AudioLevelURI)
+	require.NotContains(t, offer.SDP, sdp.TransportCCURI)
+	require.NotContains(t, offer.SDP, "apt=")
+}
+
+func TestICECredentialsPerTransport(t *testing.T) {
+	conf := &WebRTCConfig{}
+	conf.SettingEngine.SetICECredentials("sharedufrag", "sharedpasswordsharedpassword")
+	params := TransportParams{
+		ParticipantID:       "id",
+		ParticipantIdentity: "identity",
+		Config:              conf,
+		IsOfferer:           true,
+	}
+
+	credentials := func() (string, string) {
+		transport, err := NewPCTransport(params)
+		require.NoError(t, err)
+		defer transport.Close()
+		_, err = transport.pc.CreateDataChannel("test", nil)
+		require.NoError(t, err)
+		offer, err := transport.pc.CreateOffer(nil)
+		require.NoError(t, err)
+		parsed, err := offer.Unmarshal()
+		require.NoError(t, err)
+		ufrag, _ := parsed.MediaDescriptions[0].Attribute("ice-ufrag")
+		pwd, _ := parsed.MediaDescriptions[0].Attribute("ice-pwd")
+		return ufrag, pwd
+	}
+
+	ufrag1, pwd1 := credentials()
+	ufrag2, pwd2 := credentials()
+	require.NotEmpty(t, ufrag1)
+	require.NotEqual(t, "sharedufrag", ufrag1)
+	require.NotEqual(t, ufrag1, ufrag2)
+	require.NotEqual(t, pwd1, pwd2)
+}
+
+func TestNegotiatedCodecs(t *testing.T) {
+	params := TransportParams{
+		ParticipantID:       "id",
+		ParticipantIdentity: "identity",
+		Config:              &WebRTCConfig{},
+		EnabledCodecs: []*livekit.Codec{
+			{Mime: webrtc.MimeTypeOpus},
+			{Mime: webrtc.MimeTypeVP8},
+			{Mime: webrtc.MimeTypeH264},
+		},
+		IsSendSide: true,
+	}
+	transport, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer transport.Close()
+	require.Nil(t, transport.NegotiatedCodecs())
+
+	videoTrack, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "stream")
+	require.NoError(t, err)
+	_, err = transport.pc.AddTrack(videoTrack)
+	require.NoError(t, err)
+	offer, err := transport.pc.CreateOffer(nil)
+	require.NoError(t, err)
+	require.NoError(t, transport.pc.SetLocalDescription(offer))
+
+	// the subscriber only decodes VP8
+	params.EnabledCodecs = []*livekit.Codec{
+		{Mime: webrtc.MimeTypeOpus},
+		{Mime: webrtc.MimeTypeVP8},
+	}
+	params.IsSendSide = false
+	answerer, err := NewPCTransport(params)
+	require.NoError(t, err)
+	defer answerer.Close()
+	require.NoError(t, answerer.pc.SetRemoteDescription(offer))
+	answer, err := answerer.pc.CreateAnswer(nil)
+	require.NoError(t, err)
+	require.NoError(t, transport.pc.SetRemoteDescription(answer))
+
+	require.Equal(t, []*livekit.Codec{{Mime: webrtc.MimeTypeVP8}}, transport.NegotiatedCodecs())
+}
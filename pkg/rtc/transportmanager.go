@@ -19,6 +19,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 )
@@ -50,7 +51,10 @@ type TransportManagerParams struct {
 	TCPFallbackRTTThreshold  int
 	AllowUDPUnstableFallback bool
 	TURNSEnabled             bool
+	AudioOnly                bool
+	CandidatePolicy          *CandidatePolicy
 	Logger                   logger.Logger
+	Resources                *utils.ResourceOwner
 }
 
 type TransportManager struct {
@@ -60,6 +64,7 @@ type TransportManager struct {
 
 	publisher               *PCTransport
 	subscriber              *PCTransport
+	enabledCodecs           []*livekit.Codec
 	failureCount            int
 	isTransportReconfigured bool
 	lastFailure             time.Time
@@ -105,10 +110,14 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 				break
 			}
 		}
+		if params.AudioOnly && !strings.HasPrefix(strings.ToLower(c.Mime), "audio/") {
+			disabled = true
+		}
 		if !disabled {
 			enabledCodecs = append(enabledCodecs, c)
 		}
 	}
+	t.enabledCodecs = enabledCodecs
 
 	publisher, err := NewPCTransport(TransportParams{
 		ParticipantID:           params.SID,
@@ -122,6 +131,9 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 		Logger:                  LoggerWithPCTarget(params.Logger, livekit.SignalTarget_PUBLISHER),
 		SimTracks:               params.SimTracks,
 		ClientInfo:              params.ClientInfo,
+		AudioOnly:               params.AudioOnly,
+		Resources:               params.Resources,
+		CandidatePolicy:         params.CandidatePolicy,
 	})
 	if err != nil {
 		return nil, err
@@ -155,6 +167,9 @@ func NewTransportManager(params TransportManagerParams) (*TransportManager, erro
 		ClientInfo:              params.ClientInfo,
 		IsOfferer:               true,
 		IsSendSide:              true,
+		AudioOnly:               params.AudioOnly,
+		Resources:               params.Resources,
+		CandidatePolicy:         params.CandidatePolicy,
 	})
 	if err != nil {
 		return nil, err
@@ -221,6 +236,10 @@ func (t *TransportManager) IsPublisherEstablished() bool {
 	return t.publisher.IsEstablished()
 }
 
+func (t *TransportManager) HasICEDisconnected() bool {
+	return t.publisher.IsICEDisconnected() || t.subscriber.IsICEDisconnected()
+}
+
 func (t *TransportManager) GetPublisherMid(rtpReceiver *webrtc.RTPReceiver) string {
 	return t.publisher.GetMid(rtpReceiver)
 }
@@ -281,6 +300,15 @@ func (t *TransportManager) OnAnyTransportFailed(f func()) {
 	t.onAnyTransportFailed = f
 }
 
+func (t *TransportManager) OnAnyTransportNetworkChanged(f func(target livekit.SignalTarget, previous, current *webrtc.ICECandidatePair)) {
+	t.publisher.OnNetworkChanged(func(previous, current *webrtc.ICECandidatePair) {
+		f(livekit.SignalTarget_PUBLISHER, previous, current)
+	})
+	t.subscriber.OnNetworkChanged(func(previous, current *webrtc.ICECandidatePair) {
+		f(livekit.SignalTarget_SUBSCRIBER, previous, current)
+	})
+}
+
 func (t *TransportManager) OnAnyTransportNegotiationFailed(f func()) {
 	t.publisher.OnNegotiationFailed(f)
 	t.subscriber.OnNegotiationFailed(f)
@@ -550,6 +578,43 @@ func (t *TransportManager) GetICEConnectionType() types.ICEConnectionType {
 	return t.getTransport(true).GetICEConnectionType()
 }
 
+func (t *TransportManager) GetSelectedICECandidatePairs() map[livekit.SignalTarget]*webrtc.ICECandidatePair {
+	pairs := make(map[livekit.SignalTarget]*webrtc.ICECandidatePair)
+	for target, transport := range map[livekit.SignalTarget]*PCTransport{
+		livekit.SignalTarget_PUBLISHER:  t.publisher,
+		livekit.SignalTarget_SUBSCRIBER: t.subscriber,
+	} {
+		if transport == nil || transport.pc == nil {
+			continue
+		}
+		if pair, err := transport.getSelectedPair(); err == nil && pair != nil {
+			pairs[target] = pair
+		}
+	}
+	return pairs
+}
+
+// GetSubscriberCodecs returns the codecs the subscriber negotiated on the subscriber peer connection, for media kinds
+// not negotiated yet the codecs the peer connection is set up with
+func (t *TransportManager) GetSubscriberCodecs() []*livekit.Codec {
+	negotiated := t.subscriber.NegotiatedCodecs()
+	codecs := negotiated
+	for _, c := range t.enabledCodecs {
+		kind, _, _ := strings.Cut(strings.ToLower(c.Mime), "/")
+		kindNegotiated := false
+		for _, nc := range negotiated {
+			if strings.HasPrefix(strings.ToLower(nc.Mime), kind+"/") {
+				kindNegotiated = true
+				break
+			}
+		}
+		if !kindNegotiated {
+			codecs = append(codecs, c)
+		}
+	}
+	return codecs
+}
+
 func (t *TransportManager) getTransport(isPrimary bool) *PCTransport {
 	pcTransport := t.publisher
 	if (isPrimary && t.params.SubscriberAsPrimary) || (!isPrimary && !t.params.SubscriberAsPrimary) {
@@ -755,6 +820,9 @@ func (t *TransportManager) UpdateMediaRTT(rtt uint32) {
 		t.udpRTT = uint32(int(t.udpRTT) + (int(rtt)-int(t.udpRTT))/2)
 	}
 	t.lock.Unlock()
+
+	// measured by the tracks the participant is subscribed to
+	t.subscriber.UpdateMediaRTT(rtt)
 }
 
 func (t *TransportManager) UpdateLastSeenSignal() {
@@ -791,3 +859,19 @@ func (t *TransportManager) SetSubscriberAllowPause(allowPause bool) {
 func (t *TransportManager) SetSubscriberChannelCapacity(channelCapacity int64) {
 	t.subscriber.SetChannelCapacityOfStreamAllocator(channelCapacity)
 }
+
+func (t *TransportManager) SeedSubscriberChannelCapacity(channelCapacity int64) {
+	t.subscriber.SeedChannelCapacityOfStreamAllocator(channelCapacity)
+}
+
+func (t *TransportManager) GetSubscriberChannelCapacity() int64 {
+	return t.subscriber.GetChannelCapacityOfStreamAllocator()
+}
+
+func (t *TransportManager) GetSubscriptionDiagnostics(trackID livekit.TrackID) (*streamallocator.Diagnostics, error) {
+	return t.subscriber.GetStreamAllocatorDiagnostics(trackID)
+}
+
+func (t *TransportManager) SetSubscriberMaxChannelCapacity(maxChannelCapacity int64) {
+	t.subscriber.SetMaxChannelCapacityOfStreamAllocator(maxChannelCapacity)
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"strings"
+)
+
+// ClientCapabilities are features a client declares support for when joining, the server adapts to the ones it
+// implements as well instead of inferring them from the protocol version
+type ClientCapabilities uint32
+
+const (
+	// ClientCapabilityDeltaRoster - client keeps its roster across a resume and only needs the participants that changed
+	ClientCapabilityDeltaRoster ClientCapabilities = 1 << iota
+	// ClientCapabilitySinglePeerConnection - client can publish and subscribe over one peer connection
+	ClientCapabilitySinglePeerConnection
+	// ClientCapabilityResumeV2 - client resumes with the state it has, letting the server skip what it already knows
+	ClientCapabilityResumeV2
+)
+
+// ServerCapabilities are the capabilities this server implements, only those are acknowledged to clients
+const ServerCapabilities = ClientCapabilityDeltaRoster
+
+var clientCapabilityNames = []struct {
+	capability ClientCapabilities
+	name       string
+}{
+	{ClientCapabilityDeltaRoster, "delta_roster"},
+	{ClientCapabilitySinglePeerConnection, "single_pc"},
+	{ClientCapabilityResumeV2, "resume_v2"},
+}
+
+// ParseClientCapabilities reads capabilities by name, names not known to this server are ignored
+func ParseClientCapabilities(names []string) ClientCapabilities {
+	var c ClientCapabilities
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, cn := range clientCapabilityNames {
+			if cn.name == name {
+				c |= cn.capability
+				break
+			}
+		}
+	}
+	return c
+}
+
+// NegotiateClientCapabilities returns the capabilities declared by a client that the server implements
+func NegotiateClientCapabilities(names []string) ClientCapabilities {
+	return ParseClientCapabilities(names) & ServerCapabilities
+}
+
+func (c ClientCapabilities) Has(capability ClientCapabilities) bool {
+	return c&capability == capability
+}
+
+func (c ClientCapabilities) Names() []string {
+	var names []string
+	for _, cn := range clientCapabilityNames {
+		if c.Has(cn.capability) {
+			names = append(names, cn.name)
+		}
+	}
+	return names
+}
+
+func (c ClientCapabilities) String() string {
+	return strings.Join(c.Names(), ",")
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -86,6 +88,7 @@ const (
 	ParticipantCloseReasonMigrationRequested
 	ParticipantCloseReasonOvercommitted
 	ParticipantCloseReasonPublicationError
+	ParticipantCloseReasonNodeIPChanged
 )
 
 func (p ParticipantCloseReason) String() string {
@@ -130,6 +133,8 @@ func (p ParticipantCloseReason) String() string {
 		return "OVERCOMMITTED"
 	case ParticipantCloseReasonPublicationError:
 		return "PUBLICATION_ERROR"
+	case ParticipantCloseReasonNodeIPChanged:
+		return "NODE_IP_CHANGED"
 	default:
 		return fmt.Sprintf("%d", int(p))
 	}
@@ -158,9 +163,9 @@ func (p ParticipantCloseReason) ToDisconnectReason() livekit.DisconnectReason {
 		return livekit.DisconnectReason_SERVER_SHUTDOWN
 	case ParticipantCloseReasonSimulateServerLeave:
 		return livekit.DisconnectReason_SERVER_SHUTDOWN
-	case ParticipantCloseReasonOvercommitted:
+	case ParticipantCloseReasonOvercommitted, ParticipantCloseReasonMigrationRequested:
 		return livekit.DisconnectReason_SERVER_SHUTDOWN
-	case ParticipantCloseReasonNegotiateFailed, ParticipantCloseReasonPublicationError:
+	case ParticipantCloseReasonNegotiateFailed, ParticipantCloseReasonPublicationError, ParticipantCloseReasonNodeIPChanged:
 		return livekit.DisconnectReason_STATE_MISMATCH
 	default:
 		// the other types will map to unknown reason
@@ -228,6 +233,16 @@ type AddTrackParams struct {
 	Red    bool
 }
 
+// ParticipantCheckpoint is the subscription state of a participant, saved when its connection becomes unstable
+// so that it can be restored if the participant has to rejoin
+type ParticipantCheckpoint struct {
+	SubscribedTracks       map[livekit.TrackID]*livekit.UpdateTrackSettings
+	SubscriptionPermission *livekit.SubscriptionPermission
+	CreatedAt              time.Time
+	// when the subscriptions or the subscription permission last changed
+	ChangedAt time.Time
+}
+
 //counterfeiter:generate . LocalParticipant
 type LocalParticipant interface {
 	Participant
@@ -237,7 +252,9 @@ type LocalParticipant interface {
 	// getters
 	GetLogger() logger.Logger
 	GetAdaptiveStream() bool
+	GetDeviceHints() routing.DeviceHints
 	ProtocolVersion() ProtocolVersion
+	Capabilities() ClientCapabilities
 	ConnectedAt() time.Time
 	IsClosed() bool
 	IsReady() bool
@@ -246,7 +263,14 @@ type LocalParticipant interface {
 	SubscriberAsPrimary() bool
 	GetClientConfiguration() *livekit.ClientConfiguration
 	GetICEConnectionType() ICEConnectionType
+	// GetSelectedICECandidatePairs returns the candidate pair of each connected transport
+	GetSelectedICECandidatePairs() map[livekit.SignalTarget]*webrtc.ICECandidatePair
+	// CloseReason is the reason the participant has been closed with
+	CloseReason() ParticipantCloseReason
 	GetBufferFactory() *buffer.Factory
+	GetSubscriberCodecs() []*livekit.Codec
+	// GetResourceStats returns the goroutines, sockets and timers accounted to the participant
+	GetResourceStats() sutils.ResourceStats
 
 	SetResponseSink(sink routing.MessageSink)
 	CloseSignalConnection()
@@ -269,6 +293,9 @@ type LocalParticipant interface {
 	HandleAnswer(sdp webrtc.SessionDescription)
 	Negotiate(force bool)
 	ICERestart(iceConfig *livekit.ICEConfig)
+	// HandleNodeIPChanged has the participant reconnect after the external IPs of the node changed, returns whether
+	// it was asked to
+	HandleNodeIPChanged() bool
 	AddTrackToSubscriber(trackLocal webrtc.TrackLocal, params AddTrackParams) (*webrtc.RTPSender, *webrtc.RTPTransceiver, error)
 	AddTransceiverFromTrackToSubscriber(trackLocal webrtc.TrackLocal, params AddTrackParams) (*webrtc.RTPSender, *webrtc.RTPTransceiver, error)
 	RemoveTrackFromSubscriber(sender *webrtc.RTPSender) error
@@ -286,6 +313,7 @@ type LocalParticipant interface {
 	// returns list of participant identities that the current participant is subscribed to
 	GetSubscribedParticipants() []livekit.ParticipantID
 	IsSubscribedTo(sid livekit.ParticipantID) bool
+	Checkpoint() *ParticipantCheckpoint
 
 	GetAudioLevel() (smoothedLevel float64, active bool)
 	GetConnectionQuality() *livekit.ConnectionQualityInfo
@@ -293,6 +321,7 @@ type LocalParticipant interface {
 	// server sent messages
 	SendJoinResponse(joinResponse *livekit.JoinResponse) error
 	SendParticipantUpdate(participants []*livekit.ParticipantInfo) error
+	SendResumeParticipantUpdate(participants []*livekit.ParticipantInfo) error
 	SendSpeakerUpdate(speakers []*livekit.SpeakerInfo, force bool) error
 	SendDataPacket(packet *livekit.DataPacket, data []byte) error
 	SendRoomUpdate(room *livekit.Room) error
@@ -317,6 +346,10 @@ type LocalParticipant interface {
 	OnSubscribeStatusChanged(fn func(publisherID livekit.ParticipantID, subscribed bool))
 	OnClose(callback func(LocalParticipant))
 	OnClaimsChanged(callback func(LocalParticipant))
+	// OnUnstable - signalling or ICE indicate the participant is about to disconnect, fires once until it recovers
+	OnUnstable(callback func(LocalParticipant))
+	// OnNetworkChanged - the selected ICE candidate pair of a transport moved to another client address, media continues without renegotiation
+	OnNetworkChanged(callback func(LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair))
 	OnReceiverReport(dt *sfu.DownTrack, report *rtcp.ReceiverReport)
 
 	// session migration
@@ -341,6 +374,22 @@ type LocalParticipant interface {
 	// down stream bandwidth management
 	SetSubscriberAllowPause(allowPause bool)
 	SetSubscriberChannelCapacity(channelCapacity int64)
+	// starts allocation from the downlink estimate of a previous session instead of ramping up from nothing
+	SeedSubscriberChannelCapacity(channelCapacity int64)
+	// the committed downlink estimate, 0 till there is one
+	GetSubscriberChannelCapacity() int64
+	// caps the downlink allocated to the subscriber, e. g. to its share of a room budget, 0 to remove the cap
+	SetSubscriberMaxChannelCapacity(maxChannelCapacity int64)
+	// the inputs and outcome of the latest allocation of a subscribed video track
+	GetSubscriptionDiagnostics(trackID livekit.TrackID) (*streamallocator.Diagnostics, error)
+	// video subscriptions are negotiated but kept muted while the downlink is audio only
+	SetAudioOnlyDownlink(audioOnly bool)
+	IsAudioOnlyDownlink() bool
+	// tells the participant how far the node goes in shedding load and which video tracks of its room are paused
+	SendLoadShedding(level LoadSheddingLevel, pausedTrackIDs []livekit.TrackID) error
+
+	// holds forwarding of the named tracks, to be published, until all of them are ready so that they start together
+	DeclareTrackSyncGroup(tracks []string, timeout time.Duration) error
 }
 
 // Room is a container of participants, and can provide room-level actions
@@ -378,6 +427,10 @@ type MediaTrack interface {
 	IsMuted() bool
 	SetMuted(muted bool)
 
+	// highest quality of video forwarded while the node sheds load, HIGH when it does not and OFF when paused
+	SetLoadSheddingQuality(quality livekit.VideoQuality)
+	LoadSheddingQuality() livekit.VideoQuality
+
 	UpdateVideoLayers(layers []*livekit.VideoLayer)
 	IsSimulcast() bool
 
@@ -0,0 +1,31 @@
+package types
+
+import "fmt"
+
+// LoadSheddingLevel is how far a node overloaded on CPU goes in shedding video, each level including the ones below
+type LoadSheddingLevel int32
+
+const (
+	LoadSheddingNone LoadSheddingLevel = iota
+	// video is forwarded at medium quality at most
+	LoadSheddingReduceLayers
+	// the video of participants who are not speaking is paused, lowest priority first
+	LoadSheddingPauseVideo
+	// new video tracks are refused
+	LoadSheddingRefuseVideo
+)
+
+func (l LoadSheddingLevel) String() string {
+	switch l {
+	case LoadSheddingNone:
+		return "NONE"
+	case LoadSheddingReduceLayers:
+		return "REDUCE_LAYERS"
+	case LoadSheddingPauseVideo:
+		return "PAUSE_VIDEO"
+	case LoadSheddingRefuseVideo:
+		return "REFUSE_VIDEO"
+	default:
+		return fmt.Sprintf("%d", int(l))
+	}
+}
@@ -0,0 +1,164 @@
+package types
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// The encryption status of a track is not part of TrackInfo yet, it is appended as an extra field that clients
+// unaware of it skip. Being an unknown field, it is only carried by binary protobuf, JSON webhooks have it as the
+// track_encryption field instead:
+//
+//	message TrackEncryptionStatus {
+//	  bool encrypted = 1;
+//	  string cipher = 2;
+//	  uint32 key_epoch = 3; // absent till known
+//	  bool server_readable = 4;
+//	}
+//
+//	TrackInfo.encryption_status = 100;
+const (
+	trackInfoEncryptionStatusField protowire.Number = 100
+
+	encryptionStatusEncryptedField      protowire.Number = 1
+	encryptionStatusCipherField         protowire.Number = 2
+	encryptionStatusKeyEpochField       protowire.Number = 3
+	encryptionStatusServerReadableField protowire.Number = 4
+)
+
+const (
+	CipherAESGCM = "aes-gcm"
+	CipherCustom = "custom"
+)
+
+// TrackEncryptionStatus tells apps and recorders whether the media of a track can be recorded or moderated
+type TrackEncryptionStatus struct {
+	// media is end-to-end encrypted by the publisher
+	Encrypted bool   `json:"encrypted"`
+	Cipher    string `json:"cipher,omitempty"`
+	// index of the key the publisher currently encrypts with, -1 till seen or when the cipher does not expose it
+	KeyEpoch int32 `json:"key_epoch"`
+	// the server, and recorders it feeds, can decode the media
+	ServerReadable bool `json:"server_readable"`
+}
+
+// NewTrackEncryptionStatus returns the status of a track published with the encryption type, before any key epoch
+// was seen
+func NewTrackEncryptionStatus(encryption livekit.Encryption_Type) *TrackEncryptionStatus {
+	status := &TrackEncryptionStatus{
+		KeyEpoch:       -1,
+		ServerReadable: true,
+	}
+	switch encryption {
+	case livekit.Encryption_GCM:
+		status.Cipher = CipherAESGCM
+	case livekit.Encryption_CUSTOM:
+		status.Cipher = CipherCustom
+	}
+	if status.Cipher != "" {
+		status.Encrypted = true
+		status.ServerReadable = false
+	}
+	return status
+}
+
+// SetTrackEncryptionStatus attaches the status to a TrackInfo, replacing the one it has
+func SetTrackEncryptionStatus(ti *livekit.TrackInfo, status *TrackEncryptionStatus) {
+	var value []byte
+	appendBool := func(field protowire.Number, v bool) {
+		if v {
+			value = protowire.AppendTag(value, field, protowire.VarintType)
+			value = protowire.AppendVarint(value, 1)
+		}
+	}
+	appendBool(encryptionStatusEncryptedField, status.Encrypted)
+	if status.Cipher != "" {
+		value = protowire.AppendTag(value, encryptionStatusCipherField, protowire.BytesType)
+		value = protowire.AppendString(value, status.Cipher)
+	}
+	if status.KeyEpoch >= 0 {
+		value = protowire.AppendTag(value, encryptionStatusKeyEpochField, protowire.VarintType)
+		value = protowire.AppendVarint(value, uint64(status.KeyEpoch))
+	}
+	appendBool(encryptionStatusServerReadableField, status.ServerReadable)
+
+	m := ti.ProtoReflect()
+	unknown, _ := splitUnknownField(m.GetUnknown(), trackInfoEncryptionStatusField)
+	unknown = protowire.AppendTag(unknown, trackInfoEncryptionStatusField, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(unknown, value))
+}
+
+// GetTrackEncryptionStatus reads the status attached to a TrackInfo, nil when it has none
+func GetTrackEncryptionStatus(ti *livekit.TrackInfo) *TrackEncryptionStatus {
+	_, value := splitUnknownField(ti.ProtoReflect().GetUnknown(), trackInfoEncryptionStatusField)
+	if value == nil {
+		return nil
+	}
+
+	status := &TrackEncryptionStatus{KeyEpoch: -1}
+	for len(value) > 0 {
+		num, typ, n := protowire.ConsumeTag(value)
+		if n < 0 {
+			return nil
+		}
+		value = value[n:]
+
+		switch {
+		case typ == protowire.VarintType:
+			v, m := protowire.ConsumeVarint(value)
+			if m < 0 {
+				return nil
+			}
+			switch num {
+			case encryptionStatusEncryptedField:
+				status.Encrypted = v != 0
+			case encryptionStatusKeyEpochField:
+				status.KeyEpoch = int32(v)
+			case encryptionStatusServerReadableField:
+				status.ServerReadable = v != 0
+			}
+			value = value[m:]
+		case typ == protowire.BytesType && num == encryptionStatusCipherField:
+			v, m := protowire.ConsumeString(value)
+			if m < 0 {
+				return nil
+			}
+			status.Cipher = v
+			value = value[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, value)
+			if m < 0 {
+				return nil
+			}
+			value = value[m:]
+		}
+	}
+	return status
+}
+
+// splitUnknownField returns the unknown fields without the length delimited field num, and the value of the last
+// occurrence of that field
+func splitUnknownField(unknown []byte, num protowire.Number) ([]byte, []byte) {
+	var rest, value []byte
+	for len(unknown) > 0 {
+		fieldNum, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return rest, value
+		}
+		m := protowire.ConsumeFieldValue(fieldNum, typ, unknown[n:])
+		if m < 0 {
+			return rest, value
+		}
+		if fieldNum == num && typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(unknown[n : n+m])
+			if value == nil {
+				value = []byte{}
+			}
+		} else {
+			rest = append(rest, unknown[:n+m]...)
+		}
+		unknown = unknown[n+m:]
+	}
+	return rest, value
+}
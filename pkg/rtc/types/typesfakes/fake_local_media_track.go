@@ -179,6 +179,16 @@ type FakeLocalMediaTrack struct {
 	kindReturnsOnCall map[int]struct {
 		result1 livekit.TrackType
 	}
+	LoadSheddingQualityStub        func() livekit.VideoQuality
+	loadSheddingQualityMutex       sync.RWMutex
+	loadSheddingQualityArgsForCall []struct {
+	}
+	loadSheddingQualityReturns struct {
+		result1 livekit.VideoQuality
+	}
+	loadSheddingQualityReturnsOnCall map[int]struct {
+		result1 livekit.VideoQuality
+	}
 	NameStub        func() string
 	nameMutex       sync.RWMutex
 	nameArgsForCall []struct {
@@ -262,6 +272,11 @@ type FakeLocalMediaTrack struct {
 	revokeDisallowedSubscribersReturnsOnCall map[int]struct {
 		result1 []livekit.ParticipantIdentity
 	}
+	SetLoadSheddingQualityStub        func(livekit.VideoQuality)
+	setLoadSheddingQualityMutex       sync.RWMutex
+	setLoadSheddingQualityArgsForCall []struct {
+		arg1 livekit.VideoQuality
+	}
 	SetMutedStub        func(bool)
 	setMutedMutex       sync.RWMutex
 	setMutedArgsForCall []struct {
@@ -1201,6 +1216,59 @@ func (fake *FakeLocalMediaTrack) KindReturnsOnCall(i int, result1 livekit.TrackT
 	}{result1}
 }
 
+func (fake *FakeLocalMediaTrack) LoadSheddingQuality() livekit.VideoQuality {
+	fake.loadSheddingQualityMutex.Lock()
+	ret, specificReturn := fake.loadSheddingQualityReturnsOnCall[len(fake.loadSheddingQualityArgsForCall)]
+	fake.loadSheddingQualityArgsForCall = append(fake.loadSheddingQualityArgsForCall, struct {
+	}{})
+	stub := fake.LoadSheddingQualityStub
+	fakeReturns := fake.loadSheddingQualityReturns
+	fake.recordInvocation("LoadSheddingQuality", []interface{}{})
+	fake.loadSheddingQualityMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalMediaTrack) LoadSheddingQualityCallCount() int {
+	fake.loadSheddingQualityMutex.RLock()
+	defer fake.loadSheddingQualityMutex.RUnlock()
+	return len(fake.loadSheddingQualityArgsForCall)
+}
+
+func (fake *FakeLocalMediaTrack) LoadSheddingQualityCalls(stub func() livekit.VideoQuality) {
+	fake.loadSheddingQualityMutex.Lock()
+	defer fake.loadSheddingQualityMutex.Unlock()
+	fake.LoadSheddingQualityStub = stub
+}
+
+func (fake *FakeLocalMediaTrack) LoadSheddingQualityReturns(result1 livekit.VideoQuality) {
+	fake.loadSheddingQualityMutex.Lock()
+	defer fake.loadSheddingQualityMutex.Unlock()
+	fake.LoadSheddingQualityStub = nil
+	fake.loadSheddingQualityReturns = struct {
+		result1 livekit.VideoQuality
+	}{result1}
+}
+
+func (fake *FakeLocalMediaTrack) LoadSheddingQualityReturnsOnCall(i int, result1 livekit.VideoQuality) {
+	fake.loadSheddingQualityMutex.Lock()
+	defer fake.loadSheddingQualityMutex.Unlock()
+	fake.LoadSheddingQualityStub = nil
+	if fake.loadSheddingQualityReturnsOnCall == nil {
+		fake.loadSheddingQualityReturnsOnCall = make(map[int]struct {
+			result1 livekit.VideoQuality
+		})
+	}
+	fake.loadSheddingQualityReturnsOnCall[i] = struct {
+		result1 livekit.VideoQuality
+	}{result1}
+}
+
 func (fake *FakeLocalMediaTrack) Name() string {
 	fake.nameMutex.Lock()
 	ret, specificReturn := fake.nameReturnsOnCall[len(fake.nameArgsForCall)]
@@ -1660,6 +1728,38 @@ func (fake *FakeLocalMediaTrack) RevokeDisallowedSubscribersReturnsOnCall(i int,
 	}{result1}
 }
 
+func (fake *FakeLocalMediaTrack) SetLoadSheddingQuality(arg1 livekit.VideoQuality) {
+	fake.setLoadSheddingQualityMutex.Lock()
+	fake.setLoadSheddingQualityArgsForCall = append(fake.setLoadSheddingQualityArgsForCall, struct {
+		arg1 livekit.VideoQuality
+	}{arg1})
+	stub := fake.SetLoadSheddingQualityStub
+	fake.recordInvocation("SetLoadSheddingQuality", []interface{}{arg1})
+	fake.setLoadSheddingQualityMutex.Unlock()
+	if stub != nil {
+		fake.SetLoadSheddingQualityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalMediaTrack) SetLoadSheddingQualityCallCount() int {
+	fake.setLoadSheddingQualityMutex.RLock()
+	defer fake.setLoadSheddingQualityMutex.RUnlock()
+	return len(fake.setLoadSheddingQualityArgsForCall)
+}
+
+func (fake *FakeLocalMediaTrack) SetLoadSheddingQualityCalls(stub func(livekit.VideoQuality)) {
+	fake.setLoadSheddingQualityMutex.Lock()
+	defer fake.setLoadSheddingQualityMutex.Unlock()
+	fake.SetLoadSheddingQualityStub = stub
+}
+
+func (fake *FakeLocalMediaTrack) SetLoadSheddingQualityArgsForCall(i int) livekit.VideoQuality {
+	fake.setLoadSheddingQualityMutex.RLock()
+	defer fake.setLoadSheddingQualityMutex.RUnlock()
+	argsForCall := fake.setLoadSheddingQualityArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalMediaTrack) SetMuted(arg1 bool) {
 	fake.setMutedMutex.Lock()
 	fake.setMutedArgsForCall = append(fake.setMutedArgsForCall, struct {
@@ -1957,6 +2057,8 @@ func (fake *FakeLocalMediaTrack) Invocations() map[string][][]interface{} {
 	defer fake.isSubscriberMutex.RUnlock()
 	fake.kindMutex.RLock()
 	defer fake.kindMutex.RUnlock()
+	fake.loadSheddingQualityMutex.RLock()
+	defer fake.loadSheddingQualityMutex.RUnlock()
 	fake.nameMutex.RLock()
 	defer fake.nameMutex.RUnlock()
 	fake.notifySubscriberNodeMaxQualityMutex.RLock()
@@ -1977,6 +2079,8 @@ func (fake *FakeLocalMediaTrack) Invocations() map[string][][]interface{} {
 	defer fake.restartMutex.RUnlock()
 	fake.revokeDisallowedSubscribersMutex.RLock()
 	defer fake.revokeDisallowedSubscribersMutex.RUnlock()
+	fake.setLoadSheddingQualityMutex.RLock()
+	defer fake.setLoadSheddingQualityMutex.RUnlock()
 	fake.setMutedMutex.RLock()
 	defer fake.setMutedMutex.RUnlock()
 	fake.setRTTMutex.RLock()
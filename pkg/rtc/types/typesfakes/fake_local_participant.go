@@ -9,10 +9,12 @@ import (
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
-	"github.com/livekit/protocol/utils"
+	utilsa "github.com/livekit/protocol/utils"
 	"github.com/pion/rtcp"
 	webrtc "github.com/pion/webrtc/v3"
 )
@@ -109,6 +111,26 @@ type FakeLocalParticipant struct {
 	canSubscribeReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	CapabilitiesStub        func() types.ClientCapabilities
+	capabilitiesMutex       sync.RWMutex
+	capabilitiesArgsForCall []struct {
+	}
+	capabilitiesReturns struct {
+		result1 types.ClientCapabilities
+	}
+	capabilitiesReturnsOnCall map[int]struct {
+		result1 types.ClientCapabilities
+	}
+	CheckpointStub        func() *types.ParticipantCheckpoint
+	checkpointMutex       sync.RWMutex
+	checkpointArgsForCall []struct {
+	}
+	checkpointReturns struct {
+		result1 *types.ParticipantCheckpoint
+	}
+	checkpointReturnsOnCall map[int]struct {
+		result1 *types.ParticipantCheckpoint
+	}
 	ClaimGrantsStub        func() *auth.ClaimGrants
 	claimGrantsMutex       sync.RWMutex
 	claimGrantsArgsForCall []struct {
@@ -131,6 +153,16 @@ type FakeLocalParticipant struct {
 	closeReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CloseReasonStub        func() types.ParticipantCloseReason
+	closeReasonMutex       sync.RWMutex
+	closeReasonArgsForCall []struct {
+	}
+	closeReasonReturns struct {
+		result1 types.ParticipantCloseReason
+	}
+	closeReasonReturnsOnCall map[int]struct {
+		result1 types.ParticipantCloseReason
+	}
 	CloseSignalConnectionStub        func()
 	closeSignalConnectionMutex       sync.RWMutex
 	closeSignalConnectionArgsForCall []struct {
@@ -155,6 +187,18 @@ type FakeLocalParticipant struct {
 	debugInfoReturnsOnCall map[int]struct {
 		result1 map[string]interface{}
 	}
+	DeclareTrackSyncGroupStub        func([]string, time.Duration) error
+	declareTrackSyncGroupMutex       sync.RWMutex
+	declareTrackSyncGroupArgsForCall []struct {
+		arg1 []string
+		arg2 time.Duration
+	}
+	declareTrackSyncGroupReturns struct {
+		result1 error
+	}
+	declareTrackSyncGroupReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetAdaptiveStreamStub        func() bool
 	getAdaptiveStreamMutex       sync.RWMutex
 	getAdaptiveStreamArgsForCall []struct {
@@ -220,6 +264,16 @@ type FakeLocalParticipant struct {
 	getConnectionQualityReturnsOnCall map[int]struct {
 		result1 *livekit.ConnectionQualityInfo
 	}
+	GetDeviceHintsStub        func() routing.DeviceHints
+	getDeviceHintsMutex       sync.RWMutex
+	getDeviceHintsArgsForCall []struct {
+	}
+	getDeviceHintsReturns struct {
+		result1 routing.DeviceHints
+	}
+	getDeviceHintsReturnsOnCall map[int]struct {
+		result1 routing.DeviceHints
+	}
 	GetICEConnectionTypeStub        func() types.ICEConnectionType
 	getICEConnectionTypeMutex       sync.RWMutex
 	getICEConnectionTypeArgsForCall []struct {
@@ -261,6 +315,26 @@ type FakeLocalParticipant struct {
 	getPublishedTracksReturnsOnCall map[int]struct {
 		result1 []types.MediaTrack
 	}
+	GetResourceStatsStub        func() utils.ResourceStats
+	getResourceStatsMutex       sync.RWMutex
+	getResourceStatsArgsForCall []struct {
+	}
+	getResourceStatsReturns struct {
+		result1 utils.ResourceStats
+	}
+	getResourceStatsReturnsOnCall map[int]struct {
+		result1 utils.ResourceStats
+	}
+	GetSelectedICECandidatePairsStub        func() map[livekit.SignalTarget]*webrtc.ICECandidatePair
+	getSelectedICECandidatePairsMutex       sync.RWMutex
+	getSelectedICECandidatePairsArgsForCall []struct {
+	}
+	getSelectedICECandidatePairsReturns struct {
+		result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair
+	}
+	getSelectedICECandidatePairsReturnsOnCall map[int]struct {
+		result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair
+	}
 	GetSubscribedParticipantsStub        func() []livekit.ParticipantID
 	getSubscribedParticipantsMutex       sync.RWMutex
 	getSubscribedParticipantsArgsForCall []struct {
@@ -281,11 +355,54 @@ type FakeLocalParticipant struct {
 	getSubscribedTracksReturnsOnCall map[int]struct {
 		result1 []types.SubscribedTrack
 	}
+	GetSubscriberChannelCapacityStub        func() int64
+	getSubscriberChannelCapacityMutex       sync.RWMutex
+	getSubscriberChannelCapacityArgsForCall []struct {
+	}
+	getSubscriberChannelCapacityReturns struct {
+		result1 int64
+	}
+	getSubscriberChannelCapacityReturnsOnCall map[int]struct {
+		result1 int64
+	}
+	GetSubscriberCodecsStub        func() []*livekit.Codec
+	getSubscriberCodecsMutex       sync.RWMutex
+	getSubscriberCodecsArgsForCall []struct {
+	}
+	getSubscriberCodecsReturns struct {
+		result1 []*livekit.Codec
+	}
+	getSubscriberCodecsReturnsOnCall map[int]struct {
+		result1 []*livekit.Codec
+	}
+	GetSubscriptionDiagnosticsStub        func(livekit.TrackID) (*streamallocator.Diagnostics, error)
+	getSubscriptionDiagnosticsMutex       sync.RWMutex
+	getSubscriptionDiagnosticsArgsForCall []struct {
+		arg1 livekit.TrackID
+	}
+	getSubscriptionDiagnosticsReturns struct {
+		result1 *streamallocator.Diagnostics
+		result2 error
+	}
+	getSubscriptionDiagnosticsReturnsOnCall map[int]struct {
+		result1 *streamallocator.Diagnostics
+		result2 error
+	}
 	HandleAnswerStub        func(webrtc.SessionDescription)
 	handleAnswerMutex       sync.RWMutex
 	handleAnswerArgsForCall []struct {
 		arg1 webrtc.SessionDescription
 	}
+	HandleNodeIPChangedStub        func() bool
+	handleNodeIPChangedMutex       sync.RWMutex
+	handleNodeIPChangedArgsForCall []struct {
+	}
+	handleNodeIPChangedReturns struct {
+		result1 bool
+	}
+	handleNodeIPChangedReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	HandleOfferStub        func(webrtc.SessionDescription)
 	handleOfferMutex       sync.RWMutex
 	handleOfferArgsForCall []struct {
@@ -350,6 +467,16 @@ type FakeLocalParticipant struct {
 	identityReturnsOnCall map[int]struct {
 		result1 livekit.ParticipantIdentity
 	}
+	IsAudioOnlyDownlinkStub        func() bool
+	isAudioOnlyDownlinkMutex       sync.RWMutex
+	isAudioOnlyDownlinkArgsForCall []struct {
+	}
+	isAudioOnlyDownlinkReturns struct {
+		result1 bool
+	}
+	isAudioOnlyDownlinkReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	IsClosedStub        func() bool
 	isClosedMutex       sync.RWMutex
 	isClosedArgsForCall []struct {
@@ -478,6 +605,11 @@ type FakeLocalParticipant struct {
 	onMigrateStateChangeArgsForCall []struct {
 		arg1 func(p types.LocalParticipant, migrateState types.MigrateState)
 	}
+	OnNetworkChangedStub        func(func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair))
+	onNetworkChangedMutex       sync.RWMutex
+	onNetworkChangedArgsForCall []struct {
+		arg1 func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair)
+	}
 	OnParticipantUpdateStub        func(func(types.LocalParticipant))
 	onParticipantUpdateMutex       sync.RWMutex
 	onParticipantUpdateArgsForCall []struct {
@@ -514,6 +646,11 @@ type FakeLocalParticipant struct {
 	onTrackUpdatedArgsForCall []struct {
 		arg1 func(types.LocalParticipant, types.MediaTrack)
 	}
+	OnUnstableStub        func(func(types.LocalParticipant))
+	onUnstableMutex       sync.RWMutex
+	onUnstableArgsForCall []struct {
+		arg1 func(types.LocalParticipant)
+	}
 	ProtocolVersionStub        func() types.ProtocolVersion
 	protocolVersionMutex       sync.RWMutex
 	protocolVersionArgsForCall []struct {
@@ -542,6 +679,11 @@ type FakeLocalParticipant struct {
 	removeTrackFromSubscriberReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SeedSubscriberChannelCapacityStub        func(int64)
+	seedSubscriberChannelCapacityMutex       sync.RWMutex
+	seedSubscriberChannelCapacityArgsForCall []struct {
+		arg1 int64
+	}
 	SendConnectionQualityUpdateStub        func(*livekit.ConnectionQualityUpdate) error
 	sendConnectionQualityUpdateMutex       sync.RWMutex
 	sendConnectionQualityUpdateArgsForCall []struct {
@@ -576,6 +718,18 @@ type FakeLocalParticipant struct {
 	sendJoinResponseReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SendLoadSheddingStub        func(types.LoadSheddingLevel, []livekit.TrackID) error
+	sendLoadSheddingMutex       sync.RWMutex
+	sendLoadSheddingArgsForCall []struct {
+		arg1 types.LoadSheddingLevel
+		arg2 []livekit.TrackID
+	}
+	sendLoadSheddingReturns struct {
+		result1 error
+	}
+	sendLoadSheddingReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SendParticipantUpdateStub        func([]*livekit.ParticipantInfo) error
 	sendParticipantUpdateMutex       sync.RWMutex
 	sendParticipantUpdateArgsForCall []struct {
@@ -598,6 +752,17 @@ type FakeLocalParticipant struct {
 	sendRefreshTokenReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SendResumeParticipantUpdateStub        func([]*livekit.ParticipantInfo) error
+	sendResumeParticipantUpdateMutex       sync.RWMutex
+	sendResumeParticipantUpdateArgsForCall []struct {
+		arg1 []*livekit.ParticipantInfo
+	}
+	sendResumeParticipantUpdateReturns struct {
+		result1 error
+	}
+	sendResumeParticipantUpdateReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SendRoomUpdateStub        func(*livekit.Room) error
 	sendRoomUpdateMutex       sync.RWMutex
 	sendRoomUpdateArgsForCall []struct {
@@ -621,6 +786,11 @@ type FakeLocalParticipant struct {
 	sendSpeakerUpdateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetAudioOnlyDownlinkStub        func(bool)
+	setAudioOnlyDownlinkMutex       sync.RWMutex
+	setAudioOnlyDownlinkArgsForCall []struct {
+		arg1 bool
+	}
 	SetICEConfigStub        func(*livekit.ICEConfig)
 	setICEConfigMutex       sync.RWMutex
 	setICEConfigArgsForCall []struct {
@@ -680,6 +850,11 @@ type FakeLocalParticipant struct {
 	setSubscriberChannelCapacityArgsForCall []struct {
 		arg1 int64
 	}
+	SetSubscriberMaxChannelCapacityStub        func(int64)
+	setSubscriberMaxChannelCapacityMutex       sync.RWMutex
+	setSubscriberMaxChannelCapacityArgsForCall []struct {
+		arg1 int64
+	}
 	SetTrackMutedStub        func(livekit.TrackID, bool, bool)
 	setTrackMutedMutex       sync.RWMutex
 	setTrackMutedArgsForCall []struct {
@@ -716,17 +891,17 @@ type FakeLocalParticipant struct {
 	subscriberAsPrimaryReturnsOnCall map[int]struct {
 		result1 bool
 	}
-	SubscriptionPermissionStub        func() (*livekit.SubscriptionPermission, utils.TimedVersion)
+	SubscriptionPermissionStub        func() (*livekit.SubscriptionPermission, utilsa.TimedVersion)
 	subscriptionPermissionMutex       sync.RWMutex
 	subscriptionPermissionArgsForCall []struct {
 	}
 	subscriptionPermissionReturns struct {
 		result1 *livekit.SubscriptionPermission
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}
 	subscriptionPermissionReturnsOnCall map[int]struct {
 		result1 *livekit.SubscriptionPermission
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}
 	SubscriptionPermissionUpdateStub        func(livekit.ParticipantID, livekit.TrackID, bool)
 	subscriptionPermissionUpdateMutex       sync.RWMutex
@@ -745,17 +920,17 @@ type FakeLocalParticipant struct {
 	toProtoReturnsOnCall map[int]struct {
 		result1 *livekit.ParticipantInfo
 	}
-	ToProtoWithVersionStub        func() (*livekit.ParticipantInfo, utils.TimedVersion)
+	ToProtoWithVersionStub        func() (*livekit.ParticipantInfo, utilsa.TimedVersion)
 	toProtoWithVersionMutex       sync.RWMutex
 	toProtoWithVersionArgsForCall []struct {
 	}
 	toProtoWithVersionReturns struct {
 		result1 *livekit.ParticipantInfo
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}
 	toProtoWithVersionReturnsOnCall map[int]struct {
 		result1 *livekit.ParticipantInfo
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}
 	UncacheDownTrackStub        func(*webrtc.RTPTransceiver)
 	uncacheDownTrackMutex       sync.RWMutex
@@ -813,11 +988,11 @@ type FakeLocalParticipant struct {
 		arg1 livekit.TrackID
 		arg2 *livekit.UpdateTrackSettings
 	}
-	UpdateSubscriptionPermissionStub        func(*livekit.SubscriptionPermission, utils.TimedVersion, func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, func(participantID livekit.ParticipantID) types.LocalParticipant) error
+	UpdateSubscriptionPermissionStub        func(*livekit.SubscriptionPermission, utilsa.TimedVersion, func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, func(participantID livekit.ParticipantID) types.LocalParticipant) error
 	updateSubscriptionPermissionMutex       sync.RWMutex
 	updateSubscriptionPermissionArgsForCall []struct {
 		arg1 *livekit.SubscriptionPermission
-		arg2 utils.TimedVersion
+		arg2 utilsa.TimedVersion
 		arg3 func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant
 		arg4 func(participantID livekit.ParticipantID) types.LocalParticipant
 	}
@@ -1314,6 +1489,112 @@ func (fake *FakeLocalParticipant) CanSubscribeReturnsOnCall(i int, result1 bool)
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) Capabilities() types.ClientCapabilities {
+	fake.capabilitiesMutex.Lock()
+	ret, specificReturn := fake.capabilitiesReturnsOnCall[len(fake.capabilitiesArgsForCall)]
+	fake.capabilitiesArgsForCall = append(fake.capabilitiesArgsForCall, struct {
+	}{})
+	stub := fake.CapabilitiesStub
+	fakeReturns := fake.capabilitiesReturns
+	fake.recordInvocation("Capabilities", []interface{}{})
+	fake.capabilitiesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) CapabilitiesCallCount() int {
+	fake.capabilitiesMutex.RLock()
+	defer fake.capabilitiesMutex.RUnlock()
+	return len(fake.capabilitiesArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) CapabilitiesCalls(stub func() types.ClientCapabilities) {
+	fake.capabilitiesMutex.Lock()
+	defer fake.capabilitiesMutex.Unlock()
+	fake.CapabilitiesStub = stub
+}
+
+func (fake *FakeLocalParticipant) CapabilitiesReturns(result1 types.ClientCapabilities) {
+	fake.capabilitiesMutex.Lock()
+	defer fake.capabilitiesMutex.Unlock()
+	fake.CapabilitiesStub = nil
+	fake.capabilitiesReturns = struct {
+		result1 types.ClientCapabilities
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) CapabilitiesReturnsOnCall(i int, result1 types.ClientCapabilities) {
+	fake.capabilitiesMutex.Lock()
+	defer fake.capabilitiesMutex.Unlock()
+	fake.CapabilitiesStub = nil
+	if fake.capabilitiesReturnsOnCall == nil {
+		fake.capabilitiesReturnsOnCall = make(map[int]struct {
+			result1 types.ClientCapabilities
+		})
+	}
+	fake.capabilitiesReturnsOnCall[i] = struct {
+		result1 types.ClientCapabilities
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) Checkpoint() *types.ParticipantCheckpoint {
+	fake.checkpointMutex.Lock()
+	ret, specificReturn := fake.checkpointReturnsOnCall[len(fake.checkpointArgsForCall)]
+	fake.checkpointArgsForCall = append(fake.checkpointArgsForCall, struct {
+	}{})
+	stub := fake.CheckpointStub
+	fakeReturns := fake.checkpointReturns
+	fake.recordInvocation("Checkpoint", []interface{}{})
+	fake.checkpointMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) CheckpointCallCount() int {
+	fake.checkpointMutex.RLock()
+	defer fake.checkpointMutex.RUnlock()
+	return len(fake.checkpointArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) CheckpointCalls(stub func() *types.ParticipantCheckpoint) {
+	fake.checkpointMutex.Lock()
+	defer fake.checkpointMutex.Unlock()
+	fake.CheckpointStub = stub
+}
+
+func (fake *FakeLocalParticipant) CheckpointReturns(result1 *types.ParticipantCheckpoint) {
+	fake.checkpointMutex.Lock()
+	defer fake.checkpointMutex.Unlock()
+	fake.CheckpointStub = nil
+	fake.checkpointReturns = struct {
+		result1 *types.ParticipantCheckpoint
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) CheckpointReturnsOnCall(i int, result1 *types.ParticipantCheckpoint) {
+	fake.checkpointMutex.Lock()
+	defer fake.checkpointMutex.Unlock()
+	fake.CheckpointStub = nil
+	if fake.checkpointReturnsOnCall == nil {
+		fake.checkpointReturnsOnCall = make(map[int]struct {
+			result1 *types.ParticipantCheckpoint
+		})
+	}
+	fake.checkpointReturnsOnCall[i] = struct {
+		result1 *types.ParticipantCheckpoint
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) ClaimGrants() *auth.ClaimGrants {
 	fake.claimGrantsMutex.Lock()
 	ret, specificReturn := fake.claimGrantsReturnsOnCall[len(fake.claimGrantsArgsForCall)]
@@ -1429,6 +1710,59 @@ func (fake *FakeLocalParticipant) CloseReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) CloseReason() types.ParticipantCloseReason {
+	fake.closeReasonMutex.Lock()
+	ret, specificReturn := fake.closeReasonReturnsOnCall[len(fake.closeReasonArgsForCall)]
+	fake.closeReasonArgsForCall = append(fake.closeReasonArgsForCall, struct {
+	}{})
+	stub := fake.CloseReasonStub
+	fakeReturns := fake.closeReasonReturns
+	fake.recordInvocation("CloseReason", []interface{}{})
+	fake.closeReasonMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) CloseReasonCallCount() int {
+	fake.closeReasonMutex.RLock()
+	defer fake.closeReasonMutex.RUnlock()
+	return len(fake.closeReasonArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) CloseReasonCalls(stub func() types.ParticipantCloseReason) {
+	fake.closeReasonMutex.Lock()
+	defer fake.closeReasonMutex.Unlock()
+	fake.CloseReasonStub = stub
+}
+
+func (fake *FakeLocalParticipant) CloseReasonReturns(result1 types.ParticipantCloseReason) {
+	fake.closeReasonMutex.Lock()
+	defer fake.closeReasonMutex.Unlock()
+	fake.CloseReasonStub = nil
+	fake.closeReasonReturns = struct {
+		result1 types.ParticipantCloseReason
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) CloseReasonReturnsOnCall(i int, result1 types.ParticipantCloseReason) {
+	fake.closeReasonMutex.Lock()
+	defer fake.closeReasonMutex.Unlock()
+	fake.CloseReasonStub = nil
+	if fake.closeReasonReturnsOnCall == nil {
+		fake.closeReasonReturnsOnCall = make(map[int]struct {
+			result1 types.ParticipantCloseReason
+		})
+	}
+	fake.closeReasonReturnsOnCall[i] = struct {
+		result1 types.ParticipantCloseReason
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) CloseSignalConnection() {
 	fake.closeSignalConnectionMutex.Lock()
 	fake.closeSignalConnectionArgsForCall = append(fake.closeSignalConnectionArgsForCall, struct {
@@ -1559,6 +1893,73 @@ func (fake *FakeLocalParticipant) DebugInfoReturnsOnCall(i int, result1 map[stri
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) DeclareTrackSyncGroup(arg1 []string, arg2 time.Duration) error {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.declareTrackSyncGroupMutex.Lock()
+	ret, specificReturn := fake.declareTrackSyncGroupReturnsOnCall[len(fake.declareTrackSyncGroupArgsForCall)]
+	fake.declareTrackSyncGroupArgsForCall = append(fake.declareTrackSyncGroupArgsForCall, struct {
+		arg1 []string
+		arg2 time.Duration
+	}{arg1Copy, arg2})
+	stub := fake.DeclareTrackSyncGroupStub
+	fakeReturns := fake.declareTrackSyncGroupReturns
+	fake.recordInvocation("DeclareTrackSyncGroup", []interface{}{arg1Copy, arg2})
+	fake.declareTrackSyncGroupMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) DeclareTrackSyncGroupCallCount() int {
+	fake.declareTrackSyncGroupMutex.RLock()
+	defer fake.declareTrackSyncGroupMutex.RUnlock()
+	return len(fake.declareTrackSyncGroupArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) DeclareTrackSyncGroupCalls(stub func([]string, time.Duration) error) {
+	fake.declareTrackSyncGroupMutex.Lock()
+	defer fake.declareTrackSyncGroupMutex.Unlock()
+	fake.DeclareTrackSyncGroupStub = stub
+}
+
+func (fake *FakeLocalParticipant) DeclareTrackSyncGroupArgsForCall(i int) ([]string, time.Duration) {
+	fake.declareTrackSyncGroupMutex.RLock()
+	defer fake.declareTrackSyncGroupMutex.RUnlock()
+	argsForCall := fake.declareTrackSyncGroupArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLocalParticipant) DeclareTrackSyncGroupReturns(result1 error) {
+	fake.declareTrackSyncGroupMutex.Lock()
+	defer fake.declareTrackSyncGroupMutex.Unlock()
+	fake.DeclareTrackSyncGroupStub = nil
+	fake.declareTrackSyncGroupReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) DeclareTrackSyncGroupReturnsOnCall(i int, result1 error) {
+	fake.declareTrackSyncGroupMutex.Lock()
+	defer fake.declareTrackSyncGroupMutex.Unlock()
+	fake.DeclareTrackSyncGroupStub = nil
+	if fake.declareTrackSyncGroupReturnsOnCall == nil {
+		fake.declareTrackSyncGroupReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.declareTrackSyncGroupReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetAdaptiveStream() bool {
 	fake.getAdaptiveStreamMutex.Lock()
 	ret, specificReturn := fake.getAdaptiveStreamReturnsOnCall[len(fake.getAdaptiveStreamArgsForCall)]
@@ -1891,6 +2292,59 @@ func (fake *FakeLocalParticipant) GetConnectionQualityReturnsOnCall(i int, resul
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetDeviceHints() routing.DeviceHints {
+	fake.getDeviceHintsMutex.Lock()
+	ret, specificReturn := fake.getDeviceHintsReturnsOnCall[len(fake.getDeviceHintsArgsForCall)]
+	fake.getDeviceHintsArgsForCall = append(fake.getDeviceHintsArgsForCall, struct {
+	}{})
+	stub := fake.GetDeviceHintsStub
+	fakeReturns := fake.getDeviceHintsReturns
+	fake.recordInvocation("GetDeviceHints", []interface{}{})
+	fake.getDeviceHintsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetDeviceHintsCallCount() int {
+	fake.getDeviceHintsMutex.RLock()
+	defer fake.getDeviceHintsMutex.RUnlock()
+	return len(fake.getDeviceHintsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetDeviceHintsCalls(stub func() routing.DeviceHints) {
+	fake.getDeviceHintsMutex.Lock()
+	defer fake.getDeviceHintsMutex.Unlock()
+	fake.GetDeviceHintsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetDeviceHintsReturns(result1 routing.DeviceHints) {
+	fake.getDeviceHintsMutex.Lock()
+	defer fake.getDeviceHintsMutex.Unlock()
+	fake.GetDeviceHintsStub = nil
+	fake.getDeviceHintsReturns = struct {
+		result1 routing.DeviceHints
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetDeviceHintsReturnsOnCall(i int, result1 routing.DeviceHints) {
+	fake.getDeviceHintsMutex.Lock()
+	defer fake.getDeviceHintsMutex.Unlock()
+	fake.GetDeviceHintsStub = nil
+	if fake.getDeviceHintsReturnsOnCall == nil {
+		fake.getDeviceHintsReturnsOnCall = make(map[int]struct {
+			result1 routing.DeviceHints
+		})
+	}
+	fake.getDeviceHintsReturnsOnCall[i] = struct {
+		result1 routing.DeviceHints
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetICEConnectionType() types.ICEConnectionType {
 	fake.getICEConnectionTypeMutex.Lock()
 	ret, specificReturn := fake.getICEConnectionTypeReturnsOnCall[len(fake.getICEConnectionTypeArgsForCall)]
@@ -2111,6 +2565,112 @@ func (fake *FakeLocalParticipant) GetPublishedTracksReturnsOnCall(i int, result1
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetResourceStats() utils.ResourceStats {
+	fake.getResourceStatsMutex.Lock()
+	ret, specificReturn := fake.getResourceStatsReturnsOnCall[len(fake.getResourceStatsArgsForCall)]
+	fake.getResourceStatsArgsForCall = append(fake.getResourceStatsArgsForCall, struct {
+	}{})
+	stub := fake.GetResourceStatsStub
+	fakeReturns := fake.getResourceStatsReturns
+	fake.recordInvocation("GetResourceStats", []interface{}{})
+	fake.getResourceStatsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetResourceStatsCallCount() int {
+	fake.getResourceStatsMutex.RLock()
+	defer fake.getResourceStatsMutex.RUnlock()
+	return len(fake.getResourceStatsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetResourceStatsCalls(stub func() utils.ResourceStats) {
+	fake.getResourceStatsMutex.Lock()
+	defer fake.getResourceStatsMutex.Unlock()
+	fake.GetResourceStatsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetResourceStatsReturns(result1 utils.ResourceStats) {
+	fake.getResourceStatsMutex.Lock()
+	defer fake.getResourceStatsMutex.Unlock()
+	fake.GetResourceStatsStub = nil
+	fake.getResourceStatsReturns = struct {
+		result1 utils.ResourceStats
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetResourceStatsReturnsOnCall(i int, result1 utils.ResourceStats) {
+	fake.getResourceStatsMutex.Lock()
+	defer fake.getResourceStatsMutex.Unlock()
+	fake.GetResourceStatsStub = nil
+	if fake.getResourceStatsReturnsOnCall == nil {
+		fake.getResourceStatsReturnsOnCall = make(map[int]struct {
+			result1 utils.ResourceStats
+		})
+	}
+	fake.getResourceStatsReturnsOnCall[i] = struct {
+		result1 utils.ResourceStats
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSelectedICECandidatePairs() map[livekit.SignalTarget]*webrtc.ICECandidatePair {
+	fake.getSelectedICECandidatePairsMutex.Lock()
+	ret, specificReturn := fake.getSelectedICECandidatePairsReturnsOnCall[len(fake.getSelectedICECandidatePairsArgsForCall)]
+	fake.getSelectedICECandidatePairsArgsForCall = append(fake.getSelectedICECandidatePairsArgsForCall, struct {
+	}{})
+	stub := fake.GetSelectedICECandidatePairsStub
+	fakeReturns := fake.getSelectedICECandidatePairsReturns
+	fake.recordInvocation("GetSelectedICECandidatePairs", []interface{}{})
+	fake.getSelectedICECandidatePairsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSelectedICECandidatePairsCallCount() int {
+	fake.getSelectedICECandidatePairsMutex.RLock()
+	defer fake.getSelectedICECandidatePairsMutex.RUnlock()
+	return len(fake.getSelectedICECandidatePairsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSelectedICECandidatePairsCalls(stub func() map[livekit.SignalTarget]*webrtc.ICECandidatePair) {
+	fake.getSelectedICECandidatePairsMutex.Lock()
+	defer fake.getSelectedICECandidatePairsMutex.Unlock()
+	fake.GetSelectedICECandidatePairsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSelectedICECandidatePairsReturns(result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair) {
+	fake.getSelectedICECandidatePairsMutex.Lock()
+	defer fake.getSelectedICECandidatePairsMutex.Unlock()
+	fake.GetSelectedICECandidatePairsStub = nil
+	fake.getSelectedICECandidatePairsReturns = struct {
+		result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSelectedICECandidatePairsReturnsOnCall(i int, result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair) {
+	fake.getSelectedICECandidatePairsMutex.Lock()
+	defer fake.getSelectedICECandidatePairsMutex.Unlock()
+	fake.GetSelectedICECandidatePairsStub = nil
+	if fake.getSelectedICECandidatePairsReturnsOnCall == nil {
+		fake.getSelectedICECandidatePairsReturnsOnCall = make(map[int]struct {
+			result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair
+		})
+	}
+	fake.getSelectedICECandidatePairsReturnsOnCall[i] = struct {
+		result1 map[livekit.SignalTarget]*webrtc.ICECandidatePair
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) GetSubscribedParticipants() []livekit.ParticipantID {
 	fake.getSubscribedParticipantsMutex.Lock()
 	ret, specificReturn := fake.getSubscribedParticipantsReturnsOnCall[len(fake.getSubscribedParticipantsArgsForCall)]
@@ -2217,6 +2777,176 @@ func (fake *FakeLocalParticipant) GetSubscribedTracksReturnsOnCall(i int, result
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) GetSubscriberChannelCapacity() int64 {
+	fake.getSubscriberChannelCapacityMutex.Lock()
+	ret, specificReturn := fake.getSubscriberChannelCapacityReturnsOnCall[len(fake.getSubscriberChannelCapacityArgsForCall)]
+	fake.getSubscriberChannelCapacityArgsForCall = append(fake.getSubscriberChannelCapacityArgsForCall, struct {
+	}{})
+	stub := fake.GetSubscriberChannelCapacityStub
+	fakeReturns := fake.getSubscriberChannelCapacityReturns
+	fake.recordInvocation("GetSubscriberChannelCapacity", []interface{}{})
+	fake.getSubscriberChannelCapacityMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberChannelCapacityCallCount() int {
+	fake.getSubscriberChannelCapacityMutex.RLock()
+	defer fake.getSubscriberChannelCapacityMutex.RUnlock()
+	return len(fake.getSubscriberChannelCapacityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberChannelCapacityCalls(stub func() int64) {
+	fake.getSubscriberChannelCapacityMutex.Lock()
+	defer fake.getSubscriberChannelCapacityMutex.Unlock()
+	fake.GetSubscriberChannelCapacityStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberChannelCapacityReturns(result1 int64) {
+	fake.getSubscriberChannelCapacityMutex.Lock()
+	defer fake.getSubscriberChannelCapacityMutex.Unlock()
+	fake.GetSubscriberChannelCapacityStub = nil
+	fake.getSubscriberChannelCapacityReturns = struct {
+		result1 int64
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberChannelCapacityReturnsOnCall(i int, result1 int64) {
+	fake.getSubscriberChannelCapacityMutex.Lock()
+	defer fake.getSubscriberChannelCapacityMutex.Unlock()
+	fake.GetSubscriberChannelCapacityStub = nil
+	if fake.getSubscriberChannelCapacityReturnsOnCall == nil {
+		fake.getSubscriberChannelCapacityReturnsOnCall = make(map[int]struct {
+			result1 int64
+		})
+	}
+	fake.getSubscriberChannelCapacityReturnsOnCall[i] = struct {
+		result1 int64
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberCodecs() []*livekit.Codec {
+	fake.getSubscriberCodecsMutex.Lock()
+	ret, specificReturn := fake.getSubscriberCodecsReturnsOnCall[len(fake.getSubscriberCodecsArgsForCall)]
+	fake.getSubscriberCodecsArgsForCall = append(fake.getSubscriberCodecsArgsForCall, struct {
+	}{})
+	stub := fake.GetSubscriberCodecsStub
+	fakeReturns := fake.getSubscriberCodecsReturns
+	fake.recordInvocation("GetSubscriberCodecs", []interface{}{})
+	fake.getSubscriberCodecsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberCodecsCallCount() int {
+	fake.getSubscriberCodecsMutex.RLock()
+	defer fake.getSubscriberCodecsMutex.RUnlock()
+	return len(fake.getSubscriberCodecsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberCodecsCalls(stub func() []*livekit.Codec) {
+	fake.getSubscriberCodecsMutex.Lock()
+	defer fake.getSubscriberCodecsMutex.Unlock()
+	fake.GetSubscriberCodecsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberCodecsReturns(result1 []*livekit.Codec) {
+	fake.getSubscriberCodecsMutex.Lock()
+	defer fake.getSubscriberCodecsMutex.Unlock()
+	fake.GetSubscriberCodecsStub = nil
+	fake.getSubscriberCodecsReturns = struct {
+		result1 []*livekit.Codec
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriberCodecsReturnsOnCall(i int, result1 []*livekit.Codec) {
+	fake.getSubscriberCodecsMutex.Lock()
+	defer fake.getSubscriberCodecsMutex.Unlock()
+	fake.GetSubscriberCodecsStub = nil
+	if fake.getSubscriberCodecsReturnsOnCall == nil {
+		fake.getSubscriberCodecsReturnsOnCall = make(map[int]struct {
+			result1 []*livekit.Codec
+		})
+	}
+	fake.getSubscriberCodecsReturnsOnCall[i] = struct {
+		result1 []*livekit.Codec
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriptionDiagnostics(arg1 livekit.TrackID) (*streamallocator.Diagnostics, error) {
+	fake.getSubscriptionDiagnosticsMutex.Lock()
+	ret, specificReturn := fake.getSubscriptionDiagnosticsReturnsOnCall[len(fake.getSubscriptionDiagnosticsArgsForCall)]
+	fake.getSubscriptionDiagnosticsArgsForCall = append(fake.getSubscriptionDiagnosticsArgsForCall, struct {
+		arg1 livekit.TrackID
+	}{arg1})
+	stub := fake.GetSubscriptionDiagnosticsStub
+	fakeReturns := fake.getSubscriptionDiagnosticsReturns
+	fake.recordInvocation("GetSubscriptionDiagnostics", []interface{}{arg1})
+	fake.getSubscriptionDiagnosticsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeLocalParticipant) GetSubscriptionDiagnosticsCallCount() int {
+	fake.getSubscriptionDiagnosticsMutex.RLock()
+	defer fake.getSubscriptionDiagnosticsMutex.RUnlock()
+	return len(fake.getSubscriptionDiagnosticsArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) GetSubscriptionDiagnosticsCalls(stub func(livekit.TrackID) (*streamallocator.Diagnostics, error)) {
+	fake.getSubscriptionDiagnosticsMutex.Lock()
+	defer fake.getSubscriptionDiagnosticsMutex.Unlock()
+	fake.GetSubscriptionDiagnosticsStub = stub
+}
+
+func (fake *FakeLocalParticipant) GetSubscriptionDiagnosticsArgsForCall(i int) livekit.TrackID {
+	fake.getSubscriptionDiagnosticsMutex.RLock()
+	defer fake.getSubscriptionDiagnosticsMutex.RUnlock()
+	argsForCall := fake.getSubscriptionDiagnosticsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLocalParticipant) GetSubscriptionDiagnosticsReturns(result1 *streamallocator.Diagnostics, result2 error) {
+	fake.getSubscriptionDiagnosticsMutex.Lock()
+	defer fake.getSubscriptionDiagnosticsMutex.Unlock()
+	fake.GetSubscriptionDiagnosticsStub = nil
+	fake.getSubscriptionDiagnosticsReturns = struct {
+		result1 *streamallocator.Diagnostics
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLocalParticipant) GetSubscriptionDiagnosticsReturnsOnCall(i int, result1 *streamallocator.Diagnostics, result2 error) {
+	fake.getSubscriptionDiagnosticsMutex.Lock()
+	defer fake.getSubscriptionDiagnosticsMutex.Unlock()
+	fake.GetSubscriptionDiagnosticsStub = nil
+	if fake.getSubscriptionDiagnosticsReturnsOnCall == nil {
+		fake.getSubscriptionDiagnosticsReturnsOnCall = make(map[int]struct {
+			result1 *streamallocator.Diagnostics
+			result2 error
+		})
+	}
+	fake.getSubscriptionDiagnosticsReturnsOnCall[i] = struct {
+		result1 *streamallocator.Diagnostics
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeLocalParticipant) HandleAnswer(arg1 webrtc.SessionDescription) {
 	fake.handleAnswerMutex.Lock()
 	fake.handleAnswerArgsForCall = append(fake.handleAnswerArgsForCall, struct {
@@ -2249,6 +2979,59 @@ func (fake *FakeLocalParticipant) HandleAnswerArgsForCall(i int) webrtc.SessionD
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) HandleNodeIPChanged() bool {
+	fake.handleNodeIPChangedMutex.Lock()
+	ret, specificReturn := fake.handleNodeIPChangedReturnsOnCall[len(fake.handleNodeIPChangedArgsForCall)]
+	fake.handleNodeIPChangedArgsForCall = append(fake.handleNodeIPChangedArgsForCall, struct {
+	}{})
+	stub := fake.HandleNodeIPChangedStub
+	fakeReturns := fake.handleNodeIPChangedReturns
+	fake.recordInvocation("HandleNodeIPChanged", []interface{}{})
+	fake.handleNodeIPChangedMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) HandleNodeIPChangedCallCount() int {
+	fake.handleNodeIPChangedMutex.RLock()
+	defer fake.handleNodeIPChangedMutex.RUnlock()
+	return len(fake.handleNodeIPChangedArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) HandleNodeIPChangedCalls(stub func() bool) {
+	fake.handleNodeIPChangedMutex.Lock()
+	defer fake.handleNodeIPChangedMutex.Unlock()
+	fake.HandleNodeIPChangedStub = stub
+}
+
+func (fake *FakeLocalParticipant) HandleNodeIPChangedReturns(result1 bool) {
+	fake.handleNodeIPChangedMutex.Lock()
+	defer fake.handleNodeIPChangedMutex.Unlock()
+	fake.HandleNodeIPChangedStub = nil
+	fake.handleNodeIPChangedReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) HandleNodeIPChangedReturnsOnCall(i int, result1 bool) {
+	fake.handleNodeIPChangedMutex.Lock()
+	defer fake.handleNodeIPChangedMutex.Unlock()
+	fake.HandleNodeIPChangedStub = nil
+	if fake.handleNodeIPChangedReturnsOnCall == nil {
+		fake.handleNodeIPChangedReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.handleNodeIPChangedReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) HandleOffer(arg1 webrtc.SessionDescription) {
 	fake.handleOfferMutex.Lock()
 	fake.handleOfferArgsForCall = append(fake.handleOfferArgsForCall, struct {
@@ -2596,6 +3379,59 @@ func (fake *FakeLocalParticipant) IdentityReturnsOnCall(i int, result1 livekit.P
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) IsAudioOnlyDownlink() bool {
+	fake.isAudioOnlyDownlinkMutex.Lock()
+	ret, specificReturn := fake.isAudioOnlyDownlinkReturnsOnCall[len(fake.isAudioOnlyDownlinkArgsForCall)]
+	fake.isAudioOnlyDownlinkArgsForCall = append(fake.isAudioOnlyDownlinkArgsForCall, struct {
+	}{})
+	stub := fake.IsAudioOnlyDownlinkStub
+	fakeReturns := fake.isAudioOnlyDownlinkReturns
+	fake.recordInvocation("IsAudioOnlyDownlink", []interface{}{})
+	fake.isAudioOnlyDownlinkMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) IsAudioOnlyDownlinkCallCount() int {
+	fake.isAudioOnlyDownlinkMutex.RLock()
+	defer fake.isAudioOnlyDownlinkMutex.RUnlock()
+	return len(fake.isAudioOnlyDownlinkArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) IsAudioOnlyDownlinkCalls(stub func() bool) {
+	fake.isAudioOnlyDownlinkMutex.Lock()
+	defer fake.isAudioOnlyDownlinkMutex.Unlock()
+	fake.IsAudioOnlyDownlinkStub = stub
+}
+
+func (fake *FakeLocalParticipant) IsAudioOnlyDownlinkReturns(result1 bool) {
+	fake.isAudioOnlyDownlinkMutex.Lock()
+	defer fake.isAudioOnlyDownlinkMutex.Unlock()
+	fake.IsAudioOnlyDownlinkStub = nil
+	fake.isAudioOnlyDownlinkReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) IsAudioOnlyDownlinkReturnsOnCall(i int, result1 bool) {
+	fake.isAudioOnlyDownlinkMutex.Lock()
+	defer fake.isAudioOnlyDownlinkMutex.Unlock()
+	fake.IsAudioOnlyDownlinkStub = nil
+	if fake.isAudioOnlyDownlinkReturnsOnCall == nil {
+		fake.isAudioOnlyDownlinkReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.isAudioOnlyDownlinkReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) IsClosed() bool {
 	fake.isClosedMutex.Lock()
 	ret, specificReturn := fake.isClosedReturnsOnCall[len(fake.isClosedArgsForCall)]
@@ -3314,6 +4150,38 @@ func (fake *FakeLocalParticipant) OnMigrateStateChangeArgsForCall(i int) func(p
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) OnNetworkChanged(arg1 func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair)) {
+	fake.onNetworkChangedMutex.Lock()
+	fake.onNetworkChangedArgsForCall = append(fake.onNetworkChangedArgsForCall, struct {
+		arg1 func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair)
+	}{arg1})
+	stub := fake.OnNetworkChangedStub
+	fake.recordInvocation("OnNetworkChanged", []interface{}{arg1})
+	fake.onNetworkChangedMutex.Unlock()
+	if stub != nil {
+		fake.OnNetworkChangedStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) OnNetworkChangedCallCount() int {
+	fake.onNetworkChangedMutex.RLock()
+	defer fake.onNetworkChangedMutex.RUnlock()
+	return len(fake.onNetworkChangedArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) OnNetworkChangedCalls(stub func(func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair))) {
+	fake.onNetworkChangedMutex.Lock()
+	defer fake.onNetworkChangedMutex.Unlock()
+	fake.OnNetworkChangedStub = stub
+}
+
+func (fake *FakeLocalParticipant) OnNetworkChangedArgsForCall(i int) func(types.LocalParticipant, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair) {
+	fake.onNetworkChangedMutex.RLock()
+	defer fake.onNetworkChangedMutex.RUnlock()
+	argsForCall := fake.onNetworkChangedArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) OnParticipantUpdate(arg1 func(types.LocalParticipant)) {
 	fake.onParticipantUpdateMutex.Lock()
 	fake.onParticipantUpdateArgsForCall = append(fake.onParticipantUpdateArgsForCall, struct {
@@ -3539,6 +4407,38 @@ func (fake *FakeLocalParticipant) OnTrackUpdatedArgsForCall(i int) func(types.Lo
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) OnUnstable(arg1 func(types.LocalParticipant)) {
+	fake.onUnstableMutex.Lock()
+	fake.onUnstableArgsForCall = append(fake.onUnstableArgsForCall, struct {
+		arg1 func(types.LocalParticipant)
+	}{arg1})
+	stub := fake.OnUnstableStub
+	fake.recordInvocation("OnUnstable", []interface{}{arg1})
+	fake.onUnstableMutex.Unlock()
+	if stub != nil {
+		fake.OnUnstableStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) OnUnstableCallCount() int {
+	fake.onUnstableMutex.RLock()
+	defer fake.onUnstableMutex.RUnlock()
+	return len(fake.onUnstableArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) OnUnstableCalls(stub func(func(types.LocalParticipant))) {
+	fake.onUnstableMutex.Lock()
+	defer fake.onUnstableMutex.Unlock()
+	fake.OnUnstableStub = stub
+}
+
+func (fake *FakeLocalParticipant) OnUnstableArgsForCall(i int) func(types.LocalParticipant) {
+	fake.onUnstableMutex.RLock()
+	defer fake.onUnstableMutex.RUnlock()
+	argsForCall := fake.onUnstableArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) ProtocolVersion() types.ProtocolVersion {
 	fake.protocolVersionMutex.Lock()
 	ret, specificReturn := fake.protocolVersionReturnsOnCall[len(fake.protocolVersionArgsForCall)]
@@ -3687,6 +4587,38 @@ func (fake *FakeLocalParticipant) RemoveTrackFromSubscriberReturnsOnCall(i int,
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SeedSubscriberChannelCapacity(arg1 int64) {
+	fake.seedSubscriberChannelCapacityMutex.Lock()
+	fake.seedSubscriberChannelCapacityArgsForCall = append(fake.seedSubscriberChannelCapacityArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.SeedSubscriberChannelCapacityStub
+	fake.recordInvocation("SeedSubscriberChannelCapacity", []interface{}{arg1})
+	fake.seedSubscriberChannelCapacityMutex.Unlock()
+	if stub != nil {
+		fake.SeedSubscriberChannelCapacityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SeedSubscriberChannelCapacityCallCount() int {
+	fake.seedSubscriberChannelCapacityMutex.RLock()
+	defer fake.seedSubscriberChannelCapacityMutex.RUnlock()
+	return len(fake.seedSubscriberChannelCapacityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SeedSubscriberChannelCapacityCalls(stub func(int64)) {
+	fake.seedSubscriberChannelCapacityMutex.Lock()
+	defer fake.seedSubscriberChannelCapacityMutex.Unlock()
+	fake.SeedSubscriberChannelCapacityStub = stub
+}
+
+func (fake *FakeLocalParticipant) SeedSubscriberChannelCapacityArgsForCall(i int) int64 {
+	fake.seedSubscriberChannelCapacityMutex.RLock()
+	defer fake.seedSubscriberChannelCapacityMutex.RUnlock()
+	argsForCall := fake.seedSubscriberChannelCapacityArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SendConnectionQualityUpdate(arg1 *livekit.ConnectionQualityUpdate) error {
 	fake.sendConnectionQualityUpdateMutex.Lock()
 	ret, specificReturn := fake.sendConnectionQualityUpdateReturnsOnCall[len(fake.sendConnectionQualityUpdateArgsForCall)]
@@ -3876,6 +4808,73 @@ func (fake *FakeLocalParticipant) SendJoinResponseReturnsOnCall(i int, result1 e
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SendLoadShedding(arg1 types.LoadSheddingLevel, arg2 []livekit.TrackID) error {
+	var arg2Copy []livekit.TrackID
+	if arg2 != nil {
+		arg2Copy = make([]livekit.TrackID, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.sendLoadSheddingMutex.Lock()
+	ret, specificReturn := fake.sendLoadSheddingReturnsOnCall[len(fake.sendLoadSheddingArgsForCall)]
+	fake.sendLoadSheddingArgsForCall = append(fake.sendLoadSheddingArgsForCall, struct {
+		arg1 types.LoadSheddingLevel
+		arg2 []livekit.TrackID
+	}{arg1, arg2Copy})
+	stub := fake.SendLoadSheddingStub
+	fakeReturns := fake.sendLoadSheddingReturns
+	fake.recordInvocation("SendLoadShedding", []interface{}{arg1, arg2Copy})
+	fake.sendLoadSheddingMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) SendLoadSheddingCallCount() int {
+	fake.sendLoadSheddingMutex.RLock()
+	defer fake.sendLoadSheddingMutex.RUnlock()
+	return len(fake.sendLoadSheddingArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SendLoadSheddingCalls(stub func(types.LoadSheddingLevel, []livekit.TrackID) error) {
+	fake.sendLoadSheddingMutex.Lock()
+	defer fake.sendLoadSheddingMutex.Unlock()
+	fake.SendLoadSheddingStub = stub
+}
+
+func (fake *FakeLocalParticipant) SendLoadSheddingArgsForCall(i int) (types.LoadSheddingLevel, []livekit.TrackID) {
+	fake.sendLoadSheddingMutex.RLock()
+	defer fake.sendLoadSheddingMutex.RUnlock()
+	argsForCall := fake.sendLoadSheddingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeLocalParticipant) SendLoadSheddingReturns(result1 error) {
+	fake.sendLoadSheddingMutex.Lock()
+	defer fake.sendLoadSheddingMutex.Unlock()
+	fake.SendLoadSheddingStub = nil
+	fake.sendLoadSheddingReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) SendLoadSheddingReturnsOnCall(i int, result1 error) {
+	fake.sendLoadSheddingMutex.Lock()
+	defer fake.sendLoadSheddingMutex.Unlock()
+	fake.SendLoadSheddingStub = nil
+	if fake.sendLoadSheddingReturnsOnCall == nil {
+		fake.sendLoadSheddingReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.sendLoadSheddingReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) SendParticipantUpdate(arg1 []*livekit.ParticipantInfo) error {
 	var arg1Copy []*livekit.ParticipantInfo
 	if arg1 != nil {
@@ -4003,6 +5002,72 @@ func (fake *FakeLocalParticipant) SendRefreshTokenReturnsOnCall(i int, result1 e
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SendResumeParticipantUpdate(arg1 []*livekit.ParticipantInfo) error {
+	var arg1Copy []*livekit.ParticipantInfo
+	if arg1 != nil {
+		arg1Copy = make([]*livekit.ParticipantInfo, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.sendResumeParticipantUpdateMutex.Lock()
+	ret, specificReturn := fake.sendResumeParticipantUpdateReturnsOnCall[len(fake.sendResumeParticipantUpdateArgsForCall)]
+	fake.sendResumeParticipantUpdateArgsForCall = append(fake.sendResumeParticipantUpdateArgsForCall, struct {
+		arg1 []*livekit.ParticipantInfo
+	}{arg1Copy})
+	stub := fake.SendResumeParticipantUpdateStub
+	fakeReturns := fake.sendResumeParticipantUpdateReturns
+	fake.recordInvocation("SendResumeParticipantUpdate", []interface{}{arg1Copy})
+	fake.sendResumeParticipantUpdateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeLocalParticipant) SendResumeParticipantUpdateCallCount() int {
+	fake.sendResumeParticipantUpdateMutex.RLock()
+	defer fake.sendResumeParticipantUpdateMutex.RUnlock()
+	return len(fake.sendResumeParticipantUpdateArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SendResumeParticipantUpdateCalls(stub func([]*livekit.ParticipantInfo) error) {
+	fake.sendResumeParticipantUpdateMutex.Lock()
+	defer fake.sendResumeParticipantUpdateMutex.Unlock()
+	fake.SendResumeParticipantUpdateStub = stub
+}
+
+func (fake *FakeLocalParticipant) SendResumeParticipantUpdateArgsForCall(i int) []*livekit.ParticipantInfo {
+	fake.sendResumeParticipantUpdateMutex.RLock()
+	defer fake.sendResumeParticipantUpdateMutex.RUnlock()
+	argsForCall := fake.sendResumeParticipantUpdateArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeLocalParticipant) SendResumeParticipantUpdateReturns(result1 error) {
+	fake.sendResumeParticipantUpdateMutex.Lock()
+	defer fake.sendResumeParticipantUpdateMutex.Unlock()
+	fake.SendResumeParticipantUpdateStub = nil
+	fake.sendResumeParticipantUpdateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLocalParticipant) SendResumeParticipantUpdateReturnsOnCall(i int, result1 error) {
+	fake.sendResumeParticipantUpdateMutex.Lock()
+	defer fake.sendResumeParticipantUpdateMutex.Unlock()
+	fake.SendResumeParticipantUpdateStub = nil
+	if fake.sendResumeParticipantUpdateReturnsOnCall == nil {
+		fake.sendResumeParticipantUpdateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.sendResumeParticipantUpdateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeLocalParticipant) SendRoomUpdate(arg1 *livekit.Room) error {
 	fake.sendRoomUpdateMutex.Lock()
 	ret, specificReturn := fake.sendRoomUpdateReturnsOnCall[len(fake.sendRoomUpdateArgsForCall)]
@@ -4131,6 +5196,38 @@ func (fake *FakeLocalParticipant) SendSpeakerUpdateReturnsOnCall(i int, result1
 	}{result1}
 }
 
+func (fake *FakeLocalParticipant) SetAudioOnlyDownlink(arg1 bool) {
+	fake.setAudioOnlyDownlinkMutex.Lock()
+	fake.setAudioOnlyDownlinkArgsForCall = append(fake.setAudioOnlyDownlinkArgsForCall, struct {
+		arg1 bool
+	}{arg1})
+	stub := fake.SetAudioOnlyDownlinkStub
+	fake.recordInvocation("SetAudioOnlyDownlink", []interface{}{arg1})
+	fake.setAudioOnlyDownlinkMutex.Unlock()
+	if stub != nil {
+		fake.SetAudioOnlyDownlinkStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetAudioOnlyDownlinkCallCount() int {
+	fake.setAudioOnlyDownlinkMutex.RLock()
+	defer fake.setAudioOnlyDownlinkMutex.RUnlock()
+	return len(fake.setAudioOnlyDownlinkArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetAudioOnlyDownlinkCalls(stub func(bool)) {
+	fake.setAudioOnlyDownlinkMutex.Lock()
+	defer fake.setAudioOnlyDownlinkMutex.Unlock()
+	fake.SetAudioOnlyDownlinkStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetAudioOnlyDownlinkArgsForCall(i int) bool {
+	fake.setAudioOnlyDownlinkMutex.RLock()
+	defer fake.setAudioOnlyDownlinkMutex.RUnlock()
+	argsForCall := fake.setAudioOnlyDownlinkArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SetICEConfig(arg1 *livekit.ICEConfig) {
 	fake.setICEConfigMutex.Lock()
 	fake.setICEConfigArgsForCall = append(fake.setICEConfigArgsForCall, struct {
@@ -4493,6 +5590,38 @@ func (fake *FakeLocalParticipant) SetSubscriberChannelCapacityArgsForCall(i int)
 	return argsForCall.arg1
 }
 
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacity(arg1 int64) {
+	fake.setSubscriberMaxChannelCapacityMutex.Lock()
+	fake.setSubscriberMaxChannelCapacityArgsForCall = append(fake.setSubscriberMaxChannelCapacityArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.SetSubscriberMaxChannelCapacityStub
+	fake.recordInvocation("SetSubscriberMaxChannelCapacity", []interface{}{arg1})
+	fake.setSubscriberMaxChannelCapacityMutex.Unlock()
+	if stub != nil {
+		fake.SetSubscriberMaxChannelCapacityStub(arg1)
+	}
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityCallCount() int {
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
+	return len(fake.setSubscriberMaxChannelCapacityArgsForCall)
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityCalls(stub func(int64)) {
+	fake.setSubscriberMaxChannelCapacityMutex.Lock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.Unlock()
+	fake.SetSubscriberMaxChannelCapacityStub = stub
+}
+
+func (fake *FakeLocalParticipant) SetSubscriberMaxChannelCapacityArgsForCall(i int) int64 {
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
+	argsForCall := fake.setSubscriberMaxChannelCapacityArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeLocalParticipant) SetTrackMuted(arg1 livekit.TrackID, arg2 bool, arg3 bool) {
 	fake.setTrackMutedMutex.Lock()
 	fake.setTrackMutedArgsForCall = append(fake.setTrackMutedArgsForCall, struct {
@@ -4689,7 +5818,7 @@ func (fake *FakeLocalParticipant) SubscriberAsPrimaryReturnsOnCall(i int, result
 	}{result1}
 }
 
-func (fake *FakeLocalParticipant) SubscriptionPermission() (*livekit.SubscriptionPermission, utils.TimedVersion) {
+func (fake *FakeLocalParticipant) SubscriptionPermission() (*livekit.SubscriptionPermission, utilsa.TimedVersion) {
 	fake.subscriptionPermissionMutex.Lock()
 	ret, specificReturn := fake.subscriptionPermissionReturnsOnCall[len(fake.subscriptionPermissionArgsForCall)]
 	fake.subscriptionPermissionArgsForCall = append(fake.subscriptionPermissionArgsForCall, struct {
@@ -4713,35 +5842,35 @@ func (fake *FakeLocalParticipant) SubscriptionPermissionCallCount() int {
 	return len(fake.subscriptionPermissionArgsForCall)
 }
 
-func (fake *FakeLocalParticipant) SubscriptionPermissionCalls(stub func() (*livekit.SubscriptionPermission, utils.TimedVersion)) {
+func (fake *FakeLocalParticipant) SubscriptionPermissionCalls(stub func() (*livekit.SubscriptionPermission, utilsa.TimedVersion)) {
 	fake.subscriptionPermissionMutex.Lock()
 	defer fake.subscriptionPermissionMutex.Unlock()
 	fake.SubscriptionPermissionStub = stub
 }
 
-func (fake *FakeLocalParticipant) SubscriptionPermissionReturns(result1 *livekit.SubscriptionPermission, result2 utils.TimedVersion) {
+func (fake *FakeLocalParticipant) SubscriptionPermissionReturns(result1 *livekit.SubscriptionPermission, result2 utilsa.TimedVersion) {
 	fake.subscriptionPermissionMutex.Lock()
 	defer fake.subscriptionPermissionMutex.Unlock()
 	fake.SubscriptionPermissionStub = nil
 	fake.subscriptionPermissionReturns = struct {
 		result1 *livekit.SubscriptionPermission
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}{result1, result2}
 }
 
-func (fake *FakeLocalParticipant) SubscriptionPermissionReturnsOnCall(i int, result1 *livekit.SubscriptionPermission, result2 utils.TimedVersion) {
+func (fake *FakeLocalParticipant) SubscriptionPermissionReturnsOnCall(i int, result1 *livekit.SubscriptionPermission, result2 utilsa.TimedVersion) {
 	fake.subscriptionPermissionMutex.Lock()
 	defer fake.subscriptionPermissionMutex.Unlock()
 	fake.SubscriptionPermissionStub = nil
 	if fake.subscriptionPermissionReturnsOnCall == nil {
 		fake.subscriptionPermissionReturnsOnCall = make(map[int]struct {
 			result1 *livekit.SubscriptionPermission
-			result2 utils.TimedVersion
+			result2 utilsa.TimedVersion
 		})
 	}
 	fake.subscriptionPermissionReturnsOnCall[i] = struct {
 		result1 *livekit.SubscriptionPermission
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}{result1, result2}
 }
 
@@ -4832,7 +5961,7 @@ func (fake *FakeLocalParticipant) ToProtoReturnsOnCall(i int, result1 *livekit.P
 	}{result1}
 }
 
-func (fake *FakeLocalParticipant) ToProtoWithVersion() (*livekit.ParticipantInfo, utils.TimedVersion) {
+func (fake *FakeLocalParticipant) ToProtoWithVersion() (*livekit.ParticipantInfo, utilsa.TimedVersion) {
 	fake.toProtoWithVersionMutex.Lock()
 	ret, specificReturn := fake.toProtoWithVersionReturnsOnCall[len(fake.toProtoWithVersionArgsForCall)]
 	fake.toProtoWithVersionArgsForCall = append(fake.toProtoWithVersionArgsForCall, struct {
@@ -4856,35 +5985,35 @@ func (fake *FakeLocalParticipant) ToProtoWithVersionCallCount() int {
 	return len(fake.toProtoWithVersionArgsForCall)
 }
 
-func (fake *FakeLocalParticipant) ToProtoWithVersionCalls(stub func() (*livekit.ParticipantInfo, utils.TimedVersion)) {
+func (fake *FakeLocalParticipant) ToProtoWithVersionCalls(stub func() (*livekit.ParticipantInfo, utilsa.TimedVersion)) {
 	fake.toProtoWithVersionMutex.Lock()
 	defer fake.toProtoWithVersionMutex.Unlock()
 	fake.ToProtoWithVersionStub = stub
 }
 
-func (fake *FakeLocalParticipant) ToProtoWithVersionReturns(result1 *livekit.ParticipantInfo, result2 utils.TimedVersion) {
+func (fake *FakeLocalParticipant) ToProtoWithVersionReturns(result1 *livekit.ParticipantInfo, result2 utilsa.TimedVersion) {
 	fake.toProtoWithVersionMutex.Lock()
 	defer fake.toProtoWithVersionMutex.Unlock()
 	fake.ToProtoWithVersionStub = nil
 	fake.toProtoWithVersionReturns = struct {
 		result1 *livekit.ParticipantInfo
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}{result1, result2}
 }
 
-func (fake *FakeLocalParticipant) ToProtoWithVersionReturnsOnCall(i int, result1 *livekit.ParticipantInfo, result2 utils.TimedVersion) {
+func (fake *FakeLocalParticipant) ToProtoWithVersionReturnsOnCall(i int, result1 *livekit.ParticipantInfo, result2 utilsa.TimedVersion) {
 	fake.toProtoWithVersionMutex.Lock()
 	defer fake.toProtoWithVersionMutex.Unlock()
 	fake.ToProtoWithVersionStub = nil
 	if fake.toProtoWithVersionReturnsOnCall == nil {
 		fake.toProtoWithVersionReturnsOnCall = make(map[int]struct {
 			result1 *livekit.ParticipantInfo
-			result2 utils.TimedVersion
+			result2 utilsa.TimedVersion
 		})
 	}
 	fake.toProtoWithVersionReturnsOnCall[i] = struct {
 		result1 *livekit.ParticipantInfo
-		result2 utils.TimedVersion
+		result2 utilsa.TimedVersion
 	}{result1, result2}
 }
 
@@ -5204,12 +6333,12 @@ func (fake *FakeLocalParticipant) UpdateSubscribedTrackSettingsArgsForCall(i int
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeLocalParticipant) UpdateSubscriptionPermission(arg1 *livekit.SubscriptionPermission, arg2 utils.TimedVersion, arg3 func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, arg4 func(participantID livekit.ParticipantID) types.LocalParticipant) error {
+func (fake *FakeLocalParticipant) UpdateSubscriptionPermission(arg1 *livekit.SubscriptionPermission, arg2 utilsa.TimedVersion, arg3 func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, arg4 func(participantID livekit.ParticipantID) types.LocalParticipant) error {
 	fake.updateSubscriptionPermissionMutex.Lock()
 	ret, specificReturn := fake.updateSubscriptionPermissionReturnsOnCall[len(fake.updateSubscriptionPermissionArgsForCall)]
 	fake.updateSubscriptionPermissionArgsForCall = append(fake.updateSubscriptionPermissionArgsForCall, struct {
 		arg1 *livekit.SubscriptionPermission
-		arg2 utils.TimedVersion
+		arg2 utilsa.TimedVersion
 		arg3 func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant
 		arg4 func(participantID livekit.ParticipantID) types.LocalParticipant
 	}{arg1, arg2, arg3, arg4})
@@ -5232,13 +6361,13 @@ func (fake *FakeLocalParticipant) UpdateSubscriptionPermissionCallCount() int {
 	return len(fake.updateSubscriptionPermissionArgsForCall)
 }
 
-func (fake *FakeLocalParticipant) UpdateSubscriptionPermissionCalls(stub func(*livekit.SubscriptionPermission, utils.TimedVersion, func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, func(participantID livekit.ParticipantID) types.LocalParticipant) error) {
+func (fake *FakeLocalParticipant) UpdateSubscriptionPermissionCalls(stub func(*livekit.SubscriptionPermission, utilsa.TimedVersion, func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, func(participantID livekit.ParticipantID) types.LocalParticipant) error) {
 	fake.updateSubscriptionPermissionMutex.Lock()
 	defer fake.updateSubscriptionPermissionMutex.Unlock()
 	fake.UpdateSubscriptionPermissionStub = stub
 }
 
-func (fake *FakeLocalParticipant) UpdateSubscriptionPermissionArgsForCall(i int) (*livekit.SubscriptionPermission, utils.TimedVersion, func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, func(participantID livekit.ParticipantID) types.LocalParticipant) {
+func (fake *FakeLocalParticipant) UpdateSubscriptionPermissionArgsForCall(i int) (*livekit.SubscriptionPermission, utilsa.TimedVersion, func(participantIdentity livekit.ParticipantIdentity) types.LocalParticipant, func(participantID livekit.ParticipantID) types.LocalParticipant) {
 	fake.updateSubscriptionPermissionMutex.RLock()
 	defer fake.updateSubscriptionPermissionMutex.RUnlock()
 	argsForCall := fake.updateSubscriptionPermissionArgsForCall[i]
@@ -5444,16 +6573,24 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.canSkipBroadcastMutex.RUnlock()
 	fake.canSubscribeMutex.RLock()
 	defer fake.canSubscribeMutex.RUnlock()
+	fake.capabilitiesMutex.RLock()
+	defer fake.capabilitiesMutex.RUnlock()
+	fake.checkpointMutex.RLock()
+	defer fake.checkpointMutex.RUnlock()
 	fake.claimGrantsMutex.RLock()
 	defer fake.claimGrantsMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
+	fake.closeReasonMutex.RLock()
+	defer fake.closeReasonMutex.RUnlock()
 	fake.closeSignalConnectionMutex.RLock()
 	defer fake.closeSignalConnectionMutex.RUnlock()
 	fake.connectedAtMutex.RLock()
 	defer fake.connectedAtMutex.RUnlock()
 	fake.debugInfoMutex.RLock()
 	defer fake.debugInfoMutex.RUnlock()
+	fake.declareTrackSyncGroupMutex.RLock()
+	defer fake.declareTrackSyncGroupMutex.RUnlock()
 	fake.getAdaptiveStreamMutex.RLock()
 	defer fake.getAdaptiveStreamMutex.RUnlock()
 	fake.getAudioLevelMutex.RLock()
@@ -5466,6 +6603,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.getClientConfigurationMutex.RUnlock()
 	fake.getConnectionQualityMutex.RLock()
 	defer fake.getConnectionQualityMutex.RUnlock()
+	fake.getDeviceHintsMutex.RLock()
+	defer fake.getDeviceHintsMutex.RUnlock()
 	fake.getICEConnectionTypeMutex.RLock()
 	defer fake.getICEConnectionTypeMutex.RUnlock()
 	fake.getLoggerMutex.RLock()
@@ -5474,12 +6613,24 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.getPublishedTrackMutex.RUnlock()
 	fake.getPublishedTracksMutex.RLock()
 	defer fake.getPublishedTracksMutex.RUnlock()
+	fake.getResourceStatsMutex.RLock()
+	defer fake.getResourceStatsMutex.RUnlock()
+	fake.getSelectedICECandidatePairsMutex.RLock()
+	defer fake.getSelectedICECandidatePairsMutex.RUnlock()
 	fake.getSubscribedParticipantsMutex.RLock()
 	defer fake.getSubscribedParticipantsMutex.RUnlock()
 	fake.getSubscribedTracksMutex.RLock()
 	defer fake.getSubscribedTracksMutex.RUnlock()
+	fake.getSubscriberChannelCapacityMutex.RLock()
+	defer fake.getSubscriberChannelCapacityMutex.RUnlock()
+	fake.getSubscriberCodecsMutex.RLock()
+	defer fake.getSubscriberCodecsMutex.RUnlock()
+	fake.getSubscriptionDiagnosticsMutex.RLock()
+	defer fake.getSubscriptionDiagnosticsMutex.RUnlock()
 	fake.handleAnswerMutex.RLock()
 	defer fake.handleAnswerMutex.RUnlock()
+	fake.handleNodeIPChangedMutex.RLock()
+	defer fake.handleNodeIPChangedMutex.RUnlock()
 	fake.handleOfferMutex.RLock()
 	defer fake.handleOfferMutex.RUnlock()
 	fake.handleReconnectAndSendResponseMutex.RLock()
@@ -5494,6 +6645,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.iDMutex.RUnlock()
 	fake.identityMutex.RLock()
 	defer fake.identityMutex.RUnlock()
+	fake.isAudioOnlyDownlinkMutex.RLock()
+	defer fake.isAudioOnlyDownlinkMutex.RUnlock()
 	fake.isClosedMutex.RLock()
 	defer fake.isClosedMutex.RUnlock()
 	fake.isDisconnectedMutex.RLock()
@@ -5526,6 +6679,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.onICEConfigChangedMutex.RUnlock()
 	fake.onMigrateStateChangeMutex.RLock()
 	defer fake.onMigrateStateChangeMutex.RUnlock()
+	fake.onNetworkChangedMutex.RLock()
+	defer fake.onNetworkChangedMutex.RUnlock()
 	fake.onParticipantUpdateMutex.RLock()
 	defer fake.onParticipantUpdateMutex.RUnlock()
 	fake.onReceiverReportMutex.RLock()
@@ -5540,26 +6695,36 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.onTrackUnpublishedMutex.RUnlock()
 	fake.onTrackUpdatedMutex.RLock()
 	defer fake.onTrackUpdatedMutex.RUnlock()
+	fake.onUnstableMutex.RLock()
+	defer fake.onUnstableMutex.RUnlock()
 	fake.protocolVersionMutex.RLock()
 	defer fake.protocolVersionMutex.RUnlock()
 	fake.removePublishedTrackMutex.RLock()
 	defer fake.removePublishedTrackMutex.RUnlock()
 	fake.removeTrackFromSubscriberMutex.RLock()
 	defer fake.removeTrackFromSubscriberMutex.RUnlock()
+	fake.seedSubscriberChannelCapacityMutex.RLock()
+	defer fake.seedSubscriberChannelCapacityMutex.RUnlock()
 	fake.sendConnectionQualityUpdateMutex.RLock()
 	defer fake.sendConnectionQualityUpdateMutex.RUnlock()
 	fake.sendDataPacketMutex.RLock()
 	defer fake.sendDataPacketMutex.RUnlock()
 	fake.sendJoinResponseMutex.RLock()
 	defer fake.sendJoinResponseMutex.RUnlock()
+	fake.sendLoadSheddingMutex.RLock()
+	defer fake.sendLoadSheddingMutex.RUnlock()
 	fake.sendParticipantUpdateMutex.RLock()
 	defer fake.sendParticipantUpdateMutex.RUnlock()
 	fake.sendRefreshTokenMutex.RLock()
 	defer fake.sendRefreshTokenMutex.RUnlock()
+	fake.sendResumeParticipantUpdateMutex.RLock()
+	defer fake.sendResumeParticipantUpdateMutex.RUnlock()
 	fake.sendRoomUpdateMutex.RLock()
 	defer fake.sendRoomUpdateMutex.RUnlock()
 	fake.sendSpeakerUpdateMutex.RLock()
 	defer fake.sendSpeakerUpdateMutex.RUnlock()
+	fake.setAudioOnlyDownlinkMutex.RLock()
+	defer fake.setAudioOnlyDownlinkMutex.RUnlock()
 	fake.setICEConfigMutex.RLock()
 	defer fake.setICEConfigMutex.RUnlock()
 	fake.setMetadataMutex.RLock()
@@ -5580,6 +6745,8 @@ func (fake *FakeLocalParticipant) Invocations() map[string][][]interface{} {
 	defer fake.setSubscriberAllowPauseMutex.RUnlock()
 	fake.setSubscriberChannelCapacityMutex.RLock()
 	defer fake.setSubscriberChannelCapacityMutex.RUnlock()
+	fake.setSubscriberMaxChannelCapacityMutex.RLock()
+	defer fake.setSubscriberMaxChannelCapacityMutex.RUnlock()
 	fake.setTrackMutedMutex.RLock()
 	defer fake.setTrackMutedMutex.RUnlock()
 	fake.startMutex.RLock()
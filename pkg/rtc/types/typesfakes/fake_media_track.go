@@ -144,6 +144,16 @@ type FakeMediaTrack struct {
 	kindReturnsOnCall map[int]struct {
 		result1 livekit.TrackType
 	}
+	LoadSheddingQualityStub        func() livekit.VideoQuality
+	loadSheddingQualityMutex       sync.RWMutex
+	loadSheddingQualityArgsForCall []struct {
+	}
+	loadSheddingQualityReturns struct {
+		result1 livekit.VideoQuality
+	}
+	loadSheddingQualityReturnsOnCall map[int]struct {
+		result1 livekit.VideoQuality
+	}
 	NameStub        func() string
 	nameMutex       sync.RWMutex
 	nameArgsForCall []struct {
@@ -211,6 +221,11 @@ type FakeMediaTrack struct {
 	revokeDisallowedSubscribersReturnsOnCall map[int]struct {
 		result1 []livekit.ParticipantIdentity
 	}
+	SetLoadSheddingQualityStub        func(livekit.VideoQuality)
+	setLoadSheddingQualityMutex       sync.RWMutex
+	setLoadSheddingQualityArgsForCall []struct {
+		arg1 livekit.VideoQuality
+	}
 	SetMutedStub        func(bool)
 	setMutedMutex       sync.RWMutex
 	setMutedArgsForCall []struct {
@@ -962,6 +977,59 @@ func (fake *FakeMediaTrack) KindReturnsOnCall(i int, result1 livekit.TrackType)
 	}{result1}
 }
 
+func (fake *FakeMediaTrack) LoadSheddingQuality() livekit.VideoQuality {
+	fake.loadSheddingQualityMutex.Lock()
+	ret, specificReturn := fake.loadSheddingQualityReturnsOnCall[len(fake.loadSheddingQualityArgsForCall)]
+	fake.loadSheddingQualityArgsForCall = append(fake.loadSheddingQualityArgsForCall, struct {
+	}{})
+	stub := fake.LoadSheddingQualityStub
+	fakeReturns := fake.loadSheddingQualityReturns
+	fake.recordInvocation("LoadSheddingQuality", []interface{}{})
+	fake.loadSheddingQualityMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeMediaTrack) LoadSheddingQualityCallCount() int {
+	fake.loadSheddingQualityMutex.RLock()
+	defer fake.loadSheddingQualityMutex.RUnlock()
+	return len(fake.loadSheddingQualityArgsForCall)
+}
+
+func (fake *FakeMediaTrack) LoadSheddingQualityCalls(stub func() livekit.VideoQuality) {
+	fake.loadSheddingQualityMutex.Lock()
+	defer fake.loadSheddingQualityMutex.Unlock()
+	fake.LoadSheddingQualityStub = stub
+}
+
+func (fake *FakeMediaTrack) LoadSheddingQualityReturns(result1 livekit.VideoQuality) {
+	fake.loadSheddingQualityMutex.Lock()
+	defer fake.loadSheddingQualityMutex.Unlock()
+	fake.LoadSheddingQualityStub = nil
+	fake.loadSheddingQualityReturns = struct {
+		result1 livekit.VideoQuality
+	}{result1}
+}
+
+func (fake *FakeMediaTrack) LoadSheddingQualityReturnsOnCall(i int, result1 livekit.VideoQuality) {
+	fake.loadSheddingQualityMutex.Lock()
+	defer fake.loadSheddingQualityMutex.Unlock()
+	fake.LoadSheddingQualityStub = nil
+	if fake.loadSheddingQualityReturnsOnCall == nil {
+		fake.loadSheddingQualityReturnsOnCall = make(map[int]struct {
+			result1 livekit.VideoQuality
+		})
+	}
+	fake.loadSheddingQualityReturnsOnCall[i] = struct {
+		result1 livekit.VideoQuality
+	}{result1}
+}
+
 func (fake *FakeMediaTrack) Name() string {
 	fake.nameMutex.Lock()
 	ret, specificReturn := fake.nameReturnsOnCall[len(fake.nameArgsForCall)]
@@ -1326,6 +1394,38 @@ func (fake *FakeMediaTrack) RevokeDisallowedSubscribersReturnsOnCall(i int, resu
 	}{result1}
 }
 
+func (fake *FakeMediaTrack) SetLoadSheddingQuality(arg1 livekit.VideoQuality) {
+	fake.setLoadSheddingQualityMutex.Lock()
+	fake.setLoadSheddingQualityArgsForCall = append(fake.setLoadSheddingQualityArgsForCall, struct {
+		arg1 livekit.VideoQuality
+	}{arg1})
+	stub := fake.SetLoadSheddingQualityStub
+	fake.recordInvocation("SetLoadSheddingQuality", []interface{}{arg1})
+	fake.setLoadSheddingQualityMutex.Unlock()
+	if stub != nil {
+		fake.SetLoadSheddingQualityStub(arg1)
+	}
+}
+
+func (fake *FakeMediaTrack) SetLoadSheddingQualityCallCount() int {
+	fake.setLoadSheddingQualityMutex.RLock()
+	defer fake.setLoadSheddingQualityMutex.RUnlock()
+	return len(fake.setLoadSheddingQualityArgsForCall)
+}
+
+func (fake *FakeMediaTrack) SetLoadSheddingQualityCalls(stub func(livekit.VideoQuality)) {
+	fake.setLoadSheddingQualityMutex.Lock()
+	defer fake.setLoadSheddingQualityMutex.Unlock()
+	fake.SetLoadSheddingQualityStub = stub
+}
+
+func (fake *FakeMediaTrack) SetLoadSheddingQualityArgsForCall(i int) livekit.VideoQuality {
+	fake.setLoadSheddingQualityMutex.RLock()
+	defer fake.setLoadSheddingQualityMutex.RUnlock()
+	argsForCall := fake.setLoadSheddingQualityArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeMediaTrack) SetMuted(arg1 bool) {
 	fake.setMutedMutex.Lock()
 	fake.setMutedArgsForCall = append(fake.setMutedArgsForCall, struct {
@@ -1532,6 +1632,8 @@ func (fake *FakeMediaTrack) Invocations() map[string][][]interface{} {
 	defer fake.isSubscriberMutex.RUnlock()
 	fake.kindMutex.RLock()
 	defer fake.kindMutex.RUnlock()
+	fake.loadSheddingQualityMutex.RLock()
+	defer fake.loadSheddingQualityMutex.RUnlock()
 	fake.nameMutex.RLock()
 	defer fake.nameMutex.RUnlock()
 	fake.publisherIDMutex.RLock()
@@ -1546,6 +1648,8 @@ func (fake *FakeMediaTrack) Invocations() map[string][][]interface{} {
 	defer fake.removeSubscriberMutex.RUnlock()
 	fake.revokeDisallowedSubscribersMutex.RLock()
 	defer fake.revokeDisallowedSubscribersMutex.RUnlock()
+	fake.setLoadSheddingQualityMutex.RLock()
+	defer fake.setLoadSheddingQualityMutex.RUnlock()
 	fake.setMutedMutex.RLock()
 	defer fake.setMutedMutex.RUnlock()
 	fake.sourceMutex.RLock()
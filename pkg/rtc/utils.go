@@ -53,6 +53,28 @@ func ToProtoParticipants(participants []types.LocalParticipant) []*livekit.Parti
 	return infos
 }
 
+// serverParticipantInfo describes a participant of the server publishing a track of its own
+func serverParticipantInfo(
+	participantID livekit.ParticipantID,
+	identity string,
+	name string,
+	joinedAt int64,
+	track *livekit.TrackInfo,
+) *livekit.ParticipantInfo {
+	return &livekit.ParticipantInfo{
+		Sid:      string(participantID),
+		Identity: identity,
+		Name:     name,
+		State:    livekit.ParticipantInfo_ACTIVE,
+		Tracks:   []*livekit.TrackInfo{track},
+		JoinedAt: joinedAt,
+		Version:  1,
+		Permission: &livekit.ParticipantPermission{
+			CanPublish: true,
+		},
+	}
+}
+
 func ToProtoSessionDescription(sd webrtc.SessionDescription) *livekit.SessionDescription {
 	return &livekit.SessionDescription{
 		Type: sd.Type.String(),
@@ -139,8 +161,10 @@ func LoggerWithParticipant(l logger.Logger, identity livekit.ParticipantIdentity
 		values = append(values, "pID", sid)
 	}
 	values = append(values, "remote", isRemote)
+	ol := newOverrideLogger(l)
+	ol.identity = identity
 	// enable sampling per participant
-	return l.WithValues(values...)
+	return ol.WithValues(values...)
 }
 
 func LoggerWithRoom(l logger.Logger, name livekit.RoomName, roomID livekit.RoomID) logger.Logger {
@@ -151,8 +175,10 @@ func LoggerWithRoom(l logger.Logger, name livekit.RoomName, roomID livekit.RoomI
 	if roomID != "" {
 		values = append(values, "roomID", roomID)
 	}
+	ol := newOverrideLogger(l)
+	ol.room = name
 	// also sample for the room
-	return l.WithItemSampler().WithValues(values...)
+	return ol.WithItemSampler().WithValues(values...)
 }
 
 func LoggerWithTrack(l logger.Logger, trackID livekit.TrackID, isRelayed bool) logger.Logger {
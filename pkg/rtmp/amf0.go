@@ -0,0 +1,217 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// AMF0 markers, only the types used by publishing clients are supported
+const (
+	amf0Number      = 0x00
+	amf0Boolean     = 0x01
+	amf0String      = 0x02
+	amf0Object      = 0x03
+	amf0Null        = 0x05
+	amf0Undefined   = 0x06
+	amf0ECMAArray   = 0x08
+	amf0ObjectEnd   = 0x09
+	amf0StrictArray = 0x0a
+	amf0Date        = 0x0b
+	amf0LongString  = 0x0c
+)
+
+var errAMF0Truncated = errors.New("truncated AMF0 value")
+
+// decodeAMF0 decodes all values in data. Numbers are float64, objects and ECMA arrays are map[string]interface{},
+// null and undefined are nil
+func decodeAMF0(data []byte) ([]interface{}, error) {
+	r := bytes.NewReader(data)
+	var values []interface{}
+	for r.Len() > 0 {
+		v, err := readAMF0(r)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func readAMF0(r *bytes.Reader) (interface{}, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, errAMF0Truncated
+	}
+
+	switch marker {
+	case amf0Number:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errAMF0Truncated
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b[:])), nil
+	case amf0Boolean:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errAMF0Truncated
+		}
+		return b != 0, nil
+	case amf0String:
+		return readAMF0String(r, 2)
+	case amf0LongString:
+		return readAMF0String(r, 4)
+	case amf0Object:
+		return readAMF0Properties(r)
+	case amf0ECMAArray:
+		// the count is advisory, properties end with the object end marker
+		var b [4]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errAMF0Truncated
+		}
+		return readAMF0Properties(r)
+	case amf0StrictArray:
+		var b [4]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errAMF0Truncated
+		}
+		count := binary.BigEndian.Uint32(b[:])
+		if int64(count) > int64(r.Len()) {
+			return nil, errAMF0Truncated
+		}
+		values := make([]interface{}, 0, count)
+		for i := uint32(0); i < count; i++ {
+			v, err := readAMF0(r)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case amf0Date:
+		var b [10]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errAMF0Truncated
+		}
+		// milliseconds since epoch followed by an unused time zone
+		return math.Float64frombits(binary.BigEndian.Uint64(b[:8])), nil
+	case amf0Null, amf0Undefined:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported AMF0 marker 0x%02x", marker)
+	}
+}
+
+func readAMF0String(r *bytes.Reader, lengthSize int) (string, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[4-lengthSize:]); err != nil {
+		return "", errAMF0Truncated
+	}
+	l := binary.BigEndian.Uint32(b[:])
+	if int64(l) > int64(r.Len()) {
+		return "", errAMF0Truncated
+	}
+	s := make([]byte, l)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", errAMF0Truncated
+	}
+	return string(s), nil
+}
+
+func readAMF0Properties(r *bytes.Reader) (map[string]interface{}, error) {
+	props := make(map[string]interface{})
+	for {
+		key, err := readAMF0String(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			marker, err := r.ReadByte()
+			if err != nil {
+				return nil, errAMF0Truncated
+			}
+			if marker == amf0ObjectEnd {
+				return props, nil
+			}
+			if err := r.UnreadByte(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := readAMF0(r)
+		if err != nil {
+			return nil, err
+		}
+		props[key] = v
+	}
+}
+
+// encodeAMF0 encodes values, supporting the types returned by decodeAMF0 and integers
+func encodeAMF0(values ...interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if err := writeAMF0(&buf, v); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeAMF0(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(amf0Null)
+	case float64:
+		buf.WriteByte(amf0Number)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case int:
+		return writeAMF0(buf, float64(v))
+	case uint32:
+		return writeAMF0(buf, float64(v))
+	case bool:
+		buf.WriteByte(amf0Boolean)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case string:
+		if len(v) > math.MaxUint16 {
+			buf.WriteByte(amf0LongString)
+			_ = binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		} else {
+			buf.WriteByte(amf0String)
+			_ = binary.Write(buf, binary.BigEndian, uint16(len(v)))
+		}
+		buf.WriteString(v)
+	case map[string]interface{}:
+		buf.WriteByte(amf0Object)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			_ = binary.Write(buf, binary.BigEndian, uint16(len(k)))
+			buf.WriteString(k)
+			if err := writeAMF0(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.Write([]byte{0, 0, amf0ObjectEnd})
+	case []interface{}:
+		buf.WriteByte(amf0StrictArray)
+		_ = binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		for _, e := range v {
+			if err := writeAMF0(buf, e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as AMF0", v)
+	}
+	return nil
+}
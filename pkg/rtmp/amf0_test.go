@@ -0,0 +1,49 @@
+package rtmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAMF0(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		data, err := encodeAMF0("connect", 1, map[string]interface{}{
+			"app":      "live",
+			"flashVer": "FMLE/3.0",
+			"audio":    true,
+		}, nil, []interface{}{"a", 2.5})
+		require.NoError(t, err)
+
+		values, err := decodeAMF0(data)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{
+			"connect",
+			float64(1),
+			map[string]interface{}{"app": "live", "flashVer": "FMLE/3.0", "audio": true},
+			nil,
+			[]interface{}{"a", 2.5},
+		}, values)
+	})
+
+	t.Run("ECMA array", func(t *testing.T) {
+		// @setDataFrame metadata as sent by encoders
+		data := []byte{amf0ECMAArray, 0, 0, 0, 1, 0, 5, 'w', 'i', 'd', 't', 'h', amf0Number, 0x40, 0x9e, 0, 0, 0, 0, 0, 0, 0, 0, amf0ObjectEnd}
+		values, err := decodeAMF0(data)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{map[string]interface{}{"width": float64(1920)}}, values)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data, err := encodeAMF0("publish", map[string]interface{}{"key": "value"})
+		require.NoError(t, err)
+		for i := 1; i < len(data); i++ {
+			if i == 10 {
+				// a complete string value
+				continue
+			}
+			_, err := decodeAMF0(data[:i])
+			require.Error(t, err, "length %d", i)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package rtmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// message types, RTMP specification 5.4 and 7.1
+const (
+	msgSetChunkSize     = 1
+	msgAbort            = 2
+	msgAck              = 3
+	msgUserControl      = 4
+	msgWindowAckSize    = 5
+	msgSetPeerBandwidth = 6
+	msgAudio            = 8
+	msgVideo            = 9
+	msgDataAMF3         = 15
+	msgCommandAMF3      = 17
+	msgDataAMF0         = 18
+	msgCommandAMF0      = 20
+)
+
+const (
+	defaultChunkSize = 128
+	maxChunkSize     = 1 << 24
+	// timestamps from this value on are carried in the extended timestamp field
+	extendedTimestamp = 0xffffff
+	// publishers use a handful of chunk streams, bounds the memory held by partial messages
+	maxChunkStreams = 32
+	// key frames of high bitrate streams fit, the 24 bit length field allows 16MB
+	maxMessageLength = 4 << 20
+	// payloads are grown by at most this much per read, rather than by the length announced by the peer
+	payloadReadStep = 64 << 10
+)
+
+var (
+	errInvalidChunkSize    = errors.New("invalid chunk size")
+	errTooManyChunkStreams = errors.New("too many chunk streams")
+	errMessageTooLong      = errors.New("message too long")
+)
+
+type message struct {
+	typeID    uint8
+	streamID  uint32
+	timestamp uint32
+	payload   []byte
+}
+
+// chunkStream is the state the headers of a chunk stream are decoded against
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    uint32
+	typeID    uint8
+	streamID  uint32
+	extended  bool
+	payload   []byte
+}
+
+// chunkReader reassembles messages from the interleaved chunk streams of a connection
+type chunkReader struct {
+	r         io.Reader
+	chunkSize uint32
+	streams   map[uint32]*chunkStream
+	buf       [11]byte
+}
+
+func newChunkReader(r io.Reader) *chunkReader {
+	return &chunkReader{
+		r:         r,
+		chunkSize: defaultChunkSize,
+		streams:   make(map[uint32]*chunkStream),
+	}
+}
+
+func (c *chunkReader) setChunkSize(size uint32) error {
+	if size == 0 || size > maxChunkSize {
+		return errInvalidChunkSize
+	}
+	c.chunkSize = size
+	return nil
+}
+
+func (c *chunkReader) readMessage() (*message, error) {
+	for {
+		if _, err := io.ReadFull(c.r, c.buf[:1]); err != nil {
+			return nil, err
+		}
+		format := c.buf[0] >> 6
+		csid := uint32(c.buf[0] & 0x3f)
+		switch csid {
+		case 0:
+			if _, err := io.ReadFull(c.r, c.buf[:1]); err != nil {
+				return nil, err
+			}
+			csid = 64 + uint32(c.buf[0])
+		case 1:
+			if _, err := io.ReadFull(c.r, c.buf[:2]); err != nil {
+				return nil, err
+			}
+			csid = 64 + uint32(c.buf[0]) + uint32(c.buf[1])<<8
+		}
+
+		cs := c.streams[csid]
+		if cs == nil {
+			if len(c.streams) >= maxChunkStreams {
+				return nil, errTooManyChunkStreams
+			}
+			cs = &chunkStream{}
+			c.streams[csid] = cs
+		}
+
+		headerLen := [...]int{11, 7, 3, 0}[format]
+		if _, err := io.ReadFull(c.r, c.buf[:headerLen]); err != nil {
+			return nil, err
+		}
+		var ts uint32
+		if format < 3 {
+			ts = uint24(c.buf[0:3])
+			cs.extended = ts == extendedTimestamp
+		}
+		if format < 2 {
+			cs.length = uint24(c.buf[3:6])
+			cs.typeID = c.buf[6]
+			if cs.length > maxMessageLength {
+				return nil, errMessageTooLong
+			}
+		}
+		if format == 0 {
+			cs.streamID = binary.LittleEndian.Uint32(c.buf[7:11])
+		}
+		if cs.extended {
+			if _, err := io.ReadFull(c.r, c.buf[:4]); err != nil {
+				return nil, err
+			}
+			if format < 3 {
+				ts = binary.BigEndian.Uint32(c.buf[:4])
+			}
+		}
+
+		switch format {
+		case 0:
+			cs.timestamp = ts
+			cs.delta = 0
+		case 1, 2:
+			cs.delta = ts
+			cs.timestamp += ts
+		case 3:
+			if cs.payload == nil {
+				// a new message with the same header as the previous one
+				cs.timestamp += cs.delta
+			}
+		}
+
+		if cs.payload == nil {
+			cs.payload = make([]byte, 0, defaultChunkSize)
+		}
+		n := cs.length - uint32(len(cs.payload))
+		if n > c.chunkSize {
+			n = c.chunkSize
+		}
+		if err := c.readPayload(cs, int(n)); err != nil {
+			return nil, err
+		}
+
+		if uint32(len(cs.payload)) == cs.length {
+			m := &message{
+				typeID:    cs.typeID,
+				streamID:  cs.streamID,
+				timestamp: cs.timestamp,
+				payload:   cs.payload,
+			}
+			cs.payload = nil
+			return m, nil
+		}
+	}
+}
+
+// readPayload appends n bytes read to the payload of a chunk stream
+func (c *chunkReader) readPayload(cs *chunkStream, n int) error {
+	for n > 0 {
+		step := n
+		if step > payloadReadStep {
+			step = payloadReadStep
+		}
+		start := len(cs.payload)
+		cs.payload = append(cs.payload, make([]byte, step)...)
+		if _, err := io.ReadFull(c.r, cs.payload[start:]); err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+// abort drops the partially received message of a chunk stream
+func (c *chunkReader) abort(csid uint32) {
+	if cs := c.streams[csid]; cs != nil {
+		cs.payload = nil
+	}
+}
+
+// chunkWriter splits messages into chunks, each message starts with a full header
+type chunkWriter struct {
+	w         io.Writer
+	chunkSize uint32
+	buf       []byte
+}
+
+func newChunkWriter(w io.Writer) *chunkWriter {
+	return &chunkWriter{
+		w:         w,
+		chunkSize: defaultChunkSize,
+	}
+}
+
+// writeMessage writes m on chunk stream csid, which must be between 2 and 63
+func (c *chunkWriter) writeMessage(csid uint32, m *message) error {
+	ts := m.timestamp
+	extended := ts >= extendedTimestamp
+	if extended {
+		ts = extendedTimestamp
+	}
+
+	buf := c.buf[:0]
+	buf = append(buf, byte(csid&0x3f))
+	buf = appendUint24(buf, ts)
+	buf = appendUint24(buf, uint32(len(m.payload)))
+	buf = append(buf, m.typeID)
+	buf = append(buf, byte(m.streamID), byte(m.streamID>>8), byte(m.streamID>>16), byte(m.streamID>>24))
+	if extended {
+		buf = appendUint32(buf, m.timestamp)
+	}
+
+	payload := m.payload
+	for {
+		n := uint32(len(payload))
+		if n > c.chunkSize {
+			n = c.chunkSize
+		}
+		buf = append(buf, payload[:n]...)
+		payload = payload[n:]
+		if len(payload) == 0 {
+			break
+		}
+		buf = append(buf, 0xc0|byte(csid&0x3f))
+		if extended {
+			buf = appendUint32(buf, m.timestamp)
+		}
+	}
+	c.buf = buf
+
+	_, err := c.w.Write(buf)
+	return err
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func appendUint24(b []byte, v uint32) []byte {
+	return append(b, byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
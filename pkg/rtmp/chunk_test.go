@@ -0,0 +1,130 @@
+package rtmp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunks(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := newChunkWriter(&buf)
+		messages := []*message{
+			{typeID: msgCommandAMF0, payload: []byte("short")},
+			{typeID: msgVideo, streamID: 1, timestamp: 40, payload: bytes.Repeat([]byte{1, 2, 3}, 200)},
+			{typeID: msgAudio, streamID: 1, timestamp: 0x1000000, payload: bytes.Repeat([]byte{4}, 300)},
+		}
+		for _, m := range messages {
+			require.NoError(t, w.writeMessage(csidStream, m))
+		}
+
+		r := newChunkReader(&buf)
+		for _, expected := range messages {
+			m, err := r.readMessage()
+			require.NoError(t, err)
+			require.Equal(t, expected, m)
+		}
+	})
+
+	t.Run("compressed headers", func(t *testing.T) {
+		r := newChunkReader(bytes.NewReader([]byte{
+			// format 0 on chunk stream 4: timestamp 1000, length 3, video, stream 1
+			0x04, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x03, msgVideo, 1, 0, 0, 0, 'a', 'b', 'c',
+			// format 1: delta 40, length 2, audio
+			0x44, 0x00, 0x00, 0x28, 0x00, 0x00, 0x02, msgAudio, 'd', 'e',
+			// format 2: delta 20
+			0x84, 0x00, 0x00, 0x14, 'f', 'g',
+			// format 3: same delta
+			0xc4, 'h', 'i',
+		}))
+
+		expected := []*message{
+			{typeID: msgVideo, streamID: 1, timestamp: 1000, payload: []byte("abc")},
+			{typeID: msgAudio, streamID: 1, timestamp: 1040, payload: []byte("de")},
+			{typeID: msgAudio, streamID: 1, timestamp: 1060, payload: []byte("fg")},
+			{typeID: msgAudio, streamID: 1, timestamp: 1080, payload: []byte("hi")},
+		}
+		for _, e := range expected {
+			m, err := r.readMessage()
+			require.NoError(t, err)
+			require.Equal(t, e, m)
+		}
+	})
+
+	t.Run("interleaved chunk streams", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Write([]byte{0x06, 0, 0, 0, 0, 0, 130, msgVideo, 1, 0, 0, 0})
+		buf.Write(bytes.Repeat([]byte{'v'}, 128))
+		// a complete message on another chunk stream before the video continues
+		buf.Write([]byte{0x07, 0, 0, 0, 0, 0, 1, msgAudio, 1, 0, 0, 0, 'a'})
+		buf.Write([]byte{0xc6, 'v', 'v'})
+
+		r := newChunkReader(&buf)
+		m, err := r.readMessage()
+		require.NoError(t, err)
+		require.Equal(t, []byte("a"), m.payload)
+		m, err = r.readMessage()
+		require.NoError(t, err)
+		require.Equal(t, bytes.Repeat([]byte{'v'}, 130), m.payload)
+	})
+
+	t.Run("too many chunk streams", func(t *testing.T) {
+		var buf bytes.Buffer
+		for csid := 2; csid < 2+maxChunkStreams+1; csid++ {
+			buf.Write([]byte{byte(csid), 0, 0, 0, 0, 0, 1, msgAudio, 1, 0, 0, 0, 'a'})
+		}
+		r := newChunkReader(&buf)
+		for i := 0; i < maxChunkStreams; i++ {
+			_, err := r.readMessage()
+			require.NoError(t, err)
+		}
+		_, err := r.readMessage()
+		require.ErrorIs(t, err, errTooManyChunkStreams)
+	})
+
+	t.Run("message too long", func(t *testing.T) {
+		r := newChunkReader(bytes.NewReader([]byte{0x04, 0, 0, 0, 0xff, 0xff, 0xff, msgVideo, 1, 0, 0, 0}))
+		_, err := r.readMessage()
+		require.ErrorIs(t, err, errMessageTooLong)
+	})
+
+	t.Run("large message", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := newChunkWriter(&buf)
+		w.chunkSize = maxChunkSize
+		expected := &message{typeID: msgVideo, streamID: 1, payload: bytes.Repeat([]byte{5}, 3*payloadReadStep+10)}
+		require.NoError(t, w.writeMessage(csidStream, expected))
+
+		r := newChunkReader(&buf)
+		require.NoError(t, r.setChunkSize(maxChunkSize))
+		m, err := r.readMessage()
+		require.NoError(t, err)
+		require.Equal(t, expected, m)
+	})
+}
+
+func TestParseVideoTag(t *testing.T) {
+	tag, err := ParseVideoTag([]byte{0x17, AVCPacketNALU, 0xff, 0xff, 0xd8, 0, 0, 0, 1, 0x65})
+	require.NoError(t, err)
+	require.True(t, tag.Keyframe)
+	require.EqualValues(t, VideoCodecAVC, tag.CodecID)
+	require.EqualValues(t, AVCPacketNALU, tag.AVCPacketType)
+	require.EqualValues(t, -40, tag.CompositionTime)
+	require.Equal(t, []byte{0, 0, 0, 1, 0x65}, tag.Data)
+
+	tag, err = ParseVideoTag([]byte{0x27, AVCPacketNALU, 0, 0, 0})
+	require.NoError(t, err)
+	require.False(t, tag.Keyframe)
+
+	// enhanced RTMP, coded frames of HEVC
+	tag, err = ParseVideoTag([]byte{0x91, 'h', 'v', 'c', '1', 0, 0, 0})
+	require.NoError(t, err)
+	require.True(t, tag.Keyframe)
+	require.Zero(t, tag.CodecID)
+	require.Equal(t, "hvc1", tag.FourCC)
+
+	_, err = ParseVideoTag([]byte{0x17, AVCPacketNALU})
+	require.ErrorIs(t, err, ErrTruncatedTag)
+}
@@ -0,0 +1,369 @@
+package rtmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	csidControl = 2
+	csidCommand = 3
+	csidStream  = 5
+
+	// id of the message stream created for the publisher
+	publishStreamID = 1
+
+	serverChunkSize     = 4096
+	serverWindowAckSize = 2500000
+	handshakeTimeout    = 10 * time.Second
+	// publishers send media continuously, connections without traffic are dropped
+	idleTimeout = 30 * time.Second
+
+	userControlStreamBegin = 0
+	peerBandwidthDynamic   = 2
+)
+
+var (
+	ErrPublishRejected   = errors.New("publish rejected")
+	errAlreadyPublishing = errors.New("connection is already publishing")
+)
+
+// Handler decides what happens to the streams clients publish
+type Handler interface {
+	// OnPublish is called when a client starts publishing streamKey in app. Returning an error rejects the stream
+	// and closes the connection
+	OnPublish(conn *Conn, app string, streamKey string) (StreamHandler, error)
+}
+
+// StreamHandler receives the media of a published stream as FLV tag bodies, with timestamps in milliseconds
+type StreamHandler interface {
+	OnVideo(timestamp uint32, data []byte)
+	OnAudio(timestamp uint32, data []byte)
+	// OnMetadata is called with the onMetaData properties encoders send ahead of the media, e.g. videocodecid
+	OnMetadata(metadata map[string]interface{})
+	// OnClose is called once the client stopped publishing or disconnected
+	OnClose()
+}
+
+// Conn is a connection from a publishing client
+type Conn struct {
+	nc      net.Conn
+	handler Handler
+	logger  logger.Logger
+	counter *countingReader
+	reader  *chunkReader
+
+	writeLock sync.Mutex
+	bw        *bufio.Writer
+	writer    *chunkWriter
+
+	app           string
+	stream        StreamHandler
+	ackWindowSize uint32
+	lastAck       uint64
+	closed        atomic.Bool
+}
+
+func newConn(nc net.Conn, handler Handler, l logger.Logger) *Conn {
+	counter := &countingReader{r: bufio.NewReader(nc)}
+	bw := bufio.NewWriter(nc)
+	return &Conn{
+		nc:      nc,
+		handler: handler,
+		logger:  l.WithValues("remote", nc.RemoteAddr().String()),
+		counter: counter,
+		reader:  newChunkReader(counter),
+		bw:      bw,
+		writer:  newChunkWriter(bw),
+	}
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.nc.RemoteAddr()
+}
+
+func (c *Conn) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	return c.nc.Close()
+}
+
+func (c *Conn) serve() {
+	defer func() {
+		if c.stream != nil {
+			c.stream.OnClose()
+			c.stream = nil
+		}
+		_ = c.Close()
+	}()
+
+	_ = c.nc.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := serverHandshake(&struct {
+		io.Reader
+		io.Writer
+	}{c.counter, c.nc}); err != nil {
+		c.logger.Debugw("RTMP handshake failed", "error", err)
+		return
+	}
+	_ = c.nc.SetWriteDeadline(time.Time{})
+
+	for {
+		_ = c.nc.SetReadDeadline(time.Now().Add(idleTimeout))
+		m, err := c.reader.readMessage()
+		if err != nil {
+			if err != io.EOF && !c.closed.Load() {
+				c.logger.Debugw("RTMP connection closed", "error", err)
+			}
+			return
+		}
+		if err := c.handleMessage(m); err != nil {
+			if !errors.Is(err, ErrPublishRejected) {
+				c.logger.Infow("closing RTMP connection", "error", err)
+			}
+			return
+		}
+		if err := c.maybeAcknowledge(); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Conn) handleMessage(m *message) error {
+	switch m.typeID {
+	case msgSetChunkSize:
+		if len(m.payload) < 4 {
+			return errInvalidChunkSize
+		}
+		return c.reader.setChunkSize(binary.BigEndian.Uint32(m.payload) & 0x7fffffff)
+	case msgAbort:
+		if len(m.payload) >= 4 {
+			c.reader.abort(binary.BigEndian.Uint32(m.payload))
+		}
+	case msgWindowAckSize:
+		if len(m.payload) >= 4 {
+			c.ackWindowSize = binary.BigEndian.Uint32(m.payload)
+		}
+	case msgCommandAMF0:
+		return c.handleCommand(m.payload)
+	case msgCommandAMF3:
+		// AMF3 commands start with a format selector, the values are encoded with AMF0
+		if len(m.payload) > 0 {
+			return c.handleCommand(m.payload[1:])
+		}
+	case msgDataAMF0:
+		return c.handleData(m.payload)
+	case msgDataAMF3:
+		if len(m.payload) > 0 {
+			return c.handleData(m.payload[1:])
+		}
+	case msgVideo:
+		if c.stream != nil && len(m.payload) > 0 {
+			c.stream.OnVideo(m.timestamp, m.payload)
+		}
+	case msgAudio:
+		if c.stream != nil && len(m.payload) > 0 {
+			c.stream.OnAudio(m.timestamp, m.payload)
+		}
+	}
+	// acknowledgements, peer bandwidth and user control are not needed to receive a stream
+	return nil
+}
+
+// handleData passes the stream metadata on, sent as "@setDataFrame", "onMetaData", {...} by most encoders and as
+// "onMetaData", {...} by others
+func (c *Conn) handleData(payload []byte) error {
+	if c.stream == nil {
+		return nil
+	}
+	// metadata is optional, values that cannot be decoded are ignored rather than closing the connection
+	values, err := decodeAMF0(payload)
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+	if name, _ := values[0].(string); name == "@setDataFrame" {
+		values = values[1:]
+	}
+	if len(values) < 2 {
+		return nil
+	}
+	if name, _ := values[0].(string); name != "onMetaData" {
+		return nil
+	}
+	if metadata, ok := values[1].(map[string]interface{}); ok {
+		c.stream.OnMetadata(metadata)
+	}
+	return nil
+}
+
+func (c *Conn) handleCommand(payload []byte) error {
+	values, err := decodeAMF0(payload)
+	if err != nil {
+		return err
+	}
+	if len(values) < 2 {
+		return fmt.Errorf("invalid command with %d values", len(values))
+	}
+	name, _ := values[0].(string)
+	tx, _ := values[1].(float64)
+
+	switch name {
+	case "connect":
+		if len(values) > 2 {
+			if obj, ok := values[2].(map[string]interface{}); ok {
+				c.app, _ = obj["app"].(string)
+			}
+		}
+		return c.onConnect(tx)
+
+	case "releaseStream", "FCPublish":
+		return c.writeCommand(0, "_result", tx, nil)
+
+	case "createStream":
+		return c.writeCommand(0, "_result", tx, nil, publishStreamID)
+
+	case "publish":
+		var streamKey string
+		if len(values) > 3 {
+			streamKey, _ = values[3].(string)
+		}
+		return c.onPublish(streamKey)
+
+	case "FCUnpublish", "deleteStream", "closeStream":
+		if c.stream != nil {
+			c.stream.OnClose()
+			c.stream = nil
+		}
+	}
+	return nil
+}
+
+func (c *Conn) onConnect(tx float64) error {
+	if err := c.writeControl(msgWindowAckSize, appendUint32(nil, serverWindowAckSize)); err != nil {
+		return err
+	}
+	if err := c.writeControl(msgSetPeerBandwidth, append(appendUint32(nil, serverWindowAckSize), peerBandwidthDynamic)); err != nil {
+		return err
+	}
+	if err := c.writeControl(msgSetChunkSize, appendUint32(nil, serverChunkSize)); err != nil {
+		return err
+	}
+	c.writeLock.Lock()
+	c.writer.chunkSize = serverChunkSize
+	c.writeLock.Unlock()
+
+	return c.writeCommand(0, "_result", tx,
+		map[string]interface{}{
+			"fmsVer":       "FMS/3,0,1,123",
+			"capabilities": 31,
+		},
+		map[string]interface{}{
+			"level":          "status",
+			"code":           "NetConnection.Connect.Success",
+			"description":    "Connection succeeded.",
+			"objectEncoding": 0,
+		},
+	)
+}
+
+func (c *Conn) onPublish(streamKey string) error {
+	if c.stream != nil {
+		return errAlreadyPublishing
+	}
+
+	stream, err := c.handler.OnPublish(c, c.app, streamKey)
+	if err != nil {
+		c.logger.Infow("RTMP publish rejected", "app", c.app, "error", err)
+		_ = c.writeStatus("error", "NetStream.Publish.BadName", err.Error())
+		return ErrPublishRejected
+	}
+	c.stream = stream
+
+	if err := c.writeControl(msgUserControl, appendUint32([]byte{0, userControlStreamBegin}, publishStreamID)); err != nil {
+		return err
+	}
+	return c.writeStatus("status", "NetStream.Publish.Start", "Publishing started.")
+}
+
+// RequestReconnect asks the client to reconnect, with the reconnect request of enhanced RTMP. Clients that do not
+// support it ignore the request, description tells why
+func (c *Conn) RequestReconnect(description string) error {
+	return c.writeCommand(0, "onStatus", 0, nil, map[string]interface{}{
+		"level":       "status",
+		"code":        "NetConnection.Connect.ReconnectRequest",
+		"description": description,
+	})
+}
+
+// Fail tells the client why its stream is ended with an error status, and closes the connection
+func (c *Conn) Fail(description string) error {
+	err := c.writeStatus("error", "NetStream.Failed", description)
+	_ = c.Close()
+	return err
+}
+
+func (c *Conn) writeStatus(level string, code string, description string) error {
+	return c.writeCommand(publishStreamID, "onStatus", 0, nil, map[string]interface{}{
+		"level":       level,
+		"code":        code,
+		"description": description,
+	})
+}
+
+func (c *Conn) writeCommand(streamID uint32, values ...interface{}) error {
+	payload, err := encodeAMF0(values...)
+	if err != nil {
+		return err
+	}
+	csid := uint32(csidCommand)
+	if streamID != 0 {
+		csid = csidStream
+	}
+	return c.writeMessage(csid, &message{typeID: msgCommandAMF0, streamID: streamID, payload: payload})
+}
+
+func (c *Conn) writeControl(typeID uint8, payload []byte) error {
+	return c.writeMessage(csidControl, &message{typeID: typeID, payload: payload})
+}
+
+func (c *Conn) writeMessage(csid uint32, m *message) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	_ = c.nc.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+	if err := c.writer.writeMessage(csid, m); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
+
+// maybeAcknowledge sends an acknowledgement each time the window announced by the client has been received
+func (c *Conn) maybeAcknowledge() error {
+	if c.ackWindowSize == 0 || c.counter.n-c.lastAck < uint64(c.ackWindowSize) {
+		return nil
+	}
+	c.lastAck = c.counter.n
+	// the sequence number wraps around
+	return c.writeControl(msgAck, appendUint32(nil, uint32(c.counter.n)))
+}
+
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += uint64(n)
+	return n, err
+}
@@ -0,0 +1,277 @@
+package rtmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	t.Run("stream is received", func(t *testing.T) {
+		handler := &testHandler{}
+		c := dialTestServer(t, handler)
+
+		c.connect(t)
+		c.publish(t, "key")
+		require.Equal(t, "NetStream.Publish.Start", c.status(t))
+
+		video := []byte{0x17, AVCPacketNALU, 0, 0, 0, 0, 0, 0, 1, 0x65}
+		require.NoError(t, c.writer.writeMessage(6, &message{typeID: msgVideo, streamID: publishStreamID, timestamp: 33, payload: video}))
+		require.NoError(t, c.writer.writeMessage(4, &message{typeID: msgAudio, streamID: publishStreamID, timestamp: 40, payload: []byte{0xaf, 1, 2}}))
+
+		require.Eventually(t, func() bool {
+			stream := handler.getStream()
+			return stream != nil && stream.count() == 2
+		}, time.Second, 10*time.Millisecond)
+		stream := handler.getStream()
+		require.Equal(t, "live", stream.app)
+		require.Equal(t, "key", stream.streamKey)
+		require.Equal(t, []uint32{33, 40}, stream.timestamps)
+
+		_ = c.conn.Close()
+		require.Eventually(t, stream.isClosed, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		handler := &testHandler{}
+		c := dialTestServer(t, handler)
+
+		c.connect(t)
+		c.publish(t, "key")
+		require.Equal(t, "NetStream.Publish.Start", c.status(t))
+
+		payload, err := encodeAMF0("@setDataFrame", "onMetaData", map[string]interface{}{"videocodecid": 7, "width": 1280})
+		require.NoError(t, err)
+		require.NoError(t, c.writer.writeMessage(4, &message{typeID: msgDataAMF0, streamID: publishStreamID, payload: payload}))
+
+		require.Eventually(t, func() bool {
+			return handler.getStream().getMetadata() != nil
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, map[string]interface{}{"videocodecid": float64(7), "width": float64(1280)}, handler.getStream().getMetadata())
+	})
+
+	t.Run("rejected stream closes the connection", func(t *testing.T) {
+		handler := &testHandler{reject: errors.New("invalid stream key")}
+		c := dialTestServer(t, handler)
+
+		c.connect(t)
+		c.publish(t, "key")
+		require.Equal(t, "NetStream.Publish.BadName", c.status(t))
+
+		_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err := c.reader.readMessage()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("reconnect request", func(t *testing.T) {
+		handler := &testHandler{}
+		c := dialTestServer(t, handler)
+
+		c.connect(t)
+		c.publish(t, "key")
+		require.Equal(t, "NetStream.Publish.Start", c.status(t))
+
+		require.NoError(t, handler.getConn().RequestReconnect("node is draining"))
+		require.Equal(t, "NetConnection.Connect.ReconnectRequest", c.status(t))
+	})
+
+	t.Run("failed stream closes the connection", func(t *testing.T) {
+		handler := &testHandler{}
+		c := dialTestServer(t, handler)
+
+		c.connect(t)
+		c.publish(t, "key")
+		require.Equal(t, "NetStream.Publish.Start", c.status(t))
+
+		require.NoError(t, handler.getConn().Fail("audio is not supported"))
+		require.Equal(t, "NetStream.Failed", c.status(t))
+		_, err := c.reader.readMessage()
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+type testHandler struct {
+	reject error
+
+	lock   sync.Mutex
+	conn   *Conn
+	stream *testStream
+}
+
+func (h *testHandler) OnPublish(conn *Conn, app string, streamKey string) (StreamHandler, error) {
+	if h.reject != nil {
+		return nil, h.reject
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.conn = conn
+	h.stream = &testStream{app: app, streamKey: streamKey}
+	return h.stream, nil
+}
+
+func (h *testHandler) getConn() *Conn {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.conn
+}
+
+func (h *testHandler) getStream() *testStream {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.stream
+}
+
+type testStream struct {
+	app       string
+	streamKey string
+
+	lock       sync.Mutex
+	timestamps []uint32
+	metadata   map[string]interface{}
+	closed     bool
+}
+
+func (s *testStream) OnVideo(timestamp uint32, _ []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.timestamps = append(s.timestamps, timestamp)
+}
+
+func (s *testStream) OnAudio(timestamp uint32, _ []byte) {
+	s.OnVideo(timestamp, nil)
+}
+
+func (s *testStream) OnMetadata(metadata map[string]interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.metadata = metadata
+}
+
+func (s *testStream) OnClose() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+}
+
+func (s *testStream) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.timestamps)
+}
+
+func (s *testStream) getMetadata() map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.metadata
+}
+
+func (s *testStream) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+type testClient struct {
+	conn   net.Conn
+	reader *chunkReader
+	writer *chunkWriter
+	tx     float64
+}
+
+func dialTestServer(t *testing.T, handler Handler) *testClient {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := NewServer(handler, nil)
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	c0c1 := make([]byte, 1+handshakeSize)
+	c0c1[0] = rtmpVersion
+	copy(c0c1[9:], "client random")
+	_, err = conn.Write(c0c1)
+	require.NoError(t, err)
+	s0s1s2 := make([]byte, 1+2*handshakeSize)
+	_, err = io.ReadFull(conn, s0s1s2)
+	require.NoError(t, err)
+	require.EqualValues(t, rtmpVersion, s0s1s2[0])
+	require.Equal(t, c0c1[1:], s0s1s2[1+handshakeSize:])
+	_, err = conn.Write(s0s1s2[1 : 1+handshakeSize])
+	require.NoError(t, err)
+
+	return &testClient{
+		conn:   conn,
+		reader: newChunkReader(conn),
+		writer: newChunkWriter(conn),
+	}
+}
+
+func (c *testClient) command(t *testing.T, streamID uint32, name string, values ...interface{}) {
+	c.tx++
+	payload, err := encodeAMF0(append([]interface{}{name, c.tx}, values...)...)
+	require.NoError(t, err)
+	require.NoError(t, c.writer.writeMessage(csidCommand, &message{typeID: msgCommandAMF0, streamID: streamID, payload: payload}))
+}
+
+// readCommand skips protocol control messages and returns the values of the next command
+func (c *testClient) readCommand(t *testing.T) []interface{} {
+	_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		m, err := c.reader.readMessage()
+		require.NoError(t, err)
+		switch m.typeID {
+		case msgSetChunkSize:
+			require.NoError(t, c.reader.setChunkSize(binary.BigEndian.Uint32(m.payload)))
+		case msgCommandAMF0:
+			values, err := decodeAMF0(m.payload)
+			require.NoError(t, err)
+			return values
+		}
+	}
+}
+
+func (c *testClient) connect(t *testing.T) {
+	c.command(t, 0, "connect", map[string]interface{}{"app": "live", "type": "nonprivate"})
+	res := c.readCommand(t)
+	require.Equal(t, "_result", res[0])
+	require.Equal(t, "NetConnection.Connect.Success", res[3].(map[string]interface{})["code"])
+
+	c.command(t, 0, "releaseStream", nil, "key")
+	c.command(t, 0, "FCPublish", nil, "key")
+	c.command(t, 0, "createStream", nil)
+	for {
+		res = c.readCommand(t)
+		if res[1] == c.tx {
+			break
+		}
+	}
+	require.Equal(t, []interface{}{"_result", c.tx, nil, float64(publishStreamID)}, res)
+}
+
+func (c *testClient) publish(t *testing.T, streamKey string) {
+	payload, err := encodeAMF0("publish", 0, nil, streamKey, "live")
+	require.NoError(t, err)
+	require.NoError(t, c.writer.writeMessage(4, &message{typeID: msgCommandAMF0, streamID: publishStreamID, payload: payload}))
+}
+
+func (c *testClient) status(t *testing.T) string {
+	res := c.readCommand(t)
+	require.Equal(t, "onStatus", res[0])
+	info := res[3].(map[string]interface{})
+	return info["code"].(string)
+}
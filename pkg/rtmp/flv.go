@@ -0,0 +1,86 @@
+package rtmp
+
+import (
+	"errors"
+)
+
+// FLV tag values, Adobe Flash Video File Format Specification E.4.2 and E.4.3
+const (
+	VideoCodecAVC = 7
+
+	AVCPacketSequenceHeader = 0
+	AVCPacketNALU           = 1
+	AVCPacketEndOfSequence  = 2
+
+	SoundFormatMP3   = 2
+	SoundFormatAAC   = 10
+	SoundFormatSpeex = 11
+
+	videoFrameKey = 1
+	// enhanced RTMP signals codecs with a FourCC instead of a codec ID
+	videoExHeader = 0x80
+)
+
+var ErrTruncatedTag = errors.New("truncated FLV tag")
+
+type VideoTag struct {
+	Keyframe bool
+	// 0 when the codec is carried in FourCC
+	CodecID uint8
+	// enhanced RTMP codec, e.g. hvc1 or av01
+	FourCC string
+	// set for AVC
+	AVCPacketType   uint8
+	CompositionTime int32
+	Data            []byte
+}
+
+// ParseVideoTag parses the body of a video message
+func ParseVideoTag(data []byte) (*VideoTag, error) {
+	if len(data) < 1 {
+		return nil, ErrTruncatedTag
+	}
+
+	if data[0]&videoExHeader != 0 {
+		if len(data) < 5 {
+			return nil, ErrTruncatedTag
+		}
+		return &VideoTag{
+			Keyframe: (data[0]>>4)&0x7 == videoFrameKey,
+			FourCC:   string(data[1:5]),
+			Data:     data[5:],
+		}, nil
+	}
+
+	tag := &VideoTag{
+		Keyframe: data[0]>>4 == videoFrameKey,
+		CodecID:  data[0] & 0xf,
+		Data:     data[1:],
+	}
+	if tag.CodecID == VideoCodecAVC {
+		if len(data) < 5 {
+			return nil, ErrTruncatedTag
+		}
+		tag.AVCPacketType = data[1]
+		// signed 24 bit
+		tag.CompositionTime = int32(uint24(data[2:5])<<8) >> 8
+		tag.Data = data[5:]
+	}
+	return tag, nil
+}
+
+type AudioTag struct {
+	SoundFormat uint8
+	Data        []byte
+}
+
+// ParseAudioTag parses the body of an audio message
+func ParseAudioTag(data []byte) (*AudioTag, error) {
+	if len(data) < 1 {
+		return nil, ErrTruncatedTag
+	}
+	return &AudioTag{
+		SoundFormat: data[0] >> 4,
+		Data:        data[1:],
+	}, nil
+}
@@ -0,0 +1,41 @@
+package rtmp
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+const (
+	rtmpVersion   = 3
+	handshakeSize = 1536
+)
+
+var errUnsupportedVersion = errors.New("unsupported RTMP version")
+
+// serverHandshake performs the plain handshake, RTMP specification 5.2. S1 carries random bytes and S2 echoes C1,
+// publishing clients do not require the digest based handshake of Flash Player
+func serverHandshake(rw io.ReadWriter) error {
+	c0c1 := make([]byte, 1+handshakeSize)
+	if _, err := io.ReadFull(rw, c0c1); err != nil {
+		return err
+	}
+	if c0c1[0] != rtmpVersion {
+		return errUnsupportedVersion
+	}
+
+	s0s1s2 := make([]byte, 1+2*handshakeSize)
+	s0s1s2[0] = rtmpVersion
+	// time and zero fields are left at 0
+	if _, err := rand.Read(s0s1s2[9 : 1+handshakeSize]); err != nil {
+		return err
+	}
+	copy(s0s1s2[1+handshakeSize:], c0c1[1:])
+	if _, err := rw.Write(s0s1s2); err != nil {
+		return err
+	}
+
+	c2 := make([]byte, handshakeSize)
+	_, err := io.ReadFull(rw, c2)
+	return err
+}
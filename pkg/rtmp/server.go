@@ -0,0 +1,95 @@
+package rtmp
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/livekit/protocol/logger"
+)
+
+var ErrServerClosed = errors.New("rtmp: server closed")
+
+// Server accepts publishing clients, it does not serve playback
+type Server struct {
+	handler Handler
+	logger  logger.Logger
+
+	lock      sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*Conn]struct{}
+	closed    bool
+}
+
+func NewServer(handler Handler, l logger.Logger) *Server {
+	if l == nil {
+		l = logger.GetLogger()
+	}
+	return &Server{
+		handler:   handler,
+		logger:    l,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[*Conn]struct{}),
+	}
+}
+
+// Serve accepts connections on ln until the server is closed
+func (s *Server) Serve(ln net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[ln] = struct{}{}
+	s.lock.Unlock()
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			delete(s.listeners, ln)
+			s.lock.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		c := newConn(nc, s.handler, s.logger)
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			_ = nc.Close()
+			return ErrServerClosed
+		}
+		s.conns[c] = struct{}{}
+		s.lock.Unlock()
+
+		go func() {
+			c.serve()
+			s.lock.Lock()
+			delete(s.conns, c)
+			s.lock.Unlock()
+		}()
+	}
+}
+
+// Close stops the listeners and disconnects all clients
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	listeners := s.listeners
+	s.listeners = make(map[net.Listener]struct{})
+	conns := s.conns
+	s.conns = make(map[*Conn]struct{})
+	s.lock.Unlock()
+
+	for ln := range listeners {
+		_ = ln.Close()
+	}
+	for c := range conns {
+		_ = c.Close()
+	}
+	return nil
+}
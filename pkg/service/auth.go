@@ -20,6 +20,10 @@ const (
 
 type grantsKey struct{}
 
+type apiKeyKey struct{}
+
+type nodeAdminKey struct{}
+
 var (
 	ErrPermissionDenied          = errors.New("permissions denied")
 	ErrMissingAuthorization      = errors.New("invalid authorization header. Must start with " + bearerPrefix)
@@ -28,13 +32,22 @@ var (
 
 // authentication middleware
 type APIKeyAuthMiddleware struct {
-	provider auth.KeyProvider
+	provider  auth.KeyProvider
+	origins   *OriginChecker
+	adminKeys map[string]bool
 }
 
-func NewAPIKeyAuthMiddleware(provider auth.KeyProvider) *APIKeyAuthMiddleware {
-	return &APIKeyAuthMiddleware{
-		provider: provider,
+// NewAPIKeyAuthMiddleware verifies tokens, those signed with one of adminKeys may also administer the node
+func NewAPIKeyAuthMiddleware(provider auth.KeyProvider, origins *OriginChecker, adminKeys []string) *APIKeyAuthMiddleware {
+	m := &APIKeyAuthMiddleware{
+		provider:  provider,
+		origins:   origins,
+		adminKeys: make(map[string]bool, len(adminKeys)),
+	}
+	for _, key := range adminKeys {
+		m.adminKeys[key] = true
 	}
+	return m
 }
 
 func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
@@ -76,9 +89,18 @@ func (m *APIKeyAuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 			return
 		}
 
-		// set grants in context
-		ctx := r.Context()
-		r = r.WithContext(context.WithValue(ctx, grantsKey{}, grants))
+		if origin := r.Header.Get(originHeader); !m.origins.IsAllowedForToken(v.APIKey(), authToken, origin) {
+			handleError(w, http.StatusForbidden, ErrOriginNotAllowed, "origin", origin, "apiKey", v.APIKey())
+			return
+		}
+
+		// set grants and the key they were signed with in context
+		ctx := context.WithValue(r.Context(), grantsKey{}, grants)
+		ctx = WithAPIKey(ctx, v.APIKey())
+		if m.adminKeys[v.APIKey()] {
+			ctx = WithNodeAdmin(ctx)
+		}
+		r = r.WithContext(ctx)
 	}
 
 	next.ServeHTTP(w, r)
@@ -97,6 +119,21 @@ func WithGrants(ctx context.Context, grants *auth.ClaimGrants) context.Context {
 	return context.WithValue(ctx, grantsKey{}, grants)
 }
 
+// GetAPIKey returns the API key the request's token was signed with
+func GetAPIKey(ctx context.Context) string {
+	apiKey, _ := ctx.Value(apiKeyKey{}).(string)
+	return apiKey
+}
+
+func WithAPIKey(ctx context.Context, apiKey string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, apiKey)
+}
+
+// WithNodeAdmin marks the request as made with an API key allowed to administer the node
+func WithNodeAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nodeAdminKey{}, true)
+}
+
 func SetAuthorizationToken(r *http.Request, token string) {
 	r.Header.Set(authorizationHeader, bearerPrefix+token)
 }
@@ -161,6 +198,18 @@ func EnsureIngressAdminPermission(ctx context.Context) error {
 	return nil
 }
 
+// EnsureNodeAdminPermission is required for node administration such as profiling, drain and config reload.
+// grants are not enough, the token has to be signed with one of admin_api_keys
+func EnsureNodeAdminPermission(ctx context.Context) error {
+	if GetGrants(ctx) == nil {
+		return ErrPermissionDenied
+	}
+	if nodeAdmin, _ := ctx.Value(nodeAdminKey{}).(bool); !nodeAdmin {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
 // wraps authentication errors around Twirp
 func twirpAuthError(err error) error {
 	return twirp.NewError(twirp.Unauthenticated, err.Error())
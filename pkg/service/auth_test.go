@@ -19,7 +19,7 @@ func TestAuthMiddleware(t *testing.T) {
 	provider := &authfakes.FakeKeyProvider{}
 	provider.GetSecretReturns(secret)
 
-	m := service.NewAPIKeyAuthMiddleware(provider)
+	m := service.NewAPIKeyAuthMiddleware(provider, nil, nil)
 	var grants *auth.ClaimGrants
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		grants = service.GetGrants(r.Context())
@@ -58,3 +58,27 @@ func TestAuthMiddleware(t *testing.T) {
 	require.Nil(t, grants)
 	require.Equal(t, http.StatusUnauthorized, w.Code)
 }
+
+func TestAuthMiddlewareAdminKeys(t *testing.T) {
+	secret := "somesecretencodedinbase62"
+	provider := &authfakes.FakeKeyProvider{}
+	provider.GetSecretReturns(secret)
+
+	m := service.NewAPIKeyAuthMiddleware(provider, nil, []string{"APIadmin"})
+	var nodeAdminErr error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nodeAdminErr = service.EnsureNodeAdminPermission(r.Context())
+	})
+
+	for apiKey, expected := range map[string]error{"APIadmin": nil, "APIother": service.ErrPermissionDenied} {
+		token, err := auth.NewAccessToken(apiKey, secret).
+			AddGrant(&auth.VideoGrant{RoomCreate: true, RoomList: true}).
+			ToJWT()
+		require.NoError(t, err)
+
+		r := &http.Request{Header: http.Header{}}
+		service.SetAuthorizationToken(r, token)
+		m.ServeHTTP(httptest.NewRecorder(), r, handler)
+		require.Equal(t, expected, nodeAdminErr, apiKey)
+	}
+}
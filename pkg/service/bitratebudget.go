@@ -0,0 +1,136 @@
+package service
+
+import (
+	"path"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const (
+	defaultBitrateBudgetInterval = 2 * time.Second
+	defaultParticipantPriority   = 1
+)
+
+// budgetShare is what a subscriber takes part in the division of a room bitrate budget with
+type budgetShare struct {
+	priority uint32
+	// downlink estimate of the subscriber, 0 when there is none yet
+	demand int64
+}
+
+// isBitrateBudgetRoom tells whether the subscribers of a room share a bitrate budget
+func isBitrateBudgetRoom(conf *config.RoomConfig, roomName livekit.RoomName) bool {
+	if conf.BitrateBudget.MaxBitrate <= 0 {
+		return false
+	}
+	return len(conf.BitrateBudget.Rooms) == 0 || matchesRoomName(conf.BitrateBudget.Rooms, string(roomName))
+}
+
+// participantPriority returns the priority of the first entry matching the identity of a participant
+func participantPriority(priorities []config.ParticipantPriorityConfig, identity livekit.ParticipantIdentity) uint32 {
+	for _, p := range priorities {
+		for _, pattern := range p.Identities {
+			if matched, _ := path.Match(pattern, string(identity)); matched && p.Priority != 0 {
+				return p.Priority
+			}
+		}
+	}
+	return defaultParticipantPriority
+}
+
+// bitrateBudgetWorker divides the bitrate budget of a room among its subscribers till the room closes
+func (r *RoomManager) bitrateBudgetWorker(room *rtc.Room) {
+	conf := &r.config().Room.BitrateBudget
+	interval := conf.Interval
+	if interval <= 0 {
+		interval = defaultBitrateBudgetInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if room.IsClosed() {
+			return
+		}
+		applyBitrateBudget(conf, room.GetParticipants())
+	}
+}
+
+// applyBitrateBudget caps the downlink of each subscriber to its share of the budget
+func applyBitrateBudget(conf *config.BitrateBudgetConfig, participants []types.LocalParticipant) {
+	subscribers := make([]types.LocalParticipant, 0, len(participants))
+	shares := make([]budgetShare, 0, len(participants))
+	for _, p := range participants {
+		if p.IsDisconnected() {
+			continue
+		}
+		subscribers = append(subscribers, p)
+		shares = append(shares, budgetShare{
+			priority: participantPriority(conf.Priorities, p.Identity()),
+			demand:   p.GetSubscriberChannelCapacity(),
+		})
+	}
+
+	for i, allocation := range divideBitrateBudget(conf.MaxBitrate, shares) {
+		subscribers[i].SetSubscriberMaxChannelCapacity(allocation)
+	}
+}
+
+// divideBitrateBudget divides a budget in proportion to priorities. A subscriber whose proportional share is more
+// than its downlink estimate gets its estimate, the rest is divided among the others. Should the budget be more than
+// all estimates, what is left over is divided as well so that subscribers have room to probe for more
+func divideBitrateBudget(budget int64, shares []budgetShare) []int64 {
+	allocations := make([]int64, len(shares))
+	if len(shares) == 0 {
+		return allocations
+	}
+
+	open := make([]int, 0, len(shares))
+	for i := range shares {
+		open = append(open, i)
+	}
+
+	remaining := budget
+	for len(open) != 0 {
+		totalPriority := int64(0)
+		for _, i := range open {
+			totalPriority += int64(shares[i].priority)
+		}
+
+		next := make([]int, 0, len(open))
+		satisfied := int64(0)
+		for _, i := range open {
+			if demand := shares[i].demand; demand > 0 && demand <= remaining*int64(shares[i].priority)/totalPriority {
+				allocations[i] = demand
+				satisfied += demand
+			} else {
+				next = append(next, i)
+			}
+		}
+		if len(next) == len(open) {
+			// none can be satisfied, proportional shares of what is left
+			for _, i := range open {
+				allocations[i] = remaining * int64(shares[i].priority) / totalPriority
+			}
+			return allocations
+		}
+
+		remaining -= satisfied
+		open = next
+	}
+
+	// every estimate fits in the budget, the leftover is headroom
+	totalPriority := int64(0)
+	for _, share := range shares {
+		totalPriority += int64(share.priority)
+	}
+	for i, share := range shares {
+		allocations[i] += remaining * int64(share.priority) / totalPriority
+	}
+	return allocations
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+)
+
+func TestDivideBitrateBudget(t *testing.T) {
+	t.Run("divided in proportion to priority", func(t *testing.T) {
+		require.Equal(t, []int64{3_000_000, 3_000_000, 3_000_000},
+			divideBitrateBudget(9_000_000, []budgetShare{{priority: 1}, {priority: 1}, {priority: 1}}))
+		require.Equal(t, []int64{6_000_000, 3_000_000},
+			divideBitrateBudget(9_000_000, []budgetShare{{priority: 2}, {priority: 1}}))
+	})
+
+	t.Run("shares beyond an estimate go to the others", func(t *testing.T) {
+		require.Equal(t, []int64{1_000_000, 4_000_000, 4_000_000},
+			divideBitrateBudget(9_000_000, []budgetShare{{priority: 1, demand: 1_000_000}, {priority: 1}, {priority: 1}}))
+		require.Equal(t, []int64{3_000_000, 3_500_000, 3_500_000},
+			divideBitrateBudget(10_000_000, []budgetShare{{priority: 1, demand: 3_000_000}, {priority: 1, demand: 4_000_000}, {priority: 1}}))
+	})
+
+	t.Run("leftover is divided when every estimate fits", func(t *testing.T) {
+		require.Equal(t, []int64{4_500_000, 5_500_000},
+			divideBitrateBudget(10_000_000, []budgetShare{{priority: 1, demand: 1_000_000}, {priority: 1, demand: 2_000_000}}))
+	})
+
+	t.Run("no subscribers", func(t *testing.T) {
+		require.Empty(t, divideBitrateBudget(10_000_000, nil))
+	})
+}
+
+func TestApplyBitrateBudget(t *testing.T) {
+	conf := &config.BitrateBudgetConfig{
+		MaxBitrate: 6_000_000,
+		Priorities: []config.ParticipantPriorityConfig{
+			{Identities: []string{"host-*"}, Priority: 2},
+		},
+	}
+	require.Equal(t, uint32(2), participantPriority(conf.Priorities, "host-1"))
+	require.Equal(t, uint32(1), participantPriority(conf.Priorities, "guest"))
+
+	newParticipant := func(identity livekit.ParticipantIdentity, disconnected bool) *typesfakes.FakeLocalParticipant {
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IdentityReturns(identity)
+		p.IsDisconnectedReturns(disconnected)
+		return p
+	}
+	host := newParticipant("host-1", false)
+	guest := newParticipant("guest", false)
+	gone := newParticipant("gone", true)
+
+	applyBitrateBudget(conf, []types.LocalParticipant{host, guest, gone})
+	require.Equal(t, 1, host.SetSubscriberMaxChannelCapacityCallCount())
+	require.Equal(t, int64(4_000_000), host.SetSubscriberMaxChannelCapacityArgsForCall(0))
+	require.Equal(t, int64(2_000_000), guest.SetSubscriberMaxChannelCapacityArgsForCall(0))
+	require.Zero(t, gone.SetSubscriberMaxChannelCapacityCallCount())
+}
+
+func TestIsBitrateBudgetRoom(t *testing.T) {
+	conf := &config.RoomConfig{}
+	require.False(t, isBitrateBudgetRoom(conf, "room"))
+
+	conf.BitrateBudget.MaxBitrate = 1_000_000
+	require.True(t, isBitrateBudgetRoom(conf, "room"))
+
+	conf.BitrateBudget.Rooms = []string{"webinar-*"}
+	require.False(t, isBitrateBudgetRoom(conf, "room"))
+	require.True(t, isBitrateBudgetRoom(conf, "webinar-1"))
+}
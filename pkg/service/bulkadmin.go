@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	bulkAdminPathPrefix = "/bulk_admin/"
+
+	maxBulkAdminOperations = 10000
+	// operations routed at the same time per request
+	bulkAdminConcurrency = 32
+)
+
+var (
+	ErrNoBulkOperations       = errors.New("no operations requested")
+	ErrTooManyBulkOperations  = fmt.Errorf("at most %d operations can be requested at once", maxBulkAdminOperations)
+	ErrBulkMetadataMissing    = errors.New("metadata or permission must be set")
+	ErrBulkParticipantMissing = errors.New("room and identity must be set")
+)
+
+type BulkParticipant struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+}
+
+type BulkRemoveParticipantsRequest struct {
+	Participants []*BulkParticipant `json:"participants"`
+}
+
+type BulkMuteTrack struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+	Muted    bool   `json:"muted"`
+}
+
+type BulkMuteTracksRequest struct {
+	Tracks []*BulkMuteTrack `json:"tracks"`
+}
+
+type BulkParticipantUpdate struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	Metadata string `json:"metadata,omitempty"`
+	// replaces the permission of the participant when set
+	Permission *livekit.ParticipantPermission `json:"permission,omitempty"`
+}
+
+type BulkRoomUpdate struct {
+	Room     string `json:"room"`
+	Metadata string `json:"metadata"`
+}
+
+type BulkUpdateMetadataRequest struct {
+	Participants []*BulkParticipantUpdate `json:"participants,omitempty"`
+	Rooms        []*BulkRoomUpdate        `json:"rooms,omitempty"`
+}
+
+// BulkOperationResult is the outcome of an operation of a bulk request, in the order it was requested
+type BulkOperationResult struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity,omitempty"`
+	TrackSid string `json:"track_sid,omitempty"`
+	// empty when the operation was routed to the node hosting the room
+	Error string `json:"error,omitempty"`
+}
+
+type BulkOperationsResponse struct {
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []*BulkOperationResult `json:"results"`
+}
+
+type bulkOperation struct {
+	room     livekit.RoomName
+	identity livekit.ParticipantIdentity
+	trackSid string
+	msg      *livekit.RTCNodeMessage
+}
+
+// BulkAdminService applies admin operations to many participants or rooms in one request, e. g. removing or muting
+// everyone flagged by moderation across the rooms of an event. Operations are fanned out to the nodes hosting the
+// rooms through the router, one failing does not fail the others and the outcome of each is reported. Unlike the room
+// service, it does not wait for nodes to confirm. Requests are JSON posted to /bulk_admin/<Method> and each operation
+// requires the roomAdmin grant for its room
+type BulkAdminService struct {
+	roomConf  config.RoomConfig
+	router    routing.MessageRouter
+	roomStore ServiceStore
+}
+
+func NewBulkAdminService(conf *config.Config, router routing.MessageRouter, roomStore ServiceStore) *BulkAdminService {
+	return &BulkAdminService{
+		roomConf:  conf.Room,
+		router:    router,
+		roomStore: roomStore,
+	}
+}
+
+func (s *BulkAdminService) PathPrefix() string {
+	return bulkAdminPathPrefix
+}
+
+func (s *BulkAdminService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		res *BulkOperationsResponse
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, bulkAdminPathPrefix) {
+	case "RemoveParticipants":
+		req := &BulkRemoveParticipantsRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err == nil {
+			res, err = s.RemoveParticipants(r.Context(), req)
+		}
+	case "MuteTracks":
+		req := &BulkMuteTracksRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err == nil {
+			res, err = s.MuteTracks(r.Context(), req)
+		}
+	case "UpdateMetadata":
+		req := &BulkUpdateMetadataRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err == nil {
+			res, err = s.UpdateMetadata(r.Context(), req)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// RemoveParticipants disconnects the participants from their rooms
+func (s *BulkAdminService) RemoveParticipants(ctx context.Context, req *BulkRemoveParticipantsRequest) (*BulkOperationsResponse, error) {
+	ops := make([]*bulkOperation, 0, len(req.Participants))
+	for _, p := range req.Participants {
+		ops = append(ops, &bulkOperation{
+			room:     livekit.RoomName(p.Room),
+			identity: livekit.ParticipantIdentity(p.Identity),
+			msg: &livekit.RTCNodeMessage{
+				Message: &livekit.RTCNodeMessage_RemoveParticipant{
+					RemoveParticipant: &livekit.RoomParticipantIdentity{Room: p.Room, Identity: p.Identity},
+				},
+			},
+		})
+	}
+	return s.execute(ctx, "remove participants", ops)
+}
+
+// MuteTracks mutes or unmutes published tracks
+func (s *BulkAdminService) MuteTracks(ctx context.Context, req *BulkMuteTracksRequest) (*BulkOperationsResponse, error) {
+	ops := make([]*bulkOperation, 0, len(req.Tracks))
+	for _, t := range req.Tracks {
+		ops = append(ops, &bulkOperation{
+			room:     livekit.RoomName(t.Room),
+			identity: livekit.ParticipantIdentity(t.Identity),
+			trackSid: t.TrackSid,
+			msg: &livekit.RTCNodeMessage{
+				Message: &livekit.RTCNodeMessage_MuteTrack{
+					MuteTrack: &livekit.MuteRoomTrackRequest{
+						Room:     t.Room,
+						Identity: t.Identity,
+						TrackSid: t.TrackSid,
+						Muted:    t.Muted,
+					},
+				},
+			},
+		})
+	}
+	return s.execute(ctx, "mute tracks", ops)
+}
+
+// UpdateMetadata updates the metadata of participants and rooms, and the permission of participants
+func (s *BulkAdminService) UpdateMetadata(ctx context.Context, req *BulkUpdateMetadataRequest) (*BulkOperationsResponse, error) {
+	ops := make([]*bulkOperation, 0, len(req.Participants)+len(req.Rooms))
+	for _, p := range req.Participants {
+		ops = append(ops, &bulkOperation{
+			room:     livekit.RoomName(p.Room),
+			identity: livekit.ParticipantIdentity(p.Identity),
+			msg: &livekit.RTCNodeMessage{
+				Message: &livekit.RTCNodeMessage_UpdateParticipant{
+					UpdateParticipant: &livekit.UpdateParticipantRequest{
+						Room:       p.Room,
+						Identity:   p.Identity,
+						Metadata:   p.Metadata,
+						Permission: p.Permission,
+					},
+				},
+			},
+		})
+	}
+	for _, rm := range req.Rooms {
+		ops = append(ops, &bulkOperation{
+			room: livekit.RoomName(rm.Room),
+			msg: &livekit.RTCNodeMessage{
+				Message: &livekit.RTCNodeMessage_UpdateRoomMetadata{
+					UpdateRoomMetadata: &livekit.UpdateRoomMetadataRequest{
+						Room:     rm.Room,
+						Metadata: rm.Metadata,
+					},
+				},
+			},
+		})
+	}
+	return s.execute(ctx, "update metadata", ops)
+}
+
+func (s *BulkAdminService) execute(ctx context.Context, name string, ops []*bulkOperation) (*BulkOperationsResponse, error) {
+	if len(ops) == 0 {
+		return nil, ErrNoBulkOperations
+	}
+	if len(ops) > maxBulkAdminOperations {
+		return nil, ErrTooManyBulkOperations
+	}
+
+	res := &BulkOperationsResponse{
+		Results: make([]*BulkOperationResult, len(ops)),
+	}
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < bulkAdminConcurrency && w < len(ops); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				op := ops[i]
+				result := &BulkOperationResult{
+					Room:     string(op.room),
+					Identity: string(op.identity),
+					TrackSid: op.trackSid,
+				}
+				if err := s.route(ctx, op); err != nil {
+					result.Error = err.Error()
+				}
+				res.Results[i] = result
+			}
+		}()
+	}
+	for i := range ops {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, result := range res.Results {
+		if result.Error == "" {
+			res.Succeeded++
+		} else {
+			res.Failed++
+		}
+	}
+	logger.Infow("bulk admin operations routed", "operation", name, "succeeded", res.Succeeded, "failed", res.Failed)
+	return res, nil
+}
+
+// route checks an operation and writes it to the node hosting its room
+func (s *BulkAdminService) route(ctx context.Context, op *bulkOperation) error {
+	if op.room == "" {
+		return ErrBulkParticipantMissing
+	}
+	if err := EnsureAdminPermission(ctx, op.room); err != nil {
+		return ErrPermissionDenied
+	}
+
+	switch m := op.msg.Message.(type) {
+	case *livekit.RTCNodeMessage_UpdateParticipant:
+		if m.UpdateParticipant.Metadata == "" && m.UpdateParticipant.Permission == nil {
+			return ErrBulkMetadataMissing
+		}
+		if err := s.checkMetadataSize(m.UpdateParticipant.Metadata); err != nil {
+			return err
+		}
+	case *livekit.RTCNodeMessage_UpdateRoomMetadata:
+		if err := s.checkMetadataSize(m.UpdateRoomMetadata.Metadata); err != nil {
+			return err
+		}
+	}
+
+	if op.identity == "" {
+		if _, ok := op.msg.Message.(*livekit.RTCNodeMessage_UpdateRoomMetadata); !ok {
+			return ErrBulkParticipantMissing
+		}
+		// only rooms hosted by a node can be updated through it
+		if _, _, err := s.roomStore.LoadRoom(ctx, op.room, false); err != nil {
+			return err
+		}
+		return s.router.WriteRoomRTC(ctx, op.room, op.msg)
+	}
+
+	if _, err := s.roomStore.LoadParticipant(ctx, op.room, op.identity); err != nil {
+		return err
+	}
+	return s.router.WriteParticipantRTC(ctx, op.room, op.identity, op.msg)
+}
+
+func (s *BulkAdminService) checkMetadataSize(metadata string) error {
+	if maxMetadataSize := int(s.roomConf.MaxMetadataSize); maxMetadataSize > 0 && len(metadata) > maxMetadataSize {
+		return ErrMetadataExceedsLimits
+	}
+	return nil
+}
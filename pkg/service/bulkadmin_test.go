@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestBulkAdminService(t *testing.T) {
+	router := &routingfakes.FakeRouter{}
+	store := &servicefakes.FakeServiceStore{}
+	store.LoadParticipantCalls(func(_ context.Context, _ livekit.RoomName, identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+		if identity == "gone" {
+			return nil, service.ErrParticipantNotFound
+		}
+		return &livekit.ParticipantInfo{Identity: string(identity)}, nil
+	})
+	svc := service.NewBulkAdminService(&config.Config{Room: config.RoomConfig{MaxMetadataSize: 5}}, router, store)
+
+	request := func(method string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+method, strings.NewReader(body))
+		r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "room"},
+		}))
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("requires operations", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, request("RemoveParticipants", `{"participants": []}`).Code)
+	})
+
+	t.Run("reports partial failures", func(t *testing.T) {
+		w := request("RemoveParticipants", `{"participants": [
+			{"room": "room", "identity": "alice"},
+			{"room": "room", "identity": "gone"},
+			{"room": "other", "identity": "bob"},
+			{"room": "room", "identity": "carol"}
+		]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		res := &service.BulkOperationsResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.Equal(t, 2, res.Succeeded)
+		require.Equal(t, 2, res.Failed)
+		require.Len(t, res.Results, 4)
+		require.Empty(t, res.Results[0].Error)
+		require.Equal(t, service.ErrParticipantNotFound.Error(), res.Results[1].Error)
+		require.Equal(t, service.ErrPermissionDenied.Error(), res.Results[2].Error)
+		require.Equal(t, "carol", res.Results[3].Identity)
+		require.Empty(t, res.Results[3].Error)
+
+		require.Equal(t, 2, router.WriteParticipantRTCCallCount())
+	})
+
+	t.Run("checks metadata", func(t *testing.T) {
+		res, err := svc.UpdateMetadata(service.WithGrants(context.Background(), &auth.ClaimGrants{
+			Video: &auth.VideoGrant{RoomAdmin: true, Room: "room"},
+		}), &service.BulkUpdateMetadataRequest{
+			Participants: []*service.BulkParticipantUpdate{
+				{Room: "room", Identity: "alice", Metadata: "too long"},
+				{Room: "room", Identity: "bob"},
+			},
+			Rooms: []*service.BulkRoomUpdate{
+				{Room: "room", Metadata: "m"},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, res.Succeeded)
+		require.Equal(t, service.ErrMetadataExceedsLimits.Error(), res.Results[0].Error)
+		require.Equal(t, service.ErrBulkMetadataMissing.Error(), res.Results[1].Error)
+		require.Equal(t, 1, router.WriteRoomRTCCallCount())
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	capacityPath = "/capacity"
+
+	defaultTargetUtilization = 0.7
+
+	CapacityResourceCPU          = "cpu"
+	CapacityResourceBandwidth    = "bandwidth"
+	CapacityResourceTracks       = "tracks"
+	CapacityResourceParticipants = "participants"
+)
+
+type ResourceUsage struct {
+	Used  float64 `json:"used"`
+	Limit float64 `json:"limit"`
+	// Used over Limit, in percent
+	LoadPercent float64 `json:"load_percent"`
+}
+
+// NodeCapacity describes how loaded a node is, meant to be consumed by autoscalers
+type NodeCapacity struct {
+	NodeID   string `json:"node_id"`
+	State    string `json:"state"`
+	Draining bool   `json:"draining"`
+
+	// load of the most constrained resource, in percent
+	LoadPercent              float64 `json:"load_percent"`
+	TargetUtilizationPercent float64 `json:"target_utilization_percent"`
+	// LoadPercent over TargetUtilizationPercent, desired replicas = ceil(current replicas * average ratio)
+	UtilizationRatio float64                  `json:"utilization_ratio"`
+	LimitingResource string                   `json:"limiting_resource"`
+	Resources        map[string]ResourceUsage `json:"resources"`
+
+	// participants and bandwidth the node can take before reaching the target utilization
+	Participants          int32   `json:"participants"`
+	ParticipantHeadroom   int32   `json:"participant_headroom"`
+	BandwidthHeadroomBps  float64 `json:"bandwidth_headroom_bytes_per_sec,omitempty"`
+	StatsUpdatedAtSeconds int64   `json:"stats_updated_at"`
+}
+
+// GetNodeCapacity computes the capacity of a node from its stats and the configured limits
+func GetNodeCapacity(conf *config.Config, node *livekit.Node) *NodeCapacity {
+	target := float64(conf.Autoscaling.TargetUtilization)
+	if target <= 0 || target > 1 {
+		target = defaultTargetUtilization
+	}
+
+	c := &NodeCapacity{
+		NodeID:                   node.Id,
+		State:                    node.State.String(),
+		Draining:                 node.State == livekit.NodeState_SHUTTING_DOWN,
+		TargetUtilizationPercent: target * 100,
+		Resources:                make(map[string]ResourceUsage),
+	}
+	stats := node.Stats
+	if stats == nil {
+		stats = &livekit.NodeStats{}
+	}
+	c.Participants = stats.NumClients
+	c.StatsUpdatedAtSeconds = stats.UpdatedAt
+
+	addResource := func(name string, used, limit float64) {
+		if limit <= 0 {
+			return
+		}
+		usage := ResourceUsage{Used: used, Limit: limit, LoadPercent: used / limit * 100}
+		c.Resources[name] = usage
+		if usage.LoadPercent >= c.LoadPercent {
+			c.LoadPercent = usage.LoadPercent
+			c.LimitingResource = name
+		}
+	}
+	addResource(CapacityResourceCPU, float64(stats.CpuLoad), 1)
+	bandwidth := float64(stats.BytesInPerSec + stats.BytesOutPerSec)
+	addResource(CapacityResourceBandwidth, bandwidth, float64(conf.Limit.BytesPerSec))
+	addResource(CapacityResourceTracks, float64(stats.NumTracksIn+stats.NumTracksOut), float64(conf.Limit.NumTracks))
+	addResource(CapacityResourceParticipants, float64(stats.NumClients), float64(conf.Autoscaling.MaxParticipants))
+
+	c.UtilizationRatio = c.LoadPercent / c.TargetUtilizationPercent
+
+	// headroom is scaled down when another resource is more constrained than participants
+	var maxParticipants float64
+	switch {
+	case conf.Autoscaling.MaxParticipants > 0 && c.LoadPercent > 0:
+		maxParticipants = math.Min(float64(conf.Autoscaling.MaxParticipants), float64(stats.NumClients)*100/c.LoadPercent)
+	case conf.Autoscaling.MaxParticipants > 0:
+		maxParticipants = float64(conf.Autoscaling.MaxParticipants)
+	case c.LoadPercent > 0:
+		maxParticipants = float64(stats.NumClients) * 100 / c.LoadPercent
+	}
+	if headroom := math.Floor(maxParticipants*target) - float64(stats.NumClients); headroom > 0 && !c.Draining {
+		c.ParticipantHeadroom = int32(headroom)
+	}
+	if conf.Limit.BytesPerSec > 0 && !c.Draining {
+		c.BandwidthHeadroomBps = math.Max(float64(conf.Limit.BytesPerSec)*target-bandwidth, 0)
+	}
+	return c
+}
+
+func (s *LivekitServer) capacity(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(GetNodeCapacity(s.current.Get(), s.Node()))
+}
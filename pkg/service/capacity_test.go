@@ -0,0 +1,66 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestGetNodeCapacity(t *testing.T) {
+	conf := &config.Config{
+		Limit: config.LimitConfig{BytesPerSec: 1000},
+	}
+	node := &livekit.Node{
+		Id:    "ND_1",
+		State: livekit.NodeState_SERVING,
+		Stats: &livekit.NodeStats{
+			NumClients:     10,
+			CpuLoad:        0.2,
+			BytesInPerSec:  300,
+			BytesOutPerSec: 100,
+		},
+	}
+
+	t.Run("most constrained resource", func(t *testing.T) {
+		c := service.GetNodeCapacity(conf, node)
+		require.Equal(t, service.CapacityResourceBandwidth, c.LimitingResource)
+		require.InDelta(t, 40, c.LoadPercent, 0.01)
+		require.InDelta(t, 70, c.TargetUtilizationPercent, 0.01)
+		require.InDelta(t, 40.0/70, c.UtilizationRatio, 0.01)
+		require.Len(t, c.Resources, 2)
+		// 10 participants use 40%, 17 fit under 70%
+		require.Equal(t, int32(7), c.ParticipantHeadroom)
+		require.InDelta(t, 300, c.BandwidthHeadroomBps, 0.01)
+		require.False(t, c.Draining)
+	})
+
+	t.Run("participant limit and target", func(t *testing.T) {
+		conf := &config.Config{
+			Autoscaling: config.AutoscalingConfig{TargetUtilization: 0.5, MaxParticipants: 20},
+		}
+		c := service.GetNodeCapacity(conf, node)
+		require.Equal(t, service.CapacityResourceParticipants, c.LimitingResource)
+		require.InDelta(t, 50, c.LoadPercent, 0.01)
+		require.InDelta(t, 1, c.UtilizationRatio, 0.01)
+		require.Zero(t, c.ParticipantHeadroom)
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		draining := &livekit.Node{Id: "ND_1", State: livekit.NodeState_SHUTTING_DOWN, Stats: node.Stats}
+		c := service.GetNodeCapacity(conf, draining)
+		require.True(t, c.Draining)
+		require.Zero(t, c.ParticipantHeadroom)
+		require.Zero(t, c.BandwidthHeadroomBps)
+	})
+
+	t.Run("no stats", func(t *testing.T) {
+		c := service.GetNodeCapacity(&config.Config{}, &livekit.Node{Id: "ND_1"})
+		require.Zero(t, c.LoadPercent)
+		require.Equal(t, service.CapacityResourceCPU, c.LimitingResource)
+	})
+}
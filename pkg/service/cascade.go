@@ -0,0 +1,831 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/relay"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	cascadeConnectInterval = time.Second
+	cascadeConnectTimeout  = 10 * time.Second
+)
+
+// cascadeRoom is a room hosted by this node and others, media published to one of them is relayed to the others.
+// The node the room was placed on is its origin, the others are edges connected to the origin. The origin relays
+// between edges
+type cascadeRoom struct {
+	room *rtc.Room
+	// empty on the origin
+	originNodeID livekit.NodeID
+	// nodes the room is shared with, the origin on edges
+	peers map[livekit.NodeID]*relay.Conn
+	// last update of each participant relayed from a node, kept for nodes joining later
+	peerParticipants map[livekit.NodeID]map[livekit.ParticipantIdentity]*relay.Message
+	// tracks relayed to this node, by the node they come from
+	inbound map[livekit.TrackID]*cascadeInboundTrack
+	// metadata last relayed to edges, on the origin
+	metadata string
+}
+
+type cascadeInboundTrack struct {
+	nodeID   livekit.NodeID
+	identity livekit.ParticipantIdentity
+	track    *rtc.RelayedTrack
+}
+
+type cascadeOutboundKey struct {
+	trackID livekit.TrackID
+	nodeID  livekit.NodeID
+}
+
+type cascadeOutboundTrack struct {
+	roomName livekit.RoomName
+	receiver sfu.TrackReceiver
+	sender   *sfu.RelaySender
+}
+
+// CascadeManager spreads rooms over nodes once the node a room was placed on is full, relaying their media between
+// the nodes hosting them
+type CascadeManager struct {
+	config      *config.Config
+	currentNode routing.LocalNode
+	router      routing.Router
+	transport   *relay.Transport
+
+	lock     sync.RWMutex
+	rooms    map[livekit.RoomName]*cascadeRoom
+	inbound  map[livekit.TrackID]*rtc.RelayedTrack
+	outbound map[cascadeOutboundKey]*cascadeOutboundTrack
+}
+
+func NewCascadeManager(conf *config.Config, currentNode routing.LocalNode, router routing.Router) (*CascadeManager, error) {
+	m := &CascadeManager{
+		config:      conf,
+		currentNode: currentNode,
+		router:      router,
+		rooms:       make(map[livekit.RoomName]*cascadeRoom),
+		inbound:     make(map[livekit.TrackID]*rtc.RelayedTrack),
+		outbound:    make(map[cascadeOutboundKey]*cascadeOutboundTrack),
+	}
+
+	transport, err := relay.NewTransport(relay.TransportParams{
+		NodeID:              livekit.NodeID(currentNode.Id),
+		Port:                conf.MediaRelay.Port,
+		PreSharedKey:        conf.MediaRelay.PreSharedKey,
+		KeyRotationInterval: conf.MediaRelay.KeyRotationInterval,
+		Handler:             m,
+		Logger:              logger.GetLogger(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.transport = transport
+	return m, nil
+}
+
+func (m *CascadeManager) Start() error {
+	return m.transport.Start()
+}
+
+func (m *CascadeManager) Stop() {
+	m.transport.Close()
+}
+
+// AddRoom starts relaying a room created on this node. When the room was placed on another node, this node becomes
+// one of its edges and connects to the origin
+func (m *CascadeManager) AddRoom(ctx context.Context, room *rtc.Room) error {
+	origin, err := m.router.GetNodeForRoom(ctx, room.Name())
+	if err != nil {
+		return err
+	}
+
+	cr := &cascadeRoom{
+		room:             room,
+		peers:            make(map[livekit.NodeID]*relay.Conn),
+		peerParticipants: make(map[livekit.NodeID]map[livekit.ParticipantIdentity]*relay.Message),
+		inbound:          make(map[livekit.TrackID]*cascadeInboundTrack),
+	}
+	if origin.Id != m.currentNode.Id {
+		cr.originNodeID = livekit.NodeID(origin.Id)
+		room.SetRelayEdge()
+	} else {
+		cr.metadata = room.ToProto().Metadata
+	}
+	room.OnRelayData(func(dp *livekit.DataPacket) {
+		m.relayData(cr, dp)
+	})
+	room.OnRelaySpeakers(func(speakers []*livekit.SpeakerInfo) {
+		m.relaySpeakers(cr, speakers)
+	})
+
+	m.lock.Lock()
+	m.rooms[room.Name()] = cr
+	m.lock.Unlock()
+
+	if cr.originNodeID != "" {
+		room.Logger.Infow("hosting room relayed from origin node", "originNode", origin.Id)
+		addr := net.JoinHostPort(origin.Ip, strconv.Itoa(int(m.config.MediaRelay.Port)))
+		room.Resources().Go(func() {
+			m.edgeWorker(cr, addr)
+		})
+	}
+	return nil
+}
+
+// IsEdgeRoom tells whether a room hosted by this node was placed on another node
+func (m *CascadeManager) IsEdgeRoom(roomName livekit.RoomName) bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	cr := m.rooms[roomName]
+	return cr != nil && cr.originNodeID != ""
+}
+
+// RemoveRoom stops relaying a room that closed, leaving it on the nodes it is shared with. Edges close the room when
+// it closes on the origin
+func (m *CascadeManager) RemoveRoom(roomName livekit.RoomName) {
+	m.lock.Lock()
+	cr := m.rooms[roomName]
+	delete(m.rooms, roomName)
+	var peers []*relay.Conn
+	if cr != nil {
+		for _, conn := range cr.peers {
+			peers = append(peers, conn)
+		}
+		for trackID := range cr.inbound {
+			delete(m.inbound, trackID)
+		}
+	}
+	m.lock.Unlock()
+	if cr == nil {
+		return
+	}
+
+	msgType := relay.MessageLeaveRoom
+	if cr.originNodeID == "" {
+		msgType = relay.MessageCloseRoom
+	}
+	for _, conn := range peers {
+		m.closeOutbound(roomName, conn.RemoteNodeID())
+		_ = conn.SendMessage(&relay.Message{Type: msgType, Room: roomName})
+		m.closeIfUnused(conn)
+	}
+	if cr.originNodeID != "" {
+		if err := m.router.RemoveEdgeNodeForRoom(context.Background(), roomName, livekit.NodeID(m.currentNode.Id)); err != nil {
+			cr.room.Logger.Warnw("could not remove edge node for room", err)
+		}
+	}
+}
+
+// ParticipantChanged relays a change of a participant connected to this node to the nodes the room is shared with
+func (m *CascadeManager) ParticipantChanged(roomName livekit.RoomName, p types.LocalParticipant) {
+	msg, err := m.participantUpdate(roomName, p)
+	if err != nil {
+		logger.Warnw("could not relay participant update", err, "room", roomName, "participant", p.Identity())
+		return
+	}
+
+	m.lock.RLock()
+	var peers []*relay.Conn
+	if cr := m.rooms[roomName]; cr != nil {
+		for _, conn := range cr.peers {
+			peers = append(peers, conn)
+		}
+	}
+	m.lock.RUnlock()
+
+	for _, conn := range peers {
+		_ = conn.SendMessage(msg)
+	}
+}
+
+func (m *CascadeManager) participantUpdate(roomName livekit.RoomName, p types.LocalParticipant) (*relay.Message, error) {
+	msg := &relay.Message{
+		Type:             relay.MessageParticipantUpdate,
+		Room:             roomName,
+		Codecs:           make(map[livekit.TrackID]webrtc.RTPCodecParameters),
+		HeaderExtensions: make(map[livekit.TrackID][]webrtc.RTPHeaderExtensionParameter),
+	}
+	if err := msg.SetParticipant(p.ToProto()); err != nil {
+		return nil, err
+	}
+	for _, track := range p.GetPublishedTracks() {
+		if receiver, codec, ok := rtc.RelaySource(track); ok {
+			msg.Codecs[track.ID()] = codec
+			if exts := rtc.RelayHeaderExtensions(receiver); len(exts) != 0 {
+				msg.HeaderExtensions[track.ID()] = exts
+			}
+		}
+	}
+	return msg, nil
+}
+
+// RoomUpdated relays a change of the metadata of a room to its edges, the room is changed on the origin
+func (m *CascadeManager) RoomUpdated(roomName livekit.RoomName, room *livekit.Room) {
+	m.lock.Lock()
+	cr := m.rooms[roomName]
+	if cr == nil || cr.originNodeID != "" || cr.metadata == room.Metadata {
+		m.lock.Unlock()
+		return
+	}
+	cr.metadata = room.Metadata
+	peers := m.peersLocked(cr, "")
+	m.lock.Unlock()
+
+	msg := &relay.Message{Type: relay.MessageRoomUpdate, Room: roomName}
+	if err := msg.SetRoomInfo(room); err != nil {
+		cr.room.Logger.Warnw("could not relay room update", err)
+		return
+	}
+	for _, conn := range peers {
+		_ = conn.SendMessage(msg)
+	}
+}
+
+// relayData relays a data packet sent to a room on this node to the nodes the room is shared with
+func (m *CascadeManager) relayData(cr *cascadeRoom, dp *livekit.DataPacket) {
+	msg := &relay.Message{Type: relay.MessageData, Room: cr.room.Name()}
+	if err := msg.SetDataPacket(dp); err != nil {
+		cr.room.Logger.Warnw("could not relay data packet", err)
+		return
+	}
+
+	m.lock.RLock()
+	peers := m.peersLocked(cr, "")
+	m.lock.RUnlock()
+	for _, conn := range peers {
+		_ = conn.SendMessage(msg)
+	}
+}
+
+// relaySpeakers relays changes of active speakers connected to this node to the nodes the room is shared with
+func (m *CascadeManager) relaySpeakers(cr *cascadeRoom, speakers []*livekit.SpeakerInfo) {
+	msg := &relay.Message{Type: relay.MessageSpeakers, Room: cr.room.Name()}
+	if err := msg.SetSpeakers(speakers); err != nil {
+		cr.room.Logger.Warnw("could not relay speakers", err)
+		return
+	}
+
+	m.lock.RLock()
+	peers := m.peersLocked(cr, "")
+	m.lock.RUnlock()
+	for _, conn := range peers {
+		_ = conn.SendMessage(msg)
+	}
+}
+
+// peersLocked returns the connections to the nodes a room is shared with, but the one of a node to skip
+func (m *CascadeManager) peersLocked(cr *cascadeRoom, skip livekit.NodeID) []*relay.Conn {
+	peers := make([]*relay.Conn, 0, len(cr.peers))
+	for nodeID, conn := range cr.peers {
+		if nodeID != skip {
+			peers = append(peers, conn)
+		}
+	}
+	return peers
+}
+
+// passOn has the origin pass a message of an edge on to the other edges
+func (m *CascadeManager) passOn(cr *cascadeRoom, nodeID livekit.NodeID, msg *relay.Message) {
+	if cr.originNodeID != "" {
+		return
+	}
+
+	m.lock.RLock()
+	peers := m.peersLocked(cr, nodeID)
+	m.lock.RUnlock()
+	for _, conn := range peers {
+		_ = conn.SendMessage(msg)
+	}
+}
+
+// edgeWorker keeps an edge connected to the origin of the room while the room is open
+func (m *CascadeManager) edgeWorker(cr *cascadeRoom, addr string) {
+	ticker := time.NewTicker(cascadeConnectInterval)
+	defer ticker.Stop()
+
+	for !cr.room.IsClosed() {
+		m.lock.RLock()
+		conn := cr.peers[cr.originNodeID]
+		m.lock.RUnlock()
+
+		if conn == nil || conn.IsClosed() {
+			if err := m.joinOrigin(cr, addr); err != nil {
+				cr.room.Logger.Warnw("could not connect to origin node", err, "originNode", cr.originNodeID, "addr", addr)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+func (m *CascadeManager) joinOrigin(cr *cascadeRoom, addr string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cascadeConnectTimeout)
+	defer cancel()
+
+	conn, err := m.connect(ctx, cr.originNodeID, addr)
+	if err != nil {
+		return err
+	}
+
+	roomName := cr.room.Name()
+	m.lock.Lock()
+	if m.rooms[roomName] != cr {
+		m.lock.Unlock()
+		return nil
+	}
+	cr.peers[cr.originNodeID] = conn
+	m.lock.Unlock()
+
+	if err := conn.SendMessage(&relay.Message{Type: relay.MessageJoinRoom, Room: roomName}); err != nil {
+		return err
+	}
+	for _, p := range cr.room.GetParticipants() {
+		if msg, err := m.participantUpdate(roomName, p); err == nil {
+			_ = conn.SendMessage(msg)
+		}
+	}
+	return nil
+}
+
+// connect returns the connection to a node, rooms shared with the node share it
+func (m *CascadeManager) connect(ctx context.Context, nodeID livekit.NodeID, addr string) (*relay.Conn, error) {
+	m.lock.RLock()
+	for _, cr := range m.rooms {
+		if conn := cr.peers[nodeID]; conn != nil && !conn.IsClosed() {
+			m.lock.RUnlock()
+			return conn, nil
+		}
+	}
+	m.lock.RUnlock()
+
+	return m.transport.Connect(ctx, nodeID, addr)
+}
+
+// closeIfUnused closes a connection no room shares with its node any longer
+func (m *CascadeManager) closeIfUnused(conn *relay.Conn) {
+	m.lock.RLock()
+	for _, cr := range m.rooms {
+		if cr.peers[conn.RemoteNodeID()] == conn {
+			m.lock.RUnlock()
+			return
+		}
+	}
+	m.lock.RUnlock()
+
+	conn.Close()
+}
+
+// relay.ConnHandler
+func (m *CascadeManager) HandleMessage(c *relay.Conn, msg *relay.Message) {
+	nodeID := c.RemoteNodeID()
+
+	m.lock.Lock()
+	cr := m.rooms[msg.Room]
+	if cr == nil {
+		m.lock.Unlock()
+		if msg.Type != relay.MessageLeaveRoom && msg.Type != relay.MessageCloseRoom {
+			// the room closed here, or never was hosted here
+			_ = c.SendMessage(&relay.Message{Type: relay.MessageLeaveRoom, Room: msg.Room})
+		}
+		return
+	}
+	if msg.Type == relay.MessageJoinRoom {
+		if cr.originNodeID != "" {
+			m.lock.Unlock()
+			_ = c.SendMessage(&relay.Message{Type: relay.MessageLeaveRoom, Room: msg.Room})
+			return
+		}
+		cr.peers[nodeID] = c
+	} else if cr.peers[nodeID] != c {
+		m.lock.Unlock()
+		return
+	}
+	m.lock.Unlock()
+
+	switch msg.Type {
+	case relay.MessageJoinRoom:
+		m.handleJoinRoom(cr, c)
+	case relay.MessageLeaveRoom:
+		m.handleLeaveRoom(cr, nodeID)
+	case relay.MessageParticipantUpdate:
+		m.handleParticipantUpdate(cr, nodeID, msg)
+	case relay.MessageSubscribe:
+		m.handleSubscribe(cr, c, msg.TrackID)
+	case relay.MessageUnsubscribe:
+		m.closeOutboundTrack(cascadeOutboundKey{trackID: msg.TrackID, nodeID: nodeID})
+	case relay.MessagePLI:
+		if receiver := m.sourceReceiver(cr, msg.TrackID); receiver != nil {
+			receiver.SendPLI(msg.Layer, true)
+		}
+	case relay.MessageSenderReport:
+		if msg.SenderReport == nil {
+			return
+		}
+		m.lock.RLock()
+		track := m.inbound[msg.TrackID]
+		m.lock.RUnlock()
+		if track != nil {
+			track.RelayReceiver().SetSenderReport(msg.Layer, msg.SenderReport.RTPTimestamp, msg.SenderReport.NTPTimestamp)
+		}
+	case relay.MessageData:
+		dp, err := msg.DataPacket()
+		if err != nil {
+			cr.room.Logger.Warnw("could not decode relayed data packet", err, "node", nodeID)
+			return
+		}
+		m.passOn(cr, nodeID, msg)
+		cr.room.SendRelayedDataPacket(dp)
+	case relay.MessageSpeakers:
+		speakers, err := msg.SpeakerInfos()
+		if err != nil {
+			cr.room.Logger.Warnw("could not decode relayed speakers", err, "node", nodeID)
+			return
+		}
+		m.passOn(cr, nodeID, msg)
+		cr.room.UpdateRelayedSpeakers(speakers)
+	case relay.MessageRoomUpdate:
+		if nodeID != cr.originNodeID {
+			return
+		}
+		room, err := msg.RoomProto()
+		if err != nil {
+			cr.room.Logger.Warnw("could not decode relayed room", err, "node", nodeID)
+			return
+		}
+		if room.Metadata != cr.room.ToProto().Metadata {
+			cr.room.SetMetadata(room.Metadata)
+		}
+	case relay.MessageCloseRoom:
+		if nodeID != cr.originNodeID {
+			return
+		}
+		cr.room.Logger.Infow("room closed on origin node, closing")
+		cr.room.Close()
+	}
+}
+
+// relay.ConnHandler
+func (m *CascadeManager) HandleMedia(_ *relay.Conn, pkt *relay.MediaPacket) {
+	m.lock.RLock()
+	track := m.inbound[pkt.TrackID]
+	m.lock.RUnlock()
+	if track == nil {
+		return
+	}
+
+	_ = track.RelayReceiver().WritePacket(pkt.Layer, pkt.Temporal, pkt.KeyFrame, pkt.Packet)
+}
+
+// relay.ConnHandler
+func (m *CascadeManager) HandleClose(c *relay.Conn) {
+	nodeID := c.RemoteNodeID()
+
+	m.lock.RLock()
+	var rooms []*cascadeRoom
+	for _, cr := range m.rooms {
+		if cr.peers[nodeID] == c {
+			rooms = append(rooms, cr)
+		}
+	}
+	m.lock.RUnlock()
+
+	for _, cr := range rooms {
+		cr.room.Logger.Infow("relay to node closed", "node", nodeID)
+		m.handleLeaveRoom(cr, nodeID)
+	}
+}
+
+// an edge joined a room hosted here, it is sent the participants of the room
+func (m *CascadeManager) handleJoinRoom(cr *cascadeRoom, c *relay.Conn) {
+	nodeID := c.RemoteNodeID()
+	roomName := cr.room.Name()
+	cr.room.Logger.Infow("edge node joined room", "node", nodeID)
+
+	roomUpdate := &relay.Message{Type: relay.MessageRoomUpdate, Room: roomName}
+	if err := roomUpdate.SetRoomInfo(cr.room.ToProto()); err == nil {
+		_ = c.SendMessage(roomUpdate)
+	}
+	for _, p := range cr.room.GetParticipants() {
+		if msg, err := m.participantUpdate(roomName, p); err == nil {
+			_ = c.SendMessage(msg)
+		}
+	}
+
+	m.lock.RLock()
+	var updates []*relay.Message
+	for peerID, participants := range cr.peerParticipants {
+		if peerID == nodeID {
+			continue
+		}
+		for _, msg := range participants {
+			updates = append(updates, msg)
+		}
+	}
+	m.lock.RUnlock()
+	for _, msg := range updates {
+		_ = c.SendMessage(msg)
+	}
+}
+
+func (m *CascadeManager) handleLeaveRoom(cr *cascadeRoom, nodeID livekit.NodeID) {
+	roomName := cr.room.Name()
+
+	m.lock.Lock()
+	delete(cr.peers, nodeID)
+	participants := cr.peerParticipants[nodeID]
+	delete(cr.peerParticipants, nodeID)
+	for trackID, it := range cr.inbound {
+		if it.nodeID == nodeID {
+			delete(cr.inbound, trackID)
+			delete(m.inbound, trackID)
+		}
+	}
+	var peers []*relay.Conn
+	for _, conn := range cr.peers {
+		peers = append(peers, conn)
+	}
+	m.lock.Unlock()
+
+	m.closeOutbound(roomName, nodeID)
+	for identity, msg := range participants {
+		cr.room.RemoveRelayedParticipant(identity)
+
+		// the origin tells the other edges
+		if cr.originNodeID == "" {
+			if pi, err := msg.ParticipantInfo(); err == nil {
+				pi.State = livekit.ParticipantInfo_DISCONNECTED
+				left := &relay.Message{Type: relay.MessageParticipantUpdate, Room: roomName}
+				if err := left.SetParticipant(pi); err == nil {
+					for _, conn := range peers {
+						_ = conn.SendMessage(left)
+					}
+				}
+			}
+		}
+	}
+
+	if cr.originNodeID == nodeID && !cr.room.IsClosed() {
+		// the origin no longer hosts the room, or is unreachable. The edge reconnects while the origin still
+		// hosts it, the room is closed otherwise
+		if hosts, err := m.router.HostsRoom(context.Background(), roomName, cr.originNodeID); err == nil && !hosts {
+			cr.room.Logger.Infow("origin node no longer hosts room, closing")
+			cr.room.Close()
+		}
+	}
+}
+
+func (m *CascadeManager) handleParticipantUpdate(cr *cascadeRoom, nodeID livekit.NodeID, msg *relay.Message) {
+	pi, err := msg.ParticipantInfo()
+	if err != nil {
+		cr.room.Logger.Warnw("could not decode relayed participant", err, "node", nodeID)
+		return
+	}
+	identity := livekit.ParticipantIdentity(pi.Identity)
+	if cr.room.GetParticipant(identity) != nil {
+		// connected to this node too, e. g. while moving between nodes
+		return
+	}
+
+	m.lock.Lock()
+	participants := cr.peerParticipants[nodeID]
+	if participants == nil {
+		participants = make(map[livekit.ParticipantIdentity]*relay.Message)
+		cr.peerParticipants[nodeID] = participants
+	}
+	if pi.State == livekit.ParticipantInfo_DISCONNECTED {
+		delete(participants, identity)
+	} else {
+		participants[identity] = msg
+	}
+	var peers []*relay.Conn
+	if cr.originNodeID == "" {
+		for peerID, conn := range cr.peers {
+			if peerID != nodeID {
+				peers = append(peers, conn)
+			}
+		}
+	}
+	m.lock.Unlock()
+
+	// the origin passes updates on to the other edges, the tracks are relayed through it
+	for _, conn := range peers {
+		_ = conn.SendMessage(msg)
+	}
+
+	cr.room.UpdateRelayedParticipant(pi)
+
+	published := make(map[livekit.TrackID]bool)
+	if pi.State != livekit.ParticipantInfo_DISCONNECTED {
+		for _, ti := range pi.Tracks {
+			trackID := livekit.TrackID(ti.Sid)
+			codec, ok := msg.Codecs[trackID]
+			if !ok {
+				continue
+			}
+			published[trackID] = true
+
+			if track := cr.room.GetRelayedTrack(identity, trackID); track != nil {
+				track.SetMuted(ti.Muted)
+				continue
+			}
+			m.addInboundTrack(cr, nodeID, pi, ti, codec, msg.HeaderExtensions[trackID])
+		}
+	}
+
+	m.lock.Lock()
+	var removed []livekit.TrackID
+	for trackID, it := range cr.inbound {
+		if it.identity == identity && !published[trackID] {
+			removed = append(removed, trackID)
+			delete(cr.inbound, trackID)
+			delete(m.inbound, trackID)
+		}
+	}
+	m.lock.Unlock()
+	for _, trackID := range removed {
+		cr.room.RemoveRelayedTrack(identity, trackID)
+	}
+}
+
+func (m *CascadeManager) addInboundTrack(
+	cr *cascadeRoom,
+	nodeID livekit.NodeID,
+	pi *livekit.ParticipantInfo,
+	ti *livekit.TrackInfo,
+	codec webrtc.RTPCodecParameters,
+	headerExtensions []webrtc.RTPHeaderExtensionParameter,
+) {
+	roomName := cr.room.Name()
+	trackID := livekit.TrackID(ti.Sid)
+	send := func(msg *relay.Message) {
+		m.lock.RLock()
+		conn := cr.peers[nodeID]
+		m.lock.RUnlock()
+		if conn != nil {
+			_ = conn.SendMessage(msg)
+		}
+	}
+
+	track, err := cr.room.AddRelayedTrack(rtc.RelayedTrackParams{
+		TrackInfo:           ti,
+		ParticipantID:       livekit.ParticipantID(pi.Sid),
+		ParticipantIdentity: livekit.ParticipantIdentity(pi.Identity),
+		ParticipantVersion:  pi.Version,
+		Codec:               codec,
+		HeaderExtensions:    headerExtensions,
+		StreamTrackers:      m.config.Video.StreamTracker,
+		OnSubscribedChanged: func(subscribed bool) {
+			msgType := relay.MessageUnsubscribe
+			if subscribed {
+				msgType = relay.MessageSubscribe
+			}
+			send(&relay.Message{Type: msgType, Room: roomName, TrackID: trackID})
+		},
+		OnPLI: func(layer int32) {
+			send(&relay.Message{Type: relay.MessagePLI, Room: roomName, TrackID: trackID, Layer: layer})
+		},
+	})
+	if err != nil {
+		cr.room.Logger.Warnw("could not add relayed track", err, "trackID", trackID)
+		return
+	}
+	track.SetMuted(ti.Muted)
+
+	m.lock.Lock()
+	cr.inbound[trackID] = &cascadeInboundTrack{
+		nodeID:   nodeID,
+		identity: livekit.ParticipantIdentity(pi.Identity),
+		track:    track,
+	}
+	m.inbound[trackID] = track
+	m.lock.Unlock()
+}
+
+// sourceReceiver returns the receiver of a track relayed from this node, published by a participant connected to this
+// node or, on the origin, relayed from an edge
+func (m *CascadeManager) sourceReceiver(cr *cascadeRoom, trackID livekit.TrackID) sfu.TrackReceiver {
+	for _, p := range cr.room.GetParticipants() {
+		if track := p.GetPublishedTrack(trackID); track != nil {
+			receiver, _, _ := rtc.RelaySource(track)
+			return receiver
+		}
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if it := cr.inbound[trackID]; it != nil && cr.originNodeID == "" {
+		return it.track.RelayReceiver()
+	}
+	return nil
+}
+
+// a node has subscribers to a track, its media is relayed to the node until it unsubscribes
+func (m *CascadeManager) handleSubscribe(cr *cascadeRoom, c *relay.Conn, trackID livekit.TrackID) {
+	nodeID := c.RemoteNodeID()
+	receiver := m.sourceReceiver(cr, trackID)
+	if receiver == nil {
+		cr.room.Logger.Debugw("relayed track not found", "trackID", trackID, "node", nodeID)
+		return
+	}
+
+	key := cascadeOutboundKey{trackID: trackID, nodeID: nodeID}
+	roomName := cr.room.Name()
+	sender := sfu.NewRelaySender(
+		livekit.ParticipantID(fmt.Sprintf("relay_%s", nodeID)),
+		func(pkt *buffer.ExtPacket, layer int32) {
+			// the packet is sent once the connection gets to it, it is copied by marshalling
+			raw, err := pkt.Packet.Marshal()
+			if err != nil {
+				return
+			}
+			_ = c.SendMedia(&relay.MediaPacket{
+				TrackID:  trackID,
+				Layer:    layer,
+				Temporal: pkt.VideoLayer.Temporal,
+				KeyFrame: pkt.KeyFrame,
+				Packet:   raw,
+			})
+		},
+		func(layer int32, srData *buffer.RTCPSenderReportData) {
+			_ = c.SendMessage(&relay.Message{
+				Type:    relay.MessageSenderReport,
+				Room:    roomName,
+				TrackID: trackID,
+				Layer:   layer,
+				SenderReport: &relay.SenderReport{
+					RTPTimestamp: srData.RTPTimestamp,
+					NTPTimestamp: uint64(srData.NTPTimestamp),
+				},
+			})
+		},
+		func() {
+			m.lock.Lock()
+			if ot := m.outbound[key]; ot != nil && ot.receiver == receiver {
+				delete(m.outbound, key)
+			}
+			m.lock.Unlock()
+		},
+	)
+
+	m.lock.Lock()
+	previous := m.outbound[key]
+	m.outbound[key] = &cascadeOutboundTrack{roomName: roomName, receiver: receiver, sender: sender}
+	m.lock.Unlock()
+	if previous != nil {
+		previous.sender.Close()
+		if previous.receiver != receiver {
+			previous.receiver.DeleteDownTrack(previous.sender.SubscriberID())
+		}
+	}
+
+	if err := receiver.AddDownTrack(sender); err != nil {
+		cr.room.Logger.Warnw("could not relay track", err, "trackID", trackID, "node", nodeID)
+		m.closeOutboundTrack(key)
+	}
+}
+
+func (m *CascadeManager) closeOutboundTrack(key cascadeOutboundKey) {
+	m.lock.Lock()
+	ot := m.outbound[key]
+	delete(m.outbound, key)
+	m.lock.Unlock()
+	if ot == nil {
+		return
+	}
+
+	ot.sender.Close()
+	ot.receiver.DeleteDownTrack(ot.sender.SubscriberID())
+}
+
+// closeOutbound stops relaying the tracks of a room to a node
+func (m *CascadeManager) closeOutbound(roomName livekit.RoomName, nodeID livekit.NodeID) {
+	m.lock.RLock()
+	var keys []cascadeOutboundKey
+	for key, ot := range m.outbound {
+		if key.nodeID == nodeID && ot.roomName == roomName {
+			keys = append(keys, key)
+		}
+	}
+	m.lock.RUnlock()
+
+	for _, key := range keys {
+		m.closeOutboundTrack(key)
+	}
+}
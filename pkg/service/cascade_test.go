@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestCascadeManager(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	require.NoError(t, pc.Close())
+
+	conf := &config.Config{}
+	conf.MediaRelay.Port = uint32(port)
+	conf.MediaRelay.PreSharedKey = "secret"
+	conf.MediaRelay.KeyRotationInterval = time.Minute
+
+	router := &routingfakes.FakeRouter{}
+	router.GetNodeForRoomReturns(&livekit.Node{Id: "origin", Ip: "127.0.0.1"}, nil)
+
+	newRoom := func() *rtc.Room {
+		room := rtc.NewRoom(
+			&livekit.Room{Name: "room"},
+			nil,
+			rtc.WebRTCConfig{},
+			&config.AudioConfig{UpdateInterval: 500},
+			&livekit.ServerInfo{},
+			&telemetryfakes.FakeTelemetryService{},
+			nil,
+		)
+		t.Cleanup(room.Close)
+		return room
+	}
+	join := func(room *rtc.Room, identity livekit.ParticipantIdentity) *typesfakes.FakeLocalParticipant {
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IDReturns(livekit.ParticipantID("PA_" + identity))
+		p.IdentityReturns(identity)
+		p.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		p.ToProtoReturns(&livekit.ParticipantInfo{Sid: "PA_" + string(identity), Identity: string(identity), State: livekit.ParticipantInfo_ACTIVE})
+		require.NoError(t, room.Join(p, nil, nil, nil))
+		return p
+	}
+	relayedIdentities := func(room *rtc.Room) []string {
+		var identities []string
+		for _, pi := range room.GetRelayedParticipants() {
+			identities = append(identities, pi.Identity)
+		}
+		return identities
+	}
+
+	origin, err := NewCascadeManager(conf, &livekit.Node{Id: "origin"}, router)
+	require.NoError(t, err)
+	require.NoError(t, origin.Start())
+	defer origin.Stop()
+	// edges only connect out
+	edge, err := NewCascadeManager(conf, &livekit.Node{Id: "edge"}, router)
+	require.NoError(t, err)
+	defer edge.Stop()
+
+	originRoom := newRoom()
+	join(originRoom, "alice")
+	require.NoError(t, origin.AddRoom(context.Background(), originRoom))
+	require.False(t, origin.IsEdgeRoom("room"))
+
+	edgeRoom := newRoom()
+	bob := join(edgeRoom, "bob")
+	require.NoError(t, edge.AddRoom(context.Background(), edgeRoom))
+	require.True(t, edge.IsEdgeRoom("room"))
+
+	t.Run("participants are relayed both ways", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return len(relayedIdentities(edgeRoom)) == 1 && len(relayedIdentities(originRoom)) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, []string{"alice"}, relayedIdentities(edgeRoom))
+		require.Equal(t, []string{"bob"}, relayedIdentities(originRoom))
+	})
+
+	t.Run("data packets are relayed", func(t *testing.T) {
+		alice := originRoom.GetParticipant("alice").(*typesfakes.FakeLocalParticipant)
+		edgeRoom.SendDataPacket(&livekit.UserPacket{Payload: []byte("to origin")}, livekit.DataPacket_RELIABLE)
+		require.Eventually(t, func() bool {
+			return alice.SendDataPacketCallCount() == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		dp, _ := alice.SendDataPacketArgsForCall(0)
+		require.Equal(t, []byte("to origin"), dp.GetUser().Payload)
+		require.Equal(t, 1, bob.SendDataPacketCallCount())
+
+		originRoom.SendDataPacket(&livekit.UserPacket{Payload: []byte("to edge")}, livekit.DataPacket_RELIABLE)
+		require.Eventually(t, func() bool {
+			return bob.SendDataPacketCallCount() == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		dp, _ = bob.SendDataPacketArgsForCall(1)
+		require.Equal(t, []byte("to edge"), dp.GetUser().Payload)
+
+		// relayed packets are not relayed back
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, 2, alice.SendDataPacketCallCount())
+		require.Equal(t, 2, bob.SendDataPacketCallCount())
+	})
+
+	t.Run("active speakers are relayed", func(t *testing.T) {
+		alice := originRoom.GetParticipant("alice").(*typesfakes.FakeLocalParticipant)
+		alice.GetAudioLevelReturns(0.5, true)
+		require.Eventually(t, func() bool {
+			speakers := edgeRoom.GetActiveSpeakers()
+			return len(speakers) == 1 && speakers[0].Sid == "PA_alice"
+		}, 5*time.Second, 10*time.Millisecond)
+
+		alice.GetAudioLevelReturns(0, false)
+		require.Eventually(t, func() bool {
+			return len(edgeRoom.GetActiveSpeakers()) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("room metadata is relayed to edges", func(t *testing.T) {
+		origin.RoomUpdated("room", &livekit.Room{Name: "room", Metadata: "updated"})
+		require.Eventually(t, func() bool {
+			return edgeRoom.ToProto().Metadata == "updated"
+		}, 15*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("participants leaving an edge leave the origin", func(t *testing.T) {
+		bob.ToProtoReturns(&livekit.ParticipantInfo{Sid: "PA_bob", Identity: "bob", State: livekit.ParticipantInfo_DISCONNECTED})
+		edge.ParticipantChanged("room", bob)
+		require.Eventually(t, func() bool {
+			return len(relayedIdentities(originRoom)) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("edges leave once their room closes", func(t *testing.T) {
+		carol := join(edgeRoom, "carol")
+		edge.ParticipantChanged("room", carol)
+		require.Eventually(t, func() bool {
+			return len(relayedIdentities(originRoom)) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		edge.RemoveRoom("room")
+		require.Equal(t, 1, router.RemoveEdgeNodeForRoomCallCount())
+		_, roomName, nodeID := router.RemoveEdgeNodeForRoomArgsForCall(0)
+		require.Equal(t, livekit.RoomName("room"), roomName)
+		require.Equal(t, livekit.NodeID("edge"), nodeID)
+		require.Eventually(t, func() bool {
+			return len(relayedIdentities(originRoom)) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("edges close when the room closes on the origin", func(t *testing.T) {
+		other, err := NewCascadeManager(conf, &livekit.Node{Id: "other"}, router)
+		require.NoError(t, err)
+		defer other.Stop()
+		otherRoom := newRoom()
+		join(otherRoom, "dave")
+		require.NoError(t, other.AddRoom(context.Background(), otherRoom))
+		require.Eventually(t, func() bool {
+			return len(relayedIdentities(originRoom)) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		origin.RemoveRoom("room")
+		require.Eventually(t, otherRoom.IsClosed, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const configPathPrefix = "/config/"
+
+// ConfigService reloads the configuration of the node serving the request, as SIGHUP does. Only the settings listed
+// in config-sample.yaml as reloadable are applied, the response tells whether others changed and need a restart.
+// Requests are JSON posted to /config/ReloadConfig and need a token signed with an admin API key
+type ConfigService struct {
+	current *config.Current
+}
+
+func NewConfigService(current *config.Current) *ConfigService {
+	return &ConfigService{
+		current: current,
+	}
+}
+
+func (s *ConfigService) PathPrefix() string {
+	return configPathPrefix
+}
+
+func (s *ConfigService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := EnsureNodeAdminPermission(r.Context()); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, configPathPrefix) != "ReloadConfig" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	res, err := s.current.Reload()
+	if err != nil {
+		if errors.Is(err, config.ErrConfigReloadUnavailable) {
+			handleError(w, http.StatusServiceUnavailable, err)
+		} else {
+			handleError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	logger.Infow("configuration reloaded", "changed", res.Changed, "restartRequired", res.RestartRequired)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
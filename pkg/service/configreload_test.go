@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestConfigService(t *testing.T) {
+	conf, err := config.NewConfig("", true, nil, nil)
+	require.NoError(t, err)
+	current := config.NewCurrent(conf)
+	svc := NewConfigService(current)
+
+	admin := &auth.VideoGrant{RoomCreate: true, RoomList: true}
+	request := func(grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+"ReloadConfig", nil)
+		if grant != nil {
+			ctx := WithGrants(context.Background(), &auth.ClaimGrants{Video: grant})
+			if grant == admin {
+				ctx = WithNodeAdmin(ctx)
+			}
+			r = r.WithContext(ctx)
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusUnauthorized, request(nil).Code)
+	require.Equal(t, http.StatusUnauthorized, request(&auth.VideoGrant{RoomAdmin: true, Room: "room"}).Code)
+	require.Equal(t, http.StatusUnauthorized, request(&auth.VideoGrant{RoomCreate: true, RoomList: true}).Code)
+	require.Equal(t, http.StatusServiceUnavailable, request(admin).Code)
+
+	current.SetLoader(func() (*config.Config, error) {
+		return config.NewConfig("room:\n  empty_timeout: 60\n", true, nil, nil)
+	})
+	w := request(admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	res := &config.ReloadResult{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+	require.Equal(t, []string{"room.empty_timeout"}, res.Changed)
+	require.False(t, res.RestartRequired)
+	require.EqualValues(t, 60, current.Get().Room.EmptyTimeout)
+}
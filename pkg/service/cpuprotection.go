@@ -0,0 +1,316 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mackerelio/go-osstat/cpu"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	defaultCPUProtectionInterval   = 2 * time.Second
+	defaultCPUProtectionHysteresis = 0.05
+	// participants who spoke this recently keep their video
+	cpuProtectionSpeakerHold = 10 * time.Second
+)
+
+// cpuProtector sheds video step by step while the CPU of the node is overloaded, so that the node keeps forwarding
+// audio instead of degrading everything at once. Video is limited to medium quality first, then the video of
+// participants who are not speaking is paused one participant at a time, then new video tracks are refused. Paused
+// participants are resumed one at a time once the load allows, and right away when they speak
+type cpuProtector struct {
+	current      *config.Current
+	participants func() [][]types.LocalParticipant
+	cpuLoad      func() (float32, error)
+
+	level atomic.Int32 // types.LoadSheddingLevel
+
+	// the following are accessed by the worker only
+	paused    map[livekit.ParticipantID]bool
+	lastSpoke map[livekit.ParticipantID]time.Time
+	// what participants were last told, by participant
+	sent map[livekit.ParticipantID]string
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// newCPUProtector creates a protector shedding the video of participants, grouped by room
+func newCPUProtector(current *config.Current, participants func() [][]types.LocalParticipant) *cpuProtector {
+	sampler := &cpuLoadSampler{}
+	return &cpuProtector{
+		current:      current,
+		participants: participants,
+		cpuLoad:      sampler.sample,
+		paused:       make(map[livekit.ParticipantID]bool),
+		lastSpoke:    make(map[livekit.ParticipantID]time.Time),
+		sent:         make(map[livekit.ParticipantID]string),
+		stop:         make(chan struct{}),
+	}
+}
+
+func (p *cpuProtector) Start() {
+	go p.worker()
+}
+
+func (p *cpuProtector) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+func (p *cpuProtector) Level() types.LoadSheddingLevel {
+	return types.LoadSheddingLevel(p.level.Load())
+}
+
+// AdmitPublication refuses new video tracks while the node is overloaded
+func (p *cpuProtector) AdmitPublication(kind livekit.TrackType) error {
+	if kind != livekit.TrackType_VIDEO || p.Level() < types.LoadSheddingRefuseVideo {
+		return nil
+	}
+	prometheus.RecordCPUProtectionRefusedTrack()
+	return rtc.ErrVideoRefusedOverloaded
+}
+
+func (p *cpuProtector) worker() {
+	for {
+		conf := &p.current.Get().Limit.CPUProtection
+		interval := conf.Interval
+		if interval <= 0 {
+			interval = defaultCPUProtectionInterval
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(interval):
+		}
+
+		// the configuration may have been reloaded while waiting
+		conf = &p.current.Get().Limit.CPUProtection
+		if !isCPUProtectionEnabled(conf) && p.Level() == types.LoadSheddingNone && len(p.paused) == 0 {
+			continue
+		}
+
+		load, err := p.cpuLoad()
+		if err != nil {
+			logger.Warnw("could not get CPU load", err)
+			continue
+		}
+		p.update(conf, load, time.Now(), p.participants())
+	}
+}
+
+// update takes the next step of shedding for a CPU load, or of undoing it
+func (p *cpuProtector) update(conf *config.CPUProtectionConfig, load float32, now time.Time, rooms [][]types.LocalParticipant) {
+	previous := p.Level()
+	level := cpuProtectionLevel(conf, previous, load)
+	if level != previous {
+		p.level.Store(int32(level))
+		logger.Infow("CPU protection level changed", "level", level, "previous", previous, "cpuLoad", load)
+	}
+
+	present := make(map[livekit.ParticipantID]bool)
+	for _, participants := range rooms {
+		for _, lp := range participants {
+			present[lp.ID()] = true
+			if _, active := lp.GetAudioLevel(); active {
+				p.lastSpoke[lp.ID()] = now
+			}
+		}
+	}
+	for id := range p.lastSpoke {
+		if !present[id] {
+			delete(p.lastSpoke, id)
+		}
+	}
+	for id := range p.sent {
+		if !present[id] {
+			delete(p.sent, id)
+		}
+	}
+	for id := range p.paused {
+		if !present[id] || p.isSpeaking(id, now) {
+			delete(p.paused, id)
+		}
+	}
+
+	// levels are additive, a step is only taken when its own load is configured and reached
+	switch {
+	case conf.PauseVideoLoad > 0 && level >= types.LoadSheddingPauseVideo && load >= conf.PauseVideoLoad:
+		if lp := p.nextToPause(conf, rooms, now); lp != nil {
+			p.paused[lp.ID()] = true
+			logger.Infow("CPU protection pausing video", "participant", lp.Identity(), "pID", lp.ID(), "cpuLoad", load)
+		}
+	case len(p.paused) != 0 && (conf.PauseVideoLoad <= 0 || level < types.LoadSheddingPauseVideo):
+		if lp := p.nextToResume(conf, rooms); lp != nil {
+			delete(p.paused, lp.ID())
+			logger.Infow("CPU protection resuming video", "participant", lp.Identity(), "pID", lp.ID(), "cpuLoad", load)
+		}
+	}
+
+	p.apply(conf, level, rooms)
+	prometheus.SetCPUProtection(int(level), len(p.paused))
+}
+
+func (p *cpuProtector) isSpeaking(id livekit.ParticipantID, now time.Time) bool {
+	lastSpoke, ok := p.lastSpoke[id]
+	return ok && now.Sub(lastSpoke) < cpuProtectionSpeakerHold
+}
+
+// nextToPause returns the participant with the lowest priority among those publishing video who are not speaking,
+// the one silent the longest when priorities are equal
+func (p *cpuProtector) nextToPause(conf *config.CPUProtectionConfig, rooms [][]types.LocalParticipant, now time.Time) types.LocalParticipant {
+	var next types.LocalParticipant
+	var nextPriority uint32
+	var nextLastSpoke time.Time
+	for _, participants := range rooms {
+		for _, lp := range participants {
+			if p.paused[lp.ID()] || p.isSpeaking(lp.ID(), now) || !publishesVideo(lp) {
+				continue
+			}
+			priority := participantPriority(conf.Priorities, lp.Identity())
+			lastSpoke := p.lastSpoke[lp.ID()]
+			if next == nil || priority < nextPriority || (priority == nextPriority && lastSpoke.Before(nextLastSpoke)) {
+				next, nextPriority, nextLastSpoke = lp, priority, lastSpoke
+			}
+		}
+	}
+	return next
+}
+
+// nextToResume returns the paused participant with the highest priority
+func (p *cpuProtector) nextToResume(conf *config.CPUProtectionConfig, rooms [][]types.LocalParticipant) types.LocalParticipant {
+	var next types.LocalParticipant
+	var nextPriority uint32
+	for _, participants := range rooms {
+		for _, lp := range participants {
+			if !p.paused[lp.ID()] {
+				continue
+			}
+			if priority := participantPriority(conf.Priorities, lp.Identity()); next == nil || priority > nextPriority {
+				next, nextPriority = lp, priority
+			}
+		}
+	}
+	return next
+}
+
+// apply limits the video tracks published on the node to the level, and tells participants what changed for them
+func (p *cpuProtector) apply(conf *config.CPUProtectionConfig, level types.LoadSheddingLevel, rooms [][]types.LocalParticipant) {
+	quality := livekit.VideoQuality_HIGH
+	if conf.ReduceLayersLoad > 0 && level >= types.LoadSheddingReduceLayers {
+		quality = livekit.VideoQuality_MEDIUM
+	}
+
+	for _, participants := range rooms {
+		var pausedTrackIDs []livekit.TrackID
+		for _, lp := range participants {
+			for _, track := range lp.GetPublishedTracks() {
+				if track.Kind() != livekit.TrackType_VIDEO {
+					continue
+				}
+				if p.paused[lp.ID()] {
+					track.SetLoadSheddingQuality(livekit.VideoQuality_OFF)
+					pausedTrackIDs = append(pausedTrackIDs, track.ID())
+				} else {
+					track.SetLoadSheddingQuality(quality)
+				}
+			}
+		}
+
+		sort.Slice(pausedTrackIDs, func(i, j int) bool { return pausedTrackIDs[i] < pausedTrackIDs[j] })
+		state := fmt.Sprint(level, pausedTrackIDs)
+		for _, lp := range participants {
+			sent, ok := p.sent[lp.ID()]
+			if !ok && level == types.LoadSheddingNone && len(pausedTrackIDs) == 0 {
+				// nothing to tell participants that were never told otherwise
+				continue
+			}
+			if sent == state {
+				continue
+			}
+			if err := lp.SendLoadShedding(level, pausedTrackIDs); err != nil {
+				lp.GetLogger().Debugw("could not send load shedding", "error", err)
+				continue
+			}
+			p.sent[lp.ID()] = state
+		}
+	}
+}
+
+func publishesVideo(lp types.LocalParticipant) bool {
+	for _, track := range lp.GetPublishedTracks() {
+		if track.Kind() == livekit.TrackType_VIDEO && !track.IsMuted() {
+			return true
+		}
+	}
+	return false
+}
+
+func isCPUProtectionEnabled(conf *config.CPUProtectionConfig) bool {
+	return conf.ReduceLayersLoad > 0 || conf.PauseVideoLoad > 0 || conf.RefuseVideoLoad > 0
+}
+
+// cpuProtectionLevel returns the highest level whose load is reached. A level taken already is kept till the load
+// falls below its load by the hysteresis, so that shedding does not flap around a threshold
+func cpuProtectionLevel(conf *config.CPUProtectionConfig, current types.LoadSheddingLevel, load float32) types.LoadSheddingLevel {
+	hysteresis := conf.Hysteresis
+	if hysteresis <= 0 {
+		hysteresis = defaultCPUProtectionHysteresis
+	}
+
+	level := types.LoadSheddingNone
+	for _, l := range []struct {
+		level types.LoadSheddingLevel
+		load  float32
+	}{
+		{types.LoadSheddingReduceLayers, conf.ReduceLayersLoad},
+		{types.LoadSheddingPauseVideo, conf.PauseVideoLoad},
+		{types.LoadSheddingRefuseVideo, conf.RefuseVideoLoad},
+	} {
+		if l.load <= 0 {
+			continue
+		}
+		threshold := l.load
+		if l.level <= current {
+			threshold -= hysteresis
+		}
+		if load >= threshold {
+			level = l.level
+		}
+	}
+	return level
+}
+
+// ---------------------------------------------
+
+// cpuLoadSampler measures the CPU load of the node between samples, the first sample is 0
+type cpuLoadSampler struct {
+	lastTotal, lastIdle uint64
+}
+
+func (s *cpuLoadSampler) sample() (float32, error) {
+	stats, err := cpu.Get()
+	if err != nil {
+		return 0, err
+	}
+
+	var load float32
+	if s.lastTotal > 0 && s.lastTotal < stats.Total {
+		load = 1 - float32(stats.Idle-s.lastIdle)/float32(stats.Total-s.lastTotal)
+	}
+	s.lastTotal, s.lastIdle = stats.Total, stats.Idle
+	return load, nil
+}
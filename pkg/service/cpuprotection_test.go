@@ -0,0 +1,152 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+)
+
+func TestCPUProtectionLevel(t *testing.T) {
+	conf := &config.CPUProtectionConfig{
+		ReduceLayersLoad: 0.7,
+		PauseVideoLoad:   0.8,
+		RefuseVideoLoad:  0.9,
+		Hysteresis:       0.05,
+	}
+
+	require.Equal(t, types.LoadSheddingNone, cpuProtectionLevel(conf, types.LoadSheddingNone, 0.5))
+	require.Equal(t, types.LoadSheddingReduceLayers, cpuProtectionLevel(conf, types.LoadSheddingNone, 0.7))
+	require.Equal(t, types.LoadSheddingPauseVideo, cpuProtectionLevel(conf, types.LoadSheddingNone, 0.85))
+	require.Equal(t, types.LoadSheddingRefuseVideo, cpuProtectionLevel(conf, types.LoadSheddingNone, 0.95))
+
+	// levels taken are kept within the hysteresis
+	require.Equal(t, types.LoadSheddingPauseVideo, cpuProtectionLevel(conf, types.LoadSheddingPauseVideo, 0.77))
+	require.Equal(t, types.LoadSheddingReduceLayers, cpuProtectionLevel(conf, types.LoadSheddingPauseVideo, 0.74))
+	require.Equal(t, types.LoadSheddingReduceLayers, cpuProtectionLevel(conf, types.LoadSheddingReduceLayers, 0.67))
+	require.Equal(t, types.LoadSheddingReduceLayers, cpuProtectionLevel(conf, types.LoadSheddingReduceLayers, 0.78))
+	require.Equal(t, types.LoadSheddingNone, cpuProtectionLevel(conf, types.LoadSheddingReduceLayers, 0.6))
+
+	// disabled levels are skipped
+	conf.PauseVideoLoad = 0
+	require.Equal(t, types.LoadSheddingReduceLayers, cpuProtectionLevel(conf, types.LoadSheddingNone, 0.85))
+	require.Equal(t, types.LoadSheddingRefuseVideo, cpuProtectionLevel(conf, types.LoadSheddingNone, 0.95))
+	require.Equal(t, types.LoadSheddingNone, cpuProtectionLevel(&config.CPUProtectionConfig{}, types.LoadSheddingNone, 1))
+}
+
+func TestCPUProtector(t *testing.T) {
+	conf := &config.CPUProtectionConfig{
+		ReduceLayersLoad: 0.7,
+		PauseVideoLoad:   0.8,
+		RefuseVideoLoad:  0.9,
+		Priorities: []config.ParticipantPriorityConfig{
+			{Identities: []string{"host"}, Priority: 2},
+		},
+	}
+
+	newParticipant := func(identity livekit.ParticipantIdentity) (*typesfakes.FakeLocalParticipant, *typesfakes.FakeMediaTrack) {
+		track := &typesfakes.FakeMediaTrack{}
+		track.IDReturns(livekit.TrackID("TR_" + identity))
+		track.KindReturns(livekit.TrackType_VIDEO)
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IDReturns(livekit.ParticipantID("PA_" + identity))
+		p.IdentityReturns(identity)
+		p.GetPublishedTracksReturns([]types.MediaTrack{track})
+		return p, track
+	}
+	host, hostTrack := newParticipant("host")
+	silent, silentTrack := newParticipant("silent")
+	speaker, speakerTrack := newParticipant("speaker")
+	speaker.GetAudioLevelReturns(0.5, true)
+	rooms := [][]types.LocalParticipant{{host, silent, speaker}}
+
+	p := newCPUProtector(config.NewCurrent(&config.Config{}), nil)
+	now := time.Now()
+
+	// video is limited to medium quality
+	p.update(conf, 0.75, now, rooms)
+	require.Equal(t, types.LoadSheddingReduceLayers, p.Level())
+	require.Equal(t, livekit.VideoQuality_MEDIUM, hostTrack.SetLoadSheddingQualityArgsForCall(0))
+	require.Equal(t, 1, host.SendLoadSheddingCallCount())
+	require.NoError(t, p.AdmitPublication(livekit.TrackType_VIDEO))
+
+	// the video of the silent participant with the lowest priority is paused first, not the speaker's
+	p.update(conf, 0.85, now, rooms)
+	require.Equal(t, livekit.VideoQuality_OFF, silentTrack.SetLoadSheddingQualityArgsForCall(1))
+	require.Equal(t, livekit.VideoQuality_MEDIUM, hostTrack.SetLoadSheddingQualityArgsForCall(1))
+	level, paused := host.SendLoadSheddingArgsForCall(1)
+	require.Equal(t, types.LoadSheddingPauseVideo, level)
+	require.Equal(t, []livekit.TrackID{"TR_silent"}, paused)
+
+	p.update(conf, 0.85, now, rooms)
+	require.Equal(t, livekit.VideoQuality_OFF, hostTrack.SetLoadSheddingQualityArgsForCall(2))
+	require.Equal(t, livekit.VideoQuality_MEDIUM, speakerTrack.SetLoadSheddingQualityArgsForCall(2))
+
+	// nothing left to pause, new video is refused
+	p.update(conf, 0.95, now, rooms)
+	require.Equal(t, livekit.VideoQuality_MEDIUM, speakerTrack.SetLoadSheddingQualityArgsForCall(3))
+	require.ErrorIs(t, p.AdmitPublication(livekit.TrackType_VIDEO), rtc.ErrVideoRefusedOverloaded)
+	require.NoError(t, p.AdmitPublication(livekit.TrackType_AUDIO))
+
+	// participants who speak get their video back
+	silent.GetAudioLevelReturns(0.5, true)
+	p.update(conf, 0.85, now, rooms)
+	require.Equal(t, livekit.VideoQuality_MEDIUM, silentTrack.SetLoadSheddingQualityArgsForCall(4))
+	require.Equal(t, livekit.VideoQuality_OFF, hostTrack.SetLoadSheddingQualityArgsForCall(4))
+
+	// the load allows resuming, one participant at a time
+	p.update(conf, 0.7, now, rooms)
+	require.Equal(t, types.LoadSheddingReduceLayers, p.Level())
+	require.Equal(t, livekit.VideoQuality_MEDIUM, hostTrack.SetLoadSheddingQualityArgsForCall(5))
+
+	// participants are told once everything is back to normal
+	sent := host.SendLoadSheddingCallCount()
+	p.update(conf, 0.2, now, rooms)
+	require.Equal(t, types.LoadSheddingNone, p.Level())
+	require.Equal(t, livekit.VideoQuality_HIGH, hostTrack.SetLoadSheddingQualityArgsForCall(6))
+	require.Equal(t, sent+1, host.SendLoadSheddingCallCount())
+	p.update(conf, 0.2, now, rooms)
+	require.Equal(t, sent+1, host.SendLoadSheddingCallCount())
+}
+
+func TestCPUProtectorRefuseOnly(t *testing.T) {
+	conf := &config.CPUProtectionConfig{
+		RefuseVideoLoad: 0.9,
+	}
+
+	track := &typesfakes.FakeMediaTrack{}
+	track.IDReturns("TR_silent")
+	track.KindReturns(livekit.TrackType_VIDEO)
+	silent := &typesfakes.FakeLocalParticipant{}
+	silent.IDReturns("PA_silent")
+	silent.IdentityReturns("silent")
+	silent.GetPublishedTracksReturns([]types.MediaTrack{track})
+	rooms := [][]types.LocalParticipant{{silent}}
+
+	p := newCPUProtector(config.NewCurrent(&config.Config{}), nil)
+	now := time.Now()
+
+	// only new video is refused, published video is neither reduced nor paused
+	p.update(conf, 0.95, now, rooms)
+	require.Equal(t, types.LoadSheddingRefuseVideo, p.Level())
+	require.ErrorIs(t, p.AdmitPublication(livekit.TrackType_VIDEO), rtc.ErrVideoRefusedOverloaded)
+	require.Equal(t, livekit.VideoQuality_HIGH, track.SetLoadSheddingQualityArgsForCall(0))
+	level, paused := silent.SendLoadSheddingArgsForCall(0)
+	require.Equal(t, types.LoadSheddingRefuseVideo, level)
+	require.Empty(t, paused)
+
+	p.update(conf, 0.95, now, rooms)
+	require.Equal(t, livekit.VideoQuality_HIGH, track.SetLoadSheddingQualityArgsForCall(1))
+	require.Equal(t, 1, silent.SendLoadSheddingCallCount())
+
+	p.update(conf, 0.5, now, rooms)
+	require.Equal(t, types.LoadSheddingNone, p.Level())
+	require.NoError(t, p.AdmitPublication(livekit.TrackType_VIDEO))
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	dashboardPathPrefix = "/dashboard/"
+	dashboardCookie     = "livekit_dashboard"
+	dashboardIdentity   = "dashboard"
+
+	defaultDashboardSessionTTL = 8 * time.Hour
+)
+
+var (
+	ErrDashboardLoginFailed = errors.New("invalid API key or secret")
+	ErrDashboardLoginNeeded = errors.New("dashboard login required")
+)
+
+//go:embed dashboard/index.html
+var dashboardPage []byte
+
+type DashboardMuteTrackRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+	Muted    bool   `json:"muted"`
+}
+
+type DashboardParticipantRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+}
+
+type DashboardRoomRequest struct {
+	Room string `json:"room"`
+}
+
+// DashboardService serves the admin dashboard at /dashboard/, the page is backed by JSON endpoints under
+// /dashboard/api/. Logging in with an API key and its secret sets a session cookie holding a token
+// signed with that key. Track stats of a room are reported by the node hosting it.
+type DashboardService struct {
+	current     *config.Current
+	roomService livekit.RoomService
+	router      routing.Router
+	roomManager *RoomManager
+	keyProvider auth.KeyProvider
+}
+
+func NewDashboardService(
+	current *config.Current,
+	roomService livekit.RoomService,
+	router routing.Router,
+	roomManager *RoomManager,
+	keyProvider auth.KeyProvider,
+) *DashboardService {
+	return &DashboardService{
+		current:     current,
+		roomService: roomService,
+		router:      router,
+		roomManager: roomManager,
+		keyProvider: keyProvider,
+	}
+}
+
+func (s *DashboardService) PathPrefix() string {
+	return dashboardPathPrefix
+}
+
+func (s *DashboardService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.current.Get().Dashboard.Enabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, dashboardPathPrefix)
+	switch path {
+	case "", "index.html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(dashboardPage)
+		return
+	case "login":
+		s.login(w, r)
+		return
+	case "logout":
+		http.SetCookie(w, &http.Cookie{Name: dashboardCookie, Path: dashboardPathPrefix, MaxAge: -1})
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if !strings.HasPrefix(path, "api/") {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.authenticate(r); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	ctx := r.Context()
+	var res proto.Message
+	var err error
+	switch method := strings.TrimPrefix(path, "api/"); {
+	case method == "rooms" && r.Method == http.MethodGet:
+		res, err = s.roomService.ListRooms(adminContext(ctx, ""), &livekit.ListRoomsRequest{})
+	case method == "participants" && r.Method == http.MethodGet:
+		room := r.URL.Query().Get("room")
+		res, err = s.roomService.ListParticipants(adminContext(ctx, room), &livekit.ListParticipantsRequest{Room: room})
+	case method == "nodes" && r.Method == http.MethodGet:
+		s.nodes(w)
+		return
+	case method == "track_stats" && r.Method == http.MethodGet:
+		s.trackStats(w, r)
+		return
+	case method == "mute_track" && r.Method == http.MethodPost:
+		req := &DashboardMuteTrackRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err == nil {
+			res, err = s.roomService.MutePublishedTrack(adminContext(ctx, req.Room), &livekit.MuteRoomTrackRequest{
+				Room:     req.Room,
+				Identity: req.Identity,
+				TrackSid: req.TrackSid,
+				Muted:    req.Muted,
+			})
+		}
+	case method == "remove_participant" && r.Method == http.MethodPost:
+		req := &DashboardParticipantRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err == nil {
+			res, err = s.roomService.RemoveParticipant(adminContext(ctx, req.Room), &livekit.RoomParticipantIdentity{
+				Room:     req.Room,
+				Identity: req.Identity,
+			})
+		}
+	case method == "delete_room" && r.Method == http.MethodPost:
+		req := &DashboardRoomRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err == nil {
+			res, err = s.roomService.DeleteRoom(adminContext(ctx, req.Room), &livekit.DeleteRoomRequest{Room: req.Room})
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err, "path", path)
+		return
+	}
+
+	b, err := protojson.Marshal(res)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func (s *DashboardService) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	apiKey, secret := r.FormValue("api_key"), r.FormValue("api_secret")
+	expected := s.getSecret(apiKey)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) != 1 {
+		handleError(w, http.StatusUnauthorized, ErrDashboardLoginFailed, "apiKey", apiKey)
+		return
+	}
+
+	ttl := s.current.Get().Dashboard.SessionTTL
+	if ttl <= 0 {
+		ttl = defaultDashboardSessionTTL
+	}
+	token, err := auth.NewAccessToken(apiKey, secret).
+		SetIdentity(dashboardIdentity).
+		SetValidFor(ttl).
+		AddGrant(&auth.VideoGrant{RoomList: true, RoomAdmin: true, RoomCreate: true}).
+		ToJWT()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardCookie,
+		Value:    token,
+		Path:     dashboardPathPrefix,
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *DashboardService) authenticate(r *http.Request) error {
+	cookie, err := r.Cookie(dashboardCookie)
+	if err != nil {
+		return ErrDashboardLoginNeeded
+	}
+	v, err := auth.ParseAPIToken(cookie.Value)
+	if err != nil {
+		return ErrInvalidAuthorizationToken
+	}
+	secret := s.getSecret(v.APIKey())
+	if secret == "" {
+		return ErrDashboardLoginNeeded
+	}
+	grants, err := v.Verify(secret)
+	if err != nil {
+		return ErrDashboardLoginNeeded
+	}
+	if grants.Identity != dashboardIdentity || grants.Video == nil || !grants.Video.RoomList || !grants.Video.RoomAdmin || !grants.Video.RoomCreate {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// getSecret returns the secret of an API key allowed to log into the dashboard
+func (s *DashboardService) getSecret(apiKey string) string {
+	if s.keyProvider == nil || apiKey == "" {
+		return ""
+	}
+	if apiKeys := s.current.Get().Dashboard.APIKeys; len(apiKeys) != 0 {
+		allowed := false
+		for _, key := range apiKeys {
+			allowed = allowed || key == apiKey
+		}
+		if !allowed {
+			return ""
+		}
+	}
+	return s.keyProvider.GetSecret(apiKey)
+}
+
+func (s *DashboardService) nodes(w http.ResponseWriter) {
+	nodes, err := s.router.ListNodes()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	conf := s.current.Get()
+	capacities := make([]*NodeCapacity, 0, len(nodes))
+	for _, node := range nodes {
+		capacities = append(capacities, GetNodeCapacity(conf, node))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(capacities)
+}
+
+func (s *DashboardService) trackStats(w http.ResponseWriter, r *http.Request) {
+	roomName := livekit.RoomName(r.URL.Query().Get("room"))
+	var room *rtc.Room
+	if s.roomManager != nil && roomName != "" {
+		room = s.roomManager.GetRoom(r.Context(), roomName)
+	}
+	if room == nil {
+		handleError(w, http.StatusNotFound, ErrRoomNotFound, "room", roomName)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(getDashboardTrackStats(room.GetParticipants()))
+}
+
+// adminContext grants the dashboard admin permissions over a room for calls to the room service
+func adminContext(ctx context.Context, room string) context.Context {
+	return WithGrants(ctx, &auth.ClaimGrants{
+		Identity: dashboardIdentity,
+		Video:    &auth.VideoGrant{RoomList: true, RoomAdmin: true, RoomCreate: true, Room: room},
+	})
+}
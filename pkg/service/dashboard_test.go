@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestDashboardService(t *testing.T) {
+	conf := &config.Config{Dashboard: config.DashboardConfig{Enabled: true, APIKeys: []string{"admin"}}}
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{
+		"admin": "admin_secret",
+		"other": "other_secret",
+	})
+	roomService := newTestRoomService(config.RoomConfig{})
+	roomService.store.ListRoomsReturns([]*livekit.Room{{Name: "room", Sid: "RM_1"}}, nil)
+	roomService.router.ListNodesReturns([]*livekit.Node{{Id: "ND_1", Stats: &livekit.NodeStats{NumClients: 2}}}, nil)
+	svc := service.NewDashboardService(config.NewCurrent(conf), &roomService.RoomService, roomService.router, nil, provider)
+
+	login := func(key, secret string) *httptest.ResponseRecorder {
+		form := url.Values{"api_key": {key}, "api_secret": {secret}}
+		r := httptest.NewRequest(http.MethodPost, "/dashboard/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	get := func(path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("serves the page", func(t *testing.T) {
+		w := get("/dashboard/", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "<html")
+	})
+
+	t.Run("requires login", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, get("/dashboard/api/rooms", nil).Code)
+		require.Equal(t, http.StatusUnauthorized, login("admin", "wrong").Code)
+		// keys that aren't allowed can't log in
+		require.Equal(t, http.StatusUnauthorized, login("other", "other_secret").Code)
+	})
+
+	t.Run("lists rooms and nodes", func(t *testing.T) {
+		w := login("admin", "admin_secret")
+		require.Equal(t, http.StatusNoContent, w.Code)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		require.True(t, cookies[0].HttpOnly)
+
+		w = get("/dashboard/api/rooms", cookies)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "RM_1")
+
+		w = get("/dashboard/api/nodes", cookies)
+		require.Equal(t, http.StatusOK, w.Code)
+		var nodes []*service.NodeCapacity
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nodes))
+		require.Len(t, nodes, 1)
+		require.Equal(t, "ND_1", nodes[0].NodeID)
+		require.EqualValues(t, 2, nodes[0].Participants)
+
+		// track stats are reported by the node hosting the room
+		require.Equal(t, http.StatusNotFound, get("/dashboard/api/track_stats?room=room", cookies).Code)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		disabled := service.NewDashboardService(config.NewCurrent(&config.Config{}), &roomService.RoomService, roomService.router, nil, provider)
+		w := httptest.NewRecorder()
+		disabled.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dashboard/", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+// DashboardTrackStats are the receive stats of a published track, for each codec it is published in
+type DashboardTrackStats struct {
+	Identity livekit.ParticipantIdentity `json:"identity"`
+	TrackSid livekit.TrackID             `json:"track_sid"`
+	Kind     string                      `json:"kind"`
+	Source   string                      `json:"source"`
+	Muted    bool                        `json:"muted"`
+	Codecs   []*DashboardCodecStats      `json:"codecs"`
+}
+
+type DashboardCodecStats struct {
+	MimeType string `json:"mime_type"`
+	// average since the track was published, in bps
+	Bitrate              float64 `json:"bitrate"`
+	Packets              uint32  `json:"packets"`
+	PacketsLost          uint32  `json:"packets_lost"`
+	PacketLossPercentage float32 `json:"packet_loss_percentage"`
+	JitterMs             float64 `json:"jitter_ms"`
+	// video layers being received
+	Layers []*DashboardLayerStats `json:"layers,omitempty"`
+}
+
+type DashboardLayerStats struct {
+	Quality string `json:"quality"`
+	Width   uint32 `json:"width"`
+	Height  uint32 `json:"height"`
+	// current bitrate of a subscription to the layer, in bps
+	Bitrate        int64 `json:"bitrate"`
+	TemporalLayers int32 `json:"temporal_layers"`
+}
+
+// ------------------------------------------------
+
+func getDashboardTrackStats(participants []types.LocalParticipant) []*DashboardTrackStats {
+	stats := make([]*DashboardTrackStats, 0)
+	for _, p := range participants {
+		for _, track := range p.GetPublishedTracks() {
+			ts := &DashboardTrackStats{
+				Identity: p.Identity(),
+				TrackSid: track.ID(),
+				Kind:     track.Kind().String(),
+				Source:   track.Source().String(),
+				Muted:    track.IsMuted(),
+			}
+			ti := track.ToProto()
+			for _, receiver := range track.Receivers() {
+				ts.Codecs = append(ts.Codecs, getDashboardCodecStats(receiver, ti))
+			}
+			stats = append(stats, ts)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Identity != stats[j].Identity {
+			return stats[i].Identity < stats[j].Identity
+		}
+		return stats[i].TrackSid < stats[j].TrackSid
+	})
+	return stats
+}
+
+func getDashboardCodecStats(receiver sfu.TrackReceiver, ti *livekit.TrackInfo) *DashboardCodecStats {
+	if dr, ok := receiver.(*rtc.DummyReceiver); ok {
+		// codecs not published yet are not received
+		if receiver = dr.Receiver(); receiver == nil {
+			return &DashboardCodecStats{MimeType: dr.Codec().MimeType}
+		}
+	}
+
+	cs := &DashboardCodecStats{
+		MimeType: receiver.Codec().MimeType,
+	}
+	if r, ok := receiver.(interface{ GetTrackStats() *livekit.RTPStats }); ok {
+		if rtpStats := r.GetTrackStats(); rtpStats != nil {
+			cs.Bitrate = rtpStats.Bitrate
+			cs.Packets = rtpStats.Packets
+			cs.PacketsLost = rtpStats.PacketsLost
+			cs.PacketLossPercentage = rtpStats.PacketLossPercentage
+			cs.JitterMs = rtpStats.JitterCurrent / 1000
+		}
+	}
+	if ti.Type != livekit.TrackType_VIDEO {
+		return cs
+	}
+
+	// bitrates are cumulative over temporal layers, the highest received one is what a subscriber gets
+	_, bitrates := receiver.GetLayeredBitrate()
+	for spatial := range bitrates {
+		for temporal := len(bitrates[spatial]) - 1; temporal >= 0; temporal-- {
+			if bitrates[spatial][temporal] == 0 {
+				continue
+			}
+			quality := buffer.SpatialLayerToVideoQuality(int32(spatial), ti)
+			ls := &DashboardLayerStats{
+				Quality:        quality.String(),
+				Bitrate:        bitrates[spatial][temporal],
+				TemporalLayers: int32(temporal + 1),
+			}
+			for _, layer := range ti.Layers {
+				if layer.Quality == quality {
+					ls.Width, ls.Height = layer.Width, layer.Height
+				}
+			}
+			cs.Layers = append(cs.Layers, ls)
+			break
+		}
+	}
+	return cs
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/sfu"
+)
+
+type testStatsReceiver struct {
+	sfu.TrackReceiver
+	mimeType string
+	stats    *livekit.RTPStats
+	bitrates sfu.Bitrates
+}
+
+func (r *testStatsReceiver) Codec() webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: r.mimeType}}
+}
+
+func (r *testStatsReceiver) GetTrackStats() *livekit.RTPStats {
+	return r.stats
+}
+
+func (r *testStatsReceiver) GetLayeredBitrate() ([]int32, sfu.Bitrates) {
+	return nil, r.bitrates
+}
+
+func TestDashboardTrackStats(t *testing.T) {
+	video := &typesfakes.FakeMediaTrack{}
+	video.IDReturns("TR_video")
+	video.KindReturns(livekit.TrackType_VIDEO)
+	video.SourceReturns(livekit.TrackSource_CAMERA)
+	video.ToProtoReturns(&livekit.TrackInfo{
+		Sid:  "TR_video",
+		Type: livekit.TrackType_VIDEO,
+		Layers: []*livekit.VideoLayer{
+			{Quality: livekit.VideoQuality_LOW, Width: 320, Height: 180},
+			{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360},
+			{Quality: livekit.VideoQuality_HIGH, Width: 1280, Height: 720},
+		},
+	})
+	var bitrates sfu.Bitrates
+	bitrates[0] = [4]int64{50_000, 100_000, 150_000, 0}
+	// high layer is not being received
+	bitrates[1] = [4]int64{200_000, 400_000, 0, 0}
+	video.ReceiversReturns([]sfu.TrackReceiver{&testStatsReceiver{
+		mimeType: webrtc.MimeTypeVP8,
+		stats:    &livekit.RTPStats{Bitrate: 550_000, Packets: 1000, PacketsLost: 10, PacketLossPercentage: 1, JitterCurrent: 2500},
+		bitrates: bitrates,
+	}})
+
+	audio := &typesfakes.FakeMediaTrack{}
+	audio.IDReturns("TR_audio")
+	audio.KindReturns(livekit.TrackType_AUDIO)
+	audio.SourceReturns(livekit.TrackSource_MICROPHONE)
+	audio.IsMutedReturns(true)
+	audio.ToProtoReturns(&livekit.TrackInfo{Sid: "TR_audio", Type: livekit.TrackType_AUDIO})
+	audio.ReceiversReturns([]sfu.TrackReceiver{&testStatsReceiver{mimeType: webrtc.MimeTypeOpus}})
+
+	bob := &typesfakes.FakeLocalParticipant{}
+	bob.IdentityReturns("bob")
+	bob.GetPublishedTracksReturns([]types.MediaTrack{video, audio})
+	alice := &typesfakes.FakeLocalParticipant{}
+	alice.IdentityReturns("alice")
+
+	stats := getDashboardTrackStats([]types.LocalParticipant{bob, alice})
+	require.Len(t, stats, 2)
+
+	require.Equal(t, livekit.TrackID("TR_audio"), stats[0].TrackSid)
+	require.True(t, stats[0].Muted)
+	require.Len(t, stats[0].Codecs, 1)
+	require.Equal(t, webrtc.MimeTypeOpus, stats[0].Codecs[0].MimeType)
+	require.Empty(t, stats[0].Codecs[0].Layers)
+
+	require.Equal(t, &DashboardTrackStats{
+		Identity: "bob",
+		TrackSid: "TR_video",
+		Kind:     "VIDEO",
+		Source:   "CAMERA",
+		Codecs: []*DashboardCodecStats{{
+			MimeType:             webrtc.MimeTypeVP8,
+			Bitrate:              550_000,
+			Packets:              1000,
+			PacketsLost:          10,
+			PacketLossPercentage: 1,
+			JitterMs:             2.5,
+			Layers: []*DashboardLayerStats{
+				{Quality: "LOW", Width: 320, Height: 180, Bitrate: 150_000, TemporalLayers: 3},
+				{Quality: "MEDIUM", Width: 640, Height: 360, Bitrate: 400_000, TemporalLayers: 2},
+			},
+		}},
+	}, stats[1])
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const downlinkSimulatorPathPrefix = "/downlink_simulator/"
+
+var ErrInvalidDownlinkShape = errors.New("downlink bandwidth and step durations have to be positive")
+
+type DownlinkStep struct {
+	// bps
+	Bandwidth  int64 `json:"bandwidth"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+type SimulateDownlinkRequest struct {
+	Room string `json:"room"`
+	// the subscriber
+	Identity string `json:"identity"`
+	// caps the downlink at this bandwidth, in bps, when there are no steps
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+	// shapes the downlink, stepping through the bandwidths in turn. The last one holds unless looping
+	Steps []DownlinkStep `json:"steps,omitempty"`
+	Loop  bool           `json:"loop,omitempty"`
+}
+
+type ClearDownlinkRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+}
+
+// DownlinkSimulatorService overrides the downlink bandwidth estimate of a subscriber, so that client teams can
+// reproduce low bandwidth states on demand against a real server. Tracks of the subscriber are allocated as if the
+// estimate was the simulated bandwidth, the actual network is not throttled. Only served in development mode, by the
+// node hosting the room. Requests are JSON posted to /downlink_simulator/SimulateDownlink and ClearDownlink, which
+// require the roomAdmin grant for the room
+type DownlinkSimulatorService struct {
+	roomManager *RoomManager
+
+	lock    sync.Mutex
+	shapers map[livekit.RoomName]map[livekit.ParticipantIdentity]*downlinkShaper
+}
+
+type downlinkShaper struct {
+	participant types.LocalParticipant
+	steps       []DownlinkStep
+	loop        bool
+	stop        chan struct{}
+}
+
+func NewDownlinkSimulatorService(roomManager *RoomManager) *DownlinkSimulatorService {
+	return &DownlinkSimulatorService{
+		roomManager: roomManager,
+		shapers:     make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*downlinkShaper),
+	}
+}
+
+func (s *DownlinkSimulatorService) PathPrefix() string {
+	return downlinkSimulatorPathPrefix
+}
+
+func (s *DownlinkSimulatorService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		room livekit.RoomName
+		err  error
+	)
+	switch strings.TrimPrefix(r.URL.Path, downlinkSimulatorPathPrefix) {
+	case "SimulateDownlink":
+		req := &SimulateDownlinkRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+			handleError(w, http.StatusBadRequest, err)
+			return
+		}
+		room = livekit.RoomName(req.Room)
+		if err = EnsureAdminPermission(r.Context(), room); err != nil || req.Room == "" {
+			handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+			return
+		}
+		err = s.SimulateDownlink(r.Context(), req)
+	case "ClearDownlink":
+		req := &ClearDownlinkRequest{}
+		if err = json.NewDecoder(r.Body).Decode(req); err != nil {
+			handleError(w, http.StatusBadRequest, err)
+			return
+		}
+		room = livekit.RoomName(req.Room)
+		if err = EnsureAdminPermission(r.Context(), room); err != nil || req.Room == "" {
+			handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+			return
+		}
+		err = s.ClearDownlink(r.Context(), req)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidDownlinkShape):
+			handleError(w, http.StatusBadRequest, err, "room", room)
+		case errors.Is(err, ErrRoomNotFound), errors.Is(err, ErrParticipantNotFound):
+			handleError(w, http.StatusNotFound, err, "room", room)
+		default:
+			handleError(w, http.StatusInternalServerError, err, "room", room)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte("{}"))
+}
+
+// SimulateDownlink caps or shapes the downlink of a participant hosted on this node, replacing any simulation going on
+func (s *DownlinkSimulatorService) SimulateDownlink(ctx context.Context, req *SimulateDownlinkRequest) error {
+	steps := req.Steps
+	if len(steps) == 0 {
+		steps = []DownlinkStep{{Bandwidth: req.Bandwidth}}
+	}
+	for _, step := range steps {
+		// a single step is held, it needs no duration
+		if step.Bandwidth <= 0 || ((len(steps) > 1 || req.Loop) && step.DurationMs <= 0) {
+			return ErrInvalidDownlinkShape
+		}
+	}
+
+	participant, err := s.getParticipant(ctx, req.Room, req.Identity)
+	if err != nil {
+		return err
+	}
+
+	shaper := &downlinkShaper{
+		participant: participant,
+		steps:       steps,
+		loop:        req.Loop,
+		stop:        make(chan struct{}),
+	}
+	roomName := livekit.RoomName(req.Room)
+	s.lock.Lock()
+	shapers := s.shapers[roomName]
+	if shapers == nil {
+		shapers = make(map[livekit.ParticipantIdentity]*downlinkShaper)
+		s.shapers[roomName] = shapers
+	}
+	if previous := shapers[participant.Identity()]; previous != nil {
+		close(previous.stop)
+	}
+	shapers[participant.Identity()] = shaper
+	s.lock.Unlock()
+
+	logger.Infow("simulating subscriber downlink",
+		"room", req.Room,
+		"participant", participant.Identity(),
+		"steps", len(steps),
+		"loop", req.Loop,
+	)
+	go s.shape(roomName, shaper)
+	return nil
+}
+
+// ClearDownlink ends the simulation of the downlink of a participant, its tracks are allocated on its estimate again
+func (s *DownlinkSimulatorService) ClearDownlink(ctx context.Context, req *ClearDownlinkRequest) error {
+	participant, err := s.getParticipant(ctx, req.Room, req.Identity)
+	if err != nil {
+		return err
+	}
+
+	s.stopShaper(livekit.RoomName(req.Room), participant.Identity(), nil)
+	participant.SetSubscriberChannelCapacity(0)
+	logger.Infow("cleared subscriber downlink simulation", "room", req.Room, "participant", participant.Identity())
+	return nil
+}
+
+func (s *DownlinkSimulatorService) getParticipant(ctx context.Context, roomName string, identity string) (types.LocalParticipant, error) {
+	room := s.roomManager.GetRoom(ctx, livekit.RoomName(roomName))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(livekit.ParticipantIdentity(identity))
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+	return participant, nil
+}
+
+func (s *DownlinkSimulatorService) shape(roomName livekit.RoomName, shaper *downlinkShaper) {
+	defer s.stopShaper(roomName, shaper.participant.Identity(), shaper)
+
+	for {
+		for _, step := range shaper.steps {
+			if !s.applyStep(shaper, step) {
+				return
+			}
+			if step.DurationMs <= 0 {
+				// held till cleared
+				return
+			}
+
+			timer := time.NewTimer(time.Duration(step.DurationMs) * time.Millisecond)
+			select {
+			case <-shaper.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		if !shaper.loop {
+			return
+		}
+	}
+}
+
+// applyStep sets the bandwidth of a step unless the shaper was stopped, under the lock so that it cannot override the
+// bandwidth set by whoever stopped it
+func (s *DownlinkSimulatorService) applyStep(shaper *downlinkShaper, step DownlinkStep) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-shaper.stop:
+		return false
+	default:
+	}
+	if shaper.participant.State() == livekit.ParticipantInfo_DISCONNECTED {
+		return false
+	}
+	shaper.participant.SetSubscriberChannelCapacity(step.Bandwidth)
+	return true
+}
+
+// stopShaper stops the shaper of a participant, only when it is the given one unless nil
+func (s *DownlinkSimulatorService) stopShaper(roomName livekit.RoomName, identity livekit.ParticipantIdentity, shaper *downlinkShaper) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current := s.shapers[roomName][identity]
+	if current == nil || (shaper != nil && current != shaper) {
+		return
+	}
+	if shaper == nil {
+		close(current.stop)
+	}
+	delete(s.shapers[roomName], identity)
+	if len(s.shapers[roomName]) == 0 {
+		delete(s.shapers, roomName)
+	}
+}
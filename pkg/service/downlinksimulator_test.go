@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestDownlinkSimulatorService(t *testing.T) {
+	room := rtc.NewRoom(
+		&livekit.Room{Name: "room"},
+		nil,
+		rtc.WebRTCConfig{},
+		&config.AudioConfig{UpdateInterval: 500},
+		&livekit.ServerInfo{},
+		&telemetryfakes.FakeTelemetryService{},
+		nil,
+	)
+	defer room.Close()
+	svc := NewDownlinkSimulatorService(&RoomManager{
+		currentNode: &livekit.Node{Id: "node"},
+		rooms:       map[livekit.RoomName]*rtc.Room{"room": room},
+	})
+
+	p := &typesfakes.FakeLocalParticipant{}
+	p.IDReturns("PA_alice")
+	p.IdentityReturns("alice")
+	p.StateReturns(livekit.ParticipantInfo_ACTIVE)
+	p.ToProtoReturns(&livekit.ParticipantInfo{Sid: "PA_alice", Identity: "alice", State: livekit.ParticipantInfo_ACTIVE})
+	require.NoError(t, room.Join(p, nil, nil, nil))
+
+	request := func(method string, body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+method, strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+	lastChannelCapacity := func() int64 {
+		if p.SetSubscriberChannelCapacityCallCount() == 0 {
+			return -1
+		}
+		return p.SetSubscriberChannelCapacityArgsForCall(p.SetSubscriberChannelCapacityCallCount() - 1)
+	}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request("SimulateDownlink", `{"room": "room", "identity": "alice", "bandwidth": 100000}`, nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request("ClearDownlink", `{"room": "other", "identity": "alice"}`, admin).Code)
+	})
+
+	t.Run("invalid shape", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, request("SimulateDownlink", `{"room": "room", "identity": "alice"}`, admin).Code)
+		require.Equal(t, http.StatusBadRequest, request("SimulateDownlink", `{"room": "room", "identity": "alice", "steps": [{"bandwidth": 100000}, {"bandwidth": 200000}]}`, admin).Code)
+		require.Equal(t, http.StatusBadRequest, request("SimulateDownlink", `{"room": "room", "identity": "alice", "bandwidth": 100000, "loop": true}`, admin).Code)
+	})
+
+	t.Run("participant not found", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, request("SimulateDownlink", `{"room": "room", "identity": "bob", "bandwidth": 100000}`, admin).Code)
+	})
+
+	t.Run("caps the downlink", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request("SimulateDownlink", `{"room": "room", "identity": "alice", "bandwidth": 150000}`, admin).Code)
+		require.Eventually(t, func() bool {
+			return lastChannelCapacity() == 150_000
+		}, time.Second, 10*time.Millisecond)
+
+		require.Equal(t, http.StatusOK, request("ClearDownlink", `{"room": "room", "identity": "alice"}`, admin).Code)
+		require.Equal(t, int64(0), lastChannelCapacity())
+	})
+
+	t.Run("shapes the downlink", func(t *testing.T) {
+		body := `{"room": "room", "identity": "alice", "loop": true, "steps": [{"bandwidth": 300000, "duration_ms": 20}, {"bandwidth": 100000, "duration_ms": 20}]}`
+		require.Equal(t, http.StatusOK, request("SimulateDownlink", body, admin).Code)
+		require.Eventually(t, func() bool {
+			return lastChannelCapacity() == 100_000
+		}, time.Second, 5*time.Millisecond)
+		// loops back to the first step
+		require.Eventually(t, func() bool {
+			return lastChannelCapacity() == 300_000
+		}, time.Second, 5*time.Millisecond)
+
+		require.Equal(t, http.StatusOK, request("ClearDownlink", `{"room": "room", "identity": "alice"}`, admin).Code)
+		calls := p.SetSubscriberChannelCapacityCallCount()
+		time.Sleep(60 * time.Millisecond)
+		require.Equal(t, calls, p.SetSubscriberChannelCapacityCallCount())
+		require.Equal(t, int64(0), lastChannelCapacity())
+	})
+}
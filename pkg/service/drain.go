@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const drainPathPrefix = "/drain/"
+
+// DrainService puts the node serving the request into draining: no new rooms are placed on it and the rooms it
+// hosts are migrated to other nodes, their participants fully reconnecting and resuming there. Requests are JSON
+// posted to /drain/DrainNode or /drain/GetDrainStatus and need a token signed with an admin API key
+type DrainService struct {
+	roomManager *RoomManager
+}
+
+func NewDrainService(roomManager *RoomManager) *DrainService {
+	return &DrainService{
+		roomManager: roomManager,
+	}
+}
+
+func (s *DrainService) PathPrefix() string {
+	return drainPathPrefix
+}
+
+func (s *DrainService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := EnsureNodeAdminPermission(r.Context()); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, drainPathPrefix) {
+	case "DrainNode":
+		if err := s.roomManager.Drain(r.Context()); err != nil {
+			if errors.Is(err, ErrNoNodeToMigrateTo) {
+				handleError(w, http.StatusServiceUnavailable, err)
+			} else {
+				handleError(w, http.StatusInternalServerError, err)
+			}
+			return
+		}
+	case "GetDrainStatus":
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.roomManager.DrainStatus())
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const erasurePathPrefix = "/erasure/"
+
+// Nodes are asked to erase the data they keep about a participant with a RemoveParticipant message without a room,
+// marked by an extra field that nodes unaware of it skip:
+//
+//	RTCNodeMessage.erase_participant_data = 100;
+const rtcNodeMessageEraseParticipantDataField protowire.Number = 100
+
+type EraseParticipantDataRequest struct {
+	Identity string `json:"identity"`
+	// rooms to erase the participant from, all open rooms and rooms with a manifest when empty
+	Rooms []string `json:"rooms,omitempty"`
+}
+
+// ParticipantErasureReport is what was deleted for an identity
+type ParticipantErasureReport struct {
+	Identity string                    `json:"identity"`
+	ErasedAt time.Time                 `json:"erased_at"`
+	Rooms    []*RoomParticipantErasure `json:"rooms"`
+	// stats sessions dropped from the telemetry of the node that handled the request
+	TelemetrySessions int `json:"telemetry_sessions"`
+	// other nodes asked to drop the stats sessions of the participant
+	TelemetryNodes int      `json:"telemetry_nodes"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+type RoomParticipantErasure struct {
+	Room           string `json:"room"`
+	TimelineEvents int    `json:"timeline_events"`
+	// the participant was in the state mirrored for the standby node of the room
+	Mirrored bool `json:"mirrored,omitempty"`
+	// recordings of tracks the participant published, deleted from the storage bucket
+	Artifacts []*RoomArtifact `json:"artifacts,omitempty"`
+	Errors    []string        `json:"errors,omitempty"`
+}
+
+// ErasureService deletes what the stores know about a participant identity: it is removed from the mirrors of open
+// rooms, its bandwidth estimates and events are dropped, recordings of its tracks are deleted from the storage
+// bucket configured for retention, and every node drops the stats it keeps about it. Participants still in a room
+// would record new data when leaving, they have to be removed first, erasure is refused otherwise.
+// Requests are JSON posted to /erasure/EraseParticipantData and require a token signed with an admin API key
+type ErasureService struct {
+	currentNode   routing.LocalNode
+	roomStore     ObjectStore
+	router        routing.Router
+	egressStore   EgressStore
+	timelineStore RoomTimelineStore
+	manifestStore RoomManifestStore
+	uploader      *objectUploader
+	telemetry     telemetry.TelemetryService
+}
+
+func NewErasureService(
+	conf *config.Config,
+	currentNode routing.LocalNode,
+	roomStore ObjectStore,
+	router routing.Router,
+	egressStore EgressStore,
+	timelineStore RoomTimelineStore,
+	manifestStore RoomManifestStore,
+	ts telemetry.TelemetryService,
+) (*ErasureService, error) {
+	uploader, err := newObjectUploader(conf.Retention.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return &ErasureService{
+		currentNode:   currentNode,
+		roomStore:     roomStore,
+		router:        router,
+		egressStore:   egressStore,
+		timelineStore: timelineStore,
+		manifestStore: manifestStore,
+		uploader:      uploader,
+		telemetry:     ts,
+	}, nil
+}
+
+// EraseParticipantData deletes the data of the identity in the rooms, or in every room known to the stores
+func (s *ErasureService) EraseParticipantData(ctx context.Context, identity livekit.ParticipantIdentity, rooms []livekit.RoomName) (*ParticipantErasureReport, error) {
+	if identity == "" {
+		return nil, ErrIdentityEmpty
+	}
+	if len(rooms) == 0 {
+		var err error
+		if rooms, err = s.knownRooms(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// leaving records the session, stats and bandwidth estimate of the participant again
+	for _, roomName := range rooms {
+		if _, err := s.roomStore.LoadParticipant(ctx, roomName, identity); err == nil {
+			return nil, ErrParticipantActive
+		} else if err != ErrParticipantNotFound {
+			return nil, err
+		}
+	}
+
+	report := &ParticipantErasureReport{
+		Identity: string(identity),
+		ErasedAt: time.Now(),
+		Rooms:    []*RoomParticipantErasure{},
+	}
+	for _, roomName := range rooms {
+		erasure := s.eraseFromRoom(ctx, roomName, identity)
+		if erasure.Mirrored || erasure.TimelineEvents != 0 || len(erasure.Artifacts) != 0 || len(erasure.Errors) != 0 {
+			report.Rooms = append(report.Rooms, erasure)
+		}
+	}
+	report.TelemetrySessions = s.telemetry.EraseParticipant(identity)
+	s.eraseFromNodes(ctx, identity, report)
+
+	// the identity itself is not logged
+	logger.Infow("erased participant data", "rooms", len(report.Rooms), "telemetrySessions", report.TelemetrySessions, "telemetryNodes", report.TelemetryNodes)
+	return report, nil
+}
+
+// eraseFromNodes asks the other nodes to drop the stats they keep about the participant
+func (s *ErasureService) eraseFromNodes(ctx context.Context, identity livekit.ParticipantIdentity, report *ParticipantErasureReport) {
+	nodes, err := s.router.ListNodes()
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("nodes: %v", err))
+		return
+	}
+	for _, node := range nodes {
+		if node.Id == s.currentNode.Id {
+			continue
+		}
+		if err = s.router.WriteNodeRTC(ctx, node.Id, newEraseParticipantDataMessage(identity)); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("node %s: %v", node.Id, err))
+			continue
+		}
+		report.TelemetryNodes++
+	}
+}
+
+func newEraseParticipantDataMessage(identity livekit.ParticipantIdentity) *livekit.RTCNodeMessage {
+	msg := &livekit.RTCNodeMessage{
+		ParticipantKey:    string(routing.ParticipantKeyLegacy("", identity)),
+		ParticipantKeyB62: string(routing.ParticipantKey("", identity)),
+		Message: &livekit.RTCNodeMessage_RemoveParticipant{
+			RemoveParticipant: &livekit.RoomParticipantIdentity{Identity: string(identity)},
+		},
+	}
+	m := msg.ProtoReflect()
+	unknown := protowire.AppendTag(m.GetUnknown(), rtcNodeMessageEraseParticipantDataField, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, protowire.EncodeBool(true))
+	m.SetUnknown(unknown)
+	return msg
+}
+
+// isEraseParticipantDataMessage tells whether a node is asked to erase the data it keeps about a participant
+func isEraseParticipantDataMessage(msg *livekit.RTCNodeMessage) bool {
+	if msg.GetRemoveParticipant() == nil {
+		return false
+	}
+	unknown := msg.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return false
+		}
+		unknown = unknown[n:]
+
+		if num == rtcNodeMessageEraseParticipantDataField && typ == protowire.VarintType {
+			value, m := protowire.ConsumeVarint(unknown)
+			return m >= 0 && protowire.DecodeBool(value)
+		}
+
+		m := protowire.ConsumeFieldValue(num, typ, unknown)
+		if m < 0 {
+			return false
+		}
+		unknown = unknown[m:]
+	}
+	return false
+}
+
+func (s *ErasureService) knownRooms(ctx context.Context) ([]livekit.RoomName, error) {
+	var roomNames []livekit.RoomName
+	seen := make(map[livekit.RoomName]bool)
+	add := func(roomName livekit.RoomName) {
+		if !seen[roomName] {
+			seen[roomName] = true
+			roomNames = append(roomNames, roomName)
+		}
+	}
+
+	rooms, err := s.roomStore.ListRooms(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, rm := range rooms {
+		add(livekit.RoomName(rm.Name))
+	}
+	if s.manifestStore != nil {
+		manifests, err := s.manifestStore.ListRoomManifests(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range manifests {
+			add(livekit.RoomName(m.RoomName))
+		}
+	}
+	return roomNames, nil
+}
+
+func (s *ErasureService) eraseFromRoom(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) *RoomParticipantErasure {
+	erasure := &RoomParticipantErasure{Room: string(roomName)}
+	addError := func(what string, err error) {
+		erasure.Errors = append(erasure.Errors, fmt.Sprintf("%s: %v", what, err))
+	}
+
+	if err := s.roomStore.DeleteParticipantBandwidthEstimate(ctx, roomName, identity); err != nil {
+		addError("bandwidth estimate", err)
+	}
+	erasure.Mirrored = s.removeMirroredParticipant(ctx, roomName, identity, addError)
+
+	// tracks are only known from the timeline
+	trackIDs := make(map[string]bool)
+	if s.timelineStore != nil {
+		events, err := s.timelineStore.DeleteParticipantEvents(ctx, roomName, identity)
+		if err != nil {
+			addError("timeline", err)
+		}
+		erasure.TimelineEvents = len(events)
+		for _, ev := range events {
+			if ev.TrackSID != "" {
+				trackIDs[ev.TrackSID] = true
+			}
+		}
+	}
+
+	if len(trackIDs) != 0 && s.egressStore != nil && s.uploader != nil {
+		erasure.Artifacts = s.deleteTrackArtifacts(ctx, roomName, trackIDs, addError)
+		if len(erasure.Artifacts) != 0 {
+			s.removeManifestArtifacts(ctx, roomName, erasure.Artifacts, addError)
+		}
+	}
+	return erasure
+}
+
+// deleteTrackArtifacts deletes the output of track and track composite egress of the tracks
+func (s *ErasureService) deleteTrackArtifacts(ctx context.Context, roomName livekit.RoomName, trackIDs map[string]bool, addError func(string, error)) []*RoomArtifact {
+	infos, err := s.egressStore.ListEgress(ctx, roomName, false)
+	if err != nil {
+		addError("egress", err)
+		return nil
+	}
+
+	var deleted []*RoomArtifact
+	for _, info := range infos {
+		switch req := info.Request.(type) {
+		case *livekit.EgressInfo_Track:
+			if !trackIDs[req.Track.TrackId] {
+				continue
+			}
+		case *livekit.EgressInfo_TrackComposite:
+			if !trackIDs[req.TrackComposite.AudioTrackId] && !trackIDs[req.TrackComposite.VideoTrackId] {
+				continue
+			}
+		default:
+			// composites of the whole room are kept
+			continue
+		}
+
+		for _, a := range egressInfoArtifacts(info) {
+			if a.Kind != RoomArtifactRecording && a.Kind != RoomArtifactSegments {
+				continue
+			}
+			key, ok := s.uploader.ObjectKey(a.Location)
+			if !ok {
+				continue
+			}
+			if err = s.uploader.Delete(ctx, key); err != nil {
+				addError(a.Location, err)
+				continue
+			}
+			deleted = append(deleted, a)
+		}
+	}
+	return deleted
+}
+
+// removeMirroredParticipant drops the participant from the mirror of a high availability room, the node hosting the
+// room mirrors it again without the participant once it is removed
+func (s *ErasureService) removeMirroredParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, addError func(string, error)) bool {
+	mirror, err := s.roomStore.LoadRoomMirror(ctx, roomName)
+	if err != nil {
+		addError("mirror", err)
+		return false
+	}
+	if mirror == nil || mirror.Participants[identity] == nil {
+		return false
+	}
+
+	delete(mirror.Participants, identity)
+	if err = s.roomStore.StoreRoomMirror(ctx, roomName, mirror); err != nil {
+		addError("mirror", err)
+		return false
+	}
+	return true
+}
+
+func (s *ErasureService) removeManifestArtifacts(ctx context.Context, roomName livekit.RoomName, deleted []*RoomArtifact, addError func(string, error)) {
+	if s.manifestStore == nil {
+		return
+	}
+	manifests, err := s.manifestStore.ListRoomManifests(ctx)
+	if err != nil {
+		addError("manifest", err)
+		return
+	}
+
+	locations := make(map[string]bool, len(deleted))
+	for _, a := range deleted {
+		locations[a.Location] = true
+	}
+	// every session of the room has a manifest
+	for _, manifest := range manifests {
+		if manifest.RoomName != string(roomName) {
+			continue
+		}
+		artifacts := make([]*RoomArtifact, 0, len(manifest.Artifacts))
+		for _, a := range manifest.Artifacts {
+			if !locations[a.Location] {
+				artifacts = append(artifacts, a)
+			}
+		}
+		if len(artifacts) == len(manifest.Artifacts) {
+			continue
+		}
+
+		updated := *manifest
+		updated.Artifacts = artifacts
+		if err = s.manifestStore.StoreRoomManifest(ctx, &updated); err != nil {
+			addError("manifest", err)
+		}
+	}
+}
+
+func (s *ErasureService) PathPrefix() string {
+	return erasurePathPrefix
+}
+
+func (s *ErasureService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := EnsureNodeAdminPermission(r.Context()); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, erasurePathPrefix) != "EraseParticipantData" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	req := &EraseParticipantDataRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Identity == "" {
+		handleError(w, http.StatusBadRequest, ErrIdentityEmpty)
+		return
+	}
+	rooms := make([]livekit.RoomName, 0, len(req.Rooms))
+	for _, room := range req.Rooms {
+		rooms = append(rooms, livekit.RoomName(room))
+	}
+
+	report, err := s.EraseParticipantData(r.Context(), livekit.ParticipantIdentity(req.Identity), rooms)
+	if errors.Is(err, ErrParticipantActive) {
+		handleError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
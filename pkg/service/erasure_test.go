@@ -0,0 +1,189 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestEraseParticipantData(t *testing.T) {
+	var lock sync.Mutex
+	var deleted []string
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer bucket.Close()
+
+	conf := &config.Config{
+		Retention: config.RetentionConfig{
+			Storage: config.ObjectStorageConfig{
+				Endpoint:       bucket.URL,
+				Bucket:         "recordings",
+				ForcePathStyle: true,
+			},
+		},
+	}
+	ctx := context.Background()
+	roomStore := service.NewLocalStore()
+	timelineStore := service.NewLocalTimelineStore(config.TimelineConfig{})
+	manifestStore := service.NewLocalManifestStore(config.RoomManifestConfig{})
+	router := &routingfakes.FakeRouter{}
+	router.ListNodesReturns([]*livekit.Node{{Id: "ND_1"}, {Id: "ND_2"}}, nil)
+	ts := &telemetryfakes.FakeTelemetryService{}
+	ts.EraseParticipantReturns(1)
+
+	egressStore := &servicefakes.FakeEgressStore{}
+	egressStore.ListEgressReturns([]*livekit.EgressInfo{
+		{
+			EgressId:    "EG_1",
+			Request:     &livekit.EgressInfo_Track{Track: &livekit.TrackEgressRequest{TrackId: "TR_1"}},
+			FileResults: []*livekit.FileInfo{{Location: "s3://recordings/TR_1.ogg"}},
+		},
+		{
+			EgressId:    "EG_2",
+			Request:     &livekit.EgressInfo_RoomComposite{RoomComposite: &livekit.RoomCompositeEgressRequest{}},
+			FileResults: []*livekit.FileInfo{{Location: "s3://recordings/room.mp4"}},
+		},
+	}, nil)
+
+	// open room with the participant
+	require.NoError(t, roomStore.StoreRoom(ctx, &livekit.Room{Name: "room1"}, nil))
+	require.NoError(t, roomStore.StoreParticipant(ctx, "room1", &livekit.ParticipantInfo{Identity: "alice", Metadata: "email"}))
+	require.NoError(t, roomStore.StoreParticipant(ctx, "room1", &livekit.ParticipantInfo{Identity: "bob"}))
+	require.NoError(t, roomStore.StoreParticipantBandwidthEstimate(ctx, "room1", "alice", 1_000_000, time.Minute))
+	require.NoError(t, roomStore.StoreRoomMirror(ctx, "room1", &service.RoomMirror{
+		NodeID: "ND_1",
+		Participants: map[livekit.ParticipantIdentity]*service.MirroredParticipant{
+			"alice": {SID: "PA_alice"},
+			"bob":   {SID: "PA_bob"},
+		},
+	}))
+	now := time.Now()
+	require.NoError(t, timelineStore.StoreRoomEvents(ctx, "room1", []*service.RoomEvent{
+		{Type: service.RoomEventParticipantJoined, Time: now, Identity: "alice"},
+		{Type: service.RoomEventTrackPublished, Time: now.Add(time.Millisecond), Identity: "alice", TrackSID: "TR_1"},
+		{Type: service.RoomEventParticipantJoined, Time: now.Add(2 * time.Millisecond), Identity: "bob"},
+	}))
+	// closed room
+	require.NoError(t, manifestStore.StoreRoomManifest(ctx, &service.RoomManifest{
+		RoomName:   "room2",
+		FinishedAt: now,
+		Artifacts: []*service.RoomArtifact{
+			{Kind: service.RoomArtifactRecording, ID: "EG_1", Location: "s3://recordings/TR_1.ogg"},
+			{Kind: service.RoomArtifactRecording, ID: "EG_2", Location: "s3://recordings/room.mp4"},
+		},
+	}))
+	require.NoError(t, timelineStore.StoreRoomEvents(ctx, "room2", []*service.RoomEvent{
+		{Type: service.RoomEventTrackPublished, Time: now, Identity: "alice", TrackSID: "TR_1"},
+	}))
+
+	s, err := service.NewErasureService(conf, &livekit.Node{Id: "ND_1"}, roomStore, router, egressStore, timelineStore, manifestStore, ts)
+	require.NoError(t, err)
+
+	t.Run("api requires node admin", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/erasure/EraseParticipantData", strings.NewReader(`{"identity":"alice"}`))
+		r = r.WithContext(service.WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{RoomAdmin: true, Room: "room1"}}))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("participants in a room are not erased", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/erasure/EraseParticipantData", strings.NewReader(`{"identity":"alice"}`))
+		r = r.WithContext(service.WithNodeAdmin(service.WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{}})))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Zero(t, ts.EraseParticipantCallCount())
+		events, err := timelineStore.ListRoomEvents(ctx, "room1", time.Time{}, time.Time{}, 0)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+	})
+
+	t.Run("erase", func(t *testing.T) {
+		// once it left
+		require.NoError(t, roomStore.DeleteParticipant(ctx, "room1", "alice"))
+
+		r := httptest.NewRequest(http.MethodPost, "/erasure/EraseParticipantData", strings.NewReader(`{"identity":"alice"}`))
+		r = r.WithContext(service.WithNodeAdmin(service.WithGrants(ctx, &auth.ClaimGrants{Video: &auth.VideoGrant{}})))
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		report := &service.ParticipantErasureReport{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), report))
+		require.Equal(t, "alice", report.Identity)
+		require.Equal(t, 1, report.TelemetrySessions)
+		require.Equal(t, 1, report.TelemetryNodes)
+		require.Empty(t, report.Errors)
+		require.Len(t, report.Rooms, 2)
+
+		room1 := report.Rooms[0]
+		require.Equal(t, "room1", room1.Room)
+		require.True(t, room1.Mirrored)
+		require.Equal(t, 2, room1.TimelineEvents)
+		require.Len(t, room1.Artifacts, 1)
+		require.Empty(t, room1.Errors)
+
+		room2 := report.Rooms[1]
+		require.Equal(t, "room2", room2.Room)
+		require.Equal(t, 1, room2.TimelineEvents)
+
+		// only the track recording is deleted
+		require.Equal(t, []string{"/recordings/TR_1.ogg", "/recordings/TR_1.ogg"}, deleted)
+
+		// other nodes drop their stats of the participant
+		require.Equal(t, 1, router.WriteNodeRTCCallCount())
+		_, nodeID, msg := router.WriteNodeRTCArgsForCall(0)
+		require.Equal(t, "ND_2", nodeID)
+		require.Equal(t, "alice", msg.GetRemoveParticipant().Identity)
+		require.Empty(t, msg.GetRemoveParticipant().Room)
+
+		_, err := roomStore.LoadParticipant(ctx, "room1", "bob")
+		require.NoError(t, err)
+		estimate, err := roomStore.LoadParticipantBandwidthEstimate(ctx, "room1", "alice")
+		require.NoError(t, err)
+		require.Zero(t, estimate)
+		mirror, err := roomStore.LoadRoomMirror(ctx, "room1")
+		require.NoError(t, err)
+		require.Len(t, mirror.Participants, 1)
+		require.NotNil(t, mirror.Participants["bob"])
+
+		events, err := timelineStore.ListRoomEvents(ctx, "room1", time.Time{}, time.Time{}, 0)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, "bob", events[0].Identity)
+
+		manifest, err := manifestStore.LoadRoomManifest(ctx, "room2")
+		require.NoError(t, err)
+		require.Len(t, manifest.Artifacts, 1)
+		require.Equal(t, "EG_2", manifest.Artifacts[0].ID)
+	})
+
+	t.Run("nothing left", func(t *testing.T) {
+		report, err := s.EraseParticipantData(ctx, "alice", nil)
+		require.NoError(t, err)
+		require.Empty(t, report.Rooms)
+	})
+}
@@ -5,17 +5,30 @@ import (
 )
 
 var (
-	ErrEgressNotFound        = psrpc.NewErrorf(psrpc.NotFound, "egress does not exist")
-	ErrEgressNotConnected    = psrpc.NewErrorf(psrpc.Internal, "egress not connected (redis required)")
-	ErrIdentityEmpty         = psrpc.NewErrorf(psrpc.InvalidArgument, "identity cannot be empty")
-	ErrIngressNotConnected   = psrpc.NewErrorf(psrpc.Internal, "ingress not connected (redis required)")
-	ErrIngressNotFound       = psrpc.NewErrorf(psrpc.NotFound, "ingress does not exist")
-	ErrMetadataExceedsLimits = psrpc.NewErrorf(psrpc.InvalidArgument, "metadata size exceeds limits")
-	ErrOperationFailed       = psrpc.NewErrorf(psrpc.Internal, "operation cannot be completed")
-	ErrParticipantNotFound   = psrpc.NewErrorf(psrpc.NotFound, "participant does not exist")
-	ErrRoomNotFound          = psrpc.NewErrorf(psrpc.NotFound, "requested room does not exist")
-	ErrRoomLockFailed        = psrpc.NewErrorf(psrpc.Internal, "could not lock room")
-	ErrRoomUnlockFailed      = psrpc.NewErrorf(psrpc.Internal, "could not unlock room, lock token does not match")
-	ErrTrackNotFound         = psrpc.NewErrorf(psrpc.NotFound, "track is not found")
-	ErrWebHookMissingAPIKey  = psrpc.NewErrorf(psrpc.InvalidArgument, "api_key is required to use webhooks")
+	ErrEgressNotFound          = psrpc.NewErrorf(psrpc.NotFound, "egress does not exist")
+	ErrEgressNotConnected      = psrpc.NewErrorf(psrpc.Internal, "egress not connected (redis required)")
+	ErrIdentityBound           = psrpc.NewErrorf(psrpc.PermissionDenied, "identity is bound to another certificate fingerprint")
+	ErrIdentityEmpty           = psrpc.NewErrorf(psrpc.InvalidArgument, "identity cannot be empty")
+	ErrIngressNotConnected     = psrpc.NewErrorf(psrpc.Internal, "ingress not connected (redis required)")
+	ErrInvalidFingerprint      = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid certificate fingerprint")
+	ErrInvalidFingerprintProof = psrpc.NewErrorf(psrpc.Unauthenticated, "missing or invalid proof of holding the certificate of the fingerprint")
+	ErrInvalidJoinCode         = psrpc.NewErrorf(psrpc.PermissionDenied, "join code is missing or does not match")
+	ErrInvalidPresenceRequest  = psrpc.NewErrorf(psrpc.InvalidArgument, "room and counter name or watcher source are required, counts cannot be negative")
+	ErrInvalidTimelineRange    = psrpc.NewErrorf(psrpc.InvalidArgument, "invalid timeline range or limit")
+	ErrIngressNotFound         = psrpc.NewErrorf(psrpc.NotFound, "ingress does not exist")
+	ErrMetadataExceedsLimits   = psrpc.NewErrorf(psrpc.InvalidArgument, "metadata size exceeds limits")
+	ErrOperationFailed         = psrpc.NewErrorf(psrpc.Internal, "operation cannot be completed")
+	ErrParticipantActive       = psrpc.NewErrorf(psrpc.FailedPrecondition, "participant is in a room, remove it before erasing its data")
+	ErrParticipantNotFound     = psrpc.NewErrorf(psrpc.NotFound, "participant does not exist")
+	ErrPresenceNotEnabled      = psrpc.NewErrorf(psrpc.Unimplemented, "room presence is not enabled")
+	ErrRetentionNotEnabled     = psrpc.NewErrorf(psrpc.Unimplemented, "no retention policies are configured")
+	ErrRoomNotFound            = psrpc.NewErrorf(psrpc.NotFound, "requested room does not exist")
+	ErrRoomLockFailed          = psrpc.NewErrorf(psrpc.Internal, "could not lock room")
+	ErrRoomManifestNotEnabled  = psrpc.NewErrorf(psrpc.Unimplemented, "room manifest is not enabled")
+	ErrRoomManifestNotFound    = psrpc.NewErrorf(psrpc.NotFound, "no manifest for the room")
+	ErrRoomUnlockFailed        = psrpc.NewErrorf(psrpc.Internal, "could not unlock room, lock token does not match")
+	ErrTimelineNotEnabled      = psrpc.NewErrorf(psrpc.Unimplemented, "room timeline is not enabled")
+	ErrTooManyJoinCodeAttempts = psrpc.NewErrorf(psrpc.ResourceExhausted, "too many join code attempts, try again later")
+	ErrTrackNotFound           = psrpc.NewErrorf(psrpc.NotFound, "track is not found")
+	ErrWebHookMissingAPIKey    = psrpc.NewErrorf(psrpc.InvalidArgument, "api_key is required to use webhooks")
 )
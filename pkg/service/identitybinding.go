@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const defaultIdentityBindingWindow = 10 * time.Minute
+
+// IdentityBinding is the certificate fingerprint an identity is bound to in a room and the participant holding it
+type IdentityBinding struct {
+	Fingerprint   string
+	ParticipantID livekit.ParticipantID
+}
+
+// identityBindings ties participant identities to the DTLS certificate fingerprint the client that joined with them
+// proved holding the key of, so that a replayed token cannot be used from another device while the binding lasts.
+// Bindings are kept in the store, shared by the nodes a participant may join or resume on
+type identityBindings struct {
+	store  ObjectStore
+	window time.Duration
+}
+
+func newIdentityBindings(conf config.IdentityBindingConfig, store ObjectStore) *identityBindings {
+	if conf.Window <= 0 {
+		conf.Window = defaultIdentityBindingWindow
+	}
+	return &identityBindings{
+		store:  store,
+		window: conf.Window,
+	}
+}
+
+// check fails when the identity is bound to a fingerprint other than the declared one
+func (b *identityBindings) check(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, fingerprint string) error {
+	binding, err := b.store.LoadIdentityBinding(ctx, roomName, identity)
+	if err != nil {
+		return err
+	}
+	if binding != nil && binding.Fingerprint != fingerprint {
+		return ErrIdentityBound
+	}
+	return nil
+}
+
+// bind ties the identity to the fingerprint declared by the participant that joined with it
+func (b *identityBindings) bind(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, participantID livekit.ParticipantID, fingerprint string) error {
+	if fingerprint == "" {
+		return nil
+	}
+	return b.store.StoreIdentityBinding(ctx, roomName, identity, &IdentityBinding{
+		Fingerprint:   fingerprint,
+		ParticipantID: participantID,
+	}, 0)
+}
+
+// release starts the window of the binding held by a participant that is gone
+func (b *identityBindings) release(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, participantID livekit.ParticipantID) error {
+	binding, err := b.store.LoadIdentityBinding(ctx, roomName, identity)
+	// the identity may have been taken over by a new participant already
+	if err != nil || binding == nil || binding.ParticipantID != participantID {
+		return err
+	}
+	return b.store.StoreIdentityBinding(ctx, roomName, identity, binding, b.window)
+}
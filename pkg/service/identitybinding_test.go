@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestIdentityBindings(t *testing.T) {
+	ctx := context.Background()
+	b := newIdentityBindings(config.IdentityBindingConfig{Window: 50 * time.Millisecond}, NewLocalStore())
+
+	// nothing bound yet
+	require.NoError(t, b.check(ctx, "room", "alice", ""))
+	require.NoError(t, b.check(ctx, "room", "alice", "sha-256 AA"))
+
+	require.NoError(t, b.bind(ctx, "room", "alice", "PA_1", "sha-256 AA"))
+	require.NoError(t, b.check(ctx, "room", "alice", "sha-256 AA"))
+	require.ErrorIs(t, b.check(ctx, "room", "alice", "sha-256 BB"), ErrIdentityBound)
+	require.ErrorIs(t, b.check(ctx, "room", "alice", ""), ErrIdentityBound)
+	// bindings are per room
+	require.NoError(t, b.check(ctx, "other", "alice", "sha-256 BB"))
+
+	// a participant that does not hold the binding anymore does not release it
+	require.NoError(t, b.bind(ctx, "room", "alice", "PA_2", "sha-256 AA"))
+	require.NoError(t, b.release(ctx, "room", "alice", "PA_1"))
+	time.Sleep(100 * time.Millisecond)
+	require.ErrorIs(t, b.check(ctx, "room", "alice", "sha-256 BB"), ErrIdentityBound)
+
+	// held through the window after the participant is gone
+	require.NoError(t, b.release(ctx, "room", "alice", "PA_2"))
+	require.ErrorIs(t, b.check(ctx, "room", "alice", "sha-256 BB"), ErrIdentityBound)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, b.check(ctx, "room", "alice", "sha-256 BB"))
+
+	// a participant rejoining within the window holds the binding again
+	require.NoError(t, b.bind(ctx, "room", "alice", "PA_3", "sha-256 AA"))
+	require.NoError(t, b.release(ctx, "room", "alice", "PA_3"))
+	require.NoError(t, b.bind(ctx, "room", "alice", "PA_4", "sha-256 AA"))
+	time.Sleep(100 * time.Millisecond)
+	require.ErrorIs(t, b.check(ctx, "room", "alice", "sha-256 BB"), ErrIdentityBound)
+
+	// participants not declaring a fingerprint are not bound
+	require.NoError(t, b.bind(ctx, "room", "bob", "PA_5", ""))
+	require.NoError(t, b.check(ctx, "room", "bob", "sha-256 BB"))
+}
+
+func TestFingerprintNonces(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalStore()
+
+	require.NoError(t, s.StoreFingerprintNonce(ctx, "room", "alice", "n1", time.Minute))
+	// issued for another participant
+	issued, err := s.ConsumeFingerprintNonce(ctx, "room", "bob", "n1")
+	require.NoError(t, err)
+	require.False(t, issued)
+
+	issued, err = s.ConsumeFingerprintNonce(ctx, "room", "alice", "n1")
+	require.NoError(t, err)
+	require.True(t, issued)
+	// single use
+	issued, err = s.ConsumeFingerprintNonce(ctx, "room", "alice", "n1")
+	require.NoError(t, err)
+	require.False(t, issued)
+
+	require.NoError(t, s.StoreFingerprintNonce(ctx, "room", "alice", "n2", time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	issued, err = s.ConsumeFingerprintNonce(ctx, "room", "alice", "n2")
+	require.NoError(t, err)
+	require.False(t, issued)
+}
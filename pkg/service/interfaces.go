@@ -23,8 +23,47 @@ type ObjectStore interface {
 	StoreRoom(ctx context.Context, room *livekit.Room, internal *livekit.RoomInternal) error
 	DeleteRoom(ctx context.Context, roomName livekit.RoomName) error
 
+	// API key a room was created with, empty when unknown. removed with the room
+	StoreRoomAPIKey(ctx context.Context, roomName livekit.RoomName, apiKey string) error
+	LoadRoomAPIKey(ctx context.Context, roomName livekit.RoomName) (string, error)
+
+	// hash of the code participants of a room have to join with, empty clears it. removed with the room
+	StoreRoomJoinCode(ctx context.Context, roomName livekit.RoomName, codeHash string) error
+
+	// node taking a high availability room over should the node hosting it fail, empty clears it. removed with the room
+	StoreRoomStandbyNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error
+	// LoadRoomStandbyNode returns the standby node of a room, empty when it has none
+	LoadRoomStandbyNode(ctx context.Context, roomName livekit.RoomName) (livekit.NodeID, error)
+	// state of a high availability room mirrored for its standby node. removed with the room, not stored once the
+	// room is deleted
+	StoreRoomMirror(ctx context.Context, roomName livekit.RoomName, mirror *RoomMirror) error
+	// LoadRoomMirror returns the mirrored state of a room, nil when it has none
+	LoadRoomMirror(ctx context.Context, roomName livekit.RoomName) (*RoomMirror, error)
+
 	StoreParticipant(ctx context.Context, roomName livekit.RoomName, participant *livekit.ParticipantInfo) error
 	DeleteParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error
+
+	// downlink estimate of a participant, kept for ttl to start from when it reconnects
+	StoreParticipantBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, estimate int64, ttl time.Duration) error
+	// LoadParticipantBandwidthEstimate returns the downlink estimate of a participant, 0 when there is none
+	LoadParticipantBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (int64, error)
+	DeleteParticipantBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error
+
+	// secret the TURN credentials of a participant session are derived from, kept for ttl
+	StoreTURNSession(ctx context.Context, participantID livekit.ParticipantID, secret []byte, ttl time.Duration) error
+	// LoadTURNSession returns the TURN secret of a participant session, nil when it has none
+	LoadTURNSession(ctx context.Context, participantID livekit.ParticipantID) ([]byte, error)
+	DeleteTURNSession(ctx context.Context, participantID livekit.ParticipantID) error
+
+	// certificate fingerprint an identity is bound to in a room, kept for ttl, or until replaced when ttl is 0
+	StoreIdentityBinding(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, binding *IdentityBinding, ttl time.Duration) error
+	// LoadIdentityBinding returns the binding of an identity in a room, nil when it has none
+	LoadIdentityBinding(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*IdentityBinding, error)
+
+	// nonce a client joining a room signs to prove it holds the key of its certificate, kept for ttl
+	StoreFingerprintNonce(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string, ttl time.Duration) error
+	// ConsumeFingerprintNonce removes a nonce, returning whether it was issued for the identity in the room
+	ConsumeFingerprintNonce(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string) (bool, error)
 }
 
 //counterfeiter:generate . ServiceStore
@@ -34,8 +73,13 @@ type ServiceStore interface {
 	// ListRooms returns currently active rooms. if names is not nil, it'll filter and return
 	// only rooms that match
 	ListRooms(ctx context.Context, roomNames []livekit.RoomName) ([]*livekit.Room, error)
+	// ListRoomsPage returns a page of the active rooms matching opts, in their sort order, and the cursor of the next
+	// page, empty on the last one
+	ListRoomsPage(ctx context.Context, opts *ListRoomsOptions) ([]*livekit.Room, string, error)
 	LoadParticipant(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error)
 	ListParticipants(ctx context.Context, roomName livekit.RoomName) ([]*livekit.ParticipantInfo, error)
+	// LoadRoomJoinCode returns the hash of the join code of a room, empty when it has none
+	LoadRoomJoinCode(ctx context.Context, roomName livekit.RoomName) (string, error)
 }
 
 //counterfeiter:generate . EgressStore
@@ -44,6 +88,8 @@ type EgressStore interface {
 	LoadEgress(ctx context.Context, egressID string) (*livekit.EgressInfo, error)
 	ListEgress(ctx context.Context, roomName livekit.RoomName, active bool) ([]*livekit.EgressInfo, error)
 	UpdateEgress(ctx context.Context, info *livekit.EgressInfo) error
+	// SwapEgress updates an egress and atomically returns the info it replaced, nil when there was none
+	SwapEgress(ctx context.Context, info *livekit.EgressInfo) (*livekit.EgressInfo, error)
 }
 
 //counterfeiter:generate . IngressStore
@@ -57,6 +103,44 @@ type IngressStore interface {
 	DeleteIngress(ctx context.Context, info *livekit.IngressInfo) error
 }
 
+// persists the event timeline of rooms
+//
+//counterfeiter:generate . RoomTimelineStore
+type RoomTimelineStore interface {
+	StoreRoomEvents(ctx context.Context, roomName livekit.RoomName, events []*RoomEvent) error
+	// ListRoomEvents returns events recorded in [start, end) in the order they happened, a zero time leaves that side open
+	ListRoomEvents(ctx context.Context, roomName livekit.RoomName, start, end time.Time, limit int) ([]*RoomEvent, error)
+	DeleteRoomEvents(ctx context.Context, roomName livekit.RoomName) error
+	// DeleteParticipantEvents removes the events of a participant from the timeline and returns them
+	DeleteParticipantEvents(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) ([]*RoomEvent, error)
+}
+
+// persists manifests of closed rooms
+//
+//counterfeiter:generate . RoomManifestStore
+type RoomManifestStore interface {
+	// StoreRoomManifest stores the manifest of a session of a room, by room SID
+	StoreRoomManifest(ctx context.Context, manifest *RoomManifest) error
+	// LoadRoomManifest returns the manifest of the last closed session of the room
+	LoadRoomManifest(ctx context.Context, roomName livekit.RoomName) (*RoomManifest, error)
+	// ListRoomManifests returns the manifests of all sessions, oldest first
+	ListRoomManifests(ctx context.Context) ([]*RoomManifest, error)
+	DeleteRoomManifest(ctx context.Context, manifest *RoomManifest) error
+}
+
+// persists counters and reported watch counts of rooms
+//
+//counterfeiter:generate . RoomPresenceStore
+type RoomPresenceStore interface {
+	// IncrementRoomCounter adds delta to a counter of the room and returns the new value
+	IncrementRoomCounter(ctx context.Context, roomName livekit.RoomName, name string, delta int64) (int64, error)
+	// StoreRoomWatchers sets the watch count a source reports for the room, it expires after the ttl
+	StoreRoomWatchers(ctx context.Context, roomName livekit.RoomName, source string, count int64, ttl time.Duration) error
+	// LoadRoomCounters returns the counters of the room and the watch counts of sources which have not expired
+	LoadRoomCounters(ctx context.Context, roomName livekit.RoomName) (counters map[string]int64, watchers map[string]int64, err error)
+	DeleteRoomCounters(ctx context.Context, roomName livekit.RoomName) error
+}
+
 //counterfeiter:generate . RoomAllocator
 type RoomAllocator interface {
 	CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error)
@@ -7,6 +7,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/egress"
 	"github.com/livekit/protocol/livekit"
@@ -16,15 +17,17 @@ import (
 )
 
 type IOInfoService struct {
-	psrpcServer  rpc.IOInfoServer
-	es           EgressStore
-	is           IngressStore
-	telemetry    telemetry.TelemetryService
-	ecDeprecated egress.RPCClient
-	shutdown     chan struct{}
+	segmentEvents bool
+	psrpcServer   rpc.IOInfoServer
+	es            EgressStore
+	is            IngressStore
+	telemetry     telemetry.TelemetryService
+	ecDeprecated  egress.RPCClient
+	shutdown      chan struct{}
 }
 
 func NewIOInfoService(
+	conf *config.Config,
 	nodeID livekit.NodeID,
 	bus psrpc.MessageBus,
 	es EgressStore,
@@ -33,11 +36,12 @@ func NewIOInfoService(
 	ec egress.RPCClient,
 ) (*IOInfoService, error) {
 	s := &IOInfoService{
-		es:           es,
-		is:           is,
-		telemetry:    ts,
-		ecDeprecated: ec,
-		shutdown:     make(chan struct{}),
+		segmentEvents: conf.WebHook.SegmentEvents,
+		es:            es,
+		is:            is,
+		telemetry:     ts,
+		ecDeprecated:  ec,
+		shutdown:      make(chan struct{}),
 	}
 
 	if bus != nil {
@@ -67,7 +71,21 @@ func (s *IOInfoService) Start() error {
 }
 
 func (s *IOInfoService) UpdateEgressInfo(ctx context.Context, info *livekit.EgressInfo) (*emptypb.Empty, error) {
-	err := s.es.UpdateEgress(ctx, info)
+	var err error
+	if s.segmentEvents {
+		// updates of an egress may be handled by any node concurrently, the segments seen so far are those of the
+		// update replaced in the store
+		var previous *livekit.EgressInfo
+		if previous, err = s.es.SwapEgress(ctx, info); err == nil {
+			s.notifySegmentEvents(ctx, previous, info)
+		} else {
+			// segment events of this update are lost, the update itself is not
+			logger.Warnw("could not swap egress info", err, "egressID", info.EgressId)
+			err = s.es.UpdateEgress(ctx, info)
+		}
+	} else {
+		err = s.es.UpdateEgress(ctx, info)
+	}
 
 	switch info.Status {
 	case livekit.EgressStatus_EGRESS_ACTIVE:
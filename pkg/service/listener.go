@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+// apiListener is an HTTP server for the API bound to a single address
+type apiListener struct {
+	address   string
+	tlsConfig *tls.Config
+	server    *http.Server
+}
+
+func (l *apiListener) listen() (net.Listener, error) {
+	ln, err := net.Listen("tcp", l.address)
+	if err != nil {
+		return nil, err
+	}
+	if l.tlsConfig != nil {
+		ln = tls.NewListener(ln, l.tlsConfig)
+	}
+	return ln, nil
+}
+
+// NewListenerKeyProvider restricts a KeyProvider to the given API keys, all keys are accepted when empty
+func NewListenerKeyProvider(provider auth.KeyProvider, apiKeys []string) auth.KeyProvider {
+	if len(apiKeys) == 0 {
+		return provider
+	}
+	allowed := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		allowed[key] = true
+	}
+	return &listenerKeyProvider{
+		provider: provider,
+		allowed:  allowed,
+	}
+}
+
+type listenerKeyProvider struct {
+	provider auth.KeyProvider
+	allowed  map[string]bool
+}
+
+func (p *listenerKeyProvider) GetSecret(key string) string {
+	if !p.allowed[key] {
+		return ""
+	}
+	return p.provider.GetSecret(key)
+}
+
+func (p *listenerKeyProvider) NumKeys() int {
+	return len(p.allowed)
+}
+
+// NewListenerTLSConfig loads the certificates of a listener, returns nil when TLS isn't configured
+func NewListenerTLSConfig(conf config.ListenerTLSConfig) (*tls.Config, error) {
+	if conf.CertFile == "" && conf.KeyFile == "" {
+		if conf.ClientCAFile != "" {
+			return nil, errors.New("client_ca_file requires cert_file and key_file")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load listener certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		// websockets are upgraded from HTTP/1.1
+		NextProtos: []string{"http/1.1"},
+	}
+
+	if conf.ClientCAFile != "" {
+		ca, err := os.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificates found in client_ca_file")
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
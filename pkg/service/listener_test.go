@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestListenerKeyProvider(t *testing.T) {
+	provider := auth.NewFileBasedKeyProviderFromMap(map[string]string{
+		"internal": "internal_secret",
+		"external": "external_secret",
+	})
+
+	t.Run("all keys when empty", func(t *testing.T) {
+		p := service.NewListenerKeyProvider(provider, nil)
+		require.Equal(t, "internal_secret", p.GetSecret("internal"))
+		require.Equal(t, "external_secret", p.GetSecret("external"))
+	})
+
+	t.Run("restricted to allowed keys", func(t *testing.T) {
+		p := service.NewListenerKeyProvider(provider, []string{"internal"})
+		require.Equal(t, "internal_secret", p.GetSecret("internal"))
+		require.Empty(t, p.GetSecret("external"))
+		require.Equal(t, 1, p.NumKeys())
+	})
+}
+
+func TestListenerTLSConfig(t *testing.T) {
+	t.Run("plaintext", func(t *testing.T) {
+		tlsConfig, err := service.NewListenerTLSConfig(config.ListenerTLSConfig{})
+		require.NoError(t, err)
+		require.Nil(t, tlsConfig)
+	})
+
+	t.Run("client CA requires a certificate", func(t *testing.T) {
+		_, err := service.NewListenerTLSConfig(config.ListenerTLSConfig{ClientCAFile: "ca.pem"})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid certificate", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile := filepath.Join(dir, "cert.pem")
+		require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0600))
+		_, err := service.NewListenerTLSConfig(config.ListenerTLSConfig{CertFile: certFile, KeyFile: certFile})
+		require.Error(t, err)
+	})
+}
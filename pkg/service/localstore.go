@@ -17,6 +17,22 @@ type LocalStore struct {
 	roomInternal map[livekit.RoomName]*livekit.RoomInternal
 	// map of roomName => { identity: participant }
 	participants map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo
+	// map of roomName => API key the room was created with
+	apiKeys map[livekit.RoomName]string
+	// map of roomName => hash of the join code of the room
+	joinCodes map[livekit.RoomName]string
+	// map of roomName => standby node of the room
+	standbyNodes map[livekit.RoomName]livekit.NodeID
+	// map of roomName => mirrored state of the room
+	mirrors map[livekit.RoomName]*RoomMirror
+	// map of roomName/identity => downlink estimate of the participant
+	bandwidthEstimates map[participantKey]bandwidthEstimate
+	// map of participantID => TURN secret of the participant session
+	turnSessions map[livekit.ParticipantID]turnSession
+	// map of roomName/identity => certificate fingerprint the identity is bound to
+	identityBindings map[participantKey]storedIdentityBinding
+	// map of roomName/identity/nonce => expiry of the fingerprint nonce
+	fingerprintNonces map[participantNonceKey]time.Time
 
 	lock       sync.RWMutex
 	globalLock sync.Mutex
@@ -27,10 +43,49 @@ func NewLocalStore() *LocalStore {
 		rooms:        make(map[livekit.RoomName]*livekit.Room),
 		roomInternal: make(map[livekit.RoomName]*livekit.RoomInternal),
 		participants: make(map[livekit.RoomName]map[livekit.ParticipantIdentity]*livekit.ParticipantInfo),
+		apiKeys:      make(map[livekit.RoomName]string),
+		joinCodes:    make(map[livekit.RoomName]string),
+		standbyNodes: make(map[livekit.RoomName]livekit.NodeID),
+		mirrors:      make(map[livekit.RoomName]*RoomMirror),
 		lock:         sync.RWMutex{},
+
+		bandwidthEstimates: make(map[participantKey]bandwidthEstimate),
+		turnSessions:       make(map[livekit.ParticipantID]turnSession),
+		identityBindings:   make(map[participantKey]storedIdentityBinding),
+		fingerprintNonces:  make(map[participantNonceKey]time.Time),
 	}
 }
 
+type participantKey struct {
+	roomName livekit.RoomName
+	identity livekit.ParticipantIdentity
+}
+
+type bandwidthEstimate struct {
+	estimate  int64
+	expiresAt time.Time
+}
+
+type turnSession struct {
+	secret    []byte
+	expiresAt time.Time
+}
+
+type storedIdentityBinding struct {
+	binding IdentityBinding
+	// zero when the binding does not expire
+	expiresAt time.Time
+}
+
+func (b storedIdentityBinding) expired(now time.Time) bool {
+	return !b.expiresAt.IsZero() && now.After(b.expiresAt)
+}
+
+type participantNonceKey struct {
+	participantKey
+	nonce string
+}
+
 func (s *LocalStore) StoreRoom(_ context.Context, room *livekit.Room, internal *livekit.RoomInternal) error {
 	if room.CreationTime == 0 {
 		room.CreationTime = time.Now().Unix()
@@ -74,6 +129,14 @@ func (s *LocalStore) ListRooms(_ context.Context, roomNames []livekit.RoomName)
 	return rooms, nil
 }
 
+func (s *LocalStore) ListRoomsPage(ctx context.Context, opts *ListRoomsOptions) ([]*livekit.Room, string, error) {
+	rooms, err := s.ListRooms(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return paginateRooms(rooms, opts)
+}
+
 func (s *LocalStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName) error {
 	room, _, err := s.LoadRoom(ctx, roomName, false)
 	if err == ErrRoomNotFound {
@@ -88,6 +151,10 @@ func (s *LocalStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName)
 	delete(s.participants, livekit.RoomName(room.Name))
 	delete(s.rooms, livekit.RoomName(room.Name))
 	delete(s.roomInternal, livekit.RoomName(room.Name))
+	delete(s.apiKeys, livekit.RoomName(room.Name))
+	delete(s.joinCodes, livekit.RoomName(room.Name))
+	delete(s.standbyNodes, livekit.RoomName(room.Name))
+	delete(s.mirrors, livekit.RoomName(room.Name))
 	return nil
 }
 
@@ -102,6 +169,188 @@ func (s *LocalStore) UnlockRoom(_ context.Context, _ livekit.RoomName, _ string)
 	return nil
 }
 
+func (s *LocalStore) StoreRoomAPIKey(_ context.Context, roomName livekit.RoomName, apiKey string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.apiKeys[roomName] = apiKey
+	return nil
+}
+
+func (s *LocalStore) LoadRoomAPIKey(_ context.Context, roomName livekit.RoomName) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.apiKeys[roomName], nil
+}
+
+func (s *LocalStore) StoreRoomJoinCode(_ context.Context, roomName livekit.RoomName, codeHash string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if codeHash == "" {
+		delete(s.joinCodes, roomName)
+	} else {
+		s.joinCodes[roomName] = codeHash
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadRoomJoinCode(_ context.Context, roomName livekit.RoomName) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.joinCodes[roomName], nil
+}
+
+func (s *LocalStore) StoreRoomStandbyNode(_ context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if nodeID == "" {
+		delete(s.standbyNodes, roomName)
+	} else {
+		s.standbyNodes[roomName] = nodeID
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadRoomStandbyNode(_ context.Context, roomName livekit.RoomName) (livekit.NodeID, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.standbyNodes[roomName], nil
+}
+
+func (s *LocalStore) StoreRoomMirror(_ context.Context, roomName livekit.RoomName, mirror *RoomMirror) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.rooms[roomName] == nil {
+		return nil
+	}
+	s.mirrors[roomName] = mirror
+	return nil
+}
+
+func (s *LocalStore) LoadRoomMirror(_ context.Context, roomName livekit.RoomName) (*RoomMirror, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.mirrors[roomName], nil
+}
+
+func (s *LocalStore) StoreParticipantBandwidthEstimate(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, estimate int64, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	for key, be := range s.bandwidthEstimates {
+		if now.After(be.expiresAt) {
+			delete(s.bandwidthEstimates, key)
+		}
+	}
+	s.bandwidthEstimates[participantKey{roomName, identity}] = bandwidthEstimate{
+		estimate:  estimate,
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadParticipantBandwidthEstimate(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	be, ok := s.bandwidthEstimates[participantKey{roomName, identity}]
+	if !ok || time.Now().After(be.expiresAt) {
+		return 0, nil
+	}
+	return be.estimate, nil
+}
+
+func (s *LocalStore) DeleteParticipantBandwidthEstimate(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.bandwidthEstimates, participantKey{roomName, identity})
+	return nil
+}
+
+func (s *LocalStore) StoreTURNSession(_ context.Context, participantID livekit.ParticipantID, secret []byte, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	for id, ts := range s.turnSessions {
+		if now.After(ts.expiresAt) {
+			delete(s.turnSessions, id)
+		}
+	}
+	s.turnSessions[participantID] = turnSession{
+		secret:    secret,
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+func (s *LocalStore) LoadTURNSession(_ context.Context, participantID livekit.ParticipantID) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	ts, ok := s.turnSessions[participantID]
+	if !ok || time.Now().After(ts.expiresAt) {
+		return nil, nil
+	}
+	return ts.secret, nil
+}
+
+func (s *LocalStore) DeleteTURNSession(_ context.Context, participantID livekit.ParticipantID) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.turnSessions, participantID)
+	return nil
+}
+
+func (s *LocalStore) StoreIdentityBinding(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, binding *IdentityBinding, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	for key, b := range s.identityBindings {
+		if b.expired(now) {
+			delete(s.identityBindings, key)
+		}
+	}
+	b := storedIdentityBinding{binding: *binding}
+	if ttl > 0 {
+		b.expiresAt = now.Add(ttl)
+	}
+	s.identityBindings[participantKey{roomName, identity}] = b
+	return nil
+}
+
+func (s *LocalStore) LoadIdentityBinding(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*IdentityBinding, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	b, ok := s.identityBindings[participantKey{roomName, identity}]
+	if !ok || b.expired(time.Now()) {
+		return nil, nil
+	}
+	binding := b.binding
+	return &binding, nil
+}
+
+func (s *LocalStore) StoreFingerprintNonce(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	for key, expiresAt := range s.fingerprintNonces {
+		if now.After(expiresAt) {
+			delete(s.fingerprintNonces, key)
+		}
+	}
+	s.fingerprintNonces[participantNonceKey{participantKey{roomName, identity}, nonce}] = now.Add(ttl)
+	return nil
+}
+
+func (s *LocalStore) ConsumeFingerprintNonce(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := participantNonceKey{participantKey{roomName, identity}, nonce}
+	expiresAt, ok := s.fingerprintNonces[key]
+	if !ok {
+		return false, nil
+	}
+	delete(s.fingerprintNonces, key)
+	return !time.Now().After(expiresAt), nil
+}
+
 func (s *LocalStore) StoreParticipant(_ context.Context, roomName livekit.RoomName, participant *livekit.ParticipantInfo) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	logLevelPathPrefix = "/logging/"
+
+	// LogOverridesKey is a hash of room|identity => rtc.LogOverride, shared so that every node applies them
+	LogOverridesKey = "log_overrides"
+
+	defaultLogOverrideDuration = 10 * time.Minute
+	maxLogOverrideDuration     = time.Hour
+	logOverrideSyncInterval    = 5 * time.Second
+)
+
+var (
+	ErrInvalidLogLevel         = errors.New("level must be debug or info")
+	ErrInvalidLogLevelDuration = errors.New("duration must be positive and at most an hour")
+)
+
+type SetLogLevelRequest struct {
+	Room string `json:"room"`
+	// when set, only this participant is affected
+	Identity string `json:"identity,omitempty"`
+	// debug or info, empty clears the override
+	Level string `json:"level"`
+	// defaults to 10 minutes
+	DurationSeconds int64 `json:"duration_seconds,omitempty"`
+}
+
+type ListLogLevelsRequest struct {
+	Room string `json:"room"`
+}
+
+type ListLogLevelsResponse struct {
+	Overrides []*rtc.LogOverride `json:"overrides"`
+}
+
+// LogLevelService raises the log level of a room or participant for a limited time, including pion logs.
+// Requests are JSON posted to /logging/<Method> and require the roomAdmin grant for the room
+type LogLevelService struct {
+	rc       redis.UniversalClient
+	doneChan chan struct{}
+}
+
+func NewLogLevelService(conf *config.Config, rc redis.UniversalClient) (*LogLevelService, error) {
+	if err := rtc.InitLogOverrides(conf.Logging.Config); err != nil {
+		return nil, err
+	}
+	return &LogLevelService{
+		rc:       rc,
+		doneChan: make(chan struct{}),
+	}, nil
+}
+
+func (s *LogLevelService) PathPrefix() string {
+	return logLevelPathPrefix
+}
+
+// Start picks up overrides set through other nodes
+func (s *LogLevelService) Start() {
+	if s.rc == nil {
+		return
+	}
+	go s.syncWorker()
+}
+
+func (s *LogLevelService) Stop() {
+	select {
+	case <-s.doneChan:
+	default:
+		close(s.doneChan)
+	}
+}
+
+func (s *LogLevelService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, logLevelPathPrefix) {
+	case "SetLogLevel":
+		s.setLogLevel(w, r)
+	case "ListLogLevels":
+		s.listLogLevels(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *LogLevelService) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	req := &SetLogLevelRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil || req.Room == "" {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+	if req.Level != "" && req.Level != "debug" && req.Level != "info" {
+		handleError(w, http.StatusBadRequest, ErrInvalidLogLevel)
+		return
+	}
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration == 0 {
+		duration = defaultLogOverrideDuration
+	}
+	if duration < 0 || duration > maxLogOverrideDuration {
+		handleError(w, http.StatusBadRequest, ErrInvalidLogLevelDuration)
+		return
+	}
+
+	override := &rtc.LogOverride{
+		Room:      livekit.RoomName(req.Room),
+		Identity:  livekit.ParticipantIdentity(req.Identity),
+		Level:     req.Level,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	if req.Level == "" {
+		// expired overrides are removed
+		override.ExpiresAt = time.Time{}
+	}
+	if err := s.storeOverride(r.Context(), override); err != nil {
+		handleError(w, http.StatusInternalServerError, err, "room", req.Room)
+		return
+	}
+	rtc.AddLogOverride(override)
+	logger.Infow("log level override updated",
+		"room", override.Room,
+		"participant", override.Identity,
+		"level", override.Level,
+		"expiresAt", override.ExpiresAt,
+	)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *LogLevelService) listLogLevels(w http.ResponseWriter, r *http.Request) {
+	req := &ListLogLevelsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil || req.Room == "" {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	res := &ListLogLevelsResponse{Overrides: []*rtc.LogOverride{}}
+	for _, o := range rtc.GetLogOverrides() {
+		if o.Room == livekit.RoomName(req.Room) {
+			res.Overrides = append(res.Overrides, o)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *LogLevelService) storeOverride(ctx context.Context, override *rtc.LogOverride) error {
+	if s.rc == nil {
+		return nil
+	}
+
+	field := string(override.Room) + "|" + string(override.Identity)
+	if override.ExpiresAt.IsZero() {
+		return s.rc.HDel(ctx, LogOverridesKey, field).Err()
+	}
+	data, err := json.Marshal(override)
+	if err != nil {
+		return err
+	}
+	return s.rc.HSet(ctx, LogOverridesKey, field, data).Err()
+}
+
+func (s *LogLevelService) syncWorker() {
+	ticker := time.NewTicker(logOverrideSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.doneChan:
+			return
+		case <-ticker.C:
+			if err := s.syncOverrides(); err != nil {
+				logger.Warnw("could not sync log level overrides", err)
+			}
+		}
+	}
+}
+
+func (s *LogLevelService) syncOverrides() error {
+	ctx := context.Background()
+	data, err := s.rc.HGetAll(ctx, LogOverridesKey).Result()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	overrides := make([]*rtc.LogOverride, 0, len(data))
+	var expired []string
+	for field, value := range data {
+		o := &rtc.LogOverride{}
+		if err = json.Unmarshal([]byte(value), o); err != nil || !o.ExpiresAt.After(now) {
+			expired = append(expired, field)
+			continue
+		}
+		overrides = append(overrides, o)
+	}
+	if len(expired) != 0 {
+		s.rc.HDel(ctx, LogOverridesKey, expired...)
+	}
+	rtc.SetLogOverrides(overrides)
+	return nil
+}
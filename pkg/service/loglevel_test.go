@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestLogLevelService(t *testing.T) {
+	svc, err := service.NewLogLevelService(&config.Config{}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { rtc.SetLogOverrides(nil) })
+
+	request := func(method string, body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+method, strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request("SetLogLevel", `{"room": "room", "level": "debug"}`, nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request("SetLogLevel", `{"room": "other", "level": "debug"}`, admin).Code)
+	})
+
+	t.Run("validates", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, request("SetLogLevel", `{"room": "room", "level": "trace"}`, admin).Code)
+		require.Equal(t, http.StatusBadRequest, request("SetLogLevel", `{"room": "room", "level": "debug", "duration_seconds": 7200}`, admin).Code)
+	})
+
+	t.Run("sets and clears", func(t *testing.T) {
+		w := request("SetLogLevel", `{"room": "room", "identity": "alice", "level": "debug", "duration_seconds": 60}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = request("ListLogLevels", `{"room": "room"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &service.ListLogLevelsResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.Len(t, res.Overrides, 1)
+		require.EqualValues(t, "alice", res.Overrides[0].Identity)
+		require.Equal(t, "debug", res.Overrides[0].Level)
+
+		w = request("SetLogLevel", `{"room": "room", "identity": "alice"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, rtc.GetLogOverrides())
+	})
+}
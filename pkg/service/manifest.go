@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+type RoomArtifactKind string
+
+const (
+	RoomArtifactRecording RoomArtifactKind = "recording"
+	RoomArtifactSegments  RoomArtifactKind = "segments"
+	RoomArtifactStream    RoomArtifactKind = "stream"
+	RoomArtifactTimeline  RoomArtifactKind = "timeline"
+)
+
+const (
+	manifestPathPrefix = "/manifest/"
+
+	defaultManifestEgressWait   = 2 * time.Minute
+	manifestEgressCheckInterval = 2 * time.Second
+)
+
+// RoomManifest lists what a room produced, it's built once the room has closed and its egress has ended
+type RoomManifest struct {
+	RoomSID  string `json:"room_sid"`
+	RoomName string `json:"room_name"`
+	// API key the room was created with, when known
+	APIKey     string          `json:"api_key,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Artifacts  []*RoomArtifact `json:"artifacts"`
+	Usage      *RoomUsage      `json:"usage"`
+}
+
+type RoomArtifact struct {
+	Kind RoomArtifactKind `json:"kind"`
+	// egress id for recordings, segments and streams, room name for the timeline
+	ID string `json:"id"`
+	// storage URL of the file or playlist, stream URL, or API path of the timeline
+	Location  string    `json:"location"`
+	Status    string    `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	EndedAt   time.Time `json:"ended_at,omitempty"`
+}
+
+type RoomUsage struct {
+	DurationSeconds    int64 `json:"duration_seconds"`
+	Participants       int   `json:"participants"`
+	MaxParticipants    int   `json:"max_participants"`
+	ParticipantSeconds int64 `json:"participant_seconds"`
+	PublishedTracks    int   `json:"published_tracks"`
+	EgressSeconds      int64 `json:"egress_seconds"`
+}
+
+// RoomManifestRecorder tracks the usage of a room from participant updates, and builds the manifest when the room
+// closes
+type RoomManifestRecorder struct {
+	conf          config.RoomManifestConfig
+	store         RoomManifestStore
+	egressStore   EgressStore
+	timelineStore RoomTimelineStore
+	telemetry     telemetry.TelemetryService
+	logger        logger.Logger
+
+	lock               sync.Mutex
+	room               *livekit.Room
+	apiKey             string
+	startedAt          time.Time
+	active             map[livekit.ParticipantID]time.Time
+	seen               map[livekit.ParticipantID]struct{}
+	tracks             map[livekit.TrackID]struct{}
+	maxParticipants    int
+	participantSeconds time.Duration
+}
+
+func NewRoomManifestRecorder(
+	conf config.RoomManifestConfig,
+	store RoomManifestStore,
+	egressStore EgressStore,
+	timelineStore RoomTimelineStore,
+	ts telemetry.TelemetryService,
+	room *livekit.Room,
+	apiKey string,
+	l logger.Logger,
+) *RoomManifestRecorder {
+	startedAt := time.Now()
+	if room.CreationTime != 0 {
+		startedAt = time.Unix(room.CreationTime, 0)
+	}
+	return &RoomManifestRecorder{
+		conf:          conf,
+		store:         store,
+		egressStore:   egressStore,
+		timelineStore: timelineStore,
+		telemetry:     ts,
+		logger:        l,
+		room:          room,
+		apiKey:        apiKey,
+		startedAt:     startedAt,
+		active:        make(map[livekit.ParticipantID]time.Time),
+		seen:          make(map[livekit.ParticipantID]struct{}),
+		tracks:        make(map[livekit.TrackID]struct{}),
+	}
+}
+
+func (m *RoomManifestRecorder) ParticipantChanged(pi *livekit.ParticipantInfo) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pID := livekit.ParticipantID(pi.Sid)
+	for _, ti := range pi.Tracks {
+		m.tracks[livekit.TrackID(ti.Sid)] = struct{}{}
+	}
+
+	switch pi.State {
+	case livekit.ParticipantInfo_ACTIVE:
+		if _, ok := m.active[pID]; ok {
+			return
+		}
+		m.active[pID] = time.Now()
+		m.seen[pID] = struct{}{}
+		if len(m.active) > m.maxParticipants {
+			m.maxParticipants = len(m.active)
+		}
+	case livekit.ParticipantInfo_DISCONNECTED:
+		if activeAt, ok := m.active[pID]; ok {
+			m.participantSeconds += time.Since(activeAt)
+			delete(m.active, pID)
+		}
+	}
+}
+
+// Close builds the manifest once egress of the room has ended, stores it and sends the room_manifest webhook
+func (m *RoomManifestRecorder) Close(room *livekit.Room) {
+	finishedAt := time.Now()
+
+	m.lock.Lock()
+	m.room = room
+	for pID, activeAt := range m.active {
+		m.participantSeconds += finishedAt.Sub(activeAt)
+		delete(m.active, pID)
+	}
+	usage := &RoomUsage{
+		DurationSeconds:    int64(finishedAt.Sub(m.startedAt).Seconds()),
+		Participants:       len(m.seen),
+		MaxParticipants:    m.maxParticipants,
+		ParticipantSeconds: int64(m.participantSeconds.Seconds()),
+		PublishedTracks:    len(m.tracks),
+	}
+	m.lock.Unlock()
+
+	manifest := &RoomManifest{
+		RoomSID:    room.Sid,
+		RoomName:   room.Name,
+		APIKey:     m.apiKey,
+		StartedAt:  m.startedAt,
+		FinishedAt: finishedAt,
+		Artifacts:  []*RoomArtifact{},
+		Usage:      usage,
+	}
+	go m.finish(manifest)
+}
+
+func (m *RoomManifestRecorder) finish(manifest *RoomManifest) {
+	ctx := context.Background()
+
+	egressArtifacts, egressSeconds := m.egressArtifacts(ctx)
+	manifest.Artifacts = append(manifest.Artifacts, egressArtifacts...)
+	manifest.Usage.EgressSeconds = egressSeconds
+	if m.timelineStore != nil {
+		manifest.Artifacts = append(manifest.Artifacts, &RoomArtifact{
+			Kind:      RoomArtifactTimeline,
+			ID:        manifest.RoomName,
+			Location:  timelinePathPrefix + "GetRoomTimeline",
+			StartedAt: manifest.StartedAt,
+			EndedAt:   manifest.FinishedAt,
+		})
+	}
+
+	if err := m.store.StoreRoomManifest(ctx, manifest); err != nil {
+		m.logger.Warnw("could not store room manifest", err)
+	}
+	m.telemetry.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
+		Event: telemetry.EventRoomManifest,
+		Room:  m.room,
+	}, map[string]interface{}{"manifest": manifest})
+}
+
+// egressArtifacts waits for egress of the room to end and returns their results
+func (m *RoomManifestRecorder) egressArtifacts(ctx context.Context) ([]*RoomArtifact, int64) {
+	if m.egressStore == nil {
+		return nil, 0
+	}
+	roomName := livekit.RoomName(m.room.Name)
+
+	wait := m.conf.EgressWait
+	if wait <= 0 {
+		wait = defaultManifestEgressWait
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		active, err := m.egressStore.ListEgress(ctx, roomName, true)
+		if err != nil {
+			m.logger.Warnw("could not list egress for room manifest", err)
+			break
+		}
+		if !hasRoomEgress(active, m.room.Sid) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(manifestEgressCheckInterval)
+	}
+
+	infos, err := m.egressStore.ListEgress(ctx, roomName, false)
+	if err != nil {
+		m.logger.Warnw("could not list egress for room manifest", err)
+		return nil, 0
+	}
+
+	var artifacts []*RoomArtifact
+	var egressSeconds int64
+	for _, info := range infos {
+		// egress of earlier sessions of the room
+		if info.RoomId != m.room.Sid {
+			continue
+		}
+		if info.StartedAt != 0 {
+			endedAt := time.Now().UnixNano()
+			if info.EndedAt != 0 {
+				endedAt = info.EndedAt
+			}
+			egressSeconds += int64(time.Duration(endedAt - info.StartedAt).Seconds())
+		}
+		artifacts = append(artifacts, egressInfoArtifacts(info)...)
+	}
+	return artifacts, egressSeconds
+}
+
+func hasRoomEgress(infos []*livekit.EgressInfo, roomSID string) bool {
+	for _, info := range infos {
+		if info.RoomId == roomSID {
+			return true
+		}
+	}
+	return false
+}
+
+func egressInfoArtifacts(info *livekit.EgressInfo) []*RoomArtifact {
+	newArtifact := func(kind RoomArtifactKind, location string, size int64, startedAt int64, endedAt int64) *RoomArtifact {
+		a := &RoomArtifact{
+			Kind:     kind,
+			ID:       info.EgressId,
+			Location: location,
+			Status:   info.Status.String(),
+			Error:    info.Error,
+			Size:     size,
+		}
+		if startedAt != 0 {
+			a.StartedAt = time.Unix(0, startedAt)
+		}
+		if endedAt != 0 {
+			a.EndedAt = time.Unix(0, endedAt)
+		}
+		return a
+	}
+
+	var artifacts []*RoomArtifact
+	for _, f := range info.FileResults {
+		artifacts = append(artifacts, newArtifact(RoomArtifactRecording, f.Location, f.Size, f.StartedAt, f.EndedAt))
+	}
+	for _, s := range info.SegmentResults {
+		artifacts = append(artifacts, newArtifact(RoomArtifactSegments, s.PlaylistLocation, s.Size, s.StartedAt, s.EndedAt))
+	}
+	for _, s := range info.StreamResults {
+		artifacts = append(artifacts, newArtifact(RoomArtifactStream, s.Url, 0, s.StartedAt, s.EndedAt))
+	}
+	if len(artifacts) == 0 {
+		// still running or failed before producing output
+		artifacts = append(artifacts, newArtifact(RoomArtifactRecording, "", 0, info.StartedAt, info.EndedAt))
+	}
+	return artifacts
+}
+
+// ------------------------------------------------
+
+type GetRoomManifestRequest struct {
+	Room string `json:"room"`
+}
+
+// ManifestService serves the manifest of the last closed session of a room. Requests are JSON posted to
+// /manifest/<Method> and require the roomAdmin grant for the room
+type ManifestService struct {
+	store RoomManifestStore
+}
+
+func NewManifestService(store RoomManifestStore) *ManifestService {
+	return &ManifestService{
+		store: store,
+	}
+}
+
+func (s *ManifestService) PathPrefix() string {
+	return manifestPathPrefix
+}
+
+func (s *ManifestService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &GetRoomManifestRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	roomName := livekit.RoomName(req.Room)
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	if strings.TrimPrefix(r.URL.Path, manifestPathPrefix) != "GetRoomManifest" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrRoomManifestNotEnabled)
+		return
+	}
+
+	manifest, err := s.store.LoadRoomManifest(r.Context(), roomName)
+	if err == ErrRoomManifestNotFound {
+		handleError(w, http.StatusNotFound, err, "room", roomName)
+		return
+	} else if err != nil {
+		handleError(w, http.StatusInternalServerError, err, "room", roomName)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(manifest)
+}
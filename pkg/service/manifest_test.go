@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestRoomManifest(t *testing.T) {
+	store := service.NewLocalManifestStore(config.RoomManifestConfig{})
+	ts := &telemetryfakes.FakeTelemetryService{}
+	room := &livekit.Room{Sid: "RM_1", Name: "room", CreationTime: time.Now().Add(-time.Minute).Unix()}
+
+	egressStore := &servicefakes.FakeEgressStore{}
+	egressStore.ListEgressStub = func(_ context.Context, _ livekit.RoomName, active bool) ([]*livekit.EgressInfo, error) {
+		if active {
+			return nil, nil
+		}
+		startedAt := time.Now().Add(-30 * time.Second).UnixNano()
+		return []*livekit.EgressInfo{
+			{
+				EgressId:  "EG_1",
+				RoomId:    "RM_1",
+				Status:    livekit.EgressStatus_EGRESS_COMPLETE,
+				StartedAt: startedAt,
+				EndedAt:   time.Now().UnixNano(),
+				FileResults: []*livekit.FileInfo{
+					{Location: "s3://recordings/room.mp4", Size: 1000, StartedAt: startedAt, EndedAt: time.Now().UnixNano()},
+				},
+			},
+			// earlier session of the room
+			{EgressId: "EG_0", RoomId: "RM_0", Status: livekit.EgressStatus_EGRESS_COMPLETE},
+		}, nil
+	}
+
+	timelineStore := service.NewLocalTimelineStore(config.TimelineConfig{})
+	recorder := service.NewRoomManifestRecorder(config.RoomManifestConfig{}, store, egressStore, timelineStore, ts, room, "key1", logger.GetLogger())
+
+	track := &livekit.TrackInfo{Sid: "TR_1"}
+	recorder.ParticipantChanged(&livekit.ParticipantInfo{Sid: "PA_1", State: livekit.ParticipantInfo_JOINING})
+	recorder.ParticipantChanged(&livekit.ParticipantInfo{Sid: "PA_1", State: livekit.ParticipantInfo_ACTIVE, Tracks: []*livekit.TrackInfo{track}})
+	recorder.ParticipantChanged(&livekit.ParticipantInfo{Sid: "PA_2", State: livekit.ParticipantInfo_ACTIVE})
+	recorder.ParticipantChanged(&livekit.ParticipantInfo{Sid: "PA_1", State: livekit.ParticipantInfo_DISCONNECTED, Tracks: []*livekit.TrackInfo{track}})
+	recorder.Close(room)
+
+	require.Eventually(t, func() bool {
+		return ts.NotifyEventWithFieldsCallCount() == 1
+	}, time.Second, 10*time.Millisecond)
+	_, event, fields := ts.NotifyEventWithFieldsArgsForCall(0)
+	require.Equal(t, telemetry.EventRoomManifest, event.Event)
+	manifest := fields["manifest"].(*service.RoomManifest)
+
+	require.Equal(t, "RM_1", manifest.RoomSID)
+	require.Equal(t, "key1", manifest.APIKey)
+	require.Len(t, manifest.Artifacts, 2)
+	require.Equal(t, service.RoomArtifactRecording, manifest.Artifacts[0].Kind)
+	require.Equal(t, "EG_1", manifest.Artifacts[0].ID)
+	require.Equal(t, "s3://recordings/room.mp4", manifest.Artifacts[0].Location)
+	require.EqualValues(t, 1000, manifest.Artifacts[0].Size)
+	require.Equal(t, service.RoomArtifactTimeline, manifest.Artifacts[1].Kind)
+
+	require.InDelta(t, 60, manifest.Usage.DurationSeconds, 2)
+	require.Equal(t, 2, manifest.Usage.Participants)
+	require.Equal(t, 2, manifest.Usage.MaxParticipants)
+	require.Equal(t, 1, manifest.Usage.PublishedTracks)
+	require.InDelta(t, 30, manifest.Usage.EgressSeconds, 2)
+
+	t.Run("api", func(t *testing.T) {
+		svc := service.NewManifestService(store)
+		request := func(body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/manifest/GetRoomManifest", strings.NewReader(body))
+			r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+			w := httptest.NewRecorder()
+			svc.ServeHTTP(w, r)
+			return w
+		}
+
+		w := request(`{"room": "room"}`, &auth.VideoGrant{RoomAdmin: true, Room: "other"})
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = request(`{"room": "other"}`, &auth.VideoGrant{RoomAdmin: true, Room: "other"})
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		w = request(`{"room": "room"}`, &auth.VideoGrant{RoomAdmin: true, Room: "room"})
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &service.RoomManifest{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.Equal(t, "RM_1", res.RoomSID)
+		require.Len(t, res.Artifacts, 2)
+	})
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// RoomManifestPrefix is the RoomManifest JSON of a closed session of a room, by room SID
+	RoomManifestPrefix = "room_manifest:"
+	// RoomManifestsKey is a sorted set of SIDs of rooms with a manifest, scored by when the room finished
+	RoomManifestsKey = "room_manifests"
+	// RoomManifestSessionsPrefix is a sorted set of SIDs of the sessions of a room with a manifest, scored by when the
+	// session finished
+	RoomManifestSessionsPrefix = "room_manifest_sessions:"
+
+	defaultManifestRetention = 7 * 24 * time.Hour
+)
+
+func manifestRetention(conf config.RoomManifestConfig) time.Duration {
+	if conf.Retention <= 0 {
+		return defaultManifestRetention
+	}
+	return conf.Retention
+}
+
+// manifestID is the key of a manifest, sessions of a room with the same name have different SIDs
+func manifestID(m *RoomManifest) string {
+	if m.RoomSID != "" {
+		return m.RoomSID
+	}
+	return m.RoomName
+}
+
+// LocalManifestStore keeps room manifests in memory, for single node deployments
+type LocalManifestStore struct {
+	retention time.Duration
+
+	lock sync.Mutex
+	// by room SID
+	manifests map[string]*RoomManifest
+}
+
+func NewLocalManifestStore(conf config.RoomManifestConfig) *LocalManifestStore {
+	return &LocalManifestStore{
+		retention: manifestRetention(conf),
+		manifests: make(map[string]*RoomManifest),
+	}
+}
+
+func (s *LocalManifestStore) StoreRoomManifest(_ context.Context, manifest *RoomManifest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.manifests[manifestID(manifest)] = manifest
+
+	expiry := time.Now().Add(-s.retention)
+	for id, m := range s.manifests {
+		if m.FinishedAt.Before(expiry) {
+			delete(s.manifests, id)
+		}
+	}
+	return nil
+}
+
+func (s *LocalManifestStore) LoadRoomManifest(_ context.Context, roomName livekit.RoomName) (*RoomManifest, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var latest *RoomManifest
+	for _, m := range s.manifests {
+		if m.RoomName == string(roomName) && (latest == nil || m.FinishedAt.After(latest.FinishedAt)) {
+			latest = m
+		}
+	}
+	if latest == nil || latest.FinishedAt.Before(time.Now().Add(-s.retention)) {
+		return nil, ErrRoomManifestNotFound
+	}
+	return latest, nil
+}
+
+func (s *LocalManifestStore) ListRoomManifests(_ context.Context) ([]*RoomManifest, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	expiry := time.Now().Add(-s.retention)
+	manifests := make([]*RoomManifest, 0, len(s.manifests))
+	for _, m := range s.manifests {
+		if !m.FinishedAt.Before(expiry) {
+			manifests = append(manifests, m)
+		}
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].FinishedAt.Before(manifests[j].FinishedAt)
+	})
+	return manifests, nil
+}
+
+func (s *LocalManifestStore) DeleteRoomManifest(_ context.Context, manifest *RoomManifest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.manifests, manifestID(manifest))
+	return nil
+}
+
+// RedisManifestStore keeps room manifests in redis, so that they are available from any node
+type RedisManifestStore struct {
+	rc        redis.UniversalClient
+	retention time.Duration
+}
+
+func NewRedisManifestStore(rc redis.UniversalClient, conf config.RoomManifestConfig) *RedisManifestStore {
+	return &RedisManifestStore{
+		rc:        rc,
+		retention: manifestRetention(conf),
+	}
+}
+
+func (s *RedisManifestStore) StoreRoomManifest(ctx context.Context, manifest *RoomManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	id := manifestID(manifest)
+	sessionsKey := RoomManifestSessionsPrefix + manifest.RoomName
+	// manifests finished before this are expired
+	expired := "(" + strconv.FormatInt(time.Now().Add(-s.retention).Unix(), 10)
+	z := redis.Z{Score: float64(manifest.FinishedAt.Unix()), Member: id}
+
+	pp := s.rc.Pipeline()
+	pp.Set(ctx, RoomManifestPrefix+id, data, s.retention)
+	pp.ZAdd(ctx, RoomManifestsKey, z)
+	pp.ZRemRangeByScore(ctx, RoomManifestsKey, "-inf", expired)
+	pp.ZAdd(ctx, sessionsKey, z)
+	pp.ZRemRangeByScore(ctx, sessionsKey, "-inf", expired)
+	pp.Expire(ctx, sessionsKey, s.retention)
+	_, err = pp.Exec(ctx)
+	return err
+}
+
+func (s *RedisManifestStore) LoadRoomManifest(ctx context.Context, roomName livekit.RoomName) (*RoomManifest, error) {
+	// latest session first
+	ids, err := s.rc.ZRevRange(ctx, RoomManifestSessionsPrefix+string(roomName), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		manifest, err := s.loadRoomManifest(ctx, id)
+		if err == ErrRoomManifestNotFound {
+			continue
+		}
+		return manifest, err
+	}
+	return nil, ErrRoomManifestNotFound
+}
+
+func (s *RedisManifestStore) loadRoomManifest(ctx context.Context, id string) (*RoomManifest, error) {
+	data, err := s.rc.Get(ctx, RoomManifestPrefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, ErrRoomManifestNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	manifest := &RoomManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (s *RedisManifestStore) ListRoomManifests(ctx context.Context) ([]*RoomManifest, error) {
+	ids, err := s.rc.ZRange(ctx, RoomManifestsKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]*RoomManifest, 0, len(ids))
+	for _, id := range ids {
+		manifest, err := s.loadRoomManifest(ctx, id)
+		if err == ErrRoomManifestNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+func (s *RedisManifestStore) DeleteRoomManifest(ctx context.Context, manifest *RoomManifest) error {
+	id := manifestID(manifest)
+	pp := s.rc.Pipeline()
+	pp.Del(ctx, RoomManifestPrefix+id)
+	pp.ZRem(ctx, RoomManifestsKey, id)
+	pp.ZRem(ctx, RoomManifestSessionsPrefix+manifest.RoomName, id)
+	_, err := pp.Exec(ctx)
+	return err
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const objectUploadTimeout = time.Minute
+
+// objectUploader puts objects into an S3 compatible bucket, requests are signed with AWS signature version 4
+type objectUploader struct {
+	conf     config.ObjectStorageConfig
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func newObjectUploader(conf config.ObjectStorageConfig) (*objectUploader, error) {
+	if conf.Bucket == "" {
+		return nil, nil
+	}
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	endpoint := conf.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", conf.Region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint %s", endpoint)
+	}
+	return &objectUploader{
+		conf:     conf,
+		endpoint: u,
+		client:   &http.Client{Timeout: objectUploadTimeout},
+	}, nil
+}
+
+// Upload stores data under the prefix and returns the location of the object
+func (u *objectUploader) Upload(ctx context.Context, key string, contentType string, data []byte) (string, error) {
+	key = u.conf.Prefix + key
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if err = u.do(req, data); err != nil {
+		return "", fmt.Errorf("upload failed: %w", err)
+	}
+	return fmt.Sprintf("s3://%s/%s", u.conf.Bucket, key), nil
+}
+
+// Delete removes the object with the key, the prefix is not added
+func (u *objectUploader) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	if err = u.do(req, nil); err != nil {
+		return fmt.Errorf("delete failed: %w", err)
+	}
+	return nil
+}
+
+// ObjectKey returns the key of an object in the bucket from its location, either s3://<bucket>/<key> or a URL on
+// the endpoint. ok is false for locations outside the bucket
+func (u *objectUploader) ObjectKey(location string) (key string, ok bool) {
+	l, err := url.Parse(location)
+	if err != nil {
+		return "", false
+	}
+	path := strings.TrimPrefix(l.Path, "/")
+	switch {
+	case l.Scheme == "s3":
+		if l.Host != u.conf.Bucket {
+			return "", false
+		}
+	case l.Host == u.conf.Bucket+"."+u.endpoint.Host:
+	case l.Host == u.endpoint.Host:
+		path = strings.TrimPrefix(path, strings.TrimPrefix(strings.TrimSuffix(u.endpoint.Path, "/")+"/", "/"))
+		if !strings.HasPrefix(path, u.conf.Bucket+"/") {
+			return "", false
+		}
+		path = strings.TrimPrefix(path, u.conf.Bucket+"/")
+	default:
+		return "", false
+	}
+	if path == "" {
+		return "", false
+	}
+	return path, true
+}
+
+func (u *objectUploader) objectURL(key string) string {
+	objectURL := *u.endpoint
+	basePath := strings.TrimSuffix(u.endpoint.Path, "/") + "/"
+	if u.conf.ForcePathStyle {
+		basePath += u.conf.Bucket + "/"
+	} else {
+		objectURL.Host = u.conf.Bucket + "." + objectURL.Host
+	}
+	objectURL.Path = basePath + key
+	objectURL.RawPath = basePath + escapeObjectKey(key)
+	return objectURL.String()
+}
+
+func (u *objectUploader) do(req *http.Request, payload []byte) error {
+	u.sign(req, payload, time.Now())
+
+	res, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("status %s: %s", res.Status, body)
+	}
+	return nil
+}
+
+func (u *objectUploader) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := []string{
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		signedHeaders = "content-type;" + signedHeaders
+		canonicalHeaders = append([]string{"content-type:" + contentType}, canonicalHeaders...)
+	}
+	canonicalRequest := strings.Join(append(append([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+	}, canonicalHeaders...), "", signedHeaders, payloadHash), "\n")
+
+	scope := date + "/" + u.conf.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+u.conf.Secret), date)
+	key = hmacSHA256(key, u.conf.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		u.conf.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func escapeObjectKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const originHeader = "Origin"
+
+var ErrOriginNotAllowed = errors.New("origin not allowed")
+
+// OriginChecker enforces the origins browsers may connect from. Requests without an Origin header are
+// not made by browsers and are always allowed, security for those is enforced by access tokens.
+type OriginChecker struct {
+	allowed    []string
+	keyOrigins map[string][]string
+}
+
+func NewOriginChecker(conf config.CORSConfig) *OriginChecker {
+	c := &OriginChecker{
+		allowed:    normalizeOrigins(conf.AllowedOrigins),
+		keyOrigins: make(map[string][]string, len(conf.APIKeyOrigins)),
+	}
+	for key, origins := range conf.APIKeyOrigins {
+		c.keyOrigins[key] = normalizeOrigins(origins)
+	}
+	return c
+}
+
+// IsAllowed checks an origin against the server wide allowlist
+func (c *OriginChecker) IsAllowed(origin string) bool {
+	if c == nil || origin == "" || len(c.allowed) == 0 {
+		return true
+	}
+	return matchOrigin(c.allowed, origin)
+}
+
+// IsAllowedForToken checks an origin against the restrictions of the API key a token was issued with,
+// and those embedded in the token itself
+func (c *OriginChecker) IsAllowedForToken(apiKey string, token string, origin string) bool {
+	if origin == "" {
+		return true
+	}
+	if c != nil {
+		if origins, ok := c.keyOrigins[apiKey]; ok && !matchOrigin(origins, origin) {
+			return false
+		}
+	}
+	if origins := tokenOrigins(token); origins != nil && !matchOrigin(normalizeOrigins(origins), origin) {
+		return false
+	}
+	return true
+}
+
+func (c *OriginChecker) CheckRequest(r *http.Request) bool {
+	return c.IsAllowed(r.Header.Get(originHeader))
+}
+
+// tokenOrigins reads the optional origins claim of a token that has already been verified
+func tokenOrigins(token string) []string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	claims := struct {
+		Origins []string `json:"origins"`
+	}{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+	return claims.Origins
+}
+
+func normalizeOrigins(origins []string) []string {
+	normalized := make([]string, 0, len(origins))
+	for _, o := range origins {
+		normalized = append(normalized, strings.TrimSuffix(strings.ToLower(o), "/"))
+	}
+	return normalized
+}
+
+// matchOrigin matches an origin against patterns where * stands for any part of a host name
+func matchOrigin(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// OriginMiddleware rejects browser requests from origins that aren't allowed
+type OriginMiddleware struct {
+	checker *OriginChecker
+}
+
+func NewOriginMiddleware(checker *OriginChecker) *OriginMiddleware {
+	return &OriginMiddleware{
+		checker: checker,
+	}
+}
+
+func (m *OriginMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !m.checker.CheckRequest(r) {
+		handleError(w, http.StatusForbidden, ErrOriginNotAllowed, "origin", r.Header.Get(originHeader))
+		return
+	}
+	next.ServeHTTP(w, r)
+}
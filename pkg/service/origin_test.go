@@ -0,0 +1,118 @@
+package service_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestOriginChecker(t *testing.T) {
+	t.Run("any origin when not configured", func(t *testing.T) {
+		c := service.NewOriginChecker(config.CORSConfig{})
+		require.True(t, c.IsAllowed("https://evil.com"))
+	})
+
+	t.Run("allowlist with wildcards", func(t *testing.T) {
+		c := service.NewOriginChecker(config.CORSConfig{
+			AllowedOrigins: []string{"https://app.example.com", "https://*.example.org/"},
+		})
+		require.True(t, c.IsAllowed(""))
+		require.True(t, c.IsAllowed("https://app.example.com"))
+		require.True(t, c.IsAllowed("https://App.Example.com"))
+		require.True(t, c.IsAllowed("https://meet.example.org"))
+		require.False(t, c.IsAllowed("https://example.org"))
+		require.False(t, c.IsAllowed("http://app.example.com"))
+		require.False(t, c.IsAllowed("https://app.example.com.evil.com"))
+	})
+
+	t.Run("per API key", func(t *testing.T) {
+		c := service.NewOriginChecker(config.CORSConfig{
+			APIKeyOrigins: map[string][]string{"key1": {"https://app.example.com"}},
+		})
+		require.True(t, c.IsAllowedForToken("key1", "", "https://app.example.com"))
+		require.False(t, c.IsAllowedForToken("key1", "", "https://other.example.com"))
+		require.True(t, c.IsAllowedForToken("key2", "", "https://other.example.com"))
+	})
+}
+
+func TestAuthMiddlewareOrigins(t *testing.T) {
+	api := "APIabcdefg"
+	secret := "somesecretencodedinbase62"
+	provider := auth.NewSimpleKeyProvider(api, secret)
+
+	m := service.NewAPIKeyAuthMiddleware(provider, service.NewOriginChecker(config.CORSConfig{}), nil)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := func(token string, origin string) int {
+		r := &http.Request{Header: http.Header{}}
+		service.SetAuthorizationToken(r, token)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r, handler)
+		return w.Code
+	}
+
+	token := signToken(t, secret, map[string]interface{}{
+		"iss":     api,
+		"sub":     "alice",
+		"nbf":     time.Now().Add(-time.Minute).Unix(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+		"video":   map[string]interface{}{"roomJoin": true, "room": "room"},
+		"origins": []string{"https://*.example.com"},
+	})
+	require.Equal(t, http.StatusOK, request(token, "https://app.example.com"))
+	require.Equal(t, http.StatusOK, request(token, ""))
+	require.Equal(t, http.StatusForbidden, request(token, "https://evil.com"))
+
+	// tokens without origins claim are not restricted
+	unrestricted, err := auth.NewAccessToken(api, secret).AddGrant(&auth.VideoGrant{RoomJoin: true, Room: "room"}).ToJWT()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, request(unrestricted, "https://evil.com"))
+}
+
+func TestOriginMiddleware(t *testing.T) {
+	m := service.NewOriginMiddleware(service.NewOriginChecker(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+	}))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/rtc", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r, handler)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	r.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r, handler)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func signToken(t *testing.T, secret string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/playback"
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const playbackPathPrefix = "/playback/"
+
+type PlaybackRequest struct {
+	Room       string `json:"room"`
+	PlaybackID string `json:"playback_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Identity   string `json:"identity,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Metadata   string `json:"metadata,omitempty"`
+	Loop       bool   `json:"loop,omitempty"`
+	PositionMs int64  `json:"position_ms,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+type ListPlaybackResponse struct {
+	Items []*playback.Info `json:"items"`
+}
+
+// PlaybackService publishes media files and diagnostic test patterns into rooms. Requests are JSON posted to /playback/<Method>
+// and require the roomAdmin grant for the room. Playbacks are kept by the node that started them, Pause, Resume, Seek,
+// SetLoop, Stop and List only see the playbacks of the node serving the request, so deployments with several nodes
+// need to send them to the same node, e.g. with sticky load balancing
+type PlaybackService struct {
+	manager *playback.Manager
+}
+
+func NewPlaybackService(manager *playback.Manager) *PlaybackService {
+	return &PlaybackService{
+		manager: manager,
+	}
+}
+
+func (s *PlaybackService) PathPrefix() string {
+	return playbackPathPrefix
+}
+
+func (s *PlaybackService) Close() {
+	s.manager.Close()
+}
+
+func (s *PlaybackService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &PlaybackRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	roomName := livekit.RoomName(req.Room)
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var (
+		res interface{}
+		err error
+	)
+	method := strings.TrimPrefix(r.URL.Path, playbackPathPrefix)
+	switch method {
+	case "StartPlayback":
+		res, err = s.manager.Start(r.Context(), playback.StartRequest{
+			RoomName: roomName,
+			Identity: livekit.ParticipantIdentity(req.Identity),
+			Name:     livekit.ParticipantName(req.Name),
+			Metadata: req.Metadata,
+			URL:      req.URL,
+			Loop:     req.Loop,
+		})
+	case "StartTestPattern":
+		res, err = s.manager.StartTestPattern(r.Context(), playback.TestPatternRequest{
+			RoomName: roomName,
+			Identity: livekit.ParticipantIdentity(req.Identity),
+			Name:     livekit.ParticipantName(req.Name),
+			Metadata: req.Metadata,
+			Duration: time.Duration(req.DurationMs) * time.Millisecond,
+		})
+	case "PausePlayback":
+		res, err = s.manager.Pause(roomName, req.PlaybackID)
+	case "ResumePlayback":
+		res, err = s.manager.Resume(roomName, req.PlaybackID)
+	case "SeekPlayback":
+		res, err = s.manager.Seek(roomName, req.PlaybackID, time.Duration(req.PositionMs)*time.Millisecond)
+	case "SetPlaybackLoop":
+		res, err = s.manager.SetLoop(roomName, req.PlaybackID, req.Loop)
+	case "StopPlayback":
+		err = s.manager.Stop(roomName, req.PlaybackID)
+		res = struct{}{}
+	case "ListPlayback":
+		res = &ListPlaybackResponse{Items: s.manager.List(roomName)}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		handleError(w, playbackErrorStatus(err), err, "method", method, "room", roomName, "playbackID", req.PlaybackID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func playbackErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, playback.ErrPlaybackNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, playback.ErrInvalidURL),
+		errors.Is(err, playback.ErrInvalidPosition),
+		errors.Is(err, playback.ErrInvalidDuration),
+		errors.Is(err, playback.ErrUnsupportedFormat),
+		errors.Is(err, playback.ErrUnsupportedCodec),
+		errors.Is(err, playback.ErrNoPlayableTracks),
+		errors.Is(err, playback.ErrTruncated):
+		return http.StatusBadRequest
+	case errors.Is(err, playback.ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// newPlaybackConnector joins playback participants the same way RTCService joins clients
+func newPlaybackConnector(roomAllocator RoomAllocator, router routing.MessageRouter) playback.SignalConnector {
+	return func(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit) (routing.MessageSink, routing.MessageSource, error) {
+		if _, err := roomAllocator.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: string(roomName)}); err != nil {
+			return nil, nil, err
+		}
+		if r, ok := router.(routing.Router); ok {
+			pi.Region = r.GetRegion()
+		}
+		_, sink, source, err := router.StartParticipantSignal(ctx, roomName, pi)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sink, source, nil
+	}
+}
@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+
+	"github.com/livekit/livekit-server/pkg/playback"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestPlaybackService(t *testing.T) {
+	svc := service.NewPlaybackService(playback.NewManager(playback.ManagerParams{}))
+
+	request := func(method string, body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+method, strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		w := request("ListPlayback", `{"room": "room"}`, nil)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = request("ListPlayback", `{"room": "other"}`, admin)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := request("ListPlayback", `{"room": "room"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &service.ListPlaybackResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.Empty(t, res.Items)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		w := request("StartPlayback", `{"room": "room", "url": "file:///etc/passwd"}`, admin)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = request("StartTestPattern", `{"room": "room", "duration_ms": 3600000}`, admin)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = request("PausePlayback", `{"room": "room", "playback_id": "PB_unknown"}`, admin)
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		w = request("Unknown", `{"room": "room"}`, admin)
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,567 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	presencePathPrefix = "/presence/"
+
+	defaultPresenceWatcherTTL    = 30 * time.Second
+	defaultPresenceCheckInterval = 2 * time.Second
+	// long polls and subscriptions look for changes this often
+	presencePollInterval = 500 * time.Millisecond
+	maxPresenceWait      = 30 * time.Second
+	presenceWriteTimeout = 10 * time.Second
+)
+
+type GetRoomPresenceRequest struct {
+	Room string `json:"room"`
+	// when set, the response is held until the presence no longer has this version, or for wait_ms
+	Version string `json:"version,omitempty"`
+	WaitMs  int64  `json:"wait_ms,omitempty"`
+}
+
+type RoomPresence struct {
+	Room string `json:"room"`
+	// participants in the room, hidden ones such as recorders are not counted
+	Participants int `json:"participants"`
+	// participants publishing tracks
+	Publishers int `json:"publishers"`
+	// participants only subscribing
+	Subscribers int `json:"subscribers"`
+	// watch counts reported by external services by source, e.g. hls or whep
+	Watchers map[string]int64 `json:"watchers,omitempty"`
+	// subscribers and reported watchers
+	Watching int64            `json:"watching"`
+	Counters map[string]int64 `json:"counters,omitempty"`
+	// changes whenever any of the above changes
+	Version string `json:"version"`
+}
+
+type IncrementRoomCounterRequest struct {
+	Room  string `json:"room"`
+	Name  string `json:"name"`
+	Delta int64  `json:"delta"`
+}
+
+type IncrementRoomCounterResponse struct {
+	Room  string `json:"room"`
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+type ResetRoomCountersRequest struct {
+	Room string `json:"room"`
+}
+
+type ReportRoomWatchersRequest struct {
+	Room string `json:"room"`
+	// the service reporting, e.g. hls or whep. each source reports its own count
+	Source string `json:"source"`
+	Count  int64  `json:"count"`
+}
+
+// PresenceService serves how many are in and watching a room, and counters services keep per room, to pages that do
+// not join the room. Requests are JSON posted to /presence/<method> on any node:
+//   - GetRoomPresence returns the presence of a room, when given the version a page has it is held until the presence
+//     changes, for long polling
+//   - Subscribe, a WebSocket opened with GET and the room and access_token query parameters, streams the presence
+//     each time it changes
+//   - IncrementRoomCounter adds to a counter of the room, safely across nodes
+//   - ResetRoomCounters deletes the counters and watch counts of the room
+//   - ReportRoomWatchers sets the watch count of viewers outside the room, e.g. of HLS or WHEP playback
+//
+// HLS playlists and WHEP streams are served by egress and CDNs rather than by the server, their viewers are only
+// counted once the service serving them reports them with ReportRoomWatchers.
+//
+// Reading the presence of a room requires a token joining it, listing rooms or administering it, changing counters
+// and watch counts requires the roomAdmin grant for the room. Long polls and subscriptions of a room share one
+// poller per node. The node hosting a room sends room_presence_changed webhooks when its presence changes
+type PresenceService struct {
+	conf        config.PresenceConfig
+	store       RoomPresenceStore
+	roomStore   ServiceStore
+	roomManager *RoomManager
+	telemetry   telemetry.TelemetryService
+	upgrader    websocket.Upgrader
+
+	watchLock sync.Mutex
+	watches   map[livekit.RoomName]*roomPresenceWatch
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// roomPresenceWatch polls the presence of a room for the long polls and subscriptions of this node
+type roomPresenceWatch struct {
+	// each gets the latest presence
+	subscribers map[chan *RoomPresence]struct{}
+	presence    *RoomPresence
+}
+
+func NewPresenceService(
+	conf *config.Config,
+	store RoomPresenceStore,
+	roomStore ServiceStore,
+	roomManager *RoomManager,
+	ts telemetry.TelemetryService,
+) *PresenceService {
+	s := &PresenceService{
+		conf:        conf.Presence,
+		store:       store,
+		roomStore:   roomStore,
+		roomManager: roomManager,
+		telemetry:   ts,
+		watches:     make(map[livekit.RoomName]*roomPresenceWatch),
+		done:        make(chan struct{}),
+	}
+	s.upgrader.CheckOrigin = NewOriginChecker(conf.CORS).CheckRequest
+	return s
+}
+
+func (s *PresenceService) PathPrefix() string {
+	return presencePathPrefix
+}
+
+func (s *PresenceService) Start() {
+	if s.store == nil || !s.conf.Webhooks {
+		return
+	}
+	go s.worker()
+}
+
+func (s *PresenceService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *PresenceService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, presencePathPrefix)
+	if method == "Subscribe" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.subscribe(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch method {
+	case "GetRoomPresence":
+		s.getRoomPresence(w, r)
+	case "IncrementRoomCounter":
+		s.incrementRoomCounter(w, r)
+	case "ResetRoomCounters":
+		s.resetRoomCounters(w, r)
+	case "ReportRoomWatchers":
+		s.reportRoomWatchers(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *PresenceService) getRoomPresence(w http.ResponseWriter, r *http.Request) {
+	req := &GetRoomPresenceRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := ensurePresencePermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrPresenceNotEnabled)
+		return
+	}
+
+	res, err := s.GetRoomPresence(r.Context(), req)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err, "room", req.Room)
+		return
+	}
+	writePresenceResponse(w, res)
+}
+
+func (s *PresenceService) incrementRoomCounter(w http.ResponseWriter, r *http.Request) {
+	req := &IncrementRoomCounterRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrPresenceNotEnabled)
+		return
+	}
+
+	res, err := s.IncrementRoomCounter(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPresenceRequest) {
+			handleError(w, http.StatusBadRequest, err, "room", req.Room, "counter", req.Name)
+		} else {
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room, "counter", req.Name)
+		}
+		return
+	}
+	writePresenceResponse(w, res)
+}
+
+func (s *PresenceService) resetRoomCounters(w http.ResponseWriter, r *http.Request) {
+	req := &ResetRoomCountersRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrPresenceNotEnabled)
+		return
+	}
+
+	if err := s.ResetRoomCounters(r.Context(), req); err != nil {
+		if errors.Is(err, ErrInvalidPresenceRequest) {
+			handleError(w, http.StatusBadRequest, err, "room", req.Room)
+		} else {
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room)
+		}
+		return
+	}
+	writePresenceResponse(w, struct{}{})
+}
+
+func (s *PresenceService) reportRoomWatchers(w http.ResponseWriter, r *http.Request) {
+	req := &ReportRoomWatchersRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrPresenceNotEnabled)
+		return
+	}
+
+	if err := s.ReportRoomWatchers(r.Context(), req); err != nil {
+		if errors.Is(err, ErrInvalidPresenceRequest) {
+			handleError(w, http.StatusBadRequest, err, "room", req.Room, "source", req.Source)
+		} else {
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room, "source", req.Source)
+		}
+		return
+	}
+	writePresenceResponse(w, struct{}{})
+}
+
+func (s *PresenceService) subscribe(w http.ResponseWriter, r *http.Request) {
+	roomName := livekit.RoomName(r.FormValue("room"))
+	if err := ensurePresencePermission(r.Context(), roomName); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrPresenceNotEnabled)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Debugw("could not upgrade presence subscription", err, "room", roomName)
+		return
+	}
+	defer conn.Close()
+
+	// messages of the page are not expected, reading notices when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	updates, stop := s.watchRoomPresence(roomName)
+	defer stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-s.done:
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case presence := <-updates:
+			_ = conn.SetWriteDeadline(time.Now().Add(presenceWriteTimeout))
+			if err := conn.WriteJSON(presence); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// GetRoomPresence returns the presence of a room, waiting for it to change from the version of the request
+func (s *PresenceService) GetRoomPresence(ctx context.Context, req *GetRoomPresenceRequest) (*RoomPresence, error) {
+	roomName := livekit.RoomName(req.Room)
+	presence, err := s.loadRoomPresence(ctx, roomName)
+	if err != nil || req.Version == "" || req.WaitMs <= 0 {
+		return presence, err
+	}
+
+	wait := time.Duration(req.WaitMs) * time.Millisecond
+	if wait > maxPresenceWait {
+		wait = maxPresenceWait
+	}
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	updates, stop := s.watchRoomPresence(roomName)
+	defer stop()
+
+	for presence.Version == req.Version {
+		select {
+		case <-ctx.Done():
+			return presence, nil
+		case <-s.done:
+			return presence, nil
+		case <-deadline.C:
+			return presence, nil
+		case presence = <-updates:
+		}
+	}
+	return presence, nil
+}
+
+// IncrementRoomCounter adds to a counter of a room, a negative delta decrements it
+func (s *PresenceService) IncrementRoomCounter(ctx context.Context, req *IncrementRoomCounterRequest) (*IncrementRoomCounterResponse, error) {
+	if req.Room == "" || req.Name == "" {
+		return nil, ErrInvalidPresenceRequest
+	}
+
+	value, err := s.store.IncrementRoomCounter(ctx, livekit.RoomName(req.Room), req.Name, req.Delta)
+	if err != nil {
+		return nil, err
+	}
+	return &IncrementRoomCounterResponse{
+		Room:  req.Room,
+		Name:  req.Name,
+		Value: value,
+	}, nil
+}
+
+// ResetRoomCounters deletes the counters and watch counts of a room
+func (s *PresenceService) ResetRoomCounters(ctx context.Context, req *ResetRoomCountersRequest) error {
+	if req.Room == "" {
+		return ErrInvalidPresenceRequest
+	}
+	return s.store.DeleteRoomCounters(ctx, livekit.RoomName(req.Room))
+}
+
+// ReportRoomWatchers sets the watch count of a source, which has to report it again within the watcher TTL
+func (s *PresenceService) ReportRoomWatchers(ctx context.Context, req *ReportRoomWatchersRequest) error {
+	if req.Room == "" || req.Source == "" || req.Count < 0 {
+		return ErrInvalidPresenceRequest
+	}
+
+	ttl := s.conf.WatcherTTL
+	if ttl <= 0 {
+		ttl = defaultPresenceWatcherTTL
+	}
+	return s.store.StoreRoomWatchers(ctx, livekit.RoomName(req.Room), req.Source, req.Count, ttl)
+}
+
+// watchRoomPresence returns a channel receiving the presence of a room each time it changes, starting with the current
+// one. A single poller per room serves all watches of the node, it stops after the last watch did
+func (s *PresenceService) watchRoomPresence(roomName livekit.RoomName) (<-chan *RoomPresence, func()) {
+	updates := make(chan *RoomPresence, 1)
+
+	s.watchLock.Lock()
+	watch := s.watches[roomName]
+	if watch == nil {
+		watch = &roomPresenceWatch{
+			subscribers: make(map[chan *RoomPresence]struct{}),
+		}
+		s.watches[roomName] = watch
+		go s.pollRoomPresence(roomName, watch)
+	} else if watch.presence != nil {
+		updates <- watch.presence
+	}
+	watch.subscribers[updates] = struct{}{}
+	s.watchLock.Unlock()
+
+	return updates, func() {
+		s.watchLock.Lock()
+		delete(watch.subscribers, updates)
+		s.watchLock.Unlock()
+	}
+}
+
+func (s *PresenceService) pollRoomPresence(roomName livekit.RoomName, watch *roomPresenceWatch) {
+	ticker := time.NewTicker(presencePollInterval)
+	defer ticker.Stop()
+
+	for {
+		presence, err := s.loadRoomPresence(context.Background(), roomName)
+
+		s.watchLock.Lock()
+		if len(watch.subscribers) == 0 {
+			delete(s.watches, roomName)
+			s.watchLock.Unlock()
+			return
+		}
+		if err != nil {
+			logger.Warnw("could not load room presence", err, "room", roomName)
+		} else if watch.presence == nil || watch.presence.Version != presence.Version {
+			watch.presence = presence
+			for updates := range watch.subscribers {
+				// a watch that has not received the previous presence gets this one instead
+				select {
+				case <-updates:
+				default:
+				}
+				updates <- presence
+			}
+		}
+		s.watchLock.Unlock()
+
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadRoomPresence counts the participants of a room from the store, so that any node can serve it. A room that is
+// not open has no participants, its counters and watchers are still returned
+func (s *PresenceService) loadRoomPresence(ctx context.Context, roomName livekit.RoomName) (*RoomPresence, error) {
+	participants, err := s.roomStore.ListParticipants(ctx, roomName)
+	if err != nil && !errors.Is(err, ErrRoomNotFound) {
+		return nil, err
+	}
+	counters, watchers, err := s.store.LoadRoomCounters(ctx, roomName)
+	if err != nil {
+		return nil, err
+	}
+	return newRoomPresence(roomName, participants, counters, watchers), nil
+}
+
+func newRoomPresence(roomName livekit.RoomName, participants []*livekit.ParticipantInfo, counters, watchers map[string]int64) *RoomPresence {
+	presence := &RoomPresence{
+		Room: string(roomName),
+	}
+	for _, p := range participants {
+		if p.State == livekit.ParticipantInfo_DISCONNECTED || p.Permission.GetHidden() || p.Permission.GetRecorder() {
+			continue
+		}
+		presence.Participants++
+		if p.IsPublisher || len(p.Tracks) != 0 {
+			presence.Publishers++
+		}
+	}
+	presence.Subscribers = presence.Participants - presence.Publishers
+	presence.Watching = int64(presence.Subscribers)
+	if len(watchers) != 0 {
+		presence.Watchers = watchers
+		for _, count := range watchers {
+			presence.Watching += count
+		}
+	}
+	if len(counters) != 0 {
+		presence.Counters = counters
+	}
+
+	// maps are encoded with sorted keys, equal presences have equal encodings
+	data, _ := json.Marshal(presence)
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	presence.Version = hex.EncodeToString(h.Sum(nil))
+	return presence
+}
+
+// worker sends room_presence_changed webhooks for the rooms hosted on this node
+func (s *PresenceService) worker() {
+	interval := s.conf.CheckInterval
+	if interval <= 0 {
+		interval = defaultPresenceCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	versions := make(map[livekit.RoomName]string)
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx := context.Background()
+		hosted := make(map[livekit.RoomName]string, len(versions))
+		for _, room := range s.roomManager.localRooms() {
+			presence, err := s.loadRoomPresence(ctx, room.Name())
+			if err != nil {
+				logger.Warnw("could not load room presence", err, "room", room.Name())
+				continue
+			}
+			hosted[room.Name()] = presence.Version
+			if versions[room.Name()] == presence.Version {
+				continue
+			}
+			s.telemetry.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
+				Event: telemetry.EventRoomPresenceChanged,
+				Room:  room.ToProto(),
+			}, map[string]interface{}{"presence": presence})
+		}
+		versions = hosted
+	}
+}
+
+func ensurePresencePermission(ctx context.Context, roomName livekit.RoomName) error {
+	if roomName == "" {
+		return ErrPermissionDenied
+	}
+	if EnsureAdminPermission(ctx, roomName) == nil || EnsureListPermission(ctx) == nil {
+		return nil
+	}
+	if name, err := EnsureJoinPermission(ctx); err == nil && name == roomName {
+		return nil
+	}
+	return ErrPermissionDenied
+}
+
+func writePresenceResponse(w http.ResponseWriter, res interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,204 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestLocalPresenceStore(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewLocalPresenceStore(config.PresenceConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.IncrementRoomCounter(ctx, "room", "likes", 2)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	value, err := store.IncrementRoomCounter(ctx, "room", "likes", -1)
+	require.NoError(t, err)
+	require.EqualValues(t, 99, value)
+
+	require.NoError(t, store.StoreRoomWatchers(ctx, "room", "hls", 10, time.Hour))
+	require.NoError(t, store.StoreRoomWatchers(ctx, "room", "whep", 5, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	counters, watchers, err := store.LoadRoomCounters(ctx, "room")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"likes": 99}, counters)
+	require.Equal(t, map[string]int64{"hls": 10}, watchers)
+
+	// counters of rooms no longer updated expire
+	store = service.NewLocalPresenceStore(config.PresenceConfig{CounterRetention: time.Millisecond})
+	_, err = store.IncrementRoomCounter(ctx, "room", "likes", 1)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	counters, _, err = store.LoadRoomCounters(ctx, "room")
+	require.NoError(t, err)
+	require.Empty(t, counters)
+}
+
+func TestPresenceService(t *testing.T) {
+	conf := &config.Config{}
+	conf.Presence.Enabled = true
+	store := service.NewLocalPresenceStore(conf.Presence)
+	roomStore := &servicefakes.FakeServiceStore{}
+	roomStore.ListParticipantsStub = func(_ context.Context, roomName livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
+		if roomName != "room" {
+			return nil, service.ErrRoomNotFound
+		}
+		return []*livekit.ParticipantInfo{
+			{Identity: "publisher", State: livekit.ParticipantInfo_ACTIVE, IsPublisher: true},
+			{Identity: "tracks", State: livekit.ParticipantInfo_ACTIVE, Tracks: []*livekit.TrackInfo{{Sid: "TR_1"}}},
+			{Identity: "subscriber", State: livekit.ParticipantInfo_JOINED},
+			{Identity: "left", State: livekit.ParticipantInfo_DISCONNECTED},
+			{Identity: "hidden", State: livekit.ParticipantInfo_ACTIVE, Permission: &livekit.ParticipantPermission{Hidden: true}},
+			{Identity: "EG_1", State: livekit.ParticipantInfo_ACTIVE, Permission: &livekit.ParticipantPermission{Recorder: true}},
+		}, nil
+	}
+	svc := service.NewPresenceService(conf, store, roomStore, &service.RoomManager{}, &telemetryfakes.FakeTelemetryService{})
+	defer svc.Stop()
+
+	request := func(method string, body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+method, strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+
+	t.Run("permissions", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request("GetRoomPresence", `{"room":"room"}`, nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request("GetRoomPresence", `{"room":"room"}`, &auth.VideoGrant{RoomJoin: true, Room: "other"}).Code)
+		require.Equal(t, http.StatusOK, request("GetRoomPresence", `{"room":"room"}`, &auth.VideoGrant{RoomJoin: true, Room: "room"}).Code)
+		require.Equal(t, http.StatusOK, request("GetRoomPresence", `{"room":"room"}`, &auth.VideoGrant{RoomList: true}).Code)
+		require.Equal(t, http.StatusUnauthorized, request("IncrementRoomCounter", `{"room":"room","name":"likes","delta":1}`, &auth.VideoGrant{RoomJoin: true, Room: "room"}).Code)
+		require.Equal(t, http.StatusUnauthorized, request("ReportRoomWatchers", `{"room":"room","source":"hls","count":1}`, &auth.VideoGrant{RoomList: true}).Code)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, request("IncrementRoomCounter", `{"room":"room","delta":1}`, admin).Code)
+		require.Equal(t, http.StatusBadRequest, request("ReportRoomWatchers", `{"room":"room","source":"hls","count":-1}`, admin).Code)
+		require.Equal(t, http.StatusNotFound, request("Unknown", `{}`, admin).Code)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		disabled := service.NewPresenceService(&config.Config{}, nil, roomStore, &service.RoomManager{}, &telemetryfakes.FakeTelemetryService{})
+		r := httptest.NewRequest(http.MethodPost, disabled.PathPrefix()+"GetRoomPresence", strings.NewReader(`{"room":"room"}`))
+		r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{Video: admin}))
+		w := httptest.NewRecorder()
+		disabled.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	t.Run("counters and watchers", func(t *testing.T) {
+		w := request("IncrementRoomCounter", `{"room":"room","name":"likes","delta":2}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &service.IncrementRoomCounterResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.EqualValues(t, 2, res.Value)
+		require.Equal(t, http.StatusOK, request("ReportRoomWatchers", `{"room":"room","source":"hls","count":10}`, admin).Code)
+
+		w = request("GetRoomPresence", `{"room":"room"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		presence := &service.RoomPresence{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), presence))
+		require.Equal(t, 3, presence.Participants)
+		require.Equal(t, 2, presence.Publishers)
+		require.Equal(t, 1, presence.Subscribers)
+		require.EqualValues(t, 11, presence.Watching)
+		require.Equal(t, map[string]int64{"likes": 2}, presence.Counters)
+
+		// rooms that are not open still have counters
+		presence, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "closed"})
+		require.NoError(t, err)
+		require.Zero(t, presence.Participants)
+	})
+
+	t.Run("long poll", func(t *testing.T) {
+		presence, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "room"})
+		require.NoError(t, err)
+
+		// unchanged, returns once waited
+		start := time.Now()
+		unchanged, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "room", Version: presence.Version, WaitMs: 100})
+		require.NoError(t, err)
+		require.Equal(t, presence.Version, unchanged.Version)
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			_, _ = store.IncrementRoomCounter(context.Background(), "room", "likes", 1)
+		}()
+		changed, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "room", Version: presence.Version, WaitMs: 10000})
+		require.NoError(t, err)
+		require.NotEqual(t, presence.Version, changed.Version)
+		require.EqualValues(t, presence.Counters["likes"]+1, changed.Counters["likes"])
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request("ResetRoomCounters", `{"room":"room"}`, &auth.VideoGrant{RoomList: true}).Code)
+		require.Equal(t, http.StatusOK, request("ResetRoomCounters", `{"room":"room"}`, admin).Code)
+		presence, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "room"})
+		require.NoError(t, err)
+		require.Empty(t, presence.Counters)
+		require.Empty(t, presence.Watchers)
+	})
+}
+
+func TestPresenceServiceSharedPoller(t *testing.T) {
+	conf := &config.Config{}
+	conf.Presence.Enabled = true
+	store := &servicefakes.FakeRoomPresenceStore{}
+	var likes atomic.Int64
+	store.LoadRoomCountersStub = func(_ context.Context, _ livekit.RoomName) (map[string]int64, map[string]int64, error) {
+		return map[string]int64{"likes": likes.Load()}, nil, nil
+	}
+	svc := service.NewPresenceService(conf, store, &servicefakes.FakeServiceStore{}, &service.RoomManager{}, &telemetryfakes.FakeTelemetryService{})
+	defer svc.Stop()
+
+	presence, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "room"})
+	require.NoError(t, err)
+
+	// long polls of a room wait on one poller
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			changed, err := svc.GetRoomPresence(context.Background(), &service.GetRoomPresenceRequest{Room: "room", Version: presence.Version, WaitMs: 10000})
+			require.NoError(t, err)
+			require.EqualValues(t, 1, changed.Counters["likes"])
+		}()
+	}
+	time.Sleep(1200 * time.Millisecond)
+	loads := store.LoadRoomCountersCallCount()
+	// the initial loads of the requests and about two polls
+	require.LessOrEqual(t, loads, 1+20+4)
+
+	likes.Store(1)
+	wg.Wait()
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// RoomCountersPrefix is a hash of counter name to value
+	RoomCountersPrefix = "room_counters:"
+	// RoomWatchersPrefix is a hash of source to <count>:<expiry in unix ms>
+	RoomWatchersPrefix = "room_watchers:"
+
+	defaultPresenceCounterRetention = 24 * time.Hour
+)
+
+func presenceCounterRetention(conf config.PresenceConfig) time.Duration {
+	if conf.CounterRetention <= 0 {
+		return defaultPresenceCounterRetention
+	}
+	return conf.CounterRetention
+}
+
+type expiringCount struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// LocalPresenceStore keeps room counters in memory, for single node deployments
+type LocalPresenceStore struct {
+	retention time.Duration
+
+	lock     sync.Mutex
+	counters map[livekit.RoomName]map[string]int64
+	watchers map[livekit.RoomName]map[string]expiringCount
+	// counters of a room are dropped after this
+	expiresAt map[livekit.RoomName]time.Time
+}
+
+func NewLocalPresenceStore(conf config.PresenceConfig) *LocalPresenceStore {
+	return &LocalPresenceStore{
+		retention: presenceCounterRetention(conf),
+		counters:  make(map[livekit.RoomName]map[string]int64),
+		watchers:  make(map[livekit.RoomName]map[string]expiringCount),
+		expiresAt: make(map[livekit.RoomName]time.Time),
+	}
+}
+
+func (s *LocalPresenceStore) IncrementRoomCounter(_ context.Context, roomName livekit.RoomName, name string, delta int64) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expireLocked(time.Now())
+	counters := s.counters[roomName]
+	if counters == nil {
+		counters = make(map[string]int64)
+		s.counters[roomName] = counters
+	}
+	counters[name] += delta
+	s.expiresAt[roomName] = time.Now().Add(s.retention)
+	return counters[name], nil
+}
+
+func (s *LocalPresenceStore) StoreRoomWatchers(_ context.Context, roomName livekit.RoomName, source string, count int64, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	watchers := s.watchers[roomName]
+	if watchers == nil {
+		watchers = make(map[string]expiringCount)
+		s.watchers[roomName] = watchers
+	}
+	watchers[source] = expiringCount{count: count, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *LocalPresenceStore) LoadRoomCounters(_ context.Context, roomName livekit.RoomName) (map[string]int64, map[string]int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	s.expireLocked(now)
+	counters := make(map[string]int64, len(s.counters[roomName]))
+	for name, value := range s.counters[roomName] {
+		counters[name] = value
+	}
+	watchers := make(map[string]int64, len(s.watchers[roomName]))
+	for source, w := range s.watchers[roomName] {
+		if now.After(w.expiresAt) {
+			delete(s.watchers[roomName], source)
+			continue
+		}
+		watchers[source] = w.count
+	}
+	if len(s.watchers[roomName]) == 0 {
+		delete(s.watchers, roomName)
+	}
+	return counters, watchers, nil
+}
+
+func (s *LocalPresenceStore) DeleteRoomCounters(_ context.Context, roomName livekit.RoomName) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.counters, roomName)
+	delete(s.watchers, roomName)
+	delete(s.expiresAt, roomName)
+	return nil
+}
+
+func (s *LocalPresenceStore) expireLocked(now time.Time) {
+	for roomName, expiresAt := range s.expiresAt {
+		if now.After(expiresAt) {
+			delete(s.counters, roomName)
+			delete(s.expiresAt, roomName)
+		}
+	}
+}
+
+// ---------------------------------------------
+
+// RedisPresenceStore keeps room counters in redis, so that they are shared by all nodes
+type RedisPresenceStore struct {
+	rc        redis.UniversalClient
+	retention time.Duration
+}
+
+func NewRedisPresenceStore(rc redis.UniversalClient, conf config.PresenceConfig) *RedisPresenceStore {
+	return &RedisPresenceStore{
+		rc:        rc,
+		retention: presenceCounterRetention(conf),
+	}
+}
+
+func (s *RedisPresenceStore) IncrementRoomCounter(ctx context.Context, roomName livekit.RoomName, name string, delta int64) (int64, error) {
+	key := RoomCountersPrefix + string(roomName)
+	pp := s.rc.TxPipeline()
+	value := pp.HIncrBy(ctx, key, name, delta)
+	pp.Expire(ctx, key, s.retention)
+	if _, err := pp.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return value.Val(), nil
+}
+
+func (s *RedisPresenceStore) StoreRoomWatchers(ctx context.Context, roomName livekit.RoomName, source string, count int64, ttl time.Duration) error {
+	key := RoomWatchersPrefix + string(roomName)
+	value := strconv.FormatInt(count, 10) + ":" + strconv.FormatInt(time.Now().Add(ttl).UnixMilli(), 10)
+	pp := s.rc.TxPipeline()
+	pp.HSet(ctx, key, source, value)
+	// sources that stopped reporting are removed when loaded, the hash goes away with the last one
+	pp.Expire(ctx, key, ttl)
+	_, err := pp.Exec(ctx)
+	return err
+}
+
+func (s *RedisPresenceStore) LoadRoomCounters(ctx context.Context, roomName livekit.RoomName) (map[string]int64, map[string]int64, error) {
+	pp := s.rc.Pipeline()
+	countersCmd := pp.HGetAll(ctx, RoomCountersPrefix+string(roomName))
+	watchersCmd := pp.HGetAll(ctx, RoomWatchersPrefix+string(roomName))
+	if _, err := pp.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, nil, err
+	}
+
+	counters := make(map[string]int64, len(countersCmd.Val()))
+	for name, value := range countersCmd.Val() {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		counters[name] = v
+	}
+
+	now := time.Now().UnixMilli()
+	watchers := make(map[string]int64, len(watchersCmd.Val()))
+	var expired []string
+	for source, value := range watchersCmd.Val() {
+		count, expiresAt, ok := strings.Cut(value, ":")
+		c, err := strconv.ParseInt(count, 10, 64)
+		if err != nil || !ok {
+			expired = append(expired, source)
+			continue
+		}
+		if e, err := strconv.ParseInt(expiresAt, 10, 64); err != nil || e < now {
+			expired = append(expired, source)
+			continue
+		}
+		watchers[source] = c
+	}
+	if len(expired) != 0 {
+		s.rc.HDel(ctx, RoomWatchersPrefix+string(roomName), expired...)
+	}
+	return counters, watchers, nil
+}
+
+func (s *RedisPresenceStore) DeleteRoomCounters(ctx context.Context, roomName livekit.RoomName) error {
+	return s.rc.Del(ctx, RoomCountersPrefix+string(roomName), RoomWatchersPrefix+string(roomName)).Err()
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime/metrics"
+	runtimepprof "runtime/pprof"
+	"runtime/trace"
+	"strings"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/telemetry/profiler"
+)
+
+const (
+	profilingPathPrefix = "/profiling/"
+	pprofPathPrefix     = "/debug/pprof/"
+	runtimeMetricsPath  = "/debug/runtime"
+
+	defaultProfileDuration = 30 * time.Second
+	maxProfileDuration     = 5 * time.Minute
+)
+
+var (
+	ErrInvalidProfileType     = errors.New("profile type must be one of cpu, trace, heap, allocs, goroutine, block, mutex, threadcreate")
+	ErrInvalidProfileDuration = errors.New("duration must be positive and at most 5 minutes")
+	ErrProfileInProgress      = errors.New("a cpu profile or trace is already being captured")
+)
+
+type CaptureProfileRequest struct {
+	// cpu and trace are captured for the duration, others are a snapshot
+	Type string `json:"type"`
+	// defaults to 30 seconds
+	DurationSeconds int64 `json:"duration_seconds,omitempty"`
+}
+
+type CaptureProfileResponse struct {
+	NodeID   livekit.NodeID `json:"node_id"`
+	Type     string         `json:"type"`
+	Location string         `json:"location"`
+	Size     int            `json:"size"`
+}
+
+// ProfilingService captures profiles of the node serving the request, uploading them to object storage when
+// configured. it also serves pprof and runtime metrics, all requests need a token signed with an admin API key
+type ProfilingService struct {
+	nodeID   livekit.NodeID
+	uploader *objectUploader
+	debugMux *http.ServeMux
+
+	// only one cpu profile or trace can run at a time
+	capturing atomic.Bool
+}
+
+func NewProfilingService(conf *config.Config, currentNode routing.LocalNode) (*ProfilingService, error) {
+	uploader, err := newObjectUploader(conf.Profiling.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ProfilingService{
+		nodeID:   livekit.NodeID(currentNode.Id),
+		uploader: uploader,
+		debugMux: http.NewServeMux(),
+	}
+	s.debugMux.HandleFunc(pprofPathPrefix, pprof.Index)
+	s.debugMux.HandleFunc(pprofPathPrefix+"cmdline", pprof.Cmdline)
+	s.debugMux.HandleFunc(pprofPathPrefix+"profile", pprof.Profile)
+	s.debugMux.HandleFunc(pprofPathPrefix+"symbol", pprof.Symbol)
+	s.debugMux.HandleFunc(pprofPathPrefix+"trace", pprof.Trace)
+	s.debugMux.HandleFunc(runtimeMetricsPath, s.runtimeMetrics)
+	return s, nil
+}
+
+func (s *ProfilingService) PathPrefix() string {
+	return profilingPathPrefix
+}
+
+// DebugHandler serves pprof under /debug/pprof/ and runtime metrics at /debug/runtime
+func (s *ProfilingService) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := EnsureNodeAdminPermission(r.Context()); err != nil {
+			handleError(w, http.StatusUnauthorized, err)
+			return
+		}
+		s.debugMux.ServeHTTP(w, r)
+	})
+}
+
+func (s *ProfilingService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := EnsureNodeAdminPermission(r.Context()); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, profilingPathPrefix) {
+	case "CaptureProfile":
+		s.captureProfile(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *ProfilingService) captureProfile(w http.ResponseWriter, r *http.Request) {
+	req := &CaptureProfileRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration == 0 {
+		duration = defaultProfileDuration
+	}
+	if duration < 0 || duration > maxProfileDuration {
+		handleError(w, http.StatusBadRequest, ErrInvalidProfileDuration)
+		return
+	}
+
+	logger.Infow("capturing profile", "type", req.Type, "duration", duration)
+	buf := &bytes.Buffer{}
+	if err := s.capture(r.Context(), req.Type, duration, buf); err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case ErrInvalidProfileType:
+			status = http.StatusBadRequest
+		case ErrProfileInProgress:
+			status = http.StatusConflict
+		}
+		handleError(w, status, err, "type", req.Type)
+		return
+	}
+
+	extension := "pprof"
+	if req.Type == "trace" {
+		extension = "trace"
+	}
+	filename := fmt.Sprintf("%s-%s.%s", req.Type, time.Now().UTC().Format("20060102T150405Z"), extension)
+
+	if s.uploader == nil {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+
+	location, err := s.uploader.Upload(r.Context(), string(s.nodeID)+"/"+filename, "application/octet-stream", buf.Bytes())
+	if err != nil {
+		handleError(w, http.StatusBadGateway, err, "type", req.Type)
+		return
+	}
+	logger.Infow("uploaded profile", "type", req.Type, "location", location)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&CaptureProfileResponse{
+		NodeID:   s.nodeID,
+		Type:     req.Type,
+		Location: location,
+		Size:     buf.Len(),
+	})
+}
+
+func (s *ProfilingService) capture(ctx context.Context, profileType string, duration time.Duration, buf *bytes.Buffer) error {
+	switch profileType {
+	case "cpu", "trace":
+		if s.capturing.Swap(true) {
+			return ErrProfileInProgress
+		}
+		defer s.capturing.Store(false)
+
+		start, stop := runtimepprof.StartCPUProfile, runtimepprof.StopCPUProfile
+		if profileType == "trace" {
+			start, stop = trace.Start, trace.Stop
+		} else {
+			// the continuous profiler holds the only CPU profile the runtime allows
+			resume := profiler.PauseCPUProfile()
+			defer resume()
+		}
+		if err := start(buf); err != nil {
+			return err
+		}
+		// cut short when the request is cancelled
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+		}
+		stop()
+		return nil
+
+	case "heap", "allocs", "goroutine", "block", "mutex", "threadcreate":
+		return runtimepprof.Lookup(profileType).WriteTo(buf, 0)
+
+	default:
+		return ErrInvalidProfileType
+	}
+}
+
+// runtimeMetrics returns the scalar metrics of runtime/metrics, e.g. /sched/goroutines:goroutines
+func (s *ProfilingService) runtimeMetrics(w http.ResponseWriter, _ *http.Request) {
+	descs := metrics.All()
+	samples := make([]metrics.Sample, 0, len(descs))
+	for _, d := range descs {
+		if d.Kind == metrics.KindUint64 || d.Kind == metrics.KindFloat64 {
+			samples = append(samples, metrics.Sample{Name: d.Name})
+		}
+	}
+	metrics.Read(samples)
+
+	res := make(map[string]interface{}, len(samples))
+	for _, sample := range samples {
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			res[sample.Name] = sample.Value.Uint64()
+		case metrics.KindFloat64:
+			res[sample.Name] = sample.Value.Float64()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
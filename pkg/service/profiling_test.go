@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestProfilingService(t *testing.T) {
+	admin := &auth.VideoGrant{RoomCreate: true, RoomList: true}
+	request := func(h http.Handler, method string, path string, body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if grant != nil {
+			ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{Video: grant})
+			if grant == admin {
+				ctx = service.WithNodeAdmin(ctx)
+			}
+			r = r.WithContext(ctx)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("returns profile without storage", func(t *testing.T) {
+		svc, err := service.NewProfilingService(&config.Config{}, &livekit.Node{Id: "ND_1"})
+		require.NoError(t, err)
+
+		w := request(svc, http.MethodPost, "/profiling/CaptureProfile", `{"type": "heap"}`, &auth.VideoGrant{RoomAdmin: true, Room: "room"})
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		// grants without an admin API key
+		w = request(svc, http.MethodPost, "/profiling/CaptureProfile", `{"type": "heap"}`, &auth.VideoGrant{RoomCreate: true, RoomList: true})
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = request(svc, http.MethodPost, "/profiling/CaptureProfile", `{"type": "memory"}`, admin)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = request(svc, http.MethodPost, "/profiling/CaptureProfile", `{"type": "heap"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+		require.NotZero(t, w.Body.Len())
+	})
+
+	t.Run("uploads to storage", func(t *testing.T) {
+		var uploaded []byte
+		var uploadPath, authorization string
+		storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPut, r.Method)
+			uploadPath = r.URL.Path
+			authorization = r.Header.Get("Authorization")
+			uploaded, _ = io.ReadAll(r.Body)
+		}))
+		defer storage.Close()
+
+		conf := &config.Config{Profiling: config.ProfilingConfig{Storage: config.ObjectStorageConfig{
+			Endpoint:       storage.URL,
+			Bucket:         "profiles",
+			AccessKey:      "key",
+			Secret:         "secret",
+			Prefix:         "livekit/",
+			ForcePathStyle: true,
+		}}}
+		svc, err := service.NewProfilingService(conf, &livekit.Node{Id: "ND_1"})
+		require.NoError(t, err)
+
+		w := request(svc, http.MethodPost, "/profiling/CaptureProfile", `{"type": "goroutine"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &service.CaptureProfileResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.EqualValues(t, "ND_1", res.NodeID)
+		require.Equal(t, len(uploaded), res.Size)
+		require.True(t, strings.HasPrefix(uploadPath, "/profiles/livekit/ND_1/goroutine-"), uploadPath)
+		require.Equal(t, "s3://profiles"+strings.TrimPrefix(uploadPath, "/profiles"), res.Location)
+		require.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=key/"), authorization)
+	})
+
+	t.Run("debug endpoints", func(t *testing.T) {
+		svc, err := service.NewProfilingService(&config.Config{}, &livekit.Node{Id: "ND_1"})
+		require.NoError(t, err)
+		h := svc.DebugHandler()
+
+		require.Equal(t, http.StatusUnauthorized, request(h, http.MethodGet, "/debug/pprof/", "", nil).Code)
+		require.Equal(t, http.StatusOK, request(h, http.MethodGet, "/debug/pprof/", "", admin).Code)
+
+		w := request(h, http.MethodGet, "/debug/runtime", "", admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		metrics := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+		require.Contains(t, metrics, "/sched/goroutines:goroutines")
+	})
+}
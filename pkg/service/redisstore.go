@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -25,6 +26,24 @@ const (
 	// RoomsKey is hash of room_name => Room proto
 	RoomsKey        = "rooms"
 	RoomInternalKey = "room_internal"
+	// RoomAPIKeysKey is a hash of room_name => API key the room was created with
+	RoomAPIKeysKey = "room_api_keys"
+
+	// RoomJoinCodesKey is a hash of room_name => hash of the code participants have to join with
+	RoomJoinCodesKey = "room_join_codes"
+
+	// RoomStandbyNodesKey is a hash of room_name => node taking the room over should the node hosting it fail
+	RoomStandbyNodesKey = "room_standby_nodes"
+	// RoomMirrorsKey is a hash of room_name => JSON of the state mirrored for the standby node of the room
+	RoomMirrorsKey = "room_mirrors"
+
+	// RoomsByNameKey, RoomsByCreationTimeKey and RoomsByNumParticipantsKey are sorted sets of the sort members of rooms,
+	// all scored 0 so that they are ordered by member, rooms are listed a page at a time from them
+	RoomsByNameKey            = "rooms_by_name"
+	RoomsByCreationTimeKey    = "rooms_by_creation_time"
+	RoomsByNumParticipantsKey = "rooms_by_num_participants"
+	// RoomNumParticipantsMembersKey is a hash of room_name => member of the room in RoomsByNumParticipantsKey
+	RoomNumParticipantsMembersKey = "room_num_participants_members"
 
 	// EgressKey is a hash of egressID => egress info
 	EgressKey        = "egress"
@@ -40,6 +59,20 @@ const (
 	// RoomParticipantsPrefix is hash of participant_name => ParticipantInfo
 	RoomParticipantsPrefix = "room_participants:"
 
+	// ParticipantBandwidthEstimatePrefix is a key per room/participant containing its downlink estimate, expiring
+	ParticipantBandwidthEstimatePrefix = "participant_bandwidth_estimate:"
+
+	// TURNSessionPrefix is a key per participant session containing the secret of its TURN credentials, expiring
+	TURNSessionPrefix = "turn_session:"
+
+	// IdentityBindingPrefix is a hash per room/participant of the certificate fingerprint its identity is bound to
+	// and the participant holding the binding, expiring once the participant is gone
+	IdentityBindingPrefix = "identity_binding:"
+
+	// FingerprintNoncePrefix is a key per room/participant/nonce a client signs to prove it holds its certificate
+	// key, expiring
+	FingerprintNoncePrefix = "fingerprint_nonce:"
+
 	// RoomLockPrefix is a simple key containing a provided lock uid
 	RoomLockPrefix = "room_lock:"
 
@@ -47,10 +80,12 @@ const (
 )
 
 type RedisStore struct {
-	rc           redis.UniversalClient
-	unlockScript *redis.Script
-	ctx          context.Context
-	done         chan struct{}
+	rc               redis.UniversalClient
+	unlockScript     *redis.Script
+	swapEgressScript *redis.Script
+	mirrorRoomScript *redis.Script
+	ctx              context.Context
+	done             chan struct{}
 }
 
 func NewRedisStore(rc redis.UniversalClient) *RedisStore {
@@ -59,10 +94,26 @@ func NewRedisStore(rc redis.UniversalClient) *RedisStore {
 					 else return 0 
 					 end`
 
+	// sets the egress and its end, returns the info it replaced
+	swapEgressScript := `local previous = redis.call("hget", KEYS[1], ARGV[1])
+						redis.call("hset", KEYS[1], ARGV[1], ARGV[2])
+						if ARGV[3] ~= "" then
+							redis.call("hset", KEYS[2], ARGV[1], ARGV[3])
+						end
+						return previous`
+
+	// sets the mirror of a room as long as the room exists
+	mirrorRoomScript := `if redis.call("hexists", KEYS[1], ARGV[1]) == 1 then
+							return redis.call("hset", KEYS[2], ARGV[1], ARGV[2])
+						end
+						return 0`
+
 	return &RedisStore{
-		ctx:          context.Background(),
-		rc:           rc,
-		unlockScript: redis.NewScript(unlockScript),
+		ctx:              context.Background(),
+		rc:               rc,
+		unlockScript:     redis.NewScript(unlockScript),
+		swapEgressScript: redis.NewScript(swapEgressScript),
+		mirrorRoomScript: redis.NewScript(mirrorRoomScript),
 	}
 }
 
@@ -110,8 +161,22 @@ func (s *RedisStore) StoreRoom(_ context.Context, room *livekit.Room, internal *
 		return err
 	}
 
+	// the member of the room sorted by participants changes with them, the previous one is replaced
+	numParticipantsMember := roomSortMember(RoomSortNumParticipants, room)
+	previousMember, err := s.rc.HGet(s.ctx, RoomNumParticipantsMembersKey, room.Name).Result()
+	if err != nil && err != redis.Nil {
+		return errors.Wrap(err, "could not create room")
+	}
+
 	pp := s.rc.Pipeline()
 	pp.HSet(s.ctx, RoomsKey, room.Name, roomData)
+	pp.ZAdd(s.ctx, RoomsByNameKey, redis.Z{Member: room.Name})
+	pp.ZAdd(s.ctx, RoomsByCreationTimeKey, redis.Z{Member: roomSortMember(RoomSortCreationTime, room)})
+	if previousMember != "" && previousMember != numParticipantsMember {
+		pp.ZRem(s.ctx, RoomsByNumParticipantsKey, previousMember)
+	}
+	pp.ZAdd(s.ctx, RoomsByNumParticipantsKey, redis.Z{Member: numParticipantsMember})
+	pp.HSet(s.ctx, RoomNumParticipantsMembersKey, room.Name, numParticipantsMember)
 
 	var internalData []byte
 	if internal != nil {
@@ -206,15 +271,110 @@ func (s *RedisStore) ListRooms(_ context.Context, roomNames []livekit.RoomName)
 	return rooms, nil
 }
 
+// ListRoomsPage reads the sort index of the rooms from the cursor on, until the page is filled with rooms matching
+// the filter
+func (s *RedisStore) ListRoomsPage(_ context.Context, opts *ListRoomsOptions) ([]*livekit.Room, string, error) {
+	p, err := opts.page()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := RoomsByNameKey
+	switch p.sortBy {
+	case RoomSortCreationTime:
+		key = RoomsByCreationTimeKey
+	case RoomSortNumParticipants:
+		key = RoomsByNumParticipantsKey
+	}
+
+	var rooms []*livekit.Room
+	var lastMembers []string
+	after := p.after
+	// one more room than the page holds tells whether there is a next page
+	for len(rooms) <= p.limit {
+		var members []string
+		if p.descending {
+			rng := &redis.ZRangeBy{Min: "-", Max: "+", Count: int64(p.limit + 1)}
+			if after != "" {
+				rng.Max = "(" + after
+			}
+			members, err = s.rc.ZRevRangeByLex(s.ctx, key, rng).Result()
+		} else {
+			rng := &redis.ZRangeBy{Min: "-", Max: "+", Count: int64(p.limit + 1)}
+			if after != "" {
+				rng.Min = "(" + after
+			}
+			members, err = s.rc.ZRangeByLex(s.ctx, key, rng).Result()
+		}
+		if err != nil && err != redis.Nil {
+			return nil, "", errors.Wrap(err, "could not list rooms")
+		}
+		if len(members) == 0 {
+			break
+		}
+
+		names := make([]string, 0, len(members))
+		for _, member := range members {
+			names = append(names, roomNameFromSortMember(p.sortBy, member))
+		}
+		results, err := s.rc.HMGet(s.ctx, RoomsKey, names...).Result()
+		if err != nil && err != redis.Nil {
+			return nil, "", errors.Wrap(err, "could not get rooms by names")
+		}
+		for i, r := range results {
+			item, ok := r.(string)
+			if !ok {
+				continue
+			}
+			room := &livekit.Room{}
+			if err = proto.Unmarshal([]byte(item), room); err != nil {
+				return nil, "", err
+			}
+			// skip members left behind by a room stored concurrently
+			if roomSortMember(p.sortBy, room) != members[i] || !opts.matches(room) {
+				continue
+			}
+			rooms = append(rooms, room)
+			lastMembers = append(lastMembers, members[i])
+		}
+
+		after = members[len(members)-1]
+		if len(members) <= p.limit {
+			break
+		}
+	}
+
+	if len(rooms) <= p.limit {
+		return rooms, "", nil
+	}
+	return rooms[:p.limit], p.nextCursor(lastMembers[p.limit-1]), nil
+}
+
 func (s *RedisStore) DeleteRoom(ctx context.Context, roomName livekit.RoomName) error {
-	_, _, err := s.LoadRoom(ctx, roomName, false)
+	room, _, err := s.LoadRoom(ctx, roomName, false)
 	if err == ErrRoomNotFound {
 		return nil
 	}
+	numParticipantsMember, err := s.rc.HGet(s.ctx, RoomNumParticipantsMembersKey, string(roomName)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
 
 	pp := s.rc.Pipeline()
+	pp.ZRem(s.ctx, RoomsByNameKey, string(roomName))
+	if room != nil {
+		pp.ZRem(s.ctx, RoomsByCreationTimeKey, roomSortMember(RoomSortCreationTime, room))
+	}
+	if numParticipantsMember != "" {
+		pp.ZRem(s.ctx, RoomsByNumParticipantsKey, numParticipantsMember)
+	}
+	pp.HDel(s.ctx, RoomNumParticipantsMembersKey, string(roomName))
 	pp.HDel(s.ctx, RoomsKey, string(roomName))
 	pp.HDel(s.ctx, RoomInternalKey, string(roomName))
+	pp.HDel(s.ctx, RoomAPIKeysKey, string(roomName))
+	pp.HDel(s.ctx, RoomJoinCodesKey, string(roomName))
+	pp.HDel(s.ctx, RoomStandbyNodesKey, string(roomName))
+	pp.HDel(s.ctx, RoomMirrorsKey, string(roomName))
 	pp.Del(s.ctx, RoomParticipantsPrefix+string(roomName))
 
 	_, err = pp.Exec(s.ctx)
@@ -261,6 +421,151 @@ func (s *RedisStore) UnlockRoom(ctx context.Context, roomName livekit.RoomName,
 	return nil
 }
 
+func (s *RedisStore) StoreRoomAPIKey(ctx context.Context, roomName livekit.RoomName, apiKey string) error {
+	return s.rc.HSet(ctx, RoomAPIKeysKey, string(roomName), apiKey).Err()
+}
+
+func (s *RedisStore) LoadRoomAPIKey(ctx context.Context, roomName livekit.RoomName) (string, error) {
+	apiKey, err := s.rc.HGet(ctx, RoomAPIKeysKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return apiKey, err
+}
+
+func (s *RedisStore) StoreRoomJoinCode(ctx context.Context, roomName livekit.RoomName, codeHash string) error {
+	if codeHash == "" {
+		return s.rc.HDel(ctx, RoomJoinCodesKey, string(roomName)).Err()
+	}
+	return s.rc.HSet(ctx, RoomJoinCodesKey, string(roomName), codeHash).Err()
+}
+
+func (s *RedisStore) LoadRoomJoinCode(ctx context.Context, roomName livekit.RoomName) (string, error) {
+	codeHash, err := s.rc.HGet(ctx, RoomJoinCodesKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return codeHash, err
+}
+
+func (s *RedisStore) StoreRoomStandbyNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) error {
+	if nodeID == "" {
+		return s.rc.HDel(ctx, RoomStandbyNodesKey, string(roomName)).Err()
+	}
+	return s.rc.HSet(ctx, RoomStandbyNodesKey, string(roomName), string(nodeID)).Err()
+}
+
+func (s *RedisStore) LoadRoomStandbyNode(ctx context.Context, roomName livekit.RoomName) (livekit.NodeID, error) {
+	nodeID, err := s.rc.HGet(ctx, RoomStandbyNodesKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return livekit.NodeID(nodeID), err
+}
+
+func (s *RedisStore) StoreRoomMirror(ctx context.Context, roomName livekit.RoomName, mirror *RoomMirror) error {
+	data, err := json.Marshal(mirror)
+	if err != nil {
+		return err
+	}
+	// the room is deleted before its mirror, a mirror stored concurrently does not outlive the room
+	return s.mirrorRoomScript.Run(ctx, s.rc, []string{RoomsKey, RoomMirrorsKey}, string(roomName), data).Err()
+}
+
+func (s *RedisStore) LoadRoomMirror(ctx context.Context, roomName livekit.RoomName) (*RoomMirror, error) {
+	data, err := s.rc.HGet(ctx, RoomMirrorsKey, string(roomName)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	mirror := &RoomMirror{}
+	if err = json.Unmarshal([]byte(data), mirror); err != nil {
+		return nil, err
+	}
+	return mirror, nil
+}
+
+func (s *RedisStore) StoreParticipantBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, estimate int64, ttl time.Duration) error {
+	return s.rc.Set(ctx, participantBandwidthEstimateKey(roomName, identity), estimate, ttl).Err()
+}
+
+func (s *RedisStore) LoadParticipantBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (int64, error) {
+	estimate, err := s.rc.Get(ctx, participantBandwidthEstimateKey(roomName, identity)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return estimate, err
+}
+
+func (s *RedisStore) DeleteParticipantBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	return s.rc.Del(ctx, participantBandwidthEstimateKey(roomName, identity)).Err()
+}
+
+func (s *RedisStore) StoreIdentityBinding(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, binding *IdentityBinding, ttl time.Duration) error {
+	key := identityBindingKey(roomName, identity)
+	_, err := s.rc.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, key, "fingerprint", binding.Fingerprint, "participant_id", string(binding.ParticipantID))
+		if ttl > 0 {
+			p.Expire(ctx, key, ttl)
+		} else {
+			p.Persist(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) LoadIdentityBinding(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) (*IdentityBinding, error) {
+	fields, err := s.rc.HGetAll(ctx, identityBindingKey(roomName, identity)).Result()
+	if err != nil || len(fields) == 0 {
+		return nil, err
+	}
+	return &IdentityBinding{
+		Fingerprint:   fields["fingerprint"],
+		ParticipantID: livekit.ParticipantID(fields["participant_id"]),
+	}, nil
+}
+
+func identityBindingKey(roomName livekit.RoomName, identity livekit.ParticipantIdentity) string {
+	return IdentityBindingPrefix + string(roomName) + ":" + string(identity)
+}
+
+func (s *RedisStore) StoreFingerprintNonce(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string, ttl time.Duration) error {
+	return s.rc.Set(ctx, fingerprintNonceKey(roomName, identity, nonce), 1, ttl).Err()
+}
+
+func (s *RedisStore) ConsumeFingerprintNonce(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string) (bool, error) {
+	// only one of concurrent joins presenting the nonce removes it
+	removed, err := s.rc.Del(ctx, fingerprintNonceKey(roomName, identity, nonce)).Result()
+	return removed == 1, err
+}
+
+func fingerprintNonceKey(roomName livekit.RoomName, identity livekit.ParticipantIdentity, nonce string) string {
+	return FingerprintNoncePrefix + string(roomName) + ":" + string(identity) + ":" + nonce
+}
+
+func participantBandwidthEstimateKey(roomName livekit.RoomName, identity livekit.ParticipantIdentity) string {
+	return ParticipantBandwidthEstimatePrefix + string(roomName) + ":" + string(identity)
+}
+
+func (s *RedisStore) StoreTURNSession(ctx context.Context, participantID livekit.ParticipantID, secret []byte, ttl time.Duration) error {
+	return s.rc.Set(ctx, TURNSessionPrefix+string(participantID), secret, ttl).Err()
+}
+
+func (s *RedisStore) LoadTURNSession(ctx context.Context, participantID livekit.ParticipantID) ([]byte, error) {
+	secret, err := s.rc.Get(ctx, TURNSessionPrefix+string(participantID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return secret, err
+}
+
+func (s *RedisStore) DeleteTURNSession(ctx context.Context, participantID livekit.ParticipantID) error {
+	return s.rc.Del(ctx, TURNSessionPrefix+string(participantID)).Err()
+}
+
 func (s *RedisStore) StoreParticipant(_ context.Context, roomName livekit.RoomName, participant *livekit.ParticipantInfo) error {
 	key := RoomParticipantsPrefix + string(roomName)
 
@@ -425,6 +730,41 @@ func (s *RedisStore) UpdateEgress(_ context.Context, info *livekit.EgressInfo) e
 	return nil
 }
 
+// SwapEgress updates an egress and returns the info it replaced, nil when there was none. Nodes updating the same
+// egress concurrently each get the info they replaced, so that changes between updates are seen once
+func (s *RedisStore) SwapEgress(_ context.Context, info *livekit.EgressInfo) (*livekit.EgressInfo, error) {
+	data, err := proto.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	var ended string
+	if info.EndedAt != 0 {
+		ended = egressEndedValue(info.RoomName, info.EndedAt)
+	}
+
+	// the swap is a script rather than a transaction watching EgressKey, which every update of every egress changes
+	old, err := s.swapEgressScript.Run(s.ctx, s.rc, []string{EgressKey, EndedEgressKey}, info.EgressId, data, ended).Result()
+	switch err {
+	case redis.Nil:
+		return nil, nil
+	case nil:
+	default:
+		return nil, errors.Wrap(err, "could not update egress info")
+	}
+
+	oldData, ok := old.(string)
+	if !ok {
+		return nil, nil
+	}
+	previous := &livekit.EgressInfo{}
+	if err = proto.Unmarshal([]byte(oldData), previous); err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
 // Deletes egress info 24h after the egress has ended
 func (s *RedisStore) egressWorker() {
 	ticker := time.NewTicker(time.Minute * 30)
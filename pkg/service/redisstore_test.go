@@ -13,6 +13,7 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/utils"
 
+	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/service"
 )
 
@@ -198,10 +199,21 @@ func TestEgressStore(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, list, 1)
 
+	// swap returns the info replaced
+	info.Status = livekit.EgressStatus_EGRESS_ACTIVE
+	previous, err := rs.SwapEgress(ctx, info)
+	require.NoError(t, err)
+	require.Equal(t, livekit.EgressStatus_EGRESS_STARTING, previous.Status)
+	previous, err = rs.SwapEgress(ctx, info)
+	require.NoError(t, err)
+	require.Equal(t, livekit.EgressStatus_EGRESS_ACTIVE, previous.Status)
+
 	// update
 	info.Status = livekit.EgressStatus_EGRESS_COMPLETE
 	info.EndedAt = time.Now().Add(-24 * time.Hour).UnixNano()
-	require.NoError(t, rs.UpdateEgress(ctx, info))
+	previous, err = rs.SwapEgress(ctx, info)
+	require.NoError(t, err)
+	require.Equal(t, livekit.EgressStatus_EGRESS_ACTIVE, previous.Status)
 
 	// clean
 	require.NoError(t, rs.CleanEndedEgress())
@@ -280,3 +292,109 @@ func compareIngressInfo(t *testing.T, expected, v *livekit.IngressInfo) {
 	require.Equal(t, expected.StreamKey, v.StreamKey)
 	require.Equal(t, expected.RoomName, v.RoomName)
 }
+
+func TestRoomTimelinePersistence(t *testing.T) {
+	ctx := context.Background()
+	rc := redisClient()
+	rs := service.NewRedisTimelineStore(rc, config.TimelineConfig{MaxEvents: 2})
+	t.Cleanup(func() { rc.Del(ctx, service.RoomTimelinePrefix+"timeline_room") })
+
+	now := time.Now().Truncate(time.Microsecond)
+	require.NoError(t, rs.StoreRoomEvents(ctx, "timeline_room", []*service.RoomEvent{
+		{Type: service.RoomEventRoomStarted, Time: now},
+		{Type: service.RoomEventParticipantJoined, Time: now.Add(time.Millisecond), Identity: "alice"},
+		{Type: service.RoomEventParticipantLeft, Time: now.Add(2 * time.Millisecond), Identity: "alice"},
+	}))
+
+	// oldest event is trimmed
+	events, err := rs.ListRoomEvents(ctx, "timeline_room", time.Time{}, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, service.RoomEventParticipantJoined, events[0].Type)
+	require.True(t, events[0].Time.Equal(now.Add(time.Millisecond)))
+
+	events, err = rs.ListRoomEvents(ctx, "timeline_room", now, now.Add(2*time.Millisecond), 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "alice", events[0].Identity)
+}
+
+func TestListRoomsPage(t *testing.T) {
+	ctx := context.Background()
+	rs := service.NewRedisStore(redisClient())
+	t.Cleanup(func() {
+		for _, name := range []livekit.RoomName{"page_a", "page_b", "page_c", "page_d"} {
+			_ = rs.DeleteRoom(ctx, name)
+		}
+	})
+
+	for _, rm := range []*livekit.Room{
+		{Name: "page_c", NumParticipants: 3, CreationTime: 30, Metadata: "page"},
+		{Name: "page_a", NumParticipants: 1, CreationTime: 10, Metadata: "page"},
+		{Name: "page_d", NumParticipants: 0, CreationTime: 40, Metadata: "page"},
+		{Name: "page_b", NumParticipants: 5, CreationTime: 20, Metadata: "page"},
+	} {
+		require.NoError(t, rs.StoreRoom(ctx, rm, nil))
+	}
+	// moves page_b behind page_c in the participant index
+	require.NoError(t, rs.StoreRoom(ctx, &livekit.Room{Name: "page_b", NumParticipants: 2, CreationTime: 20, Metadata: "page"}, nil))
+
+	roomNames := func(rooms []*livekit.Room) []string {
+		var names []string
+		for _, rm := range rooms {
+			names = append(names, rm.Name)
+		}
+		return names
+	}
+
+	opts := &service.ListRoomsOptions{SortBy: service.RoomSortNumParticipants, Descending: true, Limit: 2, MetadataContains: "page"}
+	rooms, next, err := rs.ListRoomsPage(ctx, opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"page_c", "page_b"}, roomNames(rooms))
+	require.NotEmpty(t, next)
+
+	opts.Cursor = next
+	rooms, next, err = rs.ListRoomsPage(ctx, opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"page_a", "page_d"}, roomNames(rooms))
+	require.Empty(t, next)
+
+	rooms, _, err = rs.ListRoomsPage(ctx, &service.ListRoomsOptions{SortBy: service.RoomSortCreationTime, CreatedAfter: 15, MetadataContains: "page"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"page_b", "page_c", "page_d"}, roomNames(rooms))
+
+	require.NoError(t, rs.DeleteRoom(ctx, "page_c"))
+	rooms, _, err = rs.ListRoomsPage(ctx, &service.ListRoomsOptions{Limit: 10, MetadataContains: "page"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"page_a", "page_b", "page_d"}, roomNames(rooms))
+}
+
+func TestRoomMirrorPersistence(t *testing.T) {
+	ctx := context.Background()
+	rs := service.NewRedisStore(redisClient())
+	mirror := &service.RoomMirror{
+		NodeID: "primary",
+		Participants: map[livekit.ParticipantIdentity]*service.MirroredParticipant{
+			"alice": {SID: "PA_alice", Tracks: []*livekit.TrackInfo{{Sid: "TR_alice", Type: livekit.TrackType_AUDIO}}},
+		},
+		UpdatedAt: time.Now(),
+	}
+
+	// not stored without the room
+	require.NoError(t, rs.StoreRoomMirror(ctx, "mirror_room", mirror))
+	loaded, err := rs.LoadRoomMirror(ctx, "mirror_room")
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+
+	require.NoError(t, rs.StoreRoom(ctx, &livekit.Room{Name: "mirror_room"}, nil))
+	require.NoError(t, rs.StoreRoomMirror(ctx, "mirror_room", mirror))
+	loaded, err = rs.LoadRoomMirror(ctx, "mirror_room")
+	require.NoError(t, err)
+	require.Equal(t, livekit.TrackType_AUDIO, loaded.Participants["alice"].Tracks[0].Type)
+
+	require.NoError(t, rs.DeleteRoom(ctx, "mirror_room"))
+	require.NoError(t, rs.StoreRoomMirror(ctx, "mirror_room", mirror))
+	loaded, err = rs.LoadRoomMirror(ctx, "mirror_room")
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+}
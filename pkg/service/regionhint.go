@@ -0,0 +1,34 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/livekit/livekit-server/pkg/routing"
+)
+
+const (
+	regionHintHeader = "X-LiveKit-Region"
+	regionHintParam  = "region"
+)
+
+// RegionHintMiddleware passes the region a client asks rooms and participants to be placed in on to the room
+// allocator and router, from the region query parameter of joins or the X-LiveKit-Region header of API requests.
+// Hints are preferences, nodes of other regions are used when none of the region is available. They only apply when
+// a room is created and when a participant spills over to an edge node because the room's node is full, joining an
+// existing room on a node with capacity places the participant on that node regardless of the hint
+type RegionHintMiddleware struct{}
+
+func NewRegionHintMiddleware() *RegionHintMiddleware {
+	return &RegionHintMiddleware{}
+}
+
+func (m *RegionHintMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	region := r.Header.Get(regionHintHeader)
+	if region == "" {
+		region = r.URL.Query().Get(regionHintParam)
+	}
+	if region != "" {
+		r = r.WithContext(routing.WithRegionHint(r.Context(), region))
+	}
+	next.ServeHTTP(w, r)
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	retentionPathPrefix = "/retention/"
+
+	// RetentionLockKey is held by the node checking for expired rooms, for the check interval
+	RetentionLockKey = "retention_lock"
+
+	defaultRetentionCheckInterval = time.Hour
+)
+
+// RoomDataDeletion is what a retention policy deleted, or would delete in a dry run, for a closed room
+type RoomDataDeletion struct {
+	RoomSID    string    `json:"room_sid"`
+	RoomName   string    `json:"room_name"`
+	APIKey     string    `json:"api_key,omitempty"`
+	Policy     string    `json:"policy"`
+	DryRun     bool      `json:"dry_run"`
+	FinishedAt time.Time `json:"finished_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
+	Timeline   bool      `json:"timeline"`
+	// recordings and playlists deleted from the storage bucket
+	Artifacts []*RoomArtifact `json:"artifacts,omitempty"`
+	// the room is checked again on the next run when anything could not be deleted
+	Errors []string `json:"errors,omitempty"`
+}
+
+type RetentionReport struct {
+	DryRun    bool                `json:"dry_run"`
+	CheckedAt time.Time           `json:"checked_at"`
+	Rooms     []*RoomDataDeletion `json:"rooms"`
+}
+
+// RetentionService deletes the manifest, timeline and artifacts of closed rooms once their retention policy
+// expires. When running with redis, only one node checks at a time. Requests to /retention/PreviewRetention
+// return what would be deleted now and require a token signed with an admin API key
+type RetentionService struct {
+	conf          config.RetentionConfig
+	roomStore     ServiceStore
+	manifestStore RoomManifestStore
+	timelineStore RoomTimelineStore
+	uploader      *objectUploader
+	telemetry     telemetry.TelemetryService
+	rc            redis.UniversalClient
+	nodeID        livekit.NodeID
+
+	// only one run at a time on this node
+	runLock  sync.Mutex
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewRetentionService(
+	conf *config.Config,
+	roomStore ServiceStore,
+	manifestStore RoomManifestStore,
+	timelineStore RoomTimelineStore,
+	ts telemetry.TelemetryService,
+	rc redis.UniversalClient,
+	currentNode routing.LocalNode,
+) (*RetentionService, error) {
+	s := &RetentionService{
+		conf:          conf.Retention,
+		roomStore:     roomStore,
+		manifestStore: manifestStore,
+		timelineStore: timelineStore,
+		telemetry:     ts,
+		rc:            rc,
+		nodeID:        livekit.NodeID(currentNode.Id),
+		done:          make(chan struct{}),
+	}
+	if len(s.conf.Policies) == 0 {
+		return s, nil
+	}
+
+	// rooms are found by their manifest, it has to outlive every policy
+	if manifestStore == nil {
+		return nil, errors.New("retention policies require room_manifest to be enabled")
+	}
+	for _, p := range s.conf.Policies {
+		if p.Days <= 0 {
+			return nil, fmt.Errorf("retention policy %s: days must be positive", p.Name)
+		}
+		if days := time.Duration(p.Days) * 24 * time.Hour; days > manifestRetention(conf.RoomManifest) {
+			return nil, fmt.Errorf("retention policy %s: room_manifest.retention must be at least %d days", p.Name, p.Days)
+		}
+	}
+
+	uploader, err := newObjectUploader(s.conf.Storage)
+	if err != nil {
+		return nil, err
+	}
+	s.uploader = uploader
+	return s, nil
+}
+
+func (s *RetentionService) Start() {
+	if len(s.conf.Policies) == 0 {
+		return
+	}
+	go s.worker()
+}
+
+func (s *RetentionService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *RetentionService) worker() {
+	interval := s.conf.CheckInterval
+	if interval <= 0 {
+		interval = defaultRetentionCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			if s.rc != nil {
+				// the lock expires on its own, so the check moves to another node when this one goes away
+				locked, err := s.rc.SetNX(ctx, RetentionLockKey, string(s.nodeID), interval).Result()
+				if err != nil {
+					logger.Warnw("could not lock retention check", err)
+					continue
+				}
+				if !locked {
+					continue
+				}
+			}
+			if _, err := s.Run(ctx, s.conf.DryRun); err != nil {
+				logger.Warnw("could not apply retention policies", err)
+			}
+		}
+	}
+}
+
+// Run deletes the data of every closed room whose policy has expired. In a dry run nothing is deleted and
+// no webhooks are sent, the report lists what would be deleted
+func (s *RetentionService) Run(ctx context.Context, dryRun bool) (*RetentionReport, error) {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+
+	report := &RetentionReport{
+		DryRun:    dryRun,
+		CheckedAt: time.Now(),
+		Rooms:     []*RoomDataDeletion{},
+	}
+	if len(s.conf.Policies) == 0 {
+		return report, nil
+	}
+
+	manifests, err := s.manifestStore.ListRoomManifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the timeline of a room is shared by its sessions, it goes with the last one
+	lastFinishedAt := make(map[string]time.Time, len(manifests))
+	for _, m := range manifests {
+		if m.FinishedAt.After(lastFinishedAt[m.RoomName]) {
+			lastFinishedAt[m.RoomName] = m.FinishedAt
+		}
+	}
+	for _, m := range manifests {
+		policy := s.matchPolicy(m)
+		if policy == nil {
+			continue
+		}
+		expiredAt := m.FinishedAt.Add(time.Duration(policy.Days) * 24 * time.Hour)
+		if report.CheckedAt.Before(expiredAt) {
+			continue
+		}
+		// the timeline is shared with a new session of the room
+		if _, _, err := s.roomStore.LoadRoom(ctx, livekit.RoomName(m.RoomName), false); err == nil {
+			continue
+		}
+
+		deletion := &RoomDataDeletion{
+			RoomSID:    m.RoomSID,
+			RoomName:   m.RoomName,
+			APIKey:     m.APIKey,
+			Policy:     policy.Name,
+			DryRun:     dryRun,
+			FinishedAt: m.FinishedAt,
+			ExpiredAt:  expiredAt,
+		}
+		s.deleteRoomData(ctx, m, policy, !m.FinishedAt.Before(lastFinishedAt[m.RoomName]), deletion)
+		report.Rooms = append(report.Rooms, deletion)
+
+		if dryRun {
+			logger.Infow("retention dry run, would delete room data",
+				"room", m.RoomName,
+				"roomID", m.RoomSID,
+				"policy", policy.Name,
+				"artifacts", len(deletion.Artifacts),
+			)
+			continue
+		}
+		logger.Infow("deleted room data",
+			"room", m.RoomName,
+			"roomID", m.RoomSID,
+			"policy", policy.Name,
+			"artifacts", len(deletion.Artifacts),
+			"errors", deletion.Errors,
+		)
+		s.telemetry.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
+			Event: telemetry.EventRoomDataDeleted,
+			Room:  &livekit.Room{Sid: m.RoomSID, Name: m.RoomName},
+		}, map[string]interface{}{"retention": deletion})
+	}
+	return report, nil
+}
+
+func (s *RetentionService) matchPolicy(m *RoomManifest) *config.RetentionPolicy {
+	for i := range s.conf.Policies {
+		p := &s.conf.Policies[i]
+		if p.APIKey != "" && p.APIKey != m.APIKey {
+			continue
+		}
+		if !strings.HasPrefix(m.RoomName, p.RoomPrefix) {
+			continue
+		}
+		return p
+	}
+	return nil
+}
+
+func (s *RetentionService) deleteRoomData(
+	ctx context.Context,
+	m *RoomManifest,
+	policy *config.RetentionPolicy,
+	deleteTimeline bool,
+	deletion *RoomDataDeletion,
+) {
+	dryRun := deletion.DryRun
+	addError := func(what string, err error) {
+		deletion.Errors = append(deletion.Errors, fmt.Sprintf("%s: %v", what, err))
+	}
+
+	if policy.DeleteArtifacts && s.uploader != nil {
+		for _, a := range m.Artifacts {
+			// only the playlist of segments is known from the manifest
+			if a.Kind != RoomArtifactRecording && a.Kind != RoomArtifactSegments {
+				continue
+			}
+			key, ok := s.uploader.ObjectKey(a.Location)
+			if !ok {
+				continue
+			}
+			if !dryRun {
+				if err := s.uploader.Delete(ctx, key); err != nil {
+					addError(a.Location, err)
+					continue
+				}
+			}
+			deletion.Artifacts = append(deletion.Artifacts, a)
+		}
+	}
+
+	if s.timelineStore != nil && deleteTimeline {
+		var err error
+		if !dryRun {
+			err = s.timelineStore.DeleteRoomEvents(ctx, livekit.RoomName(m.RoomName))
+		}
+		if err != nil {
+			addError("timeline", err)
+		} else {
+			deletion.Timeline = true
+		}
+	}
+
+	// kept until everything else is deleted, so that failures are retried
+	if !dryRun && len(deletion.Errors) == 0 {
+		if err := s.manifestStore.DeleteRoomManifest(ctx, m); err != nil {
+			addError("manifest", err)
+		}
+	}
+}
+
+func (s *RetentionService) PathPrefix() string {
+	return retentionPathPrefix
+}
+
+func (s *RetentionService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := EnsureNodeAdminPermission(r.Context()); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if strings.TrimPrefix(r.URL.Path, retentionPathPrefix) != "PreviewRetention" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if len(s.conf.Policies) == 0 {
+		handleError(w, http.StatusNotImplemented, ErrRetentionNotEnabled)
+		return
+	}
+
+	report, err := s.Run(r.Context(), true)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,236 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestRetention(t *testing.T) {
+	var lock sync.Mutex
+	var deleted []string
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer bucket.Close()
+
+	conf := &config.Config{
+		Retention: config.RetentionConfig{
+			Policies: []config.RetentionPolicy{
+				{Name: "support", APIKey: "key1", RoomPrefix: "support-", Days: 2, DeleteArtifacts: true},
+				{Name: "default", Days: 5},
+			},
+			Storage: config.ObjectStorageConfig{
+				Endpoint:       bucket.URL,
+				Bucket:         "recordings",
+				ForcePathStyle: true,
+			},
+		},
+	}
+	ctx := context.Background()
+	roomStore := service.NewLocalStore()
+	manifestStore := service.NewLocalManifestStore(config.RoomManifestConfig{})
+	timelineStore := service.NewLocalTimelineStore(config.TimelineConfig{})
+	ts := &telemetryfakes.FakeTelemetryService{}
+
+	finishedAt := time.Now().Add(-3 * 24 * time.Hour)
+	for _, m := range []*service.RoomManifest{
+		{
+			RoomSID:    "RM_1",
+			RoomName:   "support-1",
+			APIKey:     "key1",
+			FinishedAt: finishedAt,
+			Artifacts: []*service.RoomArtifact{
+				{Kind: service.RoomArtifactRecording, ID: "EG_1", Location: "s3://recordings/support-1.mp4"},
+				{Kind: service.RoomArtifactRecording, ID: "EG_2", Location: "s3://elsewhere/support-1.mp4"},
+				{Kind: service.RoomArtifactTimeline, ID: "support-1"},
+			},
+		},
+		// the default policy has not expired yet
+		{RoomSID: "RM_2", RoomName: "support-2", APIKey: "key2", FinishedAt: finishedAt},
+		// open again
+		{RoomSID: "RM_3", RoomName: "support-3", APIKey: "key1", FinishedAt: finishedAt},
+	} {
+		require.NoError(t, manifestStore.StoreRoomManifest(ctx, m))
+		require.NoError(t, timelineStore.StoreRoomEvents(ctx, livekit.RoomName(m.RoomName), []*service.RoomEvent{{Time: m.FinishedAt}}))
+	}
+	require.NoError(t, roomStore.StoreRoom(ctx, &livekit.Room{Name: "support-3"}, nil))
+
+	s, err := service.NewRetentionService(conf, roomStore, manifestStore, timelineStore, ts, nil, &livekit.Node{Id: "ND_1"})
+	require.NoError(t, err)
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := s.Run(ctx, true)
+		require.NoError(t, err)
+		require.True(t, report.DryRun)
+		require.Len(t, report.Rooms, 1)
+		require.Equal(t, "support-1", report.Rooms[0].RoomName)
+		require.Equal(t, "support", report.Rooms[0].Policy)
+		require.True(t, report.Rooms[0].Timeline)
+		require.Len(t, report.Rooms[0].Artifacts, 1)
+		require.Equal(t, "EG_1", report.Rooms[0].Artifacts[0].ID)
+
+		require.Empty(t, deleted)
+		require.Zero(t, ts.NotifyEventWithFieldsCallCount())
+		_, err = manifestStore.LoadRoomManifest(ctx, "support-1")
+		require.NoError(t, err)
+	})
+
+	t.Run("api", func(t *testing.T) {
+		request := func(grant *auth.VideoGrant, nodeAdmin bool) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/retention/PreviewRetention", strings.NewReader("{}"))
+			reqCtx := service.WithGrants(ctx, &auth.ClaimGrants{Video: grant})
+			if nodeAdmin {
+				reqCtx = service.WithNodeAdmin(reqCtx)
+			}
+			r = r.WithContext(reqCtx)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			return w
+		}
+
+		w := request(&auth.VideoGrant{RoomAdmin: true, Room: "support-1"}, false)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = request(&auth.VideoGrant{RoomCreate: true, RoomList: true}, false)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = request(&auth.VideoGrant{}, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		report := &service.RetentionReport{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), report))
+		require.True(t, report.DryRun)
+		require.Len(t, report.Rooms, 1)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		report, err := s.Run(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, report.Rooms, 1)
+		require.Empty(t, report.Rooms[0].Errors)
+		require.Equal(t, []string{"/recordings/support-1.mp4"}, deleted)
+
+		_, err = manifestStore.LoadRoomManifest(ctx, "support-1")
+		require.ErrorIs(t, err, service.ErrRoomManifestNotFound)
+		events, err := timelineStore.ListRoomEvents(ctx, "support-1", time.Time{}, time.Time{}, 0)
+		require.NoError(t, err)
+		require.Empty(t, events)
+		// other rooms are kept
+		for _, roomName := range []livekit.RoomName{"support-2", "support-3"} {
+			_, err = manifestStore.LoadRoomManifest(ctx, roomName)
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, 1, ts.NotifyEventWithFieldsCallCount())
+		_, event, fields := ts.NotifyEventWithFieldsArgsForCall(0)
+		require.Equal(t, telemetry.EventRoomDataDeleted, event.Event)
+		require.Equal(t, "RM_1", event.Room.Sid)
+		deletion := fields["retention"].(*service.RoomDataDeletion)
+		require.Equal(t, "support", deletion.Policy)
+		require.False(t, deletion.DryRun)
+
+		// nothing left to delete
+		report, err = s.Run(ctx, false)
+		require.NoError(t, err)
+		require.Empty(t, report.Rooms)
+	})
+
+	t.Run("requires manifests", func(t *testing.T) {
+		_, err := service.NewRetentionService(conf, roomStore, nil, timelineStore, ts, nil, &livekit.Node{Id: "ND_1"})
+		require.Error(t, err)
+
+		longer := *conf
+		longer.Retention.Policies = []config.RetentionPolicy{{Name: "long", Days: 30}}
+		_, err = service.NewRetentionService(&longer, roomStore, manifestStore, timelineStore, ts, nil, &livekit.Node{Id: "ND_1"})
+		require.Error(t, err)
+	})
+}
+
+func TestRetentionRoomSessions(t *testing.T) {
+	var lock sync.Mutex
+	var deleted []string
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method == http.MethodDelete {
+			deleted = append(deleted, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer bucket.Close()
+
+	conf := &config.Config{
+		Retention: config.RetentionConfig{
+			Policies: []config.RetentionPolicy{{Name: "default", Days: 2, DeleteArtifacts: true}},
+			Storage: config.ObjectStorageConfig{
+				Endpoint:       bucket.URL,
+				Bucket:         "recordings",
+				ForcePathStyle: true,
+			},
+		},
+	}
+	ctx := context.Background()
+	manifestStore := service.NewLocalManifestStore(config.RoomManifestConfig{})
+	timelineStore := service.NewLocalTimelineStore(config.TimelineConfig{})
+
+	// two sessions of a room, the first one expired
+	for _, m := range []*service.RoomManifest{
+		{
+			RoomSID:    "RM_1",
+			RoomName:   "room",
+			FinishedAt: time.Now().Add(-3 * 24 * time.Hour),
+			Artifacts:  []*service.RoomArtifact{{Kind: service.RoomArtifactRecording, ID: "EG_1", Location: "s3://recordings/first.mp4"}},
+		},
+		{
+			RoomSID:    "RM_2",
+			RoomName:   "room",
+			FinishedAt: time.Now().Add(-time.Hour),
+			Artifacts:  []*service.RoomArtifact{{Kind: service.RoomArtifactRecording, ID: "EG_2", Location: "s3://recordings/second.mp4"}},
+		},
+	} {
+		require.NoError(t, manifestStore.StoreRoomManifest(ctx, m))
+	}
+	require.NoError(t, timelineStore.StoreRoomEvents(ctx, "room", []*service.RoomEvent{{Time: time.Now()}}))
+
+	s, err := service.NewRetentionService(conf, service.NewLocalStore(), manifestStore, timelineStore, &telemetryfakes.FakeTelemetryService{}, nil, &livekit.Node{Id: "ND_1"})
+	require.NoError(t, err)
+
+	report, err := s.Run(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, report.Rooms, 1)
+	require.Equal(t, "RM_1", report.Rooms[0].RoomSID)
+	// the timeline is kept for the later session
+	require.False(t, report.Rooms[0].Timeline)
+	require.Equal(t, []string{"/recordings/first.mp4"}, deleted)
+
+	manifest, err := manifestStore.LoadRoomManifest(ctx, "room")
+	require.NoError(t, err)
+	require.Equal(t, "RM_2", manifest.RoomSID)
+	manifests, err := manifestStore.ListRoomManifests(ctx)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	events, err := timelineStore.ListRoomEvents(ctx, "room", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	roomAccessPathPrefix = "/room_access/"
+
+	// generated codes are read out and typed by people, ambiguous characters are left out
+	joinCodeAlphabet      = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	generatedJoinCodeSize = 12
+	minJoinCodeSize       = 10
+	maxJoinCodeSize       = 64
+
+	// failed join code attempts allowed from an address at once, and how often another one is allowed after that
+	joinCodeAttemptBurst    = 10
+	joinCodeAttemptInterval = 6 * time.Second
+	// how often addresses that are allowed a full burst again are forgotten
+	joinCodeAttemptsPruneInterval = time.Minute
+)
+
+var ErrInvalidJoinCodeLength = errors.New("join code must be between 10 and 64 characters")
+
+type UpdateRoomRequest struct {
+	Room string `json:"room"`
+	// code participants have to join with, empty clears it unless rotate is set
+	JoinCode string `json:"join_code,omitempty"`
+	// replaces the join code with a generated one
+	Rotate bool `json:"rotate,omitempty"`
+}
+
+type UpdateRoomResponse struct {
+	Room string `json:"room"`
+	// the code now required to join, empty when the room is open to any valid token
+	JoinCode string `json:"join_code"`
+}
+
+// RoomAccessService sets the join code of a room, a secret participants supply when connecting in addition to their
+// token so that a room can be shared with a human readable code without minting new tokens. Only a hash of the code
+// is stored, it is returned once when set. Requests are JSON posted to /room_access/UpdateRoom and require the
+// roomAdmin grant for the room
+type RoomAccessService struct {
+	roomStore ObjectStore
+}
+
+func NewRoomAccessService(roomStore ObjectStore) *RoomAccessService {
+	return &RoomAccessService{
+		roomStore: roomStore,
+	}
+}
+
+func (s *RoomAccessService) PathPrefix() string {
+	return roomAccessPathPrefix
+}
+
+func (s *RoomAccessService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, roomAccessPathPrefix) {
+	case "UpdateRoom":
+		s.updateRoom(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *RoomAccessService) updateRoom(w http.ResponseWriter, r *http.Request) {
+	req := &UpdateRoomRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil || req.Room == "" {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	res, err := s.UpdateRoom(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidJoinCodeLength):
+			handleError(w, http.StatusBadRequest, err)
+		case errors.Is(err, ErrRoomNotFound):
+			handleError(w, http.StatusNotFound, err, "room", req.Room)
+		default:
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// UpdateRoom sets, rotates or clears the join code of an open room
+func (s *RoomAccessService) UpdateRoom(ctx context.Context, req *UpdateRoomRequest) (*UpdateRoomResponse, error) {
+	roomName := livekit.RoomName(req.Room)
+	if _, _, err := s.roomStore.LoadRoom(ctx, roomName, false); err != nil {
+		return nil, err
+	}
+
+	code := req.JoinCode
+	if req.Rotate {
+		var err error
+		if code, err = generateJoinCode(); err != nil {
+			return nil, err
+		}
+	}
+
+	codeHash := ""
+	if code != "" {
+		normalized := normalizeJoinCode(code)
+		if len(normalized) < minJoinCodeSize || len(normalized) > maxJoinCodeSize {
+			return nil, ErrInvalidJoinCodeLength
+		}
+		codeHash = hashJoinCode(normalized)
+	}
+	if err := s.roomStore.StoreRoomJoinCode(ctx, roomName, codeHash); err != nil {
+		return nil, err
+	}
+
+	// the code itself is not logged
+	logger.Infow("room join code updated", "room", roomName, "enabled", codeHash != "", "rotated", req.Rotate)
+	return &UpdateRoomResponse{
+		Room:     req.Room,
+		JoinCode: code,
+	}, nil
+}
+
+// checkJoinCode tells whether a code supplied by a client matches the join code hash of a room
+func checkJoinCode(codeHash string, code string) bool {
+	if codeHash == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashJoinCode(normalizeJoinCode(code)))) == 1
+}
+
+// normalizeJoinCode ignores case and the separators people add when typing a code
+func normalizeJoinCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+func hashJoinCode(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateJoinCode returns a random code formatted as XXXX-XXXX-XXXX
+func generateJoinCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+	for i := 0; i < generatedJoinCodeSize; i++ {
+		if i != 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(joinCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// ---------------------------------------------
+
+// joinCodeLimiter limits failed join code attempts per client address so that codes cannot be enumerated by
+// connecting repeatedly. Attempts are counted by each node on its own
+type joinCodeLimiter struct {
+	lock     sync.Mutex
+	attempts map[string]*joinCodeAttempts
+	prunedAt time.Time
+}
+
+type joinCodeAttempts struct {
+	// attempts still allowed, refilled one per joinCodeAttemptInterval up to joinCodeAttemptBurst
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newJoinCodeLimiter() *joinCodeLimiter {
+	return &joinCodeLimiter{
+		attempts: make(map[string]*joinCodeAttempts),
+	}
+}
+
+// allow tells whether an address has attempts left
+func (l *joinCodeLimiter) allow(address string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	a := l.attempts[address]
+	if a == nil {
+		return true
+	}
+	return a.refill(now) >= 1
+}
+
+// failed uses up an attempt of an address
+func (l *joinCodeLimiter) failed(address string, now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	a := l.attempts[address]
+	if a == nil {
+		a = &joinCodeAttempts{tokens: joinCodeAttemptBurst, updatedAt: now}
+		l.attempts[address] = a
+	}
+	if a.refill(now) >= 1 {
+		a.tokens--
+	}
+
+	if now.Sub(l.prunedAt) >= joinCodeAttemptsPruneInterval {
+		l.prunedAt = now
+		for addr, attempts := range l.attempts {
+			if attempts.refill(now) >= joinCodeAttemptBurst {
+				delete(l.attempts, addr)
+			}
+		}
+	}
+}
+
+func (a *joinCodeAttempts) refill(now time.Time) float64 {
+	if now.After(a.updatedAt) {
+		a.tokens += float64(now.Sub(a.updatedAt)) / float64(joinCodeAttemptInterval)
+		if a.tokens > joinCodeAttemptBurst {
+			a.tokens = joinCodeAttemptBurst
+		}
+		a.updatedAt = now
+	}
+	return a.tokens
+}
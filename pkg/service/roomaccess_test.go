@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestRoomAccessService(t *testing.T) {
+	store := NewLocalStore()
+	require.NoError(t, store.StoreRoom(context.Background(), &livekit.Room{Name: "room"}, nil))
+	svc := NewRoomAccessService(store)
+
+	request := func(body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+"UpdateRoom", strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+	joinCodeHash := func() string {
+		codeHash, err := store.LoadRoomJoinCode(context.Background(), "room")
+		require.NoError(t, err)
+		return codeHash
+	}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request(`{"room": "room", "join_code": "1234-5678-90"}`, nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request(`{"room": "other", "join_code": "1234-5678-90"}`, admin).Code)
+		require.Empty(t, joinCodeHash())
+	})
+
+	t.Run("validates", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, request(`{"room": "room", "join_code": "12-3"}`, admin).Code)
+		require.Equal(t, http.StatusBadRequest, request(`{"room": "room", "join_code": "1234-5678"}`, admin).Code)
+		require.Equal(t, http.StatusNotFound, request(`{"room": "other", "join_code": "1234-5678-90"}`, &auth.VideoGrant{RoomAdmin: true, Room: "other"}).Code)
+	})
+
+	t.Run("sets join code", func(t *testing.T) {
+		w := request(`{"room": "room", "join_code": "blue-fox-jumps"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &UpdateRoomResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+		require.Equal(t, "blue-fox-jumps", res.JoinCode)
+
+		// only the hash is stored, case and separators are ignored
+		codeHash := joinCodeHash()
+		require.NotContains(t, strings.ToUpper(codeHash), "BLUEFOXJUMPS")
+		require.True(t, checkJoinCode(codeHash, "blue-fox-jumps"))
+		require.True(t, checkJoinCode(codeHash, "BLUE FOX JUMPS"))
+		require.False(t, checkJoinCode(codeHash, "blue-cat-jumps"))
+		require.False(t, checkJoinCode(codeHash, ""))
+	})
+
+	t.Run("rotates join code", func(t *testing.T) {
+		previous := joinCodeHash()
+		w := request(`{"room": "room", "rotate": true}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &UpdateRoomResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+		require.Len(t, res.JoinCode, generatedJoinCodeSize+2)
+
+		codeHash := joinCodeHash()
+		require.NotEqual(t, previous, codeHash)
+		require.True(t, checkJoinCode(codeHash, res.JoinCode))
+		require.False(t, checkJoinCode(codeHash, "blue-fox-jumps"))
+	})
+
+	t.Run("clears join code", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(`{"room": "room"}`, admin).Code)
+		require.Empty(t, joinCodeHash())
+		require.True(t, checkJoinCode("", ""))
+	})
+
+	t.Run("removed with the room", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(`{"room": "room", "join_code": "1234-5678-90"}`, admin).Code)
+		require.NotEmpty(t, joinCodeHash())
+		require.NoError(t, store.DeleteRoom(context.Background(), "room"))
+		require.Empty(t, joinCodeHash())
+	})
+}
+
+func TestJoinCodeLimiter(t *testing.T) {
+	l := newJoinCodeLimiter()
+	now := time.Now()
+
+	for i := 0; i < joinCodeAttemptBurst; i++ {
+		require.True(t, l.allow("1.1.1.1", now))
+		l.failed("1.1.1.1", now)
+	}
+	require.False(t, l.allow("1.1.1.1", now))
+	// other addresses are not affected
+	require.True(t, l.allow("2.2.2.2", now))
+
+	// attempts are allowed again over time
+	require.False(t, l.allow("1.1.1.1", now.Add(joinCodeAttemptInterval/2)))
+	require.True(t, l.allow("1.1.1.1", now.Add(joinCodeAttemptInterval)))
+	l.failed("1.1.1.1", now.Add(joinCodeAttemptInterval))
+	require.False(t, l.allow("1.1.1.1", now.Add(joinCodeAttemptInterval)))
+
+	// addresses that have all their attempts back are forgotten
+	later := now.Add(joinCodeAttemptBurst * joinCodeAttemptInterval * 2)
+	l.failed("2.2.2.2", later)
+	require.NotContains(t, l.attempts, "1.1.1.1")
+	require.Contains(t, l.attempts, "2.2.2.2")
+}
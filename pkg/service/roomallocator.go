@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/livekit/protocol/livekit"
@@ -9,25 +11,27 @@ import (
 	"github.com/livekit/protocol/utils"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/routing/selector"
 )
 
 type StandardRoomAllocator struct {
-	config    *config.Config
+	// room defaults and limits are reloadable
+	current   *config.Current
 	router    routing.Router
 	selector  selector.NodeSelector
 	roomStore ObjectStore
 }
 
-func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore) (RoomAllocator, error) {
-	ns, err := selector.CreateNodeSelector(conf)
+func NewRoomAllocator(current *config.Current, router routing.Router, rs ObjectStore) (RoomAllocator, error) {
+	ns, err := selector.CreateNodeSelector(current.Get())
 	if err != nil {
 		return nil, err
 	}
 
 	return &StandardRoomAllocator{
-		config:    conf,
+		current:   current,
 		router:    router,
 		selector:  ns,
 		roomStore: rs,
@@ -37,6 +41,7 @@ func NewRoomAllocator(conf *config.Config, router routing.Router, rs ObjectStore
 // CreateRoom creates a new room from a request and allocates it to a node to handle
 // it'll also monitor its state, and cleans it up when appropriate
 func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.CreateRoomRequest) (*livekit.Room, error) {
+	conf := r.current.Get()
 	token, err := r.roomStore.LockRoom(ctx, livekit.RoomName(req.Name), 5*time.Second)
 	if err != nil {
 		return nil, err
@@ -47,14 +52,15 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 
 	// find existing room and update it
 	rm, internal, err := r.roomStore.LoadRoom(ctx, livekit.RoomName(req.Name), true)
-	if err == ErrRoomNotFound {
+	created := err == ErrRoomNotFound
+	if created {
 		rm = &livekit.Room{
 			Sid:          utils.NewGuid(utils.RoomPrefix),
 			Name:         req.Name,
 			CreationTime: time.Now().Unix(),
 			TurnPassword: utils.RandomSecret(),
 		}
-		applyDefaultRoomConfig(rm, &r.config.Room)
+		applyDefaultRoomConfig(rm, &conf.Room)
 	} else if err != nil {
 		return nil, err
 	}
@@ -75,9 +81,17 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 	if err = r.roomStore.StoreRoom(ctx, rm, internal); err != nil {
 		return nil, err
 	}
+	// retention policies apply by the key the room was created with
+	if apiKey := GetAPIKey(ctx); created && apiKey != "" {
+		if err = r.roomStore.StoreRoomAPIKey(ctx, livekit.RoomName(rm.Name), apiKey); err != nil {
+			return nil, err
+		}
+	}
 
 	// check if room already assigned
-	existing, err := r.router.GetNodeForRoom(ctx, livekit.RoomName(rm.Name))
+	roomName := livekit.RoomName(rm.Name)
+	highAvailability := isHighAvailabilityRoom(&conf.Room, roomName)
+	existing, err := r.router.GetNodeForRoom(ctx, roomName)
 	if err != routing.ErrNotFound && err != nil {
 		return nil, err
 	}
@@ -85,22 +99,31 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 	// if already assigned and still available, keep it on that node
 	if err == nil && selector.IsAvailable(existing) {
 		// if node hosting the room is full, deny entry
-		if selector.LimitsReached(r.config.Limit, existing.Stats) {
+		if selector.LimitsReached(conf.Limit, existing.Stats) {
 			return nil, routing.ErrNodeLimitReached
 		}
 
+		if highAvailability {
+			r.assignStandbyNode(ctx, roomName, livekit.NodeID(existing.Id))
+		}
 		return rm, nil
 	}
 
 	// select a new node
 	nodeID := livekit.NodeID(req.NodeId)
+	if nodeID == "" && highAvailability {
+		// the node hosting the room failed, or it is yet to be hosted, its standby takes it over
+		if nodeID = r.availableStandbyNode(ctx, roomName); nodeID != "" {
+			logger.Infow("failing over room to standby node", "room", rm.Name, "roomID", rm.Sid, "standbyNodeID", nodeID)
+		}
+	}
 	if nodeID == "" {
 		nodes, err := r.router.ListNodes()
 		if err != nil {
 			return nil, err
 		}
 
-		node, err := r.selector.SelectNode(nodes)
+		node, err := r.selectNode(ctx, nodes)
 		if err != nil {
 			return nil, err
 		}
@@ -114,12 +137,112 @@ func (r *StandardRoomAllocator) CreateRoom(ctx context.Context, req *livekit.Cre
 		return nil, err
 	}
 
+	if highAvailability {
+		r.assignStandbyNode(ctx, roomName, nodeID)
+	}
 	return rm, nil
 }
 
+// selectNode selects the node for a new room, one in the region the client asked for, otherwise the one nearest to
+// the client creating it when it has been located. Nodes clients recently failed to connect to are avoided
+func (r *StandardRoomAllocator) selectNode(ctx context.Context, nodes []*livekit.Node) (*livekit.Node, error) {
+	loc := geoip.FromContext(ctx)
+	var clientRegion string
+	if loc != nil {
+		clientRegion = loc.CountryCode
+	}
+	nodes = selector.ExcludeICEFailingNodes(r.current.Get().NodeSelector, nodes, clientRegion)
+
+	if regionHint := routing.RegionHintFromContext(ctx); regionHint != "" {
+		if inRegion := selector.GetNodesInRegion(nodes, regionHint); len(inRegion) != 0 {
+			node, err := r.selector.SelectNode(inRegion)
+			if err == nil {
+				return node, nil
+			}
+			logger.Debugw("no node available in hinted region", "region", regionHint, "error", err)
+		}
+	}
+
+	if loc != nil && loc.HasCoordinates {
+		if ls, ok := r.selector.(selector.LocationSelector); ok {
+			return ls.SelectNodeNear(nodes, loc.Latitude, loc.Longitude)
+		}
+	}
+	return r.selector.SelectNode(nodes)
+}
+
+// availableStandbyNode returns the standby node of a high availability room when it is available to take the room over
+func (r *StandardRoomAllocator) availableStandbyNode(ctx context.Context, roomName livekit.RoomName) livekit.NodeID {
+	standbyID, err := r.roomStore.LoadRoomStandbyNode(ctx, roomName)
+	if err != nil {
+		logger.Warnw("could not load standby node", err, "room", roomName)
+		return ""
+	}
+	if standbyID == "" {
+		return ""
+	}
+
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		logger.Warnw("could not list nodes", err, "room", roomName)
+		return ""
+	}
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		if livekit.NodeID(node.Id) == standbyID && !selector.LimitsReached(r.current.Get().Limit, node.Stats) {
+			return standbyID
+		}
+	}
+	return ""
+}
+
+// assignStandbyNode makes sure a high availability room has an available standby node other than the node hosting
+// it, the room runs without one when there is no other node. Failing to assign one does not fail the room
+func (r *StandardRoomAllocator) assignStandbyNode(ctx context.Context, roomName livekit.RoomName, nodeID livekit.NodeID) {
+	standbyID, err := r.roomStore.LoadRoomStandbyNode(ctx, roomName)
+	if err != nil {
+		logger.Warnw("could not load standby node", err, "room", roomName)
+		return
+	}
+
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		logger.Warnw("could not list nodes", err, "room", roomName)
+		return
+	}
+	var candidates []*livekit.Node
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		if livekit.NodeID(node.Id) == nodeID {
+			continue
+		}
+		if livekit.NodeID(node.Id) == standbyID {
+			// current standby is still good
+			return
+		}
+		candidates = append(candidates, node)
+	}
+
+	var newStandbyID livekit.NodeID
+	if len(candidates) != 0 {
+		node, err := r.selector.SelectNode(candidates)
+		if err != nil {
+			logger.Warnw("could not select standby node", err, "room", roomName)
+			return
+		}
+		newStandbyID = livekit.NodeID(node.Id)
+		logger.Infow("selected standby node for room", "room", roomName, "nodeID", nodeID, "standbyNodeID", newStandbyID)
+	} else if standbyID == "" {
+		return
+	} else {
+		logger.Warnw("no standby node available for room", nil, "room", roomName, "nodeID", nodeID)
+	}
+	if err = r.roomStore.StoreRoomStandbyNode(ctx, roomName, newStandbyID); err != nil {
+		logger.Warnw("could not store standby node", err, "room", roomName)
+	}
+}
+
 func (r *StandardRoomAllocator) ValidateCreateRoom(ctx context.Context, roomName livekit.RoomName) error {
 	// when auto create is disabled, we'll check to ensure it's already created
-	if !r.config.Room.AutoCreate {
+	if !r.current.Get().Room.AutoCreate {
 		_, _, err := r.roomStore.LoadRoom(ctx, roomName, false)
 		if err != nil {
 			return err
@@ -131,10 +254,38 @@ func (r *StandardRoomAllocator) ValidateCreateRoom(ctx context.Context, roomName
 func applyDefaultRoomConfig(room *livekit.Room, conf *config.RoomConfig) {
 	room.EmptyTimeout = conf.EmptyTimeout
 	room.MaxParticipants = conf.MaxParticipants
+	audioOnly := isAudioOnlyRoom(conf, livekit.RoomName(room.Name))
 	for _, codec := range conf.EnabledCodecs {
+		if !isCodecEnabledForRoom(codec, room.Name) {
+			continue
+		}
+		if audioOnly && !strings.HasPrefix(strings.ToLower(codec.Mime), "audio/") {
+			continue
+		}
 		room.EnabledCodecs = append(room.EnabledCodecs, &livekit.Codec{
 			Mime:     codec.Mime,
 			FmtpLine: codec.FmtpLine,
 		})
 	}
 }
+
+func isCodecEnabledForRoom(codec config.CodecSpec, roomName string) bool {
+	if len(codec.Rooms) == 0 {
+		return true
+	}
+	return matchesRoomName(codec.Rooms, roomName)
+}
+
+// isAudioOnlyRoom tells whether a room is set up for voice only, without any video machinery
+func isAudioOnlyRoom(conf *config.RoomConfig, roomName livekit.RoomName) bool {
+	return matchesRoomName(conf.AudioOnlyRooms, string(roomName))
+}
+
+func matchesRoomName(patterns []string, roomName string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, roomName); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,9 @@ package service_test
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -31,6 +33,148 @@ func TestCreateRoom(t *testing.T) {
 		require.NotEmpty(t, room.EnabledCodecs)
 	})
 
+	t.Run("codecs limited to rooms are enabled in matching rooms only", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Room.EnabledCodecs = append(conf.Room.EnabledCodecs, config.CodecSpec{
+			Mime:  "video/h265",
+			Rooms: []string{"hevc-*"},
+		})
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		ra, conf := newTestRoomAllocator(t, conf, node)
+
+		hasH265 := func(room *livekit.Room) bool {
+			for _, codec := range room.EnabledCodecs {
+				if codec.Mime == "video/h265" {
+					return true
+				}
+			}
+			return false
+		}
+
+		room, err := ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "hevc-room"})
+		require.NoError(t, err)
+		require.True(t, hasH265(room))
+		require.Len(t, room.EnabledCodecs, len(conf.Room.EnabledCodecs))
+
+		room, err = ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "myroom"})
+		require.NoError(t, err)
+		require.False(t, hasH265(room))
+		require.Len(t, room.EnabledCodecs, len(conf.Room.EnabledCodecs)-1)
+	})
+
+	t.Run("audio only rooms are created without video codecs", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Room.AudioOnlyRooms = []string{"voice-*"}
+
+		node, err := routing.NewLocalNode(conf)
+		require.NoError(t, err)
+
+		ra, _ := newTestRoomAllocator(t, conf, node)
+
+		room, err := ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "voice-room"})
+		require.NoError(t, err)
+		require.NotEmpty(t, room.EnabledCodecs)
+		for _, codec := range room.EnabledCodecs {
+			require.True(t, strings.HasPrefix(codec.Mime, "audio/"), codec.Mime)
+		}
+
+		room, err = ra.CreateRoom(context.Background(), &livekit.CreateRoomRequest{Name: "myroom"})
+		require.NoError(t, err)
+		require.Len(t, room.EnabledCodecs, len(conf.Room.EnabledCodecs))
+	})
+
+	t.Run("high availability rooms fail over to their standby node", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.Room.HighAvailability.Rooms = []string{"keynote-*"}
+
+		newNode := func(id string) *livekit.Node {
+			return &livekit.Node{
+				Id:    id,
+				State: livekit.NodeState_SERVING,
+				Stats: &livekit.NodeStats{UpdatedAt: time.Now().Unix()},
+			}
+		}
+		primary := newNode("primary")
+		standby := newNode("standby")
+
+		store := service.NewLocalStore()
+		router := &routingfakes.FakeRouter{}
+		router.GetNodeForRoomReturns(primary, nil)
+		router.ListNodesReturns([]*livekit.Node{primary, standby}, nil)
+		ra, err := service.NewRoomAllocator(config.NewCurrent(conf), router, store)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		_, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "keynote-room"})
+		require.NoError(t, err)
+		standbyID, err := store.LoadRoomStandbyNode(ctx, "keynote-room")
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("standby"), standbyID)
+
+		_, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "myroom"})
+		require.NoError(t, err)
+		standbyID, err = store.LoadRoomStandbyNode(ctx, "myroom")
+		require.NoError(t, err)
+		require.Empty(t, standbyID)
+
+		// primary stops reporting
+		primary.Stats.UpdatedAt = time.Now().Add(-time.Minute).Unix()
+		_, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "keynote-room"})
+		require.NoError(t, err)
+		require.Equal(t, 1, router.SetNodeForRoomCallCount())
+		_, roomName, nodeID := router.SetNodeForRoomArgsForCall(0)
+		require.Equal(t, livekit.RoomName("keynote-room"), roomName)
+		require.Equal(t, livekit.NodeID("standby"), nodeID)
+
+		// no other node left to stand by
+		standbyID, err = store.LoadRoomStandbyNode(ctx, "keynote-room")
+		require.NoError(t, err)
+		require.Empty(t, standbyID)
+	})
+
+	t.Run("rooms are placed in the hinted region", func(t *testing.T) {
+		conf, err := config.NewConfig("", true, nil, nil)
+		require.NoError(t, err)
+		conf.NodeSelector.SortBy = "clients"
+
+		newNode := func(id string, region string, clients int32) *livekit.Node {
+			return &livekit.Node{
+				Id:     id,
+				Region: region,
+				State:  livekit.NodeState_SERVING,
+				Stats:  &livekit.NodeStats{UpdatedAt: time.Now().Unix(), NumClients: clients},
+			}
+		}
+		router := &routingfakes.FakeRouter{}
+		router.GetNodeForRoomReturns(nil, routing.ErrNotFound)
+		router.ListNodesReturns([]*livekit.Node{
+			newNode("us", "us-east", 0),
+			newNode("eu-1", "eu-west", 20),
+			newNode("eu-2", "eu-west", 10),
+		}, nil)
+		ra, err := service.NewRoomAllocator(config.NewCurrent(conf), router, service.NewLocalStore())
+		require.NoError(t, err)
+
+		ctx := routing.WithRegionHint(context.Background(), "eu-west")
+		_, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "eu-room"})
+		require.NoError(t, err)
+		_, _, nodeID := router.SetNodeForRoomArgsForCall(0)
+		require.Equal(t, livekit.NodeID("eu-2"), nodeID)
+
+		// regions without nodes fall back to the load of all nodes
+		ctx = routing.WithRegionHint(context.Background(), "ap-south")
+		_, err = ra.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: "ap-room"})
+		require.NoError(t, err)
+		_, _, nodeID = router.SetNodeForRoomArgsForCall(1)
+		require.Equal(t, livekit.NodeID("us"), nodeID)
+	})
+
 	t.Run("reject new participants when track limit has been reached", func(t *testing.T) {
 		conf, err := config.NewConfig("", true, nil, nil)
 		require.NoError(t, err)
@@ -71,7 +215,7 @@ func newTestRoomAllocator(t *testing.T, conf *config.Config, node *livekit.Node)
 
 	router.GetNodeForRoomReturns(node, nil)
 
-	ra, err := service.NewRoomAllocator(conf, router, store)
+	ra, err := service.NewRoomAllocator(config.NewCurrent(conf), router, store)
 	require.NoError(t, err)
 	return ra, conf
 }
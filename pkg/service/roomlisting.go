@@ -0,0 +1,484 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	defaultListingLimit = 100
+	maxListingLimit     = 1000
+
+	RoomSortName            = "name"
+	RoomSortCreationTime    = "creation_time"
+	RoomSortNumParticipants = "num_participants"
+
+	ParticipantSortIdentity = "identity"
+	ParticipantSortJoinedAt = "joined_at"
+
+	// width of the sort key prefixed to names in sort members, fits any int64
+	listingKeyWidth = 20
+)
+
+// fields carrying listing options in ListRoomsRequest and ListParticipantsRequest, and the next cursor in their
+// responses, as unknown fields of those messages
+const (
+	listingMetadataContainsField protowire.Number = 100
+	listingMinParticipantsField  protowire.Number = 101
+	listingIdentityPrefixField   protowire.Number = 101
+	listingMaxParticipantsField  protowire.Number = 102
+	listingCreatedAfterField     protowire.Number = 103
+	listingJoinedAfterField      protowire.Number = 103
+	listingSortByField           protowire.Number = 104
+	listingDescendingField       protowire.Number = 105
+	listingLimitField            protowire.Number = 106
+	listingCursorField           protowire.Number = 107
+
+	listingNextCursorField protowire.Number = 100
+)
+
+var (
+	ErrInvalidListingSort   = errors.New("invalid sort order")
+	ErrInvalidListingCursor = errors.New("invalid cursor")
+)
+
+// ListRoomsOptions filter, sort and paginate the rooms listed by ListRooms, for clusters with too many rooms to list
+// them all at once
+type ListRoomsOptions struct {
+	// rooms whose metadata contains this string
+	MetadataContains string
+	MinParticipants  *uint32
+	MaxParticipants  *uint32
+	// rooms created after this unix time, in seconds
+	CreatedAfter int64
+
+	// name, creation_time or num_participants, by name when empty
+	SortBy     string
+	Descending bool
+	// rooms per page, at most 1000
+	Limit int
+	// next cursor of the previous page, requested with the same sort order
+	Cursor string
+}
+
+// ListParticipantsOptions filter, sort and paginate the participants listed by ListParticipants
+type ListParticipantsOptions struct {
+	// participants whose metadata contains this string
+	MetadataContains string
+	// participants whose identity starts with this prefix
+	IdentityPrefix string
+	// participants who joined after this unix time, in seconds
+	JoinedAfter int64
+
+	// identity or joined_at, by identity when empty
+	SortBy     string
+	Descending bool
+	// participants per page, at most 1000
+	Limit int
+	// next cursor of the previous page, requested with the same sort order
+	Cursor string
+}
+
+// listingCursor points after the last item of a page. It holds the sort member of the item rather than an offset,
+// so that rooms and participants coming and going between requests do not shift pages
+type listingCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Member     string `json:"m"`
+}
+
+// ------------------------------------------------
+
+// SetListRoomsOptions sets the listing options of a request. They are carried in the protobuf encoding only, JSON
+// requests list all rooms
+func SetListRoomsOptions(req *livekit.ListRoomsRequest, opts *ListRoomsOptions) {
+	var b []byte
+	b = appendListingString(b, listingMetadataContainsField, opts.MetadataContains)
+	if opts.MinParticipants != nil {
+		b = protowire.AppendTag(b, listingMinParticipantsField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*opts.MinParticipants))
+	}
+	if opts.MaxParticipants != nil {
+		b = protowire.AppendTag(b, listingMaxParticipantsField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*opts.MaxParticipants))
+	}
+	b = appendListingVarint(b, listingCreatedAfterField, uint64(opts.CreatedAfter))
+	b = appendListingPage(b, opts.SortBy, opts.Descending, opts.Limit, opts.Cursor)
+	setListingFields(req.ProtoReflect(), b)
+}
+
+// getListRoomsOptions returns the listing options of a request, nil when it has none
+func getListRoomsOptions(req *livekit.ListRoomsRequest) *ListRoomsOptions {
+	var opts *ListRoomsOptions
+	consumeListingFields(req.ProtoReflect().GetUnknown(), func(num protowire.Number, s string, v uint64) {
+		if num < listingMetadataContainsField || num > listingCursorField {
+			return
+		}
+		if opts == nil {
+			opts = &ListRoomsOptions{}
+		}
+		switch num {
+		case listingMetadataContainsField:
+			opts.MetadataContains = s
+		case listingMinParticipantsField:
+			n := uint32(v)
+			opts.MinParticipants = &n
+		case listingMaxParticipantsField:
+			n := uint32(v)
+			opts.MaxParticipants = &n
+		case listingCreatedAfterField:
+			opts.CreatedAfter = int64(v)
+		case listingSortByField:
+			opts.SortBy = s
+		case listingDescendingField:
+			opts.Descending = protowire.DecodeBool(v)
+		case listingLimitField:
+			opts.Limit = int(v)
+		case listingCursorField:
+			opts.Cursor = s
+		}
+	})
+	return opts
+}
+
+// SetListParticipantsOptions sets the listing options of a request. They are carried in the protobuf encoding only,
+// JSON requests list all participants
+func SetListParticipantsOptions(req *livekit.ListParticipantsRequest, opts *ListParticipantsOptions) {
+	var b []byte
+	b = appendListingString(b, listingMetadataContainsField, opts.MetadataContains)
+	b = appendListingString(b, listingIdentityPrefixField, opts.IdentityPrefix)
+	b = appendListingVarint(b, listingJoinedAfterField, uint64(opts.JoinedAfter))
+	b = appendListingPage(b, opts.SortBy, opts.Descending, opts.Limit, opts.Cursor)
+	setListingFields(req.ProtoReflect(), b)
+}
+
+// getListParticipantsOptions returns the listing options of a request, nil when it has none
+func getListParticipantsOptions(req *livekit.ListParticipantsRequest) *ListParticipantsOptions {
+	var opts *ListParticipantsOptions
+	consumeListingFields(req.ProtoReflect().GetUnknown(), func(num protowire.Number, s string, v uint64) {
+		if num < listingMetadataContainsField || num > listingCursorField {
+			return
+		}
+		if opts == nil {
+			opts = &ListParticipantsOptions{}
+		}
+		switch num {
+		case listingMetadataContainsField:
+			opts.MetadataContains = s
+		case listingIdentityPrefixField:
+			opts.IdentityPrefix = s
+		case listingJoinedAfterField:
+			opts.JoinedAfter = int64(v)
+		case listingSortByField:
+			opts.SortBy = s
+		case listingDescendingField:
+			opts.Descending = protowire.DecodeBool(v)
+		case listingLimitField:
+			opts.Limit = int(v)
+		case listingCursorField:
+			opts.Cursor = s
+		}
+	})
+	return opts
+}
+
+// GetListRoomsNextCursor returns the cursor of the page following a response, empty on the last page
+func GetListRoomsNextCursor(res *livekit.ListRoomsResponse) string {
+	return getListingNextCursor(res.ProtoReflect())
+}
+
+// GetListParticipantsNextCursor returns the cursor of the page following a response, empty on the last page
+func GetListParticipantsNextCursor(res *livekit.ListParticipantsResponse) string {
+	return getListingNextCursor(res.ProtoReflect())
+}
+
+func setListingNextCursor(m protoreflect.Message, cursor string) {
+	setListingFields(m, appendListingString(nil, listingNextCursorField, cursor))
+}
+
+func getListingNextCursor(m protoreflect.Message) string {
+	var cursor string
+	consumeListingFields(m.GetUnknown(), func(num protowire.Number, s string, _ uint64) {
+		if num == listingNextCursorField {
+			cursor = s
+		}
+	})
+	return cursor
+}
+
+func appendListingString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendListingVarint(b []byte, num protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendListingPage(b []byte, sortBy string, descending bool, limit int, cursor string) []byte {
+	b = appendListingString(b, listingSortByField, sortBy)
+	if descending {
+		b = appendListingVarint(b, listingDescendingField, protowire.EncodeBool(true))
+	}
+	if limit > 0 {
+		b = appendListingVarint(b, listingLimitField, uint64(limit))
+	}
+	return appendListingString(b, listingCursorField, cursor)
+}
+
+func setListingFields(m protoreflect.Message, fields []byte) {
+	if len(fields) == 0 {
+		return
+	}
+	m.SetUnknown(append(m.GetUnknown(), fields...))
+}
+
+// consumeListingFields calls f with the string and varint unknown fields of a message
+func consumeListingFields(unknown []byte, f func(num protowire.Number, s string, v uint64)) {
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return
+		}
+		unknown = unknown[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			value, m := protowire.ConsumeString(unknown)
+			if m < 0 {
+				return
+			}
+			f(num, value, 0)
+			unknown = unknown[m:]
+		case protowire.VarintType:
+			value, m := protowire.ConsumeVarint(unknown)
+			if m < 0 {
+				return
+			}
+			f(num, "", value)
+			unknown = unknown[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, unknown)
+			if m < 0 {
+				return
+			}
+			unknown = unknown[m:]
+		}
+	}
+}
+
+// ------------------------------------------------
+
+// listingPage is the validated sort order and page of listing options
+type listingPage struct {
+	sortBy     string
+	descending bool
+	limit      int
+	// sort member of the last item of the previous page, empty for the first page
+	after string
+}
+
+func newListingPage(sortBy string, descending bool, limit int, cursor string) (*listingPage, error) {
+	if limit <= 0 {
+		limit = defaultListingLimit
+	} else if limit > maxListingLimit {
+		limit = maxListingLimit
+	}
+	p := &listingPage{
+		sortBy:     sortBy,
+		descending: descending,
+		limit:      limit,
+	}
+	if cursor != "" {
+		c, err := decodeListingCursor(cursor)
+		if err != nil || c.SortBy != sortBy || c.Descending != descending || c.Member == "" {
+			return nil, ErrInvalidListingCursor
+		}
+		p.after = c.Member
+	}
+	return p, nil
+}
+
+func (o *ListRoomsOptions) page() (*listingPage, error) {
+	sortBy := o.SortBy
+	if sortBy == "" {
+		sortBy = RoomSortName
+	}
+	if sortBy != RoomSortName && sortBy != RoomSortCreationTime && sortBy != RoomSortNumParticipants {
+		return nil, ErrInvalidListingSort
+	}
+	return newListingPage(sortBy, o.Descending, o.Limit, o.Cursor)
+}
+
+func (o *ListParticipantsOptions) page() (*listingPage, error) {
+	sortBy := o.SortBy
+	if sortBy == "" {
+		sortBy = ParticipantSortIdentity
+	}
+	if sortBy != ParticipantSortIdentity && sortBy != ParticipantSortJoinedAt {
+		return nil, ErrInvalidListingSort
+	}
+	return newListingPage(sortBy, o.Descending, o.Limit, o.Cursor)
+}
+
+func (p *listingPage) less(a, b string) bool {
+	if p.descending {
+		return a > b
+	}
+	return a < b
+}
+
+// follows tells whether a sort member comes after the cursor of the page
+func (p *listingPage) follows(member string) bool {
+	return p.after == "" || p.less(p.after, member)
+}
+
+// nextCursor returns the cursor of the page following the one ending with the given sort member
+func (p *listingPage) nextCursor(last string) string {
+	return encodeListingCursor(&listingCursor{
+		SortBy:     p.sortBy,
+		Descending: p.descending,
+		Member:     last,
+	})
+}
+
+// roomSortMember orders rooms by the sort key, then name, when compared as strings
+func roomSortMember(sortBy string, rm *livekit.Room) string {
+	switch sortBy {
+	case RoomSortCreationTime:
+		return listingSortMember(rm.CreationTime, rm.Name)
+	case RoomSortNumParticipants:
+		return listingSortMember(int64(rm.NumParticipants), rm.Name)
+	default:
+		return rm.Name
+	}
+}
+
+func participantSortMember(sortBy string, p *livekit.ParticipantInfo) string {
+	if sortBy == ParticipantSortJoinedAt {
+		return listingSortMember(p.JoinedAt, p.Identity)
+	}
+	return p.Identity
+}
+
+func listingSortMember(key int64, name string) string {
+	return fmt.Sprintf("%0*d", listingKeyWidth, key) + name
+}
+
+// roomNameFromSortMember returns the name of the room a sort member is for
+func roomNameFromSortMember(sortBy string, member string) string {
+	if sortBy == RoomSortName || len(member) < listingKeyWidth {
+		return member
+	}
+	return member[listingKeyWidth:]
+}
+
+func (o *ListRoomsOptions) matches(rm *livekit.Room) bool {
+	if o.MetadataContains != "" && !strings.Contains(rm.Metadata, o.MetadataContains) {
+		return false
+	}
+	if o.MinParticipants != nil && rm.NumParticipants < *o.MinParticipants {
+		return false
+	}
+	if o.MaxParticipants != nil && rm.NumParticipants > *o.MaxParticipants {
+		return false
+	}
+	return o.CreatedAfter == 0 || rm.CreationTime > o.CreatedAfter
+}
+
+func (o *ListParticipantsOptions) matches(p *livekit.ParticipantInfo) bool {
+	if o.MetadataContains != "" && !strings.Contains(p.Metadata, o.MetadataContains) {
+		return false
+	}
+	if !strings.HasPrefix(p.Identity, o.IdentityPrefix) {
+		return false
+	}
+	return o.JoinedAfter == 0 || p.JoinedAt > o.JoinedAfter
+}
+
+// paginateRooms returns the page of rooms held in memory, for stores without an index to page through
+func paginateRooms(rooms []*livekit.Room, opts *ListRoomsOptions) ([]*livekit.Room, string, error) {
+	p, err := opts.page()
+	if err != nil {
+		return nil, "", err
+	}
+
+	members := make(map[*livekit.Room]string, len(rooms))
+	matched := make([]*livekit.Room, 0, len(rooms))
+	for _, rm := range rooms {
+		member := roomSortMember(p.sortBy, rm)
+		if opts.matches(rm) && p.follows(member) {
+			members[rm] = member
+			matched = append(matched, rm)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return p.less(members[matched[i]], members[matched[j]])
+	})
+
+	if len(matched) <= p.limit {
+		return matched, "", nil
+	}
+	matched = matched[:p.limit]
+	return matched, p.nextCursor(members[matched[p.limit-1]]), nil
+}
+
+// paginateParticipants returns the page of the participants of a room
+func paginateParticipants(participants []*livekit.ParticipantInfo, opts *ListParticipantsOptions) ([]*livekit.ParticipantInfo, string, error) {
+	p, err := opts.page()
+	if err != nil {
+		return nil, "", err
+	}
+
+	members := make(map[*livekit.ParticipantInfo]string, len(participants))
+	matched := make([]*livekit.ParticipantInfo, 0, len(participants))
+	for _, pi := range participants {
+		member := participantSortMember(p.sortBy, pi)
+		if opts.matches(pi) && p.follows(member) {
+			members[pi] = member
+			matched = append(matched, pi)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return p.less(members[matched[i]], members[matched[j]])
+	})
+
+	if len(matched) <= p.limit {
+		return matched, "", nil
+	}
+	matched = matched[:p.limit]
+	return matched, p.nextCursor(members[matched[p.limit-1]]), nil
+}
+
+func encodeListingCursor(c *listingCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeListingCursor(cursor string) (*listingCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	c := &listingCursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+)
+
+func TestRoomListing(t *testing.T) {
+	ctx := service.WithGrants(context.Background(), &auth.ClaimGrants{
+		Video: &auth.VideoGrant{RoomList: true, RoomAdmin: true, Room: "a"},
+	})
+	store := service.NewLocalStore()
+	for _, rm := range []*livekit.Room{
+		{Name: "c", NumParticipants: 3, CreationTime: 30, Metadata: `{"event":"launch"}`},
+		{Name: "a", NumParticipants: 1, CreationTime: 10},
+		{Name: "d", NumParticipants: 0, CreationTime: 40},
+		{Name: "b", NumParticipants: 3, CreationTime: 20, Metadata: `{"event":"launch"}`},
+	} {
+		require.NoError(t, store.StoreRoom(ctx, rm, nil))
+	}
+	for _, p := range []*livekit.ParticipantInfo{
+		{Identity: "guest-2", JoinedAt: 20},
+		{Identity: "host", JoinedAt: 10},
+		{Identity: "guest-1", JoinedAt: 30},
+	} {
+		require.NoError(t, store.StoreParticipant(ctx, "a", p))
+	}
+	svc, err := service.NewRoomService(config.RoomConfig{}, config.APIConfig{}, &routingfakes.FakeRouter{},
+		&servicefakes.FakeRoomAllocator{}, store, nil)
+	require.NoError(t, err)
+
+	// options and cursors are carried over the wire as unknown fields
+	listRooms := func(opts *service.ListRoomsOptions, names ...string) ([]string, string, error) {
+		req := &livekit.ListRoomsRequest{Names: names}
+		service.SetListRoomsOptions(req, opts)
+		data, err := proto.Marshal(req)
+		require.NoError(t, err)
+		req = &livekit.ListRoomsRequest{}
+		require.NoError(t, proto.Unmarshal(data, req))
+
+		res, err := svc.ListRooms(ctx, req)
+		if err != nil {
+			return nil, "", err
+		}
+		data, err = proto.Marshal(res)
+		require.NoError(t, err)
+		res = &livekit.ListRoomsResponse{}
+		require.NoError(t, proto.Unmarshal(data, res))
+
+		var roomNames []string
+		for _, rm := range res.Rooms {
+			roomNames = append(roomNames, rm.Name)
+		}
+		return roomNames, service.GetListRoomsNextCursor(res), nil
+	}
+
+	t.Run("lists everything without options", func(t *testing.T) {
+		res, err := svc.ListRooms(ctx, &livekit.ListRoomsRequest{})
+		require.NoError(t, err)
+		require.Len(t, res.Rooms, 4)
+		require.Empty(t, service.GetListRoomsNextCursor(res))
+	})
+
+	t.Run("filters rooms", func(t *testing.T) {
+		one := uint32(1)
+		names, next, err := listRooms(&service.ListRoomsOptions{MinParticipants: &one})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c"}, names)
+		require.Empty(t, next)
+
+		names, _, err = listRooms(&service.ListRoomsOptions{MetadataContains: `"launch"`, CreatedAfter: 20})
+		require.NoError(t, err)
+		require.Equal(t, []string{"c"}, names)
+
+		names, _, err = listRooms(&service.ListRoomsOptions{SortBy: service.RoomSortCreationTime, Descending: true}, "a", "b")
+		require.NoError(t, err)
+		require.Equal(t, []string{"b", "a"}, names)
+	})
+
+	t.Run("pages rooms in sort order", func(t *testing.T) {
+		opts := &service.ListRoomsOptions{SortBy: service.RoomSortNumParticipants, Descending: true, Limit: 3}
+		names, next, err := listRooms(opts)
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "b", "a"}, names)
+		require.NotEmpty(t, next)
+
+		opts.Cursor = next
+		names, next, err = listRooms(opts)
+		require.NoError(t, err)
+		require.Equal(t, []string{"d"}, names)
+		require.Empty(t, next)
+
+		// cursors are bound to the sort order
+		_, _, err = listRooms(&service.ListRoomsOptions{Cursor: opts.Cursor})
+		require.Equal(t, twirp.InvalidArgument, err.(twirp.Error).Code())
+		_, _, err = listRooms(&service.ListRoomsOptions{Cursor: "garbage"})
+		require.Equal(t, twirp.InvalidArgument, err.(twirp.Error).Code())
+		_, _, err = listRooms(&service.ListRoomsOptions{SortBy: "size"})
+		require.Equal(t, twirp.InvalidArgument, err.(twirp.Error).Code())
+	})
+
+	t.Run("pages participants", func(t *testing.T) {
+		opts := &service.ListParticipantsOptions{
+			IdentityPrefix: "guest-",
+			SortBy:         service.ParticipantSortJoinedAt,
+			Limit:          1,
+		}
+		req := &livekit.ListParticipantsRequest{Room: "a"}
+		service.SetListParticipantsOptions(req, opts)
+		res, err := svc.ListParticipants(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Participants, 1)
+		require.Equal(t, "guest-2", res.Participants[0].Identity)
+
+		opts.Cursor = service.GetListParticipantsNextCursor(res)
+		req = &livekit.ListParticipantsRequest{Room: "a"}
+		service.SetListParticipantsOptions(req, opts)
+		res, err = svc.ListParticipants(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Participants, 1)
+		require.Equal(t, "guest-1", res.Participants[0].Identity)
+		require.Empty(t, service.GetListParticipantsNextCursor(res))
+	})
+}
@@ -7,7 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pion/webrtc/v3"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/livekit-server/version"
@@ -18,10 +21,15 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/tracing"
+	"github.com/livekit/livekit-server/pkg/transcoder"
+	sutils "github.com/livekit/livekit-server/pkg/utils"
 )
 
 const (
@@ -29,6 +37,10 @@ const (
 	tokenRefreshInterval = 5 * time.Minute
 	tokenDefaultTTL      = 10 * time.Minute
 	iceConfigTTL         = 5 * time.Minute
+	checkpointTTL        = 2 * time.Minute
+	bandwidthEstimateTTL = time.Minute
+	// the participant's context may already be done when it closes
+	bandwidthEstimateSaveTimeout = 2 * time.Second
 )
 
 type iceConfigCacheEntry struct {
@@ -36,12 +48,17 @@ type iceConfigCacheEntry struct {
 	modifiedAt time.Time
 }
 
+type checkpointKey struct {
+	roomName livekit.RoomName
+	identity livekit.ParticipantIdentity
+}
+
 // RoomManager manages rooms and its interaction with participants.
 // It's responsible for creating, deleting rooms, as well as running sessions for participants
 type RoomManager struct {
 	lock sync.RWMutex
 
-	config            *config.Config
+	current           *config.Current
 	rtcConfig         *rtc.WebRTCConfig
 	serverInfo        *livekit.ServerInfo
 	currentNode       routing.LocalNode
@@ -51,14 +68,39 @@ type RoomManager struct {
 	clientConfManager clientconfiguration.ClientConfigurationManager
 	egressLauncher    rtc.EgressLauncher
 	versionGenerator  utils.TimedVersionGenerator
+	transcoder        *transcoder.Manager
+	timelineStore     RoomTimelineStore
+	manifestStore     RoomManifestStore
+	egressStore       EgressStore
+	// nil when geoip is not configured
+	geoIP           geoip.Provider
+	turnCredentials *TURNCredentials
+	turnQuota       *TURNQuota
 
 	rooms map[livekit.RoomName]*rtc.Room
 
 	iceConfigCache map[livekit.ParticipantIdentity]*iceConfigCacheEntry
+	// state saved when participants became unstable, restored if they rejoin
+	checkpoints map[checkpointKey]*types.ParticipantCheckpoint
+	// state of participants in rooms taken over from a failed or draining node, restored when they rejoin
+	mirroredParticipants map[checkpointKey]*MirroredParticipant
+	// rooms handed over to other nodes while draining, till they close here
+	migratedRooms    map[livekit.RoomName]*rtc.Room
+	numMigratedRooms int
+	// nil unless identity binding is enabled
+	identityBindings *identityBindings
+	// nil unless the external IPs are checked for changes
+	externalIPWatcher *rtc.ExternalIPWatcher
+	// nil when the track policy has no constraints
+	trackPolicy *rtc.TrackPolicy
+	// nil unless the media of rooms is relayed between nodes
+	cascade *CascadeManager
+	// sheds video when the node is overloaded, idle unless enabled in the configuration
+	cpuProtector *cpuProtector
 }
 
 func NewLocalRoomManager(
-	conf *config.Config,
+	current *config.Current,
 	roomStore ObjectStore,
 	currentNode routing.LocalNode,
 	router routing.Router,
@@ -66,14 +108,22 @@ func NewLocalRoomManager(
 	clientConfManager clientconfiguration.ClientConfigurationManager,
 	egressLauncher rtc.EgressLauncher,
 	versionGenerator utils.TimedVersionGenerator,
+	transcoderManager *transcoder.Manager,
+	timelineStore RoomTimelineStore,
+	manifestStore RoomManifestStore,
+	egressStore EgressStore,
+	geoIP geoip.Provider,
+	turnCredentials *TURNCredentials,
+	turnQuota *TURNQuota,
 ) (*RoomManager, error) {
+	conf := current.Get()
 	rtcConf, err := rtc.NewWebRTCConfig(conf, currentNode.Ip)
 	if err != nil {
 		return nil, err
 	}
 
 	r := &RoomManager{
-		config:            conf,
+		current:           current,
 		rtcConfig:         rtcConf,
 		currentNode:       currentNode,
 		router:            router,
@@ -82,10 +132,20 @@ func NewLocalRoomManager(
 		clientConfManager: clientConfManager,
 		egressLauncher:    egressLauncher,
 		versionGenerator:  versionGenerator,
+		transcoder:        transcoderManager,
+		timelineStore:     timelineStore,
+		manifestStore:     manifestStore,
+		egressStore:       egressStore,
+		geoIP:             geoIP,
+		turnCredentials:   turnCredentials,
+		turnQuota:         turnQuota,
 
 		rooms: make(map[livekit.RoomName]*rtc.Room),
 
-		iceConfigCache: make(map[livekit.ParticipantIdentity]*iceConfigCacheEntry),
+		iceConfigCache:       make(map[livekit.ParticipantIdentity]*iceConfigCacheEntry),
+		checkpoints:          make(map[checkpointKey]*types.ParticipantCheckpoint),
+		mirroredParticipants: make(map[checkpointKey]*MirroredParticipant),
+		migratedRooms:        make(map[livekit.RoomName]*rtc.Room),
 
 		serverInfo: &livekit.ServerInfo{
 			Edition:  livekit.ServerInfo_Standard,
@@ -96,18 +156,80 @@ func NewLocalRoomManager(
 		},
 	}
 
+	if conf.RTC.IdentityBinding.Enabled {
+		r.identityBindings = newIdentityBindings(conf.RTC.IdentityBinding, roomStore)
+	}
+
+	r.trackPolicy, err = rtc.NewTrackPolicy(conf.Room.TrackPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.RTC.UseExternalIP && conf.RTC.ExternalIPCheckInterval > 0 {
+		r.externalIPWatcher, err = rtc.NewExternalIPWatcher(conf, rtcConf, currentNode.Ip)
+		if err != nil {
+			return nil, err
+		}
+		r.externalIPWatcher.OnChanged(r.onExternalIPsChanged)
+		r.externalIPWatcher.Start()
+	}
+
+	r.cpuProtector = newCPUProtector(current, r.localParticipants)
+	r.cpuProtector.Start()
+
+	if conf.MediaRelay.Port != 0 {
+		if r.cascade, err = NewCascadeManager(conf, currentNode, router); err != nil {
+			return nil, err
+		}
+		if err = r.cascade.Start(); err != nil {
+			return nil, err
+		}
+	}
+
 	// hook up to router
 	router.OnNewParticipantRTC(r.StartSession)
+
+	if r.transcoder != nil {
+		r.transcoder.OnRenditionPublished(r.linkRendition)
+		r.transcoder.OnLayerPublished(r.linkGeneratedLayer)
+	}
 	router.OnRTCMessage(r.handleRTCMessage)
 	return r, nil
 }
 
+// config is the configuration in effect, read on use to pick up reloaded settings
+func (r *RoomManager) config() *config.Config {
+	return r.current.Get()
+}
+
 func (r *RoomManager) GetRoom(_ context.Context, roomName livekit.RoomName) *rtc.Room {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 	return r.rooms[roomName]
 }
 
+func (r *RoomManager) linkRendition(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID) {
+	room := r.GetRoom(context.Background(), roomName)
+	if room == nil {
+		logger.Warnw("could not link rendition, room not found", nil, "room", roomName, "trackID", trackID)
+		return
+	}
+	if err := room.LinkRendition(trackID, renditionTrackID); err != nil {
+		room.Logger.Warnw("could not link rendition", err, "trackID", trackID, "renditionTrackID", renditionTrackID)
+	}
+}
+
+func (r *RoomManager) linkGeneratedLayer(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID, quality livekit.VideoQuality) {
+	room := r.GetRoom(context.Background(), roomName)
+	if room == nil {
+		logger.Warnw("could not link generated layer, room not found", nil, "room", roomName, "trackID", trackID)
+		return
+	}
+	if err := room.LinkGeneratedLayer(trackID, renditionTrackID, quality); err != nil {
+		room.Logger.Warnw("could not link generated layer", err, "trackID", trackID, "renditionTrackID", renditionTrackID)
+	}
+}
+
 // DeleteRoom completely deletes all room information, including active sessions, room store, and routing info
 func (r *RoomManager) DeleteRoom(ctx context.Context, roomName livekit.RoomName) error {
 	logger.Infow("deleting room state", "room", roomName)
@@ -170,6 +292,28 @@ func (r *RoomManager) CloseIdleRooms() {
 	}
 }
 
+// localRooms returns the rooms hosted on this node
+func (r *RoomManager) localRooms() []*rtc.Room {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	rooms := make([]*rtc.Room, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// localParticipants returns the participants of the rooms hosted on this node, by room
+func (r *RoomManager) localParticipants() [][]types.LocalParticipant {
+	rooms := r.localRooms()
+	participants := make([][]types.LocalParticipant, 0, len(rooms))
+	for _, room := range rooms {
+		participants = append(participants, room.GetParticipants())
+	}
+	return participants
+}
+
 func (r *RoomManager) HasParticipants() bool {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
@@ -182,6 +326,29 @@ func (r *RoomManager) HasParticipants() bool {
 	return false
 }
 
+// ObjectCounts returns the number of rooms, their participants and tracks, and of the state kept by the manager
+// for participants, these go back to zero once all rooms are closed
+func (r *RoomManager) ObjectCounts() map[string]int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	counts := map[string]int{
+		"rooms":            len(r.rooms),
+		"ice_config_cache": len(r.iceConfigCache),
+		"checkpoints":      len(r.checkpoints),
+	}
+	var participants, tracks int
+	for _, room := range r.rooms {
+		for _, p := range room.GetParticipants() {
+			participants++
+			tracks += len(p.GetPublishedTracks())
+		}
+	}
+	counts["participants"] = participants
+	counts["tracks"] = tracks
+	return counts
+}
+
 func (r *RoomManager) Stop() {
 	// disconnect all clients
 	r.lock.RLock()
@@ -198,6 +365,18 @@ func (r *RoomManager) Stop() {
 		room.Close()
 	}
 
+	if r.externalIPWatcher != nil {
+		r.externalIPWatcher.Stop()
+	}
+
+	if r.cpuProtector != nil {
+		r.cpuProtector.Stop()
+	}
+
+	if r.cascade != nil {
+		r.cascade.Stop()
+	}
+
 	if r.rtcConfig != nil {
 		if r.rtcConfig.UDPMux != nil {
 			_ = r.rtcConfig.UDPMux.Close()
@@ -208,6 +387,44 @@ func (r *RoomManager) Stop() {
 	}
 }
 
+func (r *RoomManager) trackPolicyForRoom(roomName livekit.RoomName) *rtc.TrackPolicy {
+	rooms := r.config().Room.TrackPolicy.Rooms
+	if len(rooms) != 0 && !matchesRoomName(rooms, string(roomName)) {
+		return nil
+	}
+	return r.trackPolicy
+}
+
+// candidatePolicyForRoom returns the first candidate policy matching a room, nil when none does
+func (r *RoomManager) candidatePolicyForRoom(roomName livekit.RoomName) *rtc.CandidatePolicy {
+	for _, policyConf := range r.config().Room.CandidatePolicies {
+		if len(policyConf.Rooms) == 0 || matchesRoomName(policyConf.Rooms, string(roomName)) {
+			return rtc.NewCandidatePolicy(policyConf)
+		}
+	}
+	return nil
+}
+
+// onExternalIPsChanged has all participants reconnect, their connections were established to the previous IPs
+func (r *RoomManager) onExternalIPsChanged(previous, current []string) {
+	r.lock.RLock()
+	rooms := make([]*rtc.Room, 0, len(r.rooms))
+	for _, rm := range r.rooms {
+		rooms = append(rooms, rm)
+	}
+	r.lock.RUnlock()
+
+	var reconnected int
+	for _, room := range rooms {
+		for _, p := range room.GetParticipants() {
+			if p.HandleNodeIPChanged() {
+				reconnected++
+			}
+		}
+	}
+	logger.Infow("external IPs changed, participants asked to reconnect", "previous", previous, "current", current, "participants", reconnected)
+}
+
 // StartSession starts WebRTC session when a new participant is connected, takes place on RTC node
 func (r *RoomManager) StartSession(
 	ctx context.Context,
@@ -215,6 +432,27 @@ func (r *RoomManager) StartSession(
 	pi routing.ParticipantInit,
 	requestSource routing.MessageSource,
 	responseSink routing.MessageSink,
+) error {
+	// traced as part of the join on the signal node
+	ctx, span := tracing.Start(
+		tracing.ContextWithTraceParent(ctx, pi.TraceParent),
+		"rtc.StartSession",
+		tracing.Room(roomName),
+		tracing.Participant(pi.Identity),
+		attribute.Bool("reconnect", pi.Reconnect),
+		attribute.String("node_id", r.currentNode.Id),
+	)
+	err := r.startSession(ctx, roomName, pi, requestSource, responseSink)
+	tracing.End(span, err)
+	return err
+}
+
+func (r *RoomManager) startSession(
+	ctx context.Context,
+	roomName livekit.RoomName,
+	pi routing.ParticipantInit,
+	requestSource routing.MessageSource,
+	responseSink routing.MessageSink,
 ) error {
 	room, err := r.getOrCreateRoom(ctx, roomName)
 	if err != nil {
@@ -228,6 +466,21 @@ func (r *RoomManager) StartSession(
 	if pi.Identity == "" {
 		return nil
 	}
+	if r.identityBindings != nil {
+		if err = r.identityBindings.check(ctx, roomName, pi.Identity, pi.Fingerprint); err != nil {
+			logger.Warnw("rejecting participant with another certificate fingerprint", err,
+				"room", roomName, "participant", pi.Identity, "fingerprint", pi.Fingerprint)
+			_ = responseSink.WriteMessage(&livekit.SignalResponse{
+				Message: &livekit.SignalResponse_Leave{
+					Leave: &livekit.LeaveRequest{
+						Reason: livekit.DisconnectReason_JOIN_FAILURE,
+					},
+				},
+			})
+			return err
+		}
+	}
+	clientLocation := r.locateClient(ctx, pi)
 	participant := room.GetParticipant(pi.Identity)
 	if participant != nil {
 		// When reconnecting, it means WS has interrupted by underlying peer connection is still ok
@@ -244,7 +497,7 @@ func (r *RoomManager) StartSession(
 				iceConfig = &livekit.ICEConfig{}
 			}
 			if err = room.ResumeParticipant(participant, requestSource, responseSink,
-				r.iceServersForRoom(protoRoom, iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS),
+				r.iceServersForParticipant(ctx, protoRoom, participant.ID(), iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS, clientLocation),
 				pi.ReconnectReason); err != nil {
 				logger.Warnw("could not resume participant", err, "participant", pi.Identity)
 				return err
@@ -260,12 +513,14 @@ func (r *RoomManager) StartSession(
 	} else if pi.Reconnect {
 		// send leave request if participant is trying to reconnect without keep subscribe state
 		// but missing from the room
+		leave := &livekit.LeaveRequest{
+			CanReconnect: true,
+			Reason:       livekit.DisconnectReason_STATE_MISMATCH,
+		}
+		rtc.SetReconnectPolicy(leave, r.config().RTC.ReconnectPolicy)
 		_ = responseSink.WriteMessage(&livekit.SignalResponse{
 			Message: &livekit.SignalResponse_Leave{
-				Leave: &livekit.LeaveRequest{
-					CanReconnect: true,
-					Reason:       livekit.DisconnectReason_STATE_MISMATCH,
-				},
+				Leave: leave,
 			},
 		})
 		return errors.New("could not restart participant")
@@ -281,6 +536,7 @@ func (r *RoomManager) StartSession(
 		"reconnect", pi.Reconnect,
 		"reconnectReason", pi.ReconnectReason,
 		"adaptiveStream", pi.AdaptiveStream,
+		"capabilities", pi.Capabilities,
 	)
 
 	clientConf := r.clientConfManager.GetConfiguration(pi.Client)
@@ -289,47 +545,77 @@ func (r *RoomManager) StartSession(
 	rtcConf := *r.rtcConfig
 	rtcConf.SetBufferFactory(room.GetBufferFactory())
 	sid := livekit.ParticipantID(utils.NewGuid(utils.ParticipantPrefix))
+	// rejoining a room taken over, the participant is back with its previous ID
+	mp := r.takeMirroredParticipant(roomName, pi.Identity)
+	var previousTracks []*livekit.TrackInfo
+	if mp != nil {
+		sid = mp.SID
+		previousTracks = mp.Tracks
+	}
 	pLogger := rtc.LoggerWithParticipant(room.Logger, pi.Identity, sid, false)
 	// default allow forceTCP
 	allowFallback := true
-	if r.config.RTC.AllowTCPFallback != nil {
-		allowFallback = *r.config.RTC.AllowTCPFallback
+	if r.config().RTC.AllowTCPFallback != nil {
+		allowFallback = *r.config().RTC.AllowTCPFallback
 	}
 	// default do not force full reconnect on a publication error
 	reconnectOnPublicationError := false
-	if r.config.RTC.ReconnectOnPublicationError != nil {
-		reconnectOnPublicationError = *r.config.RTC.ReconnectOnPublicationError
+	if r.config().RTC.ReconnectOnPublicationError != nil {
+		reconnectOnPublicationError = *r.config().RTC.ReconnectOnPublicationError
 	}
 	// default do not force full reconnect on a subscription error
 	reconnectOnSubscriptionError := false
-	if r.config.RTC.ReconnectOnSubscriptionError != nil {
-		reconnectOnSubscriptionError = *r.config.RTC.ReconnectOnSubscriptionError
+	if r.config().RTC.ReconnectOnSubscriptionError != nil {
+		reconnectOnSubscriptionError = *r.config().RTC.ReconnectOnSubscriptionError
 	}
-	subscriberAllowPause := r.config.RTC.CongestionControl.AllowPause
+	subscriberAllowPause := r.config().RTC.CongestionControl.AllowPause
 	if pi.SubscriberAllowPause != nil {
 		subscriberAllowPause = *pi.SubscriberAllowPause
 	}
+	var fingerprint string
+	if r.identityBindings != nil {
+		fingerprint = pi.Fingerprint
+	}
+	var codecTranscoder rtc.CodecTranscoder
+	var simulcastGenerator rtc.SimulcastGenerator
+	if r.transcoder != nil {
+		roomTranscoder := r.transcoder.ForRoom(roomName)
+		codecTranscoder = roomTranscoder
+		if r.config().Video.SimulcastGeneration.Enabled {
+			simulcastGenerator = roomTranscoder
+		}
+	}
 	participant, err = rtc.NewParticipant(rtc.ParticipantParams{
 		Identity:                pi.Identity,
 		Name:                    pi.Name,
 		SID:                     sid,
 		Config:                  &rtcConf,
 		Sink:                    responseSink,
-		AudioConfig:             r.config.Audio,
-		VideoConfig:             r.config.Video,
+		AudioConfig:             r.config().Audio,
+		VideoConfig:             r.config().Video,
 		ProtocolVersion:         pv,
+		Capabilities:            types.NegotiateClientCapabilities(pi.Capabilities),
+		Fingerprint:             fingerprint,
 		Telemetry:               r.telemetry,
-		PLIThrottleConfig:       r.config.RTC.PLIThrottle,
-		CongestionControlConfig: r.config.RTC.CongestionControl,
+		PLIThrottleConfig:       r.config().RTC.PLIThrottle,
+		CongestionControlConfig: r.config().RTC.CongestionControl,
 		EnabledCodecs:           protoRoom.EnabledCodecs,
+		AudioOnly:               isAudioOnlyRoom(&r.config().Room, roomName),
+		E2EERequired:            matchesRoomName(r.config().Room.E2EE.Rooms, string(roomName)),
+		MaxPlaintextFrames:      r.config().Room.E2EE.MaxPlaintextFrames,
+		OpaquePayload:           matchesRoomName(r.config().Room.OpaquePayloadRooms, string(roomName)),
+		TrackPolicy:             r.trackPolicyForRoom(roomName),
+		CandidatePolicy:         r.candidatePolicyForRoom(roomName),
 		Grants:                  pi.Grants,
 		Logger:                  pLogger,
 		ClientConf:              clientConf,
 		ClientInfo:              rtc.ClientInfo{ClientInfo: pi.Client},
 		Region:                  pi.Region,
 		AdaptiveStream:          pi.AdaptiveStream,
+		DeviceHints:             pi.DeviceHints,
+		AudioOnlyDownlink:       pi.AudioOnlyDownlink,
 		AllowTCPFallback:        allowFallback,
-		TURNSEnabled:            r.config.IsTURNSEnabled(),
+		TURNSEnabled:            r.config().IsTURNSEnabled(),
 		GetParticipantInfo: func(pID livekit.ParticipantID) *livekit.ParticipantInfo {
 			if p := room.GetParticipantByID(pID); p != nil {
 				return p.ToProto()
@@ -341,23 +627,44 @@ func (r *RoomManager) StartSession(
 		VersionGenerator:             r.versionGenerator,
 		TrackResolver:                room.ResolveMediaTrackForSubscriber,
 		SubscriberAllowPause:         subscriberAllowPause,
-		SubscriptionLimitAudio:       r.config.Limit.SubscriptionLimitAudio,
-		SubscriptionLimitVideo:       r.config.Limit.SubscriptionLimitVideo,
+		SubscriptionLimitAudio:       r.config().Limit.SubscriptionLimitAudio,
+		SubscriptionLimitVideo:       r.config().Limit.SubscriptionLimitVideo,
+		CodecTranscoder:              codecTranscoder,
+		SimulcastGenerator:           simulcastGenerator,
+		ReconnectPolicy:              r.config().RTC.ReconnectPolicy,
+		AdmitSubscription:            room.AdmitSubscription,
+		AdmitPublication:             r.cpuProtector.AdmitPublication,
+		Resources:                    sutils.NewResourceOwner(string(pi.Identity), room.Resources()),
+		PreviousTracks:               previousTracks,
+		TraceContext:                 trace.SpanContextFromContext(ctx),
 	})
 	if err != nil {
 		return err
 	}
 	iceConfig := r.setIceConfig(participant)
+	if r.turnQuota != nil {
+		r.turnQuota.AddParticipant(roomName, pi.Identity, sid)
+	}
 
 	// join room
 	opts := rtc.ParticipantOptions{
 		AutoSubscribe: pi.AutoSubscribe,
 	}
-	if err = room.Join(participant, requestSource, &opts, r.iceServersForRoom(protoRoom, iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS)); err != nil {
+	if err = room.Join(participant, requestSource, &opts, r.iceServersForParticipant(ctx, protoRoom, sid, iceConfig.PreferenceSubscriber == livekit.ICECandidateType_ICT_TLS, clientLocation)); err != nil {
 		pLogger.Errorw("could not join room", err)
 		_ = participant.Close(true, types.ParticipantCloseReasonJoinFailed)
 		return err
 	}
+	r.restoreCheckpoint(room, participant)
+	r.restoreBandwidthEstimate(ctx, roomName, participant)
+	if mp != nil {
+		r.restoreMirroredParticipant(room, participant, mp)
+	}
+	if r.identityBindings != nil {
+		if err = r.identityBindings.bind(ctx, roomName, participant.Identity(), participant.ID(), pi.Fingerprint); err != nil {
+			pLogger.Errorw("could not bind identity", err)
+		}
+	}
 	if err = r.roomStore.StoreParticipant(ctx, roomName, participant.ToProto()); err != nil {
 		pLogger.Errorw("could not store participant", err)
 	}
@@ -375,8 +682,25 @@ func (r *RoomManager) StartSession(
 	persistRoomForParticipantCount(room.ToProto())
 
 	clientMeta := &livekit.AnalyticsClientMeta{Region: r.currentNode.Region, Node: r.currentNode.Id}
+	telemetry.SetClientLocation(clientMeta, clientLocation)
 	r.telemetry.ParticipantJoined(ctx, protoRoom, participant.ToProto(), pi.Client, clientMeta, true)
 	participant.OnClose(func(p types.LocalParticipant) {
+		if r.turnCredentials != nil {
+			if err := r.turnCredentials.Revoke(context.Background(), p.ID()); err != nil {
+				pLogger.Warnw("could not revoke TURN credentials", err)
+			}
+		}
+		if r.turnQuota != nil {
+			r.turnQuota.RemoveParticipant(p.ID())
+		}
+		r.saveBandwidthEstimate(roomName, p)
+		r.dropStaleCheckpoint(roomName, p)
+		if r.identityBindings != nil {
+			if err := r.identityBindings.release(context.Background(), roomName, p.Identity(), p.ID()); err != nil {
+				pLogger.Warnw("could not release identity binding", err)
+			}
+		}
+
 		if err := r.roomStore.DeleteParticipant(ctx, roomName, p.Identity()); err != nil {
 			pLogger.Errorw("could not delete participant", err)
 		}
@@ -384,7 +708,10 @@ func (r *RoomManager) StartSession(
 		// update room store with new numParticipants
 		proto := room.ToProto()
 		persistRoomForParticipantCount(proto)
-		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), true)
+		r.telemetry.ParticipantLeft(ctx, proto, p.ToProto(), true, &telemetry.ParticipantSessionEnd{
+			Reason:            p.CloseReason().ToDisconnectReason(),
+			ICECandidatePairs: p.GetSelectedICECandidatePairs(),
+		})
 	})
 	participant.OnClaimsChanged(func(participant types.LocalParticipant) {
 		pLogger.Debugw("refreshing client token after claims change")
@@ -400,6 +727,14 @@ func (r *RoomManager) StartSession(
 		}
 		r.lock.Unlock()
 	})
+	participant.OnUnstable(func(participant types.LocalParticipant) {
+		r.saveCheckpoint(roomName, participant)
+		r.saveBandwidthEstimate(roomName, participant)
+		r.telemetry.ParticipantUnstable(ctx, room.ToProto(), participant.ToProto())
+	})
+	participant.OnNetworkChanged(func(participant types.LocalParticipant, target livekit.SignalTarget, previous, current *webrtc.ICECandidatePair) {
+		r.telemetry.ParticipantNetworkChanged(ctx, room.ToProto(), participant.ToProto(), target, previous, current)
+	})
 
 	go r.rtcSessionWorker(room, participant, requestSource)
 	return nil
@@ -420,6 +755,19 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	if err != nil {
 		return nil, err
 	}
+	var apiKey string
+	if r.manifestStore != nil {
+		// the key is removed with the room, before the manifest is built
+		if apiKey, err = r.roomStore.LoadRoomAPIKey(ctx, roomName); err != nil {
+			logger.Warnw("could not load room api key", err, "room", roomName)
+		}
+	}
+	highAvailability := isHighAvailabilityRoom(&r.config().Room, roomName)
+	// mirrored by the node hosting the room when it is highly available, or by a draining node migrating it
+	mirror, err := r.roomStore.LoadRoomMirror(ctx, roomName)
+	if err != nil {
+		logger.Warnw("could not load room mirror", err, "room", roomName)
+	}
 
 	r.lock.Lock()
 
@@ -436,12 +784,80 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	}
 
 	// construct ice servers
-	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, &r.config.Audio, r.serverInfo, r.telemetry, r.egressLauncher)
+	newRoom := rtc.NewRoom(ri, internal, *r.rtcConfig, &r.config().Audio, r.serverInfo, r.telemetry, r.egressLauncher)
+	if matchesRoomName(r.config().Room.ActiveSpeakerTrack.Rooms, string(roomName)) {
+		newRoom.EnableActiveSpeakerTrack(r.config().Room.ActiveSpeakerTrack)
+	}
+	if matchesRoomName(r.config().Room.MixedAudioTrack.Rooms, string(roomName)) {
+		if err := newRoom.EnableMixedAudioTrack(r.config().Room.MixedAudioTrack); err != nil {
+			newRoom.Logger.Warnw("could not publish mixed audio track", err)
+		}
+	}
+
+	var timeline *RoomTimeline
+	if r.timelineStore != nil {
+		timeline = NewRoomTimeline(r.timelineStore, newRoom.ToProto(), newRoom.Logger)
+	}
+	var manifest *RoomManifestRecorder
+	if r.manifestStore != nil {
+		manifest = NewRoomManifestRecorder(
+			r.config().RoomManifest,
+			r.manifestStore,
+			r.egressStore,
+			r.timelineStore,
+			r.telemetry,
+			newRoom.ToProto(),
+			apiKey,
+			newRoom.Logger,
+		)
+	}
 
 	newRoom.OnClose(func() {
+		if timeline != nil {
+			timeline.Close()
+		}
+		if r.cascade != nil && r.cascade.IsEdgeRoom(roomName) {
+			// the room goes on on the node it was placed on
+			r.cascade.RemoveRoom(roomName)
+			r.lock.Lock()
+			if r.rooms[roomName] == newRoom {
+				delete(r.rooms, roomName)
+			}
+			r.lock.Unlock()
+			prometheus.RoomEnded(time.Unix(newRoom.ToProto().CreationTime, 0))
+			newRoom.Logger.Infow("room closed on edge node")
+			return
+		}
+		if r.removeMigratedRoom(newRoom) {
+			// the room goes on on the node it was migrated to
+			if r.cascade != nil {
+				r.cascade.RemoveRoom(roomName)
+			}
+			r.lock.Lock()
+			if r.rooms[roomName] == newRoom {
+				delete(r.rooms, roomName)
+			}
+			r.lock.Unlock()
+			prometheus.RoomEnded(time.Unix(newRoom.ToProto().CreationTime, 0))
+			newRoom.Logger.Infow("room closed after migrating")
+			return
+		}
+		if r.cascade != nil {
+			r.cascade.RemoveRoom(roomName)
+		}
 		roomInfo := newRoom.ToProto()
 		r.telemetry.RoomEnded(ctx, roomInfo)
+		if manifest != nil {
+			manifest.Close(roomInfo)
+		}
 		prometheus.RoomEnded(time.Unix(roomInfo.CreationTime, 0))
+		r.lock.Lock()
+		for key := range r.mirroredParticipants {
+			if key.roomName == roomName {
+				delete(r.mirroredParticipants, key)
+			}
+		}
+		r.lock.Unlock()
 		if err := r.DeleteRoom(ctx, roomName); err != nil {
 			newRoom.Logger.Errorw("could not delete room", err)
 		}
@@ -450,12 +866,32 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	})
 
 	newRoom.OnRoomUpdated(func() {
-		if err := r.roomStore.StoreRoom(ctx, newRoom.ToProto(), newRoom.Internal()); err != nil {
+		roomInfo := newRoom.ToProto()
+		if timeline != nil {
+			timeline.RoomUpdated(roomInfo)
+		}
+		if r.cascade != nil {
+			if r.cascade.IsEdgeRoom(roomName) {
+				// stored by the node the room was placed on
+				return
+			}
+			r.cascade.RoomUpdated(roomName, roomInfo)
+		}
+		if err := r.roomStore.StoreRoom(ctx, roomInfo, newRoom.Internal()); err != nil {
 			newRoom.Logger.Errorw("could not handle metadata update", err)
 		}
 	})
 
 	newRoom.OnParticipantChanged(func(p types.LocalParticipant) {
+		if timeline != nil {
+			timeline.ParticipantChanged(p.ToProto())
+		}
+		if manifest != nil {
+			manifest.ParticipantChanged(p.ToProto())
+		}
+		if r.cascade != nil {
+			r.cascade.ParticipantChanged(roomName, p)
+		}
 		if !p.IsDisconnected() {
 			if err := r.roomStore.StoreParticipant(ctx, roomName, p.ToProto()); err != nil {
 				newRoom.Logger.Errorw("could not handle participant change", err)
@@ -464,12 +900,34 @@ func (r *RoomManager) getOrCreateRoom(ctx context.Context, roomName livekit.Room
 	})
 
 	r.rooms[roomName] = newRoom
+	r.takeOver(roomName, mirror)
 
 	r.lock.Unlock()
 
 	newRoom.Hold()
+	if highAvailability {
+		newRoom.Resources().Go(func() {
+			r.mirrorWorker(newRoom)
+		})
+	}
+	if isBitrateBudgetRoom(&r.config().Room, roomName) {
+		newRoom.Resources().Go(func() {
+			r.bitrateBudgetWorker(newRoom)
+		})
+	}
 
-	r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	isEdge := false
+	if r.cascade != nil {
+		if err := r.cascade.AddRoom(ctx, newRoom); err != nil {
+			newRoom.Logger.Warnw("could not relay room", err)
+		}
+		isEdge = r.cascade.IsEdgeRoom(roomName)
+	}
+
+	if !isEdge {
+		// the room started on the node it was placed on
+		r.telemetry.RoomStarted(ctx, newRoom.ToProto())
+	}
 	prometheus.RoomStarted()
 
 	return newRoom, nil
@@ -534,6 +992,13 @@ func (r *RoomManager) rtcSessionWorker(room *rtc.Room, participant types.LocalPa
 
 // handles RTC messages resulted from Room API calls
 func (r *RoomManager) handleRTCMessage(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity, msg *livekit.RTCNodeMessage) {
+	if isEraseParticipantDataMessage(msg) {
+		// the identity itself is not logged
+		sessions := r.telemetry.EraseParticipant(identity)
+		logger.Infow("erased participant telemetry", "telemetrySessions", sessions)
+		return
+	}
+
 	r.lock.RLock()
 	room := r.rooms[roomName]
 	r.lock.RUnlock()
@@ -579,7 +1044,7 @@ func (r *RoomManager) handleRTCMessage(ctx context.Context, roomName livekit.Roo
 		}
 		pLogger.Debugw("setting track muted",
 			"trackID", rm.MuteTrack.TrackSid, "muted", rm.MuteTrack.Muted)
-		if !rm.MuteTrack.Muted && !r.config.Room.EnableRemoteUnmute {
+		if !rm.MuteTrack.Muted && !r.config().Room.EnableRemoteUnmute {
 			pLogger.Errorw("cannot unmute track, remote unmute is disabled", nil)
 			return
 		}
@@ -625,38 +1090,60 @@ func (r *RoomManager) handleRTCMessage(ctx context.Context, roomName livekit.Roo
 	}
 }
 
-func (r *RoomManager) iceServersForRoom(ri *livekit.Room, tlsOnly bool) []*livekit.ICEServer {
+// locateClient returns the location of the client of a participant, nil when it cannot be located. The signal node
+// usually located it already
+func (r *RoomManager) locateClient(ctx context.Context, pi routing.ParticipantInit) *geoip.Location {
+	if pi.ClientLocation != nil {
+		return pi.ClientLocation
+	}
+	loc, err := geoip.LookupAddress(ctx, r.geoIP, pi.Client.GetAddress())
+	if err != nil {
+		logger.Debugw("could not locate client", err, "participant", pi.Identity)
+		return nil
+	}
+	return loc
+}
+
+func (r *RoomManager) iceServersForParticipant(ctx context.Context, ri *livekit.Room, participantID livekit.ParticipantID, tlsOnly bool, clientLocation *geoip.Location) []*livekit.ICEServer {
 	var iceServers []*livekit.ICEServer
-	rtcConf := r.config.RTC
+	rtcConf := r.config().RTC
 
-	if tlsOnly && r.config.TURN.TLSPort == 0 {
+	if tlsOnly && r.config().TURN.TLSPort == 0 {
 		logger.Warnw("tls only enabled but no turn tls config", nil)
 		tlsOnly = false
 	}
 
 	hasSTUN := false
-	if r.config.TURN.Enabled {
+	if r.config().TURN.Enabled && r.turnCredentials != nil {
 		var urls []string
-		if r.config.TURN.UDPPort > 0 && !tlsOnly {
+		if r.config().TURN.UDPPort > 0 && !tlsOnly {
 			// UDP TURN is used as STUN
 			hasSTUN = true
-			urls = append(urls, fmt.Sprintf("turn:%s:%d?transport=udp", r.config.RTC.NodeIP, r.config.TURN.UDPPort))
+			urls = append(urls, fmt.Sprintf("turn:%s:%d?transport=udp", r.config().RTC.NodeIP, r.config().TURN.UDPPort))
 		}
-		if r.config.TURN.TLSPort > 0 {
-			urls = append(urls, fmt.Sprintf("turns:%s:443?transport=tcp", r.config.TURN.Domain))
+		if r.config().TURN.TLSPort > 0 {
+			urls = append(urls, fmt.Sprintf("turns:%s:443?transport=tcp", r.config().TURN.Domain))
 		}
 		if len(urls) > 0 {
-			iceServers = append(iceServers, &livekit.ICEServer{
-				Urls:       urls,
-				Username:   ri.Name,
-				Credential: ri.TurnPassword,
-			})
+			if username, credential, err := r.turnCredentials.Issue(ctx, participantID); err != nil {
+				logger.Warnw("could not issue TURN credentials", err, "pID", participantID)
+			} else {
+				iceServers = append(iceServers, &livekit.ICEServer{
+					Urls:       urls,
+					Username:   username,
+					Credential: credential,
+				})
+			}
 		}
 	}
 
 	if len(rtcConf.TURNServers) > 0 {
 		hasSTUN = true
-		for _, s := range r.config.RTC.TURNServers {
+		turnRegion := r.nearestTURNRegion(clientLocation)
+		for _, s := range r.config().RTC.TURNServers {
+			if turnRegion != "" && s.Region != "" && s.Region != turnRegion {
+				continue
+			}
 			scheme := "turn"
 			transport := "tcp"
 			if s.Protocol == "tls" {
@@ -671,13 +1158,16 @@ func (r *RoomManager) iceServersForRoom(ri *livekit.Room, tlsOnly bool) []*livek
 				Username:   s.Username,
 				Credential: s.Credential,
 			}
+			if s.Secret != "" && r.turnCredentials != nil {
+				is.Username, is.Credential = r.turnCredentials.IssueWithSecret(participantID, s.Secret)
+			}
 			iceServers = append(iceServers, is)
 		}
 	}
 
 	if len(rtcConf.STUNServers) > 0 {
 		hasSTUN = true
-		iceServers = append(iceServers, iceServerForStunServers(r.config.RTC.STUNServers))
+		iceServers = append(iceServers, iceServerForStunServers(r.config().RTC.STUNServers))
 	}
 
 	if !hasSTUN {
@@ -686,8 +1176,27 @@ func (r *RoomManager) iceServersForRoom(ri *livekit.Room, tlsOnly bool) []*livek
 	return iceServers
 }
 
+// nearestTURNRegion returns the region nearest to a client that TURN servers are configured in, empty when the
+// client is not located or servers are not configured by region
+func (r *RoomManager) nearestTURNRegion(clientLocation *geoip.Location) string {
+	if clientLocation == nil || !clientLocation.HasCoordinates {
+		return ""
+	}
+
+	var regions []config.RegionConfig
+	for _, region := range r.config().NodeSelector.Regions {
+		for _, s := range r.config().RTC.TURNServers {
+			if s.Region == region.Name {
+				regions = append(regions, region)
+				break
+			}
+		}
+	}
+	return selector.NearestRegion(regions, clientLocation.Latitude, clientLocation.Longitude)
+}
+
 func (r *RoomManager) refreshToken(participant types.LocalParticipant) error {
-	for key, secret := range r.config.Keys {
+	for key, secret := range r.config().Keys {
 		grants := participant.ClaimGrants()
 		token := auth.NewAccessToken(key, secret)
 		token.SetName(grants.Name).
@@ -716,6 +1225,99 @@ func (r *RoomManager) setIceConfig(participant types.LocalParticipant) *livekit.
 	return iceConfig
 }
 
+func (r *RoomManager) saveCheckpoint(roomName livekit.RoomName, participant types.LocalParticipant) {
+	checkpoint := participant.Checkpoint()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for key, cp := range r.checkpoints {
+		if time.Since(cp.CreatedAt) > checkpointTTL {
+			delete(r.checkpoints, key)
+		}
+	}
+	r.checkpoints[checkpointKey{roomName, participant.Identity()}] = checkpoint
+}
+
+// dropStaleCheckpoint forgets the checkpoint of a participant leaving with subscriptions or a subscription
+// permission changed since it was taken, e.g. after recovering. Restoring it would undo those changes.
+func (r *RoomManager) dropStaleCheckpoint(roomName livekit.RoomName, participant types.LocalParticipant) {
+	key := checkpointKey{roomName, participant.Identity()}
+	r.lock.RLock()
+	checkpoint := r.checkpoints[key]
+	r.lock.RUnlock()
+	if checkpoint == nil || !participant.Checkpoint().ChangedAt.After(checkpoint.CreatedAt) {
+		return
+	}
+
+	r.lock.Lock()
+	if r.checkpoints[key] == checkpoint {
+		delete(r.checkpoints, key)
+	}
+	r.lock.Unlock()
+	participant.GetLogger().Debugw("dropped stale participant checkpoint")
+}
+
+// restoreCheckpoint reapplies the state a previous session of the participant had before it became unstable,
+// so that it does not have to wait for the client to send its subscriptions again
+func (r *RoomManager) restoreCheckpoint(room *rtc.Room, participant types.LocalParticipant) {
+	key := checkpointKey{room.Name(), participant.Identity()}
+	r.lock.Lock()
+	checkpoint := r.checkpoints[key]
+	delete(r.checkpoints, key)
+	r.lock.Unlock()
+
+	if checkpoint == nil || time.Since(checkpoint.CreatedAt) > checkpointTTL {
+		return
+	}
+
+	if checkpoint.SubscriptionPermission != nil {
+		if err := room.UpdateSubscriptionPermission(participant, checkpoint.SubscriptionPermission); err != nil {
+			participant.GetLogger().Warnw("could not restore subscription permission", err)
+		}
+	}
+
+	var trackIDs []livekit.TrackID
+	for trackID, settings := range checkpoint.SubscribedTracks {
+		// skip tracks unpublished in the meantime
+		if room.ResolveMediaTrackForSubscriber(participant.Identity(), trackID).Track == nil {
+			continue
+		}
+		if settings != nil {
+			participant.UpdateSubscribedTrackSettings(trackID, settings)
+		}
+		trackIDs = append(trackIDs, trackID)
+	}
+	room.UpdateSubscriptions(participant, trackIDs, nil, true)
+	participant.GetLogger().Infow("restored participant checkpoint", "numTracks", len(trackIDs))
+}
+
+// saveBandwidthEstimate keeps the downlink estimate of a participant for a while, a client reconnecting after a
+// network blip starts from it instead of ramping up from the initial estimate again
+func (r *RoomManager) saveBandwidthEstimate(roomName livekit.RoomName, participant types.LocalParticipant) {
+	estimate := participant.GetSubscriberChannelCapacity()
+	if estimate <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), bandwidthEstimateSaveTimeout)
+	defer cancel()
+	if err := r.roomStore.StoreParticipantBandwidthEstimate(ctx, roomName, participant.Identity(), estimate, bandwidthEstimateTTL); err != nil {
+		participant.GetLogger().Warnw("could not store bandwidth estimate", err)
+	}
+}
+
+func (r *RoomManager) restoreBandwidthEstimate(ctx context.Context, roomName livekit.RoomName, participant types.LocalParticipant) {
+	estimate, err := r.roomStore.LoadParticipantBandwidthEstimate(ctx, roomName, participant.Identity())
+	if err != nil {
+		participant.GetLogger().Warnw("could not load bandwidth estimate", err)
+		return
+	}
+	if estimate <= 0 {
+		return
+	}
+	participant.SeedSubscriberChannelCapacity(estimate)
+	participant.GetLogger().Infow("restored bandwidth estimate", "estimate", estimate)
+}
+
 func (r *RoomManager) getIceConfig(participant types.LocalParticipant) *livekit.ICEConfig {
 	r.lock.Lock()
 	defer r.lock.Unlock()
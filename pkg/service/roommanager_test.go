@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestBandwidthEstimatePersistence(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore()
+	r := &RoomManager{roomStore: store}
+
+	previous := &typesfakes.FakeLocalParticipant{}
+	previous.IdentityReturns("alice")
+	previous.GetSubscriberChannelCapacityReturns(2_500_000)
+	r.saveBandwidthEstimate("room", previous)
+
+	t.Run("reconnecting participant starts from the previous estimate", func(t *testing.T) {
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IdentityReturns("alice")
+		p.GetLoggerReturns(logger.GetLogger())
+		r.restoreBandwidthEstimate(ctx, "room", p)
+		require.Equal(t, 1, p.SeedSubscriberChannelCapacityCallCount())
+		require.Equal(t, int64(2_500_000), p.SeedSubscriberChannelCapacityArgsForCall(0))
+	})
+
+	t.Run("no estimate for other participants", func(t *testing.T) {
+		p := &typesfakes.FakeLocalParticipant{}
+		p.IdentityReturns("bob")
+		r.restoreBandwidthEstimate(ctx, "room", p)
+		require.Zero(t, p.SeedSubscriberChannelCapacityCallCount())
+	})
+
+	t.Run("estimates expire", func(t *testing.T) {
+		require.NoError(t, store.StoreParticipantBandwidthEstimate(ctx, "room", "carol", 1_000_000, -time.Second))
+		estimate, err := store.LoadParticipantBandwidthEstimate(ctx, "room", "carol")
+		require.NoError(t, err)
+		require.Zero(t, estimate)
+	})
+}
+
+func TestDropStaleCheckpoint(t *testing.T) {
+	r := &RoomManager{checkpoints: make(map[checkpointKey]*types.ParticipantCheckpoint)}
+	p := &typesfakes.FakeLocalParticipant{}
+	p.IdentityReturns("alice")
+	p.GetLoggerReturns(logger.GetLogger())
+	p.CheckpointReturns(&types.ParticipantCheckpoint{CreatedAt: time.Now(), ChangedAt: time.Now().Add(-time.Minute)})
+	r.saveCheckpoint("room", p)
+
+	// nothing changed since the participant became unstable
+	r.dropStaleCheckpoint("room", p)
+	require.Contains(t, r.checkpoints, checkpointKey{"room", "alice"})
+
+	// subscriptions changed after recovering
+	p.CheckpointReturns(&types.ParticipantCheckpoint{CreatedAt: time.Now(), ChangedAt: time.Now()})
+	r.dropStaleCheckpoint("room", p)
+	require.Empty(t, r.checkpoints)
+}
+
+func TestTURNServersByRegion(t *testing.T) {
+	conf := &config.Config{}
+	conf.NodeSelector.Regions = []config.RegionConfig{
+		{Name: "us-west", Lat: 37.64, Lon: -120.88},
+		{Name: "us-east", Lat: 40.68, Lon: -74.04},
+		{Name: "eu-central", Lat: 50.11, Lon: 8.68},
+	}
+	conf.RTC.TURNServers = []config.TURNServer{
+		{Host: "turn-west", Port: 443, Protocol: "tls", Region: "us-west"},
+		{Host: "turn-east", Port: 443, Protocol: "tls", Region: "us-east"},
+		{Host: "turn-global", Port: 443, Protocol: "tls"},
+	}
+	r := &RoomManager{current: config.NewCurrent(conf)}
+	hosts := func(loc *geoip.Location) []string {
+		var hosts []string
+		for _, s := range r.iceServersForParticipant(context.Background(), &livekit.Room{Name: "room"}, "PA_participant", false, loc) {
+			hosts = append(hosts, s.Urls...)
+		}
+		return hosts
+	}
+
+	// not located, all servers
+	require.Equal(t, []string{
+		"turns:turn-west:443?transport=tcp",
+		"turns:turn-east:443?transport=tcp",
+		"turns:turn-global:443?transport=tcp",
+	}, hosts(nil))
+
+	// Boston
+	require.Equal(t, []string{
+		"turns:turn-east:443?transport=tcp",
+		"turns:turn-global:443?transport=tcp",
+	}, hosts(&geoip.Location{Latitude: 42.36, Longitude: -71.06, HasCoordinates: true}))
+
+	// Paris, nearest to us-east of the regions with servers
+	require.Equal(t, []string{
+		"turns:turn-east:443?transport=tcp",
+		"turns:turn-global:443?transport=tcp",
+	}, hosts(&geoip.Location{Latitude: 48.86, Longitude: 2.35, HasCoordinates: true}))
+}
+
+func TestEraseParticipantDataMessage(t *testing.T) {
+	data, err := proto.Marshal(newEraseParticipantDataMessage("alice"))
+	require.NoError(t, err)
+	msg := &livekit.RTCNodeMessage{}
+	require.NoError(t, proto.Unmarshal(data, msg))
+	require.True(t, isEraseParticipantDataMessage(msg))
+	require.False(t, isEraseParticipantDataMessage(&livekit.RTCNodeMessage{
+		Message: &livekit.RTCNodeMessage_RemoveParticipant{
+			RemoveParticipant: &livekit.RoomParticipantIdentity{Room: "room", Identity: "alice"},
+		},
+	}))
+
+	ts := &telemetryfakes.FakeTelemetryService{}
+	r := &RoomManager{telemetry: ts}
+	r.handleRTCMessage(context.Background(), "", "alice", msg)
+	require.Equal(t, 1, ts.EraseParticipantCallCount())
+	require.Equal(t, livekit.ParticipantIdentity("alice"), ts.EraseParticipantArgsForCall(0))
+}
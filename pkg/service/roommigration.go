@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+var ErrNoNodeToMigrateTo = errors.New("no other node is available to migrate rooms to")
+
+// NodeDrainStatus is how far a node is in handing its rooms over to other nodes
+type NodeDrainStatus struct {
+	NodeID   string `json:"node_id"`
+	Draining bool   `json:"draining"`
+	// rooms handed over, they close on this node once their participants left
+	MigratedRooms int `json:"migrated_rooms"`
+	// still on this node
+	Rooms        int `json:"rooms"`
+	Participants int `json:"participants"`
+}
+
+// Drain stops rooms from being placed on this node and migrates the rooms it hosts to other nodes. The state of
+// each room is mirrored, as for a standby node, and its participants are asked to fully reconnect. Reconnecting,
+// they place the room on another node and rejoin it with their previous IDs and subscriptions
+func (r *RoomManager) Drain(ctx context.Context) error {
+	nodes, err := r.router.ListNodes()
+	if err != nil {
+		return err
+	}
+	hasOtherNode := false
+	for _, node := range selector.GetAvailableNodes(nodes) {
+		if node.Id != r.currentNode.Id {
+			hasOtherNode = true
+			break
+		}
+	}
+	if !hasOtherNode {
+		return ErrNoNodeToMigrateTo
+	}
+
+	logger.Infow("draining node", "nodeID", r.currentNode.Id)
+	r.router.Drain()
+
+	for _, room := range r.localRooms() {
+		if err := r.migrateRoom(ctx, room); err != nil {
+			room.Logger.Warnw("could not migrate room", err)
+		}
+	}
+	return nil
+}
+
+// migrateRoom hands a room over to the node its participants place it on when they reconnect
+func (r *RoomManager) migrateRoom(ctx context.Context, room *rtc.Room) error {
+	roomName := room.Name()
+	// rooms relayed from another node go on there, their participants only need to connect elsewhere
+	if r.cascade == nil || !r.cascade.IsEdgeRoom(roomName) {
+		r.lock.Lock()
+		migrated := r.migratedRooms[roomName] == room
+		if !migrated {
+			r.migratedRooms[roomName] = room
+			r.numMigratedRooms++
+		}
+		r.lock.Unlock()
+		if migrated {
+			return nil
+		}
+
+		if err := r.mirrorRoom(ctx, room); err != nil {
+			r.lock.Lock()
+			delete(r.migratedRooms, roomName)
+			r.numMigratedRooms--
+			r.lock.Unlock()
+			return err
+		}
+		// the node reconnecting participants land on takes the room
+		if err := r.router.ClearRoomState(ctx, roomName); err != nil {
+			return err
+		}
+	}
+
+	participants := room.GetParticipants()
+	room.Logger.Infow("migrating room", "numParticipants", len(participants))
+	for _, p := range participants {
+		p.IssueFullReconnect(types.ParticipantCloseReasonMigrationRequested)
+		room.RemoveParticipant(p.Identity(), p.ID(), types.ParticipantCloseReasonMigrationRequested)
+	}
+	return nil
+}
+
+// isMigratedRoom tells whether a room was handed over to another node, it is not deleted when it closes here
+func (r *RoomManager) isMigratedRoom(room *rtc.Room) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.migratedRooms[room.Name()] == room
+}
+
+// removeMigratedRoom forgets a room closing on this node, returning whether it was handed over to another node
+func (r *RoomManager) removeMigratedRoom(room *rtc.Room) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.migratedRooms[room.Name()] != room {
+		return false
+	}
+	delete(r.migratedRooms, room.Name())
+	return true
+}
+
+func (r *RoomManager) DrainStatus() *NodeDrainStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	status := &NodeDrainStatus{
+		NodeID:        r.currentNode.Id,
+		Draining:      r.currentNode.State == livekit.NodeState_SHUTTING_DOWN,
+		MigratedRooms: r.numMigratedRooms,
+		Rooms:         len(r.rooms),
+	}
+	for _, room := range r.rooms {
+		status.Participants += len(room.GetParticipants())
+	}
+	return status
+}
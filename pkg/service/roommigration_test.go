@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/routingfakes"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestRoomManagerDrain(t *testing.T) {
+	currentNode := &livekit.Node{Id: "draining", State: livekit.NodeState_SERVING}
+	otherNode := &livekit.Node{Id: "other", State: livekit.NodeState_SERVING}
+
+	setup := func(nodes ...*livekit.Node) (*RoomManager, *routingfakes.FakeRouter, *rtc.Room, *typesfakes.FakeLocalParticipant) {
+		room := rtc.NewRoom(
+			&livekit.Room{Name: "room"},
+			nil,
+			rtc.WebRTCConfig{},
+			&config.AudioConfig{UpdateInterval: 500},
+			&livekit.ServerInfo{},
+			&telemetryfakes.FakeTelemetryService{},
+			nil,
+		)
+		t.Cleanup(room.Close)
+
+		router := &routingfakes.FakeRouter{}
+		router.ListNodesReturns(nodes, nil)
+		router.DrainCalls(func() {
+			currentNode.State = livekit.NodeState_SHUTTING_DOWN
+		})
+		currentNode.State = livekit.NodeState_SERVING
+		roomStore := NewLocalStore()
+		require.NoError(t, roomStore.StoreRoom(context.Background(), room.ToProto(), nil))
+		r := &RoomManager{
+			currentNode:          currentNode,
+			router:               router,
+			roomStore:            roomStore,
+			rooms:                map[livekit.RoomName]*rtc.Room{"room": room},
+			mirroredParticipants: make(map[checkpointKey]*MirroredParticipant),
+			migratedRooms:        make(map[livekit.RoomName]*rtc.Room),
+		}
+
+		alice := &typesfakes.FakeLocalParticipant{}
+		alice.IDReturns("PA_alice")
+		alice.IdentityReturns("alice")
+		alice.StateReturns(livekit.ParticipantInfo_ACTIVE)
+		alice.ToProtoReturns(&livekit.ParticipantInfo{Sid: "PA_alice", Identity: "alice", State: livekit.ParticipantInfo_ACTIVE})
+		alice.CheckpointReturns(&types.ParticipantCheckpoint{})
+		require.NoError(t, room.Join(alice, nil, nil, nil))
+		return r, router, room, alice
+	}
+
+	t.Run("needs another node", func(t *testing.T) {
+		r, router, _, alice := setup(currentNode)
+		require.ErrorIs(t, r.Drain(context.Background()), ErrNoNodeToMigrateTo)
+		require.Zero(t, router.DrainCallCount())
+		require.Zero(t, alice.IssueFullReconnectCallCount())
+		require.False(t, r.DrainStatus().Draining)
+	})
+
+	t.Run("migrates rooms", func(t *testing.T) {
+		r, router, room, alice := setup(currentNode, otherNode)
+		require.NoError(t, r.Drain(context.Background()))
+		require.Equal(t, 1, router.DrainCallCount())
+
+		// the room is mirrored for the next node, and is no longer placed on this one
+		mirror, err := r.roomStore.LoadRoomMirror(context.Background(), "room")
+		require.NoError(t, err)
+		require.Equal(t, livekit.NodeID("draining"), mirror.NodeID)
+		require.Equal(t, livekit.ParticipantID("PA_alice"), mirror.Participants["alice"].SID)
+		require.Equal(t, 1, router.ClearRoomStateCallCount())
+		require.True(t, r.isMigratedRoom(room))
+
+		// participants reconnect elsewhere
+		require.Equal(t, 1, alice.IssueFullReconnectCallCount())
+		require.Equal(t, types.ParticipantCloseReasonMigrationRequested, alice.IssueFullReconnectArgsForCall(0))
+		require.Equal(t, 1, alice.CloseCallCount())
+		require.Empty(t, room.GetParticipants())
+
+		status := r.DrainStatus()
+		require.True(t, status.Draining)
+		require.Equal(t, 1, status.MigratedRooms)
+		require.Zero(t, status.Participants)
+
+		// draining again does not migrate the room twice
+		require.NoError(t, r.Drain(context.Background()))
+		require.Equal(t, 1, router.ClearRoomStateCallCount())
+
+		// forgotten once closed here
+		require.True(t, r.removeMigratedRoom(room))
+		require.False(t, r.isMigratedRoom(room))
+		require.False(t, r.removeMigratedRoom(room))
+		require.Equal(t, 1, r.DrainStatus().MigratedRooms)
+	})
+}
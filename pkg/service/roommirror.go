@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+)
+
+const defaultMirrorInterval = 2 * time.Second
+
+// RoomMirror is the state of a high availability room the node hosting it mirrors, so that its standby node can take
+// the room over should the node fail. The room itself is in the store already
+type RoomMirror struct {
+	NodeID       livekit.NodeID                                       `json:"node_id"`
+	Participants map[livekit.ParticipantIdentity]*MirroredParticipant `json:"participants,omitempty"`
+	UpdatedAt    time.Time                                            `json:"updated_at"`
+}
+
+// MirroredParticipant is what a participant rejoining a room taken over gets back: its ID, the IDs of its tracks
+// once it publishes them again and its subscriptions. Messages are encoded with protojson
+type MirroredParticipant struct {
+	SID                    livekit.ParticipantID
+	Tracks                 []*livekit.TrackInfo
+	SubscribedTracks       map[livekit.TrackID]*livekit.UpdateTrackSettings
+	SubscriptionPermission *livekit.SubscriptionPermission
+}
+
+type mirroredParticipantJSON struct {
+	SID    livekit.ParticipantID `json:"sid"`
+	Tracks []json.RawMessage     `json:"tracks,omitempty"`
+	// null for tracks subscribed to with default settings
+	SubscribedTracks       map[livekit.TrackID]json.RawMessage `json:"subscribed_tracks,omitempty"`
+	SubscriptionPermission json.RawMessage                     `json:"subscription_permission,omitempty"`
+}
+
+var jsonNull = []byte("null")
+
+func (mp *MirroredParticipant) MarshalJSON() ([]byte, error) {
+	data := &mirroredParticipantJSON{SID: mp.SID}
+	for _, ti := range mp.Tracks {
+		b, err := protojson.Marshal(ti)
+		if err != nil {
+			return nil, err
+		}
+		data.Tracks = append(data.Tracks, b)
+	}
+	if len(mp.SubscribedTracks) != 0 {
+		data.SubscribedTracks = make(map[livekit.TrackID]json.RawMessage, len(mp.SubscribedTracks))
+		for trackID, settings := range mp.SubscribedTracks {
+			if settings == nil {
+				data.SubscribedTracks[trackID] = jsonNull
+				continue
+			}
+			b, err := protojson.Marshal(settings)
+			if err != nil {
+				return nil, err
+			}
+			data.SubscribedTracks[trackID] = b
+		}
+	}
+	if mp.SubscriptionPermission != nil {
+		b, err := protojson.Marshal(mp.SubscriptionPermission)
+		if err != nil {
+			return nil, err
+		}
+		data.SubscriptionPermission = b
+	}
+	return json.Marshal(data)
+}
+
+func (mp *MirroredParticipant) UnmarshalJSON(b []byte) error {
+	data := &mirroredParticipantJSON{}
+	if err := json.Unmarshal(b, data); err != nil {
+		return err
+	}
+
+	*mp = MirroredParticipant{SID: data.SID}
+	for _, raw := range data.Tracks {
+		ti := &livekit.TrackInfo{}
+		if err := protojson.Unmarshal(raw, ti); err != nil {
+			return err
+		}
+		mp.Tracks = append(mp.Tracks, ti)
+	}
+	if len(data.SubscribedTracks) != 0 {
+		mp.SubscribedTracks = make(map[livekit.TrackID]*livekit.UpdateTrackSettings, len(data.SubscribedTracks))
+		for trackID, raw := range data.SubscribedTracks {
+			if bytes.Equal(raw, jsonNull) {
+				mp.SubscribedTracks[trackID] = nil
+				continue
+			}
+			settings := &livekit.UpdateTrackSettings{}
+			if err := protojson.Unmarshal(raw, settings); err != nil {
+				return err
+			}
+			mp.SubscribedTracks[trackID] = settings
+		}
+	}
+	if len(data.SubscriptionPermission) != 0 && !bytes.Equal(data.SubscriptionPermission, jsonNull) {
+		mp.SubscriptionPermission = &livekit.SubscriptionPermission{}
+		if err := protojson.Unmarshal(data.SubscriptionPermission, mp.SubscriptionPermission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isHighAvailabilityRoom tells whether a room is mirrored to a standby node taking it over should its node fail
+func isHighAvailabilityRoom(conf *config.RoomConfig, roomName livekit.RoomName) bool {
+	return matchesRoomName(conf.HighAvailability.Rooms, string(roomName))
+}
+
+// mirrorWorker mirrors a high availability room till it closes
+func (r *RoomManager) mirrorWorker(room *rtc.Room) {
+	interval := r.config().Room.HighAvailability.MirrorInterval
+	if interval <= 0 {
+		interval = defaultMirrorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if room.IsClosed() {
+			return
+		}
+		if err := r.mirrorRoom(context.Background(), room); err != nil {
+			room.Logger.Warnw("could not mirror room", err)
+		}
+	}
+}
+
+// mirrorRoom stores the state of a room, unless it has closed. The store also keeps the mirror of a room deleted
+// meanwhile from coming back
+func (r *RoomManager) mirrorRoom(ctx context.Context, room *rtc.Room) error {
+	mirror := &RoomMirror{
+		NodeID:       livekit.NodeID(r.currentNode.Id),
+		Participants: make(map[livekit.ParticipantIdentity]*MirroredParticipant),
+		UpdatedAt:    time.Now(),
+	}
+	for _, p := range room.GetParticipants() {
+		if p.IsDisconnected() {
+			continue
+		}
+		checkpoint := p.Checkpoint()
+		mirror.Participants[p.Identity()] = &MirroredParticipant{
+			SID:                    p.ID(),
+			Tracks:                 p.ToProto().Tracks,
+			SubscribedTracks:       checkpoint.SubscribedTracks,
+			SubscriptionPermission: checkpoint.SubscriptionPermission,
+		}
+	}
+
+	if room.IsClosed() {
+		return nil
+	}
+	return r.roomStore.StoreRoomMirror(ctx, room.Name(), mirror)
+}
+
+// takeOver keeps the participants of a room mirrored by another node, for them to get their state back when they
+// rejoin this node. Mirrors older than a checkpoint are stale, their participants would have given up.
+// Called with the lock held
+func (r *RoomManager) takeOver(roomName livekit.RoomName, mirror *RoomMirror) {
+	if mirror == nil || mirror.NodeID == livekit.NodeID(r.currentNode.Id) || time.Since(mirror.UpdatedAt) > checkpointTTL {
+		return
+	}
+
+	logger.Infow("taking over room", "room", roomName, "fromNodeID", mirror.NodeID, "numParticipants", len(mirror.Participants))
+	for identity, mp := range mirror.Participants {
+		r.mirroredParticipants[checkpointKey{roomName, identity}] = mp
+	}
+}
+
+// takeMirroredParticipant returns the mirrored state of a participant rejoining a room taken over, once
+func (r *RoomManager) takeMirroredParticipant(roomName livekit.RoomName, identity livekit.ParticipantIdentity) *MirroredParticipant {
+	key := checkpointKey{roomName, identity}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	mp := r.mirroredParticipants[key]
+	delete(r.mirroredParticipants, key)
+	return mp
+}
+
+// restoreMirroredParticipant reapplies the subscriptions a participant had before the room was taken over. Unlike
+// with a checkpoint, tracks not published yet are subscribed to as well, their publishers are rejoining too
+func (r *RoomManager) restoreMirroredParticipant(room *rtc.Room, participant types.LocalParticipant, mp *MirroredParticipant) {
+	if mp.SubscriptionPermission != nil {
+		if err := room.UpdateSubscriptionPermission(participant, mp.SubscriptionPermission); err != nil {
+			participant.GetLogger().Warnw("could not restore subscription permission", err)
+		}
+	}
+
+	trackIDs := make([]livekit.TrackID, 0, len(mp.SubscribedTracks))
+	for trackID, settings := range mp.SubscribedTracks {
+		if settings != nil {
+			participant.UpdateSubscribedTrackSettings(trackID, settings)
+		}
+		trackIDs = append(trackIDs, trackID)
+	}
+	room.UpdateSubscriptions(participant, trackIDs, nil, true)
+	participant.GetLogger().Infow("restored mirrored participant", "numTracks", len(mp.Tracks), "numSubscribedTracks", len(trackIDs))
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestRoomTakeOver(t *testing.T) {
+	newRoomManager := func() *RoomManager {
+		return &RoomManager{
+			currentNode:          &livekit.Node{Id: "standby"},
+			mirroredParticipants: make(map[checkpointKey]*MirroredParticipant),
+		}
+	}
+	mirror := func(nodeID livekit.NodeID, updatedAt time.Time) *RoomMirror {
+		return &RoomMirror{
+			NodeID: nodeID,
+			Participants: map[livekit.ParticipantIdentity]*MirroredParticipant{
+				"alice": {SID: "PA_alice", Tracks: []*livekit.TrackInfo{{Sid: "TR_alice"}}},
+			},
+			UpdatedAt: updatedAt,
+		}
+	}
+
+	t.Run("participants get their mirrored state back once", func(t *testing.T) {
+		r := newRoomManager()
+		r.takeOver("room", mirror("primary", time.Now()))
+
+		require.Nil(t, r.takeMirroredParticipant("room", "bob"))
+		require.Nil(t, r.takeMirroredParticipant("other", "alice"))
+		mp := r.takeMirroredParticipant("room", "alice")
+		require.NotNil(t, mp)
+		require.Equal(t, livekit.ParticipantID("PA_alice"), mp.SID)
+		require.Nil(t, r.takeMirroredParticipant("room", "alice"))
+	})
+
+	t.Run("stale mirrors and mirrors of this node are ignored", func(t *testing.T) {
+		r := newRoomManager()
+		r.takeOver("room", mirror("primary", time.Now().Add(-time.Hour)))
+		r.takeOver("room", mirror("standby", time.Now()))
+		r.takeOver("room", nil)
+		require.Empty(t, r.mirroredParticipants)
+	})
+}
+
+func TestRoomMirrorEncoding(t *testing.T) {
+	mirror := &RoomMirror{
+		NodeID: "primary",
+		Participants: map[livekit.ParticipantIdentity]*MirroredParticipant{
+			"alice": {
+				SID: "PA_alice",
+				Tracks: []*livekit.TrackInfo{{
+					Sid:    "TR_alice",
+					Type:   livekit.TrackType_VIDEO,
+					Source: livekit.TrackSource_SCREEN_SHARE,
+					Layers: []*livekit.VideoLayer{{Quality: livekit.VideoQuality_HIGH, Width: 1280, Height: 720}},
+				}},
+				SubscribedTracks: map[livekit.TrackID]*livekit.UpdateTrackSettings{
+					"TR_bob":   {Quality: livekit.VideoQuality_MEDIUM, Width: 640},
+					"TR_carol": nil,
+				},
+				SubscriptionPermission: &livekit.SubscriptionPermission{
+					TrackPermissions: []*livekit.TrackPermission{{ParticipantIdentity: "bob", AllTracks: true}},
+				},
+			},
+			"bob": {SID: "PA_bob"},
+		},
+		UpdatedAt: time.Now().Truncate(time.Millisecond),
+	}
+
+	data, err := json.Marshal(mirror)
+	require.NoError(t, err)
+	// enums are encoded by name
+	require.Contains(t, string(data), `"SCREEN_SHARE"`)
+
+	decoded := &RoomMirror{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, mirror.NodeID, decoded.NodeID)
+	require.True(t, mirror.UpdatedAt.Equal(decoded.UpdatedAt))
+
+	alice := decoded.Participants["alice"]
+	require.Equal(t, livekit.ParticipantID("PA_alice"), alice.SID)
+	require.Len(t, alice.Tracks, 1)
+	require.True(t, proto.Equal(mirror.Participants["alice"].Tracks[0], alice.Tracks[0]))
+	require.True(t, proto.Equal(mirror.Participants["alice"].SubscribedTracks["TR_bob"], alice.SubscribedTracks["TR_bob"]))
+	// subscribed with default settings
+	settings, ok := alice.SubscribedTracks["TR_carol"]
+	require.True(t, ok)
+	require.Nil(t, settings)
+	require.True(t, proto.Equal(mirror.Participants["alice"].SubscriptionPermission, alice.SubscriptionPermission))
+
+	bob := decoded.Participants["bob"]
+	require.Equal(t, livekit.ParticipantID("PA_bob"), bob.SID)
+	require.Empty(t, bob.Tracks)
+	require.Nil(t, bob.SubscribedTracks)
+	require.Nil(t, bob.SubscriptionPermission)
+}
+
+func TestRoomMirrorNotStoredForDeletedRoom(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore()
+	mirror := &RoomMirror{NodeID: "primary", UpdatedAt: time.Now()}
+
+	require.NoError(t, store.StoreRoomMirror(ctx, "room", mirror))
+	loaded, err := store.LoadRoomMirror(ctx, "room")
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+
+	require.NoError(t, store.StoreRoom(ctx, &livekit.Room{Name: "room"}, nil))
+	require.NoError(t, store.StoreRoomMirror(ctx, "room", mirror))
+	loaded, err = store.LoadRoomMirror(ctx, "room")
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+
+	// a mirror written after the room closed does not bring it back
+	require.NoError(t, store.DeleteRoom(ctx, "room"))
+	require.NoError(t, store.StoreRoomMirror(ctx, "room", mirror))
+	loaded, err = store.LoadRoomMirror(ctx, "room")
+	require.NoError(t, err)
+	require.Nil(t, loaded)
+}
@@ -108,8 +108,23 @@ func (s *RoomService) ListRooms(ctx context.Context, req *livekit.ListRoomsReque
 	if len(req.Names) > 0 {
 		names = livekit.StringsAsRoomNames(req.Names)
 	}
-	rooms, err := s.roomStore.ListRooms(ctx, names)
+
+	// filtered, sorted and paginated when the request has listing options
+	var rooms []*livekit.Room
+	var next string
+	if opts := getListRoomsOptions(req); opts == nil {
+		rooms, err = s.roomStore.ListRooms(ctx, names)
+	} else if names != nil {
+		if rooms, err = s.roomStore.ListRooms(ctx, names); err == nil {
+			rooms, next, err = paginateRooms(rooms, opts)
+		}
+	} else {
+		rooms, next, err = s.roomStore.ListRoomsPage(ctx, opts)
+	}
 	if err != nil {
+		if errors.Is(err, ErrInvalidListingSort) || errors.Is(err, ErrInvalidListingCursor) {
+			return nil, twirp.InvalidArgumentError("listing", err.Error())
+		}
 		// TODO: translate error codes to Twirp
 		return nil, err
 	}
@@ -117,6 +132,7 @@ func (s *RoomService) ListRooms(ctx context.Context, req *livekit.ListRoomsReque
 	res := &livekit.ListRoomsResponse{
 		Rooms: rooms,
 	}
+	setListingNextCursor(res.ProtoReflect(), next)
 	return res, nil
 }
 
@@ -163,9 +179,18 @@ func (s *RoomService) ListParticipants(ctx context.Context, req *livekit.ListPar
 		return nil, err
 	}
 
+	// filtered, sorted and paginated when the request has listing options
+	var next string
+	if opts := getListParticipantsOptions(req); opts != nil {
+		if participants, next, err = paginateParticipants(participants, opts); err != nil {
+			return nil, twirp.InvalidArgumentError("listing", err.Error())
+		}
+	}
+
 	res := &livekit.ListParticipantsResponse{
 		Participants: participants,
 	}
+	setListingNextCursor(res.ProtoReflect(), next)
 	return res, nil
 }
 
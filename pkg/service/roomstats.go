@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const roomStatsPathPrefix = "/room_stats/"
+
+type GetRoomStatsRequest struct {
+	Room string `json:"room"`
+}
+
+type GetRoomStatsResponse struct {
+	Room   string         `json:"room"`
+	NodeID livekit.NodeID `json:"node_id"`
+	// resources of the room, including those of its participants
+	Resources    utils.ResourceStats         `json:"resources"`
+	Participants []*ParticipantResourceStats `json:"participants"`
+}
+
+type ParticipantResourceStats struct {
+	Identity  livekit.ParticipantIdentity `json:"identity"`
+	Resources utils.ResourceStats         `json:"resources"`
+}
+
+// RoomStatsService reports the goroutines, sockets and timers accounted to a room and each of its participants,
+// for capacity planning and leak triage per room. Rooms are reported by the node hosting them, requests are JSON
+// posted to /room_stats/GetRoomStats on that node and require the roomAdmin grant for the room
+type RoomStatsService struct {
+	roomManager *RoomManager
+}
+
+func NewRoomStatsService(roomManager *RoomManager) *RoomStatsService {
+	return &RoomStatsService{
+		roomManager: roomManager,
+	}
+}
+
+func (s *RoomStatsService) PathPrefix() string {
+	return roomStatsPathPrefix
+}
+
+func (s *RoomStatsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, roomStatsPathPrefix) {
+	case "GetRoomStats":
+		s.getRoomStats(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *RoomStatsService) getRoomStats(w http.ResponseWriter, r *http.Request) {
+	req := &GetRoomStatsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil || req.Room == "" {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	res, err := s.GetRoomStats(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrRoomNotFound) {
+			handleError(w, http.StatusNotFound, err, "room", req.Room)
+		} else {
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GetRoomStats returns the resources of a room hosted on this node
+func (s *RoomStatsService) GetRoomStats(ctx context.Context, req *GetRoomStatsRequest) (*GetRoomStatsResponse, error) {
+	room := s.roomManager.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	res := &GetRoomStatsResponse{
+		Room:      req.Room,
+		NodeID:    livekit.NodeID(s.roomManager.currentNode.Id),
+		Resources: room.Resources().Stats(),
+	}
+	for _, p := range room.GetParticipants() {
+		res.Participants = append(res.Participants, &ParticipantResourceStats{
+			Identity:  p.Identity(),
+			Resources: p.GetResourceStats(),
+		})
+	}
+	sort.Slice(res.Participants, func(i, j int) bool {
+		return res.Participants[i].Identity < res.Participants[j].Identity
+	})
+	return res, nil
+}
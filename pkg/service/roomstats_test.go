@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+func TestRoomStatsService(t *testing.T) {
+	room := rtc.NewRoom(
+		&livekit.Room{Name: "room"},
+		nil,
+		rtc.WebRTCConfig{},
+		&config.AudioConfig{UpdateInterval: 500},
+		&livekit.ServerInfo{},
+		&telemetryfakes.FakeTelemetryService{},
+		nil,
+	)
+	defer room.Close()
+	svc := NewRoomStatsService(&RoomManager{
+		currentNode: &livekit.Node{Id: "node"},
+		rooms:       map[livekit.RoomName]*rtc.Room{"room": room},
+	})
+
+	request := func(body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+"GetRoomStats", strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request(`{"room": "room"}`, nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request(`{"room": "other"}`, admin).Code)
+	})
+
+	t.Run("room not hosted on this node", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, request(`{"room": "other"}`, &auth.VideoGrant{RoomAdmin: true, Room: "other"}).Code)
+	})
+
+	t.Run("reports resources of the room", func(t *testing.T) {
+		// resources of participants are accounted to the room
+		participant := utils.NewResourceOwner("alice", room.Resources())
+		participant.AddSockets(2)
+		defer participant.AddSockets(-2)
+
+		w := request(`{"room": "room"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &GetRoomStatsResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+		require.Equal(t, "room", res.Room)
+		require.Equal(t, livekit.NodeID("node"), res.NodeID)
+		require.Equal(t, int32(2), res.Resources.Sockets)
+		// workers of the room
+		require.GreaterOrEqual(t, res.Resources.Goroutines, int32(3))
+		require.Empty(t, res.Participants)
+	})
+}
@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -13,58 +17,79 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/ua-parser/uap-go/uaparser"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/livekit/livekit-server/pkg/utils"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/livekit-server/pkg/rtc"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/telemetry/tracing"
 )
 
+// how long a client has to join with a fingerprint nonce once issued
+const fingerprintNonceTTL = time.Minute
+
 type RTCService struct {
 	router        routing.MessageRouter
 	roomAllocator RoomAllocator
-	store         ServiceStore
+	store         ObjectStore
 	upgrader      websocket.Upgrader
 	currentNode   routing.LocalNode
-	config        *config.Config
+	current       *config.Current
 	isDev         bool
-	limits        config.LimitConfig
 	parser        *uaparser.Parser
 	telemetry     telemetry.TelemetryService
+	// nil when geoip is not configured
+	geoIP geoip.Provider
+
+	joinCodeLimiter *joinCodeLimiter
+}
+
+// signalConnection reads and writes signal messages of a participant session
+type signalConnection interface {
+	ReadRequest() (*livekit.SignalRequest, int, error)
+	WriteResponse(*livekit.SignalResponse) (int, error)
+}
+
+// signalTransportConn is the connection underlying a signal connection, closed once the session ends
+type signalTransportConn interface {
+	Close() error
 }
 
 func NewRTCService(
-	conf *config.Config,
+	current *config.Current,
 	ra RoomAllocator,
-	store ServiceStore,
+	store ObjectStore,
 	router routing.MessageRouter,
 	currentNode routing.LocalNode,
 	telemetry telemetry.TelemetryService,
+	geoIP geoip.Provider,
 ) *RTCService {
+	conf := current.Get()
 	s := &RTCService{
 		router:        router,
 		roomAllocator: ra,
 		store:         store,
 		upgrader:      websocket.Upgrader{},
 		currentNode:   currentNode,
-		config:        conf,
+		current:       current,
 		isDev:         conf.Development,
-		limits:        conf.Limit,
 		parser:        uaparser.NewFromSaved(),
 		telemetry:     telemetry,
+		geoIP:         geoIP,
 	}
 
-	// allow connections from any origin, since script may be hosted anywhere
+	// allow connections from any origin unless restricted, since script may be hosted anywhere
 	// security is enforced by access tokens
-	s.upgrader.CheckOrigin = func(r *http.Request) bool {
-		return true
-	}
+	s.upgrader.CheckOrigin = NewOriginChecker(conf.CORS).CheckRequest
+	s.joinCodeLimiter = newJoinCodeLimiter()
 
 	return s
 }
@@ -95,6 +120,7 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	if claims.Identity == "" {
 		return "", pi, http.StatusBadRequest, ErrIdentityEmpty
 	}
+	tokenIdentity := livekit.ParticipantIdentity(claims.Identity)
 
 	roomName := livekit.RoomName(r.FormValue("room"))
 	reconnectParam := r.FormValue("reconnect")
@@ -104,6 +130,11 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 	adaptiveStreamParam := r.FormValue("adaptive_stream")
 	participantID := r.FormValue("sid")
 	subscriberAllowPauseParam := r.FormValue("subscriber_allow_pause")
+	capabilitiesParam := r.FormValue("capabilities")
+	fingerprintParam := r.FormValue("fingerprint")
+	joinCodeParam := r.FormValue("join_code")
+	screenWidth, _ := strconv.ParseUint(r.FormValue("screen_width"), 10, 32)
+	screenHeight, _ := strconv.ParseUint(r.FormValue("screen_height"), 10, 32)
 
 	if onlyName != "" {
 		roomName = onlyName
@@ -130,11 +161,32 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 		}
 	}
 
+	// participants resuming a session were checked when they joined, admins and hidden participants like recorders
+	// do not need a code
+	if !boolValue(reconnectParam) && !claims.Video.RoomAdmin && !claims.Video.Hidden {
+		codeHash, err := s.store.LoadRoomJoinCode(r.Context(), roomName)
+		if err != nil {
+			return "", pi, http.StatusInternalServerError, err
+		}
+		if codeHash != "" {
+			clientIP := GetClientIP(r)
+			if !s.joinCodeLimiter.allow(clientIP, time.Now()) {
+				return "", pi, http.StatusTooManyRequests, ErrTooManyJoinCodeAttempts
+			}
+			if !checkJoinCode(codeHash, joinCodeParam) {
+				s.joinCodeLimiter.failed(clientIP, time.Now())
+				return "", pi, http.StatusUnauthorized, ErrInvalidJoinCode
+			}
+		}
+	}
+
 	region := ""
 	if router, ok := s.router.(routing.Router); ok {
 		region = router.GetRegion()
-		if foundNode, err := router.GetNodeForRoom(r.Context(), roomName); err == nil {
-			if selector.LimitsReached(s.limits, foundNode.Stats) {
+		conf := s.current.Get()
+		// participants of rooms on full nodes are placed on other nodes when media of rooms is relayed
+		if foundNode, err := router.GetNodeForRoom(r.Context(), roomName); err == nil && conf.MediaRelay.Port == 0 {
+			if selector.LimitsReached(conf.Limit, foundNode.Stats) {
 				return "", pi, http.StatusServiceUnavailable, rtc.ErrLimitExceeded
 			}
 		}
@@ -149,6 +201,13 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 		Client:          s.ParseClientInfo(r),
 		Grants:          claims,
 		Region:          region,
+		DeviceHints: routing.DeviceHints{
+			ScreenWidth:  uint32(screenWidth),
+			ScreenHeight: uint32(screenHeight),
+			DeviceClass:  strings.ToLower(r.FormValue("device_class")),
+			BatterySaver: boolValue(r.FormValue("battery_saver")),
+		},
+		AudioOnlyDownlink: boolValue(r.FormValue("audio_only")),
 	}
 	if pi.Reconnect {
 		pi.ID = livekit.ParticipantID(participantID)
@@ -164,10 +223,89 @@ func (s *RTCService) validate(r *http.Request) (livekit.RoomName, routing.Partic
 		subscriberAllowPause := boolValue(subscriberAllowPauseParam)
 		pi.SubscriberAllowPause = &subscriberAllowPause
 	}
+	if fingerprintParam != "" {
+		if pi.Fingerprint = rtc.NormalizeFingerprint(fingerprintParam); pi.Fingerprint == "" {
+			return "", pi, http.StatusBadRequest, ErrInvalidFingerprint
+		}
+		// the fingerprint binds the identity, the client has to prove it holds the key of the certificate
+		if s.current.Get().RTC.IdentityBinding.Enabled {
+			if err = s.verifyFingerprintProof(r, roomName, tokenIdentity, pi.Fingerprint); err != nil {
+				return "", pi, http.StatusUnauthorized, err
+			}
+		}
+	}
+	if capabilitiesParam != "" {
+		for _, capability := range strings.Split(capabilitiesParam, ",") {
+			if capability = strings.TrimSpace(capability); capability != "" {
+				pi.Capabilities = append(pi.Capabilities, capability)
+			}
+		}
+	}
 
 	return roomName, pi, http.StatusOK, nil
 }
 
+// verifyFingerprintProof checks the signature of a nonce issued by FingerprintNonce with the key of the certificate
+// having the declared fingerprint, consuming the nonce. certificate, DER encoded, and signature are base64 encoded
+func (s *RTCService) verifyFingerprintProof(r *http.Request, roomName livekit.RoomName, identity livekit.ParticipantIdentity, fingerprint string) error {
+	nonce := r.FormValue("nonce")
+	certificate, err := base64.StdEncoding.DecodeString(r.FormValue("certificate"))
+	if err != nil || nonce == "" {
+		return ErrInvalidFingerprintProof
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.FormValue("signature"))
+	if err != nil {
+		return ErrInvalidFingerprintProof
+	}
+
+	issued, err := s.store.ConsumeFingerprintNonce(r.Context(), roomName, identity, nonce)
+	if err != nil {
+		return err
+	}
+	if !issued {
+		return ErrInvalidFingerprintProof
+	}
+	if err = rtc.VerifyFingerprintProof(fingerprint, certificate, []byte(nonce), signature); err != nil {
+		logger.Debugw("invalid fingerprint proof", err, "room", roomName, "participant", identity)
+		return ErrInvalidFingerprintProof
+	}
+	return nil
+}
+
+// FingerprintNonce issues a single use nonce to a client joining with a fingerprint when identity binding is
+// enabled. The client signs it with the key of its DTLS certificate and joins with the certificate, the nonce and
+// the signature, proving the fingerprint is of a certificate it holds
+func (s *RTCService) FingerprintNonce(w http.ResponseWriter, r *http.Request) {
+	claims := GetGrants(r.Context())
+	if claims == nil || claims.Video == nil || claims.Identity == "" {
+		handleError(w, http.StatusUnauthorized, rtc.ErrPermissionDenied)
+		return
+	}
+	onlyName, err := EnsureJoinPermission(r.Context())
+	if err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+	roomName := livekit.RoomName(r.FormValue("room"))
+	if onlyName != "" {
+		roomName = onlyName
+	}
+
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+	nonce := hex.EncodeToString(b)
+	if err = s.store.StoreFingerprintNonce(r.Context(), roomName, livekit.ParticipantIdentity(claims.Identity), nonce, fingerprintNonceTTL); err != nil {
+		handleError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"nonce": nonce})
+}
+
 func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// reject non websocket requests
 	if !websocket.IsWebSocketUpgrade(r) {
@@ -175,6 +313,22 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.serveSignal(w, r, rtc.SignalTransportWebSocket, func() (signalConnection, signalTransportConn, error) {
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewWSSignalConnection(conn), conn, nil
+	})
+}
+
+// serveSignal starts the participant session and relays signal messages until the connection returned by upgrade ends
+func (s *RTCService) serveSignal(
+	w http.ResponseWriter,
+	r *http.Request,
+	transport rtc.SignalTransport,
+	upgrade func() (signalConnection, signalTransportConn, error),
+) {
 	roomName, pi, code, err := s.validate(r)
 	if err != nil {
 		handleError(w, code, err)
@@ -186,14 +340,25 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"participant", pi.Identity,
 		"room", roomName,
 		"remote", false,
+		"transport", transport,
 	}
 
+	// the join is traced from here to the join response, continuing the trace of the client if it sent one
+	joinCtx, joinSpan := tracing.Start(
+		tracing.ContextWithClientTraceParent(r.Context(), r.Header.Get("traceparent")),
+		"signal.Join",
+		tracing.Room(roomName),
+		tracing.Participant(pi.Identity),
+		attribute.Bool("reconnect", pi.Reconnect),
+		attribute.String("transport", string(transport)),
+	)
+
 	// give it a few attempts to start session
 	var cr connectionResult
 	var initialResponse *livekit.SignalResponse
 	for i := 0; i < 3; i++ {
 		connectionTimeout := 3 * time.Second * time.Duration(i+1)
-		ctx := utils.ContextWithAttempt(r.Context(), i)
+		ctx := utils.ContextWithAttempt(joinCtx, i)
 		cr, initialResponse, err = s.startConnection(ctx, roomName, pi, connectionTimeout)
 		if err == nil {
 			break
@@ -203,6 +368,10 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			logger.Warnw("failed to start connection, retrying", err, fieldsWithAttempt...)
 		}
 	}
+	if err == nil {
+		joinSpan.SetAttributes(attribute.String("participant_sid", initialResponse.GetJoin().GetParticipant().GetSid()))
+	}
+	tracing.End(joinSpan, err)
 	if err != nil {
 		prometheus.IncrementParticipantJoinFail(1)
 		handleError(w, http.StatusInternalServerError, err, loggerFields...)
@@ -233,7 +402,7 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	done := make(chan struct{})
 	// function exits when websocket terminates, it'll close the event reading off of response sink as well
 	defer func() {
-		pLogger.Infow("finishing WS connection", "connID", cr.ConnectionID)
+		pLogger.Infow("finishing signal connection", "connID", cr.ConnectionID, "transport", transport)
 		cr.ResponseSource.Close()
 		cr.RequestSink.Close()
 		close(done)
@@ -244,14 +413,12 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// upgrade only once the basics are good to go
-	conn, err := s.upgrader.Upgrade(w, r, nil)
+	sigConn, conn, err := upgrade()
 	if err != nil {
 		handleError(w, http.StatusInternalServerError, err, loggerFields...)
 		return
 	}
-
-	// websocket established
-	sigConn := NewWSSignalConnection(conn)
+	// signal connection established
 	if count, err := sigConn.WriteResponse(initialResponse); err != nil {
 		pLogger.Warnw("could not write initial response", err)
 		return
@@ -260,8 +427,9 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			signalStats.AddBytes(uint64(count), true)
 		}
 	}
-	pLogger.Infow("new client WS connected",
+	pLogger.Infow("new client signal connected",
 		"connID", cr.ConnectionID,
+		"transport", transport,
 		"reconnect", pi.Reconnect,
 		"reconnectReason", pi.ReconnectReason,
 		"adaptiveStream", pi.AdaptiveStream,
@@ -311,7 +479,7 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				}
 
 				if count, err := sigConn.WriteResponse(res); err != nil {
-					pLogger.Warnw("error writing to websocket", err)
+					pLogger.Warnw("error writing to signal connection", err, "transport", transport)
 					return
 				} else if signalStats != nil {
 					signalStats.AddBytes(uint64(count), true)
@@ -320,24 +488,23 @@ func (s *RTCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// handle incoming requests from websocket
+	// handle incoming requests from the signal connection
 	for {
 		req, count, err := sigConn.ReadRequest()
 		// normal closure
 		if err != nil {
 			if err == io.EOF || strings.HasSuffix(err.Error(), "use of closed network connection") ||
 				websocket.IsCloseError(err, websocket.CloseAbnormalClosure, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
-				pLogger.Debugw("exit ws read loop for closed connection", "connID", cr.ConnectionID)
+				pLogger.Debugw("exit signal read loop for closed connection", "connID", cr.ConnectionID, "transport", transport)
 				return
 			} else {
-				pLogger.Errorw("error reading from websocket", err)
+				pLogger.Errorw("error reading from signal connection", err, "transport", transport)
 				return
 			}
 		}
 		if signalStats != nil {
 			signalStats.AddBytes(uint64(count), false)
 		}
-
 		switch m := req.Message.(type) {
 		case *livekit.SignalRequest_Ping:
 			count, perr := sigConn.WriteResponse(&livekit.SignalResponse{
@@ -450,9 +617,21 @@ type connectionResult struct {
 	ResponseSource routing.MessageSource
 }
 
-func (s *RTCService) startConnection(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit, timeout time.Duration) (connectionResult, *livekit.SignalResponse, error) {
-	var cr connectionResult
-	var err error
+func (s *RTCService) startConnection(ctx context.Context, roomName livekit.RoomName, pi routing.ParticipantInit, timeout time.Duration) (cr connectionResult, initialResponse *livekit.SignalResponse, err error) {
+	ctx, span := tracing.Start(ctx, "signal.StartConnection", attribute.Int("attempt", utils.GetAttempt(ctx)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	// the session on the RTC node is traced as part of this attempt
+	pi.TraceParent = tracing.TraceParent(ctx)
+
+	// a room is placed near the first client joining it, the RTC node reuses the location
+	if loc, err := geoip.LookupAddress(ctx, s.geoIP, pi.Client.GetAddress()); err != nil {
+		logger.Debugw("could not locate client", err, "participant", pi.Identity)
+	} else {
+		ctx = geoip.NewContext(ctx, loc)
+		pi.ClientLocation = loc
+	}
 	cr.Room, err = s.roomAllocator.CreateRoom(ctx, &livekit.CreateRoomRequest{Name: string(roomName)})
 	if err != nil {
 		return cr, nil, err
@@ -467,7 +646,7 @@ func (s *RTCService) startConnection(ctx context.Context, roomName livekit.RoomN
 	// wait for the first message before upgrading to websocket. If no one is
 	// responding to our connection attempt, we should terminate the connection
 	// instead of waiting forever on the WebSocket
-	initialResponse, err := readInitialResponse(cr.ResponseSource, timeout)
+	initialResponse, err = readInitialResponse(cr.ResponseSource, timeout)
 	if err != nil {
 		// close the connection to avoid leaking
 		cr.RequestSink.Close()
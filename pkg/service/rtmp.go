@@ -0,0 +1,599 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/playback"
+	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtmp"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+const (
+	rtmpPublishTimeout = 15 * time.Second
+	rtmpTrackName      = "rtmp"
+	ingestPathPrefix   = "/ingest/"
+
+	// reason streams are closed for, sent to the encoder
+	rtmpNotAllowedReason = "media is not allowed by the stream key"
+)
+
+type ListIngestSessionsRequest struct {
+	Room string `json:"room"`
+}
+
+type ListIngestSessionsResponse struct {
+	Sessions []IngestSession `json:"sessions"`
+}
+
+type RequestIngestReconnectRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+}
+
+// RTMPServer publishes the video of streams pushed by broadcast encoders into rooms. The stream key is an
+// access token with the roomJoin and canPublish grants, the stream is published by the identity of the token.
+// Keys can be scoped to the video the encoder may send with the canPublishSources grant and the ingest claim. The
+// stream joins the room once its onMetaData, or its first video, is in scope, streams declaring or sending anything
+// else are rejected without being published.
+//
+// The health of the streams pushed to a node is served as JSON posted to /ingest/<method> on that node:
+//   - ListIngestSessions returns the incoming bitrates, keyframe interval, dropped frames and warnings of the streams
+//     published in a room
+//   - RequestIngestReconnect asks the encoder publishing as an identity to reconnect, encoders not supporting the
+//     reconnect request of enhanced RTMP are disconnected after a grace period
+//
+// Both require the roomAdmin grant for the room. With health_webhooks, ingest_health_changed webhooks are sent when
+// the warnings of a stream change. RTMP has no message to guide the bitrate of encoders
+type RTMPServer struct {
+	conf        config.RTMPConfig
+	server      *rtmp.Server
+	keyProvider auth.KeyProvider
+	connect     playback.SignalConnector
+	telemetry   telemetry.TelemetryService
+
+	lock      sync.Mutex
+	streams   map[*rtmpStream]struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewRTMPServer(
+	conf *config.Config,
+	roomAllocator RoomAllocator,
+	router routing.Router,
+	keyProvider auth.KeyProvider,
+	ts telemetry.TelemetryService,
+) *RTMPServer {
+	s := &RTMPServer{
+		conf:        conf.RTMP,
+		keyProvider: keyProvider,
+		connect:     newPlaybackConnector(roomAllocator, router),
+		telemetry:   ts,
+		streams:     make(map[*rtmpStream]struct{}),
+		done:        make(chan struct{}),
+	}
+	s.server = rtmp.NewServer(s, logger.GetLogger())
+	go s.healthWorker()
+	return s
+}
+
+func (s *RTMPServer) PathPrefix() string {
+	return ingestPathPrefix
+}
+
+// Listen opens the TCP listeners on each address
+func (s *RTMPServer) Listen(addresses []string) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addresses))
+	for _, addr := range addresses {
+		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, s.conf.Port))
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+func (s *RTMPServer) Serve(ln net.Listener) error {
+	return s.server.Serve(ln)
+}
+
+func (s *RTMPServer) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	return s.server.Close()
+}
+
+func (s *RTMPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, ingestPathPrefix) {
+	case "ListIngestSessions":
+		s.listIngestSessions(w, r)
+	case "RequestIngestReconnect":
+		s.requestIngestReconnect(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *RTMPServer) listIngestSessions(w http.ResponseWriter, r *http.Request) {
+	req := &ListIngestSessionsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.ListIngestSessions(livekit.RoomName(req.Room)))
+}
+
+func (s *RTMPServer) requestIngestReconnect(w http.ResponseWriter, r *http.Request) {
+	req := &RequestIngestReconnectRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	if err := s.RequestIngestReconnect(livekit.RoomName(req.Room), livekit.ParticipantIdentity(req.Identity)); err != nil {
+		if errors.Is(err, ErrParticipantNotFound) {
+			handleError(w, http.StatusNotFound, err, "room", req.Room, "participant", req.Identity)
+		} else {
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room, "participant", req.Identity)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct{}{})
+}
+
+// ListIngestSessions returns the health of the streams published in a room from this node
+func (s *RTMPServer) ListIngestSessions(roomName livekit.RoomName) *ListIngestSessionsResponse {
+	res := &ListIngestSessionsResponse{
+		Sessions: []IngestSession{},
+	}
+	for _, stream := range s.getStreams(roomName, "") {
+		res.Sessions = append(res.Sessions, stream.health.snapshot())
+	}
+	return res
+}
+
+// RequestIngestReconnect asks the encoder publishing as identity to reconnect
+func (s *RTMPServer) RequestIngestReconnect(roomName livekit.RoomName, identity livekit.ParticipantIdentity) error {
+	streams := s.getStreams(roomName, identity)
+	if len(streams) == 0 {
+		return ErrParticipantNotFound
+	}
+	for _, stream := range streams {
+		if err := stream.requestReconnect(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RTMPServer) getStreams(roomName livekit.RoomName, identity livekit.ParticipantIdentity) []*rtmpStream {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var streams []*rtmpStream
+	for stream := range s.streams {
+		if stream.roomName == roomName && (identity == "" || stream.identity == identity) {
+			streams = append(streams, stream)
+		}
+	}
+	return streams
+}
+
+func (s *RTMPServer) removeStream(stream *rtmpStream) {
+	s.lock.Lock()
+	delete(s.streams, stream)
+	s.lock.Unlock()
+}
+
+// healthWorker samples the health of streams, and notifies when their warnings change
+func (s *RTMPServer) healthWorker() {
+	ticker := time.NewTicker(rtmpHealthInterval)
+	defer ticker.Stop()
+
+	warnings := make(map[*rtmpStream]string)
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.lock.Lock()
+		streams := make([]*rtmpStream, 0, len(s.streams))
+		for stream := range s.streams {
+			streams = append(streams, stream)
+		}
+		s.lock.Unlock()
+
+		sampled := make(map[*rtmpStream]string, len(streams))
+		for _, stream := range streams {
+			session := stream.health.sample(time.Now())
+			sampled[stream] = strings.Join(session.Warnings, ",")
+			if sampled[stream] == warnings[stream] || !s.conf.HealthWebhooks || s.telemetry == nil {
+				continue
+			}
+			s.telemetry.NotifyEventWithFields(context.Background(), &livekit.WebhookEvent{
+				Event:       telemetry.EventIngestHealthChanged,
+				Room:        &livekit.Room{Name: session.Room},
+				Participant: &livekit.ParticipantInfo{Identity: session.Identity},
+			}, map[string]interface{}{"ingest": session})
+		}
+		warnings = sampled
+	}
+}
+
+func (s *RTMPServer) OnPublish(conn *rtmp.Conn, app string, streamKey string) (rtmp.StreamHandler, error) {
+	grants, scope, err := s.verifyStreamKey(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	// the video of the stream is published as the camera track
+	if !grants.Video.GetCanPublishSource(livekit.TrackSource_CAMERA) {
+		return nil, ErrPermissionDenied
+	}
+
+	roomName := livekit.RoomName(grants.Video.Room)
+	identity := livekit.ParticipantIdentity(grants.Identity)
+	l := logger.GetLogger().WithValues("room", roomName, "participant", identity, "remote", conn.RemoteAddr().String())
+
+	// the stream joins the room once its metadata or first video shows it is in the scope of the key
+	l.Infow("RTMP stream started", "app", app)
+	stream := &rtmpStream{
+		server: s,
+		conn:   conn,
+		scope:  scope,
+		params: playback.IngestParams{
+			RoomName:  roomName,
+			Identity:  identity,
+			Name:      livekit.ParticipantName(grants.Name),
+			Metadata:  grants.Metadata,
+			TrackName: rtmpTrackName,
+			Connect:   s.connect,
+			Logger:    l,
+		},
+		roomName: roomName,
+		identity: identity,
+		logger:   l,
+		health: newRTMPHealth(IngestSession{
+			Protocol:   "rtmp",
+			Room:       string(roomName),
+			Identity:   string(identity),
+			RemoteAddr: conn.RemoteAddr().String(),
+			StartedAt:  time.Now().Unix(),
+		}, time.Now()),
+	}
+	s.lock.Lock()
+	s.streams[stream] = struct{}{}
+	s.lock.Unlock()
+	return stream, nil
+}
+
+func (s *RTMPServer) verifyStreamKey(streamKey string) (*auth.ClaimGrants, *ingestScope, error) {
+	v, err := auth.ParseAPIToken(streamKey)
+	if err != nil {
+		return nil, nil, ErrInvalidAuthorizationToken
+	}
+	secret := s.keyProvider.GetSecret(v.APIKey())
+	if secret == "" {
+		return nil, nil, ErrInvalidAuthorizationToken
+	}
+	grants, err := v.Verify(secret)
+	if err != nil {
+		return nil, nil, ErrInvalidAuthorizationToken
+	}
+
+	if _, err = EnsureJoinPermission(WithGrants(context.Background(), grants)); err != nil {
+		return nil, nil, err
+	}
+	if !grants.Video.GetCanPublish() {
+		return nil, nil, ErrPermissionDenied
+	}
+	if grants.Identity == "" {
+		return nil, nil, ErrIdentityEmpty
+	}
+
+	// the signature has been verified above
+	tok, err := jwt.ParseSigned(streamKey)
+	if err != nil {
+		return nil, nil, ErrInvalidAuthorizationToken
+	}
+	claims := ingestClaims{}
+	if err = tok.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, nil, ErrInvalidAuthorizationToken
+	}
+	return grants, newIngestScope(grants.Video, claims.Ingest), nil
+}
+
+// ingestClaims are the private claims of stream keys
+type ingestClaims struct {
+	Ingest *ingestGrant `json:"ingest,omitempty"`
+}
+
+// ingestGrant lists the video codecs an encoder may send, by name, e.g. h264. Any codec is allowed without codecs
+type ingestGrant struct {
+	VideoCodecs []string `json:"videoCodecs,omitempty"`
+}
+
+// ingestScope tells which video a stream may carry, as the camera source. Audio is never published, so it is not
+// scoped. Streams of unscoped keys are not rejected, media that cannot be published is dropped
+type ingestScope struct {
+	scoped      bool
+	video       bool
+	videoCodecs []string
+}
+
+func newIngestScope(video *auth.VideoGrant, grant *ingestGrant) *ingestScope {
+	s := &ingestScope{
+		scoped: len(video.CanPublishSources) != 0 || grant != nil,
+		video:  video.GetCanPublishSource(livekit.TrackSource_CAMERA),
+	}
+	if grant != nil {
+		s.videoCodecs = grant.VideoCodecs
+	}
+	return s
+}
+
+func (s *ingestScope) allowsVideo(codec string) bool {
+	return !s.scoped || (s.video && allowsCodec(s.videoCodecs, codec))
+}
+
+func allowsCodec(codecs []string, codec string) bool {
+	if len(codecs) == 0 {
+		return true
+	}
+	for _, c := range codecs {
+		if strings.EqualFold(c, codec) {
+			return true
+		}
+	}
+	return false
+}
+
+// rtmpVideoCodec names the codec of a video tag the way ingest grants do, empty when unknown
+func rtmpVideoCodec(tag *rtmp.VideoTag) string {
+	if tag.CodecID == rtmp.VideoCodecAVC {
+		return "h264"
+	}
+	return fourCCVideoCodec(tag.FourCC)
+}
+
+// rtmpMetadataVideoCodec names the codec declared by the videocodecid of onMetaData, a codec ID, or a FourCC as
+// a number or string with enhanced RTMP. ok is false when the metadata does not declare the video codec
+func rtmpMetadataVideoCodec(metadata map[string]interface{}) (codec string, ok bool) {
+	switch id := metadata["videocodecid"].(type) {
+	case float64:
+		if id == rtmp.VideoCodecAVC {
+			return "h264", true
+		}
+		if id >= 1<<24 && id < 1<<32 {
+			var fourCC [4]byte
+			binary.BigEndian.PutUint32(fourCC[:], uint32(id))
+			return fourCCVideoCodec(string(fourCC[:])), true
+		}
+		return "", true
+	case string:
+		if id == "avc1" {
+			return "h264", true
+		}
+		return fourCCVideoCodec(id), true
+	}
+	return "", false
+}
+
+func fourCCVideoCodec(fourCC string) string {
+	switch fourCC {
+	case "hvc1":
+		return "h265"
+	case "av01":
+		return "av1"
+	case "vp09":
+		return "vp9"
+	}
+	return ""
+}
+
+// rtmpAudioCodec names the codec of an audio tag the way ingest grants do, empty when unknown
+func rtmpAudioCodec(tag *rtmp.AudioTag) string {
+	switch tag.SoundFormat {
+	case rtmp.SoundFormatAAC:
+		return "aac"
+	case rtmp.SoundFormatMP3:
+		return "mp3"
+	case rtmp.SoundFormatSpeex:
+		return "speex"
+	}
+	return ""
+}
+
+// rtmpStream forwards the AVC video of a stream, other video codecs and audio, which cannot be published without
+// transcoding it to Opus, are reported once and dropped. The stream is published once its metadata, or its first
+// video without metadata, is in the scope of the key, streams declaring or sending video out of scope are closed
+// before joining the room
+type rtmpStream struct {
+	server             *RTMPServer
+	conn               *rtmp.Conn
+	params             playback.IngestParams
+	ingest             *playback.Ingest
+	scope              *ingestScope
+	roomName           livekit.RoomName
+	identity           livekit.ParticipantIdentity
+	health             *rtmpHealth
+	logger             logger.Logger
+	rejected           bool
+	unsupportedVideo   bool
+	unsupportedAudio   bool
+	missingAVCReported bool
+}
+
+func (r *rtmpStream) reject(reason string, keysAndValues ...interface{}) {
+	r.rejected = true
+	r.logger.Warnw("rejecting RTMP stream", nil, append([]interface{}{"reason", reason}, keysAndValues...)...)
+	_ = r.conn.Fail(reason)
+}
+
+// publish joins the room, once
+func (r *rtmpStream) publish() bool {
+	if r.ingest != nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rtmpPublishTimeout)
+	defer cancel()
+	ingest, err := playback.StartIngest(ctx, r.params)
+	if err != nil {
+		r.rejected = true
+		r.logger.Warnw("could not publish RTMP stream", err)
+		_ = r.conn.Fail("could not publish the stream")
+		return false
+	}
+	ingest.OnClosed(func() {
+		_ = r.conn.Close()
+	})
+	r.ingest = ingest
+	r.health.onPublished(ingest.TrackInfo().Sid)
+	r.logger.Infow("RTMP stream published", "trackID", ingest.TrackInfo().Sid)
+	return true
+}
+
+func (r *rtmpStream) requestReconnect() error {
+	r.logger.Infow("requesting RTMP reconnect")
+	if err := r.conn.RequestReconnect("reconnect requested by the server"); err != nil {
+		return err
+	}
+	time.AfterFunc(rtmpReconnectGrace, func() {
+		_ = r.conn.Close()
+	})
+	return nil
+}
+
+func (r *rtmpStream) OnMetadata(metadata map[string]interface{}) {
+	// encoders may send metadata again once publishing
+	if r.rejected || r.ingest != nil {
+		return
+	}
+	codec, ok := rtmpMetadataVideoCodec(metadata)
+	if !ok {
+		// checked on the first video instead
+		return
+	}
+	if !r.scope.allowsVideo(codec) {
+		r.reject(rtmpNotAllowedReason, "kind", "video", "codec", codec, "videoCodecID", metadata["videocodecid"])
+		return
+	}
+	r.publish()
+}
+
+func (r *rtmpStream) OnVideo(timestamp uint32, data []byte) {
+	if r.rejected {
+		return
+	}
+	tag, err := rtmp.ParseVideoTag(data)
+	if err != nil {
+		r.logger.Debugw("invalid RTMP video tag", "error", err)
+		return
+	}
+	codec := rtmpVideoCodec(tag)
+	if !r.scope.allowsVideo(codec) {
+		r.reject(rtmpNotAllowedReason, "kind", "video", "codec", codec, "codecID", tag.CodecID, "fourCC", tag.FourCC)
+		return
+	}
+	if !r.publish() {
+		return
+	}
+	// sequence headers are flagged as keyframes
+	keyframe := tag.Keyframe && (tag.CodecID != rtmp.VideoCodecAVC || tag.AVCPacketType == rtmp.AVCPacketNALU)
+	r.health.onVideo(time.Now(), timestamp, len(data), codec, keyframe)
+	if tag.CodecID != rtmp.VideoCodecAVC {
+		r.health.onCodecMismatch()
+		if !r.unsupportedVideo {
+			r.unsupportedVideo = true
+			r.logger.Warnw("unsupported RTMP video codec, only H.264 is published", nil, "codecID", tag.CodecID, "fourCC", tag.FourCC)
+		}
+		return
+	}
+
+	switch tag.AVCPacketType {
+	case rtmp.AVCPacketSequenceHeader:
+		if err := r.ingest.SetAVCConfig(tag.Data); err != nil {
+			r.logger.Warnw("invalid AVC configuration", err)
+		} else {
+			r.health.onAVCConfig()
+		}
+	case rtmp.AVCPacketNALU:
+		// RTMP timestamps are decoding times, sources are expected not to use B-frames
+		err := r.ingest.WriteAVCFrame(time.Duration(timestamp)*time.Millisecond, tag.Keyframe, tag.Data)
+		if errors.Is(err, playback.ErrMissingAVCConfig) {
+			r.health.onDroppedFrame(true)
+			if !r.missingAVCReported {
+				r.missingAVCReported = true
+				r.logger.Warnw("dropping RTMP video", err)
+			}
+		} else if err != nil {
+			r.health.onDroppedFrame(false)
+			r.logger.Debugw("could not write RTMP video", "error", err)
+		}
+	}
+}
+
+func (r *rtmpStream) OnAudio(_ uint32, data []byte) {
+	if r.rejected {
+		return
+	}
+	tag, err := rtmp.ParseAudioTag(data)
+	if err != nil {
+		return
+	}
+	codec := rtmpAudioCodec(tag)
+	r.health.onAudio(len(data), codec)
+	if r.unsupportedAudio {
+		return
+	}
+	r.unsupportedAudio = true
+	// WebRTC clients need Opus and the server cannot transcode audio, the video is published without it
+	r.logger.Warnw("RTMP audio is dropped, only video is published", nil, "codec", codec, "soundFormat", tag.SoundFormat)
+}
+
+func (r *rtmpStream) OnClose() {
+	r.logger.Infow("RTMP stream ended")
+	r.server.removeStream(r)
+	if r.ingest != nil {
+		r.ingest.Close()
+	}
+}
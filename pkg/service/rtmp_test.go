@@ -0,0 +1,104 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/auth/authfakes"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/rtmp"
+)
+
+func TestRTMPStreamKeyScope(t *testing.T) {
+	provider := &authfakes.FakeKeyProvider{}
+	provider.GetSecretReturns("secret")
+	s := &RTMPServer{keyProvider: provider}
+
+	streamKey := func(video *auth.VideoGrant, ingest *ingestGrant) string {
+		sig, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("secret")}, (&jose.SignerOptions{}).WithType("JWT"))
+		require.NoError(t, err)
+		token, err := jwt.Signed(sig).
+			Claims(jwt.Claims{Issuer: "key", Subject: "encoder", Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))}).
+			Claims(&auth.ClaimGrants{Video: video}).
+			Claims(ingestClaims{Ingest: ingest}).
+			CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	verify := func(video *auth.VideoGrant, ingest *ingestGrant) *ingestScope {
+		_, scope, err := s.verifyStreamKey(streamKey(video, ingest))
+		require.NoError(t, err)
+		return scope
+	}
+
+	t.Run("unscoped keys allow anything", func(t *testing.T) {
+		scope := verify(&auth.VideoGrant{RoomJoin: true, Room: "room"}, nil)
+		require.True(t, scope.allowsVideo("h265"))
+	})
+
+	t.Run("sources", func(t *testing.T) {
+		video := &auth.VideoGrant{RoomJoin: true, Room: "room"}
+		video.SetCanPublishSources([]livekit.TrackSource{livekit.TrackSource_CAMERA})
+		scope := verify(video, nil)
+		require.True(t, scope.allowsVideo("h265"))
+
+		video.SetCanPublishSources([]livekit.TrackSource{livekit.TrackSource_MICROPHONE})
+		require.False(t, verify(video, nil).allowsVideo("h264"))
+	})
+
+	t.Run("codecs", func(t *testing.T) {
+		scope := verify(&auth.VideoGrant{RoomJoin: true, Room: "room"}, &ingestGrant{VideoCodecs: []string{"H264"}})
+		require.True(t, scope.allowsVideo("h264"))
+		require.False(t, scope.allowsVideo("h265"))
+		require.False(t, scope.allowsVideo(""))
+	})
+
+	t.Run("tampered claims", func(t *testing.T) {
+		token := streamKey(&auth.VideoGrant{RoomJoin: true, Room: "room"}, &ingestGrant{VideoCodecs: []string{"h264"}})
+		other := streamKey(&auth.VideoGrant{RoomJoin: true, Room: "room"}, nil)
+		// payload of one key with the signature of another
+		tampered := token[:strings.LastIndex(token, ".")] + other[strings.LastIndex(other, "."):]
+		_, _, err := s.verifyStreamKey(tampered)
+		require.ErrorIs(t, err, ErrInvalidAuthorizationToken)
+	})
+}
+
+func TestRTMPCodecNames(t *testing.T) {
+	require.Equal(t, "h264", rtmpVideoCodec(&rtmp.VideoTag{CodecID: rtmp.VideoCodecAVC}))
+	require.Equal(t, "h265", rtmpVideoCodec(&rtmp.VideoTag{FourCC: "hvc1"}))
+	require.Equal(t, "", rtmpVideoCodec(&rtmp.VideoTag{CodecID: 2}))
+	require.Equal(t, "aac", rtmpAudioCodec(&rtmp.AudioTag{SoundFormat: rtmp.SoundFormatAAC}))
+	require.Equal(t, "", rtmpAudioCodec(&rtmp.AudioTag{SoundFormat: 7}))
+
+	metadataCodec := func(id interface{}) string {
+		codec, ok := rtmpMetadataVideoCodec(map[string]interface{}{"videocodecid": id})
+		require.True(t, ok)
+		return codec
+	}
+	require.Equal(t, "h264", metadataCodec(float64(7)))
+	require.Equal(t, "h265", metadataCodec(float64(0x68766331))) // hvc1
+	require.Equal(t, "av1", metadataCodec("av01"))
+	require.Equal(t, "", metadataCodec(float64(2)))
+	_, ok := rtmpMetadataVideoCodec(map[string]interface{}{"width": float64(1280)})
+	require.False(t, ok)
+}
+
+func TestRTMPMetadataWithoutCodec(t *testing.T) {
+	r := &rtmpStream{
+		scope:  &ingestScope{scoped: true, video: true, videoCodecs: []string{"h264"}},
+		logger: logger.GetLogger(),
+		health: newRTMPHealth(IngestSession{}, time.Now()),
+	}
+	// the codec is checked on the first video instead
+	r.OnMetadata(map[string]interface{}{"width": float64(1280)})
+	require.False(t, r.rejected)
+	require.Nil(t, r.ingest)
+}
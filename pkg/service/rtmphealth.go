@@ -0,0 +1,176 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	rtmpHealthInterval = 2 * time.Second
+	// longer keyframe intervals delay subscribers joining and recovering from loss
+	rtmpMaxKeyframeInterval = 10 * time.Second
+	// video is considered stalled when nothing is received for this long
+	rtmpVideoStallTimeout = 5 * time.Second
+	// clients supporting reconnect requests are given this long to reconnect before they are disconnected
+	rtmpReconnectGrace = 5 * time.Second
+)
+
+// ingest health warnings
+const (
+	IngestWarningCodecMismatch        = "codec_mismatch"
+	IngestWarningMissingAVCConfig     = "missing_avc_config"
+	IngestWarningDroppedFrames        = "dropped_frames"
+	IngestWarningLongKeyframeInterval = "long_keyframe_interval"
+	IngestWarningVideoStalled         = "video_stalled"
+)
+
+// IngestSession is the health of a stream pushed to this node by an encoder
+type IngestSession struct {
+	Protocol   string `json:"protocol"`
+	Room       string `json:"room"`
+	Identity   string `json:"identity"`
+	TrackSid   string `json:"track_sid"`
+	RemoteAddr string `json:"remote_addr"`
+	StartedAt  int64  `json:"started_at"`
+	// codecs received, e.g. h264 and aac
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec,omitempty"`
+	// incoming bitrates over the last couple of seconds, in bits per second
+	VideoBitrate uint64 `json:"video_bitrate"`
+	AudioBitrate uint64 `json:"audio_bitrate"`
+	// between the last two keyframes of the source
+	KeyframeIntervalMs int64 `json:"keyframe_interval_ms"`
+	// frames that could not be published, since the start of the stream
+	DroppedFrames uint64 `json:"dropped_frames"`
+	// sorted
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// rtmpHealth follows what an encoder sends, sampled every rtmpHealthInterval
+type rtmpHealth struct {
+	lock    sync.Mutex
+	session IngestSession
+
+	videoBytes    uint64
+	audioBytes    uint64
+	sampledAt     time.Time
+	lastVideoAt   time.Time
+	lastTimestamp uint32
+	keyframeAt    uint32
+	hasKeyframe   bool
+	codecMismatch bool
+	missingConfig bool
+	// dropped frames at the previous sample
+	sampledDropped uint64
+}
+
+func newRTMPHealth(session IngestSession, now time.Time) *rtmpHealth {
+	return &rtmpHealth{
+		session:     session,
+		sampledAt:   now,
+		lastVideoAt: now,
+	}
+}
+
+func (h *rtmpHealth) onVideo(now time.Time, timestamp uint32, size int, codec string, keyframe bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.videoBytes += uint64(size)
+	h.lastVideoAt = now
+	h.lastTimestamp = timestamp
+	h.session.VideoCodec = codec
+	if keyframe {
+		if h.hasKeyframe {
+			h.session.KeyframeIntervalMs = int64(timestamp - h.keyframeAt)
+		}
+		h.keyframeAt = timestamp
+		h.hasKeyframe = true
+	}
+}
+
+func (h *rtmpHealth) onPublished(trackSid string) {
+	h.lock.Lock()
+	h.session.TrackSid = trackSid
+	h.lock.Unlock()
+}
+
+func (h *rtmpHealth) onAudio(size int, codec string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.audioBytes += uint64(size)
+	h.session.AudioCodec = codec
+}
+
+func (h *rtmpHealth) onCodecMismatch() {
+	h.lock.Lock()
+	h.codecMismatch = true
+	h.lock.Unlock()
+}
+
+func (h *rtmpHealth) onAVCConfig() {
+	h.lock.Lock()
+	h.missingConfig = false
+	h.lock.Unlock()
+}
+
+func (h *rtmpHealth) onDroppedFrame(missingConfig bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.session.DroppedFrames++
+	if missingConfig {
+		h.missingConfig = true
+	}
+}
+
+// sample computes the bitrates since the previous sample and the warnings
+func (h *rtmpHealth) sample(now time.Time) IngestSession {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if elapsed := now.Sub(h.sampledAt); elapsed > 0 {
+		h.session.VideoBitrate = uint64(float64(h.videoBytes*8) / elapsed.Seconds())
+		h.session.AudioBitrate = uint64(float64(h.audioBytes*8) / elapsed.Seconds())
+	}
+	h.videoBytes = 0
+	h.audioBytes = 0
+	h.sampledAt = now
+	droppedRecent := h.session.DroppedFrames != h.sampledDropped
+	h.sampledDropped = h.session.DroppedFrames
+
+	var warnings []string
+	if h.codecMismatch {
+		warnings = append(warnings, IngestWarningCodecMismatch)
+	}
+	if h.missingConfig {
+		warnings = append(warnings, IngestWarningMissingAVCConfig)
+	}
+	if droppedRecent {
+		warnings = append(warnings, IngestWarningDroppedFrames)
+	}
+	if time.Duration(h.session.KeyframeIntervalMs)*time.Millisecond > rtmpMaxKeyframeInterval ||
+		(h.hasKeyframe && time.Duration(h.lastTimestamp-h.keyframeAt)*time.Millisecond > rtmpMaxKeyframeInterval) {
+		warnings = append(warnings, IngestWarningLongKeyframeInterval)
+	}
+	if now.Sub(h.lastVideoAt) > rtmpVideoStallTimeout {
+		warnings = append(warnings, IngestWarningVideoStalled)
+	}
+	sort.Strings(warnings)
+	h.session.Warnings = warnings
+	return h.snapshotLocked()
+}
+
+func (h *rtmpHealth) snapshot() IngestSession {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.snapshotLocked()
+}
+
+func (h *rtmpHealth) snapshotLocked() IngestSession {
+	session := h.session
+	session.Warnings = append([]string(nil), h.session.Warnings...)
+	return session
+}
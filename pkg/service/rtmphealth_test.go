@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTMPHealth(t *testing.T) {
+	start := time.Now()
+	h := newRTMPHealth(IngestSession{Protocol: "rtmp", Room: "room", Identity: "encoder"}, start)
+
+	t.Run("bitrates and keyframe interval", func(t *testing.T) {
+		for i := 0; i < 60; i++ {
+			h.onVideo(start, uint32(i*33), 1000, "h264", i%30 == 0)
+			h.onAudio(100, "aac")
+		}
+		session := h.sample(start.Add(2 * time.Second))
+		require.Equal(t, "h264", session.VideoCodec)
+		require.Equal(t, "aac", session.AudioCodec)
+		require.EqualValues(t, 60*1000*8/2, session.VideoBitrate)
+		require.EqualValues(t, 60*100*8/2, session.AudioBitrate)
+		require.EqualValues(t, 990, session.KeyframeIntervalMs)
+		require.Empty(t, session.Warnings)
+	})
+
+	t.Run("dropped frames are reported until none are dropped", func(t *testing.T) {
+		h.onDroppedFrame(true)
+		session := h.sample(start.Add(3 * time.Second))
+		require.EqualValues(t, 1, session.DroppedFrames)
+		require.Equal(t, []string{IngestWarningDroppedFrames, IngestWarningMissingAVCConfig}, session.Warnings)
+
+		h.onAVCConfig()
+		session = h.sample(start.Add(4 * time.Second))
+		require.EqualValues(t, 1, session.DroppedFrames)
+		require.Empty(t, session.Warnings)
+	})
+
+	t.Run("long keyframe intervals and stalls", func(t *testing.T) {
+		h.onVideo(start.Add(4*time.Second), 12000, 1000, "h264", false)
+		session := h.sample(start.Add(5 * time.Second))
+		require.Equal(t, []string{IngestWarningLongKeyframeInterval}, session.Warnings)
+
+		session = h.sample(start.Add(10 * time.Second))
+		require.Equal(t, []string{IngestWarningLongKeyframeInterval, IngestWarningVideoStalled}, session.Warnings)
+		require.Zero(t, session.VideoBitrate)
+		require.Equal(t, session, h.snapshot())
+	})
+
+	t.Run("codec mismatch", func(t *testing.T) {
+		h.onVideo(start.Add(10*time.Second), 12033, 1000, "h265", true)
+		h.onCodecMismatch()
+		session := h.sample(start.Add(11 * time.Second))
+		require.Equal(t, "h265", session.VideoCodec)
+		// the last interval was still long
+		require.Equal(t, []string{IngestWarningCodecMismatch, IngestWarningLongKeyframeInterval}, session.Warnings)
+	})
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+)
+
+// EgressSegment is the segment of a segmented egress output a segment event is about
+type EgressSegment struct {
+	EgressID         string `json:"egress_id"`
+	PlaylistName     string `json:"playlist_name,omitempty"`
+	PlaylistLocation string `json:"playlist_location,omitempty"`
+	// position of the segment in the playlist, from 0
+	Index int64 `json:"index"`
+	// number of segments from Index on the event is about, segments uploaded between two egress updates are sent as
+	// one segment_uploaded event
+	Count int64  `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
+// notifySegmentEvents sends the segment events of an egress update. Egress reports how many segments of each output
+// it uploaded, segments are derived from the counts of the update and of the previous one: segments counted since are
+// uploaded, the next one is started while the egress is active and failed when the egress fails. Egress reports in
+// intervals, so the segments uploaded since the previous update are sent as one event, and segments uploaded between
+// two updates are not started before
+func (s *IOInfoService) notifySegmentEvents(ctx context.Context, previous *livekit.EgressInfo, info *livekit.EgressInfo) {
+	previousActive := previous != nil && previous.Status == livekit.EgressStatus_EGRESS_ACTIVE
+	previousSegments := egressSegmentResults(previous)
+
+	notify := func(event string, segments *livekit.SegmentsInfo, index int64, count int64, errMsg string) {
+		s.telemetry.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
+			Event:      event,
+			EgressInfo: info,
+		}, map[string]interface{}{"segment": &EgressSegment{
+			EgressID:         info.EgressId,
+			PlaylistName:     segments.PlaylistName,
+			PlaylistLocation: segments.PlaylistLocation,
+			Index:            index,
+			Count:            count,
+			Error:            errMsg,
+		}})
+	}
+
+	for i, segments := range egressSegmentResults(info) {
+		var previousCount int64
+		seen := i < len(previousSegments)
+		if seen {
+			previousCount = previousSegments[i].SegmentCount
+		}
+
+		if segments.SegmentCount > previousCount {
+			notify(telemetry.EventSegmentUploaded, segments, previousCount, segments.SegmentCount-previousCount, "")
+		}
+
+		// the segment in progress at the previous update was announced already
+		started := previousActive && seen && segments.SegmentCount == previousCount
+		switch info.Status {
+		case livekit.EgressStatus_EGRESS_ACTIVE:
+			if !started {
+				notify(telemetry.EventSegmentStarted, segments, segments.SegmentCount, 1, "")
+			}
+		case livekit.EgressStatus_EGRESS_FAILED, livekit.EgressStatus_EGRESS_ABORTED:
+			if previousActive {
+				notify(telemetry.EventSegmentFailed, segments, segments.SegmentCount, 1, info.Error)
+			}
+		}
+	}
+}
+
+func egressSegmentResults(info *livekit.EgressInfo) []*livekit.SegmentsInfo {
+	if info == nil {
+		return nil
+	}
+	if len(info.SegmentResults) != 0 {
+		return info.SegmentResults
+	}
+	if segments := info.GetSegments(); segments != nil {
+		return []*livekit.SegmentsInfo{segments}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/livekit-server/pkg/service/servicefakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestSegmentEvents(t *testing.T) {
+	conf := &config.Config{}
+	conf.WebHook.SegmentEvents = true
+
+	var stored *livekit.EgressInfo
+	es := &servicefakes.FakeEgressStore{}
+	es.SwapEgressStub = func(_ context.Context, info *livekit.EgressInfo) (*livekit.EgressInfo, error) {
+		previous := stored
+		stored = proto.Clone(info).(*livekit.EgressInfo)
+		return previous, nil
+	}
+	ts := &telemetryfakes.FakeTelemetryService{}
+
+	s, err := service.NewIOInfoService(conf, "node", nil, es, nil, ts, nil)
+	require.NoError(t, err)
+
+	var events []string
+	update := func(status livekit.EgressStatus, count int64, errMsg string) []string {
+		_, err := s.UpdateEgressInfo(context.Background(), &livekit.EgressInfo{
+			EgressId: "EG_1",
+			Status:   status,
+			Error:    errMsg,
+			SegmentResults: []*livekit.SegmentsInfo{
+				{PlaylistName: "room.m3u8", SegmentCount: count},
+			},
+		})
+		require.NoError(t, err)
+
+		from := len(events)
+		for i := from; i < ts.NotifyEventWithFieldsCallCount(); i++ {
+			_, event, fields := ts.NotifyEventWithFieldsArgsForCall(i)
+			segment := fields["segment"].(*service.EgressSegment)
+			require.Equal(t, "EG_1", segment.EgressID)
+			require.Equal(t, "room.m3u8", segment.PlaylistName)
+			events = append(events, fmt.Sprintf("%s %d %d %s", event.Event, segment.Index, segment.Count, segment.Error))
+		}
+		return events[from:]
+	}
+
+	require.Empty(t, update(livekit.EgressStatus_EGRESS_STARTING, 0, ""))
+	require.Equal(t, []string{"segment_started 0 1 "}, update(livekit.EgressStatus_EGRESS_ACTIVE, 0, ""))
+	// repeated updates do not announce segments again
+	require.Empty(t, update(livekit.EgressStatus_EGRESS_ACTIVE, 0, ""))
+	// segments uploaded between updates are one event
+	require.Equal(t, []string{
+		"segment_uploaded 0 2 ",
+		"segment_started 2 1 ",
+	}, update(livekit.EgressStatus_EGRESS_ACTIVE, 2, ""))
+	require.Equal(t, []string{
+		"segment_uploaded 2 1 ",
+		"segment_failed 3 1 storage unavailable",
+	}, update(livekit.EgressStatus_EGRESS_FAILED, 3, "storage unavailable"))
+
+	// the update is stored when the swap fails
+	es.SwapEgressReturns(nil, errors.New("busy"))
+	calls := ts.NotifyEventWithFieldsCallCount()
+	_, err = s.UpdateEgressInfo(context.Background(), &livekit.EgressInfo{EgressId: "EG_1", Status: livekit.EgressStatus_EGRESS_FAILED})
+	require.NoError(t, err)
+	require.Equal(t, 1, es.UpdateEgressCallCount())
+	require.Equal(t, calls, ts.NotifyEventWithFieldsCallCount())
+
+	// disabled
+	ts = &telemetryfakes.FakeTelemetryService{}
+	s, err = service.NewIOInfoService(&config.Config{}, "node", nil, es, nil, ts, nil)
+	require.NoError(t, err)
+	_, err = s.UpdateEgressInfo(context.Background(), &livekit.EgressInfo{
+		EgressId:       "EG_2",
+		Status:         livekit.EgressStatus_EGRESS_ACTIVE,
+		SegmentResults: []*livekit.SegmentsInfo{{SegmentCount: 1}},
+	})
+	require.NoError(t, err)
+	require.Zero(t, ts.NotifyEventWithFieldsCallCount())
+}
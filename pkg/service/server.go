@@ -21,6 +21,8 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/routing"
+	"github.com/livekit/livekit-server/pkg/rtmp"
+	"github.com/livekit/livekit-server/pkg/transcoder"
 	"github.com/livekit/livekit-server/version"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/livekit"
@@ -29,63 +31,82 @@ import (
 
 type LivekitServer struct {
 	config       *config.Config
+	current      *config.Current
 	ioService    *IOInfoService
 	rtcService   *RTCService
+	playback     *PlaybackService
+	rtmp         *RTMPServer
+	logLevel     *LogLevelService
+	retention    *RetentionService
+	presence     *PresenceService
 	httpServer   *http.Server
+	listeners    []*apiListener
 	promServer   *http.Server
+	webTransport *WebTransportServer
 	router       routing.Router
 	roomManager  *RoomManager
 	signalServer *SignalServer
 	turnServer   *turn.Server
+	transcoder   *transcoder.Manager
 	currentNode  routing.LocalNode
 	running      atomic.Bool
 	doneChan     chan struct{}
 	closedChan   chan struct{}
 }
 
-func NewLivekitServer(conf *config.Config,
+func NewLivekitServer(current *config.Current,
 	roomService livekit.RoomService,
 	egressService *EgressService,
 	ingressService *IngressService,
 	ioService *IOInfoService,
 	rtcService *RTCService,
+	playbackService *PlaybackService,
+	rtmpServer *RTMPServer,
+	timelineService *TimelineService,
+	manifestService *ManifestService,
+	retentionService *RetentionService,
+	erasureService *ErasureService,
+	dashboardService *DashboardService,
+	logLevelService *LogLevelService,
+	roomAccessService *RoomAccessService,
+	bulkAdminService *BulkAdminService,
+	roomStatsService *RoomStatsService,
+	subscriptionDiagnosticsService *SubscriptionDiagnosticsService,
+	downlinkSimulatorService *DownlinkSimulatorService,
+	presenceService *PresenceService,
+	trackSyncService *TrackSyncService,
+	drainService *DrainService,
+	configService *ConfigService,
+	profilingService *ProfilingService,
 	keyProvider auth.KeyProvider,
 	router routing.Router,
 	roomManager *RoomManager,
 	signalServer *SignalServer,
 	turnServer *turn.Server,
+	transcoderManager *transcoder.Manager,
 	currentNode routing.LocalNode,
 ) (s *LivekitServer, err error) {
+	conf := current.Get()
 	s = &LivekitServer{
 		config:       conf,
+		current:      current,
 		ioService:    ioService,
 		rtcService:   rtcService,
+		playback:     playbackService,
+		rtmp:         rtmpServer,
+		logLevel:     logLevelService,
+		retention:    retentionService,
+		presence:     presenceService,
 		router:       router,
 		roomManager:  roomManager,
 		signalServer: signalServer,
 		// turn server starts automatically
 		turnServer:  turnServer,
+		transcoder:  transcoderManager,
 		currentNode: currentNode,
 		closedChan:  make(chan struct{}),
 	}
 
-	middlewares := []negroni.Handler{
-		// always first
-		negroni.NewRecovery(),
-		// CORS is allowed, we rely on token authentication to prevent improper use
-		cors.New(cors.Options{
-			AllowOriginFunc: func(origin string) bool {
-				return true
-			},
-			AllowedHeaders: []string{"*"},
-			// allow preflight to be cached for a day
-			MaxAge: 86400,
-		}),
-	}
-	if keyProvider != nil {
-		middlewares = append(middlewares, NewAPIKeyAuthMiddleware(keyProvider))
-	}
-
 	twirpLoggingHook := TwirpLogger(logger.GetLogger())
 	twirpRequestStatusHook := TwirpRequestStatusReporter()
 	roomServer := livekit.NewRoomServiceServer(roomService, twirpLoggingHook)
@@ -103,16 +124,73 @@ func NewLivekitServer(conf *config.Config,
 		mux = http.DefaultServeMux
 		mux.HandleFunc("/debug/goroutine", s.debugGoroutines)
 		mux.HandleFunc("/debug/rooms", s.debugInfo)
+		mux.Handle(downlinkSimulatorService.PathPrefix(), downlinkSimulatorService)
 	}
 	mux.Handle(roomServer.PathPrefix(), roomServer)
 	mux.Handle(egressServer.PathPrefix(), egressServer)
 	mux.Handle(ingressServer.PathPrefix(), ingressServer)
+	mux.Handle(playbackService.PathPrefix(), playbackService)
+	mux.Handle(timelineService.PathPrefix(), timelineService)
+	mux.Handle(manifestService.PathPrefix(), manifestService)
+	mux.Handle(retentionService.PathPrefix(), retentionService)
+	mux.Handle(erasureService.PathPrefix(), erasureService)
+	mux.Handle(logLevelService.PathPrefix(), logLevelService)
+	mux.Handle(roomAccessService.PathPrefix(), roomAccessService)
+	mux.Handle(bulkAdminService.PathPrefix(), bulkAdminService)
+	mux.Handle(roomStatsService.PathPrefix(), roomStatsService)
+	mux.Handle(subscriptionDiagnosticsService.PathPrefix(), subscriptionDiagnosticsService)
+	mux.Handle(presenceService.PathPrefix(), presenceService)
+	mux.Handle(trackSyncService.PathPrefix(), trackSyncService)
+	mux.Handle(drainService.PathPrefix(), drainService)
+	mux.Handle(configService.PathPrefix(), configService)
+	if rtmpServer != nil {
+		mux.Handle(rtmpServer.PathPrefix(), rtmpServer)
+	}
+	if conf.Dashboard.Enabled {
+		mux.Handle(dashboardService.PathPrefix(), dashboardService)
+	}
+	if conf.Profiling.Enabled {
+		mux.Handle(profilingService.PathPrefix(), profilingService)
+		if !conf.Development {
+			// registered onto DefaultServeMux without authentication in development
+			mux.Handle(pprofPathPrefix, profilingService.DebugHandler())
+			mux.Handle(runtimeMetricsPath, profilingService.DebugHandler())
+		}
+	}
 	mux.Handle("/rtc", rtcService)
 	mux.HandleFunc("/rtc/validate", rtcService.Validate)
+	mux.HandleFunc("/rtc/nonce", rtcService.FingerprintNonce)
+	mux.HandleFunc(capacityPath, s.capacity)
 	mux.HandleFunc("/", s.defaultHandler)
 
-	s.httpServer = &http.Server{
-		Handler: configureMiddlewares(mux, middlewares...),
+	if len(conf.Listeners) == 0 {
+		s.httpServer = &http.Server{
+			Handler: configureMiddlewares(mux, apiMiddlewares(conf, keyProvider)...),
+		}
+	}
+	for _, lc := range conf.Listeners {
+		if lc.Address == "" {
+			err = errors.New("listener address is required")
+			return
+		}
+		l := &apiListener{address: lc.Address}
+		if l.tlsConfig, err = NewListenerTLSConfig(lc.TLS); err != nil {
+			return
+		}
+		listenerKeyProvider := keyProvider
+		if keyProvider != nil {
+			listenerKeyProvider = NewListenerKeyProvider(keyProvider, lc.APIKeys)
+		}
+		l.server = &http.Server{
+			Handler: configureMiddlewares(mux, apiMiddlewares(conf, listenerKeyProvider)...),
+		}
+		s.listeners = append(s.listeners, l)
+	}
+
+	if conf.WebTransport.Port != 0 {
+		if s.webTransport, err = NewWebTransportServer(conf, rtcService, keyProvider); err != nil {
+			return
+		}
 	}
 
 	if conf.PrometheusPort > 0 {
@@ -167,6 +245,9 @@ func (s *LivekitServer) Start() error {
 		return err
 	}
 
+	s.retention.Start()
+	s.presence.Start()
+
 	addresses := s.config.BindAddresses
 	if addresses == nil {
 		addresses = []string{""}
@@ -174,29 +255,80 @@ func (s *LivekitServer) Start() error {
 
 	// ensure we could listen
 	listeners := make([]net.Listener, 0)
+	httpServers := make([]*http.Server, 0)
 	promListeners := make([]net.Listener, 0)
-	for _, addr := range addresses {
-		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, s.config.Port))
+	transcoderListeners := make([]net.Listener, 0)
+	var rtmpListeners []net.Listener
+	var webTransportConns []net.PacketConn
+	for _, l := range s.listeners {
+		ln, err := l.listen()
 		if err != nil {
 			return err
 		}
 		listeners = append(listeners, ln)
+		httpServers = append(httpServers, l.server)
+	}
+	for _, addr := range addresses {
+		if s.httpServer != nil {
+			ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, s.config.Port))
+			if err != nil {
+				return err
+			}
+			listeners = append(listeners, ln)
+			httpServers = append(httpServers, s.httpServer)
+		}
 
 		if s.promServer != nil {
-			ln, err = net.Listen("tcp", fmt.Sprintf("%s:%d", addr, s.config.PrometheusPort))
+			ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, s.config.PrometheusPort))
 			if err != nil {
 				return err
 			}
 			promListeners = append(promListeners, ln)
 		}
+
+		if s.transcoder != nil {
+			ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, s.config.Transcoder.Port))
+			if err != nil {
+				return err
+			}
+			transcoderListeners = append(transcoderListeners, ln)
+		}
+	}
+
+	if s.webTransport != nil {
+		conns, err := s.webTransport.Listen(addresses)
+		if err != nil {
+			return err
+		}
+		webTransportConns = conns
+	}
+
+	if s.rtmp != nil {
+		lns, err := s.rtmp.Listen(addresses)
+		if err != nil {
+			return err
+		}
+		rtmpListeners = lns
 	}
 
 	values := []interface{}{
-		"portHttp", s.config.Port,
 		"nodeID", s.currentNode.Id,
 		"nodeIP", s.currentNode.Ip,
 		"version", version.Version,
 	}
+	if s.httpServer != nil {
+		values = append(values, "portHttp", s.config.Port)
+	} else {
+		listenerValues := make([]string, 0, len(s.listeners))
+		for _, l := range s.listeners {
+			if l.tlsConfig != nil {
+				listenerValues = append(listenerValues, "https://"+l.address)
+			} else {
+				listenerValues = append(listenerValues, "http://"+l.address)
+			}
+		}
+		values = append(values, "listeners", listenerValues)
+	}
 	if s.config.BindAddresses != nil {
 		values = append(values, "bindAddresses", s.config.BindAddresses)
 	}
@@ -213,6 +345,15 @@ func (s *LivekitServer) Start() error {
 	if s.config.PrometheusPort != 0 {
 		values = append(values, "portPrometheus", s.config.PrometheusPort)
 	}
+	if s.config.Transcoder.Port != 0 {
+		values = append(values, "portTranscoder", s.config.Transcoder.Port)
+	}
+	if s.webTransport != nil {
+		values = append(values, "portWebTransport", s.config.WebTransport.Port)
+	}
+	if s.rtmp != nil {
+		values = append(values, "portRTMP", s.config.RTMP.Port)
+	}
 	if s.config.Region != "" {
 		values = append(values, "region", s.config.Region)
 	}
@@ -222,11 +363,31 @@ func (s *LivekitServer) Start() error {
 		go s.promServer.Serve(promLn)
 	}
 
+	for _, transcoderLn := range transcoderListeners {
+		go s.transcoder.Serve(transcoderLn)
+	}
+
+	for _, conn := range webTransportConns {
+		go func(conn net.PacketConn) {
+			if err := s.webTransport.Serve(conn); err != nil && err != http.ErrServerClosed {
+				logger.Errorw("webtransport server stopped", err)
+			}
+		}(conn)
+	}
+
+	for _, ln := range rtmpListeners {
+		go func(ln net.Listener) {
+			if err := s.rtmp.Serve(ln); err != nil && err != rtmp.ErrServerClosed {
+				logger.Errorw("rtmp server stopped", err)
+			}
+		}(ln)
+	}
+
 	httpGroup := &errgroup.Group{}
-	for _, ln := range listeners {
-		l := ln
+	for i, ln := range listeners {
+		l, server := ln, httpServers[i]
 		httpGroup.Go(func() error {
-			return s.httpServer.Serve(l)
+			return server.Serve(l)
 		})
 	}
 	go func() {
@@ -237,6 +398,7 @@ func (s *LivekitServer) Start() error {
 	}()
 
 	go s.backgroundWorker()
+	s.logLevel.Start()
 
 	// give time for Serve goroutine to start
 	time.Sleep(100 * time.Millisecond)
@@ -248,12 +410,31 @@ func (s *LivekitServer) Start() error {
 	// wait for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	_ = s.httpServer.Shutdown(ctx)
+	if s.httpServer != nil {
+		_ = s.httpServer.Shutdown(ctx)
+	}
+	for _, l := range s.listeners {
+		_ = l.server.Shutdown(ctx)
+	}
 
 	if s.turnServer != nil {
 		_ = s.turnServer.Close()
 	}
 
+	if s.transcoder != nil {
+		s.transcoder.Stop()
+	}
+
+	if s.webTransport != nil {
+		_ = s.webTransport.Close()
+	}
+
+	if s.rtmp != nil {
+		_ = s.rtmp.Close()
+	}
+
+	s.playback.Close()
+	s.logLevel.Stop()
 	s.roomManager.Stop()
 	s.signalServer.Stop()
 	s.ioService.Stop()
@@ -279,6 +460,8 @@ func (s *LivekitServer) Stop(force bool) {
 	}
 
 	s.router.Stop()
+	s.retention.Stop()
+	s.presence.Stop()
 	close(s.doneChan)
 
 	// wait for fully closed
@@ -347,6 +530,27 @@ func (s *LivekitServer) backgroundWorker() {
 	}
 }
 
+func apiMiddlewares(conf *config.Config, keyProvider auth.KeyProvider) []negroni.Handler {
+	origins := NewOriginChecker(conf.CORS)
+	middlewares := []negroni.Handler{
+		// always first
+		negroni.NewRecovery(),
+		// CORS is allowed from any origin unless restricted, we rely on token authentication to prevent improper use
+		cors.New(cors.Options{
+			AllowOriginFunc: origins.IsAllowed,
+			AllowedHeaders:  []string{"*"},
+			// allow preflight to be cached for a day
+			MaxAge: 86400,
+		}),
+		NewOriginMiddleware(origins),
+		NewRegionHintMiddleware(),
+	}
+	if keyProvider != nil {
+		middlewares = append(middlewares, NewAPIKeyAuthMiddleware(keyProvider, origins, conf.AdminAPIKeys))
+	}
+	return middlewares
+}
+
 func configureMiddlewares(handler http.Handler, middlewares ...negroni.Handler) *negroni.Negroni {
 	n := negroni.New()
 	for _, m := range middlewares {
@@ -51,6 +51,20 @@ type FakeEgressStore struct {
 	storeEgressReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SwapEgressStub        func(context.Context, *livekit.EgressInfo) (*livekit.EgressInfo, error)
+	swapEgressMutex       sync.RWMutex
+	swapEgressArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.EgressInfo
+	}
+	swapEgressReturns struct {
+		result1 *livekit.EgressInfo
+		result2 error
+	}
+	swapEgressReturnsOnCall map[int]struct {
+		result1 *livekit.EgressInfo
+		result2 error
+	}
 	UpdateEgressStub        func(context.Context, *livekit.EgressInfo) error
 	updateEgressMutex       sync.RWMutex
 	updateEgressArgsForCall []struct {
@@ -260,6 +274,71 @@ func (fake *FakeEgressStore) StoreEgressReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeEgressStore) SwapEgress(arg1 context.Context, arg2 *livekit.EgressInfo) (*livekit.EgressInfo, error) {
+	fake.swapEgressMutex.Lock()
+	ret, specificReturn := fake.swapEgressReturnsOnCall[len(fake.swapEgressArgsForCall)]
+	fake.swapEgressArgsForCall = append(fake.swapEgressArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.EgressInfo
+	}{arg1, arg2})
+	stub := fake.SwapEgressStub
+	fakeReturns := fake.swapEgressReturns
+	fake.recordInvocation("SwapEgress", []interface{}{arg1, arg2})
+	fake.swapEgressMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEgressStore) SwapEgressCallCount() int {
+	fake.swapEgressMutex.RLock()
+	defer fake.swapEgressMutex.RUnlock()
+	return len(fake.swapEgressArgsForCall)
+}
+
+func (fake *FakeEgressStore) SwapEgressCalls(stub func(context.Context, *livekit.EgressInfo) (*livekit.EgressInfo, error)) {
+	fake.swapEgressMutex.Lock()
+	defer fake.swapEgressMutex.Unlock()
+	fake.SwapEgressStub = stub
+}
+
+func (fake *FakeEgressStore) SwapEgressArgsForCall(i int) (context.Context, *livekit.EgressInfo) {
+	fake.swapEgressMutex.RLock()
+	defer fake.swapEgressMutex.RUnlock()
+	argsForCall := fake.swapEgressArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEgressStore) SwapEgressReturns(result1 *livekit.EgressInfo, result2 error) {
+	fake.swapEgressMutex.Lock()
+	defer fake.swapEgressMutex.Unlock()
+	fake.SwapEgressStub = nil
+	fake.swapEgressReturns = struct {
+		result1 *livekit.EgressInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEgressStore) SwapEgressReturnsOnCall(i int, result1 *livekit.EgressInfo, result2 error) {
+	fake.swapEgressMutex.Lock()
+	defer fake.swapEgressMutex.Unlock()
+	fake.SwapEgressStub = nil
+	if fake.swapEgressReturnsOnCall == nil {
+		fake.swapEgressReturnsOnCall = make(map[int]struct {
+			result1 *livekit.EgressInfo
+			result2 error
+		})
+	}
+	fake.swapEgressReturnsOnCall[i] = struct {
+		result1 *livekit.EgressInfo
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEgressStore) UpdateEgress(arg1 context.Context, arg2 *livekit.EgressInfo) error {
 	fake.updateEgressMutex.Lock()
 	ret, specificReturn := fake.updateEgressReturnsOnCall[len(fake.updateEgressArgsForCall)]
@@ -331,6 +410,8 @@ func (fake *FakeEgressStore) Invocations() map[string][][]interface{} {
 	defer fake.loadEgressMutex.RUnlock()
 	fake.storeEgressMutex.RLock()
 	defer fake.storeEgressMutex.RUnlock()
+	fake.swapEgressMutex.RLock()
+	defer fake.swapEgressMutex.RUnlock()
 	fake.updateEgressMutex.RLock()
 	defer fake.updateEgressMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -11,6 +11,22 @@ import (
 )
 
 type FakeObjectStore struct {
+	ConsumeFingerprintNonceStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, string) (bool, error)
+	consumeFingerprintNonceMutex       sync.RWMutex
+	consumeFingerprintNonceArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 string
+	}
+	consumeFingerprintNonceReturns struct {
+		result1 bool
+		result2 error
+	}
+	consumeFingerprintNonceReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	DeleteParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) error
 	deleteParticipantMutex       sync.RWMutex
 	deleteParticipantArgsForCall []struct {
@@ -24,6 +40,19 @@ type FakeObjectStore struct {
 	deleteParticipantReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeleteParticipantBandwidthEstimateStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) error
+	deleteParticipantBandwidthEstimateMutex       sync.RWMutex
+	deleteParticipantBandwidthEstimateArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}
+	deleteParticipantBandwidthEstimateReturns struct {
+		result1 error
+	}
+	deleteParticipantBandwidthEstimateReturnsOnCall map[int]struct {
+		result1 error
+	}
 	DeleteRoomStub        func(context.Context, livekit.RoomName) error
 	deleteRoomMutex       sync.RWMutex
 	deleteRoomArgsForCall []struct {
@@ -36,6 +65,18 @@ type FakeObjectStore struct {
 	deleteRoomReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeleteTURNSessionStub        func(context.Context, livekit.ParticipantID) error
+	deleteTURNSessionMutex       sync.RWMutex
+	deleteTURNSessionArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+	}
+	deleteTURNSessionReturns struct {
+		result1 error
+	}
+	deleteTURNSessionReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ListParticipantsStub        func(context.Context, livekit.RoomName) ([]*livekit.ParticipantInfo, error)
 	listParticipantsMutex       sync.RWMutex
 	listParticipantsArgsForCall []struct {
@@ -64,6 +105,37 @@ type FakeObjectStore struct {
 		result1 []*livekit.Room
 		result2 error
 	}
+	ListRoomsPageStub        func(context.Context, *service.ListRoomsOptions) ([]*livekit.Room, string, error)
+	listRoomsPageMutex       sync.RWMutex
+	listRoomsPageArgsForCall []struct {
+		arg1 context.Context
+		arg2 *service.ListRoomsOptions
+	}
+	listRoomsPageReturns struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}
+	listRoomsPageReturnsOnCall map[int]struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}
+	LoadIdentityBindingStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*service.IdentityBinding, error)
+	loadIdentityBindingMutex       sync.RWMutex
+	loadIdentityBindingArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}
+	loadIdentityBindingReturns struct {
+		result1 *service.IdentityBinding
+		result2 error
+	}
+	loadIdentityBindingReturnsOnCall map[int]struct {
+		result1 *service.IdentityBinding
+		result2 error
+	}
 	LoadParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error)
 	loadParticipantMutex       sync.RWMutex
 	loadParticipantArgsForCall []struct {
@@ -79,6 +151,21 @@ type FakeObjectStore struct {
 		result1 *livekit.ParticipantInfo
 		result2 error
 	}
+	LoadParticipantBandwidthEstimateStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (int64, error)
+	loadParticipantBandwidthEstimateMutex       sync.RWMutex
+	loadParticipantBandwidthEstimateArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}
+	loadParticipantBandwidthEstimateReturns struct {
+		result1 int64
+		result2 error
+	}
+	loadParticipantBandwidthEstimateReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
 	LoadRoomStub        func(context.Context, livekit.RoomName, bool) (*livekit.Room, *livekit.RoomInternal, error)
 	loadRoomMutex       sync.RWMutex
 	loadRoomArgsForCall []struct {
@@ -96,6 +183,76 @@ type FakeObjectStore struct {
 		result2 *livekit.RoomInternal
 		result3 error
 	}
+	LoadRoomAPIKeyStub        func(context.Context, livekit.RoomName) (string, error)
+	loadRoomAPIKeyMutex       sync.RWMutex
+	loadRoomAPIKeyArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomAPIKeyReturns struct {
+		result1 string
+		result2 error
+	}
+	loadRoomAPIKeyReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	LoadRoomJoinCodeStub        func(context.Context, livekit.RoomName) (string, error)
+	loadRoomJoinCodeMutex       sync.RWMutex
+	loadRoomJoinCodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomJoinCodeReturns struct {
+		result1 string
+		result2 error
+	}
+	loadRoomJoinCodeReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	LoadRoomMirrorStub        func(context.Context, livekit.RoomName) (*service.RoomMirror, error)
+	loadRoomMirrorMutex       sync.RWMutex
+	loadRoomMirrorArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomMirrorReturns struct {
+		result1 *service.RoomMirror
+		result2 error
+	}
+	loadRoomMirrorReturnsOnCall map[int]struct {
+		result1 *service.RoomMirror
+		result2 error
+	}
+	LoadRoomStandbyNodeStub        func(context.Context, livekit.RoomName) (livekit.NodeID, error)
+	loadRoomStandbyNodeMutex       sync.RWMutex
+	loadRoomStandbyNodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomStandbyNodeReturns struct {
+		result1 livekit.NodeID
+		result2 error
+	}
+	loadRoomStandbyNodeReturnsOnCall map[int]struct {
+		result1 livekit.NodeID
+		result2 error
+	}
+	LoadTURNSessionStub        func(context.Context, livekit.ParticipantID) ([]byte, error)
+	loadTURNSessionMutex       sync.RWMutex
+	loadTURNSessionArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+	}
+	loadTURNSessionReturns struct {
+		result1 []byte
+		result2 error
+	}
+	loadTURNSessionReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	LockRoomStub        func(context.Context, livekit.RoomName, time.Duration) (string, error)
 	lockRoomMutex       sync.RWMutex
 	lockRoomArgsForCall []struct {
@@ -111,6 +268,36 @@ type FakeObjectStore struct {
 		result1 string
 		result2 error
 	}
+	StoreFingerprintNonceStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, string, time.Duration) error
+	storeFingerprintNonceMutex       sync.RWMutex
+	storeFingerprintNonceArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 string
+		arg5 time.Duration
+	}
+	storeFingerprintNonceReturns struct {
+		result1 error
+	}
+	storeFingerprintNonceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreIdentityBindingStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, *service.IdentityBinding, time.Duration) error
+	storeIdentityBindingMutex       sync.RWMutex
+	storeIdentityBindingArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 *service.IdentityBinding
+		arg5 time.Duration
+	}
+	storeIdentityBindingReturns struct {
+		result1 error
+	}
+	storeIdentityBindingReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StoreParticipantStub        func(context.Context, livekit.RoomName, *livekit.ParticipantInfo) error
 	storeParticipantMutex       sync.RWMutex
 	storeParticipantArgsForCall []struct {
@@ -124,6 +311,21 @@ type FakeObjectStore struct {
 	storeParticipantReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StoreParticipantBandwidthEstimateStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, int64, time.Duration) error
+	storeParticipantBandwidthEstimateMutex       sync.RWMutex
+	storeParticipantBandwidthEstimateArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 int64
+		arg5 time.Duration
+	}
+	storeParticipantBandwidthEstimateReturns struct {
+		result1 error
+	}
+	storeParticipantBandwidthEstimateReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StoreRoomStub        func(context.Context, *livekit.Room, *livekit.RoomInternal) error
 	storeRoomMutex       sync.RWMutex
 	storeRoomArgsForCall []struct {
@@ -137,6 +339,72 @@ type FakeObjectStore struct {
 	storeRoomReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StoreRoomAPIKeyStub        func(context.Context, livekit.RoomName, string) error
+	storeRoomAPIKeyMutex       sync.RWMutex
+	storeRoomAPIKeyArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+	}
+	storeRoomAPIKeyReturns struct {
+		result1 error
+	}
+	storeRoomAPIKeyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreRoomJoinCodeStub        func(context.Context, livekit.RoomName, string) error
+	storeRoomJoinCodeMutex       sync.RWMutex
+	storeRoomJoinCodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+	}
+	storeRoomJoinCodeReturns struct {
+		result1 error
+	}
+	storeRoomJoinCodeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreRoomMirrorStub        func(context.Context, livekit.RoomName, *service.RoomMirror) error
+	storeRoomMirrorMutex       sync.RWMutex
+	storeRoomMirrorArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *service.RoomMirror
+	}
+	storeRoomMirrorReturns struct {
+		result1 error
+	}
+	storeRoomMirrorReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreRoomStandbyNodeStub        func(context.Context, livekit.RoomName, livekit.NodeID) error
+	storeRoomStandbyNodeMutex       sync.RWMutex
+	storeRoomStandbyNodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.NodeID
+	}
+	storeRoomStandbyNodeReturns struct {
+		result1 error
+	}
+	storeRoomStandbyNodeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StoreTURNSessionStub        func(context.Context, livekit.ParticipantID, []byte, time.Duration) error
+	storeTURNSessionMutex       sync.RWMutex
+	storeTURNSessionArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 []byte
+		arg4 time.Duration
+	}
+	storeTURNSessionReturns struct {
+		result1 error
+	}
+	storeTURNSessionReturnsOnCall map[int]struct {
+		result1 error
+	}
 	UnlockRoomStub        func(context.Context, livekit.RoomName, string) error
 	unlockRoomMutex       sync.RWMutex
 	unlockRoomArgsForCall []struct {
@@ -154,6 +422,73 @@ type FakeObjectStore struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeObjectStore) ConsumeFingerprintNonce(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity, arg4 string) (bool, error) {
+	fake.consumeFingerprintNonceMutex.Lock()
+	ret, specificReturn := fake.consumeFingerprintNonceReturnsOnCall[len(fake.consumeFingerprintNonceArgsForCall)]
+	fake.consumeFingerprintNonceArgsForCall = append(fake.consumeFingerprintNonceArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ConsumeFingerprintNonceStub
+	fakeReturns := fake.consumeFingerprintNonceReturns
+	fake.recordInvocation("ConsumeFingerprintNonce", []interface{}{arg1, arg2, arg3, arg4})
+	fake.consumeFingerprintNonceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) ConsumeFingerprintNonceCallCount() int {
+	fake.consumeFingerprintNonceMutex.RLock()
+	defer fake.consumeFingerprintNonceMutex.RUnlock()
+	return len(fake.consumeFingerprintNonceArgsForCall)
+}
+
+func (fake *FakeObjectStore) ConsumeFingerprintNonceCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, string) (bool, error)) {
+	fake.consumeFingerprintNonceMutex.Lock()
+	defer fake.consumeFingerprintNonceMutex.Unlock()
+	fake.ConsumeFingerprintNonceStub = stub
+}
+
+func (fake *FakeObjectStore) ConsumeFingerprintNonceArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity, string) {
+	fake.consumeFingerprintNonceMutex.RLock()
+	defer fake.consumeFingerprintNonceMutex.RUnlock()
+	argsForCall := fake.consumeFingerprintNonceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeObjectStore) ConsumeFingerprintNonceReturns(result1 bool, result2 error) {
+	fake.consumeFingerprintNonceMutex.Lock()
+	defer fake.consumeFingerprintNonceMutex.Unlock()
+	fake.ConsumeFingerprintNonceStub = nil
+	fake.consumeFingerprintNonceReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) ConsumeFingerprintNonceReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.consumeFingerprintNonceMutex.Lock()
+	defer fake.consumeFingerprintNonceMutex.Unlock()
+	fake.ConsumeFingerprintNonceStub = nil
+	if fake.consumeFingerprintNonceReturnsOnCall == nil {
+		fake.consumeFingerprintNonceReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.consumeFingerprintNonceReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeObjectStore) DeleteParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) error {
 	fake.deleteParticipantMutex.Lock()
 	ret, specificReturn := fake.deleteParticipantReturnsOnCall[len(fake.deleteParticipantArgsForCall)]
@@ -217,6 +552,69 @@ func (fake *FakeObjectStore) DeleteParticipantReturnsOnCall(i int, result1 error
 	}{result1}
 }
 
+func (fake *FakeObjectStore) DeleteParticipantBandwidthEstimate(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) error {
+	fake.deleteParticipantBandwidthEstimateMutex.Lock()
+	ret, specificReturn := fake.deleteParticipantBandwidthEstimateReturnsOnCall[len(fake.deleteParticipantBandwidthEstimateArgsForCall)]
+	fake.deleteParticipantBandwidthEstimateArgsForCall = append(fake.deleteParticipantBandwidthEstimateArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.DeleteParticipantBandwidthEstimateStub
+	fakeReturns := fake.deleteParticipantBandwidthEstimateReturns
+	fake.recordInvocation("DeleteParticipantBandwidthEstimate", []interface{}{arg1, arg2, arg3})
+	fake.deleteParticipantBandwidthEstimateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) DeleteParticipantBandwidthEstimateCallCount() int {
+	fake.deleteParticipantBandwidthEstimateMutex.RLock()
+	defer fake.deleteParticipantBandwidthEstimateMutex.RUnlock()
+	return len(fake.deleteParticipantBandwidthEstimateArgsForCall)
+}
+
+func (fake *FakeObjectStore) DeleteParticipantBandwidthEstimateCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) error) {
+	fake.deleteParticipantBandwidthEstimateMutex.Lock()
+	defer fake.deleteParticipantBandwidthEstimateMutex.Unlock()
+	fake.DeleteParticipantBandwidthEstimateStub = stub
+}
+
+func (fake *FakeObjectStore) DeleteParticipantBandwidthEstimateArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity) {
+	fake.deleteParticipantBandwidthEstimateMutex.RLock()
+	defer fake.deleteParticipantBandwidthEstimateMutex.RUnlock()
+	argsForCall := fake.deleteParticipantBandwidthEstimateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) DeleteParticipantBandwidthEstimateReturns(result1 error) {
+	fake.deleteParticipantBandwidthEstimateMutex.Lock()
+	defer fake.deleteParticipantBandwidthEstimateMutex.Unlock()
+	fake.DeleteParticipantBandwidthEstimateStub = nil
+	fake.deleteParticipantBandwidthEstimateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) DeleteParticipantBandwidthEstimateReturnsOnCall(i int, result1 error) {
+	fake.deleteParticipantBandwidthEstimateMutex.Lock()
+	defer fake.deleteParticipantBandwidthEstimateMutex.Unlock()
+	fake.DeleteParticipantBandwidthEstimateStub = nil
+	if fake.deleteParticipantBandwidthEstimateReturnsOnCall == nil {
+		fake.deleteParticipantBandwidthEstimateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteParticipantBandwidthEstimateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) DeleteRoom(arg1 context.Context, arg2 livekit.RoomName) error {
 	fake.deleteRoomMutex.Lock()
 	ret, specificReturn := fake.deleteRoomReturnsOnCall[len(fake.deleteRoomArgsForCall)]
@@ -279,6 +677,68 @@ func (fake *FakeObjectStore) DeleteRoomReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeObjectStore) DeleteTURNSession(arg1 context.Context, arg2 livekit.ParticipantID) error {
+	fake.deleteTURNSessionMutex.Lock()
+	ret, specificReturn := fake.deleteTURNSessionReturnsOnCall[len(fake.deleteTURNSessionArgsForCall)]
+	fake.deleteTURNSessionArgsForCall = append(fake.deleteTURNSessionArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+	}{arg1, arg2})
+	stub := fake.DeleteTURNSessionStub
+	fakeReturns := fake.deleteTURNSessionReturns
+	fake.recordInvocation("DeleteTURNSession", []interface{}{arg1, arg2})
+	fake.deleteTURNSessionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) DeleteTURNSessionCallCount() int {
+	fake.deleteTURNSessionMutex.RLock()
+	defer fake.deleteTURNSessionMutex.RUnlock()
+	return len(fake.deleteTURNSessionArgsForCall)
+}
+
+func (fake *FakeObjectStore) DeleteTURNSessionCalls(stub func(context.Context, livekit.ParticipantID) error) {
+	fake.deleteTURNSessionMutex.Lock()
+	defer fake.deleteTURNSessionMutex.Unlock()
+	fake.DeleteTURNSessionStub = stub
+}
+
+func (fake *FakeObjectStore) DeleteTURNSessionArgsForCall(i int) (context.Context, livekit.ParticipantID) {
+	fake.deleteTURNSessionMutex.RLock()
+	defer fake.deleteTURNSessionMutex.RUnlock()
+	argsForCall := fake.deleteTURNSessionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) DeleteTURNSessionReturns(result1 error) {
+	fake.deleteTURNSessionMutex.Lock()
+	defer fake.deleteTURNSessionMutex.Unlock()
+	fake.DeleteTURNSessionStub = nil
+	fake.deleteTURNSessionReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) DeleteTURNSessionReturnsOnCall(i int, result1 error) {
+	fake.deleteTURNSessionMutex.Lock()
+	defer fake.deleteTURNSessionMutex.Unlock()
+	fake.DeleteTURNSessionStub = nil
+	if fake.deleteTURNSessionReturnsOnCall == nil {
+		fake.deleteTURNSessionReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteTURNSessionReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) ListParticipants(arg1 context.Context, arg2 livekit.RoomName) ([]*livekit.ParticipantInfo, error) {
 	fake.listParticipantsMutex.Lock()
 	ret, specificReturn := fake.listParticipantsReturnsOnCall[len(fake.listParticipantsArgsForCall)]
@@ -414,35 +874,169 @@ func (fake *FakeObjectStore) ListRoomsReturnsOnCall(i int, result1 []*livekit.Ro
 	}{result1, result2}
 }
 
-func (fake *FakeObjectStore) LoadParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
-	fake.loadParticipantMutex.Lock()
-	ret, specificReturn := fake.loadParticipantReturnsOnCall[len(fake.loadParticipantArgsForCall)]
-	fake.loadParticipantArgsForCall = append(fake.loadParticipantArgsForCall, struct {
+func (fake *FakeObjectStore) ListRoomsPage(arg1 context.Context, arg2 *service.ListRoomsOptions) ([]*livekit.Room, string, error) {
+	fake.listRoomsPageMutex.Lock()
+	ret, specificReturn := fake.listRoomsPageReturnsOnCall[len(fake.listRoomsPageArgsForCall)]
+	fake.listRoomsPageArgsForCall = append(fake.listRoomsPageArgsForCall, struct {
 		arg1 context.Context
-		arg2 livekit.RoomName
-		arg3 livekit.ParticipantIdentity
-	}{arg1, arg2, arg3})
-	stub := fake.LoadParticipantStub
-	fakeReturns := fake.loadParticipantReturns
-	fake.recordInvocation("LoadParticipant", []interface{}{arg1, arg2, arg3})
-	fake.loadParticipantMutex.Unlock()
+		arg2 *service.ListRoomsOptions
+	}{arg1, arg2})
+	stub := fake.ListRoomsPageStub
+	fakeReturns := fake.listRoomsPageReturns
+	fake.recordInvocation("ListRoomsPage", []interface{}{arg1, arg2})
+	fake.listRoomsPageMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3
 	}
-	return fakeReturns.result1, fakeReturns.result2
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
-func (fake *FakeObjectStore) LoadParticipantCallCount() int {
-	fake.loadParticipantMutex.RLock()
-	defer fake.loadParticipantMutex.RUnlock()
-	return len(fake.loadParticipantArgsForCall)
+func (fake *FakeObjectStore) ListRoomsPageCallCount() int {
+	fake.listRoomsPageMutex.RLock()
+	defer fake.listRoomsPageMutex.RUnlock()
+	return len(fake.listRoomsPageArgsForCall)
 }
 
-func (fake *FakeObjectStore) LoadParticipantCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error)) {
-	fake.loadParticipantMutex.Lock()
+func (fake *FakeObjectStore) ListRoomsPageCalls(stub func(context.Context, *service.ListRoomsOptions) ([]*livekit.Room, string, error)) {
+	fake.listRoomsPageMutex.Lock()
+	defer fake.listRoomsPageMutex.Unlock()
+	fake.ListRoomsPageStub = stub
+}
+
+func (fake *FakeObjectStore) ListRoomsPageArgsForCall(i int) (context.Context, *service.ListRoomsOptions) {
+	fake.listRoomsPageMutex.RLock()
+	defer fake.listRoomsPageMutex.RUnlock()
+	argsForCall := fake.listRoomsPageArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) ListRoomsPageReturns(result1 []*livekit.Room, result2 string, result3 error) {
+	fake.listRoomsPageMutex.Lock()
+	defer fake.listRoomsPageMutex.Unlock()
+	fake.ListRoomsPageStub = nil
+	fake.listRoomsPageReturns = struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeObjectStore) ListRoomsPageReturnsOnCall(i int, result1 []*livekit.Room, result2 string, result3 error) {
+	fake.listRoomsPageMutex.Lock()
+	defer fake.listRoomsPageMutex.Unlock()
+	fake.ListRoomsPageStub = nil
+	if fake.listRoomsPageReturnsOnCall == nil {
+		fake.listRoomsPageReturnsOnCall = make(map[int]struct {
+			result1 []*livekit.Room
+			result2 string
+			result3 error
+		})
+	}
+	fake.listRoomsPageReturnsOnCall[i] = struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeObjectStore) LoadIdentityBinding(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (*service.IdentityBinding, error) {
+	fake.loadIdentityBindingMutex.Lock()
+	ret, specificReturn := fake.loadIdentityBindingReturnsOnCall[len(fake.loadIdentityBindingArgsForCall)]
+	fake.loadIdentityBindingArgsForCall = append(fake.loadIdentityBindingArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.LoadIdentityBindingStub
+	fakeReturns := fake.loadIdentityBindingReturns
+	fake.recordInvocation("LoadIdentityBinding", []interface{}{arg1, arg2, arg3})
+	fake.loadIdentityBindingMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadIdentityBindingCallCount() int {
+	fake.loadIdentityBindingMutex.RLock()
+	defer fake.loadIdentityBindingMutex.RUnlock()
+	return len(fake.loadIdentityBindingArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadIdentityBindingCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*service.IdentityBinding, error)) {
+	fake.loadIdentityBindingMutex.Lock()
+	defer fake.loadIdentityBindingMutex.Unlock()
+	fake.LoadIdentityBindingStub = stub
+}
+
+func (fake *FakeObjectStore) LoadIdentityBindingArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity) {
+	fake.loadIdentityBindingMutex.RLock()
+	defer fake.loadIdentityBindingMutex.RUnlock()
+	argsForCall := fake.loadIdentityBindingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) LoadIdentityBindingReturns(result1 *service.IdentityBinding, result2 error) {
+	fake.loadIdentityBindingMutex.Lock()
+	defer fake.loadIdentityBindingMutex.Unlock()
+	fake.LoadIdentityBindingStub = nil
+	fake.loadIdentityBindingReturns = struct {
+		result1 *service.IdentityBinding
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadIdentityBindingReturnsOnCall(i int, result1 *service.IdentityBinding, result2 error) {
+	fake.loadIdentityBindingMutex.Lock()
+	defer fake.loadIdentityBindingMutex.Unlock()
+	fake.LoadIdentityBindingStub = nil
+	if fake.loadIdentityBindingReturnsOnCall == nil {
+		fake.loadIdentityBindingReturnsOnCall = make(map[int]struct {
+			result1 *service.IdentityBinding
+			result2 error
+		})
+	}
+	fake.loadIdentityBindingReturnsOnCall[i] = struct {
+		result1 *service.IdentityBinding
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
+	fake.loadParticipantMutex.Lock()
+	ret, specificReturn := fake.loadParticipantReturnsOnCall[len(fake.loadParticipantArgsForCall)]
+	fake.loadParticipantArgsForCall = append(fake.loadParticipantArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.LoadParticipantStub
+	fakeReturns := fake.loadParticipantReturns
+	fake.recordInvocation("LoadParticipant", []interface{}{arg1, arg2, arg3})
+	fake.loadParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadParticipantCallCount() int {
+	fake.loadParticipantMutex.RLock()
+	defer fake.loadParticipantMutex.RUnlock()
+	return len(fake.loadParticipantArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadParticipantCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error)) {
+	fake.loadParticipantMutex.Lock()
 	defer fake.loadParticipantMutex.Unlock()
 	fake.LoadParticipantStub = stub
 }
@@ -480,6 +1074,72 @@ func (fake *FakeObjectStore) LoadParticipantReturnsOnCall(i int, result1 *liveki
 	}{result1, result2}
 }
 
+func (fake *FakeObjectStore) LoadParticipantBandwidthEstimate(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (int64, error) {
+	fake.loadParticipantBandwidthEstimateMutex.Lock()
+	ret, specificReturn := fake.loadParticipantBandwidthEstimateReturnsOnCall[len(fake.loadParticipantBandwidthEstimateArgsForCall)]
+	fake.loadParticipantBandwidthEstimateArgsForCall = append(fake.loadParticipantBandwidthEstimateArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.LoadParticipantBandwidthEstimateStub
+	fakeReturns := fake.loadParticipantBandwidthEstimateReturns
+	fake.recordInvocation("LoadParticipantBandwidthEstimate", []interface{}{arg1, arg2, arg3})
+	fake.loadParticipantBandwidthEstimateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadParticipantBandwidthEstimateCallCount() int {
+	fake.loadParticipantBandwidthEstimateMutex.RLock()
+	defer fake.loadParticipantBandwidthEstimateMutex.RUnlock()
+	return len(fake.loadParticipantBandwidthEstimateArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadParticipantBandwidthEstimateCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (int64, error)) {
+	fake.loadParticipantBandwidthEstimateMutex.Lock()
+	defer fake.loadParticipantBandwidthEstimateMutex.Unlock()
+	fake.LoadParticipantBandwidthEstimateStub = stub
+}
+
+func (fake *FakeObjectStore) LoadParticipantBandwidthEstimateArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity) {
+	fake.loadParticipantBandwidthEstimateMutex.RLock()
+	defer fake.loadParticipantBandwidthEstimateMutex.RUnlock()
+	argsForCall := fake.loadParticipantBandwidthEstimateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) LoadParticipantBandwidthEstimateReturns(result1 int64, result2 error) {
+	fake.loadParticipantBandwidthEstimateMutex.Lock()
+	defer fake.loadParticipantBandwidthEstimateMutex.Unlock()
+	fake.LoadParticipantBandwidthEstimateStub = nil
+	fake.loadParticipantBandwidthEstimateReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadParticipantBandwidthEstimateReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.loadParticipantBandwidthEstimateMutex.Lock()
+	defer fake.loadParticipantBandwidthEstimateMutex.Unlock()
+	fake.LoadParticipantBandwidthEstimateStub = nil
+	if fake.loadParticipantBandwidthEstimateReturnsOnCall == nil {
+		fake.loadParticipantBandwidthEstimateReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.loadParticipantBandwidthEstimateReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeObjectStore) LoadRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 bool) (*livekit.Room, *livekit.RoomInternal, error) {
 	fake.loadRoomMutex.Lock()
 	ret, specificReturn := fake.loadRoomReturnsOnCall[len(fake.loadRoomArgsForCall)]
@@ -549,6 +1209,331 @@ func (fake *FakeObjectStore) LoadRoomReturnsOnCall(i int, result1 *livekit.Room,
 	}{result1, result2, result3}
 }
 
+func (fake *FakeObjectStore) LoadRoomAPIKey(arg1 context.Context, arg2 livekit.RoomName) (string, error) {
+	fake.loadRoomAPIKeyMutex.Lock()
+	ret, specificReturn := fake.loadRoomAPIKeyReturnsOnCall[len(fake.loadRoomAPIKeyArgsForCall)]
+	fake.loadRoomAPIKeyArgsForCall = append(fake.loadRoomAPIKeyArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomAPIKeyStub
+	fakeReturns := fake.loadRoomAPIKeyReturns
+	fake.recordInvocation("LoadRoomAPIKey", []interface{}{arg1, arg2})
+	fake.loadRoomAPIKeyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadRoomAPIKeyCallCount() int {
+	fake.loadRoomAPIKeyMutex.RLock()
+	defer fake.loadRoomAPIKeyMutex.RUnlock()
+	return len(fake.loadRoomAPIKeyArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadRoomAPIKeyCalls(stub func(context.Context, livekit.RoomName) (string, error)) {
+	fake.loadRoomAPIKeyMutex.Lock()
+	defer fake.loadRoomAPIKeyMutex.Unlock()
+	fake.LoadRoomAPIKeyStub = stub
+}
+
+func (fake *FakeObjectStore) LoadRoomAPIKeyArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomAPIKeyMutex.RLock()
+	defer fake.loadRoomAPIKeyMutex.RUnlock()
+	argsForCall := fake.loadRoomAPIKeyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) LoadRoomAPIKeyReturns(result1 string, result2 error) {
+	fake.loadRoomAPIKeyMutex.Lock()
+	defer fake.loadRoomAPIKeyMutex.Unlock()
+	fake.LoadRoomAPIKeyStub = nil
+	fake.loadRoomAPIKeyReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomAPIKeyReturnsOnCall(i int, result1 string, result2 error) {
+	fake.loadRoomAPIKeyMutex.Lock()
+	defer fake.loadRoomAPIKeyMutex.Unlock()
+	fake.LoadRoomAPIKeyStub = nil
+	if fake.loadRoomAPIKeyReturnsOnCall == nil {
+		fake.loadRoomAPIKeyReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.loadRoomAPIKeyReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomJoinCode(arg1 context.Context, arg2 livekit.RoomName) (string, error) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	ret, specificReturn := fake.loadRoomJoinCodeReturnsOnCall[len(fake.loadRoomJoinCodeArgsForCall)]
+	fake.loadRoomJoinCodeArgsForCall = append(fake.loadRoomJoinCodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomJoinCodeStub
+	fakeReturns := fake.loadRoomJoinCodeReturns
+	fake.recordInvocation("LoadRoomJoinCode", []interface{}{arg1, arg2})
+	fake.loadRoomJoinCodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadRoomJoinCodeCallCount() int {
+	fake.loadRoomJoinCodeMutex.RLock()
+	defer fake.loadRoomJoinCodeMutex.RUnlock()
+	return len(fake.loadRoomJoinCodeArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadRoomJoinCodeCalls(stub func(context.Context, livekit.RoomName) (string, error)) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	defer fake.loadRoomJoinCodeMutex.Unlock()
+	fake.LoadRoomJoinCodeStub = stub
+}
+
+func (fake *FakeObjectStore) LoadRoomJoinCodeArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomJoinCodeMutex.RLock()
+	defer fake.loadRoomJoinCodeMutex.RUnlock()
+	argsForCall := fake.loadRoomJoinCodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) LoadRoomJoinCodeReturns(result1 string, result2 error) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	defer fake.loadRoomJoinCodeMutex.Unlock()
+	fake.LoadRoomJoinCodeStub = nil
+	fake.loadRoomJoinCodeReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomJoinCodeReturnsOnCall(i int, result1 string, result2 error) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	defer fake.loadRoomJoinCodeMutex.Unlock()
+	fake.LoadRoomJoinCodeStub = nil
+	if fake.loadRoomJoinCodeReturnsOnCall == nil {
+		fake.loadRoomJoinCodeReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.loadRoomJoinCodeReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomMirror(arg1 context.Context, arg2 livekit.RoomName) (*service.RoomMirror, error) {
+	fake.loadRoomMirrorMutex.Lock()
+	ret, specificReturn := fake.loadRoomMirrorReturnsOnCall[len(fake.loadRoomMirrorArgsForCall)]
+	fake.loadRoomMirrorArgsForCall = append(fake.loadRoomMirrorArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomMirrorStub
+	fakeReturns := fake.loadRoomMirrorReturns
+	fake.recordInvocation("LoadRoomMirror", []interface{}{arg1, arg2})
+	fake.loadRoomMirrorMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadRoomMirrorCallCount() int {
+	fake.loadRoomMirrorMutex.RLock()
+	defer fake.loadRoomMirrorMutex.RUnlock()
+	return len(fake.loadRoomMirrorArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadRoomMirrorCalls(stub func(context.Context, livekit.RoomName) (*service.RoomMirror, error)) {
+	fake.loadRoomMirrorMutex.Lock()
+	defer fake.loadRoomMirrorMutex.Unlock()
+	fake.LoadRoomMirrorStub = stub
+}
+
+func (fake *FakeObjectStore) LoadRoomMirrorArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomMirrorMutex.RLock()
+	defer fake.loadRoomMirrorMutex.RUnlock()
+	argsForCall := fake.loadRoomMirrorArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) LoadRoomMirrorReturns(result1 *service.RoomMirror, result2 error) {
+	fake.loadRoomMirrorMutex.Lock()
+	defer fake.loadRoomMirrorMutex.Unlock()
+	fake.LoadRoomMirrorStub = nil
+	fake.loadRoomMirrorReturns = struct {
+		result1 *service.RoomMirror
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomMirrorReturnsOnCall(i int, result1 *service.RoomMirror, result2 error) {
+	fake.loadRoomMirrorMutex.Lock()
+	defer fake.loadRoomMirrorMutex.Unlock()
+	fake.LoadRoomMirrorStub = nil
+	if fake.loadRoomMirrorReturnsOnCall == nil {
+		fake.loadRoomMirrorReturnsOnCall = make(map[int]struct {
+			result1 *service.RoomMirror
+			result2 error
+		})
+	}
+	fake.loadRoomMirrorReturnsOnCall[i] = struct {
+		result1 *service.RoomMirror
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomStandbyNode(arg1 context.Context, arg2 livekit.RoomName) (livekit.NodeID, error) {
+	fake.loadRoomStandbyNodeMutex.Lock()
+	ret, specificReturn := fake.loadRoomStandbyNodeReturnsOnCall[len(fake.loadRoomStandbyNodeArgsForCall)]
+	fake.loadRoomStandbyNodeArgsForCall = append(fake.loadRoomStandbyNodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomStandbyNodeStub
+	fakeReturns := fake.loadRoomStandbyNodeReturns
+	fake.recordInvocation("LoadRoomStandbyNode", []interface{}{arg1, arg2})
+	fake.loadRoomStandbyNodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadRoomStandbyNodeCallCount() int {
+	fake.loadRoomStandbyNodeMutex.RLock()
+	defer fake.loadRoomStandbyNodeMutex.RUnlock()
+	return len(fake.loadRoomStandbyNodeArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadRoomStandbyNodeCalls(stub func(context.Context, livekit.RoomName) (livekit.NodeID, error)) {
+	fake.loadRoomStandbyNodeMutex.Lock()
+	defer fake.loadRoomStandbyNodeMutex.Unlock()
+	fake.LoadRoomStandbyNodeStub = stub
+}
+
+func (fake *FakeObjectStore) LoadRoomStandbyNodeArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomStandbyNodeMutex.RLock()
+	defer fake.loadRoomStandbyNodeMutex.RUnlock()
+	argsForCall := fake.loadRoomStandbyNodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) LoadRoomStandbyNodeReturns(result1 livekit.NodeID, result2 error) {
+	fake.loadRoomStandbyNodeMutex.Lock()
+	defer fake.loadRoomStandbyNodeMutex.Unlock()
+	fake.LoadRoomStandbyNodeStub = nil
+	fake.loadRoomStandbyNodeReturns = struct {
+		result1 livekit.NodeID
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadRoomStandbyNodeReturnsOnCall(i int, result1 livekit.NodeID, result2 error) {
+	fake.loadRoomStandbyNodeMutex.Lock()
+	defer fake.loadRoomStandbyNodeMutex.Unlock()
+	fake.LoadRoomStandbyNodeStub = nil
+	if fake.loadRoomStandbyNodeReturnsOnCall == nil {
+		fake.loadRoomStandbyNodeReturnsOnCall = make(map[int]struct {
+			result1 livekit.NodeID
+			result2 error
+		})
+	}
+	fake.loadRoomStandbyNodeReturnsOnCall[i] = struct {
+		result1 livekit.NodeID
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadTURNSession(arg1 context.Context, arg2 livekit.ParticipantID) ([]byte, error) {
+	fake.loadTURNSessionMutex.Lock()
+	ret, specificReturn := fake.loadTURNSessionReturnsOnCall[len(fake.loadTURNSessionArgsForCall)]
+	fake.loadTURNSessionArgsForCall = append(fake.loadTURNSessionArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+	}{arg1, arg2})
+	stub := fake.LoadTURNSessionStub
+	fakeReturns := fake.loadTURNSessionReturns
+	fake.recordInvocation("LoadTURNSession", []interface{}{arg1, arg2})
+	fake.loadTURNSessionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeObjectStore) LoadTURNSessionCallCount() int {
+	fake.loadTURNSessionMutex.RLock()
+	defer fake.loadTURNSessionMutex.RUnlock()
+	return len(fake.loadTURNSessionArgsForCall)
+}
+
+func (fake *FakeObjectStore) LoadTURNSessionCalls(stub func(context.Context, livekit.ParticipantID) ([]byte, error)) {
+	fake.loadTURNSessionMutex.Lock()
+	defer fake.loadTURNSessionMutex.Unlock()
+	fake.LoadTURNSessionStub = stub
+}
+
+func (fake *FakeObjectStore) LoadTURNSessionArgsForCall(i int) (context.Context, livekit.ParticipantID) {
+	fake.loadTURNSessionMutex.RLock()
+	defer fake.loadTURNSessionMutex.RUnlock()
+	argsForCall := fake.loadTURNSessionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeObjectStore) LoadTURNSessionReturns(result1 []byte, result2 error) {
+	fake.loadTURNSessionMutex.Lock()
+	defer fake.loadTURNSessionMutex.Unlock()
+	fake.LoadTURNSessionStub = nil
+	fake.loadTURNSessionReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeObjectStore) LoadTURNSessionReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.loadTURNSessionMutex.Lock()
+	defer fake.loadTURNSessionMutex.Unlock()
+	fake.LoadTURNSessionStub = nil
+	if fake.loadTURNSessionReturnsOnCall == nil {
+		fake.loadTURNSessionReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.loadTURNSessionReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeObjectStore) LockRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 time.Duration) (string, error) {
 	fake.lockRoomMutex.Lock()
 	ret, specificReturn := fake.lockRoomReturnsOnCall[len(fake.lockRoomArgsForCall)]
@@ -615,6 +1600,136 @@ func (fake *FakeObjectStore) LockRoomReturnsOnCall(i int, result1 string, result
 	}{result1, result2}
 }
 
+func (fake *FakeObjectStore) StoreFingerprintNonce(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity, arg4 string, arg5 time.Duration) error {
+	fake.storeFingerprintNonceMutex.Lock()
+	ret, specificReturn := fake.storeFingerprintNonceReturnsOnCall[len(fake.storeFingerprintNonceArgsForCall)]
+	fake.storeFingerprintNonceArgsForCall = append(fake.storeFingerprintNonceArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 string
+		arg5 time.Duration
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.StoreFingerprintNonceStub
+	fakeReturns := fake.storeFingerprintNonceReturns
+	fake.recordInvocation("StoreFingerprintNonce", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.storeFingerprintNonceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreFingerprintNonceCallCount() int {
+	fake.storeFingerprintNonceMutex.RLock()
+	defer fake.storeFingerprintNonceMutex.RUnlock()
+	return len(fake.storeFingerprintNonceArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreFingerprintNonceCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, string, time.Duration) error) {
+	fake.storeFingerprintNonceMutex.Lock()
+	defer fake.storeFingerprintNonceMutex.Unlock()
+	fake.StoreFingerprintNonceStub = stub
+}
+
+func (fake *FakeObjectStore) StoreFingerprintNonceArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity, string, time.Duration) {
+	fake.storeFingerprintNonceMutex.RLock()
+	defer fake.storeFingerprintNonceMutex.RUnlock()
+	argsForCall := fake.storeFingerprintNonceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeObjectStore) StoreFingerprintNonceReturns(result1 error) {
+	fake.storeFingerprintNonceMutex.Lock()
+	defer fake.storeFingerprintNonceMutex.Unlock()
+	fake.StoreFingerprintNonceStub = nil
+	fake.storeFingerprintNonceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreFingerprintNonceReturnsOnCall(i int, result1 error) {
+	fake.storeFingerprintNonceMutex.Lock()
+	defer fake.storeFingerprintNonceMutex.Unlock()
+	fake.StoreFingerprintNonceStub = nil
+	if fake.storeFingerprintNonceReturnsOnCall == nil {
+		fake.storeFingerprintNonceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeFingerprintNonceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreIdentityBinding(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity, arg4 *service.IdentityBinding, arg5 time.Duration) error {
+	fake.storeIdentityBindingMutex.Lock()
+	ret, specificReturn := fake.storeIdentityBindingReturnsOnCall[len(fake.storeIdentityBindingArgsForCall)]
+	fake.storeIdentityBindingArgsForCall = append(fake.storeIdentityBindingArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 *service.IdentityBinding
+		arg5 time.Duration
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.StoreIdentityBindingStub
+	fakeReturns := fake.storeIdentityBindingReturns
+	fake.recordInvocation("StoreIdentityBinding", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.storeIdentityBindingMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreIdentityBindingCallCount() int {
+	fake.storeIdentityBindingMutex.RLock()
+	defer fake.storeIdentityBindingMutex.RUnlock()
+	return len(fake.storeIdentityBindingArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreIdentityBindingCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, *service.IdentityBinding, time.Duration) error) {
+	fake.storeIdentityBindingMutex.Lock()
+	defer fake.storeIdentityBindingMutex.Unlock()
+	fake.StoreIdentityBindingStub = stub
+}
+
+func (fake *FakeObjectStore) StoreIdentityBindingArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity, *service.IdentityBinding, time.Duration) {
+	fake.storeIdentityBindingMutex.RLock()
+	defer fake.storeIdentityBindingMutex.RUnlock()
+	argsForCall := fake.storeIdentityBindingArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeObjectStore) StoreIdentityBindingReturns(result1 error) {
+	fake.storeIdentityBindingMutex.Lock()
+	defer fake.storeIdentityBindingMutex.Unlock()
+	fake.StoreIdentityBindingStub = nil
+	fake.storeIdentityBindingReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreIdentityBindingReturnsOnCall(i int, result1 error) {
+	fake.storeIdentityBindingMutex.Lock()
+	defer fake.storeIdentityBindingMutex.Unlock()
+	fake.StoreIdentityBindingStub = nil
+	if fake.storeIdentityBindingReturnsOnCall == nil {
+		fake.storeIdentityBindingReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeIdentityBindingReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) StoreParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 *livekit.ParticipantInfo) error {
 	fake.storeParticipantMutex.Lock()
 	ret, specificReturn := fake.storeParticipantReturnsOnCall[len(fake.storeParticipantArgsForCall)]
@@ -678,6 +1793,71 @@ func (fake *FakeObjectStore) StoreParticipantReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
+func (fake *FakeObjectStore) StoreParticipantBandwidthEstimate(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity, arg4 int64, arg5 time.Duration) error {
+	fake.storeParticipantBandwidthEstimateMutex.Lock()
+	ret, specificReturn := fake.storeParticipantBandwidthEstimateReturnsOnCall[len(fake.storeParticipantBandwidthEstimateArgsForCall)]
+	fake.storeParticipantBandwidthEstimateArgsForCall = append(fake.storeParticipantBandwidthEstimateArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+		arg4 int64
+		arg5 time.Duration
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.StoreParticipantBandwidthEstimateStub
+	fakeReturns := fake.storeParticipantBandwidthEstimateReturns
+	fake.recordInvocation("StoreParticipantBandwidthEstimate", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.storeParticipantBandwidthEstimateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreParticipantBandwidthEstimateCallCount() int {
+	fake.storeParticipantBandwidthEstimateMutex.RLock()
+	defer fake.storeParticipantBandwidthEstimateMutex.RUnlock()
+	return len(fake.storeParticipantBandwidthEstimateArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreParticipantBandwidthEstimateCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity, int64, time.Duration) error) {
+	fake.storeParticipantBandwidthEstimateMutex.Lock()
+	defer fake.storeParticipantBandwidthEstimateMutex.Unlock()
+	fake.StoreParticipantBandwidthEstimateStub = stub
+}
+
+func (fake *FakeObjectStore) StoreParticipantBandwidthEstimateArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity, int64, time.Duration) {
+	fake.storeParticipantBandwidthEstimateMutex.RLock()
+	defer fake.storeParticipantBandwidthEstimateMutex.RUnlock()
+	argsForCall := fake.storeParticipantBandwidthEstimateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeObjectStore) StoreParticipantBandwidthEstimateReturns(result1 error) {
+	fake.storeParticipantBandwidthEstimateMutex.Lock()
+	defer fake.storeParticipantBandwidthEstimateMutex.Unlock()
+	fake.StoreParticipantBandwidthEstimateStub = nil
+	fake.storeParticipantBandwidthEstimateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreParticipantBandwidthEstimateReturnsOnCall(i int, result1 error) {
+	fake.storeParticipantBandwidthEstimateMutex.Lock()
+	defer fake.storeParticipantBandwidthEstimateMutex.Unlock()
+	fake.StoreParticipantBandwidthEstimateStub = nil
+	if fake.storeParticipantBandwidthEstimateReturnsOnCall == nil {
+		fake.storeParticipantBandwidthEstimateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeParticipantBandwidthEstimateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) StoreRoom(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.RoomInternal) error {
 	fake.storeRoomMutex.Lock()
 	ret, specificReturn := fake.storeRoomReturnsOnCall[len(fake.storeRoomArgsForCall)]
@@ -741,6 +1921,327 @@ func (fake *FakeObjectStore) StoreRoomReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeObjectStore) StoreRoomAPIKey(arg1 context.Context, arg2 livekit.RoomName, arg3 string) error {
+	fake.storeRoomAPIKeyMutex.Lock()
+	ret, specificReturn := fake.storeRoomAPIKeyReturnsOnCall[len(fake.storeRoomAPIKeyArgsForCall)]
+	fake.storeRoomAPIKeyArgsForCall = append(fake.storeRoomAPIKeyArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomAPIKeyStub
+	fakeReturns := fake.storeRoomAPIKeyReturns
+	fake.recordInvocation("StoreRoomAPIKey", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomAPIKeyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreRoomAPIKeyCallCount() int {
+	fake.storeRoomAPIKeyMutex.RLock()
+	defer fake.storeRoomAPIKeyMutex.RUnlock()
+	return len(fake.storeRoomAPIKeyArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreRoomAPIKeyCalls(stub func(context.Context, livekit.RoomName, string) error) {
+	fake.storeRoomAPIKeyMutex.Lock()
+	defer fake.storeRoomAPIKeyMutex.Unlock()
+	fake.StoreRoomAPIKeyStub = stub
+}
+
+func (fake *FakeObjectStore) StoreRoomAPIKeyArgsForCall(i int) (context.Context, livekit.RoomName, string) {
+	fake.storeRoomAPIKeyMutex.RLock()
+	defer fake.storeRoomAPIKeyMutex.RUnlock()
+	argsForCall := fake.storeRoomAPIKeyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) StoreRoomAPIKeyReturns(result1 error) {
+	fake.storeRoomAPIKeyMutex.Lock()
+	defer fake.storeRoomAPIKeyMutex.Unlock()
+	fake.StoreRoomAPIKeyStub = nil
+	fake.storeRoomAPIKeyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomAPIKeyReturnsOnCall(i int, result1 error) {
+	fake.storeRoomAPIKeyMutex.Lock()
+	defer fake.storeRoomAPIKeyMutex.Unlock()
+	fake.StoreRoomAPIKeyStub = nil
+	if fake.storeRoomAPIKeyReturnsOnCall == nil {
+		fake.storeRoomAPIKeyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomAPIKeyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomJoinCode(arg1 context.Context, arg2 livekit.RoomName, arg3 string) error {
+	fake.storeRoomJoinCodeMutex.Lock()
+	ret, specificReturn := fake.storeRoomJoinCodeReturnsOnCall[len(fake.storeRoomJoinCodeArgsForCall)]
+	fake.storeRoomJoinCodeArgsForCall = append(fake.storeRoomJoinCodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomJoinCodeStub
+	fakeReturns := fake.storeRoomJoinCodeReturns
+	fake.recordInvocation("StoreRoomJoinCode", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomJoinCodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreRoomJoinCodeCallCount() int {
+	fake.storeRoomJoinCodeMutex.RLock()
+	defer fake.storeRoomJoinCodeMutex.RUnlock()
+	return len(fake.storeRoomJoinCodeArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreRoomJoinCodeCalls(stub func(context.Context, livekit.RoomName, string) error) {
+	fake.storeRoomJoinCodeMutex.Lock()
+	defer fake.storeRoomJoinCodeMutex.Unlock()
+	fake.StoreRoomJoinCodeStub = stub
+}
+
+func (fake *FakeObjectStore) StoreRoomJoinCodeArgsForCall(i int) (context.Context, livekit.RoomName, string) {
+	fake.storeRoomJoinCodeMutex.RLock()
+	defer fake.storeRoomJoinCodeMutex.RUnlock()
+	argsForCall := fake.storeRoomJoinCodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) StoreRoomJoinCodeReturns(result1 error) {
+	fake.storeRoomJoinCodeMutex.Lock()
+	defer fake.storeRoomJoinCodeMutex.Unlock()
+	fake.StoreRoomJoinCodeStub = nil
+	fake.storeRoomJoinCodeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomJoinCodeReturnsOnCall(i int, result1 error) {
+	fake.storeRoomJoinCodeMutex.Lock()
+	defer fake.storeRoomJoinCodeMutex.Unlock()
+	fake.StoreRoomJoinCodeStub = nil
+	if fake.storeRoomJoinCodeReturnsOnCall == nil {
+		fake.storeRoomJoinCodeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomJoinCodeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomMirror(arg1 context.Context, arg2 livekit.RoomName, arg3 *service.RoomMirror) error {
+	fake.storeRoomMirrorMutex.Lock()
+	ret, specificReturn := fake.storeRoomMirrorReturnsOnCall[len(fake.storeRoomMirrorArgsForCall)]
+	fake.storeRoomMirrorArgsForCall = append(fake.storeRoomMirrorArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 *service.RoomMirror
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomMirrorStub
+	fakeReturns := fake.storeRoomMirrorReturns
+	fake.recordInvocation("StoreRoomMirror", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomMirrorMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreRoomMirrorCallCount() int {
+	fake.storeRoomMirrorMutex.RLock()
+	defer fake.storeRoomMirrorMutex.RUnlock()
+	return len(fake.storeRoomMirrorArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreRoomMirrorCalls(stub func(context.Context, livekit.RoomName, *service.RoomMirror) error) {
+	fake.storeRoomMirrorMutex.Lock()
+	defer fake.storeRoomMirrorMutex.Unlock()
+	fake.StoreRoomMirrorStub = stub
+}
+
+func (fake *FakeObjectStore) StoreRoomMirrorArgsForCall(i int) (context.Context, livekit.RoomName, *service.RoomMirror) {
+	fake.storeRoomMirrorMutex.RLock()
+	defer fake.storeRoomMirrorMutex.RUnlock()
+	argsForCall := fake.storeRoomMirrorArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) StoreRoomMirrorReturns(result1 error) {
+	fake.storeRoomMirrorMutex.Lock()
+	defer fake.storeRoomMirrorMutex.Unlock()
+	fake.StoreRoomMirrorStub = nil
+	fake.storeRoomMirrorReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomMirrorReturnsOnCall(i int, result1 error) {
+	fake.storeRoomMirrorMutex.Lock()
+	defer fake.storeRoomMirrorMutex.Unlock()
+	fake.StoreRoomMirrorStub = nil
+	if fake.storeRoomMirrorReturnsOnCall == nil {
+		fake.storeRoomMirrorReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomMirrorReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomStandbyNode(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.NodeID) error {
+	fake.storeRoomStandbyNodeMutex.Lock()
+	ret, specificReturn := fake.storeRoomStandbyNodeReturnsOnCall[len(fake.storeRoomStandbyNodeArgsForCall)]
+	fake.storeRoomStandbyNodeArgsForCall = append(fake.storeRoomStandbyNodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.NodeID
+	}{arg1, arg2, arg3})
+	stub := fake.StoreRoomStandbyNodeStub
+	fakeReturns := fake.storeRoomStandbyNodeReturns
+	fake.recordInvocation("StoreRoomStandbyNode", []interface{}{arg1, arg2, arg3})
+	fake.storeRoomStandbyNodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreRoomStandbyNodeCallCount() int {
+	fake.storeRoomStandbyNodeMutex.RLock()
+	defer fake.storeRoomStandbyNodeMutex.RUnlock()
+	return len(fake.storeRoomStandbyNodeArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreRoomStandbyNodeCalls(stub func(context.Context, livekit.RoomName, livekit.NodeID) error) {
+	fake.storeRoomStandbyNodeMutex.Lock()
+	defer fake.storeRoomStandbyNodeMutex.Unlock()
+	fake.StoreRoomStandbyNodeStub = stub
+}
+
+func (fake *FakeObjectStore) StoreRoomStandbyNodeArgsForCall(i int) (context.Context, livekit.RoomName, livekit.NodeID) {
+	fake.storeRoomStandbyNodeMutex.RLock()
+	defer fake.storeRoomStandbyNodeMutex.RUnlock()
+	argsForCall := fake.storeRoomStandbyNodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeObjectStore) StoreRoomStandbyNodeReturns(result1 error) {
+	fake.storeRoomStandbyNodeMutex.Lock()
+	defer fake.storeRoomStandbyNodeMutex.Unlock()
+	fake.StoreRoomStandbyNodeStub = nil
+	fake.storeRoomStandbyNodeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreRoomStandbyNodeReturnsOnCall(i int, result1 error) {
+	fake.storeRoomStandbyNodeMutex.Lock()
+	defer fake.storeRoomStandbyNodeMutex.Unlock()
+	fake.StoreRoomStandbyNodeStub = nil
+	if fake.storeRoomStandbyNodeReturnsOnCall == nil {
+		fake.storeRoomStandbyNodeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomStandbyNodeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreTURNSession(arg1 context.Context, arg2 livekit.ParticipantID, arg3 []byte, arg4 time.Duration) error {
+	var arg3Copy []byte
+	if arg3 != nil {
+		arg3Copy = make([]byte, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.storeTURNSessionMutex.Lock()
+	ret, specificReturn := fake.storeTURNSessionReturnsOnCall[len(fake.storeTURNSessionArgsForCall)]
+	fake.storeTURNSessionArgsForCall = append(fake.storeTURNSessionArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 []byte
+		arg4 time.Duration
+	}{arg1, arg2, arg3Copy, arg4})
+	stub := fake.StoreTURNSessionStub
+	fakeReturns := fake.storeTURNSessionReturns
+	fake.recordInvocation("StoreTURNSession", []interface{}{arg1, arg2, arg3Copy, arg4})
+	fake.storeTURNSessionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeObjectStore) StoreTURNSessionCallCount() int {
+	fake.storeTURNSessionMutex.RLock()
+	defer fake.storeTURNSessionMutex.RUnlock()
+	return len(fake.storeTURNSessionArgsForCall)
+}
+
+func (fake *FakeObjectStore) StoreTURNSessionCalls(stub func(context.Context, livekit.ParticipantID, []byte, time.Duration) error) {
+	fake.storeTURNSessionMutex.Lock()
+	defer fake.storeTURNSessionMutex.Unlock()
+	fake.StoreTURNSessionStub = stub
+}
+
+func (fake *FakeObjectStore) StoreTURNSessionArgsForCall(i int) (context.Context, livekit.ParticipantID, []byte, time.Duration) {
+	fake.storeTURNSessionMutex.RLock()
+	defer fake.storeTURNSessionMutex.RUnlock()
+	argsForCall := fake.storeTURNSessionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeObjectStore) StoreTURNSessionReturns(result1 error) {
+	fake.storeTURNSessionMutex.Lock()
+	defer fake.storeTURNSessionMutex.Unlock()
+	fake.StoreTURNSessionStub = nil
+	fake.storeTURNSessionReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeObjectStore) StoreTURNSessionReturnsOnCall(i int, result1 error) {
+	fake.storeTURNSessionMutex.Lock()
+	defer fake.storeTURNSessionMutex.Unlock()
+	fake.StoreTURNSessionStub = nil
+	if fake.storeTURNSessionReturnsOnCall == nil {
+		fake.storeTURNSessionReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeTURNSessionReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeObjectStore) UnlockRoom(arg1 context.Context, arg2 livekit.RoomName, arg3 string) error {
 	fake.unlockRoomMutex.Lock()
 	ret, specificReturn := fake.unlockRoomReturnsOnCall[len(fake.unlockRoomArgsForCall)]
@@ -807,24 +2308,62 @@ func (fake *FakeObjectStore) UnlockRoomReturnsOnCall(i int, result1 error) {
 func (fake *FakeObjectStore) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.consumeFingerprintNonceMutex.RLock()
+	defer fake.consumeFingerprintNonceMutex.RUnlock()
 	fake.deleteParticipantMutex.RLock()
 	defer fake.deleteParticipantMutex.RUnlock()
+	fake.deleteParticipantBandwidthEstimateMutex.RLock()
+	defer fake.deleteParticipantBandwidthEstimateMutex.RUnlock()
 	fake.deleteRoomMutex.RLock()
 	defer fake.deleteRoomMutex.RUnlock()
+	fake.deleteTURNSessionMutex.RLock()
+	defer fake.deleteTURNSessionMutex.RUnlock()
 	fake.listParticipantsMutex.RLock()
 	defer fake.listParticipantsMutex.RUnlock()
 	fake.listRoomsMutex.RLock()
 	defer fake.listRoomsMutex.RUnlock()
+	fake.listRoomsPageMutex.RLock()
+	defer fake.listRoomsPageMutex.RUnlock()
+	fake.loadIdentityBindingMutex.RLock()
+	defer fake.loadIdentityBindingMutex.RUnlock()
 	fake.loadParticipantMutex.RLock()
 	defer fake.loadParticipantMutex.RUnlock()
+	fake.loadParticipantBandwidthEstimateMutex.RLock()
+	defer fake.loadParticipantBandwidthEstimateMutex.RUnlock()
 	fake.loadRoomMutex.RLock()
 	defer fake.loadRoomMutex.RUnlock()
+	fake.loadRoomAPIKeyMutex.RLock()
+	defer fake.loadRoomAPIKeyMutex.RUnlock()
+	fake.loadRoomJoinCodeMutex.RLock()
+	defer fake.loadRoomJoinCodeMutex.RUnlock()
+	fake.loadRoomMirrorMutex.RLock()
+	defer fake.loadRoomMirrorMutex.RUnlock()
+	fake.loadRoomStandbyNodeMutex.RLock()
+	defer fake.loadRoomStandbyNodeMutex.RUnlock()
+	fake.loadTURNSessionMutex.RLock()
+	defer fake.loadTURNSessionMutex.RUnlock()
 	fake.lockRoomMutex.RLock()
 	defer fake.lockRoomMutex.RUnlock()
+	fake.storeFingerprintNonceMutex.RLock()
+	defer fake.storeFingerprintNonceMutex.RUnlock()
+	fake.storeIdentityBindingMutex.RLock()
+	defer fake.storeIdentityBindingMutex.RUnlock()
 	fake.storeParticipantMutex.RLock()
 	defer fake.storeParticipantMutex.RUnlock()
+	fake.storeParticipantBandwidthEstimateMutex.RLock()
+	defer fake.storeParticipantBandwidthEstimateMutex.RUnlock()
 	fake.storeRoomMutex.RLock()
 	defer fake.storeRoomMutex.RUnlock()
+	fake.storeRoomAPIKeyMutex.RLock()
+	defer fake.storeRoomAPIKeyMutex.RUnlock()
+	fake.storeRoomJoinCodeMutex.RLock()
+	defer fake.storeRoomJoinCodeMutex.RUnlock()
+	fake.storeRoomMirrorMutex.RLock()
+	defer fake.storeRoomMirrorMutex.RUnlock()
+	fake.storeRoomStandbyNodeMutex.RLock()
+	defer fake.storeRoomStandbyNodeMutex.RUnlock()
+	fake.storeTURNSessionMutex.RLock()
+	defer fake.storeTURNSessionMutex.RUnlock()
 	fake.unlockRoomMutex.RLock()
 	defer fake.unlockRoomMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -0,0 +1,351 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/livekit"
+)
+
+type FakeRoomManifestStore struct {
+	DeleteRoomManifestStub        func(context.Context, *service.RoomManifest) error
+	deleteRoomManifestMutex       sync.RWMutex
+	deleteRoomManifestArgsForCall []struct {
+		arg1 context.Context
+		arg2 *service.RoomManifest
+	}
+	deleteRoomManifestReturns struct {
+		result1 error
+	}
+	deleteRoomManifestReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ListRoomManifestsStub        func(context.Context) ([]*service.RoomManifest, error)
+	listRoomManifestsMutex       sync.RWMutex
+	listRoomManifestsArgsForCall []struct {
+		arg1 context.Context
+	}
+	listRoomManifestsReturns struct {
+		result1 []*service.RoomManifest
+		result2 error
+	}
+	listRoomManifestsReturnsOnCall map[int]struct {
+		result1 []*service.RoomManifest
+		result2 error
+	}
+	LoadRoomManifestStub        func(context.Context, livekit.RoomName) (*service.RoomManifest, error)
+	loadRoomManifestMutex       sync.RWMutex
+	loadRoomManifestArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomManifestReturns struct {
+		result1 *service.RoomManifest
+		result2 error
+	}
+	loadRoomManifestReturnsOnCall map[int]struct {
+		result1 *service.RoomManifest
+		result2 error
+	}
+	StoreRoomManifestStub        func(context.Context, *service.RoomManifest) error
+	storeRoomManifestMutex       sync.RWMutex
+	storeRoomManifestArgsForCall []struct {
+		arg1 context.Context
+		arg2 *service.RoomManifest
+	}
+	storeRoomManifestReturns struct {
+		result1 error
+	}
+	storeRoomManifestReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRoomManifestStore) DeleteRoomManifest(arg1 context.Context, arg2 *service.RoomManifest) error {
+	fake.deleteRoomManifestMutex.Lock()
+	ret, specificReturn := fake.deleteRoomManifestReturnsOnCall[len(fake.deleteRoomManifestArgsForCall)]
+	fake.deleteRoomManifestArgsForCall = append(fake.deleteRoomManifestArgsForCall, struct {
+		arg1 context.Context
+		arg2 *service.RoomManifest
+	}{arg1, arg2})
+	stub := fake.DeleteRoomManifestStub
+	fakeReturns := fake.deleteRoomManifestReturns
+	fake.recordInvocation("DeleteRoomManifest", []interface{}{arg1, arg2})
+	fake.deleteRoomManifestMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomManifestStore) DeleteRoomManifestCallCount() int {
+	fake.deleteRoomManifestMutex.RLock()
+	defer fake.deleteRoomManifestMutex.RUnlock()
+	return len(fake.deleteRoomManifestArgsForCall)
+}
+
+func (fake *FakeRoomManifestStore) DeleteRoomManifestCalls(stub func(context.Context, *service.RoomManifest) error) {
+	fake.deleteRoomManifestMutex.Lock()
+	defer fake.deleteRoomManifestMutex.Unlock()
+	fake.DeleteRoomManifestStub = stub
+}
+
+func (fake *FakeRoomManifestStore) DeleteRoomManifestArgsForCall(i int) (context.Context, *service.RoomManifest) {
+	fake.deleteRoomManifestMutex.RLock()
+	defer fake.deleteRoomManifestMutex.RUnlock()
+	argsForCall := fake.deleteRoomManifestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomManifestStore) DeleteRoomManifestReturns(result1 error) {
+	fake.deleteRoomManifestMutex.Lock()
+	defer fake.deleteRoomManifestMutex.Unlock()
+	fake.DeleteRoomManifestStub = nil
+	fake.deleteRoomManifestReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomManifestStore) DeleteRoomManifestReturnsOnCall(i int, result1 error) {
+	fake.deleteRoomManifestMutex.Lock()
+	defer fake.deleteRoomManifestMutex.Unlock()
+	fake.DeleteRoomManifestStub = nil
+	if fake.deleteRoomManifestReturnsOnCall == nil {
+		fake.deleteRoomManifestReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteRoomManifestReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomManifestStore) ListRoomManifests(arg1 context.Context) ([]*service.RoomManifest, error) {
+	fake.listRoomManifestsMutex.Lock()
+	ret, specificReturn := fake.listRoomManifestsReturnsOnCall[len(fake.listRoomManifestsArgsForCall)]
+	fake.listRoomManifestsArgsForCall = append(fake.listRoomManifestsArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.ListRoomManifestsStub
+	fakeReturns := fake.listRoomManifestsReturns
+	fake.recordInvocation("ListRoomManifests", []interface{}{arg1})
+	fake.listRoomManifestsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomManifestStore) ListRoomManifestsCallCount() int {
+	fake.listRoomManifestsMutex.RLock()
+	defer fake.listRoomManifestsMutex.RUnlock()
+	return len(fake.listRoomManifestsArgsForCall)
+}
+
+func (fake *FakeRoomManifestStore) ListRoomManifestsCalls(stub func(context.Context) ([]*service.RoomManifest, error)) {
+	fake.listRoomManifestsMutex.Lock()
+	defer fake.listRoomManifestsMutex.Unlock()
+	fake.ListRoomManifestsStub = stub
+}
+
+func (fake *FakeRoomManifestStore) ListRoomManifestsArgsForCall(i int) context.Context {
+	fake.listRoomManifestsMutex.RLock()
+	defer fake.listRoomManifestsMutex.RUnlock()
+	argsForCall := fake.listRoomManifestsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoomManifestStore) ListRoomManifestsReturns(result1 []*service.RoomManifest, result2 error) {
+	fake.listRoomManifestsMutex.Lock()
+	defer fake.listRoomManifestsMutex.Unlock()
+	fake.ListRoomManifestsStub = nil
+	fake.listRoomManifestsReturns = struct {
+		result1 []*service.RoomManifest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomManifestStore) ListRoomManifestsReturnsOnCall(i int, result1 []*service.RoomManifest, result2 error) {
+	fake.listRoomManifestsMutex.Lock()
+	defer fake.listRoomManifestsMutex.Unlock()
+	fake.ListRoomManifestsStub = nil
+	if fake.listRoomManifestsReturnsOnCall == nil {
+		fake.listRoomManifestsReturnsOnCall = make(map[int]struct {
+			result1 []*service.RoomManifest
+			result2 error
+		})
+	}
+	fake.listRoomManifestsReturnsOnCall[i] = struct {
+		result1 []*service.RoomManifest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomManifestStore) LoadRoomManifest(arg1 context.Context, arg2 livekit.RoomName) (*service.RoomManifest, error) {
+	fake.loadRoomManifestMutex.Lock()
+	ret, specificReturn := fake.loadRoomManifestReturnsOnCall[len(fake.loadRoomManifestArgsForCall)]
+	fake.loadRoomManifestArgsForCall = append(fake.loadRoomManifestArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomManifestStub
+	fakeReturns := fake.loadRoomManifestReturns
+	fake.recordInvocation("LoadRoomManifest", []interface{}{arg1, arg2})
+	fake.loadRoomManifestMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomManifestStore) LoadRoomManifestCallCount() int {
+	fake.loadRoomManifestMutex.RLock()
+	defer fake.loadRoomManifestMutex.RUnlock()
+	return len(fake.loadRoomManifestArgsForCall)
+}
+
+func (fake *FakeRoomManifestStore) LoadRoomManifestCalls(stub func(context.Context, livekit.RoomName) (*service.RoomManifest, error)) {
+	fake.loadRoomManifestMutex.Lock()
+	defer fake.loadRoomManifestMutex.Unlock()
+	fake.LoadRoomManifestStub = stub
+}
+
+func (fake *FakeRoomManifestStore) LoadRoomManifestArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomManifestMutex.RLock()
+	defer fake.loadRoomManifestMutex.RUnlock()
+	argsForCall := fake.loadRoomManifestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomManifestStore) LoadRoomManifestReturns(result1 *service.RoomManifest, result2 error) {
+	fake.loadRoomManifestMutex.Lock()
+	defer fake.loadRoomManifestMutex.Unlock()
+	fake.LoadRoomManifestStub = nil
+	fake.loadRoomManifestReturns = struct {
+		result1 *service.RoomManifest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomManifestStore) LoadRoomManifestReturnsOnCall(i int, result1 *service.RoomManifest, result2 error) {
+	fake.loadRoomManifestMutex.Lock()
+	defer fake.loadRoomManifestMutex.Unlock()
+	fake.LoadRoomManifestStub = nil
+	if fake.loadRoomManifestReturnsOnCall == nil {
+		fake.loadRoomManifestReturnsOnCall = make(map[int]struct {
+			result1 *service.RoomManifest
+			result2 error
+		})
+	}
+	fake.loadRoomManifestReturnsOnCall[i] = struct {
+		result1 *service.RoomManifest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomManifestStore) StoreRoomManifest(arg1 context.Context, arg2 *service.RoomManifest) error {
+	fake.storeRoomManifestMutex.Lock()
+	ret, specificReturn := fake.storeRoomManifestReturnsOnCall[len(fake.storeRoomManifestArgsForCall)]
+	fake.storeRoomManifestArgsForCall = append(fake.storeRoomManifestArgsForCall, struct {
+		arg1 context.Context
+		arg2 *service.RoomManifest
+	}{arg1, arg2})
+	stub := fake.StoreRoomManifestStub
+	fakeReturns := fake.storeRoomManifestReturns
+	fake.recordInvocation("StoreRoomManifest", []interface{}{arg1, arg2})
+	fake.storeRoomManifestMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomManifestStore) StoreRoomManifestCallCount() int {
+	fake.storeRoomManifestMutex.RLock()
+	defer fake.storeRoomManifestMutex.RUnlock()
+	return len(fake.storeRoomManifestArgsForCall)
+}
+
+func (fake *FakeRoomManifestStore) StoreRoomManifestCalls(stub func(context.Context, *service.RoomManifest) error) {
+	fake.storeRoomManifestMutex.Lock()
+	defer fake.storeRoomManifestMutex.Unlock()
+	fake.StoreRoomManifestStub = stub
+}
+
+func (fake *FakeRoomManifestStore) StoreRoomManifestArgsForCall(i int) (context.Context, *service.RoomManifest) {
+	fake.storeRoomManifestMutex.RLock()
+	defer fake.storeRoomManifestMutex.RUnlock()
+	argsForCall := fake.storeRoomManifestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomManifestStore) StoreRoomManifestReturns(result1 error) {
+	fake.storeRoomManifestMutex.Lock()
+	defer fake.storeRoomManifestMutex.Unlock()
+	fake.StoreRoomManifestStub = nil
+	fake.storeRoomManifestReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomManifestStore) StoreRoomManifestReturnsOnCall(i int, result1 error) {
+	fake.storeRoomManifestMutex.Lock()
+	defer fake.storeRoomManifestMutex.Unlock()
+	fake.StoreRoomManifestStub = nil
+	if fake.storeRoomManifestReturnsOnCall == nil {
+		fake.storeRoomManifestReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomManifestReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomManifestStore) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.deleteRoomManifestMutex.RLock()
+	defer fake.deleteRoomManifestMutex.RUnlock()
+	fake.listRoomManifestsMutex.RLock()
+	defer fake.listRoomManifestsMutex.RUnlock()
+	fake.loadRoomManifestMutex.RLock()
+	defer fake.loadRoomManifestMutex.RUnlock()
+	fake.storeRoomManifestMutex.RLock()
+	defer fake.storeRoomManifestMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRoomManifestStore) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.RoomManifestStore = new(FakeRoomManifestStore)
@@ -0,0 +1,369 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/livekit"
+)
+
+type FakeRoomPresenceStore struct {
+	DeleteRoomCountersStub        func(context.Context, livekit.RoomName) error
+	deleteRoomCountersMutex       sync.RWMutex
+	deleteRoomCountersArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	deleteRoomCountersReturns struct {
+		result1 error
+	}
+	deleteRoomCountersReturnsOnCall map[int]struct {
+		result1 error
+	}
+	IncrementRoomCounterStub        func(context.Context, livekit.RoomName, string, int64) (int64, error)
+	incrementRoomCounterMutex       sync.RWMutex
+	incrementRoomCounterArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+		arg4 int64
+	}
+	incrementRoomCounterReturns struct {
+		result1 int64
+		result2 error
+	}
+	incrementRoomCounterReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
+	LoadRoomCountersStub        func(context.Context, livekit.RoomName) (map[string]int64, map[string]int64, error)
+	loadRoomCountersMutex       sync.RWMutex
+	loadRoomCountersArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomCountersReturns struct {
+		result1 map[string]int64
+		result2 map[string]int64
+		result3 error
+	}
+	loadRoomCountersReturnsOnCall map[int]struct {
+		result1 map[string]int64
+		result2 map[string]int64
+		result3 error
+	}
+	StoreRoomWatchersStub        func(context.Context, livekit.RoomName, string, int64, time.Duration) error
+	storeRoomWatchersMutex       sync.RWMutex
+	storeRoomWatchersArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+		arg4 int64
+		arg5 time.Duration
+	}
+	storeRoomWatchersReturns struct {
+		result1 error
+	}
+	storeRoomWatchersReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRoomPresenceStore) DeleteRoomCounters(arg1 context.Context, arg2 livekit.RoomName) error {
+	fake.deleteRoomCountersMutex.Lock()
+	ret, specificReturn := fake.deleteRoomCountersReturnsOnCall[len(fake.deleteRoomCountersArgsForCall)]
+	fake.deleteRoomCountersArgsForCall = append(fake.deleteRoomCountersArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.DeleteRoomCountersStub
+	fakeReturns := fake.deleteRoomCountersReturns
+	fake.recordInvocation("DeleteRoomCounters", []interface{}{arg1, arg2})
+	fake.deleteRoomCountersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomPresenceStore) DeleteRoomCountersCallCount() int {
+	fake.deleteRoomCountersMutex.RLock()
+	defer fake.deleteRoomCountersMutex.RUnlock()
+	return len(fake.deleteRoomCountersArgsForCall)
+}
+
+func (fake *FakeRoomPresenceStore) DeleteRoomCountersCalls(stub func(context.Context, livekit.RoomName) error) {
+	fake.deleteRoomCountersMutex.Lock()
+	defer fake.deleteRoomCountersMutex.Unlock()
+	fake.DeleteRoomCountersStub = stub
+}
+
+func (fake *FakeRoomPresenceStore) DeleteRoomCountersArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.deleteRoomCountersMutex.RLock()
+	defer fake.deleteRoomCountersMutex.RUnlock()
+	argsForCall := fake.deleteRoomCountersArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomPresenceStore) DeleteRoomCountersReturns(result1 error) {
+	fake.deleteRoomCountersMutex.Lock()
+	defer fake.deleteRoomCountersMutex.Unlock()
+	fake.DeleteRoomCountersStub = nil
+	fake.deleteRoomCountersReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomPresenceStore) DeleteRoomCountersReturnsOnCall(i int, result1 error) {
+	fake.deleteRoomCountersMutex.Lock()
+	defer fake.deleteRoomCountersMutex.Unlock()
+	fake.DeleteRoomCountersStub = nil
+	if fake.deleteRoomCountersReturnsOnCall == nil {
+		fake.deleteRoomCountersReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteRoomCountersReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomPresenceStore) IncrementRoomCounter(arg1 context.Context, arg2 livekit.RoomName, arg3 string, arg4 int64) (int64, error) {
+	fake.incrementRoomCounterMutex.Lock()
+	ret, specificReturn := fake.incrementRoomCounterReturnsOnCall[len(fake.incrementRoomCounterArgsForCall)]
+	fake.incrementRoomCounterArgsForCall = append(fake.incrementRoomCounterArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+		arg4 int64
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.IncrementRoomCounterStub
+	fakeReturns := fake.incrementRoomCounterReturns
+	fake.recordInvocation("IncrementRoomCounter", []interface{}{arg1, arg2, arg3, arg4})
+	fake.incrementRoomCounterMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomPresenceStore) IncrementRoomCounterCallCount() int {
+	fake.incrementRoomCounterMutex.RLock()
+	defer fake.incrementRoomCounterMutex.RUnlock()
+	return len(fake.incrementRoomCounterArgsForCall)
+}
+
+func (fake *FakeRoomPresenceStore) IncrementRoomCounterCalls(stub func(context.Context, livekit.RoomName, string, int64) (int64, error)) {
+	fake.incrementRoomCounterMutex.Lock()
+	defer fake.incrementRoomCounterMutex.Unlock()
+	fake.IncrementRoomCounterStub = stub
+}
+
+func (fake *FakeRoomPresenceStore) IncrementRoomCounterArgsForCall(i int) (context.Context, livekit.RoomName, string, int64) {
+	fake.incrementRoomCounterMutex.RLock()
+	defer fake.incrementRoomCounterMutex.RUnlock()
+	argsForCall := fake.incrementRoomCounterArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeRoomPresenceStore) IncrementRoomCounterReturns(result1 int64, result2 error) {
+	fake.incrementRoomCounterMutex.Lock()
+	defer fake.incrementRoomCounterMutex.Unlock()
+	fake.IncrementRoomCounterStub = nil
+	fake.incrementRoomCounterReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomPresenceStore) IncrementRoomCounterReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.incrementRoomCounterMutex.Lock()
+	defer fake.incrementRoomCounterMutex.Unlock()
+	fake.IncrementRoomCounterStub = nil
+	if fake.incrementRoomCounterReturnsOnCall == nil {
+		fake.incrementRoomCounterReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.incrementRoomCounterReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomPresenceStore) LoadRoomCounters(arg1 context.Context, arg2 livekit.RoomName) (map[string]int64, map[string]int64, error) {
+	fake.loadRoomCountersMutex.Lock()
+	ret, specificReturn := fake.loadRoomCountersReturnsOnCall[len(fake.loadRoomCountersArgsForCall)]
+	fake.loadRoomCountersArgsForCall = append(fake.loadRoomCountersArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomCountersStub
+	fakeReturns := fake.loadRoomCountersReturns
+	fake.recordInvocation("LoadRoomCounters", []interface{}{arg1, arg2})
+	fake.loadRoomCountersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeRoomPresenceStore) LoadRoomCountersCallCount() int {
+	fake.loadRoomCountersMutex.RLock()
+	defer fake.loadRoomCountersMutex.RUnlock()
+	return len(fake.loadRoomCountersArgsForCall)
+}
+
+func (fake *FakeRoomPresenceStore) LoadRoomCountersCalls(stub func(context.Context, livekit.RoomName) (map[string]int64, map[string]int64, error)) {
+	fake.loadRoomCountersMutex.Lock()
+	defer fake.loadRoomCountersMutex.Unlock()
+	fake.LoadRoomCountersStub = stub
+}
+
+func (fake *FakeRoomPresenceStore) LoadRoomCountersArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomCountersMutex.RLock()
+	defer fake.loadRoomCountersMutex.RUnlock()
+	argsForCall := fake.loadRoomCountersArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomPresenceStore) LoadRoomCountersReturns(result1 map[string]int64, result2 map[string]int64, result3 error) {
+	fake.loadRoomCountersMutex.Lock()
+	defer fake.loadRoomCountersMutex.Unlock()
+	fake.LoadRoomCountersStub = nil
+	fake.loadRoomCountersReturns = struct {
+		result1 map[string]int64
+		result2 map[string]int64
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeRoomPresenceStore) LoadRoomCountersReturnsOnCall(i int, result1 map[string]int64, result2 map[string]int64, result3 error) {
+	fake.loadRoomCountersMutex.Lock()
+	defer fake.loadRoomCountersMutex.Unlock()
+	fake.LoadRoomCountersStub = nil
+	if fake.loadRoomCountersReturnsOnCall == nil {
+		fake.loadRoomCountersReturnsOnCall = make(map[int]struct {
+			result1 map[string]int64
+			result2 map[string]int64
+			result3 error
+		})
+	}
+	fake.loadRoomCountersReturnsOnCall[i] = struct {
+		result1 map[string]int64
+		result2 map[string]int64
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeRoomPresenceStore) StoreRoomWatchers(arg1 context.Context, arg2 livekit.RoomName, arg3 string, arg4 int64, arg5 time.Duration) error {
+	fake.storeRoomWatchersMutex.Lock()
+	ret, specificReturn := fake.storeRoomWatchersReturnsOnCall[len(fake.storeRoomWatchersArgsForCall)]
+	fake.storeRoomWatchersArgsForCall = append(fake.storeRoomWatchersArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 string
+		arg4 int64
+		arg5 time.Duration
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.StoreRoomWatchersStub
+	fakeReturns := fake.storeRoomWatchersReturns
+	fake.recordInvocation("StoreRoomWatchers", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.storeRoomWatchersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomPresenceStore) StoreRoomWatchersCallCount() int {
+	fake.storeRoomWatchersMutex.RLock()
+	defer fake.storeRoomWatchersMutex.RUnlock()
+	return len(fake.storeRoomWatchersArgsForCall)
+}
+
+func (fake *FakeRoomPresenceStore) StoreRoomWatchersCalls(stub func(context.Context, livekit.RoomName, string, int64, time.Duration) error) {
+	fake.storeRoomWatchersMutex.Lock()
+	defer fake.storeRoomWatchersMutex.Unlock()
+	fake.StoreRoomWatchersStub = stub
+}
+
+func (fake *FakeRoomPresenceStore) StoreRoomWatchersArgsForCall(i int) (context.Context, livekit.RoomName, string, int64, time.Duration) {
+	fake.storeRoomWatchersMutex.RLock()
+	defer fake.storeRoomWatchersMutex.RUnlock()
+	argsForCall := fake.storeRoomWatchersArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeRoomPresenceStore) StoreRoomWatchersReturns(result1 error) {
+	fake.storeRoomWatchersMutex.Lock()
+	defer fake.storeRoomWatchersMutex.Unlock()
+	fake.StoreRoomWatchersStub = nil
+	fake.storeRoomWatchersReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomPresenceStore) StoreRoomWatchersReturnsOnCall(i int, result1 error) {
+	fake.storeRoomWatchersMutex.Lock()
+	defer fake.storeRoomWatchersMutex.Unlock()
+	fake.StoreRoomWatchersStub = nil
+	if fake.storeRoomWatchersReturnsOnCall == nil {
+		fake.storeRoomWatchersReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomWatchersReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomPresenceStore) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.deleteRoomCountersMutex.RLock()
+	defer fake.deleteRoomCountersMutex.RUnlock()
+	fake.incrementRoomCounterMutex.RLock()
+	defer fake.incrementRoomCounterMutex.RUnlock()
+	fake.loadRoomCountersMutex.RLock()
+	defer fake.loadRoomCountersMutex.RUnlock()
+	fake.storeRoomWatchersMutex.RLock()
+	defer fake.storeRoomWatchersMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRoomPresenceStore) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.RoomPresenceStore = new(FakeRoomPresenceStore)
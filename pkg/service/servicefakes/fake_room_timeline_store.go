@@ -0,0 +1,369 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package servicefakes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livekit/livekit-server/pkg/service"
+	"github.com/livekit/protocol/livekit"
+)
+
+type FakeRoomTimelineStore struct {
+	DeleteParticipantEventsStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) ([]*service.RoomEvent, error)
+	deleteParticipantEventsMutex       sync.RWMutex
+	deleteParticipantEventsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}
+	deleteParticipantEventsReturns struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}
+	deleteParticipantEventsReturnsOnCall map[int]struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}
+	DeleteRoomEventsStub        func(context.Context, livekit.RoomName) error
+	deleteRoomEventsMutex       sync.RWMutex
+	deleteRoomEventsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	deleteRoomEventsReturns struct {
+		result1 error
+	}
+	deleteRoomEventsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ListRoomEventsStub        func(context.Context, livekit.RoomName, time.Time, time.Time, int) ([]*service.RoomEvent, error)
+	listRoomEventsMutex       sync.RWMutex
+	listRoomEventsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 time.Time
+		arg4 time.Time
+		arg5 int
+	}
+	listRoomEventsReturns struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}
+	listRoomEventsReturnsOnCall map[int]struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}
+	StoreRoomEventsStub        func(context.Context, livekit.RoomName, []*service.RoomEvent) error
+	storeRoomEventsMutex       sync.RWMutex
+	storeRoomEventsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 []*service.RoomEvent
+	}
+	storeRoomEventsReturns struct {
+		result1 error
+	}
+	storeRoomEventsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRoomTimelineStore) DeleteParticipantEvents(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) ([]*service.RoomEvent, error) {
+	fake.deleteParticipantEventsMutex.Lock()
+	ret, specificReturn := fake.deleteParticipantEventsReturnsOnCall[len(fake.deleteParticipantEventsArgsForCall)]
+	fake.deleteParticipantEventsArgsForCall = append(fake.deleteParticipantEventsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 livekit.ParticipantIdentity
+	}{arg1, arg2, arg3})
+	stub := fake.DeleteParticipantEventsStub
+	fakeReturns := fake.deleteParticipantEventsReturns
+	fake.recordInvocation("DeleteParticipantEvents", []interface{}{arg1, arg2, arg3})
+	fake.deleteParticipantEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomTimelineStore) DeleteParticipantEventsCallCount() int {
+	fake.deleteParticipantEventsMutex.RLock()
+	defer fake.deleteParticipantEventsMutex.RUnlock()
+	return len(fake.deleteParticipantEventsArgsForCall)
+}
+
+func (fake *FakeRoomTimelineStore) DeleteParticipantEventsCalls(stub func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) ([]*service.RoomEvent, error)) {
+	fake.deleteParticipantEventsMutex.Lock()
+	defer fake.deleteParticipantEventsMutex.Unlock()
+	fake.DeleteParticipantEventsStub = stub
+}
+
+func (fake *FakeRoomTimelineStore) DeleteParticipantEventsArgsForCall(i int) (context.Context, livekit.RoomName, livekit.ParticipantIdentity) {
+	fake.deleteParticipantEventsMutex.RLock()
+	defer fake.deleteParticipantEventsMutex.RUnlock()
+	argsForCall := fake.deleteParticipantEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomTimelineStore) DeleteParticipantEventsReturns(result1 []*service.RoomEvent, result2 error) {
+	fake.deleteParticipantEventsMutex.Lock()
+	defer fake.deleteParticipantEventsMutex.Unlock()
+	fake.DeleteParticipantEventsStub = nil
+	fake.deleteParticipantEventsReturns = struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomTimelineStore) DeleteParticipantEventsReturnsOnCall(i int, result1 []*service.RoomEvent, result2 error) {
+	fake.deleteParticipantEventsMutex.Lock()
+	defer fake.deleteParticipantEventsMutex.Unlock()
+	fake.DeleteParticipantEventsStub = nil
+	if fake.deleteParticipantEventsReturnsOnCall == nil {
+		fake.deleteParticipantEventsReturnsOnCall = make(map[int]struct {
+			result1 []*service.RoomEvent
+			result2 error
+		})
+	}
+	fake.deleteParticipantEventsReturnsOnCall[i] = struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomTimelineStore) DeleteRoomEvents(arg1 context.Context, arg2 livekit.RoomName) error {
+	fake.deleteRoomEventsMutex.Lock()
+	ret, specificReturn := fake.deleteRoomEventsReturnsOnCall[len(fake.deleteRoomEventsArgsForCall)]
+	fake.deleteRoomEventsArgsForCall = append(fake.deleteRoomEventsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.DeleteRoomEventsStub
+	fakeReturns := fake.deleteRoomEventsReturns
+	fake.recordInvocation("DeleteRoomEvents", []interface{}{arg1, arg2})
+	fake.deleteRoomEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomTimelineStore) DeleteRoomEventsCallCount() int {
+	fake.deleteRoomEventsMutex.RLock()
+	defer fake.deleteRoomEventsMutex.RUnlock()
+	return len(fake.deleteRoomEventsArgsForCall)
+}
+
+func (fake *FakeRoomTimelineStore) DeleteRoomEventsCalls(stub func(context.Context, livekit.RoomName) error) {
+	fake.deleteRoomEventsMutex.Lock()
+	defer fake.deleteRoomEventsMutex.Unlock()
+	fake.DeleteRoomEventsStub = stub
+}
+
+func (fake *FakeRoomTimelineStore) DeleteRoomEventsArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.deleteRoomEventsMutex.RLock()
+	defer fake.deleteRoomEventsMutex.RUnlock()
+	argsForCall := fake.deleteRoomEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoomTimelineStore) DeleteRoomEventsReturns(result1 error) {
+	fake.deleteRoomEventsMutex.Lock()
+	defer fake.deleteRoomEventsMutex.Unlock()
+	fake.DeleteRoomEventsStub = nil
+	fake.deleteRoomEventsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomTimelineStore) DeleteRoomEventsReturnsOnCall(i int, result1 error) {
+	fake.deleteRoomEventsMutex.Lock()
+	defer fake.deleteRoomEventsMutex.Unlock()
+	fake.DeleteRoomEventsStub = nil
+	if fake.deleteRoomEventsReturnsOnCall == nil {
+		fake.deleteRoomEventsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteRoomEventsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomTimelineStore) ListRoomEvents(arg1 context.Context, arg2 livekit.RoomName, arg3 time.Time, arg4 time.Time, arg5 int) ([]*service.RoomEvent, error) {
+	fake.listRoomEventsMutex.Lock()
+	ret, specificReturn := fake.listRoomEventsReturnsOnCall[len(fake.listRoomEventsArgsForCall)]
+	fake.listRoomEventsArgsForCall = append(fake.listRoomEventsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 time.Time
+		arg4 time.Time
+		arg5 int
+	}{arg1, arg2, arg3, arg4, arg5})
+	stub := fake.ListRoomEventsStub
+	fakeReturns := fake.listRoomEventsReturns
+	fake.recordInvocation("ListRoomEvents", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.listRoomEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoomTimelineStore) ListRoomEventsCallCount() int {
+	fake.listRoomEventsMutex.RLock()
+	defer fake.listRoomEventsMutex.RUnlock()
+	return len(fake.listRoomEventsArgsForCall)
+}
+
+func (fake *FakeRoomTimelineStore) ListRoomEventsCalls(stub func(context.Context, livekit.RoomName, time.Time, time.Time, int) ([]*service.RoomEvent, error)) {
+	fake.listRoomEventsMutex.Lock()
+	defer fake.listRoomEventsMutex.Unlock()
+	fake.ListRoomEventsStub = stub
+}
+
+func (fake *FakeRoomTimelineStore) ListRoomEventsArgsForCall(i int) (context.Context, livekit.RoomName, time.Time, time.Time, int) {
+	fake.listRoomEventsMutex.RLock()
+	defer fake.listRoomEventsMutex.RUnlock()
+	argsForCall := fake.listRoomEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeRoomTimelineStore) ListRoomEventsReturns(result1 []*service.RoomEvent, result2 error) {
+	fake.listRoomEventsMutex.Lock()
+	defer fake.listRoomEventsMutex.Unlock()
+	fake.ListRoomEventsStub = nil
+	fake.listRoomEventsReturns = struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomTimelineStore) ListRoomEventsReturnsOnCall(i int, result1 []*service.RoomEvent, result2 error) {
+	fake.listRoomEventsMutex.Lock()
+	defer fake.listRoomEventsMutex.Unlock()
+	fake.ListRoomEventsStub = nil
+	if fake.listRoomEventsReturnsOnCall == nil {
+		fake.listRoomEventsReturnsOnCall = make(map[int]struct {
+			result1 []*service.RoomEvent
+			result2 error
+		})
+	}
+	fake.listRoomEventsReturnsOnCall[i] = struct {
+		result1 []*service.RoomEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRoomTimelineStore) StoreRoomEvents(arg1 context.Context, arg2 livekit.RoomName, arg3 []*service.RoomEvent) error {
+	var arg3Copy []*service.RoomEvent
+	if arg3 != nil {
+		arg3Copy = make([]*service.RoomEvent, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.storeRoomEventsMutex.Lock()
+	ret, specificReturn := fake.storeRoomEventsReturnsOnCall[len(fake.storeRoomEventsArgsForCall)]
+	fake.storeRoomEventsArgsForCall = append(fake.storeRoomEventsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+		arg3 []*service.RoomEvent
+	}{arg1, arg2, arg3Copy})
+	stub := fake.StoreRoomEventsStub
+	fakeReturns := fake.storeRoomEventsReturns
+	fake.recordInvocation("StoreRoomEvents", []interface{}{arg1, arg2, arg3Copy})
+	fake.storeRoomEventsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoomTimelineStore) StoreRoomEventsCallCount() int {
+	fake.storeRoomEventsMutex.RLock()
+	defer fake.storeRoomEventsMutex.RUnlock()
+	return len(fake.storeRoomEventsArgsForCall)
+}
+
+func (fake *FakeRoomTimelineStore) StoreRoomEventsCalls(stub func(context.Context, livekit.RoomName, []*service.RoomEvent) error) {
+	fake.storeRoomEventsMutex.Lock()
+	defer fake.storeRoomEventsMutex.Unlock()
+	fake.StoreRoomEventsStub = stub
+}
+
+func (fake *FakeRoomTimelineStore) StoreRoomEventsArgsForCall(i int) (context.Context, livekit.RoomName, []*service.RoomEvent) {
+	fake.storeRoomEventsMutex.RLock()
+	defer fake.storeRoomEventsMutex.RUnlock()
+	argsForCall := fake.storeRoomEventsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeRoomTimelineStore) StoreRoomEventsReturns(result1 error) {
+	fake.storeRoomEventsMutex.Lock()
+	defer fake.storeRoomEventsMutex.Unlock()
+	fake.StoreRoomEventsStub = nil
+	fake.storeRoomEventsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomTimelineStore) StoreRoomEventsReturnsOnCall(i int, result1 error) {
+	fake.storeRoomEventsMutex.Lock()
+	defer fake.storeRoomEventsMutex.Unlock()
+	fake.StoreRoomEventsStub = nil
+	if fake.storeRoomEventsReturnsOnCall == nil {
+		fake.storeRoomEventsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeRoomEventsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRoomTimelineStore) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.deleteParticipantEventsMutex.RLock()
+	defer fake.deleteParticipantEventsMutex.RUnlock()
+	fake.deleteRoomEventsMutex.RLock()
+	defer fake.deleteRoomEventsMutex.RUnlock()
+	fake.listRoomEventsMutex.RLock()
+	defer fake.listRoomEventsMutex.RUnlock()
+	fake.storeRoomEventsMutex.RLock()
+	defer fake.storeRoomEventsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRoomTimelineStore) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ service.RoomTimelineStore = new(FakeRoomTimelineStore)
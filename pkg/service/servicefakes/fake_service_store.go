@@ -38,6 +38,22 @@ type FakeServiceStore struct {
 		result1 []*livekit.Room
 		result2 error
 	}
+	ListRoomsPageStub        func(context.Context, *service.ListRoomsOptions) ([]*livekit.Room, string, error)
+	listRoomsPageMutex       sync.RWMutex
+	listRoomsPageArgsForCall []struct {
+		arg1 context.Context
+		arg2 *service.ListRoomsOptions
+	}
+	listRoomsPageReturns struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}
+	listRoomsPageReturnsOnCall map[int]struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}
 	LoadParticipantStub        func(context.Context, livekit.RoomName, livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error)
 	loadParticipantMutex       sync.RWMutex
 	loadParticipantArgsForCall []struct {
@@ -70,6 +86,20 @@ type FakeServiceStore struct {
 		result2 *livekit.RoomInternal
 		result3 error
 	}
+	LoadRoomJoinCodeStub        func(context.Context, livekit.RoomName) (string, error)
+	loadRoomJoinCodeMutex       sync.RWMutex
+	loadRoomJoinCodeArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}
+	loadRoomJoinCodeReturns struct {
+		result1 string
+		result2 error
+	}
+	loadRoomJoinCodeReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -209,6 +239,74 @@ func (fake *FakeServiceStore) ListRoomsReturnsOnCall(i int, result1 []*livekit.R
 	}{result1, result2}
 }
 
+func (fake *FakeServiceStore) ListRoomsPage(arg1 context.Context, arg2 *service.ListRoomsOptions) ([]*livekit.Room, string, error) {
+	fake.listRoomsPageMutex.Lock()
+	ret, specificReturn := fake.listRoomsPageReturnsOnCall[len(fake.listRoomsPageArgsForCall)]
+	fake.listRoomsPageArgsForCall = append(fake.listRoomsPageArgsForCall, struct {
+		arg1 context.Context
+		arg2 *service.ListRoomsOptions
+	}{arg1, arg2})
+	stub := fake.ListRoomsPageStub
+	fakeReturns := fake.listRoomsPageReturns
+	fake.recordInvocation("ListRoomsPage", []interface{}{arg1, arg2})
+	fake.listRoomsPageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeServiceStore) ListRoomsPageCallCount() int {
+	fake.listRoomsPageMutex.RLock()
+	defer fake.listRoomsPageMutex.RUnlock()
+	return len(fake.listRoomsPageArgsForCall)
+}
+
+func (fake *FakeServiceStore) ListRoomsPageCalls(stub func(context.Context, *service.ListRoomsOptions) ([]*livekit.Room, string, error)) {
+	fake.listRoomsPageMutex.Lock()
+	defer fake.listRoomsPageMutex.Unlock()
+	fake.ListRoomsPageStub = stub
+}
+
+func (fake *FakeServiceStore) ListRoomsPageArgsForCall(i int) (context.Context, *service.ListRoomsOptions) {
+	fake.listRoomsPageMutex.RLock()
+	defer fake.listRoomsPageMutex.RUnlock()
+	argsForCall := fake.listRoomsPageArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeServiceStore) ListRoomsPageReturns(result1 []*livekit.Room, result2 string, result3 error) {
+	fake.listRoomsPageMutex.Lock()
+	defer fake.listRoomsPageMutex.Unlock()
+	fake.ListRoomsPageStub = nil
+	fake.listRoomsPageReturns = struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeServiceStore) ListRoomsPageReturnsOnCall(i int, result1 []*livekit.Room, result2 string, result3 error) {
+	fake.listRoomsPageMutex.Lock()
+	defer fake.listRoomsPageMutex.Unlock()
+	fake.ListRoomsPageStub = nil
+	if fake.listRoomsPageReturnsOnCall == nil {
+		fake.listRoomsPageReturnsOnCall = make(map[int]struct {
+			result1 []*livekit.Room
+			result2 string
+			result3 error
+		})
+	}
+	fake.listRoomsPageReturnsOnCall[i] = struct {
+		result1 []*livekit.Room
+		result2 string
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeServiceStore) LoadParticipant(arg1 context.Context, arg2 livekit.RoomName, arg3 livekit.ParticipantIdentity) (*livekit.ParticipantInfo, error) {
 	fake.loadParticipantMutex.Lock()
 	ret, specificReturn := fake.loadParticipantReturnsOnCall[len(fake.loadParticipantArgsForCall)]
@@ -344,6 +442,71 @@ func (fake *FakeServiceStore) LoadRoomReturnsOnCall(i int, result1 *livekit.Room
 	}{result1, result2, result3}
 }
 
+func (fake *FakeServiceStore) LoadRoomJoinCode(arg1 context.Context, arg2 livekit.RoomName) (string, error) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	ret, specificReturn := fake.loadRoomJoinCodeReturnsOnCall[len(fake.loadRoomJoinCodeArgsForCall)]
+	fake.loadRoomJoinCodeArgsForCall = append(fake.loadRoomJoinCodeArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.RoomName
+	}{arg1, arg2})
+	stub := fake.LoadRoomJoinCodeStub
+	fakeReturns := fake.loadRoomJoinCodeReturns
+	fake.recordInvocation("LoadRoomJoinCode", []interface{}{arg1, arg2})
+	fake.loadRoomJoinCodeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeServiceStore) LoadRoomJoinCodeCallCount() int {
+	fake.loadRoomJoinCodeMutex.RLock()
+	defer fake.loadRoomJoinCodeMutex.RUnlock()
+	return len(fake.loadRoomJoinCodeArgsForCall)
+}
+
+func (fake *FakeServiceStore) LoadRoomJoinCodeCalls(stub func(context.Context, livekit.RoomName) (string, error)) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	defer fake.loadRoomJoinCodeMutex.Unlock()
+	fake.LoadRoomJoinCodeStub = stub
+}
+
+func (fake *FakeServiceStore) LoadRoomJoinCodeArgsForCall(i int) (context.Context, livekit.RoomName) {
+	fake.loadRoomJoinCodeMutex.RLock()
+	defer fake.loadRoomJoinCodeMutex.RUnlock()
+	argsForCall := fake.loadRoomJoinCodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeServiceStore) LoadRoomJoinCodeReturns(result1 string, result2 error) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	defer fake.loadRoomJoinCodeMutex.Unlock()
+	fake.LoadRoomJoinCodeStub = nil
+	fake.loadRoomJoinCodeReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeServiceStore) LoadRoomJoinCodeReturnsOnCall(i int, result1 string, result2 error) {
+	fake.loadRoomJoinCodeMutex.Lock()
+	defer fake.loadRoomJoinCodeMutex.Unlock()
+	fake.LoadRoomJoinCodeStub = nil
+	if fake.loadRoomJoinCodeReturnsOnCall == nil {
+		fake.loadRoomJoinCodeReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.loadRoomJoinCodeReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeServiceStore) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -351,10 +514,14 @@ func (fake *FakeServiceStore) Invocations() map[string][][]interface{} {
 	defer fake.listParticipantsMutex.RUnlock()
 	fake.listRoomsMutex.RLock()
 	defer fake.listRoomsMutex.RUnlock()
+	fake.listRoomsPageMutex.RLock()
+	defer fake.listRoomsPageMutex.RUnlock()
 	fake.loadParticipantMutex.RLock()
 	defer fake.loadParticipantMutex.RUnlock()
 	fake.loadRoomMutex.RLock()
 	defer fake.loadRoomMutex.RUnlock()
+	fake.loadRoomJoinCodeMutex.RLock()
+	defer fake.loadRoomJoinCodeMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
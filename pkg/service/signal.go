@@ -79,11 +79,18 @@ func NewDefaultSignalServer(
 			}
 
 			if rtcNode.Id != currentNode.Id {
-				err = routing.ErrIncorrectRTCNode
-				logger.Errorw("called participant on incorrect node", err,
-					"rtcNode", rtcNode,
-				)
-				return err
+				// the room may be relayed to this node
+				hosts, err := router.HostsRoom(ctx, roomName, livekit.NodeID(currentNode.Id))
+				if err != nil {
+					return err
+				}
+				if !hosts {
+					err = routing.ErrIncorrectRTCNode
+					logger.Errorw("called participant on incorrect node", err,
+						"rtcNode", rtcNode,
+					)
+					return err
+				}
 			}
 
 			pKey := routing.ParticipantKeyLegacy(roomName, pi.Identity)
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+)
+
+const subscriptionDiagnosticsPathPrefix = "/subscription_diagnostics/"
+
+var ErrSubscriptionNotFound = errors.New("participant is not subscribed to the track")
+
+type ExplainSubscriptionRequest struct {
+	Room string `json:"room"`
+	// the subscriber
+	Identity string `json:"identity"`
+	TrackSid string `json:"track_sid"`
+}
+
+type ExplainSubscriptionResponse struct {
+	Room              string                      `json:"room"`
+	NodeID            livekit.NodeID              `json:"node_id"`
+	Identity          livekit.ParticipantIdentity `json:"identity"`
+	TrackSid          livekit.TrackID             `json:"track_sid"`
+	PublisherIdentity livekit.ParticipantIdentity `json:"publisher_identity"`
+	Kind              string                      `json:"kind"`
+	// the subscriber disabled the track
+	Muted bool `json:"muted"`
+	// allocation of video tracks, none for audio tracks which are always forwarded
+	Allocation *streamallocator.Diagnostics `json:"allocation,omitempty"`
+}
+
+// SubscriptionDiagnosticsService tells why a subscriber receives the layer of a video track it does, from the inputs
+// of the latest allocation of its downlink: the channel capacity estimate, the demands and priorities of the tracks
+// competing for it, pause and exempt states. Subscriptions are reported by the node hosting the room, requests are JSON
+// posted to /subscription_diagnostics/ExplainSubscription on that node and require the roomAdmin grant for the room
+type SubscriptionDiagnosticsService struct {
+	roomManager *RoomManager
+}
+
+func NewSubscriptionDiagnosticsService(roomManager *RoomManager) *SubscriptionDiagnosticsService {
+	return &SubscriptionDiagnosticsService{
+		roomManager: roomManager,
+	}
+}
+
+func (s *SubscriptionDiagnosticsService) PathPrefix() string {
+	return subscriptionDiagnosticsPathPrefix
+}
+
+func (s *SubscriptionDiagnosticsService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, subscriptionDiagnosticsPathPrefix) {
+	case "ExplainSubscription":
+		s.explainSubscription(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *SubscriptionDiagnosticsService) explainSubscription(w http.ResponseWriter, r *http.Request) {
+	req := &ExplainSubscriptionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := EnsureAdminPermission(r.Context(), livekit.RoomName(req.Room)); err != nil || req.Room == "" {
+		handleError(w, http.StatusUnauthorized, ErrPermissionDenied)
+		return
+	}
+
+	res, err := s.ExplainSubscription(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrRoomNotFound) || errors.Is(err, ErrParticipantNotFound) || errors.Is(err, ErrSubscriptionNotFound) {
+			handleError(w, http.StatusNotFound, err, "room", req.Room, "participant", req.Identity, "trackID", req.TrackSid)
+		} else {
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room, "participant", req.Identity, "trackID", req.TrackSid)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// ExplainSubscription returns the state of a subscription of a participant in a room hosted on this node
+func (s *SubscriptionDiagnosticsService) ExplainSubscription(ctx context.Context, req *ExplainSubscriptionRequest) (*ExplainSubscriptionResponse, error) {
+	room := s.roomManager.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(livekit.ParticipantIdentity(req.Identity))
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	trackID := livekit.TrackID(req.TrackSid)
+	for _, subTrack := range participant.GetSubscribedTracks() {
+		if subTrack.ID() != trackID {
+			continue
+		}
+
+		res := &ExplainSubscriptionResponse{
+			Room:              req.Room,
+			NodeID:            livekit.NodeID(s.roomManager.currentNode.Id),
+			Identity:          participant.Identity(),
+			TrackSid:          trackID,
+			PublisherIdentity: subTrack.PublisherIdentity(),
+			Kind:              subTrack.MediaTrack().Kind().String(),
+			Muted:             subTrack.IsMuted(),
+		}
+		if subTrack.MediaTrack().Kind() == livekit.TrackType_VIDEO {
+			allocation, err := participant.GetSubscriptionDiagnostics(trackID)
+			if err != nil && !errors.Is(err, streamallocator.ErrDiagnosticsTrackNotFound) {
+				return nil, err
+			}
+			// not yet bound, or the subscriber downlink is not allocated
+			res.Allocation = allocation
+		}
+		return res, nil
+	}
+	return nil, ErrSubscriptionNotFound
+}
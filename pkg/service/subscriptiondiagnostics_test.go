@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/sfu/streamallocator"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestSubscriptionDiagnosticsService(t *testing.T) {
+	room := rtc.NewRoom(
+		&livekit.Room{Name: "room"},
+		nil,
+		rtc.WebRTCConfig{},
+		&config.AudioConfig{UpdateInterval: 500},
+		&livekit.ServerInfo{},
+		&telemetryfakes.FakeTelemetryService{},
+		nil,
+	)
+	defer room.Close()
+	svc := NewSubscriptionDiagnosticsService(&RoomManager{
+		currentNode: &livekit.Node{Id: "node"},
+		rooms:       map[livekit.RoomName]*rtc.Room{"room": room},
+	})
+
+	videoTrack := &typesfakes.FakeMediaTrack{}
+	videoTrack.KindReturns(livekit.TrackType_VIDEO)
+	video := &typesfakes.FakeSubscribedTrack{}
+	video.IDReturns("TR_video")
+	video.PublisherIdentityReturns("bob")
+	video.MediaTrackReturns(videoTrack)
+
+	audioTrack := &typesfakes.FakeMediaTrack{}
+	audioTrack.KindReturns(livekit.TrackType_AUDIO)
+	audio := &typesfakes.FakeSubscribedTrack{}
+	audio.IDReturns("TR_audio")
+	audio.IsMutedReturns(true)
+	audio.MediaTrackReturns(audioTrack)
+
+	p := &typesfakes.FakeLocalParticipant{}
+	p.IDReturns("PA_alice")
+	p.IdentityReturns("alice")
+	p.StateReturns(livekit.ParticipantInfo_JOINED)
+	p.ToProtoReturns(&livekit.ParticipantInfo{Sid: "PA_alice", Identity: "alice", State: livekit.ParticipantInfo_JOINED})
+	p.GetSubscribedTracksReturns([]types.SubscribedTrack{video, audio})
+	p.GetSubscriptionDiagnosticsReturns(&streamallocator.Diagnostics{
+		Track:  &streamallocator.TrackDiagnostics{TrackID: "TR_video"},
+		Reason: "limited by channel capacity",
+	}, nil)
+	require.NoError(t, room.Join(p, nil, nil, nil))
+
+	request := func(body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+"ExplainSubscription", strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+
+	t.Run("requires room admin", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request(`{"room": "room", "identity": "alice", "track_sid": "TR_video"}`, nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request(`{"room": "other"}`, admin).Code)
+	})
+
+	t.Run("subscription not found", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, request(`{"room": "other"}`, &auth.VideoGrant{RoomAdmin: true, Room: "other"}).Code)
+		require.Equal(t, http.StatusNotFound, request(`{"room": "room", "identity": "carol", "track_sid": "TR_video"}`, admin).Code)
+		require.Equal(t, http.StatusNotFound, request(`{"room": "room", "identity": "alice", "track_sid": "TR_other"}`, admin).Code)
+	})
+
+	t.Run("explains video allocation", func(t *testing.T) {
+		w := request(`{"room": "room", "identity": "alice", "track_sid": "TR_video"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &ExplainSubscriptionResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+		require.Equal(t, livekit.NodeID("node"), res.NodeID)
+		require.Equal(t, livekit.ParticipantIdentity("bob"), res.PublisherIdentity)
+		require.Equal(t, livekit.TrackType_VIDEO.String(), res.Kind)
+		require.NotNil(t, res.Allocation)
+		require.Equal(t, "limited by channel capacity", res.Allocation.Reason)
+		require.Equal(t, livekit.TrackID("TR_video"), p.GetSubscriptionDiagnosticsArgsForCall(0))
+	})
+
+	t.Run("audio is not allocated", func(t *testing.T) {
+		w := request(`{"room": "room", "identity": "alice", "track_sid": "TR_audio"}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &ExplainSubscriptionResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+		require.True(t, res.Muted)
+		require.Nil(t, res.Allocation)
+		require.Equal(t, 1, p.GetSubscriptionDiagnosticsCallCount())
+	})
+}
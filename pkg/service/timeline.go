@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+type RoomEventType string
+
+const (
+	RoomEventRoomStarted                RoomEventType = "room_started"
+	RoomEventRoomEnded                  RoomEventType = "room_ended"
+	RoomEventRoomMetadataChanged        RoomEventType = "room_metadata_changed"
+	RoomEventParticipantJoined          RoomEventType = "participant_joined"
+	RoomEventParticipantActive          RoomEventType = "participant_active"
+	RoomEventParticipantLeft            RoomEventType = "participant_left"
+	RoomEventParticipantMetadataChanged RoomEventType = "participant_metadata_changed"
+	RoomEventTrackPublished             RoomEventType = "track_published"
+	RoomEventTrackUnpublished           RoomEventType = "track_unpublished"
+	RoomEventTrackMuted                 RoomEventType = "track_muted"
+	RoomEventTrackUnmuted               RoomEventType = "track_unmuted"
+)
+
+const (
+	timelinePathPrefix = "/timeline/"
+
+	defaultTimelineLimit = 1000
+	maxTimelineLimit     = 10000
+	timelineQueueSize    = 1000
+)
+
+// RoomEvent is an entry of the room timeline
+type RoomEvent struct {
+	Type RoomEventType `json:"type"`
+	Time time.Time     `json:"time"`
+
+	RoomSID        string `json:"room_sid,omitempty"`
+	ParticipantSID string `json:"participant_sid,omitempty"`
+	Identity       string `json:"identity,omitempty"`
+	Name           string `json:"name,omitempty"`
+	TrackSID       string `json:"track_sid,omitempty"`
+	TrackName      string `json:"track_name,omitempty"`
+	TrackType      string `json:"track_type,omitempty"`
+	TrackSource    string `json:"track_source,omitempty"`
+	Metadata       string `json:"metadata,omitempty"`
+}
+
+// RoomTimeline derives room events from room and participant updates and persists them in order
+type RoomTimeline struct {
+	store  RoomTimelineStore
+	name   livekit.RoomName
+	logger logger.Logger
+	queue  *utils.OpsQueue
+
+	lock         sync.Mutex
+	room         *livekit.Room
+	participants map[livekit.ParticipantID]*livekit.ParticipantInfo
+	lastTime     time.Time
+}
+
+func NewRoomTimeline(store RoomTimelineStore, room *livekit.Room, l logger.Logger) *RoomTimeline {
+	t := &RoomTimeline{
+		store:        store,
+		name:         livekit.RoomName(room.Name),
+		logger:       l,
+		queue:        utils.NewOpsQueue(l, "room-timeline", timelineQueueSize),
+		room:         room,
+		participants: make(map[livekit.ParticipantID]*livekit.ParticipantInfo),
+	}
+	t.queue.Start()
+
+	t.lock.Lock()
+	t.recordLocked(t.roomEvent(RoomEventRoomStarted))
+	t.lock.Unlock()
+	return t
+}
+
+func (t *RoomTimeline) RoomUpdated(room *livekit.Room) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	changed := room.Metadata != t.room.Metadata
+	t.room = room
+	if changed {
+		ev := t.roomEvent(RoomEventRoomMetadataChanged)
+		ev.Metadata = room.Metadata
+		t.recordLocked(ev)
+	}
+}
+
+func (t *RoomTimeline) ParticipantChanged(pi *livekit.ParticipantInfo) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	pID := livekit.ParticipantID(pi.Sid)
+	prev := t.participants[pID]
+	if prev == nil {
+		if pi.State == livekit.ParticipantInfo_DISCONNECTED {
+			return
+		}
+		prev = &livekit.ParticipantInfo{Metadata: pi.Metadata, Name: pi.Name}
+		t.recordLocked(t.participantEvent(RoomEventParticipantJoined, pi))
+	}
+
+	if pi.State == livekit.ParticipantInfo_ACTIVE && prev.State != livekit.ParticipantInfo_ACTIVE {
+		t.recordLocked(t.participantEvent(RoomEventParticipantActive, pi))
+	}
+	if pi.Metadata != prev.Metadata || pi.Name != prev.Name {
+		ev := t.participantEvent(RoomEventParticipantMetadataChanged, pi)
+		ev.Metadata = pi.Metadata
+		t.recordLocked(ev)
+	}
+
+	prevTracks := make(map[string]*livekit.TrackInfo, len(prev.Tracks))
+	for _, ti := range prev.Tracks {
+		prevTracks[ti.Sid] = ti
+	}
+	disconnected := pi.State == livekit.ParticipantInfo_DISCONNECTED
+	if !disconnected {
+		// tracks left in prevTracks are unpublished, all of them when the participant leaves
+		for _, ti := range pi.Tracks {
+			prevTrack := prevTracks[ti.Sid]
+			delete(prevTracks, ti.Sid)
+			switch {
+			case prevTrack == nil:
+				t.recordLocked(t.trackEvent(RoomEventTrackPublished, pi, ti))
+				if ti.Muted {
+					t.recordLocked(t.trackEvent(RoomEventTrackMuted, pi, ti))
+				}
+			case ti.Muted && !prevTrack.Muted:
+				t.recordLocked(t.trackEvent(RoomEventTrackMuted, pi, ti))
+			case !ti.Muted && prevTrack.Muted:
+				t.recordLocked(t.trackEvent(RoomEventTrackUnmuted, pi, ti))
+			}
+		}
+	}
+	for _, ti := range prevTracks {
+		t.recordLocked(t.trackEvent(RoomEventTrackUnpublished, pi, ti))
+	}
+
+	if disconnected {
+		delete(t.participants, pID)
+		t.recordLocked(t.participantEvent(RoomEventParticipantLeft, pi))
+		return
+	}
+	t.participants[pID] = pi
+}
+
+// Close records the end of the room, after it departed participants are not recorded anymore
+func (t *RoomTimeline) Close() {
+	t.lock.Lock()
+	for _, pi := range t.participants {
+		t.recordLocked(t.participantEvent(RoomEventParticipantLeft, pi))
+	}
+	t.participants = make(map[livekit.ParticipantID]*livekit.ParticipantInfo)
+	t.recordLocked(t.roomEvent(RoomEventRoomEnded))
+	t.lock.Unlock()
+
+	t.queue.Stop()
+}
+
+func (t *RoomTimeline) recordLocked(ev *RoomEvent) {
+	// events are ordered by time, keep it strictly increasing at the microsecond resolution of the stores
+	now := time.Now().Truncate(time.Microsecond)
+	if !now.After(t.lastTime) {
+		now = t.lastTime.Add(time.Microsecond)
+	}
+	t.lastTime = now
+	ev.Time = now
+
+	t.queue.Enqueue(func() {
+		if err := t.store.StoreRoomEvents(context.Background(), t.name, []*RoomEvent{ev}); err != nil {
+			t.logger.Warnw("could not store room event", err, "event", ev.Type)
+		}
+	})
+}
+
+func (t *RoomTimeline) roomEvent(eventType RoomEventType) *RoomEvent {
+	return &RoomEvent{
+		Type:    eventType,
+		RoomSID: t.room.Sid,
+	}
+}
+
+func (t *RoomTimeline) participantEvent(eventType RoomEventType, pi *livekit.ParticipantInfo) *RoomEvent {
+	ev := t.roomEvent(eventType)
+	ev.ParticipantSID = pi.Sid
+	ev.Identity = pi.Identity
+	ev.Name = pi.Name
+	return ev
+}
+
+func (t *RoomTimeline) trackEvent(eventType RoomEventType, pi *livekit.ParticipantInfo, ti *livekit.TrackInfo) *RoomEvent {
+	ev := t.participantEvent(eventType, pi)
+	ev.TrackSID = ti.Sid
+	ev.TrackName = ti.Name
+	ev.TrackType = ti.Type.String()
+	ev.TrackSource = ti.Source.String()
+	return ev
+}
+
+// ------------------------------------------------
+
+type GetRoomTimelineRequest struct {
+	Room string `json:"room"`
+	// unix milliseconds, zero leaves the range open
+	StartMs int64 `json:"start_ms,omitempty"`
+	EndMs   int64 `json:"end_ms,omitempty"`
+	Limit   int   `json:"limit,omitempty"`
+}
+
+type GetRoomTimelineResponse struct {
+	Events []*RoomEvent `json:"events"`
+}
+
+// TimelineService serves the persisted room timelines. Requests are JSON posted to /timeline/<Method>
+// and require the roomAdmin grant for the room
+type TimelineService struct {
+	store RoomTimelineStore
+}
+
+func NewTimelineService(store RoomTimelineStore) *TimelineService {
+	return &TimelineService{
+		store: store,
+	}
+}
+
+func (s *TimelineService) PathPrefix() string {
+	return timelinePathPrefix
+}
+
+func (s *TimelineService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &GetRoomTimelineRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	roomName := livekit.RoomName(req.Room)
+	if err := EnsureAdminPermission(r.Context(), roomName); err != nil {
+		handleError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, timelinePathPrefix)
+	if method != "GetRoomTimeline" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.store == nil {
+		handleError(w, http.StatusNotImplemented, ErrTimelineNotEnabled)
+		return
+	}
+	if req.Limit < 0 || req.StartMs < 0 || req.EndMs < 0 || (req.EndMs != 0 && req.EndMs < req.StartMs) {
+		handleError(w, http.StatusBadRequest, ErrInvalidTimelineRange)
+		return
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultTimelineLimit
+	} else if limit > maxTimelineLimit {
+		limit = maxTimelineLimit
+	}
+	var start, end time.Time
+	if req.StartMs != 0 {
+		start = time.UnixMilli(req.StartMs)
+	}
+	if req.EndMs != 0 {
+		end = time.UnixMilli(req.EndMs)
+	}
+
+	events, err := s.store.ListRoomEvents(r.Context(), roomName, start, end, limit)
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, err, "room", roomName)
+		return
+	}
+	if events == nil {
+		events = []*RoomEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&GetRoomTimelineResponse{Events: events})
+}
@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/service"
+)
+
+func TestRoomTimeline(t *testing.T) {
+	store := service.NewLocalTimelineStore(config.TimelineConfig{})
+	room := &livekit.Room{Sid: "RM_1", Name: "room"}
+	timeline := service.NewRoomTimeline(store, room, logger.GetLogger())
+
+	p := &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", State: livekit.ParticipantInfo_JOINING}
+	timeline.ParticipantChanged(p)
+
+	p = &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", State: livekit.ParticipantInfo_ACTIVE}
+	timeline.ParticipantChanged(p)
+
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE}
+	p = &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", State: livekit.ParticipantInfo_ACTIVE, Tracks: []*livekit.TrackInfo{track}}
+	timeline.ParticipantChanged(p)
+
+	// unrelated updates are not recorded
+	timeline.ParticipantChanged(p)
+
+	muted := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_AUDIO, Source: livekit.TrackSource_MICROPHONE, Muted: true}
+	p = &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", State: livekit.ParticipantInfo_ACTIVE, Metadata: "away", Tracks: []*livekit.TrackInfo{muted}}
+	timeline.ParticipantChanged(p)
+
+	timeline.RoomUpdated(&livekit.Room{Sid: "RM_1", Name: "room", Metadata: "topic"})
+
+	p = &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", State: livekit.ParticipantInfo_DISCONNECTED, Metadata: "away", Tracks: []*livekit.TrackInfo{muted}}
+	timeline.ParticipantChanged(p)
+
+	// joined and active in the same update
+	timeline.ParticipantChanged(&livekit.ParticipantInfo{Sid: "PA_2", Identity: "bob", State: livekit.ParticipantInfo_ACTIVE})
+	timeline.Close()
+
+	expected := []service.RoomEventType{
+		service.RoomEventRoomStarted,
+		service.RoomEventParticipantJoined,
+		service.RoomEventParticipantActive,
+		service.RoomEventTrackPublished,
+		service.RoomEventParticipantMetadataChanged,
+		service.RoomEventTrackMuted,
+		service.RoomEventRoomMetadataChanged,
+		service.RoomEventTrackUnpublished,
+		service.RoomEventParticipantLeft,
+		service.RoomEventParticipantJoined,
+		service.RoomEventParticipantActive,
+		service.RoomEventParticipantLeft,
+		service.RoomEventRoomEnded,
+	}
+	var events []*service.RoomEvent
+	require.Eventually(t, func() bool {
+		events, _ = store.ListRoomEvents(context.Background(), "room", time.Time{}, time.Time{}, 100)
+		return len(events) == len(expected)
+	}, time.Second, 10*time.Millisecond)
+
+	for i, ev := range events {
+		require.Equal(t, expected[i], ev.Type, "event %d", i)
+		require.Equal(t, "RM_1", ev.RoomSID)
+		if i > 0 {
+			require.True(t, ev.Time.After(events[i-1].Time))
+		}
+	}
+	require.Equal(t, "TR_1", events[3].TrackSID)
+	require.Equal(t, "AUDIO", events[3].TrackType)
+	require.Equal(t, "away", events[4].Metadata)
+	require.Equal(t, "topic", events[6].Metadata)
+	require.Equal(t, "bob", events[11].Identity)
+
+	// time range and limit
+	start, end := events[2].Time, events[5].Time
+	ranged, err := store.ListRoomEvents(context.Background(), "room", start, end, 100)
+	require.NoError(t, err)
+	require.Len(t, ranged, 3)
+	require.Equal(t, service.RoomEventParticipantActive, ranged[0].Type)
+
+	limited, err := store.ListRoomEvents(context.Background(), "room", start, time.Time{}, 2)
+	require.NoError(t, err)
+	require.Len(t, limited, 2)
+}
+
+func TestLocalTimelineStoreMaxEvents(t *testing.T) {
+	store := service.NewLocalTimelineStore(config.TimelineConfig{MaxEvents: 2})
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.StoreRoomEvents(context.Background(), "room", []*service.RoomEvent{
+			{Type: service.RoomEventParticipantJoined, Time: now.Add(time.Duration(i) * time.Millisecond), Identity: string(rune('a' + i))},
+		}))
+	}
+
+	events, err := store.ListRoomEvents(context.Background(), "room", time.Time{}, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "b", events[0].Identity)
+}
+
+func TestTimelineService(t *testing.T) {
+	store := service.NewLocalTimelineStore(config.TimelineConfig{})
+	now := time.Now()
+	require.NoError(t, store.StoreRoomEvents(context.Background(), "room", []*service.RoomEvent{
+		{Type: service.RoomEventRoomStarted, Time: now.Add(-time.Minute)},
+		{Type: service.RoomEventParticipantJoined, Time: now, Identity: "alice"},
+	}))
+
+	request := func(svc *service.TimelineService, body string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+"GetRoomTimeline", strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(service.WithGrants(context.Background(), &auth.ClaimGrants{Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+	svc := service.NewTimelineService(store)
+
+	t.Run("requires room admin", func(t *testing.T) {
+		w := request(svc, `{"room": "room"}`, nil)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		w = request(svc, `{"room": "other"}`, admin)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("filters by time", func(t *testing.T) {
+		w := request(svc, `{"room": "room", "start_ms": `+strconv.FormatInt(now.Add(-time.Second).UnixMilli(), 10)+`}`, admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &service.GetRoomTimelineResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+		require.Len(t, res.Events, 1)
+		require.Equal(t, "alice", res.Events[0].Identity)
+
+		w = request(svc, `{"room": "room", "start_ms": 10, "end_ms": 5}`, admin)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		w := request(service.NewTimelineService(nil), `{"room": "room"}`, admin)
+		require.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	// RoomTimelinePrefix is a sorted set of RoomEvent JSON, scored by event time in unix microseconds
+	RoomTimelinePrefix = "room_timeline:"
+
+	defaultTimelineRetention = 7 * 24 * time.Hour
+	defaultTimelineMaxEvents = 10000
+)
+
+func timelineLimits(conf config.TimelineConfig) (time.Duration, int) {
+	retention, maxEvents := conf.Retention, conf.MaxEvents
+	if retention <= 0 {
+		retention = defaultTimelineRetention
+	}
+	if maxEvents <= 0 {
+		maxEvents = defaultTimelineMaxEvents
+	}
+	return retention, maxEvents
+}
+
+// LocalTimelineStore keeps room timelines in memory, for single node deployments
+type LocalTimelineStore struct {
+	retention time.Duration
+	maxEvents int
+
+	lock      sync.Mutex
+	timelines map[livekit.RoomName][]*RoomEvent
+}
+
+func NewLocalTimelineStore(conf config.TimelineConfig) *LocalTimelineStore {
+	retention, maxEvents := timelineLimits(conf)
+	return &LocalTimelineStore{
+		retention: retention,
+		maxEvents: maxEvents,
+		timelines: make(map[livekit.RoomName][]*RoomEvent),
+	}
+}
+
+func (s *LocalTimelineStore) StoreRoomEvents(_ context.Context, roomName livekit.RoomName, events []*RoomEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	timeline := append(s.timelines[roomName], events...)
+	if len(timeline) > s.maxEvents {
+		timeline = append([]*RoomEvent{}, timeline[len(timeline)-s.maxEvents:]...)
+	}
+	s.timelines[roomName] = timeline
+
+	// drop rooms that have been quiet for longer than retention
+	expiry := time.Now().Add(-s.retention)
+	for name, tl := range s.timelines {
+		if tl[len(tl)-1].Time.Before(expiry) {
+			delete(s.timelines, name)
+		}
+	}
+	return nil
+}
+
+func (s *LocalTimelineStore) ListRoomEvents(_ context.Context, roomName livekit.RoomName, start, end time.Time, limit int) ([]*RoomEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var events []*RoomEvent
+	for _, ev := range s.timelines[roomName] {
+		if !start.IsZero() && ev.Time.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !ev.Time.Before(end) {
+			break
+		}
+		events = append(events, ev)
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (s *LocalTimelineStore) DeleteRoomEvents(_ context.Context, roomName livekit.RoomName) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.timelines, roomName)
+	return nil
+}
+
+func (s *LocalTimelineStore) DeleteParticipantEvents(_ context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) ([]*RoomEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var kept, deleted []*RoomEvent
+	for _, ev := range s.timelines[roomName] {
+		if ev.Identity == string(identity) {
+			deleted = append(deleted, ev)
+		} else {
+			kept = append(kept, ev)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.timelines, roomName)
+	} else if len(deleted) != 0 {
+		s.timelines[roomName] = kept
+	}
+	return deleted, nil
+}
+
+// RedisTimelineStore keeps room timelines in redis, so that they are available from any node
+type RedisTimelineStore struct {
+	rc        redis.UniversalClient
+	retention time.Duration
+	maxEvents int
+}
+
+func NewRedisTimelineStore(rc redis.UniversalClient, conf config.TimelineConfig) *RedisTimelineStore {
+	retention, maxEvents := timelineLimits(conf)
+	return &RedisTimelineStore{
+		rc:        rc,
+		retention: retention,
+		maxEvents: maxEvents,
+	}
+}
+
+func (s *RedisTimelineStore) StoreRoomEvents(ctx context.Context, roomName livekit.RoomName, events []*RoomEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	members := make([]redis.Z, 0, len(events))
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		members = append(members, redis.Z{Score: float64(ev.Time.UnixMicro()), Member: data})
+	}
+
+	key := RoomTimelinePrefix + string(roomName)
+	pp := s.rc.Pipeline()
+	pp.ZAdd(ctx, key, members...)
+	// keep the most recent events
+	pp.ZRemRangeByRank(ctx, key, 0, int64(-s.maxEvents-1))
+	pp.Expire(ctx, key, s.retention)
+	_, err := pp.Exec(ctx)
+	return err
+}
+
+func (s *RedisTimelineStore) ListRoomEvents(ctx context.Context, roomName livekit.RoomName, start, end time.Time, limit int) ([]*RoomEvent, error) {
+	rangeBy := &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "+inf",
+		Count: int64(limit),
+	}
+	if !start.IsZero() {
+		rangeBy.Min = strconv.FormatInt(start.UnixMicro(), 10)
+	}
+	if !end.IsZero() {
+		rangeBy.Max = "(" + strconv.FormatInt(end.UnixMicro(), 10)
+	}
+
+	data, err := s.rc.ZRangeByScore(ctx, RoomTimelinePrefix+string(roomName), rangeBy).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*RoomEvent, 0, len(data))
+	for _, d := range data {
+		ev := &RoomEvent{}
+		if err = json.Unmarshal([]byte(d), ev); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func (s *RedisTimelineStore) DeleteRoomEvents(ctx context.Context, roomName livekit.RoomName) error {
+	return s.rc.Del(ctx, RoomTimelinePrefix+string(roomName)).Err()
+}
+
+func (s *RedisTimelineStore) DeleteParticipantEvents(ctx context.Context, roomName livekit.RoomName, identity livekit.ParticipantIdentity) ([]*RoomEvent, error) {
+	key := RoomTimelinePrefix + string(roomName)
+	data, err := s.rc.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []*RoomEvent
+	var members []interface{}
+	for _, d := range data {
+		ev := &RoomEvent{}
+		if err = json.Unmarshal([]byte(d), ev); err != nil {
+			return nil, err
+		}
+		if ev.Identity == string(identity) {
+			deleted = append(deleted, ev)
+			members = append(members, d)
+		}
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	if err = s.rc.ZRem(ctx, key, members...).Err(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	trackSyncPathPrefix = "/track_sync/"
+
+	defaultTrackSyncTimeout = 5 * time.Second
+	maxTrackSyncTimeout     = 30 * time.Second
+)
+
+var ErrInvalidTrackSyncTimeout = errors.New("timeout cannot be negative")
+
+type DeclareTrackSyncGroupRequest struct {
+	Room     string `json:"room"`
+	Identity string `json:"identity"`
+	// names or track IDs of tracks yet to be published
+	Tracks []string `json:"tracks"`
+	// forwarding starts regardless this long after the first packet of any of the tracks, 5s when 0, at most 30s
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+}
+
+type DeclareTrackSyncGroupResponse struct {
+	TimeoutMs int64 `json:"timeout_ms"`
+}
+
+// TrackSyncService lets a publisher declare tracks that have to start forwarding together, e.g. the audio and video
+// of a clip, so that subscribers do not get one before the other. The declaration is made before the tracks are
+// published. RTMP ingest publishes as soon as the encoder connects, its tracks cannot be declared. Requests are JSON
+// posted to /track_sync/DeclareTrackSyncGroup on the node hosting the room and require the roomAdmin grant, or a
+// token of the participant itself with the canPublish grant
+type TrackSyncService struct {
+	roomManager *RoomManager
+}
+
+func NewTrackSyncService(roomManager *RoomManager) *TrackSyncService {
+	return &TrackSyncService{
+		roomManager: roomManager,
+	}
+}
+
+func (s *TrackSyncService) PathPrefix() string {
+	return trackSyncPathPrefix
+}
+
+func (s *TrackSyncService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, trackSyncPathPrefix) {
+	case "DeclareTrackSyncGroup":
+		s.declareTrackSyncGroup(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *TrackSyncService) declareTrackSyncGroup(w http.ResponseWriter, r *http.Request) {
+	req := &DeclareTrackSyncGroupRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		handleError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := ensureTrackSyncPermission(r.Context(), req); err != nil {
+		handleError(w, http.StatusUnauthorized, err, "room", req.Room, "participant", req.Identity)
+		return
+	}
+
+	res, err := s.DeclareTrackSyncGroup(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTrackSyncTimeout), errors.Is(err, rtc.ErrInvalidTrackSyncGroup):
+			handleError(w, http.StatusBadRequest, err, "room", req.Room, "participant", req.Identity)
+		case errors.Is(err, rtc.ErrTrackSyncGroupConflict):
+			handleError(w, http.StatusConflict, err, "room", req.Room, "participant", req.Identity)
+		case errors.Is(err, ErrRoomNotFound), errors.Is(err, ErrParticipantNotFound):
+			handleError(w, http.StatusNotFound, err, "room", req.Room, "participant", req.Identity)
+		default:
+			handleError(w, http.StatusInternalServerError, err, "room", req.Room, "participant", req.Identity)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// DeclareTrackSyncGroup holds forwarding of the declared tracks of a participant on this node until all of them are
+// ready
+func (s *TrackSyncService) DeclareTrackSyncGroup(ctx context.Context, req *DeclareTrackSyncGroupRequest) (*DeclareTrackSyncGroupResponse, error) {
+	if req.TimeoutMs < 0 {
+		return nil, ErrInvalidTrackSyncTimeout
+	}
+	timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+	if timeout == 0 {
+		timeout = defaultTrackSyncTimeout
+	} else if timeout > maxTrackSyncTimeout {
+		timeout = maxTrackSyncTimeout
+	}
+
+	room := s.roomManager.GetRoom(ctx, livekit.RoomName(req.Room))
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	participant := room.GetParticipant(livekit.ParticipantIdentity(req.Identity))
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+
+	if err := participant.DeclareTrackSyncGroup(req.Tracks, timeout); err != nil {
+		return nil, err
+	}
+	return &DeclareTrackSyncGroupResponse{TimeoutMs: timeout.Milliseconds()}, nil
+}
+
+// ensureTrackSyncPermission allows room admins, and participants declaring their own tracks
+func ensureTrackSyncPermission(ctx context.Context, req *DeclareTrackSyncGroupRequest) error {
+	if EnsureAdminPermission(ctx, livekit.RoomName(req.Room)) == nil {
+		return nil
+	}
+
+	claims := GetGrants(ctx)
+	if claims == nil || claims.Video == nil {
+		return ErrPermissionDenied
+	}
+	if !claims.Video.RoomJoin || claims.Video.Room != req.Room || claims.Identity != req.Identity || !claims.Video.GetCanPublish() {
+		return ErrPermissionDenied
+	}
+	return nil
+}
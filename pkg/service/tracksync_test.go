@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
+)
+
+func TestTrackSyncService(t *testing.T) {
+	room := rtc.NewRoom(
+		&livekit.Room{Name: "room"},
+		nil,
+		rtc.WebRTCConfig{},
+		&config.AudioConfig{UpdateInterval: 500},
+		&livekit.ServerInfo{},
+		&telemetryfakes.FakeTelemetryService{},
+		nil,
+	)
+	defer room.Close()
+	svc := NewTrackSyncService(&RoomManager{
+		currentNode: &livekit.Node{Id: "node"},
+		rooms:       map[livekit.RoomName]*rtc.Room{"room": room},
+	})
+
+	alice := &typesfakes.FakeLocalParticipant{}
+	alice.IDReturns("PA_alice")
+	alice.IdentityReturns("alice")
+	alice.StateReturns(livekit.ParticipantInfo_ACTIVE)
+	alice.ToProtoReturns(&livekit.ParticipantInfo{Sid: "PA_alice", Identity: "alice", State: livekit.ParticipantInfo_ACTIVE})
+	require.NoError(t, room.Join(alice, nil, nil, nil))
+
+	request := func(body string, identity string, grant *auth.VideoGrant) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, svc.PathPrefix()+"DeclareTrackSyncGroup", strings.NewReader(body))
+		if grant != nil {
+			r = r.WithContext(WithGrants(context.Background(), &auth.ClaimGrants{Identity: identity, Video: grant}))
+		}
+		w := httptest.NewRecorder()
+		svc.ServeHTTP(w, r)
+		return w
+	}
+	admin := &auth.VideoGrant{RoomAdmin: true, Room: "room"}
+	body := `{"room": "room", "identity": "alice", "tracks": ["mic", "camera"]}`
+
+	t.Run("requires admin or the publisher itself", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, request(body, "", nil).Code)
+		require.Equal(t, http.StatusUnauthorized, request(body, "bob", &auth.VideoGrant{RoomJoin: true, Room: "room"}).Code)
+		subscriber := &auth.VideoGrant{RoomJoin: true, Room: "room"}
+		subscriber.SetCanPublish(false)
+		require.Equal(t, http.StatusUnauthorized, request(body, "alice", subscriber).Code)
+		require.Equal(t, http.StatusUnauthorized, request(body, "", &auth.VideoGrant{RoomAdmin: true, Room: "other"}).Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, request(`{"room": "room", "identity": "bob", "tracks": ["mic", "camera"]}`, "", admin).Code)
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, request(`{"room": "room", "identity": "alice", "tracks": ["mic", "camera"], "timeout_ms": -1}`, "", admin).Code)
+	})
+
+	t.Run("declares", func(t *testing.T) {
+		w := request(body, "alice", &auth.VideoGrant{RoomJoin: true, Room: "room"})
+		require.Equal(t, http.StatusOK, w.Code)
+		res := &DeclareTrackSyncGroupResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(res))
+		require.Equal(t, defaultTrackSyncTimeout.Milliseconds(), res.TimeoutMs)
+
+		tracks, timeout := alice.DeclareTrackSyncGroupArgsForCall(0)
+		require.Equal(t, []string{"mic", "camera"}, tracks)
+		require.Equal(t, defaultTrackSyncTimeout, timeout)
+	})
+
+	t.Run("timeout is capped", func(t *testing.T) {
+		w := request(`{"room": "room", "identity": "alice", "tracks": ["mic", "camera"], "timeout_ms": 600000}`, "", admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		_, timeout := alice.DeclareTrackSyncGroupArgsForCall(1)
+		require.Equal(t, maxTrackSyncTimeout, timeout)
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		alice.DeclareTrackSyncGroupReturns(rtc.ErrTrackSyncGroupConflict)
+		require.Equal(t, http.StatusConflict, request(body, "", admin).Code)
+		alice.DeclareTrackSyncGroupReturns(rtc.ErrInvalidTrackSyncGroup)
+		require.Equal(t, http.StatusBadRequest, request(body, "", admin).Code)
+	})
+}
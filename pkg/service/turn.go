@@ -1,15 +1,17 @@
 package service
 
 import (
-	"context"
 	"crypto/tls"
 	"net"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/pion/turn/v2"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 
-	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/logger/pionlogger"
 
@@ -24,9 +26,11 @@ const (
 	allocateRetries = 50
 	turnMinPort     = 1024
 	turnMaxPort     = 30000
+
+	defaultTURNCertCacheDir = "turn-certs"
 )
 
-func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone bool) (*turn.Server, error) {
+func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, quota *TURNQuota, standalone bool) (*turn.Server, error) {
 	turnConf := conf.TURN
 	if !turnConf.Enabled {
 		return nil, nil
@@ -65,23 +69,48 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 			return nil, errors.New("TURN domain is not correct")
 		}
 
-		if !turnConf.ExternalTLS {
-			cert, err := tls.LoadX509KeyPair(turnConf.CertFile, turnConf.KeyFile)
-			if err != nil {
-				return nil, errors.Wrap(err, "TURN tls cert required")
-			}
+		if turnConf.TLSAuto.Enabled && turnConf.ExternalTLS {
+			return nil, errors.New("TURN tls_auto cannot be used with external_tls")
+		}
 
-			tlsListener, err := tls.Listen("tcp4", "0.0.0.0:"+strconv.Itoa(turnConf.TLSPort),
-				&tls.Config{
+		if !turnConf.ExternalTLS {
+			var tlsConfig *tls.Config
+			var certManager *autocert.Manager
+			if turnConf.TLSAuto.Enabled {
+				var err error
+				if certManager, err = newTURNCertManager(turnConf); err != nil {
+					return nil, err
+				}
+				tlsConfig = certManager.TLSConfig()
+				tlsConfig.MinVersion = tls.VersionTLS12
+			} else {
+				cert, err := tls.LoadX509KeyPair(turnConf.CertFile, turnConf.KeyFile)
+				if err != nil {
+					return nil, errors.Wrap(err, "TURN tls cert required")
+				}
+				tlsConfig = &tls.Config{
 					MinVersion:   tls.VersionTLS12,
 					Certificates: []tls.Certificate{cert},
-				})
+				}
+			}
+
+			tlsListener, err := tls.Listen("tcp4", "0.0.0.0:"+strconv.Itoa(turnConf.TLSPort), tlsConfig)
 			if err != nil {
 				return nil, errors.Wrap(err, "could not listen on TURN TCP port")
 			}
+			if certManager != nil {
+				if err = startTURNCertManager(certManager, turnConf); err != nil {
+					_ = tlsListener.Close()
+					return nil, err
+				}
+				logValues = append(logValues, "turn.tlsAuto", true)
+			}
 			if standalone {
 				tlsListener = telemetry.NewListener(tlsListener)
 			}
+			if quota != nil {
+				tlsListener = quota.WrapListener(tlsListener)
+			}
 
 			listenerConfig := turn.ListenerConfig{
 				Listener:              tlsListener,
@@ -96,6 +125,9 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 			if standalone {
 				tcpListener = telemetry.NewListener(tcpListener)
 			}
+			if quota != nil {
+				tcpListener = quota.WrapListener(tcpListener)
+			}
 
 			listenerConfig := turn.ListenerConfig{
 				Listener:              tcpListener,
@@ -115,6 +147,9 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 		if standalone {
 			udpListener = telemetry.NewPacketConn(udpListener, prometheus.Incoming)
 		}
+		if quota != nil {
+			udpListener = quota.WrapPacketConn(udpListener)
+		}
 
 		packetConfig := turn.PacketConnConfig{
 			PacketConn:            udpListener,
@@ -128,14 +163,72 @@ func NewTurnServer(conf *config.Config, authHandler turn.AuthHandler, standalone
 	return turn.NewServer(serverConfig)
 }
 
-func newTurnAuthHandler(roomStore ObjectStore) turn.AuthHandler {
-	return func(username, realm string, srcAddr net.Addr) (key []byte, ok bool) {
-		// room id should be the username, create a hashed room id
-		rm, _, err := roomStore.LoadRoom(context.Background(), livekit.RoomName(username), false)
+// newTURNCertManager obtains the certificate of the TURN domain when it's first needed, and renews it in the
+// background
+func newTURNCertManager(turnConf config.TURNConfig) (*autocert.Manager, error) {
+	autoConf := turnConf.TLSAuto
+	if autoConf.HTTPPort <= 0 && turnConf.TLSPort != 443 {
+		return nil, errors.New("TURN tls_auto requires tls_port 443 or http_port to validate the domain")
+	}
+
+	cacheDir := autoConf.CacheDir
+	if cacheDir == "" {
+		cacheDir = defaultTURNCertCacheDir
+	}
+	m := &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Cache:       autocert.DirCache(cacheDir),
+		HostPolicy:  autocert.HostWhitelist(turnConf.Domain),
+		Email:       autoConf.Email,
+		RenewBefore: autoConf.RenewBefore,
+	}
+	if autoConf.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: autoConf.DirectoryURL}
+	}
+	return m, nil
+}
+
+// startTURNCertManager answers HTTP-01 challenges when configured, and requests the certificate right away so
+// that the first TURN client doesn't wait for it
+func startTURNCertManager(m *autocert.Manager, turnConf config.TURNConfig) error {
+	if port := turnConf.TLSAuto.HTTPPort; port > 0 {
+		ln, err := net.Listen("tcp", ":"+strconv.Itoa(port))
 		if err != nil {
-			return nil, false
+			return errors.Wrap(err, "could not listen on TURN ACME HTTP port")
+		}
+		go func() {
+			// only challenges are answered, other requests are redirected to https
+			if err := http.Serve(ln, m.HTTPHandler(nil)); err != nil {
+				logger.Warnw("TURN ACME HTTP server stopped", err)
+			}
+		}()
+	}
+
+	go func() {
+		hello := &tls.ClientHelloInfo{
+			ServerName:        turnConf.Domain,
+			CipherSuites:      []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			SupportedVersions: []uint16{tls.VersionTLS12},
+		}
+		if _, err := m.GetCertificate(hello); err != nil {
+			logger.Errorw("could not obtain TURN certificate", err, "domain", turnConf.Domain)
+		}
+	}()
+	return nil
+}
+
+func newTurnAuthHandler(credentials *TURNCredentials, quota *TURNQuota) turn.AuthHandler {
+	return func(username, realm string, srcAddr net.Addr) (key []byte, ok bool) {
+		now := time.Now()
+		key, ok = credentials.AuthKey(username, now)
+		if !ok || quota == nil {
+			return
 		}
 
-		return turn.GenerateAuthKey(username, LivekitRealm, rm.TurnPassword), true
+		_, participantID, _ := parseTURNUsername(username)
+		if !quota.Authorize(participantID, srcAddr, now) {
+			return nil, false
+		}
+		return key, true
 	}
 }
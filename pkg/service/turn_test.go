@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestTURNAutoTLS(t *testing.T) {
+	turnConf := func(modify func(c *config.TURNConfig)) *config.Config {
+		conf := &config.Config{
+			TURN: config.TURNConfig{
+				Enabled: true,
+				Domain:  "turn.myhost.com",
+				TLSPort: 5349,
+				TLSAuto: config.TURNAutoTLSConfig{Enabled: true},
+			},
+		}
+		modify(&conf.TURN)
+		return conf
+	}
+
+	t.Run("not with external tls", func(t *testing.T) {
+		_, err := NewTurnServer(turnConf(func(c *config.TURNConfig) { c.ExternalTLS = true }), nil, nil, false)
+		require.Error(t, err)
+	})
+
+	t.Run("domain has to be validated", func(t *testing.T) {
+		_, err := NewTurnServer(turnConf(func(c *config.TURNConfig) {}), nil, nil, false)
+		require.Error(t, err)
+	})
+
+	t.Run("certificate manager", func(t *testing.T) {
+		conf := turnConf(func(c *config.TURNConfig) {
+			c.TLSPort = 443
+			c.TLSAuto.DirectoryURL = "https://acme.myhost.com/directory"
+		})
+		m, err := newTURNCertManager(conf.TURN)
+		require.NoError(t, err)
+		require.Equal(t, "https://acme.myhost.com/directory", m.Client.DirectoryURL)
+		// answers TLS-ALPN-01 challenges
+		require.Contains(t, m.TLSConfig().NextProtos, "acme-tls/1")
+		require.Error(t, m.HostPolicy(nil, "other.myhost.com"))
+		require.NoError(t, m.HostPolicy(nil, "turn.myhost.com"))
+	})
+}
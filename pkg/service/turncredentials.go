@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pion/turn/v2"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	turnSessionSecretSize = 32
+	// TURN requests are authenticated synchronously, a slow store should not hold the TURN server up for long
+	turnStoreTimeout = 2 * time.Second
+)
+
+// TURNCredentials issues the credentials participants authenticate with at TURN servers. They are per participant
+// session and expire after the configured TTL. Those of the embedded TURN server are also revoked when the
+// participant disconnects, so leaked connection details are of no use once the session is over. The secrets of
+// sessions are kept in the room store, so that with a TURN domain balanced across nodes, the node a participant
+// relays through validates the credentials issued by the node it joined.
+//
+// Credentials follow the TURN REST API scheme: the username is the expiry time and the participant ID, the credential
+// an HMAC of the username. This lets TURN servers configured with a shared secret validate them as well
+type TURNCredentials struct {
+	current *config.Current
+	store   ObjectStore
+}
+
+func NewTURNCredentials(current *config.Current, store ObjectStore) *TURNCredentials {
+	return &TURNCredentials{
+		current: current,
+		store:   store,
+	}
+}
+
+// Issue returns credentials of the embedded TURN server for a participant session. A participant reconnecting is
+// issued new credentials of the same session, those issued before stay valid till they expire
+func (c *TURNCredentials) Issue(ctx context.Context, participantID livekit.ParticipantID) (username string, credential string, err error) {
+	ttl := c.current.Get().TURN.CredentialTTL
+	secret, err := c.store.LoadTURNSession(ctx, participantID)
+	if err != nil {
+		return "", "", err
+	}
+	if secret == nil {
+		secret = make([]byte, turnSessionSecretSize)
+		if _, err = rand.Read(secret); err != nil {
+			return "", "", err
+		}
+	}
+	// kept till the credentials issued last expire
+	if err = c.store.StoreTURNSession(ctx, participantID, secret, ttl); err != nil {
+		return "", "", err
+	}
+
+	username = turnUsername(participantID, time.Now().Add(ttl))
+	return username, turnCredential(secret, username), nil
+}
+
+// IssueWithSecret returns credentials for a participant at a TURN server configured with a shared secret
+func (c *TURNCredentials) IssueWithSecret(participantID livekit.ParticipantID, secret string) (username string, credential string) {
+	username = turnUsername(participantID, time.Now().Add(c.current.Get().TURN.CredentialTTL))
+	return username, turnCredential([]byte(secret), username)
+}
+
+// Revoke invalidates the credentials of the embedded TURN server issued for a participant session
+func (c *TURNCredentials) Revoke(ctx context.Context, participantID livekit.ParticipantID) error {
+	return c.store.DeleteTURNSession(ctx, participantID)
+}
+
+// AuthKey returns the key a TURN request of a user is authenticated with, ok is false when the credentials of the
+// user have expired or have been revoked
+func (c *TURNCredentials) AuthKey(username string, now time.Time) (key []byte, ok bool) {
+	expiry, participantID, ok := parseTURNUsername(username)
+	if !ok || now.After(expiry) {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), turnStoreTimeout)
+	defer cancel()
+	secret, err := c.store.LoadTURNSession(ctx, participantID)
+	if err != nil {
+		logger.Warnw("could not load TURN session", err, "pID", participantID)
+		return nil, false
+	}
+	if secret == nil {
+		return nil, false
+	}
+
+	return turn.GenerateAuthKey(username, LivekitRealm, turnCredential(secret, username)), true
+}
+
+func turnUsername(participantID livekit.ParticipantID, expiry time.Time) string {
+	return strconv.FormatInt(expiry.Unix(), 10) + ":" + string(participantID)
+}
+
+func parseTURNUsername(username string) (expiry time.Time, participantID livekit.ParticipantID, ok bool) {
+	parts := strings.SplitN(username, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return
+	}
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+	return time.Unix(unix, 0), livekit.ParticipantID(parts[1]), true
+}
+
+func turnCredential(secret []byte, username string) string {
+	mac := hmac.New(sha1.New, secret)
+	mac.Write([]byte(username))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/turn/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestTURNCredentials(t *testing.T) {
+	conf := &config.Config{}
+	conf.TURN.CredentialTTL = time.Hour
+	store := NewLocalStore()
+	c := NewTURNCredentials(config.NewCurrent(conf), store)
+	ctx := context.Background()
+	now := time.Now()
+
+	username, credential, err := c.Issue(ctx, "PA_alice")
+	require.NoError(t, err)
+	require.NotEmpty(t, credential)
+
+	key, ok := c.AuthKey(username, now)
+	require.True(t, ok)
+	require.Equal(t, turn.GenerateAuthKey(username, LivekitRealm, credential), key)
+
+	t.Run("valid on other nodes", func(t *testing.T) {
+		other := NewTURNCredentials(config.NewCurrent(conf), store)
+		otherKey, ok := other.AuthKey(username, now)
+		require.True(t, ok)
+		require.Equal(t, key, otherKey)
+	})
+
+	t.Run("scoped to the participant", func(t *testing.T) {
+		other, _, err := c.Issue(ctx, "PA_bob")
+		require.NoError(t, err)
+		otherKey, ok := c.AuthKey(other, now)
+		require.True(t, ok)
+		require.NotEqual(t, turn.GenerateAuthKey(other, LivekitRealm, credential), otherKey)
+
+		// the expiry of a username is covered by the credential
+		extended := turnUsername("PA_alice", now.Add(48*time.Hour))
+		extendedKey, ok := c.AuthKey(extended, now)
+		require.True(t, ok)
+		require.NotEqual(t, turn.GenerateAuthKey(extended, LivekitRealm, credential), extendedKey)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		_, ok := c.AuthKey(username, now.Add(2*time.Hour))
+		require.False(t, ok)
+	})
+
+	t.Run("revoked on disconnect", func(t *testing.T) {
+		reissued, _, err := c.Issue(ctx, "PA_alice")
+		require.NoError(t, err)
+		require.NoError(t, c.Revoke(ctx, "PA_alice"))
+		_, ok := c.AuthKey(username, now)
+		require.False(t, ok)
+		_, ok = c.AuthKey(reissued, now)
+		require.False(t, ok)
+	})
+
+	t.Run("malformed usernames", func(t *testing.T) {
+		for _, u := range []string{"", "room", "soon:PA_bob", "123:"} {
+			_, ok := c.AuthKey(u, now)
+			require.False(t, ok, u)
+		}
+	})
+
+	t.Run("shared secret", func(t *testing.T) {
+		username, credential := c.IssueWithSecret("PA_carol", "secret")
+		expiry, participantID, ok := parseTURNUsername(username)
+		require.True(t, ok)
+		require.EqualValues(t, "PA_carol", participantID)
+		require.WithinDuration(t, now.Add(time.Hour), expiry, 2*time.Second)
+		require.Equal(t, turnCredential([]byte("secret"), username), credential)
+	})
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+)
+
+const (
+	// clients refresh their allocations and permissions well within this, an address not authenticating for longer
+	// has no allocation left
+	turnAllocationIdleTimeout = 10 * time.Minute
+
+	turnLimitParticipantAllocations = "participant_allocations"
+	turnLimitRoomAllocations        = "room_allocations"
+	turnLimitParticipantBandwidth   = "participant_bandwidth"
+	turnLimitRoomBandwidth          = "room_bandwidth"
+)
+
+// TURNQuota limits the relay allocations and bandwidth of the embedded TURN server per participant and per room.
+//
+// The TURN server does not tell about allocations, they are accounted by client address instead: an address
+// authenticating for the first time with the credentials of a participant is a new allocation, which is refused
+// when over the limit, and it is released when the address stays silent for longer than allocations live without
+// a refresh. Relayed traffic passes between the clients and the server on the listeners of the server, it is metered
+// there by client address
+type TURNQuota struct {
+	conf config.TURNQuotaConfig
+
+	lock     sync.RWMutex
+	sessions map[livekit.ParticipantID]*turnQuotaSession
+	rooms    map[livekit.RoomName]*turnQuotaRoom
+	clients  map[string]*turnQuotaClient
+}
+
+type turnQuotaUsage struct {
+	allocations int
+	limiter     *bandwidthLimiter
+}
+
+type turnQuotaRoom struct {
+	usage    turnQuotaUsage
+	sessions int
+}
+
+type turnQuotaSession struct {
+	roomName livekit.RoomName
+	identity livekit.ParticipantIdentity
+	usage    turnQuotaUsage
+}
+
+type turnQuotaClient struct {
+	session  *turnQuotaSession
+	lastSeen time.Time
+}
+
+// NewTURNQuota creates the quota of the configuration, nil when it has no limits
+func NewTURNQuota(conf *config.Config) *TURNQuota {
+	quotaConf := conf.TURN.Quota
+	if quotaConf.MaxAllocationsPerParticipant <= 0 && quotaConf.MaxAllocationsPerRoom <= 0 &&
+		quotaConf.MaxBitratePerParticipant <= 0 && quotaConf.MaxBitratePerRoom <= 0 {
+		return nil
+	}
+
+	return &TURNQuota{
+		conf:     quotaConf,
+		sessions: make(map[livekit.ParticipantID]*turnQuotaSession),
+		rooms:    make(map[livekit.RoomName]*turnQuotaRoom),
+		clients:  make(map[string]*turnQuotaClient),
+	}
+}
+
+// AddParticipant accounts the relay usage of a participant session to the participant and the room
+func (q *TURNQuota) AddParticipant(roomName livekit.RoomName, identity livekit.ParticipantIdentity, participantID livekit.ParticipantID) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.sessions[participantID] != nil {
+		return
+	}
+	room := q.rooms[roomName]
+	if room == nil {
+		room = &turnQuotaRoom{usage: turnQuotaUsage{limiter: newBandwidthLimiter(q.conf.MaxBitratePerRoom)}}
+		q.rooms[roomName] = room
+	}
+	room.sessions++
+	q.sessions[participantID] = &turnQuotaSession{
+		roomName: roomName,
+		identity: identity,
+		usage:    turnQuotaUsage{limiter: newBandwidthLimiter(q.conf.MaxBitratePerParticipant)},
+	}
+}
+
+// RemoveParticipant releases the allocations of a participant session that is over
+func (q *TURNQuota) RemoveParticipant(participantID livekit.ParticipantID) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	session := q.sessions[participantID]
+	if session == nil {
+		return
+	}
+	for addr, client := range q.clients {
+		if client.session == session {
+			q.releaseLocked(addr, client)
+		}
+	}
+	delete(q.sessions, participantID)
+
+	if room := q.rooms[session.roomName]; room != nil {
+		room.sessions--
+		if room.sessions <= 0 {
+			delete(q.rooms, session.roomName)
+		}
+	}
+	prometheus.SetTURNAllocations(len(q.clients))
+}
+
+// Authorize tells whether a participant, whose credentials are valid, may send a request from a client address. The
+// first request of an address allocates and is refused when the participant or its room is out of allocations
+func (q *TURNQuota) Authorize(participantID livekit.ParticipantID, addr net.Addr, now time.Time) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	session := q.sessions[participantID]
+	if session == nil {
+		// not joined on this node, nothing to account to
+		return true
+	}
+
+	key := addr.String()
+	if client := q.clients[key]; client != nil {
+		if client.session == session {
+			client.lastSeen = now
+			return true
+		}
+		// the address was reused by another client
+		q.releaseLocked(key, client)
+	}
+
+	q.expireLocked(now)
+
+	room := q.rooms[session.roomName]
+	if max := q.conf.MaxAllocationsPerParticipant; max > 0 && session.usage.allocations >= max {
+		q.deny(session, turnLimitParticipantAllocations)
+		return false
+	}
+	if max := q.conf.MaxAllocationsPerRoom; max > 0 && room != nil && room.usage.allocations >= max {
+		q.deny(session, turnLimitRoomAllocations)
+		return false
+	}
+
+	q.clients[key] = &turnQuotaClient{
+		session:  session,
+		lastSeen: now,
+	}
+	session.usage.allocations++
+	if room != nil {
+		room.usage.allocations++
+	}
+	prometheus.SetTURNAllocations(len(q.clients))
+	return true
+}
+
+// Reserve takes the bytes relayed to or from a client address from the bandwidth of its participant and room. When
+// over the bitrate, it returns how long to wait for the bytes to be within it, and whether waiting is any good: bytes
+// which are not waited for are dropped and not taken
+func (q *TURNQuota) Reserve(addr net.Addr, bytes int, now time.Time, wait bool) (time.Duration, bool) {
+	q.lock.RLock()
+	client := q.clients[addr.String()]
+	var room *turnQuotaRoom
+	if client != nil {
+		room = q.rooms[client.session.roomName]
+	}
+	q.lock.RUnlock()
+	if client == nil {
+		// not allocated, e. g. binding requests
+		return 0, true
+	}
+
+	delay, ok := client.session.usage.limiter.take(bytes, now, wait)
+	if !ok {
+		prometheus.RecordTURNBytesDropped(turnLimitParticipantBandwidth, bytes)
+		return 0, false
+	}
+	if room != nil {
+		roomDelay, ok := room.usage.limiter.take(bytes, now, wait)
+		if !ok {
+			prometheus.RecordTURNBytesDropped(turnLimitRoomBandwidth, bytes)
+			return 0, false
+		}
+		if roomDelay > delay {
+			delay = roomDelay
+		}
+	}
+	return delay, true
+}
+
+func (q *TURNQuota) deny(session *turnQuotaSession, limit string) {
+	logger.Infow("TURN allocation denied",
+		"room", session.roomName,
+		"participant", session.identity,
+		"limit", limit,
+	)
+	prometheus.RecordTURNAllocationDenied(limit)
+}
+
+func (q *TURNQuota) expireLocked(now time.Time) {
+	for addr, client := range q.clients {
+		if now.Sub(client.lastSeen) > turnAllocationIdleTimeout {
+			q.releaseLocked(addr, client)
+		}
+	}
+}
+
+func (q *TURNQuota) releaseLocked(addr string, client *turnQuotaClient) {
+	delete(q.clients, addr)
+	client.session.usage.allocations--
+	if room := q.rooms[client.session.roomName]; room != nil {
+		room.usage.allocations--
+	}
+}
+
+// WrapPacketConn meters the packets of a UDP listener, dropping those over the bitrate
+func (q *TURNQuota) WrapPacketConn(conn net.PacketConn) net.PacketConn {
+	return &turnQuotaPacketConn{PacketConn: conn, quota: q}
+}
+
+// WrapListener meters the connections of a TCP listener, delaying reads and writes over the bitrate as TCP streams
+// cannot drop any
+func (q *TURNQuota) WrapListener(listener net.Listener) net.Listener {
+	return &turnQuotaListener{Listener: listener, quota: q}
+}
+
+// ---------------------------------------------
+
+type turnQuotaPacketConn struct {
+	net.PacketConn
+	quota *TURNQuota
+}
+
+func (c *turnQuotaPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil || n == 0 {
+			return n, addr, err
+		}
+		if _, ok := c.quota.Reserve(addr, n, time.Now(), false); ok {
+			return n, addr, err
+		}
+	}
+}
+
+func (c *turnQuotaPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if _, ok := c.quota.Reserve(addr, len(p), time.Now(), false); !ok {
+		// lost on the way, as far as the sender is concerned
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+type turnQuotaListener struct {
+	net.Listener
+	quota *TURNQuota
+}
+
+func (l *turnQuotaListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &turnQuotaConn{Conn: conn, quota: l.quota}, nil
+}
+
+type turnQuotaConn struct {
+	net.Conn
+	quota *TURNQuota
+}
+
+func (c *turnQuotaConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.wait(n)
+	}
+	return n, err
+}
+
+func (c *turnQuotaConn) Write(b []byte) (int, error) {
+	c.wait(len(b))
+	return c.Conn.Write(b)
+}
+
+func (c *turnQuotaConn) wait(bytes int) {
+	if delay, _ := c.quota.Reserve(c.RemoteAddr(), bytes, time.Now(), true); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// ---------------------------------------------
+
+// bandwidthLimiter is a token bucket of bytes, holding up to a second worth of the bitrate
+type bandwidthLimiter struct {
+	lock           sync.Mutex
+	bytesPerSecond float64
+	tokens         float64
+	updatedAt      time.Time
+}
+
+func newBandwidthLimiter(bitrate int64) *bandwidthLimiter {
+	if bitrate < 0 {
+		bitrate = 0
+	}
+	return &bandwidthLimiter{
+		bytesPerSecond: float64(bitrate) / 8,
+		tokens:         float64(bitrate) / 8,
+	}
+}
+
+func (l *bandwidthLimiter) take(bytes int, now time.Time, wait bool) (time.Duration, bool) {
+	if l.bytesPerSecond == 0 {
+		return 0, true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.updatedAt.IsZero() {
+		l.tokens += now.Sub(l.updatedAt).Seconds() * l.bytesPerSecond
+		if l.tokens > l.bytesPerSecond {
+			l.tokens = l.bytesPerSecond
+		}
+	}
+	l.updatedAt = now
+
+	if l.tokens >= float64(bytes) {
+		l.tokens -= float64(bytes)
+		return 0, true
+	}
+	if !wait {
+		return 0, false
+	}
+	// borrowed from the refill to come
+	l.tokens -= float64(bytes)
+	return time.Duration(-l.tokens / l.bytesPerSecond * float64(time.Second)), true
+}
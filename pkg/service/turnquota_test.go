@@ -0,0 +1,104 @@
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestTURNQuota(t *testing.T) {
+	newQuota := func(quotaConf config.TURNQuotaConfig) *TURNQuota {
+		conf := &config.Config{}
+		conf.TURN.Quota = quotaConf
+		q := NewTURNQuota(conf)
+		require.NotNil(t, q)
+		q.AddParticipant("room", "alice", "PA_alice")
+		q.AddParticipant("room", "bob", "PA_bob")
+		return q
+	}
+	addr := func(port int) net.Addr {
+		return &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: port}
+	}
+	now := time.Now()
+
+	t.Run("no limits", func(t *testing.T) {
+		require.Nil(t, NewTURNQuota(&config.Config{}))
+	})
+
+	t.Run("limits allocations per participant", func(t *testing.T) {
+		q := newQuota(config.TURNQuotaConfig{MaxAllocationsPerParticipant: 2})
+		require.True(t, q.Authorize("PA_alice", addr(1000), now))
+		require.True(t, q.Authorize("PA_alice", addr(1001), now))
+		require.False(t, q.Authorize("PA_alice", addr(1002), now))
+		// known addresses keep their allocation
+		require.True(t, q.Authorize("PA_alice", addr(1000), now))
+		require.True(t, q.Authorize("PA_bob", addr(2000), now))
+		// participants not joined on this node are not accounted
+		require.True(t, q.Authorize("PA_carol", addr(3000), now))
+	})
+
+	t.Run("limits allocations per room", func(t *testing.T) {
+		q := newQuota(config.TURNQuotaConfig{MaxAllocationsPerRoom: 2})
+		require.True(t, q.Authorize("PA_alice", addr(1000), now))
+		require.True(t, q.Authorize("PA_bob", addr(2000), now))
+		require.False(t, q.Authorize("PA_bob", addr(2001), now))
+
+		q.AddParticipant("other", "alice", "PA_alice2")
+		require.True(t, q.Authorize("PA_alice2", addr(4000), now))
+	})
+
+	t.Run("releases allocations", func(t *testing.T) {
+		q := newQuota(config.TURNQuotaConfig{MaxAllocationsPerParticipant: 1, MaxAllocationsPerRoom: 2})
+		require.True(t, q.Authorize("PA_alice", addr(1000), now))
+		require.True(t, q.Authorize("PA_bob", addr(2000), now))
+		require.False(t, q.Authorize("PA_alice", addr(1001), now))
+
+		// idle allocations expire
+		later := now.Add(turnAllocationIdleTimeout / 2)
+		require.True(t, q.Authorize("PA_bob", addr(2000), later))
+		later = now.Add(turnAllocationIdleTimeout + time.Second)
+		require.True(t, q.Authorize("PA_alice", addr(1001), later))
+
+		// the room is full again until bob leaves
+		q.AddParticipant("room", "carol", "PA_carol")
+		require.False(t, q.Authorize("PA_carol", addr(3000), later))
+		q.RemoveParticipant("PA_bob")
+		require.True(t, q.Authorize("PA_carol", addr(3000), later))
+	})
+
+	t.Run("limits bandwidth", func(t *testing.T) {
+		// 1000 bytes per second
+		q := newQuota(config.TURNQuotaConfig{MaxBitratePerParticipant: 8000, MaxBitratePerRoom: 12000})
+		require.True(t, q.Authorize("PA_alice", addr(1000), now))
+		require.True(t, q.Authorize("PA_bob", addr(2000), now))
+
+		delay, ok := q.Reserve(addr(1000), 800, now, false)
+		require.True(t, ok)
+		require.Zero(t, delay)
+		// dropped, not taken
+		_, ok = q.Reserve(addr(1000), 400, now, false)
+		require.False(t, ok)
+		// delayed till the refill
+		delay, ok = q.Reserve(addr(1000), 400, now, true)
+		require.True(t, ok)
+		require.Equal(t, 200*time.Millisecond, delay)
+
+		// the room has 300 bytes left
+		_, ok = q.Reserve(addr(2000), 400, now, false)
+		require.False(t, ok)
+		_, ok = q.Reserve(addr(2000), 300, now, false)
+		require.True(t, ok)
+
+		// refilled after a second
+		_, ok = q.Reserve(addr(2000), 600, now.Add(time.Second), false)
+		require.True(t, ok)
+
+		// addresses without allocation are not metered
+		_, ok = q.Reserve(addr(5000), 100_000, now, false)
+		require.True(t, ok)
+	})
+}
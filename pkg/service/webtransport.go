@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/webtransport-go"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/rtc"
+)
+
+const (
+	// time for the client to open the signal stream once the session is established
+	wtStreamTimeout = 10 * time.Second
+)
+
+// WebTransportServer accepts signal connections over WebTransport on the path /rtc of a QUIC listener. quic-go does not
+// support connection migration, it disables it in its transport parameters: a client moving between networks
+// reconnects as it does over WebSocket
+type WebTransportServer struct {
+	conf       config.WebTransportConfig
+	server     *webtransport.Server
+	rtcService *RTCService
+}
+
+func NewWebTransportServer(conf *config.Config, rtcService *RTCService, keyProvider auth.KeyProvider) (*WebTransportServer, error) {
+	tlsConfig, err := NewListenerTLSConfig(conf.WebTransport.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return nil, errors.New("webtransport requires cert_file and key_file")
+	}
+	// ALPN is negotiated by http3
+	tlsConfig.NextProtos = nil
+
+	s := &WebTransportServer{
+		conf:       conf.WebTransport,
+		rtcService: rtcService,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rtc", s.handleRTC)
+	origins := NewOriginChecker(conf.CORS)
+	s.server = &webtransport.Server{
+		H3: http3.Server{
+			Handler:   configureMiddlewares(mux, apiMiddlewares(conf, keyProvider)...),
+			TLSConfig: tlsConfig,
+			QuicConfig: &quic.Config{
+				KeepAlivePeriod: pingFrequency,
+			},
+		},
+		// security is enforced by access tokens
+		CheckOrigin: origins.CheckRequest,
+	}
+	return s, nil
+}
+
+// Listen opens the UDP sockets on each address
+func (s *WebTransportServer) Listen(addresses []string) ([]net.PacketConn, error) {
+	conns := make([]net.PacketConn, 0, len(addresses))
+	for _, addr := range addresses {
+		conn, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", addr, s.conf.Port))
+		if err != nil {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+func (s *WebTransportServer) Serve(conn net.PacketConn) error {
+	return s.server.Serve(conn)
+}
+
+func (s *WebTransportServer) Close() error {
+	return s.server.Close()
+}
+
+func (s *WebTransportServer) handleRTC(w http.ResponseWriter, r *http.Request) {
+	// sessions are established with extended CONNECT
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.rtcService.serveSignal(w, r, rtc.SignalTransportWebTransport, func() (signalConnection, signalTransportConn, error) {
+		session, err := s.server.Upgrade(w, r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), wtStreamTimeout)
+		defer cancel()
+		stream, err := session.AcceptStream(ctx)
+		if err != nil {
+			logger.Debugw("webtransport client did not open signal stream", "error", err)
+			_ = session.CloseWithError(wtSessionClosed, "no signal stream")
+			return nil, nil, err
+		}
+		c := NewWTSignalConnection(session, stream)
+		return c, c, nil
+	})
+}
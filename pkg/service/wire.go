@@ -15,11 +15,15 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
+	"github.com/livekit/livekit-server/pkg/playback"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/transcoder"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/egress"
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
 	redisLiveKit "github.com/livekit/protocol/redis"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
@@ -27,8 +31,9 @@ import (
 	"github.com/livekit/psrpc"
 )
 
-func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*LivekitServer, error) {
+func InitializeServer(current *config.Current, currentNode routing.LocalNode) (*LivekitServer, error) {
 	wire.Build(
+		getConfig,
 		getNodeID,
 		createRedisClient,
 		createStore,
@@ -36,6 +41,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		createKeyProvider,
 		createWebhookNotifier,
 		createClientConfiguration,
+		createGeoIPProvider,
 		routing.CreateRouter,
 		getRoomConf,
 		config.DefaultAPIConfig,
@@ -60,7 +66,32 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 		getSignalRelayConfig,
 		NewDefaultSignalServer,
 		routing.NewSignalClient,
+		getTranscoderManager,
+		getPlaybackManager,
+		NewPlaybackService,
+		getRTMPServer,
+		createTimelineStore,
+		NewTimelineService,
+		createManifestStore,
+		NewManifestService,
+		NewRetentionService,
+		NewErasureService,
+		NewDashboardService,
+		NewLogLevelService,
+		NewRoomAccessService,
+		NewBulkAdminService,
+		NewRoomStatsService,
+		NewSubscriptionDiagnosticsService,
+		NewDownlinkSimulatorService,
+		createPresenceStore,
+		NewPresenceService,
+		NewTrackSyncService,
+		NewDrainService,
+		NewConfigService,
+		NewProfilingService,
 		NewLocalRoomManager,
+		NewTURNCredentials,
+		NewTURNQuota,
 		newTurnAuthHandler,
 		newInProcessTurnServer,
 		utils.NewDefaultTimedVersionGenerator,
@@ -76,6 +107,7 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 		getMessageBus,
 		getSignalRelayConfig,
 		routing.NewSignalClient,
+		config.NewCurrent,
 		routing.CreateRouter,
 	)
 
@@ -114,9 +146,41 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
+func getTranscoderManager(conf *config.Config, keyProvider auth.KeyProvider) *transcoder.Manager {
+	if conf.Transcoder.Port == 0 {
+		return nil
+	}
+	return transcoder.NewManager(keyProvider)
+}
+
+func getRTMPServer(conf *config.Config, roomAllocator RoomAllocator, router routing.Router, keyProvider auth.KeyProvider, ts telemetry.TelemetryService) *RTMPServer {
+	if conf.RTMP.Port == 0 {
+		return nil
+	}
+	return NewRTMPServer(conf, roomAllocator, router, keyProvider, ts)
+}
+
+func getPlaybackManager(conf *config.Config, roomAllocator RoomAllocator, router routing.Router) *playback.Manager {
+	return playback.NewManager(playback.ManagerParams{
+		Connect:          newPlaybackConnector(roomAllocator, router),
+		MaxFileSize:      conf.Playback.MaxFileSize,
+		AllowPrivateURLs: conf.Playback.AllowPrivateURLs,
+	})
+}
+
+func getConfig(current *config.Current) *config.Config {
+	return current.Get()
+}
+
+func createWebhookNotifier(current *config.Current, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
+	conf := current.Get()
 	wc := conf.WebHook
 	if len(wc.URLs) == 0 {
+		current.OnReload(func(conf *config.Config) {
+			if len(conf.WebHook.URLs) != 0 {
+				logger.Warnw("webhooks were not configured at startup, a restart is required to send them", nil)
+			}
+		})
 		return nil, nil
 	}
 	secret := provider.GetSecret(wc.APIKey)
@@ -124,7 +188,25 @@ func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webh
 		return nil, ErrWebHookMissingAPIKey
 	}
 
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+	params := telemetry.WebhookNotifierParams{
+		URLs:             wc.URLs,
+		APIKey:           wc.APIKey,
+		APISecret:        secret,
+		ReorderWindow:    wc.ReorderWindow,
+		MaxRetryDuration: wc.MaxRetryDuration,
+	}
+	if conf.Logging.PIIRedaction.Webhooks {
+		redactor, err := conf.Logging.PIIRedaction.NewRedactor()
+		if err != nil {
+			return nil, err
+		}
+		params.Redactor = redactor
+	}
+	n := telemetry.NewWebhookNotifier(params)
+	current.OnReload(func(conf *config.Config) {
+		n.SetURLs(conf.WebHook.URLs)
+	})
+	return n, nil
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
@@ -141,6 +223,36 @@ func createStore(rc redis.UniversalClient) ObjectStore {
 	return NewLocalStore()
 }
 
+func createManifestStore(conf *config.Config, rc redis.UniversalClient) RoomManifestStore {
+	if !conf.RoomManifest.Enabled {
+		return nil
+	}
+	if rc != nil {
+		return NewRedisManifestStore(rc, conf.RoomManifest)
+	}
+	return NewLocalManifestStore(conf.RoomManifest)
+}
+
+func createPresenceStore(conf *config.Config, rc redis.UniversalClient) RoomPresenceStore {
+	if !conf.Presence.Enabled {
+		return nil
+	}
+	if rc != nil {
+		return NewRedisPresenceStore(rc, conf.Presence)
+	}
+	return NewLocalPresenceStore(conf.Presence)
+}
+
+func createTimelineStore(conf *config.Config, rc redis.UniversalClient) RoomTimelineStore {
+	if !conf.Timeline.Enabled {
+		return nil
+	}
+	if rc != nil {
+		return NewRedisTimelineStore(rc, conf.Timeline)
+	}
+	return NewLocalTimelineStore(conf.Timeline)
+}
+
 func getMessageBus(rc redis.UniversalClient) psrpc.MessageBus {
 	if rc == nil {
 		return psrpc.NewLocalMessageBus()
@@ -182,6 +294,10 @@ func createClientConfiguration() clientconfiguration.ClientConfigurationManager
 	return clientconfiguration.NewStaticClientConfigurationManager(clientconfiguration.StaticConfigurations)
 }
 
+func createGeoIPProvider(config *config.Config) (geoip.Provider, error) {
+	return geoip.NewProvider(config.GeoIP)
+}
+
 func getRoomConf(config *config.Config) config.RoomConfig {
 	return config.Room
 }
@@ -190,6 +306,6 @@ func getSignalRelayConfig(config *config.Config) config.SignalRelayConfig {
 	return config.SignalRelay
 }
 
-func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler) (*turn.Server, error) {
-	return NewTurnServer(conf, authHandler, false)
+func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler, quota *TURNQuota) (*turn.Server, error) {
+	return NewTurnServer(conf, authHandler, quota, false)
 }
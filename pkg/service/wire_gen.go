@@ -10,11 +10,15 @@ import (
 	"fmt"
 	"github.com/livekit/livekit-server/pkg/clientconfiguration"
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/geoip"
+	"github.com/livekit/livekit-server/pkg/playback"
 	"github.com/livekit/livekit-server/pkg/routing"
 	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/transcoder"
 	"github.com/livekit/protocol/auth"
 	"github.com/livekit/protocol/egress"
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
 	redis2 "github.com/livekit/protocol/redis"
 	"github.com/livekit/protocol/rpc"
 	"github.com/livekit/protocol/utils"
@@ -33,7 +37,8 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*LivekitServer, error) {
+func InitializeServer(current *config.Current, currentNode routing.LocalNode) (*LivekitServer, error) {
+	conf := getConfig(current)
 	roomConfig := getRoomConf(conf)
 	apiConfig := config.DefaultAPIConfig()
 	universalClient, err := createRedisClient(conf)
@@ -47,9 +52,9 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(conf, universalClient, currentNode, signalClient)
+	router := routing.CreateRouter(current, universalClient, currentNode, signalClient)
 	objectStore := createStore(universalClient)
-	roomAllocator, err := NewRoomAllocator(conf, router, objectStore)
+	roomAllocator, err := NewRoomAllocator(current, router, objectStore)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +68,7 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	queuedNotifier, err := createWebhookNotifier(conf, keyProvider)
+	queuedNotifier, err := createWebhookNotifier(current, keyProvider)
 	if err != nil {
 		return nil, err
 	}
@@ -82,14 +87,23 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	}
 	ingressStore := getIngressStore(objectStore)
 	ingressService := NewIngressService(ingressConfig, nodeID, messageBus, ingressClient, ingressStore, roomService, telemetryService)
-	ioInfoService, err := NewIOInfoService(nodeID, messageBus, egressStore, ingressStore, telemetryService, rpcClient)
+	ioInfoService, err := NewIOInfoService(conf, nodeID, messageBus, egressStore, ingressStore, telemetryService, rpcClient)
 	if err != nil {
 		return nil, err
 	}
-	rtcService := NewRTCService(conf, roomAllocator, objectStore, router, currentNode, telemetryService)
+	provider, err := createGeoIPProvider(conf)
+	if err != nil {
+		return nil, err
+	}
+	rtcService := NewRTCService(current, roomAllocator, objectStore, router, currentNode, telemetryService, provider)
 	clientConfigurationManager := createClientConfiguration()
 	timedVersionGenerator := utils.NewDefaultTimedVersionGenerator()
-	roomManager, err := NewLocalRoomManager(conf, objectStore, currentNode, router, telemetryService, clientConfigurationManager, rtcEgressLauncher, timedVersionGenerator)
+	manager := getTranscoderManager(conf, keyProvider)
+	roomTimelineStore := createTimelineStore(conf, universalClient)
+	roomManifestStore := createManifestStore(conf, universalClient)
+	turnCredentials := NewTURNCredentials(current, objectStore)
+	turnQuota := NewTURNQuota(conf)
+	roomManager, err := NewLocalRoomManager(current, objectStore, currentNode, router, telemetryService, clientConfigurationManager, rtcEgressLauncher, timedVersionGenerator, manager, roomTimelineStore, roomManifestStore, egressStore, provider, turnCredentials, turnQuota)
 	if err != nil {
 		return nil, err
 	}
@@ -97,12 +111,44 @@ func InitializeServer(conf *config.Config, currentNode routing.LocalNode) (*Live
 	if err != nil {
 		return nil, err
 	}
-	authHandler := newTurnAuthHandler(objectStore)
-	server, err := newInProcessTurnServer(conf, authHandler)
+	authHandler := newTurnAuthHandler(turnCredentials, turnQuota)
+	server, err := newInProcessTurnServer(conf, authHandler, turnQuota)
+	if err != nil {
+		return nil, err
+	}
+	playbackManager := getPlaybackManager(conf, roomAllocator, router)
+	playbackService := NewPlaybackService(playbackManager)
+	rtmpServer := getRTMPServer(conf, roomAllocator, router, keyProvider, telemetryService)
+	timelineService := NewTimelineService(roomTimelineStore)
+	manifestService := NewManifestService(roomManifestStore)
+	retentionService, err := NewRetentionService(conf, objectStore, roomManifestStore, roomTimelineStore, telemetryService, universalClient, currentNode)
+	if err != nil {
+		return nil, err
+	}
+	erasureService, err := NewErasureService(conf, currentNode, objectStore, router, egressStore, roomTimelineStore, roomManifestStore, telemetryService)
+	if err != nil {
+		return nil, err
+	}
+	dashboardService := NewDashboardService(current, roomService, router, roomManager, keyProvider)
+	logLevelService, err := NewLogLevelService(conf, universalClient)
+	if err != nil {
+		return nil, err
+	}
+	roomAccessService := NewRoomAccessService(objectStore)
+	bulkAdminService := NewBulkAdminService(conf, router, objectStore)
+	roomStatsService := NewRoomStatsService(roomManager)
+	subscriptionDiagnosticsService := NewSubscriptionDiagnosticsService(roomManager)
+	downlinkSimulatorService := NewDownlinkSimulatorService(roomManager)
+	roomPresenceStore := createPresenceStore(conf, universalClient)
+	presenceService := NewPresenceService(conf, roomPresenceStore, objectStore, roomManager, telemetryService)
+	trackSyncService := NewTrackSyncService(roomManager)
+	drainService := NewDrainService(roomManager)
+	configService := NewConfigService(current)
+	profilingService, err := NewProfilingService(conf, currentNode)
 	if err != nil {
 		return nil, err
 	}
-	livekitServer, err := NewLivekitServer(conf, roomService, egressService, ingressService, ioInfoService, rtcService, keyProvider, router, roomManager, signalServer, server, currentNode)
+	livekitServer, err := NewLivekitServer(current, roomService, egressService, ingressService, ioInfoService, rtcService, playbackService, rtmpServer, timelineService, manifestService, retentionService, erasureService, dashboardService, logLevelService, roomAccessService, bulkAdminService, roomStatsService, subscriptionDiagnosticsService, downlinkSimulatorService, presenceService, trackSyncService, drainService, configService, profilingService, keyProvider, router, roomManager, signalServer, server, manager, currentNode)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +167,8 @@ func InitializeRouter(conf *config.Config, currentNode routing.LocalNode) (routi
 	if err != nil {
 		return nil, err
 	}
-	router := routing.CreateRouter(conf, universalClient, currentNode, signalClient)
+	current := config.NewCurrent(conf)
+	router := routing.CreateRouter(current, universalClient, currentNode, signalClient)
 	return router, nil
 }
 
@@ -159,9 +206,41 @@ func createKeyProvider(conf *config.Config) (auth.KeyProvider, error) {
 	return auth.NewFileBasedKeyProviderFromMap(conf.Keys), nil
 }
 
-func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
+func getTranscoderManager(conf *config.Config, keyProvider auth.KeyProvider) *transcoder.Manager {
+	if conf.Transcoder.Port == 0 {
+		return nil
+	}
+	return transcoder.NewManager(keyProvider)
+}
+
+func getRTMPServer(conf *config.Config, roomAllocator RoomAllocator, router routing.Router, keyProvider auth.KeyProvider, ts telemetry.TelemetryService) *RTMPServer {
+	if conf.RTMP.Port == 0 {
+		return nil
+	}
+	return NewRTMPServer(conf, roomAllocator, router, keyProvider, ts)
+}
+
+func getPlaybackManager(conf *config.Config, roomAllocator RoomAllocator, router routing.Router) *playback.Manager {
+	return playback.NewManager(playback.ManagerParams{
+		Connect:          newPlaybackConnector(roomAllocator, router),
+		MaxFileSize:      conf.Playback.MaxFileSize,
+		AllowPrivateURLs: conf.Playback.AllowPrivateURLs,
+	})
+}
+
+func getConfig(current *config.Current) *config.Config {
+	return current.Get()
+}
+
+func createWebhookNotifier(current *config.Current, provider auth.KeyProvider) (webhook.QueuedNotifier, error) {
+	conf := current.Get()
 	wc := conf.WebHook
 	if len(wc.URLs) == 0 {
+		current.OnReload(func(conf *config.Config) {
+			if len(conf.WebHook.URLs) != 0 {
+				logger.Warnw("webhooks were not configured at startup, a restart is required to send them", nil)
+			}
+		})
 		return nil, nil
 	}
 	secret := provider.GetSecret(wc.APIKey)
@@ -169,7 +248,25 @@ func createWebhookNotifier(conf *config.Config, provider auth.KeyProvider) (webh
 		return nil, ErrWebHookMissingAPIKey
 	}
 
-	return webhook.NewDefaultNotifier(wc.APIKey, secret, wc.URLs), nil
+	params := telemetry.WebhookNotifierParams{
+		URLs:             wc.URLs,
+		APIKey:           wc.APIKey,
+		APISecret:        secret,
+		ReorderWindow:    wc.ReorderWindow,
+		MaxRetryDuration: wc.MaxRetryDuration,
+	}
+	if conf.Logging.PIIRedaction.Webhooks {
+		redactor, err := conf.Logging.PIIRedaction.NewRedactor()
+		if err != nil {
+			return nil, err
+		}
+		params.Redactor = redactor
+	}
+	n := telemetry.NewWebhookNotifier(params)
+	current.OnReload(func(conf *config.Config) {
+		n.SetURLs(conf.WebHook.URLs)
+	})
+	return n, nil
 }
 
 func createRedisClient(conf *config.Config) (redis.UniversalClient, error) {
@@ -186,6 +283,36 @@ func createStore(rc redis.UniversalClient) ObjectStore {
 	return NewLocalStore()
 }
 
+func createManifestStore(conf *config.Config, rc redis.UniversalClient) RoomManifestStore {
+	if !conf.RoomManifest.Enabled {
+		return nil
+	}
+	if rc != nil {
+		return NewRedisManifestStore(rc, conf.RoomManifest)
+	}
+	return NewLocalManifestStore(conf.RoomManifest)
+}
+
+func createPresenceStore(conf *config.Config, rc redis.UniversalClient) RoomPresenceStore {
+	if !conf.Presence.Enabled {
+		return nil
+	}
+	if rc != nil {
+		return NewRedisPresenceStore(rc, conf.Presence)
+	}
+	return NewLocalPresenceStore(conf.Presence)
+}
+
+func createTimelineStore(conf *config.Config, rc redis.UniversalClient) RoomTimelineStore {
+	if !conf.Timeline.Enabled {
+		return nil
+	}
+	if rc != nil {
+		return NewRedisTimelineStore(rc, conf.Timeline)
+	}
+	return NewLocalTimelineStore(conf.Timeline)
+}
+
 func getMessageBus(rc redis.UniversalClient) psrpc.MessageBus {
 	if rc == nil {
 		return psrpc.NewLocalMessageBus()
@@ -227,6 +354,10 @@ func createClientConfiguration() clientconfiguration.ClientConfigurationManager
 	return clientconfiguration.NewStaticClientConfigurationManager(clientconfiguration.StaticConfigurations)
 }
 
+func createGeoIPProvider(config2 *config.Config) (geoip.Provider, error) {
+	return geoip.NewProvider(config2.GeoIP)
+}
+
 func getRoomConf(config2 *config.Config) config.RoomConfig {
 	return config2.Room
 }
@@ -235,6 +366,6 @@ func getSignalRelayConfig(config2 *config.Config) config.SignalRelayConfig {
 	return config2.SignalRelay
 }
 
-func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler) (*turn.Server, error) {
-	return NewTurnServer(conf, authHandler, false)
+func newInProcessTurnServer(conf *config.Config, authHandler turn.AuthHandler, quota *TURNQuota) (*turn.Server, error) {
+	return NewTurnServer(conf, authHandler, quota, false)
 }
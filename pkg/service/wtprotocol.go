@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/quic-go/webtransport-go"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	// largest signal message accepted from a client
+	maxWTMessageSize = 1 << 20
+
+	wtSessionClosed webtransport.SessionErrorCode = 0
+)
+
+// WTSignalConnection carries signal messages on a bidirectional WebTransport stream,
+// each message is protobuf encoded and prefixed by its length as a 32-bit big endian integer
+type WTSignalConnection struct {
+	session *webtransport.Session
+	stream  webtransport.Stream
+	reader  *bufio.Reader
+	mu      sync.Mutex
+}
+
+func NewWTSignalConnection(session *webtransport.Session, stream webtransport.Stream) *WTSignalConnection {
+	return &WTSignalConnection{
+		session: session,
+		stream:  stream,
+		reader:  bufio.NewReader(stream),
+	}
+}
+
+func (c *WTSignalConnection) ReadRequest() (*livekit.SignalRequest, int, error) {
+	payload, err := readWTMessage(c.reader)
+	if err != nil {
+		var connErr *webtransport.ConnectionError
+		var streamErr *webtransport.StreamError
+		if errors.As(err, &connErr) || errors.As(err, &streamErr) {
+			// session closed by either side
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+
+	msg := &livekit.SignalRequest{}
+	err = proto.Unmarshal(payload, msg)
+	return msg, len(payload), err
+}
+
+func (c *WTSignalConnection) WriteResponse(msg *livekit.SignalResponse) (int, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(payload), writeWTMessage(c.stream, payload)
+}
+
+func (c *WTSignalConnection) RemoteAddr() net.Addr {
+	return c.session.RemoteAddr()
+}
+
+func (c *WTSignalConnection) Close() error {
+	return c.session.CloseWithError(wtSessionClosed, "")
+}
+
+func readWTMessage(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxWTMessageSize {
+		return nil, fmt.Errorf("signal message too large: %d bytes", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func writeWTMessage(w io.Writer, payload []byte) error {
+	buf := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+	copy(buf[4:], payload)
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestWTMessageFraming(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, writeWTMessage(buf, []byte("offer")))
+		require.NoError(t, writeWTMessage(buf, nil))
+
+		payload, err := readWTMessage(buf)
+		require.NoError(t, err)
+		require.Equal(t, []byte("offer"), payload)
+		payload, err = readWTMessage(buf)
+		require.NoError(t, err)
+		require.Empty(t, payload)
+		_, err = readWTMessage(buf)
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, writeWTMessage(buf, []byte("answer")))
+		_, err := readWTMessage(bytes.NewReader(buf.Bytes()[:7]))
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], maxWTMessageSize+1)
+		_, err := readWTMessage(bytes.NewReader(size[:]))
+		require.Error(t, err)
+	})
+}
+
+func TestWebTransportRequiresCertificate(t *testing.T) {
+	_, err := NewWebTransportServer(&config.Config{
+		WebTransport: config.WebTransportConfig{Port: 7885},
+	}, nil, nil)
+	require.Error(t, err)
+}
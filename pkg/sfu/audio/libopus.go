@@ -0,0 +1,98 @@
+//go:build opus && cgo
+
+package audio
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+
+static int livekit_opus_encoder_set_bitrate(OpusEncoder *enc, opus_int32 bitrate) {
+	return opus_encoder_ctl(enc, OPUS_SET_BITRATE(bitrate));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+// LibOpus is the opus codec of libopus, linked in builds with the opus tag
+type LibOpus struct{}
+
+func (LibOpus) NewDecoder(sampleRate int) (OpusDecoder, error) {
+	var errCode C.int
+	dec := C.opus_decoder_create(C.opus_int32(sampleRate), 1, &errCode)
+	if errCode != C.OPUS_OK {
+		return nil, libOpusError("could not create opus decoder", errCode)
+	}
+	d := &libOpusDecoder{dec: dec}
+	runtime.SetFinalizer(d, func(d *libOpusDecoder) {
+		C.opus_decoder_destroy(d.dec)
+	})
+	return d, nil
+}
+
+func (LibOpus) NewEncoder(sampleRate int, bitrate int) (OpusEncoder, error) {
+	var errCode C.int
+	enc := C.opus_encoder_create(C.opus_int32(sampleRate), 1, C.OPUS_APPLICATION_AUDIO, &errCode)
+	if errCode != C.OPUS_OK {
+		return nil, libOpusError("could not create opus encoder", errCode)
+	}
+	if errCode = C.livekit_opus_encoder_set_bitrate(enc, C.opus_int32(bitrate)); errCode != C.OPUS_OK {
+		C.opus_encoder_destroy(enc)
+		return nil, libOpusError("could not set opus bitrate", errCode)
+	}
+	e := &libOpusEncoder{enc: enc}
+	runtime.SetFinalizer(e, func(e *libOpusEncoder) {
+		C.opus_encoder_destroy(e.enc)
+	})
+	return e, nil
+}
+
+type libOpusDecoder struct {
+	dec *C.OpusDecoder
+}
+
+func (d *libOpusDecoder) Decode(packet []byte, pcm []int16) (int, error) {
+	if len(pcm) == 0 {
+		return 0, errors.New("empty pcm buffer")
+	}
+	// packet loss concealment without a packet, for the duration pcm holds
+	var data *C.uchar
+	if len(packet) != 0 {
+		data = (*C.uchar)(unsafe.Pointer(&packet[0]))
+	}
+	n := C.opus_decode(d.dec,
+		data, C.opus_int32(len(packet)),
+		(*C.opus_int16)(unsafe.Pointer(&pcm[0])), C.int(len(pcm)), 0)
+	runtime.KeepAlive(d)
+	if n < 0 {
+		return 0, libOpusError("could not decode opus packet", n)
+	}
+	return int(n), nil
+}
+
+type libOpusEncoder struct {
+	enc *C.OpusEncoder
+}
+
+func (e *libOpusEncoder) Encode(pcm []int16, packet []byte) (int, error) {
+	if len(pcm) == 0 || len(packet) == 0 {
+		return 0, errors.New("empty pcm or opus packet buffer")
+	}
+	n := C.opus_encode(e.enc,
+		(*C.opus_int16)(unsafe.Pointer(&pcm[0])), C.int(len(pcm)),
+		(*C.uchar)(unsafe.Pointer(&packet[0])), C.opus_int32(len(packet)))
+	runtime.KeepAlive(e)
+	if n < 0 {
+		return 0, libOpusError("could not encode opus frame", n)
+	}
+	return int(n), nil
+}
+
+func libOpusError(what string, code C.int) error {
+	return fmt.Errorf("%s: %s", what, C.GoString(C.opus_strerror(code)))
+}
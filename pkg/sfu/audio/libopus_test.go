@@ -0,0 +1,79 @@
+//go:build opus && cgo
+
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestLibOpus(t *testing.T) {
+	enc, err := LibOpus{}.NewEncoder(MixerSampleRate, 32000)
+	require.NoError(t, err)
+	dec, err := LibOpus{}.NewDecoder(MixerSampleRate)
+	require.NoError(t, err)
+
+	pcm := make([]int16, MixerFrameSamples)
+	decoded := make([]int16, mixerMaxDecodedSamples)
+	packet := make([]byte, mixerMaxPacketSize)
+	var energy float64
+	for frame := 0; frame < 10; frame++ {
+		for i := range pcm {
+			pcm[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(frame*MixerFrameSamples+i)/MixerSampleRate))
+		}
+		n, err := enc.Encode(pcm, packet)
+		require.NoError(t, err)
+		require.Greater(t, n, 0)
+
+		samples, err := dec.Decode(packet[:n], decoded)
+		require.NoError(t, err)
+		require.Equal(t, MixerFrameSamples, samples)
+		energy = 0
+		for _, s := range decoded[:samples] {
+			energy += float64(s) * float64(s)
+		}
+	}
+	// past the codec delay the tone comes back
+	require.Greater(t, math.Sqrt(energy/float64(MixerFrameSamples)), 1000.0)
+
+	// a lost frame is concealed
+	samples, err := dec.Decode(nil, decoded[:MixerFrameSamples])
+	require.NoError(t, err)
+	require.Equal(t, MixerFrameSamples, samples)
+
+	_, err = dec.Decode([]byte{0xff, 0xff, 0xff}, decoded)
+	require.Error(t, err)
+}
+
+func TestLibOpusMixer(t *testing.T) {
+	m, err := NewMixer(MixerParams{Codec: LibOpus{}, Bitrate: 32000, JitterFrames: 1})
+	require.NoError(t, err)
+	var mixes map[livekit.ParticipantID][]byte
+	m.OnMix(func(packets map[livekit.ParticipantID][]byte) {
+		mixes = packets
+	})
+	require.NoError(t, m.AddInput("PA_a"))
+
+	enc, err := LibOpus{}.NewEncoder(MixerSampleRate, 32000)
+	require.NoError(t, err)
+	pcm := make([]int16, MixerFrameSamples)
+	packet := make([]byte, mixerMaxPacketSize)
+	for frame := 0; frame < 3; frame++ {
+		n, err := enc.Encode(pcm, packet)
+		require.NoError(t, err)
+		require.NoError(t, m.WritePacket("PA_a", uint16(frame), packet[:n]))
+		m.mix()
+	}
+
+	// the mix decodes as a frame of opus again
+	dec, err := LibOpus{}.NewDecoder(MixerSampleRate)
+	require.NoError(t, err)
+	require.NotEmpty(t, mixes[""])
+	samples, err := dec.Decode(mixes[""], make([]int16, mixerMaxDecodedSamples))
+	require.NoError(t, err)
+	require.Equal(t, MixerFrameSamples, samples)
+}
@@ -0,0 +1,416 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	MixerSampleRate    = 48000
+	MixerFrameDuration = 20 * time.Millisecond
+	// samples of a mono frame
+	MixerFrameSamples = MixerSampleRate * int(MixerFrameDuration/time.Millisecond) / 1000
+
+	mixerDefaultJitterFrames = 3
+	mixerDefaultBitrate      = 32000
+	// opus packets carry at most 120 ms
+	mixerMaxDecodedSamples = MixerSampleRate * 120 / 1000
+	mixerMaxPacketSize     = 1500
+)
+
+var (
+	ErrNoOpusCodec       = errors.New("no opus codec registered")
+	ErrUnknownMixerInput = errors.New("unknown mixer input")
+)
+
+// OpusDecoder decodes opus packets into 16 bit mono PCM
+type OpusDecoder interface {
+	// Decode decodes a packet into pcm, returning the number of samples decoded. A nil packet conceals a lost one,
+	// filling pcm
+	Decode(packet []byte, pcm []int16) (int, error)
+}
+
+// OpusEncoder encodes frames of 16 bit mono PCM
+type OpusEncoder interface {
+	// Encode encodes a frame into packet, returning the size of the encoded packet
+	Encode(pcm []int16, packet []byte) (int, error)
+}
+
+// OpusCodec creates the opus decoders and encoders audio mixers work with, e. g. bindings of libopus
+type OpusCodec interface {
+	NewDecoder(sampleRate int) (OpusDecoder, error)
+	NewEncoder(sampleRate int, bitrate int) (OpusEncoder, error)
+}
+
+var (
+	opusCodecMu sync.RWMutex
+	opusCodec   OpusCodec
+)
+
+// RegisterOpusCodec provides the codec audio mixers decode and encode with. None is registered by default, mixing is
+// available in builds which link an opus implementation and register it
+func RegisterOpusCodec(codec OpusCodec) {
+	opusCodecMu.Lock()
+	defer opusCodecMu.Unlock()
+
+	opusCodec = codec
+}
+
+// GetOpusCodec returns the registered opus codec, nil when there is none
+func GetOpusCodec() OpusCodec {
+	opusCodecMu.RLock()
+	defer opusCodecMu.RUnlock()
+
+	return opusCodec
+}
+
+// ---------------------------------------------------------------------
+
+type MixerParams struct {
+	// defaults to the registered codec
+	Codec OpusCodec
+	// bitrate of the mixes, in bps
+	Bitrate int
+	// frames buffered per input to absorb jitter
+	JitterFrames int
+	Logger       logger.Logger
+}
+
+type mixerPacket struct {
+	sn      uint16
+	payload []byte
+}
+
+type mixerInput struct {
+	decoder OpusDecoder
+	decoded []int16
+
+	lock sync.Mutex
+	// packets waiting to be decoded, in sequence number order
+	packets []mixerPacket
+	// sequence number of the packet to decode next
+	nextSN  uint16
+	started bool
+	pending []int16
+	primed  bool
+}
+
+type mixerOutput struct {
+	encoder OpusEncoder
+	packet  []byte
+}
+
+// Mixer mixes the audio of its inputs in frames of 20 ms, encoding a mix of all of them and, for inputs it is asked
+// to, a mix of all inputs but that one. Packets of an input are buffered for a few frames to absorb jitter and
+// decoded in sequence number order, lost ones are concealed by the decoder. An input running dry is buffered again
+// before it is mixed in
+type Mixer struct {
+	params MixerParams
+
+	lock    sync.Mutex
+	inputs  map[livekit.ParticipantID]*mixerInput
+	outputs map[livekit.ParticipantID]*mixerOutput
+	onMix   func(packets map[livekit.ParticipantID][]byte)
+	closed  chan struct{}
+}
+
+func NewMixer(params MixerParams) (*Mixer, error) {
+	if params.Codec == nil {
+		params.Codec = GetOpusCodec()
+		if params.Codec == nil {
+			return nil, ErrNoOpusCodec
+		}
+	}
+	if params.Bitrate <= 0 {
+		params.Bitrate = mixerDefaultBitrate
+	}
+	if params.JitterFrames <= 0 {
+		params.JitterFrames = mixerDefaultJitterFrames
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+
+	m := &Mixer{
+		params:  params,
+		inputs:  make(map[livekit.ParticipantID]*mixerInput),
+		outputs: make(map[livekit.ParticipantID]*mixerOutput),
+		closed:  make(chan struct{}),
+	}
+	// the mix of all inputs
+	if err := m.AddOutput(""); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// OnMix is called every frame with the encoded mixes, keyed by the input they leave out, the mix of all inputs
+// having an empty key
+func (m *Mixer) OnMix(f func(packets map[livekit.ParticipantID][]byte)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.onMix = f
+}
+
+func (m *Mixer) AddInput(id livekit.ParticipantID) error {
+	decoder, err := m.params.Codec.NewDecoder(MixerSampleRate)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.inputs[id]; !ok {
+		m.inputs[id] = &mixerInput{
+			decoder: decoder,
+			decoded: make([]int16, mixerMaxDecodedSamples),
+		}
+	}
+	return nil
+}
+
+func (m *Mixer) RemoveInput(id livekit.ParticipantID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.inputs, id)
+}
+
+func (m *Mixer) HasInput(id livekit.ParticipantID) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.inputs[id]
+	return ok
+}
+
+// WritePacket adds a packet of an input to its jitter buffer, packets may arrive out of order
+func (m *Mixer) WritePacket(id livekit.ParticipantID, sn uint16, packet []byte) error {
+	m.lock.Lock()
+	input := m.inputs[id]
+	m.lock.Unlock()
+	if input == nil {
+		return ErrUnknownMixerInput
+	}
+
+	input.addPacket(sn, packet, 2*m.params.JitterFrames)
+	return nil
+}
+
+// AddOutput has the mixer encode a mix leaving out an input, an empty id being the mix of all inputs
+func (m *Mixer) AddOutput(excluded livekit.ParticipantID) error {
+	m.lock.Lock()
+	_, ok := m.outputs[excluded]
+	m.lock.Unlock()
+	if ok {
+		return nil
+	}
+
+	encoder, err := m.params.Codec.NewEncoder(MixerSampleRate, m.params.Bitrate)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.outputs[excluded]; !ok {
+		m.outputs[excluded] = &mixerOutput{
+			encoder: encoder,
+			packet:  make([]byte, mixerMaxPacketSize),
+		}
+	}
+	return nil
+}
+
+// RemoveOutput stops encoding the mix leaving out an input, the mix of all inputs is always encoded
+func (m *Mixer) RemoveOutput(excluded livekit.ParticipantID) {
+	if excluded == "" {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.outputs, excluded)
+}
+
+// Start mixes a frame every 20 ms till the mixer is closed
+func (m *Mixer) Start() {
+	go func() {
+		ticker := time.NewTicker(MixerFrameDuration)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.closed:
+				return
+			case <-ticker.C:
+				m.mix()
+			}
+		}
+	}()
+}
+
+func (m *Mixer) Close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	select {
+	case <-m.closed:
+	default:
+		close(m.closed)
+	}
+}
+
+// mix takes a frame of each input, encoding the mixes. Called from a single goroutine only, encoders are not
+// shared with any other
+func (m *Mixer) mix() {
+	m.lock.Lock()
+	inputs := make(map[livekit.ParticipantID]*mixerInput, len(m.inputs))
+	for id, input := range m.inputs {
+		inputs[id] = input
+	}
+	outputs := make(map[livekit.ParticipantID]*mixerOutput, len(m.outputs))
+	for excluded, output := range m.outputs {
+		outputs[excluded] = output
+	}
+	onMix := m.onMix
+	m.lock.Unlock()
+
+	var sum [MixerFrameSamples]int32
+	frames := make(map[livekit.ParticipantID][]int16, len(inputs))
+	for id, input := range inputs {
+		if frame := input.nextFrame(m.params.JitterFrames, m.params.Logger); frame != nil {
+			frames[id] = frame
+			for i, sample := range frame {
+				sum[i] += int32(sample)
+			}
+		}
+	}
+
+	var pcm [MixerFrameSamples]int16
+	packets := make(map[livekit.ParticipantID][]byte, len(outputs))
+	for excluded, output := range outputs {
+		excludedFrame := frames[excluded]
+		for i := range pcm {
+			v := sum[i]
+			if excludedFrame != nil {
+				v -= int32(excludedFrame[i])
+			}
+			pcm[i] = clip(v)
+		}
+
+		n, err := output.encoder.Encode(pcm[:], output.packet)
+		if err != nil {
+			m.params.Logger.Warnw("could not encode mix", err, "excluded", excluded)
+			continue
+		}
+		packets[excluded] = append([]byte(nil), output.packet[:n]...)
+	}
+
+	if onMix != nil && len(packets) != 0 {
+		onMix(packets)
+	}
+}
+
+// addPacket inserts a packet in sequence number order, dropping duplicates and packets arriving after their turn.
+// when more than maxPackets are buffered the input is running behind, the oldest are dropped
+func (i *mixerInput) addPacket(sn uint16, payload []byte, maxPackets int) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.started && int16(sn-i.nextSN) < 0 {
+		return
+	}
+
+	idx := len(i.packets)
+	for idx > 0 && int16(sn-i.packets[idx-1].sn) <= 0 {
+		if sn == i.packets[idx-1].sn {
+			return
+		}
+		idx--
+	}
+	// the payload is only valid during the write
+	i.packets = append(i.packets, mixerPacket{})
+	copy(i.packets[idx+1:], i.packets[idx:])
+	i.packets[idx] = mixerPacket{sn: sn, payload: append([]byte(nil), payload...)}
+
+	if len(i.packets) > maxPackets {
+		i.packets = i.packets[len(i.packets)-maxPackets:]
+		i.nextSN = i.packets[0].sn
+	}
+}
+
+// decodeLocked decodes buffered packets till a frame is pending. a gap of a few packets is concealed once a later
+// packet is in, larger gaps, e.g. after the publisher was muted, are skipped
+func (i *mixerInput) decodeLocked(maxConcealed int, logger logger.Logger) {
+	for len(i.pending) < MixerFrameSamples && len(i.packets) != 0 {
+		if !i.started {
+			i.nextSN = i.packets[0].sn
+			i.started = true
+		}
+
+		var payload []byte
+		if gap := int(i.packets[0].sn - i.nextSN); gap == 0 {
+			payload = i.packets[0].payload
+			i.packets = i.packets[1:]
+		} else if gap > maxConcealed {
+			i.nextSN = i.packets[0].sn
+			continue
+		}
+		i.nextSN++
+
+		pcm := i.decoded
+		if payload == nil {
+			pcm = pcm[:MixerFrameSamples]
+		}
+		n, err := i.decoder.Decode(payload, pcm)
+		if err != nil {
+			logger.Debugw("could not decode mixer input", err, "concealed", payload == nil)
+			continue
+		}
+		i.pending = append(i.pending, pcm[:n]...)
+	}
+}
+
+// nextFrame takes a frame from the buffer of an input, nil while the input is buffering
+func (i *mixerInput) nextFrame(jitterFrames int, logger logger.Logger) []int16 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if !i.primed {
+		if len(i.packets) < jitterFrames && len(i.pending) < jitterFrames*MixerFrameSamples {
+			return nil
+		}
+		i.primed = true
+	}
+
+	i.decodeLocked(jitterFrames, logger)
+	frame := make([]int16, MixerFrameSamples)
+	n := copy(frame, i.pending)
+	i.pending = i.pending[n:]
+	if n < MixerFrameSamples {
+		// ran dry, buffer again
+		i.primed = false
+	}
+	return frame
+}
+
+func clip(v int32) int16 {
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(v)
+	}
+}
@@ -0,0 +1,175 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// pcmCodec decodes packets of little endian PCM, encoding the first sample of a frame only. lost packets are
+// concealed with concealedSample
+type pcmCodec struct{}
+
+const concealedSample = -1
+
+func (pcmCodec) NewDecoder(_ int) (OpusDecoder, error)        { return pcmCodec{}, nil }
+func (pcmCodec) NewEncoder(_ int, _ int) (OpusEncoder, error) { return pcmCodec{}, nil }
+
+func (pcmCodec) Decode(packet []byte, pcm []int16) (int, error) {
+	if packet == nil {
+		for i := range pcm {
+			pcm[i] = concealedSample
+		}
+		return len(pcm), nil
+	}
+	n := len(packet) / 2
+	for i := 0; i < n; i++ {
+		pcm[i] = int16(binary.LittleEndian.Uint16(packet[2*i:]))
+	}
+	return n, nil
+}
+
+func (pcmCodec) Encode(pcm []int16, packet []byte) (int, error) {
+	binary.LittleEndian.PutUint16(packet, uint16(pcm[0]))
+	return 2, nil
+}
+
+func pcmFrame(sample int16) []byte {
+	packet := make([]byte, 2*MixerFrameSamples)
+	for i := 0; i < MixerFrameSamples; i++ {
+		binary.LittleEndian.PutUint16(packet[2*i:], uint16(sample))
+	}
+	return packet
+}
+
+func firstSample(packet []byte) int16 {
+	return int16(binary.LittleEndian.Uint16(packet))
+}
+
+func TestMixer(t *testing.T) {
+	t.Run("needs a codec", func(t *testing.T) {
+		_, err := NewMixer(MixerParams{})
+		require.ErrorIs(t, err, ErrNoOpusCodec)
+	})
+
+	m, err := NewMixer(MixerParams{Codec: pcmCodec{}, JitterFrames: 1})
+	require.NoError(t, err)
+	var mixes map[livekit.ParticipantID][]byte
+	m.OnMix(func(packets map[livekit.ParticipantID][]byte) {
+		mixes = packets
+	})
+
+	sns := make(map[livekit.ParticipantID]uint16)
+	write := func(id livekit.ParticipantID, sample int16) error {
+		sn := sns[id]
+		sns[id]++
+		return m.WritePacket(id, sn, pcmFrame(sample))
+	}
+
+	require.ErrorIs(t, write("PA_a", 1), ErrUnknownMixerInput)
+	delete(sns, "PA_a")
+	require.NoError(t, m.AddInput("PA_a"))
+	require.NoError(t, m.AddInput("PA_b"))
+	require.NoError(t, m.AddOutput("PA_a"))
+
+	t.Run("mixes all inputs and leaves out excluded ones", func(t *testing.T) {
+		require.NoError(t, write("PA_a", 100))
+		require.NoError(t, write("PA_b", 20))
+		m.mix()
+
+		require.Len(t, mixes, 2)
+		require.Equal(t, int16(120), firstSample(mixes[""]))
+		require.Equal(t, int16(20), firstSample(mixes["PA_a"]))
+	})
+
+	t.Run("inputs without audio are silent", func(t *testing.T) {
+		require.NoError(t, write("PA_b", 20))
+		m.mix()
+
+		require.Equal(t, int16(20), firstSample(mixes[""]))
+		require.Equal(t, int16(20), firstSample(mixes["PA_a"]))
+	})
+
+	t.Run("clips", func(t *testing.T) {
+		require.NoError(t, write("PA_a", 30000))
+		require.NoError(t, write("PA_b", 30000))
+		m.mix()
+
+		require.Equal(t, int16(32767), firstSample(mixes[""]))
+		require.Equal(t, int16(30000), firstSample(mixes["PA_a"]))
+	})
+
+	t.Run("removed outputs and inputs", func(t *testing.T) {
+		m.RemoveOutput("PA_a")
+		m.RemoveInput("PA_b")
+		require.NoError(t, write("PA_a", 5))
+		m.mix()
+
+		require.Len(t, mixes, 1)
+		require.Equal(t, int16(5), firstSample(mixes[""]))
+	})
+}
+
+func TestMixerInputBuffering(t *testing.T) {
+	input := &mixerInput{decoder: pcmCodec{}, decoded: make([]int16, mixerMaxDecodedSamples)}
+	l := logger.GetLogger()
+
+	// buffers till the jitter frames are in
+	input.addPacket(0, pcmFrame(1), 4)
+	require.Nil(t, input.nextFrame(2, l))
+	input.addPacket(1, pcmFrame(2), 4)
+	require.Equal(t, int16(1), input.nextFrame(2, l)[0])
+	require.Equal(t, int16(2), input.nextFrame(2, l)[0])
+
+	// ran dry, buffers again
+	require.Equal(t, int16(0), input.nextFrame(2, l)[0])
+	require.Nil(t, input.nextFrame(2, l))
+}
+
+func TestMixerInputJitterBuffer(t *testing.T) {
+	input := &mixerInput{decoder: pcmCodec{}, decoded: make([]int16, mixerMaxDecodedSamples)}
+	l := logger.GetLogger()
+	nextSample := func() int16 {
+		frame := input.nextFrame(1, l)
+		require.NotNil(t, frame)
+		return frame[0]
+	}
+
+	t.Run("reorders", func(t *testing.T) {
+		input.addPacket(65535, pcmFrame(1), 8)
+		input.addPacket(1, pcmFrame(3), 8)
+		input.addPacket(0, pcmFrame(2), 8)
+		// duplicate
+		input.addPacket(1, pcmFrame(4), 8)
+		require.Equal(t, int16(1), nextSample())
+		require.Equal(t, int16(2), nextSample())
+		require.Equal(t, int16(3), nextSample())
+
+		// arrived after its turn
+		input.addPacket(0, pcmFrame(5), 8)
+		require.Empty(t, input.packets)
+	})
+
+	t.Run("conceals lost packets", func(t *testing.T) {
+		input.addPacket(3, pcmFrame(6), 8)
+		require.Equal(t, int16(concealedSample), nextSample())
+		require.Equal(t, int16(6), nextSample())
+	})
+
+	t.Run("skips large gaps", func(t *testing.T) {
+		input.addPacket(100, pcmFrame(7), 8)
+		require.Equal(t, int16(7), nextSample())
+	})
+
+	t.Run("drops the oldest packets when running behind", func(t *testing.T) {
+		for sn := uint16(101); sn < 111; sn++ {
+			input.addPacket(sn, pcmFrame(int16(sn)), 8)
+		}
+		require.Len(t, input.packets, 8)
+		require.Equal(t, int16(103), nextSample())
+	})
+}
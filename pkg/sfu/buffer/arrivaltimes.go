@@ -0,0 +1,129 @@
+package buffer
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const (
+	arrivalTimeSlots = 1024
+	// timestamps older than this when packets reach their buffer are not used
+	maxArrivalTimeAge        = time.Second
+	arrivalTimeIdleTimeout   = 30 * time.Second
+	arrivalTimeSweepInterval = 10 * time.Second
+)
+
+type arrivalTimeSlot struct {
+	sn uint16
+	at int64
+}
+
+type ssrcArrivalTimes struct {
+	lock     sync.Mutex
+	slots    [arrivalTimeSlots]arrivalTimeSlot
+	lastUsed int64
+}
+
+// ArrivalTimes holds the times the kernel received RTP packets, recorded by the sockets media is read from. Packets
+// are decrypted and queued before they reach their buffer, the recorded time is looked up there by SSRC and sequence
+// number, which SRTP leaves unencrypted, so that jitter and bandwidth estimation are not skewed by the time packets
+// waited to be read under load. Recorded times that are not recent are ignored, which guards against publishers
+// using the same SSRC and against clocks that are off, e.g. NIC clocks not synchronized with the system clock
+type ArrivalTimes struct {
+	lock      sync.RWMutex
+	ssrcs     map[uint32]*ssrcArrivalTimes
+	lastSweep int64
+}
+
+func NewArrivalTimes() *ArrivalTimes {
+	return &ArrivalTimes{
+		ssrcs:     make(map[uint32]*ssrcArrivalTimes),
+		lastSweep: time.Now().UnixNano(),
+	}
+}
+
+// Record records when the kernel received a packet, packets other than RTP are ignored
+func (a *ArrivalTimes) Record(pkt []byte, at time.Time) {
+	ssrc, sn, ok := rtpPacketID(pkt)
+	if !ok {
+		return
+	}
+	nanos := at.UnixNano()
+
+	a.lock.RLock()
+	s := a.ssrcs[ssrc]
+	sweep := nanos-a.lastSweep > int64(arrivalTimeSweepInterval)
+	a.lock.RUnlock()
+	if s == nil || sweep {
+		a.lock.Lock()
+		if sweep {
+			a.sweep(nanos)
+		}
+		if s = a.ssrcs[ssrc]; s == nil {
+			s = &ssrcArrivalTimes{}
+			a.ssrcs[ssrc] = s
+		}
+		a.lock.Unlock()
+	}
+
+	s.lock.Lock()
+	s.slots[sn%arrivalTimeSlots] = arrivalTimeSlot{sn: sn, at: nanos}
+	s.lastUsed = nanos
+	s.lock.Unlock()
+}
+
+// Get returns when the kernel received a packet, now when that was not recorded
+func (a *ArrivalTimes) Get(pkt []byte, now time.Time) time.Time {
+	if a == nil {
+		return now
+	}
+	ssrc, sn, ok := rtpPacketID(pkt)
+	if !ok {
+		return now
+	}
+
+	a.lock.RLock()
+	s := a.ssrcs[ssrc]
+	a.lock.RUnlock()
+	if s == nil {
+		return now
+	}
+
+	s.lock.Lock()
+	slot := s.slots[sn%arrivalTimeSlots]
+	s.lock.Unlock()
+	if slot.sn != sn || slot.at == 0 {
+		return now
+	}
+	at := time.Unix(0, slot.at)
+	if age := now.Sub(at); age < 0 || age > maxArrivalTimeAge {
+		return now
+	}
+	return at
+}
+
+func (a *ArrivalTimes) sweep(now int64) {
+	a.lastSweep = now
+	for ssrc, s := range a.ssrcs {
+		s.lock.Lock()
+		idle := now-s.lastUsed > int64(arrivalTimeIdleTimeout)
+		s.lock.Unlock()
+		if idle {
+			delete(a.ssrcs, ssrc)
+		}
+	}
+}
+
+// rtpPacketID reads the SSRC and sequence number of an RTP packet, ok is false for other packets sharing the
+// transport (RFC 7983) and for RTCP
+func rtpPacketID(pkt []byte) (ssrc uint32, sn uint16, ok bool) {
+	if len(pkt) < rtpFixedHeaderSize || pkt[0] < 128 || pkt[0] > 191 {
+		return 0, 0, false
+	}
+	// RTCP packet types 192-223 take the place of the marker bit and payload type
+	if pt := pkt[1]; pt >= 192 && pt <= 223 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(pkt[8:12]), binary.BigEndian.Uint16(pkt[2:4]), true
+}
@@ -0,0 +1,75 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func rtpPacket(t *testing.T, ssrc uint32, sn uint16) []byte {
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: sn,
+			SSRC:           ssrc,
+		},
+		Payload: []byte{1, 2, 3},
+	}
+	b, err := pkt.Marshal()
+	require.NoError(t, err)
+	return b
+}
+
+func TestArrivalTimes(t *testing.T) {
+	a := NewArrivalTimes()
+	now := time.Now()
+	at := now.Add(-20 * time.Millisecond)
+
+	a.Record(rtpPacket(t, 1234, 100), at)
+	require.Equal(t, at.UnixNano(), a.Get(rtpPacket(t, 1234, 100), now).UnixNano())
+
+	// not recorded
+	require.Equal(t, now, a.Get(rtpPacket(t, 1234, 101), now))
+	require.Equal(t, now, a.Get(rtpPacket(t, 5678, 100), now))
+
+	// slot reused by a later sequence number
+	a.Record(rtpPacket(t, 1234, 100+arrivalTimeSlots), at)
+	require.Equal(t, now, a.Get(rtpPacket(t, 1234, 100), now))
+
+	// too old
+	a.Record(rtpPacket(t, 1234, 200), now.Add(-2*maxArrivalTimeAge))
+	require.Equal(t, now, a.Get(rtpPacket(t, 1234, 200), now))
+
+	// in the future
+	a.Record(rtpPacket(t, 1234, 201), now.Add(time.Second))
+	require.Equal(t, now, a.Get(rtpPacket(t, 1234, 201), now))
+
+	// not set
+	var nilArrivalTimes *ArrivalTimes
+	require.Equal(t, now, nilArrivalTimes.Get(rtpPacket(t, 1234, 100), now))
+}
+
+func TestArrivalTimesIgnoresRTCP(t *testing.T) {
+	a := NewArrivalTimes()
+	now := time.Now()
+
+	sr, err := (&rtcp.SenderReport{SSRC: 1234}).Marshal()
+	require.NoError(t, err)
+	a.Record(sr, now.Add(-20*time.Millisecond))
+	require.Empty(t, a.ssrcs)
+	require.Equal(t, now, a.Get(sr, now))
+}
+
+func TestArrivalTimesSweep(t *testing.T) {
+	a := NewArrivalTimes()
+	start := time.Now()
+
+	a.Record(rtpPacket(t, 1234, 100), start)
+	a.Record(rtpPacket(t, 5678, 100), start.Add(arrivalTimeIdleTimeout+arrivalTimeSweepInterval))
+	require.Len(t, a.ssrcs, 1)
+	require.Contains(t, a.ssrcs, uint32(5678))
+}
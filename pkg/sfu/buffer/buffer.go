@@ -3,6 +3,7 @@ package buffer
 import (
 	"encoding/binary"
 	"io"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -10,16 +11,15 @@ import (
 	"github.com/gammazero/deque"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
-	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"go.uber.org/atomic"
 
 	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/sfu/twcc"
 	"github.com/livekit/mediatransportutil"
 	"github.com/livekit/mediatransportutil/pkg/bucket"
 	"github.com/livekit/mediatransportutil/pkg/nack"
-	"github.com/livekit/mediatransportutil/pkg/twcc"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
@@ -28,6 +28,13 @@ import (
 
 const (
 	ReportDelta = time.Second
+
+	// window over which packet rate is measured for duration based bucket sizing
+	bucketSizingWindow = 5 * time.Second
+	minBucketPackets   = 32
+	maxBucketPackets   = 4000
+
+	rtpFixedHeaderSize = 12
 )
 
 type pendingPacket struct {
@@ -50,8 +57,10 @@ type Buffer struct {
 	sync.RWMutex
 	bucket        *bucket.Bucket
 	nacker        *nack.NackQueue
-	videoPool     *sync.Pool
-	audioPool     *sync.Pool
+	pools         *Pools
+	pool          *Pool
+	isScreenShare bool
+	bucketPackets int
 	codecType     webrtc.RTPCodecType
 	extPackets    deque.Deque[*ExtPacket]
 	pPackets      []pendingPacket
@@ -60,10 +69,12 @@ type Buffer struct {
 	clockRate     uint32
 	lastReport    time.Time
 	twccExt       uint8
+	ccfb          bool
 	audioLevelExt uint8
 	bound         bool
 	closed        atomic.Bool
 	mime          string
+	isRED         bool
 
 	// supported feedbacks
 	latestTSForAudioLevelInitialized bool
@@ -75,6 +86,14 @@ type Buffer struct {
 
 	lastPacketRead int
 
+	// duration based bucket sizing
+	targetDuration time.Duration
+	minPackets     int
+	sizingStart    time.Time
+	sizingPackets  int
+	headSN         uint16
+	headSNValid    bool
+
 	pliThrottle int64
 
 	rtpStats             *RTPStats
@@ -83,6 +102,10 @@ type Buffer struct {
 
 	lastFractionLostToReport uint8 // Last fraction lost from subscribers, should report to publisher; Audio only
 
+	// packets of a red stream recovered from redundancy
+	redRecovered  uint32
+	redRecoverBuf []byte
+
 	// callbacks
 	onClose            func()
 	onRtcpFeedback     func([]rtcp.Packet)
@@ -98,18 +121,25 @@ type Buffer struct {
 	ddParser          *DependencyDescriptorParser
 	maxLayerChangedCB func(int32, int32)
 
+	// payloads are not inspected when set, e. g. frames encrypted by clients with insertable streams. Key frames
+	// and layers are taken from the dependency descriptor or frame marking extensions only
+	opaquePayload   bool
+	frameMarkingExt uint8
+
 	paused              bool
 	frameRateCalculator [DefaultMaxLayerSpatial + 1]FrameRateCalculator
 	frameRateCalculated bool
+
+	// kernel receive times of packets, nil unless packet timestamping is enabled
+	arrivalTimes *ArrivalTimes
 }
 
 // NewBuffer constructs a new Buffer
-func NewBuffer(ssrc uint32, vp, ap *sync.Pool) *Buffer {
+func NewBuffer(ssrc uint32, pools *Pools) *Buffer {
 	l := logger.GetLogger() // will be reset with correct context via SetLogger
 	b := &Buffer{
 		mediaSSRC:   ssrc,
-		videoPool:   vp,
-		audioPool:   ap,
+		pools:       pools,
 		pliThrottle: int64(500 * time.Millisecond),
 		logger:      l,
 	}
@@ -127,6 +157,31 @@ func (b *Buffer) SetLogger(logger logger.Logger) {
 	}
 }
 
+// SetTrackSource lets the buffer pick the pool tier for the track, needs to be called before Bind
+func (b *Buffer) SetTrackSource(source livekit.TrackSource) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.isScreenShare = source == livekit.TrackSource_SCREEN_SHARE
+}
+
+// SetOpaquePayload has the buffer leave payloads uninspected, needs to be called before Bind
+func (b *Buffer) SetOpaquePayload(opaque bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.opaquePayload = opaque
+}
+
+// SetArrivalTimes sets where the kernel receive times of packets are recorded, they are used in place of the time
+// packets are written
+func (b *Buffer) SetArrivalTimes(arrivalTimes *ArrivalTimes) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.arrivalTimes = arrivalTimes
+}
+
 func (b *Buffer) SetPaused(paused bool) {
 	b.Lock()
 	defer b.Unlock()
@@ -165,6 +220,7 @@ func (b *Buffer) Bind(params webrtc.RTPParameters, codec webrtc.RTPCodecCapabili
 	b.clockRate = codec.ClockRate
 	b.lastReport = time.Now()
 	b.mime = strings.ToLower(codec.MimeType)
+	b.isRED = b.mime == "audio/red"
 
 	for _, ext := range params.HeaderExtensions {
 		switch ext.URI {
@@ -181,19 +237,37 @@ func (b *Buffer) Bind(params webrtc.RTPParameters, codec webrtc.RTPCodecCapabili
 				frc.SetMaxLayer(spatial, temporal)
 			})
 
+		case FrameMarkingURI:
+			b.frameMarkingExt = uint8(ext.ID)
+
 		case sdp.AudioLevelURI:
 			b.audioLevelExt = uint8(ext.ID)
 			b.audioLevel = audio.NewAudioLevel(b.audioLevelParams)
 		}
 	}
+	if b.opaquePayload && strings.HasPrefix(b.mime, "video/") && b.ddExt == 0 && b.frameMarkingExt == 0 {
+		b.logger.Warnw("opaque payload without dependency descriptor or frame marking, key frames cannot be detected", nil)
+	}
 
 	switch {
 	case strings.HasPrefix(b.mime, "audio/"):
 		b.codecType = webrtc.RTPCodecTypeAudio
-		b.bucket = bucket.NewBucket(b.audioPool.Get().(*[]byte))
+		b.pool = b.pools.Audio
+		b.bucketPackets = b.pool.NumPackets()
+		b.targetDuration = b.pool.Duration()
+		b.minPackets = b.pool.MinPackets()
+		b.bucket = bucket.NewBucket(b.pool.Get())
 	case strings.HasPrefix(b.mime, "video/"):
 		b.codecType = webrtc.RTPCodecTypeVideo
-		b.bucket = bucket.NewBucket(b.videoPool.Get().(*[]byte))
+		if b.isScreenShare {
+			b.pool = b.pools.ScreenShare
+		} else {
+			b.pool = b.pools.Video
+		}
+		b.bucketPackets = b.pool.NumPackets()
+		b.targetDuration = b.pool.Duration()
+		b.minPackets = b.pool.MinPackets()
+		b.bucket = bucket.NewBucket(b.pool.Get())
 		if b.frameRateCalculator[0] == nil {
 			if strings.EqualFold(codec.MimeType, webrtc.MimeTypeVP8) {
 				b.frameRateCalculator[0] = NewFrameRateCalculatorVP8(b.clockRate, b.logger)
@@ -226,13 +300,14 @@ func (b *Buffer) Bind(params webrtc.RTPParameters, codec webrtc.RTPCodecCapabili
 					}
 				}
 			}
-		case webrtc.TypeRTCPFBNACK:
-			// pion use a single mediaengine to manage negotiated codecs of peerconnection, that means we can't have different
-			// codec settings at track level for same codec type, so enable nack for all audio receivers but don't create nack queue
-			// for red codec.
-			if strings.EqualFold(b.mime, "audio/red") {
-				return
+		case "ack":
+			if fb.Parameter == string(twcc.FormatCCFB) {
+				b.logger.Debugw("Setting feedback", "type", fb.Type, "parameter", fb.Parameter)
+				b.ccfb = true
 			}
+		case webrtc.TypeRTCPFBNACK:
+			// packets of a red stream are recovered from the redundancy of the following packets before they are
+			// NACKed, only longer bursts are retransmitted
 			b.logger.Debugw("Setting feedback", "type", webrtc.TypeRTCPFBNACK)
 			b.nacker = nack.NewNACKQueue()
 		}
@@ -260,12 +335,12 @@ func (b *Buffer) Write(pkt []byte) (n int, err error) {
 		copy(packet, pkt)
 		b.pPackets = append(b.pPackets, pendingPacket{
 			packet:      packet,
-			arrivalTime: time.Now(),
+			arrivalTime: b.arrivalTimes.Get(pkt, time.Now()),
 		})
 		return
 	}
 
-	b.calc(pkt, time.Now())
+	b.calc(pkt, b.arrivalTimes.Get(pkt, time.Now()))
 	return
 }
 
@@ -320,18 +395,15 @@ func (b *Buffer) Close() error {
 	defer b.Unlock()
 
 	b.closeOnce.Do(func() {
-		if b.bucket != nil && b.codecType == webrtc.RTPCodecTypeVideo {
-			b.videoPool.Put(b.bucket.Src())
-		}
-		if b.bucket != nil && b.codecType == webrtc.RTPCodecTypeAudio {
-			b.audioPool.Put(b.bucket.Src())
+		if b.bucket != nil && b.pool != nil {
+			b.pool.Put(b.bucket.Src())
 		}
 
 		b.closed.Store(true)
 
 		if b.rtpStats != nil {
 			b.rtpStats.Stop()
-			b.logger.Infow("rtp stats", "direction", "upstream", "stats", b.rtpStats.ToString())
+			b.logger.Infow("rtp stats", "direction", "upstream", "stats", b.rtpStats.ToString(), "redRecovered", b.redRecovered)
 			if b.onFinalRtpStats != nil {
 				b.onFinalRtpStats(b.rtpStats)
 			}
@@ -393,6 +465,11 @@ func (b *Buffer) SetRTT(rtt uint32) {
 }
 
 func (b *Buffer) calc(pkt []byte, arrivalTime time.Time) {
+	if len(pkt) < rtpFixedHeaderSize || len(pkt) > bucket.MaxPktSize {
+		b.logger.Warnw("dropping RTP packet of invalid size", nil, "size", len(pkt))
+		return
+	}
+
 	pktBuf, err := b.bucket.AddPacket(pkt)
 	if err != nil {
 		//
@@ -403,7 +480,7 @@ func (b *Buffer) calc(pkt []byte, arrivalTime time.Time) {
 		// But, do not forward those packets
 		//
 		var rtpPacket rtp.Packet
-		if uerr := rtpPacket.Unmarshal(pkt); uerr == nil {
+		if uerr := unmarshalRTP(&rtpPacket, pkt); uerr == nil {
 			b.updateStreamState(&rtpPacket, arrivalTime)
 			b.processHeaderExtensions(&rtpPacket, arrivalTime)
 		}
@@ -415,15 +492,25 @@ func (b *Buffer) calc(pkt []byte, arrivalTime time.Time) {
 	}
 
 	var p rtp.Packet
-	err = p.Unmarshal(pktBuf)
+	err = unmarshalRTP(&p, pktBuf)
 	if err != nil {
 		b.logger.Warnw("error unmarshaling RTP packet", err)
 		return
 	}
 
-	b.updateStreamState(&p, arrivalTime)
+	if !b.headSNValid || p.SequenceNumber-b.headSN < (1<<15) {
+		b.headSN = p.SequenceNumber
+		b.headSNValid = true
+	}
+	b.sizingPackets++
+
+	flowState := b.updateStreamState(&p, arrivalTime)
 	b.processHeaderExtensions(&p, arrivalTime)
 
+	if b.isRED && flowState.HasLoss {
+		b.recoverFromRED(&p, flowState, arrivalTime)
+	}
+
 	b.doNACKs()
 
 	b.doReports(arrivalTime)
@@ -486,7 +573,7 @@ func (b *Buffer) doFpsCalc(ep *ExtPacket) {
 	}
 }
 
-func (b *Buffer) updateStreamState(p *rtp.Packet, arrivalTime time.Time) {
+func (b *Buffer) updateStreamState(p *rtp.Packet, arrivalTime time.Time) RTPFlowState {
 	flowState := b.rtpStats.Update(&p.Header, len(p.Payload), int(p.PaddingSize), arrivalTime)
 
 	if b.nacker != nil {
@@ -498,12 +585,69 @@ func (b *Buffer) updateStreamState(p *rtp.Packet, arrivalTime time.Time) {
 			}
 		}
 	}
+	return flowState
+}
+
+// recoverFromRED rebuilds the packets lost right before a red packet from its redundant blocks. A recovered packet
+// has the redundant data as its primary encoding and is forwarded ahead of the packet it was recovered from. Loss
+// stats are not updated, so that the publisher keeps seeing the loss of the network and adapts its redundancy.
+func (b *Buffer) recoverFromRED(p *rtp.Packet, flowState RTPFlowState, arrivalTime time.Time) {
+	blocks, err := parseRedundantREDBlocks(p.Payload)
+	if err != nil || len(blocks) == 0 {
+		return
+	}
+
+	for i, block := range blocks {
+		// blocks are the payloads of the packets right before, oldest first
+		sn := p.SequenceNumber - uint16(len(blocks)-i)
+		if sn-flowState.LossStartInclusive >= flowState.LossEndExclusive-flowState.LossStartInclusive {
+			continue
+		}
+
+		header := p.Header
+		header.SequenceNumber = sn
+		header.Timestamp -= block.tsOffset
+		header.Marker = false
+		header.Padding = false
+		header.Extension = false
+		header.Extensions = nil
+		recovered := rtp.Packet{
+			Header:  header,
+			Payload: append([]byte{block.pt}, block.payload...),
+		}
+		if b.redRecoverBuf == nil {
+			b.redRecoverBuf = make([]byte, bucket.MaxPktSize)
+		}
+		n, err := recovered.MarshalTo(b.redRecoverBuf)
+		if err != nil {
+			continue
+		}
+		pktBuf, err := b.bucket.AddPacket(b.redRecoverBuf[:n])
+		if err != nil {
+			continue
+		}
+
+		var rp rtp.Packet
+		if err = unmarshalRTP(&rp, pktBuf); err != nil {
+			continue
+		}
+		if b.nacker != nil {
+			b.nacker.Remove(sn)
+		}
+		b.redRecovered++
+
+		if ep := b.getExtPacket(&rp, arrivalTime); ep != nil {
+			b.extPackets.PushBack(ep)
+		}
+	}
 }
 
 func (b *Buffer) processHeaderExtensions(p *rtp.Packet, arrivalTime time.Time) {
 	// submit to TWCC even if it is a padding only packet. Clients use padding only packets as probes
 	// for bandwidth estimation
-	if b.twcc != nil && b.twccExt != 0 {
+	if b.twcc != nil && b.ccfb {
+		b.twcc.PushStream(p.SSRC, p.SequenceNumber, arrivalTime.UnixNano(), p.Marker)
+	} else if b.twcc != nil && b.twccExt != 0 {
 		if ext := p.GetExtension(b.twccExt); ext != nil {
 			b.twcc.Push(binary.BigEndian.Uint16(ext[0:2]), arrivalTime.UnixNano(), p.Marker)
 		}
@@ -554,6 +698,14 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime time.Time) *Ext
 			// DD-TODO : notify active decode target change if changed.
 		}
 	}
+	if b.opaquePayload {
+		b.setOpaqueFrameInfo(ep)
+		if ep.KeyFrame && b.rtpStats != nil {
+			b.rtpStats.UpdateKeyFrame(1)
+		}
+		return ep
+	}
+
 	switch b.mime {
 	case "video/vp8":
 		vp8Packet := VP8{}
@@ -572,8 +724,7 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime time.Time) *Ext
 		ep.Payload = vp8Packet
 	case "video/vp9":
 		if ep.DependencyDescriptor == nil {
-			var vp9Packet codecs.VP9Packet
-			_, err := vp9Packet.Unmarshal(rtpPacket.Payload)
+			vp9Packet, err := UnmarshalVP9(rtpPacket.Payload)
 			if err != nil {
 				b.logger.Warnw("could not unmarshal VP9 packet", err)
 				return nil
@@ -587,6 +738,8 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime time.Time) *Ext
 		ep.KeyFrame = IsVP9KeyFrame(rtpPacket.Payload)
 	case "video/h264":
 		ep.KeyFrame = IsH264KeyFrame(rtpPacket.Payload)
+	case "video/h265":
+		ep.KeyFrame = IsH265KeyFrame(rtpPacket.Payload)
 	case "video/av1":
 		ep.KeyFrame = IsAV1KeyFrame(rtpPacket.Payload)
 	}
@@ -600,6 +753,30 @@ func (b *Buffer) getExtPacket(rtpPacket *rtp.Packet, arrivalTime time.Time) *Ext
 	return ep
 }
 
+// setOpaqueFrameInfo takes key frames and layers of a packet whose payload is not inspected from its header extensions
+func (b *Buffer) setOpaqueFrameInfo(ep *ExtPacket) {
+	if ddwdt := ep.DependencyDescriptor; ddwdt != nil {
+		// the structure is attached to the first packet of key frames
+		ep.KeyFrame = ddwdt.Descriptor.FirstPacketInFrame && ddwdt.Descriptor.AttachedStructure != nil
+		if b.mime == "video/vp8" {
+			// vp8 does not have spatial scalability, layers are simulcast streams
+			ep.Spatial = InvalidLayerSpatial
+		}
+		return
+	}
+
+	if b.frameMarkingExt == 0 {
+		return
+	}
+	if ext := ep.Packet.GetExtension(b.frameMarkingExt); ext != nil {
+		fm := FrameMarking{}
+		if err := fm.Unmarshal(ext); err == nil {
+			ep.KeyFrame = fm.IsKeyFrame()
+			ep.Temporal = int32(fm.TID)
+		}
+	}
+}
+
 func (b *Buffer) doNACKs() {
 	if b.nacker == nil {
 		return
@@ -623,6 +800,8 @@ func (b *Buffer) doReports(arrivalTime time.Time) {
 
 	b.lastReport = arrivalTime
 
+	b.maybeResizeBucket(arrivalTime)
+
 	// RTCP reports
 	pkts := b.getRTCP()
 	if pkts != nil && b.onRtcpFeedback != nil {
@@ -630,6 +809,75 @@ func (b *Buffer) doReports(arrivalTime time.Time) {
 	}
 }
 
+// maybeResizeBucket sizes the bucket to hold the target duration of media at the packet rate seen
+// over the last sizing window. It grows when short and shrinks only when at least twice the needed size.
+func (b *Buffer) maybeResizeBucket(now time.Time) {
+	if b.targetDuration == 0 || b.bucket == nil {
+		return
+	}
+
+	if b.sizingStart.IsZero() {
+		b.sizingStart = now
+		b.sizingPackets = 0
+		return
+	}
+
+	elapsed := now.Sub(b.sizingStart)
+	if elapsed < bucketSizingWindow {
+		return
+	}
+
+	packetRate := float64(b.sizingPackets) / elapsed.Seconds()
+	b.sizingStart = now
+	b.sizingPackets = 0
+
+	numPackets := int(math.Ceil(packetRate * b.targetDuration.Seconds()))
+	if numPackets < minBucketPackets {
+		numPackets = minBucketPackets
+	}
+	if numPackets < b.minPackets {
+		numPackets = b.minPackets
+	}
+	if numPackets > maxBucketPackets {
+		numPackets = maxBucketPackets
+	}
+	if numPackets <= b.bucketPackets && numPackets*2 > b.bucketPackets {
+		return
+	}
+
+	b.resizeBucket(numPackets)
+}
+
+func (b *Buffer) resizeBucket(numPackets int) {
+	buf := make([]byte, numPackets*bucket.MaxPktSize)
+	resized := bucket.NewBucket(&buf)
+
+	// carry over the most recent packets so that pending reads and retransmissions can be served
+	if b.headSNValid {
+		numToCopy := numPackets
+		if b.bucketPackets < numToCopy {
+			numToCopy = b.bucketPackets
+		}
+
+		pkt := make([]byte, bucket.MaxPktSize)
+		for i := numToCopy - 1; i >= 0; i-- {
+			n, err := b.bucket.GetPacket(pkt, b.headSN-uint16(i))
+			if err != nil {
+				continue
+			}
+			_, _ = resized.AddPacket(pkt[:n])
+		}
+	}
+
+	b.logger.Debugw("resizing bucket", "from", b.bucketPackets, "to", numPackets, "targetDuration", b.targetDuration)
+
+	// resized storage is not of the pool size, so it is not returned to the pool on close. The replaced storage is
+	// not returned either, the packet being processed and packets recovered from RED may still point into it
+	b.pool = nil
+	b.bucket = resized
+	b.bucketPackets = numPackets
+}
+
 func (b *Buffer) buildNACKPacket() ([]rtcp.Packet, int) {
 	if nacks, numSeqNumsNacked := b.nacker.Pairs(); len(nacks) > 0 {
 		pkts := []rtcp.Packet{&rtcp.TransportLayerNack{
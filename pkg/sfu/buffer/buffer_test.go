@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"encoding/hex"
 	"math"
 	"sync"
 	"testing"
@@ -8,10 +9,13 @@ import (
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 
 	"github.com/livekit/mediatransportutil/pkg/nack"
+
+	dd "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
 )
 
 var vp8Codec = webrtc.RTPCodecParameters{
@@ -33,16 +37,26 @@ var opusCodec = webrtc.RTPCodecParameters{
 	PayloadType: 96,
 }
 
+var redCodec = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{
+		MimeType:  "audio/red",
+		ClockRate: 48000,
+		RTCPFeedback: []webrtc.RTCPFeedback{{
+			Type: "nack",
+		}},
+	},
+	PayloadType: 63,
+}
+
+func newTestPools() *Pools {
+	return NewPools(PoolConfig{NumPackets: 1}, PoolConfig{NumPackets: 1}, PoolConfig{NumPackets: 1})
+}
+
 func TestNack(t *testing.T) {
-	pool := &sync.Pool{
-		New: func() interface{} {
-			b := make([]byte, 1500)
-			return &b
-		},
-	}
+	pools := newTestPools()
 
 	t.Run("nack normal", func(t *testing.T) {
-		buff := NewBuffer(123, pool, pool)
+		buff := NewBuffer(123, pools)
 		buff.codecType = webrtc.RTPCodecTypeVideo
 		require.NotNil(t, buff)
 		var wg sync.WaitGroup
@@ -87,7 +101,7 @@ func TestNack(t *testing.T) {
 	})
 
 	t.Run("nack with seq wrap", func(t *testing.T) {
-		buff := NewBuffer(123, pool, pool)
+		buff := NewBuffer(123, pools)
 		buff.codecType = webrtc.RTPCodecTypeVideo
 		require.NotNil(t, buff)
 		var wg sync.WaitGroup
@@ -146,6 +160,83 @@ func TestNack(t *testing.T) {
 	})
 }
 
+// redPacket carries the opus payloads of the packets before it as redundant blocks
+func redPacket(t *testing.T, sn uint16, redundancy int) []byte {
+	opusPayload := func(sn uint16) []byte {
+		return []byte{byte(sn), 0xfc, 0xff, 0xfe}
+	}
+
+	var header, data []byte
+	for i := redundancy; i > 0; i-- {
+		block := opusPayload(sn - uint16(i))
+		tsOffset := uint32(i) * 960
+		header = append(header, 0x80|111, byte(tsOffset>>6), byte(tsOffset<<2)|byte(len(block)>>8), byte(len(block)))
+		data = append(data, block...)
+	}
+	header = append(header, 111)
+	data = append(data, opusPayload(sn)...)
+
+	pkt := rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 63, SequenceNumber: sn, Timestamp: uint32(sn) * 960, SSRC: 123},
+		Payload: append(header, data...),
+	}
+	b, err := pkt.Marshal()
+	require.NoError(t, err)
+	return b
+}
+
+func TestREDRecovery(t *testing.T) {
+	pools := NewPools(PoolConfig{NumPackets: 100}, PoolConfig{NumPackets: 1}, PoolConfig{NumPackets: 1})
+	buff := NewBuffer(123, pools)
+	var nacked []uint16
+	buff.OnRtcpFeedback(func(fb []rtcp.Packet) {
+		for _, pkt := range fb {
+			if p, ok := pkt.(*rtcp.TransportLayerNack); ok {
+				for _, pair := range p.Nacks {
+					nacked = append(nacked, pair.PacketList()...)
+				}
+			}
+		}
+	})
+	buff.Bind(webrtc.RTPParameters{
+		Codecs: []webrtc.RTPCodecParameters{redCodec},
+	}, redCodec.RTPCodecCapability)
+	require.NotNil(t, buff.nacker)
+
+	// 2 is covered by the redundancy of 3, 5 and 6 by the redundancy of 7, the burst of 9 to 11 is longer than the
+	// redundancy of 12 covers
+	for _, sn := range []uint16{0, 1, 3, 4, 7, 8, 12} {
+		_, err := buff.Write(redPacket(t, sn, 2))
+		require.NoError(t, err)
+	}
+	// only what could not be recovered is NACKed
+	buff.nacker.SetRTT(20)
+	time.Sleep(100 * time.Millisecond)
+	_, err := buff.Write(redPacket(t, 13, 2))
+	require.NoError(t, err)
+	require.Equal(t, []uint16{9}, nacked)
+
+	buf := make([]byte, 1500)
+	var read []uint16
+	for buff.extPackets.Len() > 0 {
+		ep, err := buff.ReadExtended(buf)
+		require.NoError(t, err)
+		read = append(read, ep.Packet.SequenceNumber)
+
+		if ep.Packet.SequenceNumber == 2 {
+			require.Equal(t, uint32(2*960), ep.Packet.Timestamp)
+			require.Equal(t, []byte{111, 2, 0xfc, 0xff, 0xfe}, ep.Packet.Payload)
+		}
+	}
+	require.Equal(t, []uint16{0, 1, 2, 3, 4, 5, 6, 7, 8, 10, 11, 12, 13}, read)
+	require.EqualValues(t, 5, buff.redRecovered)
+
+	// the original arriving late is not forwarded again
+	_, err = buff.Write(redPacket(t, 2, 2))
+	require.NoError(t, err)
+	require.Zero(t, buff.extPackets.Len())
+}
+
 func TestNewBuffer(t *testing.T) {
 	tests := []struct {
 		name string
@@ -178,13 +269,8 @@ func TestNewBuffer(t *testing.T) {
 					},
 				},
 			}
-			pool := &sync.Pool{
-				New: func() interface{} {
-					b := make([]byte, 1500)
-					return &b
-				},
-			}
-			buff := NewBuffer(123, pool, pool)
+			pools := newTestPools()
+			buff := NewBuffer(123, pools)
 			buff.codecType = webrtc.RTPCodecTypeVideo
 			require.NotNil(t, buff)
 			buff.OnRtcpFeedback(func(_ []rtcp.Packet) {
@@ -205,13 +291,8 @@ func TestNewBuffer(t *testing.T) {
 }
 
 func TestFractionLostReport(t *testing.T) {
-	pool := &sync.Pool{
-		New: func() interface{} {
-			b := make([]byte, 1500)
-			return &b
-		},
-	}
-	buff := NewBuffer(123, pool, pool)
+	pools := newTestPools()
+	buff := NewBuffer(123, pools)
 	require.NotNil(t, buff)
 	buff.codecType = webrtc.RTPCodecTypeVideo
 	var wg sync.WaitGroup
@@ -247,3 +328,325 @@ func TestFractionLostReport(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestBucketResize(t *testing.T) {
+	pools := NewPools(
+		PoolConfig{NumPackets: 1},
+		PoolConfig{NumPackets: 10, Duration: 2 * time.Second},
+		PoolConfig{NumPackets: 1},
+	)
+	buff := NewBuffer(123, pools)
+	buff.OnRtcpFeedback(func(_ []rtcp.Packet) {})
+	buff.Bind(webrtc.RTPParameters{
+		HeaderExtensions: nil,
+		Codecs:           []webrtc.RTPCodecParameters{vp8Codec},
+	}, vp8Codec.RTPCodecCapability)
+	require.Equal(t, 10, buff.bucketPackets)
+
+	for i := 0; i < 100; i++ {
+		pkt := rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(65500 + i), Timestamp: uint32(i)},
+			Payload: []byte{0xff, 0xff, 0xff, 0xfd, 0xb4, 0x9f, 0x94, 0x1},
+		}
+		b, err := pkt.Marshal()
+		require.NoError(t, err)
+		_, err = buff.Write(b)
+		require.NoError(t, err)
+	}
+
+	// 50 packets/second at 2 seconds target duration needs 100 packets of storage
+	now := time.Now()
+	buff.Lock()
+	buff.sizingStart = now.Add(-bucketSizingWindow)
+	buff.sizingPackets = 50 * int(bucketSizingWindow.Seconds())
+	buff.maybeResizeBucket(now)
+	buff.Unlock()
+	require.Equal(t, 100, buff.bucketPackets)
+	require.Nil(t, buff.pool)
+	// replaced storage may still be referenced by packets in flight, it is not handed out again
+	require.Zero(t, pools.Video.Stats().Puts)
+
+	// most recent packets are carried over, wrapping around
+	pkt := make([]byte, 1500)
+	for i := 90; i < 100; i++ {
+		_, err := buff.GetPacket(pkt, uint16(65500+i))
+		require.NoError(t, err)
+	}
+	_, err := buff.GetPacket(pkt, uint16(65500+89-65536))
+	require.Error(t, err)
+
+	// no change within hysteresis
+	buff.Lock()
+	buff.sizingStart = now.Add(-bucketSizingWindow)
+	buff.sizingPackets = 35 * int(bucketSizingWindow.Seconds())
+	buff.maybeResizeBucket(now)
+	buff.Unlock()
+	require.Equal(t, 100, buff.bucketPackets)
+
+	// does not shrink below the minimum of the pool
+	buff.minPackets = 80
+	buff.Lock()
+	buff.sizingStart = now.Add(-bucketSizingWindow)
+	buff.sizingPackets = 10 * int(bucketSizingWindow.Seconds())
+	buff.maybeResizeBucket(now)
+	buff.Unlock()
+	require.Equal(t, 100, buff.bucketPackets)
+}
+
+// fuzz inputs are sequences of packets, each prefixed by its length as a big endian uint16
+func joinPackets(pkts ...[]byte) []byte {
+	var data []byte
+	for _, pkt := range pkts {
+		data = append(data, byte(len(pkt)>>8), byte(len(pkt)))
+		data = append(data, pkt...)
+	}
+	return data
+}
+
+func splitPackets(data []byte) [][]byte {
+	var pkts [][]byte
+	for len(data) >= 2 {
+		size := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if size > len(data) {
+			size = len(data)
+		}
+		pkts = append(pkts, data[:size])
+		data = data[size:]
+	}
+	return pkts
+}
+
+const (
+	fuzzAudioLevelExtID = 1
+	fuzzDDExtID         = 8
+)
+
+func fuzzRTPSeed(f *testing.F, sn uint16, ts uint32, payload []byte, extID uint8, ext []byte) []byte {
+	pkt := rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			SequenceNumber: sn,
+			Timestamp:      ts,
+			SSRC:           123,
+		},
+		Payload: payload,
+	}
+	if ext != nil {
+		if err := pkt.SetExtension(extID, ext); err != nil {
+			f.Fatal(err)
+		}
+	}
+	b, err := pkt.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	return b
+}
+
+// FuzzBufferWrite feeds RTP packets to buffers of each codec with the dependency descriptor and audio level
+// extensions negotiated, packets are processed like the ones read from a publisher
+func FuzzBufferWrite(f *testing.F) {
+	// dependency descriptors of a L3T3 AV1 stream captured from Chrome, the first one attaches the structure
+	ddKeyFrame, _ := hex.DecodeString("c1017280081485214eafffaaaa863cf0430c10c302afc0aaa0063c00430010c002a000a80006000040001d954926e082b04a0941b820ac1282503157f974000ca864330e222222eca8655304224230eca877530077004200ef008601df010d")
+	ddDelta, _ := hex.DecodeString("86017340fc")
+	vp8KeyFrame := []byte{0xff, 0xff, 0xff, 0xfd, 0xb4, 0x9f, 0x94, 0x1}
+	vp8Delta := []byte{0xff, 0xff, 0xff, 0xfd, 0xb4, 0x9f, 0x95, 0x1}
+	h264IDR := []byte{0x78, 0x00, 0x0a, 0x67, 0x42, 0xc0, 0x1f, 0x8c, 0x8d, 0x40, 0x50, 0x1e, 0xd0, 0x0f, 0x00, 0x04, 0x68, 0xce, 0x3c, 0x80, 0x00, 0x03, 0x65, 0x88, 0x84}
+	av1Sequence := []byte{0x28, 0x0a, 0x0b, 0x00, 0x00, 0x00, 0x24, 0xc4, 0xff, 0xdf, 0x00, 0x68, 0x02}
+	vp9KeyFrame := []byte{0x8f, 0xa0, 0xfd, 0x18, 0x07, 0x80, 0x03, 0x24, 0x02, 0xcf, 0x00, 0x00}
+	opus := []byte{0xf8, 0xff, 0xfe}
+
+	f.Add(joinPackets(
+		fuzzRTPSeed(f, 100, 3000, vp8KeyFrame, fuzzDDExtID, ddKeyFrame),
+		fuzzRTPSeed(f, 101, 6000, vp8Delta, fuzzDDExtID, ddDelta),
+		fuzzRTPSeed(f, 103, 9000, vp8Delta, 0, nil),
+	))
+	f.Add(joinPackets(
+		fuzzRTPSeed(f, 65535, 3000, h264IDR, 0, nil),
+		fuzzRTPSeed(f, 0, 3000, av1Sequence, fuzzDDExtID, ddKeyFrame),
+		fuzzRTPSeed(f, 1, 3000, vp9KeyFrame, 0, nil),
+	))
+	f.Add(joinPackets(
+		fuzzRTPSeed(f, 10, 960, opus, fuzzAudioLevelExtID, []byte{0x9f}),
+		fuzzRTPSeed(f, 11, 1920, opus, fuzzAudioLevelExtID, []byte{0x20}),
+	))
+	f.Add(joinPackets(fuzzRTPSeed(f, 5, 0, nil, 0, nil)))
+
+	codecs := []webrtc.RTPCodecParameters{vp8Codec, opusCodec}
+	for _, mime := range []string{webrtc.MimeTypeVP9, webrtc.MimeTypeH264, webrtc.MimeTypeAV1} {
+		codec := vp8Codec
+		codec.MimeType = mime
+		codecs = append(codecs, codec)
+	}
+	headerExtensions := []webrtc.RTPHeaderExtensionParameter{
+		{URI: sdp.AudioLevelURI, ID: fuzzAudioLevelExtID},
+		{URI: dd.ExtensionUrl, ID: fuzzDDExtID},
+	}
+	pools := newTestPools()
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pkts := splitPackets(data)
+		for _, codec := range codecs {
+			buff := NewBuffer(123, pools)
+			buff.OnRtcpFeedback(func(_ []rtcp.Packet) {})
+			buff.Bind(webrtc.RTPParameters{
+				HeaderExtensions: headerExtensions,
+				Codecs:           []webrtc.RTPCodecParameters{codec},
+			}, codec.RTPCodecCapability)
+
+			buf := make([]byte, 1500)
+			for _, pkt := range pkts {
+				_, _ = buff.Write(pkt)
+
+				buff.Lock()
+				for buff.extPackets.Len() > 0 {
+					buff.patchExtPacket(buff.extPackets.PopFront(), buf)
+				}
+				buff.Unlock()
+			}
+			buff.getRTCP()
+			_ = buff.Close()
+		}
+	})
+}
+
+// FuzzRTCP feeds RTCP compound packets through the handling of publisher sender reports and of subscriber receiver
+// reports and feedback
+func FuzzRTCP(f *testing.F) {
+	seeds := [][]rtcp.Packet{
+		{
+			&rtcp.SenderReport{SSRC: 123, NTPTime: 0xe7a5c1d35ee978d4, RTPTime: 3000, PacketCount: 100, OctetCount: 120000},
+			&rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+				Source: 123,
+				Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: "oX1pxhmyyL9Fkb6A"}},
+			}}},
+		},
+		{
+			&rtcp.ReceiverReport{SSRC: 1, Reports: []rtcp.ReceptionReport{{
+				SSRC:               123,
+				FractionLost:       12,
+				TotalLost:          3,
+				LastSequenceNumber: 100,
+				Jitter:             90,
+				LastSenderReport:   0xc1d35ee9,
+				Delay:              655,
+			}}},
+			&rtcp.ReceiverEstimatedMaximumBitrate{SenderSSRC: 1, Bitrate: 1_500_000, SSRCs: []uint32{123}},
+		},
+		{
+			&rtcp.TransportLayerNack{SenderSSRC: 1, MediaSSRC: 123, Nacks: rtcp.NackPairsFromSequenceNumbers([]uint16{10, 11, 13, 40})},
+			&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 123},
+			&rtcp.FullIntraRequest{SenderSSRC: 1, MediaSSRC: 123, FIR: []rtcp.FIREntry{{SSRC: 123, SequenceNumber: 1}}},
+		},
+		{
+			&rtcp.TransportLayerCC{
+				SenderSSRC:         1,
+				MediaSSRC:          123,
+				BaseSequenceNumber: 100,
+				PacketStatusCount:  2,
+				ReferenceTime:      4057090,
+				FbPktCount:         23,
+				PacketChunks: []rtcp.PacketStatusChunk{
+					&rtcp.RunLengthChunk{PacketStatusSymbol: rtcp.TypeTCCPacketReceivedSmallDelta, RunLength: 2},
+				},
+				RecvDeltas: []*rtcp.RecvDelta{
+					{Type: rtcp.TypeTCCPacketReceivedSmallDelta, Delta: 4000},
+					{Type: rtcp.TypeTCCPacketReceivedSmallDelta, Delta: 8000},
+				},
+			},
+		},
+	}
+	for _, pkts := range seeds {
+		b, err := rtcp.Marshal(pkts)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+
+	pools := newTestPools()
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buff := NewBuffer(123, pools)
+		buff.OnRtcpFeedback(func(_ []rtcp.Packet) {})
+		buff.Bind(webrtc.RTPParameters{
+			Codecs: []webrtc.RTPCodecParameters{vp8Codec},
+		}, vp8Codec.RTPCodecCapability)
+		defer buff.Close()
+
+		downstreamStats := NewRTPStats(RTPStatsParams{
+			ClockRate: vp8Codec.ClockRate,
+			Logger:    buff.logger,
+		})
+		defer downstreamStats.Stop()
+
+		pkts, err := UnmarshalRTCP(data)
+		if err != nil {
+			return
+		}
+
+		for _, pkt := range pkts {
+			switch p := pkt.(type) {
+			case *rtcp.SenderReport:
+				buff.SetSenderReportData(p.RTPTime, p.NTPTime)
+			case *rtcp.ReceiverReport:
+				for _, r := range p.Reports {
+					downstreamStats.UpdateFromReceiverReport(r)
+				}
+			case *rtcp.TransportLayerNack:
+				var numNACKs uint32
+				for _, pair := range p.Nacks {
+					numNACKs += uint32(len(pair.PacketList()))
+				}
+				downstreamStats.UpdateNack(numNACKs)
+			}
+		}
+		buff.getRTCP()
+		downstreamStats.ToProto()
+	})
+}
+
+func TestOpaquePayload(t *testing.T) {
+	pools := NewPools(PoolConfig{NumPackets: 100}, PoolConfig{NumPackets: 1}, PoolConfig{NumPackets: 1})
+	buff := NewBuffer(123, pools)
+	buff.SetOpaquePayload(true)
+	buff.Bind(webrtc.RTPParameters{
+		HeaderExtensions: []webrtc.RTPHeaderExtensionParameter{{URI: FrameMarkingURI, ID: 5}},
+		Codecs:           []webrtc.RTPCodecParameters{vp8Codec},
+	}, vp8Codec.RTPCodecCapability)
+
+	buf := make([]byte, 1500)
+	write := func(sn uint16, frameMarking byte) *ExtPacket {
+		pkt := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    96,
+				SequenceNumber: sn,
+				Timestamp:      uint32(sn) * 3000,
+				SSRC:           123,
+			},
+			// encrypted, not a VP8 payload descriptor
+			Payload: []byte{0xff},
+		}
+		require.NoError(t, pkt.SetExtension(5, []byte{frameMarking, 0x00, 0x00}))
+		b, err := pkt.Marshal()
+		require.NoError(t, err)
+		_, err = buff.Write(b)
+		require.NoError(t, err)
+
+		ep, err := buff.ReadExtended(buf)
+		require.NoError(t, err)
+		return ep
+	}
+
+	ep := write(1, 0xe0)
+	require.True(t, ep.KeyFrame)
+	require.Equal(t, int32(0), ep.Temporal)
+	require.Nil(t, ep.Payload)
+
+	ep = write(2, 0xc1)
+	require.False(t, ep.KeyFrame)
+	require.Equal(t, int32(1), ep.Temporal)
+}
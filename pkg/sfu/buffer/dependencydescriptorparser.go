@@ -31,6 +31,8 @@ func NewDependencyDescriptorParser(ddExtID uint8, logger logger.Logger, onMaxLay
 type DependencyDescriptorWithDecodeTarget struct {
 	Descriptor    *dd.DependencyDescriptor
 	DecodeTargets []DependencyDescriptorDecodeTarget
+	// structure the descriptor was parsed with, attached to an earlier packet of the stream if not to this one
+	Structure *dd.FrameDependencyStructure
 }
 
 func (r *DependencyDescriptorParser) Parse(pkt *rtp.Packet) (*DependencyDescriptorWithDecodeTarget, VideoLayer, error) {
@@ -92,6 +94,7 @@ func (r *DependencyDescriptorParser) Parse(pkt *rtp.Packet) (*DependencyDescript
 	withDecodeTargets := &DependencyDescriptorWithDecodeTarget{
 		Descriptor:    &ddVal,
 		DecodeTargets: r.decodeTargets,
+		Structure:     r.structure,
 	}
 
 	return withDecodeTargets, videoLayer, nil
@@ -129,6 +132,42 @@ func ProcessFrameDependencyStructure(structure *dd.FrameDependencyStructure) []D
 	return decodeTargets
 }
 
+// IsKSVC returns true if spatial layers depend on lower spatial layers only at key frames, like the _KEY
+// scalability modes (L3T3_KEY). A decode target of such a stream needs just one frame of each lower spatial layer,
+// the one of the key frame, and the bitrate of a spatial layer does not include the lower layers.
+func IsKSVC(structure *dd.FrameDependencyStructure) bool {
+	if structure == nil {
+		return false
+	}
+
+	decodeTargets := ProcessFrameDependencyStructure(structure)
+	hasSpatialLayers := false
+	for _, dt := range decodeTargets {
+		if dt.Layer.Spatial == 0 {
+			continue
+		}
+		hasSpatialLayers = true
+
+		// count the frames of each lower spatial layer the decode target needs
+		numTemplates := make(map[int]int)
+		for _, t := range structure.Templates {
+			if t.SpatialId >= int(dt.Layer.Spatial) || dt.Target >= len(t.DecodeTargetIndications) {
+				continue
+			}
+			if t.DecodeTargetIndications[dt.Target] == dd.DecodeTargetNotPresent {
+				continue
+			}
+
+			numTemplates[t.SpatialId]++
+			if numTemplates[t.SpatialId] > 1 {
+				return false
+			}
+		}
+	}
+
+	return hasSpatialLayers
+}
+
 func GetActiveDecodeTargetBitmask(layer VideoLayer, decodeTargets []DependencyDescriptorDecodeTarget) *uint32 {
 	activeBitMask := uint32(0)
 	var maxSpatial, maxTemporal int32
@@ -0,0 +1,54 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	dd "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
+)
+
+func TestIsKSVC(t *testing.T) {
+	const (
+		n = dd.DecodeTargetNotPresent
+		s = dd.DecodeTargetSwitch
+		r = dd.DecodeTargetRequired
+	)
+
+	// L2T1_KEY, delta frames of S0 are not needed by S1
+	ksvc := &dd.FrameDependencyStructure{
+		NumDecodeTargets: 2,
+		Templates: []*dd.FrameDependencyTemplate{
+			{SpatialId: 0, DecodeTargetIndications: []dd.DecodeTargetIndication{s, s}},
+			{SpatialId: 0, DecodeTargetIndications: []dd.DecodeTargetIndication{s, n}, FrameDiffs: []int{2}},
+			{SpatialId: 1, DecodeTargetIndications: []dd.DecodeTargetIndication{n, s}, FrameDiffs: []int{1}},
+			{SpatialId: 1, DecodeTargetIndications: []dd.DecodeTargetIndication{n, r}, FrameDiffs: []int{2}},
+		},
+	}
+	require.True(t, IsKSVC(ksvc))
+
+	// L2T1, S1 needs every frame of S0
+	svc := &dd.FrameDependencyStructure{
+		NumDecodeTargets: 2,
+		Templates: []*dd.FrameDependencyTemplate{
+			{SpatialId: 0, DecodeTargetIndications: []dd.DecodeTargetIndication{s, s}},
+			{SpatialId: 0, DecodeTargetIndications: []dd.DecodeTargetIndication{s, r}, FrameDiffs: []int{2}},
+			{SpatialId: 1, DecodeTargetIndications: []dd.DecodeTargetIndication{n, s}, FrameDiffs: []int{1}},
+			{SpatialId: 1, DecodeTargetIndications: []dd.DecodeTargetIndication{n, r}, FrameDiffs: []int{2, 1}},
+		},
+	}
+	require.False(t, IsKSVC(svc))
+
+	// L1T3, no spatial layers
+	temporal := &dd.FrameDependencyStructure{
+		NumDecodeTargets: 3,
+		Templates: []*dd.FrameDependencyTemplate{
+			{TemporalId: 0, DecodeTargetIndications: []dd.DecodeTargetIndication{s, s, s}},
+			{TemporalId: 1, DecodeTargetIndications: []dd.DecodeTargetIndication{n, s, r}, FrameDiffs: []int{2}},
+			{TemporalId: 2, DecodeTargetIndications: []dd.DecodeTargetIndication{n, n, s}, FrameDiffs: []int{1}},
+		},
+	}
+	require.False(t, IsKSVC(temporal))
+
+	require.False(t, IsKSVC(nil))
+}
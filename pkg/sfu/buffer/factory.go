@@ -5,47 +5,59 @@ import (
 	"sync"
 
 	"github.com/pion/transport/v2/packetio"
+)
 
-	"github.com/livekit/mediatransportutil/pkg/bucket"
+const (
+	DefaultAudioBufferPackets       = 200
+	DefaultVideoBufferPackets       = 500
+	DefaultScreenShareBufferPackets = 500
 )
 
 type FactoryOfBufferFactory struct {
-	videoPool *sync.Pool
-	audioPool *sync.Pool
+	pools        *Pools
+	arrivalTimes *ArrivalTimes
 }
 
-func NewFactoryOfBufferFactory(trackingPackets int) *FactoryOfBufferFactory {
+func NewFactoryOfBufferFactory(audio, video, screenShare PoolConfig) *FactoryOfBufferFactory {
+	if audio.NumPackets <= 0 {
+		audio.NumPackets = DefaultAudioBufferPackets
+	}
+	if video.NumPackets <= 0 {
+		video.NumPackets = DefaultVideoBufferPackets
+	}
+	if screenShare.NumPackets <= 0 {
+		screenShare.NumPackets = DefaultScreenShareBufferPackets
+	}
 	return &FactoryOfBufferFactory{
-		videoPool: &sync.Pool{
-			New: func() interface{} {
-				b := make([]byte, trackingPackets*bucket.MaxPktSize)
-				return &b
-			},
-		},
-		audioPool: &sync.Pool{
-			New: func() interface{} {
-				b := make([]byte, bucket.MaxPktSize*200)
-				return &b
-			},
-		},
+		pools: NewPools(audio, video, screenShare),
 	}
 }
 
+// SetArrivalTimes sets where the kernel receive times of packets are recorded, for the buffers of factories created
+// afterwards
+func (f *FactoryOfBufferFactory) SetArrivalTimes(arrivalTimes *ArrivalTimes) {
+	f.arrivalTimes = arrivalTimes
+}
+
 func (f *FactoryOfBufferFactory) CreateBufferFactory() *Factory {
 	return &Factory{
-		videoPool:   f.videoPool,
-		audioPool:   f.audioPool,
-		rtpBuffers:  make(map[uint32]*Buffer),
-		rtcpReaders: make(map[uint32]*RTCPReader),
+		pools:        f.pools,
+		arrivalTimes: f.arrivalTimes,
+		rtpBuffers:   make(map[uint32]*Buffer),
+		rtcpReaders:  make(map[uint32]*RTCPReader),
 	}
 }
 
+func (f *FactoryOfBufferFactory) PoolStats() (audio PoolStats, video PoolStats, screenShare PoolStats) {
+	return f.pools.Audio.Stats(), f.pools.Video.Stats(), f.pools.ScreenShare.Stats()
+}
+
 type Factory struct {
 	sync.RWMutex
-	videoPool   *sync.Pool
-	audioPool   *sync.Pool
-	rtpBuffers  map[uint32]*Buffer
-	rtcpReaders map[uint32]*RTCPReader
+	pools        *Pools
+	arrivalTimes *ArrivalTimes
+	rtpBuffers   map[uint32]*Buffer
+	rtcpReaders  map[uint32]*RTCPReader
 }
 
 func (f *Factory) GetOrNew(packetType packetio.BufferPacketType, ssrc uint32) io.ReadWriteCloser {
@@ -68,7 +80,8 @@ func (f *Factory) GetOrNew(packetType packetio.BufferPacketType, ssrc uint32) io
 		if reader, ok := f.rtpBuffers[ssrc]; ok {
 			return reader
 		}
-		buffer := NewBuffer(ssrc, f.videoPool, f.audioPool)
+		buffer := NewBuffer(ssrc, f.pools)
+		buffer.SetArrivalTimes(f.arrivalTimes)
 		f.rtpBuffers[ssrc] = buffer
 		buffer.OnClose(func() {
 			f.Lock()
@@ -333,6 +333,13 @@ func (f *FrameRateCalculatorDD) Completed() bool {
 }
 
 func (f *FrameRateCalculatorDD) SetMaxLayer(spatial, temporal int32) {
+	// layers come from the structure sent by the publisher, do not go past the tracked ones
+	if spatial > DefaultMaxLayerSpatial {
+		spatial = DefaultMaxLayerSpatial
+	}
+	if temporal > DefaultMaxLayerTemporal {
+		temporal = DefaultMaxLayerTemporal
+	}
 	f.maxSpatial, f.maxTemporal = spatial, temporal
 }
 
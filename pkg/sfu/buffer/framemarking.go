@@ -0,0 +1,62 @@
+package buffer
+
+// FrameMarkingURI is the header extension marking frame boundaries, independent frames and temporal layers without
+// looking into the payload (draft-ietf-avtext-framemarking)
+const FrameMarkingURI = "urn:ietf:params:rtp-hdrext:framemarking"
+
+// FrameMarking is a helper to get frame data from the frame marking extension
+/*
+	Short form, non-scalable streams
+			0 1 2 3 4 5 6 7
+			+-+-+-+-+-+-+-+-+
+			|S|E|I|D|0 0 0 0|
+			+-+-+-+-+-+-+-+-+
+
+	Long form, scalable streams
+			0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+			|S|E|I|D|B| TID |      LID      |   TL0PICIDX   |
+			+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type FrameMarking struct {
+	StartOfFrame  bool
+	EndOfFrame    bool
+	Independent   bool
+	Discardable   bool
+	BaseLayerSync bool
+	TID           uint8
+	LID           uint8
+	TL0PICIDX     uint8
+}
+
+// Unmarshal parses the frame marking extension
+func (f *FrameMarking) Unmarshal(ext []byte) error {
+	if f == nil {
+		return errNilPacket
+	}
+	if len(ext) == 0 {
+		return errShortPacket
+	}
+
+	f.StartOfFrame = ext[0]&0x80 != 0
+	f.EndOfFrame = ext[0]&0x40 != 0
+	f.Independent = ext[0]&0x20 != 0
+	f.Discardable = ext[0]&0x10 != 0
+	if len(ext) == 1 {
+		return nil
+	}
+	if len(ext) < 3 {
+		return errShortPacket
+	}
+
+	f.BaseLayerSync = ext[0]&0x08 != 0
+	f.TID = ext[0] & 0x07
+	f.LID = ext[1]
+	f.TL0PICIDX = ext[2]
+	return nil
+}
+
+// IsKeyFrame tells whether the packet starts an independent frame
+func (f *FrameMarking) IsKeyFrame() bool {
+	return f.StartOfFrame && f.Independent
+}
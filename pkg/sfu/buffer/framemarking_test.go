@@ -0,0 +1,37 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameMarking_Unmarshal(t *testing.T) {
+	t.Run("short form", func(t *testing.T) {
+		fm := FrameMarking{}
+		require.NoError(t, fm.Unmarshal([]byte{0xa0}))
+		require.True(t, fm.StartOfFrame)
+		require.False(t, fm.EndOfFrame)
+		require.True(t, fm.Independent)
+		require.True(t, fm.IsKeyFrame())
+	})
+
+	t.Run("long form", func(t *testing.T) {
+		fm := FrameMarking{}
+		require.NoError(t, fm.Unmarshal([]byte{0x5a, 0x01, 0x07}))
+		require.False(t, fm.StartOfFrame)
+		require.True(t, fm.EndOfFrame)
+		require.True(t, fm.Discardable)
+		require.True(t, fm.BaseLayerSync)
+		require.Equal(t, uint8(2), fm.TID)
+		require.Equal(t, uint8(1), fm.LID)
+		require.Equal(t, uint8(7), fm.TL0PICIDX)
+		require.False(t, fm.IsKeyFrame())
+	})
+
+	t.Run("short", func(t *testing.T) {
+		fm := FrameMarking{}
+		require.Error(t, fm.Unmarshal(nil))
+		require.Error(t, fm.Unmarshal([]byte{0x80, 0x01}))
+	})
+}
@@ -4,6 +4,10 @@ import (
 	"encoding/binary"
 	"errors"
 
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+
 	"github.com/livekit/protocol/logger"
 )
 
@@ -159,7 +163,8 @@ func (v *VP8) MarshalTo(buf []byte) error {
 
 	idx := 0
 	buf[idx] = v.FirstByte
-	if v.I || v.L || v.T || v.K {
+	// keep an extension byte without any fields, it is accounted for in the header size
+	if v.I || v.L || v.T || v.K || v.FirstByte&0x80 != 0 {
 		buf[idx] |= 0x80 // X bit
 		idx++
 
@@ -213,6 +218,47 @@ func (v *VP8) MarshalTo(buf []byte) error {
 
 // -------------------------------------
 
+// unmarshalRTP parses an RTP packet, pion does not bounds check header
+// extension elements and panics on one running past the extension block
+func unmarshalRTP(p *rtp.Packet, buf []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errInvalidPacket
+		}
+	}()
+
+	return p.Unmarshal(buf)
+}
+
+// UnmarshalRTCP parses a compound RTCP packet, pion does not bounds check
+// some feedback packets, a NACK without any pairs panics
+func UnmarshalRTCP(buf []byte) (pkts []rtcp.Packet, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkts, err = nil, errInvalidPacket
+		}
+	}()
+
+	return rtcp.Unmarshal(buf)
+}
+
+// -------------------------------------
+
+// UnmarshalVP9 parses the VP9 payload descriptor, pion does not bounds check
+// the scalability structure and panics on a truncated one
+func UnmarshalVP9(payload []byte) (vp9Packet codecs.VP9Packet, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errShortPacket
+		}
+	}()
+
+	_, err = vp9Packet.Unmarshal(payload)
+	return
+}
+
+// -------------------------------------
+
 func VPxPictureIdSizeDiff(mBit1 bool, mBit2 bool) int {
 	if mBit1 == mBit2 {
 		return 0
@@ -296,6 +342,61 @@ func IsH264KeyFrame(payload []byte) bool {
 
 // -------------------------------------
 
+const (
+	h265NALTypeBLAWLP = 16
+	h265NALTypeCRA    = 21
+	h265NALTypeVPS    = 32
+	h265NALTypeSPS    = 33
+	h265NALTypeAP     = 48
+	h265NALTypeFU     = 49
+)
+
+// isH265KeyFrameNALU is true for parameter sets and for IRAP pictures (BLA, IDR and CRA), which some encoders send
+// without repeating the parameter sets
+func isH265KeyFrameNALU(nalu byte) bool {
+	return nalu == h265NALTypeVPS || nalu == h265NALTypeSPS || (nalu >= h265NALTypeBLAWLP && nalu <= h265NALTypeCRA)
+}
+
+// IsH265KeyFrame detects if h265 payload is a keyframe, either its parameter sets or an IRAP picture, also when
+// aggregated or fragmented, https://datatracker.ietf.org/doc/html/rfc7798#section-4.4
+func IsH265KeyFrame(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+	nalu := (payload[0] >> 1) & 0x3F
+	switch nalu {
+	case h265NALTypeAP:
+		// aggregation packet, 16 bit size prefixed NAL units following the payload header
+		i := 2
+		for i+2 <= len(payload) {
+			length := int(payload[i])<<8 | int(payload[i+1])
+			i += 2
+			if length < 2 || i+length > len(payload) {
+				return false
+			}
+			if isH265KeyFrameNALU((payload[i] >> 1) & 0x3F) {
+				return true
+			}
+			i += length
+		}
+		return false
+	case h265NALTypeFU:
+		// fragmentation unit, the FU header follows the payload header
+		if len(payload) < 3 {
+			return false
+		}
+		if (payload[2] & 0x80) == 0 {
+			// not a starting fragment
+			return false
+		}
+		return isH265KeyFrameNALU(payload[2] & 0x3F)
+	default:
+		return isH265KeyFrameNALU(nalu)
+	}
+}
+
+// -------------------------------------
+
 func IsVP9KeyFrame(payload []byte) bool {
 	payloadLen := len(payload)
 	if payloadLen < 1 {
@@ -372,6 +473,10 @@ func IsAV1KeyFrame(payload []byte) bool {
 			if len(data) <= offset {
 				return nil, offset, offset > 0
 			}
+			if offset == 8 {
+				// leb128 values are at most 8 bytes, a longer one would overflow the length
+				return nil, offset, false
+			}
 			l := data[offset]
 			length |= int(l&0x7f) << (offset * 7)
 			offset++
@@ -425,3 +530,45 @@ func IsAV1KeyFrame(payload []byte) bool {
 }
 
 // -------------------------------------
+
+type redBlock struct {
+	pt       uint8
+	tsOffset uint32
+	length   int
+	payload  []byte
+}
+
+// parseRedundantREDBlocks returns the redundant blocks of a RED payload, oldest first. Each block carries the
+// payload of one of the packets preceding the RED packet, https://datatracker.ietf.org/doc/html/rfc2198#section-3
+func parseRedundantREDBlocks(payload []byte) ([]redBlock, error) {
+	var blocks []redBlock
+	for {
+		if len(payload) == 0 {
+			return nil, errShortPacket
+		}
+		if payload[0]&0x80 == 0 {
+			// header of the primary encoding
+			payload = payload[1:]
+			break
+		}
+		if len(payload) < 4 {
+			return nil, errShortPacket
+		}
+		blockHead := binary.BigEndian.Uint32(payload)
+		blocks = append(blocks, redBlock{
+			pt:       uint8(blockHead>>24) & 0x7f,
+			tsOffset: (blockHead >> 10) & 0x3fff,
+			length:   int(blockHead & 0x03ff),
+		})
+		payload = payload[4:]
+	}
+
+	for i := range blocks {
+		if len(payload) < blocks[i].length {
+			return nil, errShortPacket
+		}
+		blocks[i].payload = payload[:blocks[i].length]
+		payload = payload[blocks[i].length:]
+	}
+	return blocks, nil
+}
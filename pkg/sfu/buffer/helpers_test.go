@@ -94,3 +94,132 @@ func TestVP8Helper_Unmarshal(t *testing.T) {
 }
 
 // ------------------------------------------
+
+func TestIsH265KeyFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  []byte
+		keyFrame bool
+	}{
+		{
+			name:    "Empty payload",
+			payload: []byte{},
+		},
+		{
+			name:     "Single SPS NAL unit",
+			payload:  []byte{0x42, 0x01, 0x01, 0x01, 0x60},
+			keyFrame: true,
+		},
+		{
+			name:     "Single VPS NAL unit",
+			payload:  []byte{0x40, 0x01, 0x0c, 0x01},
+			keyFrame: true,
+		},
+		{
+			name:     "Single CRA NAL unit",
+			payload:  []byte{0x2a, 0x01, 0xaf, 0x0d},
+			keyFrame: true,
+		},
+		{
+			name:    "Single trailing picture NAL unit",
+			payload: []byte{0x02, 0x01, 0xd0, 0x0e},
+		},
+		{
+			name:    "Single RASL NAL unit",
+			payload: []byte{0x10, 0x01, 0xd0, 0x0e},
+		},
+		{
+			name: "Aggregation packet of VPS, SPS and PPS",
+			payload: []byte{
+				0x60, 0x01,
+				0x00, 0x04, 0x40, 0x01, 0x0c, 0x01,
+				0x00, 0x04, 0x42, 0x01, 0x01, 0x01,
+				0x00, 0x03, 0x44, 0x01, 0xc1,
+			},
+			keyFrame: true,
+		},
+		{
+			name: "Aggregation packet with an IDR",
+			payload: []byte{
+				0x60, 0x01,
+				0x00, 0x03, 0x4e, 0x01, 0x05,
+				0x00, 0x04, 0x26, 0x01, 0xaf, 0x0d,
+			},
+			keyFrame: true,
+		},
+		{
+			name: "Aggregation packet of trailing pictures",
+			payload: []byte{
+				0x60, 0x01,
+				0x00, 0x04, 0x02, 0x01, 0xd0, 0x0e,
+				0x00, 0x04, 0x02, 0x01, 0xd0, 0x0f,
+			},
+		},
+		{
+			name:    "Truncated aggregation packet",
+			payload: []byte{0x60, 0x01, 0x00, 0x04, 0x4e, 0x01, 0x05, 0x01, 0x00, 0x08, 0x42, 0x01},
+		},
+		{
+			name:     "Starting fragment of an IDR",
+			payload:  []byte{0x62, 0x01, 0x93, 0xaf, 0x0d},
+			keyFrame: true,
+		},
+		{
+			name:    "Continuing fragment of an IDR",
+			payload: []byte{0x62, 0x01, 0x13, 0xaf, 0x0d},
+		},
+		{
+			name:    "Starting fragment of a trailing picture",
+			payload: []byte{0x62, 0x01, 0x81, 0xaf, 0x0d},
+		},
+		{
+			name:    "Continuing fragment of an SPS",
+			payload: []byte{0x62, 0x01, 0x21, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.keyFrame, IsH265KeyFrame(tt.payload))
+		})
+	}
+}
+
+func FuzzVP8Unmarshal(f *testing.F) {
+	f.Add([]byte{0xff, 0x20, 0x1, 0x2, 0x3, 0x4})
+	f.Add([]byte{0xff, 0xff, 0x92, 0x67, 0x3, 0x4, 0x5})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xfd, 0xb4, 0x9f, 0x94, 0x1})
+	f.Add([]byte{0x10, 0x02, 0x9d, 0x01, 0x2a})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		var vp8 VP8
+		if err := vp8.Unmarshal(payload); err != nil {
+			return
+		}
+
+		// forwarded packets have their descriptor re-written in front of the original payload
+		header, err := vp8.Marshal()
+		require.NoError(t, err)
+		forwarded := append(header, payload[vp8.HeaderSize:]...)
+
+		var actual VP8
+		require.NoError(t, actual.Unmarshal(forwarded))
+		require.Equal(t, vp8, actual)
+	})
+}
+
+func FuzzIsKeyFrame(f *testing.F) {
+	f.Add([]byte{0x78, 0x00, 0x0a, 0x67, 0x42, 0xc0, 0x1f, 0x8c, 0x8d, 0x40, 0x50, 0x1e, 0xd0, 0x0f})
+	f.Add([]byte{0x7c, 0x87, 0x88, 0x84, 0x00})
+	f.Add([]byte{0x60, 0x01, 0x00, 0x04, 0x40, 0x01, 0x0c, 0x01, 0x00, 0x04, 0x42, 0x01, 0x01, 0x01})
+	f.Add([]byte{0x8f, 0xa0, 0xfd, 0x18, 0x07, 0x80, 0x03, 0x24, 0x02, 0xcf, 0x00, 0x00})
+	f.Add([]byte{0x28, 0x0a, 0x0b, 0x00, 0x00, 0x00, 0x24, 0xc4, 0xff, 0xdf, 0x00, 0x68, 0x02})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		IsH264KeyFrame(payload)
+		IsH265KeyFrame(payload)
+		IsVP9KeyFrame(payload)
+		IsAV1KeyFrame(payload)
+		_, _ = UnmarshalVP9(payload)
+	})
+}
@@ -0,0 +1,121 @@
+package buffer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+)
+
+type PoolConfig struct {
+	// number of packets storage is allocated for
+	NumPackets int
+	// when non-zero, buffers resize storage to hold this much media at the observed packet rate
+	Duration time.Duration
+	// resized storage holds at least this many packets, e.g. the history retransmissions are served from
+	MinPackets int
+}
+
+// Pool hands out packet storage for buckets, sized to hold a fixed number of packets
+type Pool struct {
+	numPackets int
+	duration   time.Duration
+	minPackets int
+	pool       *sync.Pool
+
+	gets        atomic.Uint64
+	puts        atomic.Uint64
+	allocations atomic.Uint64
+}
+
+func NewPool(numPackets int, duration time.Duration) *Pool {
+	p := &Pool{
+		numPackets: numPackets,
+		duration:   duration,
+	}
+	p.pool = &sync.Pool{
+		New: func() interface{} {
+			p.allocations.Inc()
+			b := make([]byte, numPackets*bucket.MaxPktSize)
+			return &b
+		},
+	}
+	return p
+}
+
+func (p *Pool) NumPackets() int {
+	return p.numPackets
+}
+
+func (p *Pool) Duration() time.Duration {
+	return p.duration
+}
+
+func (p *Pool) MinPackets() int {
+	return p.minPackets
+}
+
+func (p *Pool) Get() *[]byte {
+	p.gets.Inc()
+	return p.pool.Get().(*[]byte)
+}
+
+func (p *Pool) Put(b *[]byte) {
+	p.puts.Inc()
+	p.pool.Put(b)
+}
+
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		NumPackets:  p.numPackets,
+		Gets:        p.gets.Load(),
+		Puts:        p.puts.Load(),
+		Allocations: p.allocations.Load(),
+	}
+}
+
+// ------------------------------------------------
+
+type PoolStats struct {
+	NumPackets  int
+	Gets        uint64
+	Puts        uint64
+	Allocations uint64
+}
+
+// ReuseRatio is the fraction of gets served without allocating
+func (s PoolStats) ReuseRatio() float64 {
+	if s.Gets == 0 || s.Allocations >= s.Gets {
+		return 0
+	}
+	return float64(s.Gets-s.Allocations) / float64(s.Gets)
+}
+
+func (s PoolStats) String() string {
+	return fmt.Sprintf("PoolStats{packets: %d, gets: %d, puts: %d, allocations: %d, reuse: %.2f}",
+		s.NumPackets, s.Gets, s.Puts, s.Allocations, s.ReuseRatio())
+}
+
+// ------------------------------------------------
+
+// Pools is the set of tiered pools, buffers pick one based on the kind of track they are bound to
+type Pools struct {
+	Audio       *Pool
+	Video       *Pool
+	ScreenShare *Pool
+}
+
+func NewPools(audio, video, screenShare PoolConfig) *Pools {
+	pools := &Pools{
+		Audio:       NewPool(audio.NumPackets, audio.Duration),
+		Video:       NewPool(video.NumPackets, video.Duration),
+		ScreenShare: NewPool(screenShare.NumPackets, screenShare.Duration),
+	}
+	pools.Audio.minPackets = audio.MinPackets
+	pools.Video.minPackets = video.MinPackets
+	pools.ScreenShare.minPackets = screenShare.MinPackets
+	return pools
+}
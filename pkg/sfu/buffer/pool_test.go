@@ -0,0 +1,29 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+)
+
+func TestPool(t *testing.T) {
+	p := NewPool(4, 0)
+
+	b := p.Get()
+	require.Equal(t, 4*bucket.MaxPktSize, len(*b))
+	p.Put(b)
+
+	stats := p.Stats()
+	require.Equal(t, 4, stats.NumPackets)
+	require.Equal(t, uint64(1), stats.Gets)
+	require.Equal(t, uint64(1), stats.Puts)
+	require.Equal(t, uint64(1), stats.Allocations)
+}
+
+func TestPoolStats_ReuseRatio(t *testing.T) {
+	require.Equal(t, float64(0), PoolStats{}.ReuseRatio())
+	require.Equal(t, float64(0), PoolStats{Gets: 2, Allocations: 2}.ReuseRatio())
+	require.Equal(t, 0.75, PoolStats{Gets: 4, Allocations: 1}.ReuseRatio())
+}
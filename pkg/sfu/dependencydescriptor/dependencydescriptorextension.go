@@ -84,7 +84,7 @@ func (d *DependencyDescriptor) MarshalSizeWithActiveChains(activeChains uint32)
 
 func (d *DependencyDescriptor) String() string {
 	return fmt.Sprintf("DependencyDescriptor{FirstPacketInFrame: %v, LastPacketInFrame: %v, FrameNumber: %v, FrameDependencies: %+v, Resolution: %+v, ActiveDecodeTargetsBitmask: %v, AttachedStructure: %v}",
-		d.FirstPacketInFrame, d.LastPacketInFrame, d.FrameNumber, *d.FrameDependencies, *d.Resolution, formatBitmask(d.ActiveDecodeTargetsBitmask), d.AttachedStructure)
+		d.FirstPacketInFrame, d.LastPacketInFrame, d.FrameNumber, d.FrameDependencies, d.Resolution, formatBitmask(d.ActiveDecodeTargetsBitmask), d.AttachedStructure)
 }
 
 // ------------------------------------------------------------------------------
@@ -49,3 +49,66 @@ func TestDependencyDescriptorUnmarshal(t *testing.T) {
 		t.Log(ddVal.String())
 	}
 }
+
+func FuzzDependencyDescriptorUnmarshal(f *testing.F) {
+	// hex bytes from traffic capture, the first one attaches the structure used by the others
+	hexes := []string{
+		"c1017280081485214eafffaaaa863cf0430c10c302afc0aaa0063c00430010c002a000a80006000040001d954926e082b04a0941b820ac1282503157f974000ca864330e222222eca8655304224230eca877530077004200ef008601df010d",
+		"86017340fc",
+		"46017340fc",
+		"c3017540fc",
+		"88017640fc",
+		"c2017840fc",
+		"860173",
+		"8b0174",
+		"0b0174",
+		"c30175",
+	}
+	var captured [][]byte
+	for _, h := range hexes {
+		buf, err := hex.DecodeString(h)
+		if err != nil {
+			f.Fatal(err)
+		}
+		captured = append(captured, buf)
+		f.Add(buf)
+	}
+
+	var keyFrame DependencyDescriptor
+	if _, err := (&DependencyDescriptorExtension{Descriptor: &keyFrame}).Unmarshal(captured[0]); err != nil {
+		f.Fatal(err)
+	}
+	structure := keyFrame.AttachedStructure
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		// without a structure only descriptors attaching one can be parsed
+		for _, s := range []*FrameDependencyStructure{nil, structure} {
+			var ddVal DependencyDescriptor
+			ext := DependencyDescriptorExtension{
+				Structure:  s,
+				Descriptor: &ddVal,
+			}
+			if _, err := ext.Unmarshal(buf); err != nil {
+				continue
+			}
+			_ = ddVal.String()
+
+			if ddVal.AttachedStructure != nil {
+				ext.Structure = ddVal.AttachedStructure
+			}
+			marshalled, err := ext.Marshal()
+			if err != nil {
+				continue
+			}
+
+			// what is forwarded can be parsed by the subscriber
+			var forwarded DependencyDescriptor
+			if _, err := (&DependencyDescriptorExtension{Structure: ext.Structure, Descriptor: &forwarded}).Unmarshal(marshalled); err != nil {
+				t.Fatalf("could not unmarshal forwarded descriptor %x of %x: %v", marshalled, buf, err)
+			}
+			if forwarded.FrameNumber != ddVal.FrameNumber {
+				t.Fatalf("frame number changed, expected %d, got %d", ddVal.FrameNumber, forwarded.FrameNumber)
+			}
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"strings"
 	"sync"
 	"time"
@@ -173,6 +174,7 @@ type ReceiverReportListener func(dt *DownTrack, report *rtcp.ReceiverReport)
 // - closed
 // once closed, a DownTrack cannot be re-used.
 type DownTrack struct {
+	params        DownTrackParams
 	logger        logger.Logger
 	id            livekit.TrackID
 	subscriberID  livekit.ParticipantID
@@ -187,11 +189,17 @@ type DownTrack struct {
 
 	forwarder *Forwarder
 
+	// retransmissions are sent on an RTX stream when the subscriber accepted one, in-band otherwise
+	rtxSSRC           uint32
+	rtxPayloadType    uint8
+	rtxSequenceNumber atomic.Uint32
+
 	upstreamCodecs         []webrtc.RTPCodecParameters
 	codec                  webrtc.RTPCodecCapability
 	rtpHeaderExtensions    []webrtc.RTPHeaderExtensionParameter
 	absSendTimeID          int
 	dependencyDescriptorID int
+	receiverLock           sync.RWMutex
 	receiver               TrackReceiver
 	transceiver            *webrtc.RTPTransceiver
 	writeStream            webrtc.TrackLocalWriter
@@ -245,15 +253,28 @@ type DownTrack struct {
 	onRttUpdate func(dt *DownTrack, rtt uint32)
 }
 
+type DownTrackParams struct {
+	Codecs        []webrtc.RTPCodecParameters
+	Receiver      TrackReceiver
+	BufferFactory *buffer.Factory
+	SubID         livekit.ParticipantID
+	MaxTrack      int
+	Logger        logger.Logger
+
+	// when non-zero, number of audio packets kept for retransmission and
+	// age after which they are no longer retransmitted
+	AudioNACKHistorySize int
+	AudioNACKMaxLatency  time.Duration
+
+	// payloads are forwarded without being inspected or munged, e. g. frames encrypted with insertable streams
+	OpaquePayload bool
+}
+
 // NewDownTrack returns a DownTrack.
-func NewDownTrack(
-	codecs []webrtc.RTPCodecParameters,
-	r TrackReceiver,
-	bf *buffer.Factory,
-	subID livekit.ParticipantID,
-	mt int,
-	logger logger.Logger,
-) (*DownTrack, error) {
+func NewDownTrack(params DownTrackParams) (*DownTrack, error) {
+	codecs := params.Codecs
+	r := params.Receiver
+
 	var kind webrtc.RTPCodecType
 	switch {
 	case strings.HasPrefix(codecs[0].MimeType, "audio/"):
@@ -265,12 +286,13 @@ func NewDownTrack(
 	}
 
 	d := &DownTrack{
-		logger:         logger,
+		params:         params,
+		logger:         params.Logger,
 		id:             r.TrackID(),
-		subscriberID:   subID,
-		maxTrack:       mt,
+		subscriberID:   params.SubID,
+		maxTrack:       params.MaxTrack,
 		streamID:       r.StreamID(),
-		bufferFactory:  bf,
+		bufferFactory:  params.BufferFactory,
 		receiver:       r,
 		upstreamCodecs: codecs,
 		kind:           kind,
@@ -279,9 +301,10 @@ func NewDownTrack(
 	d.forwarder = NewForwarder(
 		d.kind,
 		d.logger,
-		d.receiver.GetReferenceLayerRTPTimestamp,
+		d.getReferenceLayerRTPTimestamp,
 		d.getExpectedRTPTimestamp,
 	)
+	d.forwarder.SetOpaquePayload(params.OpaquePayload)
 	d.forwarder.OnParkedLayerExpired(func() {
 		if sal := d.getStreamAllocatorListener(); sal != nil {
 			sal.OnSubscriptionChanged(d)
@@ -349,6 +372,14 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 	d.payloadType = uint8(codec.PayloadType)
 	d.writeStream = t.WriteStream()
 	d.mime = strings.ToLower(codec.MimeType)
+	if d.kind == webrtc.RTPCodecTypeVideo {
+		if rtxPayloadType, ok := rtxPayloadTypeFor(codec.PayloadType, t.CodecParameters()); ok {
+			d.rtxSSRC = RTXSSRC(d.ssrc)
+			d.rtxPayloadType = rtxPayloadType
+			d.rtxSequenceNumber.Store(uint32(rand.Intn(1 << 16)))
+			d.logger.Debugw("retransmitting on rtx stream", "rtxSSRC", d.rtxSSRC, "rtxPayloadType", d.rtxPayloadType)
+		}
+	}
 	if rr := d.bufferFactory.GetOrNew(packetio.RTCPBufferPacket, uint32(t.SSRC())).(*buffer.RTCPReader); rr != nil {
 		rr.OnPacket(func(pkt []byte) {
 			d.handleRTCP(pkt)
@@ -357,7 +388,12 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 	}
 
 	if d.kind == webrtc.RTPCodecTypeAudio {
-		d.sequencer = newSequencer(d.maxTrack, 0, d.logger)
+		if d.params.AudioNACKHistorySize > 0 {
+			d.sequencer = newSequencer(d.params.AudioNACKHistorySize, 0, d.logger)
+			d.sequencer.setMaxAge(d.params.AudioNACKMaxLatency)
+		} else {
+			d.sequencer = newSequencer(d.maxTrack, 0, d.logger)
+		}
 	} else {
 		d.sequencer = newSequencer(d.maxTrack, maxPadding, d.logger)
 	}
@@ -369,7 +405,7 @@ func (d *DownTrack) Bind(t webrtc.TrackLocalContext) (webrtc.RTPCodecParameters,
 	d.bound.Store(true)
 	d.bindLock.Unlock()
 
-	d.forwarder.DetermineCodec(d.codec, d.receiver.HeaderExtensions())
+	d.forwarder.DetermineCodec(d.codec, d.getReceiver().HeaderExtensions())
 
 	d.logger.Debugw("downtrack bound")
 	d.onBindAndConnected()
@@ -385,7 +421,7 @@ func (d *DownTrack) Unbind(_ webrtc.TrackLocalContext) error {
 }
 
 func (d *DownTrack) TrackInfoAvailable() {
-	ti := d.receiver.TrackInfo()
+	ti := d.getReceiver().TrackInfo()
 	if ti == nil {
 		return
 	}
@@ -518,7 +554,7 @@ func (d *DownTrack) maybeStartKeyFrameRequester() {
 	//
 	d.stopKeyFrameRequester()
 
-	// SVC-TODO : don't need pli/lrr when layer comes down
+	// no key frame is needed to switch down SVC layers, the forwarder reports those as locked
 	locked, layer := d.forwarder.CheckSync()
 	if !locked {
 		go d.keyFrameRequester(d.keyFrameRequestGeneration.Load(), layer)
@@ -545,7 +581,7 @@ func (d *DownTrack) keyFrameRequester(generation uint32, layer int32) {
 	for {
 		if d.connected.Load() {
 			d.logger.Debugw("sending PLI for layer lock", "generation", generation, "layer", layer)
-			d.receiver.SendPLI(layer, false)
+			d.getReceiver().SendPLI(layer, false)
 			d.rtpStats.UpdateLayerLockPliAndTime(1)
 		}
 
@@ -740,6 +776,46 @@ func (d *DownTrack) WritePaddingRTP(bytesToSend int, paddingOnMute bool) int {
 	return bytesSent
 }
 
+// WriteProbePaddingRTP writes padding only packets to probe the channel. When the subscriber accepted an RTX stream,
+// they are sent on it, taking no sequence numbers of the media stream and not waiting for frame boundaries, else it
+// falls back to WritePaddingRTP.
+func (d *DownTrack) WriteProbePaddingRTP(bytesToSend int) int {
+	if d.rtxPayloadType == 0 || !d.rtpStats.IsActive() {
+		return d.WritePaddingRTP(bytesToSend, false)
+	}
+
+	num := (bytesToSend + RTPPaddingMaxPayloadSize + RTPPaddingEstimatedHeaderSize - 1) / (RTPPaddingMaxPayloadSize + RTPPaddingEstimatedHeaderSize)
+	timestamp, _ := d.rtpStats.GetExpectedRTPTimestamp(time.Now())
+
+	bytesSent := 0
+	for i := 0; i < num; i++ {
+		hdr := rtp.Header{
+			Version:        2,
+			Padding:        true,
+			PayloadType:    d.rtxPayloadType,
+			SequenceNumber: uint16(d.rtxSequenceNumber.Inc()),
+			Timestamp:      timestamp,
+			SSRC:           d.rtxSSRC,
+			CSRC:           []uint32{},
+		}
+		if err := d.writeRTPHeaderExtensions(&hdr); err != nil {
+			return bytesSent
+		}
+
+		payload := make([]byte, RTPPaddingMaxPayloadSize)
+		// last byte of padding has padding size including that byte
+		payload[RTPPaddingMaxPayloadSize-1] = byte(RTPPaddingMaxPayloadSize)
+
+		if _, err := d.writeStream.WriteRTP(&hdr, payload); err != nil {
+			return bytesSent
+		}
+
+		bytesSent += hdr.MarshalSize() + len(payload)
+	}
+
+	return bytesSent
+}
+
 // Mute enables or disables media forwarding - subscriber triggered
 func (d *DownTrack) Mute(muted bool) {
 	changed, maxLayer := d.forwarder.Mute(muted)
@@ -859,7 +935,7 @@ func (d *DownTrack) CloseWithFlush(flush bool) {
 
 		d.bound.Store(false)
 		d.logger.Debugw("closing sender", "kind", d.kind)
-		d.receiver.DeleteDownTrack(d.subscriberID)
+		d.getReceiver().DeleteDownTrack(d.subscriberID)
 
 		if d.rtcpReader != nil && flush {
 			d.logger.Debugw("downtrack close rtcp reader")
@@ -1014,18 +1090,26 @@ func (d *DownTrack) IsDeficient() bool {
 	return d.forwarder.IsDeficient()
 }
 
+func (d *DownTrack) CurrentLayer() buffer.VideoLayer {
+	return d.forwarder.CurrentLayer()
+}
+
+func (d *DownTrack) LastAllocation() VideoAllocation {
+	return d.forwarder.LastAllocation()
+}
+
 func (d *DownTrack) BandwidthRequested() int64 {
-	_, brs := d.receiver.GetLayeredBitrate()
+	_, brs := d.getReceiver().GetLayeredBitrate()
 	return d.forwarder.BandwidthRequested(brs)
 }
 
 func (d *DownTrack) DistanceToDesired() float64 {
-	al, brs := d.receiver.GetLayeredBitrate()
+	al, brs := d.getReceiver().GetLayeredBitrate()
 	return d.forwarder.DistanceToDesired(al, brs)
 }
 
 func (d *DownTrack) AllocateOptimal(allowOvershoot bool) VideoAllocation {
-	al, brs := d.receiver.GetLayeredBitrate()
+	al, brs := d.getReceiver().GetLayeredBitrate()
 	allocation := d.forwarder.AllocateOptimal(al, brs, allowOvershoot)
 	d.maybeStartKeyFrameRequester()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired)
@@ -1033,7 +1117,7 @@ func (d *DownTrack) AllocateOptimal(allowOvershoot bool) VideoAllocation {
 }
 
 func (d *DownTrack) ProvisionalAllocatePrepare() {
-	al, brs := d.receiver.GetLayeredBitrate()
+	al, brs := d.getReceiver().GetLayeredBitrate()
 	d.forwarder.ProvisionalAllocatePrepare(al, brs)
 }
 
@@ -1061,7 +1145,7 @@ func (d *DownTrack) ProvisionalAllocateCommit() VideoAllocation {
 }
 
 func (d *DownTrack) AllocateNextHigher(availableChannelCapacity int64, allowOvershoot bool) (VideoAllocation, bool) {
-	al, brs := d.receiver.GetLayeredBitrate()
+	al, brs := d.getReceiver().GetLayeredBitrate()
 	allocation, available := d.forwarder.AllocateNextHigher(availableChannelCapacity, al, brs, allowOvershoot)
 	d.maybeStartKeyFrameRequester()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired)
@@ -1069,14 +1153,14 @@ func (d *DownTrack) AllocateNextHigher(availableChannelCapacity int64, allowOver
 }
 
 func (d *DownTrack) GetNextHigherTransition(allowOvershoot bool) (VideoTransition, bool) {
-	_, brs := d.receiver.GetLayeredBitrate()
+	_, brs := d.getReceiver().GetLayeredBitrate()
 	transition, available := d.forwarder.GetNextHigherTransition(brs, allowOvershoot)
 	d.logger.Debugw("stream: get next higher layer", "transition", transition, "available", available, "bitrates", brs)
 	return transition, available
 }
 
 func (d *DownTrack) Pause() VideoAllocation {
-	al, brs := d.receiver.GetLayeredBitrate()
+	al, brs := d.getReceiver().GetLayeredBitrate()
 	allocation := d.forwarder.Pause(al, brs)
 	d.maybeStartKeyFrameRequester()
 	d.maybeAddTransition(allocation.BandwidthNeeded, allocation.DistanceToDesired)
@@ -1087,6 +1171,77 @@ func (d *DownTrack) Resync() {
 	d.forwarder.Resync()
 }
 
+// Receiver returns the receiver this down track is forwarding from
+func (d *DownTrack) Receiver() TrackReceiver {
+	return d.getReceiver()
+}
+
+// SetReceiver moves the down track to a receiver of a different media source without re-binding,
+// forwarding continues from the next key frame of the new source with continuous sequence numbers and time stamps.
+func (d *DownTrack) SetReceiver(r TrackReceiver) {
+	d.bindLock.Lock()
+	d.receiverLock.Lock()
+	prev := d.receiver
+	d.receiver = r
+	d.receiverLock.Unlock()
+
+	if !d.bound.Load() {
+		// receiver is set up when binding
+		d.bindLock.Unlock()
+		return
+	}
+
+	prev.DeleteDownTrack(d.subscriberID)
+	if d.sequencer != nil {
+		// packets of previous source cannot be retransmitted from new receiver
+		d.sequencer.flush()
+	}
+	d.forwarder.ResyncSource()
+	d.bindLock.Unlock()
+
+	if err := r.AddDownTrack(d); err != nil {
+		d.logger.Warnw("could not add down track to new receiver", err)
+		return
+	}
+	d.logger.Infow("moved to new receiver", "codec", r.Codec().MimeType)
+
+	if d.kind == webrtc.RTPCodecTypeVideo {
+		d.maybeStartKeyFrameRequester()
+	}
+}
+
+// ResyncSource prepares the down track for the media of a different source arriving through the same receiver,
+// forwarding continues from the next key frame of the new source with continuous sequence numbers and time stamps.
+func (d *DownTrack) ResyncSource() {
+	d.bindLock.Lock()
+	if !d.bound.Load() {
+		d.bindLock.Unlock()
+		return
+	}
+
+	if d.sequencer != nil {
+		// packets of previous source cannot be retransmitted
+		d.sequencer.flush()
+	}
+	d.forwarder.ResyncSource()
+	d.bindLock.Unlock()
+
+	if d.kind == webrtc.RTPCodecTypeVideo {
+		d.maybeStartKeyFrameRequester()
+	}
+}
+
+func (d *DownTrack) getReceiver() TrackReceiver {
+	d.receiverLock.RLock()
+	defer d.receiverLock.RUnlock()
+
+	return d.receiver
+}
+
+func (d *DownTrack) getReferenceLayerRTPTimestamp(ts uint32, layer int32, referenceLayer int32) (uint32, error) {
+	return d.getReceiver().GetReferenceLayerRTPTimestamp(ts, layer, referenceLayer)
+}
+
 func (d *DownTrack) CreateSourceDescriptionChunks() []rtcp.SourceDescriptionChunk {
 	if !d.bound.Load() {
 		return nil
@@ -1287,7 +1442,7 @@ func (d *DownTrack) writeH264BlankFrame(hdr *rtp.Header, frameEndNeeded bool) (i
 }
 
 func (d *DownTrack) handleRTCP(bytes []byte) {
-	pkts, err := rtcp.Unmarshal(bytes)
+	pkts, err := buffer.UnmarshalRTCP(bytes)
 	if err != nil {
 		d.logger.Errorw("unmarshal rtcp receiver packets err", err)
 		return
@@ -1299,7 +1454,7 @@ func (d *DownTrack) handleRTCP(bytes []byte) {
 			_, layer := d.forwarder.CheckSync()
 			if layer != buffer.InvalidLayerSpatial && !d.forwarder.IsAnyMuted() {
 				d.logger.Debugw("sending PLI RTCP", "layer", layer)
-				d.receiver.SendPLI(layer, false)
+				d.getReceiver().SendPLI(layer, false)
 				d.isNACKThrottled.Store(true)
 				d.rtpStats.UpdatePliTime()
 				pliOnce = false
@@ -1449,7 +1604,7 @@ func (d *DownTrack) retransmitPackets(nacks []uint16) {
 		}
 
 		pktBuff := *src
-		n, err := d.receiver.ReadRTP(pktBuff, uint8(meta.layer), meta.sourceSeqNo)
+		n, err := d.getReceiver().ReadRTP(pktBuff, uint8(meta.layer), meta.sourceSeqNo)
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -1498,11 +1653,15 @@ func (d *DownTrack) retransmitPackets(nacks []uint16) {
 			continue
 		}
 
-		if _, err = d.writeStream.WriteRTP(&pkt.Header, payload); err != nil {
+		hdr := &pkt.Header
+		if d.rtxPayloadType != 0 {
+			hdr, payload = d.toRTXPacket(&pkt.Header, payload)
+		}
+		if _, err = d.writeStream.WriteRTP(hdr, payload); err != nil {
 			d.logger.Errorw("writing rtx packet err", err)
 		} else {
-			d.streamAllocatorBytesCounter.Add(uint32(pkt.Header.MarshalSize() + len(payload)))
-			d.bytesRetransmitted.Add(uint32(pkt.Header.MarshalSize() + len(payload)))
+			d.streamAllocatorBytesCounter.Add(uint32(hdr.MarshalSize() + len(payload)))
+			d.bytesRetransmitted.Add(uint32(hdr.MarshalSize() + len(payload)))
 
 			d.rtpStats.Update(&pkt.Header, len(payload), 0, time.Now())
 		}
@@ -1528,6 +1687,22 @@ func (d *DownTrack) retransmitPackets(nacks []uint16) {
 	}
 }
 
+// toRTXPacket wraps a packet being retransmitted in an RTX packet (RFC 4588), i. e. sent on the RTX stream with its
+// own sequence numbers and with the original sequence number prepended to the payload
+func (d *DownTrack) toRTXPacket(hdr *rtp.Header, payload []byte) (*rtp.Header, []byte) {
+	rtxHdr := *hdr
+	rtxHdr.SSRC = d.rtxSSRC
+	rtxHdr.PayloadType = d.rtxPayloadType
+	rtxHdr.SequenceNumber = uint16(d.rtxSequenceNumber.Inc())
+	// payload does not include padding of the original packet
+	rtxHdr.Padding = false
+
+	rtxPayload := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(rtxPayload, hdr.SequenceNumber)
+	copy(rtxPayload[2:], payload)
+	return &rtxHdr, rtxPayload
+}
+
 type extensionData struct {
 	id      uint8
 	payload []byte
@@ -1680,7 +1855,7 @@ func (d *DownTrack) onBindAndConnected() {
 		if d.kind == webrtc.RTPCodecTypeVideo {
 			_, layer := d.forwarder.CheckSync()
 			if layer != buffer.InvalidLayerSpatial {
-				d.receiver.SendPLI(layer, true)
+				d.getReceiver().SendPLI(layer, true)
 			}
 		}
 
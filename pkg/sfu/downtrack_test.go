@@ -0,0 +1,60 @@
+package sfu
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTX(t *testing.T) {
+	codecs := []webrtc.RTPCodecParameters{
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, PayloadType: 96},
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: MimeTypeRTX, SDPFmtpLine: "apt=96"}, PayloadType: 97},
+		{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, SDPFmtpLine: "profile-id=0"}, PayloadType: 98},
+	}
+	pt, ok := rtxPayloadTypeFor(96, codecs)
+	require.True(t, ok)
+	require.Equal(t, uint8(97), pt)
+	_, ok = rtxPayloadTypeFor(98, codecs)
+	require.False(t, ok)
+
+	require.Equal(t, RTXSSRC(1234), RTXSSRC(1234))
+	require.NotEqual(t, RTXSSRC(1234), RTXSSRC(1235))
+	require.NotEqual(t, uint32(1234), RTXSSRC(1234))
+
+	d := &DownTrack{
+		rtxSSRC:        RTXSSRC(1234),
+		rtxPayloadType: 97,
+	}
+	d.rtxSequenceNumber.Store(0xffff)
+	hdr := &rtp.Header{
+		Version:        2,
+		Padding:        true,
+		Marker:         true,
+		PayloadType:    96,
+		SequenceNumber: 1000,
+		Timestamp:      90000,
+		SSRC:           1234,
+	}
+	payload := []byte{1, 2, 3}
+
+	rtxHdr, rtxPayload := d.toRTXPacket(hdr, payload)
+	require.Equal(t, RTXSSRC(1234), rtxHdr.SSRC)
+	require.Equal(t, uint8(97), rtxHdr.PayloadType)
+	require.Equal(t, uint16(0), rtxHdr.SequenceNumber)
+	require.Equal(t, uint32(90000), rtxHdr.Timestamp)
+	require.True(t, rtxHdr.Marker)
+	require.False(t, rtxHdr.Padding)
+	require.Equal(t, uint16(1000), binary.BigEndian.Uint16(rtxPayload))
+	require.Equal(t, payload, rtxPayload[2:])
+
+	// original is untouched, it is accounted on the media stream
+	require.Equal(t, uint32(1234), hdr.SSRC)
+	require.Equal(t, uint16(1000), hdr.SequenceNumber)
+
+	rtxHdr, _ = d.toRTXPacket(hdr, payload)
+	require.Equal(t, uint16(1), rtxHdr.SequenceNumber)
+}
@@ -6,11 +6,14 @@ import (
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/telemetry/errorreporting"
 )
 
 type DownTrackSpreaderParams struct {
-	Threshold int
-	Logger    logger.Logger
+	Threshold    int
+	Logger       logger.Logger
+	ErrorContext []interface{}
 }
 
 type DownTrackSpreader struct {
@@ -84,10 +87,20 @@ func (d *DownTrackSpreader) Broadcast(writer func(TrackSender)) {
 	// WriteRTP takes about 50µs on average, so we write to 2 down tracks per loop.
 	step := uint64(2)
 	utils.ParallelExec(downTracks, threshold, step, func(dt TrackSender) {
+		defer d.recoverDownTrack(dt)
 		writer(dt)
 	})
 }
 
+// recoverDownTrack closes the down track of a subscriber that panicked, the others keep receiving
+func (d *DownTrackSpreader) recoverDownTrack(dt TrackSender) {
+	if p := recover(); p != nil {
+		keysAndValues := append(append([]interface{}{}, d.params.ErrorContext...), "subscriberID", dt.SubscriberID())
+		errorreporting.HandlePanic(p, d.params.Logger, keysAndValues...)
+		go dt.Close()
+	}
+}
+
 func (d *DownTrackSpreader) DownTrackCount() int {
 	d.downTrackMu.RLock()
 	defer d.downTrackMu.RUnlock()
@@ -0,0 +1,50 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+type testTrackSender struct {
+	TrackSender
+	subscriberID livekit.ParticipantID
+	panics       bool
+	written      atomic.Int32
+	closed       atomic.Bool
+}
+
+func (s *testTrackSender) SubscriberID() livekit.ParticipantID { return s.subscriberID }
+func (s *testTrackSender) Close()                              { s.closed.Store(true) }
+
+func (s *testTrackSender) WriteRTP(_ *buffer.ExtPacket, _ int32) error {
+	if s.panics {
+		panic("broken down track")
+	}
+	s.written.Inc()
+	return nil
+}
+
+func TestDownTrackSpreaderRecover(t *testing.T) {
+	d := NewDownTrackSpreader(DownTrackSpreaderParams{Logger: logger.GetLogger()})
+	broken := &testTrackSender{subscriberID: "PA_broken", panics: true}
+	healthy := &testTrackSender{subscriberID: "PA_healthy"}
+	d.Store(broken)
+	d.Store(healthy)
+
+	require.NotPanics(t, func() {
+		d.Broadcast(func(dt TrackSender) {
+			_ = dt.WriteRTP(nil, 0)
+		})
+	})
+	require.EqualValues(t, 1, healthy.written.Load())
+	require.Eventually(t, broken.closed.Load, time.Second, 10*time.Millisecond)
+	require.False(t, healthy.closed.Load())
+}
@@ -0,0 +1,119 @@
+package sfu
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	// shortest authentication tag of the SFrame cipher suites, AES_128_CTR_HMAC_SHA256_32
+	sframeMinTagSize = 4
+
+	defaultMaxPlaintextFrames = 5
+)
+
+// SFrameHeaderSize returns the size of the SFrame header (RFC 9605) a frame starts with, false when the frame is too
+// short to hold the header it announces and an authentication tag
+func SFrameHeaderSize(frame []byte) (int, bool) {
+	if len(frame) == 0 {
+		return 0, false
+	}
+
+	config := frame[0]
+	size := 1
+	// the extended flags announce the length of the key ID and counter, which follow the config byte
+	if config&0x80 != 0 {
+		size += int((config>>4)&0x07) + 1
+	}
+	if config&0x08 != 0 {
+		size += int(config&0x07) + 1
+	}
+	if len(frame) < size+sframeMinTagSize {
+		return 0, false
+	}
+	return size, true
+}
+
+// FrameEncryptionValidator checks that the frames of a track are end-to-end encrypted as its publisher declared.
+// Frames encrypted with AES-GCM by LiveKit clients are recognized by their trailer, which the last packet of a frame
+// carries, frames of the custom encryption by the SFrame header the first packet of a frame starts with, after the
+// payload descriptor of the codec. Only the structure is checked, the server cannot tell ciphertext from media which
+// happens to look alike, but publishers not encrypting are caught within a few frames. Not safe for concurrent use,
+// each layer of a track has one of its own
+type FrameEncryptionValidator struct {
+	encryption         livekit.Encryption_Type
+	isAudio            bool
+	isVP8              bool
+	maxPlaintextFrames int
+
+	lastTimestamp   uint32
+	started         bool
+	plaintextFrames int
+}
+
+// NewFrameEncryptionValidator creates a validator failing after maxPlaintextFrames consecutive frames are found not
+// to be encrypted
+func NewFrameEncryptionValidator(encryption livekit.Encryption_Type, mime string, maxPlaintextFrames int) *FrameEncryptionValidator {
+	if maxPlaintextFrames <= 0 {
+		maxPlaintextFrames = defaultMaxPlaintextFrames
+	}
+	return &FrameEncryptionValidator{
+		encryption:         encryption,
+		isAudio:            strings.HasPrefix(strings.ToLower(mime), "audio/"),
+		isVP8:              strings.EqualFold(mime, webrtc.MimeTypeVP8),
+		maxPlaintextFrames: maxPlaintextFrames,
+	}
+}
+
+// Validate checks the frame a packet belongs to when the packet holds what identifies an encrypted frame, returns
+// false once too many consecutive frames were not encrypted
+func (v *FrameEncryptionValidator) Validate(pkt *buffer.ExtPacket) bool {
+	payload := pkt.Packet.Payload
+	if len(payload) == 0 {
+		// padding
+		return v.plaintextFrames < v.maxPlaintextFrames
+	}
+
+	var encrypted bool
+	switch v.encryption {
+	case livekit.Encryption_GCM:
+		if !v.isAudio && !pkt.Packet.Marker {
+			return v.plaintextFrames < v.maxPlaintextFrames
+		}
+		_, encrypted = E2EEKeyIndex(payload)
+
+	case livekit.Encryption_CUSTOM:
+		if !v.isAudio {
+			frameStart := !v.started || pkt.Packet.Timestamp != v.lastTimestamp
+			v.started = true
+			v.lastTimestamp = pkt.Packet.Timestamp
+			if !frameStart {
+				return v.plaintextFrames < v.maxPlaintextFrames
+			}
+			if v.isVP8 {
+				// the payload descriptor is added when packetizing, outside of the encrypted frame. Buffers of
+				// opaque payloads leave it unparsed
+				vp8, ok := pkt.Payload.(buffer.VP8)
+				if !ok {
+					_ = vp8.Unmarshal(payload)
+				}
+				if vp8.HeaderSize <= len(payload) {
+					payload = payload[vp8.HeaderSize:]
+				}
+			}
+		}
+		_, encrypted = SFrameHeaderSize(payload)
+	}
+
+	if encrypted {
+		v.plaintextFrames = 0
+	} else {
+		v.plaintextFrames++
+	}
+	return v.plaintextFrames < v.maxPlaintextFrames
+}
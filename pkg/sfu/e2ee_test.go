@@ -0,0 +1,83 @@
+package sfu
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+func TestSFrameHeaderSize(t *testing.T) {
+	// key ID and counter in the config byte
+	size, ok := SFrameHeaderSize([]byte{0x12, 1, 2, 3, 4})
+	require.True(t, ok)
+	require.Equal(t, 1, size)
+
+	// 2 byte key ID and 3 byte counter
+	size, ok = SFrameHeaderSize(append([]byte{0x9a, 0, 1, 0, 0, 1}, make([]byte, 4)...))
+	require.True(t, ok)
+	require.Equal(t, 6, size)
+
+	// too short for the announced header and a tag
+	_, ok = SFrameHeaderSize([]byte{0x9a, 0, 1, 0, 0, 1})
+	require.False(t, ok)
+	_, ok = SFrameHeaderSize(nil)
+	require.False(t, ok)
+}
+
+func TestFrameEncryptionValidator(t *testing.T) {
+	gcmPacket := func(marker bool, encrypted bool) *buffer.ExtPacket {
+		payload := make([]byte, 40)
+		if encrypted {
+			payload[len(payload)-2] = e2eeIVLength
+		}
+		return &buffer.ExtPacket{Packet: &rtp.Packet{Header: rtp.Header{Marker: marker}, Payload: payload}}
+	}
+
+	t.Run("gcm video checks the last packet of frames", func(t *testing.T) {
+		v := NewFrameEncryptionValidator(livekit.Encryption_GCM, webrtc.MimeTypeVP8, 2)
+		for i := 0; i < 5; i++ {
+			require.True(t, v.Validate(gcmPacket(false, false)))
+			require.True(t, v.Validate(gcmPacket(true, true)))
+		}
+
+		require.True(t, v.Validate(gcmPacket(true, false)))
+		// an encrypted frame resets the count
+		require.True(t, v.Validate(gcmPacket(true, true)))
+		require.True(t, v.Validate(gcmPacket(true, false)))
+		require.False(t, v.Validate(gcmPacket(true, false)))
+	})
+
+	t.Run("sframe video checks the first packet of frames after the payload descriptor", func(t *testing.T) {
+		v := NewFrameEncryptionValidator(livekit.Encryption_CUSTOM, webrtc.MimeTypeVP8, 1)
+		first := &buffer.ExtPacket{
+			Packet:  &rtp.Packet{Header: rtp.Header{Timestamp: 1000}, Payload: []byte{0x90, 0x80, 0x01, 0x12, 1, 2, 3, 4}},
+			Payload: buffer.VP8{HeaderSize: 3},
+		}
+		require.True(t, v.Validate(first))
+		// rest of the frame
+		require.True(t, v.Validate(&buffer.ExtPacket{Packet: &rtp.Packet{Header: rtp.Header{Timestamp: 1000}, Payload: []byte{1}}}))
+
+		// descriptor of an opaque payload, left unparsed by the buffer
+		plaintext := &buffer.ExtPacket{
+			Packet: &rtp.Packet{Header: rtp.Header{Timestamp: 4000}, Payload: []byte{0x90, 0x80, 0x01, 0x9f}},
+		}
+		require.False(t, v.Validate(plaintext))
+	})
+
+	t.Run("audio checks every packet", func(t *testing.T) {
+		v := NewFrameEncryptionValidator(livekit.Encryption_GCM, webrtc.MimeTypeOpus, 1)
+		require.True(t, v.Validate(gcmPacket(false, true)))
+		require.False(t, v.Validate(gcmPacket(false, false)))
+	})
+
+	t.Run("undeclared encryption fails", func(t *testing.T) {
+		v := NewFrameEncryptionValidator(livekit.Encryption_NONE, webrtc.MimeTypeOpus, 1)
+		require.False(t, v.Validate(gcmPacket(false, true)))
+	})
+}
@@ -0,0 +1,249 @@
+package fec
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	MimeTypeFlexFEC = "video/flexfec-03"
+
+	// fixed part of the RTP header, recovered from the FlexFEC header, the rest of a packet is recovered from the payload
+	rtpHeaderSize = 12
+
+	// FlexFEC-03 header with a single protected SSRC, size depends on the number of mask chunks used
+	flexFECHeaderSize1 = 20
+	flexFECHeaderSize2 = 24
+	flexFECHeaderSize3 = 32
+
+	// mask chunks cover offsets from SN base of 0-14, 15-45 and 46-108
+	maskChunk1Bits = 15
+	maskChunk2Bits = 46
+	maxMaskBits    = 109
+
+	// media packets protected together at most, keeps repair packets of a group close to the media they protect
+	defaultMaxGroupSize = 48
+)
+
+type protectedPacket struct {
+	sequenceNumber uint16
+	data           []byte
+}
+
+// Encoder generates FlexFEC-03 repair packets for a media stream. Media packets are grouped up to frame boundaries
+// and each repair packet is the XOR of an interleaved subset of a group, a receiver missing one packet of a subset
+// rebuilds it from the repair packet and the rest of the subset without waiting for a retransmission.
+type Encoder struct {
+	ssrc           uint32
+	payloadType    uint8
+	sequenceNumber uint16
+	maxGroupSize   int
+
+	protection float64
+
+	protectedSSRC uint32
+	packets       []protectedPacket
+}
+
+func NewEncoder(ssrc uint32, payloadType uint8) *Encoder {
+	return &Encoder{
+		ssrc:           ssrc,
+		payloadType:    payloadType,
+		sequenceNumber: uint16(rand.Intn(1 << 15)),
+		maxGroupSize:   defaultMaxGroupSize,
+	}
+}
+
+func (e *Encoder) SSRC() uint32 {
+	return e.ssrc
+}
+
+// SetProtection sets the number of repair packets sent relative to media packets, 0 stops protection
+func (e *Encoder) SetProtection(protection float64) {
+	if protection <= 0 {
+		e.Reset()
+		protection = 0
+	}
+	e.protection = math.Min(protection, 1.0)
+}
+
+func (e *Encoder) Protection() float64 {
+	return e.protection
+}
+
+// Reset drops the packets of the current group without protecting them
+func (e *Encoder) Reset() {
+	e.packets = e.packets[:0]
+}
+
+// Push adds a marshalled media packet as it was sent and returns the repair packets to send after it, if any.
+// Packets older than the last one pushed are retransmissions and are not protected again.
+func (e *Encoder) Push(pkt []byte, endOfFrame bool) []*rtp.Packet {
+	if e.protection <= 0 || len(pkt) < rtpHeaderSize {
+		return nil
+	}
+
+	sn := binary.BigEndian.Uint16(pkt[2:4])
+	ssrc := binary.BigEndian.Uint32(pkt[8:12])
+
+	var repairPackets []*rtp.Packet
+	if len(e.packets) != 0 {
+		if ssrc != e.protectedSSRC {
+			e.Reset()
+		} else {
+			diff := sn - e.packets[len(e.packets)-1].sequenceNumber
+			if diff == 0 || diff > (1<<15) {
+				return nil
+			}
+
+			// out of the range of the mask, protect what the group has
+			if sn-e.packets[0].sequenceNumber >= maxMaskBits {
+				repairPackets = e.generate(true)
+			}
+		}
+	}
+
+	e.protectedSSRC = ssrc
+	data := make([]byte, len(pkt))
+	copy(data, pkt)
+	e.packets = append(e.packets, protectedPacket{sequenceNumber: sn, data: data})
+
+	if endOfFrame || len(e.packets) >= e.maxGroupSize {
+		repairPackets = append(repairPackets, e.generate(len(e.packets) >= e.maxGroupSize)...)
+	}
+	return repairPackets
+}
+
+// generate returns the repair packets of the current group. Small groups are carried over to the next frame till
+// at least one repair packet is due unless forced.
+func (e *Encoder) generate(force bool) []*rtp.Packet {
+	numPackets := len(e.packets)
+	numRepair := int(math.Round(float64(numPackets) * e.protection))
+	if numRepair == 0 {
+		if !force {
+			return nil
+		}
+		numRepair = 1
+	}
+	if numRepair > numPackets {
+		numRepair = numPackets
+	}
+
+	repairPackets := make([]*rtp.Packet, 0, numRepair)
+	subset := make([]protectedPacket, 0, (numPackets+numRepair-1)/numRepair)
+	for j := 0; j < numRepair; j++ {
+		subset = subset[:0]
+		for i := j; i < numPackets; i += numRepair {
+			subset = append(subset, e.packets[i])
+		}
+		repairPackets = append(repairPackets, e.repairPacket(subset))
+	}
+
+	e.Reset()
+	return repairPackets
+}
+
+// repairPacket XORs the packets of a subset into a repair packet with a FlexFEC-03 header for a single SSRC
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|R|F|P|X|  CC   |M| PT recovery |        length recovery        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                          TS recovery                          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|   SSRCCount   |                    reserved                   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                             SSRC_i                            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|           SN base_i           |k|          Mask [0-14]        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|k|                   Mask [15-45] (optional)                   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|k|                                                             |
+//	+-+                   Mask [46-108] (optional)                  |
+//	|                                                               |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+func (e *Encoder) repairPacket(subset []protectedPacket) *rtp.Packet {
+	baseSN := subset[0].sequenceNumber
+	maxOffset := subset[len(subset)-1].sequenceNumber - baseSN
+	headerSize := flexFECHeaderSize1
+	switch {
+	case maxOffset >= maskChunk2Bits:
+		headerSize = flexFECHeaderSize3
+	case maxOffset >= maskChunk1Bits:
+		headerSize = flexFECHeaderSize2
+	}
+
+	payloadSize := 0
+	for _, p := range subset {
+		if len(p.data)-rtpHeaderSize > payloadSize {
+			payloadSize = len(p.data) - rtpHeaderSize
+		}
+	}
+
+	payload := make([]byte, headerSize+payloadSize)
+	var timestamp uint32
+	for _, p := range subset {
+		payload[0] ^= p.data[0]
+		payload[1] ^= p.data[1]
+		binary.BigEndian.PutUint16(payload[2:4], binary.BigEndian.Uint16(payload[2:4])^uint16(len(p.data)-rtpHeaderSize))
+		for i := 4; i < 8; i++ {
+			payload[i] ^= p.data[i]
+		}
+		xorBytes(payload[headerSize:], p.data[rtpHeaderSize:])
+
+		timestamp = binary.BigEndian.Uint32(p.data[4:8])
+	}
+	// R and F bits are not used
+	payload[0] &= 0x3f
+
+	payload[8] = 1
+	binary.BigEndian.PutUint32(payload[12:16], e.protectedSSRC)
+	binary.BigEndian.PutUint16(payload[16:18], baseSN)
+
+	for _, p := range subset {
+		offset := int(p.sequenceNumber - baseSN)
+		switch {
+		case offset < maskChunk1Bits:
+			payload[18+(offset+1)/8] |= 0x80 >> ((offset + 1) % 8)
+		case offset < maskChunk2Bits:
+			bit := offset - maskChunk1Bits + 1
+			payload[20+bit/8] |= 0x80 >> (bit % 8)
+		default:
+			bit := offset - maskChunk2Bits + 1
+			payload[24+bit/8] |= 0x80 >> (bit % 8)
+		}
+	}
+	// k bit marks the last mask chunk
+	switch headerSize {
+	case flexFECHeaderSize1:
+		payload[18] |= 0x80
+	case flexFECHeaderSize2:
+		payload[20] |= 0x80
+	default:
+		payload[24] |= 0x80
+	}
+
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    e.payloadType,
+			SequenceNumber: e.sequenceNumber,
+			Timestamp:      timestamp,
+			SSRC:           e.ssrc,
+		},
+		Payload: payload,
+	}
+	e.sequenceNumber++
+	return pkt
+}
+
+func xorBytes(dst []byte, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,210 @@
+package fec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func mediaPacket(t *testing.T, sn uint16, marker bool, payloadSize int) []byte {
+	payload := make([]byte, payloadSize)
+	for i := range payload {
+		payload[i] = byte(int(sn) + i)
+	}
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    96,
+			SequenceNumber: sn,
+			Timestamp:      uint32(sn/4) * 3000,
+			SSRC:           0x1234,
+		},
+		Payload: payload,
+	}
+	if sn%2 == 0 {
+		require.NoError(t, pkt.Header.SetExtension(1, []byte{byte(sn)}))
+	}
+	data, err := pkt.Marshal()
+	require.NoError(t, err)
+	return data
+}
+
+// protectedSequenceNumbers reads the mask of a repair packet
+func protectedSequenceNumbers(repair *rtp.Packet) []uint16 {
+	payload := repair.Payload
+	baseSN := binary.BigEndian.Uint16(payload[16:18])
+
+	var sns []uint16
+	chunks := []struct {
+		start  int
+		size   int
+		offset int
+	}{
+		{18, 2, 0},
+		{20, 4, maskChunk1Bits},
+		{24, 8, maskChunk2Bits},
+	}
+	for _, c := range chunks {
+		for bit := 1; bit < c.size*8; bit++ {
+			if payload[c.start+bit/8]&(0x80>>(bit%8)) != 0 {
+				sns = append(sns, baseSN+uint16(c.offset+bit-1))
+			}
+		}
+		if payload[c.start]&0x80 != 0 {
+			break
+		}
+	}
+	return sns
+}
+
+func headerSize(repair *rtp.Packet) int {
+	switch {
+	case repair.Payload[18]&0x80 != 0:
+		return flexFECHeaderSize1
+	case repair.Payload[20]&0x80 != 0:
+		return flexFECHeaderSize2
+	default:
+		return flexFECHeaderSize3
+	}
+}
+
+// recover rebuilds the one packet of a repair packet's mask missing from received
+func recoverPacket(t *testing.T, repair *rtp.Packet, received map[uint16][]byte) []byte {
+	fecHeader := make([]byte, 8)
+	copy(fecHeader, repair.Payload[:8])
+	payload := make([]byte, len(repair.Payload)-headerSize(repair))
+	copy(payload, repair.Payload[headerSize(repair):])
+
+	missing := -1
+	for _, sn := range protectedSequenceNumbers(repair) {
+		pkt, ok := received[sn]
+		if !ok {
+			require.Equal(t, -1, missing, "more than one packet missing")
+			missing = int(sn)
+			continue
+		}
+		fecHeader[0] ^= pkt[0]
+		fecHeader[1] ^= pkt[1]
+		binary.BigEndian.PutUint16(fecHeader[2:4], binary.BigEndian.Uint16(fecHeader[2:4])^uint16(len(pkt)-rtpHeaderSize))
+		for i := 4; i < 8; i++ {
+			fecHeader[i] ^= pkt[i]
+		}
+		xorBytes(payload, pkt[rtpHeaderSize:])
+	}
+	require.NotEqual(t, -1, missing, "no packet missing")
+
+	length := binary.BigEndian.Uint16(fecHeader[2:4])
+	recovered := make([]byte, rtpHeaderSize+int(length))
+	recovered[0] = 0x80 | (fecHeader[0] & 0x3f)
+	recovered[1] = fecHeader[1]
+	binary.BigEndian.PutUint16(recovered[2:4], uint16(missing))
+	copy(recovered[4:8], fecHeader[4:8])
+	copy(recovered[8:12], repair.Payload[12:16])
+	copy(recovered[rtpHeaderSize:], payload[:length])
+	return recovered
+}
+
+func TestEncoderRecovery(t *testing.T) {
+	e := NewEncoder(0x5678, 49)
+	e.SetProtection(0.25)
+
+	sent := make(map[uint16][]byte)
+	var repairPackets []*rtp.Packet
+	for sn := uint16(65532); sn != 12; sn++ {
+		pkt := mediaPacket(t, sn, sn%4 == 3, 100+int(sn%7)*50)
+		sent[sn] = pkt
+		repairPackets = append(repairPackets, e.Push(pkt, sn%4 == 3)...)
+	}
+	// 16 packets in frames of 4, one repair packet per frame
+	require.Len(t, repairPackets, 4)
+
+	protected := make(map[uint16]bool)
+	for i, repair := range repairPackets {
+		require.Equal(t, uint32(0x5678), repair.SSRC)
+		require.Equal(t, uint8(49), repair.PayloadType)
+		if i > 0 {
+			require.Equal(t, repairPackets[i-1].SequenceNumber+1, repair.SequenceNumber)
+		}
+
+		sns := protectedSequenceNumbers(repair)
+		require.Len(t, sns, 4)
+		for _, sn := range sns {
+			protected[sn] = true
+
+			// lose each packet in turn and rebuild it
+			received := make(map[uint16][]byte)
+			for _, other := range sns {
+				if other != sn {
+					received[other] = sent[other]
+				}
+			}
+			require.Equal(t, sent[sn], recoverPacket(t, repair, received))
+		}
+	}
+	require.Len(t, protected, 16)
+}
+
+func TestEncoderGrouping(t *testing.T) {
+	t.Run("small frames are grouped", func(t *testing.T) {
+		e := NewEncoder(0x5678, 49)
+		e.SetProtection(0.1)
+
+		var repairPackets []*rtp.Packet
+		for sn := uint16(0); sn < 10; sn++ {
+			repairPackets = append(repairPackets, e.Push(mediaPacket(t, sn, true, 100), true)...)
+		}
+		require.Len(t, repairPackets, 2)
+		require.Equal(t, []uint16{0, 1, 2, 3, 4}, protectedSequenceNumbers(repairPackets[0]))
+		require.Equal(t, []uint16{5, 6, 7, 8, 9}, protectedSequenceNumbers(repairPackets[1]))
+	})
+
+	t.Run("interleaved subsets", func(t *testing.T) {
+		e := NewEncoder(0x5678, 49)
+		e.SetProtection(0.5)
+
+		var repairPackets []*rtp.Packet
+		for sn := uint16(0); sn < 40; sn++ {
+			repairPackets = append(repairPackets, e.Push(mediaPacket(t, sn, sn == 39, 100), sn == 39)...)
+		}
+		require.Len(t, repairPackets, 20)
+		require.Equal(t, []uint16{0, 20}, protectedSequenceNumbers(repairPackets[0]))
+		require.Equal(t, []uint16{19, 39}, protectedSequenceNumbers(repairPackets[19]))
+		// offsets past the first mask chunk need a longer header
+		require.Equal(t, flexFECHeaderSize2, headerSize(repairPackets[0]))
+	})
+
+	t.Run("retransmissions are not protected", func(t *testing.T) {
+		e := NewEncoder(0x5678, 49)
+		e.SetProtection(0.5)
+
+		require.Empty(t, e.Push(mediaPacket(t, 10, false, 100), false))
+		require.Empty(t, e.Push(mediaPacket(t, 11, false, 100), false))
+		require.Empty(t, e.Push(mediaPacket(t, 10, false, 100), false))
+		repairPackets := e.Push(mediaPacket(t, 12, true, 100), true)
+		require.Len(t, repairPackets, 2)
+		require.Equal(t, []uint16{10, 12}, protectedSequenceNumbers(repairPackets[0]))
+		require.Equal(t, []uint16{11}, protectedSequenceNumbers(repairPackets[1]))
+	})
+
+	t.Run("gaps beyond the mask flush the group", func(t *testing.T) {
+		e := NewEncoder(0x5678, 49)
+		e.SetProtection(0.1)
+
+		require.Empty(t, e.Push(mediaPacket(t, 0, true, 100), true))
+		repairPackets := e.Push(mediaPacket(t, 200, false, 100), false)
+		require.Len(t, repairPackets, 1)
+		require.Equal(t, []uint16{0}, protectedSequenceNumbers(repairPackets[0]))
+	})
+
+	t.Run("no protection", func(t *testing.T) {
+		e := NewEncoder(0x5678, 49)
+		e.SetProtection(0.5)
+		require.Empty(t, e.Push(mediaPacket(t, 0, false, 100), false))
+
+		e.SetProtection(0)
+		require.Empty(t, e.Push(mediaPacket(t, 1, true, 100), true))
+	})
+}
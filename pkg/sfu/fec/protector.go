@@ -0,0 +1,182 @@
+package fec
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	// repair packets sent per lost packet, losses are not spread evenly across the subsets of a group
+	lossProtectionFactor = 2.0
+)
+
+type ProtectorParams struct {
+	PayloadType uint8
+	// loss below this is left to retransmissions
+	MinLoss float64
+	// repair packets relative to media packets at most
+	MaxOverhead float64
+	Logger      logger.Logger
+}
+
+// Protector adds FlexFEC repair streams to video sent on a peer connection. It is an interceptor placed closest to
+// the transport so that repair packets protect media packets as they go out, header extensions included.
+type Protector struct {
+	params ProtectorParams
+
+	negotiated atomic.Bool
+
+	lock    sync.RWMutex
+	streams map[uint32]*protectedStream
+}
+
+type protectedStream struct {
+	lock    sync.Mutex
+	encoder *Encoder
+}
+
+func NewProtector(params ProtectorParams) *Protector {
+	return &Protector{
+		params:  params,
+		streams: make(map[uint32]*protectedStream),
+	}
+}
+
+// NewInterceptor implements interceptor.Factory, a Protector serves a single peer connection
+func (p *Protector) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return &protectorInterceptor{protector: p}, nil
+}
+
+// SetNegotiated enables repair streams once the remote side accepted FlexFEC
+func (p *Protector) SetNegotiated(negotiated bool) {
+	if p.negotiated.Swap(negotiated) != negotiated {
+		p.params.Logger.Debugw("flexfec negotiation changed", "negotiated", negotiated)
+	}
+}
+
+// FECSSRC returns the SSRC of the repair stream of a media stream, allocated on first use
+func (p *Protector) FECSSRC(mediaSSRC uint32) uint32 {
+	return p.getOrCreateStream(mediaSSRC).encoder.SSRC()
+}
+
+// SetLoss updates the protection of a media stream from the fraction of its packets lost by the receiver
+func (p *Protector) SetLoss(mediaSSRC uint32, loss float64) {
+	protection := p.protectionForLoss(loss)
+
+	s := p.getOrCreateStream(mediaSSRC)
+	s.lock.Lock()
+	previous := s.encoder.Protection()
+	s.encoder.SetProtection(protection)
+	s.lock.Unlock()
+
+	if (previous == 0) != (protection == 0) {
+		p.params.Logger.Debugw("flexfec protection changed", "ssrc", mediaSSRC, "loss", loss, "protection", protection)
+	}
+}
+
+func (p *Protector) protectionForLoss(loss float64) float64 {
+	if loss <= 0 || loss < p.params.MinLoss {
+		return 0
+	}
+	return math.Min(loss*lossProtectionFactor, p.params.MaxOverhead)
+}
+
+func (p *Protector) getOrCreateStream(mediaSSRC uint32) *protectedStream {
+	p.lock.RLock()
+	s := p.streams[mediaSSRC]
+	p.lock.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if s = p.streams[mediaSSRC]; s == nil {
+		s = &protectedStream{
+			encoder: NewEncoder(rand.Uint32(), p.params.PayloadType),
+		}
+		p.streams[mediaSSRC] = s
+	}
+	return s
+}
+
+func (p *Protector) getStream(mediaSSRC uint32) *protectedStream {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.streams[mediaSSRC]
+}
+
+func (p *Protector) protect(header *rtp.Header, payload []byte) []*rtp.Packet {
+	// padding only packets are probes, nothing to recover
+	if !p.negotiated.Load() || header.Padding {
+		return nil
+	}
+
+	s := p.getStream(header.SSRC)
+	if s == nil {
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.encoder.Protection() == 0 {
+		return nil
+	}
+
+	pkt := make([]byte, header.MarshalSize()+len(payload))
+	n, err := header.MarshalTo(pkt)
+	if err != nil {
+		return nil
+	}
+	copy(pkt[n:], payload)
+	return s.encoder.Push(pkt, header.Marker)
+}
+
+func (p *Protector) reset(mediaSSRC uint32) {
+	if s := p.getStream(mediaSSRC); s != nil {
+		s.lock.Lock()
+		s.encoder.Reset()
+		s.lock.Unlock()
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+type protectorInterceptor struct {
+	interceptor.NoOp
+	protector *Protector
+}
+
+func (i *protectorInterceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	if !strings.HasPrefix(strings.ToLower(info.MimeType), "video/") {
+		return writer
+	}
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		n, err := writer.Write(header, payload, attributes)
+		if err != nil {
+			return n, err
+		}
+
+		for _, repair := range i.protector.protect(header, payload) {
+			if _, err := writer.Write(&repair.Header, repair.Payload, attributes); err != nil {
+				break
+			}
+		}
+		return n, nil
+	})
+}
+
+func (i *protectorInterceptor) UnbindLocalStream(info *interceptor.StreamInfo) {
+	// SSRC of a sender stays across track replacement, keep its repair stream for the next track
+	i.protector.reset(info.SSRC)
+}
@@ -0,0 +1,74 @@
+package fec
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestProtector(t *testing.T) {
+	p := NewProtector(ProtectorParams{
+		PayloadType: 49,
+		MinLoss:     0.02,
+		MaxOverhead: 0.3,
+		Logger:      logger.GetLogger(),
+	})
+
+	require.Equal(t, 0.0, p.protectionForLoss(0.01))
+	require.Equal(t, 0.1, p.protectionForLoss(0.05))
+	require.Equal(t, 0.3, p.protectionForLoss(0.5))
+
+	i, err := p.NewInterceptor("")
+	require.NoError(t, err)
+
+	var written []*rtp.Header
+	writer := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 0x1234, MimeType: "video/VP8"}, interceptor.RTPWriterFunc(
+		func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+			written = append(written, header)
+			return len(payload), nil
+		},
+	))
+	writeFrame := func(sn uint16) {
+		for ; sn%4 != 3; sn++ {
+			_, err := writer.Write(&rtp.Header{Version: 2, SequenceNumber: sn, SSRC: 0x1234}, make([]byte, 100), nil)
+			require.NoError(t, err)
+		}
+		_, err := writer.Write(&rtp.Header{Version: 2, Marker: true, SequenceNumber: sn, SSRC: 0x1234}, make([]byte, 100), nil)
+		require.NoError(t, err)
+	}
+
+	fecSSRC := p.FECSSRC(0x1234)
+	require.Equal(t, fecSSRC, p.FECSSRC(0x1234))
+	require.NotEqual(t, fecSSRC, p.FECSSRC(0x4321))
+
+	// not protected till negotiated and lossy
+	p.SetLoss(0x1234, 0.2)
+	writeFrame(0)
+	require.Len(t, written, 4)
+
+	p.SetNegotiated(true)
+	p.SetLoss(0x1234, 0.01)
+	writeFrame(4)
+	require.Len(t, written, 8)
+
+	p.SetLoss(0x1234, 0.2)
+	writeFrame(8)
+	require.Len(t, written, 13)
+	require.Equal(t, fecSSRC, written[12].SSRC)
+	require.Equal(t, uint8(49), written[12].PayloadType)
+
+	// audio is not protected
+	audioWriter := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 0x1234, MimeType: "audio/opus"}, interceptor.RTPWriterFunc(
+		func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+			written = append(written, header)
+			return len(payload), nil
+		},
+	))
+	_, err = audioWriter.Write(&rtp.Header{Version: 2, Marker: true, SequenceNumber: 12, SSRC: 0x1234}, make([]byte, 100), nil)
+	require.NoError(t, err)
+	require.Len(t, written, 14)
+}
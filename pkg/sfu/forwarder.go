@@ -14,6 +14,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/codecmunger"
 	dd "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 	"github.com/livekit/livekit-server/pkg/sfu/videolayerselector"
 	"github.com/livekit/livekit-server/pkg/sfu/videolayerselector/temporallayerselector"
 )
@@ -114,10 +115,11 @@ type VideoTransition struct {
 	From           buffer.VideoLayer
 	To             buffer.VideoLayer
 	BandwidthDelta int64
+	NeedsKeyFrame  bool
 }
 
 func (v VideoTransition) String() string {
-	return fmt.Sprintf("VideoTransition{from: %s, to: %s, del: %d}", v.From, v.To, v.BandwidthDelta)
+	return fmt.Sprintf("VideoTransition{from: %s, to: %s, del: %d, kf: %v}", v.From, v.To, v.BandwidthDelta, v.NeedsKeyFrame)
 }
 
 // -------------------------------------------------------------------
@@ -159,6 +161,7 @@ type Forwarder struct {
 	logger                        logger.Logger
 	getReferenceLayerRTPTimestamp func(ts uint32, layer int32, referenceLayer int32) (uint32, error)
 	getExpectedRTPTimestamp       func(at time.Time) (uint32, error)
+	clock                         utils.Clock
 
 	muted    bool
 	pubMuted bool
@@ -166,6 +169,7 @@ type Forwarder struct {
 	started               bool
 	lastSSRC              uint32
 	referenceLayerSpatial int32
+	isSourceChanged       bool
 
 	parkedLayerTimer *time.Timer
 
@@ -178,6 +182,8 @@ type Forwarder struct {
 	vls videolayerselector.VideoLayerSelector
 
 	codecMunger codecmunger.CodecMunger
+	// payloads are not inspected or munged, layers are selected by header extensions only
+	opaquePayload bool
 
 	onParkedLayerExpired func()
 }
@@ -193,6 +199,7 @@ func NewForwarder(
 		logger:                        logger,
 		getReferenceLayerRTPTimestamp: getReferenceLayerRTPTimestamp,
 		getExpectedRTPTimestamp:       getExpectedRTPTimestamp,
+		clock:                         utils.SystemClock,
 		referenceLayerSpatial:         buffer.InvalidLayerSpatial,
 		lastAllocation:                VideoAllocationDefault,
 		rtpMunger:                     NewRTPMunger(logger),
@@ -206,6 +213,14 @@ func NewForwarder(
 	return f
 }
 
+// SetClock replaces the wall clock, for simulations
+func (f *Forwarder) SetClock(clock utils.Clock) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.clock = clock
+}
+
 func (f *Forwarder) SetMaxPublishedLayer(maxPublishedLayer int32) bool {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -248,6 +263,15 @@ func (f *Forwarder) getOnParkedLayerExpired() func() {
 	return f.onParkedLayerExpired
 }
 
+// SetOpaquePayload forwards payloads as they are, for frames encrypted by clients. Needs to be called before the codec
+// is determined
+func (f *Forwarder) SetOpaquePayload(opaque bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.opaquePayload = opaque
+}
+
 func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions []webrtc.RTPHeaderExtensionParameter) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -257,6 +281,11 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions [
 	}
 	f.codec = codec
 
+	if f.opaquePayload {
+		f.determineOpaqueLayerSelector(codec, extensions)
+		return
+	}
+
 	switch strings.ToLower(codec.MimeType) {
 	case "video/vp8":
 		f.codecMunger = codecmunger.NewVP8FromNull(f.codecMunger, f.logger)
@@ -266,7 +295,7 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions [
 			f.vls = videolayerselector.NewSimulcast(f.logger)
 		}
 		f.vls.SetTemporalLayerSelector(temporallayerselector.NewVP8(f.logger))
-	case "video/h264":
+	case "video/h264", "video/h265":
 		if f.vls != nil {
 			f.vls = videolayerselector.NewSimulcastFromNull(f.vls)
 		} else {
@@ -305,6 +334,37 @@ func (f *Forwarder) DetermineCodec(codec webrtc.RTPCodecCapability, extensions [
 	}
 }
 
+// determineOpaqueLayerSelector selects layers without looking into payloads, which leaves the codec munger a null
+// one. Scalable codecs need the dependency descriptor, other codecs are forwarded as simulcast, all temporal layers of
+// a stream together
+func (f *Forwarder) determineOpaqueLayerSelector(codec webrtc.RTPCodecCapability, extensions []webrtc.RTPHeaderExtensionParameter) {
+	if !strings.HasPrefix(strings.ToLower(codec.MimeType), "video/") {
+		return
+	}
+
+	isDDAvailable := false
+	for _, ext := range extensions {
+		if ext.URI == dd.ExtensionUrl {
+			isDDAvailable = true
+			break
+		}
+	}
+	if isDDAvailable && IsSvcCodec(codec.MimeType) {
+		if f.vls != nil {
+			f.vls = videolayerselector.NewDependencyDescriptorFromNull(f.vls)
+		} else {
+			f.vls = videolayerselector.NewDependencyDescriptor(f.logger)
+		}
+		return
+	}
+
+	if f.vls != nil {
+		f.vls = videolayerselector.NewSimulcastFromNull(f.vls)
+	} else {
+		f.vls = videolayerselector.NewSimulcast(f.logger)
+	}
+}
+
 func (f *Forwarder) GetState() ForwarderState {
 	f.lock.RLock()
 	defer f.lock.RUnlock()
@@ -469,6 +529,14 @@ func (f *Forwarder) TargetLayer() buffer.VideoLayer {
 	return f.vls.GetTarget()
 }
 
+// LastAllocation returns the outcome of the latest allocation
+func (f *Forwarder) LastAllocation() VideoAllocation {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.lastAllocation
+}
+
 func (f *Forwarder) isDeficientLocked() bool {
 	return f.lastAllocation.IsDeficient
 }
@@ -755,6 +823,7 @@ func (f *Forwarder) ProvisionalAllocateGetCooperativeTransition(allowOvershoot b
 			From:           f.vls.GetTarget(),
 			To:             f.provisional.allocatedLayer,
 			BandwidthDelta: bandwidthRequired - getBandwidthNeeded(f.provisional.Bitrates, existingTargetLayer, f.lastAllocation.BandwidthRequested),
+			NeedsKeyFrame:  f.isKeyFrameNeededLocked(f.vls.GetTarget(), f.provisional.allocatedLayer),
 		}
 	}
 
@@ -796,6 +865,7 @@ func (f *Forwarder) ProvisionalAllocateGetCooperativeTransition(allowOvershoot b
 					From:           existingTargetLayer,
 					To:             maximalLayer,
 					BandwidthDelta: maximalBandwidthRequired - getBandwidthNeeded(f.provisional.Bitrates, existingTargetLayer, f.lastAllocation.BandwidthRequested),
+					NeedsKeyFrame:  f.isKeyFrameNeededLocked(existingTargetLayer, maximalLayer),
 				}
 			}
 		}
@@ -862,6 +932,7 @@ func (f *Forwarder) ProvisionalAllocateGetCooperativeTransition(allowOvershoot b
 		From:           f.vls.GetTarget(),
 		To:             targetLayer,
 		BandwidthDelta: bandwidthRequired - getBandwidthNeeded(f.provisional.Bitrates, existingTargetLayer, f.lastAllocation.BandwidthRequested),
+		NeedsKeyFrame:  f.isKeyFrameNeededLocked(f.vls.GetTarget(), targetLayer),
 	}
 }
 
@@ -879,6 +950,7 @@ func (f *Forwarder) ProvisionalAllocateGetBestWeightedTransition() VideoTransiti
 	//      Best offer is calculated as bandwidth saved moving to a down layer divided by cost.
 	//      Cost has two components
 	//        a. Transition cost: Spatial layer switch is expensive due to key frame requirement, but temporal layer switch is free.
+	//           Switching down spatial layers of SVC does not need a key frame and is free too.
 	//        b. Quality cost: The farther away from desired layers, the higher the quality cost.
 	//
 	f.lock.Lock()
@@ -892,6 +964,7 @@ func (f *Forwarder) ProvisionalAllocateGetBestWeightedTransition() VideoTransiti
 			From:           targetLayer,
 			To:             f.provisional.allocatedLayer,
 			BandwidthDelta: 0 - getBandwidthNeeded(f.provisional.Bitrates, targetLayer, f.lastAllocation.BandwidthRequested),
+			NeedsKeyFrame:  f.isKeyFrameNeededLocked(targetLayer, f.provisional.allocatedLayer),
 		}
 	}
 
@@ -921,6 +994,7 @@ func (f *Forwarder) ProvisionalAllocateGetBestWeightedTransition() VideoTransiti
 			From:           targetLayer,
 			To:             f.provisional.allocatedLayer,
 			BandwidthDelta: 0 - getBandwidthNeeded(f.provisional.Bitrates, targetLayer, f.lastAllocation.BandwidthRequested),
+			NeedsKeyFrame:  f.isKeyFrameNeededLocked(targetLayer, f.provisional.allocatedLayer),
 		}
 	}
 
@@ -939,8 +1013,7 @@ func (f *Forwarder) ProvisionalAllocateGetBestWeightedTransition() VideoTransiti
 			bandwidthDelta := int64(math.Max(float64(0), float64(existingBandwidthNeeded-f.provisional.Bitrates[s][t])))
 
 			transitionCost := int32(0)
-			// LK-TODO: SVC will need a different cost transition
-			if targetLayer.Spatial != s {
+			if f.vls.IsKeyFrameNeededToSwitch(targetLayer.Spatial, s) {
 				transitionCost = TransitionCostSpatial
 			}
 
@@ -963,6 +1036,7 @@ func (f *Forwarder) ProvisionalAllocateGetBestWeightedTransition() VideoTransiti
 		From:           targetLayer,
 		To:             bestLayer,
 		BandwidthDelta: bestBandwidthDelta,
+		NeedsKeyFrame:  f.isKeyFrameNeededLocked(targetLayer, bestLayer),
 	}
 }
 
@@ -1198,6 +1272,7 @@ func (f *Forwarder) GetNextHigherTransition(brs Bitrates, allowOvershoot bool) (
 					From:           targetLayer,
 					To:             buffer.VideoLayer{Spatial: s, Temporal: t},
 					BandwidthDelta: bandwidthRequested - alreadyAllocated,
+					NeedsKeyFrame:  f.isKeyFrameNeededLocked(targetLayer, buffer.VideoLayer{Spatial: s, Temporal: t}),
 				}
 
 				return true, transition, true
@@ -1293,8 +1368,11 @@ func (f *Forwarder) Pause(availableLayers []int32, brs Bitrates) VideoAllocation
 
 func (f *Forwarder) updateAllocation(alloc VideoAllocation, reason string) VideoAllocation {
 	// restrict target temporal to 0 if codec does not support temporal layers
-	if alloc.TargetLayer.IsValid() && strings.ToLower(f.codec.MimeType) == "video/h264" {
-		alloc.TargetLayer.Temporal = 0
+	if alloc.TargetLayer.IsValid() {
+		switch strings.ToLower(f.codec.MimeType) {
+		case "video/h264", "video/h265":
+			alloc.TargetLayer.Temporal = 0
+		}
 	}
 
 	if alloc.IsDeficient != f.lastAllocation.IsDeficient ||
@@ -1329,6 +1407,17 @@ func (f *Forwarder) Resync() {
 	f.resyncLocked()
 }
 
+// ResyncSource resyncs to a different media source, time stamps of the new source are not related
+// to the previous source, so the next time stamp is derived from expected time stamp of the down stream
+func (f *Forwarder) ResyncSource() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.resyncLocked()
+	f.referenceLayerSpatial = buffer.InvalidLayerSpatial
+	f.isSourceChanged = true
+}
+
 func (f *Forwarder) resyncLocked() {
 	f.vls.SetCurrent(buffer.InvalidLayer)
 	f.lastSSRC = 0
@@ -1364,10 +1453,17 @@ func (f *Forwarder) CheckSync() (locked bool, layer int32) {
 	defer f.lock.RUnlock()
 
 	layer = f.vls.GetRequestSpatial()
-	locked = layer == f.vls.GetCurrent().Spatial || f.vls.GetParked().IsValid()
+	currentSpatial := f.vls.GetCurrent().Spatial
+	locked = layer == currentSpatial ||
+		f.vls.GetParked().IsValid() ||
+		(currentSpatial != buffer.InvalidLayerSpatial && !f.vls.IsKeyFrameNeededToSwitch(currentSpatial, layer))
 	return
 }
 
+func (f *Forwarder) isKeyFrameNeededLocked(from buffer.VideoLayer, to buffer.VideoLayer) bool {
+	return f.vls.IsKeyFrameNeededToSwitch(from.Spatial, to.Spatial)
+}
+
 func (f *Forwarder) FilterRTX(nacks []uint16) (filtered []uint16, disallowedLayers [buffer.DefaultMaxLayerSpatial + 1]bool) {
 	if !FlagFilterRTX {
 		filtered = nacks
@@ -1435,58 +1531,32 @@ func (f *Forwarder) getTranslationParamsCommon(extPkt *buffer.ExtPacket, layer i
 			f.rtpMunger.SetLastSnTs(extPkt)
 			f.codecMunger.SetLast(extPkt)
 		} else {
-			if f.referenceLayerSpatial == buffer.InvalidLayerSpatial {
-				// on a resume, reference layer may not be set, so only set when it is invalid
-				f.referenceLayerSpatial = layer
-			}
-
-			// Compute how much time passed between the old RTP extPkt
-			// and the current packet, and fix timestamp on source change
-			//
-			// There are three time stamps to consider here
-			//   1. lastTS -> time stamp of last sent packet
-			//   2. refTS -> time stamp of this packet (after munging) calculated using feed's RTCP sender report
-			//   3. expectedTS -> time stamp of this packet (after munging) calculated using this stream's RTCP sender report
-			// Ideally, refTS and expectedTS should be very close and lastTS should be before both of those.
-			// But, cases like muting/unmuting, clock vagaries make them not satisfy those conditions always.
-			//
-			// There are 6 orderings to consider (considering only inequalities). Resolve them using following rules
-			//   1. Timestamp has to move forward
-			//   2. Keep next time stamp close to expected
-			lastTS := f.rtpMunger.GetLast().LastTS
-			refTS := lastTS
-			expectedTS := lastTS
-			switchingAt := time.Now()
-			if f.getReferenceLayerRTPTimestamp != nil {
-				ts, err := f.getReferenceLayerRTPTimestamp(extPkt.Packet.Timestamp, layer, f.referenceLayerSpatial)
-				if err == nil {
-					refTS = ts
-				}
-			}
-			if f.getExpectedRTPTimestamp != nil {
-				ts, err := f.getExpectedRTPTimestamp(switchingAt)
-				if err == nil {
-					expectedTS = ts
-				}
-			}
-			nextTS, explain := getNextTimestamp(lastTS, refTS, expectedTS)
-			f.logger.Debugw(
-				"next timestamp on switch",
-				"switchingAt", switchingAt.String(),
-				"lastTS", lastTS,
-				"refTS", refTS,
-				"expectedTS", expectedTS,
-				"nextTS", nextTS,
-				"jump", nextTS-lastTS,
-				"explanation", explain,
-			)
-
-			f.rtpMunger.UpdateSnTsOffsets(extPkt, 1, nextTS-lastTS)
-			f.codecMunger.UpdateOffsets(extPkt)
+			f.resyncOffsets(extPkt, layer, 1)
 		}
 
 		f.logger.Debugw("switching feed", "from", f.lastSSRC, "to", extPkt.Packet.SSRC)
 		f.lastSSRC = extPkt.Packet.SSRC
+		f.isSourceChanged = false
+	} else if f.started {
+		// a sender restart can move sequence numbers to a different space without changing SSRC
+		switch jump, numDropped := f.rtpMunger.DetectJump(extPkt); jump {
+		case SequenceNumberJumpPending:
+			if tp == nil {
+				tp = &TranslationParams{}
+			}
+			tp.shouldDrop = true
+			return tp, nil
+
+		case SequenceNumberJumpConfirmed:
+			f.logger.Infow(
+				"sequence number jump, resyncing",
+				"ssrc", extPkt.Packet.SSRC,
+				"sn", extPkt.Packet.SequenceNumber,
+				"highestIncomingSN", f.rtpMunger.GetParams().highestIncomingSN,
+				"dropped", numDropped,
+			)
+			f.resyncOffsets(extPkt, layer, uint16(numDropped)+1)
+		}
 	}
 
 	if tp == nil {
@@ -1505,6 +1575,60 @@ func (f *Forwarder) getTranslationParamsCommon(extPkt *buffer.ExtPacket, layer i
 	return tp, nil
 }
 
+// should be called with lock held
+func (f *Forwarder) resyncOffsets(extPkt *buffer.ExtPacket, layer int32, snAdjust uint16) {
+	if f.referenceLayerSpatial == buffer.InvalidLayerSpatial {
+		// on a resume, reference layer may not be set, so only set when it is invalid
+		f.referenceLayerSpatial = layer
+	}
+
+	// Compute how much time passed between the old RTP extPkt
+	// and the current packet, and fix timestamp on source change
+	//
+	// There are three time stamps to consider here
+	//   1. lastTS -> time stamp of last sent packet
+	//   2. refTS -> time stamp of this packet (after munging) calculated using feed's RTCP sender report
+	//   3. expectedTS -> time stamp of this packet (after munging) calculated using this stream's RTCP sender report
+	// Ideally, refTS and expectedTS should be very close and lastTS should be before both of those.
+	// But, cases like muting/unmuting, clock vagaries make them not satisfy those conditions always.
+	//
+	// There are 6 orderings to consider (considering only inequalities). Resolve them using following rules
+	//   1. Timestamp has to move forward
+	//   2. Keep next time stamp close to expected
+	lastTS := f.rtpMunger.GetLast().LastTS
+	refTS := lastTS
+	expectedTS := lastTS
+	switchingAt := f.clock.Now()
+	if f.getExpectedRTPTimestamp != nil {
+		ts, err := f.getExpectedRTPTimestamp(switchingAt)
+		if err == nil {
+			expectedTS = ts
+		}
+	}
+	if f.isSourceChanged {
+		refTS = expectedTS
+	} else if f.getReferenceLayerRTPTimestamp != nil {
+		ts, err := f.getReferenceLayerRTPTimestamp(extPkt.Packet.Timestamp, layer, f.referenceLayerSpatial)
+		if err == nil {
+			refTS = ts
+		}
+	}
+	nextTS, explain := getNextTimestamp(lastTS, refTS, expectedTS)
+	f.logger.Debugw(
+		"next timestamp on switch",
+		"switchingAt", switchingAt.String(),
+		"lastTS", lastTS,
+		"refTS", refTS,
+		"expectedTS", expectedTS,
+		"nextTS", nextTS,
+		"jump", nextTS-lastTS,
+		"explanation", explain,
+	)
+
+	f.rtpMunger.UpdateSnTsOffsets(extPkt, snAdjust, nextTS-lastTS)
+	f.codecMunger.UpdateOffsets(extPkt)
+}
+
 // should be called with lock held
 func (f *Forwarder) getTranslationParamsAudio(extPkt *buffer.ExtPacket, layer int32) (*TranslationParams, error) {
 	return f.getTranslationParamsCommon(extPkt, layer, nil)
@@ -1535,7 +1659,9 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 	tp.ddBytes = result.DependencyDescriptorExtension
 	tp.marker = result.RTPMarker
 
-	if FlagPauseOnDowngrade && f.isDeficientLocked() && f.vls.GetTarget().Spatial < f.vls.GetCurrent().Spatial {
+	currentSpatial := f.vls.GetCurrent().Spatial
+	if FlagPauseOnDowngrade && f.isDeficientLocked() && f.vls.GetTarget().Spatial < currentSpatial &&
+		f.vls.IsKeyFrameNeededToSwitch(currentSpatial, f.vls.GetTarget().Spatial) {
 		//
 		// If target layer is lower than both the current and
 		// maximum subscribed layer, it is due to bandwidth
@@ -1554,6 +1680,8 @@ func (f *Forwarder) getTranslationParamsVideo(extPkt *buffer.ExtPacket, layer in
 		//
 		// To differentiate between the two cases, drop only when in DEFICIENT state.
 		//
+		// Layers that can be switched down without a key frame switch at the next frame boundary, no need to drop.
+		//
 		tp.shouldDrop = true
 		return tp, nil
 	}
@@ -2,6 +2,7 @@ package sfu
 
 import (
 	"testing"
+	"time"
 
 	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
@@ -661,6 +662,7 @@ func TestForwarderProvisionalAllocateGetCooperativeTransition(t *testing.T) {
 		From:           buffer.InvalidLayer,
 		To:             buffer.VideoLayer{Spatial: 0, Temporal: 0},
 		BandwidthDelta: 1,
+		NeedsKeyFrame:  true,
 	}
 	transition := f.ProvisionalAllocateGetCooperativeTransition(false)
 	require.Equal(t, expectedTransition, transition)
@@ -760,6 +762,7 @@ func TestForwarderProvisionalAllocateGetCooperativeTransition(t *testing.T) {
 		From:           buffer.InvalidLayer,
 		To:             buffer.VideoLayer{Spatial: 1, Temporal: 0},
 		BandwidthDelta: 5,
+		NeedsKeyFrame:  true,
 	}
 	transition = f.ProvisionalAllocateGetCooperativeTransition(true)
 	require.Equal(t, expectedTransition, transition)
@@ -800,6 +803,7 @@ func TestForwarderProvisionalAllocateGetCooperativeTransition(t *testing.T) {
 		From:           buffer.InvalidLayer,
 		To:             buffer.VideoLayer{Spatial: 0, Temporal: 2},
 		BandwidthDelta: -5, // 5 was the bandwidth needed for the last allocation
+		NeedsKeyFrame:  true,
 	}
 	transition = f.ProvisionalAllocateGetCooperativeTransition(true)
 	require.Equal(t, expectedTransition, transition)
@@ -860,6 +864,56 @@ func TestForwarderProvisionalAllocateGetBestWeightedTransition(t *testing.T) {
 	require.Equal(t, expectedTransition, transition)
 }
 
+func TestForwarderProvisionalAllocateGetBestWeightedTransitionSVC(t *testing.T) {
+	f := newForwarder(testutils.TestVP9Codec, webrtc.RTPCodecTypeVideo)
+	f.SetMaxSpatialLayer(buffer.DefaultMaxLayerSpatial)
+	f.SetMaxTemporalLayer(buffer.DefaultMaxLayerTemporal)
+
+	bitrates := Bitrates{
+		{1, 2, 3, 4},
+		{5, 6, 7, 8},
+		{9, 10, 11, 12},
+	}
+
+	f.ProvisionalAllocatePrepare(nil, bitrates)
+
+	// switching down spatial layers does not need a key frame, unlike VP8 with the same bitrates the best offer is
+	// a spatial switch giving back more bits
+	f.vls.SetTarget(buffer.VideoLayer{Spatial: 2, Temporal: 2})
+	f.lastAllocation.BandwidthRequested = bitrates[2][2]
+	expectedTransition := VideoTransition{
+		From:           f.TargetLayer(),
+		To:             buffer.VideoLayer{Spatial: 0, Temporal: 0},
+		BandwidthDelta: 10,
+	}
+	transition := f.ProvisionalAllocateGetBestWeightedTransition()
+	require.Equal(t, expectedTransition, transition)
+}
+
+func TestForwarderCheckSyncSVC(t *testing.T) {
+	f := newForwarder(testutils.TestVP9Codec, webrtc.RTPCodecTypeVideo)
+	f.vls.SetCurrent(buffer.VideoLayer{Spatial: 2, Temporal: 2})
+
+	// switching down is locked, no key frame request
+	f.vls.SetRequestSpatial(0)
+	locked, layer := f.CheckSync()
+	require.True(t, locked)
+	require.Equal(t, int32(0), layer)
+
+	f.vls.SetCurrent(buffer.VideoLayer{Spatial: 0, Temporal: 2})
+	f.vls.SetRequestSpatial(1)
+	locked, layer = f.CheckSync()
+	require.False(t, locked)
+	require.Equal(t, int32(1), layer)
+
+	// simulcast needs a key frame to switch down
+	f = newForwarder(testutils.TestVP8Codec, webrtc.RTPCodecTypeVideo)
+	f.vls.SetCurrent(buffer.VideoLayer{Spatial: 2, Temporal: 2})
+	f.vls.SetRequestSpatial(0)
+	locked, _ = f.CheckSync()
+	require.False(t, locked)
+}
+
 func TestForwarderAllocateNextHigher(t *testing.T) {
 	f := newForwarder(testutils.TestOpusCodec, webrtc.RTPCodecTypeAudio)
 	f.SetMaxSpatialLayer(buffer.DefaultMaxLayerSpatial)
@@ -1322,6 +1376,52 @@ func TestForwarderGetTranslationParamsAudio(t *testing.T) {
 	require.Equal(t, f.lastSSRC, params.SSRC)
 }
 
+func TestForwarderResyncSource(t *testing.T) {
+	// reference time stamp of the new source is not related to the previous source
+	getReferenceLayerRTPTimestamp := func(ts uint32, _ int32, _ int32) (uint32, error) {
+		return ts + (1 << 30), nil
+	}
+	getExpectedRTPTimestamp := func(_ time.Time) (uint32, error) {
+		return 0xabcdef + 960, nil
+	}
+	f := NewForwarder(webrtc.RTPCodecTypeAudio, logger.GetLogger(), getReferenceLayerRTPTimestamp, getExpectedRTPTimestamp)
+	f.DetermineCodec(testutils.TestOpusCodec, nil)
+
+	params := &testutils.TestExtPacketParams{
+		SequenceNumber: 23333,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+		PayloadSize:    20,
+	}
+	extPkt, _ := testutils.GetTestExtPacket(params)
+	_, err := f.GetTranslationParams(extPkt, 0)
+	require.NoError(t, err)
+
+	f.ResyncSource()
+
+	// new source continues sequence numbers and time stamps follow the down stream
+	params = &testutils.TestExtPacketParams{
+		SequenceNumber: 100,
+		Timestamp:      0x1234,
+		SSRC:           0x87654321,
+		PayloadSize:    20,
+	}
+	extPkt, _ = testutils.GetTestExtPacket(params)
+
+	expectedTP := TranslationParams{
+		rtp: &TranslationParamsRTP{
+			snOrdering:     SequenceNumberOrderingContiguous,
+			sequenceNumber: 23334,
+			timestamp:      0xabcdef + 960,
+		},
+	}
+	actualTP, err := f.GetTranslationParams(extPkt, 0)
+	require.NoError(t, err)
+	require.Equal(t, expectedTP, *actualTP)
+	require.Equal(t, params.SSRC, f.lastSSRC)
+	require.False(t, f.isSourceChanged)
+}
+
 func TestForwarderGetTranslationParamsVideo(t *testing.T) {
 	f := newForwarder(testutils.TestVP8Codec, webrtc.RTPCodecTypeVideo)
 
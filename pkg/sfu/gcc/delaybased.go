@@ -0,0 +1,401 @@
+package gcc
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// packets sent within this interval of the first packet of a group form a group
+	burstInterval = 5 * time.Millisecond
+
+	trendlineSmoothing     = 0.9
+	trendlineWindowSize    = 20
+	trendlineThresholdGain = 4.0
+	trendlineMaxDeltas     = 60
+
+	// thresholds are in ms
+	overuseInitialThreshold    = 12.5
+	overuseMinThreshold        = 6.0
+	overuseMaxThreshold        = 600.0
+	overuseThresholdMaxAdapt   = 15.0
+	overuseKUp                 = 0.0087
+	overuseKDown               = 0.039
+	overuseTime                = 10.0
+	maxThresholdUpdateInterval = 100 * time.Millisecond
+)
+
+type bandwidthUsage int
+
+const (
+	bandwidthUsageNormal bandwidthUsage = iota
+	bandwidthUsageUnderusing
+	bandwidthUsageOverusing
+)
+
+func (b bandwidthUsage) String() string {
+	switch b {
+	case bandwidthUsageNormal:
+		return "NORMAL"
+	case bandwidthUsageUnderusing:
+		return "UNDERUSING"
+	case bandwidthUsageOverusing:
+		return "OVERUSING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+type packetGroup struct {
+	firstSend   time.Time
+	lastSend    time.Time
+	lastArrival time.Duration
+}
+
+type trendSample struct {
+	arrival float64
+	delay   float64
+}
+
+// delayDetector tells whether queues build up on the path from the trend of the one way delay variation between
+// groups of packets, against a threshold that adapts to the jitter of the path
+type delayDetector struct {
+	current     packetGroup
+	hasCurrent  bool
+	previous    packetGroup
+	hasPrevious bool
+
+	firstArrival     time.Duration
+	hasFirstArrival  bool
+	accumulatedDelay float64
+	smoothedDelay    float64
+	numDeltas        int
+	window           []trendSample
+	trend            float64
+	previousTrend    float64
+	modifiedTrend    float64
+
+	threshold           float64
+	lastThresholdUpdate time.Time
+	timeOverUsing       float64
+	overuseCounter      int
+	usage               bandwidthUsage
+}
+
+func newDelayDetector() *delayDetector {
+	return &delayDetector{
+		threshold:     overuseInitialThreshold,
+		timeOverUsing: -1,
+	}
+}
+
+// update takes received packets in the order they were sent
+func (d *delayDetector) update(report *packetReport, now time.Time) bandwidthUsage {
+	if !d.hasCurrent {
+		d.startGroup(report)
+		return d.usage
+	}
+
+	// reordered packets of a completed group are ignored
+	if report.sendTime.Before(d.current.firstSend) {
+		return d.usage
+	}
+
+	if report.sendTime.Sub(d.current.firstSend) <= burstInterval {
+		if report.sendTime.After(d.current.lastSend) {
+			d.current.lastSend = report.sendTime
+		}
+		if report.arrival > d.current.lastArrival {
+			d.current.lastArrival = report.arrival
+		}
+		return d.usage
+	}
+
+	if d.hasPrevious {
+		d.updateTrend(
+			d.current.lastSend.Sub(d.previous.lastSend),
+			d.current.lastArrival-d.previous.lastArrival,
+			d.current.lastArrival,
+			now,
+		)
+	}
+	d.previous = d.current
+	d.hasPrevious = true
+	d.startGroup(report)
+	return d.usage
+}
+
+func (d *delayDetector) startGroup(report *packetReport) {
+	d.current = packetGroup{
+		firstSend:   report.sendTime,
+		lastSend:    report.sendTime,
+		lastArrival: report.arrival,
+	}
+	d.hasCurrent = true
+}
+
+func (d *delayDetector) updateTrend(sendDelta time.Duration, arrivalDelta time.Duration, arrival time.Duration, now time.Time) {
+	if !d.hasFirstArrival {
+		d.firstArrival = arrival
+		d.hasFirstArrival = true
+	}
+	if d.numDeltas < trendlineMaxDeltas {
+		d.numDeltas++
+	}
+
+	d.accumulatedDelay += durationMs(arrivalDelta) - durationMs(sendDelta)
+	d.smoothedDelay = trendlineSmoothing*d.smoothedDelay + (1-trendlineSmoothing)*d.accumulatedDelay
+
+	d.window = append(d.window, trendSample{
+		arrival: durationMs(arrival - d.firstArrival),
+		delay:   d.smoothedDelay,
+	})
+	if len(d.window) > trendlineWindowSize {
+		d.window = d.window[len(d.window)-trendlineWindowSize:]
+	}
+	if len(d.window) == trendlineWindowSize {
+		if slope, ok := linearFitSlope(d.window); ok {
+			d.trend = slope
+		}
+	}
+
+	d.detect(durationMs(sendDelta), now)
+}
+
+func (d *delayDetector) detect(sendDelta float64, now time.Time) {
+	if d.numDeltas < 2 {
+		d.usage = bandwidthUsageNormal
+		return
+	}
+
+	d.modifiedTrend = float64(d.numDeltas) * d.trend * trendlineThresholdGain
+	switch {
+	case d.modifiedTrend > d.threshold:
+		if d.timeOverUsing < 0 {
+			// assume the overuse started half way through the group
+			d.timeOverUsing = sendDelta / 2
+		} else {
+			d.timeOverUsing += sendDelta
+		}
+		d.overuseCounter++
+		if d.timeOverUsing > overuseTime && d.overuseCounter > 1 && d.trend >= d.previousTrend {
+			d.timeOverUsing = 0
+			d.overuseCounter = 0
+			d.usage = bandwidthUsageOverusing
+		}
+	case d.modifiedTrend < -d.threshold:
+		d.timeOverUsing = -1
+		d.overuseCounter = 0
+		d.usage = bandwidthUsageUnderusing
+	default:
+		d.timeOverUsing = -1
+		d.overuseCounter = 0
+		d.usage = bandwidthUsageNormal
+	}
+	d.previousTrend = d.trend
+
+	d.updateThreshold(now)
+}
+
+func (d *delayDetector) updateThreshold(now time.Time) {
+	if d.lastThresholdUpdate.IsZero() {
+		d.lastThresholdUpdate = now
+	}
+
+	trend := math.Abs(d.modifiedTrend)
+	// spikes, e. g. a path change, should not make the detector insensitive
+	if trend > d.threshold+overuseThresholdMaxAdapt {
+		d.lastThresholdUpdate = now
+		return
+	}
+
+	k := overuseKUp
+	if trend < d.threshold {
+		k = overuseKDown
+	}
+	elapsed := now.Sub(d.lastThresholdUpdate)
+	if elapsed > maxThresholdUpdateInterval {
+		elapsed = maxThresholdUpdateInterval
+	}
+	d.threshold += k * (trend - d.threshold) * durationMs(elapsed)
+	d.threshold = math.Max(overuseMinThreshold, math.Min(d.threshold, overuseMaxThreshold))
+	d.lastThresholdUpdate = now
+}
+
+func linearFitSlope(samples []trendSample) (float64, bool) {
+	sumX, sumY := 0.0, 0.0
+	for _, s := range samples {
+		sumX += s.arrival
+		sumY += s.delay
+	}
+	avgX := sumX / float64(len(samples))
+	avgY := sumY / float64(len(samples))
+
+	numerator, denominator := 0.0, 0.0
+	for _, s := range samples {
+		numerator += (s.arrival - avgX) * (s.delay - avgY)
+		denominator += (s.arrival - avgX) * (s.arrival - avgX)
+	}
+	if denominator == 0 {
+		return 0, false
+	}
+	return numerator / denominator, true
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ---------------------------------------------------------------------
+
+const (
+	rateDecreaseFactor = 0.85
+	// multiplicative increase per second while far from the link capacity
+	rateIncreaseFactor = 1.08
+	rateMinIncrease    = 1000
+	// additive increase is at least this much per second
+	rateMinAdditiveIncrease = 4000
+	maxRateUpdateInterval   = time.Second
+
+	// the estimate does not run away from what is actually sent
+	ackedBitrateHeadroomFactor = 1.5
+	ackedBitrateHeadroom       = 10 * 1000
+
+	additiveIncreaseFrameRate  = 30
+	additiveIncreasePacketBits = 1200 * 8
+	responseTimeExtra          = 100 * time.Millisecond
+)
+
+type rateControlState int
+
+const (
+	rateControlStateHold rateControlState = iota
+	rateControlStateIncrease
+	rateControlStateDecrease
+)
+
+// rateController is the AIMD controller of the delay based estimate, it increases the rate while the path is
+// normal and cuts it to a fraction of the acknowledged bitrate on overuse
+type rateController struct {
+	bitrate    float64
+	minBitrate float64
+	maxBitrate float64
+
+	state        rateControlState
+	lastUpdate   time.Time
+	lastDecrease time.Time
+
+	linkCapacity *linkCapacityEstimator
+}
+
+func newRateController(initialBitrate float64, minBitrate float64, maxBitrate float64) *rateController {
+	return &rateController{
+		bitrate:      initialBitrate,
+		minBitrate:   minBitrate,
+		maxBitrate:   maxBitrate,
+		linkCapacity: &linkCapacityEstimator{},
+	}
+}
+
+func (r *rateController) update(usage bandwidthUsage, ackedBitrate float64, rtt time.Duration, now time.Time) float64 {
+	switch usage {
+	case bandwidthUsageNormal:
+		if r.state == rateControlStateHold {
+			r.state = rateControlStateIncrease
+		}
+	case bandwidthUsageOverusing:
+		r.state = rateControlStateDecrease
+	case bandwidthUsageUnderusing:
+		r.state = rateControlStateHold
+	}
+
+	elapsed := time.Duration(0)
+	if !r.lastUpdate.IsZero() {
+		elapsed = now.Sub(r.lastUpdate)
+		if elapsed > maxRateUpdateInterval {
+			elapsed = maxRateUpdateInterval
+		}
+	}
+	r.lastUpdate = now
+
+	switch r.state {
+	case rateControlStateIncrease:
+		if r.linkCapacity.valid && ackedBitrate > r.linkCapacity.upperBound() {
+			r.linkCapacity.reset()
+		}
+
+		bitrate := r.bitrate
+		if r.linkCapacity.valid {
+			bitrate += r.additiveIncrease(rtt, elapsed)
+		} else {
+			bitrate += math.Max(r.bitrate*(math.Pow(rateIncreaseFactor, elapsed.Seconds())-1), rateMinIncrease*elapsed.Seconds())
+		}
+		if ackedBitrate > 0 {
+			if limit := ackedBitrateHeadroomFactor*ackedBitrate + ackedBitrateHeadroom; bitrate > limit {
+				bitrate = math.Max(r.bitrate, limit)
+			}
+		}
+		r.bitrate = bitrate
+
+	case rateControlStateDecrease:
+		// the delay builds up for a round trip after a decrease, decreasing again before that overshoots
+		if r.lastDecrease.IsZero() || now.Sub(r.lastDecrease) >= rtt {
+			target := rateDecreaseFactor * r.bitrate
+			if ackedBitrate > 0 {
+				target = rateDecreaseFactor * ackedBitrate
+				r.linkCapacity.onOveruse(ackedBitrate)
+			}
+			if target < r.bitrate {
+				r.bitrate = target
+			}
+			r.lastDecrease = now
+		}
+		r.state = rateControlStateHold
+	}
+
+	r.bitrate = math.Max(r.minBitrate, math.Min(r.bitrate, r.maxBitrate))
+	return r.bitrate
+}
+
+// additiveIncrease adds about a packet per frame per response time
+func (r *rateController) additiveIncrease(rtt time.Duration, elapsed time.Duration) float64 {
+	bitsPerFrame := r.bitrate / additiveIncreaseFrameRate
+	packetsPerFrame := math.Ceil(bitsPerFrame / additiveIncreasePacketBits)
+	packetBits := bitsPerFrame / packetsPerFrame
+
+	increase := math.Max(rateMinAdditiveIncrease, packetBits/(rtt+responseTimeExtra).Seconds())
+	return increase * elapsed.Seconds()
+}
+
+// ---------------------------------------------------------------------
+
+// linkCapacityEstimator averages the acknowledged bitrate at overuse, the rate is increased cautiously near it
+type linkCapacityEstimator struct {
+	valid bool
+	// kbps
+	estimate  float64
+	deviation float64
+}
+
+func (l *linkCapacityEstimator) onOveruse(ackedBitrate float64) {
+	sample := ackedBitrate / 1000
+	if !l.valid {
+		l.valid = true
+		l.estimate = sample
+		l.deviation = 0.4
+	} else {
+		l.estimate = 0.95*l.estimate + 0.05*sample
+	}
+
+	err := l.estimate - sample
+	l.deviation = 0.95*l.deviation + 0.05*err*err/math.Max(l.estimate, 1)
+	l.deviation = math.Max(0.4, math.Min(l.deviation, 2.5))
+}
+
+func (l *linkCapacityEstimator) upperBound() float64 {
+	return (l.estimate + 3*math.Sqrt(l.deviation*l.estimate)) * 1000
+}
+
+func (l *linkCapacityEstimator) reset() {
+	*l = linkCapacityEstimator{}
+}
@@ -0,0 +1,143 @@
+package gcc
+
+import (
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// packets sent and not yet reported on, feedback of older packets is ignored
+	sendHistorySize = 1 << 13
+
+	referenceTimeResolution = 64 * time.Millisecond
+	referenceTimeBits       = 24
+)
+
+type sentPacket struct {
+	seq      uint16
+	sendTime time.Time
+	size     int
+	valid    bool
+	reported bool
+	received bool
+}
+
+// packetReport is the outcome of a sent packet as reported by transport-cc feedback
+type packetReport struct {
+	sendTime time.Time
+	size     int
+	received bool
+	// in the clock of the receiver, only differences are meaningful
+	arrival time.Duration
+	// lost packets can be reported as received by a later feedback, they count towards loss only once
+	firstReport bool
+}
+
+// sendHistory matches transport-cc feedback with the packets sent. The same feedback is seen more than once when
+// it is fed by both the interceptor and the stream allocator, packets are reported on once.
+type sendHistory struct {
+	packets []sentPacket
+
+	hasReferenceTime      bool
+	lastReferenceTime     uint32
+	extendedReferenceTime int64
+}
+
+func newSendHistory() *sendHistory {
+	return &sendHistory{
+		packets: make([]sentPacket, sendHistorySize),
+	}
+}
+
+func (h *sendHistory) add(seq uint16, sendTime time.Time, size int) {
+	h.packets[int(seq)%len(h.packets)] = sentPacket{
+		seq:      seq,
+		sendTime: sendTime,
+		size:     size,
+		valid:    true,
+	}
+}
+
+func (h *sendHistory) onFeedback(fb *rtcp.TransportLayerCC) []packetReport {
+	var reports []packetReport
+
+	seq := fb.BaseSequenceNumber
+	remaining := int(fb.PacketStatusCount)
+	deltas := fb.RecvDeltas
+	arrival := h.unwrapReferenceTime(fb.ReferenceTime)
+	onStatus := func(symbol uint16) {
+		received := false
+		switch symbol {
+		case rtcp.TypeTCCPacketReceivedSmallDelta, rtcp.TypeTCCPacketReceivedLargeDelta:
+			// a malformed feedback without enough deltas has the rest of its packets taken as lost
+			if len(deltas) != 0 {
+				arrival += time.Duration(deltas[0].Delta) * time.Microsecond
+				deltas = deltas[1:]
+				received = true
+			}
+		}
+
+		if report, ok := h.report(seq, received, arrival); ok {
+			reports = append(reports, report)
+		}
+		seq++
+		remaining--
+	}
+
+	for _, chunk := range fb.PacketChunks {
+		switch c := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			for i := uint16(0); i < c.RunLength && remaining > 0; i++ {
+				onStatus(c.PacketStatusSymbol)
+			}
+		case *rtcp.StatusVectorChunk:
+			for i := 0; i < len(c.SymbolList) && remaining > 0; i++ {
+				onStatus(c.SymbolList[i])
+			}
+		}
+	}
+	return reports
+}
+
+func (h *sendHistory) report(seq uint16, received bool, arrival time.Duration) (packetReport, bool) {
+	p := &h.packets[int(seq)%len(h.packets)]
+	if !p.valid || p.seq != seq || p.received {
+		return packetReport{}, false
+	}
+
+	firstReport := !p.reported
+	p.reported = true
+	if !received && !firstReport {
+		return packetReport{}, false
+	}
+	p.received = received
+
+	return packetReport{
+		sendTime:    p.sendTime,
+		size:        p.size,
+		received:    received,
+		arrival:     arrival,
+		firstReport: firstReport,
+	}, true
+}
+
+// unwrapReferenceTime extends the 24 bit reference time of feedback, feedback can arrive out of order
+func (h *sendHistory) unwrapReferenceTime(referenceTime uint32) time.Duration {
+	referenceTime &= 1<<referenceTimeBits - 1
+	if !h.hasReferenceTime {
+		h.hasReferenceTime = true
+		h.extendedReferenceTime = int64(referenceTime)
+	} else {
+		diff := int64(referenceTime) - int64(h.lastReferenceTime)
+		if diff >= 1<<(referenceTimeBits-1) {
+			diff -= 1 << referenceTimeBits
+		} else if diff < -(1 << (referenceTimeBits - 1)) {
+			diff += 1 << referenceTimeBits
+		}
+		h.extendedReferenceTime += diff
+	}
+	h.lastReferenceTime = referenceTime
+
+	return time.Duration(h.extendedReferenceTime) * referenceTimeResolution
+}
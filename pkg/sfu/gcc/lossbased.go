@@ -0,0 +1,57 @@
+package gcc
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	lossLow            = 0.02
+	lossHigh           = 0.1
+	lossIncreaseFactor = 1.05
+	lossDecreaseFactor = 0.5
+
+	// loss is evaluated over enough packets to not react to a single lost packet
+	lossMinPackets  = 20
+	lossMinInterval = 100 * time.Millisecond
+)
+
+// lossController backs off in proportion to the loss above lossHigh, loss which delay does not reveal, e. g. on
+// wireless links or shallow buffers. It never raises the estimate above the delay based one.
+type lossController struct {
+	bitrate    float64
+	minBitrate float64
+
+	lost       int
+	total      int
+	lastUpdate time.Time
+	lossRatio  float64
+}
+
+func newLossController(initialBitrate float64, minBitrate float64) *lossController {
+	return &lossController{
+		bitrate:    initialBitrate,
+		minBitrate: minBitrate,
+	}
+}
+
+func (l *lossController) update(lost int, total int, delayBitrate float64, now time.Time) float64 {
+	l.lost += lost
+	l.total += total
+	if l.total >= lossMinPackets && now.Sub(l.lastUpdate) >= lossMinInterval {
+		l.lossRatio = float64(l.lost) / float64(l.total)
+		l.lost = 0
+		l.total = 0
+		l.lastUpdate = now
+
+		switch {
+		case l.lossRatio > lossHigh:
+			l.bitrate *= 1 - lossDecreaseFactor*l.lossRatio
+		case l.lossRatio < lossLow:
+			l.bitrate *= lossIncreaseFactor
+		}
+	}
+
+	l.bitrate = math.Max(l.minBitrate, math.Min(l.bitrate, delayBitrate))
+	return l.bitrate
+}
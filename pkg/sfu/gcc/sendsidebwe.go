@@ -0,0 +1,265 @@
+package gcc
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+const (
+	DefaultMinBitrate = 10 * 1000
+	DefaultMaxBitrate = 50 * 1000 * 1000
+
+	defaultRTT       = 200 * time.Millisecond
+	rttSmoothing     = 0.9
+	ackedBitrateSpan = 500 * time.Millisecond
+	// acknowledged bitrate is not known till packets arrived over at least this long
+	ackedBitrateMinSpan = 150 * time.Millisecond
+)
+
+type SendSideBWEParams struct {
+	InitialBitrate int
+	MinBitrate     int
+	MaxBitrate     int
+	Clock          utils.Clock
+	Logger         logger.Logger
+}
+
+// SendSideBWE is a send side Google Congestion Control estimator. It combines a delay based estimate, from the
+// trend of the one way delay variation reported by transport-cc feedback, with a loss based estimate, and targets
+// the lower of the two. It has the method set of pion's cc.BandwidthEstimator.
+type SendSideBWE struct {
+	params SendSideBWEParams
+
+	lock                  sync.Mutex
+	history               *sendHistory
+	delayDetector         *delayDetector
+	rateController        *rateController
+	lossController        *lossController
+	ackedBitrate          *ackedBitrate
+	rtt                   time.Duration
+	usage                 bandwidthUsage
+	targetBitrate         int
+	onTargetBitrateChange func(bitrate int)
+	closed                bool
+}
+
+func NewSendSideBWE(params SendSideBWEParams) *SendSideBWE {
+	if params.MinBitrate == 0 {
+		params.MinBitrate = DefaultMinBitrate
+	}
+	if params.MaxBitrate == 0 {
+		params.MaxBitrate = DefaultMaxBitrate
+	}
+	if params.InitialBitrate == 0 {
+		params.InitialBitrate = params.MinBitrate
+	}
+	if params.Clock == nil {
+		params.Clock = utils.SystemClock
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+
+	initialBitrate := float64(params.InitialBitrate)
+	return &SendSideBWE{
+		params:         params,
+		history:        newSendHistory(),
+		delayDetector:  newDelayDetector(),
+		rateController: newRateController(initialBitrate, float64(params.MinBitrate), float64(params.MaxBitrate)),
+		lossController: newLossController(initialBitrate, float64(params.MinBitrate)),
+		ackedBitrate:   &ackedBitrate{},
+		rtt:            defaultRTT,
+		targetBitrate:  params.InitialBitrate,
+	}
+}
+
+// AddStream records the transport-wide sequence number of packets as they are sent
+func (s *SendSideBWE) AddStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	var extID uint8
+	for _, ext := range info.RTPHeaderExtensions {
+		if ext.URI == sdp.TransportCCURI {
+			extID = uint8(ext.ID)
+			break
+		}
+	}
+	if extID == 0 {
+		return writer
+	}
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if ext := header.GetExtension(extID); len(ext) >= 2 {
+			s.onPacketSent(binary.BigEndian.Uint16(ext), header.MarshalSize()+len(payload))
+		}
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+func (s *SendSideBWE) WriteRTCP(pkts []rtcp.Packet, _ interceptor.Attributes) error {
+	for _, pkt := range pkts {
+		if fb, ok := pkt.(*rtcp.TransportLayerCC); ok {
+			s.onFeedback(fb)
+		}
+	}
+	return nil
+}
+
+func (s *SendSideBWE) GetTargetBitrate() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.targetBitrate
+}
+
+func (s *SendSideBWE) OnTargetBitrateChange(f func(bitrate int)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.onTargetBitrateChange = f
+}
+
+func (s *SendSideBWE) GetStats() map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return map[string]interface{}{
+		"targetBitrate":     s.targetBitrate,
+		"delayBasedBitrate": int(s.rateController.bitrate),
+		"lossBasedBitrate":  int(s.lossController.bitrate),
+		"ackedBitrate":      int(s.ackedBitrate.bitrate()),
+		"usage":             s.usage.String(),
+		"trend":             s.delayDetector.modifiedTrend,
+		"threshold":         s.delayDetector.threshold,
+		"lossRatio":         s.lossController.lossRatio,
+		"rtt":               s.rtt.String(),
+	}
+}
+
+func (s *SendSideBWE) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *SendSideBWE) onPacketSent(seq uint16, size int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.history.add(seq, s.params.Clock.Now(), size)
+}
+
+func (s *SendSideBWE) onFeedback(fb *rtcp.TransportLayerCC) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+
+	reports := s.history.onFeedback(fb)
+	if len(reports) == 0 {
+		s.lock.Unlock()
+		return
+	}
+
+	now := s.params.Clock.Now()
+	lost, total := 0, 0
+	overusing := false
+	var lastSent time.Time
+	for i := range reports {
+		report := &reports[i]
+		if report.firstReport {
+			total++
+			if !report.received {
+				lost++
+			}
+		}
+		if !report.received {
+			continue
+		}
+
+		s.ackedBitrate.add(report.arrival, report.size)
+		if s.delayDetector.update(report, now) == bandwidthUsageOverusing {
+			overusing = true
+		}
+		if report.sendTime.After(lastSent) {
+			lastSent = report.sendTime
+		}
+	}
+	// an overuse detected on any group of the feedback is acted on
+	usage := s.delayDetector.usage
+	if overusing {
+		usage = bandwidthUsageOverusing
+	}
+	if usage != s.usage {
+		s.params.Logger.Debugw("bandwidth usage changed", "usage", usage, "trend", s.delayDetector.modifiedTrend, "threshold", s.delayDetector.threshold)
+		s.usage = usage
+	}
+	// feedback is sent shortly after the last packet it reports on arrives
+	if !lastSent.IsZero() {
+		s.rtt = time.Duration(rttSmoothing*float64(s.rtt) + (1-rttSmoothing)*float64(now.Sub(lastSent)))
+	}
+
+	delayBitrate := s.rateController.update(s.usage, s.ackedBitrate.bitrate(), s.rtt, now)
+	lossBitrate := s.lossController.update(lost, total, delayBitrate, now)
+	targetBitrate := int(math.Min(delayBitrate, lossBitrate))
+
+	changed := targetBitrate != s.targetBitrate
+	s.targetBitrate = targetBitrate
+	onTargetBitrateChange := s.onTargetBitrateChange
+	s.lock.Unlock()
+
+	if changed && onTargetBitrateChange != nil {
+		onTargetBitrateChange(targetBitrate)
+	}
+}
+
+// ---------------------------------------------------------------------
+
+type ackedSample struct {
+	arrival time.Duration
+	size    int
+}
+
+// ackedBitrate is the bitrate at which packets arrived at the receiver over a sliding window
+type ackedBitrate struct {
+	samples []ackedSample
+	bytes   int
+}
+
+func (a *ackedBitrate) add(arrival time.Duration, size int) {
+	a.samples = append(a.samples, ackedSample{arrival: arrival, size: size})
+	a.bytes += size
+
+	evict := 0
+	for evict < len(a.samples)-1 && arrival-a.samples[evict].arrival > ackedBitrateSpan {
+		a.bytes -= a.samples[evict].size
+		evict++
+	}
+	a.samples = a.samples[evict:]
+}
+
+func (a *ackedBitrate) bitrate() float64 {
+	if len(a.samples) < 2 {
+		return 0
+	}
+
+	first := a.samples[0]
+	span := a.samples[len(a.samples)-1].arrival - first.arrival
+	if span < ackedBitrateMinSpan {
+		return 0
+	}
+	// the first packet marks the start of the window, it arrived before it
+	return float64(a.bytes-first.size) * 8 / span.Seconds()
+}
@@ -0,0 +1,249 @@
+package gcc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+const (
+	testExtID        = 5
+	testPacketSize   = 1200
+	testPropagation  = 20 * time.Millisecond
+	testSendInterval = 5 * time.Millisecond
+	testFeedbackTick = 50 * time.Millisecond
+)
+
+type testArrival struct {
+	seq      uint16
+	received bool
+	arrival  time.Duration
+}
+
+// testLink sends at the target bitrate of the estimator through a bottleneck of a capacity and returns feedback on
+// the packets that went through it
+type testLink struct {
+	t        *testing.T
+	clock    *utils.SimulatedClock
+	start    time.Time
+	bwe      *SendSideBWE
+	writer   interceptor.RTPWriter
+	capacity int
+	// every lossEvery-th packet is lost
+	lossEvery int
+
+	seq         uint16
+	credit      float64
+	lastArrival time.Duration
+	pending     []testArrival
+}
+
+func newTestLink(t *testing.T, initialBitrate int, capacity int) *testLink {
+	clock := utils.NewSimulatedClock(time.Now())
+	bwe := NewSendSideBWE(SendSideBWEParams{
+		InitialBitrate: initialBitrate,
+		Clock:          clock,
+	})
+	writer := bwe.AddStream(&interceptor.StreamInfo{
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: sdp.TransportCCURI, ID: testExtID}},
+	}, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		return header.MarshalSize() + len(payload), nil
+	}))
+
+	return &testLink{
+		t:        t,
+		clock:    clock,
+		start:    clock.Now(),
+		bwe:      bwe,
+		writer:   writer,
+		capacity: capacity,
+	}
+}
+
+func (l *testLink) run(d time.Duration) {
+	for elapsed := time.Duration(0); elapsed < d; elapsed += testSendInterval {
+		l.send()
+		l.clock.Advance(testSendInterval)
+		if elapsed%testFeedbackTick == 0 {
+			l.feedback()
+		}
+	}
+}
+
+func (l *testLink) send() {
+	l.credit += float64(l.bwe.GetTargetBitrate()) * testSendInterval.Seconds() / 8
+	for l.credit >= testPacketSize {
+		l.credit -= testPacketSize
+
+		header := &rtp.Header{Version: 2, SSRC: 1234}
+		require.NoError(l.t, header.SetExtension(testExtID, []byte{byte(l.seq >> 8), byte(l.seq)}))
+		_, err := l.writer.Write(header, make([]byte, testPacketSize-header.MarshalSize()), nil)
+		require.NoError(l.t, err)
+
+		now := l.clock.Now().Sub(l.start)
+		arrival := testArrival{seq: l.seq}
+		if l.lossEvery == 0 || int(l.seq)%l.lossEvery != 0 {
+			// queued behind the packets before it at the bottleneck
+			transmit := time.Duration(float64(testPacketSize*8) / float64(l.capacity) * float64(time.Second))
+			if l.lastArrival < now+testPropagation {
+				l.lastArrival = now + testPropagation
+			}
+			l.lastArrival += transmit
+			arrival.received = true
+			arrival.arrival = l.lastArrival
+		}
+		l.pending = append(l.pending, arrival)
+		l.seq++
+	}
+}
+
+func (l *testLink) feedback() {
+	now := l.clock.Now().Sub(l.start)
+	var arrivals []testArrival
+	for len(l.pending) != 0 && (!l.pending[0].received || l.pending[0].arrival <= now) {
+		arrivals = append(arrivals, l.pending[0])
+		l.pending = l.pending[1:]
+	}
+	if fb := newTestFeedback(arrivals); fb != nil {
+		require.NoError(l.t, l.bwe.WriteRTCP([]rtcp.Packet{fb}, nil))
+	}
+}
+
+func newTestFeedback(arrivals []testArrival) *rtcp.TransportLayerCC {
+	if len(arrivals) == 0 {
+		return nil
+	}
+
+	fb := &rtcp.TransportLayerCC{
+		BaseSequenceNumber: arrivals[0].seq,
+		PacketStatusCount:  uint16(len(arrivals)),
+	}
+	var last time.Duration
+	hasReference := false
+	var chunk *rtcp.StatusVectorChunk
+	for _, a := range arrivals {
+		if chunk == nil || len(chunk.SymbolList) == 7 {
+			chunk = &rtcp.StatusVectorChunk{
+				Type:       rtcp.TypeTCCStatusVectorChunk,
+				SymbolSize: rtcp.TypeTCCSymbolSizeTwoBit,
+			}
+			fb.PacketChunks = append(fb.PacketChunks, chunk)
+		}
+		if !a.received {
+			chunk.SymbolList = append(chunk.SymbolList, rtcp.TypeTCCPacketNotReceived)
+			continue
+		}
+
+		if !hasReference {
+			hasReference = true
+			fb.ReferenceTime = uint32(a.arrival / referenceTimeResolution)
+			last = time.Duration(fb.ReferenceTime) * referenceTimeResolution
+		}
+		delta := &rtcp.RecvDelta{
+			Type:  rtcp.TypeTCCPacketReceivedSmallDelta,
+			Delta: (a.arrival - last).Microseconds(),
+		}
+		if delta.Delta > 63750 {
+			delta.Type = rtcp.TypeTCCPacketReceivedLargeDelta
+		}
+		chunk.SymbolList = append(chunk.SymbolList, delta.Type)
+		fb.RecvDeltas = append(fb.RecvDeltas, delta)
+		last = a.arrival
+	}
+	return fb
+}
+
+func TestSendHistory(t *testing.T) {
+	h := newSendHistory()
+	now := time.Now()
+	for seq := uint16(65530); seq != 4; seq++ {
+		h.add(seq, now, testPacketSize)
+		now = now.Add(time.Millisecond)
+	}
+
+	arrivals := make([]testArrival, 0, 10)
+	for seq := uint16(65530); seq != 4; seq++ {
+		arrivals = append(arrivals, testArrival{
+			seq:      seq,
+			received: seq != 65535,
+			arrival:  time.Second + time.Duration(seq-65530)*time.Millisecond,
+		})
+	}
+
+	t.Run("packets are reported across wrap around", func(t *testing.T) {
+		reports := h.onFeedback(newTestFeedback(arrivals))
+		require.Len(t, reports, 10)
+		for i, report := range reports {
+			require.True(t, report.firstReport)
+			require.Equal(t, i != 5, report.received)
+			if report.received {
+				require.Equal(t, time.Second+time.Duration(i)*time.Millisecond, report.arrival)
+			}
+		}
+	})
+
+	t.Run("feedback seen again is ignored", func(t *testing.T) {
+		require.Empty(t, h.onFeedback(newTestFeedback(arrivals)))
+	})
+
+	t.Run("late packets are reported once more", func(t *testing.T) {
+		reports := h.onFeedback(newTestFeedback([]testArrival{{seq: 65535, received: true, arrival: 2 * time.Second}}))
+		require.Len(t, reports, 1)
+		require.True(t, reports[0].received)
+		require.False(t, reports[0].firstReport)
+	})
+
+	t.Run("packets not sent are ignored", func(t *testing.T) {
+		require.Empty(t, h.onFeedback(newTestFeedback([]testArrival{{seq: 100, received: true}})))
+	})
+}
+
+func TestSendSideBWE(t *testing.T) {
+	t.Run("increases while the link has capacity", func(t *testing.T) {
+		// far from the capacity, multiplicative increase at 8% per second
+		link := newTestLink(t, 300*1000, 5*1000*1000)
+		link.run(20 * time.Second)
+
+		require.Greater(t, link.bwe.GetTargetBitrate(), 1000*1000)
+		require.Less(t, link.bwe.GetTargetBitrate(), 6*1000*1000)
+	})
+
+	t.Run("decreases when queues build up", func(t *testing.T) {
+		link := newTestLink(t, 3*1000*1000, 1000*1000)
+
+		var changes []int
+		link.bwe.OnTargetBitrateChange(func(bitrate int) {
+			changes = append(changes, bitrate)
+		})
+		link.run(5 * time.Second)
+
+		// cut to a fraction of what goes through the bottleneck
+		require.NotEmpty(t, changes)
+		require.Equal(t, link.bwe.GetTargetBitrate(), changes[len(changes)-1])
+		require.InDelta(t, rateDecreaseFactor*1000*1000, link.bwe.GetTargetBitrate(), 100*1000)
+	})
+
+	t.Run("decreases on loss", func(t *testing.T) {
+		link := newTestLink(t, 2*1000*1000, 50*1000*1000)
+		link.lossEvery = 4
+		link.run(3 * time.Second)
+
+		require.Less(t, link.bwe.GetTargetBitrate(), 1000*1000)
+		require.InDelta(t, 0.25, link.bwe.GetStats()["lossRatio"], 0.05)
+	})
+
+	t.Run("nothing is estimated once closed", func(t *testing.T) {
+		link := newTestLink(t, 300*1000, 5*1000*1000)
+		require.NoError(t, link.bwe.Close())
+		link.run(2 * time.Second)
+
+		require.Equal(t, 300*1000, link.bwe.GetTargetBitrate())
+	})
+}
@@ -0,0 +1,169 @@
+package sfu
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+var (
+	ErrLayerNotGenerated = errors.New("receiver does not generate layers")
+	ErrLayerPublished    = errors.New("layer is published")
+)
+
+// generatedLayer feeds a single layer track, generated server side from a publication, into the receiver of that
+// publication as one of its spatial layers. It subscribes to the generated track like a down track would.
+type generatedLayer struct {
+	parent   *WebRTCReceiver
+	receiver TrackReceiver
+	layer    int32
+	closed   atomic.Bool
+}
+
+func (g *generatedLayer) WriteRTP(pkt *buffer.ExtPacket, _ int32) error {
+	if g.closed.Load() {
+		return nil
+	}
+
+	g.parent.forwardGeneratedRTP(pkt, g.layer)
+	return nil
+}
+
+func (g *generatedLayer) HandleRTCPSenderReportData(_ webrtc.PayloadType, _ int32, srData *buffer.RTCPSenderReportData) error {
+	if g.closed.Load() {
+		return nil
+	}
+
+	g.parent.streamTrackerManager.SetRTCPSenderReportData(g.layer, srData)
+	g.parent.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.HandleRTCPSenderReportData(g.parent.codec.PayloadType, g.layer, srData)
+	})
+	return nil
+}
+
+func (g *generatedLayer) Close() {
+	g.parent.removeGeneratedLayer(g)
+}
+
+func (g *generatedLayer) IsClosed() bool {
+	return g.closed.Load()
+}
+
+func (g *generatedLayer) ID() string {
+	return string(g.SubscriberID())
+}
+
+func (g *generatedLayer) SubscriberID() livekit.ParticipantID {
+	return livekit.ParticipantID(fmt.Sprintf("%s_layer_%d", g.parent.trackID, g.layer))
+}
+
+func (g *generatedLayer) UpTrackLayersChange()                       {}
+func (g *generatedLayer) UpTrackBitrateAvailabilityChange()          {}
+func (g *generatedLayer) UpTrackMaxPublishedLayerChange(_ int32)     {}
+func (g *generatedLayer) UpTrackMaxTemporalLayerSeenChange(_ int32)  {}
+func (g *generatedLayer) UpTrackBitrateReport(_ []int32, _ Bitrates) {}
+func (g *generatedLayer) TrackInfoAvailable()                        {}
+
+// ---------------------------------------------------------------------
+
+// AddGeneratedLayer attaches a track generated from the publication as a lower spatial layer, till the generated
+// track closes or RemoveGeneratedLayer is called
+func (w *WebRTCReceiver) AddGeneratedLayer(layer int32, receiver TrackReceiver) error {
+	if !w.generatesLayers {
+		return ErrLayerNotGenerated
+	}
+	if w.closed.Load() {
+		return ErrReceiverClosed
+	}
+	if layer < 0 || int(layer) >= len(w.generatedLayers) {
+		return ErrBufferNotFound
+	}
+	if w.getBuffer(layer) != nil {
+		return ErrLayerPublished
+	}
+
+	g := &generatedLayer{
+		parent:   w,
+		receiver: receiver,
+		layer:    layer,
+	}
+	w.generatedLayersMu.Lock()
+	previous := w.generatedLayers[layer]
+	w.generatedLayers[layer] = g
+	w.generatedLayersMu.Unlock()
+	if previous != nil {
+		previous.closed.Store(true)
+		previous.receiver.DeleteDownTrack(previous.SubscriberID())
+	} else if w.useTrackers {
+		w.streamTrackerManager.AddTracker(layer)
+	}
+
+	if err := receiver.AddDownTrack(g); err != nil {
+		w.removeGeneratedLayer(g)
+		return err
+	}
+
+	w.logger.Infow("generated layer added", "layer", layer, "generatedTrackID", receiver.TrackID())
+	return nil
+}
+
+// RemoveGeneratedLayer detaches the generated track of a spatial layer
+func (w *WebRTCReceiver) RemoveGeneratedLayer(layer int32) {
+	if g := w.getGeneratedLayer(layer); g != nil {
+		w.removeGeneratedLayer(g)
+	}
+}
+
+func (w *WebRTCReceiver) removeGeneratedLayer(g *generatedLayer) {
+	w.generatedLayersMu.Lock()
+	if w.generatedLayers[g.layer] != g {
+		w.generatedLayersMu.Unlock()
+		return
+	}
+	w.generatedLayers[g.layer] = nil
+	w.generatedLayersMu.Unlock()
+
+	g.closed.Store(true)
+	g.receiver.DeleteDownTrack(g.SubscriberID())
+	w.streamTrackerManager.RemoveTracker(g.layer)
+	w.logger.Infow("generated layer removed", "layer", g.layer, "generatedTrackID", g.receiver.TrackID())
+}
+
+func (w *WebRTCReceiver) getGeneratedLayer(layer int32) *generatedLayer {
+	if layer < 0 || int(layer) >= len(w.generatedLayers) {
+		return nil
+	}
+
+	w.generatedLayersMu.RLock()
+	defer w.generatedLayersMu.RUnlock()
+
+	return w.generatedLayers[layer]
+}
+
+func (w *WebRTCReceiver) removeGeneratedLayers() {
+	for layer := range w.generatedLayers {
+		w.RemoveGeneratedLayer(int32(layer))
+	}
+}
+
+func (w *WebRTCReceiver) forwardGeneratedRTP(pkt *buffer.ExtPacket, layer int32) {
+	if tracker := w.streamTrackerManager.GetTracker(layer); tracker != nil {
+		tracker.Observe(
+			pkt.Temporal,
+			len(pkt.RawPacket),
+			len(pkt.Packet.Payload),
+			pkt.Packet.Marker,
+			pkt.Packet.Timestamp,
+		)
+	}
+
+	w.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.WriteRTP(pkt, layer)
+	})
+}
@@ -1,6 +1,7 @@
 package sfu
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/pion/webrtc/v3"
@@ -28,3 +29,50 @@ func codecParametersFuzzySearch(needle webrtc.RTPCodecParameters, haystack []web
 }
 
 // -----------------------------------------------
+
+const (
+	MimeTypeRTX = "video/rtx"
+
+	rtxSSRCMask       = 0x5bd1e995
+	rtxSSRCMultiplier = 0x9e3779b1
+)
+
+// RTXSSRC returns the SSRC of the RTX stream of a media stream sent to a subscriber. It is derived from the media
+// SSRC so that a DownTrack replacing another on a sender, without renegotiation, retransmits on the signalled stream
+func RTXSSRC(mediaSSRC uint32) uint32 {
+	// multiplying by an odd number is a bijection, media streams of a peer connection get distinct RTX streams
+	return (mediaSSRC ^ rtxSSRCMask) * rtxSSRCMultiplier
+}
+
+// rtxPayloadTypeFor returns the payload type of the negotiated RTX stream carrying retransmissions of a codec
+func rtxPayloadTypeFor(payloadType webrtc.PayloadType, codecs []webrtc.RTPCodecParameters) (uint8, bool) {
+	apt := fmt.Sprintf("apt=%d", payloadType)
+	for _, c := range codecs {
+		if !strings.EqualFold(c.MimeType, MimeTypeRTX) {
+			continue
+		}
+		for _, param := range strings.Split(c.SDPFmtpLine, ";") {
+			if strings.TrimSpace(param) == apt {
+				return uint8(c.PayloadType), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// -----------------------------------------------
+
+const (
+	// frames encrypted by LiveKit clients end with the IV and a trailer holding the IV length and the key index
+	e2eeIVLength    = 12
+	e2eeTrailerSize = 2
+)
+
+// E2EEKeyIndex returns the index of the key an AES-GCM end-to-end encrypted frame was encrypted with, given the
+// payload of the last packet of the frame
+func E2EEKeyIndex(payload []byte) (uint8, bool) {
+	if len(payload) < e2eeIVLength+e2eeTrailerSize || payload[len(payload)-2] != e2eeIVLength {
+		return 0, false
+	}
+	return payload[len(payload)-1], true
+}
@@ -0,0 +1,357 @@
+package sfu
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+var ErrNotRetransmittable = errors.New("packets of a mix are not retransmitted")
+
+type MixedAudioReceiverParams struct {
+	TrackID  livekit.TrackID
+	StreamID string
+	Codec    webrtc.RTPCodecParameters
+	Mixer    audio.MixerParams
+	// subscribers whose audio is mixed are forwarded a mix without it
+	ExcludeOwnAudio bool
+	Logger          logger.Logger
+}
+
+// MixedAudioReceiver forwards a mix of the audio of several receivers, decoding and mixing them and encoding the mix.
+// It subscribes to the receivers like a down track would, so that they keep their own subscribers
+type MixedAudioReceiver struct {
+	params            MixedAudioReceiverParams
+	mixer             *audio.Mixer
+	downTrackSpreader *DownTrackSpreader
+	closed            atomic.Bool
+
+	// header of the packets of a mix, only touched when mixing
+	ssrc           uint32
+	sequenceNumber uint16
+	timestamp      uint32
+
+	lock   sync.RWMutex
+	inputs map[livekit.ParticipantID]*mixedAudioInput
+}
+
+type mixedAudioInput struct {
+	source TrackReceiver
+	tap    *mixedAudioInputTap
+}
+
+func NewMixedAudioReceiver(params MixedAudioReceiverParams) (*MixedAudioReceiver, error) {
+	if params.Mixer.Logger == nil {
+		params.Mixer.Logger = params.Logger
+	}
+	mixer, err := audio.NewMixer(params.Mixer)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &MixedAudioReceiver{
+		params: params,
+		mixer:  mixer,
+		downTrackSpreader: NewDownTrackSpreader(DownTrackSpreaderParams{
+			Logger:       params.Logger,
+			ErrorContext: []interface{}{"trackID", params.TrackID},
+		}),
+		ssrc:           rand.Uint32(),
+		sequenceNumber: uint16(rand.Uint32()),
+		timestamp:      rand.Uint32(),
+		inputs:         make(map[livekit.ParticipantID]*mixedAudioInput),
+	}
+	mixer.OnMix(r.writeMix)
+	mixer.Start()
+	return r, nil
+}
+
+// SetInput mixes in the audio of a participant received by source, replacing the receiver mixed in for the participant
+// before. Sources of other codecs than opus, e. g. RED, are mixed in through their primary receiver
+func (r *MixedAudioReceiver) SetInput(participantID livekit.ParticipantID, source TrackReceiver) error {
+	if r.closed.Load() {
+		return ErrReceiverClosed
+	}
+	if !strings.EqualFold(source.Codec().MimeType, webrtc.MimeTypeOpus) {
+		if primary := source.GetPrimaryReceiverForRed(); primary != nil {
+			source = primary
+		}
+	}
+	if !strings.EqualFold(source.Codec().MimeType, webrtc.MimeTypeOpus) {
+		return ErrSourceCodecMismatch
+	}
+
+	r.lock.RLock()
+	current := r.inputs[participantID]
+	r.lock.RUnlock()
+	if current != nil && current.source == source && !current.tap.IsClosed() {
+		return nil
+	}
+	r.RemoveInput(participantID)
+
+	if err := r.mixer.AddInput(participantID); err != nil {
+		return err
+	}
+	input := &mixedAudioInput{
+		source: source,
+		tap:    &mixedAudioInputTap{parent: r, participantID: participantID},
+	}
+	r.lock.Lock()
+	r.inputs[participantID] = input
+	r.lock.Unlock()
+
+	if err := source.AddDownTrack(input.tap); err != nil {
+		r.RemoveInput(participantID)
+		return err
+	}
+	if r.params.ExcludeOwnAudio && r.downTrackSpreader.HasDownTrack(participantID) {
+		if err := r.mixer.AddOutput(participantID); err != nil {
+			r.params.Logger.Warnw("could not add mix without own audio", err, "participantID", participantID)
+		}
+	}
+	r.params.Logger.Debugw("mixing in audio", "participantID", participantID, "sourceTrackID", source.TrackID())
+	return nil
+}
+
+// RemoveInput stops mixing in the audio of a participant
+func (r *MixedAudioReceiver) RemoveInput(participantID livekit.ParticipantID) {
+	r.lock.Lock()
+	input := r.inputs[participantID]
+	delete(r.inputs, participantID)
+	r.lock.Unlock()
+	if input == nil {
+		return
+	}
+
+	input.tap.closed.Store(true)
+	input.source.DeleteDownTrack(input.tap.SubscriberID())
+	r.mixer.RemoveInput(participantID)
+	r.mixer.RemoveOutput(participantID)
+}
+
+// Inputs returns the participants whose audio is mixed in
+func (r *MixedAudioReceiver) Inputs() []livekit.ParticipantID {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	inputs := make([]livekit.ParticipantID, 0, len(r.inputs))
+	for participantID := range r.inputs {
+		inputs = append(inputs, participantID)
+	}
+	return inputs
+}
+
+func (r *MixedAudioReceiver) removeTap(tap *mixedAudioInputTap) {
+	r.lock.RLock()
+	input := r.inputs[tap.participantID]
+	r.lock.RUnlock()
+	if input != nil && input.tap == tap {
+		r.RemoveInput(tap.participantID)
+	}
+}
+
+// writeMix forwards the mixes of a frame, down tracks of subscribers which have a mix of their own get that one
+func (r *MixedAudioReceiver) writeMix(packets map[livekit.ParticipantID][]byte) {
+	r.sequenceNumber++
+	r.timestamp += uint32(audio.MixerFrameSamples)
+	arrival := time.Now()
+
+	extPackets := make(map[livekit.ParticipantID]*buffer.ExtPacket, len(packets))
+	for excluded, payload := range packets {
+		extPackets[excluded] = &buffer.ExtPacket{
+			Arrival: arrival,
+			Packet: &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    uint8(r.params.Codec.PayloadType),
+					SequenceNumber: r.sequenceNumber,
+					Timestamp:      r.timestamp,
+					SSRC:           r.ssrc,
+				},
+				Payload: payload,
+			},
+		}
+	}
+	mix := extPackets[""]
+
+	r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		pkt := extPackets[dt.SubscriberID()]
+		if pkt == nil {
+			pkt = mix
+		}
+		if pkt != nil {
+			_ = dt.WriteRTP(pkt, 0)
+		}
+	})
+}
+
+func (r *MixedAudioReceiver) TrackID() livekit.TrackID {
+	return r.params.TrackID
+}
+
+func (r *MixedAudioReceiver) StreamID() string {
+	return r.params.StreamID
+}
+
+func (r *MixedAudioReceiver) Codec() webrtc.RTPCodecParameters {
+	return r.params.Codec
+}
+
+func (r *MixedAudioReceiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	return nil
+}
+
+func (r *MixedAudioReceiver) IsClosed() bool {
+	return r.closed.Load()
+}
+
+func (r *MixedAudioReceiver) ReadRTP(_ []byte, _ uint8, _ uint16) (int, error) {
+	return 0, ErrNotRetransmittable
+}
+
+func (r *MixedAudioReceiver) GetLayeredBitrate() ([]int32, Bitrates) {
+	return nil, Bitrates{}
+}
+
+func (r *MixedAudioReceiver) GetAudioLevel() (float64, bool) {
+	return 0, false
+}
+
+func (r *MixedAudioReceiver) SendPLI(_ int32, _ bool) {}
+
+func (r *MixedAudioReceiver) SetUpTrackPaused(_ bool) {}
+
+func (r *MixedAudioReceiver) SetMaxExpectedSpatialLayer(_ int32) {}
+
+func (r *MixedAudioReceiver) AddDownTrack(track TrackSender) error {
+	if r.closed.Load() {
+		return ErrReceiverClosed
+	}
+
+	subscriberID := track.SubscriberID()
+	if r.downTrackSpreader.HasDownTrack(subscriberID) {
+		r.params.Logger.Infow("subscriberID already exists, replacing downtrack", "subscriberID", subscriberID)
+	}
+
+	track.TrackInfoAvailable()
+	r.downTrackSpreader.Store(track)
+
+	if r.params.ExcludeOwnAudio && r.mixer.HasInput(subscriberID) {
+		if err := r.mixer.AddOutput(subscriberID); err != nil {
+			r.params.Logger.Warnw("could not add mix without own audio", err, "participantID", subscriberID)
+		}
+	}
+	return nil
+}
+
+func (r *MixedAudioReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	if r.closed.Load() {
+		return
+	}
+
+	r.downTrackSpreader.Free(subscriberID)
+	r.mixer.RemoveOutput(subscriberID)
+}
+
+func (r *MixedAudioReceiver) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"TrackID":    r.params.TrackID,
+		"DownTracks": r.downTrackSpreader.DownTrackCount(),
+		"Inputs":     len(r.Inputs()),
+	}
+}
+
+func (r *MixedAudioReceiver) TrackInfo() *livekit.TrackInfo {
+	return nil
+}
+
+func (r *MixedAudioReceiver) GetPrimaryReceiverForRed() TrackReceiver {
+	return r
+}
+
+func (r *MixedAudioReceiver) GetRedReceiver() TrackReceiver {
+	return r
+}
+
+func (r *MixedAudioReceiver) GetTemporalLayerFpsForSpatial(_ int32) []float32 {
+	return nil
+}
+
+func (r *MixedAudioReceiver) GetReferenceLayerRTPTimestamp(_ uint32, _ int32, _ int32) (uint32, error) {
+	return 0, ErrNotRetransmittable
+}
+
+// Close stops mixing and closes the down tracks
+func (r *MixedAudioReceiver) Close() {
+	if r.closed.Swap(true) {
+		return
+	}
+
+	r.mixer.Close()
+	for _, participantID := range r.Inputs() {
+		r.RemoveInput(participantID)
+	}
+	for _, dt := range r.downTrackSpreader.ResetAndGetDownTracks() {
+		dt.Close()
+	}
+}
+
+// ---------------------------------------------------------------------
+
+// mixedAudioInputTap subscribes to the audio of a participant, writing its packets to the mixer
+type mixedAudioInputTap struct {
+	parent        *MixedAudioReceiver
+	participantID livekit.ParticipantID
+	closed        atomic.Bool
+}
+
+func (t *mixedAudioInputTap) WriteRTP(pkt *buffer.ExtPacket, _ int32) error {
+	if t.closed.Load() || len(pkt.Packet.Payload) == 0 {
+		return nil
+	}
+
+	return t.parent.mixer.WritePacket(t.participantID, pkt.Packet.SequenceNumber, pkt.Packet.Payload)
+}
+
+func (t *mixedAudioInputTap) HandleRTCPSenderReportData(_ webrtc.PayloadType, _ int32, _ *buffer.RTCPSenderReportData) error {
+	return nil
+}
+
+// Close is called when the source closes, the participant is no longer mixed in till set again
+func (t *mixedAudioInputTap) Close() {
+	if t.closed.Swap(true) {
+		return
+	}
+	t.parent.removeTap(t)
+}
+
+func (t *mixedAudioInputTap) IsClosed() bool {
+	return t.closed.Load()
+}
+
+func (t *mixedAudioInputTap) ID() string {
+	return string(t.SubscriberID())
+}
+
+func (t *mixedAudioInputTap) SubscriberID() livekit.ParticipantID {
+	return livekit.ParticipantID(string(t.parent.params.TrackID) + "_mixer")
+}
+
+func (t *mixedAudioInputTap) UpTrackLayersChange()                       {}
+func (t *mixedAudioInputTap) UpTrackBitrateAvailabilityChange()          {}
+func (t *mixedAudioInputTap) UpTrackMaxPublishedLayerChange(_ int32)     {}
+func (t *mixedAudioInputTap) UpTrackMaxTemporalLayerSeenChange(_ int32)  {}
+func (t *mixedAudioInputTap) UpTrackBitrateReport(_ []int32, _ Bitrates) {}
+func (t *mixedAudioInputTap) TrackInfoAvailable()                        {}
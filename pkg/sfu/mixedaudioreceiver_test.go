@@ -0,0 +1,99 @@
+package sfu
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/audio"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+type testOpusCodec struct{}
+
+func (testOpusCodec) NewDecoder(_ int) (audio.OpusDecoder, error)        { return testOpusCodec{}, nil }
+func (testOpusCodec) NewEncoder(_ int, _ int) (audio.OpusEncoder, error) { return testOpusCodec{}, nil }
+func (testOpusCodec) Decode(_ []byte, _ []int16) (int, error)            { return 0, nil }
+func (testOpusCodec) Encode(_ []int16, _ []byte) (int, error)            { return 0, nil }
+
+type testMixTrackSender struct {
+	testTrackSender
+
+	lock     sync.Mutex
+	payloads []string
+}
+
+func (s *testMixTrackSender) TrackInfoAvailable() {}
+
+func (s *testMixTrackSender) WriteRTP(pkt *buffer.ExtPacket, _ int32) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.payloads = append(s.payloads, string(pkt.Packet.Payload))
+	return nil
+}
+
+func (s *testMixTrackSender) lastPayload() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.payloads) == 0 {
+		return ""
+	}
+	return s.payloads[len(s.payloads)-1]
+}
+
+func TestMixedAudioReceiver(t *testing.T) {
+	r, err := NewMixedAudioReceiver(MixedAudioReceiverParams{
+		TrackID:         "TR_mix",
+		Codec:           webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, PayloadType: 111},
+		Mixer:           audio.MixerParams{Codec: testOpusCodec{}},
+		ExcludeOwnAudio: true,
+		Logger:          logger.GetLogger(),
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	a := newTestSourceReceiver("TR_a", webrtc.MimeTypeOpus)
+	require.ErrorIs(t, r.SetInput("PA_b", newTestSourceReceiver("TR_b", webrtc.MimeTypeVP8)), ErrSourceCodecMismatch)
+	require.NoError(t, r.SetInput("PA_a", a))
+	require.Len(t, a.senders, 1)
+	// setting the same source again does nothing
+	require.NoError(t, r.SetInput("PA_a", a))
+	require.Len(t, a.senders, 1)
+	require.Equal(t, []livekit.ParticipantID{"PA_a"}, r.Inputs())
+
+	own := &testMixTrackSender{testTrackSender: testTrackSender{subscriberID: "PA_a"}}
+	other := &testMixTrackSender{testTrackSender: testTrackSender{subscriberID: "PA_c"}}
+	require.NoError(t, r.AddDownTrack(own))
+	require.NoError(t, r.AddDownTrack(other))
+
+	t.Run("subscribers get the mix without their own audio", func(t *testing.T) {
+		r.writeMix(map[livekit.ParticipantID][]byte{"": []byte("all"), "PA_a": []byte("all but a")})
+		require.Equal(t, "all but a", own.lastPayload())
+		require.Equal(t, "all", other.lastPayload())
+	})
+
+	t.Run("source closing", func(t *testing.T) {
+		for _, sender := range a.senders {
+			sender.Close()
+		}
+		require.Empty(t, r.Inputs())
+
+		r.writeMix(map[livekit.ParticipantID][]byte{"": []byte("none")})
+		require.Equal(t, "none", own.lastPayload())
+	})
+
+	t.Run("close", func(t *testing.T) {
+		require.NoError(t, r.SetInput("PA_a", newTestSourceReceiver("TR_a2", webrtc.MimeTypeOpus)))
+		r.Close()
+		require.Empty(t, r.Inputs())
+		require.True(t, own.closed.Load())
+		require.ErrorIs(t, r.SetInput("PA_a", a), ErrReceiverClosed)
+	})
+}
@@ -0,0 +1,403 @@
+package pacer
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+const (
+	DefaultMultiplier    = 1.5
+	DefaultMaxBurst      = 40 * time.Millisecond
+	DefaultMaxQueueDelay = 250 * time.Millisecond
+
+	// a packet can always go out once the budget is positive, however small the burst allowed
+	minBurstBytes = 1500
+)
+
+// Priority of a packet, queued packets are sent in priority order
+type Priority int
+
+const (
+	PriorityAudio Priority = iota
+	PriorityVideo
+	PriorityPadding
+
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityAudio:
+		return "AUDIO"
+	case PriorityVideo:
+		return "VIDEO"
+	case PriorityPadding:
+		return "PADDING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+type PacerParams struct {
+	// packets are sent at this multiple of the bitrate, leaving room for the forwarded rate to fluctuate
+	Multiplier float64
+	// burst tolerance, packets worth this long at the pacing rate are sent back to back after the pacer idled
+	MaxBurst time.Duration
+	// media packets queued longer than this are sent regardless of the rate, padding is dropped
+	MaxQueueDelay time.Duration
+	Logger        logger.Logger
+}
+
+type PacerStats struct {
+	Bitrate        int64
+	QueuedPackets  int
+	QueuedBytes    int
+	SentPackets    uint64
+	OverduePackets uint64
+	DroppedPadding uint64
+}
+
+// Pacer smooths the packets sent on a peer connection to the estimated channel capacity, so that bursts of forwarded
+// packets, e.g. key frames and layer switches, do not overflow queues on the path to the subscriber. It is an
+// interceptor placed farthest from the transport so that transport-wide sequence numbers and send side bandwidth
+// estimation see packets as they go out. Packets are queued by priority, audio ahead of video ahead of padding, and
+// sent by Run. Until a bitrate is set, packets are sent as they are written.
+type Pacer struct {
+	params PacerParams
+
+	lock        sync.Mutex
+	queues      [numPriorities][]*queuedPacket
+	queuedBytes int
+	bitrate     int64
+	budget      float64
+	budgetAt    time.Time
+	stats       PacerStats
+	stopped     bool
+
+	wake chan struct{}
+	stop chan struct{}
+}
+
+type queuedPacket struct {
+	header     rtp.Header
+	payload    []byte
+	buf        *[]byte
+	stream     *pacedStream
+	attributes interceptor.Attributes
+	queuedAt   time.Time
+}
+
+func (q *queuedPacket) size() int {
+	return q.header.MarshalSize() + len(q.payload)
+}
+
+var payloadPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 1500)
+		return &b
+	},
+}
+
+func NewPacer(params PacerParams) *Pacer {
+	if params.Multiplier <= 0 {
+		params.Multiplier = DefaultMultiplier
+	}
+	if params.MaxBurst <= 0 {
+		params.MaxBurst = DefaultMaxBurst
+	}
+	if params.MaxQueueDelay <= 0 {
+		params.MaxQueueDelay = DefaultMaxQueueDelay
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+	return &Pacer{
+		params: params,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+// NewInterceptor implements interceptor.Factory, a Pacer serves a single peer connection
+func (p *Pacer) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return &pacerInterceptor{pacer: p}, nil
+}
+
+// Stop ends Run, packets still queued are dropped
+func (p *Pacer) Stop() {
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return
+	}
+	p.stopped = true
+	close(p.stop)
+
+	for priority := range p.queues {
+		for _, qp := range p.queues[priority] {
+			releasePacket(qp)
+		}
+		p.queues[priority] = nil
+	}
+	p.queuedBytes = 0
+	p.lock.Unlock()
+}
+
+// SetBitrate sets the estimated channel capacity packets are paced to, estimates of 0 are ignored
+func (p *Pacer) SetBitrate(bitrate int64) {
+	if bitrate <= 0 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bitrate == 0 {
+		p.params.Logger.Debugw("pacer: pacing", "bitrate", bitrate)
+		p.budgetAt = time.Now()
+	}
+	p.bitrate = bitrate
+}
+
+func (p *Pacer) GetStats() PacerStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	stats := p.stats
+	stats.Bitrate = p.bitrate
+	for _, q := range p.queues {
+		stats.QueuedPackets += len(q)
+	}
+	stats.QueuedBytes = p.queuedBytes
+	return stats
+}
+
+// enqueue queues a packet to be sent at the pacing rate, it returns false when the packet is to be sent right away
+func (p *Pacer) enqueue(priority Priority, header *rtp.Header, payload []byte, stream *pacedStream, attributes interceptor.Attributes) bool {
+	p.lock.Lock()
+	if p.stopped || p.bitrate == 0 {
+		p.lock.Unlock()
+		return false
+	}
+
+	// the payload may be reused by the caller once written, e.g. it points into the packet buffer of the publisher
+	qp := &queuedPacket{
+		header:     *header,
+		stream:     stream,
+		attributes: attributes,
+		queuedAt:   time.Now(),
+	}
+	if len(payload) <= 1500 {
+		qp.buf = payloadPool.Get().(*[]byte)
+		qp.payload = (*qp.buf)[:len(payload)]
+	} else {
+		qp.payload = make([]byte, len(payload))
+	}
+	copy(qp.payload, payload)
+	// extensions are set again on send, do not share them with the caller
+	qp.header.Extensions = append([]rtp.Extension(nil), header.Extensions...)
+
+	p.queues[priority] = append(p.queues[priority], qp)
+	p.queuedBytes += qp.size()
+	p.lock.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Run sends queued packets till the pacer is stopped
+func (p *Pacer) Run() {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.wake:
+		case <-timer.C:
+		}
+
+		for {
+			qp, wait := p.next(time.Now())
+			if qp == nil {
+				if wait > 0 {
+					timer.Reset(wait)
+				}
+				break
+			}
+			p.send(qp)
+		}
+	}
+}
+
+// next returns the packet to send now, or how long to wait for budget when there are packets queued
+func (p *Pacer) next(now time.Time) (*queuedPacket, time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stopped {
+		return nil, 0
+	}
+
+	p.dropOverduePadding(now)
+
+	rate := float64(p.bitrate) * p.params.Multiplier / 8
+	maxBudget := rate * p.params.MaxBurst.Seconds()
+	if maxBudget < minBurstBytes {
+		maxBudget = minBurstBytes
+	}
+	p.budget += rate * now.Sub(p.budgetAt).Seconds()
+	if p.budget > maxBudget {
+		p.budget = maxBudget
+	}
+	p.budgetAt = now
+
+	priority := Priority(-1)
+	for pr := range p.queues {
+		if len(p.queues[pr]) != 0 {
+			priority = Priority(pr)
+			break
+		}
+	}
+	if priority < 0 {
+		return nil, 0
+	}
+
+	if p.budget <= 0 {
+		// media queued too long goes out regardless, in priority order
+		priority = -1
+		for pr := PriorityAudio; pr < PriorityPadding; pr++ {
+			if q := p.queues[pr]; len(q) != 0 && now.Sub(q[0].queuedAt) >= p.params.MaxQueueDelay {
+				priority = pr
+				p.stats.OverduePackets++
+				break
+			}
+		}
+		if priority < 0 {
+			// till there is budget, or the oldest packet is overdue
+			wait := time.Duration(-p.budget / rate * float64(time.Second))
+			for _, q := range p.queues {
+				if len(q) != 0 {
+					if overdueIn := p.params.MaxQueueDelay - now.Sub(q[0].queuedAt); overdueIn < wait {
+						wait = overdueIn
+					}
+				}
+			}
+			if wait < time.Millisecond {
+				wait = time.Millisecond
+			}
+			return nil, wait
+		}
+	}
+
+	qp := p.queues[priority][0]
+	p.queues[priority][0] = nil
+	p.queues[priority] = p.queues[priority][1:]
+
+	size := qp.size()
+	p.queuedBytes -= size
+	p.budget -= float64(size)
+	p.stats.SentPackets++
+	return qp, 0
+}
+
+// padding probes for capacity, late it only adds to the queue
+func (p *Pacer) dropOverduePadding(now time.Time) {
+	q := p.queues[PriorityPadding]
+	dropped := 0
+	for dropped < len(q) && now.Sub(q[dropped].queuedAt) >= p.params.MaxQueueDelay {
+		p.queuedBytes -= q[dropped].size()
+		releasePacket(q[dropped])
+		q[dropped] = nil
+		dropped++
+	}
+	if dropped != 0 {
+		p.queues[PriorityPadding] = q[dropped:]
+		p.stats.DroppedPadding += uint64(dropped)
+	}
+}
+
+func (p *Pacer) send(qp *queuedPacket) {
+	defer releasePacket(qp)
+
+	if qp.stream.absSendTimeID != 0 {
+		if b, err := rtp.NewAbsSendTimeExtension(time.Now()).Marshal(); err == nil {
+			_ = qp.header.SetExtension(qp.stream.absSendTimeID, b)
+		}
+	}
+	if _, err := qp.stream.writer.Write(&qp.header, qp.payload, qp.attributes); err != nil {
+		// the first error of a stream is logged, the caller gets it on its next write to the stream
+		if qp.stream.writeErr.Swap(err) == nil {
+			p.params.Logger.Warnw("pacer: could not write packet", err, "ssrc", qp.header.SSRC)
+		}
+	}
+}
+
+func releasePacket(qp *queuedPacket) {
+	if qp.buf != nil {
+		payloadPool.Put(qp.buf)
+		qp.buf = nil
+	}
+}
+
+// ---------------------------------------------------------------------------
+
+type pacerInterceptor struct {
+	interceptor.NoOp
+	pacer *Pacer
+}
+
+// pacedStream is a local stream bound to the pacer
+type pacedStream struct {
+	writer interceptor.RTPWriter
+	// abs-send-time is set when the packet is sent, 0 when not negotiated
+	absSendTimeID uint8
+	// error of writing a queued packet, not yet returned to the caller
+	writeErr atomic.Error
+}
+
+func (i *pacerInterceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	priority := PriorityVideo
+	if strings.HasPrefix(strings.ToLower(info.MimeType), "audio/") {
+		priority = PriorityAudio
+	}
+	stream := &pacedStream{writer: writer}
+	for _, ext := range info.RTPHeaderExtensions {
+		if ext.URI == sdp.ABSSendTimeURI {
+			stream.absSendTimeID = uint8(ext.ID)
+			break
+		}
+	}
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if err := stream.writeErr.Swap(nil); err != nil {
+			return 0, err
+		}
+
+		packetPriority := priority
+		if isPaddingOnly(header, payload) {
+			packetPriority = PriorityPadding
+		}
+		if i.pacer.enqueue(packetPriority, header, payload, stream, attributes) {
+			return header.MarshalSize() + len(payload), nil
+		}
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+func isPaddingOnly(header *rtp.Header, payload []byte) bool {
+	return header.Padding && len(payload) != 0 && int(payload[len(payload)-1]) == len(payload)
+}
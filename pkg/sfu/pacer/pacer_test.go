@@ -0,0 +1,200 @@
+package pacer
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/require"
+)
+
+type sentPacket struct {
+	header  rtp.Header
+	payload []byte
+	at      time.Time
+}
+
+type testWriter struct {
+	lock sync.Mutex
+	sent []sentPacket
+	err  error
+}
+
+func (w *testWriter) Write(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.sent = append(w.sent, sentPacket{header: *header, payload: append([]byte(nil), payload...), at: time.Now()})
+	return header.MarshalSize() + len(payload), nil
+}
+
+func (w *testWriter) setError(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.err = err
+}
+
+func (w *testWriter) getSent() []sentPacket {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return append([]sentPacket(nil), w.sent...)
+}
+
+func newTestPacer(t *testing.T, params PacerParams) (*Pacer, func(mimeType string, extensions ...interceptor.RTPHeaderExtension) interceptor.RTPWriter, *testWriter) {
+	p := NewPacer(params)
+	go p.Run()
+	t.Cleanup(p.Stop)
+
+	i, err := p.NewInterceptor("")
+	require.NoError(t, err)
+
+	w := &testWriter{}
+	bind := func(mimeType string, extensions ...interceptor.RTPHeaderExtension) interceptor.RTPWriter {
+		return i.BindLocalStream(&interceptor.StreamInfo{MimeType: mimeType, RTPHeaderExtensions: extensions}, w)
+	}
+	return p, bind, w
+}
+
+func write(t *testing.T, writer interceptor.RTPWriter, ssrc uint32, sn uint16, size int) {
+	_, err := writer.Write(&rtp.Header{Version: 2, SSRC: ssrc, SequenceNumber: sn}, make([]byte, size), nil)
+	require.NoError(t, err)
+}
+
+func TestPacer(t *testing.T) {
+	t.Run("sent as written till a bitrate is set", func(t *testing.T) {
+		_, bind, w := newTestPacer(t, PacerParams{})
+		video := bind("video/VP8")
+		for sn := uint16(0); sn < 100; sn++ {
+			write(t, video, 1, sn, 1000)
+		}
+		require.Len(t, w.getSent(), 100)
+	})
+
+	t.Run("bursts are smoothed to the pacing rate", func(t *testing.T) {
+		p, bind, w := newTestPacer(t, PacerParams{Multiplier: 1, MaxBurst: 10 * time.Millisecond})
+		// 100 kB/s, 1 kB burst
+		p.SetBitrate(800_000)
+		video := bind("video/VP8")
+
+		start := time.Now()
+		// a key frame of 20 kB
+		for sn := uint16(0); sn < 20; sn++ {
+			write(t, video, 1, sn, 1000-12)
+		}
+		require.Eventually(t, func() bool { return len(w.getSent()) == 20 }, 2*time.Second, 5*time.Millisecond)
+
+		sent := w.getSent()
+		for i, s := range sent {
+			require.Equal(t, uint16(i), s.header.SequenceNumber)
+		}
+		require.InDelta(t, 190*time.Millisecond, sent[19].at.Sub(start), float64(60*time.Millisecond))
+		require.Zero(t, p.GetStats().QueuedPackets)
+	})
+
+	t.Run("audio ahead of video ahead of padding", func(t *testing.T) {
+		p, bind, w := newTestPacer(t, PacerParams{Multiplier: 1, MaxBurst: 10 * time.Millisecond})
+		p.SetBitrate(800_000)
+		video := bind("video/VP8")
+		audio := bind("audio/opus")
+
+		// budget is used up by the first packet, the rest is queued
+		write(t, video, 1, 0, 1500)
+		require.Eventually(t, func() bool { return len(w.getSent()) == 1 }, time.Second, time.Millisecond)
+		padding := make([]byte, 255)
+		padding[254] = 255
+		_, err := video.Write(&rtp.Header{Version: 2, SSRC: 1, SequenceNumber: 1, Padding: true}, padding, nil)
+		require.NoError(t, err)
+		write(t, video, 1, 2, 1000)
+		write(t, audio, 2, 0, 100)
+
+		require.Eventually(t, func() bool { return len(w.getSent()) == 4 }, time.Second, 5*time.Millisecond)
+		sent := w.getSent()
+		require.Equal(t, uint16(0), sent[0].header.SequenceNumber)
+		require.Equal(t, uint32(2), sent[1].header.SSRC)
+		require.Equal(t, uint16(2), sent[2].header.SequenceNumber)
+		require.True(t, sent[3].header.Padding)
+	})
+
+	t.Run("media queued too long is sent, padding dropped", func(t *testing.T) {
+		p, bind, w := newTestPacer(t, PacerParams{Multiplier: 1, MaxQueueDelay: 50 * time.Millisecond})
+		// 1 kB/s, nothing but the burst goes out in time
+		p.SetBitrate(8_000)
+		video := bind("video/VP8")
+
+		for sn := uint16(0); sn < 5; sn++ {
+			write(t, video, 1, sn, 1000)
+		}
+		padding := make([]byte, 255)
+		padding[254] = 255
+		_, err := video.Write(&rtp.Header{Version: 2, SSRC: 1, SequenceNumber: 5, Padding: true}, padding, nil)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool { return len(w.getSent()) == 5 }, time.Second, 5*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		require.Len(t, w.getSent(), 5)
+
+		stats := p.GetStats()
+		require.Equal(t, uint64(1), stats.DroppedPadding)
+		require.NotZero(t, stats.OverduePackets)
+		require.Zero(t, stats.QueuedPackets)
+	})
+
+	t.Run("payload is copied and abs-send-time set when sent", func(t *testing.T) {
+		p, bind, w := newTestPacer(t, PacerParams{Multiplier: 1})
+		p.SetBitrate(800_000)
+		video := bind("video/VP8", interceptor.RTPHeaderExtension{URI: sdp.ABSSendTimeURI, ID: 3})
+
+		payload := []byte{1, 2, 3}
+		_, err := video.Write(&rtp.Header{Version: 2, SSRC: 1}, payload, nil)
+		require.NoError(t, err)
+		payload[0] = 9
+
+		require.Eventually(t, func() bool { return len(w.getSent()) == 1 }, time.Second, 5*time.Millisecond)
+		sent := w.getSent()[0]
+		require.Equal(t, []byte{1, 2, 3}, sent.payload)
+		require.NotNil(t, sent.header.GetExtension(3))
+	})
+
+	t.Run("write errors are returned on the next write", func(t *testing.T) {
+		p, bind, w := newTestPacer(t, PacerParams{Multiplier: 1})
+		p.SetBitrate(800_000)
+		video := bind("video/VP8")
+		audio := bind("audio/opus")
+		w.setError(io.ErrClosedPipe)
+
+		write(t, video, 1, 0, 100)
+		require.Eventually(t, func() bool { return p.GetStats().SentPackets == 1 }, time.Second, 5*time.Millisecond)
+
+		_, err := video.Write(&rtp.Header{Version: 2, SSRC: 1, SequenceNumber: 1}, make([]byte, 100), nil)
+		require.ErrorIs(t, err, io.ErrClosedPipe)
+		// reported once, and only to the stream that failed
+		write(t, audio, 2, 0, 100)
+		write(t, video, 1, 2, 100)
+	})
+
+	t.Run("queued packets are dropped on stop", func(t *testing.T) {
+		p, bind, w := newTestPacer(t, PacerParams{Multiplier: 1})
+		p.SetBitrate(8_000)
+		video := bind("video/VP8")
+		for sn := uint16(0); sn < 10; sn++ {
+			write(t, video, 1, sn, 1000)
+		}
+		p.Stop()
+
+		require.Less(t, len(w.getSent()), 10)
+		require.Zero(t, p.GetStats().QueuedPackets)
+		// sent as written once stopped
+		sent := len(w.getSent())
+		write(t, video, 1, 10, 1000)
+		require.Len(t, w.getSent(), sent+1)
+	})
+}
@@ -12,7 +12,6 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/livekit/mediatransportutil/pkg/bucket"
-	"github.com/livekit/mediatransportutil/pkg/twcc"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
 
@@ -20,6 +19,9 @@ import (
 	"github.com/livekit/livekit-server/pkg/sfu/audio"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/connectionquality"
+	"github.com/livekit/livekit-server/pkg/sfu/twcc"
+	"github.com/livekit/livekit-server/pkg/telemetry/errorreporting"
+	"github.com/livekit/livekit-server/pkg/telemetry/profiler"
 )
 
 var (
@@ -99,8 +101,31 @@ type WebRTCReceiver struct {
 	upTrackMu sync.RWMutex
 	upTracks  [buffer.DefaultMaxLayerSpatial + 1]*webrtc.TrackRemote
 
+	// lower layers of a single layer publication, generated server side
+	generatesLayers   bool
+	generatedLayersMu sync.RWMutex
+	generatedLayers   [buffer.DefaultMaxLayerSpatial + 1]*generatedLayer
+
 	lbThreshold int
 
+	// room and participant the track belongs to, for error reports
+	errorContext []interface{}
+	// goroutine labels, for continuous profiling
+	profilingLabels []string
+	// detailed stats, nil unless enabled
+	histograms *TrackHistograms
+	// forwarding is held until the other tracks of the barrier are ready when set
+	startBarrier       *StartBarrier
+	startBarrierMember string
+	// key index of frames encrypted by the publisher, -1 till seen
+	keyIndex         atomic.Int32
+	onKeyIndexChange func(keyIndex uint8)
+	// frames are checked to be encrypted as declared when set, nothing is forwarded once they are not
+	frameEncryption    livekit.Encryption_Type
+	maxPlaintextFrames int
+	onPlaintext        func()
+	plaintext          atomic.Bool
+
 	streamTrackerManager *StreamTrackerManager
 
 	downTrackSpreader *DownTrackSpreader
@@ -167,6 +192,69 @@ func WithLoadBalanceThreshold(downTracks int) ReceiverOpts {
 	}
 }
 
+// WithErrorContext adds key/value pairs, e.g. room and participant, to errors reported for the track
+func WithErrorContext(keysAndValues ...interface{}) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.errorContext = append(w.errorContext, keysAndValues...)
+		return w
+	}
+}
+
+// WithProfilingLabels labels the goroutines forwarding the track, e.g. with the room, in continuous profiles
+func WithProfilingLabels(labels ...string) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.profilingLabels = append(w.profilingLabels, labels...)
+		return w
+	}
+}
+
+// WithKeyIndexObserver reads the key index from the trailer of frames end-to-end encrypted with AES-GCM by the
+// publisher, f is called when it changes
+func WithKeyIndexObserver(f func(keyIndex uint8)) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.onKeyIndexChange = f
+		return w
+	}
+}
+
+// WithGeneratedLayers places a single layer publication at the top spatial layer, lower layers are generated from it
+// and attached with AddGeneratedLayer
+func WithGeneratedLayers() ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.generatesLayers = true
+		return w
+	}
+}
+
+// WithFrameEncryptionCheck checks that frames are end-to-end encrypted with the encryption declared by the publisher,
+// f is called once maxPlaintextFrames consecutive frames were not. The receiver stops forwarding the track then
+func WithFrameEncryptionCheck(encryption livekit.Encryption_Type, maxPlaintextFrames int, f func()) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.frameEncryption = encryption
+		w.maxPlaintextFrames = maxPlaintextFrames
+		w.onPlaintext = f
+		return w
+	}
+}
+
+// WithTrackHistograms records jitter and forwarding delay of each packet
+func WithTrackHistograms(histograms *TrackHistograms) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.histograms = histograms
+		return w
+	}
+}
+
+// WithStartBarrier holds forwarding until the other tracks of the barrier are ready, member is the name the track
+// was declared with
+func WithStartBarrier(barrier *StartBarrier, member string) ReceiverOpts {
+	return func(w *WebRTCReceiver) *WebRTCReceiver {
+		w.startBarrier = barrier
+		w.startBarrierMember = member
+		return w
+	}
+}
+
 // NewWebRTCReceiver creates a new webrtc track receiver
 func NewWebRTCReceiver(
 	receiver *webrtc.RTPReceiver,
@@ -186,9 +274,10 @@ func NewWebRTCReceiver(
 		kind:      track.Kind(),
 		twcc:      twcc,
 		trackInfo: trackInfo,
-		isSVC:     IsSvcCodec(track.Codec().MimeType),
+		isSVC:     IsSvcCodec(track.Codec().MimeType) && track.RID() == "", // simulcast layers have a RID each
 		isRED:     IsRedCodec(track.Codec().MimeType),
 	}
+	w.keyIndex.Store(-1)
 
 	w.streamTrackerManager = NewStreamTrackerManager(logger, trackInfo, w.isSVC, w.codec.ClockRate, trackersConfig)
 	w.streamTrackerManager.SetListener(w)
@@ -196,10 +285,19 @@ func NewWebRTCReceiver(
 	for _, opt := range opts {
 		w = opt(w)
 	}
+	w.errorContext = append(w.errorContext, "trackID", w.trackID, "mime", w.codec.MimeType)
+	if w.startBarrier != nil && w.kind == webrtc.RTPCodecTypeVideo {
+		w.startBarrier.OnKeyFrameNeeded(w.startBarrierMember, func() {
+			for layer := int32(0); layer <= buffer.DefaultMaxLayerSpatial; layer++ {
+				w.SendPLI(layer, true)
+			}
+		})
+	}
 
 	w.downTrackSpreader = NewDownTrackSpreader(DownTrackSpreaderParams{
-		Threshold: w.lbThreshold,
-		Logger:    logger,
+		Threshold:    w.lbThreshold,
+		Logger:       logger,
+		ErrorContext: w.errorContext,
 	})
 
 	w.connectionStats = connectionquality.NewConnectionStats(connectionquality.ConnectionStatsParams{
@@ -299,6 +397,9 @@ func (w *WebRTCReceiver) AddUpTrack(track *webrtc.TrackRemote, buff *buffer.Buff
 	layer := int32(0)
 	if w.Kind() == webrtc.RTPCodecTypeVideo && !w.isSVC {
 		layer = buffer.RidToSpatialLayer(track.RID(), w.trackInfo)
+		if w.generatesLayers && track.RID() == "" {
+			layer = buffer.VideoQualityToSpatialLayer(livekit.VideoQuality_HIGH, w.trackInfo)
+		}
 	}
 	buff.SetLogger(w.logger.WithValues("layer", layer))
 	buff.SetTWCC(w.twcc)
@@ -486,6 +587,9 @@ func (w *WebRTCReceiver) SendPLI(layer int32, force bool) {
 	// SVC-TODO :  should send LRR (Layer Refresh Request) instead of PLI
 	buff := w.getBuffer(layer)
 	if buff == nil {
+		if g := w.getGeneratedLayer(layer); g != nil {
+			g.receiver.SendPLI(0, force)
+		}
 		return
 	}
 
@@ -520,6 +624,9 @@ func (w *WebRTCReceiver) getBufferLocked(layer int32) *buffer.Buffer {
 func (w *WebRTCReceiver) ReadRTP(buf []byte, layer uint8, sn uint16) (int, error) {
 	b := w.getBuffer(int32(layer))
 	if b == nil {
+		if g := w.getGeneratedLayer(int32(layer)); g != nil {
+			return g.receiver.ReadRTP(buf, 0, sn)
+		}
 		return 0, ErrBufferNotFound
 	}
 
@@ -594,26 +701,25 @@ func (w *WebRTCReceiver) getDeltaStats() map[uint32]*buffer.StreamStatsWithLayer
 }
 
 func (w *WebRTCReceiver) forwardRTP(layer int32) {
+	profiler.SetGoroutineLabels(w.profilingLabels...)
+
 	pktBuf := make([]byte, bucket.MaxPktSize)
 	tracker := w.streamTrackerManager.GetTracker(layer)
+	var encryptionValidator *FrameEncryptionValidator
+	if w.onPlaintext != nil {
+		encryptionValidator = NewFrameEncryptionValidator(w.frameEncryption, w.codec.MimeType, w.maxPlaintextFrames)
+	}
 
 	defer func() {
-		w.closeOnce.Do(func() {
-			w.closed.Store(true)
-			w.closeTracks()
-			if pr := w.primaryReceiver.Load(); pr != nil {
-				pr.(*RedPrimaryReceiver).Close()
-			}
-			if pr := w.redReceiver.Load(); pr != nil {
-				pr.(*RedReceiver).Close()
-			}
-		})
+		w.closeOnce.Do(w.close)
 
 		w.streamTrackerManager.RemoveTracker(layer)
 		if w.isSVC {
 			w.streamTrackerManager.RemoveAllTrackers()
 		}
 	}()
+	// a panic closes this track only
+	defer errorreporting.Recover(w.logger, append(append([]interface{}{}, w.errorContext...), "layer", layer)...)
 
 	for {
 		w.bufferMu.RLock()
@@ -627,7 +733,11 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 
 		spatialTracker := tracker
 		spatialLayer := layer
-		if pkt.Spatial >= 0 {
+		if w.isSVC && pkt.Spatial >= 0 {
+			if ddwdt := pkt.DependencyDescriptor; ddwdt != nil && ddwdt.Descriptor.AttachedStructure != nil {
+				w.streamTrackerManager.SetKSVC(buffer.IsKSVC(ddwdt.Descriptor.AttachedStructure))
+			}
+
 			// svc packet, dispatch to correct tracker
 			spatialLayer = pkt.Spatial
 			spatialTracker = w.streamTrackerManager.GetTracker(pkt.Spatial)
@@ -646,9 +756,35 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 			)
 		}
 
-		w.downTrackSpreader.Broadcast(func(dt TrackSender) {
-			_ = dt.WriteRTP(pkt, spatialLayer)
-		})
+		if encryptionValidator != nil && !encryptionValidator.Validate(pkt) && !w.plaintext.Swap(true) {
+			w.logger.Warnw("track is not end-to-end encrypted, dropping it", nil, "layer", layer, "encryption", w.frameEncryption)
+			// removing the track closes the receiver
+			go w.onPlaintext()
+		}
+		if w.plaintext.Load() {
+			continue
+		}
+
+		if w.onKeyIndexChange != nil && (w.kind == webrtc.RTPCodecTypeAudio || pkt.Packet.Marker) {
+			w.observeKeyIndex(pkt.Packet.Payload)
+		}
+
+		if w.startBarrier != nil && !w.startBarrier.Admit(w.startBarrierMember, w.kind == webrtc.RTPCodecTypeVideo, pkt.KeyFrame) {
+			continue
+		}
+
+		if w.histograms != nil {
+			w.histograms.RecordArrival(layer, pkt.Arrival, pkt.Packet.Timestamp)
+			w.downTrackSpreader.Broadcast(func(dt TrackSender) {
+				if dt.WriteRTP(pkt, spatialLayer) == nil {
+					w.histograms.RecordForwardingDelay(time.Since(pkt.Arrival))
+				}
+			})
+		} else {
+			w.downTrackSpreader.Broadcast(func(dt TrackSender) {
+				_ = dt.WriteRTP(pkt, spatialLayer)
+			})
+		}
 
 		if redPktWriter != nil {
 			redPktWriter(pkt, spatialLayer)
@@ -656,10 +792,51 @@ func (w *WebRTCReceiver) forwardRTP(layer int32) {
 	}
 }
 
+func (w *WebRTCReceiver) observeKeyIndex(payload []byte) {
+	keyIndex, ok := E2EEKeyIndex(payload)
+	if !ok {
+		return
+	}
+	if w.keyIndex.Swap(int32(keyIndex)) != int32(keyIndex) {
+		w.onKeyIndexChange(keyIndex)
+	}
+}
+
+// Close stops a receiver whose up tracks are no longer published, e.g. after a codec switch. Forwarding stops with
+// the buffers of the up tracks, down tracks still attached are closed
+func (w *WebRTCReceiver) Close() {
+	w.bufferMu.RLock()
+	buffers := w.buffers
+	w.bufferMu.RUnlock()
+
+	forwarding := false
+	for _, buff := range buffers {
+		if buff != nil {
+			_ = buff.Close()
+			forwarding = true
+		}
+	}
+	if !forwarding {
+		w.closeOnce.Do(w.close)
+	}
+}
+
+func (w *WebRTCReceiver) close() {
+	w.closed.Store(true)
+	w.closeTracks()
+	if pr := w.primaryReceiver.Load(); pr != nil {
+		pr.(*RedPrimaryReceiver).Close()
+	}
+	if pr := w.redReceiver.Load(); pr != nil {
+		pr.(*RedReceiver).Close()
+	}
+}
+
 // closeTracks close all tracks from Receiver
 func (w *WebRTCReceiver) closeTracks() {
 	w.connectionStats.Close()
 	w.streamTrackerManager.Close()
+	w.removeGeneratedLayers()
 
 	for _, dt := range w.downTrackSpreader.ResetAndGetDownTracks() {
 		dt.Close()
@@ -735,6 +912,9 @@ func (w *WebRTCReceiver) GetRedReceiver() TrackReceiver {
 func (w *WebRTCReceiver) GetTemporalLayerFpsForSpatial(layer int32) []float32 {
 	b := w.getBuffer(layer)
 	if b == nil {
+		if g := w.getGeneratedLayer(layer); g != nil {
+			return g.receiver.GetTemporalLayerFpsForSpatial(0)
+		}
 		return nil
 	}
 
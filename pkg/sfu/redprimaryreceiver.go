@@ -110,7 +110,9 @@ func (r *RedPrimaryReceiver) ReadRTP(buf []byte, layer uint8, sn uint16) (int, e
 	}
 
 	var pkt rtp.Packet
-	pkt.Unmarshal(buf[:n])
+	if err = pkt.Unmarshal(buf[:n]); err != nil {
+		return 0, err
+	}
 	payload, err := extractPrimaryEncodingForRED(pkt.Payload)
 	if err != nil {
 		return 0, err
@@ -179,6 +181,9 @@ func extractPktsFromRed(redPkt *rtp.Packet, recoverBits byte) ([]*rtp.Packet, er
 	var blocks []block
 	var blockLength int
 	for {
+		if len(payload) == 0 {
+			return nil, ErrIncompleteRedHeader
+		}
 		if payload[0]&0x80 == 0 {
 			// last block is primary encoding data
 			payload = payload[1:]
@@ -245,6 +250,9 @@ func extractPrimaryEncodingForRED(payload []byte) ([]byte, error) {
 
 	var blockLength int
 	for {
+		if len(payload) == 0 {
+			return nil, ErrIncompleteRedHeader
+		}
 		if payload[0]&0x80 == 0 {
 			// last block is primary encoding data
 			payload = payload[1:]
@@ -353,3 +353,18 @@ func TestExtractPrimaryEncodingForRED(t *testing.T) {
 
 	verifyPktsEqual(t, pkts, primaryPkts)
 }
+
+func TestExtractFromTruncatedRED(t *testing.T) {
+	for _, payload := range [][]byte{
+		{},
+		// header of a redundant block without the primary block header
+		{0x80 | 111, 0x00, 0x04, 0x02},
+		// redundant block longer than the payload
+		{0x80 | 111, 0x00, 0x04, 0x08, 111, 0x01},
+	} {
+		_, err := extractPrimaryEncodingForRED(payload)
+		require.Error(t, err)
+		_, err = extractPktsFromRed(&rtp.Packet{Payload: payload}, 0xff)
+		require.Error(t, err)
+	}
+}
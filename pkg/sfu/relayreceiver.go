@@ -0,0 +1,491 @@
+package sfu
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/mediatransportutil"
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	dd "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
+)
+
+// packets of each layer kept for retransmissions to subscribers
+const relayHistorySize = 512
+
+type RelayReceiverParams struct {
+	TrackID   livekit.TrackID
+	StreamID  string
+	Codec     webrtc.RTPCodecParameters
+	TrackInfo *livekit.TrackInfo
+	// header extensions of the relayed packets, with the IDs the publisher negotiated
+	HeaderExtensions []webrtc.RTPHeaderExtensionParameter
+	// layers of video are tracked like those of a published track
+	StreamTrackers config.StreamTrackersConfig
+	// called when the first subscriber comes and when the last one goes, the node sending the media only relays it
+	// while there are subscribers
+	OnSubscribedChanged func(subscribed bool)
+	// called when subscribers need a key frame of a layer
+	OnPLI  func(layer int32)
+	Logger logger.Logger
+}
+
+// RelayReceiver forwards a track published on another node, whose packets are relayed to this node
+type RelayReceiver struct {
+	params               RelayReceiverParams
+	kind                 webrtc.RTPCodecType
+	isVP8                bool
+	isVP9                bool
+	downTrackSpreader    *DownTrackSpreader
+	streamTrackerManager *StreamTrackerManager
+	closed               atomic.Bool
+	// layers of scalable codecs share a stream, its packets are kept as those of layer 0
+	isSVC    bool
+	ddParser *buffer.DependencyDescriptorParser
+
+	lock       sync.Mutex
+	history    [buffer.DefaultMaxLayerSpatial + 1]*relayHistory
+	subscribed bool
+}
+
+func NewRelayReceiver(params RelayReceiverParams) *RelayReceiver {
+	r := &RelayReceiver{
+		params: params,
+		kind:   webrtc.RTPCodecTypeAudio,
+		isVP8:  strings.EqualFold(params.Codec.MimeType, webrtc.MimeTypeVP8),
+		isVP9:  strings.EqualFold(params.Codec.MimeType, webrtc.MimeTypeVP9),
+		isSVC:  IsSvcCodec(params.Codec.MimeType),
+		downTrackSpreader: NewDownTrackSpreader(DownTrackSpreaderParams{
+			Logger:       params.Logger,
+			ErrorContext: []interface{}{"trackID", params.TrackID, "relayed", true},
+		}),
+	}
+	if strings.HasPrefix(strings.ToLower(params.Codec.MimeType), "video/") {
+		r.kind = webrtc.RTPCodecTypeVideo
+	}
+	r.streamTrackerManager = NewStreamTrackerManager(params.Logger, params.TrackInfo, r.isSVC, params.Codec.ClockRate, params.StreamTrackers)
+	r.streamTrackerManager.SetListener(r)
+	if r.isSVC {
+		for _, ext := range params.HeaderExtensions {
+			if ext.URI == dd.ExtensionUrl {
+				r.ddParser = buffer.NewDependencyDescriptorParser(uint8(ext.ID), params.Logger, func(_, _ int32) {})
+			}
+		}
+	}
+	return r
+}
+
+// WritePacket forwards a relayed packet of a layer, with the temporal layer and key frame flag the sending node
+// found parsing it
+func (r *RelayReceiver) WritePacket(layer int32, temporal int32, keyFrame bool, raw []byte) error {
+	if r.closed.Load() {
+		return ErrReceiverClosed
+	}
+	if layer < 0 || int(layer) >= len(r.history) {
+		layer = 0
+	}
+
+	pkt := &rtp.Packet{}
+	if err := pkt.Unmarshal(raw); err != nil {
+		return err
+	}
+	ep := &buffer.ExtPacket{
+		VideoLayer: buffer.VideoLayer{
+			Spatial:  buffer.InvalidLayerSpatial,
+			Temporal: temporal,
+		},
+		Arrival:   time.Now(),
+		Packet:    pkt,
+		KeyFrame:  keyFrame,
+		RawPacket: raw,
+	}
+	if err := r.parseLayers(ep); err != nil {
+		return err
+	}
+
+	historyLayer := layer
+	if r.isSVC {
+		historyLayer = 0
+		if ep.Spatial >= 0 {
+			layer = ep.Spatial
+		}
+	}
+	r.lock.Lock()
+	history := r.history[historyLayer]
+	if history == nil {
+		history = &relayHistory{}
+		r.history[historyLayer] = history
+	}
+	history.add(pkt.SequenceNumber, raw)
+	r.lock.Unlock()
+
+	if r.kind == webrtc.RTPCodecTypeVideo {
+		if ddwdt := ep.DependencyDescriptor; ddwdt != nil && ddwdt.Descriptor.AttachedStructure != nil {
+			r.streamTrackerManager.SetKSVC(buffer.IsKSVC(ddwdt.Descriptor.AttachedStructure))
+		}
+		tracker := r.streamTrackerManager.GetTracker(layer)
+		if tracker == nil {
+			tracker = r.streamTrackerManager.AddTracker(layer)
+		}
+		if tracker != nil {
+			tracker.Observe(ep.Temporal, len(raw), len(pkt.Payload), pkt.Marker, pkt.Timestamp)
+		}
+	}
+
+	r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.WriteRTP(ep, layer)
+	})
+	return nil
+}
+
+// parseLayers parses what the forwarder needs of a packet. Layers of scalable codecs are taken from the dependency
+// descriptor, or from the payload descriptor of VP9 sent without it, as the publisher's buffer does
+func (r *RelayReceiver) parseLayers(ep *buffer.ExtPacket) error {
+	pkt := ep.Packet
+	if len(pkt.Payload) == 0 {
+		return nil
+	}
+
+	if r.ddParser != nil {
+		if ddVal, videoLayer, err := r.ddParser.Parse(pkt); err == nil && ddVal != nil {
+			ep.DependencyDescriptor = ddVal
+			ep.VideoLayer = videoLayer
+			return nil
+		}
+	}
+
+	switch {
+	case r.isVP8:
+		// the forwarder rewrites the picture IDs of VP8
+		vp8 := buffer.VP8{}
+		if err := vp8.Unmarshal(pkt.Payload); err != nil {
+			return err
+		}
+		ep.Payload = vp8
+	case r.isVP9 && r.ddParser == nil:
+		vp9, err := buffer.UnmarshalVP9(pkt.Payload)
+		if err != nil {
+			return err
+		}
+		ep.VideoLayer = buffer.VideoLayer{
+			Spatial:  int32(vp9.SID),
+			Temporal: int32(vp9.TID),
+		}
+		ep.Payload = vp9
+	}
+	return nil
+}
+
+// SetSenderReport passes on a sender report of a layer relayed along with the packets
+func (r *RelayReceiver) SetSenderReport(layer int32, rtpTimestamp uint32, ntpTimestamp uint64) {
+	if r.closed.Load() {
+		return
+	}
+
+	srData := &buffer.RTCPSenderReportData{
+		RTPTimestamp: rtpTimestamp,
+		NTPTimestamp: mediatransportutil.NtpTime(ntpTimestamp),
+		ArrivalTime:  time.Now(),
+	}
+	r.streamTrackerManager.SetRTCPSenderReportData(layer, srData)
+	r.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.HandleRTCPSenderReportData(r.params.Codec.PayloadType, layer, srData)
+	})
+}
+
+func (r *RelayReceiver) TrackID() livekit.TrackID {
+	return r.params.TrackID
+}
+
+func (r *RelayReceiver) StreamID() string {
+	return r.params.StreamID
+}
+
+func (r *RelayReceiver) Codec() webrtc.RTPCodecParameters {
+	return r.params.Codec
+}
+
+func (r *RelayReceiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	return r.params.HeaderExtensions
+}
+
+func (r *RelayReceiver) IsClosed() bool {
+	return r.closed.Load()
+}
+
+func (r *RelayReceiver) ReadRTP(buf []byte, layer uint8, sn uint16) (int, error) {
+	if r.isSVC {
+		layer = 0
+	}
+	if int(layer) >= len(r.history) {
+		return 0, ErrBufferNotFound
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	history := r.history[layer]
+	if history == nil {
+		return 0, ErrBufferNotFound
+	}
+	return history.read(buf, sn)
+}
+
+func (r *RelayReceiver) GetLayeredBitrate() ([]int32, Bitrates) {
+	return r.streamTrackerManager.GetLayeredBitrate()
+}
+
+func (r *RelayReceiver) GetAudioLevel() (float64, bool) {
+	return 0, false
+}
+
+func (r *RelayReceiver) SendPLI(layer int32, _ bool) {
+	if r.closed.Load() || r.params.OnPLI == nil {
+		return
+	}
+	r.params.OnPLI(layer)
+}
+
+func (r *RelayReceiver) SetUpTrackPaused(paused bool) {
+	r.streamTrackerManager.SetPaused(paused)
+}
+
+func (r *RelayReceiver) SetMaxExpectedSpatialLayer(layer int32) {
+	r.streamTrackerManager.SetMaxExpectedSpatialLayer(layer)
+}
+
+func (r *RelayReceiver) AddDownTrack(track TrackSender) error {
+	if r.closed.Load() {
+		return ErrReceiverClosed
+	}
+
+	if r.downTrackSpreader.HasDownTrack(track.SubscriberID()) {
+		r.params.Logger.Infow("subscriberID already exists, replacing downtrack", "subscriberID", track.SubscriberID())
+	}
+
+	track.TrackInfoAvailable()
+	track.UpTrackMaxPublishedLayerChange(r.streamTrackerManager.GetMaxPublishedLayer())
+	track.UpTrackMaxTemporalLayerSeenChange(r.streamTrackerManager.GetMaxTemporalLayerSeen())
+
+	r.downTrackSpreader.Store(track)
+	r.updateSubscribed()
+	return nil
+}
+
+func (r *RelayReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	if r.closed.Load() {
+		return
+	}
+
+	r.downTrackSpreader.Free(subscriberID)
+	r.updateSubscribed()
+}
+
+func (r *RelayReceiver) updateSubscribed() {
+	subscribed := r.downTrackSpreader.DownTrackCount() != 0
+
+	r.lock.Lock()
+	changed := r.subscribed != subscribed
+	r.subscribed = subscribed
+	r.lock.Unlock()
+
+	if changed && r.params.OnSubscribedChanged != nil {
+		r.params.OnSubscribedChanged(subscribed)
+	}
+}
+
+// IsSubscribed tells whether the track has subscribers on this node
+func (r *RelayReceiver) IsSubscribed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.subscribed
+}
+
+func (r *RelayReceiver) DebugInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"TrackID":    r.params.TrackID,
+		"Relayed":    true,
+		"DownTracks": r.downTrackSpreader.DownTrackCount(),
+	}
+}
+
+func (r *RelayReceiver) TrackInfo() *livekit.TrackInfo {
+	return r.params.TrackInfo
+}
+
+func (r *RelayReceiver) GetPrimaryReceiverForRed() TrackReceiver {
+	return r
+}
+
+func (r *RelayReceiver) GetRedReceiver() TrackReceiver {
+	return r
+}
+
+func (r *RelayReceiver) GetTemporalLayerFpsForSpatial(_ int32) []float32 {
+	return nil
+}
+
+func (r *RelayReceiver) GetReferenceLayerRTPTimestamp(ts uint32, layer int32, referenceLayer int32) (uint32, error) {
+	return r.streamTrackerManager.GetReferenceLayerRTPTimestamp(ts, layer, referenceLayer)
+}
+
+// StreamTrackerManagerListener.OnAvailableLayersChanged
+func (r *RelayReceiver) OnAvailableLayersChanged() {
+	for _, dt := range r.downTrackSpreader.GetDownTracks() {
+		dt.UpTrackLayersChange()
+	}
+}
+
+// StreamTrackerManagerListener.OnBitrateAvailabilityChanged
+func (r *RelayReceiver) OnBitrateAvailabilityChanged() {
+	for _, dt := range r.downTrackSpreader.GetDownTracks() {
+		dt.UpTrackBitrateAvailabilityChange()
+	}
+}
+
+// StreamTrackerManagerListener.OnMaxPublishedLayerChanged
+func (r *RelayReceiver) OnMaxPublishedLayerChanged(maxPublishedLayer int32) {
+	for _, dt := range r.downTrackSpreader.GetDownTracks() {
+		dt.UpTrackMaxPublishedLayerChange(maxPublishedLayer)
+	}
+}
+
+// StreamTrackerManagerListener.OnMaxTemporalLayerSeenChanged
+func (r *RelayReceiver) OnMaxTemporalLayerSeenChanged(maxTemporalLayerSeen int32) {
+	for _, dt := range r.downTrackSpreader.GetDownTracks() {
+		dt.UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen)
+	}
+}
+
+// StreamTrackerManagerListener.OnMaxAvailableLayerChanged
+func (r *RelayReceiver) OnMaxAvailableLayerChanged(_ int32) {}
+
+// StreamTrackerManagerListener.OnBitrateReport
+func (r *RelayReceiver) OnBitrateReport(availableLayers []int32, bitrates Bitrates) {
+	for _, dt := range r.downTrackSpreader.GetDownTracks() {
+		dt.UpTrackBitrateReport(availableLayers, bitrates)
+	}
+}
+
+// Close stops forwarding and closes the down tracks
+func (r *RelayReceiver) Close() {
+	if r.closed.Swap(true) {
+		return
+	}
+
+	r.streamTrackerManager.Close()
+	for _, dt := range r.downTrackSpreader.ResetAndGetDownTracks() {
+		dt.Close()
+	}
+}
+
+// ---------------------------------------------------------------------
+
+type relayHistoryEntry struct {
+	sn    uint16
+	valid bool
+	data  []byte
+}
+
+type relayHistory struct {
+	entries [relayHistorySize]relayHistoryEntry
+}
+
+func (h *relayHistory) add(sn uint16, data []byte) {
+	h.entries[int(sn)%relayHistorySize] = relayHistoryEntry{sn: sn, valid: true, data: data}
+}
+
+func (h *relayHistory) read(buf []byte, sn uint16) (int, error) {
+	entry := h.entries[int(sn)%relayHistorySize]
+	if !entry.valid || entry.sn != sn {
+		return 0, bucket.ErrPacketNotFound
+	}
+	if len(buf) < len(entry.data) {
+		return 0, bucket.ErrBufferTooSmall
+	}
+	return copy(buf, entry.data), nil
+}
+
+// ---------------------------------------------------------------------
+
+// RelaySender taps a receiver like a down track would, handing its packets and sender reports to a relay to
+// another node
+type RelaySender struct {
+	subscriberID livekit.ParticipantID
+	payloadType  webrtc.PayloadType
+	onPacket     func(pkt *buffer.ExtPacket, layer int32)
+	onReport     func(layer int32, srData *buffer.RTCPSenderReportData)
+	onClose      func()
+	closed       atomic.Bool
+}
+
+// NewRelaySender creates a tap subscribing in the name of subscriberID, which has to be unique among the
+// subscribers of the receiver
+func NewRelaySender(
+	subscriberID livekit.ParticipantID,
+	onPacket func(pkt *buffer.ExtPacket, layer int32),
+	onReport func(layer int32, srData *buffer.RTCPSenderReportData),
+	onClose func(),
+) *RelaySender {
+	return &RelaySender{
+		subscriberID: subscriberID,
+		onPacket:     onPacket,
+		onReport:     onReport,
+		onClose:      onClose,
+	}
+}
+
+func (s *RelaySender) WriteRTP(pkt *buffer.ExtPacket, layer int32) error {
+	if s.closed.Load() {
+		return nil
+	}
+	s.onPacket(pkt, layer)
+	return nil
+}
+
+func (s *RelaySender) HandleRTCPSenderReportData(_ webrtc.PayloadType, layer int32, srData *buffer.RTCPSenderReportData) error {
+	if s.closed.Load() || srData == nil || s.onReport == nil {
+		return nil
+	}
+	s.onReport(layer, srData)
+	return nil
+}
+
+// Close is called when the receiver closes, or by the relay when it stops
+func (s *RelaySender) Close() {
+	if s.closed.Swap(true) {
+		return
+	}
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+func (s *RelaySender) IsClosed() bool {
+	return s.closed.Load()
+}
+
+func (s *RelaySender) ID() string {
+	return string(s.subscriberID)
+}
+
+func (s *RelaySender) SubscriberID() livekit.ParticipantID {
+	return s.subscriberID
+}
+
+func (s *RelaySender) UpTrackLayersChange()                       {}
+func (s *RelaySender) UpTrackBitrateAvailabilityChange()          {}
+func (s *RelaySender) UpTrackMaxPublishedLayerChange(_ int32)     {}
+func (s *RelaySender) UpTrackMaxTemporalLayerSeenChange(_ int32)  {}
+func (s *RelaySender) UpTrackBitrateReport(_ []int32, _ Bitrates) {}
+func (s *RelaySender) TrackInfoAvailable()                        {}
@@ -0,0 +1,134 @@
+package sfu
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/mediatransportutil/pkg/bucket"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+type testRelayTrackSender struct {
+	testMixTrackSender
+}
+
+func (s *testRelayTrackSender) UpTrackMaxPublishedLayerChange(_ int32)    {}
+func (s *testRelayTrackSender) UpTrackMaxTemporalLayerSeenChange(_ int32) {}
+
+func TestRelayReceiver(t *testing.T) {
+	var subscribed []bool
+	var plis []int32
+	r := NewRelayReceiver(RelayReceiverParams{
+		TrackID:             "TR_relayed",
+		StreamID:            "stream",
+		Codec:               webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000}, PayloadType: 111},
+		TrackInfo:           &livekit.TrackInfo{Sid: "TR_relayed", Type: livekit.TrackType_AUDIO},
+		OnSubscribedChanged: func(s bool) { subscribed = append(subscribed, s) },
+		OnPLI:               func(layer int32) { plis = append(plis, layer) },
+		Logger:              logger.GetLogger(),
+	})
+
+	first := &testRelayTrackSender{testMixTrackSender{testTrackSender: testTrackSender{subscriberID: "PA_a"}}}
+	second := &testRelayTrackSender{testMixTrackSender{testTrackSender: testTrackSender{subscriberID: "PA_b"}}}
+	require.NoError(t, r.AddDownTrack(first))
+	require.NoError(t, r.AddDownTrack(second))
+	require.Equal(t, []bool{true}, subscribed)
+	require.True(t, r.IsSubscribed())
+
+	raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 111, SequenceNumber: 10}, Payload: []byte("audio")}).Marshal()
+	require.NoError(t, err)
+	require.NoError(t, r.WritePacket(0, buffer.InvalidLayerTemporal, false, raw))
+	require.Equal(t, "audio", first.lastPayload())
+	require.Equal(t, "audio", second.lastPayload())
+
+	// packets are kept for retransmissions
+	buf := make([]byte, 1500)
+	n, err := r.ReadRTP(buf, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, raw, buf[:n])
+	_, err = r.ReadRTP(buf, 0, 11)
+	require.ErrorIs(t, err, bucket.ErrPacketNotFound)
+	_, err = r.ReadRTP(buf, 1, 10)
+	require.ErrorIs(t, err, ErrBufferNotFound)
+
+	r.SendPLI(0, false)
+	require.Equal(t, []int32{0}, plis)
+
+	r.DeleteDownTrack("PA_a")
+	require.Equal(t, []bool{true}, subscribed)
+	r.DeleteDownTrack("PA_b")
+	require.Equal(t, []bool{true, false}, subscribed)
+
+	r.Close()
+	require.ErrorIs(t, r.WritePacket(0, 0, false, raw), ErrReceiverClosed)
+	require.ErrorIs(t, r.AddDownTrack(first), ErrReceiverClosed)
+}
+
+type testSVCTrackSender struct {
+	testRelayTrackSender
+	layers []buffer.VideoLayer
+}
+
+func (s *testSVCTrackSender) WriteRTP(pkt *buffer.ExtPacket, layer int32) error {
+	s.layers = append(s.layers, buffer.VideoLayer{Spatial: layer, Temporal: pkt.Temporal})
+	return nil
+}
+
+func TestRelayReceiverSVC(t *testing.T) {
+	r := NewRelayReceiver(RelayReceiverParams{
+		TrackID:   "TR_relayed",
+		StreamID:  "stream",
+		Codec:     webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000}, PayloadType: 98},
+		TrackInfo: &livekit.TrackInfo{Sid: "TR_relayed", Type: livekit.TrackType_VIDEO},
+		Logger:    logger.GetLogger(),
+	})
+	defer r.Close()
+
+	dt := &testSVCTrackSender{testRelayTrackSender: testRelayTrackSender{testMixTrackSender{testTrackSender: testTrackSender{subscriberID: "PA_a"}}}}
+	require.NoError(t, r.AddDownTrack(dt))
+
+	// VP9 payload descriptor of spatial layer 1, temporal layer 1
+	raw, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 98, SequenceNumber: 20},
+		Payload: []byte{0x28, 0x22, 0x00, 0xaa},
+	}).Marshal()
+	require.NoError(t, err)
+	require.NoError(t, r.WritePacket(1, 1, false, raw))
+	require.Equal(t, []buffer.VideoLayer{{Spatial: 1, Temporal: 1}}, dt.layers)
+
+	// layers share the stream, retransmissions are served whatever layer they are asked for
+	buf := make([]byte, 1500)
+	n, err := r.ReadRTP(buf, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, raw, buf[:n])
+}
+
+func TestRelaySender(t *testing.T) {
+	var layers []int32
+	var reports []uint32
+	closed := 0
+	s := NewRelaySender(
+		"relay_node",
+		func(_ *buffer.ExtPacket, layer int32) { layers = append(layers, layer) },
+		func(_ int32, srData *buffer.RTCPSenderReportData) { reports = append(reports, srData.RTPTimestamp) },
+		func() { closed++ },
+	)
+	require.Equal(t, livekit.ParticipantID("relay_node"), s.SubscriberID())
+
+	require.NoError(t, s.WriteRTP(&buffer.ExtPacket{}, 2))
+	require.NoError(t, s.HandleRTCPSenderReportData(96, 2, &buffer.RTCPSenderReportData{RTPTimestamp: 1000}))
+	require.Equal(t, []int32{2}, layers)
+	require.Equal(t, []uint32{1000}, reports)
+
+	s.Close()
+	s.Close()
+	require.Equal(t, 1, closed)
+	require.NoError(t, s.WriteRTP(&buffer.ExtPacket{}, 1))
+	require.Equal(t, []int32{2}, layers)
+}
@@ -21,11 +21,26 @@ const (
 	SequenceNumberOrderingDuplicate
 )
 
+type SequenceNumberJump int
+
+const (
+	SequenceNumberJumpNone SequenceNumberJump = iota
+	SequenceNumberJumpPending
+	SequenceNumberJumpConfirmed
+)
+
 const (
 	RtxGateWindow = 2000
 
 	SnOffsetCacheSize = 4096
 	SnOffsetCacheMask = SnOffsetCacheSize - 1
+
+	// a sequence number moving by more than SnJumpThreshold in either direction is treated as
+	// the sender restarting in a new sequence number space once SnJumpConfirmPackets packets
+	// (allowing reordering within SnJumpReorderWindow) agree on the new space
+	SnJumpThreshold      = SnOffsetCacheSize
+	SnJumpConfirmPackets = 3
+	SnJumpReorderWindow  = 64
 )
 
 type TranslationParamsRTP struct {
@@ -68,6 +83,10 @@ type RTPMungerParams struct {
 
 	rtxGateSn         uint16
 	isInRtxGateRegion bool
+
+	jumpStartSN uint16
+	jumpLastSN  uint16
+	jumpPackets int
 }
 
 type RTPMunger struct {
@@ -118,6 +137,7 @@ func (r *RTPMunger) SetLastSnTs(extPkt *buffer.ExtPacket) {
 		r.tsOffset = extPkt.Packet.Timestamp - r.lastTS - 1
 	}
 	r.started = true
+	r.jumpPackets = 0
 }
 
 func (r *RTPMunger) UpdateSnTsOffsets(extPkt *buffer.ExtPacket, snAdjust uint16, tsAdjust uint32) {
@@ -128,6 +148,52 @@ func (r *RTPMunger) UpdateSnTsOffsets(extPkt *buffer.ExtPacket, snAdjust uint16,
 	// clear offsets cache layer/source switch
 	r.snOffsetsWritePtr = 0
 	r.snOffsetsOccupancy = 0
+
+	r.jumpPackets = 0
+}
+
+// DetectJump checks if the packet is in a sequence number space far away from the current one.
+// Packets are reported as pending till enough packets confirm the jump. On confirmation, the number of
+// packets dropped while pending is returned and the caller is expected to re-anchor via UpdateSnTsOffsets.
+func (r *RTPMunger) DetectJump(extPkt *buffer.ExtPacket) (SequenceNumberJump, int) {
+	sn := extPkt.Packet.SequenceNumber
+	diff := sn - r.highestIncomingSN
+	if !r.started || (diff <= SnJumpThreshold || diff >= (1<<16)-SnJumpThreshold) {
+		r.jumpPackets = 0
+		return SequenceNumberJumpNone, 0
+	}
+
+	if r.jumpPackets != 0 {
+		fromLast := sn - r.jumpLastSN
+		if fromLast == 0 {
+			// duplicate of a pending packet
+			return SequenceNumberJumpPending, 0
+		}
+		if fromLast > SnJumpReorderWindow && fromLast < (1<<16)-SnJumpReorderWindow {
+			// not near the previous candidate, start over
+			r.jumpPackets = 0
+		}
+	}
+
+	if r.jumpPackets == 0 {
+		r.jumpStartSN = sn
+		r.jumpLastSN = sn
+	} else {
+		if sn-r.jumpStartSN > (1 << 15) {
+			r.jumpStartSN = sn
+		}
+		if sn-r.jumpLastSN < (1 << 15) {
+			r.jumpLastSN = sn
+		}
+	}
+	r.jumpPackets++
+	if r.jumpPackets < SnJumpConfirmPackets {
+		return SequenceNumberJumpPending, 0
+	}
+
+	numDropped := int(sn - r.jumpStartSN)
+	r.jumpPackets = 0
+	return SequenceNumberJumpConfirmed, numDropped
 }
 
 func (r *RTPMunger) PacketDropped(extPkt *buffer.ExtPacket) {
@@ -1,12 +1,14 @@
 package sfu
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/livekit/protocol/logger"
 
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
 	"github.com/livekit/livekit-server/pkg/sfu/testutils"
 )
 
@@ -528,3 +530,165 @@ func TestIsOnFrameBoundary(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, r.IsOnFrameBoundary())
 }
+
+func TestDetectJump(t *testing.T) {
+	r := newRTPMunger()
+
+	params := &testutils.TestExtPacketParams{
+		SequenceNumber: 40000,
+		Timestamp:      0xabcdef,
+		SSRC:           0x12345678,
+		PayloadSize:    20,
+	}
+	extPkt, _ := testutils.GetTestExtPacket(params)
+	r.SetLastSnTs(extPkt)
+	_, err := r.UpdateAndGetSnTs(extPkt)
+	require.NoError(t, err)
+
+	// small gaps and wrap around of regular stream are not jumps
+	for _, sn := range []uint16{40001, 40010, 40005} {
+		params.SequenceNumber = sn
+		extPkt, _ = testutils.GetTestExtPacket(params)
+		jump, _ := r.DetectJump(extPkt)
+		require.Equal(t, SequenceNumberJumpNone, jump)
+		r.UpdateAndGetSnTs(extPkt)
+	}
+
+	// a single stray packet far away is pending and then forgotten when stream continues
+	params.SequenceNumber = 100
+	extPkt, _ = testutils.GetTestExtPacket(params)
+	jump, _ := r.DetectJump(extPkt)
+	require.Equal(t, SequenceNumberJumpPending, jump)
+
+	params.SequenceNumber = 40011
+	extPkt, _ = testutils.GetTestExtPacket(params)
+	jump, _ = r.DetectJump(extPkt)
+	require.Equal(t, SequenceNumberJumpNone, jump)
+	require.Equal(t, 0, r.jumpPackets)
+
+	// restart to a lower sequence number, with some reordering, gets confirmed
+	for i, sn := range []uint16{101, 100} {
+		params.SequenceNumber = sn
+		extPkt, _ = testutils.GetTestExtPacket(params)
+		jump, _ = r.DetectJump(extPkt)
+		require.Equal(t, SequenceNumberJumpPending, jump, "i: %d", i)
+	}
+	params.SequenceNumber = 102
+	extPkt, _ = testutils.GetTestExtPacket(params)
+	jump, numDropped := r.DetectJump(extPkt)
+	require.Equal(t, SequenceNumberJumpConfirmed, jump)
+	require.Equal(t, 2, numDropped)
+
+	// re-anchor leaves a gap for the dropped packets
+	r.UpdateSnTsOffsets(extPkt, uint16(numDropped)+1, 1)
+	tp, err := r.UpdateAndGetSnTs(extPkt)
+	require.NoError(t, err)
+	require.Equal(t, uint16(40010+3), tp.sequenceNumber)
+
+	// jump forward past the threshold
+	for i := 0; i < SnJumpConfirmPackets-1; i++ {
+		params.SequenceNumber = 102 + SnJumpThreshold + 1 + uint16(i)
+		extPkt, _ = testutils.GetTestExtPacket(params)
+		jump, _ = r.DetectJump(extPkt)
+		require.Equal(t, SequenceNumberJumpPending, jump)
+	}
+	params.SequenceNumber = 102 + SnJumpThreshold + SnJumpConfirmPackets
+	extPkt, _ = testutils.GetTestExtPacket(params)
+	jump, numDropped = r.DetectJump(extPkt)
+	require.Equal(t, SequenceNumberJumpConfirmed, jump)
+	require.Equal(t, SnJumpConfirmPackets-1, numDropped)
+}
+
+// forwardThroughMunger drives the munger the way the forwarder does for a single SSRC
+func forwardThroughMunger(r *RTPMunger, extPkt *buffer.ExtPacket) (*TranslationParamsRTP, error) {
+	if !r.started {
+		r.SetLastSnTs(extPkt)
+	} else {
+		switch jump, numDropped := r.DetectJump(extPkt); jump {
+		case SequenceNumberJumpPending:
+			return nil, ErrOutOfOrderSequenceNumberCacheMiss
+		case SequenceNumberJumpConfirmed:
+			r.UpdateSnTsOffsets(extPkt, uint16(numDropped)+1, 1)
+		}
+	}
+	return r.UpdateAndGetSnTs(extPkt)
+}
+
+func TestMungerSequenceNumberResilience(t *testing.T) {
+	// property: whatever the incoming sequence number/timestamp space does (restarts, reordering, wrap around),
+	// in-order output moves forward by a bounded amount and out-of-order output never gets ahead of it
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		r := newRTPMunger()
+
+		sn := uint16(rng.Intn(1 << 16))
+		ts := rng.Uint32()
+		var incoming []*buffer.ExtPacket
+		for i := 0; i < 5000; i++ {
+			if rng.Intn(500) == 0 {
+				// sender restart in a random space far enough to not look like loss, same SSRC
+				sn += uint16(SnJumpThreshold + 1 + rng.Intn((1<<16)-2*SnJumpThreshold-1))
+				ts = rng.Uint32()
+			}
+			extPkt, err := testutils.GetTestExtPacket(&testutils.TestExtPacketParams{
+				SequenceNumber: sn,
+				Timestamp:      ts,
+				SSRC:           0x12345678,
+				PayloadSize:    20,
+			})
+			require.NoError(t, err)
+			incoming = append(incoming, extPkt)
+
+			sn++
+			if rng.Intn(3) == 0 {
+				ts += 3000
+			}
+		}
+
+		// reorder within a small window
+		for i := 0; i < len(incoming)-1; i++ {
+			if rng.Intn(10) == 0 {
+				j := i + 1 + rng.Intn(3)
+				if j < len(incoming) {
+					incoming[i], incoming[j] = incoming[j], incoming[i]
+				}
+			}
+		}
+
+		started := false
+		var highestSN uint16
+		var highestTS uint32
+		numForwarded := 0
+		for i, extPkt := range incoming {
+			tp, err := forwardThroughMunger(r, extPkt)
+			if err != nil {
+				require.ErrorIs(t, err, ErrOutOfOrderSequenceNumberCacheMiss, "seed: %d, i: %d", seed, i)
+				continue
+			}
+			numForwarded++
+
+			if !started {
+				started = true
+				highestSN = tp.sequenceNumber
+				highestTS = tp.timestamp
+				continue
+			}
+
+			snDiff := tp.sequenceNumber - highestSN
+			tsDiff := tp.timestamp - highestTS
+			switch tp.snOrdering {
+			case SequenceNumberOrderingOutOfOrder:
+				require.True(t, snDiff > (1<<15), "seed: %d, i: %d, snDiff: %d", seed, i, snDiff)
+
+			default:
+				require.True(t, snDiff > 0 && snDiff <= 8, "seed: %d, i: %d, snDiff: %d", seed, i, snDiff)
+				require.True(t, tsDiff < (1<<31), "seed: %d, i: %d, tsDiff: %d", seed, i, tsDiff)
+				highestSN = tp.sequenceNumber
+				highestTS = tp.timestamp
+			}
+		}
+
+		// only packets around restarts and reordering are lost
+		require.Greater(t, numForwarded, len(incoming)*9/10, "seed: %d", seed)
+	}
+}
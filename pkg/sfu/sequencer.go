@@ -38,6 +38,8 @@ type packetMeta struct {
 	// Modified timestamp for current associated
 	// down track.
 	timestamp uint32
+	// The time this packet was sent, same resolution as lastNack.
+	sentAt uint32
 	// The last time this packet was nack requested.
 	// Sometimes clients request the same packet more than once, so keep
 	// track of the requested packets helps to avoid writing multiple times
@@ -66,6 +68,7 @@ type sequencer struct {
 	headSN       uint16
 	startTime    int64
 	rtt          uint32
+	maxAge       uint32
 	logger       logger.Logger
 }
 
@@ -92,6 +95,14 @@ func (s *sequencer) setRTT(rtt uint32) {
 	}
 }
 
+// setMaxAge sets the age after which packets are not retransmitted, 0 means no limit
+func (s *sequencer) setMaxAge(maxAge time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.maxAge = uint32(maxAge.Milliseconds())
+}
+
 func (s *sequencer) push(sn, offSn uint16, timeStamp uint32, layer int8, codecBytes []byte, ddBytes []byte) {
 	s.Lock()
 	defer s.Unlock()
@@ -105,6 +116,7 @@ func (s *sequencer) push(sn, offSn uint16, timeStamp uint32, layer int8, codecBy
 		sourceSeqNo: sn,
 		targetSeqNo: offSn,
 		timestamp:   timeStamp,
+		sentAt:      uint32(time.Now().UnixNano()/1e6 - s.startTime),
 		layer:       layer,
 		codecBytes:  append([]byte{}, codecBytes...),
 		ddBytes:     append([]byte{}, ddBytes...),
@@ -130,6 +142,16 @@ func (s *sequencer) pushPadding(offSn uint16) {
 	s.seq[slot] = nil
 }
 
+// flush drops packet history, used when packets can no longer be read from the source they were forwarded from
+func (s *sequencer) flush() {
+	s.Lock()
+	defer s.Unlock()
+
+	for i := range s.seq {
+		s.seq[i] = nil
+	}
+}
+
 func (s *sequencer) getSlot(offSn uint16) (int, bool) {
 	if !s.init {
 		s.headSN = offSn - 1
@@ -187,6 +209,11 @@ func (s *sequencer) getPacketsMeta(seqNo []uint16) []packetMeta {
 			continue
 		}
 
+		if s.maxAge != 0 && refTime-seq.sentAt > s.maxAge {
+			// too late to be useful at the remote end
+			continue
+		}
+
 		if seq.lastNack == 0 || refTime-seq.lastNack > uint32(math.Min(float64(ignoreRetransmission), float64(2*s.rtt))) {
 			seq.nacked++
 			seq.lastNack = refTime
@@ -121,3 +121,41 @@ func Test_sequencer_getNACKSeqNo(t *testing.T) {
 		})
 	}
 }
+
+func Test_sequencer_maxAge(t *testing.T) {
+	seq := newSequencer(100, 0, logger.GetLogger())
+	seq.setMaxAge(50 * time.Millisecond)
+
+	for i := uint16(1); i < 10; i++ {
+		seq.push(i, i, 123, 0, nil, nil)
+	}
+
+	res := seq.getPacketsMeta([]uint16{2, 3})
+	require.Equal(t, 2, len(res))
+
+	// packets aged past the cutoff should not be retransmitted
+	time.Sleep(120 * time.Millisecond)
+	res = seq.getPacketsMeta([]uint16{4, 5})
+	require.Equal(t, 0, len(res))
+
+	seq.push(10, 10, 123, 0, nil, nil)
+	res = seq.getPacketsMeta([]uint16{10})
+	require.Equal(t, 1, len(res))
+}
+
+func Test_sequencer_flush(t *testing.T) {
+	seq := newSequencer(100, 0, logger.GetLogger())
+
+	for i := uint16(1); i < 10; i++ {
+		seq.push(i, i, 123, 0, nil, nil)
+	}
+	seq.flush()
+
+	res := seq.getPacketsMeta([]uint16{2, 3})
+	require.Equal(t, 0, len(res))
+
+	// packets after flush continue the sequence
+	seq.push(10, 10, 123, 0, nil, nil)
+	res = seq.getPacketsMeta([]uint16{10})
+	require.Equal(t, 1, len(res))
+}
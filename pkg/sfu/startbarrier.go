@@ -0,0 +1,174 @@
+package sfu
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+type startBarrierMember struct {
+	ready   bool
+	video   bool
+	started bool
+	// asks the publisher of a video member for a key frame
+	onKeyFrameNeeded func()
+}
+
+// StartBarrier holds the forwarding of a set of tracks until all of them are ready, so that they start together,
+// e.g. the audio and video of a clip. Audio is ready with its first packet and video with its first key frame.
+// Once all are ready, video starts at its next key frame and audio is held until all video has started, so that
+// audio does not lead video. Packets held are dropped. Forwarding starts regardless when the timeout expires, counted
+// from the first packet of any member so that a slow publisher does not run out the time before sending
+type StartBarrier struct {
+	logger  logger.Logger
+	timeout time.Duration
+
+	lock     sync.Mutex
+	members  map[string]*startBarrierMember
+	allReady bool
+	// started with the first packet of a member
+	timer *time.Timer
+
+	released atomic.Bool
+}
+
+func NewStartBarrier(members []string, timeout time.Duration, logger logger.Logger) *StartBarrier {
+	b := &StartBarrier{
+		logger:  logger,
+		timeout: timeout,
+		members: make(map[string]*startBarrierMember, len(members)),
+	}
+	for _, member := range members {
+		b.members[member] = &startBarrierMember{}
+	}
+	return b
+}
+
+// Members returns the names the tracks of the barrier were declared with
+func (b *StartBarrier) Members() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	members := make([]string, 0, len(b.members))
+	for member := range b.members {
+		members = append(members, member)
+	}
+	return members
+}
+
+func (b *StartBarrier) IsReleased() bool {
+	return b.released.Load()
+}
+
+// OnKeyFrameNeeded sets how a key frame of a video member is requested, when the key frame it was ready with has
+// been dropped while waiting for the other members
+func (b *StartBarrier) OnKeyFrameNeeded(member string, f func()) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if m := b.members[member]; m != nil {
+		m.onKeyFrameNeeded = f
+	}
+}
+
+// Admit tells whether a packet of a member can be forwarded
+func (b *StartBarrier) Admit(member string, video bool, keyFrame bool) bool {
+	if b.released.Load() {
+		return true
+	}
+
+	b.lock.Lock()
+	m := b.members[member]
+	if m == nil {
+		b.lock.Unlock()
+		return true
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.timeout, b.onTimeout)
+	}
+
+	var keyFrameRequests []func()
+	if !m.ready && (!video || keyFrame) {
+		m.ready = true
+		m.video = video
+		keyFrameRequests = b.checkReadyLocked()
+	}
+
+	admit := false
+	if b.allReady {
+		if video {
+			if !m.started && keyFrame {
+				m.started = true
+				b.checkStartedLocked()
+			}
+			admit = m.started
+		} else {
+			admit = b.released.Load()
+		}
+	}
+	b.lock.Unlock()
+
+	for _, f := range keyFrameRequests {
+		go f()
+	}
+	return admit
+}
+
+// checkReadyLocked returns the key frame requests of video members once all members are ready
+func (b *StartBarrier) checkReadyLocked() []func() {
+	for _, m := range b.members {
+		if !m.ready {
+			return nil
+		}
+	}
+	b.allReady = true
+	b.logger.Debugw("start barrier ready")
+
+	var keyFrameRequests []func()
+	for _, m := range b.members {
+		if m.video && m.onKeyFrameNeeded != nil {
+			keyFrameRequests = append(keyFrameRequests, m.onKeyFrameNeeded)
+		}
+	}
+	b.checkStartedLocked()
+	return keyFrameRequests
+}
+
+func (b *StartBarrier) checkStartedLocked() {
+	for _, m := range b.members {
+		if m.video && !m.started {
+			return
+		}
+	}
+	b.releaseLocked()
+}
+
+func (b *StartBarrier) releaseLocked() {
+	if b.released.Swap(true) {
+		return
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.logger.Infow("start barrier released")
+}
+
+func (b *StartBarrier) onTimeout() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.released.Load() {
+		return
+	}
+	var waiting []string
+	for member, m := range b.members {
+		if !m.ready || (m.video && !m.started) {
+			waiting = append(waiting, member)
+		}
+	}
+	b.logger.Warnw("start barrier timed out, forwarding all tracks", nil, "waiting", waiting)
+	b.releaseLocked()
+}
@@ -0,0 +1,70 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestStartBarrier(t *testing.T) {
+	t.Run("tracks start together", func(t *testing.T) {
+		b := NewStartBarrier([]string{"audio", "video"}, time.Minute, logger.GetLogger())
+		keyFrameRequests := atomic.NewInt32(0)
+		b.OnKeyFrameNeeded("video", func() {
+			keyFrameRequests.Inc()
+		})
+
+		// video is ready with a key frame, dropped while audio is not ready
+		require.False(t, b.Admit("video", true, false))
+		require.False(t, b.Admit("video", true, true))
+		require.False(t, b.Admit("video", true, false))
+
+		// audio is ready but held until video starts, which needs a new key frame
+		require.False(t, b.Admit("audio", false, false))
+		require.Eventually(t, func() bool {
+			return keyFrameRequests.Load() == 1
+		}, time.Second, 10*time.Millisecond)
+		require.False(t, b.Admit("video", true, false))
+		require.False(t, b.Admit("audio", false, false))
+		require.False(t, b.IsReleased())
+
+		require.True(t, b.Admit("video", true, true))
+		require.True(t, b.IsReleased())
+		require.True(t, b.Admit("audio", false, false))
+		require.True(t, b.Admit("video", true, false))
+	})
+
+	t.Run("video ready last starts with its key frame", func(t *testing.T) {
+		b := NewStartBarrier([]string{"audio", "video"}, time.Minute, logger.GetLogger())
+		require.False(t, b.Admit("audio", false, false))
+		require.False(t, b.Admit("video", true, false))
+		require.True(t, b.Admit("video", true, true))
+		require.True(t, b.Admit("audio", false, false))
+	})
+
+	t.Run("tracks outside the barrier are not held", func(t *testing.T) {
+		b := NewStartBarrier([]string{"audio", "video"}, time.Minute, logger.GetLogger())
+		require.True(t, b.Admit("other", true, false))
+	})
+
+	t.Run("timeout releases", func(t *testing.T) {
+		b := NewStartBarrier([]string{"audio", "video"}, 10*time.Millisecond, logger.GetLogger())
+		require.False(t, b.Admit("audio", false, false))
+		require.Eventually(t, b.IsReleased, time.Second, 10*time.Millisecond)
+		require.True(t, b.Admit("audio", false, false))
+		require.True(t, b.Admit("video", true, false))
+	})
+
+	t.Run("timeout counts from the first packet", func(t *testing.T) {
+		b := NewStartBarrier([]string{"audio", "video"}, 10*time.Millisecond, logger.GetLogger())
+		time.Sleep(30 * time.Millisecond)
+		require.False(t, b.IsReleased())
+
+		require.False(t, b.Admit("audio", false, false))
+		require.Eventually(t, b.IsReleased, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,107 @@
+package streamallocator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/rtcp"
+
+	"github.com/livekit/protocol/logger"
+
+	lkgcc "github.com/livekit/livekit-server/pkg/sfu/gcc"
+)
+
+const (
+	// the estimator of pion interceptors, delay based only
+	BandwidthEstimatorPion = "pion"
+	// send side GCC, delay and loss based
+	BandwidthEstimatorGCC = "gcc"
+
+	bandwidthEstimatorInitialBitrate = 1 * 1000 * 1000
+)
+
+var ErrUnknownBandwidthEstimator = errors.New("unknown bandwidth estimator")
+
+// BandwidthEstimator estimates the capacity of the channel to a subscriber from transport-cc feedback. It sees
+// packets as they are sent through AddStream and feedback through WriteRTCP. The method set is that of the estimators
+// of pion's cc interceptor, so any of them can be plugged in.
+type BandwidthEstimator interface {
+	AddStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter
+	WriteRTCP(pkts []rtcp.Packet, attributes interceptor.Attributes) error
+	GetTargetBitrate() int
+	OnTargetBitrateChange(f func(bitrate int))
+	GetStats() map[string]interface{}
+	Close() error
+}
+
+type BandwidthEstimatorParams struct {
+	InitialBitrate int
+	Logger         logger.Logger
+}
+
+type BandwidthEstimatorFactory func(params BandwidthEstimatorParams) (BandwidthEstimator, error)
+
+var (
+	bandwidthEstimatorsMu sync.RWMutex
+	bandwidthEstimators   = map[string]BandwidthEstimatorFactory{
+		BandwidthEstimatorPion: newPionBandwidthEstimator,
+		BandwidthEstimatorGCC:  newGCCBandwidthEstimator,
+	}
+)
+
+// RegisterBandwidthEstimator makes an estimator selectable by name through congestion_control.bandwidth_estimator,
+// replacing any estimator registered with that name
+func RegisterBandwidthEstimator(name string, factory BandwidthEstimatorFactory) {
+	bandwidthEstimatorsMu.Lock()
+	defer bandwidthEstimatorsMu.Unlock()
+
+	bandwidthEstimators[name] = factory
+}
+
+// HasBandwidthEstimator tells whether an estimator is registered with the name, an empty name is the default one
+func HasBandwidthEstimator(name string) bool {
+	return getBandwidthEstimatorFactory(name) != nil
+}
+
+// NewBandwidthEstimator creates an estimator registered with the name, an empty name is the default one
+func NewBandwidthEstimator(name string, params BandwidthEstimatorParams) (BandwidthEstimator, error) {
+	factory := getBandwidthEstimatorFactory(name)
+	if factory == nil {
+		return nil, ErrUnknownBandwidthEstimator
+	}
+	if params.InitialBitrate == 0 {
+		params.InitialBitrate = bandwidthEstimatorInitialBitrate
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+	return factory(params)
+}
+
+func getBandwidthEstimatorFactory(name string) BandwidthEstimatorFactory {
+	if name == "" {
+		name = BandwidthEstimatorPion
+	}
+
+	bandwidthEstimatorsMu.RLock()
+	defer bandwidthEstimatorsMu.RUnlock()
+
+	return bandwidthEstimators[name]
+}
+
+func newPionBandwidthEstimator(params BandwidthEstimatorParams) (BandwidthEstimator, error) {
+	// pacing is done by the stream allocator
+	return gcc.NewSendSideBWE(
+		gcc.SendSideBWEInitialBitrate(params.InitialBitrate),
+		gcc.SendSideBWEPacer(gcc.NewNoOpPacer()),
+	)
+}
+
+func newGCCBandwidthEstimator(params BandwidthEstimatorParams) (BandwidthEstimator, error) {
+	return lkgcc.NewSendSideBWE(lkgcc.SendSideBWEParams{
+		InitialBitrate: params.InitialBitrate,
+		Logger:         params.Logger,
+	}), nil
+}
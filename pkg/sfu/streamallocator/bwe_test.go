@@ -0,0 +1,31 @@
+package streamallocator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	lkgcc "github.com/livekit/livekit-server/pkg/sfu/gcc"
+)
+
+func TestBandwidthEstimators(t *testing.T) {
+	require.True(t, HasBandwidthEstimator(""))
+	require.True(t, HasBandwidthEstimator(BandwidthEstimatorGCC))
+	require.False(t, HasBandwidthEstimator("custom"))
+
+	_, err := NewBandwidthEstimator("custom", BandwidthEstimatorParams{})
+	require.ErrorIs(t, err, ErrUnknownBandwidthEstimator)
+
+	bwe, err := NewBandwidthEstimator(BandwidthEstimatorGCC, BandwidthEstimatorParams{})
+	require.NoError(t, err)
+	require.IsType(t, &lkgcc.SendSideBWE{}, bwe)
+	require.Equal(t, bandwidthEstimatorInitialBitrate, bwe.GetTargetBitrate())
+
+	RegisterBandwidthEstimator("custom", func(params BandwidthEstimatorParams) (BandwidthEstimator, error) {
+		return lkgcc.NewSendSideBWE(lkgcc.SendSideBWEParams{InitialBitrate: params.InitialBitrate / 2}), nil
+	})
+	require.True(t, HasBandwidthEstimator("custom"))
+	bwe, err = NewBandwidthEstimator("custom", BandwidthEstimatorParams{})
+	require.NoError(t, err)
+	require.Equal(t, bandwidthEstimatorInitialBitrate/2, bwe.GetTargetBitrate())
+}
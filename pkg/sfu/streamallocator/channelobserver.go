@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
 // ------------------------------------------------
@@ -77,13 +79,14 @@ type ChannelObserver struct {
 	repeatedNacks       uint32
 }
 
-func NewChannelObserver(params ChannelObserverParams, logger logger.Logger) *ChannelObserver {
+func NewChannelObserver(params ChannelObserverParams, clock utils.Clock, logger logger.Logger) *ChannelObserver {
 	return &ChannelObserver{
 		params: params,
 		logger: logger,
 		estimateTrend: NewTrendDetector(TrendDetectorParams{
 			Name:                   params.Name + "-estimate",
 			Logger:                 logger,
+			Clock:                  clock,
 			RequiredSamples:        params.EstimateRequiredSamples,
 			DownwardTrendThreshold: params.EstimateDownwardTrendThreshold,
 			CollapseThreshold:      params.EstimateCollapseThreshold,
@@ -91,6 +94,7 @@ func NewChannelObserver(params ChannelObserverParams, logger logger.Logger) *Cha
 		nackTracker: NewNackTracker(NackTrackerParams{
 			Name:              params.Name + "-nack",
 			Logger:            logger,
+			Clock:             clock,
 			WindowMinDuration: params.NackWindowMinDuration,
 			WindowMaxDuration: params.NackWindowMaxDuration,
 			RatioThreshold:    params.NackRatioThreshold,
@@ -0,0 +1,192 @@
+package streamallocator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const diagnosticsTimeout = 2 * time.Second
+
+var (
+	ErrDiagnosticsTrackNotFound = errors.New("track is not allocated by the stream allocator")
+	ErrDiagnosticsTimeout       = errors.New("stream allocator did not answer in time")
+)
+
+// Diagnostics are the inputs and the outcome of the latest allocation of a subscribed video track, what is needed to
+// tell why the subscriber receives the layer it does
+type Diagnostics struct {
+	CongestionControl bool   `json:"congestion_control"`
+	State             string `json:"state"`
+	AllowPause        bool   `json:"allow_pause"`
+	IsProbing         bool   `json:"is_probing"`
+	// the latest estimate received, the capacity committed from the estimates and what tracks are allocated from
+	// after applying the minimum, the override and the cap, in bps
+	ReceivedEstimate           int64 `json:"received_estimate"`
+	CommittedChannelCapacity   int64 `json:"committed_channel_capacity"`
+	MinChannelCapacity         int64 `json:"min_channel_capacity,omitempty"`
+	OverriddenChannelCapacity  int64 `json:"overridden_channel_capacity,omitempty"`
+	MaxChannelCapacity         int64 `json:"max_channel_capacity,omitempty"`
+	AllocatableChannelCapacity int64 `json:"allocatable_channel_capacity"`
+
+	Track *TrackDiagnostics `json:"track"`
+	// the other video tracks of the subscriber, competing for the channel capacity
+	OtherTracks []*TrackDiagnostics `json:"other_tracks,omitempty"`
+
+	Reason string `json:"reason"`
+}
+
+type TrackDiagnostics struct {
+	TrackID     livekit.TrackID       `json:"track_id"`
+	PublisherID livekit.ParticipantID `json:"publisher_id,omitempty"`
+	Source      string                `json:"source"`
+	IsSimulcast bool                  `json:"is_simulcast"`
+	Priority    uint8                 `json:"priority"`
+	// exempt tracks are allocated optimally, regardless of the channel capacity
+	Exempt    bool `json:"exempt"`
+	Paused    bool `json:"paused"`
+	Deficient bool `json:"deficient"`
+
+	MaxLayer     buffer.VideoLayer `json:"max_layer"`
+	TargetLayer  buffer.VideoLayer `json:"target_layer"`
+	CurrentLayer buffer.VideoLayer `json:"current_layer"`
+	PauseReason  string            `json:"pause_reason"`
+	// bps of the target layer and of the optimal layer
+	BandwidthRequested int64        `json:"bandwidth_requested"`
+	BandwidthNeeded    int64        `json:"bandwidth_needed"`
+	DistanceToDesired  float64      `json:"distance_to_desired"`
+	LossEstimate       float64      `json:"loss_estimate"`
+	Bitrates           sfu.Bitrates `json:"bitrates"`
+
+	pauseReason sfu.VideoPauseReason
+}
+
+// GetDiagnostics returns what the allocation of a subscribed video track is based on. It is answered by the event
+// loop, so that it is consistent with the allocation
+func (s *StreamAllocator) GetDiagnostics(trackID livekit.TrackID) (*Diagnostics, error) {
+	resultCh := make(chan *Diagnostics, 1)
+	s.postEvent(Event{
+		Signal:  streamAllocatorSignalDiagnostics,
+		TrackID: trackID,
+		Data:    resultCh,
+	})
+
+	select {
+	case diagnostics := <-resultCh:
+		if diagnostics == nil {
+			return nil, ErrDiagnosticsTrackNotFound
+		}
+		return diagnostics, nil
+	case <-time.After(diagnosticsTimeout):
+		return nil, ErrDiagnosticsTimeout
+	}
+}
+
+func (s *StreamAllocator) handleSignalDiagnostics(event *Event) {
+	event.Data.(chan *Diagnostics) <- s.getDiagnostics(event.TrackID)
+}
+
+func (s *StreamAllocator) getDiagnostics(trackID livekit.TrackID) *Diagnostics {
+	d := &Diagnostics{
+		CongestionControl:          s.params.Config.Enabled,
+		State:                      s.state.String(),
+		AllowPause:                 s.allowPause,
+		IsProbing:                  s.probeClusterId != ProbeClusterIdInvalid,
+		ReceivedEstimate:           s.lastReceivedEstimate,
+		CommittedChannelCapacity:   s.committedChannelCapacity,
+		MinChannelCapacity:         s.params.Config.MinChannelCapacity,
+		OverriddenChannelCapacity:  s.overriddenChannelCapacity,
+		MaxChannelCapacity:         s.maxChannelCapacity,
+		AllocatableChannelCapacity: s.getAllocatableChannelCapacity(),
+	}
+	for _, track := range s.getTracks() {
+		if track.ID() == trackID {
+			d.Track = getTrackDiagnostics(track)
+		} else {
+			d.OtherTracks = append(d.OtherTracks, getTrackDiagnostics(track))
+		}
+	}
+	if d.Track == nil {
+		return nil
+	}
+
+	d.Reason = d.explain()
+	return d
+}
+
+func getTrackDiagnostics(track *Track) *TrackDiagnostics {
+	downTrack := track.DownTrack()
+	allocation := downTrack.LastAllocation()
+	return &TrackDiagnostics{
+		TrackID:            track.ID(),
+		PublisherID:        track.PublisherID(),
+		Source:             track.source.String(),
+		IsSimulcast:        track.isSimulcast,
+		Priority:           track.Priority(),
+		Exempt:             !track.IsManaged(),
+		Paused:             track.isPaused,
+		Deficient:          allocation.IsDeficient,
+		MaxLayer:           downTrack.MaxLayer(),
+		TargetLayer:        allocation.TargetLayer,
+		CurrentLayer:       downTrack.CurrentLayer(),
+		PauseReason:        allocation.PauseReason.String(),
+		BandwidthRequested: allocation.BandwidthRequested,
+		BandwidthNeeded:    allocation.BandwidthNeeded,
+		DistanceToDesired:  allocation.DistanceToDesired,
+		LossEstimate:       track.LossEstimate(),
+		Bitrates:           allocation.Bitrates,
+		pauseReason:        allocation.PauseReason,
+	}
+}
+
+// explain tells in words which of the inputs decided the layer of the track
+func (d *Diagnostics) explain() string {
+	t := d.Track
+	switch t.pauseReason {
+	case sfu.VideoPauseReasonMuted:
+		return "paused, the subscriber disabled the track"
+	case sfu.VideoPauseReasonPubMuted:
+		return "paused, the publisher muted the track"
+	case sfu.VideoPauseReasonFeedDry:
+		return "paused, the publisher does not send any layer of the track"
+	case sfu.VideoPauseReasonBandwidth:
+		return fmt.Sprintf(
+			"paused, %d bps of channel capacity left by %d track(s) of higher or same priority do not cover the lowest layer",
+			d.AllocatableChannelCapacity,
+			d.numCompeting(),
+		)
+	}
+
+	if !d.CongestionControl && d.MaxChannelCapacity == 0 {
+		return fmt.Sprintf("congestion control is disabled, target layer %s is the best available up to max layer %s", t.TargetLayer, t.MaxLayer)
+	}
+	if t.Exempt {
+		return fmt.Sprintf("exempt from congestion control as a screen share without simulcast, target layer %s is the best available up to max layer %s", t.TargetLayer, t.MaxLayer)
+	}
+	if t.Deficient {
+		return fmt.Sprintf(
+			"limited by channel capacity, target layer %s is below max layer %s, %d bps are shared with %d track(s) of higher or same priority",
+			t.TargetLayer,
+			t.MaxLayer,
+			d.AllocatableChannelCapacity,
+			d.numCompeting(),
+		)
+	}
+	return fmt.Sprintf("not limited, target layer %s is the best the publisher sends up to max layer %s", t.TargetLayer, t.MaxLayer)
+}
+
+// numCompeting returns the number of other managed tracks allocated before or along with the track
+func (d *Diagnostics) numCompeting() int {
+	num := 0
+	for _, other := range d.OtherTracks {
+		if !other.Exempt && other.Priority >= d.Track.Priority {
+			num++
+		}
+	}
+	return num
+}
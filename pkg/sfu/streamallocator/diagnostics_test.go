@@ -0,0 +1,95 @@
+package streamallocator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+func TestDiagnostics(t *testing.T) {
+	sim := NewSimulator(SimulatorParams{
+		Config: simulatorConfig,
+		Logger: logger.GetLogger(),
+	})
+	replay := func(events ...TraceEvent) {
+		for _, event := range events {
+			sim.advanceTo(sim.start.Add(time.Duration(event.At) * time.Millisecond))
+			sim.apply(event)
+			sim.process()
+		}
+	}
+	setChannelCapacity := func(channelCapacity int64) {
+		sim.allocator.postEvent(Event{
+			Signal: streamAllocatorSignalSetChannelCapacity,
+			Data:   channelCapacity,
+		})
+		sim.process()
+	}
+
+	events := []TraceEvent{
+		{Type: TraceEventAddTrack, TrackID: "TR_camera", Source: livekit.TrackSource_CAMERA, IsSimulcast: true},
+		{Type: TraceEventAddTrack, TrackID: "TR_screen", Source: livekit.TrackSource_SCREEN_SHARE},
+		{Type: TraceEventLayers, TrackID: "TR_camera", AvailableLayers: []int32{0, 1, 2}, Bitrates: &sfu.Bitrates{
+			{100_000, 150_000, 200_000},
+			{300_000, 450_000, 600_000},
+			{900_000, 1_300_000, 1_700_000},
+		}},
+		{Type: TraceEventLayers, TrackID: "TR_screen", AvailableLayers: []int32{0}, Bitrates: &sfu.Bitrates{
+			{400_000, 600_000, 800_000},
+		}},
+	}
+	for at := int64(100); at <= 1000; at += 100 {
+		events = append(events, TraceEvent{At: at, Type: TraceEventEstimate, Estimate: 4_000_000})
+	}
+	replay(events...)
+
+	require.Nil(t, sim.allocator.getDiagnostics("TR_unknown"))
+
+	camera := sim.allocator.getDiagnostics("TR_camera")
+	require.NotNil(t, camera)
+	require.Equal(t, streamAllocatorStateStable.String(), camera.State)
+	require.Equal(t, int64(4_000_000), camera.ReceivedEstimate)
+	require.False(t, camera.Track.Exempt)
+	require.Equal(t, buffer.VideoLayer{Spatial: 2, Temporal: 2}, camera.Track.TargetLayer)
+	require.Len(t, camera.OtherTracks, 1)
+	require.Equal(t, livekit.TrackID("TR_screen"), camera.OtherTracks[0].TrackID)
+	require.True(t, strings.HasPrefix(camera.Reason, "not limited"), camera.Reason)
+
+	screen := sim.allocator.getDiagnostics("TR_screen")
+	require.True(t, screen.Track.Exempt)
+	require.True(t, strings.HasPrefix(screen.Reason, "exempt from congestion control"), screen.Reason)
+
+	// the screen share takes all of it, none left for the camera
+	setChannelCapacity(700_000)
+	camera = sim.allocator.getDiagnostics("TR_camera")
+	require.Equal(t, int64(700_000), camera.AllocatableChannelCapacity)
+	require.Equal(t, sfu.VideoPauseReasonBandwidth.String(), camera.Track.PauseReason)
+	require.True(t, strings.HasPrefix(camera.Reason, "paused, 700000 bps of channel capacity"), camera.Reason)
+
+	// what the screen share leaves is not enough for the highest layer of the camera
+	setChannelCapacity(1_200_000)
+	camera = sim.allocator.getDiagnostics("TR_camera")
+	require.True(t, camera.Track.Deficient)
+	require.Less(t, camera.Track.TargetLayer.Spatial, camera.Track.MaxLayer.Spatial)
+	require.True(t, strings.HasPrefix(camera.Reason, "limited by channel capacity"), camera.Reason)
+}
+
+func TestGetDiagnostics(t *testing.T) {
+	s := NewStreamAllocator(StreamAllocatorParams{
+		Config: simulatorConfig,
+		Logger: logger.GetLogger(),
+	})
+	s.Start()
+	defer s.Stop()
+
+	_, err := s.GetDiagnostics("TR_unknown")
+	require.ErrorIs(t, err, ErrDiagnosticsTrackNotFound)
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
 // ------------------------------------------------
@@ -12,6 +14,7 @@ import (
 type NackTrackerParams struct {
 	Name              string
 	Logger            logger.Logger
+	Clock             utils.Clock
 	WindowMinDuration time.Duration
 	WindowMaxDuration time.Duration
 	RatioThreshold    float64
@@ -36,7 +39,7 @@ func NewNackTracker(params NackTrackerParams) *NackTracker {
 }
 
 func (n *NackTracker) Add(packets uint32, repeatedNacks uint32) {
-	if n.params.WindowMaxDuration != 0 && !n.windowStartTime.IsZero() && time.Since(n.windowStartTime) > n.params.WindowMaxDuration {
+	if n.params.WindowMaxDuration != 0 && !n.windowStartTime.IsZero() && n.params.Clock.Now().Sub(n.windowStartTime) > n.params.WindowMaxDuration {
 		n.updateHistory()
 
 		n.windowStartTime = time.Time{}
@@ -51,7 +54,7 @@ func (n *NackTracker) Add(packets uint32, repeatedNacks uint32) {
 	// or isolated losses
 	//
 	if n.repeatedNacks == 0 && repeatedNacks != 0 {
-		n.windowStartTime = time.Now()
+		n.windowStartTime = n.params.Clock.Now()
 	}
 
 	if !n.windowStartTime.IsZero() {
@@ -73,7 +76,7 @@ func (n *NackTracker) GetRatio() float64 {
 }
 
 func (n *NackTracker) IsTriggered() bool {
-	if n.params.WindowMinDuration != 0 && !n.windowStartTime.IsZero() && time.Since(n.windowStartTime) > n.params.WindowMinDuration {
+	if n.params.WindowMinDuration != 0 && !n.windowStartTime.IsZero() && n.params.Clock.Now().Sub(n.windowStartTime) > n.params.WindowMinDuration {
 		return n.GetRatio() > n.params.RatioThreshold
 	}
 
@@ -83,7 +86,7 @@ func (n *NackTracker) IsTriggered() bool {
 func (n *NackTracker) ToString() string {
 	window := ""
 	if !n.windowStartTime.IsZero() {
-		now := time.Now()
+		now := n.params.Clock.Now()
 		elapsed := now.Sub(n.windowStartTime).Seconds()
 		window = fmt.Sprintf("t: %+v|%+v|%.2fs", n.windowStartTime.Format(time.UnixDate), now.Format(time.UnixDate), elapsed)
 	}
@@ -114,6 +114,8 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
 type ProberListener interface {
@@ -124,10 +126,14 @@ type ProberListener interface {
 
 type ProberParams struct {
 	Logger logger.Logger
+	Clock  utils.Clock
 }
 
 type Prober struct {
 	logger logger.Logger
+	clock  utils.Clock
+	// clusters are processed by Step instead of a goroutine, for simulations
+	stepped bool
 
 	clusterId atomic.Uint32
 
@@ -144,6 +150,7 @@ type Prober struct {
 func NewProber(params ProberParams) *Prober {
 	p := &Prober{
 		logger: params.Logger,
+		clock:  params.Clock,
 	}
 	p.clusters.SetMinCapacity(2)
 	return p
@@ -201,7 +208,7 @@ func (p *Prober) AddCluster(mode ProbeClusterMode, desiredRateBps int, expectedR
 	}
 
 	clusterId := ProbeClusterId(p.clusterId.Inc())
-	cluster := NewCluster(clusterId, mode, desiredRateBps, expectedRateBps, minDuration, maxDuration)
+	cluster := NewCluster(clusterId, mode, desiredRateBps, expectedRateBps, minDuration, maxDuration, p.clock)
 	p.logger.Debugw("cluster added", "cluster", cluster.String())
 
 	p.pushBackClusterAndMaybeStart(cluster)
@@ -276,7 +283,9 @@ func (p *Prober) pushBackClusterAndMaybeStart(cluster *Cluster) {
 	if p.clusters.Len() == 1 {
 		p.activeStateQueue = append(p.activeStateQueue, true)
 
-		go p.run()
+		if !p.stepped {
+			go p.run()
+		}
 	}
 	p.clustersMu.Unlock()
 
@@ -319,32 +328,43 @@ func (p *Prober) run() {
 	for {
 		<-timer.C
 
-		// wake up and check for probes to send
-		cluster = p.getFrontCluster()
-		if cluster == nil {
+		sleepDuration, ok := p.Step()
+		if !ok {
 			return
 		}
 
-		cluster.Process(p.getProberListener())
+		timer.Reset(sleepDuration)
+	}
+}
 
-		if cluster.IsFinished() {
-			p.logger.Debugw("cluster finished", "cluster", cluster.String())
+// Step processes the front cluster, and returns how long to wait before the next step, false when there are no
+// clusters left
+func (p *Prober) Step() (time.Duration, bool) {
+	// wake up and check for probes to send
+	cluster := p.getFrontCluster()
+	if cluster == nil {
+		return 0, false
+	}
 
-			if pl := p.getProberListener(); pl != nil {
-				pl.OnProbeClusterDone(cluster.GetInfo())
-			}
+	cluster.Process(p.getProberListener())
 
-			p.popFrontCluster(cluster)
-		}
+	if cluster.IsFinished() {
+		p.logger.Debugw("cluster finished", "cluster", cluster.String())
 
-		// determine how long to sleep
-		cluster := p.getFrontCluster()
-		if cluster == nil {
-			return
+		if pl := p.getProberListener(); pl != nil {
+			pl.OnProbeClusterDone(cluster.GetInfo())
 		}
 
-		timer.Reset(cluster.GetSleepDuration())
+		p.popFrontCluster(cluster)
 	}
+
+	// determine how long to sleep
+	cluster = p.getFrontCluster()
+	if cluster == nil {
+		return 0, false
+	}
+
+	return cluster.GetSleepDuration(), true
 }
 
 // ---------------------------------
@@ -408,14 +428,16 @@ type Cluster struct {
 	bytesSentProbe    int
 	bytesSentNonProbe int
 	startTime         time.Time
+	clock             utils.Clock
 }
 
-func NewCluster(id ProbeClusterId, mode ProbeClusterMode, desiredRateBps int, expectedRateBps int, minDuration time.Duration, maxDuration time.Duration) *Cluster {
+func NewCluster(id ProbeClusterId, mode ProbeClusterMode, desiredRateBps int, expectedRateBps int, minDuration time.Duration, maxDuration time.Duration, clock utils.Clock) *Cluster {
 	c := &Cluster{
 		id:          id,
 		mode:        mode,
 		minDuration: minDuration,
 		maxDuration: maxDuration,
+		clock:       clock,
 	}
 	c.initBuckets(desiredRateBps, expectedRateBps, minDuration)
 	c.desiredBytes = c.buckets[len(c.buckets)-1].desiredBytes
@@ -469,7 +491,7 @@ func (c *Cluster) Start() {
 	defer c.lock.Unlock()
 
 	if c.startTime.IsZero() {
-		c.startTime = time.Now()
+		c.startTime = c.clock.Now()
 	}
 }
 
@@ -499,7 +521,7 @@ func (c *Cluster) IsFinished() bool {
 	defer c.lock.RUnlock()
 
 	// if already past deadline, end the cluster
-	timeElapsed := time.Since(c.startTime)
+	timeElapsed := c.clock.Now().Sub(c.startTime)
 	if timeElapsed > c.maxDuration {
 		return true
 	}
@@ -520,13 +542,13 @@ func (c *Cluster) GetInfo() ProbeClusterInfo {
 	return ProbeClusterInfo{
 		Id:        c.id,
 		BytesSent: c.bytesSentProbe + c.bytesSentNonProbe,
-		Duration:  time.Since(c.startTime),
+		Duration:  c.clock.Now().Sub(c.startTime),
 	}
 }
 
 func (c *Cluster) Process(pl ProberListener) {
 	c.lock.RLock()
-	timeElapsed := time.Since(c.startTime)
+	timeElapsed := c.clock.Now().Sub(c.startTime)
 
 	// Calculate number of probe bytes that should have been sent since start.
 	// Overall goal is to send desired number of probe bytes in minDuration.
@@ -564,7 +586,7 @@ func (c *Cluster) Process(pl ProberListener) {
 func (c *Cluster) String() string {
 	activeTimeMs := int64(0)
 	if !c.startTime.IsZero() {
-		activeTimeMs = time.Since(c.startTime).Milliseconds()
+		activeTimeMs = c.clock.Now().Sub(c.startTime).Milliseconds()
 	}
 
 	return fmt.Sprintf("id: %d, mode: %s, bytes: desired %d / probe %d / non-probe %d / remaining: %d, time(ms): active %d / min %d / max %d",
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/utils/timeseries"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
 // ------------------------------------------------
@@ -17,6 +19,8 @@ const (
 // ------------------------------------------------
 
 type RateMonitor struct {
+	clock utils.Clock
+
 	bitrateEstimate             *timeseries.TimeSeries[int64]
 	managedBytesSent            *timeseries.TimeSeries[uint32]
 	managedBytesRetransmitted   *timeseries.TimeSeries[uint32]
@@ -27,8 +31,9 @@ type RateMonitor struct {
 	history []string
 }
 
-func NewRateMonitor() *RateMonitor {
+func NewRateMonitor(clock utils.Clock) *RateMonitor {
 	return &RateMonitor{
+		clock: clock,
 		bitrateEstimate: timeseries.NewTimeSeries[int64](timeseries.TimeSeriesParams{
 			UpdateOp: timeseries.TimeSeriesUpdateOpLatest,
 			Window:   rateMonitorWindow,
@@ -53,7 +58,7 @@ func NewRateMonitor() *RateMonitor {
 }
 
 func (r *RateMonitor) Update(estimate int64, managedBytesSent uint32, managedBytesRetransmitted uint32, unmanagedBytesSent uint32, unmanagedBytesRetransmitted uint32) {
-	now := time.Now()
+	now := r.clock.Now()
 	r.bitrateEstimate.AddSampleAt(estimate, now)
 	r.managedBytesSent.AddSampleAt(managedBytesSent, now)
 	r.managedBytesRetransmitted.AddSampleAt(managedBytesRetransmitted, now)
@@ -73,7 +78,8 @@ func (r *RateMonitor) GetQueuingGuess() float64 {
 }
 
 func (r *RateMonitor) getRates(monitorDuration time.Duration) (float64, float64, float64, float64, float64, float64) {
-	threshold := time.Now().Add(-monitorDuration)
+	now := r.clock.Now()
+	threshold := now.Add(-monitorDuration)
 	bitrateEstimateSamples := r.bitrateEstimate.GetSamplesAfter(threshold)
 	managedBytesSentSamples := r.managedBytesSent.GetSamplesAfter(threshold)
 	managedBytesRetransmittedSamples := r.managedBytesRetransmitted.GetSamplesAfter(threshold)
@@ -84,7 +90,7 @@ func (r *RateMonitor) getRates(monitorDuration time.Duration) (float64, float64,
 		return 0.0, 0.0, 0.0, 0.0, 0.0, 0.0
 	}
 
-	totalBitrateEstimate := getTimeWeightedSum(bitrateEstimateSamples)
+	totalBitrateEstimate := getTimeWeightedSum(bitrateEstimateSamples, now)
 	totalManagedSent := getRate(managedBytesSentSamples) * 8
 	totalManagedRetransmitted := getRate(managedBytesRetransmittedSamples) * 8
 	totalUnmanagedSent := getRate(unmanagedBytesSentSamples) * 8
@@ -112,7 +118,7 @@ func (r *RateMonitor) updateHistory() {
 
 	r.history = append(
 		r.history,
-		fmt.Sprintf("t: %+v, e: %.2f, m: %.2f/%.2f, um: %.2f/%.2f, qd: %.2f", time.Now().UnixMilli(), e, m, mr, um, umr, qd),
+		fmt.Sprintf("t: %+v, e: %.2f, m: %.2f/%.2f, um: %.2f/%.2f, qd: %.2f", r.clock.Now().UnixMilli(), e, m, mr, um, umr, qd),
 	)
 }
 
@@ -122,7 +128,7 @@ func (r *RateMonitor) GetHistory() []string {
 
 // ------------------------------------------------
 
-func getTimeWeightedSum[T int64 | uint32](samples []timeseries.TimeSeriesSample[T]) float64 {
+func getTimeWeightedSum[T int64 | uint32](samples []timeseries.TimeSeriesSample[T], now time.Time) float64 {
 	if len(samples) < 2 {
 		return 0.0
 	}
@@ -133,7 +139,7 @@ func getTimeWeightedSum[T int64 | uint32](samples []timeseries.TimeSeriesSample[
 		sum += diff * float64(samples[i-1].Value)
 	}
 
-	diff := time.Now().Sub(samples[len(samples)-1].At).Seconds()
+	diff := now.Sub(samples[len(samples)-1].At).Seconds()
 	sum += diff * float64(samples[len(samples)-1].Value)
 	return sum
 }
@@ -0,0 +1,516 @@
+package streamallocator
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+var simulatedCodec = webrtc.RTPCodecCapability{
+	MimeType:  webrtc.MimeTypeVP8,
+	ClockRate: 90000,
+}
+
+type SimulatorParams struct {
+	Config config.CongestionControlConfig
+	Logger logger.Logger
+}
+
+// Simulator replays a trace against a stream allocator on a simulated clock. Events, periodic pings and probes are
+// processed on the calling goroutine in time order, so a trace always yields the same allocations.
+//
+// Estimates are replayed as recorded, they do not react to allocations or probes of the simulated allocator.
+type Simulator struct {
+	params    SimulatorParams
+	clock     *utils.SimulatedClock
+	start     time.Time
+	allocator *StreamAllocator
+	tracks    map[livekit.TrackID]*simulatedTrack
+	paused    map[livekit.TrackID]bool
+
+	nextPingAt  time.Time
+	nextProbeAt time.Time
+
+	steps []SimulationStep
+}
+
+// SimulationResult lists the allocator state every time it changed
+type SimulationResult struct {
+	Steps []SimulationStep `json:"steps"`
+}
+
+type SimulationStep struct {
+	// milliseconds since the start of the trace
+	At              int64                 `json:"at"`
+	State           string                `json:"state"`
+	ChannelCapacity int64                 `json:"channel_capacity"`
+	Tracks          []SimulatedTrackState `json:"tracks"`
+}
+
+type SimulatedTrackState struct {
+	TrackID     livekit.TrackID   `json:"track_id"`
+	TargetLayer buffer.VideoLayer `json:"target_layer"`
+	Bandwidth   int64             `json:"bandwidth"`
+	Paused      bool              `json:"paused,omitempty"`
+}
+
+func NewSimulator(params SimulatorParams) *Simulator {
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+
+	// any fixed time, results are relative to it
+	start := time.Unix(1_000_000_000, 0)
+	s := &Simulator{
+		params: params,
+		clock:  utils.NewSimulatedClock(start),
+		start:  start,
+		tracks: make(map[livekit.TrackID]*simulatedTrack),
+		paused: make(map[livekit.TrackID]bool),
+	}
+	s.allocator = NewStreamAllocator(StreamAllocatorParams{
+		Config: params.Config,
+		Logger: params.Logger,
+		Clock:  s.clock,
+	})
+	s.allocator.prober.stepped = true
+	s.allocator.OnStreamStateChange(s.onStreamStateChange)
+	s.nextPingAt = start.Add(PeriodicPingInterval)
+	return s
+}
+
+// Run replays the events, which have to be ordered by time, and keeps running for the given duration after the last
+func (s *Simulator) Run(events []TraceEvent, tail time.Duration) *SimulationResult {
+	for _, event := range events {
+		s.advanceTo(s.start.Add(time.Duration(event.At) * time.Millisecond))
+		s.apply(event)
+		s.process()
+	}
+	s.advanceTo(s.clock.Now().Add(tail))
+
+	s.allocator.Stop()
+	return &SimulationResult{
+		Steps: s.steps,
+	}
+}
+
+func (s *Simulator) advanceTo(at time.Time) {
+	for {
+		next := s.nextPingAt
+		isProbe := false
+		if !s.nextProbeAt.IsZero() && s.nextProbeAt.Before(next) {
+			next = s.nextProbeAt
+			isProbe = true
+		}
+		if next.After(at) {
+			break
+		}
+
+		s.clock.AdvanceTo(next)
+		if isProbe {
+			s.nextProbeAt = time.Time{}
+			if sleepDuration, ok := s.allocator.prober.Step(); ok {
+				s.nextProbeAt = next.Add(sleepDuration)
+			}
+		} else {
+			s.nextPingAt = next.Add(PeriodicPingInterval)
+			s.allocator.postEvent(Event{
+				Signal: streamAllocatorSignalPeriodicPing,
+			})
+		}
+		s.process()
+	}
+
+	s.clock.AdvanceTo(at)
+}
+
+func (s *Simulator) apply(event TraceEvent) {
+	track := s.tracks[event.TrackID]
+
+	switch event.Type {
+	case TraceEventAddTrack:
+		track = newSimulatedTrack(event.TrackID, uint32(len(s.tracks)+1), s.clock, s.params.Logger)
+		track.onResume = s.onResume
+		s.tracks[event.TrackID] = track
+		s.allocator.addTrack(track, AddTrackParams{
+			Source:      event.Source,
+			Priority:    event.Priority,
+			IsSimulcast: event.IsSimulcast,
+		})
+		s.allocator.maybePostEventAllocateTrack(event.TrackID)
+
+	case TraceEventRemoveTrack:
+		if track != nil {
+			s.allocator.removeTrack(track)
+			delete(s.tracks, event.TrackID)
+			delete(s.paused, event.TrackID)
+		}
+
+	case TraceEventPriority:
+		s.allocator.setTrackPriority(event.TrackID, event.Priority)
+
+	case TraceEventLayers:
+		if track != nil && event.Bitrates != nil {
+			track.setLayers(event.AvailableLayers, *event.Bitrates)
+			s.allocator.maybePostEventAllocateTrack(event.TrackID)
+		}
+
+	case TraceEventMaxLayer:
+		if track != nil && event.MaxLayer != nil {
+			track.forwarder.SetMaxSpatialLayer(event.MaxLayer.Spatial)
+			track.forwarder.SetMaxTemporalLayer(event.MaxLayer.Temporal)
+			s.allocator.onSubscribedLayerChanged(event.TrackID, *event.MaxLayer)
+		}
+
+	case TraceEventAllowPause:
+		s.allocator.postEvent(Event{
+			Signal: streamAllocatorSignalSetAllowPause,
+			Data:   event.AllowPause,
+		})
+
+	case TraceEventEstimate:
+		s.allocator.postEvent(Event{
+			Signal: streamAllocatorSignalEstimate,
+			Data:   event.Estimate,
+		})
+
+	case TraceEventNACKStats:
+		if track != nil {
+			track.packets = event.Packets
+			track.repeatedNacks = event.RepeatedNACKs
+		}
+
+	case TraceEventNACK:
+		s.allocator.postEvent(Event{
+			Signal:  streamAllocatorSignalNACK,
+			TrackID: event.TrackID,
+			Data:    event.NACKs,
+		})
+
+	case TraceEventReceiverReport:
+		if event.ReceiverReport != nil {
+			s.allocator.postEvent(Event{
+				Signal:  streamAllocatorSignalRTCPReceiverReport,
+				TrackID: event.TrackID,
+				Data:    *event.ReceiverReport,
+			})
+		}
+	}
+}
+
+// process handles the queued events, including the ones they queue, and records the resulting state
+func (s *Simulator) process() {
+	for {
+		select {
+		case event := <-s.allocator.eventCh:
+			s.allocator.handleEvent(&event)
+			continue
+		default:
+		}
+
+		// tracks switch layers, which could resume them and queue more events
+		for _, track := range s.tracks {
+			track.forwardToTarget()
+		}
+		if len(s.allocator.eventCh) == 0 {
+			break
+		}
+	}
+
+	if s.nextProbeAt.IsZero() && s.allocator.prober.IsRunning() {
+		// the prober starts a cluster and sleeps before processing it
+		if cluster := s.allocator.prober.getFrontCluster(); cluster != nil {
+			s.nextProbeAt = s.clock.Now().Add(cluster.GetSleepDuration())
+		}
+	}
+
+	s.recordStep()
+}
+
+func (s *Simulator) recordStep() {
+	step := SimulationStep{
+		At:              s.clock.Now().Sub(s.start).Milliseconds(),
+		State:           s.allocator.state.String(),
+		ChannelCapacity: s.allocator.committedChannelCapacity,
+	}
+	for _, track := range s.allocator.getTracks() {
+		st := s.tracks[track.ID()]
+		step.Tracks = append(step.Tracks, SimulatedTrackState{
+			TrackID:     track.ID(),
+			TargetLayer: st.forwarder.TargetLayer(),
+			Bandwidth:   st.bandwidth,
+			Paused:      s.paused[track.ID()],
+		})
+	}
+
+	if len(s.steps) != 0 {
+		last := s.steps[len(s.steps)-1]
+		last.At = step.At
+		if reflect.DeepEqual(last, step) {
+			return
+		}
+	}
+	s.steps = append(s.steps, step)
+}
+
+func (s *Simulator) onResume(track *simulatedTrack) {
+	s.allocator.postEvent(Event{
+		Signal:  streamAllocatorSignalResume,
+		TrackID: track.id,
+	})
+}
+
+func (s *Simulator) onStreamStateChange(update *StreamStateUpdate) error {
+	for _, state := range update.StreamStates {
+		s.paused[state.TrackID] = state.State == StreamStatePaused
+	}
+	return nil
+}
+
+// ------------------------------------------------
+
+// simulatedTrack allocates with the forwarder of a down track, and sends what it is allocated
+type simulatedTrack struct {
+	id        livekit.TrackID
+	ssrc      uint32
+	clock     utils.Clock
+	forwarder *sfu.Forwarder
+	onResume  func(track *simulatedTrack)
+
+	availableLayers []int32
+	bitrates        sfu.Bitrates
+
+	isForwarding bool
+	sn           [buffer.DefaultMaxLayerSpatial + 1]uint16
+	ts           uint32
+	// bits per second of the current allocation
+	bandwidth    int64
+	bytesSent    uint32
+	bytesSentAt  time.Time
+	paddingBytes uint32
+
+	packets       uint32
+	repeatedNacks uint32
+}
+
+func newSimulatedTrack(id livekit.TrackID, ssrc uint32, clock utils.Clock, logger logger.Logger) *simulatedTrack {
+	f := sfu.NewForwarder(webrtc.RTPCodecTypeVideo, logger, nil, nil)
+	f.SetClock(clock)
+	f.DetermineCodec(simulatedCodec, nil)
+	f.SetMaxSpatialLayer(buffer.DefaultMaxLayerSpatial)
+	f.SetMaxTemporalLayer(buffer.DefaultMaxLayerTemporal)
+
+	return &simulatedTrack{
+		id:          id,
+		ssrc:        ssrc,
+		clock:       clock,
+		forwarder:   f,
+		bytesSentAt: clock.Now(),
+	}
+}
+
+func (t *simulatedTrack) setLayers(availableLayers []int32, bitrates sfu.Bitrates) {
+	t.availableLayers = availableLayers
+	t.bitrates = bitrates
+
+	maxSpatial := buffer.InvalidLayerSpatial
+	for _, layer := range availableLayers {
+		if layer > maxSpatial {
+			maxSpatial = layer
+		}
+	}
+	if maxSpatial != buffer.InvalidLayerSpatial {
+		t.forwarder.SetMaxPublishedLayer(maxSpatial)
+	}
+
+	maxTemporal := buffer.InvalidLayerTemporal
+	for _, spatial := range bitrates {
+		for temporal, bitrate := range spatial {
+			if bitrate != 0 && int32(temporal) > maxTemporal {
+				maxTemporal = int32(temporal)
+			}
+		}
+	}
+	if maxTemporal != buffer.InvalidLayerTemporal {
+		t.forwarder.SetMaxTemporalLayerSeen(maxTemporal)
+	}
+}
+
+func (t *simulatedTrack) setAllocation(allocation sfu.VideoAllocation) sfu.VideoAllocation {
+	t.accumulateBytesSent()
+	t.bandwidth = allocation.BandwidthRequested
+
+	return allocation
+}
+
+// forwardToTarget switches the forwarder to its target layer with a key frame on the target spatial layer and a
+// layer sync frame on the target temporal layer, as if the publisher answered the key frame request right away
+func (t *simulatedTrack) forwardToTarget() {
+	target := t.forwarder.TargetLayer()
+	if target.IsValid() && target.Spatial != t.forwarder.CurrentLayer().Spatial {
+		t.forward(target, true)
+	}
+	if target.IsValid() && target.Temporal != t.forwarder.CurrentLayer().Temporal {
+		t.forward(target, false)
+	}
+
+	// a down track resumes on the first packet it forwards after a pause
+	isForwarding := t.forwarder.CurrentLayer().IsValid()
+	if isForwarding && !t.isForwarding && t.onResume != nil {
+		t.onResume(t)
+	}
+	t.isForwarding = isForwarding
+}
+
+func (t *simulatedTrack) forward(layer buffer.VideoLayer, isKeyFrame bool) {
+	if layer.Spatial < 0 || int(layer.Spatial) >= len(t.sn) {
+		return
+	}
+
+	t.sn[layer.Spatial]++
+	t.ts += 3000
+	packet := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			SequenceNumber: t.sn[layer.Spatial],
+			Timestamp:      t.ts,
+			SSRC:           t.ssrc<<8 | uint32(layer.Spatial),
+		},
+		Payload: make([]byte, 10),
+	}
+	raw, err := packet.Marshal()
+	if err != nil {
+		return
+	}
+
+	_, _ = t.forwarder.GetTranslationParams(&buffer.ExtPacket{
+		VideoLayer: layer,
+		Arrival:    t.clock.Now(),
+		Packet:     packet,
+		Payload: buffer.VP8{
+			FirstByte:  0x10,
+			I:          true,
+			M:          true,
+			PictureID:  t.sn[layer.Spatial] & 0x7fff,
+			T:          true,
+			TID:        uint8(layer.Temporal),
+			Y:          true,
+			S:          true,
+			HeaderSize: 5,
+			IsKeyFrame: isKeyFrame,
+		},
+		KeyFrame:  isKeyFrame,
+		RawPacket: raw,
+	}, layer.Spatial)
+}
+
+func (t *simulatedTrack) accumulateBytesSent() {
+	now := t.clock.Now()
+	t.bytesSent += uint32(float64(t.bandwidth) * now.Sub(t.bytesSentAt).Seconds() / 8)
+	t.bytesSentAt = now
+}
+
+func (t *simulatedTrack) ID() string {
+	return string(t.id)
+}
+
+func (t *simulatedTrack) SSRC() uint32 {
+	return t.ssrc
+}
+
+func (t *simulatedTrack) MaxLayer() buffer.VideoLayer {
+	return t.forwarder.MaxLayer()
+}
+
+func (t *simulatedTrack) CurrentLayer() buffer.VideoLayer {
+	return t.forwarder.CurrentLayer()
+}
+
+func (t *simulatedTrack) LastAllocation() sfu.VideoAllocation {
+	return t.forwarder.LastAllocation()
+}
+
+func (t *simulatedTrack) IsDeficient() bool {
+	return t.forwarder.IsDeficient()
+}
+
+func (t *simulatedTrack) BandwidthRequested() int64 {
+	return t.forwarder.BandwidthRequested(t.bitrates)
+}
+
+func (t *simulatedTrack) DistanceToDesired() float64 {
+	return t.forwarder.DistanceToDesired(t.availableLayers, t.bitrates)
+}
+
+func (t *simulatedTrack) AllocateOptimal(allowOvershoot bool) sfu.VideoAllocation {
+	return t.setAllocation(t.forwarder.AllocateOptimal(t.availableLayers, t.bitrates, allowOvershoot))
+}
+
+func (t *simulatedTrack) ProvisionalAllocatePrepare() {
+	t.forwarder.ProvisionalAllocatePrepare(t.availableLayers, t.bitrates)
+}
+
+func (t *simulatedTrack) ProvisionalAllocate(availableChannelCapacity int64, layers buffer.VideoLayer, allowPause bool, allowOvershoot bool) int64 {
+	return t.forwarder.ProvisionalAllocate(availableChannelCapacity, layers, allowPause, allowOvershoot)
+}
+
+func (t *simulatedTrack) ProvisionalAllocateGetCooperativeTransition(allowOvershoot bool) sfu.VideoTransition {
+	return t.forwarder.ProvisionalAllocateGetCooperativeTransition(allowOvershoot)
+}
+
+func (t *simulatedTrack) ProvisionalAllocateGetBestWeightedTransition() sfu.VideoTransition {
+	return t.forwarder.ProvisionalAllocateGetBestWeightedTransition()
+}
+
+func (t *simulatedTrack) ProvisionalAllocateCommit() sfu.VideoAllocation {
+	return t.setAllocation(t.forwarder.ProvisionalAllocateCommit())
+}
+
+func (t *simulatedTrack) AllocateNextHigher(availableChannelCapacity int64, allowOvershoot bool) (sfu.VideoAllocation, bool) {
+	allocation, available := t.forwarder.AllocateNextHigher(availableChannelCapacity, t.availableLayers, t.bitrates, allowOvershoot)
+	return t.setAllocation(allocation), available
+}
+
+func (t *simulatedTrack) GetNextHigherTransition(allowOvershoot bool) (sfu.VideoTransition, bool) {
+	return t.forwarder.GetNextHigherTransition(t.bitrates, allowOvershoot)
+}
+
+func (t *simulatedTrack) Pause() sfu.VideoAllocation {
+	return t.setAllocation(t.forwarder.Pause(t.availableLayers, t.bitrates))
+}
+
+func (t *simulatedTrack) WritePaddingRTP(bytesToSend int, _ bool) int {
+	t.paddingBytes += uint32(bytesToSend)
+	return bytesToSend
+}
+
+func (t *simulatedTrack) WriteProbePaddingRTP(bytesToSend int) int {
+	return t.WritePaddingRTP(bytesToSend, false)
+}
+
+func (t *simulatedTrack) GetNackStats() (uint32, uint32) {
+	return t.packets, t.repeatedNacks
+}
+
+func (t *simulatedTrack) GetAndResetBytesSent() (uint32, uint32) {
+	t.accumulateBytesSent()
+	bytesSent := t.bytesSent + t.paddingBytes
+	t.bytesSent = 0
+	t.paddingBytes = 0
+	return bytesSent, 0
+}
+
+func (t *simulatedTrack) SetStreamAllocatorReportInterval(_ time.Duration) {}
+
+func (t *simulatedTrack) ClearStreamAllocatorReportInterval() {}
@@ -0,0 +1,281 @@
+package streamallocator
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+var updateGolden = flag.Bool("update", false, "update the expected simulation results in testdata")
+
+var simulatorConfig = config.CongestionControlConfig{
+	Enabled:    true,
+	AllowPause: true,
+	ProbeMode:  config.CongestionControlProbeModePadding,
+}
+
+func simulate(t *testing.T, tracePath string) *SimulationResult {
+	f, err := os.Open(tracePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	events, err := ReadTrace(f)
+	require.NoError(t, err)
+
+	sim := NewSimulator(SimulatorParams{
+		Config: simulatorConfig,
+		Logger: logger.GetLogger(),
+	})
+	return sim.Run(events, 10*time.Second)
+}
+
+// TestSimulatorTraces replays the recorded traces and compares the allocations with the expected ones, a change in
+// allocator behaviour shows up as a diff of the golden file. Run with -update to accept it
+func TestSimulatorTraces(t *testing.T) {
+	traces, err := filepath.Glob("testdata/*.jsonl")
+	require.NoError(t, err)
+	require.NotEmpty(t, traces)
+
+	for _, tracePath := range traces {
+		tracePath := tracePath
+		t.Run(filepath.Base(tracePath), func(t *testing.T) {
+			result := simulate(t, tracePath)
+
+			// replays are reproducible
+			require.Equal(t, result, simulate(t, tracePath))
+
+			actual, err := json.MarshalIndent(result, "", "  ")
+			require.NoError(t, err)
+
+			goldenPath := strings.TrimSuffix(tracePath, ".jsonl") + ".golden.json"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, append(actual, '\n'), 0644))
+				return
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			require.NoError(t, err, "run with -update to create the expected results")
+			require.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestSimulatorCongestion(t *testing.T) {
+	result := simulate(t, "testdata/congestion.jsonl")
+
+	var wentDeficient, cameraPaused, recovered bool
+	for _, step := range result.Steps {
+		if len(step.Tracks) != 2 {
+			// tracks are being added
+			continue
+		}
+		camera, screen := step.Tracks[0], step.Tracks[1]
+		require.Equal(t, livekit.TrackID("TR_camera"), camera.TrackID)
+		require.Equal(t, livekit.TrackID("TR_screen"), screen.TrackID)
+
+		if step.State == streamAllocatorStateDeficient.String() {
+			wentDeficient = true
+		}
+		if camera.Paused {
+			cameraPaused = true
+			// pausing the camera keeps the screen share going
+			require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 2}, screen.TargetLayer)
+		}
+		if cameraPaused && step.ChannelCapacity == 3_000_000 && camera.TargetLayer.Spatial > 0 {
+			recovered = true
+		}
+	}
+	require.True(t, wentDeficient)
+	require.True(t, cameraPaused)
+	// probes find the recovered channel capacity and the camera is upgraded again
+	require.True(t, recovered)
+}
+
+func TestTraceRecorder(t *testing.T) {
+	clock := utils.NewSimulatedClock(time.Now())
+	var buf bytes.Buffer
+	recorder := NewTraceRecorder(&buf, clock, logger.GetLogger())
+
+	recorder.Record(TraceEvent{
+		Type:        TraceEventAddTrack,
+		TrackID:     "TR_video",
+		Source:      livekit.TrackSource_CAMERA,
+		IsSimulcast: true,
+	})
+	clock.Advance(250 * time.Millisecond)
+	recorder.Record(TraceEvent{
+		Type:     TraceEventEstimate,
+		Estimate: 1_000_000,
+	})
+	clock.Advance(50 * time.Millisecond)
+	recorder.Record(TraceEvent{
+		Type:    TraceEventNACK,
+		TrackID: "TR_video",
+		NACKs:   []sfu.NackInfo{{SequenceNumber: 10, Attempts: 2}},
+	})
+
+	// a nil recorder does nothing
+	var nilRecorder *TraceRecorder
+	nilRecorder.Record(TraceEvent{Type: TraceEventEstimate})
+
+	events, err := ReadTrace(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []TraceEvent{
+		{
+			At:          0,
+			Type:        TraceEventAddTrack,
+			TrackID:     "TR_video",
+			Source:      livekit.TrackSource_CAMERA,
+			IsSimulcast: true,
+		},
+		{
+			At:       250,
+			Type:     TraceEventEstimate,
+			Estimate: 1_000_000,
+		},
+		{
+			At:      300,
+			Type:    TraceEventNACK,
+			TrackID: "TR_video",
+			NACKs:   []sfu.NackInfo{{SequenceNumber: 10, Attempts: 2}},
+		},
+	}, events)
+}
+
+func TestSimulatorProbeCycle(t *testing.T) {
+	// a screen share needing well below the channel capacity, estimates grow with what is sent
+	events := func(lossFrom int64) []TraceEvent {
+		events := []TraceEvent{
+			{Type: TraceEventAddTrack, TrackID: "TR_screen", Source: livekit.TrackSource_SCREEN_SHARE},
+			{Type: TraceEventLayers, TrackID: "TR_screen", AvailableLayers: []int32{0}, Bitrates: &sfu.Bitrates{{400_000, 600_000, 800_000}}},
+		}
+		for at := int64(100); at <= 30_000; at += 100 {
+			events = append(events, TraceEvent{At: at, Type: TraceEventEstimate, Estimate: 1_000_000 + at*50})
+			if lossFrom != 0 && at >= lossFrom && at%500 == 0 {
+				events = append(events, TraceEvent{
+					At:             at,
+					Type:           TraceEventReceiverReport,
+					TrackID:        "TR_screen",
+					ReceiverReport: &rtcp.ReceptionReport{SSRC: 1, FractionLost: 64},
+				})
+			}
+		}
+		return events
+	}
+	run := func(probeMode config.CongestionControlProbeMode, lossFrom int64) (*Simulator, *SimulationResult) {
+		conf := simulatorConfig
+		conf.ProbeMode = probeMode
+		sim := NewSimulator(SimulatorParams{
+			Config: conf,
+			Logger: logger.GetLogger(),
+		})
+		return sim, sim.Run(events(lossFrom), time.Second)
+	}
+	highestChannelCapacity := func(result *SimulationResult) int64 {
+		highest := int64(0)
+		for _, step := range result.Steps {
+			require.Equal(t, streamAllocatorStateStable.String(), step.State)
+			if step.ChannelCapacity > highest {
+				highest = step.ChannelCapacity
+			}
+		}
+		return highest
+	}
+
+	t.Run("padding probes only deficient allocations", func(t *testing.T) {
+		_, result := run(config.CongestionControlProbeModePadding, 0)
+		require.Zero(t, highestChannelCapacity(result))
+	})
+
+	t.Run("cycles probe stable allocations", func(t *testing.T) {
+		sim, result := run(config.CongestionControlProbeModeBBR, 0)
+		require.Greater(t, highestChannelCapacity(result), int64(1_500_000))
+		require.Equal(t, ProbeCycleInterval, sim.allocator.probeInterval)
+	})
+
+	t.Run("cycles abort and back off on loss", func(t *testing.T) {
+		// the first cycle starts at 10 seconds
+		sim, result := run(config.CongestionControlProbeModeBBR, 10_000)
+		require.Zero(t, highestChannelCapacity(result))
+		require.Equal(t, 2*ProbeCycleInterval, sim.allocator.probeInterval)
+	})
+
+	t.Run("lossy channel is not probed", func(t *testing.T) {
+		sim, result := run(config.CongestionControlProbeModeBBR, 100)
+		require.Zero(t, highestChannelCapacity(result))
+		require.Equal(t, ProbeCycleInterval, sim.allocator.probeInterval)
+	})
+}
+
+func TestSimulatorMaxChannelCapacity(t *testing.T) {
+	cameraLayers := TraceEvent{Type: TraceEventLayers, TrackID: "TR_camera", AvailableLayers: []int32{0, 1, 2}, Bitrates: &sfu.Bitrates{
+		{100_000, 150_000, 200_000},
+		{300_000, 450_000, 600_000},
+		{900_000, 1_300_000, 1_700_000},
+	}}
+
+	for _, enabled := range []bool{true, false} {
+		conf := simulatorConfig
+		conf.Enabled = enabled
+		sim := NewSimulator(SimulatorParams{
+			Config: conf,
+			Logger: logger.GetLogger(),
+		})
+		replay := func(events ...TraceEvent) {
+			for _, event := range events {
+				sim.advanceTo(sim.start.Add(time.Duration(event.At) * time.Millisecond))
+				sim.apply(event)
+				sim.process()
+			}
+		}
+		estimates := func(from int64) []TraceEvent {
+			var events []TraceEvent
+			for at := from; at < from+1000; at += 100 {
+				events = append(events, TraceEvent{At: at, Type: TraceEventEstimate, Estimate: 4_000_000})
+			}
+			return events
+		}
+		setMaxChannelCapacity := func(maxChannelCapacity int64) {
+			sim.allocator.SetMaxChannelCapacity(maxChannelCapacity)
+			sim.process()
+		}
+
+		replay(TraceEvent{Type: TraceEventAddTrack, TrackID: "TR_camera", Source: livekit.TrackSource_CAMERA, IsSimulcast: true}, cameraLayers)
+		replay(estimates(100)...)
+		require.Equal(t, int32(2), sim.allocator.getDiagnostics("TR_camera").Track.TargetLayer.Spatial, "enabled: %v", enabled)
+
+		// a lower cap sheds what is allocated above it
+		setMaxChannelCapacity(500_000)
+		require.LessOrEqual(t, sim.allocator.getExpectedBandwidthUsage(), int64(500_000), "enabled: %v", enabled)
+		camera := sim.allocator.getDiagnostics("TR_camera")
+		require.True(t, strings.HasPrefix(camera.Reason, "limited by channel capacity"), camera.Reason)
+
+		// a track allocated again stays within the cap
+		cameraLayers.At = 1100
+		replay(cameraLayers)
+		require.LessOrEqual(t, sim.allocator.getExpectedBandwidthUsage(), int64(500_000), "enabled: %v", enabled)
+
+		// and gets back up once the cap is lifted
+		setMaxChannelCapacity(0)
+		replay(estimates(1200)...)
+		cameraLayers.At = 2200
+		replay(cameraLayers)
+		require.Equal(t, int32(2), sim.allocator.getDiagnostics("TR_camera").Track.TargetLayer.Spatial, "enabled: %v", enabled)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
 	"go.uber.org/atomic"
@@ -18,6 +17,7 @@ import (
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
 const (
@@ -36,6 +36,21 @@ const (
 	ProbeMinDuration = 20 * time.Second
 	ProbeMaxDuration = 21 * time.Second
 
+	// BBR style probe cycles of the bbr probe mode, run whatever the state of the allocation
+	ProbeCycleInterval      = 10 * time.Second
+	ProbeCycleBackoffFactor = 2.0
+	ProbeCycleIntervalMax   = 60 * time.Second
+	ProbeCycleGain          = 1.25
+	ProbeCycleMinDuration   = 3 * time.Second
+	ProbeCycleMaxDuration   = 4 * time.Second
+	ProbeCycleMinPaddingBps = 100 * 1000       // 100 kbps
+	ProbeCycleMaxPaddingBps = 1000 * 1000      // 1 Mbps
+	ProbeCycleMaxCapacity   = 10 * 1000 * 1000 // 10 Mbps
+	// a probe cycle is aborted when the subscriber reports more loss than this on any track
+	ProbeCycleMaxLoss = 0.05
+
+	PeriodicPingInterval = 500 * time.Millisecond
+
 	PriorityMin                = uint8(1)
 	PriorityMax                = uint8(255)
 	PriorityDefaultScreenshare = PriorityMax
@@ -110,6 +125,9 @@ const (
 	streamAllocatorSignalSetChannelCapacity
 	streamAllocatorSignalNACK
 	streamAllocatorSignalRTCPReceiverReport
+	streamAllocatorSignalSeedChannelCapacity
+	streamAllocatorSignalSetMaxChannelCapacity
+	streamAllocatorSignalDiagnostics
 )
 
 func (s streamAllocatorSignal) String() string {
@@ -138,6 +156,12 @@ func (s streamAllocatorSignal) String() string {
 		return "NACK"
 	case streamAllocatorSignalRTCPReceiverReport:
 		return "RTCP_RECEIVER_REPORT"
+	case streamAllocatorSignalSeedChannelCapacity:
+		return "SEED_CHANNEL_CAPACITY"
+	case streamAllocatorSignalSetMaxChannelCapacity:
+		return "SET_MAX_CHANNEL_CAPACITY"
+	case streamAllocatorSignalDiagnostics:
+		return "DIAGNOSTICS"
 	default:
 		return fmt.Sprintf("%d", int(s))
 	}
@@ -160,20 +184,31 @@ func (e Event) String() string {
 type StreamAllocatorParams struct {
 	Config config.CongestionControlConfig
 	Logger logger.Logger
+	// defaults to the system clock
+	Clock utils.Clock
+	// records the inputs of the allocator when set
+	TraceRecorder *TraceRecorder
 }
 
 type StreamAllocator struct {
 	params StreamAllocatorParams
+	clock  utils.Clock
 
 	onStreamStateChange func(update *StreamStateUpdate) error
+	onLossEstimate      func(ssrc uint32, loss float64)
+	onEstimate          func(estimate int64)
 
-	bwe cc.BandwidthEstimator
+	bwe BandwidthEstimator
 
 	allowPause bool
 
 	lastReceivedEstimate      int64
 	committedChannelCapacity  int64
 	overriddenChannelCapacity int64
+	// cap on the channel capacity allocated, e. g. a share of a room budget, estimation goes on regardless
+	maxChannelCapacity int64
+	// committed channel capacity, readable outside of the event loop
+	channelCapacity atomic.Int64
 
 	probeInterval         time.Duration
 	lastProbeStartTime    time.Time
@@ -202,13 +237,19 @@ type StreamAllocator struct {
 }
 
 func NewStreamAllocator(params StreamAllocatorParams) *StreamAllocator {
+	clock := params.Clock
+	if clock == nil {
+		clock = utils.SystemClock
+	}
 	s := &StreamAllocator{
 		params:     params,
+		clock:      clock,
 		allowPause: params.Config.AllowPause,
 		prober: NewProber(ProberParams{
 			Logger: params.Logger,
+			Clock:  clock,
 		}),
-		rateMonitor: NewRateMonitor(),
+		rateMonitor: NewRateMonitor(clock),
 		videoTracks: make(map[livekit.TrackID]*Track),
 		eventCh:     make(chan Event, 1000),
 	}
@@ -240,7 +281,17 @@ func (s *StreamAllocator) OnStreamStateChange(f func(update *StreamStateUpdate)
 	s.onStreamStateChange = f
 }
 
-func (s *StreamAllocator) SetBandwidthEstimator(bwe cc.BandwidthEstimator) {
+// OnLossEstimate is called with the smoothed loss of a video track whenever the subscriber reports on it
+func (s *StreamAllocator) OnLossEstimate(f func(ssrc uint32, loss float64)) {
+	s.onLossEstimate = f
+}
+
+// OnEstimate is called with each estimate of the channel capacity received, from REMB or the send side estimator
+func (s *StreamAllocator) OnEstimate(f func(estimate int64)) {
+	s.onEstimate = f
+}
+
+func (s *StreamAllocator) SetBandwidthEstimator(bwe BandwidthEstimator) {
 	if bwe != nil {
 		bwe.OnTargetBitrateChange(s.onTargetBitrateChange)
 	}
@@ -259,29 +310,53 @@ func (s *StreamAllocator) AddTrack(downTrack *sfu.DownTrack, params AddTrackPara
 		return
 	}
 
-	track := NewTrack(downTrack, params.Source, params.IsSimulcast, params.PublisherID, s.params.Logger)
+	s.addTrack(downTrack, params)
+	if s.params.TraceRecorder != nil {
+		s.params.TraceRecorder.Record(TraceEvent{
+			Type:        TraceEventAddTrack,
+			TrackID:     livekit.TrackID(downTrack.ID()),
+			Source:      params.Source,
+			IsSimulcast: params.IsSimulcast,
+			Priority:    params.Priority,
+		})
+		s.recordLayers(downTrack)
+	}
+
+	downTrack.SetStreamAllocatorListener(s)
+
+	s.maybePostEventAllocateTrack(livekit.TrackID(downTrack.ID()))
+}
+
+func (s *StreamAllocator) addTrack(downTrack DownTrack, params AddTrackParams) {
+	track := NewTrack(downTrack, params.Source, params.IsSimulcast, params.PublisherID, s.clock, s.params.Logger)
 	track.SetPriority(params.Priority)
 
 	s.videoTracksMu.Lock()
 	s.videoTracks[livekit.TrackID(downTrack.ID())] = track
 	s.videoTracksMu.Unlock()
 
-	downTrack.SetStreamAllocatorListener(s)
 	if s.prober.IsRunning() {
 		// STREAM-ALLOCATOR-TODO: this can be changed to adapt to probe rate
 		downTrack.SetStreamAllocatorReportInterval(50 * time.Millisecond)
 	}
-
-	s.maybePostEventAllocateTrack(downTrack)
 }
 
 func (s *StreamAllocator) RemoveTrack(downTrack *sfu.DownTrack) {
+	s.removeTrack(downTrack)
+}
+
+func (s *StreamAllocator) removeTrack(downTrack DownTrack) {
 	s.videoTracksMu.Lock()
 	if existing := s.videoTracks[livekit.TrackID(downTrack.ID())]; existing != nil && existing.DownTrack() == downTrack {
 		delete(s.videoTracks, livekit.TrackID(downTrack.ID()))
 	}
 	s.videoTracksMu.Unlock()
 
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:    TraceEventRemoveTrack,
+		TrackID: livekit.TrackID(downTrack.ID()),
+	})
+
 	// STREAM-ALLOCATOR-TODO: use any saved bandwidth to re-distribute
 	s.postEvent(Event{
 		Signal: streamAllocatorSignalAdjustState,
@@ -289,8 +364,18 @@ func (s *StreamAllocator) RemoveTrack(downTrack *sfu.DownTrack) {
 }
 
 func (s *StreamAllocator) SetTrackPriority(downTrack *sfu.DownTrack, priority uint8) {
+	s.setTrackPriority(livekit.TrackID(downTrack.ID()), priority)
+}
+
+func (s *StreamAllocator) setTrackPriority(trackID livekit.TrackID, priority uint8) {
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:     TraceEventPriority,
+		TrackID:  trackID,
+		Priority: priority,
+	})
+
 	s.videoTracksMu.Lock()
-	if track := s.videoTracks[livekit.TrackID(downTrack.ID())]; track != nil {
+	if track := s.videoTracks[trackID]; track != nil {
 		changed := track.SetPriority(priority)
 		if changed && !s.isAllocateAllPending {
 			// do a full allocation on a track priority change to keep it simple
@@ -304,6 +389,11 @@ func (s *StreamAllocator) SetTrackPriority(downTrack *sfu.DownTrack, priority ui
 }
 
 func (s *StreamAllocator) SetAllowPause(allowPause bool) {
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:       TraceEventAllowPause,
+		AllowPause: allowPause,
+	})
+
 	s.postEvent(Event{
 		Signal: streamAllocatorSignalSetAllowPause,
 		Data:   allowPause,
@@ -317,6 +407,29 @@ func (s *StreamAllocator) SetChannelCapacity(channelCapacity int64) {
 	})
 }
 
+// SeedChannelCapacity starts allocation from a channel capacity estimated before, e. g. by a previous session of
+// the subscriber, instead of ramping up from nothing. It is ignored once an estimate is committed
+func (s *StreamAllocator) SeedChannelCapacity(channelCapacity int64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSeedChannelCapacity,
+		Data:   channelCapacity,
+	})
+}
+
+// SetMaxChannelCapacity caps the channel capacity tracks are allocated, 0 to remove the cap. Unlike an override,
+// allocation follows the estimate while it is below the cap
+func (s *StreamAllocator) SetMaxChannelCapacity(maxChannelCapacity int64) {
+	s.postEvent(Event{
+		Signal: streamAllocatorSignalSetMaxChannelCapacity,
+		Data:   maxChannelCapacity,
+	})
+}
+
+// GetChannelCapacity returns the committed channel capacity, 0 till there is one
+func (s *StreamAllocator) GetChannelCapacity() int64 {
+	return s.channelCapacity.Load()
+}
+
 func (s *StreamAllocator) resetState() {
 	s.channelObserver = s.newChannelObserverNonProbe()
 	s.resetProbe()
@@ -421,34 +534,48 @@ func (s *StreamAllocator) onTargetBitrateChange(bitrate int) {
 
 // called when feeding track's layer availability changes
 func (s *StreamAllocator) OnAvailableLayersChanged(downTrack *sfu.DownTrack) {
-	s.maybePostEventAllocateTrack(downTrack)
+	s.recordLayers(downTrack)
+	s.maybePostEventAllocateTrack(livekit.TrackID(downTrack.ID()))
 }
 
 // called when feeding track's bitrate measurement of any layer is available
 func (s *StreamAllocator) OnBitrateAvailabilityChanged(downTrack *sfu.DownTrack) {
-	s.maybePostEventAllocateTrack(downTrack)
+	s.recordLayers(downTrack)
+	s.maybePostEventAllocateTrack(livekit.TrackID(downTrack.ID()))
 }
 
 // called when feeding track's max published spatial layer changes
 func (s *StreamAllocator) OnMaxPublishedSpatialChanged(downTrack *sfu.DownTrack) {
-	s.maybePostEventAllocateTrack(downTrack)
+	s.recordLayers(downTrack)
+	s.maybePostEventAllocateTrack(livekit.TrackID(downTrack.ID()))
 }
 
 // called when feeding track's max published temporal layer changes
 func (s *StreamAllocator) OnMaxPublishedTemporalChanged(downTrack *sfu.DownTrack) {
-	s.maybePostEventAllocateTrack(downTrack)
+	s.recordLayers(downTrack)
+	s.maybePostEventAllocateTrack(livekit.TrackID(downTrack.ID()))
 }
 
 // called when subscription settings changes (muting/unmuting of track)
 func (s *StreamAllocator) OnSubscriptionChanged(downTrack *sfu.DownTrack) {
-	s.maybePostEventAllocateTrack(downTrack)
+	s.maybePostEventAllocateTrack(livekit.TrackID(downTrack.ID()))
 }
 
 // called when subscribed layer changes (limiting max layer)
 func (s *StreamAllocator) OnSubscribedLayerChanged(downTrack *sfu.DownTrack, layer buffer.VideoLayer) {
+	s.onSubscribedLayerChanged(livekit.TrackID(downTrack.ID()), layer)
+}
+
+func (s *StreamAllocator) onSubscribedLayerChanged(trackID livekit.TrackID, layer buffer.VideoLayer) {
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:     TraceEventMaxLayer,
+		TrackID:  trackID,
+		MaxLayer: &layer,
+	})
+
 	shouldPost := false
 	s.videoTracksMu.Lock()
-	if track := s.videoTracks[livekit.TrackID(downTrack.ID())]; track != nil {
+	if track := s.videoTracks[trackID]; track != nil {
 		if track.SetMaxLayer(layer) && track.SetDirty(true) {
 			shouldPost = true
 		}
@@ -458,7 +585,7 @@ func (s *StreamAllocator) OnSubscribedLayerChanged(downTrack *sfu.DownTrack, lay
 	if shouldPost {
 		s.postEvent(Event{
 			Signal:  streamAllocatorSignalAllocateTrack,
-			TrackID: livekit.TrackID(downTrack.ID()),
+			TrackID: trackID,
 		})
 	}
 }
@@ -523,10 +650,10 @@ func (s *StreamAllocator) OnActiveChanged(isActive bool) {
 	}
 }
 
-func (s *StreamAllocator) maybePostEventAllocateTrack(downTrack *sfu.DownTrack) {
+func (s *StreamAllocator) maybePostEventAllocateTrack(trackID livekit.TrackID) {
 	shouldPost := false
 	s.videoTracksMu.Lock()
-	if track := s.videoTracks[livekit.TrackID(downTrack.ID())]; track != nil {
+	if track := s.videoTracks[trackID]; track != nil {
 		if track.SetDirty(true) {
 			shouldPost = true
 		}
@@ -536,11 +663,25 @@ func (s *StreamAllocator) maybePostEventAllocateTrack(downTrack *sfu.DownTrack)
 	if shouldPost {
 		s.postEvent(Event{
 			Signal:  streamAllocatorSignalAllocateTrack,
-			TrackID: livekit.TrackID(downTrack.ID()),
+			TrackID: trackID,
 		})
 	}
 }
 
+func (s *StreamAllocator) recordLayers(downTrack *sfu.DownTrack) {
+	if s.params.TraceRecorder == nil {
+		return
+	}
+
+	availableLayers, bitrates := downTrack.Receiver().GetLayeredBitrate()
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:            TraceEventLayers,
+		TrackID:         livekit.TrackID(downTrack.ID()),
+		AvailableLayers: availableLayers,
+		Bitrates:        &bitrates,
+	})
+}
+
 func (s *StreamAllocator) postEvent(event Event) {
 	s.eventChMu.RLock()
 	if s.isStopped.Load() {
@@ -565,7 +706,7 @@ func (s *StreamAllocator) processEvents() {
 }
 
 func (s *StreamAllocator) ping() {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := time.NewTicker(PeriodicPingInterval)
 	defer ticker.Stop()
 
 	for {
@@ -606,6 +747,12 @@ func (s *StreamAllocator) handleEvent(event *Event) {
 		s.handleSignalNACK(event)
 	case streamAllocatorSignalRTCPReceiverReport:
 		s.handleSignalRTCPReceiverReport(event)
+	case streamAllocatorSignalSeedChannelCapacity:
+		s.handleSignalSeedChannelCapacity(event)
+	case streamAllocatorSignalSetMaxChannelCapacity:
+		s.handleSignalSetMaxChannelCapacity(event)
+	case streamAllocatorSignalDiagnostics:
+		s.handleSignalDiagnostics(event)
 	}
 }
 
@@ -638,8 +785,26 @@ func (s *StreamAllocator) handleSignalAdjustState(event *Event) {
 
 func (s *StreamAllocator) handleSignalEstimate(event *Event) {
 	receivedEstimate, _ := event.Data.(int64)
+	if s.params.TraceRecorder != nil {
+		for _, track := range s.getTracks() {
+			packets, repeatedNacks := track.DownTrack().GetNackStats()
+			s.params.TraceRecorder.Record(TraceEvent{
+				Type:          TraceEventNACKStats,
+				TrackID:       track.ID(),
+				Packets:       packets,
+				RepeatedNACKs: repeatedNacks,
+			})
+		}
+		s.params.TraceRecorder.Record(TraceEvent{
+			Type:     TraceEventEstimate,
+			Estimate: receivedEstimate,
+		})
+	}
 	s.lastReceivedEstimate = receivedEstimate
 	s.monitorRate(receivedEstimate)
+	if s.onEstimate != nil {
+		s.onEstimate(receivedEstimate)
+	}
 
 	// while probing, maintain estimate separately to enable keeping current committed estimate if probe fails
 	if s.isInProbe() {
@@ -651,12 +816,12 @@ func (s *StreamAllocator) handleSignalEstimate(event *Event) {
 
 func (s *StreamAllocator) handleSignalPeriodicPing(event *Event) {
 	// finalize probe if necessary
-	if s.isInProbe() && !s.probeEndTime.IsZero() && time.Now().After(s.probeEndTime) {
+	if s.isInProbe() && !s.probeEndTime.IsZero() && s.clock.Now().After(s.probeEndTime) {
 		s.finalizeProbe()
 	}
 
-	// probe if necessary and timing is right
-	if s.state == streamAllocatorStateDeficient {
+	// probe if necessary and timing is right, probe cycles do not wait for an allocation to be deficient
+	if s.state == streamAllocatorStateDeficient || s.params.Config.ProbeMode == config.CongestionControlProbeModeBBR {
 		s.maybeProbe()
 	}
 
@@ -671,7 +836,12 @@ func (s *StreamAllocator) handleSignalSendProbe(event *Event) {
 
 	bytesSent := 0
 	for _, track := range s.getTracks() {
-		sent := track.WritePaddingRTP(bytesToSend)
+		var sent int
+		if s.params.Config.ProbeMode == config.CongestionControlProbeModeBBR {
+			sent = track.WriteProbePaddingRTP(bytesToSend)
+		} else {
+			sent = track.WritePaddingRTP(bytesToSend)
+		}
 		bytesSent += sent
 		bytesToSend -= sent
 		if bytesToSend <= 0 {
@@ -733,11 +903,58 @@ func (s *StreamAllocator) handleSignalSetChannelCapacity(event *Event) {
 		s.allocateAllTracks()
 	} else {
 		s.params.Logger.Infow("clearing  override channel capacity")
+		// back to the committed channel capacity
+		s.allocateAllTracks()
+	}
+}
+
+func (s *StreamAllocator) handleSignalSeedChannelCapacity(event *Event) {
+	channelCapacity := event.Data.(int64)
+	if channelCapacity <= 0 || s.committedChannelCapacity != 0 {
+		return
+	}
+
+	s.params.Logger.Infow("stream allocator: seeding channel capacity", "capacity(bps)", channelCapacity)
+	s.commitChannelCapacity(channelCapacity)
+	s.allocateAllTracks()
+}
+
+func (s *StreamAllocator) handleSignalSetMaxChannelCapacity(event *Event) {
+	maxChannelCapacity := event.Data.(int64)
+	if maxChannelCapacity < 0 {
+		maxChannelCapacity = 0
+	}
+	if maxChannelCapacity == s.maxChannelCapacity {
+		return
+	}
+
+	s.params.Logger.Debugw("stream allocator: setting max channel capacity", "old(bps)", s.maxChannelCapacity, "new(bps)", maxChannelCapacity)
+	raised := s.maxChannelCapacity > 0 && (maxChannelCapacity == 0 || maxChannelCapacity > s.maxChannelCapacity)
+	s.maxChannelCapacity = maxChannelCapacity
+
+	// a budget share moves a little on every division, tracks are allocated again when what they use does not fit
+	// in the cap anymore or when deficient tracks may use more
+	switch {
+	case maxChannelCapacity > 0 && s.getExpectedBandwidthUsage() > maxChannelCapacity:
+		s.allocateAllTracks()
+
+	case raised && s.state == streamAllocatorStateDeficient:
+		if maxChannelCapacity == 0 && (!s.params.Config.Enabled || s.getAllocatableChannelCapacity() == 0) {
+			// without the cap, nothing constrains the tracks
+			s.allocateAllTracksOptimal()
+		} else {
+			s.allocateAllTracks()
+		}
 	}
 }
 
 func (s *StreamAllocator) handleSignalNACK(event *Event) {
 	nackInfos := event.Data.([]sfu.NackInfo)
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:    TraceEventNACK,
+		TrackID: event.TrackID,
+		NACKs:   nackInfos,
+	})
 
 	s.videoTracksMu.Lock()
 	track := s.videoTracks[event.TrackID]
@@ -750,6 +967,11 @@ func (s *StreamAllocator) handleSignalNACK(event *Event) {
 
 func (s *StreamAllocator) handleSignalRTCPReceiverReport(event *Event) {
 	rr := event.Data.(rtcp.ReceptionReport)
+	s.params.TraceRecorder.Record(TraceEvent{
+		Type:           TraceEventReceiverReport,
+		TrackID:        event.TrackID,
+		ReceiverReport: &rr,
+	})
 
 	s.videoTracksMu.Lock()
 	track := s.videoTracks[event.TrackID]
@@ -757,6 +979,22 @@ func (s *StreamAllocator) handleSignalRTCPReceiverReport(event *Event) {
 
 	if track != nil {
 		track.ProcessRTCPReceiverReport(rr)
+		if s.onLossEstimate != nil {
+			s.onLossEstimate(rr.SSRC, track.LossEstimate())
+		}
+
+		if s.params.Config.ProbeMode == config.CongestionControlProbeModeBBR &&
+			s.isInProbe() &&
+			s.abortedProbeClusterId == ProbeClusterIdInvalid &&
+			track.LossEstimate() > ProbeCycleMaxLoss {
+			s.params.Logger.Infow(
+				"stream allocator: probe: aborting, loss",
+				"cluster", s.probeClusterId,
+				"trackID", track.ID(),
+				"loss", track.LossEstimate(),
+			)
+			s.abortProbe()
+		}
 	}
 }
 
@@ -769,7 +1007,7 @@ func (s *StreamAllocator) setState(state streamAllocatorState) {
 	s.state = state
 
 	// reset probe to enforce a delay after state change before probing
-	s.lastProbeStartTime = time.Now()
+	s.lastProbeStartTime = s.clock.Now()
 }
 
 func (s *StreamAllocator) adjustState() {
@@ -801,7 +1039,7 @@ func (s *StreamAllocator) handleNewEstimateInProbe() {
 	}
 
 	switch {
-	case !s.probeTrendObserved && time.Since(s.lastProbeStartTime) > ProbeTrendWait:
+	case !s.probeTrendObserved && s.clock.Now().Sub(s.lastProbeStartTime) > ProbeTrendWait:
 		//
 		// More of a safety net.
 		// In rare cases, the estimate gets stuck. Prevent from probe running amok
@@ -866,7 +1104,7 @@ func (s *StreamAllocator) handleNewEstimateInNonProbe() {
 		"nackHistory", s.channelObserver.GetNackHistory(),
 		"trackHistory", s.getTracksHistory(),
 	)
-	s.committedChannelCapacity = estimateToCommit
+	s.commitChannelCapacity(estimateToCommit)
 
 	// reset to get new set of samples for next trend
 	s.channelObserver = s.newChannelObserverNonProbe()
@@ -877,10 +1115,21 @@ func (s *StreamAllocator) handleNewEstimateInNonProbe() {
 	s.allocateAllTracks()
 }
 
+func (s *StreamAllocator) commitChannelCapacity(channelCapacity int64) {
+	s.committedChannelCapacity = channelCapacity
+	s.channelCapacity.Store(channelCapacity)
+}
+
 func (s *StreamAllocator) allocateTrack(track *Track) {
 	// abort any probe that may be running when a track specific change needs allocation
 	s.abortProbe()
 
+	// an optimal allocation could go past the cap, all tracks are allocated within it
+	if s.maxChannelCapacity > 0 && track.IsManaged() {
+		s.allocateAllTracks()
+		return
+	}
+
 	// if not deficient, free pass allocate track
 	if !s.params.Config.Enabled || s.state == streamAllocatorStateStable || !track.IsManaged() {
 		update := NewStreamStateUpdate()
@@ -938,16 +1187,35 @@ func (s *StreamAllocator) allocateTrack(track *Track) {
 		t.ProvisionalAllocatePrepare()
 	}
 
+	// tracks that can give back bits without a key frame go first, a key frame is a burst of bits on a congested channel
+	var keyFrameContributors []*Track
+	var keyFrameTransitions []sfu.VideoTransition
 	for _, t := range minDistanceSorted {
+		if bandwidthAcquired >= transition.BandwidthDelta {
+			break
+		}
+
 		tx := t.ProvisionalAllocateGetBestWeightedTransition()
-		if tx.BandwidthDelta < 0 {
-			contributingTracks = append(contributingTracks, t)
+		if tx.BandwidthDelta >= 0 {
+			continue
+		}
 
-			bandwidthAcquired += -tx.BandwidthDelta
-			if bandwidthAcquired >= transition.BandwidthDelta {
-				break
-			}
+		if tx.NeedsKeyFrame {
+			keyFrameContributors = append(keyFrameContributors, t)
+			keyFrameTransitions = append(keyFrameTransitions, tx)
+			continue
 		}
+
+		contributingTracks = append(contributingTracks, t)
+		bandwidthAcquired += -tx.BandwidthDelta
+	}
+	for i, t := range keyFrameContributors {
+		if bandwidthAcquired >= transition.BandwidthDelta {
+			break
+		}
+
+		contributingTracks = append(contributingTracks, t)
+		bandwidthAcquired += -keyFrameTransitions[i].BandwidthDelta
 	}
 
 	update := NewStreamStateUpdate()
@@ -1010,7 +1278,7 @@ func (s *StreamAllocator) finalizeProbe() {
 		"old(bps)", s.committedChannelCapacity,
 		"new(bps)", highestEstimateInProbe,
 	)
-	s.committedChannelCapacity = highestEstimateInProbe
+	s.commitChannelCapacity(highestEstimateInProbe)
 
 	s.maybeBoostDeficientTracks()
 }
@@ -1025,6 +1293,9 @@ func (s *StreamAllocator) maybeBoostDeficientTracks() {
 			"override", committedChannelCapacity,
 		)
 	}
+	if s.maxChannelCapacity > 0 && committedChannelCapacity > s.maxChannelCapacity {
+		committedChannelCapacity = s.maxChannelCapacity
+	}
 	availableChannelCapacity := committedChannelCapacity - s.getExpectedBandwidthUsage()
 	if availableChannelCapacity <= 0 {
 		return
@@ -1054,8 +1325,8 @@ func (s *StreamAllocator) maybeBoostDeficientTracks() {
 }
 
 func (s *StreamAllocator) allocateAllTracks() {
-	if !s.params.Config.Enabled {
-		// nothing else to do when disabled
+	if !s.params.Config.Enabled && s.maxChannelCapacity == 0 {
+		// nothing else to do when disabled, unless capped
 		return
 	}
 
@@ -1073,21 +1344,14 @@ func (s *StreamAllocator) allocateAllTracks() {
 	//
 	update := NewStreamStateUpdate()
 
-	availableChannelCapacity := s.committedChannelCapacity
-	if s.params.Config.MinChannelCapacity > availableChannelCapacity {
-		availableChannelCapacity = s.params.Config.MinChannelCapacity
-		s.params.Logger.Debugw(
-			"stream allocator: overriding channel capacity with min channel capacity",
-			"actual", s.committedChannelCapacity,
-			"override", availableChannelCapacity,
-		)
-	}
-	if s.overriddenChannelCapacity > 0 {
-		availableChannelCapacity = s.overriddenChannelCapacity
+	availableChannelCapacity := s.getAllocatableChannelCapacity()
+	if availableChannelCapacity != s.committedChannelCapacity {
 		s.params.Logger.Debugw(
 			"stream allocator: overriding channel capacity",
 			"actual", s.committedChannelCapacity,
 			"override", availableChannelCapacity,
+			"min", s.params.Config.MinChannelCapacity,
+			"max", s.maxChannelCapacity,
 		)
 	}
 
@@ -1161,6 +1425,42 @@ func (s *StreamAllocator) allocateAllTracks() {
 	s.adjustState()
 }
 
+// allocateAllTracksOptimal gives every track the allocation it gets when nothing constrains it
+func (s *StreamAllocator) allocateAllTracksOptimal() {
+	update := NewStreamStateUpdate()
+	for _, track := range s.getTracks() {
+		allocation := track.AllocateOptimal(FlagAllowOvershootWhileOptimal)
+		if allocation.PauseReason == sfu.VideoPauseReasonBandwidth && track.SetPaused(true) {
+			update.HandleStreamingChange(true, track)
+		}
+	}
+	s.maybeSendUpdate(update)
+
+	s.adjustState()
+}
+
+// getAllocatableChannelCapacity returns the channel capacity tracks are allocated from, the committed one unless
+// raised to the configured minimum, overridden or capped
+func (s *StreamAllocator) getAllocatableChannelCapacity() int64 {
+	if !s.params.Config.Enabled {
+		// estimates are not followed when disabled, only the cap
+		return s.maxChannelCapacity
+	}
+
+	channelCapacity := s.committedChannelCapacity
+	if s.params.Config.MinChannelCapacity > channelCapacity {
+		channelCapacity = s.params.Config.MinChannelCapacity
+	}
+	if s.overriddenChannelCapacity > 0 {
+		channelCapacity = s.overriddenChannelCapacity
+	}
+	// till there is an estimate, tracks are allocated up to the cap
+	if s.maxChannelCapacity > 0 && (channelCapacity == 0 || channelCapacity > s.maxChannelCapacity) {
+		channelCapacity = s.maxChannelCapacity
+	}
+	return channelCapacity
+}
+
 func (s *StreamAllocator) maybeSendUpdate(update *StreamStateUpdate) {
 	if update.Empty() {
 		return
@@ -1203,15 +1503,21 @@ func (s *StreamAllocator) getNackDelta() (uint32, uint32) {
 }
 
 func (s *StreamAllocator) newChannelObserverProbe() *ChannelObserver {
-	return NewChannelObserver(ChannelObserverParamsProbe, s.params.Logger)
+	return NewChannelObserver(ChannelObserverParamsProbe, s.clock, s.params.Logger)
 }
 
 func (s *StreamAllocator) newChannelObserverNonProbe() *ChannelObserver {
-	return NewChannelObserver(ChannelObserverParamsNonProbe, s.params.Logger)
+	return NewChannelObserver(ChannelObserverParamsNonProbe, s.clock, s.params.Logger)
 }
 
 func (s *StreamAllocator) initProbe(probeGoalDeltaBps int64) {
-	s.lastProbeStartTime = time.Now()
+	// overshoot a bit to account for noise (in measurement/estimate etc)
+	probeGoalBps := s.getExpectedBandwidthUsage() + ((probeGoalDeltaBps * ProbePct) / 100)
+	s.startProbe(probeGoalBps, ProbeMinDuration, ProbeMaxDuration)
+}
+
+func (s *StreamAllocator) startProbe(probeGoalBps int64, minDuration time.Duration, maxDuration time.Duration) {
+	s.lastProbeStartTime = s.clock.Now()
 
 	expectedBandwidthUsage := s.getExpectedBandwidthUsage()
 	if float64(expectedBandwidthUsage) > 1.5*float64(s.committedChannelCapacity) {
@@ -1227,8 +1533,7 @@ func (s *StreamAllocator) initProbe(probeGoalDeltaBps int64) {
 			fmt.Errorf("expected too high, expected: %d, committed: %d", expectedBandwidthUsage, s.committedChannelCapacity),
 		)
 	}
-	// overshoot a bit to account for noise (in measurement/estimate etc)
-	s.probeGoalBps = expectedBandwidthUsage + ((probeGoalDeltaBps * ProbePct) / 100)
+	s.probeGoalBps = probeGoalBps
 
 	s.abortedProbeClusterId = ProbeClusterIdInvalid
 
@@ -1247,8 +1552,8 @@ func (s *StreamAllocator) initProbe(probeGoalDeltaBps int64) {
 		ProbeClusterModeUniform,
 		int(s.probeGoalBps),
 		int(expectedBandwidthUsage),
-		ProbeMinDuration,
-		ProbeMaxDuration,
+		minDuration,
+		maxDuration,
 	)
 	s.params.Logger.Infow(
 		"stream allocator: starting probe",
@@ -1257,13 +1562,12 @@ func (s *StreamAllocator) initProbe(probeGoalDeltaBps int64) {
 		"committed", s.committedChannelCapacity,
 		"lastReceived", s.lastReceivedEstimate,
 		"channel", channelState,
-		"probeGoalDeltaBps", probeGoalDeltaBps,
 		"goalBps", s.probeGoalBps,
 	)
 }
 
 func (s *StreamAllocator) resetProbe() {
-	s.lastProbeStartTime = time.Now()
+	s.lastProbeStartTime = s.clock.Now()
 
 	s.resetProbeInterval()
 
@@ -1276,6 +1580,14 @@ func (s *StreamAllocator) clearProbe() {
 }
 
 func (s *StreamAllocator) backoffProbeInterval() {
+	if s.params.Config.ProbeMode == config.CongestionControlProbeModeBBR {
+		s.probeInterval = time.Duration(s.probeInterval.Seconds()*ProbeCycleBackoffFactor) * time.Second
+		if s.probeInterval > ProbeCycleIntervalMax {
+			s.probeInterval = ProbeCycleIntervalMax
+		}
+		return
+	}
+
 	s.probeInterval = time.Duration(s.probeInterval.Seconds()*ProbeBackoffFactor) * time.Second
 	if s.probeInterval > ProbeWaitMax {
 		s.probeInterval = ProbeWaitMax
@@ -1283,6 +1595,11 @@ func (s *StreamAllocator) backoffProbeInterval() {
 }
 
 func (s *StreamAllocator) resetProbeInterval() {
+	if s.params.Config.ProbeMode == config.CongestionControlProbeModeBBR {
+		s.probeInterval = ProbeCycleInterval
+		return
+	}
+
 	s.probeInterval = ProbeWaitBase
 }
 
@@ -1300,10 +1617,14 @@ func (s *StreamAllocator) isInProbe() bool {
 }
 
 func (s *StreamAllocator) maybeProbe() {
-	if time.Since(s.lastProbeStartTime) < s.probeInterval || s.probeClusterId != ProbeClusterIdInvalid || s.overriddenChannelCapacity > 0 {
+	if s.clock.Now().Sub(s.lastProbeStartTime) < s.probeInterval || s.probeClusterId != ProbeClusterIdInvalid || s.overriddenChannelCapacity > 0 {
 		// do not probe if channel capacity is overridden
 		return
 	}
+	if s.maxChannelCapacity > 0 && (!s.params.Config.Enabled || s.committedChannelCapacity > s.maxChannelCapacity) {
+		// no more than the cap can be allocated whatever a probe finds
+		return
+	}
 
 	switch s.params.Config.ProbeMode {
 	case config.CongestionControlProbeModeMedia:
@@ -1311,6 +1632,8 @@ func (s *StreamAllocator) maybeProbe() {
 		s.adjustState()
 	case config.CongestionControlProbeModePadding:
 		s.maybeProbeWithPadding()
+	case config.CongestionControlProbeModeBBR:
+		s.maybeProbeCycle()
 	}
 }
 
@@ -1328,7 +1651,7 @@ func (s *StreamAllocator) maybeProbeWithMedia() {
 		}
 		s.maybeSendUpdate(update)
 
-		s.lastProbeStartTime = time.Now()
+		s.lastProbeStartTime = s.clock.Now()
 		break
 	}
 }
@@ -1346,6 +1669,50 @@ func (s *StreamAllocator) maybeProbeWithPadding() {
 	}
 }
 
+// maybeProbeCycle probes at a gain over the channel capacity so that the estimate can grow back after congestion
+// while little media is sent. A deficient track raises the goal to its next higher layer, padding is bounded to limit
+// the congestion a probe can cause.
+func (s *StreamAllocator) maybeProbeCycle() {
+	channelCapacity := s.committedChannelCapacity
+	if s.lastReceivedEstimate > channelCapacity {
+		channelCapacity = s.lastReceivedEstimate
+	}
+	if channelCapacity == 0 || channelCapacity >= ProbeCycleMaxCapacity {
+		return
+	}
+	for _, track := range s.getTracks() {
+		if track.LossEstimate() > ProbeCycleMaxLoss {
+			// probing a lossy channel only adds to the loss
+			return
+		}
+	}
+
+	expectedBandwidthUsage := s.getExpectedBandwidthUsage()
+	probeGoalBps := int64(float64(channelCapacity) * ProbeCycleGain)
+	for _, track := range s.getMaxDistanceSortedDeficient() {
+		transition, available := track.GetNextHigherTransition(FlagAllowOvershootInProbe)
+		if !available || transition.BandwidthDelta < 0 {
+			continue
+		}
+
+		if goalBps := expectedBandwidthUsage + ((transition.BandwidthDelta * ProbePct) / 100); goalBps > probeGoalBps {
+			probeGoalBps = goalBps
+		}
+		break
+	}
+
+	paddingBps := probeGoalBps - expectedBandwidthUsage
+	if paddingBps < ProbeCycleMinPaddingBps {
+		paddingBps = ProbeCycleMinPaddingBps
+	}
+	if paddingBps > ProbeCycleMaxPaddingBps {
+		paddingBps = ProbeCycleMaxPaddingBps
+	}
+	s.startProbe(expectedBandwidthUsage+paddingBps, ProbeCycleMinDuration, ProbeCycleMaxDuration)
+}
+
+// getTracks returns the tracks ordered by ID, so that allocations do not depend on map iteration order and
+// simulations are reproducible
 func (s *StreamAllocator) getTracks() []*Track {
 	s.videoTracksMu.RLock()
 	tracks := make([]*Track, 0, len(s.videoTracks))
@@ -1354,56 +1721,53 @@ func (s *StreamAllocator) getTracks() []*Track {
 	}
 	s.videoTracksMu.RUnlock()
 
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].ID() < tracks[j].ID()
+	})
 	return tracks
 }
 
 func (s *StreamAllocator) getSorted() TrackSorter {
-	s.videoTracksMu.RLock()
 	var trackSorter TrackSorter
-	for _, track := range s.videoTracks {
+	for _, track := range s.getTracks() {
 		if !track.IsManaged() {
 			continue
 		}
 
 		trackSorter = append(trackSorter, track)
 	}
-	s.videoTracksMu.RUnlock()
 
-	sort.Sort(trackSorter)
+	sort.Stable(trackSorter)
 
 	return trackSorter
 }
 
 func (s *StreamAllocator) getMinDistanceSorted(exclude *Track) MinDistanceSorter {
-	s.videoTracksMu.RLock()
 	var minDistanceSorter MinDistanceSorter
-	for _, track := range s.videoTracks {
+	for _, track := range s.getTracks() {
 		if !track.IsManaged() || track == exclude {
 			continue
 		}
 
 		minDistanceSorter = append(minDistanceSorter, track)
 	}
-	s.videoTracksMu.RUnlock()
 
-	sort.Sort(minDistanceSorter)
+	sort.Stable(minDistanceSorter)
 
 	return minDistanceSorter
 }
 
 func (s *StreamAllocator) getMaxDistanceSortedDeficient() MaxDistanceSorter {
-	s.videoTracksMu.RLock()
 	var maxDistanceSorter MaxDistanceSorter
-	for _, track := range s.videoTracks {
+	for _, track := range s.getTracks() {
 		if !track.IsManaged() || !track.IsDeficient() {
 			continue
 		}
 
 		maxDistanceSorter = append(maxDistanceSorter, track)
 	}
-	s.videoTracksMu.RUnlock()
 
-	sort.Sort(maxDistanceSorter)
+	sort.Stable(maxDistanceSorter)
 
 	return maxDistanceSorter
 }
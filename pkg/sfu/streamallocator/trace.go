@@ -0,0 +1,127 @@
+package streamallocator
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
+)
+
+// TraceEventType is an input of the stream allocator that is recorded in traces
+type TraceEventType string
+
+const (
+	TraceEventAddTrack    TraceEventType = "add_track"
+	TraceEventRemoveTrack TraceEventType = "remove_track"
+	TraceEventPriority    TraceEventType = "priority"
+	// layers and bitrates published by the source of a track
+	TraceEventLayers TraceEventType = "layers"
+	// max layer subscribed to
+	TraceEventMaxLayer   TraceEventType = "max_layer"
+	TraceEventAllowPause TraceEventType = "allow_pause"
+	// channel capacity estimate, from REMB or from the TWCC based send side estimator
+	TraceEventEstimate TraceEventType = "estimate"
+	// packet and repeated NACK counters of a track, recorded ahead of each estimate that uses them
+	TraceEventNACKStats      TraceEventType = "nack_stats"
+	TraceEventNACK           TraceEventType = "nack"
+	TraceEventReceiverReport TraceEventType = "receiver_report"
+)
+
+// TraceEvent is one line of a trace, only the fields of its type are set
+type TraceEvent struct {
+	// milliseconds since the start of the trace
+	At   int64          `json:"at"`
+	Type TraceEventType `json:"type"`
+
+	TrackID     livekit.TrackID     `json:"track_id,omitempty"`
+	Source      livekit.TrackSource `json:"source,omitempty"`
+	IsSimulcast bool                `json:"is_simulcast,omitempty"`
+	Priority    uint8               `json:"priority,omitempty"`
+
+	AvailableLayers []int32            `json:"available_layers,omitempty"`
+	Bitrates        *sfu.Bitrates      `json:"bitrates,omitempty"`
+	MaxLayer        *buffer.VideoLayer `json:"max_layer,omitempty"`
+	AllowPause      bool               `json:"allow_pause,omitempty"`
+
+	Estimate       int64                 `json:"estimate,omitempty"`
+	Packets        uint32                `json:"packets,omitempty"`
+	RepeatedNACKs  uint32                `json:"repeated_nacks,omitempty"`
+	NACKs          []sfu.NackInfo        `json:"nacks,omitempty"`
+	ReceiverReport *rtcp.ReceptionReport `json:"receiver_report,omitempty"`
+}
+
+// TraceRecorder writes the inputs of a stream allocator as JSON lines, to be replayed by the Simulator
+type TraceRecorder struct {
+	clock  utils.Clock
+	logger logger.Logger
+
+	lock    sync.Mutex
+	start   time.Time
+	encoder *json.Encoder
+	failed  bool
+}
+
+func NewTraceRecorder(w io.Writer, clock utils.Clock, logger logger.Logger) *TraceRecorder {
+	if clock == nil {
+		clock = utils.SystemClock
+	}
+	return &TraceRecorder{
+		clock:   clock,
+		logger:  logger,
+		start:   clock.Now(),
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (r *TraceRecorder) Record(event TraceEvent) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.failed {
+		return
+	}
+
+	event.At = r.clock.Now().Sub(r.start).Milliseconds()
+	if err := r.encoder.Encode(&event); err != nil {
+		// stop on the first error, a trace with holes cannot be replayed
+		r.failed = true
+		r.logger.Warnw("stream allocator: could not record trace", err)
+	}
+}
+
+// ReadTrace reads a trace written by a TraceRecorder, events are ordered by time
+func ReadTrace(r io.Reader) ([]TraceEvent, error) {
+	var events []TraceEvent
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var event TraceEvent
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At < events[j].At
+	})
+	return events, nil
+}
@@ -12,14 +12,47 @@ import (
 
 	"github.com/livekit/livekit-server/pkg/sfu"
 	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
+// weight of the previous loss estimate when a receiver report comes in
+const lossEstimateSmoothing = 0.8
+
+// DownTrack is the subscribed video track being allocated, implemented by *sfu.DownTrack and by the simulated
+// tracks of the Simulator
+type DownTrack interface {
+	ID() string
+	SSRC() uint32
+	MaxLayer() buffer.VideoLayer
+	CurrentLayer() buffer.VideoLayer
+	LastAllocation() sfu.VideoAllocation
+	IsDeficient() bool
+	BandwidthRequested() int64
+	DistanceToDesired() float64
+	AllocateOptimal(allowOvershoot bool) sfu.VideoAllocation
+	ProvisionalAllocatePrepare()
+	ProvisionalAllocate(availableChannelCapacity int64, layers buffer.VideoLayer, allowPause bool, allowOvershoot bool) int64
+	ProvisionalAllocateGetCooperativeTransition(allowOvershoot bool) sfu.VideoTransition
+	ProvisionalAllocateGetBestWeightedTransition() sfu.VideoTransition
+	ProvisionalAllocateCommit() sfu.VideoAllocation
+	AllocateNextHigher(availableChannelCapacity int64, allowOvershoot bool) (sfu.VideoAllocation, bool)
+	GetNextHigherTransition(allowOvershoot bool) (sfu.VideoTransition, bool)
+	Pause() sfu.VideoAllocation
+	WritePaddingRTP(bytesToSend int, paddingOnMute bool) int
+	WriteProbePaddingRTP(bytesToSend int) int
+	GetNackStats() (totalPackets uint32, totalRepeatedNACKs uint32)
+	GetAndResetBytesSent() (uint32, uint32)
+	SetStreamAllocatorReportInterval(interval time.Duration)
+	ClearStreamAllocatorReportInterval()
+}
+
 type Track struct {
-	downTrack   *sfu.DownTrack
+	downTrack   DownTrack
 	source      livekit.TrackSource
 	isSimulcast bool
 	priority    uint8
 	publisherID livekit.ParticipantID
+	clock       utils.Clock
 	logger      logger.Logger
 
 	maxLayer buffer.VideoLayer
@@ -37,6 +70,7 @@ type Track struct {
 	highestSequenceNumberAtLastRead uint32
 	highestSequenceNumber           uint32
 	maxRTT                          uint32
+	lossEstimate                    float64
 	// STREAM-ALLOCATOR-EXPERIMENTAL-TODO: remove after experimental
 	receiverReportHistory []string
 
@@ -46,14 +80,16 @@ type Track struct {
 }
 
 func NewTrack(
-	downTrack *sfu.DownTrack,
+	downTrack DownTrack,
 	source livekit.TrackSource,
 	isSimulcast bool,
 	publisherID livekit.ParticipantID,
+	clock utils.Clock,
 	logger logger.Logger,
 ) *Track {
 	t := &Track{
 		downTrack:             downTrack,
+		clock:                 clock,
 		source:                source,
 		isSimulcast:           isSimulcast,
 		publisherID:           publisherID,
@@ -109,7 +145,7 @@ func (t *Track) Priority() uint8 {
 	return t.priority
 }
 
-func (t *Track) DownTrack() *sfu.DownTrack {
+func (t *Track) DownTrack() DownTrack {
 	return t.downTrack
 }
 
@@ -138,6 +174,10 @@ func (t *Track) WritePaddingRTP(bytesToSend int) int {
 	return t.downTrack.WritePaddingRTP(bytesToSend, false)
 }
 
+func (t *Track) WriteProbePaddingRTP(bytesToSend int) int {
+	return t.downTrack.WriteProbePaddingRTP(bytesToSend)
+}
+
 func (t *Track) AllocateOptimal(allowOvershoot bool) sfu.VideoAllocation {
 	return t.downTrack.AllocateOptimal(allowOvershoot)
 }
@@ -251,6 +291,9 @@ func (t *Track) ProcessRTCPReceiverReport(rr rtcp.ReceptionReport) {
 		t.receiverReportInitialized = true
 		t.totalLostAtLastRead = rr.TotalLost
 		t.highestSequenceNumberAtLastRead = rr.LastSequenceNumber
+		t.lossEstimate = float64(rr.FractionLost) / 256.0
+	} else {
+		t.lossEstimate = lossEstimateSmoothing*t.lossEstimate + (1.0-lossEstimateSmoothing)*float64(rr.FractionLost)/256.0
 	}
 
 	t.totalLost = rr.TotalLost
@@ -265,6 +308,11 @@ func (t *Track) ProcessRTCPReceiverReport(rr rtcp.ReceptionReport) {
 	t.updateReceiverReportHistory()
 }
 
+// LossEstimate is the smoothed fraction of packets lost by the subscriber, from receiver reports
+func (t *Track) LossEstimate() float64 {
+	return t.lossEstimate
+}
+
 func (t *Track) GetRTCPReceiverReportDelta() (uint32, uint32, uint32) {
 	deltaPackets := t.highestSequenceNumber - t.highestSequenceNumberAtLastRead
 	t.highestSequenceNumberAtLastRead = t.highestSequenceNumber
@@ -287,7 +335,7 @@ func (t *Track) UpdateHistory() {
 }
 
 func (t *Track) GetHistory() string {
-	return fmt.Sprintf("t: %+v, n: %+v, rr: %+v", time.Now(), t.nackHistory, t.receiverReportHistory)
+	return fmt.Sprintf("t: %+v, n: %+v, rr: %+v", t.clock.Now(), t.nackHistory, t.receiverReportHistory)
 }
 
 // STREAM-ALLOCATOR-EXPERIMENTAL-TODO:
@@ -326,7 +374,7 @@ func (t *Track) updateNackHistory() {
 	}
 	t.nackHistory = append(
 		t.nackHistory,
-		fmt.Sprintf("t: %+v, l: %d, h: %d, sp: %d, nnd: %d, dens: %.2f, nns: %d, int: %.2f, nr: %d", time.Now().UnixMilli(), l, h, spread, nnd, density, nns, intensity, nr),
+		fmt.Sprintf("t: %+v, l: %d, h: %d, sp: %d, nnd: %d, dens: %.2f, nns: %d, int: %.2f, nr: %d", t.clock.Now().UnixMilli(), l, h, spread, nnd, density, nns, intensity, nr),
 	)
 }
 
@@ -338,7 +386,7 @@ func (t *Track) updateReceiverReportHistory() {
 	dl, dp, maxRTT := t.GetRTCPReceiverReportDelta()
 	t.receiverReportHistory = append(
 		t.receiverReportHistory,
-		fmt.Sprintf("t: %+v, l: %d, p: %d, rtt: %d", time.Now().Format(time.UnixDate), dl, dp, maxRTT),
+		fmt.Sprintf("t: %+v, l: %d, p: %d, rtt: %d", t.clock.Now().Format(time.UnixDate), dl, dp, maxRTT),
 	)
 }
 
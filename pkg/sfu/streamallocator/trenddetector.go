@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/utils"
 )
 
 // ------------------------------------------------
@@ -35,6 +37,7 @@ func (t TrendDirection) String() string {
 type TrendDetectorParams struct {
 	Name                   string
 	Logger                 logger.Logger
+	Clock                  utils.Clock
 	RequiredSamples        int
 	DownwardTrendThreshold float64
 	CollapseThreshold      time.Duration
@@ -58,7 +61,7 @@ type TrendDetector struct {
 func NewTrendDetector(params TrendDetectorParams) *TrendDetector {
 	return &TrendDetector{
 		params:    params,
-		startTime: time.Now(),
+		startTime: params.Clock.Now(),
 		direction: TrendDirectionNeutral,
 	}
 }
@@ -69,7 +72,7 @@ func (t *TrendDetector) Seed(value int64) {
 	}
 
 	t.values = append(t.values, value)
-	t.lastSampleAt = time.Now()
+	t.lastSampleAt = t.params.Clock.Now()
 	t.hasFallen = false
 }
 
@@ -99,7 +102,7 @@ func (t *TrendDetector) AddValue(value int64) {
 		lastValue = t.values[len(t.values)-1]
 	}
 	if lastValue == value && t.params.CollapseThreshold > 0 {
-		if !t.hasFallen || (!t.lastSampleAt.IsZero() && time.Since(t.lastSampleAt) < t.params.CollapseThreshold) {
+		if !t.hasFallen || (!t.lastSampleAt.IsZero() && t.params.Clock.Now().Sub(t.lastSampleAt) < t.params.CollapseThreshold) {
 			return
 		}
 	}
@@ -107,7 +110,7 @@ func (t *TrendDetector) AddValue(value int64) {
 	if lastValue > value {
 		t.hasFallen = true
 	}
-	t.lastSampleAt = time.Now()
+	t.lastSampleAt = t.params.Clock.Now()
 
 	if len(t.values) == t.params.RequiredSamples {
 		t.values = t.values[1:]
@@ -134,7 +137,7 @@ func (t *TrendDetector) GetDirection() TrendDirection {
 }
 
 func (t *TrendDetector) ToString() string {
-	now := time.Now()
+	now := t.params.Clock.Now()
 	elapsed := now.Sub(t.startTime).Seconds()
 	return fmt.Sprintf("n: %s, t: %+v|%+v|%.2fs, v: %d|%d|%d|%+v|%.2f",
 		t.params.Name,
@@ -41,6 +41,8 @@ type StreamTrackerManager struct {
 	availableLayers  []int32
 	maxExpectedLayer int32
 	paused           bool
+	// spatial layers of an SVC stream depend on lower ones only at key frames
+	isKSVC bool
 
 	senderReportMu sync.RWMutex
 	senderReports  [buffer.DefaultMaxLayerSpatial + 1]*buffer.RTCPSenderReportData
@@ -237,6 +239,18 @@ func (s *StreamTrackerManager) SetPaused(paused bool) {
 	}
 }
 
+// SetKSVC is called with the dependency structure of SVC streams, the bitrates of K-SVC spatial layers do not
+// include lower spatial layers
+func (s *StreamTrackerManager) SetKSVC(isKSVC bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isKSVC != isKSVC {
+		s.logger.Debugw("spatial layer dependency changed", "isKSVC", isKSVC)
+		s.isKSVC = isKSVC
+	}
+}
+
 func (s *StreamTrackerManager) IsPaused() bool {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -362,7 +376,7 @@ func (s *StreamTrackerManager) getLayeredBitrateLocked() ([]int32, Bitrates) {
 		}
 	}
 
-	if s.isSVC {
+	if s.isSVC && !s.isKSVC {
 		for i := len(br) - 1; i >= 1; i-- {
 			for j := len(br[i]) - 1; j >= 0; j-- {
 				if br[i][j] != 0 {
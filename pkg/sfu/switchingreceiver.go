@@ -0,0 +1,333 @@
+package sfu
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+var (
+	ErrNoSource            = errors.New("receiver has no source")
+	ErrSourceCodecMismatch = errors.New("source codec does not match receiver codec")
+)
+
+type SwitchingReceiverParams struct {
+	TrackID  livekit.TrackID
+	StreamID string
+	Codec    webrtc.RTPCodecParameters
+	Logger   logger.Logger
+}
+
+// SwitchingReceiver forwards the media of one of several receivers at a time, e. g. the video of whichever
+// participant is speaking. It subscribes to the current source like a down track would, so that the source keeps
+// its own subscribers. On a switch, down tracks continue from the next key frame of the new source
+type SwitchingReceiver struct {
+	params            SwitchingReceiverParams
+	downTrackSpreader *DownTrackSpreader
+	closed            atomic.Bool
+
+	lock   sync.RWMutex
+	source TrackReceiver
+	tap    *switchingReceiverTap
+}
+
+func NewSwitchingReceiver(params SwitchingReceiverParams) *SwitchingReceiver {
+	return &SwitchingReceiver{
+		params: params,
+		downTrackSpreader: NewDownTrackSpreader(DownTrackSpreaderParams{
+			Logger:       params.Logger,
+			ErrorContext: []interface{}{"trackID", params.TrackID},
+		}),
+	}
+}
+
+// SetSource switches to forwarding a receiver of the same codec, nil stops forwarding
+func (s *SwitchingReceiver) SetSource(source TrackReceiver) error {
+	if s.closed.Load() {
+		return ErrReceiverClosed
+	}
+	if source != nil && !strings.EqualFold(source.Codec().MimeType, s.params.Codec.MimeType) {
+		return ErrSourceCodecMismatch
+	}
+
+	s.lock.Lock()
+	if s.source == source {
+		s.lock.Unlock()
+		return nil
+	}
+	prevSource, prevTap := s.source, s.tap
+	var tap *switchingReceiverTap
+	if source != nil {
+		tap = &switchingReceiverTap{parent: s}
+	}
+	s.source, s.tap = source, tap
+	s.lock.Unlock()
+
+	if prevTap != nil {
+		prevTap.closed.Store(true)
+		prevSource.DeleteDownTrack(prevTap.SubscriberID())
+	}
+
+	downTracks := s.downTrackSpreader.GetDownTracks()
+	for _, dt := range downTracks {
+		if r, ok := dt.(interface{ ResyncSource() }); ok {
+			r.ResyncSource()
+		}
+	}
+	if source == nil {
+		s.params.Logger.Debugw("source cleared")
+		return nil
+	}
+
+	if err := source.AddDownTrack(tap); err != nil {
+		s.removeTap(tap)
+		return err
+	}
+	for _, dt := range downTracks {
+		dt.TrackInfoAvailable()
+		dt.UpTrackLayersChange()
+	}
+	s.params.Logger.Debugw("source switched", "sourceTrackID", source.TrackID())
+	return nil
+}
+
+// Source returns the receiver currently forwarded, nil when there is none
+func (s *SwitchingReceiver) Source() TrackReceiver {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.source
+}
+
+func (s *SwitchingReceiver) removeTap(tap *switchingReceiverTap) {
+	tap.closed.Store(true)
+
+	s.lock.Lock()
+	if s.tap == tap {
+		s.source, s.tap = nil, nil
+	}
+	s.lock.Unlock()
+}
+
+func (s *SwitchingReceiver) TrackID() livekit.TrackID {
+	return s.params.TrackID
+}
+
+func (s *SwitchingReceiver) StreamID() string {
+	return s.params.StreamID
+}
+
+func (s *SwitchingReceiver) Codec() webrtc.RTPCodecParameters {
+	return s.params.Codec
+}
+
+func (s *SwitchingReceiver) HeaderExtensions() []webrtc.RTPHeaderExtensionParameter {
+	if source := s.Source(); source != nil {
+		return source.HeaderExtensions()
+	}
+	return nil
+}
+
+func (s *SwitchingReceiver) IsClosed() bool {
+	return s.closed.Load()
+}
+
+func (s *SwitchingReceiver) ReadRTP(buf []byte, layer uint8, sn uint16) (int, error) {
+	if source := s.Source(); source != nil {
+		return source.ReadRTP(buf, layer, sn)
+	}
+	return 0, ErrNoSource
+}
+
+func (s *SwitchingReceiver) GetLayeredBitrate() ([]int32, Bitrates) {
+	if source := s.Source(); source != nil {
+		return source.GetLayeredBitrate()
+	}
+	return nil, Bitrates{}
+}
+
+func (s *SwitchingReceiver) GetAudioLevel() (float64, bool) {
+	return 0, false
+}
+
+func (s *SwitchingReceiver) SendPLI(layer int32, force bool) {
+	if source := s.Source(); source != nil {
+		source.SendPLI(layer, force)
+	}
+}
+
+// SetUpTrackPaused is ignored, sources are paused by their own subscribers only
+func (s *SwitchingReceiver) SetUpTrackPaused(_ bool) {}
+
+// SetMaxExpectedSpatialLayer is ignored, sources are managed by their own publications
+func (s *SwitchingReceiver) SetMaxExpectedSpatialLayer(_ int32) {}
+
+func (s *SwitchingReceiver) AddDownTrack(track TrackSender) error {
+	if s.closed.Load() {
+		return ErrReceiverClosed
+	}
+
+	if s.downTrackSpreader.HasDownTrack(track.SubscriberID()) {
+		s.params.Logger.Infow("subscriberID already exists, replacing downtrack", "subscriberID", track.SubscriberID())
+	}
+
+	track.TrackInfoAvailable()
+	s.downTrackSpreader.Store(track)
+	return nil
+}
+
+func (s *SwitchingReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	if s.closed.Load() {
+		return
+	}
+
+	s.downTrackSpreader.Free(subscriberID)
+}
+
+func (s *SwitchingReceiver) DebugInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"TrackID":    s.params.TrackID,
+		"DownTracks": s.downTrackSpreader.DownTrackCount(),
+	}
+	if source := s.Source(); source != nil {
+		info["SourceTrackID"] = source.TrackID()
+	}
+	return info
+}
+
+func (s *SwitchingReceiver) TrackInfo() *livekit.TrackInfo {
+	if source := s.Source(); source != nil {
+		return source.TrackInfo()
+	}
+	return nil
+}
+
+func (s *SwitchingReceiver) GetPrimaryReceiverForRed() TrackReceiver {
+	return s
+}
+
+func (s *SwitchingReceiver) GetRedReceiver() TrackReceiver {
+	return s
+}
+
+func (s *SwitchingReceiver) GetTemporalLayerFpsForSpatial(layer int32) []float32 {
+	if source := s.Source(); source != nil {
+		return source.GetTemporalLayerFpsForSpatial(layer)
+	}
+	return nil
+}
+
+func (s *SwitchingReceiver) GetReferenceLayerRTPTimestamp(ts uint32, layer int32, referenceLayer int32) (uint32, error) {
+	if source := s.Source(); source != nil {
+		return source.GetReferenceLayerRTPTimestamp(ts, layer, referenceLayer)
+	}
+	return 0, ErrNoSource
+}
+
+// Close stops forwarding and closes the down tracks
+func (s *SwitchingReceiver) Close() {
+	if s.closed.Swap(true) {
+		return
+	}
+
+	s.lock.Lock()
+	source, tap := s.source, s.tap
+	s.source, s.tap = nil, nil
+	s.lock.Unlock()
+	if tap != nil {
+		tap.closed.Store(true)
+		source.DeleteDownTrack(tap.SubscriberID())
+	}
+
+	for _, dt := range s.downTrackSpreader.ResetAndGetDownTracks() {
+		dt.Close()
+	}
+}
+
+// ---------------------------------------------------------------------
+
+// switchingReceiverTap subscribes to the current source of a switching receiver, spreading its packets to the
+// down tracks of the switching receiver
+type switchingReceiverTap struct {
+	parent *SwitchingReceiver
+	closed atomic.Bool
+}
+
+func (t *switchingReceiverTap) WriteRTP(pkt *buffer.ExtPacket, layer int32) error {
+	if t.closed.Load() {
+		return nil
+	}
+
+	t.parent.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.WriteRTP(pkt, layer)
+	})
+	return nil
+}
+
+func (t *switchingReceiverTap) HandleRTCPSenderReportData(payloadType webrtc.PayloadType, layer int32, srData *buffer.RTCPSenderReportData) error {
+	if t.closed.Load() {
+		return nil
+	}
+
+	t.parent.downTrackSpreader.Broadcast(func(dt TrackSender) {
+		_ = dt.HandleRTCPSenderReportData(payloadType, layer, srData)
+	})
+	return nil
+}
+
+// Close is called when the source closes, the switching receiver is left without one till switched again
+func (t *switchingReceiverTap) Close() {
+	t.parent.removeTap(t)
+}
+
+func (t *switchingReceiverTap) IsClosed() bool {
+	return t.closed.Load()
+}
+
+func (t *switchingReceiverTap) ID() string {
+	return string(t.SubscriberID())
+}
+
+func (t *switchingReceiverTap) SubscriberID() livekit.ParticipantID {
+	return livekit.ParticipantID(string(t.parent.params.TrackID) + "_switching")
+}
+
+func (t *switchingReceiverTap) UpTrackLayersChange() {
+	t.broadcast(func(dt TrackSender) { dt.UpTrackLayersChange() })
+}
+
+func (t *switchingReceiverTap) UpTrackBitrateAvailabilityChange() {
+	t.broadcast(func(dt TrackSender) { dt.UpTrackBitrateAvailabilityChange() })
+}
+
+func (t *switchingReceiverTap) UpTrackMaxPublishedLayerChange(maxPublishedLayer int32) {
+	t.broadcast(func(dt TrackSender) { dt.UpTrackMaxPublishedLayerChange(maxPublishedLayer) })
+}
+
+func (t *switchingReceiverTap) UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen int32) {
+	t.broadcast(func(dt TrackSender) { dt.UpTrackMaxTemporalLayerSeenChange(maxTemporalLayerSeen) })
+}
+
+func (t *switchingReceiverTap) UpTrackBitrateReport(availableLayers []int32, bitrates Bitrates) {
+	t.broadcast(func(dt TrackSender) { dt.UpTrackBitrateReport(availableLayers, bitrates) })
+}
+
+func (t *switchingReceiverTap) TrackInfoAvailable() {}
+
+func (t *switchingReceiverTap) broadcast(f func(dt TrackSender)) {
+	if t.closed.Load() {
+		return
+	}
+
+	for _, dt := range t.parent.downTrackSpreader.GetDownTracks() {
+		f(dt)
+	}
+}
@@ -0,0 +1,121 @@
+package sfu
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+type testSourceReceiver struct {
+	TrackReceiver
+	trackID livekit.TrackID
+	mime    string
+	senders map[livekit.ParticipantID]TrackSender
+	plis    atomic.Int32
+}
+
+func newTestSourceReceiver(trackID livekit.TrackID, mime string) *testSourceReceiver {
+	return &testSourceReceiver{
+		trackID: trackID,
+		mime:    mime,
+		senders: make(map[livekit.ParticipantID]TrackSender),
+	}
+}
+
+func (r *testSourceReceiver) TrackID() livekit.TrackID { return r.trackID }
+func (r *testSourceReceiver) TrackInfo() *livekit.TrackInfo {
+	return &livekit.TrackInfo{Sid: string(r.trackID)}
+}
+func (r *testSourceReceiver) Codec() webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: r.mime}}
+}
+func (r *testSourceReceiver) SendPLI(_ int32, _ bool)                 { r.plis.Inc() }
+func (r *testSourceReceiver) GetPrimaryReceiverForRed() TrackReceiver { return r }
+
+func (r *testSourceReceiver) AddDownTrack(track TrackSender) error {
+	r.senders[track.SubscriberID()] = track
+	return nil
+}
+
+func (r *testSourceReceiver) DeleteDownTrack(subscriberID livekit.ParticipantID) {
+	delete(r.senders, subscriberID)
+}
+
+func (r *testSourceReceiver) forward() {
+	for _, s := range r.senders {
+		_ = s.WriteRTP(nil, 0)
+	}
+}
+
+type testSwitchedTrackSender struct {
+	testTrackSender
+	resyncs   atomic.Int32
+	infoCalls atomic.Int32
+}
+
+func (s *testSwitchedTrackSender) ResyncSource()        { s.resyncs.Inc() }
+func (s *testSwitchedTrackSender) TrackInfoAvailable()  { s.infoCalls.Inc() }
+func (s *testSwitchedTrackSender) UpTrackLayersChange() {}
+
+func TestSwitchingReceiver(t *testing.T) {
+	s := NewSwitchingReceiver(SwitchingReceiverParams{
+		TrackID: "TR_switching",
+		Codec:   webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}},
+		Logger:  logger.GetLogger(),
+	})
+	dt := &testSwitchedTrackSender{testTrackSender: testTrackSender{subscriberID: "PA_sub"}}
+	require.NoError(t, s.AddDownTrack(dt))
+
+	// nothing is forwarded without a source
+	s.SendPLI(0, false)
+	_, err := s.ReadRTP(nil, 0, 0)
+	require.ErrorIs(t, err, ErrNoSource)
+
+	a := newTestSourceReceiver("TR_a", webrtc.MimeTypeVP8)
+	b := newTestSourceReceiver("TR_b", webrtc.MimeTypeVP8)
+	require.NoError(t, s.SetSource(a))
+	require.Len(t, a.senders, 1)
+	a.forward()
+	require.EqualValues(t, 1, dt.written.Load())
+	s.SendPLI(0, false)
+	require.EqualValues(t, 1, a.plis.Load())
+	require.Equal(t, "TR_a", s.TrackInfo().Sid)
+
+	// switching detaches from the previous source and resyncs down tracks
+	require.NoError(t, s.SetSource(b))
+	require.Empty(t, a.senders)
+	require.Len(t, b.senders, 1)
+	require.EqualValues(t, 2, dt.resyncs.Load())
+	a.forward()
+	b.forward()
+	require.EqualValues(t, 2, dt.written.Load())
+
+	// switching to the current source does nothing
+	require.NoError(t, s.SetSource(b))
+	require.EqualValues(t, 2, dt.resyncs.Load())
+
+	require.ErrorIs(t, s.SetSource(newTestSourceReceiver("TR_c", webrtc.MimeTypeH264)), ErrSourceCodecMismatch)
+	require.Equal(t, b, s.Source())
+
+	t.Run("source closing", func(t *testing.T) {
+		for _, sender := range b.senders {
+			sender.Close()
+		}
+		require.Nil(t, s.Source())
+		b.forward()
+		require.EqualValues(t, 2, dt.written.Load())
+	})
+
+	t.Run("close", func(t *testing.T) {
+		require.NoError(t, s.SetSource(a))
+		s.Close()
+		require.Empty(t, a.senders)
+		require.True(t, dt.closed.Load())
+		require.ErrorIs(t, s.SetSource(b), ErrReceiverClosed)
+	})
+}
@@ -77,6 +77,11 @@ var TestVP8Codec = webrtc.RTPCodecCapability{
 	ClockRate: 90000,
 }
 
+var TestVP9Codec = webrtc.RTPCodecCapability{
+	MimeType:  "video/vp9",
+	ClockRate: 90000,
+}
+
 var TestOpusCodec = webrtc.RTPCodecCapability{
 	MimeType:  "audio/opus",
 	ClockRate: 48000,
@@ -0,0 +1,101 @@
+package sfu
+
+import (
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// values are recorded in microseconds
+	histogramMaxJitter = int64(10 * time.Second / time.Microsecond)
+	histogramMaxDelay  = int64(10 * time.Second / time.Microsecond)
+	// 1% resolution keeps a histogram around 20KB
+	histogramPrecision = 2
+)
+
+// TrackHistograms records the inter-arrival jitter of the packets of a published track, as the per packet
+// transit time difference of RFC 3550 6.4.1, and the delay from arrival to being written to each subscriber
+type TrackHistograms struct {
+	clockRate uint32
+
+	lock        sync.Mutex
+	jitter      *hdrhistogram.Histogram
+	delay       *hdrhistogram.Histogram
+	startedAt   time.Time
+	lastByLayer map[int32]packetTiming
+}
+
+type packetTiming struct {
+	arrival   time.Time
+	timestamp uint32
+}
+
+func NewTrackHistograms(clockRate uint32) *TrackHistograms {
+	return &TrackHistograms{
+		clockRate:   clockRate,
+		jitter:      hdrhistogram.New(1, histogramMaxJitter, histogramPrecision),
+		delay:       hdrhistogram.New(1, histogramMaxDelay, histogramPrecision),
+		startedAt:   time.Now(),
+		lastByLayer: make(map[int32]packetTiming),
+	}
+}
+
+// RecordArrival records the transit time difference with the previous packet of the same layer, layers have
+// independent RTP timestamps
+func (h *TrackHistograms) RecordArrival(layer int32, arrival time.Time, timestamp uint32) {
+	if h.clockRate == 0 {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	last, ok := h.lastByLayer[layer]
+	h.lastByLayer[layer] = packetTiming{arrival: arrival, timestamp: timestamp}
+	if !ok {
+		return
+	}
+
+	arrivalDiff := arrival.Sub(last.arrival)
+	timestampDiff := time.Duration(int32(timestamp-last.timestamp)) * time.Second / time.Duration(h.clockRate)
+	d := arrivalDiff - timestampDiff
+	if d < 0 {
+		d = -d
+	}
+	_ = h.jitter.RecordValue(clampHistogramValue(d, histogramMaxJitter))
+}
+
+func (h *TrackHistograms) RecordForwardingDelay(delay time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	_ = h.delay.RecordValue(clampHistogramValue(delay, histogramMaxDelay))
+}
+
+// Rotate returns the jitter and forwarding delay histograms recorded since the previous call and starts new ones
+func (h *TrackHistograms) Rotate() (jitter *hdrhistogram.Histogram, delay *hdrhistogram.Histogram) {
+	now := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	jitter, delay = h.jitter, h.delay
+	for _, hist := range []*hdrhistogram.Histogram{jitter, delay} {
+		hist.SetStartTimeMs(h.startedAt.UnixMilli())
+		hist.SetEndTimeMs(now.UnixMilli())
+	}
+	h.jitter = hdrhistogram.New(1, histogramMaxJitter, histogramPrecision)
+	h.delay = hdrhistogram.New(1, histogramMaxDelay, histogramPrecision)
+	h.startedAt = now
+	return
+}
+
+func clampHistogramValue(d time.Duration, max int64) int64 {
+	v := d.Microseconds()
+	if v > max {
+		return max
+	}
+	return v
+}
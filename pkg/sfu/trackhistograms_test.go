@@ -0,0 +1,58 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackHistograms(t *testing.T) {
+	t.Run("jitter is the transit time difference per layer", func(t *testing.T) {
+		h := NewTrackHistograms(90000)
+		start := time.Now()
+
+		// layer 0 at 30 fps, second frame arrives 10ms late
+		h.RecordArrival(0, start, 1000)
+		h.RecordArrival(0, start.Add(43*time.Millisecond), 1000+2970)
+		// layer 1 has an unrelated timestamp base and arrives on time
+		h.RecordArrival(1, start, 500000)
+		h.RecordArrival(1, start.Add(33*time.Millisecond), 500000+2970)
+
+		jitter, delay := h.Rotate()
+		require.EqualValues(t, 2, jitter.TotalCount())
+		require.InDelta(t, 10000, jitter.Max(), 100)
+		require.InDelta(t, 0, jitter.Min(), 100)
+		require.EqualValues(t, 0, delay.TotalCount())
+	})
+
+	t.Run("rotate starts new histograms", func(t *testing.T) {
+		h := NewTrackHistograms(48000)
+		h.RecordForwardingDelay(2 * time.Millisecond)
+		h.RecordForwardingDelay(time.Minute)
+
+		_, delay := h.Rotate()
+		require.EqualValues(t, 2, delay.TotalCount())
+		// clamped to the highest trackable value
+		require.InDelta(t, histogramMaxDelay, delay.Max(), float64(histogramMaxDelay)/100)
+		require.LessOrEqual(t, delay.StartTimeMs(), delay.EndTimeMs())
+
+		_, delay = h.Rotate()
+		require.EqualValues(t, 0, delay.TotalCount())
+	})
+
+	t.Run("encoded histograms can be decoded", func(t *testing.T) {
+		h := NewTrackHistograms(48000)
+		for i := 1; i <= 100; i++ {
+			h.RecordForwardingDelay(time.Duration(i) * 10 * time.Microsecond)
+		}
+		_, delay := h.Rotate()
+
+		encoded, err := delay.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		require.NoError(t, err)
+		decoded, err := hdrhistogram.Decode(encoded)
+		require.NoError(t, err)
+		require.True(t, delay.Equals(decoded))
+	})
+}
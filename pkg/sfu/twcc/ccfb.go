@@ -0,0 +1,156 @@
+package twcc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// arrival time offsets are in 1/1024 seconds, the largest one is over-range
+	atoUnitsPerSecond = 1024
+	atoMax            = 0x1FFE
+	atoOverRange      = 0x1FFF
+
+	// a report block covers at most this many packets, keeping reports within the MTU, older ones are reported lost
+	ccfbMaxPacketsPerStream = 512
+
+	// seconds from the NTP epoch to the unix epoch
+	ntpEpochOffset = 2208988800
+)
+
+type ccfbStream struct {
+	started bool
+	// first sequence number not reported yet
+	nextExtSN    uint32
+	highestExtSN uint32
+	arrivals     map[uint32]int64
+}
+
+func (s *ccfbStream) push(sn uint16, timeNS int64) {
+	if !s.started {
+		s.started = true
+		s.nextExtSN = uint32(sn)
+		s.highestExtSN = uint32(sn)
+		s.arrivals = make(map[uint32]int64)
+	}
+
+	extSN := uint32(int64(s.highestExtSN) + int64(int16(sn-uint16(s.highestExtSN))))
+	if int32(extSN-s.nextExtSN) < 0 {
+		// already reported lost, or from before the stream started
+		return
+	}
+	s.arrivals[extSN] = timeNS
+	if extSN > s.highestExtSN {
+		s.highestExtSN = extSN
+	}
+}
+
+func (s *ccfbStream) reportBlock(ssrc uint32, nowNS int64) *rtcp.CCFeedbackReportBlock {
+	if !s.started || s.highestExtSN < s.nextExtSN {
+		return nil
+	}
+
+	if s.highestExtSN-s.nextExtSN >= ccfbMaxPacketsPerStream {
+		first := s.highestExtSN - ccfbMaxPacketsPerStream + 1
+		for extSN := range s.arrivals {
+			if extSN < first {
+				delete(s.arrivals, extSN)
+			}
+		}
+		s.nextExtSN = first
+	}
+
+	block := &rtcp.CCFeedbackReportBlock{
+		MediaSSRC:     ssrc,
+		BeginSequence: uint16(s.nextExtSN),
+		MetricBlocks:  make([]rtcp.CCFeedbackMetricBlock, 0, s.highestExtSN-s.nextExtSN+1),
+	}
+	for extSN := s.nextExtSN; extSN <= s.highestExtSN; extSN++ {
+		arrival, ok := s.arrivals[extSN]
+		if !ok {
+			block.MetricBlocks = append(block.MetricBlocks, rtcp.CCFeedbackMetricBlock{})
+			continue
+		}
+		delete(s.arrivals, extSN)
+		block.MetricBlocks = append(block.MetricBlocks, rtcp.CCFeedbackMetricBlock{
+			Received:          true,
+			ArrivalTimeOffset: arrivalTimeOffset(nowNS - arrival),
+		})
+	}
+	s.nextExtSN = s.highestExtSN + 1
+	return block
+}
+
+// PushStream records the arrival of a packet of an rtp stream, used with FormatCCFB
+func (t *Responder) PushStream(ssrc uint32, sn uint16, timeNS int64, marker bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	s := t.streams[ssrc]
+	if s == nil {
+		s = &ccfbStream{}
+		t.streams[ssrc] = s
+	}
+	s.push(sn, timeNS)
+	t.streamPackets++
+
+	if t.lastReport == 0 {
+		t.lastReport = timeNS
+	}
+	delta := time.Duration(timeNS - t.lastReport)
+	if t.streamPackets > 20 &&
+		(delta >= t.params.FeedbackInterval || t.streamPackets > 100 || (marker && delta >= t.params.FeedbackInterval/2)) {
+		if pkt := t.buildCCFeedbackPacket(timeNS); pkt != nil && t.onFeedback != nil {
+			t.onFeedback(pkt)
+		}
+		t.lastReport = timeNS
+		t.streamPackets = 0
+	}
+}
+
+func (t *Responder) buildCCFeedbackPacket(nowNS int64) rtcp.RawPacket {
+	ssrcs := make([]uint32, 0, len(t.streams))
+	for ssrc := range t.streams {
+		ssrcs = append(ssrcs, ssrc)
+	}
+	sort.Slice(ssrcs, func(i, j int) bool { return ssrcs[i] < ssrcs[j] })
+
+	report := rtcp.CCFeedbackReport{
+		SenderSSRC:      t.sSSRC,
+		ReportTimestamp: ntpCompact(nowNS),
+	}
+	for _, ssrc := range ssrcs {
+		if block := t.streams[ssrc].reportBlock(ssrc, nowNS); block != nil {
+			report.ReportBlocks = append(report.ReportBlocks, *block)
+		}
+	}
+	if len(report.ReportBlocks) == 0 {
+		return nil
+	}
+
+	pkt, err := report.Marshal()
+	if err != nil {
+		return nil
+	}
+	return pkt
+}
+
+func arrivalTimeOffset(delta int64) uint16 {
+	if delta < 0 {
+		return 0
+	}
+	ato := delta * atoUnitsPerSecond / int64(time.Second)
+	if ato > atoMax {
+		return atoOverRange
+	}
+	return uint16(ato)
+}
+
+// ntpCompact is the middle 32 bits of the NTP timestamp of a unix time in nanoseconds
+func ntpCompact(timeNS int64) uint32 {
+	seconds := uint64(timeNS/int64(time.Second)) + ntpEpochOffset
+	fraction := uint64(timeNS%int64(time.Second)) << 32 / uint64(time.Second)
+	return uint32(seconds<<16) | uint32(fraction>>16)
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of the forwarding and allocation logic, simulations replace it to run faster than
+// real time and reproducibly
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+// SimulatedClock only moves when advanced
+type SimulatedClock struct {
+	lock sync.RWMutex
+	now  time.Time
+}
+
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{
+		now: start,
+	}
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.now
+}
+
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// AdvanceTo moves the clock to t, earlier times are ignored
+func (c *SimulatedClock) AdvanceTo(t time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if t.After(c.now) {
+		c.now = t
+	}
+}
@@ -37,6 +37,10 @@ func (b *Base) IsOvershootOkay() bool {
 	return false
 }
 
+func (b *Base) IsKeyFrameNeededToSwitch(fromSpatial int32, toSpatial int32) bool {
+	return toSpatial != buffer.InvalidLayerSpatial && toSpatial != fromSpatial
+}
+
 func (b *Base) SetTemporalLayerSelector(tls temporallayerselector.TemporalLayerSelector) {
 	b.tls = tls
 }
@@ -9,20 +9,31 @@ import (
 	"github.com/livekit/protocol/logger"
 )
 
+// DependencyDescriptor selects frames of an SVC stream, or of simulcast streams each carrying their own dependency
+// descriptor, using the decode targets of the dependency structure. Simulcast streams have a single spatial layer
+// each and are told apart by the layer they are received on.
 type DependencyDescriptor struct {
 	*Base
 
+	// received stream being forwarded, always 0 for SVC
+	stream    int32
 	frameNum  *utils.WrapAround[uint16, uint64]
 	decisions *SelectorDecisionCache
 
+	// forwarded frame numbers continue across simulcast stream switches
+	frameNumStart    uint64
+	outFrameNumStart uint16
+	lastOutFrameNum  uint16
+	hasForwarded     bool
+
 	needsDecodeTargetBitmask   bool
 	activeDecodeTargetsBitmask *uint32
-	structure                  *dede.FrameDependencyStructure
 }
 
 func NewDependencyDescriptor(logger logger.Logger) *DependencyDescriptor {
 	return &DependencyDescriptor{
 		Base:      NewBase(logger),
+		stream:    buffer.InvalidLayerSpatial,
 		frameNum:  utils.NewWrapAround[uint16, uint64](),
 		decisions: NewSelectorDecisionCache(256),
 	}
@@ -31,6 +42,7 @@ func NewDependencyDescriptor(logger logger.Logger) *DependencyDescriptor {
 func NewDependencyDescriptorFromNull(vls VideoLayerSelector) *DependencyDescriptor {
 	return &DependencyDescriptor{
 		Base:      vls.(*Null).Base,
+		stream:    buffer.InvalidLayerSpatial,
 		frameNum:  utils.NewWrapAround[uint16, uint64](),
 		decisions: NewSelectorDecisionCache(256),
 	}
@@ -40,14 +52,33 @@ func (d *DependencyDescriptor) IsOvershootOkay() bool {
 	return false
 }
 
-func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (result VideoLayerSelectorResult) {
+func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, layer int32) (result VideoLayerSelectorResult) {
 	ddwdt := extPkt.DependencyDescriptor
-	if ddwdt == nil {
-		// packet doesn't have dependency descriptor
+	if ddwdt == nil || ddwdt.Structure == nil {
+		// packet doesn't have dependency descriptor or the structure to interpret it has not been received yet
 		return
 	}
 
 	dd := ddwdt.Descriptor
+	fd := dd.FrameDependencies
+	structure := ddwdt.Structure
+
+	isSimulcast := isSingleSpatialLayer(ddwdt.DecodeTargets)
+	stream := int32(0)
+	if isSimulcast {
+		stream = layer
+	}
+	if stream != d.stream {
+		if !d.canSwitchStream(extPkt, stream) {
+			// a packet of another simulcast stream
+			return
+		}
+		d.switchStream(dd, stream)
+	}
+	if isSimulcast && !d.currentLayer.IsValid() && stream > d.targetLayer.Spatial {
+		// resume at a stream not higher than the target
+		return
+	}
 
 	// a packet is relevant as long as it has DD extension
 	result.IsRelevant = true
@@ -55,9 +86,8 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 	frameNum := d.frameNum.Update(dd.FrameNumber)
 	extFrameNum := frameNum.ExtendedVal
 
-	fd := dd.FrameDependencies
 	incomingLayer := buffer.VideoLayer{
-		Spatial:  int32(fd.SpatialId),
+		Spatial:  stream + int32(fd.SpatialId),
 		Temporal: int32(fd.TemporalId),
 	}
 
@@ -70,8 +100,10 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 	switch sd {
 	case selectorDecisionForwarded:
 		// a packet of an alreadty forwarded frame, maintain decision
-		result.RTPMarker = extPkt.Packet.Header.Marker || (dd.LastPacketInFrame && d.currentLayer.Spatial == int32(fd.SpatialId))
+		result.RTPMarker = extPkt.Packet.Header.Marker || (dd.LastPacketInFrame && d.currentLayer.Spatial == incomingLayer.Spatial)
+		result.DependencyDescriptorExtension = d.marshal(dd, structure, extFrameNum)
 		result.IsSelected = true
+		return
 
 	case selectorDecisionDropped:
 		// a packet of an alreadty dropped frame, maintain decision
@@ -110,13 +142,6 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 		return
 	}
 
-	// DD-TODO should not update for out-of-order RTP packets
-	if dd.AttachedStructure != nil {
-		// update decode target layer and active decode targets
-		// DD-TODO : these targets info can be shared by all the downtracks, no need calculate in every selector
-		d.updateDependencyStructure(dd.AttachedStructure)
-	}
-
 	// DD-TODO : we don't have a rtp queue to ensure the order of packets now,
 	// so we don't know packet is lost/out of order, that cause us can't detect
 	// frame integrity, entire frame is forwareded, whether frame chain is broken.
@@ -130,12 +155,18 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 	}
 
 	// find decode target closest to targetLayer
+	dtis := fd.DecodeTargetIndications
 	highestDecodeTarget := buffer.DependencyDescriptorDecodeTarget{
 		Target: -1,
 		Layer:  buffer.InvalidLayer,
 	}
 	for _, dt := range ddwdt.DecodeTargets {
-		if dt.Layer.Spatial > d.targetLayer.Spatial || dt.Layer.Temporal > d.targetLayer.Temporal {
+		// spatial layer of a simulcast stream is switched with key frames of the stream
+		dtLayer := buffer.VideoLayer{
+			Spatial:  stream + dt.Layer.Spatial,
+			Temporal: dt.Layer.Temporal,
+		}
+		if (!isSimulcast && dtLayer.Spatial > d.targetLayer.Spatial) || dtLayer.Temporal > d.targetLayer.Temporal {
 			continue
 		}
 
@@ -143,19 +174,26 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 			continue
 		}
 
-		if len(d.structure.DecodeTargetProtectedByChain) == 0 {
+		// switching up needs a frame the decode target can be started from, keep the current target till then
+		if d.currentLayer.IsValid() && dtLayer.GreaterThan(d.currentLayer) {
+			if dt.Target >= len(dtis) || dtis[dt.Target] != dede.DecodeTargetSwitch {
+				continue
+			}
+		}
+
+		if len(structure.DecodeTargetProtectedByChain) == 0 {
 			highestDecodeTarget = dt
-			//d.logger.Debugw("select target", "highestDecodeTarget", highestDecodeTarget, "dtis", fd.DecodeTargetIndications)
+			highestDecodeTarget.Layer = dtLayer
 			break
 		}
 
-		if len(d.structure.DecodeTargetProtectedByChain) < dt.Target {
+		if len(structure.DecodeTargetProtectedByChain) <= dt.Target {
 			// look for lower target
 			continue
 		}
 
-		chainIdx := d.structure.DecodeTargetProtectedByChain[dt.Target]
-		if len(fd.ChainDiffs) < chainIdx {
+		chainIdx := structure.DecodeTargetProtectedByChain[dt.Target]
+		if len(fd.ChainDiffs) <= chainIdx {
 			// look for lower target
 			continue
 		}
@@ -169,23 +207,17 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 		}
 
 		highestDecodeTarget = dt
-		//d.logger.Debugw("select target", "highestDecodeTarget", highestDecodeTarget, "dtis", fd.DecodeTargetIndications)
+		highestDecodeTarget.Layer = dtLayer
 		break
 	}
 
 	if highestDecodeTarget.Target < 0 {
 		// no active decode target, do not select
-		//d.logger.Debugw(fmt.Sprintf("drop packet for no target found, decodeTargets %v, tagetLayer %v, incoming %v",
-		//d.decodeTargets,
-		//d.targetLayer,
-		//incomingLayer,
-		//))
 		d.decisions.AddDropped(extFrameNum)
 		return
 	}
 
-	dtis := fd.DecodeTargetIndications
-	if len(dtis) < highestDecodeTarget.Target {
+	if len(dtis) <= highestDecodeTarget.Target {
 		// dtis error, dependency descriptor might lost
 		d.logger.Debugw(fmt.Sprintf("drop packet for dtis error, dtis %v, highestDecodeTarget %+v, incoming: %v",
 			dtis,
@@ -199,13 +231,6 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 	// DD-TODO : if bandwidth in congest, could drop the 'Discardable' packet
 	dti := dtis[highestDecodeTarget.Target]
 	if dti == dede.DecodeTargetNotPresent {
-		//d.logger.Debugw(fmt.Sprintf("drop packet for decode target not present, dtis %v, highestDecodeTarget %d, incoming %v, fn: %d/%d",
-		//dtis,
-		//highestDecodeTarget,
-		//incomingLayer,
-		//dd.FrameNumber,
-		//extFrameNum,
-		//))
 		d.decisions.AddDropped(extFrameNum)
 		return
 	}
@@ -218,7 +243,7 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 				"current", incomingLayer,
 				"target", d.targetLayer,
 				"max", d.maxLayer,
-				"layer", fd.SpatialId,
+				"layer", incomingLayer.Spatial,
 				"req", d.requestSpatial,
 				"maxSeen", d.maxSeenLayer,
 				"feed", extPkt.Packet.SSRC,
@@ -235,7 +260,7 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 				"current", d.currentLayer,
 				"target", d.targetLayer,
 				"max", d.maxLayer,
-				"layer", fd.SpatialId,
+				"layer", incomingLayer.Spatial,
 				"req", d.requestSpatial,
 				"maxSeen", d.maxSeenLayer,
 				"feed", extPkt.Packet.SSRC,
@@ -243,31 +268,22 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 		}
 	}
 
-	ddExtension := &dede.DependencyDescriptorExtension{
-		Descriptor: dd,
-		Structure:  d.structure,
-	}
-	if dd.AttachedStructure == nil {
-		if d.needsDecodeTargetBitmask {
-			d.needsDecodeTargetBitmask = false
+	if dd.AttachedStructure == nil && d.needsDecodeTargetBitmask {
+		d.needsDecodeTargetBitmask = false
 
-			d.activeDecodeTargetsBitmask = buffer.GetActiveDecodeTargetBitmask(d.targetLayer, ddwdt.DecodeTargets)
-			d.logger.Debugw("setting decode target bitmask", "activeDecodeTargetsBitmask", d.activeDecodeTargetsBitmask)
+		maskLayer := d.targetLayer
+		if isSimulcast {
+			// decode targets of a simulcast stream have temporal layers only
+			maskLayer.Spatial = 0
 		}
-
-		if d.activeDecodeTargetsBitmask != nil {
-			// clone and override activebitmask
-			ddClone := *ddExtension.Descriptor
-			ddClone.ActiveDecodeTargetsBitmask = d.activeDecodeTargetsBitmask
-			ddExtension.Descriptor = &ddClone
-			// d.logger.Debugw("set active decode targets bitmask", "activeDecodeTargetsBitmask", d.activeDecodeTargetsBitmask)
+		activeDecodeTargetsBitmask := buffer.GetActiveDecodeTargetBitmask(maskLayer, ddwdt.DecodeTargets)
+		if activeDecodeTargetsBitmask == nil && d.activeDecodeTargetsBitmask != nil {
+			// the receiver keeps the last bitmask, all targets have to be activated again explicitly
+			allActive := uint32(1)<<structure.NumDecodeTargets - 1
+			activeDecodeTargetsBitmask = &allActive
 		}
-	}
-	bytes, err := ddExtension.Marshal()
-	if err != nil {
-		d.logger.Warnw("error marshalling dependency descriptor extension", err)
-	} else {
-		result.DependencyDescriptorExtension = bytes
+		d.activeDecodeTargetsBitmask = activeDecodeTargetsBitmask
+		d.logger.Debugw("setting decode target bitmask", "activeDecodeTargetsBitmask", d.activeDecodeTargetsBitmask)
 	}
 
 	// DD-TODO START
@@ -282,11 +298,81 @@ func (d *DependencyDescriptor) Select(extPkt *buffer.ExtPacket, _layer int32) (r
 	// goal of dependency descriptor
 	// DD-TODO END
 	d.decisions.AddForwarded(extFrameNum)
-	result.RTPMarker = extPkt.Packet.Header.Marker || (dd.LastPacketInFrame && d.currentLayer.Spatial == int32(fd.SpatialId))
+	result.DependencyDescriptorExtension = d.marshal(dd, structure, extFrameNum)
+	result.RTPMarker = extPkt.Packet.Header.Marker || (dd.LastPacketInFrame && d.currentLayer.Spatial == incomingLayer.Spatial)
 	result.IsSelected = true
 	return
 }
 
+// canSwitchStream allows switching simulcast streams at key frames, towards the target layer
+func (d *DependencyDescriptor) canSwitchStream(extPkt *buffer.ExtPacket, stream int32) bool {
+	if !extPkt.KeyFrame || !extPkt.DependencyDescriptor.Descriptor.FirstPacketInFrame {
+		return false
+	}
+
+	if !d.currentLayer.IsValid() {
+		return stream <= d.targetLayer.Spatial
+	}
+
+	current := d.currentLayer.Spatial
+	return (stream > current && stream <= d.targetLayer.Spatial) || (stream < current && stream >= d.targetLayer.Spatial)
+}
+
+func (d *DependencyDescriptor) switchStream(dd *dede.DependencyDescriptor, stream int32) {
+	if d.stream != buffer.InvalidLayerSpatial {
+		d.logger.Infow(
+			"switching stream",
+			"from", d.stream,
+			"to", stream,
+			"current", d.currentLayer,
+			"target", d.targetLayer,
+		)
+	}
+
+	// frame numbers and decisions of streams are independent
+	d.stream = stream
+	d.frameNum = utils.NewWrapAround[uint16, uint64]()
+	d.decisions = NewSelectorDecisionCache(256)
+	d.frameNumStart = uint64(dd.FrameNumber)
+	if d.hasForwarded {
+		d.outFrameNumStart = d.lastOutFrameNum + 1
+	} else {
+		d.outFrameNumStart = dd.FrameNumber
+	}
+
+	// decode targets of the new stream are not known to the receiver yet
+	d.needsDecodeTargetBitmask = true
+	d.activeDecodeTargetsBitmask = nil
+}
+
+// marshal writes the dependency descriptor to forward, with the frame number continuing the forwarded ones and the
+// active decode targets of the target layer
+func (d *DependencyDescriptor) marshal(dd *dede.DependencyDescriptor, structure *dede.FrameDependencyStructure, extFrameNum uint64) []byte {
+	outFrameNum := d.outFrameNumStart + uint16(extFrameNum-d.frameNumStart)
+	if !d.hasForwarded || outFrameNum-d.lastOutFrameNum < 0x8000 {
+		d.lastOutFrameNum = outFrameNum
+		d.hasForwarded = true
+	}
+
+	ddClone := *dd
+	ddClone.FrameNumber = outFrameNum
+	if dd.AttachedStructure == nil && d.activeDecodeTargetsBitmask != nil {
+		// override active bitmask
+		ddClone.ActiveDecodeTargetsBitmask = d.activeDecodeTargetsBitmask
+	}
+
+	ddExtension := &dede.DependencyDescriptorExtension{
+		Descriptor: &ddClone,
+		Structure:  structure,
+	}
+	bytes, err := ddExtension.Marshal()
+	if err != nil {
+		d.logger.Warnw("error marshalling dependency descriptor extension", err)
+		return nil
+	}
+	return bytes
+}
+
 func (d *DependencyDescriptor) SetTarget(targetLayer buffer.VideoLayer) {
 	if targetLayer == d.targetLayer {
 		return
@@ -297,6 +383,12 @@ func (d *DependencyDescriptor) SetTarget(targetLayer buffer.VideoLayer) {
 	d.needsDecodeTargetBitmask = true
 }
 
-func (d *DependencyDescriptor) updateDependencyStructure(structure *dede.FrameDependencyStructure) {
-	d.structure = structure
+// isSingleSpatialLayer returns true for structures without spatial layers, like the ones of simulcast streams
+func isSingleSpatialLayer(decodeTargets []buffer.DependencyDescriptorDecodeTarget) bool {
+	for _, dt := range decodeTargets {
+		if dt.Layer.Spatial != 0 {
+			return false
+		}
+	}
+	return true
 }
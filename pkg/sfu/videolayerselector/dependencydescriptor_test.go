@@ -0,0 +1,157 @@
+package videolayerselector
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+	dede "github.com/livekit/livekit-server/pkg/sfu/dependencydescriptor"
+)
+
+const (
+	dtiN = dede.DecodeTargetNotPresent
+	dtiD = dede.DecodeTargetDiscardable
+	dtiS = dede.DecodeTargetSwitch
+)
+
+// L1T3 with a cycle of four frames: T0, T2, T1, T2
+var l1t3Structure = &dede.FrameDependencyStructure{
+	NumDecodeTargets:             3,
+	NumChains:                    1,
+	DecodeTargetProtectedByChain: []int{0, 0, 0},
+	Templates: []*dede.FrameDependencyTemplate{
+		{TemporalId: 0, DecodeTargetIndications: []dede.DecodeTargetIndication{dtiS, dtiS, dtiS}, ChainDiffs: []int{0}},
+		{TemporalId: 0, DecodeTargetIndications: []dede.DecodeTargetIndication{dtiS, dtiS, dtiS}, FrameDiffs: []int{4}, ChainDiffs: []int{4}},
+		{TemporalId: 1, DecodeTargetIndications: []dede.DecodeTargetIndication{dtiN, dtiD, dtiS}, FrameDiffs: []int{2}, ChainDiffs: []int{2}},
+		{TemporalId: 2, DecodeTargetIndications: []dede.DecodeTargetIndication{dtiN, dtiN, dtiD}, FrameDiffs: []int{1}, ChainDiffs: []int{1}},
+		{TemporalId: 2, DecodeTargetIndications: []dede.DecodeTargetIndication{dtiN, dtiN, dtiD}, FrameDiffs: []int{1}, ChainDiffs: []int{3}},
+	},
+}
+
+// template of each frame of the cycle
+var l1t3Cycle = []int{1, 3, 2, 4}
+
+// l1t3Packet returns the single packet of a frame, the template follows from the frame number
+func l1t3Packet(frameNum uint16, isKeyFrame bool, ssrc uint32) *buffer.ExtPacket {
+	template := l1t3Structure.Templates[l1t3Cycle[frameNum%4]]
+	var attachedStructure *dede.FrameDependencyStructure
+	if isKeyFrame {
+		template = l1t3Structure.Templates[0]
+		attachedStructure = l1t3Structure
+	}
+
+	return &buffer.ExtPacket{
+		Packet: &rtp.Packet{
+			Header: rtp.Header{
+				Marker: true,
+				SSRC:   ssrc,
+			},
+		},
+		KeyFrame: isKeyFrame,
+		VideoLayer: buffer.VideoLayer{
+			Spatial:  0,
+			Temporal: int32(template.TemporalId),
+		},
+		DependencyDescriptor: &buffer.DependencyDescriptorWithDecodeTarget{
+			Descriptor: &dede.DependencyDescriptor{
+				FirstPacketInFrame: true,
+				LastPacketInFrame:  true,
+				FrameNumber:        frameNum,
+				FrameDependencies:  template.Clone(),
+				AttachedStructure:  attachedStructure,
+			},
+			DecodeTargets: buffer.ProcessFrameDependencyStructure(l1t3Structure),
+			Structure:     l1t3Structure,
+		},
+	}
+}
+
+func forwardedFrameNumber(t *testing.T, result VideoLayerSelectorResult) uint16 {
+	var descriptor dede.DependencyDescriptor
+	ext := &dede.DependencyDescriptorExtension{
+		Descriptor: &descriptor,
+		Structure:  l1t3Structure,
+	}
+	_, err := ext.Unmarshal(result.DependencyDescriptorExtension)
+	require.NoError(t, err)
+	return descriptor.FrameNumber
+}
+
+func TestDependencyDescriptorTemporalUpgradeAtSwitch(t *testing.T) {
+	d := NewDependencyDescriptor(logger.GetLogger())
+	d.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 0})
+
+	result := d.Select(l1t3Packet(100, true, 1), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.IsResuming)
+	require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 0}, d.GetCurrent())
+
+	// higher temporal layers are dropped
+	require.False(t, d.Select(l1t3Packet(101, false, 1), 0).IsSelected)
+	require.False(t, d.Select(l1t3Packet(102, false, 1), 0).IsSelected)
+	require.False(t, d.Select(l1t3Packet(103, false, 1), 0).IsSelected)
+	require.True(t, d.Select(l1t3Packet(104, false, 1), 0).IsSelected)
+
+	d.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 2})
+
+	// T2 frame is not a switch point, stays at T0
+	require.False(t, d.Select(l1t3Packet(105, false, 1), 0).IsSelected)
+	require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 0}, d.GetCurrent())
+
+	// T1 frame is a switch point for T2
+	result = d.Select(l1t3Packet(106, false, 1), 0)
+	require.True(t, result.IsSelected)
+	require.False(t, result.IsResuming)
+	require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 2}, d.GetCurrent())
+	require.Equal(t, uint16(106), forwardedFrameNumber(t, result))
+
+	require.True(t, d.Select(l1t3Packet(107, false, 1), 0).IsSelected)
+	require.True(t, d.Select(l1t3Packet(108, false, 1), 0).IsSelected)
+}
+
+func TestDependencyDescriptorSimulcast(t *testing.T) {
+	d := NewDependencyDescriptor(logger.GetLogger())
+	d.SetMax(buffer.VideoLayer{Spatial: 1, Temporal: 2})
+	d.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 2})
+
+	// each stream has its own frame numbers
+	result := d.Select(l1t3Packet(500, true, 1), 0)
+	require.True(t, result.IsSelected)
+	require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 2}, d.GetCurrent())
+
+	// a key frame of a stream above the target does not switch
+	result = d.Select(l1t3Packet(20, true, 2), 1)
+	require.False(t, result.IsSelected)
+	require.False(t, result.IsRelevant)
+
+	result = d.Select(l1t3Packet(501, false, 1), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.IsRelevant)
+	require.Equal(t, uint16(501), forwardedFrameNumber(t, result))
+
+	d.SetTarget(buffer.VideoLayer{Spatial: 1, Temporal: 2})
+
+	// keeps forwarding the lower stream till a key frame of the target stream
+	require.False(t, d.Select(l1t3Packet(21, false, 2), 1).IsSelected)
+	require.True(t, d.Select(l1t3Packet(502, false, 1), 0).IsSelected)
+
+	result = d.Select(l1t3Packet(24, true, 2), 1)
+	require.True(t, result.IsSelected)
+	require.True(t, result.IsSwitchingToMaxSpatial)
+	require.Equal(t, buffer.VideoLayer{Spatial: 1, Temporal: 2}, d.GetCurrent())
+	// frame numbers continue the forwarded ones
+	require.Equal(t, uint16(503), forwardedFrameNumber(t, result))
+
+	// the previous stream is not forwarded anymore
+	result = d.Select(l1t3Packet(503, false, 1), 0)
+	require.False(t, result.IsSelected)
+	require.False(t, result.IsRelevant)
+
+	result = d.Select(l1t3Packet(25, false, 2), 1)
+	require.True(t, result.IsSelected)
+	require.Equal(t, uint16(504), forwardedFrameNumber(t, result))
+}
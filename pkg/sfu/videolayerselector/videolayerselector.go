@@ -17,6 +17,7 @@ type VideoLayerSelectorResult struct {
 
 type VideoLayerSelector interface {
 	IsOvershootOkay() bool
+	IsKeyFrameNeededToSwitch(fromSpatial int32, toSpatial int32) bool
 
 	SetTemporalLayerSelector(tls temporallayerselector.TemporalLayerSelector)
 
@@ -26,6 +26,13 @@ func (v *VP9) IsOvershootOkay() bool {
 	return false
 }
 
+// IsKeyFrameNeededToSwitch - lower spatial layers never predict from higher ones, neither in full SVC nor in k-SVC
+// where spatial layers depend on each other only in key pictures, so switching down can happen at the end of a frame.
+// Switching up needs a key picture in k-SVC, and in full SVC as higher layers also predict from their own past frames.
+func (v *VP9) IsKeyFrameNeededToSwitch(fromSpatial int32, toSpatial int32) bool {
+	return toSpatial > fromSpatial
+}
+
 func (v *VP9) Select(extPkt *buffer.ExtPacket, _layer int32) (result VideoLayerSelectorResult) {
 	vp9, ok := extPkt.Payload.(codecs.VP9Packet)
 	if !ok {
@@ -42,6 +49,7 @@ func (v *VP9) Select(extPkt *buffer.ExtPacket, _layer int32) (result VideoLayerS
 			}
 
 			updatedLayer = extPkt.VideoLayer
+			currentLayer = updatedLayer
 		} else {
 			if v.currentLayer.Temporal != v.targetLayer.Temporal {
 				if v.currentLayer.Temporal < v.targetLayer.Temporal {
@@ -66,8 +74,10 @@ func (v *VP9) Select(extPkt *buffer.ExtPacket, _layer int32) (result VideoLayerS
 						updatedLayer.Spatial = extPkt.VideoLayer.Spatial
 					}
 				} else {
-					// spatial scale down
-					if vp9.E {
+					// spatial scale down at the end of a frame of the target layer, no key frame needed,
+					// higher layers of the picture are dropped
+					if vp9.E && extPkt.VideoLayer.Spatial == v.targetLayer.Spatial {
+						currentLayer.Spatial = v.targetLayer.Spatial
 						updatedLayer.Spatial = v.targetLayer.Spatial
 					}
 				}
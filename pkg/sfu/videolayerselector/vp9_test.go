@@ -0,0 +1,96 @@
+package videolayerselector
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+// kSVCPacket returns the single packet of a spatial layer frame of an L3T1_KEY picture,
+// spatial layers predict from the ones below only in key pictures
+func kSVCPacket(spatial int32, isKeyPicture bool) *buffer.ExtPacket {
+	return &buffer.ExtPacket{
+		Packet: &rtp.Packet{
+			Header: rtp.Header{
+				Marker: spatial == 2,
+			},
+		},
+		KeyFrame: isKeyPicture && spatial == 0,
+		VideoLayer: buffer.VideoLayer{
+			Spatial:  spatial,
+			Temporal: 0,
+		},
+		Payload: codecs.VP9Packet{
+			P:   !isKeyPicture,
+			B:   true,
+			E:   true,
+			SID: uint8(spatial),
+			D:   isKeyPicture && spatial != 0,
+		},
+	}
+}
+
+func TestVP9KSVCSwitchDown(t *testing.T) {
+	v := NewVP9(logger.GetLogger())
+	v.SetTarget(buffer.VideoLayer{Spatial: 2, Temporal: 0})
+
+	// resumes at the key picture
+	result := v.Select(kSVCPacket(0, true), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.IsResuming)
+	require.True(t, v.Select(kSVCPacket(1, true), 0).IsSelected)
+	result = v.Select(kSVCPacket(2, true), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.RTPMarker)
+	require.Equal(t, buffer.VideoLayer{Spatial: 2, Temporal: 0}, v.GetCurrent())
+
+	// lower layers are forwarded, end of picture is at the highest forwarded layer
+	result = v.Select(kSVCPacket(0, false), 0)
+	require.True(t, result.IsSelected)
+	require.False(t, result.RTPMarker)
+	require.True(t, v.Select(kSVCPacket(1, false), 0).IsSelected)
+	require.True(t, v.Select(kSVCPacket(2, false), 0).IsSelected)
+
+	require.False(t, v.IsKeyFrameNeededToSwitch(2, 0))
+	v.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 0})
+
+	// switches down at the end of the target layer frame without a key picture
+	result = v.Select(kSVCPacket(0, false), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.RTPMarker)
+	require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 0}, v.GetCurrent())
+	require.False(t, v.Select(kSVCPacket(1, false), 0).IsSelected)
+	require.False(t, v.Select(kSVCPacket(2, false), 0).IsSelected)
+
+	result = v.Select(kSVCPacket(0, false), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.RTPMarker)
+}
+
+func TestVP9KSVCSwitchUp(t *testing.T) {
+	v := NewVP9(logger.GetLogger())
+	v.SetTarget(buffer.VideoLayer{Spatial: 0, Temporal: 0})
+	require.True(t, v.Select(kSVCPacket(0, true), 0).IsSelected)
+	require.False(t, v.Select(kSVCPacket(1, true), 0).IsSelected)
+
+	require.True(t, v.IsKeyFrameNeededToSwitch(0, 1))
+	v.SetTarget(buffer.VideoLayer{Spatial: 1, Temporal: 0})
+
+	// higher layer frames of pictures other than key pictures are not decodable without its past frames
+	require.True(t, v.Select(kSVCPacket(0, false), 0).IsSelected)
+	require.False(t, v.Select(kSVCPacket(1, false), 0).IsSelected)
+	require.Equal(t, buffer.VideoLayer{Spatial: 0, Temporal: 0}, v.GetCurrent())
+
+	require.True(t, v.Select(kSVCPacket(0, true), 0).IsSelected)
+	result := v.Select(kSVCPacket(1, true), 0)
+	require.True(t, result.IsSelected)
+	require.True(t, result.RTPMarker)
+	require.Equal(t, buffer.VideoLayer{Spatial: 1, Temporal: 0}, v.GetCurrent())
+	require.False(t, v.Select(kSVCPacket(2, true), 0).IsSelected)
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/geoip"
+)
+
+// The location of clients is not part of AnalyticsClientMeta yet, it is appended as extra fields that analytics
+// consumers unaware of them skip:
+//
+//	AnalyticsClientMeta.country_code = 100;
+//	AnalyticsClientMeta.client_region = 101;
+//	AnalyticsClientMeta.city = 102;
+const (
+	clientMetaCountryCodeField  protowire.Number = 100
+	clientMetaClientRegionField protowire.Number = 101
+	clientMetaCityField         protowire.Number = 102
+)
+
+// SetClientLocation attaches the location of a client to its analytics metadata
+func SetClientLocation(meta *livekit.AnalyticsClientMeta, loc *geoip.Location) {
+	if loc == nil {
+		return
+	}
+
+	m := meta.ProtoReflect()
+	unknown := m.GetUnknown()
+	for _, f := range []struct {
+		num   protowire.Number
+		value string
+	}{
+		{clientMetaCountryCodeField, loc.CountryCode},
+		{clientMetaClientRegionField, loc.Region},
+		{clientMetaCityField, loc.City},
+	} {
+		if f.value == "" {
+			continue
+		}
+		unknown = protowire.AppendTag(unknown, f.num, protowire.BytesType)
+		unknown = protowire.AppendString(unknown, f.value)
+	}
+	m.SetUnknown(unknown)
+}
+
+// clientCountryCode returns the country code of a client attached to its analytics metadata, if any
+func clientCountryCode(meta *livekit.AnalyticsClientMeta) string {
+	if meta == nil {
+		return ""
+	}
+
+	var countryCode string
+	b := meta.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return countryCode
+		}
+		b = b[n:]
+		if num == clientMetaCountryCodeField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return countryCode
+			}
+			countryCode = v
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return countryCode
+		}
+		b = b[n:]
+	}
+	return countryCode
+}
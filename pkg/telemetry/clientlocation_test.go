@@ -0,0 +1,43 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/geoip"
+)
+
+func TestSetClientLocation(t *testing.T) {
+	meta := &livekit.AnalyticsClientMeta{Region: "us-east", Node: "node"}
+	SetClientLocation(meta, nil)
+	require.Empty(t, meta.ProtoReflect().GetUnknown())
+
+	SetClientLocation(meta, &geoip.Location{CountryCode: "GB", City: "London"})
+	data, err := proto.Marshal(meta)
+	require.NoError(t, err)
+	decoded := &livekit.AnalyticsClientMeta{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+	require.Equal(t, "us-east", decoded.Region)
+
+	fields := map[protowire.Number]string{}
+	unknown := decoded.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, _, n := protowire.ConsumeTag(unknown)
+		require.Positive(t, n)
+		value, m := protowire.ConsumeString(unknown[n:])
+		require.Positive(t, m)
+		fields[num] = value
+		unknown = unknown[n+m:]
+	}
+	require.Equal(t, map[protowire.Number]string{
+		clientMetaCountryCodeField: "GB",
+		clientMetaCityField:        "London",
+	}, fields)
+	require.Equal(t, "GB", clientCountryCode(decoded))
+	require.Empty(t, clientCountryCode(&livekit.AnalyticsClientMeta{}))
+}
@@ -0,0 +1,391 @@
+package errorreporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/version"
+)
+
+const (
+	// events are dropped rather than blocking media goroutines when the queue is full
+	eventQueueSize = 100
+	sendTimeout    = 5 * time.Second
+	maxStackFrames = 64
+)
+
+var (
+	ErrInvalidDSN         = errors.New("invalid error reporting DSN")
+	ErrInvalidPanicPolicy = errors.New("panic policy must be recover or exit")
+)
+
+var (
+	lock        sync.RWMutex
+	reporter    *Reporter
+	panicPolicy = config.PanicPolicyRecover
+)
+
+// Init sets up reporting to the configured DSN, and the panic policy used by Recover
+func Init(conf config.ErrorReportingConfig, nodeID string, env string) error {
+	policy := conf.PanicPolicy
+	switch policy {
+	case "":
+		policy = config.PanicPolicyRecover
+	case config.PanicPolicyRecover, config.PanicPolicyExit:
+	default:
+		return ErrInvalidPanicPolicy
+	}
+
+	var r *Reporter
+	if conf.DSN != "" {
+		var err error
+		if r, err = NewReporter(conf.DSN, nodeID, env); err != nil {
+			return err
+		}
+		r.Start()
+	}
+
+	lock.Lock()
+	prev := reporter
+	reporter = r
+	panicPolicy = policy
+	lock.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+	return nil
+}
+
+// Close sends queued events and stops reporting
+func Close() {
+	lock.Lock()
+	r := reporter
+	reporter = nil
+	lock.Unlock()
+
+	if r != nil {
+		r.Close()
+	}
+}
+
+func getReporter() (*Reporter, config.PanicPolicy) {
+	lock.RLock()
+	defer lock.RUnlock()
+	return reporter, panicPolicy
+}
+
+// CaptureError reports an error that was handled, keysAndValues are attached as tags
+func CaptureError(err error, keysAndValues ...interface{}) {
+	if r, _ := getReporter(); r != nil {
+		r.CaptureError(err, keysAndValues...)
+	}
+}
+
+// Recover reports a panic of the calling goroutine, it must be deferred directly.
+// With the recover policy the goroutine unwinds normally so that deferred cleanup closes what it was serving,
+// otherwise the panic continues once reported
+func Recover(l logger.Logger, keysAndValues ...interface{}) {
+	if p := recover(); p != nil {
+		HandlePanic(p, l, keysAndValues...)
+	}
+}
+
+// HandlePanic reports a recovered panic, see Recover
+func HandlePanic(p any, l logger.Logger, keysAndValues ...interface{}) {
+	if l == nil {
+		l = logger.GetLogger()
+	}
+	err := panicError(p)
+	l.Errorw("recovered panic", err, append([]interface{}{"panic", p}, keysAndValues...)...)
+
+	r, policy := getReporter()
+	if r != nil {
+		r.CapturePanic(p, keysAndValues...)
+	}
+	if policy == config.PanicPolicyExit {
+		if r != nil {
+			r.Close()
+		}
+		panic(p)
+	}
+}
+
+func panicError(p any) error {
+	switch e := p.(type) {
+	case error:
+		return e
+	case string:
+		return errors.New(e)
+	default:
+		return fmt.Errorf("%v", p)
+	}
+}
+
+// ------------------------------------------------
+
+// Reporter sends events to a Sentry compatible store endpoint
+type Reporter struct {
+	storeURL string
+	auth     string
+	nodeID   string
+	env      string
+	client   *http.Client
+
+	lock   sync.RWMutex
+	closed bool
+	events chan *event
+	done   chan struct{}
+}
+
+func NewReporter(dsn string, nodeID string, env string) (*Reporter, error) {
+	storeURL, auth, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Reporter{
+		storeURL: storeURL,
+		auth:     auth,
+		nodeID:   nodeID,
+		env:      env,
+		client:   &http.Client{Timeout: sendTimeout},
+		events:   make(chan *event, eventQueueSize),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// parseDSN returns the store endpoint and auth header of a DSN, https://<key>[:<secret>]@<host>[/<path>]/<project>
+func parseDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User == nil || u.User.Username() == "" {
+		return "", "", ErrInvalidDSN
+	}
+	prefix, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if project == "" {
+		return "", "", ErrInvalidDSN
+	}
+
+	storeURL := fmt.Sprintf("%s://%s%sapi/%s/store/", u.Scheme, u.Host, prefix, project)
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=livekit-server/%s, sentry_key=%s", version.Version, u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	return storeURL, auth, nil
+}
+
+func (r *Reporter) Start() {
+	go r.sendWorker()
+}
+
+// Close sends queued events, giving up after the send timeout
+func (r *Reporter) Close() {
+	r.lock.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.lock.Unlock()
+
+	select {
+	case <-r.done:
+	case <-time.After(sendTimeout):
+	}
+}
+
+func (r *Reporter) CaptureError(err error, keysAndValues ...interface{}) {
+	e := r.newEvent("error", keysAndValues)
+	e.Exception = &exceptions{Values: []exception{{
+		Type:       fmt.Sprintf("%T", err),
+		Value:      err.Error(),
+		Stacktrace: stacktrace(3, false),
+	}}}
+	r.enqueue(e)
+}
+
+func (r *Reporter) CapturePanic(p any, keysAndValues ...interface{}) {
+	e := r.newEvent("fatal", keysAndValues)
+	e.Exception = &exceptions{Values: []exception{{
+		Type:       "panic",
+		Value:      panicError(p).Error(),
+		Stacktrace: stacktrace(4, true),
+	}}}
+	r.enqueue(e)
+}
+
+func (r *Reporter) enqueue(e *event) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.events <- e:
+	default:
+		logger.Warnw("error reporting queue full, dropping event", nil, "eventID", e.EventID)
+	}
+}
+
+func (r *Reporter) sendWorker() {
+	defer close(r.done)
+
+	for e := range r.events {
+		if err := r.send(e); err != nil {
+			logger.Warnw("could not send error report", err, "eventID", e.EventID)
+		}
+	}
+}
+
+func (r *Reporter) send(e *event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+func (r *Reporter) newEvent(level string, keysAndValues []interface{}) *event {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	e := &event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Level:       level,
+		Platform:    "go",
+		Release:     "livekit-server@" + version.Version,
+		Environment: r.env,
+		ServerName:  r.nodeID,
+		Tags:        make(map[string]string),
+		Extra:       make(map[string]interface{}),
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		// tag values are limited to 200 characters
+		key := fmt.Sprint(keysAndValues[i])
+		if value := fmt.Sprint(keysAndValues[i+1]); len(value) <= 200 && !strings.Contains(value, "\n") {
+			e.Tags[key] = value
+		} else {
+			e.Extra[key] = value
+		}
+	}
+	return e
+}
+
+// stacktrace of the caller, for panics it starts where the panic was raised
+func stacktrace(skip int, fromPanic bool) *stack {
+	pcs := make([]uintptr, maxStackFrames)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var collected []frame
+	for {
+		f, more := frames.Next()
+		if fromPanic && f.Function == "runtime.gopanic" {
+			// drop the deferred handlers
+			collected = collected[:0]
+			fromPanic = false
+			if !more {
+				break
+			}
+			continue
+		}
+		module, function := splitFunction(f.Function)
+		collected = append(collected, frame{
+			Function: function,
+			Module:   module,
+			Filename: path.Base(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(module, "github.com/livekit/"),
+		})
+		if !more {
+			break
+		}
+	}
+
+	// oldest frame first
+	for i, j := 0, len(collected)-1; i < j; i, j = i+1, j-1 {
+		collected[i], collected[j] = collected[j], collected[i]
+	}
+	return &stack{Frames: collected}
+}
+
+// splitFunction splits github.com/livekit/livekit-server/pkg/sfu.(*WebRTCReceiver).forwardRTP into package and function
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot], name[slash+2+dot:]
+	}
+	return "", name
+}
+
+// ------------------------------------------------
+
+type event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   *exceptions            `json:"exception,omitempty"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace *stack `json:"stacktrace,omitempty"`
+}
+
+type stack struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/pion/webrtc/v3"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/livekit/livekit-server/pkg/rtc/types"
 	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -13,7 +16,37 @@ import (
 	"github.com/livekit/protocol/webhook"
 )
 
+const (
+	// EventParticipantUnstable is sent when a participant is likely to disconnect, apps can use it to warn the user
+	EventParticipantUnstable = "participant_unstable"
+	// EventParticipantNetworkChanged is sent when a participant's media moved to another network without
+	// reconnecting, the candidate pairs before and after are in the network_change field
+	EventParticipantNetworkChanged = "participant_network_changed"
+	// EventRoomManifest is sent after room_finished once the artifacts of the room are known, the manifest is
+	// in the manifest field
+	EventRoomManifest = "room_manifest"
+	// EventRoomDataDeleted is sent when a retention policy deleted the data of a closed room, what was deleted is
+	// in the retention field
+	EventRoomDataDeleted = "room_data_deleted"
+	// EventSegmentStarted, EventSegmentUploaded and EventSegmentFailed follow the segments of segmented egress
+	// outputs, the segment is in the segment field
+	EventSegmentStarted  = "segment_started"
+	EventSegmentUploaded = "segment_uploaded"
+	EventSegmentFailed   = "segment_failed"
+	// EventRoomPresenceChanged is sent when participant, publisher or watch counts or counters of a room changed,
+	// the presence is in the presence field
+	EventRoomPresenceChanged = "room_presence_changed"
+	// EventIngestHealthChanged is sent when the warnings of a stream pushed by an encoder changed, its health is in
+	// the ingest field
+	EventIngestHealthChanged = "ingest_health_changed"
+)
+
 func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.WebhookEvent) {
+	t.NotifyEventWithFields(ctx, event, nil)
+}
+
+// NotifyEventWithFields adds fields to the payload when the notifier supports it, e.g. the session summary
+func (t *telemetryService) NotifyEventWithFields(ctx context.Context, event *livekit.WebhookEvent, fields map[string]interface{}) {
 	if t.notifier == nil {
 		return
 	}
@@ -21,7 +54,13 @@ func (t *telemetryService) NotifyEvent(ctx context.Context, event *livekit.Webho
 	event.CreatedAt = time.Now().Unix()
 	event.Id = utils.NewGuid("EV_")
 
-	if err := t.notifier.QueueNotify(ctx, event); err != nil {
+	var err error
+	if n, ok := t.notifier.(fieldsNotifier); ok && len(fields) != 0 {
+		err = n.QueueNotifyWithFields(ctx, event, fields)
+	} else {
+		err = t.notifier.QueueNotify(ctx, event)
+	}
+	if err != nil {
 		logger.Warnw("failed to notify webhook", err, "event", event.Event)
 	}
 }
@@ -69,13 +108,14 @@ func (t *telemetryService) ParticipantJoined(
 		prometheus.IncrementParticipantRtcConnected(1)
 		prometheus.AddParticipant()
 
-		t.createWorker(
+		worker := t.createWorker(
 			ctx,
 			livekit.RoomID(room.Sid),
 			livekit.RoomName(room.Name),
 			livekit.ParticipantID(participant.Sid),
 			livekit.ParticipantIdentity(participant.Identity),
 		)
+		worker.SetClient(clientNetworkType(clientInfo), clientCountryCode(clientMeta))
 
 		if shouldSendEvent {
 			ev := newParticipantEvent(livekit.AnalyticsEventType_PARTICIPANT_JOINED, room, participant)
@@ -114,6 +154,8 @@ func (t *telemetryService) ParticipantActive(
 
 			// need to also account for participant count
 			prometheus.AddParticipant()
+		} else if networkType, clientRegion, hasClient := worker.Client(); hasClient && !worker.IsConnected() {
+			prometheus.RecordICEConnection(networkType, clientRegion, true)
 		}
 		worker.SetConnected()
 
@@ -144,14 +186,26 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 	room *livekit.Room,
 	participant *livekit.ParticipantInfo,
 	shouldSendEvent bool,
+	sessionEnd *ParticipantSessionEnd,
 ) {
 	t.enqueue(func() {
 		isConnected := false
 		hasWorker := false
+		var summary *ParticipantSessionSummary
 		if worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid)); ok {
 			hasWorker = true
 			isConnected = worker.IsConnected()
 			worker.Close()
+
+			if networkType, clientRegion, hasClient := worker.Client(); hasClient && !isConnected && isICEFailure(sessionEnd) {
+				prometheus.RecordICEConnection(networkType, clientRegion, false)
+			}
+
+			var joinedAt time.Time
+			if participant.JoinedAt != 0 {
+				joinedAt = time.Unix(participant.JoinedAt, 0)
+			}
+			summary = worker.SessionSummary(joinedAt, sessionEnd)
 		}
 
 		if hasWorker {
@@ -160,17 +214,55 @@ func (t *telemetryService) ParticipantLeft(ctx context.Context,
 		}
 
 		if isConnected && shouldSendEvent {
-			t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			t.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
 				Event:       webhook.EventParticipantLeft,
 				Room:        room,
 				Participant: participant,
-			})
+			}, map[string]interface{}{"session_summary": summary})
 
 			t.SendEvent(ctx, newParticipantEvent(livekit.AnalyticsEventType_PARTICIPANT_LEFT, room, participant))
 		}
 	})
 }
 
+func (t *telemetryService) ParticipantUnstable(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+) {
+	t.enqueue(func() {
+		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantUnstable,
+			Room:        room,
+			Participant: participant,
+		})
+	})
+}
+
+func (t *telemetryService) ParticipantNetworkChanged(
+	ctx context.Context,
+	room *livekit.Room,
+	participant *livekit.ParticipantInfo,
+	target livekit.SignalTarget,
+	previous *webrtc.ICECandidatePair,
+	current *webrtc.ICECandidatePair,
+) {
+	t.enqueue(func() {
+		if worker, ok := t.getWorker(livekit.ParticipantID(participant.Sid)); ok {
+			worker.OnNetworkChange()
+		}
+
+		t.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
+			Event:       EventParticipantNetworkChanged,
+			Room:        room,
+			Participant: participant,
+		}, map[string]interface{}{"network_change": &NetworkChange{
+			Previous: newICECandidatePair(target, previous),
+			Current:  newICECandidatePair(target, current),
+		}})
+	})
+}
+
 func (t *telemetryService) TrackPublishRequested(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
@@ -203,12 +295,17 @@ func (t *telemetryService) TrackPublished(
 			Sid:      string(participantID),
 			Identity: string(identity),
 		}
-		t.NotifyEvent(ctx, &livekit.WebhookEvent{
+		// the encryption status is not part of TrackInfo in JSON payloads, it is in the track_encryption field
+		var fields map[string]interface{}
+		if status := types.GetTrackEncryptionStatus(track); status != nil {
+			fields = map[string]interface{}{"track_encryption": status}
+		}
+		t.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
 			Event:       webhook.EventTrackPublished,
 			Room:        room,
 			Participant: participant,
 			Track:       track,
-		})
+		}, fields)
 
 		ev := newTrackEvent(livekit.AnalyticsEventType_TRACK_PUBLISHED, room, participantID, track)
 		ev.Participant = participant
@@ -380,6 +477,28 @@ func (t *telemetryService) TrackPublishRTPStats(
 	})
 }
 
+func (t *telemetryService) TrackHistograms(
+	ctx context.Context,
+	participantID livekit.ParticipantID,
+	identity livekit.ParticipantIdentity,
+	track *livekit.TrackInfo,
+	mimeType string,
+	jitter *hdrhistogram.Histogram,
+	delay *hdrhistogram.Histogram,
+) {
+	t.enqueue(func() {
+		t.NotifyEventWithFields(ctx, &livekit.WebhookEvent{
+			Event: EventTrackHistograms,
+			Room:  t.getRoomDetails(participantID),
+			Participant: &livekit.ParticipantInfo{
+				Sid:      string(participantID),
+				Identity: string(identity),
+			},
+			Track: track,
+		}, map[string]interface{}{"histograms": newTrackHistograms(track, mimeType, jitter, delay)})
+	})
+}
+
 func (t *telemetryService) TrackSubscribeRTPStats(
 	ctx context.Context,
 	participantID livekit.ParticipantID,
@@ -2,12 +2,21 @@ package telemetry_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/pion/webrtc/v3"
 	"github.com/stretchr/testify/require"
 
 	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/telemetry/prometheus"
+	"github.com/livekit/livekit-server/pkg/telemetry/telemetryfakes"
 )
 
 func Test_OnParticipantJoin_EventIsSent(t *testing.T) {
@@ -70,7 +79,7 @@ func Test_OnParticipantLeft_EventIsSent(t *testing.T) {
 
 	// do
 	fixture.sut.ParticipantActive(context.Background(), room, participantInfo, &livekit.AnalyticsClientMeta{})
-	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, true, nil)
 	time.Sleep(time.Millisecond * 500)
 
 	// test
@@ -222,3 +231,220 @@ func Test_OnTrackSubscribed_EventIsSent(t *testing.T) {
 	require.Equal(t, publisherInfo.Identity, eventTrackSubscribed.Publisher.Identity)
 
 }
+
+type fieldsRecorder struct {
+	lock   sync.Mutex
+	events []*livekit.WebhookEvent
+	fields []map[string]interface{}
+}
+
+func (r *fieldsRecorder) QueueNotify(ctx context.Context, event *livekit.WebhookEvent) error {
+	return r.QueueNotifyWithFields(ctx, event, nil)
+}
+
+func (r *fieldsRecorder) QueueNotifyWithFields(_ context.Context, event *livekit.WebhookEvent, fields map[string]interface{}) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event)
+	r.fields = append(r.fields, fields)
+	return nil
+}
+
+func Test_OnParticipantLeft_SessionSummaryIsSent(t *testing.T) {
+	notifier := &fieldsRecorder{}
+	sut := telemetry.NewTelemetryService(notifier, &telemetryfakes.FakeAnalyticsService{})
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID), JoinedAt: time.Now().Add(-time.Minute).Unix()}
+	sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	sut.ParticipantActive(context.Background(), room, participantInfo, nil)
+
+	key := telemetry.StatsKeyForTrack(livekit.StreamType_UPSTREAM, partSID, "TR_1", livekit.TrackSource_CAMERA, livekit.TrackType_VIDEO)
+	sut.TrackStats(key, &livekit.AnalyticsStat{Score: 4, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 1000, PrimaryPackets: 10, PacketsLost: 1}}})
+	sut.TrackStats(key, &livekit.AnalyticsStat{Score: 5, Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 500, PrimaryPackets: 5}}})
+	sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, ""), &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 20}}})
+
+	sut.ParticipantLeft(context.Background(), room, participantInfo, true, &telemetry.ParticipantSessionEnd{
+		Reason: livekit.DisconnectReason_CLIENT_INITIATED,
+		ICECandidatePairs: map[livekit.SignalTarget]*webrtc.ICECandidatePair{
+			livekit.SignalTarget_PUBLISHER: {
+				Local:  &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeHost, Protocol: webrtc.ICEProtocolUDP},
+				Remote: &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeSrflx, Protocol: webrtc.ICEProtocolUDP},
+			},
+		},
+	})
+
+	var summary *telemetry.ParticipantSessionSummary
+	require.Eventually(t, func() bool {
+		notifier.lock.Lock()
+		defer notifier.lock.Unlock()
+		for i, e := range notifier.events {
+			if e.Event == webhook.EventParticipantLeft {
+				summary = notifier.fields[i]["session_summary"].(*telemetry.ParticipantSessionSummary)
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	require.InDelta(t, 60, summary.DurationSeconds, 2)
+	require.Equal(t, "CLIENT_INITIATED", summary.DisconnectReason)
+	require.Equal(t, []*telemetry.ICECandidatePair{{Transport: "PUBLISHER", Protocol: "udp", LocalType: "host", RemoteType: "srflx"}}, summary.ICECandidatePairs)
+	require.EqualValues(t, 1500, summary.BytesReceived)
+	require.EqualValues(t, 20, summary.BytesSent)
+	require.InDelta(t, 4.5, summary.AverageScore, 0.01)
+	require.Len(t, summary.PublishedTracks, 1)
+	require.Empty(t, summary.SubscribedTracks)
+	track := summary.PublishedTracks[0]
+	require.Equal(t, "TR_1", track.TrackID)
+	require.Equal(t, "VIDEO", track.Type)
+	require.Equal(t, "CAMERA", track.Source)
+	require.EqualValues(t, 1500, track.Bytes)
+	require.EqualValues(t, 15, track.Packets)
+	require.EqualValues(t, 1, track.PacketsLost)
+}
+
+func Test_OnParticipantNetworkChanged_EventIsSent(t *testing.T) {
+	notifier := &fieldsRecorder{}
+	sut := telemetry.NewTelemetryService(notifier, &telemetryfakes.FakeAnalyticsService{})
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "part1"}
+	sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+	sut.ParticipantActive(context.Background(), room, participantInfo, nil)
+
+	previous := &webrtc.ICECandidatePair{
+		Local:  &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeHost, Protocol: webrtc.ICEProtocolUDP},
+		Remote: &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeHost, Protocol: webrtc.ICEProtocolUDP, Address: "192.168.1.10", Port: 5000},
+	}
+	current := &webrtc.ICECandidatePair{
+		Local:  &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeHost, Protocol: webrtc.ICEProtocolUDP},
+		Remote: &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypePrflx, Protocol: webrtc.ICEProtocolUDP, Address: "203.0.113.4", Port: 6000},
+	}
+	sut.ParticipantNetworkChanged(context.Background(), room, participantInfo, livekit.SignalTarget_SUBSCRIBER, previous, current)
+	sut.ParticipantLeft(context.Background(), room, participantInfo, true, nil)
+
+	var change *telemetry.NetworkChange
+	var summary *telemetry.ParticipantSessionSummary
+	require.Eventually(t, func() bool {
+		notifier.lock.Lock()
+		defer notifier.lock.Unlock()
+		for i, e := range notifier.events {
+			switch e.Event {
+			case telemetry.EventParticipantNetworkChanged:
+				change = notifier.fields[i]["network_change"].(*telemetry.NetworkChange)
+			case webhook.EventParticipantLeft:
+				summary = notifier.fields[i]["session_summary"].(*telemetry.ParticipantSessionSummary)
+			}
+		}
+		return change != nil && summary != nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, &telemetry.NetworkChange{
+		Previous: &telemetry.ICECandidatePair{Transport: "SUBSCRIBER", Protocol: "udp", LocalType: "host", RemoteType: "host"},
+		Current:  &telemetry.ICECandidatePair{Transport: "SUBSCRIBER", Protocol: "udp", LocalType: "host", RemoteType: "prflx"},
+	}, change)
+	require.Equal(t, 1, summary.NetworkChanges)
+}
+
+func Test_OnTrackHistograms_EventIsSent(t *testing.T) {
+	notifier := &fieldsRecorder{}
+	sut := telemetry.NewTelemetryService(notifier, &telemetryfakes.FakeAnalyticsService{})
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	participantInfo := &livekit.ParticipantInfo{Sid: "part1", Identity: "identity1"}
+	sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
+
+	jitter := hdrhistogram.New(1, 10_000_000, 2)
+	delay := hdrhistogram.New(1, 10_000_000, 2)
+	for i := int64(1); i <= 100; i++ {
+		_ = jitter.RecordValue(i * 100)
+		_ = delay.RecordValue(i)
+	}
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_VIDEO}
+	sut.TrackHistograms(context.Background(), "part1", "identity1", track, "video/vp8", jitter, delay)
+
+	var event *livekit.WebhookEvent
+	var histograms *telemetry.TrackHistograms
+	require.Eventually(t, func() bool {
+		notifier.lock.Lock()
+		defer notifier.lock.Unlock()
+		for i, e := range notifier.events {
+			if e.Event == telemetry.EventTrackHistograms {
+				event = e
+				histograms = notifier.fields[i]["histograms"].(*telemetry.TrackHistograms)
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, "RoomSid", event.Room.Sid)
+	require.Equal(t, "identity1", event.Participant.Identity)
+	require.Equal(t, "TR_1", event.Track.Sid)
+	require.Equal(t, "video/vp8", histograms.Mime)
+	require.EqualValues(t, 100, histograms.Jitter.Count)
+	require.InDelta(t, 5000, histograms.Jitter.P50, 50)
+	require.InDelta(t, 100, histograms.ForwardingDelay.Max, 1)
+
+	decoded, err := hdrhistogram.Decode([]byte(histograms.Jitter.Encoded))
+	require.NoError(t, err)
+	require.True(t, jitter.Equals(decoded))
+}
+
+func Test_OnTrackPublished_EncryptionStatusIsSent(t *testing.T) {
+	notifier := &fieldsRecorder{}
+	sut := telemetry.NewTelemetryService(notifier, &telemetryfakes.FakeAnalyticsService{})
+
+	track := &livekit.TrackInfo{Sid: "TR_1", Type: livekit.TrackType_AUDIO, Encryption: livekit.Encryption_GCM}
+	status := types.NewTrackEncryptionStatus(track.Encryption)
+	status.KeyEpoch = 2
+	types.SetTrackEncryptionStatus(track, status)
+	sut.TrackPublished(context.Background(), "part1", "identity1", track)
+
+	var encryption *types.TrackEncryptionStatus
+	require.Eventually(t, func() bool {
+		notifier.lock.Lock()
+		defer notifier.lock.Unlock()
+		for i, e := range notifier.events {
+			if e.Event == webhook.EventTrackPublished {
+				encryption = notifier.fields[i]["track_encryption"].(*types.TrackEncryptionStatus)
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, &types.TrackEncryptionStatus{Encrypted: true, Cipher: types.CipherAESGCM, KeyEpoch: 2}, encryption)
+}
+
+func Test_ICEConnectionOutcomesAreRecorded(t *testing.T) {
+	sut := telemetry.NewTelemetryService(&fieldsRecorder{}, &telemetryfakes.FakeAnalyticsService{})
+
+	room := &livekit.Room{Sid: "RoomSid", Name: "RoomName"}
+	clientInfo := &livekit.ClientInfo{Network: "ice-outcome-test"}
+
+	connected := &livekit.ParticipantInfo{Sid: "connected"}
+	sut.ParticipantJoined(context.Background(), room, connected, clientInfo, nil, true)
+	sut.ParticipantActive(context.Background(), room, connected, nil)
+	// resuming does not count as another connection
+	sut.ParticipantActive(context.Background(), room, connected, nil)
+
+	failed := &livekit.ParticipantInfo{Sid: "failed"}
+	sut.ParticipantJoined(context.Background(), room, failed, clientInfo, nil, true)
+	sut.ParticipantLeft(context.Background(), room, failed, true, &telemetry.ParticipantSessionEnd{Reason: livekit.DisconnectReason_STATE_MISMATCH})
+
+	left := &livekit.ParticipantInfo{Sid: "left"}
+	sut.ParticipantJoined(context.Background(), room, left, clientInfo, nil, true)
+	sut.ParticipantLeft(context.Background(), room, left, true, &telemetry.ParticipantSessionEnd{Reason: livekit.DisconnectReason_CLIENT_INITIATED})
+
+	require.Eventually(t, func() bool {
+		for _, cs := range prometheus.GetICEConnectionStats() {
+			if cs.NetworkType == clientInfo.Network {
+				return cs.Attempts == 2 && cs.Successes == 1
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
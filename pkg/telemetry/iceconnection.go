@@ -0,0 +1,28 @@
+package telemetry
+
+import (
+	"github.com/livekit/protocol/livekit"
+)
+
+const unknownNetworkType = "unknown"
+
+func clientNetworkType(clientInfo *livekit.ClientInfo) string {
+	if clientInfo == nil || clientInfo.Network == "" {
+		return unknownNetworkType
+	}
+	return clientInfo.Network
+}
+
+// isICEFailure checks if a participant leaving before it became active did so because it could not connect, rather
+// than e. g. because it left or was removed
+func isICEFailure(sessionEnd *ParticipantSessionEnd) bool {
+	if sessionEnd == nil {
+		return false
+	}
+	switch sessionEnd.Reason {
+	case livekit.DisconnectReason_JOIN_FAILURE, livekit.DisconnectReason_STATE_MISMATCH:
+		return true
+	default:
+		return false
+	}
+}
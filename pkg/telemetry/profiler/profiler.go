@@ -0,0 +1,263 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+const (
+	defaultApplicationName = "livekit-server"
+	defaultUploadInterval  = 10 * time.Second
+	uploadTimeout          = 10 * time.Second
+)
+
+var (
+	// goroutines are only labelled while profiles are being pushed
+	enabled atomic.Bool
+
+	lock    sync.Mutex
+	current *Profiler
+
+	// held while the continuous CPU profile runs, or while it is paused
+	cpuLock sync.Mutex
+	// asks the worker to end its CPU profile early
+	pauseChan = make(chan struct{}, 1)
+)
+
+// Start pushes CPU and heap profiles to the configured server until Stop
+func Start(conf config.ContinuousProfilingConfig, nodeID string, env string) error {
+	if conf.ServerAddress == "" {
+		return nil
+	}
+	p, err := NewProfiler(conf, nodeID, env)
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+	prev := current
+	current = p
+	lock.Unlock()
+	if prev != nil {
+		prev.Stop()
+	}
+
+	p.Start()
+	enabled.Store(true)
+	return nil
+}
+
+func Stop() {
+	lock.Lock()
+	p := current
+	current = nil
+	lock.Unlock()
+
+	enabled.Store(false)
+	if p != nil {
+		p.Stop()
+	}
+}
+
+// PauseCPUProfile stops the continuous CPU profile so that the caller can capture its own, until resume is called.
+// the runtime allows only one CPU profile at a time
+func PauseCPUProfile() (resume func()) {
+	select {
+	case pauseChan <- struct{}{}:
+	default:
+	}
+	cpuLock.Lock()
+	// not needed once the lock is held, e.g. when no profiler is running
+	select {
+	case <-pauseChan:
+	default:
+	}
+	return cpuLock.Unlock
+}
+
+// SetGoroutineLabels labels samples of the calling goroutine, and of goroutines it starts, e.g. with the room
+// a media goroutine is serving. labels are key/value pairs
+func SetGoroutineLabels(labels ...string) {
+	if !enabled.Load() || len(labels) == 0 {
+		return
+	}
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(labels...)))
+}
+
+// ------------------------------------------------
+
+// Profiler pushes profiles to a Pyroscope compatible ingest endpoint
+type Profiler struct {
+	ingestURL string
+	appName   string
+	authToken string
+	interval  time.Duration
+	client    *http.Client
+
+	doneChan chan struct{}
+	stopped  chan struct{}
+}
+
+func NewProfiler(conf config.ContinuousProfilingConfig, nodeID string, env string) (*Profiler, error) {
+	u, err := url.Parse(conf.ServerAddress)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid continuous profiling server address %s", conf.ServerAddress)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ingest"
+
+	name := conf.ApplicationName
+	if name == "" {
+		name = defaultApplicationName
+	}
+	interval := conf.UploadInterval
+	if interval <= 0 {
+		interval = defaultUploadInterval
+	}
+
+	labels := map[string]string{"node_id": nodeID}
+	if env != "" {
+		labels["env"] = env
+	}
+	for k, v := range conf.Labels {
+		labels[k] = v
+	}
+
+	return &Profiler{
+		ingestURL: u.String(),
+		appName:   name + formatLabels(labels),
+		authToken: conf.AuthToken,
+		interval:  interval,
+		client:    &http.Client{Timeout: uploadTimeout},
+		doneChan:  make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}, nil
+}
+
+// formatLabels returns {k1=v1,k2=v2} sorted by key
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (p *Profiler) Start() {
+	go p.worker()
+}
+
+func (p *Profiler) Stop() {
+	select {
+	case <-p.doneChan:
+	default:
+		close(p.doneChan)
+	}
+	<-p.stopped
+}
+
+func (p *Profiler) worker() {
+	defer close(p.stopped)
+
+	for {
+		// waits while a profile is captured through the profiling API
+		cpuLock.Lock()
+		from := time.Now()
+		cpu := &bytes.Buffer{}
+		cpuErr := pprof.StartCPUProfile(cpu)
+		if cpuErr != nil {
+			logger.Debugw("could not start cpu profile", "error", cpuErr)
+		}
+
+		done := false
+		select {
+		case <-p.doneChan:
+			done = true
+		case <-pauseChan:
+		case <-time.After(p.interval):
+		}
+
+		until := time.Now()
+		if cpuErr == nil {
+			pprof.StopCPUProfile()
+		}
+		cpuLock.Unlock()
+		if cpuErr == nil {
+			p.upload(cpu, from, until)
+		}
+
+		heap := &bytes.Buffer{}
+		if err := pprof.Lookup("heap").WriteTo(heap, 0); err == nil {
+			p.upload(heap, from, until)
+		}
+
+		if done {
+			return
+		}
+	}
+}
+
+func (p *Profiler) upload(profile *bytes.Buffer, from time.Time, until time.Time) {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("profile", "profile.pprof")
+	if err == nil {
+		_, err = fw.Write(profile.Bytes())
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err != nil {
+		logger.Warnw("could not encode profile", err)
+		return
+	}
+
+	query := url.Values{
+		"name":       {p.appName},
+		"from":       {strconv.FormatInt(from.Unix(), 10)},
+		"until":      {strconv.FormatInt(until.Unix(), 10)},
+		"format":     {"pprof"},
+		"spyName":    {"gospy"},
+		"sampleRate": {"100"},
+	}
+	req, err := http.NewRequest(http.MethodPost, p.ingestURL+"?"+query.Encode(), body)
+	if err != nil {
+		logger.Warnw("could not create profile upload", err)
+		return
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if p.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.authToken)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		logger.Warnw("could not upload profile", err)
+		return
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 300 {
+		logger.Warnw("could not upload profile", nil, "status", res.StatusCode)
+	}
+}
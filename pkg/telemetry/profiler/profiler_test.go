@@ -0,0 +1,82 @@
+package profiler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/config"
+)
+
+func TestProfiler(t *testing.T) {
+	var lock sync.Mutex
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ingest", r.URL.Path)
+		require.Equal(t, "pprof", r.URL.Query().Get("format"))
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		f, _, err := r.FormFile("profile")
+		require.NoError(t, err)
+		_ = f.Close()
+
+		lock.Lock()
+		names = append(names, r.URL.Query().Get("name"))
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	require.NoError(t, Start(config.ContinuousProfilingConfig{
+		ServerAddress:  server.URL,
+		AuthToken:      "token",
+		UploadInterval: 50 * time.Millisecond,
+		Labels:         map[string]string{"cluster": "test"},
+	}, "ND_1", "prod"))
+
+	labelled := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		SetGoroutineLabels("room", "hot-room")
+		close(labelled)
+		<-release
+	}()
+	<-labelled
+	goroutines := &bytes.Buffer{}
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(goroutines, 1))
+	close(release)
+	require.Contains(t, goroutines.String(), `labels: {"room":"hot-room"}`)
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		// cpu and heap
+		return len(names) >= 2
+	}, time.Second, 10*time.Millisecond)
+	Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, "livekit-server{cluster=test,env=prod,node_id=ND_1}", names[0])
+}
+
+func TestPauseCPUProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	require.NoError(t, Start(config.ContinuousProfilingConfig{
+		ServerAddress:  server.URL,
+		UploadInterval: time.Minute,
+	}, "ND_1", ""))
+	defer Stop()
+
+	// the continuous profile is stopped early, and not restarted until resumed
+	resume := PauseCPUProfile()
+	require.NoError(t, pprof.StartCPUProfile(&bytes.Buffer{}))
+	pprof.StopCPUProfile()
+	resume()
+}
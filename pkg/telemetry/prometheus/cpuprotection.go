@@ -0,0 +1,58 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promCPUProtectionLevel              prometheus.Gauge
+	promCPUProtectionPausedParticipants prometheus.Gauge
+	promCPUProtectionRefusedTracks      prometheus.Counter
+)
+
+func initCPUProtectionStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promCPUProtectionLevel = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "cpu_protection",
+		Name:        "level",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promCPUProtectionPausedParticipants = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "cpu_protection",
+		Name:        "paused_participants",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promCPUProtectionRefusedTracks = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "cpu_protection",
+		Name:        "refused_tracks",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+
+	prometheus.MustRegister(promCPUProtectionLevel)
+	prometheus.MustRegister(promCPUProtectionPausedParticipants)
+	prometheus.MustRegister(promCPUProtectionRefusedTracks)
+}
+
+// SetCPUProtection records how far the node goes in shedding load, 0 when it does not, and the number of
+// participants whose video it paused
+func SetCPUProtection(level int, pausedParticipants int) {
+	if promCPUProtectionLevel == nil {
+		return
+	}
+
+	promCPUProtectionLevel.Set(float64(level))
+	promCPUProtectionPausedParticipants.Set(float64(pausedParticipants))
+}
+
+// RecordCPUProtectionRefusedTrack counts a video track refused because the node is overloaded
+func RecordCPUProtectionRefusedTrack() {
+	if promCPUProtectionRefusedTracks == nil {
+		return
+	}
+
+	promCPUProtectionRefusedTracks.Inc()
+}
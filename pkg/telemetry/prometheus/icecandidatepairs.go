@@ -0,0 +1,184 @@
+package prometheus
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+const (
+	ICEPathUDP   = "udp"
+	ICEPathTCP   = "tcp"
+	ICEPathRelay = "relay"
+
+	ICERestartInitiatorServer = "server"
+	ICERestartInitiatorClient = "client"
+)
+
+var (
+	promICECandidatePairTotal   *prometheus.CounterVec
+	promICECandidatePairCurrent *prometheus.GaugeVec
+	promICECandidatePairBytes   *prometheus.CounterVec
+	promICECandidatePairRTT     *prometheus.HistogramVec
+	promICERestartTotal         *prometheus.CounterVec
+)
+
+func initICECandidatePairStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promICECandidatePairTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ice_candidate_pair",
+		Name:        "total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"transport", "local_type", "remote_type", "protocol"})
+	promICECandidatePairCurrent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ice_candidate_pair",
+		Name:        "current",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"transport", "path"})
+	promICECandidatePairBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ice_candidate_pair",
+		Name:        "bytes",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"transport", "path", "direction"})
+	promICECandidatePairRTT = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ice_candidate_pair",
+		Name:        "rtt_ms",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+		Buckets:     []float64{10, 25, 50, 100, 150, 200, 300, 500, 1000, 2000},
+	}, []string{"path"})
+	promICERestartTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ice_restart",
+		Name:        "total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"transport", "initiator"})
+
+	prometheus.MustRegister(promICECandidatePairTotal)
+	prometheus.MustRegister(promICECandidatePairCurrent)
+	prometheus.MustRegister(promICECandidatePairBytes)
+	prometheus.MustRegister(promICECandidatePairRTT)
+	prometheus.MustRegister(promICERestartTotal)
+}
+
+// ICECandidatePair describes a selected candidate pair by the types of its candidates, host, srflx, prflx or relay,
+// and the protocol of the local candidate, udp or tcp
+type ICECandidatePair struct {
+	LocalType  string
+	RemoteType string
+	Protocol   string
+}
+
+// Path is how media of the pair travels, relay when either candidate is relayed by a TURN server, the protocol
+// otherwise
+func (p ICECandidatePair) Path() string {
+	if p.LocalType == ICEPathRelay || p.RemoteType == ICEPathRelay {
+		return ICEPathRelay
+	}
+	if p.Protocol == ICEPathTCP {
+		return ICEPathTCP
+	}
+	return ICEPathUDP
+}
+
+// ICETransportMetrics are the ICE metrics of a peer connection, bytes and round trip times are accounted to the path
+// of the candidate pair selected when they are recorded
+type ICETransportMetrics struct {
+	transport string
+
+	lock          sync.Mutex
+	path          string
+	bytesSent     uint64
+	bytesReceived uint64
+	closed        bool
+}
+
+// NewICETransportMetrics creates the metrics of a peer connection, transport being publisher or subscriber
+func NewICETransportMetrics(transport string) *ICETransportMetrics {
+	return &ICETransportMetrics{transport: transport}
+}
+
+func (m *ICETransportMetrics) SetSelectedPair(pair ICECandidatePair) {
+	path := pair.Path()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed || promICECandidatePairTotal == nil {
+		return
+	}
+
+	promICECandidatePairTotal.WithLabelValues(m.transport, pair.LocalType, pair.RemoteType, pair.Protocol).Inc()
+	if path == m.path {
+		return
+	}
+	if m.path != "" {
+		promICECandidatePairCurrent.WithLabelValues(m.transport, m.path).Dec()
+	}
+	promICECandidatePairCurrent.WithLabelValues(m.transport, path).Inc()
+	m.path = path
+}
+
+// RecordBytes records the bytes sent and received by the ICE transport so far, what was not recorded before is
+// accounted to the path of the selected pair. The counts start over when the transport is replaced
+func (m *ICETransportMetrics) RecordBytes(sent, received uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	sentDelta, receivedDelta := sent, received
+	if sent >= m.bytesSent {
+		sentDelta = sent - m.bytesSent
+	}
+	if received >= m.bytesReceived {
+		receivedDelta = received - m.bytesReceived
+	}
+	m.bytesSent, m.bytesReceived = sent, received
+	if m.path == "" || promICECandidatePairBytes == nil {
+		return
+	}
+
+	if sentDelta > 0 {
+		promICECandidatePairBytes.WithLabelValues(m.transport, m.path, string(Outgoing)).Add(float64(sentDelta))
+	}
+	if receivedDelta > 0 {
+		promICECandidatePairBytes.WithLabelValues(m.transport, m.path, string(Incoming)).Add(float64(receivedDelta))
+	}
+}
+
+// RecordRTT records a round trip time in milliseconds measured over the selected pair
+func (m *ICETransportMetrics) RecordRTT(rtt uint32) {
+	m.lock.Lock()
+	path := m.path
+	m.lock.Unlock()
+	if path == "" || promICECandidatePairRTT == nil {
+		return
+	}
+
+	promICECandidatePairRTT.WithLabelValues(path).Observe(float64(rtt))
+}
+
+// ICERestart counts an ICE restart, initiated by the server or by the client
+func (m *ICETransportMetrics) ICERestart(initiator string) {
+	if promICERestartTotal == nil {
+		return
+	}
+
+	promICERestartTotal.WithLabelValues(m.transport, initiator).Inc()
+}
+
+// Close removes the peer connection from the pairs currently selected
+func (m *ICETransportMetrics) Close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		return
+	}
+
+	m.closed = true
+	if m.path != "" && promICECandidatePairCurrent != nil {
+		promICECandidatePairCurrent.WithLabelValues(m.transport, m.path).Dec()
+	}
+}
@@ -0,0 +1,54 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestICECandidatePairPath(t *testing.T) {
+	require.Equal(t, ICEPathUDP, ICECandidatePair{LocalType: "host", RemoteType: "srflx", Protocol: "udp"}.Path())
+	require.Equal(t, ICEPathTCP, ICECandidatePair{LocalType: "host", RemoteType: "prflx", Protocol: "tcp"}.Path())
+	require.Equal(t, ICEPathRelay, ICECandidatePair{LocalType: "host", RemoteType: "relay", Protocol: "udp"}.Path())
+}
+
+func TestICETransportMetrics(t *testing.T) {
+	initICECandidatePairStats("test", livekit.NodeType_SERVER, "test")
+
+	m := NewICETransportMetrics("subscriber")
+	// nothing is accounted before a pair is selected
+	m.RecordBytes(100, 50)
+	m.RecordRTT(20)
+
+	m.SetSelectedPair(ICECandidatePair{LocalType: "host", RemoteType: "srflx", Protocol: "udp"})
+	require.Equal(t, 1.0, testutil.ToFloat64(promICECandidatePairCurrent.WithLabelValues("subscriber", ICEPathUDP)))
+	m.RecordBytes(1100, 550)
+	m.RecordRTT(20)
+	require.Equal(t, 1000.0, testutil.ToFloat64(promICECandidatePairBytes.WithLabelValues("subscriber", ICEPathUDP, string(Outgoing))))
+	require.Equal(t, 500.0, testutil.ToFloat64(promICECandidatePairBytes.WithLabelValues("subscriber", ICEPathUDP, string(Incoming))))
+
+	// the client moved to TURN
+	m.SetSelectedPair(ICECandidatePair{LocalType: "host", RemoteType: "relay", Protocol: "udp"})
+	require.Equal(t, 0.0, testutil.ToFloat64(promICECandidatePairCurrent.WithLabelValues("subscriber", ICEPathUDP)))
+	require.Equal(t, 1.0, testutil.ToFloat64(promICECandidatePairCurrent.WithLabelValues("subscriber", ICEPathRelay)))
+	m.RecordBytes(1600, 550)
+	require.Equal(t, 500.0, testutil.ToFloat64(promICECandidatePairBytes.WithLabelValues("subscriber", ICEPathRelay, string(Outgoing))))
+
+	// counts starting over
+	m.RecordBytes(200, 100)
+	require.Equal(t, 700.0, testutil.ToFloat64(promICECandidatePairBytes.WithLabelValues("subscriber", ICEPathRelay, string(Outgoing))))
+	require.Equal(t, 100.0, testutil.ToFloat64(promICECandidatePairBytes.WithLabelValues("subscriber", ICEPathRelay, string(Incoming))))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(promICECandidatePairTotal.WithLabelValues("subscriber", "host", "srflx", "udp"))+
+		testutil.ToFloat64(promICECandidatePairTotal.WithLabelValues("subscriber", "host", "relay", "udp")))
+
+	m.ICERestart(ICERestartInitiatorServer)
+	require.Equal(t, 1.0, testutil.ToFloat64(promICERestartTotal.WithLabelValues("subscriber", ICERestartInitiatorServer)))
+
+	m.Close()
+	m.Close()
+	require.Equal(t, 0.0, testutil.ToFloat64(promICECandidatePairCurrent.WithLabelValues("subscriber", ICEPathRelay)))
+}
@@ -0,0 +1,158 @@
+package prometheus
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+const (
+	// ICE connection outcomes published with node stats are those of the last few minutes, so that a node recovers
+	// once its failures have aged out
+	iceConnectionBucketDuration = time.Minute
+	iceConnectionBuckets        = 5
+
+	iceConnectionStatusSuccess = "success"
+	iceConnectionStatusFailure = "failure"
+)
+
+var (
+	promICEConnectionTotal *prometheus.CounterVec
+
+	iceConnections = newICEConnectionWindow(iceConnectionBucketDuration, iceConnectionBuckets)
+)
+
+func initICEConnectionStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promICEConnectionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "ice_connection",
+		Name:        "total",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"network_type", "client_region", "status"})
+
+	prometheus.MustRegister(promICEConnectionTotal)
+}
+
+// RecordICEConnection records whether the ICE connection of a participant was established, networkType being the
+// type of network the client reported and clientRegion the country it was located in, if known
+func RecordICEConnection(networkType string, clientRegion string, connected bool) {
+	iceConnections.record(time.Now(), networkType, clientRegion, connected)
+
+	if promICEConnectionTotal != nil {
+		status := iceConnectionStatusFailure
+		if connected {
+			status = iceConnectionStatusSuccess
+		}
+		promICEConnectionTotal.WithLabelValues(networkType, clientRegion, status).Inc()
+	}
+}
+
+// GetICEConnectionStats returns the ICE connection outcomes of the last few minutes
+func GetICEConnectionStats() []*selector.ICEConnectionStats {
+	return iceConnections.stats(time.Now())
+}
+
+// ---------------------------------------------
+
+type iceConnectionKey struct {
+	networkType  string
+	clientRegion string
+}
+
+type iceConnectionCounts struct {
+	attempts  uint32
+	successes uint32
+}
+
+type iceConnectionBucket struct {
+	index  int64
+	counts map[iceConnectionKey]*iceConnectionCounts
+}
+
+// iceConnectionWindow counts outcomes in a ring of buckets, a bucket being reused once it is out of the window
+type iceConnectionWindow struct {
+	bucketDuration time.Duration
+
+	lock    sync.Mutex
+	buckets []iceConnectionBucket
+}
+
+func newICEConnectionWindow(bucketDuration time.Duration, buckets int) *iceConnectionWindow {
+	return &iceConnectionWindow{
+		bucketDuration: bucketDuration,
+		buckets:        make([]iceConnectionBucket, buckets),
+	}
+}
+
+func (w *iceConnectionWindow) bucketIndex(at time.Time) int64 {
+	return at.UnixNano() / int64(w.bucketDuration)
+}
+
+func (w *iceConnectionWindow) record(at time.Time, networkType string, clientRegion string, connected bool) {
+	index := w.bucketIndex(at)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	bucket := &w.buckets[index%int64(len(w.buckets))]
+	if bucket.index != index || bucket.counts == nil {
+		bucket.index = index
+		bucket.counts = make(map[iceConnectionKey]*iceConnectionCounts)
+	}
+
+	key := iceConnectionKey{networkType: networkType, clientRegion: clientRegion}
+	counts := bucket.counts[key]
+	if counts == nil {
+		counts = &iceConnectionCounts{}
+		bucket.counts[key] = counts
+	}
+	counts.attempts++
+	if connected {
+		counts.successes++
+	}
+}
+
+func (w *iceConnectionWindow) stats(at time.Time) []*selector.ICEConnectionStats {
+	index := w.bucketIndex(at)
+
+	w.lock.Lock()
+	totals := make(map[iceConnectionKey]*iceConnectionCounts)
+	for _, bucket := range w.buckets {
+		if bucket.counts == nil || index-bucket.index >= int64(len(w.buckets)) || bucket.index > index {
+			continue
+		}
+		for key, counts := range bucket.counts {
+			total := totals[key]
+			if total == nil {
+				total = &iceConnectionCounts{}
+				totals[key] = total
+			}
+			total.attempts += counts.attempts
+			total.successes += counts.successes
+		}
+	}
+	w.lock.Unlock()
+
+	stats := make([]*selector.ICEConnectionStats, 0, len(totals))
+	for key, total := range totals {
+		stats = append(stats, &selector.ICEConnectionStats{
+			NetworkType:  key.networkType,
+			ClientRegion: key.clientRegion,
+			Attempts:     total.attempts,
+			Successes:    total.successes,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].NetworkType != stats[j].NetworkType {
+			return stats[i].NetworkType < stats[j].NetworkType
+		}
+		return stats[i].ClientRegion < stats[j].ClientRegion
+	})
+	return stats
+}
@@ -0,0 +1,39 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+func TestICEConnectionWindow(t *testing.T) {
+	w := newICEConnectionWindow(time.Minute, 5)
+	start := time.Unix(1_700_000_000, 0)
+
+	w.record(start, "wifi", "GB", true)
+	w.record(start.Add(30*time.Second), "wifi", "GB", false)
+	w.record(start.Add(2*time.Minute), "wifi", "GB", false)
+	w.record(start.Add(2*time.Minute), "cellular", "", true)
+
+	require.Equal(t, []*selector.ICEConnectionStats{
+		{NetworkType: "cellular", Attempts: 1, Successes: 1},
+		{NetworkType: "wifi", ClientRegion: "GB", Attempts: 3, Successes: 1},
+	}, w.stats(start.Add(3*time.Minute)))
+
+	// the failures of the first minute age out
+	require.Equal(t, []*selector.ICEConnectionStats{
+		{NetworkType: "cellular", Attempts: 1, Successes: 1},
+		{NetworkType: "wifi", ClientRegion: "GB", Attempts: 1},
+	}, w.stats(start.Add(5*time.Minute)))
+
+	// a bucket is reused once out of the window
+	w.record(start.Add(7*time.Minute), "wifi", "GB", true)
+	require.Equal(t, []*selector.ICEConnectionStats{
+		{NetworkType: "wifi", ClientRegion: "GB", Attempts: 1, Successes: 1},
+	}, w.stats(start.Add(7*time.Minute)))
+
+	require.Empty(t, w.stats(start.Add(time.Hour)))
+}
@@ -9,6 +9,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/routing/selector"
 	"github.com/livekit/protocol/livekit"
 )
 
@@ -95,7 +96,12 @@ func Init(nodeID string, nodeType livekit.NodeType, env string) {
 
 	initPacketStats(nodeID, nodeType, env)
 	initRoomStats(nodeID, nodeType, env)
+	initRelayStats(nodeID, nodeType, env)
 	initPSRPCStats(nodeID, nodeType, env)
+	initICEConnectionStats(nodeID, nodeType, env)
+	initICECandidatePairStats(nodeID, nodeType, env)
+	initCPUProtectionStats(nodeID, nodeType, env)
+	initTURNStats(nodeID, nodeType, env)
 }
 
 func GetUpdatedNodeStats(prev *livekit.NodeStats, prevAverage *livekit.NodeStats) (*livekit.NodeStats, bool, error) {
@@ -197,6 +203,8 @@ func GetUpdatedNodeStats(prev *livekit.NodeStats, prevAverage *livekit.NodeStats
 		TrackSubscribeAttemptsPerSec:     prevAverage.TrackSubscribeAttemptsPerSec,
 		TrackSubscribeSuccessPerSec:      prevAverage.TrackSubscribeSuccessPerSec,
 	}
+	selector.SetICEConnectionStats(stats, GetICEConnectionStats())
+	selector.SetPacketTimestampingStats(stats, GetPacketTimestampingStats())
 
 	// update stats
 	if computeAverage {
@@ -0,0 +1,44 @@
+package prometheus
+
+import (
+	"go.uber.org/atomic"
+
+	"github.com/livekit/livekit-server/pkg/routing/selector"
+)
+
+var packetTimestamping struct {
+	mode            atomic.String
+	available       atomic.Bool
+	hardwarePackets atomic.Uint64
+	softwarePackets atomic.Uint64
+}
+
+// SetPacketTimestamping records that the node is configured to take the receive times of media packets from the
+// kernel, and whether its sockets could be set up for it
+func SetPacketTimestamping(mode string, available bool) {
+	packetTimestamping.mode.Store(mode)
+	packetTimestamping.available.Store(available)
+}
+
+// RecordPacketTimestamp counts a packet received with a kernel timestamp, taken by the NIC when hardware is set
+func RecordPacketTimestamp(hardware bool) {
+	if hardware {
+		packetTimestamping.hardwarePackets.Inc()
+	} else {
+		packetTimestamping.softwarePackets.Inc()
+	}
+}
+
+// GetPacketTimestampingStats returns nil when packet timestamping is not configured
+func GetPacketTimestampingStats() *selector.PacketTimestampingStats {
+	mode := packetTimestamping.mode.Load()
+	if mode == "" {
+		return nil
+	}
+	return &selector.PacketTimestampingStats{
+		Mode:            mode,
+		Available:       packetTimestamping.available.Load(),
+		HardwarePackets: packetTimestamping.hardwarePackets.Load(),
+		SoftwarePackets: packetTimestamping.softwarePackets.Load(),
+	}
+}
@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promRelayLinkUp        *prometheus.GaugeVec
+	promRelayLinkPackets   *prometheus.CounterVec
+	promRelayLinkErrors    *prometheus.CounterVec
+	promRelayLinkRotations *prometheus.CounterVec
+)
+
+func initRelayStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promRelayLinkUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "relay_link",
+		Name:        "up",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"remote_node_id"})
+	promRelayLinkPackets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "relay_link",
+		Name:        "packets",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"remote_node_id", "direction"})
+	promRelayLinkErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "relay_link",
+		Name:        "errors",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"remote_node_id", "error"})
+	promRelayLinkRotations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "relay_link",
+		Name:        "key_rotations",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"remote_node_id"})
+
+	prometheus.MustRegister(promRelayLinkUp)
+	prometheus.MustRegister(promRelayLinkPackets)
+	prometheus.MustRegister(promRelayLinkErrors)
+	prometheus.MustRegister(promRelayLinkRotations)
+}
+
+// RelayLinkMetrics are the health metrics of a media link to another node, bound to the remote node once so that
+// recording them per packet is cheap
+type RelayLinkMetrics struct {
+	remoteNodeID livekit.NodeID
+	up           prometheus.Gauge
+	sent         prometheus.Counter
+	received     prometheus.Counter
+	rotations    prometheus.Counter
+}
+
+func NewRelayLinkMetrics(remoteNodeID livekit.NodeID) *RelayLinkMetrics {
+	return &RelayLinkMetrics{
+		remoteNodeID: remoteNodeID,
+		up:           promRelayLinkUp.WithLabelValues(string(remoteNodeID)),
+		sent:         promRelayLinkPackets.WithLabelValues(string(remoteNodeID), "sent"),
+		received:     promRelayLinkPackets.WithLabelValues(string(remoteNodeID), "received"),
+		rotations:    promRelayLinkRotations.WithLabelValues(string(remoteNodeID)),
+	}
+}
+
+func (m *RelayLinkMetrics) SetUp(up bool) {
+	if up {
+		m.up.Set(1)
+	} else {
+		m.up.Set(0)
+	}
+}
+
+func (m *RelayLinkMetrics) PacketSent() {
+	m.sent.Inc()
+}
+
+func (m *RelayLinkMetrics) PacketReceived() {
+	m.received.Inc()
+}
+
+func (m *RelayLinkMetrics) KeyRotated() {
+	m.rotations.Inc()
+}
+
+func (m *RelayLinkMetrics) Error(err error) {
+	promRelayLinkErrors.WithLabelValues(string(m.remoteNodeID), err.Error()).Inc()
+}
+
+// Delete removes the metrics of a link that is gone
+func (m *RelayLinkMetrics) Delete() {
+	labels := prometheus.Labels{"remote_node_id": string(m.remoteNodeID)}
+	promRelayLinkUp.DeletePartialMatch(labels)
+	promRelayLinkPackets.DeletePartialMatch(labels)
+	promRelayLinkErrors.DeletePartialMatch(labels)
+	promRelayLinkRotations.DeletePartialMatch(labels)
+}
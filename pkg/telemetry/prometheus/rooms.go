@@ -1,12 +1,15 @@
 package prometheus
 
 import (
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/atomic"
 
 	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/utils"
 )
 
 var (
@@ -29,6 +32,11 @@ var (
 	promTrackSubscribedCurrent *prometheus.GaugeVec
 	promTrackPublishCounter    *prometheus.CounterVec
 	promTrackSubscribeCounter  *prometheus.CounterVec
+	promRoomResources          *prometheus.GaugeVec
+
+	// resources of each room, summed into promRoomResources so that room names do not become labels
+	roomResourcesLock sync.Mutex
+	roomResources     = make(map[livekit.RoomName]utils.ResourceStats)
 )
 
 func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
@@ -77,6 +85,12 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
 		Name:        "subscribe_counter",
 		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
 	}, []string{"state", "error"})
+	promRoomResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "room",
+		Name:        "resources",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"resource"})
 
 	prometheus.MustRegister(promRoomCurrent)
 	prometheus.MustRegister(promRoomDuration)
@@ -85,6 +99,7 @@ func initRoomStats(nodeID string, nodeType livekit.NodeType, env string) {
 	prometheus.MustRegister(promTrackSubscribedCurrent)
 	prometheus.MustRegister(promTrackPublishCounter)
 	prometheus.MustRegister(promTrackSubscribeCounter)
+	prometheus.MustRegister(promRoomResources)
 }
 
 func RoomStarted() {
@@ -158,3 +173,33 @@ func RecordTrackSubscribeFailure(err error, isUserError bool) {
 		trackSubscribeUserError.Inc()
 	}
 }
+
+// SetRoomResources records the goroutines, sockets and timers accounted to a room. metrics are the totals of the
+// node's rooms, GetRoomStats returns those of a single room
+func SetRoomResources(roomName livekit.RoomName, stats utils.ResourceStats) {
+	roomResourcesLock.Lock()
+	defer roomResourcesLock.Unlock()
+
+	roomResources[roomName] = stats
+	updateRoomResourcesLocked()
+}
+
+func DeleteRoomResources(roomName livekit.RoomName) {
+	roomResourcesLock.Lock()
+	defer roomResourcesLock.Unlock()
+
+	delete(roomResources, roomName)
+	updateRoomResourcesLocked()
+}
+
+func updateRoomResourcesLocked() {
+	var total utils.ResourceStats
+	for _, stats := range roomResources {
+		total.Goroutines += stats.Goroutines
+		total.Sockets += stats.Sockets
+		total.Timers += stats.Timers
+	}
+	promRoomResources.WithLabelValues("goroutines").Set(float64(total.Goroutines))
+	promRoomResources.WithLabelValues("sockets").Set(float64(total.Sockets))
+	promRoomResources.WithLabelValues("timers").Set(float64(total.Timers))
+}
@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+var (
+	promTURNAllocations       prometheus.Gauge
+	promTURNAllocationsDenied *prometheus.CounterVec
+	promTURNBytesDropped      *prometheus.CounterVec
+)
+
+func initTURNStats(nodeID string, nodeType livekit.NodeType, env string) {
+	promTURNAllocations = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "allocations",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	})
+	promTURNAllocationsDenied = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "allocations_denied",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"limit"})
+	promTURNBytesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   livekitNamespace,
+		Subsystem:   "turn",
+		Name:        "bytes_dropped",
+		ConstLabels: prometheus.Labels{"node_id": nodeID, "node_type": nodeType.String(), "env": env},
+	}, []string{"limit"})
+
+	prometheus.MustRegister(promTURNAllocations)
+	prometheus.MustRegister(promTURNAllocationsDenied)
+	prometheus.MustRegister(promTURNBytesDropped)
+}
+
+// SetTURNAllocations records the number of relay allocations of the embedded TURN server the quota accounts for
+func SetTURNAllocations(allocations int) {
+	if promTURNAllocations != nil {
+		promTURNAllocations.Set(float64(allocations))
+	}
+}
+
+// RecordTURNAllocationDenied records a relay allocation denied for exceeding the given limit
+func RecordTURNAllocationDenied(limit string) {
+	if promTURNAllocationsDenied != nil {
+		promTURNAllocationsDenied.WithLabelValues(limit).Inc()
+	}
+}
+
+// RecordTURNBytesDropped records relayed bytes dropped for exceeding the given limit
+func RecordTURNBytesDropped(limit string, bytes int) {
+	if promTURNBytesDropped != nil {
+		promTURNBytesDropped.WithLabelValues(limit).Add(float64(bytes))
+	}
+}
@@ -0,0 +1,166 @@
+package telemetry
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// ParticipantSessionEnd describes how a participant's session ended
+type ParticipantSessionEnd struct {
+	Reason livekit.DisconnectReason
+	// selected pair of each transport, keyed by publisher or subscriber
+	ICECandidatePairs map[livekit.SignalTarget]*webrtc.ICECandidatePair
+}
+
+// ParticipantSessionSummary is sent as session_summary with participant_left webhooks
+type ParticipantSessionSummary struct {
+	DurationSeconds   int64               `json:"duration_seconds"`
+	DisconnectReason  string              `json:"disconnect_reason"`
+	ICECandidatePairs []*ICECandidatePair `json:"ice_candidate_pairs,omitempty"`
+	// bytes received from and sent to the participant, including data channels
+	BytesReceived uint64 `json:"bytes_received"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	// connection quality score averaged over the session, 0 when no stats have been reported
+	AverageScore float32 `json:"average_score,omitempty"`
+	// times the client moved to another network without reconnecting
+	NetworkChanges   int                    `json:"network_changes,omitempty"`
+	PublishedTracks  []*TrackSessionSummary `json:"published_tracks,omitempty"`
+	SubscribedTracks []*TrackSessionSummary `json:"subscribed_tracks,omitempty"`
+}
+
+type ICECandidatePair struct {
+	Transport  string `json:"transport"`
+	Protocol   string `json:"protocol"`
+	LocalType  string `json:"local_type"`
+	RemoteType string `json:"remote_type"`
+}
+
+func newICECandidatePair(target livekit.SignalTarget, pair *webrtc.ICECandidatePair) *ICECandidatePair {
+	if pair == nil || pair.Local == nil || pair.Remote == nil {
+		return nil
+	}
+	return &ICECandidatePair{
+		Transport:  target.String(),
+		Protocol:   pair.Local.Protocol.String(),
+		LocalType:  pair.Local.Typ.String(),
+		RemoteType: pair.Remote.Typ.String(),
+	}
+}
+
+// NetworkChange is sent as network_change with participant_network_changed webhooks
+type NetworkChange struct {
+	Previous *ICECandidatePair `json:"previous,omitempty"`
+	Current  *ICECandidatePair `json:"current,omitempty"`
+}
+
+type TrackSessionSummary struct {
+	TrackID      string  `json:"track_id"`
+	Type         string  `json:"type"`
+	Source       string  `json:"source"`
+	Bytes        uint64  `json:"bytes"`
+	Packets      uint64  `json:"packets"`
+	PacketsLost  uint64  `json:"packets_lost"`
+	AverageScore float32 `json:"average_score,omitempty"`
+
+	scoreSum   float64
+	scoreCount int
+}
+
+// sessionStats accumulates the stats reported for a participant over the session
+type sessionStats struct {
+	tracks         map[livekit.StreamType]map[livekit.TrackID]*TrackSessionSummary
+	bytesReceived  uint64
+	bytesSent      uint64
+	scoreSum       float64
+	scoreCount     int
+	networkChanges int
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{
+		tracks: map[livekit.StreamType]map[livekit.TrackID]*TrackSessionSummary{
+			livekit.StreamType_UPSTREAM:   {},
+			livekit.StreamType_DOWNSTREAM: {},
+		},
+	}
+}
+
+func (s *sessionStats) add(key StatsKey, stat *livekit.AnalyticsStat) {
+	var bytes, packets, packetsLost uint64
+	for _, stream := range stat.Streams {
+		bytes += stream.PrimaryBytes + stream.RetransmitBytes + stream.PaddingBytes
+		packets += uint64(stream.PrimaryPackets + stream.RetransmitPackets + stream.PaddingPackets)
+		packetsLost += uint64(stream.PacketsLost)
+	}
+	if key.streamType == livekit.StreamType_DOWNSTREAM {
+		s.bytesSent += bytes
+	} else {
+		s.bytesReceived += bytes
+	}
+	if !key.track {
+		return
+	}
+
+	if stat.Score > 0 {
+		s.scoreSum += float64(stat.Score)
+		s.scoreCount++
+	}
+
+	track := s.tracks[key.streamType][key.trackID]
+	if track == nil {
+		track = &TrackSessionSummary{
+			TrackID: string(key.trackID),
+			Type:    key.trackType.String(),
+			Source:  key.trackSource.String(),
+		}
+		s.tracks[key.streamType][key.trackID] = track
+	}
+	track.Bytes += bytes
+	track.Packets += packets
+	track.PacketsLost += packetsLost
+	if stat.Score > 0 {
+		track.scoreSum += float64(stat.Score)
+		track.scoreCount++
+		track.AverageScore = float32(track.scoreSum / float64(track.scoreCount))
+	}
+}
+
+func (s *sessionStats) summary(joinedAt time.Time, end *ParticipantSessionEnd) *ParticipantSessionSummary {
+	summary := &ParticipantSessionSummary{
+		DurationSeconds:  int64(time.Since(joinedAt).Seconds()),
+		DisconnectReason: livekit.DisconnectReason_UNKNOWN_REASON.String(),
+		BytesReceived:    s.bytesReceived,
+		BytesSent:        s.bytesSent,
+		NetworkChanges:   s.networkChanges,
+		PublishedTracks:  sortedTrackSummaries(s.tracks[livekit.StreamType_UPSTREAM]),
+		SubscribedTracks: sortedTrackSummaries(s.tracks[livekit.StreamType_DOWNSTREAM]),
+	}
+	if s.scoreCount != 0 {
+		summary.AverageScore = float32(s.scoreSum / float64(s.scoreCount))
+	}
+	if end != nil {
+		summary.DisconnectReason = end.Reason.String()
+		for _, target := range []livekit.SignalTarget{livekit.SignalTarget_PUBLISHER, livekit.SignalTarget_SUBSCRIBER} {
+			if pair := newICECandidatePair(target, end.ICECandidatePairs[target]); pair != nil {
+				summary.ICECandidatePairs = append(summary.ICECandidatePairs, pair)
+			}
+		}
+	}
+	return summary
+}
+
+func sortedTrackSummaries(tracks map[livekit.TrackID]*TrackSessionSummary) []*TrackSessionSummary {
+	summaries := make([]*TrackSessionSummary, 0, len(tracks))
+	for _, track := range tracks {
+		t := *track
+		summaries = append(summaries, &t)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].TrackID < summaries[j].TrackID
+	})
+	return summaries
+}
@@ -78,7 +78,7 @@ func (t *telemetryService) TrackStats(key StatsKey, stat *livekit.AnalyticsStat)
 		}
 
 		if worker, ok := t.getWorker(key.participantID); ok {
-			worker.OnTrackStat(key.trackID, key.streamType, stat)
+			worker.OnTrackStat(key, stat)
 		}
 	})
 }
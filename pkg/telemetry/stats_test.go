@@ -475,7 +475,7 @@ func Test_AnalyticsSentWhenParticipantLeaves(t *testing.T) {
 	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, nil, nil, true)
 
 	// do
-	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, true)
+	fixture.sut.ParticipantLeft(context.Background(), room, participantInfo, true, nil)
 
 	// should not be called if there are no track stats
 	time.Sleep(time.Millisecond * 500)
@@ -604,3 +604,22 @@ func (f *telemetryServiceFixture) flush() {
 	time.Sleep(time.Millisecond * 500)
 	f.sut.FlushStats()
 }
+
+func Test_EraseParticipant_DropsPendingStats(t *testing.T) {
+	fixture := createFixture()
+
+	room := &livekit.Room{}
+	partSID := livekit.ParticipantID("part1")
+	participantInfo := &livekit.ParticipantInfo{Sid: string(partSID), Identity: "alice"}
+	fixture.sut.ParticipantJoined(context.Background(), room, participantInfo, &livekit.ClientInfo{}, nil, true)
+
+	stat := &livekit.AnalyticsStat{Streams: []*livekit.AnalyticsStream{{PrimaryBytes: 33, PrimaryPackets: 1}}}
+	fixture.sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, "trackID"), stat)
+	// events are handled asynchronously
+	time.Sleep(time.Millisecond * 500)
+
+	require.Equal(t, 1, fixture.sut.EraseParticipant("alice"))
+	fixture.sut.TrackStats(telemetry.StatsKeyForData(livekit.StreamType_DOWNSTREAM, partSID, "trackID"), stat)
+	fixture.flush()
+	require.Zero(t, fixture.analytics.SendStatsCallCount())
+}
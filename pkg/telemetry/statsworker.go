@@ -21,10 +21,16 @@ type StatsWorker struct {
 	participantID       livekit.ParticipantID
 	participantIdentity livekit.ParticipantIdentity
 	isConnected         bool
+	// labels of the ICE connection outcome, set when the participant joined through this node
+	networkType  string
+	clientRegion string
+	hasClient    bool
 
 	lock             sync.RWMutex
 	outgoingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat
 	incomingPerTrack map[livekit.TrackID][]*livekit.AnalyticsStat
+	sessionStats     *sessionStats
+	createdAt        time.Time
 	closedAt         time.Time
 }
 
@@ -45,20 +51,31 @@ func newStatsWorker(
 		participantIdentity: identity,
 		outgoingPerTrack:    make(map[livekit.TrackID][]*livekit.AnalyticsStat),
 		incomingPerTrack:    make(map[livekit.TrackID][]*livekit.AnalyticsStat),
+		sessionStats:        newSessionStats(),
+		createdAt:           time.Now(),
 	}
 	return s
 }
 
-func (s *StatsWorker) OnTrackStat(trackID livekit.TrackID, direction livekit.StreamType, stat *livekit.AnalyticsStat) {
+func (s *StatsWorker) OnTrackStat(key StatsKey, stat *livekit.AnalyticsStat) {
 	s.lock.Lock()
-	if direction == livekit.StreamType_DOWNSTREAM {
-		s.outgoingPerTrack[trackID] = append(s.outgoingPerTrack[trackID], stat)
+	if key.streamType == livekit.StreamType_DOWNSTREAM {
+		s.outgoingPerTrack[key.trackID] = append(s.outgoingPerTrack[key.trackID], stat)
 	} else {
-		s.incomingPerTrack[trackID] = append(s.incomingPerTrack[trackID], stat)
+		s.incomingPerTrack[key.trackID] = append(s.incomingPerTrack[key.trackID], stat)
+	}
+	if isValid(stat) {
+		s.sessionStats.add(key, stat)
 	}
 	s.lock.Unlock()
 }
 
+func (s *StatsWorker) OnNetworkChange() {
+	s.lock.Lock()
+	s.sessionStats.networkChanges++
+	s.lock.Unlock()
+}
+
 func (s *StatsWorker) ParticipantID() livekit.ParticipantID {
 	return s.participantID
 }
@@ -69,6 +86,24 @@ func (s *StatsWorker) SetConnected() {
 	s.lock.Unlock()
 }
 
+// SetClient sets the network type and region of the client, the ICE connection outcome of which is recorded once
+// it is connected or has failed to
+func (s *StatsWorker) SetClient(networkType string, clientRegion string) {
+	s.lock.Lock()
+	s.networkType = networkType
+	s.clientRegion = clientRegion
+	s.hasClient = true
+	s.lock.Unlock()
+}
+
+// Client returns the network type and region of the client, ok is false when they have not been set
+func (s *StatsWorker) Client() (networkType string, clientRegion string, ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.networkType, s.clientRegion, s.hasClient
+}
+
 func (s *StatsWorker) IsConnected() bool {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -104,6 +139,30 @@ func (s *StatsWorker) Close() {
 	s.lock.Unlock()
 }
 
+// Discard closes the worker without sending the stats it has not sent yet
+func (s *StatsWorker) Discard() {
+	s.lock.Lock()
+	s.incomingPerTrack = make(map[livekit.TrackID][]*livekit.AnalyticsStat)
+	s.outgoingPerTrack = make(map[livekit.TrackID][]*livekit.AnalyticsStat)
+	s.sessionStats = newSessionStats()
+	if s.closedAt.IsZero() {
+		s.closedAt = time.Now()
+	}
+	s.lock.Unlock()
+}
+
+// SessionSummary summarizes the stats reported since the participant joined, joinedAt defaults to when the worker
+// was created
+func (s *StatsWorker) SessionSummary(joinedAt time.Time, end *ParticipantSessionEnd) *ParticipantSessionSummary {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if joinedAt.IsZero() {
+		joinedAt = s.createdAt
+	}
+	return s.sessionStats.summary(joinedAt, end)
+}
+
 func (s *StatsWorker) ClosedAt() time.Time {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
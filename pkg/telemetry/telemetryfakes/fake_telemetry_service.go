@@ -5,8 +5,10 @@ import (
 	"context"
 	"sync"
 
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/livekit/livekit-server/pkg/telemetry"
 	"github.com/livekit/protocol/livekit"
+	webrtc "github.com/pion/webrtc/v3"
 )
 
 type FakeTelemetryService struct {
@@ -28,6 +30,17 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 *livekit.EgressInfo
 	}
+	EraseParticipantStub        func(livekit.ParticipantIdentity) int
+	eraseParticipantMutex       sync.RWMutex
+	eraseParticipantArgsForCall []struct {
+		arg1 livekit.ParticipantIdentity
+	}
+	eraseParticipantReturns struct {
+		result1 int
+	}
+	eraseParticipantReturnsOnCall map[int]struct {
+		result1 int
+	}
 	FlushStatsStub        func()
 	flushStatsMutex       sync.RWMutex
 	flushStatsArgsForCall []struct {
@@ -38,6 +51,13 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 *livekit.WebhookEvent
 	}
+	NotifyEventWithFieldsStub        func(context.Context, *livekit.WebhookEvent, map[string]interface{})
+	notifyEventWithFieldsMutex       sync.RWMutex
+	notifyEventWithFieldsArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.WebhookEvent
+		arg3 map[string]interface{}
+	}
 	ParticipantActiveStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, *livekit.AnalyticsClientMeta)
 	participantActiveMutex       sync.RWMutex
 	participantActiveArgsForCall []struct {
@@ -56,13 +76,24 @@ type FakeTelemetryService struct {
 		arg5 *livekit.AnalyticsClientMeta
 		arg6 bool
 	}
-	ParticipantLeftStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, bool)
+	ParticipantLeftStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, bool, *telemetry.ParticipantSessionEnd)
 	participantLeftMutex       sync.RWMutex
 	participantLeftArgsForCall []struct {
 		arg1 context.Context
 		arg2 *livekit.Room
 		arg3 *livekit.ParticipantInfo
 		arg4 bool
+		arg5 *telemetry.ParticipantSessionEnd
+	}
+	ParticipantNetworkChangedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair)
+	participantNetworkChangedMutex       sync.RWMutex
+	participantNetworkChangedArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 livekit.SignalTarget
+		arg5 *webrtc.ICECandidatePair
+		arg6 *webrtc.ICECandidatePair
 	}
 	ParticipantResumedStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.NodeID, livekit.ReconnectReason)
 	participantResumedMutex       sync.RWMutex
@@ -73,6 +104,13 @@ type FakeTelemetryService struct {
 		arg4 livekit.NodeID
 		arg5 livekit.ReconnectReason
 	}
+	ParticipantUnstableStub        func(context.Context, *livekit.Room, *livekit.ParticipantInfo)
+	participantUnstableMutex       sync.RWMutex
+	participantUnstableArgsForCall []struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}
 	RoomEndedStub        func(context.Context, *livekit.Room)
 	roomEndedMutex       sync.RWMutex
 	roomEndedArgsForCall []struct {
@@ -97,6 +135,17 @@ type FakeTelemetryService struct {
 		arg1 context.Context
 		arg2 []*livekit.AnalyticsStat
 	}
+	TrackHistogramsStub        func(context.Context, livekit.ParticipantID, livekit.ParticipantIdentity, *livekit.TrackInfo, string, *hdrhistogram.Histogram, *hdrhistogram.Histogram)
+	trackHistogramsMutex       sync.RWMutex
+	trackHistogramsArgsForCall []struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.ParticipantIdentity
+		arg4 *livekit.TrackInfo
+		arg5 string
+		arg6 *hdrhistogram.Histogram
+		arg7 *hdrhistogram.Histogram
+	}
 	TrackMaxSubscribedVideoQualityStub        func(context.Context, livekit.ParticipantID, *livekit.TrackInfo, string, livekit.VideoQuality)
 	trackMaxSubscribedVideoQualityMutex       sync.RWMutex
 	trackMaxSubscribedVideoQualityArgsForCall []struct {
@@ -313,6 +362,67 @@ func (fake *FakeTelemetryService) EgressUpdatedArgsForCall(i int) (context.Conte
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) EraseParticipant(arg1 livekit.ParticipantIdentity) int {
+	fake.eraseParticipantMutex.Lock()
+	ret, specificReturn := fake.eraseParticipantReturnsOnCall[len(fake.eraseParticipantArgsForCall)]
+	fake.eraseParticipantArgsForCall = append(fake.eraseParticipantArgsForCall, struct {
+		arg1 livekit.ParticipantIdentity
+	}{arg1})
+	stub := fake.EraseParticipantStub
+	fakeReturns := fake.eraseParticipantReturns
+	fake.recordInvocation("EraseParticipant", []interface{}{arg1})
+	fake.eraseParticipantMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeTelemetryService) EraseParticipantCallCount() int {
+	fake.eraseParticipantMutex.RLock()
+	defer fake.eraseParticipantMutex.RUnlock()
+	return len(fake.eraseParticipantArgsForCall)
+}
+
+func (fake *FakeTelemetryService) EraseParticipantCalls(stub func(livekit.ParticipantIdentity) int) {
+	fake.eraseParticipantMutex.Lock()
+	defer fake.eraseParticipantMutex.Unlock()
+	fake.EraseParticipantStub = stub
+}
+
+func (fake *FakeTelemetryService) EraseParticipantArgsForCall(i int) livekit.ParticipantIdentity {
+	fake.eraseParticipantMutex.RLock()
+	defer fake.eraseParticipantMutex.RUnlock()
+	argsForCall := fake.eraseParticipantArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeTelemetryService) EraseParticipantReturns(result1 int) {
+	fake.eraseParticipantMutex.Lock()
+	defer fake.eraseParticipantMutex.Unlock()
+	fake.EraseParticipantStub = nil
+	fake.eraseParticipantReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeTelemetryService) EraseParticipantReturnsOnCall(i int, result1 int) {
+	fake.eraseParticipantMutex.Lock()
+	defer fake.eraseParticipantMutex.Unlock()
+	fake.EraseParticipantStub = nil
+	if fake.eraseParticipantReturnsOnCall == nil {
+		fake.eraseParticipantReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.eraseParticipantReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
 func (fake *FakeTelemetryService) FlushStats() {
 	fake.flushStatsMutex.Lock()
 	fake.flushStatsArgsForCall = append(fake.flushStatsArgsForCall, struct {
@@ -370,6 +480,40 @@ func (fake *FakeTelemetryService) NotifyEventArgsForCall(i int) (context.Context
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) NotifyEventWithFields(arg1 context.Context, arg2 *livekit.WebhookEvent, arg3 map[string]interface{}) {
+	fake.notifyEventWithFieldsMutex.Lock()
+	fake.notifyEventWithFieldsArgsForCall = append(fake.notifyEventWithFieldsArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.WebhookEvent
+		arg3 map[string]interface{}
+	}{arg1, arg2, arg3})
+	stub := fake.NotifyEventWithFieldsStub
+	fake.recordInvocation("NotifyEventWithFields", []interface{}{arg1, arg2, arg3})
+	fake.notifyEventWithFieldsMutex.Unlock()
+	if stub != nil {
+		fake.NotifyEventWithFieldsStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) NotifyEventWithFieldsCallCount() int {
+	fake.notifyEventWithFieldsMutex.RLock()
+	defer fake.notifyEventWithFieldsMutex.RUnlock()
+	return len(fake.notifyEventWithFieldsArgsForCall)
+}
+
+func (fake *FakeTelemetryService) NotifyEventWithFieldsCalls(stub func(context.Context, *livekit.WebhookEvent, map[string]interface{})) {
+	fake.notifyEventWithFieldsMutex.Lock()
+	defer fake.notifyEventWithFieldsMutex.Unlock()
+	fake.NotifyEventWithFieldsStub = stub
+}
+
+func (fake *FakeTelemetryService) NotifyEventWithFieldsArgsForCall(i int) (context.Context, *livekit.WebhookEvent, map[string]interface{}) {
+	fake.notifyEventWithFieldsMutex.RLock()
+	defer fake.notifyEventWithFieldsMutex.RUnlock()
+	argsForCall := fake.notifyEventWithFieldsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
 func (fake *FakeTelemetryService) ParticipantActive(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 *livekit.AnalyticsClientMeta) {
 	fake.participantActiveMutex.Lock()
 	fake.participantActiveArgsForCall = append(fake.participantActiveArgsForCall, struct {
@@ -442,19 +586,20 @@ func (fake *FakeTelemetryService) ParticipantJoinedArgsForCall(i int) (context.C
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
 }
 
-func (fake *FakeTelemetryService) ParticipantLeft(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 bool) {
+func (fake *FakeTelemetryService) ParticipantLeft(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 bool, arg5 *telemetry.ParticipantSessionEnd) {
 	fake.participantLeftMutex.Lock()
 	fake.participantLeftArgsForCall = append(fake.participantLeftArgsForCall, struct {
 		arg1 context.Context
 		arg2 *livekit.Room
 		arg3 *livekit.ParticipantInfo
 		arg4 bool
-	}{arg1, arg2, arg3, arg4})
+		arg5 *telemetry.ParticipantSessionEnd
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.ParticipantLeftStub
-	fake.recordInvocation("ParticipantLeft", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("ParticipantLeft", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.participantLeftMutex.Unlock()
 	if stub != nil {
-		fake.ParticipantLeftStub(arg1, arg2, arg3, arg4)
+		fake.ParticipantLeftStub(arg1, arg2, arg3, arg4, arg5)
 	}
 }
 
@@ -464,17 +609,54 @@ func (fake *FakeTelemetryService) ParticipantLeftCallCount() int {
 	return len(fake.participantLeftArgsForCall)
 }
 
-func (fake *FakeTelemetryService) ParticipantLeftCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, bool)) {
+func (fake *FakeTelemetryService) ParticipantLeftCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, bool, *telemetry.ParticipantSessionEnd)) {
 	fake.participantLeftMutex.Lock()
 	defer fake.participantLeftMutex.Unlock()
 	fake.ParticipantLeftStub = stub
 }
 
-func (fake *FakeTelemetryService) ParticipantLeftArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, bool) {
+func (fake *FakeTelemetryService) ParticipantLeftArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, bool, *telemetry.ParticipantSessionEnd) {
 	fake.participantLeftMutex.RLock()
 	defer fake.participantLeftMutex.RUnlock()
 	argsForCall := fake.participantLeftArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
+}
+
+func (fake *FakeTelemetryService) ParticipantNetworkChanged(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.SignalTarget, arg5 *webrtc.ICECandidatePair, arg6 *webrtc.ICECandidatePair) {
+	fake.participantNetworkChangedMutex.Lock()
+	fake.participantNetworkChangedArgsForCall = append(fake.participantNetworkChangedArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+		arg4 livekit.SignalTarget
+		arg5 *webrtc.ICECandidatePair
+		arg6 *webrtc.ICECandidatePair
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
+	stub := fake.ParticipantNetworkChangedStub
+	fake.recordInvocation("ParticipantNetworkChanged", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.participantNetworkChangedMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantNetworkChangedStub(arg1, arg2, arg3, arg4, arg5, arg6)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantNetworkChangedCallCount() int {
+	fake.participantNetworkChangedMutex.RLock()
+	defer fake.participantNetworkChangedMutex.RUnlock()
+	return len(fake.participantNetworkChangedArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantNetworkChangedCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair)) {
+	fake.participantNetworkChangedMutex.Lock()
+	defer fake.participantNetworkChangedMutex.Unlock()
+	fake.ParticipantNetworkChangedStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantNetworkChangedArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo, livekit.SignalTarget, *webrtc.ICECandidatePair, *webrtc.ICECandidatePair) {
+	fake.participantNetworkChangedMutex.RLock()
+	defer fake.participantNetworkChangedMutex.RUnlock()
+	argsForCall := fake.participantNetworkChangedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
 }
 
 func (fake *FakeTelemetryService) ParticipantResumed(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo, arg4 livekit.NodeID, arg5 livekit.ReconnectReason) {
@@ -513,6 +695,40 @@ func (fake *FakeTelemetryService) ParticipantResumedArgsForCall(i int) (context.
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
+func (fake *FakeTelemetryService) ParticipantUnstable(arg1 context.Context, arg2 *livekit.Room, arg3 *livekit.ParticipantInfo) {
+	fake.participantUnstableMutex.Lock()
+	fake.participantUnstableArgsForCall = append(fake.participantUnstableArgsForCall, struct {
+		arg1 context.Context
+		arg2 *livekit.Room
+		arg3 *livekit.ParticipantInfo
+	}{arg1, arg2, arg3})
+	stub := fake.ParticipantUnstableStub
+	fake.recordInvocation("ParticipantUnstable", []interface{}{arg1, arg2, arg3})
+	fake.participantUnstableMutex.Unlock()
+	if stub != nil {
+		fake.ParticipantUnstableStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeTelemetryService) ParticipantUnstableCallCount() int {
+	fake.participantUnstableMutex.RLock()
+	defer fake.participantUnstableMutex.RUnlock()
+	return len(fake.participantUnstableArgsForCall)
+}
+
+func (fake *FakeTelemetryService) ParticipantUnstableCalls(stub func(context.Context, *livekit.Room, *livekit.ParticipantInfo)) {
+	fake.participantUnstableMutex.Lock()
+	defer fake.participantUnstableMutex.Unlock()
+	fake.ParticipantUnstableStub = stub
+}
+
+func (fake *FakeTelemetryService) ParticipantUnstableArgsForCall(i int) (context.Context, *livekit.Room, *livekit.ParticipantInfo) {
+	fake.participantUnstableMutex.RLock()
+	defer fake.participantUnstableMutex.RUnlock()
+	argsForCall := fake.participantUnstableArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
 func (fake *FakeTelemetryService) RoomEnded(arg1 context.Context, arg2 *livekit.Room) {
 	fake.roomEndedMutex.Lock()
 	fake.roomEndedArgsForCall = append(fake.roomEndedArgsForCall, struct {
@@ -650,6 +866,44 @@ func (fake *FakeTelemetryService) SendStatsArgsForCall(i int) (context.Context,
 	return argsForCall.arg1, argsForCall.arg2
 }
 
+func (fake *FakeTelemetryService) TrackHistograms(arg1 context.Context, arg2 livekit.ParticipantID, arg3 livekit.ParticipantIdentity, arg4 *livekit.TrackInfo, arg5 string, arg6 *hdrhistogram.Histogram, arg7 *hdrhistogram.Histogram) {
+	fake.trackHistogramsMutex.Lock()
+	fake.trackHistogramsArgsForCall = append(fake.trackHistogramsArgsForCall, struct {
+		arg1 context.Context
+		arg2 livekit.ParticipantID
+		arg3 livekit.ParticipantIdentity
+		arg4 *livekit.TrackInfo
+		arg5 string
+		arg6 *hdrhistogram.Histogram
+		arg7 *hdrhistogram.Histogram
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	stub := fake.TrackHistogramsStub
+	fake.recordInvocation("TrackHistograms", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7})
+	fake.trackHistogramsMutex.Unlock()
+	if stub != nil {
+		fake.TrackHistogramsStub(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+	}
+}
+
+func (fake *FakeTelemetryService) TrackHistogramsCallCount() int {
+	fake.trackHistogramsMutex.RLock()
+	defer fake.trackHistogramsMutex.RUnlock()
+	return len(fake.trackHistogramsArgsForCall)
+}
+
+func (fake *FakeTelemetryService) TrackHistogramsCalls(stub func(context.Context, livekit.ParticipantID, livekit.ParticipantIdentity, *livekit.TrackInfo, string, *hdrhistogram.Histogram, *hdrhistogram.Histogram)) {
+	fake.trackHistogramsMutex.Lock()
+	defer fake.trackHistogramsMutex.Unlock()
+	fake.TrackHistogramsStub = stub
+}
+
+func (fake *FakeTelemetryService) TrackHistogramsArgsForCall(i int) (context.Context, livekit.ParticipantID, livekit.ParticipantIdentity, *livekit.TrackInfo, string, *hdrhistogram.Histogram, *hdrhistogram.Histogram) {
+	fake.trackHistogramsMutex.RLock()
+	defer fake.trackHistogramsMutex.RUnlock()
+	argsForCall := fake.trackHistogramsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7
+}
+
 func (fake *FakeTelemetryService) TrackMaxSubscribedVideoQuality(arg1 context.Context, arg2 livekit.ParticipantID, arg3 *livekit.TrackInfo, arg4 string, arg5 livekit.VideoQuality) {
 	fake.trackMaxSubscribedVideoQualityMutex.Lock()
 	fake.trackMaxSubscribedVideoQualityArgsForCall = append(fake.trackMaxSubscribedVideoQualityArgsForCall, struct {
@@ -1150,18 +1404,26 @@ func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	defer fake.egressStartedMutex.RUnlock()
 	fake.egressUpdatedMutex.RLock()
 	defer fake.egressUpdatedMutex.RUnlock()
+	fake.eraseParticipantMutex.RLock()
+	defer fake.eraseParticipantMutex.RUnlock()
 	fake.flushStatsMutex.RLock()
 	defer fake.flushStatsMutex.RUnlock()
 	fake.notifyEventMutex.RLock()
 	defer fake.notifyEventMutex.RUnlock()
+	fake.notifyEventWithFieldsMutex.RLock()
+	defer fake.notifyEventWithFieldsMutex.RUnlock()
 	fake.participantActiveMutex.RLock()
 	defer fake.participantActiveMutex.RUnlock()
 	fake.participantJoinedMutex.RLock()
 	defer fake.participantJoinedMutex.RUnlock()
 	fake.participantLeftMutex.RLock()
 	defer fake.participantLeftMutex.RUnlock()
+	fake.participantNetworkChangedMutex.RLock()
+	defer fake.participantNetworkChangedMutex.RUnlock()
 	fake.participantResumedMutex.RLock()
 	defer fake.participantResumedMutex.RUnlock()
+	fake.participantUnstableMutex.RLock()
+	defer fake.participantUnstableMutex.RUnlock()
 	fake.roomEndedMutex.RLock()
 	defer fake.roomEndedMutex.RUnlock()
 	fake.roomStartedMutex.RLock()
@@ -1170,6 +1432,8 @@ func (fake *FakeTelemetryService) Invocations() map[string][][]interface{} {
 	defer fake.sendEventMutex.RUnlock()
 	fake.sendStatsMutex.RLock()
 	defer fake.sendStatsMutex.RUnlock()
+	fake.trackHistogramsMutex.RLock()
+	defer fake.trackHistogramsMutex.RUnlock()
 	fake.trackMaxSubscribedVideoQualityMutex.RLock()
 	defer fake.trackMaxSubscribedVideoQualityMutex.RUnlock()
 	fake.trackMutedMutex.RLock()
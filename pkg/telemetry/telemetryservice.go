@@ -5,6 +5,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/pion/webrtc/v3"
+
 	"github.com/livekit/livekit-server/pkg/config"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -25,8 +28,13 @@ type TelemetryService interface {
 	ParticipantActive(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, clientMeta *livekit.AnalyticsClientMeta)
 	// ParticipantResumed - there has been an ICE restart or connection resume attempt
 	ParticipantResumed(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, nodeID livekit.NodeID, reason livekit.ReconnectReason)
-	// ParticipantLeft - the participant leaves the room, only sent if ParticipantActive has been called before
-	ParticipantLeft(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, shouldSendEvent bool)
+	// ParticipantLeft - the participant leaves the room, only sent if ParticipantActive has been called before.
+	// the webhook includes a summary of the session
+	ParticipantLeft(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, shouldSendEvent bool, sessionEnd *ParticipantSessionEnd)
+	// ParticipantUnstable - the participant's connection shows signs of an imminent disconnect
+	ParticipantUnstable(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo)
+	// ParticipantNetworkChanged - the selected candidate pair of a transport moved to another client address
+	ParticipantNetworkChanged(ctx context.Context, room *livekit.Room, participant *livekit.ParticipantInfo, target livekit.SignalTarget, previous *webrtc.ICECandidatePair, current *webrtc.ICECandidatePair)
 	// TrackPublishRequested - a publication attempt has been received
 	TrackPublishRequested(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo)
 	// TrackPublished - a publication attempt has been successful
@@ -51,6 +59,8 @@ type TelemetryService interface {
 	TrackMaxSubscribedVideoQuality(ctx context.Context, participantID livekit.ParticipantID, track *livekit.TrackInfo, mime string, maxQuality livekit.VideoQuality)
 	TrackPublishRTPStats(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, mimeType string, layer int, stats *livekit.RTPStats)
 	TrackSubscribeRTPStats(ctx context.Context, participantID livekit.ParticipantID, trackID livekit.TrackID, mimeType string, stats *livekit.RTPStats)
+	// TrackHistograms - jitter and forwarding delay of a published track over an interval, when detailed stats are enabled
+	TrackHistograms(ctx context.Context, participantID livekit.ParticipantID, identity livekit.ParticipantIdentity, track *livekit.TrackInfo, mimeType string, jitter *hdrhistogram.Histogram, delay *hdrhistogram.Histogram)
 	EgressStarted(ctx context.Context, info *livekit.EgressInfo)
 	EgressUpdated(ctx context.Context, info *livekit.EgressInfo)
 	EgressEnded(ctx context.Context, info *livekit.EgressInfo)
@@ -58,7 +68,13 @@ type TelemetryService interface {
 	// helpers
 	AnalyticsService
 	NotifyEvent(ctx context.Context, event *livekit.WebhookEvent)
+	// NotifyEventWithFields adds top level fields to the webhook payload, notifiers that don't support it send
+	// the event without them
+	NotifyEventWithFields(ctx context.Context, event *livekit.WebhookEvent, fields map[string]interface{})
 	FlushStats()
+	// EraseParticipant drops the stats kept on this node for sessions of the participant, and returns how many
+	// sessions were dropped
+	EraseParticipant(identity livekit.ParticipantIdentity) int
 }
 
 const (
@@ -157,6 +173,21 @@ func (t *telemetryService) createWorker(ctx context.Context,
 	return worker
 }
 
+func (t *telemetryService) EraseParticipant(identity livekit.ParticipantIdentity) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	erased := 0
+	for participantID, worker := range t.workers {
+		if worker.participantIdentity == identity {
+			delete(t.workers, participantID)
+			worker.Discard()
+			erased++
+		}
+	}
+	return erased
+}
+
 func (t *telemetryService) cleanupWorkers() {
 	t.lock.Lock()
 	defer t.lock.Unlock()
@@ -0,0 +1,180 @@
+package tracing
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+
+	"github.com/livekit/livekit-server/pkg/config"
+	"github.com/livekit/livekit-server/pkg/utils"
+	"github.com/livekit/livekit-server/version"
+)
+
+const (
+	instrumentationName = "github.com/livekit/livekit-server"
+	defaultServiceName  = "livekit-server"
+	shutdownTimeout     = 5 * time.Second
+
+	traceParentHeader = "traceparent"
+)
+
+var (
+	// W3C trace context, the format of traceparent headers
+	propagator = propagation.TraceContext{}
+
+	lock     sync.Mutex
+	provider *sdktrace.TracerProvider
+	// ratio of traces sampled, for trace context coming from clients
+	sampleRatio = atomic.NewFloat64(1)
+)
+
+// Init exports spans to the configured collector until Close. Without a collector spans are not recorded, though
+// trace context received is passed on
+func Init(conf config.TracingConfig, nodeID string, env string) error {
+	if conf.Endpoint == "" {
+		return nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(conf.Endpoint)}
+	if conf.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(conf.Headers) != 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(conf.Headers))
+	}
+	// connects in the background, spans are queued meanwhile
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+
+	serviceName := conf.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version.Version),
+		semconv.ServiceInstanceID(nodeID),
+	}
+	if env != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(env))
+	}
+	ratio := conf.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	sampleRatio.Store(float64(ratio))
+
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(float64(ratio)))),
+	)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warnw("could not export spans", err)
+	}))
+	otel.SetTracerProvider(p)
+
+	lock.Lock()
+	prev := provider
+	provider = p
+	lock.Unlock()
+	if prev != nil {
+		shutdown(prev)
+	}
+	return nil
+}
+
+// Close exports queued spans and stops exporting
+func Close() {
+	lock.Lock()
+	p := provider
+	provider = nil
+	lock.Unlock()
+
+	if p != nil {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		shutdown(p)
+	}
+}
+
+func shutdown(p *sdktrace.TracerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != nil {
+		logger.Warnw("could not export queued spans", err)
+	}
+}
+
+// Start starts a span, a child of the span of ctx if there is one
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartFrom starts a span in the trace of parent, e.g. of the join of a participant, as a child of it
+func StartFrom(parent trace.SpanContext, name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := Start(trace.ContextWithSpanContext(context.Background(), parent), name, attrs...)
+	return span
+}
+
+// End ends a span, marking it failed when there is an error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceParent returns the traceparent header carrying the span of ctx to another node, empty without a span
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get(traceParentHeader)
+}
+
+// ContextWithTraceParent continues the trace of a traceparent header, ctx is returned as is when it is not valid
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
+
+// ContextWithClientTraceParent continues the trace of a traceparent header sent by a client. Its sampled flag is
+// ignored, clients could otherwise have all their joins traced, the trace is sampled at the configured ratio instead
+func ContextWithClientTraceParent(ctx context.Context, traceParent string) context.Context {
+	ctx = ContextWithTraceParent(ctx, traceParent)
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ctx
+	}
+	sampled := rand.Float64() < sampleRatio.Load()
+	return trace.ContextWithRemoteSpanContext(ctx, sc.WithTraceFlags(sc.TraceFlags().WithSampled(sampled)))
+}
+
+// Room returns the room attribute of spans, with the room name redacted as participant identities are
+func Room(roomName livekit.RoomName) attribute.KeyValue {
+	return attribute.String("room", utils.GetPIIRedactor().Pseudonym(string(roomName)))
+}
+
+// Participant returns the participant attribute of spans, with the identity redacted
+func Participant(identity livekit.ParticipantIdentity) attribute.KeyValue {
+	return attribute.String("participant", utils.GetPIIRedactor().Identity(string(identity)))
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceParent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	const clientTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx, join := Start(ContextWithTraceParent(context.Background(), clientTraceParent), "signal.Join")
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", join.SpanContext().TraceID().String())
+
+	// carried to another node
+	traceParent := TraceParent(ctx)
+	require.NotEmpty(t, traceParent)
+	_, session := Start(ContextWithTraceParent(context.Background(), traceParent), "rtc.StartSession")
+	require.Equal(t, join.SpanContext().TraceID(), session.SpanContext().TraceID())
+	End(session, errors.New("could not join"))
+	End(join, nil)
+
+	connect := StartFrom(session.SpanContext(), "rtc.Connect")
+	End(connect, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	require.Equal(t, "rtc.StartSession", spans[0].Name())
+	require.Equal(t, join.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, codes.Unset, spans[1].Status().Code)
+	require.Equal(t, session.SpanContext().SpanID(), spans[2].Parent().SpanID())
+
+	// invalid or missing trace context starts a new trace
+	ctx = ContextWithTraceParent(context.Background(), "invalid")
+	require.False(t, trace.SpanContextFromContext(ctx).IsValid())
+	require.Empty(t, TraceParent(context.Background()))
+}
+
+func TestClientTraceParent(t *testing.T) {
+	t.Cleanup(func() { sampleRatio.Store(1) })
+
+	const sampled = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	const notSampled = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+
+	sampleRatio.Store(0)
+	sc := trace.SpanContextFromContext(ContextWithClientTraceParent(context.Background(), sampled))
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	require.False(t, sc.IsSampled())
+
+	sampleRatio.Store(1)
+	sc = trace.SpanContextFromContext(ContextWithClientTraceParent(context.Background(), notSampled))
+	require.True(t, sc.IsSampled())
+
+	require.False(t, trace.SpanContextFromContext(ContextWithClientTraceParent(context.Background(), "invalid")).IsValid())
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// EventTrackHistograms is sent periodically for each published track when detailed stats are enabled, the
+// histograms are in the histograms field
+const EventTrackHistograms = "track_histograms"
+
+// TrackHistograms is sent as histograms with track_histograms webhooks. Values are in microseconds
+type TrackHistograms struct {
+	TrackID         string            `json:"track_id"`
+	Mime            string            `json:"mime"`
+	StartTime       int64             `json:"start_time"`
+	EndTime         int64             `json:"end_time"`
+	Jitter          *HistogramSummary `json:"jitter"`
+	ForwardingDelay *HistogramSummary `json:"forwarding_delay"`
+}
+
+type HistogramSummary struct {
+	Count int64 `json:"count"`
+	P50   int64 `json:"p50"`
+	P99   int64 `json:"p99"`
+	Max   int64 `json:"max"`
+	// HdrHistogram V2 compressed and base64 encoded, can be decoded by any HdrHistogram implementation
+	Encoded string `json:"encoded"`
+}
+
+func newTrackHistograms(track *livekit.TrackInfo, mimeType string, jitter *hdrhistogram.Histogram, delay *hdrhistogram.Histogram) *TrackHistograms {
+	return &TrackHistograms{
+		TrackID:         track.Sid,
+		Mime:            mimeType,
+		StartTime:       jitter.StartTimeMs(),
+		EndTime:         jitter.EndTimeMs(),
+		Jitter:          newHistogramSummary(jitter),
+		ForwardingDelay: newHistogramSummary(delay),
+	}
+}
+
+func newHistogramSummary(h *hdrhistogram.Histogram) *HistogramSummary {
+	s := &HistogramSummary{
+		Count: h.TotalCount(),
+		P50:   h.ValueAtQuantile(50),
+		P99:   h.ValueAtQuantile(99),
+		Max:   h.Max(),
+	}
+	encoded, err := h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		logger.Warnw("could not encode histogram", err)
+	} else {
+		s.Encoded = string(encoded)
+	}
+	return s
+}
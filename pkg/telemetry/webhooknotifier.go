@@ -0,0 +1,609 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+const (
+	defaultWebhookReorderWindow    = 500 * time.Millisecond
+	defaultWebhookMaxRetryDuration = 5 * time.Minute
+	defaultWebhookQueueSize        = 10000
+	webhookSendTimeout             = 10 * time.Second
+	webhookMinBackoff              = 500 * time.Millisecond
+	webhookMaxBackoff              = 30 * time.Second
+	webhookMaxAttempts             = 10
+	// sequences of streams without events for this long are forgotten
+	webhookSequenceIdleTimeout = 10 * time.Minute
+)
+
+var ErrWebhookNotifierStopped = errors.New("webhook notifier has been stopped")
+
+// fieldsNotifier adds fields that livekit.WebhookEvent doesn't have to the payload
+type fieldsNotifier interface {
+	QueueNotifyWithFields(ctx context.Context, event *livekit.WebhookEvent, fields map[string]interface{}) error
+}
+
+type WebhookNotifierParams struct {
+	URLs      []string
+	APIKey    string
+	APISecret string
+	// events are held this long so that events of a participant enqueued out of order can be put back in order
+	ReorderWindow time.Duration
+	// failed deliveries are retried for this long, and at most webhookMaxAttempts times, before the event is dropped
+	MaxRetryDuration time.Duration
+	// per URL, the oldest event is dropped when full
+	QueueSize int
+	// replaces the identity, name and metadata of participants in payloads when set
+	Redactor *utils.PIIRedactor
+	Logger   logger.Logger
+}
+
+// WebhookNotifier delivers webhooks of a room in order, at least once.
+//
+// Events are buffered for the reorder window, an event of a participant raced by a later one is moved back
+// in front of it, so that e.g. track_published is never sent after participant_left. Released events are
+// numbered per room, the payload carries ordering_key, sequence and idempotency_key (the event id, which is
+// the same for retries) next to the event fields. Events to a URL are sent one at a time in sequence order, failed
+// deliveries are retried with a jittered backoff while later events go on, consumers put retried events back in
+// place by their sequence.
+type WebhookNotifier struct {
+	params WebhookNotifierParams
+
+	lock      sync.Mutex
+	pending   map[string][]*pendingWebhook
+	sequences map[string]*webhookSequence
+	senders   []*webhookSender
+
+	stopOnce sync.Once
+	doneChan chan struct{}
+}
+
+type pendingWebhook struct {
+	event      *livekit.WebhookEvent
+	fields     map[string]interface{}
+	scope      string
+	rank       int
+	receivedAt time.Time
+}
+
+type webhookSequence struct {
+	next     uint64
+	lastUsed time.Time
+}
+
+type sequencedWebhook struct {
+	event       *livekit.WebhookEvent
+	fields      map[string]interface{}
+	orderingKey string
+	sequence    uint64
+}
+
+func NewWebhookNotifier(params WebhookNotifierParams) *WebhookNotifier {
+	if params.ReorderWindow < 0 {
+		params.ReorderWindow = 0
+	}
+	if params.MaxRetryDuration == 0 {
+		params.MaxRetryDuration = defaultWebhookMaxRetryDuration
+	}
+	if params.QueueSize == 0 {
+		params.QueueSize = defaultWebhookQueueSize
+	}
+	if params.Logger == nil {
+		params.Logger = logger.GetLogger()
+	}
+
+	n := &WebhookNotifier{
+		params:    params,
+		pending:   make(map[string][]*pendingWebhook),
+		sequences: make(map[string]*webhookSequence),
+		doneChan:  make(chan struct{}),
+	}
+	for _, url := range params.URLs {
+		s := newWebhookSender(url, n)
+		n.senders = append(n.senders, s)
+		go s.worker()
+	}
+	go n.releaseWorker()
+	return n
+}
+
+// Stop stops accepting events, pending events are sent unless forced
+func (n *WebhookNotifier) Stop(force bool) {
+	n.stopOnce.Do(func() {
+		close(n.doneChan)
+	})
+	if !force {
+		n.release(true)
+	}
+	n.lock.Lock()
+	senders := n.senders
+	n.lock.Unlock()
+	for _, s := range senders {
+		s.stop(force)
+	}
+}
+
+// SetURLs changes the URLs webhooks are sent to. Senders of URLs that are no longer configured deliver the events
+// they were handed before stopping, URLs added receive events released from now on
+func (n *WebhookNotifier) SetURLs(urls []string) {
+	select {
+	case <-n.doneChan:
+		return
+	default:
+	}
+
+	n.lock.Lock()
+	existing := make(map[string]*webhookSender, len(n.senders))
+	for _, s := range n.senders {
+		existing[s.url] = s
+	}
+	senders := make([]*webhookSender, 0, len(urls))
+	for _, url := range urls {
+		if s, ok := existing[url]; ok {
+			senders = append(senders, s)
+			delete(existing, url)
+			continue
+		}
+		s := newWebhookSender(url, n)
+		senders = append(senders, s)
+		go s.worker()
+	}
+	n.senders = senders
+	n.lock.Unlock()
+
+	for _, s := range existing {
+		go s.stop(false)
+	}
+}
+
+func (n *WebhookNotifier) QueueNotify(ctx context.Context, event *livekit.WebhookEvent) error {
+	return n.QueueNotifyWithFields(ctx, event, nil)
+}
+
+// QueueNotifyWithFields queues an event with additional top level fields in the payload
+func (n *WebhookNotifier) QueueNotifyWithFields(_ context.Context, event *livekit.WebhookEvent, fields map[string]interface{}) error {
+	select {
+	case <-n.doneChan:
+		return ErrWebhookNotifierStopped
+	default:
+	}
+
+	if n.params.Redactor != nil && event.Participant != nil {
+		event = redactWebhookEvent(event, n.params.Redactor)
+	}
+
+	key, scope, rank := webhookOrdering(event)
+	p := &pendingWebhook{
+		event:      event,
+		fields:     fields,
+		scope:      scope,
+		rank:       rank,
+		receivedAt: time.Now(),
+	}
+
+	n.lock.Lock()
+	events := n.pending[key]
+	// in front of the first event of the same scope it should have been sent before
+	idx := len(events)
+	for i, e := range events {
+		if e.rank > p.rank && (e.scope == p.scope || e.scope == "" || p.scope == "") {
+			idx = i
+			break
+		}
+	}
+	events = append(events, nil)
+	copy(events[idx+1:], events[idx:])
+	events[idx] = p
+	n.pending[key] = events
+	n.lock.Unlock()
+
+	if n.params.ReorderWindow == 0 {
+		n.release(true)
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) releaseWorker() {
+	interval := n.params.ReorderWindow / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.doneChan:
+			return
+		case <-ticker.C:
+			n.release(false)
+		}
+	}
+}
+
+// release numbers the events that have been held for the reorder window and hands them to the senders
+func (n *WebhookNotifier) release(all bool) {
+	now := time.Now()
+	var released []*sequencedWebhook
+
+	n.lock.Lock()
+	for key, events := range n.pending {
+		i := 0
+		for ; i < len(events); i++ {
+			if !all && now.Sub(events[i].receivedAt) < n.params.ReorderWindow {
+				break
+			}
+			seq := n.sequences[key]
+			if seq == nil {
+				seq = &webhookSequence{}
+				n.sequences[key] = seq
+			}
+			seq.next++
+			seq.lastUsed = now
+			released = append(released, &sequencedWebhook{
+				event:       events[i].event,
+				fields:      events[i].fields,
+				orderingKey: key,
+				sequence:    seq.next,
+			})
+		}
+		if i == len(events) {
+			delete(n.pending, key)
+		} else {
+			n.pending[key] = events[i:]
+		}
+	}
+	for key, seq := range n.sequences {
+		if now.Sub(seq.lastUsed) > webhookSequenceIdleTimeout {
+			delete(n.sequences, key)
+		}
+	}
+	senders := n.senders
+	n.lock.Unlock()
+
+	for _, e := range released {
+		for _, s := range senders {
+			s.push(e)
+		}
+	}
+}
+
+func redactWebhookEvent(event *livekit.WebhookEvent, r *utils.PIIRedactor) *livekit.WebhookEvent {
+	redacted := proto.Clone(event).(*livekit.WebhookEvent)
+	p := redacted.Participant
+	p.Identity = r.Identity(p.Identity)
+	p.Name = r.Data(p.Name)
+	p.Metadata = r.Data(p.Metadata)
+	return redacted
+}
+
+// webhookOrdering returns the stream an event is ordered in, the scope within the stream (participant, egress,
+// ingress or the room itself) and its rank, events are moved in front of later events of their scope with a
+// higher rank
+func webhookOrdering(event *livekit.WebhookEvent) (string, string, int) {
+	var key, scope string
+	switch {
+	case event.Participant != nil:
+		scope = event.Participant.Sid
+	case event.EgressInfo != nil:
+		scope = event.EgressInfo.EgressId
+		key = event.EgressInfo.RoomId
+	case event.IngressInfo != nil:
+		scope = event.IngressInfo.IngressId
+	}
+	if event.Room != nil {
+		key = event.Room.Sid
+		if key == "" {
+			key = event.Room.Name
+		}
+	}
+	if key == "" {
+		key = scope
+	}
+
+	rank := 2
+	switch event.Event {
+	case webhook.EventRoomStarted:
+		rank = 0
+	case webhook.EventParticipantJoined, webhook.EventEgressStarted, webhook.EventIngressStarted:
+		rank = 1
+	case webhook.EventParticipantLeft, webhook.EventEgressEnded, webhook.EventIngressEnded:
+		rank = 3
+	case webhook.EventRoomFinished:
+		rank = 4
+	case EventRoomManifest:
+		rank = 5
+	}
+	return key, scope, rank
+}
+
+// ------------------------------------------------
+
+type webhookSender struct {
+	url      string
+	notifier *WebhookNotifier
+	client   *http.Client
+
+	lock     sync.Mutex
+	queue    []*sequencedWebhook
+	dropped  int32
+	draining bool
+
+	// accessed by the worker only
+	retries []*retryingWebhook
+
+	jobSignal chan struct{}
+	forceStop chan struct{}
+	done      chan struct{}
+}
+
+// retryingWebhook is an event whose delivery failed, with the payload it is retried with
+type retryingWebhook struct {
+	e         *sequencedWebhook
+	payload   []byte
+	attempts  int
+	deadline  time.Time
+	nextRetry time.Time
+}
+
+func newWebhookSender(url string, n *WebhookNotifier) *webhookSender {
+	return &webhookSender{
+		url:       url,
+		notifier:  n,
+		client:    &http.Client{Timeout: webhookSendTimeout},
+		jobSignal: make(chan struct{}, 1),
+		forceStop: make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (s *webhookSender) push(e *sequencedWebhook) {
+	s.lock.Lock()
+	s.queue = append(s.queue, e)
+	if len(s.queue) > s.notifier.params.QueueSize {
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.lock.Unlock()
+
+	select {
+	case s.jobSignal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *webhookSender) stop(force bool) {
+	s.lock.Lock()
+	alreadyDraining := s.draining
+	s.draining = true
+	s.lock.Unlock()
+
+	if force {
+		select {
+		case <-s.forceStop:
+		default:
+			close(s.forceStop)
+		}
+	}
+	if !alreadyDraining {
+		select {
+		case s.jobSignal <- struct{}{}:
+		default:
+		}
+	}
+	<-s.done
+}
+
+func (s *webhookSender) worker() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.forceStop:
+			return
+		default:
+		}
+
+		// retries that are due go first, they are older than anything queued
+		now := time.Now()
+		var wait time.Duration
+		if len(s.retries) != 0 {
+			r := s.retries[0]
+			if !r.nextRetry.After(now) {
+				s.retries = s.retries[1:]
+				s.retry(r)
+				continue
+			}
+			wait = r.nextRetry.Sub(now)
+		}
+
+		s.lock.Lock()
+		var e *sequencedWebhook
+		if len(s.queue) != 0 {
+			e = s.queue[0]
+			s.queue = s.queue[1:]
+		}
+		draining := s.draining
+		s.lock.Unlock()
+
+		if e != nil {
+			s.deliver(e)
+			continue
+		}
+		if len(s.retries) == 0 {
+			if draining {
+				return
+			}
+			select {
+			case <-s.jobSignal:
+			case <-s.forceStop:
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-s.jobSignal:
+		case <-s.forceStop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// deliver sends the event once, scheduling a retry when that fails
+func (s *webhookSender) deliver(e *sequencedWebhook) {
+	// events are shared by the senders of all URLs, the number dropped is of this URL
+	event := proto.Clone(e.event).(*livekit.WebhookEvent)
+	s.lock.Lock()
+	event.NumDropped = s.dropped
+	s.dropped = 0
+	s.lock.Unlock()
+	e = &sequencedWebhook{
+		event:       event,
+		fields:      e.fields,
+		orderingKey: e.orderingKey,
+		sequence:    e.sequence,
+	}
+
+	payload, err := encodeSequencedWebhook(e)
+	if err != nil {
+		s.notifier.params.Logger.Warnw("could not encode webhook", err, "event", e.event.Event)
+		s.countDropped()
+		return
+	}
+
+	s.retry(&retryingWebhook{
+		e:        e,
+		payload:  payload,
+		deadline: time.Now().Add(s.notifier.params.MaxRetryDuration),
+	})
+}
+
+func (s *webhookSender) retry(r *retryingWebhook) {
+	retry, err := s.send(r.e, r.payload)
+	if err == nil {
+		return
+	}
+
+	r.attempts++
+	backoff := webhookMinBackoff << (r.attempts - 1)
+	if backoff > webhookMaxBackoff || backoff <= 0 {
+		backoff = webhookMaxBackoff
+	}
+	// jittered, so that retries of many events and nodes do not arrive together
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	r.nextRetry = time.Now().Add(backoff)
+	if !retry || r.attempts >= webhookMaxAttempts || r.nextRetry.After(r.deadline) {
+		s.notifier.params.Logger.Warnw("failed to send webhook", err,
+			"url", s.url,
+			"event", r.e.event.Event,
+			"eventID", r.e.event.Id,
+			"attempts", r.attempts,
+		)
+		s.countDropped()
+		return
+	}
+
+	idx := len(s.retries)
+	for i, other := range s.retries {
+		if other.nextRetry.After(r.nextRetry) {
+			idx = i
+			break
+		}
+	}
+	s.retries = append(s.retries, nil)
+	copy(s.retries[idx+1:], s.retries[idx:])
+	s.retries[idx] = r
+}
+
+func (s *webhookSender) countDropped() {
+	s.lock.Lock()
+	s.dropped++
+	s.lock.Unlock()
+}
+
+// send posts the payload, returns whether a failure should be retried
+func (s *webhookSender) send(e *sequencedWebhook, payload []byte) (bool, error) {
+	sum := sha256.Sum256(payload)
+	token, err := auth.NewAccessToken(s.notifier.params.APIKey, s.notifier.params.APISecret).
+		SetValidFor(5 * time.Minute).
+		SetSha256(base64.StdEncoding.EncodeToString(sum[:])).
+		ToJWT()
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", token)
+	// use a custom mime type to ensure signature is checked prior to parsing
+	req.Header.Set("Content-Type", "application/webhook+json")
+	req.Header.Set("Idempotency-Key", e.event.Id)
+	req.Header.Set("X-LiveKit-Sequence", strconv.FormatUint(e.sequence, 10))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = res.Body.Close()
+	switch {
+	case res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned status %d", res.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook rejected with status %d", res.StatusCode)
+	}
+}
+
+// encodeSequencedWebhook adds the extra and ordering fields to the JSON encoding of the event,
+// webhook.ReceiveWebhookEvent discards them
+func encodeSequencedWebhook(e *sequencedWebhook) ([]byte, error) {
+	encoded, err := protojson.Marshal(e.event)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range e.fields {
+		if fields[k], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	if fields["ordering_key"], err = json.Marshal(e.orderingKey); err != nil {
+		return nil, err
+	}
+	if fields["sequence"], err = json.Marshal(e.sequence); err != nil {
+		return nil, err
+	}
+	if fields["idempotency_key"], err = json.Marshal(e.event.Id); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
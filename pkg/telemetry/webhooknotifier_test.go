@@ -0,0 +1,241 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/webhook"
+
+	"github.com/livekit/livekit-server/pkg/telemetry"
+	"github.com/livekit/livekit-server/pkg/utils"
+)
+
+type receivedWebhook struct {
+	Event          string `json:"event"`
+	Id             string `json:"id"`
+	OrderingKey    string `json:"ordering_key"`
+	Sequence       uint64 `json:"sequence"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	room := &livekit.Room{Sid: "RM_1", Name: "room"}
+	participant := &livekit.ParticipantInfo{Sid: "PA_1", Identity: "p1"}
+	provider := auth.NewSimpleKeyProvider("key", "secret")
+
+	var lock sync.Mutex
+	var received []receivedWebhook
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		// still a valid webhook
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		_, err = webhook.ReceiveWebhookEvent(r, provider)
+		require.NoError(t, err)
+
+		e := receivedWebhook{}
+		require.NoError(t, json.Unmarshal(body, &e))
+		require.Equal(t, e.IdempotencyKey, r.Header.Get("Idempotency-Key"))
+		received = append(received, e)
+	}))
+	defer server.Close()
+
+	n := telemetry.NewWebhookNotifier(telemetry.WebhookNotifierParams{
+		URLs:          []string{server.URL},
+		APIKey:        "key",
+		APISecret:     "secret",
+		ReorderWindow: 100 * time.Millisecond,
+	})
+
+	events := []*livekit.WebhookEvent{
+		{Id: "EV_1", Event: webhook.EventParticipantJoined, Room: room, Participant: participant},
+		{Id: "EV_2", Event: webhook.EventParticipantLeft, Room: room, Participant: participant},
+		// raced participant_left
+		{Id: "EV_3", Event: webhook.EventTrackPublished, Room: room, Participant: participant, Track: &livekit.TrackInfo{Sid: "TR_1"}},
+	}
+	for _, e := range events {
+		require.NoError(t, n.QueueNotify(context.Background(), e))
+	}
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	n.Stop(false)
+
+	lock.Lock()
+	defer lock.Unlock()
+	// the failed first event is retried after later ones, consumers order by sequence
+	sort.Slice(received, func(i, j int) bool { return received[i].Sequence < received[j].Sequence })
+	require.Equal(t, []string{webhook.EventParticipantJoined, webhook.EventTrackPublished, webhook.EventParticipantLeft},
+		[]string{received[0].Event, received[1].Event, received[2].Event})
+	for i, e := range received {
+		require.Equal(t, "RM_1", e.OrderingKey)
+		require.EqualValues(t, i+1, e.Sequence)
+		require.Equal(t, e.Id, e.IdempotencyKey)
+	}
+}
+
+func TestWebhookNotifierRedaction(t *testing.T) {
+	var lock sync.Mutex
+	var received []*livekit.WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := webhook.ReceiveWebhookEvent(r, auth.NewSimpleKeyProvider("key", "secret"))
+		require.NoError(t, err)
+		lock.Lock()
+		received = append(received, event)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	redactor, err := utils.NewPIIRedactor(utils.PIIRedactionRedact, "secret")
+	require.NoError(t, err)
+	n := telemetry.NewWebhookNotifier(telemetry.WebhookNotifierParams{
+		URLs:      []string{server.URL},
+		APIKey:    "key",
+		APISecret: "secret",
+		Redactor:  redactor,
+	})
+
+	participant := &livekit.ParticipantInfo{Sid: "PA_1", Identity: "alice", Name: "Alice", Metadata: "email"}
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{
+		Id:          "EV_1",
+		Event:       webhook.EventParticipantJoined,
+		Room:        &livekit.Room{Sid: "RM_1"},
+		Participant: participant,
+	}))
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	n.Stop(false)
+
+	p := received[0].Participant
+	require.Equal(t, "PA_1", p.Sid)
+	require.Equal(t, redactor.Identity("alice"), p.Identity)
+	require.Equal(t, "[redacted]", p.Name)
+	require.Equal(t, "[redacted]", p.Metadata)
+	// the caller's event is left alone
+	require.Equal(t, "alice", participant.Identity)
+}
+
+func TestWebhookNotifierSetURLs(t *testing.T) {
+	var lock sync.Mutex
+	received := make(map[string][]string)
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event, err := webhook.ReceiveWebhookEvent(r, auth.NewSimpleKeyProvider("key", "secret"))
+			require.NoError(t, err)
+			lock.Lock()
+			received[name] = append(received[name], event.Id)
+			lock.Unlock()
+		}))
+	}
+	first := newServer("first")
+	defer first.Close()
+	second := newServer("second")
+	defer second.Close()
+
+	n := telemetry.NewWebhookNotifier(telemetry.WebhookNotifierParams{
+		URLs:      []string{first.URL},
+		APIKey:    "key",
+		APISecret: "secret",
+	})
+	defer n.Stop(false)
+
+	receivedBy := func(name string, count int) func() bool {
+		return func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return len(received[name]) == count
+		}
+	}
+	room := &livekit.Room{Sid: "RM_1"}
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{Id: "EV_1", Event: webhook.EventRoomStarted, Room: room}))
+	require.Eventually(t, receivedBy("first", 1), 5*time.Second, 10*time.Millisecond)
+
+	n.SetURLs([]string{second.URL})
+	require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{Id: "EV_2", Event: webhook.EventRoomFinished, Room: room}))
+	require.Eventually(t, receivedBy("second", 1), 5*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Equal(t, []string{"EV_1"}, received["first"])
+	require.Equal(t, []string{"EV_2"}, received["second"])
+}
+
+func TestWebhookNotifierFailingURL(t *testing.T) {
+	var lock sync.Mutex
+	received := make(map[string][]*livekit.WebhookEvent)
+	newServer := func(name string, failing bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event, err := webhook.ReceiveWebhookEvent(r, auth.NewSimpleKeyProvider("key", "secret"))
+			require.NoError(t, err)
+			switch {
+			case failing && event.Id == "EV_1":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case failing && event.Id == "EV_rejected":
+				w.WriteHeader(http.StatusBadRequest)
+			default:
+				lock.Lock()
+				received[name] = append(received[name], event)
+				lock.Unlock()
+			}
+		}))
+	}
+	healthy := newServer("healthy", false)
+	defer healthy.Close()
+	failing := newServer("failing", true)
+	defer failing.Close()
+
+	n := telemetry.NewWebhookNotifier(telemetry.WebhookNotifierParams{
+		URLs:      []string{healthy.URL, failing.URL},
+		APIKey:    "key",
+		APISecret: "secret",
+	})
+	defer n.Stop(true)
+
+	room := &livekit.Room{Sid: "RM_1"}
+	for _, id := range []string{"EV_1", "EV_rejected", "EV_2"} {
+		require.NoError(t, n.QueueNotify(context.Background(), &livekit.WebhookEvent{Id: id, Event: webhook.EventRoomStarted, Room: room}))
+	}
+
+	// events after the one being retried are not held back
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(received["healthy"]) == 3 && len(received["failing"]) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	// each URL reports its own drops
+	require.Equal(t, "EV_2", received["failing"][0].Id)
+	require.EqualValues(t, 1, received["failing"][0].NumDropped)
+	for _, event := range received["healthy"] {
+		require.Zero(t, event.NumDropped)
+	}
+}
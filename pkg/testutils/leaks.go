@@ -0,0 +1,230 @@
+package testutils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MetricGoroutines  = "goroutines"
+	MetricHeapObjects = "heap_objects"
+	MetricHeapInuse   = "heap_inuse"
+	MetricOpenFDs     = "open_fds"
+
+	// number of goroutine stacks listed in a report
+	reportedStacks = 10
+
+	// heap wanders a little from cycle to cycle even without a leak
+	defaultHeapTolerance = 0.05
+)
+
+// ResourceSnapshot is taken after each room churn cycle of a soak test, when the server should be back at rest
+type ResourceSnapshot struct {
+	Cycle   int
+	Metrics map[string]int64
+	// goroutine counts by stack
+	Stacks map[string]int
+}
+
+// TakeResourceSnapshot collects garbage and snapshots goroutines, heap and open file descriptors of the process
+// along with the given object counts
+func TakeResourceSnapshot(cycle int, objects map[string]int) ResourceSnapshot {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	s := ResourceSnapshot{
+		Cycle: cycle,
+		Metrics: map[string]int64{
+			MetricGoroutines:  int64(runtime.NumGoroutine()),
+			MetricHeapObjects: int64(ms.HeapObjects),
+			MetricHeapInuse:   int64(ms.HeapInuse),
+		},
+		Stacks: goroutineStacks(),
+	}
+	if fds, err := countOpenFDs(); err == nil {
+		s.Metrics[MetricOpenFDs] = int64(fds)
+	}
+	for name, count := range objects {
+		s.Metrics[name] = int64(count)
+	}
+	return s
+}
+
+func countOpenFDs() (int, error) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err == nil {
+			// less the one used for reading the directory
+			return len(entries) - 1, nil
+		}
+	}
+	return 0, fmt.Errorf("open file descriptors cannot be listed on %s", runtime.GOOS)
+}
+
+// goroutineStacks parses the aggregated goroutine profile, keyed by the functions of each stack
+func goroutineStacks() map[string]int {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	stacks := make(map[string]int)
+	var count int
+	var frames []string
+	flush := func() {
+		if count > 0 && len(frames) > 0 {
+			stacks[strings.Join(frames, "\n")] += count
+		}
+		count, frames = 0, nil
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, " @ "):
+			// start of a stack: "<count> @ <pcs>"
+			flush()
+			count, _ = strconv.Atoi(strings.Fields(line)[0])
+		case strings.HasPrefix(line, "#\t"):
+			// "#\t<pc>\t<function>+<offset>\t<file>:<line>"
+			fields := strings.Split(line, "\t")
+			if len(fields) >= 4 {
+				frames = append(frames, fmt.Sprintf("%s %s", fields[2], strings.TrimSpace(fields[3])))
+			}
+		}
+	}
+	flush()
+	return stacks
+}
+
+// Leak is a metric that grew in each of the cycles of the detection window, by more than its tolerance overall
+type Leak struct {
+	Metric string
+	Values []int64
+}
+
+// LeakDetector reports metrics growing monotonically across room churn cycles. A server at rest after a cycle should
+// be back to where it was, growth that continues for the whole window is not warm up or noise
+type LeakDetector struct {
+	window     int
+	tolerances map[string]float64
+	snapshots  []ResourceSnapshot
+}
+
+func NewLeakDetector(window int) *LeakDetector {
+	if window < 2 {
+		window = 2
+	}
+	return &LeakDetector{
+		window: window,
+		tolerances: map[string]float64{
+			MetricHeapObjects: defaultHeapTolerance,
+			MetricHeapInuse:   defaultHeapTolerance,
+		},
+	}
+}
+
+// SetTolerance sets the growth of a metric over the window, relative to its value at the start of the window,
+// that is not reported as a leak
+func (d *LeakDetector) SetTolerance(metric string, tolerance float64) {
+	d.tolerances[metric] = tolerance
+}
+
+func (d *LeakDetector) Add(s ResourceSnapshot) {
+	d.snapshots = append(d.snapshots, s)
+}
+
+func (d *LeakDetector) Leaks() []Leak {
+	if len(d.snapshots) <= d.window {
+		return nil
+	}
+
+	// the window spans its cycles and the one before them
+	recent := d.snapshots[len(d.snapshots)-d.window-1:]
+	var leaks []Leak
+	for _, metric := range d.metrics() {
+		values := make([]int64, 0, len(recent))
+		growing := true
+		for i, s := range recent {
+			value, ok := s.Metrics[metric]
+			if !ok || (i > 0 && value <= values[i-1]) {
+				growing = false
+				break
+			}
+			values = append(values, value)
+		}
+		if growing && float64(values[len(values)-1]-values[0]) > d.tolerances[metric]*float64(values[0]) {
+			leaks = append(leaks, Leak{Metric: metric, Values: values})
+		}
+	}
+	return leaks
+}
+
+func (d *LeakDetector) metrics() []string {
+	var metrics []string
+	if len(d.snapshots) == 0 {
+		return metrics
+	}
+	for metric := range d.snapshots[len(d.snapshots)-1].Metrics {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// Report diffs the last snapshot against the first one, metric by metric, and lists the goroutine stacks that grew
+func (d *LeakDetector) Report() string {
+	if len(d.snapshots) == 0 {
+		return "no snapshots"
+	}
+	first, last := d.snapshots[0], d.snapshots[len(d.snapshots)-1]
+
+	leaking := make(map[string]bool)
+	for _, leak := range d.Leaks() {
+		leaking[leak.Metric] = true
+	}
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "resources after cycle %d compared to cycle %d\n", last.Cycle, first.Cycle)
+	for _, metric := range d.metrics() {
+		marker := " "
+		if leaking[metric] {
+			marker = "!"
+		}
+		_, _ = fmt.Fprintf(&b, "%s %-20s %12d -> %12d (%+d)\n",
+			marker, metric, first.Metrics[metric], last.Metrics[metric], last.Metrics[metric]-first.Metrics[metric])
+	}
+
+	type stackDiff struct {
+		stack string
+		diff  int
+	}
+	var diffs []stackDiff
+	for stack, count := range last.Stacks {
+		if diff := count - first.Stacks[stack]; diff > 0 {
+			diffs = append(diffs, stackDiff{stack: stack, diff: diff})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].diff != diffs[j].diff {
+			return diffs[i].diff > diffs[j].diff
+		}
+		return diffs[i].stack < diffs[j].stack
+	})
+	if len(diffs) > reportedStacks {
+		diffs = diffs[:reportedStacks]
+	}
+	for _, diff := range diffs {
+		_, _ = fmt.Fprintf(&b, "\n+%d goroutines\n%s\n", diff.diff, diff.stack)
+	}
+	return b.String()
+}
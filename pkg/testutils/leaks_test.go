@@ -0,0 +1,65 @@
+package testutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeakDetector(t *testing.T) {
+	snapshot := func(cycle int, goroutines int64, rooms int64, stacks map[string]int) ResourceSnapshot {
+		return ResourceSnapshot{
+			Cycle: cycle,
+			Metrics: map[string]int64{
+				MetricGoroutines: goroutines,
+				"rooms":          rooms,
+			},
+			Stacks: stacks,
+		}
+	}
+
+	d := NewLeakDetector(3)
+	d.Add(snapshot(0, 100, 0, map[string]int{"idle": 100}))
+	d.Add(snapshot(1, 104, 0, map[string]int{"idle": 100, "worker": 4}))
+	// going back down is not a leak
+	d.Add(snapshot(2, 102, 0, map[string]int{"idle": 100, "worker": 2}))
+	d.Add(snapshot(3, 105, 0, map[string]int{"idle": 100, "worker": 5}))
+	d.Add(snapshot(4, 106, 0, map[string]int{"idle": 100, "worker": 6}))
+	require.Empty(t, d.Leaks())
+
+	d.Add(snapshot(5, 110, 0, map[string]int{"idle": 100, "worker": 10}))
+	require.Equal(t, []Leak{{Metric: MetricGoroutines, Values: []int64{102, 105, 106, 110}}}, d.Leaks())
+
+	report := d.Report()
+	require.Contains(t, report, "resources after cycle 5 compared to cycle 0")
+	require.Contains(t, report, "! goroutines")
+	require.Contains(t, report, "  rooms")
+	require.Contains(t, report, "+10 goroutines\nworker")
+	require.NotContains(t, report, "idle")
+}
+
+func TestLeakDetectorTolerance(t *testing.T) {
+	d := NewLeakDetector(2)
+	for cycle, heap := range []int64{1000, 1010, 1020, 1030} {
+		d.Add(ResourceSnapshot{Cycle: cycle, Metrics: map[string]int64{MetricHeapInuse: heap}})
+	}
+	require.Empty(t, d.Leaks())
+
+	d.SetTolerance(MetricHeapInuse, 0.01)
+	require.Equal(t, []Leak{{Metric: MetricHeapInuse, Values: []int64{1010, 1020, 1030}}}, d.Leaks())
+}
+
+func TestTakeResourceSnapshot(t *testing.T) {
+	s := TakeResourceSnapshot(1, map[string]int{"rooms": 2})
+	require.Equal(t, 1, s.Cycle)
+	require.Equal(t, int64(2), s.Metrics["rooms"])
+	require.Positive(t, s.Metrics[MetricGoroutines])
+	require.Positive(t, s.Metrics[MetricHeapInuse])
+	require.NotEmpty(t, s.Stacks)
+
+	var stacks int
+	for _, count := range s.Stacks {
+		stacks += count
+	}
+	require.Positive(t, stacks)
+}
@@ -0,0 +1,463 @@
+package transcoder
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types"
+	"github.com/livekit/livekit-server/pkg/sfu/buffer"
+)
+
+const (
+	jobPrefix         = "TJ_"
+	workerSendBufSize = 32
+	bearerPrefix      = "Bearer "
+)
+
+var (
+	ErrNoWorkerAvailable = errors.New("no transcoder worker available")
+	ErrWorkerBusy        = errors.New("transcoder worker cannot accept more messages")
+)
+
+type jobKey struct {
+	roomName livekit.RoomName
+	trackID  livekit.TrackID
+	mime     string
+	// rid of the generated layer, empty for codec renditions
+	rid string
+}
+
+type job struct {
+	key      jobKey
+	start    StartJob
+	quality  livekit.VideoQuality
+	workerID string
+	state    JobState
+}
+
+type worker struct {
+	info   RegisterWorker
+	sendCh chan *ServerMessage
+	jobs   map[string]*job
+}
+
+func (w *worker) canProduce(mime string) bool {
+	if w.info.MaxJobs > 0 && len(w.jobs) >= w.info.MaxJobs {
+		return false
+	}
+	if len(w.info.Codecs) == 0 {
+		return true
+	}
+	for _, c := range w.info.Codecs {
+		if strings.EqualFold(c, mime) {
+			return true
+		}
+	}
+	return false
+}
+
+// ------------------------------------------------
+
+// Manager accepts transcoder workers and hands out jobs to produce alternate codec renditions of tracks
+type Manager struct {
+	keyProvider auth.KeyProvider
+	server      *grpc.Server
+	logger      logger.Logger
+
+	lock      sync.Mutex
+	workers   map[string]*worker
+	jobs      map[string]*job
+	jobsByKey map[jobKey]*job
+
+	onRenditionPublished func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID)
+	onLayerPublished     func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID, quality livekit.VideoQuality)
+}
+
+func NewManager(keyProvider auth.KeyProvider) *Manager {
+	m := &Manager{
+		keyProvider: keyProvider,
+		logger:      logger.GetLogger().WithValues("component", "transcoder"),
+		workers:     make(map[string]*worker),
+		jobs:        make(map[string]*job),
+		jobsByKey:   make(map[jobKey]*job),
+	}
+	m.server = grpc.NewServer(grpc.ForceServerCodec(jsonCodec{}))
+	m.server.RegisterService(&serviceDesc, m)
+	return m
+}
+
+// OnRenditionPublished is called when a worker has published the rendition of a track
+func (m *Manager) OnRenditionPublished(f func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID)) {
+	m.lock.Lock()
+	m.onRenditionPublished = f
+	m.lock.Unlock()
+}
+
+// OnLayerPublished is called when a worker has published a generated simulcast layer of a track
+func (m *Manager) OnLayerPublished(f func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID, quality livekit.VideoQuality)) {
+	m.lock.Lock()
+	m.onLayerPublished = f
+	m.lock.Unlock()
+}
+
+func (m *Manager) Serve(ln net.Listener) error {
+	return m.server.Serve(ln)
+}
+
+func (m *Manager) Stop() {
+	m.server.Stop()
+}
+
+func (m *Manager) NumWorkers() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.workers)
+}
+
+// ForRoom returns a transcoder for tracks in the given room
+func (m *Manager) ForRoom(roomName livekit.RoomName) *RoomTranscoder {
+	return &RoomTranscoder{
+		manager:  m,
+		roomName: roomName,
+	}
+}
+
+func (m *Manager) requestTranscode(
+	roomName livekit.RoomName,
+	track types.MediaTrack,
+	from webrtc.RTPCodecParameters,
+	to webrtc.RTPCodecCapability,
+) error {
+	key := jobKey{
+		roomName: roomName,
+		trackID:  track.ID(),
+		mime:     strings.ToLower(to.MimeType),
+	}
+	_, err := m.startJob(track, &job{
+		key: key,
+		start: StartJob{
+			RoomName:          string(roomName),
+			TrackID:           string(track.ID()),
+			PublisherIdentity: string(track.PublisherIdentity()),
+			FromMimeType:      from.MimeType,
+			ToMimeType:        to.MimeType,
+			ToFmtpLine:        to.SDPFmtpLine,
+		},
+	})
+	return err
+}
+
+func (m *Manager) requestLayers(
+	roomName livekit.RoomName,
+	track types.MediaTrack,
+	codec webrtc.RTPCodecParameters,
+	layers []*livekit.VideoLayer,
+) error {
+	var started []string
+	for _, layer := range layers {
+		rid := buffer.VideoQualityToRid(layer.Quality, nil)
+		jobID, err := m.startJob(track, &job{
+			key: jobKey{
+				roomName: roomName,
+				trackID:  track.ID(),
+				mime:     strings.ToLower(codec.MimeType),
+				rid:      rid,
+			},
+			start: StartJob{
+				RoomName:          string(roomName),
+				TrackID:           string(track.ID()),
+				PublisherIdentity: string(track.PublisherIdentity()),
+				FromMimeType:      codec.MimeType,
+				ToMimeType:        codec.MimeType,
+				ToFmtpLine:        codec.SDPFmtpLine,
+				Layer: &LayerSpec{
+					Rid:     rid,
+					Width:   layer.Width,
+					Height:  layer.Height,
+					Bitrate: layer.Bitrate,
+				},
+			},
+			quality: layer.Quality,
+		})
+		if err != nil {
+			// all or nothing, a partial set of layers is not announced
+			for _, id := range started {
+				m.stopJob(id)
+			}
+			return err
+		}
+		if jobID != "" {
+			started = append(started, jobID)
+		}
+	}
+	return nil
+}
+
+// startJob hands a job to the least loaded worker that can produce its codec, returns the job ID when started and
+// an empty one when the same job is already running
+func (m *Manager) startJob(track types.MediaTrack, j *job) (string, error) {
+	m.lock.Lock()
+	if _, ok := m.jobsByKey[j.key]; ok {
+		m.lock.Unlock()
+		return "", nil
+	}
+
+	var selected *worker
+	for _, w := range m.workers {
+		if !w.canProduce(j.start.ToMimeType) {
+			continue
+		}
+		if selected == nil || len(w.jobs) < len(selected.jobs) {
+			selected = w
+		}
+	}
+	if selected == nil {
+		m.lock.Unlock()
+		return "", ErrNoWorkerAvailable
+	}
+
+	j.start.JobID = utils.NewGuid(jobPrefix)
+	j.workerID = selected.info.WorkerID
+	j.state = JobStateStarted
+	select {
+	case selected.sendCh <- &ServerMessage{StartJob: &j.start}:
+	default:
+		m.lock.Unlock()
+		return "", ErrWorkerBusy
+	}
+
+	selected.jobs[j.start.JobID] = j
+	m.jobs[j.start.JobID] = j
+	m.jobsByKey[j.key] = j
+	m.lock.Unlock()
+
+	m.logger.Infow("transcode job started",
+		"jobID", j.start.JobID,
+		"workerID", j.workerID,
+		"room", j.key.roomName,
+		"trackID", j.key.trackID,
+		"from", j.start.FromMimeType,
+		"to", j.start.ToMimeType,
+		"rid", j.key.rid,
+	)
+
+	jobID := j.start.JobID
+	track.AddOnClose(func() {
+		m.stopJob(jobID)
+	})
+	return jobID, nil
+}
+
+func (m *Manager) stopJob(jobID string) {
+	m.lock.Lock()
+	j := m.removeJobLocked(jobID)
+	var w *worker
+	if j != nil {
+		w = m.workers[j.workerID]
+	}
+	if w != nil {
+		select {
+		case w.sendCh <- &ServerMessage{StopJob: &StopJob{JobID: jobID}}:
+		default:
+			m.logger.Warnw("could not send stop job", ErrWorkerBusy, "jobID", jobID, "workerID", j.workerID)
+		}
+	}
+	m.lock.Unlock()
+}
+
+func (m *Manager) removeJobLocked(jobID string) *job {
+	j := m.jobs[jobID]
+	if j == nil {
+		return nil
+	}
+
+	delete(m.jobs, jobID)
+	if m.jobsByKey[j.key] == j {
+		delete(m.jobsByKey, j.key)
+	}
+	if w := m.workers[j.workerID]; w != nil {
+		delete(w.jobs, jobID)
+	}
+	return j
+}
+
+func (m *Manager) handleStatus(workerID string, js *JobStatus) {
+	m.lock.Lock()
+	j := m.jobs[js.JobID]
+	if j == nil || j.workerID != workerID {
+		m.lock.Unlock()
+		m.logger.Debugw("status for unknown job", "jobID", js.JobID, "workerID", workerID)
+		return
+	}
+
+	var onRenditionPublished func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID)
+	var onLayerPublished func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID, quality livekit.VideoQuality)
+	switch js.State {
+	case JobStatePublished:
+		j.state = js.State
+		if j.start.Layer != nil {
+			onLayerPublished = m.onLayerPublished
+		} else {
+			onRenditionPublished = m.onRenditionPublished
+		}
+
+	case JobStateEnded, JobStateFailed:
+		m.removeJobLocked(js.JobID)
+	}
+	m.lock.Unlock()
+
+	m.logger.Infow("transcode job status",
+		"jobID", js.JobID,
+		"workerID", workerID,
+		"state", js.State,
+		"renditionTrackID", js.RenditionTrackID,
+		"error", js.Error,
+	)
+
+	if js.RenditionTrackID != "" {
+		if onRenditionPublished != nil {
+			onRenditionPublished(j.key.roomName, j.key.trackID, livekit.TrackID(js.RenditionTrackID))
+		}
+		if onLayerPublished != nil {
+			onLayerPublished(j.key.roomName, j.key.trackID, livekit.TrackID(js.RenditionTrackID), j.quality)
+		}
+	}
+}
+
+func (m *Manager) authenticate(ctx context.Context) error {
+	if m.keyProvider == nil {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], bearerPrefix) {
+		return status.Error(codes.Unauthenticated, "missing authorization token")
+	}
+
+	v, err := auth.ParseAPIToken(values[0][len(bearerPrefix):])
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "invalid authorization token")
+	}
+	secret := m.keyProvider.GetSecret(v.APIKey())
+	if secret == "" {
+		return status.Error(codes.Unauthenticated, "invalid API key: "+v.APIKey())
+	}
+	grants, err := v.Verify(secret)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "invalid token: "+err.Error())
+	}
+	if grants.Video == nil || !grants.Video.RoomAdmin {
+		return status.Error(codes.PermissionDenied, "roomAdmin grant is required")
+	}
+	return nil
+}
+
+func (m *Manager) work(stream grpc.ServerStream) error {
+	if err := m.authenticate(stream.Context()); err != nil {
+		return err
+	}
+
+	msg := &WorkerMessage{}
+	if err := stream.RecvMsg(msg); err != nil {
+		return err
+	}
+	if msg.Register == nil || msg.Register.WorkerID == "" {
+		return status.Error(codes.InvalidArgument, "first message must register worker")
+	}
+
+	w := &worker{
+		info:   *msg.Register,
+		sendCh: make(chan *ServerMessage, workerSendBufSize),
+		jobs:   make(map[string]*job),
+	}
+	workerID := w.info.WorkerID
+
+	m.lock.Lock()
+	if _, ok := m.workers[workerID]; ok {
+		m.lock.Unlock()
+		return status.Error(codes.AlreadyExists, "worker already registered")
+	}
+	m.workers[workerID] = w
+	m.lock.Unlock()
+
+	m.logger.Infow("transcoder worker registered", "workerID", workerID, "codecs", w.info.Codecs, "maxJobs", w.info.MaxJobs)
+	defer m.unregister(w)
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	sendErr := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-w.sendCh:
+				if err := stream.SendMsg(msg); err != nil {
+					sendErr <- err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		msg := &WorkerMessage{}
+		if err := stream.RecvMsg(msg); err != nil {
+			select {
+			case err = <-sendErr:
+			default:
+			}
+			return err
+		}
+
+		if msg.Status != nil {
+			m.handleStatus(workerID, msg.Status)
+		}
+	}
+}
+
+func (m *Manager) unregister(w *worker) {
+	m.lock.Lock()
+	delete(m.workers, w.info.WorkerID)
+	for jobID := range w.jobs {
+		m.removeJobLocked(jobID)
+	}
+	m.lock.Unlock()
+
+	m.logger.Infow("transcoder worker unregistered", "workerID", w.info.WorkerID)
+}
+
+// ------------------------------------------------
+
+// RoomTranscoder requests transcoding of tracks in a room
+type RoomTranscoder struct {
+	manager  *Manager
+	roomName livekit.RoomName
+}
+
+func (r *RoomTranscoder) RequestTranscode(track types.MediaTrack, from webrtc.RTPCodecParameters, to webrtc.RTPCodecCapability) error {
+	return r.manager.requestTranscode(r.roomName, track, from, to)
+}
+
+// RequestLayers starts generating lower simulcast layers of a single layer track, either all of them or none
+func (r *RoomTranscoder) RequestLayers(track types.MediaTrack, codec webrtc.RTPCodecParameters, layers []*livekit.VideoLayer) error {
+	return r.manager.requestLayers(r.roomName, track, codec, layers)
+}
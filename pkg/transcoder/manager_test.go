@@ -0,0 +1,239 @@
+package transcoder
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+
+	"github.com/livekit/livekit-server/pkg/rtc/types/typesfakes"
+)
+
+var (
+	vp8Codec = webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}}
+	h264     = webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264}
+)
+
+func TestRequestTranscode(t *testing.T) {
+	t.Run("no worker", func(t *testing.T) {
+		m, _ := startManager(t, nil)
+		err := m.ForRoom("room").RequestTranscode(newTrack("TR_1"), vp8Codec, h264)
+		require.ErrorIs(t, err, ErrNoWorkerAvailable)
+	})
+
+	t.Run("job is started and rendition linked", func(t *testing.T) {
+		m, dial := startManager(t, nil)
+		linked := make(chan [3]string, 1)
+		m.OnRenditionPublished(func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID) {
+			linked <- [3]string{string(roomName), string(trackID), string(renditionTrackID)}
+		})
+
+		stream := registerWorker(t, m, dial(context.Background()), "w1", []string{webrtc.MimeTypeH264}, 0)
+
+		track := newTrack("TR_1")
+		require.NoError(t, m.ForRoom("room").RequestTranscode(track, vp8Codec, h264))
+		// duplicate requests do not start another job
+		require.NoError(t, m.ForRoom("room").RequestTranscode(track, vp8Codec, h264))
+
+		msg, err := stream.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, msg.StartJob)
+		jobID := msg.StartJob.JobID
+		require.Equal(t, "room", msg.StartJob.RoomName)
+		require.Equal(t, "TR_1", msg.StartJob.TrackID)
+		require.Equal(t, webrtc.MimeTypeVP8, msg.StartJob.FromMimeType)
+		require.Equal(t, webrtc.MimeTypeH264, msg.StartJob.ToMimeType)
+
+		require.NoError(t, stream.Send(&WorkerMessage{Status: &JobStatus{
+			JobID:            jobID,
+			State:            JobStatePublished,
+			RenditionTrackID: "TR_2",
+		}}))
+		select {
+		case l := <-linked:
+			require.Equal(t, [3]string{"room", "TR_1", "TR_2"}, l)
+		case <-time.After(time.Second):
+			t.Fatal("rendition not published")
+		}
+
+		// closing source track stops the job
+		require.Equal(t, 1, track.AddOnCloseCallCount())
+		track.AddOnCloseArgsForCall(0)()
+		msg, err = stream.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, msg.StopJob)
+		require.Equal(t, jobID, msg.StopJob.JobID)
+	})
+
+	t.Run("codec and capacity", func(t *testing.T) {
+		m, dial := startManager(t, nil)
+		registerWorker(t, m, dial(context.Background()), "vp8-only", []string{webrtc.MimeTypeVP8}, 0)
+
+		err := m.ForRoom("room").RequestTranscode(newTrack("TR_1"), vp8Codec, h264)
+		require.ErrorIs(t, err, ErrNoWorkerAvailable)
+
+		registerWorker(t, m, dial(context.Background()), "single", nil, 1)
+		require.NoError(t, m.ForRoom("room").RequestTranscode(newTrack("TR_1"), vp8Codec, h264))
+		err = m.ForRoom("room").RequestTranscode(newTrack("TR_2"), vp8Codec, h264)
+		require.ErrorIs(t, err, ErrNoWorkerAvailable)
+	})
+
+	t.Run("jobs are released when worker disconnects", func(t *testing.T) {
+		m, dial := startManager(t, nil)
+		conn := dial(context.Background())
+		registerWorker(t, m, conn, "w1", nil, 1)
+		require.NoError(t, m.ForRoom("room").RequestTranscode(newTrack("TR_1"), vp8Codec, h264))
+
+		require.NoError(t, conn.Close())
+		require.Eventually(t, func() bool { return m.NumWorkers() == 0 }, time.Second, 10*time.Millisecond)
+
+		registerWorker(t, m, dial(context.Background()), "w2", nil, 1)
+		require.NoError(t, m.ForRoom("room").RequestTranscode(newTrack("TR_1"), vp8Codec, h264))
+	})
+}
+
+func TestRequestLayers(t *testing.T) {
+	layers := []*livekit.VideoLayer{
+		{Quality: livekit.VideoQuality_LOW, Width: 320, Height: 180, Bitrate: 150_000},
+		{Quality: livekit.VideoQuality_MEDIUM, Width: 640, Height: 360, Bitrate: 500_000},
+	}
+
+	t.Run("layer jobs are started and linked", func(t *testing.T) {
+		m, dial := startManager(t, nil)
+		linked := make(chan livekit.VideoQuality, 2)
+		m.OnRenditionPublished(func(_ livekit.RoomName, _ livekit.TrackID, _ livekit.TrackID) {
+			t.Error("layer linked as rendition")
+		})
+		m.OnLayerPublished(func(roomName livekit.RoomName, trackID livekit.TrackID, renditionTrackID livekit.TrackID, quality livekit.VideoQuality) {
+			require.Equal(t, livekit.RoomName("room"), roomName)
+			require.Equal(t, livekit.TrackID("TR_1"), trackID)
+			linked <- quality
+		})
+
+		stream := registerWorker(t, m, dial(context.Background()), "w1", nil, 0)
+		track := newTrack("TR_1")
+		require.NoError(t, m.ForRoom("room").RequestLayers(track, vp8Codec, layers))
+
+		for _, layer := range layers {
+			msg, err := stream.Recv()
+			require.NoError(t, err)
+			require.NotNil(t, msg.StartJob)
+			require.Equal(t, webrtc.MimeTypeVP8, msg.StartJob.FromMimeType)
+			require.Equal(t, webrtc.MimeTypeVP8, msg.StartJob.ToMimeType)
+			require.NotNil(t, msg.StartJob.Layer)
+			require.Equal(t, layer.Width, msg.StartJob.Layer.Width)
+			require.Equal(t, layer.Height, msg.StartJob.Layer.Height)
+			require.Equal(t, layer.Bitrate, msg.StartJob.Layer.Bitrate)
+
+			require.NoError(t, stream.Send(&WorkerMessage{Status: &JobStatus{
+				JobID:            msg.StartJob.JobID,
+				State:            JobStatePublished,
+				RenditionTrackID: "TR_" + msg.StartJob.Layer.Rid,
+			}}))
+			select {
+			case quality := <-linked:
+				require.Equal(t, layer.Quality, quality)
+			case <-time.After(time.Second):
+				t.Fatal("layer not published")
+			}
+		}
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		m, dial := startManager(t, nil)
+		stream := registerWorker(t, m, dial(context.Background()), "single", nil, 1)
+
+		err := m.ForRoom("room").RequestLayers(newTrack("TR_1"), vp8Codec, layers)
+		require.ErrorIs(t, err, ErrNoWorkerAvailable)
+
+		msg, err := stream.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, msg.StartJob)
+		msg, err = stream.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, msg.StopJob)
+
+		// capacity is released for other tracks
+		require.NoError(t, m.ForRoom("room").RequestTranscode(newTrack("TR_2"), vp8Codec, h264))
+	})
+}
+
+func TestWorkerAuth(t *testing.T) {
+	keyProvider := auth.NewFileBasedKeyProviderFromMap(map[string]string{"key": "secret"})
+	m, dial := startManager(t, keyProvider)
+
+	connect := func(grant *auth.VideoGrant) error {
+		token, err := auth.NewAccessToken("key", "secret").AddGrant(grant).ToJWT()
+		require.NoError(t, err)
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", bearerPrefix+token)
+		stream, err := Connect(ctx, dial(ctx))
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&WorkerMessage{Register: &RegisterWorker{WorkerID: "w1"}}))
+		_, err = stream.Recv()
+		return err
+	}
+
+	require.Error(t, connect(&auth.VideoGrant{RoomJoin: true}))
+	require.Equal(t, 0, m.NumWorkers())
+
+	go func() {
+		_ = connect(&auth.VideoGrant{RoomAdmin: true})
+	}()
+	require.Eventually(t, func() bool { return m.NumWorkers() == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func startManager(t *testing.T, keyProvider auth.KeyProvider) (*Manager, func(ctx context.Context) *grpc.ClientConn) {
+	m := NewManager(keyProvider)
+	ln := bufconn.Listen(1 << 20)
+	go func() {
+		_ = m.Serve(ln)
+	}()
+	t.Cleanup(m.Stop)
+
+	dial := func(ctx context.Context) *grpc.ClientConn {
+		conn, err := grpc.DialContext(ctx, "bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return ln.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		return conn
+	}
+	return m, dial
+}
+
+func registerWorker(t *testing.T, m *Manager, conn *grpc.ClientConn, workerID string, codecs []string, maxJobs int) *WorkerStream {
+	numWorkers := m.NumWorkers()
+	// stream lives as long as the connection
+	stream, err := Connect(context.Background(), conn)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&WorkerMessage{Register: &RegisterWorker{
+		WorkerID: workerID,
+		Codecs:   codecs,
+		MaxJobs:  maxJobs,
+	}}))
+	require.Eventually(t, func() bool { return m.NumWorkers() == numWorkers+1 }, time.Second, 10*time.Millisecond)
+	return stream
+}
+
+func newTrack(trackID livekit.TrackID) *typesfakes.FakeMediaTrack {
+	track := &typesfakes.FakeMediaTrack{}
+	track.IDReturns(trackID)
+	track.PublisherIdentityReturns("publisher")
+	return track
+}
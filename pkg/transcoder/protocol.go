@@ -0,0 +1,152 @@
+package transcoder
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+)
+
+// Transcoder workers keep a single bidirectional stream open for their lifetime. The first message a worker
+// sends registers it, after that the server sends jobs and the worker reports job status on the same stream.
+// Messages are JSON encoded (gRPC content-subtype "json"), so workers do not need generated stubs.
+//
+// A job asks the worker to subscribe to a track, transcode it and publish the result to the same room as a
+// hidden participant. Once published, the worker reports the rendition track ID and the SFU attaches the
+// rendition to the source track so that it is picked for subscribers that cannot decode the source codec.
+//
+// A job with a layer asks the worker to scale a single layer track down instead, keeping its codec. The SFU
+// attaches the published result as a lower simulcast layer of the source track.
+
+const (
+	ServiceName = "livekit.Transcoder"
+	workMethod  = "/" + ServiceName + "/Work"
+)
+
+type JobState string
+
+const (
+	JobStateStarted   JobState = "started"
+	JobStatePublished JobState = "published"
+	JobStateEnded     JobState = "ended"
+	JobStateFailed    JobState = "failed"
+)
+
+// WorkerMessage is sent from worker to server, exactly one field is set
+type WorkerMessage struct {
+	Register *RegisterWorker `json:"register,omitempty"`
+	Status   *JobStatus      `json:"status,omitempty"`
+}
+
+type RegisterWorker struct {
+	WorkerID string `json:"worker_id"`
+	// mime types the worker can produce, any codec when empty
+	Codecs []string `json:"codecs,omitempty"`
+	// maximum number of concurrent jobs, unlimited when 0
+	MaxJobs int `json:"max_jobs,omitempty"`
+}
+
+type JobStatus struct {
+	JobID string   `json:"job_id"`
+	State JobState `json:"state"`
+	// set when state is published
+	RenditionTrackID string `json:"rendition_track_id,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// ServerMessage is sent from server to worker, exactly one field is set
+type ServerMessage struct {
+	StartJob *StartJob `json:"start_job,omitempty"`
+	StopJob  *StopJob  `json:"stop_job,omitempty"`
+}
+
+type StartJob struct {
+	JobID             string `json:"job_id"`
+	RoomName          string `json:"room_name"`
+	TrackID           string `json:"track_id"`
+	PublisherIdentity string `json:"publisher_identity"`
+	FromMimeType      string `json:"from_mime_type"`
+	ToMimeType        string `json:"to_mime_type"`
+	ToFmtpLine        string `json:"to_fmtp_line,omitempty"`
+	// set when generating a simulcast layer
+	Layer *LayerSpec `json:"layer,omitempty"`
+}
+
+type LayerSpec struct {
+	// rid the layer is known by, q or h
+	Rid    string `json:"rid"`
+	Width  uint32 `json:"width"`
+	Height uint32 `json:"height"`
+	// target bitrate in bps, left to the worker when 0
+	Bitrate uint32 `json:"bitrate,omitempty"`
+}
+
+type StopJob struct {
+	JobID string `json:"job_id"`
+}
+
+// ------------------------------------------------
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// ------------------------------------------------
+
+type workServer interface {
+	work(stream grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*workServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Work",
+			Handler:       workHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+func workHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(workServer).work(stream)
+}
+
+// WorkerStream is the worker side of the Work stream
+type WorkerStream struct {
+	grpc.ClientStream
+}
+
+// Connect opens the Work stream on a connection to the server, used by workers written in Go
+func Connect(ctx context.Context, conn *grpc.ClientConn, opts ...grpc.CallOption) (*WorkerStream, error) {
+	opts = append(opts, grpc.ForceCodec(jsonCodec{}))
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], workMethod, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkerStream{ClientStream: stream}, nil
+}
+
+func (s *WorkerStream) Send(msg *WorkerMessage) error {
+	return s.SendMsg(msg)
+}
+
+func (s *WorkerStream) Recv() (*ServerMessage, error) {
+	msg := &ServerMessage{}
+	if err := s.RecvMsg(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
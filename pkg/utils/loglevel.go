@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"go.uber.org/atomic"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// LeveledLogger filters entries below a level that can be changed at runtime, the logger it wraps is expected to
+// log at debug level. Loggers derived from it share the level, so that loggers of rooms and participants created
+// before a change follow it as well
+type LeveledLogger struct {
+	base  logger.Logger
+	level *atomic.Int32
+}
+
+func NewLeveledLogger(l logger.Logger, level string) *LeveledLogger {
+	return &LeveledLogger{
+		// account for the wrapper
+		base:  l.WithCallDepth(1),
+		level: atomic.NewInt32(int32(logger.ParseZapLevel(level))),
+	}
+}
+
+func (l *LeveledLogger) SetLevel(level string) {
+	l.level.Store(int32(logger.ParseZapLevel(level)))
+}
+
+func (l *LeveledLogger) enabled(level zapcore.Level) bool {
+	return int32(level) >= l.level.Load()
+}
+
+func (l *LeveledLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	if l.enabled(zapcore.DebugLevel) {
+		l.base.Debugw(msg, keysAndValues...)
+	}
+}
+
+func (l *LeveledLogger) Infow(msg string, keysAndValues ...interface{}) {
+	if l.enabled(zapcore.InfoLevel) {
+		l.base.Infow(msg, keysAndValues...)
+	}
+}
+
+func (l *LeveledLogger) Warnw(msg string, err error, keysAndValues ...interface{}) {
+	if l.enabled(zapcore.WarnLevel) {
+		l.base.Warnw(msg, err, keysAndValues...)
+	}
+}
+
+func (l *LeveledLogger) Errorw(msg string, err error, keysAndValues ...interface{}) {
+	if l.enabled(zapcore.ErrorLevel) {
+		l.base.Errorw(msg, err, keysAndValues...)
+	}
+}
+
+func (l *LeveledLogger) WithValues(keysAndValues ...interface{}) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithValues(keysAndValues...)
+	return &dup
+}
+
+func (l *LeveledLogger) WithName(name string) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithName(name)
+	return &dup
+}
+
+func (l *LeveledLogger) WithCallDepth(depth int) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithCallDepth(depth)
+	return &dup
+}
+
+func (l *LeveledLogger) WithItemSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithItemSampler()
+	return &dup
+}
+
+func (l *LeveledLogger) WithoutSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithoutSampler()
+	return &dup
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeveledLogger(t *testing.T) {
+	base := &warningRecorder{}
+	l := NewLeveledLogger(base, "error")
+	derived := l.WithValues("participant", "alice")
+
+	derived.Warnw("filtered", nil)
+	require.Empty(t, base.warnings())
+
+	// loggers derived before the change follow it
+	l.SetLevel("info")
+	derived.Warnw("logged", nil)
+	require.Len(t, base.warnings(), 1)
+	require.Equal(t, []interface{}{"participant", "alice"}, base.warnings()[0].context)
+}
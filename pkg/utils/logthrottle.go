@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// above this many distinct warnings per interval, warnings are logged without aggregation
+const maxThrottledWarnings = 10000
+
+// ThrottledLogger aggregates repeated identical warnings. The first occurrence is logged right away, repeats
+// within the interval are counted and logged as a single summary entry when the interval ends.
+// Warnings are identical when the message and the values of the logger they are logged with match,
+// so a misbehaving participant only throttles its own warnings.
+type ThrottledLogger struct {
+	base     logger.Logger
+	throttle *warningThrottle
+
+	// identifies the logger the warnings are logged with
+	context string
+}
+
+type warningThrottle struct {
+	interval time.Duration
+
+	lock     sync.Mutex
+	warnings map[string]*throttledWarning
+}
+
+type throttledWarning struct {
+	logger  logger.Logger
+	msg     string
+	err     error
+	values  []interface{}
+	count   int
+	started time.Time
+}
+
+func NewThrottledLogger(l logger.Logger, interval time.Duration) *ThrottledLogger {
+	return &ThrottledLogger{
+		// account for the wrapper
+		base: l.WithCallDepth(1),
+		throttle: &warningThrottle{
+			interval: interval,
+			warnings: make(map[string]*throttledWarning),
+		},
+	}
+}
+
+func (l *ThrottledLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.base.Debugw(msg, keysAndValues...)
+}
+
+func (l *ThrottledLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.base.Infow(msg, keysAndValues...)
+}
+
+func (l *ThrottledLogger) Warnw(msg string, err error, keysAndValues ...interface{}) {
+	if l.throttle.suppress(l, msg, err, keysAndValues) {
+		return
+	}
+	l.base.Warnw(msg, err, keysAndValues...)
+}
+
+func (l *ThrottledLogger) Errorw(msg string, err error, keysAndValues ...interface{}) {
+	l.base.Errorw(msg, err, keysAndValues...)
+}
+
+func (l *ThrottledLogger) WithValues(keysAndValues ...interface{}) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithValues(keysAndValues...)
+	dup.context = l.context + throttleContext(keysAndValues)
+	return &dup
+}
+
+func (l *ThrottledLogger) WithName(name string) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithName(name)
+	dup.context = l.context + "." + name
+	return &dup
+}
+
+func (l *ThrottledLogger) WithCallDepth(depth int) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithCallDepth(depth)
+	return &dup
+}
+
+func (l *ThrottledLogger) WithItemSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithItemSampler()
+	return &dup
+}
+
+func (l *ThrottledLogger) WithoutSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithoutSampler()
+	return &dup
+}
+
+// suppress returns true when the warning has been logged within the interval, it will be part of the summary
+func (t *warningThrottle) suppress(l *ThrottledLogger, msg string, err error, keysAndValues []interface{}) bool {
+	key := l.context + "|" + msg
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	w := t.warnings[key]
+	if w == nil {
+		if len(t.warnings) >= maxThrottledWarnings {
+			return false
+		}
+		t.warnings[key] = &throttledWarning{started: time.Now()}
+		time.AfterFunc(t.interval, func() { t.flush(key) })
+		return false
+	}
+
+	w.logger = l.base
+	w.msg = msg
+	w.err = err
+	w.values = keysAndValues
+	w.count++
+	return true
+}
+
+func (t *warningThrottle) flush(key string) {
+	t.lock.Lock()
+	w := t.warnings[key]
+	if w == nil {
+		t.lock.Unlock()
+		return
+	}
+	if w.count == 0 {
+		// quiet for an interval, the next occurrence is logged right away
+		delete(t.warnings, key)
+		t.lock.Unlock()
+		return
+	}
+	summary := *w
+	w.count = 0
+	w.started = time.Now()
+	time.AfterFunc(t.interval, func() { t.flush(key) })
+	t.lock.Unlock()
+
+	// values of the last occurrence
+	values := append([]interface{}{"repeated", summary.count, "since", summary.started}, summary.values...)
+	summary.logger.Warnw(summary.msg, summary.err, values...)
+}
+
+// throttleContext identifies logger values, only scalars are formatted as other values could be modified concurrently
+func throttleContext(keysAndValues []interface{}) string {
+	var sb strings.Builder
+	for _, v := range keysAndValues {
+		sb.WriteByte('|')
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			sb.WriteString(fmt.Sprint(v))
+		default:
+			sb.WriteString(fmt.Sprintf("%T", v))
+		}
+	}
+	return sb.String()
+}
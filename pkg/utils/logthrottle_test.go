@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/logger"
+)
+
+func TestThrottledLogger(t *testing.T) {
+	base := &warningRecorder{}
+	l := NewThrottledLogger(base, 50*time.Millisecond)
+	alice := l.WithValues("participant", "alice")
+	bob := l.WithValues("participant", "bob")
+
+	for i := 0; i < 10; i++ {
+		alice.Warnw("could not parse packet", nil, "sequenceNumber", i)
+	}
+	bob.Warnw("could not parse packet", nil)
+	alice.Warnw("other warning", nil)
+	require.Len(t, base.warnings(), 3)
+
+	require.Eventually(t, func() bool { return len(base.warnings()) == 4 }, time.Second, 10*time.Millisecond)
+	summary := base.warnings()[3]
+	require.Equal(t, "could not parse packet", summary.msg)
+	require.Equal(t, []interface{}{"participant", "alice"}, summary.context)
+	require.Equal(t, "repeated", summary.values[0])
+	require.Equal(t, 9, summary.values[1])
+	require.Equal(t, []interface{}{"sequenceNumber", 9}, summary.values[4:])
+
+	// logged right away once quiet for an interval
+	time.Sleep(150 * time.Millisecond)
+	alice.Warnw("could not parse packet", nil)
+	require.Len(t, base.warnings(), 5)
+}
+
+type recordedWarning struct {
+	msg     string
+	context []interface{}
+	values  []interface{}
+}
+
+type warningRecorder struct {
+	root    *warningRecorder
+	lock    sync.Mutex
+	entries []recordedWarning
+	context []interface{}
+}
+
+func (r *warningRecorder) warnings() []recordedWarning {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]recordedWarning{}, r.entries...)
+}
+
+func (r *warningRecorder) Debugw(_ string, _ ...interface{}) {}
+
+func (r *warningRecorder) Infow(_ string, _ ...interface{}) {}
+
+func (r *warningRecorder) Warnw(msg string, _ error, keysAndValues ...interface{}) {
+	root := r
+	if r.root != nil {
+		root = r.root
+	}
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	root.entries = append(root.entries, recordedWarning{msg: msg, context: r.context, values: keysAndValues})
+}
+
+func (r *warningRecorder) Errorw(_ string, _ error, _ ...interface{}) {}
+
+func (r *warningRecorder) WithValues(keysAndValues ...interface{}) logger.Logger {
+	root := r
+	if r.root != nil {
+		root = r.root
+	}
+	return &warningRecorder{root: root, context: append(append([]interface{}{}, r.context...), keysAndValues...)}
+}
+
+func (r *warningRecorder) WithName(_ string) logger.Logger   { return r }
+func (r *warningRecorder) WithCallDepth(_ int) logger.Logger { return r }
+func (r *warningRecorder) WithItemSampler() logger.Logger    { return r }
+func (r *warningRecorder) WithoutSampler() logger.Logger     { return r }
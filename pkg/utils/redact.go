@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/livekit/protocol/logger"
+)
+
+type PIIRedactionMode string
+
+const (
+	// identities, names and metadata are replaced by pseudonyms
+	PIIRedactionHash PIIRedactionMode = "hash"
+	// identities are replaced by pseudonyms, names and metadata are removed
+	PIIRedactionRedact PIIRedactionMode = "redact"
+
+	pseudonymPrefix = "anon_"
+	redactedValue   = "[redacted]"
+)
+
+// logger values holding participant data
+var (
+	piiIdentityKeys = map[string]bool{
+		"participant": true,
+		"identity":    true,
+		"publisher":   true,
+		"subscriber":  true,
+		"pIdentity":   true,
+	}
+	piiDataKeys = map[string]bool{
+		"metadata":        true,
+		"participantName": true,
+	}
+)
+
+var piiRedactor atomic.Value
+
+// PIIRedactor replaces participant identities, names and metadata. Pseudonyms are keyed hashes, so the same
+// identity always maps to the same pseudonym and can be correlated across logs without being revealed.
+// A nil redactor leaves values as they are
+type PIIRedactor struct {
+	mode PIIRedactionMode
+	key  []byte
+}
+
+func NewPIIRedactor(mode PIIRedactionMode, hashKey string) (*PIIRedactor, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case PIIRedactionHash, PIIRedactionRedact:
+	default:
+		return nil, fmt.Errorf("invalid pii redaction mode %s", mode)
+	}
+	if hashKey == "" {
+		// pseudonyms of an unkeyed hash could be reversed by hashing candidate identities
+		return nil, fmt.Errorf("pii redaction mode %s requires a hash_key", mode)
+	}
+	return &PIIRedactor{
+		mode: mode,
+		key:  []byte(hashKey),
+	}, nil
+}
+
+// SetPIIRedactor sets the redactor used for values that are not logged, such as error reports
+func SetPIIRedactor(r *PIIRedactor) {
+	piiRedactor.Store(r)
+}
+
+func GetPIIRedactor() *PIIRedactor {
+	r, _ := piiRedactor.Load().(*PIIRedactor)
+	return r
+}
+
+func (r *PIIRedactor) Pseudonym(value string) string {
+	if r == nil || value == "" {
+		return value
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (r *PIIRedactor) Identity(identity string) string {
+	return r.Pseudonym(identity)
+}
+
+// Data redacts a participant name or metadata
+func (r *PIIRedactor) Data(value string) string {
+	if r == nil || value == "" {
+		return value
+	}
+	if r.mode == PIIRedactionRedact {
+		return redactedValue
+	}
+	return r.Pseudonym(value)
+}
+
+func (r *PIIRedactor) redactValues(keysAndValues []interface{}) []interface{} {
+	var redacted []interface{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, _ := keysAndValues[i].(string)
+		isIdentity := piiIdentityKeys[key]
+		if !isIdentity && !piiDataKeys[key] {
+			continue
+		}
+		v := reflect.ValueOf(keysAndValues[i+1])
+		if v.Kind() != reflect.String {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]interface{}{}, keysAndValues...)
+		}
+		if isIdentity {
+			redacted[i+1] = r.Identity(v.String())
+		} else {
+			redacted[i+1] = r.Data(v.String())
+		}
+	}
+	if redacted == nil {
+		return keysAndValues
+	}
+	return redacted
+}
+
+// ------------------------------------------------
+
+// RedactingLogger replaces participant identities, names and metadata in logged values
+type RedactingLogger struct {
+	base     logger.Logger
+	redactor *PIIRedactor
+}
+
+func NewRedactingLogger(l logger.Logger, r *PIIRedactor) *RedactingLogger {
+	return &RedactingLogger{
+		// account for the wrapper
+		base:     l.WithCallDepth(1),
+		redactor: r,
+	}
+}
+
+func (l *RedactingLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.base.Debugw(msg, l.redactor.redactValues(keysAndValues)...)
+}
+
+func (l *RedactingLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.base.Infow(msg, l.redactor.redactValues(keysAndValues)...)
+}
+
+func (l *RedactingLogger) Warnw(msg string, err error, keysAndValues ...interface{}) {
+	l.base.Warnw(msg, err, l.redactor.redactValues(keysAndValues)...)
+}
+
+func (l *RedactingLogger) Errorw(msg string, err error, keysAndValues ...interface{}) {
+	l.base.Errorw(msg, err, l.redactor.redactValues(keysAndValues)...)
+}
+
+func (l *RedactingLogger) WithValues(keysAndValues ...interface{}) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithValues(l.redactor.redactValues(keysAndValues)...)
+	return &dup
+}
+
+func (l *RedactingLogger) WithName(name string) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithName(name)
+	return &dup
+}
+
+func (l *RedactingLogger) WithCallDepth(depth int) logger.Logger {
+	dup := *l
+	dup.base = l.base.WithCallDepth(depth)
+	return &dup
+}
+
+func (l *RedactingLogger) WithItemSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithItemSampler()
+	return &dup
+}
+
+func (l *RedactingLogger) WithoutSampler() logger.Logger {
+	dup := *l
+	dup.base = l.base.WithoutSampler()
+	return &dup
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+func TestRedactingLogger(t *testing.T) {
+	t.Run("hash", func(t *testing.T) {
+		r, err := NewPIIRedactor(PIIRedactionHash, "secret")
+		require.NoError(t, err)
+		base := &warningRecorder{}
+		l := NewRedactingLogger(base, r).WithValues("participant", livekit.ParticipantIdentity("alice"), "pID", "PA_1")
+		l.Warnw("could not parse packet", nil, "metadata", "email", "publisher", "bob", "sequenceNumber", 1)
+
+		warnings := base.warnings()
+		require.Len(t, warnings, 1)
+		alice := warnings[0].context[1].(string)
+		require.True(t, strings.HasPrefix(alice, "anon_"))
+		require.Equal(t, "PA_1", warnings[0].context[3])
+		require.Equal(t, r.Pseudonym("email"), warnings[0].values[1])
+		require.Equal(t, r.Identity("bob"), warnings[0].values[3])
+		require.Equal(t, 1, warnings[0].values[5])
+
+		// pseudonyms are stable for a key
+		same, _ := NewPIIRedactor(PIIRedactionHash, "secret")
+		require.Equal(t, alice, same.Identity("alice"))
+		other, _ := NewPIIRedactor(PIIRedactionHash, "other")
+		require.NotEqual(t, alice, other.Identity("alice"))
+	})
+
+	t.Run("redact", func(t *testing.T) {
+		r, err := NewPIIRedactor(PIIRedactionRedact, "secret")
+		require.NoError(t, err)
+		require.Equal(t, redactedValue, r.Data("email"))
+		require.Equal(t, "", r.Data(""))
+		require.True(t, strings.HasPrefix(r.Identity("alice"), "anon_"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r, err := NewPIIRedactor("", "")
+		require.NoError(t, err)
+		require.Nil(t, r)
+		require.Equal(t, "alice", r.Identity("alice"))
+
+		_, err = NewPIIRedactor("mask", "")
+		require.Error(t, err)
+	})
+
+	t.Run("missing hash key", func(t *testing.T) {
+		_, err := NewPIIRedactor(PIIRedactionHash, "")
+		require.Error(t, err)
+		_, err = NewPIIRedactor(PIIRedactionRedact, "")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// ResourceOwner accounts for the goroutines, sockets and timers started on behalf of a workload, e. g. a room or
+// a participant in it, so capacity and leaks can be looked at per workload. Resources of an owner are also
+// accounted to its parent. A nil owner does not account, but still starts goroutines and timers.
+type ResourceOwner struct {
+	name   string
+	parent *ResourceOwner
+
+	goroutines atomic.Int32
+	sockets    atomic.Int32
+	timers     atomic.Int32
+}
+
+type ResourceStats struct {
+	Goroutines int32 `json:"goroutines"`
+	Sockets    int32 `json:"sockets"`
+	Timers     int32 `json:"timers"`
+}
+
+func NewResourceOwner(name string, parent *ResourceOwner) *ResourceOwner {
+	return &ResourceOwner{
+		name:   name,
+		parent: parent,
+	}
+}
+
+func (o *ResourceOwner) Name() string {
+	if o == nil {
+		return ""
+	}
+	return o.name
+}
+
+// Go runs f in a goroutine accounted to the owner till f returns
+func (o *ResourceOwner) Go(f func()) {
+	o.addGoroutines(1)
+	go func() {
+		defer o.addGoroutines(-1)
+		f()
+	}()
+}
+
+// AfterFunc is time.AfterFunc with the timer accounted to the owner till it fires or is stopped,
+// and f accounted as a goroutine while it runs
+func (o *ResourceOwner) AfterFunc(d time.Duration, f func()) *ResourceTimer {
+	o.addTimers(1)
+	t := &ResourceTimer{owner: o}
+	t.timer = time.AfterFunc(d, func() {
+		o.addTimers(-1)
+		o.addGoroutines(1)
+		defer o.addGoroutines(-1)
+		f()
+	})
+	return t
+}
+
+// AddSockets accounts sockets opened, or closed with a negative delta, to the owner
+func (o *ResourceOwner) AddSockets(delta int32) {
+	for ; o != nil; o = o.parent {
+		o.sockets.Add(delta)
+	}
+}
+
+func (o *ResourceOwner) Stats() ResourceStats {
+	if o == nil {
+		return ResourceStats{}
+	}
+	return ResourceStats{
+		Goroutines: o.goroutines.Load(),
+		Sockets:    o.sockets.Load(),
+		Timers:     o.timers.Load(),
+	}
+}
+
+func (o *ResourceOwner) addGoroutines(delta int32) {
+	for ; o != nil; o = o.parent {
+		o.goroutines.Add(delta)
+	}
+}
+
+func (o *ResourceOwner) addTimers(delta int32) {
+	for ; o != nil; o = o.parent {
+		o.timers.Add(delta)
+	}
+}
+
+// ---------------------------------------------------------------------
+
+// ResourceTimer is a timer started by ResourceOwner.AfterFunc
+type ResourceTimer struct {
+	owner *ResourceOwner
+	timer *time.Timer
+}
+
+// Stop has the semantics of time.Timer.Stop, the timer is no longer accounted when it was stopped before firing
+func (t *ResourceTimer) Stop() bool {
+	if !t.timer.Stop() {
+		return false
+	}
+	t.owner.addTimers(-1)
+	return true
+}
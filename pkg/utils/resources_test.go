@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceOwner(t *testing.T) {
+	t.Run("resources are accounted to parents", func(t *testing.T) {
+		room := NewResourceOwner("room", nil)
+		alice := NewResourceOwner("alice", room)
+		bob := NewResourceOwner("bob", room)
+
+		done := make(chan struct{})
+		alice.Go(func() { <-done })
+		bob.Go(func() { <-done })
+		alice.AddSockets(2)
+
+		require.Equal(t, ResourceStats{Goroutines: 1, Sockets: 2}, alice.Stats())
+		require.Equal(t, ResourceStats{Goroutines: 1}, bob.Stats())
+		require.Equal(t, ResourceStats{Goroutines: 2, Sockets: 2}, room.Stats())
+
+		close(done)
+		alice.AddSockets(-2)
+		require.Eventually(t, func() bool {
+			return room.Stats() == ResourceStats{}
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("timers are accounted till fired or stopped", func(t *testing.T) {
+		o := NewResourceOwner("room", nil)
+
+		stopped := o.AfterFunc(time.Hour, func() {})
+		fired := make(chan struct{})
+		release := make(chan struct{})
+		o.AfterFunc(time.Millisecond, func() {
+			close(fired)
+			<-release
+		})
+		<-fired
+		// fired timer runs as a goroutine
+		require.Equal(t, ResourceStats{Goroutines: 1, Timers: 1}, o.Stats())
+
+		require.True(t, stopped.Stop())
+		require.False(t, stopped.Stop())
+		close(release)
+		require.Eventually(t, func() bool {
+			return o.Stats() == ResourceStats{}
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("nil owner does not account", func(t *testing.T) {
+		var o *ResourceOwner
+		done := make(chan struct{})
+		o.Go(func() { close(done) })
+		<-done
+		o.AddSockets(1)
+		require.True(t, o.AfterFunc(time.Hour, func() {}).Stop())
+		require.Equal(t, ResourceStats{}, o.Stats())
+	})
+}
@@ -150,7 +150,7 @@ func createSingleNodeServer(configUpdater func(*config.Config)) *service.Livekit
 	}
 	currentNode.Id = utils.NewGuid(nodeID1)
 
-	s, err := service.InitializeServer(conf, currentNode)
+	s, err := service.InitializeServer(config.NewCurrent(conf), currentNode)
 	if err != nil {
 		panic(fmt.Sprintf("could not create server: %v", err))
 	}
@@ -178,7 +178,7 @@ func createMultiNodeServer(nodeID string, port uint32) *service.LivekitServer {
 	currentNode.Id = nodeID
 
 	// redis routing and store
-	s, err := service.InitializeServer(conf, currentNode)
+	s, err := service.InitializeServer(config.NewCurrent(conf), currentNode)
 	if err != nil {
 		panic(fmt.Sprintf("could not create server: %v", err))
 	}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/livekit-server/pkg/testutils"
+	testclient "github.com/livekit/livekit-server/test/client"
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+var (
+	soakDuration = flag.Duration("soak", 0, "run the soak test for this long, i.e. go test ./test -run TestSoak -soak=1h")
+	soakRooms    = flag.Int("soak-rooms", 4, "rooms created in each cycle of the soak test")
+	soakWindow   = flag.Int("soak-window", 5, "cycles a resource has to grow in for the soak test to fail")
+	soakWarmUp   = flag.Int("soak-warm-up", 3, "cycles run before snapshots are compared, pools and caches fill up during these")
+	// workers of closed participants and signal connections exit on their next tick
+	soakSettle = flag.Duration("soak-settle", 15*time.Second, "time given to the server to wind down after each cycle")
+)
+
+// TestSoak churns rooms with publishers and subscribers till the soak duration is up. After each cycle all rooms are
+// closed and a snapshot of the process is taken, the test fails when any resource keeps growing across cycles
+func TestSoak(t *testing.T) {
+	if *soakDuration == 0 {
+		t.Skip("soak mode is not enabled, run with -soak=<duration>")
+		return
+	}
+
+	s, finish := setupSingleNodeTest("TestSoak")
+	defer finish()
+
+	detector := testutils.NewLeakDetector(*soakWindow)
+	defer func() {
+		t.Log(detector.Report())
+	}()
+
+	deadline := time.Now().Add(*soakDuration)
+	for cycle := 0; time.Now().Before(deadline); cycle++ {
+		for i := 0; i < *soakRooms; i++ {
+			soakRoom(t, fmt.Sprintf("soak-%d-%d", cycle, i))
+		}
+
+		testutils.WithTimeout(t, func() string {
+			if counts := s.RoomManager().ObjectCounts(); counts["rooms"] != 0 {
+				return fmt.Sprintf("rooms were not closed: %v", counts)
+			}
+			return ""
+		})
+		time.Sleep(*soakSettle)
+
+		snapshot := testutils.TakeResourceSnapshot(cycle, s.RoomManager().ObjectCounts())
+		logger.Infow("soak cycle complete", "cycle", cycle, "metrics", snapshot.Metrics)
+		if cycle < *soakWarmUp {
+			continue
+		}
+		detector.Add(snapshot)
+		if leaks := detector.Leaks(); len(leaks) != 0 {
+			t.Fatalf("resources grew over the last %d cycles: %+v\n%s", *soakWindow, leaks, detector.Report())
+		}
+	}
+}
+
+// soakRoom runs a room with a publisher and a subscriber receiving its tracks, then deletes it
+func soakRoom(t *testing.T, room string) {
+	pub := createRTCClientWithToken(joinToken(room, "publisher"), defaultServerPort, nil)
+	sub := createRTCClientWithToken(joinToken(room, "subscriber"), defaultServerPort, nil)
+	waitUntilConnected(t, pub, sub)
+
+	var writers []*testclient.TrackWriter
+	for _, mime := range []string{"audio/opus", "video/vp8"} {
+		tw, err := pub.AddStaticTrack(mime, mime, mime)
+		require.NoError(t, err)
+		writers = append(writers, tw)
+	}
+
+	testutils.WithTimeout(t, func() string {
+		if len(sub.SubscribedTracks()[pub.ID()]) != len(writers) {
+			return "subscriber did not receive the tracks of publisher"
+		}
+		return ""
+	})
+
+	stopWriters(writers...)
+	stopClients(pub, sub)
+
+	_, err := roomClient.DeleteRoom(contextWithToken(createRoomToken()), &livekit.DeleteRoomRequest{
+		Room: room,
+	})
+	require.NoError(t, err)
+}
@@ -124,7 +124,7 @@ func setupServerWithWebhook() (server *service.LivekitServer, testServer *webhoo
 	}
 	currentNode.Id = utils.NewGuid(nodeID1)
 
-	server, err = service.InitializeServer(conf, currentNode)
+	server, err = service.InitializeServer(config.NewCurrent(conf), currentNode)
 	if err != nil {
 		return
 	}